@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// client calls a Skyline server's HTTP API. The admin token is sent as the
+// skyline_admin cookie, which the server accepts in place of a browser
+// login; a profile token is sent as a bearer token.
+type client struct {
+	baseURL      string
+	adminToken   string
+	profileToken string
+	http         *http.Client
+}
+
+// apiError is a non-2xx answer from the server.
+type apiError struct {
+	Status int
+	Body   string
+}
+
+func (e *apiError) Error() string {
+	body := strings.TrimSpace(e.Body)
+	if body == "" {
+		body = http.StatusText(e.Status)
+	}
+	return fmt.Sprintf("server returned %d: %s", e.Status, body)
+}
+
+func (c *client) newRequest(ctx context.Context, method, path string, query url.Values, body any) (*http.Request, error) {
+	u := strings.TrimRight(c.baseURL, "/") + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.adminToken != "" {
+		req.AddCookie(&http.Cookie{Name: "skyline_admin", Value: c.adminToken})
+	}
+	if c.profileToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.profileToken)
+	}
+	return req, nil
+}
+
+// do sends a request and returns the response body; non-2xx answers are
+// *apiError.
+func (c *client) do(ctx context.Context, method, path string, query url.Values, body any) ([]byte, error) {
+	req, err := c.newRequest(ctx, method, path, query, body)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return data, &apiError{Status: resp.StatusCode, Body: string(data)}
+	}
+	return data, nil
+}
+
+// sseEvent is one Server-Sent Event.
+type sseEvent struct {
+	Name string
+	Data string
+}
+
+// stream reads the Server-Sent Events at path until ctx ends or the server
+// closes the stream, calling fn for each event.
+func (c *client) stream(ctx context.Context, path string, fn func(sseEvent)) error {
+	req, err := c.newRequest(ctx, http.MethodGet, path, nil, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	// The stream is long-lived; only the context ends it.
+	streaming := &http.Client{Transport: c.http.Transport}
+	resp, err := streaming.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		return &apiError{Status: resp.StatusCode, Body: string(data)}
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64<<10), 16<<20)
+	var event sseEvent
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if len(data) > 0 || event.Name != "" {
+				event.Data = strings.Join(data, "\n")
+				fn(event)
+			}
+			event, data = sseEvent{}, nil
+		case strings.HasPrefix(line, "event:"):
+			event.Name = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	return scanner.Err()
+}
+
+// newClient returns a client for baseURL. The server always serves TLS,
+// usually with a self-signed certificate: caCert adds a certificate to the
+// system roots, defaulting to the one the server generates in
+// ~/.skyline/tls when it exists, and insecure skips verification.
+func newClient(baseURL, adminToken, profileToken, caCert string, insecure bool, timeout time.Duration) (*client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	tlsConfig := &tls.Config{InsecureSkipVerify: insecure}
+	if caCert == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			if p := filepath.Join(home, ".skyline", "tls", "skyline.crt"); fileExists(p) {
+				caCert = p
+			}
+		}
+	}
+	if caCert != "" && !insecure {
+		pem, err := os.ReadFile(caCert)
+		if err != nil {
+			return nil, fmt.Errorf("read CA certificate: %w", err)
+		}
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in %s", caCert)
+		}
+		tlsConfig.RootCAs = roots
+	}
+	transport.TLSClientConfig = tlsConfig
+	return &client{
+		baseURL:      baseURL,
+		adminToken:   adminToken,
+		profileToken: profileToken,
+		http:         &http.Client{Timeout: timeout, Transport: transport},
+	}, nil
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
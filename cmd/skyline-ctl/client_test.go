@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClientTLS(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"profiles":[]}`)
+	}))
+	server.Config.ErrorLog = log.New(io.Discard, "", 0) // the rejected handshake
+	server.StartTLS()
+	defer server.Close()
+	t.Setenv("HOME", t.TempDir())
+	ctx := context.Background()
+
+	// The test server's certificate is self-signed, like Skyline's.
+	c, err := newClient(server.URL, "", "", "", false, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.do(ctx, http.MethodGet, "/profiles", nil, nil); err == nil {
+		t.Fatal("an untrusted certificate was accepted")
+	}
+
+	caCert := filepath.Join(t.TempDir(), "skyline.crt")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caCert, certPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if c, err = newClient(server.URL, "", "", caCert, false, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.do(ctx, http.MethodGet, "/profiles", nil, nil); err != nil {
+		t.Errorf("with --ca-cert: %v", err)
+	}
+
+	// The certificate the server generates is trusted by default.
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.MkdirAll(filepath.Join(home, ".skyline", "tls"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".skyline", "tls", "skyline.crt"), certPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if c, err = newClient(server.URL, "", "", "", false, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.do(ctx, http.MethodGet, "/profiles", nil, nil); err != nil {
+		t.Errorf("with ~/.skyline/tls/skyline.crt: %v", err)
+	}
+
+	if _, err := newClient(server.URL, "", "", filepath.Join(home, "missing.crt"), false, time.Second); err == nil {
+		t.Error("a missing CA certificate was accepted")
+	}
+	if _, err := newClient(server.URL, "", "", os.Args[0], false, time.Second); err == nil || !strings.Contains(err.Error(), "no certificates") {
+		t.Errorf("a file without certificates: %v", err)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/empty":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			http.Error(w, "invalid credentials", http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+	c, err := newClient(server.URL+"/", "", "", "", true, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.do(context.Background(), http.MethodGet, "/admin/audit", nil, nil)
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusUnauthorized || err.Error() != "server returned 401: invalid credentials" {
+		t.Errorf("err = %v", err)
+	}
+	if _, err := c.do(context.Background(), http.MethodGet, "/empty", nil, nil); err == nil || err.Error() != "server returned 403: Forbidden" {
+		t.Errorf("err without a body = %v", err)
+	}
+}
+
+func TestClientStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "text/event-stream" {
+			http.Error(w, "not a stream request", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": keepalive\n\n")
+		fmt.Fprint(w, "event: audit\ndata: {\"profile\":\"ops\",\"event_type\":\"execute\",\"tool_name\":\"api__get\",\"status_code\":200,\"success\":true}\n\n")
+		fmt.Fprint(w, "event: audit\ndata: {\"profile\":\"other\",\"event_type\":\"execute\",\"success\":true}\n\n")
+		fmt.Fprint(w, "event: audit\ndata: {\"profile\":\"ops\",\"event_type\":\"connect\",\"success\":true}\n\n")
+		fmt.Fprint(w, "event: agent\ndata: line one\ndata: line two\n\n")
+	}))
+	defer server.Close()
+
+	c, err := newClient(server.URL, "admin", "", "", true, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []sseEvent
+	if err := c.stream(context.Background(), "/admin/events", func(ev sseEvent) { events = append(events, ev) }); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 4 || events[3] != (sseEvent{Name: "agent", Data: "line one\nline two"}) {
+		t.Errorf("events = %+v", events)
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"--server", server.URL, "tail", "--profile", "ops"}, &stdout, &stderr)
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if code != 0 || len(lines) != 1 || !strings.Contains(lines[0], "api__get") || !strings.HasSuffix(lines[0], "ok") {
+		t.Errorf("tail: %d %q %q", code, stdout.String(), stderr.String())
+	}
+}
@@ -0,0 +1,678 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// errUsage reports bad arguments; the usage has already been printed.
+var errUsage = errors.New("usage")
+
+// parseFlags parses a command's flags and checks its positional argument
+// count.
+func parseFlags(g *globals, name, usageLine string, args []string, minArgs, maxArgs int, setup func(fs *flag.FlagSet)) (*flag.FlagSet, error) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(g.stderr)
+	if setup != nil {
+		setup(fs)
+	}
+	fs.Usage = func() {
+		fmt.Fprintf(g.stderr, "Usage: skyline-ctl %s\n", usageLine)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, errUsage
+	}
+	if fs.NArg() < minArgs || (maxArgs >= 0 && fs.NArg() > maxArgs) {
+		fs.Usage()
+		return nil, errUsage
+	}
+	return fs, nil
+}
+
+// printJSON writes data, a JSON document, indented.
+func printJSON(w io.Writer, data []byte) error {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, "", "  "); err != nil {
+		_, err = w.Write(data)
+		return err
+	}
+	buf.WriteByte('\n')
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+func profilePath(name string, suffix ...string) string {
+	return "/profiles/" + url.PathEscape(name) + strings.Join(suffix, "")
+}
+
+func runProfiles(ctx context.Context, g *globals, args []string) error {
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+	switch sub, rest := args[0], args[1:]; sub {
+	case "list":
+		if _, err := parseFlags(g, "profiles list", "profiles list", rest, 0, 0, nil); err != nil {
+			return err
+		}
+		data, err := g.client.do(ctx, http.MethodGet, "/profiles", nil, nil)
+		if err != nil {
+			return err
+		}
+		if g.output == "json" {
+			return printJSON(g.stdout, data)
+		}
+		var resp struct {
+			Profiles []string `json:"profiles"`
+		}
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return err
+		}
+		for _, name := range resp.Profiles {
+			fmt.Fprintln(g.stdout, name)
+		}
+		return nil
+
+	case "get":
+		fs, err := parseFlags(g, "profiles get", "profiles get <name>", rest, 1, 1, nil)
+		if err != nil {
+			return err
+		}
+		var query url.Values
+		if g.output == "json" {
+			query = url.Values{"format": {"json"}}
+		}
+		data, err := g.client.do(ctx, http.MethodGet, profilePath(fs.Arg(0)), query, nil)
+		if err != nil {
+			return err
+		}
+		if g.output == "json" {
+			return printJSON(g.stdout, data)
+		}
+		if !bytes.HasSuffix(data, []byte("\n")) {
+			data = append(data, '\n')
+		}
+		_, err = g.stdout.Write(data)
+		return err
+
+	case "put":
+		var file, token *string
+		fs, err := parseFlags(g, "profiles put", "profiles put -f <config.yaml|-> [--profile-token token] <name>", rest, 1, 1, func(fs *flag.FlagSet) {
+			file = fs.String("f", "", "Profile config YAML file, or - for stdin (required)")
+			token = fs.String("profile-token", "", "Bearer token for the profile; required when creating it, kept when omitted on update")
+		})
+		if err != nil {
+			return err
+		}
+		if *file == "" {
+			fs.Usage()
+			return errUsage
+		}
+		var config []byte
+		if *file == "-" {
+			config, err = io.ReadAll(os.Stdin)
+		} else {
+			config, err = os.ReadFile(*file)
+		}
+		if err != nil {
+			return err
+		}
+		body := map[string]any{"config_yaml": string(config)}
+		if *token != "" {
+			body["token"] = *token
+		}
+		if _, err := g.client.do(ctx, http.MethodPut, profilePath(fs.Arg(0)), nil, body); err != nil {
+			return err
+		}
+		fmt.Fprintf(g.stdout, "saved profile %s\n", fs.Arg(0))
+		return nil
+
+	case "delete":
+		fs, err := parseFlags(g, "profiles delete", "profiles delete <name>", rest, 1, 1, nil)
+		if err != nil {
+			return err
+		}
+		if _, err := g.client.do(ctx, http.MethodDelete, profilePath(fs.Arg(0)), nil, nil); err != nil {
+			return err
+		}
+		fmt.Fprintf(g.stdout, "deleted profile %s\n", fs.Arg(0))
+		return nil
+	}
+	fmt.Fprintf(g.stderr, "Usage: skyline-ctl profiles [list | get <name> | put -f <file> <name> | delete <name>]\n")
+	return errUsage
+}
+
+func runTools(ctx context.Context, g *globals, args []string) error {
+	fs, err := parseFlags(g, "tools", "tools <profile>", args, 1, 1, nil)
+	if err != nil {
+		return err
+	}
+	data, err := g.client.do(ctx, http.MethodGet, profilePath(fs.Arg(0), "/tools"), nil, nil)
+	if err != nil {
+		return err
+	}
+	if g.output == "json" {
+		return printJSON(g.stdout, data)
+	}
+	var resp struct {
+		Tools []struct {
+			Name        string `json:"name"`
+			Description string `json:"description"`
+		} `json:"tools"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(g.stdout, 0, 4, 2, ' ', 0)
+	for _, tool := range resp.Tools {
+		fmt.Fprintf(tw, "%s\t%s\n", tool.Name, firstLine(tool.Description, 80))
+	}
+	return tw.Flush()
+}
+
+func runExec(ctx context.Context, g *globals, args []string) error {
+	var argsJSON, argsFile *string
+	var async *bool
+	fs, err := parseFlags(g, "exec", "exec [--args JSON | --args-file file] [--async] <profile> <tool>", args, 2, 2, func(fs *flag.FlagSet) {
+		argsJSON = fs.String("args", "", "Tool arguments as a JSON object")
+		argsFile = fs.String("args-file", "", "File holding the tool arguments as a JSON object, or - for stdin")
+		async = fs.Bool("async", false, "Run as a background job and print its ID; poll with the skyline__get_job_result tool")
+	})
+	if err != nil {
+		return err
+	}
+	raw := []byte(*argsJSON)
+	switch {
+	case *argsFile == "-":
+		raw, err = io.ReadAll(os.Stdin)
+	case *argsFile != "":
+		raw, err = os.ReadFile(*argsFile)
+	}
+	if err != nil {
+		return err
+	}
+	arguments := map[string]any{}
+	if len(bytes.TrimSpace(raw)) > 0 {
+		if err := json.Unmarshal(raw, &arguments); err != nil {
+			return fmt.Errorf("arguments must be a JSON object: %w", err)
+		}
+	}
+	data, err := g.client.do(ctx, http.MethodPost, profilePath(fs.Arg(0), "/execute"), nil, map[string]any{
+		"tool_name": fs.Arg(1),
+		"arguments": arguments,
+		"async":     *async,
+	})
+	var apiErr *apiError
+	if errors.As(err, &apiErr) && json.Valid(data) {
+		// Argument and upstream errors are JSON envelopes; show them whole.
+		_ = printJSON(g.stdout, data)
+		return fmt.Errorf("server returned %d", apiErr.Status)
+	}
+	if err != nil {
+		return err
+	}
+	return printJSON(g.stdout, data)
+}
+
+// specAuthUsage lists the credential flags of detect, test and operations.
+const specAuthUsage = "[--bearer-token token | --basic user:password | --api-key Header=value | --auth-from profile/api]"
+
+// specAuthFlags registers the credential flags of detect, test and
+// operations. The returned function builds the auth field of the request
+// body from them, or nil when none is set.
+func specAuthFlags(fs *flag.FlagSet) func() (map[string]any, error) {
+	bearer := fs.String("bearer-token", "", "Bearer token sent with the requests")
+	basic := fs.String("basic", "", "Basic auth credentials, user:password")
+	apiKey := fs.String("api-key", "", "API key header, Header=value")
+	from := fs.String("auth-from", "", "Reuse the auth of an API in a profile, profile/api")
+	return func() (map[string]any, error) {
+		var auth map[string]any
+		set := 0
+		if *bearer != "" {
+			auth = map[string]any{"type": "bearer", "token": *bearer}
+			set++
+		}
+		if *basic != "" {
+			user, password, ok := strings.Cut(*basic, ":")
+			if !ok {
+				return nil, fmt.Errorf("--basic must be user:password")
+			}
+			auth = map[string]any{"type": "basic", "username": user, "password": password}
+			set++
+		}
+		if *apiKey != "" {
+			header, value, ok := strings.Cut(*apiKey, "=")
+			if !ok {
+				return nil, fmt.Errorf("--api-key must be Header=value")
+			}
+			auth = map[string]any{"type": "api-key", "header": header, "value": value}
+			set++
+		}
+		if *from != "" {
+			profile, api, ok := strings.Cut(*from, "/")
+			if !ok {
+				return nil, fmt.Errorf("--auth-from must be profile/api")
+			}
+			auth = map[string]any{"profile": profile, "api": api}
+			set++
+		}
+		if set > 1 {
+			return nil, fmt.Errorf("use only one of --bearer-token, --basic, --api-key and --auth-from")
+		}
+		return auth, nil
+	}
+}
+
+func runDetect(ctx context.Context, g *globals, args []string) error {
+	var authFlags func() (map[string]any, error)
+	var stopOnFirst bool
+	var timeout int
+	fs, err := parseFlags(g, "detect", "detect [--stop-on-first] [--timeout seconds] "+specAuthUsage+" <base-url>", args, 1, 1, func(fs *flag.FlagSet) {
+		authFlags = specAuthFlags(fs)
+		fs.BoolVar(&stopOnFirst, "stop-on-first", false, "stop probing once a spec is found")
+		fs.IntVar(&timeout, "timeout", 0, "deadline for all probes in seconds (server default 10, at most 12)")
+	})
+	if err != nil {
+		return err
+	}
+	body := map[string]any{"base_url": fs.Arg(0)}
+	if stopOnFirst {
+		body["stop_on_first"] = true
+	}
+	if timeout > 0 {
+		body["timeout_seconds"] = timeout
+	}
+	auth, err := authFlags()
+	if err != nil {
+		return err
+	}
+	if auth != nil {
+		body["auth"] = auth
+	}
+	data, err := g.client.do(ctx, http.MethodPost, "/detect", nil, body)
+	if err != nil {
+		return err
+	}
+	if g.output == "json" {
+		return printJSON(g.stdout, data)
+	}
+	var resp struct {
+		Online   bool `json:"online"`
+		Detected []struct {
+			Type    string `json:"type"`
+			SpecURL string `json:"spec_url"`
+			Status  int    `json:"status"`
+			Found   bool   `json:"found"`
+		} `json:"detected"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return err
+	}
+	if !resp.Online {
+		fmt.Fprintf(g.stdout, "%s is not reachable\n", fs.Arg(0))
+		return nil
+	}
+	tw := tabwriter.NewWriter(g.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TYPE\tSPEC URL\tSTATUS")
+	found := 0
+	for _, probe := range resp.Detected {
+		if probe.Found {
+			found++
+			fmt.Fprintf(tw, "%s\t%s\t%d\n", probe.Type, probe.SpecURL, probe.Status)
+		}
+	}
+	if found == 0 {
+		fmt.Fprintf(g.stdout, "no API specs found at %s\n", fs.Arg(0))
+		return nil
+	}
+	return tw.Flush()
+}
+
+func runTest(ctx context.Context, g *globals, args []string) error {
+	var authFlags func() (map[string]any, error)
+	fs, err := parseFlags(g, "test", "test "+specAuthUsage+" <spec-url>", args, 1, 1, func(fs *flag.FlagSet) {
+		authFlags = specAuthFlags(fs)
+	})
+	if err != nil {
+		return err
+	}
+	body := map[string]any{"spec_url": fs.Arg(0)}
+	auth, err := authFlags()
+	if err != nil {
+		return err
+	}
+	if auth != nil {
+		body["auth"] = auth
+	}
+	data, err := g.client.do(ctx, http.MethodPost, "/test", nil, body)
+	if err != nil {
+		return err
+	}
+	if g.output == "json" {
+		return printJSON(g.stdout, data)
+	}
+	var resp struct {
+		Online bool   `json:"online"`
+		Status int    `json:"status"`
+		Error  string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return err
+	}
+	if !resp.Online {
+		return fmt.Errorf("%s is not reachable: %s", fs.Arg(0), resp.Error)
+	}
+	fmt.Fprintf(g.stdout, "%s is reachable (status %d)\n", fs.Arg(0), resp.Status)
+	return nil
+}
+
+func runOperations(ctx context.Context, g *globals, args []string) error {
+	var specType, name *string
+	var authFlags func() (map[string]any, error)
+	fs, err := parseFlags(g, "operations", "operations [--spec-type type] [--name api] "+specAuthUsage+" [spec-url]", args, 0, 1, func(fs *flag.FlagSet) {
+		specType = fs.String("spec-type", "", "Spec type hint, e.g. openapi or graphql")
+		name = fs.String("name", "", "Well-known API name (e.g. slack) whose spec URL the server resolves")
+		authFlags = specAuthFlags(fs)
+	})
+	if err != nil {
+		return err
+	}
+	if fs.NArg() == 0 && *name == "" {
+		fs.Usage()
+		return errUsage
+	}
+	body := map[string]any{"spec_url": fs.Arg(0)}
+	if *specType != "" {
+		body["spec_type"] = *specType
+	}
+	if *name != "" {
+		body["name"] = *name
+	}
+	auth, err := authFlags()
+	if err != nil {
+		return err
+	}
+	if auth != nil {
+		body["auth"] = auth
+	}
+	data, err := g.client.do(ctx, http.MethodPost, "/operations", nil, body)
+	if err != nil {
+		return err
+	}
+	if g.output == "json" {
+		return printJSON(g.stdout, data)
+	}
+	var resp struct {
+		Operations []struct {
+			ID      string `json:"id"`
+			Method  string `json:"method"`
+			Path    string `json:"path"`
+			Summary string `json:"summary"`
+		} `json:"operations"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return err
+	}
+	if resp.Error != "" {
+		return errors.New(resp.Error)
+	}
+	tw := tabwriter.NewWriter(g.stdout, 0, 4, 2, ' ', 0)
+	for _, op := range resp.Operations {
+		fmt.Fprintf(tw, "%s\t%s %s\t%s\n", op.ID, strings.ToUpper(op.Method), op.Path, firstLine(op.Summary, 60))
+	}
+	return tw.Flush()
+}
+
+// auditEvent is the subset of an audit event the text output shows.
+type auditEvent struct {
+	Timestamp  time.Time `json:"timestamp"`
+	Profile    string    `json:"profile"`
+	EventType  string    `json:"event_type"`
+	ToolName   string    `json:"tool_name"`
+	DurationMs int64     `json:"duration_ms"`
+	StatusCode int       `json:"status_code"`
+	Success    bool      `json:"success"`
+	ErrorMsg   string    `json:"error_msg"`
+	ClientAddr string    `json:"client_addr"`
+}
+
+func (e auditEvent) result() string {
+	if e.Success {
+		return "ok"
+	}
+	return "error: " + firstLine(e.ErrorMsg, 80)
+}
+
+func runAudit(ctx context.Context, g *globals, args []string) error {
+	var profile, eventType, tool *string
+	var limit *int
+	if _, err := parseFlags(g, "audit", "audit [--profile name] [--event-type type] [--tool name] [--limit n]", args, 0, 0, func(fs *flag.FlagSet) {
+		profile = fs.String("profile", "", "Only events of this profile")
+		eventType = fs.String("event-type", "", "Only events of this type: execute, code, connect, disconnect or error")
+		tool = fs.String("tool", "", "Only calls of this tool")
+		limit = fs.Int("limit", 50, "Maximum number of events (at most 1000)")
+	}); err != nil {
+		return err
+	}
+	query := url.Values{"limit": {strconv.Itoa(*limit)}}
+	for key, value := range map[string]string{"profile": *profile, "event_type": *eventType, "tool_name": *tool} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	data, err := g.client.do(ctx, http.MethodGet, "/admin/audit", query, nil)
+	if err != nil {
+		return err
+	}
+	if g.output == "json" {
+		return printJSON(g.stdout, data)
+	}
+	var resp struct {
+		Events []auditEvent `json:"events"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(g.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tPROFILE\tTYPE\tTOOL\tSTATUS\tDURATION\tRESULT")
+	for _, e := range resp.Events {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%dms\t%s\n",
+			e.Timestamp.Local().Format(time.DateTime), e.Profile, e.EventType, e.ToolName, e.StatusCode, e.DurationMs, e.result())
+	}
+	return tw.Flush()
+}
+
+func runStats(ctx context.Context, g *globals, args []string) error {
+	var profile, since *string
+	if _, err := parseFlags(g, "stats", "stats [--profile name] [--since 24h|RFC3339]", args, 0, 0, func(fs *flag.FlagSet) {
+		profile = fs.String("profile", "", "Only this profile")
+		since = fs.String("since", "", "Start of the window: a duration ago (e.g. 1h) or an RFC 3339 time; default 24h")
+	}); err != nil {
+		return err
+	}
+	query := url.Values{}
+	if *profile != "" {
+		query.Set("profile", *profile)
+	}
+	if *since != "" {
+		if d, err := time.ParseDuration(*since); err == nil {
+			query.Set("since", time.Now().Add(-d).UTC().Format(time.RFC3339))
+		} else if _, err := time.Parse(time.RFC3339, *since); err == nil {
+			query.Set("since", *since)
+		} else {
+			return fmt.Errorf("--since must be a duration or an RFC 3339 time")
+		}
+	}
+	data, err := g.client.do(ctx, http.MethodGet, "/admin/stats", query, nil)
+	if err != nil {
+		return err
+	}
+	if g.output == "json" {
+		return printJSON(g.stdout, data)
+	}
+	type apiStats struct {
+		Name      string  `json:"name"`
+		Calls     int64   `json:"calls"`
+		ErrorRate float64 `json:"error_rate"`
+		AvgMs     int64   `json:"avg_ms"`
+	}
+	var resp struct {
+		AuditStats struct {
+			TotalRequests  int64      `json:"total_requests"`
+			FailedRequests int64      `json:"failed_requests"`
+			ErrorRate      float64    `json:"error_rate"`
+			AvgDurationMs  int64      `json:"avg_duration_ms"`
+			MaxDurationMs  int64      `json:"max_duration_ms"`
+			TopTools       []apiStats `json:"top_tools"`
+		} `json:"audit_stats"`
+		Quota map[string][]struct {
+			Scope     string `json:"scope"`
+			Period    string `json:"period"`
+			Limit     int    `json:"limit"`
+			Used      int64  `json:"used"`
+			Remaining int64  `json:"remaining"`
+		} `json:"quota"`
+		Period struct {
+			Since time.Time `json:"since"`
+		} `json:"period"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return err
+	}
+	s := resp.AuditStats
+	fmt.Fprintf(g.stdout, "Since %s\n", resp.Period.Since.Local().Format(time.DateTime))
+	fmt.Fprintf(g.stdout, "Calls: %d (%d failed, %.1f%% errors)\n", s.TotalRequests, s.FailedRequests, s.ErrorRate)
+	fmt.Fprintf(g.stdout, "Duration: avg %dms, max %dms\n", s.AvgDurationMs, s.MaxDurationMs)
+	tw := tabwriter.NewWriter(g.stdout, 0, 4, 2, ' ', 0)
+	if len(s.TopTools) > 0 {
+		fmt.Fprintln(tw, "\nTOOL\tCALLS\tERRORS\tAVG")
+		for _, t := range s.TopTools {
+			fmt.Fprintf(tw, "%s\t%d\t%.1f%%\t%dms\n", t.Name, t.Calls, t.ErrorRate, t.AvgMs)
+		}
+	}
+	if len(resp.Quota) > 0 {
+		fmt.Fprintln(tw, "\nPROFILE\tBUDGET\tPERIOD\tUSED\tLIMIT\tREMAINING")
+		for name, budgets := range resp.Quota {
+			for _, b := range budgets {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%d\n", name, b.Scope, b.Period, b.Used, b.Limit, b.Remaining)
+			}
+		}
+	}
+	return tw.Flush()
+}
+
+func runSessions(ctx context.Context, g *globals, args []string) error {
+	if len(args) > 0 && args[0] == "disconnect" {
+		fs, err := parseFlags(g, "sessions disconnect", "sessions disconnect <session-id>", args[1:], 1, 1, nil)
+		if err != nil {
+			return err
+		}
+		if _, err := g.client.do(ctx, http.MethodDelete, "/admin/sessions/"+url.PathEscape(fs.Arg(0)), nil, nil); err != nil {
+			return err
+		}
+		fmt.Fprintf(g.stdout, "disconnected session %s\n", fs.Arg(0))
+		return nil
+	}
+	if len(args) > 0 && args[0] == "list" {
+		args = args[1:]
+	}
+	if _, err := parseFlags(g, "sessions", "sessions [list | disconnect <session-id>]", args, 0, 0, nil); err != nil {
+		return err
+	}
+	data, err := g.client.do(ctx, http.MethodGet, "/admin/sessions", nil, nil)
+	if err != nil {
+		return err
+	}
+	if g.output == "json" {
+		return printJSON(g.stdout, data)
+	}
+	var resp struct {
+		Sessions []struct {
+			ID         string `json:"id"`
+			Profile    string `json:"profile"`
+			ClientInfo *struct {
+				Name    string `json:"name"`
+				Version string `json:"version"`
+			} `json:"client_info"`
+			ClientAddr    string    `json:"client_addr"`
+			ConnectedAt   time.Time `json:"connected_at"`
+			InFlight      int64     `json:"in_flight"`
+			Subscriptions int       `json:"subscriptions"`
+			RequestCount  int64     `json:"request_count"`
+		} `json:"sessions"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(g.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tPROFILE\tCLIENT\tADDRESS\tCONNECTED\tIN FLIGHT\tSUBS\tCALLS")
+	for _, s := range resp.Sessions {
+		clientName := "-"
+		if s.ClientInfo != nil {
+			clientName = strings.TrimSpace(s.ClientInfo.Name + " " + s.ClientInfo.Version)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\t%d\t%d\n", s.ID, s.Profile, clientName, s.ClientAddr,
+			s.ConnectedAt.Local().Format(time.DateTime), s.InFlight, s.Subscriptions, s.RequestCount)
+	}
+	return tw.Flush()
+}
+
+func runTail(ctx context.Context, g *globals, args []string) error {
+	var profile *string
+	var all *bool
+	if _, err := parseFlags(g, "tail", "tail [--profile name] [--all]", args, 0, 0, func(fs *flag.FlagSet) {
+		profile = fs.String("profile", "", "Only events of this profile")
+		all = fs.Bool("all", false, "Show every audit and agent event, not just executions")
+	}); err != nil {
+		return err
+	}
+	return g.client.stream(ctx, "/admin/events", func(ev sseEvent) {
+		if ev.Name != "audit" && !(*all && ev.Name == "agent") {
+			return
+		}
+		var e auditEvent
+		if err := json.Unmarshal([]byte(ev.Data), &e); err != nil {
+			return
+		}
+		if *profile != "" && e.Profile != *profile {
+			return
+		}
+		if !*all && e.EventType != "execute" && e.EventType != "code" {
+			return
+		}
+		if g.output == "json" {
+			fmt.Fprintln(g.stdout, ev.Data)
+			return
+		}
+		if ev.Name == "agent" {
+			fmt.Fprintf(g.stdout, "agent  %s\n", ev.Data)
+			return
+		}
+		fmt.Fprintf(g.stdout, "%s  %-12s %-8s %-40s %4d %6dms  %s\n",
+			e.Timestamp.Local().Format(time.TimeOnly), e.Profile, e.EventType, e.ToolName, e.StatusCode, e.DurationMs, e.result())
+	})
+}
+
+// firstLine returns the first line of s, cut to max runes.
+func firstLine(s string, max int) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i]
+	}
+	if r := []rune(s); len(r) > max {
+		return string(r[:max-1]) + "…"
+	}
+	return s
+}
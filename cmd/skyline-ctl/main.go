@@ -0,0 +1,132 @@
+// Command skyline-ctl manages a running Skyline server through its HTTP API:
+// profiles, tool execution, spec detection, audit logs, stats and live
+// activity.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+)
+
+// Version is set at build time with -ldflags "-X main.Version=...".
+var Version = "dev"
+
+const defaultServer = "https://localhost:8191"
+
+// globals are the options shared by every command.
+type globals struct {
+	client *client
+	output string // text or json
+	stdout io.Writer
+	stderr io.Writer
+}
+
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, g *globals, args []string) error
+}
+
+var commands = []command{
+	{"profiles", "List, show, create/update or delete profiles", runProfiles},
+	{"tools", "List a profile's tools", runTools},
+	{"exec", "Execute a tool", runExec},
+	{"detect", "Detect API specs at a base URL", runDetect},
+	{"test", "Check that a spec URL is reachable", runTest},
+	{"operations", "List the operations a spec defines", runOperations},
+	{"audit", "Query the audit log", runAudit},
+	{"stats", "Show aggregated call statistics", runStats},
+	{"sessions", "List or disconnect MCP sessions", runSessions},
+	{"tail", "Follow tool executions live", runTail},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("skyline-ctl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	server := fs.String("server", envOr("SKYLINE_SERVER", defaultServer), "Skyline server URL (env SKYLINE_SERVER)")
+	adminToken := fs.String("admin-token", os.Getenv("SKYLINE_ADMIN_TOKEN"), "Admin token (env SKYLINE_ADMIN_TOKEN)")
+	profileToken := fs.String("token", os.Getenv("SKYLINE_PROFILE_TOKEN"), "Profile bearer token, instead of the admin token for profile commands (env SKYLINE_PROFILE_TOKEN)")
+	caCert := fs.String("ca-cert", os.Getenv("SKYLINE_CA_CERT"), "CA certificate to trust, default ~/.skyline/tls/skyline.crt when present (env SKYLINE_CA_CERT)")
+	insecure := fs.Bool("insecure", false, "Skip TLS certificate verification")
+	output := fs.String("output", "text", "Output format: text, json")
+	timeout := fs.Duration("timeout", 60*time.Second, "Request timeout")
+	version := fs.Bool("version", false, "Print the version and exit")
+	fs.Usage = func() { usage(fs, stderr) }
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if *version {
+		fmt.Fprintf(stdout, "skyline-ctl v%s\n", Version)
+		return 0
+	}
+	if *output != "text" && *output != "json" {
+		fmt.Fprintf(stderr, "unsupported output %q\n", *output)
+		return 2
+	}
+	if fs.NArg() == 0 {
+		usage(fs, stderr)
+		return 2
+	}
+
+	name := fs.Arg(0)
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		c, err := newClient(*server, *adminToken, *profileToken, *caCert, *insecure, *timeout)
+		if err != nil {
+			fmt.Fprintf(stderr, "%v\n", err)
+			return 1
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		g := &globals{
+			client: c,
+			output: *output,
+			stdout: stdout,
+			stderr: stderr,
+		}
+		if err := cmd.run(ctx, g, fs.Args()[1:]); err != nil {
+			if errors.Is(err, errUsage) {
+				return 2
+			}
+			fmt.Fprintf(stderr, "%s: %v\n", name, err)
+			return 1
+		}
+		return 0
+	}
+	fmt.Fprintf(stderr, "unknown command %q\n\n", name)
+	usage(fs, stderr)
+	return 2
+}
+
+func usage(fs *flag.FlagSet, w io.Writer) {
+	fmt.Fprintf(w, "skyline-ctl v%s - manage a Skyline server\n\n", Version)
+	fmt.Fprintf(w, "Usage: skyline-ctl [options] <command> [arguments]\n\n")
+	fmt.Fprintf(w, "Commands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-12s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(w, "\nRun 'skyline-ctl <command> -h' for a command's arguments.\n\nOptions:\n")
+	fs.PrintDefaults()
+}
+
+func envOr(name, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return fallback
+}
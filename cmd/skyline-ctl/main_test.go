@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorded is a request the fake server received.
+type recorded struct {
+	Method, Path, RawQuery string
+	Body                   map[string]any
+	Cookie, Authorization  string
+}
+
+// fakeServer answers every request with the response registered for its
+// "METHOD /path" and records it.
+type fakeServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []recorded
+}
+
+type reply struct {
+	status int
+	body   string
+}
+
+func newFakeServer(t *testing.T, replies map[string]reply) *fakeServer {
+	t.Helper()
+	// Keep the developer's ~/.skyline/tls certificate out of the tests.
+	t.Setenv("HOME", t.TempDir())
+	for _, env := range []string{"SKYLINE_SERVER", "SKYLINE_ADMIN_TOKEN", "SKYLINE_PROFILE_TOKEN", "SKYLINE_CA_CERT"} {
+		t.Setenv(env, "")
+	}
+	f := &fakeServer{}
+	f.Server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := recorded{Method: r.Method, Path: r.URL.EscapedPath(), RawQuery: r.URL.RawQuery, Authorization: r.Header.Get("Authorization")}
+		if c, err := r.Cookie("skyline_admin"); err == nil {
+			rec.Cookie = c.Value
+		}
+		if data, _ := io.ReadAll(r.Body); len(data) > 0 {
+			_ = json.Unmarshal(data, &rec.Body)
+		}
+		f.mu.Lock()
+		f.requests = append(f.requests, rec)
+		f.mu.Unlock()
+		resp, ok := replies[r.Method+" "+r.URL.EscapedPath()]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if resp.status == 0 {
+			resp.status = http.StatusOK
+		}
+		w.WriteHeader(resp.status)
+		io.WriteString(w, resp.body)
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeServer) last(t *testing.T) recorded {
+	t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.requests) == 0 {
+		t.Fatal("the server received no request")
+	}
+	return f.requests[len(f.requests)-1]
+}
+
+// ctl runs skyline-ctl against f and returns its exit code and output.
+func (f *fakeServer) ctl(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(append([]string{"--server", f.URL, "--insecure", "--admin-token", "admin"}, args...), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRunArguments(t *testing.T) {
+	f := newFakeServer(t, nil)
+	tests := []struct {
+		name   string
+		args   []string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{"version", []string{"--version"}, 0, "skyline-ctl vdev", ""},
+		{"help", []string{"-h"}, 0, "", "Commands:"},
+		{"no command", nil, 2, "", "Usage: skyline-ctl"},
+		{"unknown command", []string{"frobnicate"}, 2, "", `unknown command "frobnicate"`},
+		{"unknown flag", []string{"--frob"}, 2, "", "flag provided but not defined"},
+		{"bad output", []string{"--output", "yaml", "profiles"}, 2, "", `unsupported output "yaml"`},
+		{"missing argument", []string{"tools"}, 2, "", "Usage: skyline-ctl tools <profile>"},
+		{"extra argument", []string{"profiles", "get", "a", "b"}, 2, "", "Usage: skyline-ctl profiles get <name>"},
+		{"unknown subcommand", []string{"profiles", "rename"}, 2, "", "Usage: skyline-ctl profiles"},
+		{"put without file", []string{"profiles", "put", "ops"}, 2, "", "-f"},
+		{"operations without spec", []string{"operations"}, 2, "", "Usage: skyline-ctl operations"},
+		{"two auth flags", []string{"detect", "--bearer-token", "x", "--basic", "u:p", "https://api.example.com"}, 1, "", "use only one of"},
+		{"bad basic", []string{"test", "--basic", "user", "https://api.example.com/spec"}, 1, "", "--basic must be user:password"},
+		{"bad since", []string{"stats", "--since", "yesterday"}, 1, "", "--since must be"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(append([]string{"--server", f.URL, "--insecure"}, tt.args...), &stdout, &stderr)
+			if code != tt.code || !strings.Contains(stdout.String(), tt.stdout) || !strings.Contains(stderr.String(), tt.stderr) {
+				t.Errorf("exit %d, stdout %q, stderr %q; want %d, %q, %q", code, stdout.String(), stderr.String(), tt.code, tt.stdout, tt.stderr)
+			}
+		})
+	}
+	if len(f.requests) != 0 {
+		t.Errorf("bad arguments reached the server: %+v", f.requests)
+	}
+}
+
+func TestProfilesCommands(t *testing.T) {
+	f := newFakeServer(t, map[string]reply{
+		"GET /profiles":            {body: `{"profiles":["default","acme/ops"]}`},
+		"GET /profiles/acme%2Fops": {body: `{"name":"acme/ops","apis":[]}`},
+		"PUT /profiles/ops":        {body: `{"status":"ok"}`},
+		"DELETE /profiles/ops":     {body: `{"status":"ok"}`},
+		"GET /profiles/ops/tools":  {body: `{"tools":[{"name":"api__get","description":"Get a thing\nwith details"}]}`},
+		"DELETE /profiles/missing": {status: http.StatusNotFound, body: "404 page not found\n"},
+	})
+
+	if code, out, _ := f.ctl("profiles"); code != 0 || out != "default\nacme/ops\n" {
+		t.Errorf("profiles: %d %q", code, out)
+	}
+	if r := f.last(t); r.Method != http.MethodGet || r.Path != "/profiles" || r.Cookie != "admin" || r.Authorization != "" {
+		t.Errorf("profiles request: %+v", r)
+	}
+
+	code, out, _ := f.ctl("--output", "json", "profiles", "get", "acme/ops")
+	if code != 0 || !strings.Contains(out, "\n  \"name\": \"acme/ops\"") {
+		t.Errorf("profiles get: %d %q", code, out)
+	}
+	if r := f.last(t); r.Path != "/profiles/acme%2Fops" || r.RawQuery != "format=json" {
+		t.Errorf("profiles get request: %+v", r)
+	}
+
+	file := filepath.Join(t.TempDir(), "ops.yaml")
+	if err := os.WriteFile(file, []byte("apis: []\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if code, out, _ := f.ctl("profiles", "put", "-f", file, "--profile-token", "secret", "ops"); code != 0 || out != "saved profile ops\n" {
+		t.Errorf("profiles put: %d %q", code, out)
+	}
+	if r := f.last(t); r.Method != http.MethodPut || r.Body["config_yaml"] != "apis: []\n" || r.Body["token"] != "secret" {
+		t.Errorf("profiles put request: %+v", r)
+	}
+	if code, out, _ := f.ctl("profiles", "delete", "ops"); code != 0 || out != "deleted profile ops\n" {
+		t.Errorf("profiles delete: %d %q", code, out)
+	}
+	if code, _, errOut := f.ctl("profiles", "delete", "missing"); code != 1 || errOut != "profiles: server returned 404: 404 page not found\n" {
+		t.Errorf("deleting a missing profile: %d %q", code, errOut)
+	}
+
+	if code, out, _ := f.ctl("tools", "ops"); code != 0 || out != "api__get  Get a thing\n" {
+		t.Errorf("tools: %d %q", code, out)
+	}
+}
+
+func TestExecCommand(t *testing.T) {
+	f := newFakeServer(t, map[string]reply{
+		"POST /profiles/ops/execute":  {body: `{"status":200,"body":{"id":1}}`},
+		"POST /profiles/bad/execute":  {status: http.StatusBadRequest, body: `{"error":"missing id"}`},
+		"POST /profiles/down/execute": {status: http.StatusBadGateway, body: "upstream down"},
+	})
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"--server", f.URL, "--insecure", "--token", "profile-token", "exec", "--args", `{"id":1}`, "--async", "ops", "api__get"}, &stdout, &stderr)
+	if code != 0 || !strings.Contains(stdout.String(), `"id": 1`) {
+		t.Errorf("exec: %d %q %q", code, stdout.String(), stderr.String())
+	}
+	r := f.last(t)
+	if r.Authorization != "Bearer profile-token" || r.Cookie != "" {
+		t.Errorf("exec credentials: %+v", r)
+	}
+	if r.Body["tool_name"] != "api__get" || r.Body["async"] != true || r.Body["arguments"].(map[string]any)["id"] != float64(1) {
+		t.Errorf("exec body: %+v", r.Body)
+	}
+
+	// JSON errors are printed whole.
+	if code, out, errOut := f.ctl("exec", "bad", "api__get"); code != 1 || !strings.Contains(out, `"error": "missing id"`) || errOut != "exec: server returned 400\n" {
+		t.Errorf("exec error: %d %q %q", code, out, errOut)
+	}
+	if code, _, errOut := f.ctl("exec", "down", "api__get"); code != 1 || errOut != "exec: server returned 502: upstream down\n" {
+		t.Errorf("exec upstream error: %d %q", code, errOut)
+	}
+	if code, _, errOut := f.ctl("exec", "--args", "[1]", "ops", "api__get"); code != 1 || !strings.Contains(errOut, "arguments must be a JSON object") {
+		t.Errorf("exec with array arguments: %d %q", code, errOut)
+	}
+}
+
+func TestQueryCommands(t *testing.T) {
+	f := newFakeServer(t, map[string]reply{
+		"POST /detect":             {body: `{"online":true,"detected":[{"type":"openapi","spec_url":"https://api.example.com/openapi.json","status":200,"found":true},{"type":"graphql","found":false}]}`},
+		"GET /admin/audit":         {body: `{"events":[{"timestamp":"2026-01-02T03:04:05Z","profile":"ops","event_type":"execute","tool_name":"api__get","duration_ms":12,"status_code":500,"success":false,"error_msg":"boom\ntrace"}]}`},
+		"GET /admin/stats":         {body: `{"audit_stats":{"total_requests":3},"period":{"since":"2026-01-01T00:00:00Z"}}`},
+		"DELETE /admin/sessions/a": {body: `{}`},
+	})
+
+	code, out, _ := f.ctl("detect", "--stop-on-first", "--timeout", "5", "--api-key", "X-Key=abc", "https://api.example.com")
+	if code != 0 || !strings.Contains(out, "openapi  https://api.example.com/openapi.json  200") || strings.Contains(out, "graphql") {
+		t.Errorf("detect: %d %q", code, out)
+	}
+	body := f.last(t).Body
+	auth, _ := body["auth"].(map[string]any)
+	if body["stop_on_first"] != true || body["timeout_seconds"] != float64(5) || auth["type"] != "api-key" || auth["header"] != "X-Key" || auth["value"] != "abc" {
+		t.Errorf("detect body: %+v", body)
+	}
+
+	code, out, _ = f.ctl("audit", "--profile", "ops", "--tool", "api__get", "--limit", "5")
+	if code != 0 || !strings.Contains(out, "error: boom") || strings.Contains(out, "trace") {
+		t.Errorf("audit: %d %q", code, out)
+	}
+	if q := f.last(t).RawQuery; q != "limit=5&profile=ops&tool_name=api__get" {
+		t.Errorf("audit query: %s", q)
+	}
+
+	before := time.Now().Add(-time.Hour).Add(-time.Second)
+	if code, out, _ := f.ctl("stats", "--since", "1h"); code != 0 || !strings.Contains(out, "Calls: 3") {
+		t.Errorf("stats: %d %q", code, out)
+	}
+	query, _ := url.ParseQuery(f.last(t).RawQuery)
+	since, err := time.Parse(time.RFC3339, query.Get("since"))
+	if err != nil || since.Before(before) || since.After(time.Now().Add(-time.Hour)) {
+		t.Errorf("stats since = %v, %v", since, err)
+	}
+
+	if code, out, _ := f.ctl("sessions", "disconnect", "a"); code != 0 || out != "disconnected session a\n" {
+		t.Errorf("sessions disconnect: %d %q", code, out)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"skyline-mcp/internal/accesspolicy"
+)
+
+// endpointGroup returns the security.access group a path belongs to, or ""
+// for health probes, which load balancers must always reach.
+func endpointGroup(path string) string {
+	switch path {
+	case "/healthz", "/livez", "/readyz":
+		return ""
+	case "/metrics":
+		return accesspolicy.Metrics
+	case "/mcp", "/oauth/register", "/oauth/authorize", "/oauth/token":
+		return accesspolicy.Gateway
+	}
+	if strings.HasPrefix(path, "/.well-known/") {
+		return accesspolicy.Gateway
+	}
+	if strings.HasPrefix(path, "/profiles/") {
+		for _, suffix := range []string{"/mcp", "/tools", "/execute", "/code"} {
+			if strings.HasSuffix(path, suffix) {
+				return accesspolicy.Gateway
+			}
+		}
+	}
+	return accesspolicy.Management
+}
+
+// enforceAccess rejects requests from networks that security.access does
+// not allow for the endpoint's group.
+func (s *server) enforceAccess(next http.Handler) http.Handler {
+	if !s.access.Restricted() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if group := endpointGroup(r.URL.Path); group != "" && !s.access.Allow(group, r) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
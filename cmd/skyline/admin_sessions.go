@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/term"
+
+	"skyline-mcp/internal/ratelimit"
+	"skyline-mcp/internal/serverconfig"
+)
+
+const (
+	adminCookieName        = "skyline_admin"
+	defaultAdminSessionTTL = 8 * time.Hour
+)
+
+// adminSessions issues and checks admin session cookies. A cookie carries
+// its expiry and a random ID, signed with a key derived from the server key
+// and the admin credentials: sessions are valid on every replica sharing
+// the key, and all of them end when the password or admin token changes.
+type adminSessions struct {
+	key          []byte
+	passwordHash []byte // bcrypt; nil when password login is off
+	ttl          time.Duration
+
+	mu      sync.Mutex
+	revoked map[string]time.Time // signed-out session IDs, until they expire
+}
+
+func newAdminSessions(serverKey []byte, adminToken string, cfg serverconfig.ServerSection) (*adminSessions, error) {
+	a := &adminSessions{ttl: cfg.AdminSessionTTL, revoked: map[string]time.Time{}}
+	if a.ttl <= 0 {
+		a.ttl = defaultAdminSessionTTL
+	}
+	if cfg.AdminPasswordHash != "" {
+		a.passwordHash = []byte(cfg.AdminPasswordHash)
+		if _, err := bcrypt.Cost(a.passwordHash); err != nil {
+			return nil, fmt.Errorf("server.adminPasswordHash is not a bcrypt hash: %w", err)
+		}
+	}
+	mac := hmac.New(sha256.New, serverKey)
+	mac.Write([]byte("skyline admin session\x00" + cfg.AdminPasswordHash + "\x00" + adminToken))
+	a.key = mac.Sum(nil)
+	return a, nil
+}
+
+// passwordLogin reports whether an admin password is configured.
+func (a *adminSessions) passwordLogin() bool {
+	return a != nil && a.passwordHash != nil
+}
+
+func (a *adminSessions) checkPassword(password string) bool {
+	return a.passwordLogin() && bcrypt.CompareHashAndPassword(a.passwordHash, []byte(password)) == nil
+}
+
+// issue returns a new session cookie value and its expiry.
+func (a *adminSessions) issue() (string, time.Time) {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	expires := time.Now().Add(a.ttl)
+	payload := strconv.FormatInt(expires.Unix(), 10) + "." + hex.EncodeToString(id)
+	return payload + "." + a.sign(payload), expires
+}
+
+func (a *adminSessions) sign(payload string) string {
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// parse checks a cookie value's signature and expiry and returns its ID.
+func (a *adminSessions) parse(value string) (string, time.Time, bool) {
+	payload, sig, ok := cutLast(value, ".")
+	if !ok || subtle.ConstantTimeCompare([]byte(sig), []byte(a.sign(payload))) != 1 {
+		return "", time.Time{}, false
+	}
+	exp, id, ok := strings.Cut(payload, ".")
+	if !ok {
+		return "", time.Time{}, false
+	}
+	unix, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	expires := time.Unix(unix, 0)
+	if !time.Now().Before(expires) {
+		return "", time.Time{}, false
+	}
+	return id, expires, true
+}
+
+func (a *adminSessions) valid(value string) bool {
+	if a == nil {
+		return false
+	}
+	id, _, ok := a.parse(value)
+	if !ok {
+		return false
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, revoked := a.revoked[id]
+	return !revoked
+}
+
+// revoke ends a session on this node. Other replicas keep accepting it
+// until it expires.
+func (a *adminSessions) revoke(value string) {
+	id, expires, ok := a.parse(value)
+	if !ok {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := time.Now()
+	for rid, exp := range a.revoked {
+		if !now.Before(exp) {
+			delete(a.revoked, rid)
+		}
+	}
+	a.revoked[id] = expires
+}
+
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// adminLoginsPerMinute is how many sign-in attempts each client may make.
+const adminLoginsPerMinute = 10
+
+// loginLimits rate limits admin sign-in attempts per client, so one client
+// guessing passwords cannot lock everyone else out of the admin UI.
+type loginLimits struct {
+	mu      sync.Mutex
+	clients map[string]*clientLogins
+}
+
+type clientLogins struct {
+	limiter  *ratelimit.Limiter
+	lastSeen time.Time
+}
+
+// allow counts an attempt by client and returns 0 when it may go ahead, or
+// how long the client has to wait.
+func (l *loginLimits) allow(client string) time.Duration {
+	l.mu.Lock()
+	now := time.Now()
+	// A limiter idle for a minute has refilled and is as good as a new one.
+	for c, entry := range l.clients {
+		if now.Sub(entry.lastSeen) >= time.Minute {
+			delete(l.clients, c)
+		}
+	}
+	if l.clients == nil {
+		l.clients = map[string]*clientLogins{}
+	}
+	entry, ok := l.clients[client]
+	if !ok {
+		entry = &clientLogins{limiter: ratelimit.New(adminLoginsPerMinute, 0, 0)}
+		l.clients[client] = entry
+	}
+	entry.lastSeen = now
+	l.mu.Unlock()
+	return entry.limiter.Allow()
+}
+
+// adminLoginRequired reports whether the management endpoints are closed to
+// callers without an admin session or a profile or tenant token.
+func (s *server) adminLoginRequired() bool {
+	return s.adminSessions.passwordLogin()
+}
+
+// requireAdminLogin wraps a setup endpoint such as /detect so that, once an
+// admin password is configured, only admin sessions may use it.
+func (s *server) requireAdminLogin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.adminLoginRequired() && !s.isAdminSession(r) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// runHashPassword implements `skyline hash-password`: it reads a password
+// from the terminal, or the first line of stdin, and prints the bcrypt hash
+// for server.adminPasswordHash.
+func runHashPassword(stdin *os.File, stdout, stderr io.Writer) int {
+	var password []byte
+	if term.IsTerminal(int(stdin.Fd())) {
+		fmt.Fprint(stderr, "Admin password: ")
+		pw, err := term.ReadPassword(int(stdin.Fd()))
+		fmt.Fprintln(stderr)
+		if err != nil {
+			fmt.Fprintf(stderr, "read password: %v\n", err)
+			return 2
+		}
+		password = pw
+	} else {
+		line, err := bufio.NewReader(stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			fmt.Fprintf(stderr, "read password: %v\n", err)
+			return 2
+		}
+		password = []byte(strings.TrimRight(line, "\r\n"))
+	}
+	if len(password) < 8 {
+		fmt.Fprintln(stderr, "password must be at least 8 characters")
+		return 1
+	}
+	hash, err := bcrypt.GenerateFromPassword(password, bcrypt.DefaultCost)
+	if err != nil {
+		fmt.Fprintf(stderr, "hash password: %v\n", err)
+		return 2
+	}
+	fmt.Fprintln(stdout, string(hash))
+	return 0
+}
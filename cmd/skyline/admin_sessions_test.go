@@ -0,0 +1,146 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"skyline-mcp/internal/serverconfig"
+)
+
+func newPasswordSessions(t *testing.T, password string, ttl time.Duration) *adminSessions {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := newAdminSessions(testStorageKey(), testAdminToken, serverconfig.ServerSection{
+		AdminPasswordHash: string(hash),
+		AdminSessionTTL:   ttl,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func TestAdminSessionsRejectInvalidHash(t *testing.T) {
+	_, err := newAdminSessions(testStorageKey(), testAdminToken, serverconfig.ServerSection{AdminPasswordHash: "plaintext"})
+	if err == nil || !strings.Contains(err.Error(), "not a bcrypt hash") {
+		t.Errorf("err = %v, want a bcrypt error", err)
+	}
+}
+
+func TestAdminSessionsCheckPassword(t *testing.T) {
+	a := newPasswordSessions(t, "correct horse", 0)
+	if !a.passwordLogin() || a.ttl != defaultAdminSessionTTL {
+		t.Fatalf("passwordLogin = %v, ttl = %s", a.passwordLogin(), a.ttl)
+	}
+	if !a.checkPassword("correct horse") {
+		t.Error("the right password was rejected")
+	}
+	if a.checkPassword("battery staple") {
+		t.Error("a wrong password was accepted")
+	}
+
+	none := newTestServer(t, nil).adminSessions
+	if none.passwordLogin() || none.checkPassword("") {
+		t.Error("password login is on without a configured hash")
+	}
+}
+
+func TestAdminSessionsIssueAndRevoke(t *testing.T) {
+	a := newPasswordSessions(t, "pw", time.Hour)
+	value, expires := a.issue()
+	if d := time.Until(expires); d <= 59*time.Minute || d > time.Hour {
+		t.Errorf("session expires in %s, want an hour", d)
+	}
+	if !a.valid(value) {
+		t.Fatal("a fresh session is not valid")
+	}
+	other, _ := a.issue()
+	if other == value {
+		t.Fatal("two sessions share a cookie value")
+	}
+
+	a.revoke(value)
+	if a.valid(value) {
+		t.Error("a revoked session is still valid")
+	}
+	if !a.valid(other) {
+		t.Error("revoking one session ended another")
+	}
+
+	var nilSessions *adminSessions
+	if nilSessions.valid(other) {
+		t.Error("a nil adminSessions accepted a session")
+	}
+}
+
+func TestAdminSessionsRejectForgedCookies(t *testing.T) {
+	a := newPasswordSessions(t, "pw", time.Hour)
+	value, _ := a.issue()
+	payload, sig, _ := cutLast(value, ".")
+	exp, id, _ := strings.Cut(payload, ".")
+
+	// Pushing the expiry out breaks the signature.
+	later, _ := strconv.ParseInt(exp, 10, 64)
+	extended := strconv.FormatInt(later+3600, 10) + "." + id + "." + sig
+
+	// A session signed under another admin token.
+	rotated, err := newAdminSessions(testStorageKey(), "another-token", serverconfig.ServerSection{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	foreign, _ := rotated.issue()
+
+	for name, v := range map[string]string{
+		"extended":     extended,
+		"unsigned":     payload,
+		"empty":        "",
+		"other secret": foreign,
+	} {
+		if a.valid(v) {
+			t.Errorf("%s cookie was accepted", name)
+		}
+	}
+}
+
+func TestAdminSessionsExpire(t *testing.T) {
+	a := newPasswordSessions(t, "pw", time.Hour)
+	past := strconv.FormatInt(time.Now().Add(-time.Second).Unix(), 10) + ".00112233445566778899aabbccddeeff"
+	if a.valid(past + "." + a.sign(past)) {
+		t.Error("an expired session was accepted")
+	}
+}
+
+func TestAdminSignInRateLimitedPerClient(t *testing.T) {
+	s := newTestServer(t, nil)
+	from := func(addr string) requestOption {
+		return func(r *http.Request) { r.RemoteAddr = addr }
+	}
+	wrong := map[string]string{"token": "wrong"}
+	for i := 0; i < adminLoginsPerMinute; i++ {
+		if w := call(t, s.handleAdminAuth, http.MethodPost, "/admin/auth", wrong, from("192.0.2.1:1000")); w.Code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: status %d, want 401", i+1, w.Code)
+		}
+	}
+	w := call(t, s.handleAdminAuth, http.MethodPost, "/admin/auth", wrong, from("192.0.2.1:2000"))
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("attempt past the limit: status %d, Retry-After %q", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	// Another client still signs in.
+	w = call(t, s.handleAdminAuth, http.MethodPost, "/admin/auth", map[string]string{"token": testAdminToken}, from("198.51.100.7:1000"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("other client: status %d: %s", w.Code, w.Body.String())
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || !s.adminSessions.valid(cookies[0].Value) {
+		t.Errorf("sign-in set cookies %v, want one valid session", cookies)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// lockedOutError is returned by authorizeProfile for clients locked out
+// after too many invalid tokens.
+type lockedOutError struct {
+	retryAfter time.Duration
+}
+
+func (e *lockedOutError) Error() string {
+	return "too many failed attempts; try again later"
+}
+
+// writeAuthError answers a request authorizeProfile rejected: 429 with
+// Retry-After for locked-out clients, 401 otherwise.
+func writeAuthError(w http.ResponseWriter, err error) {
+	var locked *lockedOutError
+	if errors.As(err, &locked) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(locked.retryAfter.Seconds()))))
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusUnauthorized)
+}
+
+// lockoutClient returns the address auth failures are counted against.
+// Unlike clientIP it believes X-Forwarded-For only from trusted proxies, so
+// rotating the header does not dodge a lockout.
+func (s *server) lockoutClient(r *http.Request) string {
+	if addr, ok := s.access.ClientAddr(r); ok {
+		return addr.String()
+	}
+	return r.RemoteAddr
+}
+
+// checkLockout returns a lockedOutError when the client is locked out of
+// the profile.
+func (s *server) checkLockout(r *http.Request, profileName string) error {
+	left, locked := s.lockout.Locked(r.Context(), profileName, s.lockoutClient(r))
+	if !locked {
+		return nil
+	}
+	s.metrics.RecordAuthBlocked()
+	return &lockedOutError{retryAfter: left}
+}
+
+// countAuthFailure counts an invalid token towards a lockout and audits
+// the lockout when one starts.
+func (s *server) countAuthFailure(r *http.Request, profileName string) {
+	s.metrics.RecordAuthFailure()
+	client := s.lockoutClient(r)
+	lockedFor, failures := s.lockout.Fail(r.Context(), profileName, client)
+	if lockedFor == 0 {
+		return
+	}
+	s.metrics.RecordAuthLockout()
+	s.logger.Warn("client locked out of profile", "profile", profileName, "client", client, "failures", failures, "duration", lockedFor)
+	if s.auditLogger != nil {
+		s.auditLogger.LogError(profileName, "auth_lockout", fmt.Sprintf("locked out for %s after %d failed attempts", lockedFor, failures), client)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"slices"
+
+	"skyline-mcp/internal/mcp"
+	"skyline-mcp/internal/oidc"
+)
+
+// oidcClaims verifies a JWT bearer token with the configured OIDC provider
+// and returns its claims, or nil when OIDC is off or the token is not a
+// valid one.
+func (s *server) oidcClaims(ctx context.Context, token string) *oidc.Claims {
+	if s.oidc == nil || !oidc.LooksLikeJWT(token) {
+		return nil
+	}
+	claims, err := s.oidc.Verify(ctx, token)
+	if err != nil {
+		s.logger.Debug("rejected OIDC token", "error", err)
+		return nil
+	}
+	return claims
+}
+
+// isOIDCAdmin reports whether the request carries an IdP token granted the
+// admin scope.
+func (s *server) isOIDCAdmin(r *http.Request) bool {
+	claims := s.oidcClaims(r.Context(), bearerToken(r.Header.Get("Authorization")))
+	return claims != nil && claims.HasScope(s.oidc.Config().AdminScope)
+}
+
+// oidcMCPAllowed reports whether an IdP token may use a profile's MCP
+// endpoint: it needs the MCP scope and, when a profiles claim is set, the
+// profile listed in it.
+func (s *server) oidcMCPAllowed(token, profileName string) bool {
+	claims := s.oidcClaims(context.Background(), token)
+	if claims == nil {
+		return false
+	}
+	cfg := s.oidc.Config()
+	if !claims.HasScope(cfg.MCPScope) {
+		return false
+	}
+	return cfg.ProfilesClaim == "" || slices.Contains(claims.Strings(cfg.ProfilesClaim), profileName)
+}
+
+// configureMCPOIDC lets a profile's MCP endpoint accept IdP tokens and
+// points unauthorized clients at the provider, as the MCP authorization
+// spec describes. With requireForMCP, profile tokens and tokens from the
+// built-in authorization server stop working.
+func (s *server) configureMCPOIDC(streamable *mcp.StreamableHTTPServer, profileName string) {
+	if s.oidc == nil {
+		return
+	}
+	streamable.ResourceMetadata = func(r *http.Request) string {
+		return "https://" + r.Host + "/.well-known/oauth-protected-resource"
+	}
+	fallback := streamable.OAuthValidator
+	if s.oidc.Config().RequireForMCP {
+		streamable.VerifyToken = func(string) bool { return false }
+		fallback = nil
+	}
+	streamable.OAuthValidator = func(token string) (string, bool) {
+		if s.oidcMCPAllowed(token, profileName) {
+			return "", true
+		}
+		if fallback != nil {
+			return fallback(token)
+		}
+		return "", false
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/mcp"
+	"skyline-mcp/internal/runtime"
+)
+
+// authField is a credential a user can type in to replace one an upstream
+// rejected.
+type authField struct {
+	key, title string
+}
+
+// renewableFields returns the credentials of auth to ask for, or nil when
+// the user cannot renew them by hand.
+func renewableFields(auth *config.AuthConfig) []authField {
+	if auth == nil {
+		return nil
+	}
+	switch auth.Type {
+	case "bearer":
+		return []authField{{"token", "Bearer token"}}
+	case "api-key":
+		return []authField{{"value", "API key (" + auth.Header + ")"}}
+	case "basic", "wsse":
+		return []authField{{"username", "Username"}, {"password", "Password"}}
+	case "oauth2":
+		if auth.Flow == "device_code" {
+			return nil // the device flow asks the user itself
+		}
+		return []authField{{"refresh_token", "OAuth refresh token"}}
+	}
+	return nil
+}
+
+// renewAPIAuth asks the user of the MCP client for new credentials for an
+// API whose upstream rejected them, stores them in the profile and moves
+// the profile's sessions to a registry that uses them. Without an
+// elicitation-capable client it returns instructions for the agent.
+func (s *server) renewAPIAuth(ctx context.Context, profileName, apiName string, upErr *runtime.UpstreamError) (bool, string) {
+	before, fields, ok := s.apiAuth(profileName, apiName)
+	if !ok || len(fields) == 0 {
+		return false, ""
+	}
+	names := make([]string, len(fields))
+	for i, f := range fields {
+		names[i] = f.key
+	}
+	instructions := fmt.Sprintf("The upstream rejected the credentials of API %s with status %d. Ask the user for a new %s and have them update it in profile %s (Web UI, or PUT /profiles/%s), then retry.",
+		apiName, upErr.Status, strings.Join(names, " and "), profileName, profileName)
+
+	muVal, _ := s.authRenewals.LoadOrStore(profileName+"/"+apiName, &sync.Mutex{})
+	mu := muVal.(*sync.Mutex)
+	mu.Lock()
+	defer mu.Unlock()
+	// Another call may have renewed them while this one waited.
+	if current, _, ok := s.apiAuth(profileName, apiName); ok && !sameCredentials(before, current) {
+		return true, ""
+	}
+
+	properties := make(map[string]any, len(fields))
+	for _, f := range fields {
+		properties[f.key] = map[string]any{"type": "string", "title": f.title}
+	}
+	res, err := mcp.Elicit(ctx, fmt.Sprintf("API %s rejected its credentials (status %d). Enter new ones to store in profile %s and retry the call.",
+		apiName, upErr.Status, profileName), map[string]any{
+		"type":       "object",
+		"properties": properties,
+		"required":   names,
+	})
+	switch {
+	case errors.Is(err, mcp.ErrElicitationUnsupported):
+		return false, instructions
+	case err != nil:
+		s.logger.Warn("credential renewal failed", "profile", profileName, "api", apiName, "error", err)
+		return false, instructions
+	case res.Action != "accept":
+		return false, fmt.Sprintf("The user declined to enter new credentials for API %s. Do not retry the call.", apiName)
+	}
+
+	values := make(map[string]string, len(fields))
+	for _, f := range fields {
+		v, _ := res.Content[f.key].(string)
+		if strings.TrimSpace(v) == "" {
+			return false, instructions
+		}
+		values[f.key] = strings.TrimSpace(v)
+	}
+	updated, err := s.storeAPIAuth(profileName, apiName, values)
+	if err != nil {
+		s.logger.Warn("store renewed credentials failed", "profile", profileName, "api", apiName, "error", err)
+		return false, instructions
+	}
+	if _, err := s.getOrCreateStreamable(ctx, updated); err != nil {
+		s.logger.Warn("rebuild after credential renewal failed", "profile", profileName, "api", apiName, "error", err)
+		return false, instructions
+	}
+	s.logger.Info("credentials renewed through elicitation", "profile", profileName, "api", apiName)
+	return true, ""
+}
+
+// apiAuth returns the auth config of an API in a stored profile and the
+// fields a user can renew.
+func (s *server) apiAuth(profileName, apiName string) (*config.AuthConfig, []authField, bool) {
+	s.mu.RLock()
+	prof, ok := s.findProfile(profileName)
+	s.mu.RUnlock()
+	if !ok {
+		return nil, nil, false
+	}
+	for _, api := range prof.ToConfig().APIs {
+		if api.Name == apiName {
+			return api.Auth, renewableFields(api.Auth), true
+		}
+	}
+	return nil, nil, false
+}
+
+func sameCredentials(a, b *config.AuthConfig) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// storeAPIAuth writes new credentials for an API into a profile and
+// persists it.
+func (s *server) storeAPIAuth(profileName, apiName string, values map[string]string) (profile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prof, ok := s.findProfile(profileName)
+	if !ok {
+		return profile{}, fmt.Errorf("profile %s no longer exists", profileName)
+	}
+	var cfg config.Config
+	if err := yaml.Unmarshal([]byte(prof.ConfigYAML), &cfg); err != nil {
+		return profile{}, fmt.Errorf("invalid stored config: %w", err)
+	}
+	var auth *config.AuthConfig
+	for i := range cfg.APIs {
+		if cfg.APIs[i].Name == apiName {
+			auth = cfg.APIs[i].Auth
+		}
+	}
+	if auth == nil {
+		return profile{}, fmt.Errorf("API %s has no auth config", apiName)
+	}
+	for key, v := range values {
+		switch key {
+		case "token":
+			auth.Token = v
+		case "value":
+			auth.Value = v
+		case "username":
+			auth.Username = v
+		case "password":
+			auth.Password = v
+		case "refresh_token":
+			auth.RefreshToken = v
+		}
+	}
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return profile{}, err
+	}
+	if err := config.ValidateYAML(data); err != nil {
+		return profile{}, err
+	}
+	prof.ConfigYAML = strings.TrimSpace(string(data))
+	s.updateProfile(prof)
+	if err := s.save(); err != nil {
+		return profile{}, fmt.Errorf("persist profile: %w", err)
+	}
+	return prof, nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"time"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/runtime"
+)
+
+// runBench implements `skyline bench`: it calls one tool of a profile from
+// several goroutines for a while, through the same executor as the server,
+// and reports throughput, latency percentiles, how the calls ended and what
+// the API's rate limiter and circuit breaker did. Interrupting the run
+// reports the calls made so far.
+// Exit codes: 0 = the run completed, 2 = usage or load error.
+func runBench(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	source := addProfileSourceFlags(fs)
+	toolName := fs.String("tool", "", "Tool to call (required)")
+	argsJSON := fs.String("args", "", "Tool arguments as a JSON object (default: an example from the input schema)")
+	concurrency := fs.Int("concurrency", 1, "Calls kept in flight")
+	duration := fs.Duration("duration", 30*time.Second, "How long to start new calls for")
+	requests := fs.Int("requests", 0, "Stop after this many calls (0: run for --duration)")
+	allowWrites := fs.Bool("allow-writes", false, "Allow calling a tool that is not read-only")
+	format := fs.String("format", "text", "Output format: text, json")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: skyline bench --tool name [--args json] [--concurrency n] [--duration 60s] [--requests n] [--format text|json] <config-file>\n")
+		fmt.Fprintf(stderr, "       skyline bench --profile name --tool name [options]\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if !source.valid(fs) || *toolName == "" {
+		fs.Usage()
+		return 2
+	}
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(stderr, "unsupported format %q\n", *format)
+		return 2
+	}
+	if *concurrency < 1 || *duration <= 0 || *requests < 0 {
+		fmt.Fprintf(stderr, "--concurrency and --duration must be positive and --requests must not be negative\n")
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	tools, err := source.load(ctx, fs, stderr)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	tool := tools.registry.Tools[*toolName]
+	if tool == nil || tool.Operation == nil {
+		fmt.Fprintf(stderr, "tool %q not found\n", *toolName)
+		return 2
+	}
+	var callArgs map[string]any
+	if *argsJSON != "" {
+		if err := json.Unmarshal([]byte(*argsJSON), &callArgs); err != nil {
+			fmt.Fprintf(stderr, "--args: %v\n", err)
+			return 2
+		}
+	} else {
+		callArgs, _ = canonical.ExampleValue(tool.InputSchema, true).(map[string]any)
+	}
+	if err := tool.ValidateArguments(callArgs); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	if !*allowWrites && !tool.ReadOnlyCall(callArgs) {
+		fmt.Fprintf(stderr, "%s is not read-only; pass --allow-writes to call it anyway\n", *toolName)
+		return 2
+	}
+
+	report := tools.executor.Bench(ctx, tool.Operation, callArgs, runtime.BenchOptions{
+		Concurrency: *concurrency,
+		Duration:    *duration,
+		Requests:    *requests,
+	})
+	// The report names the tool as called, which may be an alias.
+	report.Tool = *toolName
+	if *format == "json" {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(report)
+	} else {
+		printBenchReport(stdout, report)
+	}
+	return 0
+}
+
+func printBenchReport(w io.Writer, r *runtime.BenchReport) {
+	fmt.Fprintf(w, "%s: %d calls in flight for %.1fs\n", r.Tool, r.Concurrency, r.Seconds)
+	fmt.Fprintf(w, "requests      %d (%.1f/s), %d errors\n", r.Requests, r.Throughput, r.Errors)
+	l := r.Latency
+	fmt.Fprintf(w, "latency ms    mean %.1f  p50 %.1f  p90 %.1f  p95 %.1f  p99 %.1f  max %.1f\n", l.Mean, l.P50, l.P90, l.P95, l.P99, l.Max)
+	outcomes := make([]string, 0, len(r.Outcomes))
+	for _, name := range slices.Sorted(maps.Keys(r.Outcomes)) {
+		outcomes = append(outcomes, fmt.Sprintf("%s: %d", name, r.Outcomes[name]))
+	}
+	fmt.Fprintf(w, "outcomes      %s\n", strings.Join(outcomes, "  "))
+	for _, name := range slices.Sorted(maps.Keys(r.ErrorSamples)) {
+		fmt.Fprintf(w, "  %s: %s\n", name, r.ErrorSamples[name])
+	}
+	fmt.Fprintf(w, "rate limiter  %d calls waited, %.1fs in total\n", r.Throttled, r.ThrottledSeconds)
+	if r.BreakerState != "" {
+		fmt.Fprintf(w, "breaker       opened %d times, now %s\n", r.BreakerTrips, r.BreakerState)
+	}
+}
@@ -4,17 +4,23 @@ import (
 	"context"
 	"crypto/sha256"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
 	"log/slog"
 
+	"skyline-mcp/internal/audit"
 	"skyline-mcp/internal/canonical"
 	"skyline-mcp/internal/config"
 	"skyline-mcp/internal/email"
+	codeexec "skyline-mcp/internal/executor"
+	"skyline-mcp/internal/jobs"
 	"skyline-mcp/internal/mcp"
+	"skyline-mcp/internal/metrics"
 	"skyline-mcp/internal/polling"
 	"skyline-mcp/internal/runtime"
+	"skyline-mcp/internal/serverconfig"
 	"skyline-mcp/internal/spec"
 )
 
@@ -23,8 +29,19 @@ type registryCache struct {
 	registry   *mcp.Registry
 	executor   *runtime.Executor
 	services   []*canonical.Service
+	loadErrors []canonical.LoadError // APIs of the profile that failed to load
+	parsed     *spec.ParsedSpecs     // lets the next rebuild reparse only changed APIs
 	configHash string
 	createdAt  time.Time
+
+	// Periodic API probes run only while the entry is cached; see
+	// profileCache.set.
+	health *config.HealthCheckConfig
+
+	// Code execution sandbox, set up on first use (see codeExecutorFor).
+	codeOnce sync.Once
+	codeExec *codeexec.Executor
+	codeErr  error
 }
 
 // profileCache manages per-profile caches of parsed specs, registries, and executors.
@@ -68,20 +85,86 @@ func (pc *profileCache) get(profileName, configHash string) (*registryCache, boo
 	return entry, true
 }
 
-// set stores a cache entry for the given profile.
+// set stores a cache entry for the given profile and starts its health
+// checks. A replaced entry's executor is closed after a grace period.
 func (pc *profileCache) set(profileName string, entry *registryCache) {
 	pc.mu.Lock()
 	defer pc.mu.Unlock()
+	if old, ok := pc.entries[profileName]; ok && old != entry {
+		retireExecutor(old.executor)
+	}
 	pc.entries[profileName] = entry
+	startHealthChecks(entry.executor, entry.health)
+}
+
+// peek returns the entry for a profile regardless of age or config hash.
+func (pc *profileCache) peek(profileName string) (*registryCache, bool) {
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+	entry, ok := pc.entries[profileName]
+	return entry, ok
+}
+
+// snapshot returns the current entries by profile name.
+func (pc *profileCache) snapshot() map[string]*registryCache {
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+	out := make(map[string]*registryCache, len(pc.entries))
+	for name, entry := range pc.entries {
+		out[name] = entry
+	}
+	return out
+}
+
+// touch restarts the TTL of a profile's entry, e.g. after a background
+// refresh confirmed its specs are still current.
+func (pc *profileCache) touch(profileName string) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if entry, ok := pc.entries[profileName]; ok {
+		entry.createdAt = time.Now()
+	}
 }
 
-// evict removes the cache entry for the given profile.
+// evict removes the cache entry for the given profile. Its executor is
+// closed after a grace period.
 func (pc *profileCache) evict(profileName string) {
 	pc.mu.Lock()
 	defer pc.mu.Unlock()
+	if old, ok := pc.entries[profileName]; ok {
+		retireExecutor(old.executor)
+	}
 	delete(pc.entries, profileName)
 }
 
+// retireExecutor closes a replaced executor once in-flight tool calls had
+// time to finish.
+func retireExecutor(executor *runtime.Executor) {
+	time.AfterFunc(retiredExecutorGrace, func() { _ = executor.Close() })
+}
+
+// upstreamConnStats reports the connection pools of every profile's
+// upstream APIs for the metrics collector.
+func (s *server) upstreamConnStats() []metrics.UpstreamConnStats {
+	pools := s.httpClients.Stats()
+	out := make([]metrics.UpstreamConnStats, len(pools))
+	for i, p := range pools {
+		out[i] = metrics.UpstreamConnStats{
+			Profile:    p.Profile,
+			API:        p.API,
+			Open:       p.Open,
+			Idle:       p.Idle,
+			Active:     p.Active,
+			Dials:      p.Dials,
+			DialErrors: p.DialErrors,
+			Reused:     p.Reused,
+			DNSLookups: p.DNSLookups,
+			DNSSeconds: p.DNSTime.Seconds(),
+		}
+	}
+	return out
+}
+
 // getOrBuild returns a cached registry/executor or builds a new one.
 // Returns (cache entry, hit, error).
 func (s *server) getOrBuildCache(ctx context.Context, prof profile) (*registryCache, bool, error) {
@@ -96,7 +179,10 @@ func (s *server) getOrBuildCache(ctx context.Context, prof profile) (*registryCa
 	}
 
 	s.metrics.RecordCacheMiss()
-	entry, _, err := s.buildRegistryCache(ctx, prof)
+	// After an edit only the APIs that changed are reparsed; an entry that
+	// merely expired has every spec fetched again.
+	prev, _ := s.cache.peek(prof.Name)
+	entry, _, err := s.buildRegistryCacheFrom(ctx, prof, prev, prev != nil && prev.configHash == hash)
 	if err != nil {
 		return nil, false, err
 	}
@@ -107,23 +193,36 @@ func (s *server) getOrBuildCache(ctx context.Context, prof profile) (*registryCa
 
 // buildRegistryCache builds a fresh registry cache entry for a profile.
 func (s *server) buildRegistryCache(ctx context.Context, prof profile) (*registryCache, bool, error) {
-	cfg := prof.ToConfig()
-	// Strip disabled APIs before building the registry/executor
-	active := cfg.APIs[:0]
-	for _, api := range cfg.APIs {
-		if !api.Disabled {
-			active = append(active, api)
-		}
-	}
-	cfg.APIs = active
-	s.redactor.AddSecrets(cfg.Secrets())
+	return s.buildRegistryCacheFrom(ctx, prof, nil, false)
+}
 
-	services, err := spec.LoadServices(ctx, cfg, s.logger, s.redactor)
+// buildRegistryCacheFrom builds a registry cache entry reusing the specs
+// and tools of prev (which may be nil) for APIs that did not change. With
+// refetch, specs are fetched again and only changed documents reparsed.
+func (s *server) buildRegistryCacheFrom(ctx context.Context, prof profile, prev *registryCache, refetch bool) (*registryCache, bool, error) {
+	if vars := prof.Variables(); len(vars) > 0 {
+		return nil, false, fmt.Errorf("profile %s is a template (variables %s); create profiles from it with POST /profiles/%s/clone",
+			prof.Name, strings.Join(vars, ", "), prof.Name)
+	}
+	cfg, err := s.activeConfig(prof)
+	if err != nil {
+		return nil, false, err
+	}
+	var prevParsed *spec.ParsedSpecs
+	var prevRegistry *mcp.Registry
+	if prev != nil {
+		prevParsed, prevRegistry = prev.parsed, prev.registry
+	}
+	loaded, err := spec.LoadServicesIncremental(ctx, cfg, prevParsed, refetch, s.logger, s.redactor)
 	if err != nil {
 		return nil, false, fmt.Errorf("load services: %w", err)
 	}
+	services, loadErrors := loaded.Services, loaded.LoadErrors
+	if prev != nil {
+		s.logger.Debug("rebuilt registry incrementally", "profile", prof.Name, "apis", len(cfg.APIs), "reused", len(loaded.Unchanged))
+	}
 
-	registry, err := mcp.NewRegistry(services)
+	registry, err := mcp.NewRegistryReusing(prevRegistry, services, loaded.Unchanged, mcp.RegistryOptions{Examples: cfg.ToolExamples, Aliases: cfg.ToolAliases, Overrides: cfg.ToolOverrides})
 	if err != nil {
 		return nil, false, fmt.Errorf("build registry: %w", err)
 	}
@@ -133,6 +232,66 @@ func (s *server) buildRegistryCache(ctx context.Context, prof profile) (*registr
 		return nil, false, fmt.Errorf("create executor: %w", err)
 	}
 
+	// Keep upstream connections alive across rebuilds of the profile.
+	executor.UseHTTPClients(s.httpClients, prof.Name)
+	// Keep OAuth2 tokens too, so a device flow is authorized only once.
+	executor.UseOAuth2Tokens(s.oauth2Tokens, prof.Name)
+	// Share rate limits and circuit breakers with the other replicas.
+	if s.cluster != nil {
+		executor.UseSharedState(s.cluster.store, prof.Name)
+	}
+	// Count budgets in the audit database so they survive restarts.
+	executor.UseQuotaStore(s.auditLogger, prof.Name)
+	// Likewise learned response schemas.
+	executor.UseSchemaStore(s.auditLogger, prof.Name)
+	// Async jobs outlive the registry they were started from.
+	executor.SetJobStore(s.jobStore(prof.Name))
+	executor.SetLoadErrors(loadErrors)
+	// Hooks get the profile's code execution memory limit.
+	if s.serverCfg != nil {
+		if limit, err := serverconfig.ParseByteSize(s.serverCfg.Runtime.CodeExecution.ForProfile(prof.Name).MemoryLimit); err == nil && limit > 0 {
+			executor.SetHookMemoryLimit(limit)
+		}
+	}
+	// Resolve gRPC service descriptors before the first tool call needs them.
+	go executor.WarmGRPCDescriptors(context.Background())
+	// Audit what data policies filter out of results.
+	executor.SetDataPolicyHook(func(ctx context.Context, op *canonical.Operation, filtered []runtime.FilteredField) {
+		s.auditLogger.LogDataPolicy(ctx, prof.Name, op.ServiceName, op.ToolName, filtered)
+	})
+	// Audit every remote command with the output it produced.
+	executor.SetSSHHook(func(ctx context.Context, op *canonical.Operation, run runtime.SSHRun) {
+		s.auditLogger.LogRemoteCommand(ctx, prof.Name, op.ServiceName, op.ToolName, audit.RemoteCommand{
+			Host: run.Host, User: run.User, Command: run.Command, ExitCode: run.ExitCode, Signal: run.Signal,
+			Stdout: run.Stdout, Stderr: run.Stderr, Truncated: run.Truncated,
+		}, run.Duration, run.Error)
+	})
+	// Audit circuit breaker trips so alerting can report them.
+	executor.SetBreakerHook(func(api string, open bool, lastErr error) {
+		msg := ""
+		if lastErr != nil {
+			msg = lastErr.Error()
+		}
+		s.auditLogger.LogCircuitBreaker(prof.Name, api, open, msg)
+	})
+	// Tell whoever watches the logs or the MCP clients how to authorize an
+	// API using the OAuth device flow.
+	executor.SetDeviceCodeHook(func(api string, code runtime.DeviceCode) {
+		s.logger.Warn("API needs authorization: open the verification URL and enter the code",
+			"profile", prof.Name, "api", api, "verification_uri", code.VerificationURI,
+			"user_code", code.UserCode, "expires_at", code.ExpiresAt)
+		if streamable, _, ok := s.profileStreamable(prof.Name); ok {
+			streamable.NotifyMessage("warning", "skyline", map[string]any{
+				"message":                   fmt.Sprintf("API %s needs authorization: open %s and enter code %s", api, code.VerificationURI, code.UserCode),
+				"api":                       api,
+				"user_code":                 code.UserCode,
+				"verification_uri":          code.VerificationURI,
+				"verification_uri_complete": code.VerificationURIComplete,
+				"expires_at":                code.ExpiresAt,
+			})
+		}
+	})
+
 	// Register email protocol handler if any email-type APIs exist.
 	registerEmailProtocol(executor, cfg, s.logger, s.emailPersistent)
 
@@ -145,13 +304,35 @@ func (s *server) buildRegistryCache(ctx context.Context, prof profile) (*registr
 	}
 
 	return &registryCache{
-		registry:  registry,
-		executor:  executor,
-		services:  services,
-		createdAt: time.Now(),
+		registry:   registry,
+		executor:   executor,
+		services:   services,
+		loadErrors: loadErrors,
+		parsed:     loaded.Parsed,
+		createdAt:  time.Now(),
+		health:     cfg.HealthCheck,
 	}, false, nil
 }
 
+// activeConfig returns the profile's config with disabled APIs stripped and
+// its secrets registered with the redactor. Profiles stored before
+// server.profileFilesDir was enforced are checked again here.
+func (s *server) activeConfig(prof profile) (*config.Config, error) {
+	cfg := prof.ToConfig()
+	if err := s.checkProfileFiles(prof.Name, cfg); err != nil {
+		return nil, fmt.Errorf("profile %s: %w", prof.Name, err)
+	}
+	active := cfg.APIs[:0]
+	for _, api := range cfg.APIs {
+		if !api.Disabled {
+			active = append(active, api)
+		}
+	}
+	cfg.APIs = active
+	s.redactor.AddSecrets(cfg.Secrets())
+	return cfg, nil
+}
+
 // registerEmailPolling sets up poll jobs for email APIs with polling enabled.
 func registerEmailPolling(engine *polling.Engine, cfg *config.Config, logger *slog.Logger) {
 	for _, api := range cfg.APIs {
@@ -203,6 +384,35 @@ func registerEmailResources(registry *mcp.Registry, cfg *config.Config) {
 	}
 }
 
+// startHealthChecks starts the executor's periodic API probes when the
+// config has a health_check section. Shared by cache and transport paths.
+func startHealthChecks(executor *runtime.Executor, hc *config.HealthCheckConfig) {
+	if hc != nil {
+		executor.StartHealthChecks(time.Duration(hc.IntervalSeconds)*time.Second, time.Duration(hc.TimeoutSeconds)*time.Second)
+	}
+}
+
+// logDataPolicy logs fields filtered by data policies in the modes that
+// run without an audit log.
+func logDataPolicy(executor *runtime.Executor, logger *slog.Logger) {
+	executor.SetDataPolicyHook(func(ctx context.Context, op *canonical.Operation, filtered []runtime.FilteredField) {
+		paths := make([]string, len(filtered))
+		for i, f := range filtered {
+			paths[i] = f.Path + " (" + f.Action + ")"
+		}
+		logger.Info("data policy filtered response fields", "api", op.ServiceName, "tool", op.ToolName, "fields", paths)
+	})
+}
+
+// logSSHCommands logs the commands ssh tools run in the modes that run
+// without an audit log.
+func logSSHCommands(executor *runtime.Executor, logger *slog.Logger) {
+	executor.SetSSHHook(func(ctx context.Context, op *canonical.Operation, run runtime.SSHRun) {
+		logger.Info("ran remote command", "api", op.ServiceName, "tool", op.ToolName, "host", run.Host, "user", run.User,
+			"command", run.Command, "exit_code", run.ExitCode, "duration", run.Duration, "error", run.Error)
+	})
+}
+
 // registerEmailProtocol registers the email protocol handler on an executor
 // for any email-type APIs in the config. Shared by cache and transport paths.
 func registerEmailProtocol(executor *runtime.Executor, cfg *config.Config, logger *slog.Logger, pm *email.PersistentManager) {
@@ -227,3 +437,10 @@ func registerEmailProtocol(executor *runtime.Executor, cfg *config.Config, logge
 		})
 	}
 }
+
+// jobStore returns the async job store of a profile, creating it on first
+// use.
+func (s *server) jobStore(profileName string) *jobs.Store {
+	store, _ := s.jobStores.LoadOrStore(profileName, jobs.NewStore(0, 0))
+	return store.(*jobs.Store)
+}
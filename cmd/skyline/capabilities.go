@@ -0,0 +1,93 @@
+package main
+
+import (
+	"net/http"
+
+	"skyline-mcp/internal/mcp"
+	"skyline-mcp/internal/spec"
+)
+
+// mcpTransports lists the MCP transports the binary serves: Streamable HTTP
+// in server mode and stdio with --transport stdio.
+var mcpTransports = []string{"streamable-http", "stdio"}
+
+// newCapabilities describes this build with the given optional features.
+func newCapabilities(features map[string]bool) *mcp.Capabilities {
+	return &mcp.Capabilities{
+		Version:     Version,
+		SpecFormats: spec.SpecFormats(),
+		Transports:  mcpTransports,
+		Features:    features,
+	}
+}
+
+// capabilities describes the profile server and the features its
+// server config enables.
+func (s *server) capabilities() *mcp.Capabilities {
+	features := map[string]bool{
+		"code_execution": false,
+		"cache":          s.cache != nil,
+		"spec_refresh":   false,
+		"subscriptions":  true,
+		"audit":          s.auditLogger != nil,
+		"oidc":           s.oidc != nil,
+		"cluster":        s.cluster != nil,
+		"plugins":        false,
+	}
+	if s.serverCfg != nil {
+		features["code_execution"] = s.serverCfg.Runtime.CodeExecution.Enabled
+		features["spec_refresh"] = s.cache != nil && s.serverCfg.Runtime.Cache.RefreshInterval > 0
+		features["plugins"] = len(s.serverCfg.Plugins) > 0
+	}
+	return newCapabilities(features)
+}
+
+// profileCapabilities is capabilities as seen by a session of prof, whose
+// config can turn code execution off.
+func (s *server) profileCapabilities(prof profile) *mcp.Capabilities {
+	caps := s.capabilities()
+	if !prof.ToConfig().CodeExecutionEnabled() {
+		caps.Features["code_execution"] = false
+	}
+	return caps
+}
+
+// handleCapabilities reports the server's version, supported spec formats
+// and transports, enabled features and the tool counts of the profiles the
+// caller may list, as GET /profiles would. Counts are given for profiles
+// whose specs are loaded; loading is not triggered.
+// GET /capabilities
+func (s *server) handleCapabilities(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	caps := s.capabilities()
+	scope, scoped := s.requestTenant(r)
+	admin := s.isAdminSession(r)
+	if !s.adminLoginRequired() || admin || scoped {
+		s.mu.RLock()
+		profiles := make([]profile, 0, len(s.store.Profiles))
+		for _, p := range s.store.Profiles {
+			if p.Name != "" && (admin || tenantOf(p.Name) == scope) {
+				profiles = append(profiles, p)
+			}
+		}
+		s.mu.RUnlock()
+		caps.Profiles = []mcp.ProfileCapabilities{}
+		for _, p := range profiles {
+			var registry *mcp.Registry
+			if s.cache != nil {
+				if entry, ok := s.cache.peek(p.Name); ok {
+					registry = entry.registry
+				}
+			}
+			if streamable, _, ok := s.profileStreamable(p.Name); ok && registry == nil {
+				registry = streamable.Server().Registry()
+			}
+			caps.Profiles = append(caps.Profiles, mcp.ProfileCapabilitiesOf(p.Name, registry))
+		}
+	}
+	writeJSON(w, http.StatusOK, caps)
+}
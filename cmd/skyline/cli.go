@@ -21,7 +21,9 @@ func init() {
 		fmt.Fprintf(os.Stderr, "  --transport <mode>          Transport mode: stdio, http (default: http)\n")
 		fmt.Fprintf(os.Stderr, "  --admin                     Enable Web UI and admin dashboard (default: true)\n")
 		fmt.Fprintf(os.Stderr, "  --bind <addr>               Network interface and port (default: localhost:8191)\n")
-		fmt.Fprintf(os.Stderr, "  --config <path>             Server config.yaml path (default: ~/.skyline/config.yaml)\n\n")
+		fmt.Fprintf(os.Stderr, "  --config <path>             Server config.yaml path (default: ~/.skyline/config.yaml)\n")
+		fmt.Fprintf(os.Stderr, "  --serve-profile <name>      Also serve this profile's MCP endpoint at /mcp on --mcp-listen\n")
+		fmt.Fprintf(os.Stderr, "  --mcp-listen <addr>         Listen address for --serve-profile (default: localhost:8192)\n\n")
 		fmt.Fprintf(os.Stderr, "Logging:\n")
 		fmt.Fprintf(os.Stderr, "  --log-format <format>       Log output format: text, json (default: text)\n")
 		fmt.Fprintf(os.Stderr, "  --log-level <level>         Log level: debug, info, warn, error (default: info)\n\n")
@@ -45,17 +47,29 @@ func init() {
 		fmt.Fprintf(os.Stderr, "  --env-file <path>           Optional env file to load before startup\n")
 		fmt.Fprintf(os.Stderr, "  --version, -v               Show version information\n")
 		fmt.Fprintf(os.Stderr, "  --help, -h                  Show this help message\n\n")
+		fmt.Fprintf(os.Stderr, "Environment:\n")
+		fmt.Fprintf(os.Stderr, "  SKYLINE_<FLAG>              Default for a flag above, e.g. SKYLINE_BIND, SKYLINE_LOG_LEVEL\n")
+		fmt.Fprintf(os.Stderr, "  SKYLINE_<SECTION>_<KEY>     Override a config.yaml setting, e.g. SKYLINE_SERVER_LISTEN,\n")
+		fmt.Fprintf(os.Stderr, "                              SKYLINE_AUDIT_DATABASE (see README)\n\n")
 		fmt.Fprintf(os.Stderr, "Commands:\n")
 		fmt.Fprintf(os.Stderr, "  skyline gateway start       Start server in the background\n")
 		fmt.Fprintf(os.Stderr, "  skyline gateway stop        Stop the background server\n")
 		fmt.Fprintf(os.Stderr, "  skyline gateway restart     Restart the background server\n")
 		fmt.Fprintf(os.Stderr, "  skyline gateway status      Show whether the server is running\n")
-		fmt.Fprintf(os.Stderr, "  skyline update              Update Skyline to the latest version\n\n")
+		fmt.Fprintf(os.Stderr, "  skyline validate <file>...  Validate profile config files (line/column diagnostics)\n")
+		fmt.Fprintf(os.Stderr, "  skyline validate --schema   Print the profile config JSON Schema\n")
+		fmt.Fprintf(os.Stderr, "  skyline lint <file>...      Flag risky settings in profile config files\n")
+		fmt.Fprintf(os.Stderr, "  skyline spec-diff <file>    Compare a config's upstream specs with a saved snapshot\n")
+		fmt.Fprintf(os.Stderr, "  skyline test <file>         Check a config's read-only tools against their contracts\n")
+		fmt.Fprintf(os.Stderr, "  skyline bench --tool <t> <file>  Load test one tool through the executor\n")
+		fmt.Fprintf(os.Stderr, "  skyline update              Update Skyline to the latest version (--channel beta, --check-only)\n\n")
 		fmt.Fprintf(os.Stderr, "Examples:\n")
 		fmt.Fprintf(os.Stderr, "  # Start server in the background\n")
 		fmt.Fprintf(os.Stderr, "  skyline gateway start\n\n")
 		fmt.Fprintf(os.Stderr, "  # Start HTTP server in the foreground (default)\n")
 		fmt.Fprintf(os.Stderr, "  skyline\n\n")
+		fmt.Fprintf(os.Stderr, "  # Control plane plus one profile's MCP endpoint in one process\n")
+		fmt.Fprintf(os.Stderr, "  skyline --serve-profile github --mcp-listen 0.0.0.0:8192\n\n")
 		fmt.Fprintf(os.Stderr, "  # Start in STDIO mode (for Claude Desktop)\n")
 		fmt.Fprintf(os.Stderr, "  skyline --transport stdio --config config.yaml\n\n")
 		fmt.Fprintf(os.Stderr, "  # Validate encrypted profiles\n")
@@ -179,8 +193,9 @@ func runInitProfiles(storagePath, keyFlag, keyEnv string, logger *slog.Logger) i
 	}
 
 	// Create profiles store seeded with a default profile
+	def, token := newDefaultProfile()
 	store := profileStore{
-		Profiles: []profile{newDefaultProfile()},
+		Profiles: []profile{def},
 	}
 
 	// Marshal to YAML
@@ -221,6 +236,7 @@ func runInitProfiles(storagePath, keyFlag, keyEnv string, logger *slog.Logger) i
 		"path", profilesPath,
 		"key_env", keyEnv,
 		"profiles", 0,
+		"default_profile_token", token,
 	)
 	return 0
 }
@@ -254,6 +270,31 @@ func loadEnvFile(path string) error {
 	return nil
 }
 
+// envFlags are the flags that fall back to a SKYLINE_<NAME> environment
+// variable (--log-level → SKYLINE_LOG_LEVEL) when not given on the command
+// line, so containers can set them without overriding the entrypoint.
+var envFlags = []string{"transport", "admin", "bind", "storage", "config", "auth-mode", "key-env", "env-file", "log-format", "log-level", "serve-profile", "mcp-listen"}
+
+// applyFlagEnv sets unset flags in fs from their environment variables.
+func applyFlagEnv(fs *flag.FlagSet, lookup func(string) (string, bool)) error {
+	explicit := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	for _, name := range envFlags {
+		if explicit[name] {
+			continue
+		}
+		envName := "SKYLINE_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+		value, ok := lookup(envName)
+		if !ok || strings.TrimSpace(value) == "" {
+			continue
+		}
+		if err := fs.Set(name, strings.TrimSpace(value)); err != nil {
+			return fmt.Errorf("%s: %w", envName, err)
+		}
+	}
+	return nil
+}
+
 func fileExists(path string) bool {
 	_, err := os.Stat(path)
 	return err == nil
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"time"
+
+	"skyline-mcp/internal/cluster"
+	"skyline-mcp/internal/serverconfig"
+)
+
+const (
+	// sessionOwnerTTL bounds how long a session → node mapping survives if the
+	// owning node dies without sending a disconnect.
+	sessionOwnerTTL = 24 * time.Hour
+	// forwardedHeader marks node-to-node hops so a request is proxied at most once.
+	forwardedHeader = "X-Skyline-Forwarded"
+)
+
+// clusterNode is this replica's handle on distributed mode.
+type clusterNode struct {
+	store   *cluster.Store
+	nodeURL string
+	proxy   http.RoundTripper
+	logger  *slog.Logger
+}
+
+func newClusterNode(cfg serverconfig.ClusterSection, logger *slog.Logger) (*clusterNode, error) {
+	store, err := cluster.Open(cfg.Redis, cfg.KeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := store.Ping(ctx); err != nil {
+		store.Close()
+		return nil, fmt.Errorf("connect to redis: %w", err)
+	}
+
+	node := &clusterNode{
+		store:   store,
+		nodeURL: strings.TrimRight(cfg.NodeURL, "/"),
+		logger:  logger,
+	}
+	if node.nodeURL != "" {
+		if _, err := url.Parse(node.nodeURL); err != nil {
+			store.Close()
+			return nil, fmt.Errorf("invalid cluster.nodeURL: %w", err)
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		if cfg.InsecureSkipVerify {
+			//nolint:gosec // Opt-in: replicas commonly use the auto-generated self-signed certificates
+			transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+		}
+		node.proxy = transport
+	} else {
+		logger.Warn("cluster.nodeURL not set — MCP sessions will not be routed between replicas; use sticky sessions at the load balancer")
+	}
+	return node, nil
+}
+
+func sessionOwnerKey(sessionID string) string { return "session:" + sessionID }
+
+// claimSession records that sessionID lives on this node.
+func (c *clusterNode) claimSession(sessionID string) {
+	if c.nodeURL == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := c.store.Set(ctx, sessionOwnerKey(sessionID), []byte(c.nodeURL), sessionOwnerTTL); err != nil {
+		c.logger.Warn("cluster: could not record session owner", "session_id", sessionID, "error", err)
+	}
+}
+
+// releaseSession forgets the owner of a closed session.
+func (c *clusterNode) releaseSession(sessionID string) {
+	if c.nodeURL == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_ = c.store.Del(ctx, sessionOwnerKey(sessionID))
+}
+
+// routeSession proxies r to the replica that owns sessionID. It returns false
+// when the session is unknown or owned by this node, leaving the request to
+// the local handler (which answers 404 for unknown sessions as usual).
+func (c *clusterNode) routeSession(w http.ResponseWriter, r *http.Request, sessionID string) bool {
+	if c.proxy == nil || r.Header.Get(forwardedHeader) != "" {
+		return false
+	}
+	raw, ok, err := c.store.Get(r.Context(), sessionOwnerKey(sessionID))
+	if err != nil {
+		c.logger.Warn("cluster: session owner lookup failed", "session_id", sessionID, "error", err)
+		return false
+	}
+	owner := string(raw)
+	if !ok || owner == c.nodeURL {
+		return false
+	}
+	target, err := url.Parse(owner)
+	if err != nil {
+		return false
+	}
+
+	c.logger.Debug("cluster: routing session to owner", "session_id", sessionID, "owner", owner)
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(target)
+			pr.Out.Host = pr.In.Host // keeps the owner's Origin check against the public host
+			pr.SetXForwarded()
+			pr.Out.Header.Set(forwardedHeader, c.nodeURL)
+		},
+		Transport:     c.proxy,
+		FlushInterval: -1, // stream SSE responses as they arrive
+		ErrorHandler: func(w http.ResponseWriter, _ *http.Request, err error) {
+			// The owner is gone and so is the session; a 404 makes the client
+			// re-initialize, which lands it on a live replica.
+			c.logger.Warn("cluster: session owner unreachable", "session_id", sessionID, "owner", owner, "error", err)
+			c.releaseSession(sessionID)
+			http.Error(w, "session not found - initialize first", http.StatusNotFound)
+		},
+	}
+	proxy.ServeHTTP(w, r)
+	return true
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"skyline-mcp/internal/mcp"
+)
+
+// runContractTests implements `skyline test`: it calls the read-only tools of
+// a profile, either the stored profile named by --profile or a config file,
+// and checks their status codes and output schemas. The checks come from the
+// config's contract_tests; without any, every read-only tool is called with
+// example arguments.
+// Exit codes: 0 = all checks passed, 1 = a check failed, 2 = usage, load or
+// I/O error.
+func runContractTests(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	source := addProfileSourceFlags(fs)
+	format := fs.String("format", "text", "Output format: text, json, junit")
+	output := fs.String("output", "", "Write the report to this file instead of stdout")
+	timeout := fs.Int("timeout", 30, "Seconds each call may take")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: skyline test [--format text|json|junit] [--output file] <config-file>\n")
+		fmt.Fprintf(stderr, "       skyline test --profile name [--storage file] [--key key] [--format text|json|junit] [--output file]\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if !source.valid(fs) {
+		fs.Usage()
+		return 2
+	}
+	if *format != "text" && *format != "json" && *format != "junit" {
+		fmt.Fprintf(stderr, "unsupported format %q\n", *format)
+		return 2
+	}
+	if *timeout <= 0 {
+		fmt.Fprintf(stderr, "--timeout must be positive\n")
+		return 2
+	}
+
+	ctx := context.Background()
+	tools, err := source.load(ctx, fs, stderr)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	report := mcp.RunContractTests(ctx, tools.registry, tools.executor, tools.cfg.ContractTests, time.Duration(*timeout)*time.Second)
+	// An API whose spec did not load fails the run like a failed check.
+	for _, le := range tools.loadErrors {
+		report.Results = append(report.Results, mcp.ContractResult{Name: "load " + le.Service, Service: le.Service, Failures: []string{le.Error}})
+		report.Failed++
+	}
+
+	w := stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(stderr, "create report: %v\n", err)
+			return 2
+		}
+		defer f.Close()
+		w = f
+	}
+	switch *format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(report)
+	case "junit":
+		err = report.WriteJUnit(w, tools.name)
+	default:
+		printContractReport(w, report)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "write report: %v\n", err)
+		return 2
+	}
+	if report.Failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// printContractReport renders one line per check and a summary.
+func printContractReport(w io.Writer, report *mcp.ContractReport) {
+	for _, res := range report.Results {
+		if res.Passed {
+			fmt.Fprintf(w, "PASS  %s  (status %d, %.2fs)\n", res.Name, res.Status, res.Seconds)
+			continue
+		}
+		fmt.Fprintf(w, "FAIL  %s\n", res.Name)
+		for _, failure := range res.Failures {
+			fmt.Fprintf(w, "    %s\n", failure)
+		}
+	}
+	fmt.Fprintf(w, "%d passed, %d failed\n", report.Passed, report.Failed)
+}
@@ -1,61 +1,111 @@
 package main
 
 import (
+	"context"
 	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"os"
 	"path/filepath"
+	goruntime "runtime"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
 
 	"skyline-mcp/internal/audit"
+	"skyline-mcp/internal/mcp"
+	"skyline-mcp/internal/quota"
 )
 
-// isAdminSession returns true if the request carries a valid admin session cookie.
+// isAdminSession returns true if the request carries a valid admin session
+// cookie. Without an admin password, the admin token itself is accepted as
+// the cookie, for scripts. With OIDC on, an IdP bearer token granted the
+// admin scope counts as a session too.
 func (s *server) isAdminSession(r *http.Request) bool {
-	cookie, err := r.Cookie("skyline_admin")
+	if s.isOIDCAdmin(r) {
+		return true
+	}
+	cookie, err := r.Cookie(adminCookieName)
 	if err != nil {
 		return false
 	}
-	return subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(s.adminToken)) == 1
+	if s.adminSessions.valid(cookie.Value) {
+		return true
+	}
+	return !s.adminLoginRequired() && subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(s.adminToken)) == 1
 }
 
-// handleAdminAuth handles GET (check) and POST (login) for admin authentication.
+// handleAdminAuth checks the session (GET), signs in with the admin
+// password or token (POST) and signs out (DELETE). Sign-in sets a session
+// cookie that expires after server.adminSessionTTL.
 func (s *server) handleAdminAuth(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
 		if !s.isAdminSession(r) {
-			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			// Tells the login page whether to ask for a password or the token.
+			writeJSON(w, http.StatusUnauthorized, map[string]any{
+				"error":          "unauthorized",
+				"password_login": s.adminLoginRequired(),
+			})
 			return
 		}
 		writeJSON(w, http.StatusOK, map[string]any{"status": "ok"})
 	case http.MethodPost:
 		limitBody(w, r)
 		var req struct {
-			Token string `json:"token"`
+			Password string `json:"password"`
+			Token    string `json:"token"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			http.Error(w, "invalid request body", http.StatusBadRequest)
 			return
 		}
-		if subtle.ConstantTimeCompare([]byte(req.Token), []byte(s.adminToken)) != 1 {
-			http.Error(w, "invalid token", http.StatusUnauthorized)
+		if wait := s.loginLimits.allow(s.lockoutClient(r)); wait > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, "rate limited — try again shortly", http.StatusTooManyRequests)
 			return
 		}
+		ok := req.Password != "" && s.adminSessions.checkPassword(req.Password)
+		if !ok && req.Token != "" {
+			ok = subtle.ConstantTimeCompare([]byte(req.Token), []byte(s.adminToken)) == 1
+		}
+		if !ok || s.adminSessions == nil {
+			s.logger.Warn("admin sign-in failed", "client", clientIP(r))
+			http.Error(w, "invalid credentials", http.StatusUnauthorized)
+			return
+		}
+		value, expires := s.adminSessions.issue()
 		http.SetCookie(w, &http.Cookie{
-			Name:     "skyline_admin",
-			Value:    s.adminToken,
+			Name:     adminCookieName,
+			Value:    value,
 			Path:     "/",
+			Expires:  expires,
 			Secure:   true,
 			HttpOnly: true,
 			SameSite: http.SameSiteStrictMode,
-			MaxAge:   86400 * 7, // 7 days
 		})
-		writeJSON(w, http.StatusOK, map[string]any{"status": "ok"})
+		s.logger.Info("admin signed in", "client", clientIP(r))
+		writeJSON(w, http.StatusOK, map[string]any{"status": "ok", "expires_at": expires.UTC()})
+	case http.MethodDelete:
+		if cookie, err := r.Cookie(adminCookieName); err == nil && s.adminSessions != nil {
+			s.adminSessions.revoke(cookie.Value)
+		}
+		http.SetCookie(w, &http.Cookie{
+			Name:     adminCookieName,
+			Value:    "",
+			Path:     "/",
+			MaxAge:   -1,
+			Secure:   true,
+			HttpOnly: true,
+			SameSite: http.SameSiteStrictMode,
+		})
+		w.WriteHeader(http.StatusNoContent)
 	default:
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 	}
@@ -119,6 +169,28 @@ func (s *server) handleAudit(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handleAuditVerify checks the audit log hash chain and its signed
+// checkpoints. It answers 200 either way; "valid" carries the outcome.
+func (s *server) handleAuditVerify(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.isAdminSession(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	result, err := s.auditLogger.Verify()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("verify audit log: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !result.Valid {
+		s.logger.Warn("audit log verification failed", "broken_at", result.BrokenAt, "problem", result.Problem)
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
 // handleStats returns aggregated statistics
 func (s *server) handleStats(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -152,9 +224,17 @@ func (s *server) handleStats(w http.ResponseWriter, r *http.Request) {
 	// Get metrics snapshot
 	metricsSnapshot := s.metrics.Snapshot()
 
+	quotas, err := s.quotaUsage(r.Context(), profileName)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("get quota usage: %v", err), http.StatusInternalServerError)
+		return
+	}
+
 	writeJSON(w, http.StatusOK, map[string]any{
 		"audit_stats":      auditStats,
 		"metrics_snapshot": metricsSnapshot,
+		"quota":            quotas,
+		"memory":           s.memoryStats(profileName),
 		"version":          Version,
 		"period": map[string]any{
 			"since": since,
@@ -163,6 +243,142 @@ func (s *server) handleStats(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handleAnalytics serves per-tool call counts, error rates and latency
+// percentiles over a window, with a series per hour or day, from the audit
+// database's rollups.
+func (s *server) handleAnalytics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.isAdminSession(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if s.auditLogger == nil {
+		http.Error(w, "audit logging is disabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	query := r.URL.Query()
+	opts := audit.AnalyticsOptions{
+		Profile: query.Get("profile"),
+		Tool:    query.Get("tool"),
+		Bucket:  query.Get("bucket"),
+	}
+	for name, dst := range map[string]*time.Time{"since": &opts.Since, "until": &opts.Until} {
+		if v := query.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				http.Error(w, name+" must be an RFC 3339 time", http.StatusBadRequest)
+				return
+			}
+			*dst = t
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		opts.Limit = n
+	}
+
+	analytics, err := s.auditLogger.Analytics(opts)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("analytics: %v", err), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, analytics)
+}
+
+// memoryStats reports the process heap and the schema statistics of every
+// cached registry, or of the named profile's.
+func (s *server) memoryStats(profileName string) map[string]any {
+	var ms goruntime.MemStats
+	goruntime.ReadMemStats(&ms)
+	registries := map[string]mcp.RegistryStats{}
+	if s.cache != nil {
+		for name, entry := range s.cache.snapshot() {
+			if profileName == "" || name == profileName {
+				registries[name] = entry.registry.Stats()
+			}
+		}
+	}
+	return map[string]any{
+		"heap_alloc_bytes": ms.HeapAlloc,
+		"heap_inuse_bytes": ms.HeapInuse,
+		"sys_bytes":        ms.Sys,
+		"num_gc":           ms.NumGC,
+		"registries":       registries,
+	}
+}
+
+// quotaUsage reports the call budgets of every profile that has any, or
+// of the named one. Usage comes from the audit database, so profiles whose
+// registry is not cached are included.
+func (s *server) quotaUsage(ctx context.Context, profileName string) (map[string][]quota.Usage, error) {
+	s.mu.RLock()
+	profiles := append([]profile(nil), s.store.Profiles...)
+	s.mu.RUnlock()
+
+	out := map[string][]quota.Usage{}
+	for _, prof := range profiles {
+		if profileName != "" && prof.Name != profileName {
+			continue
+		}
+		tracker := quota.New(prof.ToConfig(), s.logger)
+		if tracker == nil {
+			continue
+		}
+		tracker.UseStore(s.auditLogger, prof.Name)
+		usage, err := tracker.Usage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		out[prof.Name] = usage
+	}
+	return out, nil
+}
+
+// handleAdminHealth returns the API health of every profile whose registry
+// is cached. ?profile= narrows to one profile; ?refresh=true probes now
+// instead of returning the last periodic result.
+func (s *server) handleAdminHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.isAdminSession(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if s.cache == nil {
+		writeJSON(w, http.StatusOK, map[string]any{"profiles": map[string]any{}})
+		return
+	}
+
+	query := r.URL.Query()
+	profileName := query.Get("profile")
+	refresh := query.Get("refresh") == "true"
+	profiles := map[string]any{}
+	for name, entry := range s.cache.snapshot() {
+		if profileName != "" && name != profileName {
+			continue
+		}
+		if refresh {
+			entry.executor.CheckHealth(r.Context())
+		}
+		profiles[name] = map[string]any{
+			"apis":           entry.executor.Health(),
+			"health_checks":  entry.health != nil,
+			"registry_built": entry.createdAt,
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"profiles": profiles})
+}
+
 // handleSessions returns current active MCP sessions.
 func (s *server) handleSessions(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -174,9 +390,43 @@ func (s *server) handleSessions(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	sessions := s.sessionTracker.Snapshot()
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].ConnectedAt.Before(sessions[j].ConnectedAt) })
 	writeJSON(w, http.StatusOK, map[string]any{"sessions": sessions})
 }
 
+// handleSession serves /admin/sessions/{id}: GET returns one session and
+// DELETE force-disconnects it. Only sessions connected to this replica are
+// visible.
+func (s *server) handleSession(w http.ResponseWriter, r *http.Request) {
+	if !s.isAdminSession(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/admin/sessions/")
+	if id == "" || strings.Contains(id, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		sess := s.sessionTracker.Get(id)
+		if sess == nil {
+			http.Error(w, "session not found", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, sess)
+	case http.MethodDelete:
+		if !s.sessionTracker.Disconnect(id) {
+			http.Error(w, "session not found", http.StatusNotFound)
+			return
+		}
+		s.logger.Info("session disconnected by admin", "session_id", id, "client", clientIP(r))
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
 // handleEventStream serves a Server-Sent Events stream of live audit + agent events.
 func (s *server) handleEventStream(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
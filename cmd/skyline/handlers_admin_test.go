@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"skyline-mcp/internal/audit"
+)
+
+func TestAnalyticsHandler(t *testing.T) {
+	s := newTestServer(t, nil)
+	get := func(target string, opts ...requestOption) (int, map[string]any) {
+		w := call(t, s.handleAnalytics, http.MethodGet, target, nil, opts...)
+		if w.Code != http.StatusOK {
+			return w.Code, nil
+		}
+		return w.Code, decodeBody(t, w)
+	}
+	if code, _ := get("/admin/analytics", asAdmin); code != http.StatusServiceUnavailable {
+		t.Errorf("without an audit database: %d", code)
+	}
+
+	logger, err := audit.NewLogger(filepath.Join(t.TempDir(), "audit.db"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logger.Close()
+	s.auditLogger = logger
+	logger.LogExecute(context.Background(), "ops", "api", "api__get", nil, 30*time.Millisecond, 200, true, "", "", 0, 0)
+	logger.LogExecute(context.Background(), "ops", "api", "api__get", nil, 70*time.Millisecond, 500, false, "boom", "", 0, 0)
+
+	if code, _ := get("/admin/analytics"); code != http.StatusUnauthorized {
+		t.Errorf("without a session: %d", code)
+	}
+	if code := call(t, s.handleAnalytics, http.MethodPost, "/admin/analytics", nil, asAdmin).Code; code != http.StatusMethodNotAllowed {
+		t.Errorf("POST: %d", code)
+	}
+	code, body := get("/admin/analytics?profile=ops&limit=5", asAdmin)
+	if code != http.StatusOK {
+		t.Fatalf("analytics: %d", code)
+	}
+	totals := body["totals"].(map[string]any)
+	if totals["calls"] != float64(2) || totals["error_rate"] != float64(50) || len(body["series"].([]any)) < 24 {
+		t.Errorf("analytics = %v", body)
+	}
+
+	since := queryTime(time.Now().Add(-48 * time.Hour))
+	if _, body := get("/admin/analytics?bucket=day&since="+since, asAdmin); body == nil || body["bucket"] != "day" || len(body["series"].([]any)) < 2 {
+		t.Errorf("daily analytics = %v", body)
+	}
+	for _, query := range []string{"since=yesterday", "until=2026-13-01", "limit=0", "limit=x", "bucket=week", "since=" + queryTime(time.Now().Add(time.Hour))} {
+		if code, _ := get("/admin/analytics?"+query, asAdmin); code != http.StatusBadRequest {
+			t.Errorf("%s: %d", query, code)
+		}
+	}
+}
+
+// queryTime formats t for a query string.
+func queryTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05Z")
+}
@@ -0,0 +1,416 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"skyline-mcp/internal/config"
+)
+
+// profileBundle is the plaintext of an exported bundle. Token hashes and
+// configs are included as stored, so the bundle is encrypted like the
+// store and imported profiles keep their tokens.
+type profileBundle struct {
+	ExportedAt time.Time `yaml:"exported_at"`
+	Profiles   []profile `yaml:"profiles"`
+}
+
+// bundleKey returns the key a bundle is sealed with: the one given in the
+// request, or the server key so that the bundle imports into any server
+// sharing it.
+func (s *server) bundleKey(raw string) ([]byte, error) {
+	if strings.TrimSpace(raw) == "" {
+		return s.key, nil
+	}
+	key, err := decodeKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key: %w", err)
+	}
+	return key, nil
+}
+
+// decodeBulkRequest decodes a JSON body into v. An empty body leaves v
+// unchanged.
+func decodeBulkRequest(w http.ResponseWriter, r *http.Request, v any) bool {
+	limitBody(w, r)
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "invalid json body", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// handleProfilesExport returns every profile, or the ones named, as an
+// encrypted bundle for POST /admin/profiles/import.
+func (s *server) handleProfilesExport(w http.ResponseWriter, r *http.Request) {
+	if !s.isAdminSession(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Key      string   `json:"key"`
+		Profiles []string `json:"profiles"`
+	}
+	if !decodeBulkRequest(w, r, &req) {
+		return
+	}
+	key, err := s.bundleKey(req.Key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	bundle := profileBundle{ExportedAt: time.Now().UTC()}
+	s.mu.RLock()
+	for _, name := range req.Profiles {
+		if _, ok := s.findProfile(name); !ok {
+			s.mu.RUnlock()
+			http.Error(w, fmt.Sprintf("profile %q not found", name), http.StatusNotFound)
+			return
+		}
+	}
+	for _, p := range s.store.Profiles {
+		if len(req.Profiles) == 0 || slices.Contains(req.Profiles, p.Name) {
+			bundle.Profiles = append(bundle.Profiles, profile{Name: p.Name, TokenHash: p.TokenHash, ConfigYAML: p.ConfigYAML})
+		}
+	}
+	s.mu.RUnlock()
+
+	plain, err := yaml.Marshal(bundle)
+	if err != nil {
+		http.Error(w, "failed to encode bundle", http.StatusInternalServerError)
+		return
+	}
+	env, err := encrypt(plain, key)
+	if err != nil {
+		http.Error(w, "failed to encrypt bundle", http.StatusInternalServerError)
+		return
+	}
+	names := make([]string, len(bundle.Profiles))
+	for i, p := range bundle.Profiles {
+		names[i] = p.Name
+	}
+	s.logger.Info("profiles exported", "profiles", len(names), "client", clientIP(r))
+	writeJSON(w, http.StatusOK, map[string]any{
+		"bundle":      env,
+		"profiles":    names,
+		"exported_at": bundle.ExportedAt,
+	})
+}
+
+// handleProfilesImport creates or replaces many profiles at once, from an
+// exported bundle or a plain list. Every profile is checked before any is
+// saved, so an import applies completely or not at all.
+func (s *server) handleProfilesImport(w http.ResponseWriter, r *http.Request) {
+	if !s.isAdminSession(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Bundle    *envelope `json:"bundle"`
+		Key       string    `json:"key"`
+		Profiles  []profile `json:"profiles"`
+		Overwrite bool      `json:"overwrite"`
+	}
+	if !decodeBulkRequest(w, r, &req) {
+		return
+	}
+	incoming := req.Profiles
+	if req.Bundle != nil {
+		key, err := s.bundleKey(req.Key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		plain, err := decrypt(*req.Bundle, key)
+		if err != nil {
+			http.Error(w, "bundle does not decrypt (wrong key or corrupted data)", http.StatusBadRequest)
+			return
+		}
+		var bundle profileBundle
+		if err := yaml.Unmarshal(plain, &bundle); err != nil {
+			http.Error(w, fmt.Sprintf("invalid bundle: %v", err), http.StatusBadRequest)
+			return
+		}
+		incoming = append(incoming, bundle.Profiles...)
+	}
+	for i := range incoming {
+		incoming[i].Token = strings.TrimSpace(incoming[i].Token)
+	}
+	hashTokens(incoming)
+	if len(incoming) == 0 {
+		http.Error(w, "bundle or profiles is required", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var problems []string
+	seen := map[string]bool{}
+	for i := range incoming {
+		p := &incoming[i]
+		p.Name = strings.TrimSpace(p.Name)
+		p.ConfigYAML = strings.TrimSpace(p.ConfigYAML)
+		switch {
+		case p.Name == "":
+			problems = append(problems, fmt.Sprintf("profiles[%d]: name is required", i))
+			continue
+		case seen[p.Name]:
+			problems = append(problems, fmt.Sprintf("profile %q: listed more than once", p.Name))
+			continue
+		}
+		seen[p.Name] = true
+		if _, err := s.checkTenantProfileName(p.Name); err != nil {
+			problems = append(problems, fmt.Sprintf("profile %q: %v", p.Name, err))
+		}
+		if err := config.ValidateYAML([]byte(p.ConfigYAML)); err != nil {
+			problems = append(problems, fmt.Sprintf("profile %q: invalid config_yaml: %v", p.Name, err))
+		} else if err := s.checkProfileFiles(p.Name, p.ToConfig()); err != nil {
+			problems = append(problems, fmt.Sprintf("profile %q: invalid config_yaml: %v", p.Name, err))
+		}
+	}
+	if len(problems) > 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"errors": problems})
+		return
+	}
+
+	prev := slices.Clone(s.store.Profiles)
+	created, updated, skipped := []string{}, []string{}, []string{}
+	tokens := map[string]string{}
+	for _, p := range incoming {
+		existing, ok := s.findProfile(p.Name)
+		switch {
+		case ok && !req.Overwrite:
+			skipped = append(skipped, p.Name)
+		case ok:
+			existing.ConfigYAML = p.ConfigYAML
+			if p.TokenHash != "" {
+				existing.TokenHash = p.TokenHash
+			}
+			s.updateProfile(existing)
+			updated = append(updated, p.Name)
+		default:
+			if p.TokenHash == "" {
+				token := generateProfileToken()
+				tokens[p.Name] = token
+				p.TokenHash = hashProfileToken(token)
+			}
+			s.store.Profiles = append(s.store.Profiles, profile{Name: p.Name, TokenHash: p.TokenHash, ConfigYAML: p.ConfigYAML})
+			created = append(created, p.Name)
+		}
+	}
+	if err := s.save(); err != nil {
+		s.store.Profiles = prev
+		s.persistFailed(w, err)
+		return
+	}
+	s.logger.Info("profiles imported", "created", len(created), "updated", len(updated), "skipped", len(skipped), "client", clientIP(r))
+	writeJSON(w, http.StatusOK, map[string]any{
+		"created": created,
+		"updated": updated,
+		"skipped": skipped,
+		"tokens":  tokens,
+	})
+}
+
+// handleProfilesPatch sets fields of one API in every profile that defines
+// it, e.g. a new base_url_override after an upstream moves. A null value
+// removes the field. Like an import, it applies to all profiles or none.
+func (s *server) handleProfilesPatch(w http.ResponseWriter, r *http.Request) {
+	if !s.isAdminSession(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		API      string         `json:"api"`
+		Set      map[string]any `json:"set"`
+		Profiles []string       `json:"profiles"`
+		DryRun   bool           `json:"dry_run"`
+	}
+	if !decodeBulkRequest(w, r, &req) {
+		return
+	}
+	req.API = strings.TrimSpace(req.API)
+	if req.API == "" || len(req.Set) == 0 {
+		http.Error(w, "api and set are required", http.StatusBadRequest)
+		return
+	}
+	if _, ok := req.Set["name"]; ok {
+		http.Error(w, "set cannot change the API name", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, name := range req.Profiles {
+		if _, ok := s.findProfile(name); !ok {
+			http.Error(w, fmt.Sprintf("profile %q not found", name), http.StatusNotFound)
+			return
+		}
+	}
+	var problems []string
+	patched := map[string]string{}
+	for _, p := range s.store.Profiles {
+		if len(req.Profiles) > 0 && !slices.Contains(req.Profiles, p.Name) {
+			continue
+		}
+		out, ok, err := patchAPIConfig(p.ConfigYAML, req.API, req.Set)
+		if err == nil && ok {
+			err = s.checkProfileFiles(p.Name, profile{ConfigYAML: out}.ToConfig())
+		}
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("profile %q: %v", p.Name, err))
+			continue
+		}
+		if ok {
+			patched[p.Name] = out
+		}
+	}
+	if len(problems) > 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"errors": problems})
+		return
+	}
+	names := make([]string, 0, len(patched))
+	for name := range patched {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if req.DryRun || len(names) == 0 {
+		writeJSON(w, http.StatusOK, map[string]any{"updated": names, "dry_run": req.DryRun})
+		return
+	}
+
+	prev := slices.Clone(s.store.Profiles)
+	for _, name := range names {
+		prof, _ := s.findProfile(name)
+		prof.ConfigYAML = patched[name]
+		s.updateProfile(prof)
+	}
+	if err := s.save(); err != nil {
+		s.store.Profiles = prev
+		s.persistFailed(w, err)
+		return
+	}
+	// Cached registries no longer match the configs, so the next request
+	// to each profile rebuilds it.
+	s.logger.Info("profiles patched", "api", req.API, "profiles", len(names), "client", clientIP(r))
+	writeJSON(w, http.StatusOK, map[string]any{"updated": names, "dry_run": false})
+}
+
+// patchAPIConfig applies set to the API named api in configYAML. It edits
+// the parsed YAML so the rest of the config, comments included, is kept.
+// It reports false when the config has no such API.
+func patchAPIConfig(configYAML, api string, set map[string]any) (string, bool, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(configYAML), &doc); err != nil {
+		return "", false, fmt.Errorf("invalid stored config: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return "", false, nil
+	}
+	apis := mappingValue(doc.Content[0], "apis")
+	if apis == nil || apis.Kind != yaml.SequenceNode {
+		return "", false, nil
+	}
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	index := -1
+	for i, item := range apis.Content {
+		if name := mappingValue(item, "name"); name == nil || name.Value != api {
+			continue
+		}
+		index = i
+		for _, k := range keys {
+			if err := setMappingValue(item, k, set[k]); err != nil {
+				return "", false, fmt.Errorf("%s: %w", k, err)
+			}
+		}
+	}
+	if index < 0 {
+		return "", false, nil
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return "", false, err
+	}
+	data := buf.Bytes()
+	// Unknown keys would be dropped silently when the config is decoded.
+	for _, d := range config.Diagnose(data) {
+		for _, k := range keys {
+			path := fmt.Sprintf("apis[%d].%s", index, k)
+			if d.Severity == config.SeverityError && (d.Path == path || strings.HasPrefix(d.Path, path+".")) {
+				return "", false, errors.New(d.String())
+			}
+		}
+	}
+	if err := config.ValidateYAML(data); err != nil {
+		return "", false, err
+	}
+	return strings.TrimSpace(string(data)), true, nil
+}
+
+// mappingValue returns the value of key in a YAML mapping node.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setMappingValue replaces or adds key in a mapping node, or removes it
+// when value is nil.
+func setMappingValue(node *yaml.Node, key string, value any) error {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value != key {
+			continue
+		}
+		if value == nil {
+			node.Content = slices.Delete(node.Content, i, i+2)
+			return nil
+		}
+		return node.Content[i+1].Encode(value)
+	}
+	if value == nil {
+		return nil
+	}
+	var val yaml.Node
+	if err := val.Encode(value); err != nil {
+		return err
+	}
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, &val)
+	return nil
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const petstoreConfig = `apis:
+  - name: petstore
+    spec_url: https://petstore.example.com/openapi.json
+    # pinned by the platform team
+    timeout_seconds: 10`
+
+// putProfile creates or replaces a profile with token "tok-<name>".
+func putProfile(t *testing.T, s *server, name, configYAML string) {
+	t.Helper()
+	body := map[string]string{"token": "tok-" + name, "config_yaml": configYAML}
+	if w := call(t, s.handleProfileRoute, http.MethodPut, "/profiles/"+name, body, asAdmin); w.Code != http.StatusOK {
+		t.Fatalf("put %s: %d %s", name, w.Code, w.Body)
+	}
+}
+
+func profileConfig(t *testing.T, s *server, name string) string {
+	t.Helper()
+	prof, ok := s.findProfile(name)
+	if !ok {
+		t.Fatalf("profile %s not found", name)
+	}
+	return prof.ConfigYAML
+}
+
+func TestProfilesExportImport(t *testing.T) {
+	src := newTestServer(t, nil)
+	putProfile(t, src, "ops", petstoreConfig)
+	putProfile(t, src, "billing", "apis: []")
+	key := "hex:" + hex.EncodeToString([]byte("a key other than the server's 32"))
+
+	if w := call(t, src.handleProfilesExport, http.MethodPost, "/admin/profiles/export", nil, withBearer("tok-ops")); w.Code != http.StatusUnauthorized {
+		t.Errorf("export with a profile token: %d", w.Code)
+	}
+	if w := call(t, src.handleProfilesExport, http.MethodPost, "/admin/profiles/export", map[string]any{"profiles": []string{"nope"}}, asAdmin); w.Code != http.StatusNotFound {
+		t.Errorf("export of an unknown profile: %d", w.Code)
+	}
+	w := call(t, src.handleProfilesExport, http.MethodPost, "/admin/profiles/export", map[string]any{"key": key, "profiles": []string{"ops", "billing"}}, asAdmin)
+	if w.Code != http.StatusOK {
+		t.Fatalf("export: %d %s", w.Code, w.Body)
+	}
+	exported := decodeBody(t, w)
+	if !reflect.DeepEqual(exported["profiles"], []any{"ops", "billing"}) {
+		t.Errorf("exported %v", exported["profiles"])
+	}
+	bundle := exported["bundle"]
+
+	dst := newTestServer(t, nil)
+	importBody := func(extra map[string]any) map[string]any {
+		body := map[string]any{"bundle": bundle, "key": key}
+		for k, v := range extra {
+			body[k] = v
+		}
+		return body
+	}
+	if w := call(t, dst.handleProfilesImport, http.MethodPost, "/admin/profiles/import", map[string]any{"bundle": bundle}, asAdmin); w.Code != http.StatusBadRequest {
+		t.Errorf("import with the wrong key: %d", w.Code)
+	}
+
+	// A bundle and a plain list in one import.
+	w = call(t, dst.handleProfilesImport, http.MethodPost, "/admin/profiles/import", importBody(map[string]any{
+		"profiles": []map[string]string{{"name": "fresh", "config_yaml": "apis: []"}},
+	}), asAdmin)
+	if w.Code != http.StatusOK {
+		t.Fatalf("import: %d %s", w.Code, w.Body)
+	}
+	res := decodeBody(t, w)
+	if !reflect.DeepEqual(res["created"], []any{"fresh", "ops", "billing"}) || len(res["updated"].([]any)) != 0 {
+		t.Errorf("import = %v", res)
+	}
+	tokens, _ := res["tokens"].(map[string]any)
+	if len(tokens) != 1 || tokens["fresh"] == nil {
+		t.Errorf("tokens = %v, want one for the profile without a token", tokens)
+	}
+	// Imported profiles keep their tokens and configs, comments included.
+	if code := call(t, dst.handleProfileRoute, http.MethodGet, "/profiles/ops", nil, withBearer("tok-ops")).Code; code != http.StatusOK {
+		t.Errorf("reading an imported profile with its old token: %d", code)
+	}
+	if got := profileConfig(t, dst, "ops"); got != petstoreConfig {
+		t.Errorf("imported config:\n%s", got)
+	}
+
+	// Existing profiles are skipped unless overwrite is set.
+	putProfile(t, dst, "ops", "apis: []")
+	w = call(t, dst.handleProfilesImport, http.MethodPost, "/admin/profiles/import", importBody(nil), asAdmin)
+	if res := decodeBody(t, w); !reflect.DeepEqual(res["skipped"], []any{"ops", "billing"}) || profileConfig(t, dst, "ops") != "apis: []" {
+		t.Errorf("import without overwrite = %v", res)
+	}
+	w = call(t, dst.handleProfilesImport, http.MethodPost, "/admin/profiles/import", importBody(map[string]any{"overwrite": true}), asAdmin)
+	if res := decodeBody(t, w); !reflect.DeepEqual(res["updated"], []any{"ops", "billing"}) || profileConfig(t, dst, "ops") != petstoreConfig {
+		t.Errorf("import with overwrite = %v", res)
+	}
+}
+
+func TestProfilesImportIsAllOrNothing(t *testing.T) {
+	s := newTestServer(t, nil)
+	w := call(t, s.handleProfilesImport, http.MethodPost, "/admin/profiles/import", map[string]any{
+		"profiles": []map[string]string{
+			{"name": "good", "config_yaml": "apis: []"},
+			{"name": "bad", "config_yaml": "apis: {"},
+			{"name": "good", "config_yaml": "apis: []"},
+			{"name": "", "config_yaml": "apis: []"},
+		},
+	}, asAdmin)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("import: %d %s", w.Code, w.Body)
+	}
+	problems := decodeBody(t, w)["errors"].([]any)
+	if len(problems) != 3 {
+		t.Errorf("errors = %v", problems)
+	}
+	if got := listProfiles(t, s, asAdmin); !reflect.DeepEqual(got, []any{"default"}) {
+		t.Errorf("a failed import saved %v", got)
+	}
+	if w := call(t, s.handleProfilesImport, http.MethodPost, "/admin/profiles/import", nil, asAdmin); w.Code != http.StatusBadRequest {
+		t.Errorf("empty import: %d", w.Code)
+	}
+}
+
+func TestProfilesPatch(t *testing.T) {
+	s := newTestServer(t, nil)
+	putProfile(t, s, "ops", petstoreConfig)
+	putProfile(t, s, "staging", petstoreConfig)
+	putProfile(t, s, "billing", "apis: []")
+	patch := func(body map[string]any) *httptest.ResponseRecorder {
+		return call(t, s.handleProfilesPatch, http.MethodPost, "/admin/profiles/patch", body, asAdmin)
+	}
+	moved := map[string]any{"base_url_override": "https://pets.example.net"}
+
+	// A dry run reports the profiles without changing them.
+	if r := patch(map[string]any{"api": "petstore", "set": moved, "dry_run": true}); r.Code != http.StatusOK || !strings.Contains(r.Body.String(), `"updated":["ops","staging"]`) {
+		t.Fatalf("dry run: %d %s", r.Code, r.Body)
+	}
+	if profileConfig(t, s, "ops") != petstoreConfig {
+		t.Fatal("a dry run changed the config")
+	}
+
+	if r := patch(map[string]any{"api": "petstore", "set": moved, "profiles": []string{"ops"}}); r.Code != http.StatusOK || !strings.Contains(r.Body.String(), `"updated":["ops"]`) {
+		t.Fatalf("patch: %d %s", r.Code, r.Body)
+	}
+	got := profileConfig(t, s, "ops")
+	if !strings.Contains(got, "base_url_override: https://pets.example.net") || !strings.Contains(got, "# pinned by the platform team") {
+		t.Errorf("patched config:\n%s", got)
+	}
+	if profileConfig(t, s, "staging") != petstoreConfig {
+		t.Error("a profile left out of profiles was patched")
+	}
+
+	// null removes a field.
+	if r := patch(map[string]any{"api": "petstore", "set": map[string]any{"timeout_seconds": nil}}); r.Code != http.StatusOK {
+		t.Fatalf("remove: %d %s", r.Code, r.Body)
+	}
+	if strings.Contains(profileConfig(t, s, "staging"), "timeout_seconds") {
+		t.Errorf("field was not removed:\n%s", profileConfig(t, s, "staging"))
+	}
+
+	for name, tc := range map[string]struct {
+		body map[string]any
+		code int
+	}{
+		"unknown field":   {map[string]any{"api": "petstore", "set": map[string]any{"base_ulr": "x"}}, http.StatusBadRequest},
+		"renaming":        {map[string]any{"api": "petstore", "set": map[string]any{"name": "pets"}}, http.StatusBadRequest},
+		"nothing to set":  {map[string]any{"api": "petstore"}, http.StatusBadRequest},
+		"unknown profile": {map[string]any{"api": "petstore", "set": moved, "profiles": []string{"nope"}}, http.StatusNotFound},
+	} {
+		if r := patch(tc.body); r.Code != tc.code {
+			t.Errorf("%s: %d %s, want %d", name, r.Code, r.Body, tc.code)
+		}
+	}
+	if w := call(t, s.handleProfilesPatch, http.MethodPost, "/admin/profiles/patch", map[string]any{"api": "petstore", "set": moved}, withBearer("tok-ops")); w.Code != http.StatusUnauthorized {
+		t.Errorf("patch with a profile token: %d", w.Code)
+	}
+}
+
+func TestBulkOperationsReportConflicts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profiles.db")
+	open := func() *server {
+		storage, err := openSQLiteStorage(path, testStorageKey())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { storage.Close() })
+		return newTestServer(t, storage)
+	}
+	a := open()
+	putProfile(t, a, "ops", petstoreConfig)
+	b := open()
+	putProfile(t, b, "ops", "apis: []")
+
+	// a's copy of ops is stale.
+	slower := strings.Replace(petstoreConfig, "timeout_seconds: 10", "timeout_seconds: 20", 1)
+	importOps := map[string]any{"overwrite": true, "profiles": []map[string]string{{"name": "ops", "config_yaml": slower}}}
+	if w := call(t, a.handleProfilesImport, http.MethodPost, "/admin/profiles/import", importOps, asAdmin); w.Code != http.StatusConflict {
+		t.Fatalf("stale import: %d %s", w.Code, w.Body)
+	}
+	if got := profileConfig(t, a, "ops"); got != "apis: []" {
+		t.Errorf("a did not reload after the conflict: %q", got)
+	}
+	if w := call(t, a.handleProfilesImport, http.MethodPost, "/admin/profiles/import", importOps, asAdmin); w.Code != http.StatusOK {
+		t.Fatalf("retried import: %d %s", w.Code, w.Body)
+	}
+
+	// Now b's copy is stale.
+	patch := map[string]any{"api": "petstore", "set": map[string]any{"base_url_override": "https://pets.example.net"}}
+	if err := b.reload(); err != nil {
+		t.Fatal(err)
+	}
+	putProfile(t, a, "ops", petstoreConfig)
+	if w := call(t, b.handleProfilesPatch, http.MethodPost, "/admin/profiles/patch", patch, asAdmin); w.Code != http.StatusConflict {
+		t.Fatalf("stale patch: %d %s", w.Code, w.Body)
+	}
+	if w := call(t, b.handleProfilesPatch, http.MethodPost, "/admin/profiles/patch", patch, asAdmin); w.Code != http.StatusOK {
+		t.Fatalf("retried patch: %d %s", w.Code, w.Body)
+	}
+	if got := profileConfig(t, b, "ops"); !strings.Contains(got, "timeout_seconds: 10") || !strings.Contains(got, "base_url_override") {
+		t.Errorf("the patch did not apply to a's change:\n%s", got)
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"skyline-mcp/internal/codegen"
+	codeexec "skyline-mcp/internal/executor"
+	"skyline-mcp/internal/runtime"
+	"skyline-mcp/internal/serverconfig"
+)
+
+// handleProfileCode runs a script against a profile's tools in the code
+// execution sandbox (POST /profiles/{name}/code). The runtime's limits and
+// egress policy apply, with the profile's overrides. The script and the
+// tools it called are recorded in the audit log.
+func (s *server) handleProfileCode(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	limitBody(w, r)
+
+	name := extractProfileName(r.URL.Path, "/profiles/", "/code")
+	if name == "" {
+		http.Error(w, "profile name required", http.StatusBadRequest)
+		return
+	}
+	s.mu.RLock()
+	prof, ok := s.findProfile(name)
+	s.mu.RUnlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if err := s.authorizeProfile(r, prof); err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	if s.serverCfg == nil || !s.serverCfg.Runtime.CodeExecution.Enabled || !prof.ToConfig().CodeExecutionEnabled() {
+		http.Error(w, "code execution not enabled", http.StatusNotImplemented)
+		return
+	}
+
+	var req codeexec.ExecuteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json body", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.Code) == "" {
+		http.Error(w, "code is required", http.StatusBadRequest)
+		return
+	}
+
+	codeCfg := s.serverCfg.Runtime.CodeExecution.ForProfile(prof.Name)
+	// The configured timeout is a ceiling for the script's own.
+	if limit := int(codeCfg.Timeout / time.Second); limit > 0 && (req.Timeout <= 0 || req.Timeout > limit) {
+		req.Timeout = limit
+	}
+
+	loadCtx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	cached, _, err := s.getOrBuildCache(loadCtx, prof)
+	cancel()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("load services: %v", err), http.StatusInternalServerError)
+		return
+	}
+	exec, err := s.codeExecutorFor(cached, prof.Name, codeCfg)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("code execution setup: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	startTime := time.Now()
+	result, err := exec.Execute(r.Context(), req)
+	if err != nil {
+		s.auditLogger.LogCodeExecution(r.Context(), prof.Name, exec.Engine(), req.Code, nil, time.Since(startTime), 1, err.Error(), clientIP(r))
+		http.Error(w, fmt.Sprintf("execution failed: %v", err), http.StatusInternalServerError)
+		return
+	}
+	s.auditLogger.LogCodeExecution(r.Context(), prof.Name, exec.Engine(), req.Code, result.ToolsCalled, time.Since(startTime), result.ExitCode, result.Error, clientIP(r))
+	writeJSON(w, http.StatusOK, result)
+}
+
+// codeExecutorFor returns the profile's code executor, setting it up on
+// first use. Tool calls from scripts go straight to the profile's executor
+// and are audited like other tool calls, with client "code".
+func (s *server) codeExecutorFor(entry *registryCache, profileName string, codeCfg serverconfig.CodeExecutionConfig) (*codeexec.Executor, error) {
+	entry.codeOnce.Do(func() {
+		opts := codeexec.Options{
+			Engine:       codeCfg.Engine,
+			DenoPath:     codeCfg.DenoPath,
+			Timeout:      codeCfg.Timeout,
+			CPUTime:      codeCfg.CPUTime,
+			AllowedHosts: codeCfg.AllowedHosts,
+		}
+		if codeCfg.MemoryLimit != "" {
+			limit, err := serverconfig.ParseByteSize(codeCfg.MemoryLimit)
+			if err != nil {
+				entry.codeErr = fmt.Errorf("memoryLimit: %w", err)
+				return
+			}
+			opts.MemoryLimit = limit
+		}
+		workspace := filepath.Join(os.TempDir(), "skyline-workspace", "profile-"+profileConfigHash(profileName)[:16])
+		exec, err := codegen.SetupCodeExecution(entry.registry, s.logger, workspace, opts)
+		if err != nil {
+			entry.codeErr = err
+			return
+		}
+		if exec == nil {
+			entry.codeErr = fmt.Errorf("no code execution runtime available")
+			return
+		}
+		exec.SetDirectCallFunc(func(ctx context.Context, toolName string, args map[string]any) (any, error) {
+			tool, ok := entry.registry.Tools[toolName]
+			if !ok || tool.Operation == nil {
+				return nil, fmt.Errorf("tool not found: %s", toolName)
+			}
+			ctx, err := runtime.WithTimeoutArgument(ctx, args)
+			if err != nil {
+				return nil, err
+			}
+			if err := tool.ValidateArguments(args); err != nil {
+				return nil, err
+			}
+			reqBytes, _ := json.Marshal(args)
+			start := time.Now()
+			result, err := entry.executor.Execute(ctx, tool.Operation, args)
+			duration := time.Since(start)
+			if err != nil {
+				s.auditLogger.LogExecute(ctx, profileName, tool.Operation.ServiceName, toolName, args,
+					duration, 0, false, err.Error(), "code", int64(len(reqBytes)), 0)
+				s.metrics.RecordRequest(profileName, toolName, duration, false)
+				return nil, err
+			}
+			respBytes, _ := json.Marshal(result.Body)
+			s.auditLogger.LogExecute(ctx, profileName, tool.Operation.ServiceName, toolName, args,
+				duration, result.Status, true, "", "code", int64(len(reqBytes)), int64(len(respBytes)))
+			s.metrics.RecordRequest(profileName, toolName, duration, true)
+			return result.Body, nil
+		})
+		entry.codeExec = exec
+	})
+	return entry.codeExec, entry.codeErr
+}
@@ -6,27 +6,26 @@ import (
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"strings"
 	"time"
 
+	"gopkg.in/yaml.v3"
+
 	"skyline-mcp/internal/canonical"
-	"skyline-mcp/internal/parsers/asyncapi"
-	"skyline-mcp/internal/parsers/graphql"
-	"skyline-mcp/internal/parsers/insomnia"
-	"skyline-mcp/internal/parsers/openrpc"
-	"skyline-mcp/internal/parsers/postman"
-	"skyline-mcp/internal/parsers/raml"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/detect"
+	"skyline-mcp/internal/redact"
 	"skyline-mcp/internal/spec"
 )
 
-const rpcDiscoverPayload = `{"jsonrpc":"2.0","method":"rpc.discover","id":1,"params":[]}`
-
-var graphqlIntrospectionPayload = func() string {
-	b, _ := json.Marshal(map[string]string{"query": spec.GraphQLIntrospectionQuery})
-	return string(b)
-}()
+const (
+	// defaultDetectTimeout bounds a whole /detect run; requests may ask
+	// for up to maxDetectTimeout, which stays under the server's write
+	// timeout.
+	defaultDetectTimeout = 10 * time.Second
+	maxDetectTimeout     = 12 * time.Second
+)
 
 func (s *server) handleDetect(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -52,157 +51,199 @@ func (s *server) handleDetect(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resp := detectResponse{BaseURL: baseURL}
-
-	// Build auth header to forward during probing if a token was provided.
-	var probeAuth map[string]string
-	if tok := strings.TrimSpace(req.BearerToken); tok != "" {
-		probeAuth = map[string]string{"Authorization": "Bearer " + tok}
-	}
-
-	type probe struct {
-		Type        string
-		Path        string
-		Method      string
-		Body        []byte
-		Headers     map[string]string
-		AllowUnauth bool // treat HTTP 401 as "found" (server exists but requires auth)
-	}
-
-	probes := []probe{
-		{Type: "jira-rest", Path: "/rest/api/3/serverInfo", Method: http.MethodGet},
-		// Kubernetes-specific paths — probe these first and allow 401 so we can show
-		// the kubeconfig upload helper even when no token has been supplied yet.
-		{Type: "swagger2", Path: "/openapi/v2", Method: http.MethodGet, AllowUnauth: true},
-		{Type: "openapi", Path: "/openapi/v3", Method: http.MethodGet, AllowUnauth: true},
-		{Type: "openapi", Path: "/openapi.json", Method: http.MethodGet},
-		{Type: "openapi", Path: "/openapi.yaml", Method: http.MethodGet},
-		{Type: "openapi", Path: "/openapi/openapi.json", Method: http.MethodGet},
-		{Type: "openapi", Path: "/openapi/openapi.yaml", Method: http.MethodGet},
-		{Type: "openapi", Path: "/v3/api-docs", Method: http.MethodGet},
-		{Type: "swagger2", Path: "/swagger.json", Method: http.MethodGet},
-		{Type: "swagger2", Path: "/swagger.yaml", Method: http.MethodGet},
-		{Type: "swagger2", Path: "/swagger/swagger.json", Method: http.MethodGet},
-		{Type: "swagger2", Path: "/v2/api-docs", Method: http.MethodGet},
-		{Type: "wsdl", Path: "/wsdl", Method: http.MethodGet},
-		{Type: "wsdl", Path: "/wsdl?wsdl", Method: http.MethodGet},
-		{Type: "wsdl", Path: "/wdsl/wsdl", Method: http.MethodGet},
-		{Type: "odata", Path: "/$metadata", Method: http.MethodGet},
-		{Type: "odata", Path: "/odata/$metadata", Method: http.MethodGet},
-		{Type: "ckan", Path: "/api/3/action/package_list", Method: http.MethodGet},
-		{Type: "openrpc", Path: "/jsonrpc/openrpc.json", Method: http.MethodGet},
-		{Type: "openrpc", Path: "/openrpc.json", Method: http.MethodGet},
-		{Type: "openrpc", Path: "/jsonrpc", Method: http.MethodPost, Body: []byte(rpcDiscoverPayload), Headers: map[string]string{"Content-Type": "application/json"}},
-		{Type: "openrpc", Path: "/rpc", Method: http.MethodPost, Body: []byte(rpcDiscoverPayload), Headers: map[string]string{"Content-Type": "application/json"}},
-		{Type: "graphql", Path: "/graphql/schema", Method: http.MethodGet},
-		{Type: "graphql", Path: "/graphql", Method: http.MethodPost, Body: []byte(graphqlIntrospectionPayload), Headers: map[string]string{"Content-Type": "application/json"}},
-		{Type: "graphql", Path: "/api/graphql", Method: http.MethodPost, Body: []byte(graphqlIntrospectionPayload), Headers: map[string]string{"Content-Type": "application/json"}},
-		{Type: "asyncapi", Path: "/asyncapi.json", Method: http.MethodGet},
-		{Type: "asyncapi", Path: "/asyncapi.yaml", Method: http.MethodGet},
-		{Type: "asyncapi", Path: "/asyncapi.yml", Method: http.MethodGet},
-		{Type: "insomnia", Path: "/insomnia.json", Method: http.MethodGet},
-	}
-	if basePathLooksLikeGraphQL(baseURL) {
-		probes = append([]probe{
-			{Type: "graphql", Path: "", Method: http.MethodPost, Body: []byte(graphqlIntrospectionPayload), Headers: map[string]string{"Content-Type": "application/json"}},
-			{Type: "graphql", Path: "/schema", Method: http.MethodGet},
-		}, probes...)
-	}
-
-	// mergeHeaders returns a new map with base headers overridden/extended by extra.
-	mergeHeaders := func(base, extra map[string]string) map[string]string {
-		if len(base) == 0 && len(extra) == 0 {
-			return nil
-		}
-		out := make(map[string]string, len(base)+len(extra))
-		for k, v := range base {
-			out[k] = v
+	auth, ok := s.detectAuthFor(w, r, req.Auth)
+	if !ok {
+		return
+	}
+	if auth == nil && strings.TrimSpace(req.BearerToken) != "" {
+		auth = &config.AuthConfig{Type: "bearer", Token: strings.TrimSpace(req.BearerToken)}
+	}
+
+	probes := s.detectProbes.Probes()
+	if len(req.Probes) > 0 {
+		if len(req.Probes) > detect.MaxProbes {
+			http.Error(w, fmt.Sprintf("at most %d probes are allowed", detect.MaxProbes), http.StatusBadRequest)
+			return
 		}
-		for k, v := range extra {
-			out[k] = v
+		probes = make([]detect.Probe, 0, len(req.Probes))
+		for _, p := range req.Probes {
+			compiled, err := detect.Compile(p)
+			if err != nil {
+				http.Error(w, "invalid probe: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			probes = append(probes, compiled)
 		}
-		return out
 	}
+	probes = detect.ForBaseURL(probes, baseURL)
+
+	timeout := defaultDetectTimeout
+	if req.TimeoutSeconds > 0 {
+		timeout = min(time.Duration(req.TimeoutSeconds)*time.Second, maxDetectTimeout)
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
 
 	client := &http.Client{
-		Timeout: 8 * time.Second,
 		Transport: &http.Transport{
 			TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, //nolint:gosec // intentional: detect probes user-supplied URLs
 		},
 	}
-	for _, p := range probes {
-		target := strings.TrimRight(baseURL, "/") + p.Path
-		headers := mergeHeaders(p.Headers, probeAuth)
-		found, status, err := s.probeURL(client, p.Method, target, p.Body, headers, p.AllowUnauth)
+	start := time.Now()
+	results := detect.Run(ctx, client, baseURL, probes, detect.RunOptions{
+		Headers:     authHeaders(auth),
+		StopOnFirst: req.StopOnFirst,
+	})
+
+	resp := detectResponse{BaseURL: baseURL, ElapsedMS: time.Since(start).Milliseconds()}
+	for _, res := range results {
 		item := detectProbe{
-			Type:     p.Type,
-			SpecURL:  target,
-			Method:   p.Method,
-			Status:   status,
-			Found:    found,
-			Endpoint: target,
+			Type:       res.Probe.Type,
+			SpecURL:    res.URL,
+			Method:     res.Probe.Method,
+			Status:     res.Status,
+			Found:      res.Found,
+			Skipped:    res.Skipped,
+			Endpoint:   res.URL,
+			DurationMS: res.Duration.Milliseconds(),
 		}
-		if err != nil {
-			item.Error = err.Error()
+		if res.Error != "" {
+			item.Error = s.redactAuth(res.Error, auth)
 		}
 		resp.Detected = append(resp.Detected, item)
-		if found {
+		if res.Reached {
 			resp.Online = true
 		}
 	}
 
-	adapters := map[string]func([]byte) bool{
-		"openapi":  spec.NewOpenAPIAdapter().Detect,
-		"swagger2": spec.NewSwagger2Adapter().Detect,
-		"graphql": func(raw []byte) bool {
-			return graphql.LooksLikeGraphQLSDL(raw) || graphql.LooksLikeGraphQLIntrospection(raw)
-		},
-		"wsdl":     spec.NewWSDLAdapter().Detect,
-		"odata":    looksLikeODataMetadata,
-		"postman":  postman.LooksLikePostmanCollection,
-		"openrpc":  openrpc.LooksLikeOpenRPC,
-		"asyncapi": asyncapi.LooksLikeAsyncAPI,
-		"insomnia": insomnia.LooksLikeInsomniaCollection,
-		"raml":     raml.LooksLikeRAML,
-	}
+	resp.Detected = applyJiraRestHint(resp.Detected, baseURL)
 
-	for i := range resp.Detected {
-		if !resp.Detected[i].Found {
-			continue
-		}
-		if resp.Detected[i].Type == "jira-rest" {
-			continue
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleDetectSuggest turns the output of /detect into an API config and
+// the full profile YAML to PUT to /profiles/{name}. With profile set, the
+// API is added to that profile's config.
+func (s *server) handleDetectSuggest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	// Loading the suggested spec fetches user-supplied URLs, like /detect.
+	if s.detectLimiter != nil {
+		if err := s.detectLimiter.Wait(r.Context()); err != nil {
+			http.Error(w, "rate limited — try again shortly", http.StatusTooManyRequests)
+			return
 		}
-		// If the probe succeeded only because we allow 401 (server exists but requires
-		// auth), skip content validation — we cannot fetch the spec without credentials.
-		if resp.Detected[i].Status == http.StatusUnauthorized {
-			continue
+	}
+	limitBody(w, r)
+	var req suggestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json body", http.StatusBadRequest)
+		return
+	}
+	baseURL := strings.TrimSpace(req.BaseURL)
+	if baseURL == "" {
+		http.Error(w, "base_url is required", http.StatusBadRequest)
+		return
+	}
+	auth, ok := s.detectAuthFor(w, r, req.Auth)
+	if !ok {
+		return
+	}
+
+	var cfg config.Config
+	if req.Profile != "" {
+		s.mu.RLock()
+		prof, found := s.findProfile(req.Profile)
+		s.mu.RUnlock()
+		if found {
+			if err := s.authorizeProfile(r, prof); err != nil {
+				writeAuthError(w, err)
+				return
+			}
+			if err := yaml.Unmarshal([]byte(prof.ConfigYAML), &cfg); err != nil {
+				http.Error(w, "profile config is not valid YAML", http.StatusInternalServerError)
+				return
+			}
 		}
-		isOpenRPCDiscover := resp.Detected[i].Type == "openrpc" && resp.Detected[i].Method == http.MethodPost
-		var postBody []byte
-		if isOpenRPCDiscover {
-			postBody = []byte(rpcDiscoverPayload)
+	}
+
+	var found []detect.Found
+	for _, d := range req.Detected {
+		if d.Found {
+			found = append(found, detect.Found{Type: d.Type, URL: d.SpecURL, Method: d.Method, Status: d.Status})
 		}
-		raw, err := s.fetchRaw(client, resp.Detected[i].Method, resp.Detected[i].SpecURL, resp.Detected[i].Method == http.MethodPost && !isOpenRPCDiscover, postBody, probeAuth)
-		if err != nil {
-			resp.Detected[i].Found = false
-			resp.Detected[i].Error = err.Error()
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = uniqueAPIName(&cfg, detect.SuggestName(baseURL))
+	}
+	suggestion, err := detect.Suggest(baseURL, found, req.Type, name, auth)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), maxDetectTimeout)
+	defer cancel()
+	detect.Verify(ctx, suggestion, baseURL, s.logger, s.redactor)
+
+	cfg.APIs = append(cfg.APIs, suggestion.API)
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		http.Error(w, "failed to marshal config", http.StatusInternalServerError)
+		return
+	}
+	if err := config.ValidateYAML(data); err != nil {
+		http.Error(w, fmt.Sprintf("suggested config is invalid: %v", err), http.StatusBadRequest)
+		return
+	}
+	resp := suggestResponse{
+		API:        suggestion.API,
+		Type:       suggestion.Type,
+		Profile:    req.Profile,
+		ConfigYAML: string(data),
+		Operations: suggestion.Operations,
+	}
+	for _, warning := range suggestion.Warnings {
+		resp.Warnings = append(resp.Warnings, s.redactAuth(warning, auth))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// uniqueAPIName returns name, or name with a numeric suffix when cfg
+// already has an API called that.
+func uniqueAPIName(cfg *config.Config, name string) string {
+	taken := map[string]bool{}
+	for _, api := range cfg.APIs {
+		taken[api.Name] = true
+	}
+	candidate := name
+	for i := 2; taken[candidate]; i++ {
+		candidate = fmt.Sprintf("%s_%d", name, i)
+	}
+	return candidate
+}
+
+// handleDetectProbes lists the probes /detect tries, so the UI can show
+// them and send back an edited list. Header values are redacted.
+func (s *server) handleDetectProbes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	probes := s.detectProbes.Probes()
+	for i := range probes {
+		if len(probes[i].Headers) == 0 {
 			continue
 		}
-		// For rpc.discover responses, unwrap the JSON-RPC result.
-		if isOpenRPCDiscover {
-			raw = unwrapJSONRPCResult(raw)
-		}
-		detectFn := adapters[resp.Detected[i].Type]
-		if detectFn == nil || !detectFn(raw) {
-			resp.Detected[i].Found = false
-			resp.Detected[i].Error = "content did not match detected type"
+		headers := make(map[string]string, len(probes[i].Headers))
+		for k, v := range probes[i].Headers {
+			headers[k] = s.redactor.Redact(v)
 		}
+		probes[i].Headers = headers
 	}
-
-	resp.Detected = applyJiraRestHint(resp.Detected, baseURL)
-
-	writeJSON(w, http.StatusOK, resp)
+	writeJSON(w, http.StatusOK, map[string]any{"probes": probes})
 }
 
 func (s *server) handleTest(w http.ResponseWriter, r *http.Request) {
@@ -228,15 +269,19 @@ func (s *server) handleTest(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "spec_url is required", http.StatusBadRequest)
 		return
 	}
+	auth, ok := s.detectAuthFor(w, r, req.Auth)
+	if !ok {
+		return
+	}
 	client := &http.Client{Timeout: 8 * time.Second}
-	found, status, err := s.probeURL(client, http.MethodGet, specURL, nil, nil)
+	found, status, err := s.probeURL(client, http.MethodGet, specURL, nil, authHeaders(auth))
 	resp := testResponse{
 		SpecURL: specURL,
 		Online:  found,
 		Status:  status,
 	}
 	if err != nil {
-		resp.Error = err.Error()
+		resp.Error = s.redactAuth(err.Error(), auth)
 	}
 	writeJSON(w, http.StatusOK, resp)
 }
@@ -279,15 +324,19 @@ func (s *server) handleOperations(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "spec_url is required", http.StatusBadRequest)
 		return
 	}
+	auth, ok := s.detectAuthFor(w, r, req.Auth)
+	if !ok {
+		return
+	}
 
 	// Fetch and parse the spec
 	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
 	defer cancel()
 
-	operations, err := s.fetchOperations(ctx, specURL, req.SpecType)
+	operations, err := s.fetchOperations(ctx, specURL, auth)
 	if err != nil {
 		writeJSON(w, http.StatusOK, operationsResponse{
-			Error: err.Error(),
+			Error: s.redactAuth(err.Error(), auth),
 		})
 		return
 	}
@@ -297,11 +346,11 @@ func (s *server) handleOperations(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func (s *server) fetchOperations(ctx context.Context, specURL, specType string) ([]operationInfo, error) {
+func (s *server) fetchOperations(ctx context.Context, specURL string, auth *config.AuthConfig) ([]operationInfo, error) {
 	fetcher := spec.NewFetcher(30 * time.Second)
 
 	// Fetch spec
-	raw, err := fetcher.Fetch(ctx, specURL, nil)
+	raw, err := fetcher.Fetch(ctx, specURL, auth)
 	if err != nil {
 		return nil, fmt.Errorf("fetch spec: %w", err)
 	}
@@ -313,6 +362,7 @@ func (s *server) fetchOperations(ctx context.Context, specURL, specType string)
 		spec.NewAsyncAPIAdapter(),
 		spec.NewPostmanAdapter(),
 		spec.NewInsomniaAdapter(),
+		spec.NewHARAdapter(),
 		spec.NewGoogleDiscoveryAdapter(),
 		spec.NewOpenRPCAdapter(),
 		spec.NewGraphQLAdapter(),
@@ -330,7 +380,7 @@ func (s *server) fetchOperations(ctx context.Context, specURL, specType string)
 		}
 		parsed, err := adapter.Parse(ctx, raw, "temp", "")
 		if err != nil {
-			s.logger.Debug("adapter parse error", "adapter", fmt.Sprintf("%T", adapter), "error", err)
+			s.logger.Debug("adapter parse error", "adapter", fmt.Sprintf("%T", adapter), "error", s.redactAuth(err.Error(), auth))
 			continue
 		}
 		service = parsed
@@ -355,6 +405,93 @@ func (s *server) fetchOperations(ctx context.Context, specURL, specType string)
 	return result, nil
 }
 
+// detectAuthFor resolves the credentials of a detect, test or operations
+// request. A reference to a profile API needs the same access as reading
+// the profile. On failure it writes the error response and returns false.
+func (s *server) detectAuthFor(w http.ResponseWriter, r *http.Request, da *detectAuth) (*config.AuthConfig, bool) {
+	if da == nil {
+		return nil, true
+	}
+	var auth *config.AuthConfig
+	if da.Profile != "" || da.API != "" {
+		if da.Type != "" {
+			http.Error(w, "auth: set either type or profile and api", http.StatusBadRequest)
+			return nil, false
+		}
+		if da.Profile == "" || da.API == "" {
+			http.Error(w, "auth.profile and auth.api are both required", http.StatusBadRequest)
+			return nil, false
+		}
+		s.mu.RLock()
+		prof, ok := s.findProfile(da.Profile)
+		s.mu.RUnlock()
+		if !ok {
+			http.Error(w, "profile not found", http.StatusNotFound)
+			return nil, false
+		}
+		if err := s.authorizeProfile(r, prof); err != nil {
+			writeAuthError(w, err)
+			return nil, false
+		}
+		found := false
+		for _, api := range prof.ToConfig().APIs {
+			if api.Name == da.API {
+				auth, found = api.Auth, true
+				break
+			}
+		}
+		if !found {
+			http.Error(w, fmt.Sprintf("api %q not found in profile %q", da.API, da.Profile), http.StatusNotFound)
+			return nil, false
+		}
+		if auth == nil {
+			return nil, true
+		}
+	} else {
+		auth = &config.AuthConfig{Type: da.Type, Token: da.Token, Username: da.Username, Password: da.Password, Header: da.Header, Value: da.Value}
+	}
+	switch auth.Type {
+	case "bearer", "basic", "api-key":
+	default:
+		http.Error(w, fmt.Sprintf("auth.type %q cannot be used here; use bearer, basic or api-key", auth.Type), http.StatusBadRequest)
+		return nil, false
+	}
+	if err := auth.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	return auth, true
+}
+
+// authHeaders returns the headers that carry auth.
+func authHeaders(auth *config.AuthConfig) map[string]string {
+	if auth == nil {
+		return nil
+	}
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	spec.ApplyAuth(req, auth)
+	headers := make(map[string]string, len(req.Header))
+	for name := range req.Header {
+		headers[name] = req.Header.Get(name)
+	}
+	return headers
+}
+
+// redactAuth applies the server's redaction to text and removes the
+// secrets of auth, which the redactor has not seen when given inline.
+func (s *server) redactAuth(text string, auth *config.AuthConfig) string {
+	text = s.redactor.Redact(text)
+	if auth == nil {
+		return text
+	}
+	for _, secret := range []string{auth.Token, auth.Password, auth.Value} {
+		if secret != "" {
+			text = strings.ReplaceAll(text, secret, redact.Placeholder)
+		}
+	}
+	return text
+}
+
 func (s *server) probeURL(client *http.Client, method, url string, body []byte, headers map[string]string, allowUnauth ...bool) (bool, int, error) {
 	req, err := http.NewRequest(method, url, bytes.NewReader(body))
 	if err != nil {
@@ -378,57 +515,6 @@ func (s *server) probeURL(client *http.Client, method, url string, body []byte,
 	return true, resp.StatusCode, nil
 }
 
-func (s *server) fetchRaw(client *http.Client, method, url string, useIntrospection bool, explicitBody []byte, extraHeaders ...map[string]string) ([]byte, error) {
-	var body []byte
-	if len(explicitBody) > 0 {
-		body = explicitBody
-	} else if useIntrospection {
-		body = []byte(graphqlIntrospectionPayload)
-	}
-	req, err := http.NewRequest(method, url, bytes.NewReader(body))
-	if err != nil {
-		return nil, err
-	}
-	if method == http.MethodPost {
-		req.Header.Set("Content-Type", "application/json")
-	}
-	req.Header.Set("Accept", "application/json, text/yaml, application/yaml, application/xml, text/xml, */*")
-	for _, hdrs := range extraHeaders {
-		for k, v := range hdrs {
-			req.Header.Set(k, v)
-		}
-	}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
-	}
-	return io.ReadAll(resp.Body)
-}
-
-func unwrapJSONRPCResult(raw []byte) []byte {
-	var rpcResp struct {
-		Result json.RawMessage `json:"result"`
-	}
-	if err := json.Unmarshal(raw, &rpcResp); err == nil && len(rpcResp.Result) > 0 {
-		return []byte(rpcResp.Result)
-	}
-	return raw
-}
-
-func looksLikeODataMetadata(raw []byte) bool {
-	s := string(raw)
-	return strings.Contains(s, "edmx:Edmx") || strings.Contains(s, "<edmx:DataServices") || strings.Contains(s, "oasis-open.org/odata")
-}
-
-func basePathLooksLikeGraphQL(baseURL string) bool {
-	lower := strings.ToLower(baseURL)
-	return strings.Contains(lower, "/graphql")
-}
-
 func applyJiraRestHint(detected []detectProbe, baseURL string) []detectProbe {
 	if !strings.HasSuffix(strings.ToLower(baseURL), ".atlassian.net") {
 		return detected
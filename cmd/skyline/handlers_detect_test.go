@@ -0,0 +1,138 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"skyline-mcp/internal/detect"
+	"skyline-mcp/internal/redact"
+	"skyline-mcp/internal/serverconfig"
+)
+
+const detectSpec = `{"openapi":"3.0.0","info":{"title":"t","version":"1"},
+"paths":{"/pets":{"get":{"operationId":"listPets","responses":{"200":{"description":"ok"}}}}}}`
+
+// protectedUpstream serves detectSpec at /openapi.json to requests whose
+// header carries value, and answers 401 otherwise.
+func protectedUpstream(t *testing.T, header, value string) *httptest.Server {
+	t.Helper()
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(header) != value {
+			http.Error(w, "missing credentials", http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/openapi.json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(detectSpec))
+	}))
+	t.Cleanup(upstream.Close)
+	return upstream
+}
+
+func newDetectServer(t *testing.T) *server {
+	t.Helper()
+	s := newTestServer(t, nil)
+	s.redactor = redact.NewRedactor()
+	probes, err := detect.NewRegistry(serverconfig.DetectSection{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.detectProbes = probes
+	return s
+}
+
+func TestDetectRequestsSendCredentials(t *testing.T) {
+	s := newDetectServer(t)
+	upstream := protectedUpstream(t, "Authorization", "Bearer sesame")
+	specURL := upstream.URL + "/openapi.json"
+	bearer := map[string]string{"type": "bearer", "token": "sesame"}
+
+	w := call(t, s.handleTest, http.MethodPost, "/test", map[string]any{"spec_url": specURL, "auth": bearer})
+	if body := decodeBody(t, w); body["online"] != true || body["status"] != float64(200) {
+		t.Errorf("test with credentials: %v", body)
+	}
+	w = call(t, s.handleTest, http.MethodPost, "/test", map[string]any{"spec_url": specURL})
+	if body := decodeBody(t, w); body["online"] != false || body["status"] != float64(401) {
+		t.Errorf("test without credentials: %v", body)
+	}
+
+	w = call(t, s.handleOperations, http.MethodPost, "/operations", map[string]any{"spec_url": specURL, "auth": bearer})
+	if body := decodeBody(t, w); !strings.Contains(w.Body.String(), `"id":"listPets"`) {
+		t.Errorf("operations with credentials: %v", body)
+	}
+
+	probes := []map[string]string{{"type": "openapi", "path": "/openapi.json", "method": "GET"}}
+	for name, req := range map[string]map[string]any{
+		"auth":         {"base_url": upstream.URL, "probes": probes, "auth": bearer},
+		"bearer_token": {"base_url": upstream.URL, "probes": probes, "bearer_token": "sesame"},
+	} {
+		w = call(t, s.handleDetect, http.MethodPost, "/detect", req)
+		if !strings.Contains(w.Body.String(), `"found":true`) {
+			t.Errorf("detect with %s: %d %s", name, w.Code, w.Body)
+		}
+	}
+}
+
+func TestDetectAuthFromProfile(t *testing.T) {
+	s := newDetectServer(t)
+	upstream := protectedUpstream(t, "X-Api-Key", "from-profile")
+	specURL := upstream.URL + "/openapi.json"
+	putProfile(t, s, "ops", `apis:
+  - name: pets
+    spec_url: `+specURL+`
+    auth:
+      type: api-key
+      header: X-Api-Key
+      value: from-profile
+  - name: open
+    spec_url: `+specURL)
+
+	ref := func(profile, api string) map[string]any {
+		return map[string]any{"spec_url": specURL, "auth": map[string]string{"profile": profile, "api": api}}
+	}
+	w := call(t, s.handleTest, http.MethodPost, "/test", ref("ops", "pets"), withBearer("tok-ops"))
+	if body := decodeBody(t, w); body["online"] != true {
+		t.Errorf("test with the profile's credentials: %v", body)
+	}
+	// An API without auth sends none.
+	w = call(t, s.handleTest, http.MethodPost, "/test", ref("ops", "open"), withBearer("tok-ops"))
+	if body := decodeBody(t, w); body["status"] != float64(401) {
+		t.Errorf("test with an API without auth: %v", body)
+	}
+
+	for name, tc := range map[string]struct {
+		auth map[string]string
+		opts []requestOption
+		code int
+	}{
+		"no access":         {map[string]string{"profile": "ops", "api": "pets"}, nil, http.StatusUnauthorized},
+		"unknown profile":   {map[string]string{"profile": "nope", "api": "pets"}, []requestOption{asAdmin}, http.StatusNotFound},
+		"unknown api":       {map[string]string{"profile": "ops", "api": "nope"}, []requestOption{withBearer("tok-ops")}, http.StatusNotFound},
+		"profile only":      {map[string]string{"profile": "ops"}, []requestOption{withBearer("tok-ops")}, http.StatusBadRequest},
+		"type and profile":  {map[string]string{"type": "bearer", "token": "x", "profile": "ops", "api": "pets"}, nil, http.StatusBadRequest},
+		"unsupported type":  {map[string]string{"type": "oauth2"}, nil, http.StatusBadRequest},
+		"incomplete bearer": {map[string]string{"type": "bearer"}, nil, http.StatusBadRequest},
+	} {
+		w := call(t, s.handleTest, http.MethodPost, "/test", map[string]any{"spec_url": specURL, "auth": tc.auth}, tc.opts...)
+		if w.Code != tc.code {
+			t.Errorf("%s: %d %s, want %d", name, w.Code, w.Body, tc.code)
+		}
+	}
+}
+
+func TestDetectErrorsHideCredentials(t *testing.T) {
+	s := newDetectServer(t)
+	w := call(t, s.handleOperations, http.MethodPost, "/operations", map[string]any{
+		"spec_url": "http://127.0.0.1:1/openapi.json?key=hunter2",
+		"auth":     map[string]string{"type": "basic", "username": "u", "password": "hunter2"},
+	})
+	body := decodeBody(t, w)
+	if msg, _ := body["error"].(string); msg == "" || strings.Contains(msg, "hunter2") {
+		t.Errorf("error = %q", msg)
+	}
+}
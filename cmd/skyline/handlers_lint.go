@@ -0,0 +1,44 @@
+package main
+
+import (
+	"net/http"
+
+	"skyline-mcp/internal/config"
+)
+
+// handleProfileLint reports risky settings in a profile's config, such as
+// write-capable APIs without a rate limit or secrets written inline.
+// GET /profiles/{name}/lint
+func (s *server) handleProfileLint(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := extractProfileName(r.URL.Path, "/profiles/", "/lint")
+	if name == "" {
+		http.Error(w, "profile name required", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.RLock()
+	prof, ok := s.findProfile(name)
+	s.mu.RUnlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if err := s.authorizeProfile(r, prof); err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	findings := config.Lint([]byte(prof.ConfigYAML))
+	if findings == nil {
+		findings = []config.Diagnostic{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"profile":  prof.Name,
+		"findings": findings,
+	})
+}
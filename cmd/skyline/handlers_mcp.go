@@ -4,12 +4,16 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
 	"skyline-mcp/internal/config"
 	"skyline-mcp/internal/email"
 	"skyline-mcp/internal/mcp"
+	"skyline-mcp/internal/runtime"
+	"skyline-mcp/internal/serverconfig"
+	"skyline-mcp/internal/spec"
 )
 
 // handleProfileMCP handles Streamable HTTP MCP connections for a profile.
@@ -22,7 +26,13 @@ func (s *server) handleProfileMCP(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "profile name required", http.StatusBadRequest)
 		return
 	}
+	s.serveProfileMCP(w, r, name)
+}
 
+// serveProfileMCP serves one Streamable HTTP MCP request for the named
+// profile. The profile is looked up per request so edits made through the
+// control plane apply to new sessions.
+func (s *server) serveProfileMCP(w http.ResponseWriter, r *http.Request, name string) {
 	// Look up profile
 	s.mu.RLock()
 	prof, ok := s.findProfile(name)
@@ -31,6 +41,10 @@ func (s *server) handleProfileMCP(w http.ResponseWriter, r *http.Request) {
 		http.NotFound(w, r)
 		return
 	}
+	if err := s.checkLockout(r, prof.Name); err != nil {
+		writeAuthError(w, err)
+		return
+	}
 
 	// Reject connections to disabled profiles
 	if profCfg := prof.ToConfig(); profCfg.Disabled {
@@ -45,6 +59,14 @@ func (s *server) handleProfileMCP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// In distributed mode, hand requests for sessions created on another
+	// replica over to that replica.
+	if s.cluster != nil {
+		if sid := r.Header.Get("Mcp-Session-Id"); sid != "" && !streamable.HasSession(sid) && s.cluster.routeSession(w, r, sid) {
+			return
+		}
+	}
+
 	// Delegate to StreamableHTTPServer (implements http.Handler)
 	streamable.ServeHTTP(w, r)
 }
@@ -66,23 +88,37 @@ func (s *server) getOrCreateStreamable(ctx context.Context, prof profile) (*mcp.
 	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
+	var prev *registryCache
+	if s.cache != nil {
+		prev, _ = s.cache.peek(prof.Name)
+	}
 	cached, _, err := s.getOrBuildCache(ctx, prof)
 	if err != nil {
 		return nil, err
 	}
 
+	// After a profile edit, the endpoint and its sessions carry over to the
+	// rebuilt registry unless the profile's token changed.
+	if streamable, oldKey, ok := s.profileStreamable(prof.Name); ok && sameAuth(streamable.AuthConfig(), s.streamableAuth(prof)) {
+		streamable.Server().SetRegistry(cached.registry, cached.executor)
+		streamable.Server().SetMaxResponseBytesByAPI(apiResponseLimits(prof.ToConfig()))
+		streamable.Server().SetCapabilities(s.profileCapabilities(prof))
+		s.mcpServers.Delete(oldKey)
+		s.mcpServers.Store(cacheKey, streamable)
+		if prev == nil || !spec.DiffServices(prev.services, cached.services).Empty() {
+			streamable.NotifyToolsListChanged()
+		}
+		s.logger.Info("moved MCP sessions to the edited profile's registry", "profile", prof.Name)
+		return streamable, nil
+	}
+
 	// Create MCP server for this profile
 	mcpServer := mcp.NewServer(cached.registry, cached.executor, s.logger, s.redactor, Version)
+	mcpServer.SetProfile(prof.Name)
+	mcpServer.SetCapabilities(s.profileCapabilities(prof))
 
 	// Apply per-API response truncation limits
-	profCfg := prof.ToConfig()
-	apiLimits := make(map[string]int, len(profCfg.APIs))
-	for _, api := range profCfg.APIs {
-		if api.MaxResponseBytes != nil {
-			apiLimits[api.Name] = *api.MaxResponseBytes
-		}
-	}
-	mcpServer.SetMaxResponseBytesByAPI(apiLimits)
+	mcpServer.SetMaxResponseBytesByAPI(apiResponseLimits(prof.ToConfig()))
 
 	// Wire up audit logging + metrics for MCP tool calls
 	profileName := prof.Name
@@ -118,17 +154,33 @@ func (s *server) getOrCreateStreamable(ctx context.Context, prof profile) (*mcp.
 		s.metrics.RecordRequest(profileName, event.ToolName, event.Duration, event.Success)
 	})
 
-	// Create StreamableHTTPServer first so we can wire the subscribe hook
-	var authCfg *config.AuthConfig
-	if s.authMode == "bearer" && prof.Token != "" {
-		authCfg = &config.AuthConfig{
-			Type:  "bearer",
-			Token: prof.Token,
+	// registry/refresh re-fetches the profile's specs on demand.
+	mcpServer.SetRefreshHook(func(ctx context.Context) (any, error) {
+		s.mu.RLock()
+		current, ok := s.findProfile(profileName)
+		s.mu.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("profile %s no longer exists", profileName)
 		}
-	}
+		diff, err := s.reloadProfile(ctx, current)
+		if err != nil {
+			return nil, fmt.Errorf("refresh failed, keeping the current tools: %w", err)
+		}
+		if diff == nil {
+			// Edited or evicted since this session started; the next
+			// request builds the registry from scratch.
+			return map[string]any{"changed": false}, nil
+		}
+		return map[string]any{"changed": !diff.Empty(), "diff": diff}, nil
+	})
+
+	// Ask the user for new credentials when an upstream rejects them.
+	mcpServer.SetAuthRenewHook(func(ctx context.Context, apiName string, upErr *runtime.UpstreamError) (bool, string) {
+		return s.renewAPIAuth(ctx, profileName, apiName, upErr)
+	})
 
-	// Create StreamableHTTPServer
-	streamable := mcp.NewStreamableHTTPServer(mcpServer, s.logger, authCfg)
+	// Create StreamableHTTPServer first so we can wire the subscribe hook
+	streamable := mcp.NewStreamableHTTPServer(mcpServer, s.logger, s.streamableAuth(prof))
 
 	// Wire resource subscribe/unsubscribe to session tracking
 	mcpServer.SetSubscribeHook(func(sessionID, uri string, subscribe bool) bool {
@@ -138,9 +190,14 @@ func (s *server) getOrCreateStreamable(ctx context.Context, prof profile) (*mcp.
 		return streamable.UnsubscribeSession(sessionID, uri)
 	})
 
-	// Wire CORS allowed origins from server config
-	if s.serverCfg != nil && s.serverCfg.Security.CORS != nil && s.serverCfg.Security.CORS.Enabled {
-		streamable.AllowedOrigins = s.serverCfg.Security.CORS.Origins
+	s.configureMCPEndpoint(streamable)
+
+	streamable.ClientAddr = clientIP
+	if auth := streamable.AuthConfig(); auth != nil {
+		streamable.VerifyToken = profile{TokenHash: auth.Token}.checkToken
+	}
+	streamable.OnUnauthorized = func(r *http.Request) {
+		s.logAuthFailure(r, profileName)
 	}
 
 	// Wire OAuth validator for ChatGPT MCP compatibility
@@ -153,23 +210,34 @@ func (s *server) getOrCreateStreamable(ctx context.Context, prof profile) (*mcp.
 			return at.ProfileToken, true
 		}
 	}
+	s.configureMCPOIDC(streamable, profileName)
 
 	// Track MCP session lifecycle for active connection metrics + agent monitoring
 	streamable.SetSessionHook(func(event mcp.SessionEvent) {
 		event.Profile = profileName
-		s.logger.Info("MCP session event", "session_id", event.SessionID, "type", event.Type, "profile", profileName, "client_info", event.ClientInfo)
+		s.logger.Info("MCP session event", "session_id", event.SessionID, "type", event.Type, "profile", profileName, "client_info", event.ClientInfo, "reason", event.Reason)
 		if event.Type == "connected" {
-			s.sessionTracker.Register(event.SessionID, profileName, event.ClientInfo)
+			s.sessionTracker.Register(event, streamable)
 			s.metrics.RecordConnection(true)
+			if s.cluster != nil {
+				s.cluster.claimSession(event.SessionID)
+			}
 		} else {
 			s.sessionTracker.Unregister(event.SessionID)
 			s.metrics.RecordConnection(false)
+			if event.Reason == "idle" || event.Reason == "unresponsive" {
+				s.metrics.RecordReapedConnection(event.Reason)
+			}
+			if s.cluster != nil {
+				s.cluster.releaseSession(event.SessionID)
+			}
 		}
 		s.agentHub.Publish(map[string]any{
 			"type":        "session_" + event.Type,
 			"session_id":  event.SessionID,
 			"profile":     profileName,
 			"client_info": event.ClientInfo,
+			"reason":      event.Reason,
 			"timestamp":   time.Now(),
 		})
 	})
@@ -205,3 +273,80 @@ func (s *server) getOrCreateStreamable(ctx context.Context, prof profile) (*mcp.
 	s.logger.Info("created MCP Streamable HTTP server", "profile", prof.Name)
 	return streamable, nil
 }
+
+// profileStreamable returns the MCP endpoint cached for any version of the
+// profile's config, and its cache key.
+func (s *server) profileStreamable(name string) (*mcp.StreamableHTTPServer, string, bool) {
+	var found *mcp.StreamableHTTPServer
+	var foundKey string
+	s.mcpServers.Range(func(key, val any) bool {
+		if k, ok := key.(string); ok && strings.HasPrefix(k, name+":") {
+			found, foundKey = val.(*mcp.StreamableHTTPServer), k
+			return false
+		}
+		return true
+	})
+	return found, foundKey, found != nil
+}
+
+// streamableAuth returns the bearer auth a profile's MCP endpoint requires,
+// or nil when it is open. Its token is the stored hash, which changes with
+// the token; the endpoint's VerifyToken checks presented tokens against it.
+func (s *server) streamableAuth(prof profile) *config.AuthConfig {
+	if (s.authMode == "bearer" || tenantOf(prof.Name) != "") && prof.TokenHash != "" {
+		return &config.AuthConfig{
+			Type:  "bearer",
+			Token: prof.TokenHash,
+		}
+	}
+	return nil
+}
+
+func sameAuth(a, b *config.AuthConfig) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Type == b.Type && a.Token == b.Token
+}
+
+// apiResponseLimits returns the per-API max_response_bytes of cfg.
+func apiResponseLimits(cfg *config.Config) map[string]int {
+	limits := make(map[string]int, len(cfg.APIs))
+	for _, api := range cfg.APIs {
+		if api.MaxResponseBytes != nil {
+			limits[api.Name] = *api.MaxResponseBytes
+		}
+	}
+	return limits
+}
+
+// configureMCPEndpoint applies the CORS and security.mcp settings of the
+// server config to a profile's MCP endpoint.
+func (s *server) configureMCPEndpoint(streamable *mcp.StreamableHTTPServer) {
+	if s.serverCfg == nil {
+		return
+	}
+	if u, err := url.Parse(s.serverCfg.Server.PublicURL); err == nil && u.Hostname() != "" {
+		streamable.AllowedHosts = append(streamable.AllowedHosts, u.Hostname())
+	}
+	sec := s.serverCfg.Security
+	if sec.CORS != nil && sec.CORS.Enabled {
+		streamable.AllowedOrigins = append(streamable.AllowedOrigins, sec.CORS.Origins...)
+	}
+	if sec.MCP == nil {
+		return
+	}
+	streamable.AllowedOrigins = append(streamable.AllowedOrigins, sec.MCP.AllowedOrigins...)
+	streamable.QueryToken = sec.MCP.QueryToken
+	streamable.SetIdleTimeout(sec.MCP.IdleTimeout)
+	streamable.KeepaliveInterval = sec.MCP.KeepaliveInterval
+	streamable.PingInterval = sec.MCP.PingInterval
+	streamable.PingTimeout = sec.MCP.PingTimeout
+	if sec.MCP.MaxMessageSize != "" {
+		// Validated at startup.
+		streamable.MaxMessageSize, _ = serverconfig.ParseByteSize(sec.MCP.MaxMessageSize)
+	}
+	if sec.MCP.CompressMinSize != "" {
+		streamable.CompressMinSize, _ = serverconfig.ParseByteSize(sec.MCP.CompressMinSize)
+	}
+}
@@ -8,12 +8,23 @@ import (
 )
 
 // handleOAuthProtectedResource serves RFC 9728 Protected Resource Metadata.
+// With OIDC configured it names the IdP as the authorization server.
 // GET /.well-known/oauth-protected-resource
 func (s *server) handleOAuthProtectedResource(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
+	if s.oidc != nil {
+		cfg := s.oidc.Config()
+		writeJSON(w, http.StatusOK, map[string]any{
+			"resource":                 cfg.Audience,
+			"authorization_servers":    []string{cfg.Issuer},
+			"scopes_supported":         []string{cfg.MCPScope, cfg.AdminScope},
+			"bearer_methods_supported": []string{"header"},
+		})
+		return
+	}
 	issuer := "https://" + r.Host
 	writeJSON(w, http.StatusOK, map[string]any{
 		"resource":              issuer,
@@ -174,7 +185,7 @@ func (s *server) handleOAuthAuthorizePost(w http.ResponseWriter, r *http.Request
 	s.mu.RLock()
 	prof, ok := s.findProfile(profileName)
 	s.mu.RUnlock()
-	if !ok || prof.Token != profileToken {
+	if !ok || !prof.checkToken(profileToken) {
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
 		w.Write([]byte(renderConsentPageWithError(
 			client.Name, clientID, redirectURI, codeChallenge, codeChallengeMethod, state,
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -11,7 +12,12 @@ import (
 
 	"gopkg.in/yaml.v3"
 
+	"skyline-mcp/internal/canonical"
 	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/jobs"
+	"skyline-mcp/internal/mcp"
+	"skyline-mcp/internal/quota"
+	"skyline-mcp/internal/runtime"
 )
 
 // clientIP extracts the real client IP from the request, respecting
@@ -33,15 +39,28 @@ func clientIP(r *http.Request) string {
 func (s *server) handleProfiles(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
+		// Tenants see only their own profiles; other callers see only
+		// profiles outside any tenant.
+		scope, scoped := s.requestTenant(r)
+		admin := s.isAdminSession(r)
+		if s.adminLoginRequired() && !admin && !scoped {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
 		s.mu.RLock()
 		names := make([]string, 0, len(s.store.Profiles))
 		for _, p := range s.store.Profiles {
-			if p.Name != "" {
+			if p.Name != "" && (admin || tenantOf(p.Name) == scope) {
 				names = append(names, p.Name)
 			}
 		}
 		s.mu.RUnlock()
-		writeJSON(w, http.StatusOK, map[string]any{"profiles": names, "default": defaultProfileName})
+		resp := map[string]any{"profiles": names, "default": defaultProfileName}
+		if scope != "" {
+			resp["tenant"] = scope
+			delete(resp, "default")
+		}
+		writeJSON(w, http.StatusOK, resp)
 	default:
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 	}
@@ -57,10 +76,30 @@ func (s *server) handleProfileRoute(w http.ResponseWriter, r *http.Request) {
 		s.handleProfileExecute(w, r)
 		return
 	}
+	if strings.HasSuffix(path, "/code") {
+		s.handleProfileCode(w, r)
+		return
+	}
+	if strings.HasSuffix(path, "/spec-diff") {
+		s.handleProfileSpecDiff(w, r)
+		return
+	}
+	if strings.HasSuffix(path, "/lint") {
+		s.handleProfileLint(w, r)
+		return
+	}
 	if strings.HasSuffix(path, "/mcp") {
 		s.handleProfileMCP(w, r)
 		return
 	}
+	if strings.HasSuffix(path, "/clone") {
+		s.handleProfileClone(w, r)
+		return
+	}
+	if strings.HasSuffix(path, "/share") {
+		s.handleProfileShare(w, r)
+		return
+	}
 	s.handleProfile(w, r)
 }
 
@@ -80,8 +119,15 @@ func (s *server) handleProfile(w http.ResponseWriter, r *http.Request) {
 			http.NotFound(w, r)
 			return
 		}
-		if err := s.authorizeProfile(r, prof); err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+		// A signed share link stands in for the token; see handleProfileShare.
+		if r.URL.Query().Has("sig") {
+			if err := s.checkShareLink(r, prof); err != nil {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+			s.logger.Info("profile fetched with share link", "profile", prof.Name, "client", clientIP(r))
+		} else if err := s.authorizeProfile(r, prof); err != nil {
+			writeAuthError(w, err)
 			return
 		}
 		if strings.EqualFold(r.URL.Query().Get("format"), "json") {
@@ -90,11 +136,15 @@ func (s *server) handleProfile(w http.ResponseWriter, r *http.Request) {
 				http.Error(w, "invalid stored config", http.StatusInternalServerError)
 				return
 			}
-			writeJSON(w, http.StatusOK, map[string]any{
+			// Only the token's hash is stored, so it cannot be returned.
+			resp := map[string]any{
 				"name":   prof.Name,
-				"token":  prof.Token,
 				"config": cfg,
-			})
+			}
+			if vars := prof.Variables(); len(vars) > 0 {
+				resp["variables"] = vars
+			}
+			writeJSON(w, http.StatusOK, resp)
 			return
 		}
 		w.Header().Set("Content-Type", "text/yaml; charset=utf-8")
@@ -129,50 +179,71 @@ func (s *server) handleProfile(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, fmt.Sprintf("invalid config_yaml: %v", err), http.StatusBadRequest)
 			return
 		}
+		if err := s.checkProfileFiles(name, profile{ConfigYAML: req.ConfigYAML}.ToConfig()); err != nil {
+			http.Error(w, fmt.Sprintf("invalid config_yaml: %v", err), http.StatusBadRequest)
+			return
+		}
 
 		s.mu.Lock()
 		defer s.mu.Unlock()
 		existing, ok := s.findProfile(name)
-		if s.authMode == "bearer" && !s.isAdminSession(r) {
+		scope, scoped := s.requestTenant(r)
+		owner := tenantOf(name)
+		switch {
+		case s.isAdminSession(r):
+		case scoped:
+			if owner != scope {
+				http.Error(w, fmt.Sprintf("profile name must start with %q", scope+"/"), http.StatusForbidden)
+				return
+			}
+		case owner != "" && !ok:
+			http.Error(w, "creating a tenant profile requires the tenant token", http.StatusUnauthorized)
+			return
+		case !ok && s.adminLoginRequired():
+			http.Error(w, "creating a profile requires an admin session", http.StatusUnauthorized)
+			return
+		case s.authMode == "bearer" || owner != "" || s.adminLoginRequired():
 			token := bearerToken(r.Header.Get("Authorization"))
 			if ok {
-				if token == "" || token != existing.Token {
+				if !existing.checkToken(token) {
 					http.Error(w, "unauthorized", http.StatusUnauthorized)
 					return
 				}
 			} else {
-				if token == "" || token != req.Token {
+				if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(req.Token)) != 1 {
 					http.Error(w, "unauthorized", http.StatusUnauthorized)
 					return
 				}
 			}
 		}
-		if req.Token == "" {
-			if ok {
-				req.Token = existing.Token
-			} else {
-				http.Error(w, "token is required", http.StatusBadRequest)
-				return
-			}
+		if status, err := s.checkTenantProfileName(name); err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+		if req.Token == "" && !ok {
+			http.Error(w, "token is required", http.StatusBadRequest)
+			return
 		}
 		if ok {
-			existing.Token = req.Token
+			// An empty token keeps the current one.
+			if req.Token != "" {
+				existing.TokenHash = hashProfileToken(req.Token)
+			}
 			existing.ConfigYAML = req.ConfigYAML
 			s.updateProfile(existing)
 		} else {
 			s.store.Profiles = append(s.store.Profiles, profile{
 				Name:       name,
-				Token:      req.Token,
+				TokenHash:  hashProfileToken(req.Token),
 				ConfigYAML: req.ConfigYAML,
 			})
 		}
 		if err := s.save(); err != nil {
-			http.Error(w, "failed to persist", http.StatusInternalServerError)
+			s.persistFailed(w, err)
 			return
 		}
-		if s.cache != nil {
-			s.cache.evict(name)
-		}
+		// The cached entry is kept: it no longer matches the config, so the
+		// next request rebuilds it, reparsing only the APIs that changed.
 		writeJSON(w, http.StatusOK, map[string]any{"status": "ok"})
 	case http.MethodDelete:
 		if name == defaultProfileName {
@@ -187,38 +258,176 @@ func (s *server) handleProfile(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		if err := s.authorizeProfile(r, prof); err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			writeAuthError(w, err)
 			return
 		}
 		s.deleteProfile(name)
 		if err := s.save(); err != nil {
-			http.Error(w, "failed to persist", http.StatusInternalServerError)
+			s.persistFailed(w, err)
 			return
 		}
 		if s.cache != nil {
 			s.cache.evict(name)
 		}
+		s.httpClients.Forget(name)
+		s.oauth2Tokens.Forget(name)
 		writeJSON(w, http.StatusOK, map[string]any{"status": "ok"})
 	default:
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 	}
 }
 
+// checkTenantProfileName checks that a new profile name in a tenant names
+// an existing tenant and has the form <tenant>/<profile>. Callers hold
+// s.mu.
+func (s *server) checkTenantProfileName(name string) (int, error) {
+	owner := tenantOf(name)
+	if owner == "" {
+		return 0, nil
+	}
+	if _, exists := s.findTenant(owner); !exists {
+		return http.StatusNotFound, fmt.Errorf("unknown tenant %q", owner)
+	}
+	if rest := strings.TrimPrefix(name, owner+"/"); rest == "" || strings.Contains(rest, "/") {
+		return http.StatusBadRequest, errors.New("tenant profile names must be <tenant>/<profile>")
+	}
+	return 0, nil
+}
+
+// handleProfileClone creates a profile from the config of another. A
+// template profile's {{var.NAME}} placeholders are filled from variables,
+// so per-user profiles differ only in what the template leaves open. The
+// new profile's token is generated unless given, and returned.
+func (s *server) handleProfileClone(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	source := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/profiles/"), "/clone")
+	limitBody(w, r)
+	var req struct {
+		Name      string            `json:"name"`
+		Token     string            `json:"token"`
+		Variables map[string]string `json:"variables"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json body", http.StatusBadRequest)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	req.Token = strings.TrimSpace(req.Token)
+	if req.Name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	src, ok := s.findProfile(source)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if err := s.authorizeProfile(r, src); err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	if _, exists := s.findProfile(req.Name); exists {
+		http.Error(w, "profile already exists", http.StatusConflict)
+		return
+	}
+	// The clone may go where the caller could create a profile with PUT.
+	owner := tenantOf(req.Name)
+	scope, scoped := s.requestTenant(r)
+	switch {
+	case s.isAdminSession(r):
+	case scoped && owner != scope:
+		http.Error(w, fmt.Sprintf("profile name must start with %q", scope+"/"), http.StatusForbidden)
+		return
+	case !scoped && owner != "":
+		http.Error(w, "creating a tenant profile requires the tenant token", http.StatusUnauthorized)
+		return
+	case !scoped && s.adminLoginRequired():
+		http.Error(w, "creating a profile requires an admin session", http.StatusUnauthorized)
+		return
+	}
+	if status, err := s.checkTenantProfileName(req.Name); err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	configYAML, err := fillTemplate(src.ConfigYAML, req.Variables)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid variables: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err := s.checkProfileFiles(req.Name, profile{ConfigYAML: configYAML}.ToConfig()); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Token == "" {
+		req.Token = generateProfileToken()
+	}
+	s.store.Profiles = append(s.store.Profiles, profile{
+		Name:       req.Name,
+		TokenHash:  hashProfileToken(req.Token),
+		ConfigYAML: configYAML,
+	})
+	if err := s.save(); err != nil {
+		s.deleteProfile(req.Name)
+		s.persistFailed(w, err)
+		return
+	}
+	s.logger.Info("profile cloned", "profile", req.Name, "source", source, "client", clientIP(r))
+	writeJSON(w, http.StatusCreated, map[string]any{"name": req.Name, "token": req.Token})
+}
+
 func (s *server) authorizeProfile(r *http.Request, prof profile) error {
 	// Admin session bypasses per-profile token auth
 	if s.isAdminSession(r) {
 		return nil
 	}
-	if s.authMode != "bearer" {
+	// Locked-out clients are refused even with the right token, so
+	// guessing cannot go on during the lockout.
+	if err := s.checkLockout(r, prof.Name); err != nil {
+		return err
+	}
+	// A tenant token grants access to the tenant's profiles only.
+	if scope, ok := s.requestTenant(r); ok {
+		if tenantOf(prof.Name) != scope {
+			s.logAuthFailure(r, prof.Name)
+			return fmt.Errorf("unauthorized")
+		}
+		return nil
+	}
+	// Tenant profiles always require a token, whatever the auth mode, and
+	// so do all profiles once an admin password is set.
+	if s.authMode != "bearer" && tenantOf(prof.Name) == "" && !s.adminLoginRequired() {
 		return nil
 	}
-	token := bearerToken(r.Header.Get("Authorization"))
-	if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(prof.Token)) != 1 {
+	if !prof.checkToken(bearerToken(r.Header.Get("Authorization"))) {
+		s.logAuthFailure(r, prof.Name)
 		return fmt.Errorf("unauthorized")
 	}
+	s.lockout.Succeed(r.Context(), prof.Name, s.lockoutClient(r))
 	return nil
 }
 
+// logAuthFailure audits a request rejected for a profile, so repeated
+// failures from one client can raise an alert. Invalid tokens also count
+// towards a lockout; missing ones are not guesses.
+func (s *server) logAuthFailure(r *http.Request, profileName string) {
+	reason := "invalid token"
+	if bearerToken(r.Header.Get("Authorization")) == "" {
+		reason = "missing token"
+	} else {
+		s.countAuthFailure(r, profileName)
+	}
+	if s.auditLogger == nil {
+		return
+	}
+	s.auditLogger.LogError(profileName, "auth_failure", reason, clientIP(r))
+}
+
 func (s *server) handleProfileTools(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
@@ -241,7 +450,7 @@ func (s *server) handleProfileTools(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if err := s.authorizeProfile(r, prof); err != nil {
-		http.Error(w, err.Error(), http.StatusUnauthorized)
+		writeAuthError(w, err)
 		return
 	}
 
@@ -261,13 +470,25 @@ func (s *server) handleProfileTools(w http.ResponseWriter, r *http.Request) {
 			Name:         tool.Name,
 			Description:  tool.Description,
 			InputSchema:  tool.InputSchema,
-			OutputSchema: tool.OutputSchema,
+			OutputSchema: mcp.ToolOutputSchema(tool, cached.executor),
 		})
 	}
 
-	writeJSON(w, http.StatusOK, map[string]any{"tools": tools})
+	resp := map[string]any{"tools": tools}
+	if len(cached.loadErrors) > 0 {
+		resp["service_errors"] = cached.loadErrors
+	}
+	writeJSON(w, http.StatusOK, resp)
 }
 
+const (
+	// defaultExecuteTimeout bounds tools without a timeout of their own,
+	// such as workflows, on the execute endpoint.
+	defaultExecuteTimeout = 30 * time.Second
+	// executeGrace is the time left after a tool's timeout to report it.
+	executeGrace = 5 * time.Second
+)
+
 func (s *server) handleProfileExecute(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
@@ -291,7 +512,7 @@ func (s *server) handleProfileExecute(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if err := s.authorizeProfile(r, prof); err != nil {
-		http.Error(w, err.Error(), http.StatusUnauthorized)
+		writeAuthError(w, err)
 		return
 	}
 
@@ -313,10 +534,11 @@ func (s *server) handleProfileExecute(w http.ResponseWriter, r *http.Request) {
 	reqBytes, _ := json.Marshal(req.Arguments)
 	reqSize := int64(len(reqBytes))
 
-	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	ctx := r.Context()
+	loadCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	cached, _, err := s.getOrBuildCache(ctx, prof)
+	cached, _, err := s.getOrBuildCache(loadCtx, prof)
 	if err != nil {
 		errMsg := fmt.Sprintf("load services: %v", err)
 		s.auditLogger.LogExecute(ctx, name, "", req.ToolName, req.Arguments,
@@ -337,11 +559,67 @@ func (s *server) handleProfileExecute(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	execCtx, err := runtime.WithTimeoutArgument(ctx, req.Arguments)
+	if err != nil {
+		s.auditLogger.LogExecute(ctx, name, tool.Operation.ServiceName, req.ToolName, req.Arguments,
+			time.Since(startTime), http.StatusBadRequest, false, err.Error(), clientAddr, reqSize, 0)
+		s.metrics.RecordRequest(name, req.ToolName, time.Since(startTime), false)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	async, err := runtime.TakeAsyncArgument(req.Arguments)
+	if err != nil {
+		s.auditLogger.LogExecute(ctx, name, tool.Operation.ServiceName, req.ToolName, req.Arguments,
+			time.Since(startTime), http.StatusBadRequest, false, err.Error(), clientAddr, reqSize, 0)
+		s.metrics.RecordRequest(name, req.ToolName, time.Since(startTime), false)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := tool.ValidateArguments(req.Arguments); err != nil {
+		errMsg := s.redactor.Redact(err.Error())
+		s.auditLogger.LogExecute(ctx, name, tool.Operation.ServiceName, req.ToolName, req.Arguments,
+			time.Since(startTime), http.StatusBadRequest, false, errMsg, clientAddr, reqSize, 0)
+		s.metrics.RecordRequest(name, req.ToolName, time.Since(startTime), false)
+		writeJSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if async || req.Async {
+		s.startExecuteJob(execCtx, w, name, cached, tool.Operation, req, clientAddr, reqSize)
+		return
+	}
+
+	// Bound the call by the tool's own timeout and keep the connection
+	// writable for as long as the call may run.
+	timeout := cached.executor.ExecutionTimeout(execCtx, tool.Operation)
+	if timeout <= 0 {
+		timeout = defaultExecuteTimeout
+	}
+	execCtx, cancelExec := context.WithTimeout(execCtx, timeout+executeGrace)
+	defer cancelExec()
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(timeout + executeGrace))
+
 	// Execute the operation
-	result, err := cached.executor.Execute(ctx, tool.Operation, req.Arguments)
+	result, err := cached.executor.Execute(execCtx, tool.Operation, req.Arguments)
 	duration := time.Since(startTime)
 	if err != nil {
 		errMsg := fmt.Sprintf("execute: %v", err)
+		var upErr *runtime.UpstreamError
+		if errors.As(err, &upErr) {
+			s.auditLogger.LogExecute(ctx, name, tool.Operation.ServiceName, req.ToolName, req.Arguments,
+				duration, upErr.Status, false, errMsg, clientAddr, reqSize, 0)
+			s.metrics.RecordRequest(name, req.ToolName, duration, false)
+			writeJSON(w, upErr.Status, map[string]any{"error": upErr})
+			return
+		}
+		var quotaErr *quota.ExceededError
+		if errors.As(err, &quotaErr) {
+			s.auditLogger.LogExecute(ctx, name, tool.Operation.ServiceName, req.ToolName, req.Arguments,
+				duration, http.StatusTooManyRequests, false, errMsg, clientAddr, reqSize, 0)
+			s.metrics.RecordRequest(name, req.ToolName, duration, false)
+			writeJSON(w, http.StatusTooManyRequests, map[string]any{"error": quotaErr.Error(), "quota": quotaErr})
+			return
+		}
 		s.auditLogger.LogExecute(ctx, name, tool.Operation.ServiceName, req.ToolName, req.Arguments,
 			duration, 0, false, errMsg, clientAddr, reqSize, 0)
 		s.metrics.RecordRequest(name, req.ToolName, duration, false)
@@ -359,6 +637,29 @@ func (s *server) handleProfileExecute(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, result)
 }
 
+// startExecuteJob runs an execute request in the background and answers
+// 202 with the job; the call is audited when the job finishes.
+func (s *server) startExecuteJob(ctx context.Context, w http.ResponseWriter, name string, cached *registryCache, op *canonical.Operation, req executeRequest, clientAddr string, reqSize int64) {
+	job, err := cached.executor.StartJob(ctx, op, req.Arguments, "", func(job jobs.Job) {
+		duration := time.Duration(job.DurationMs) * time.Millisecond
+		status, resSize := 0, int64(0)
+		if result, ok := job.Result.(*runtime.Result); ok {
+			status = result.Status
+			resBytes, _ := json.Marshal(result)
+			resSize = int64(len(resBytes))
+		}
+		success := job.Status == jobs.StatusSucceeded
+		s.auditLogger.LogExecute(context.Background(), name, op.ServiceName, req.ToolName, req.Arguments,
+			duration, status, success, s.redactor.Redact(job.Error), clientAddr, reqSize, resSize)
+		s.metrics.RecordRequest(name, req.ToolName, duration, success)
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, job)
+}
+
 func bearerToken(header string) string {
 	header = strings.TrimSpace(header)
 	if header == "" {
@@ -379,3 +680,19 @@ func extractProfileName(path, prefix, suffix string) string {
 	path = strings.TrimSuffix(path, suffix)
 	return strings.TrimSpace(path)
 }
+
+// persistFailed answers a request whose change could not be saved, after
+// the handler has rolled back its in-memory change. When another instance
+// changed the same profile first, the store is reloaded so a retry applies
+// to the current version.
+func (s *server) persistFailed(w http.ResponseWriter, err error) {
+	if errors.Is(err, errStoreConflict) {
+		if err := s.reload(); err != nil {
+			s.logger.Error("reload profiles after a conflicting write failed", "error", err)
+		}
+		http.Error(w, "profile store was changed by another instance; retry", http.StatusConflict)
+		return
+	}
+	s.logger.Error("persist profiles failed", "error", err)
+	http.Error(w, "failed to persist", http.StatusInternalServerError)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestProfileClone(t *testing.T) {
+	s := newTestServer(t, nil)
+	putProfile(t, s, "crm", crmTemplate)
+	clone := func(source string, body any, opts ...requestOption) (int, map[string]any) {
+		w := call(t, s.handleProfileRoute, http.MethodPost, "/profiles/"+source+"/clone", body, opts...)
+		if w.Code != http.StatusCreated {
+			return w.Code, nil
+		}
+		return w.Code, decodeBody(t, w)
+	}
+
+	w := call(t, s.handleProfileRoute, http.MethodGet, "/profiles/crm?format=json", nil, withBearer("tok-crm"))
+	if vars := decodeBody(t, w)["variables"]; !reflect.DeepEqual(vars, []any{"api_key", "region"}) {
+		t.Errorf("template variables = %v", vars)
+	}
+	// Templates are not served.
+	tmpl, _ := s.findProfile("crm")
+	if _, _, err := s.getOrBuildCache(context.Background(), tmpl); err == nil || !strings.Contains(err.Error(), "is a template") {
+		t.Errorf("building the template: %v", err)
+	}
+
+	// The source profile's token is enough without an admin password.
+	vars := map[string]string{"region": "eu", "api_key": "sk-eu"}
+	code, body := clone("crm", map[string]any{"name": "crm-eu", "variables": vars}, withBearer("tok-crm"))
+	if code != http.StatusCreated || body["name"] != "crm-eu" || body["token"] == "" {
+		t.Fatalf("clone: %d %v", code, body)
+	}
+	if got := profileConfig(t, s, "crm-eu"); !strings.Contains(got, "https://eu.crm.example.com") || strings.Contains(got, "{{") {
+		t.Errorf("cloned config:\n%s", got)
+	}
+	token, _ := body["token"].(string)
+	if code := call(t, s.handleProfileRoute, http.MethodGet, "/profiles/crm-eu", nil, withBearer(token)).Code; code != http.StatusOK {
+		t.Errorf("reading the clone with its token: %d", code)
+	}
+	if code, body := clone("crm-eu", map[string]any{"name": "crm-eu-copy", "token": "chosen"}, asAdmin); code != http.StatusCreated || body["token"] != "chosen" {
+		t.Errorf("copying a profile with a token: %d %v", code, body)
+	}
+
+	for name, tc := range map[string]struct {
+		source string
+		body   any
+		opts   []requestOption
+		code   int
+	}{
+		"invalid body":      {"crm", "{", []requestOption{asAdmin}, http.StatusBadRequest},
+		"no name":           {"crm", map[string]any{"name": " ", "variables": vars}, []requestOption{asAdmin}, http.StatusBadRequest},
+		"unknown source":    {"nope", map[string]any{"name": "x"}, []requestOption{asAdmin}, http.StatusNotFound},
+		"no access":         {"crm", map[string]any{"name": "x", "variables": vars}, nil, http.StatusUnauthorized},
+		"wrong token":       {"crm", map[string]any{"name": "x", "variables": vars}, []requestOption{withBearer("tok-other")}, http.StatusUnauthorized},
+		"name taken":        {"crm", map[string]any{"name": "crm-eu", "variables": vars}, []requestOption{asAdmin}, http.StatusConflict},
+		"missing variables": {"crm", map[string]any{"name": "x", "variables": map[string]string{"region": "us"}}, []requestOption{asAdmin}, http.StatusBadRequest},
+		"unknown tenant":    {"crm", map[string]any{"name": "nope/x", "variables": vars}, []requestOption{asAdmin}, http.StatusNotFound},
+	} {
+		if code, _ := clone(tc.source, tc.body, tc.opts...); code != tc.code {
+			t.Errorf("%s: %d, want %d", name, code, tc.code)
+		}
+	}
+	if code := call(t, s.handleProfileRoute, http.MethodGet, "/profiles/crm/clone", nil, asAdmin).Code; code != http.StatusMethodNotAllowed {
+		t.Errorf("GET: %d", code)
+	}
+	if got := listProfiles(t, s, asAdmin); len(got) != 4 {
+		t.Errorf("failed clones saved profiles: %v", got)
+	}
+}
+
+func TestProfileCloneInTenant(t *testing.T) {
+	s := newTestServer(t, nil)
+	acme := createTenant(t, s, "acme")
+	createTenant(t, s, "globex")
+	putProfile(t, s, "acme/crm", crmTemplate)
+	putProfile(t, s, "crm", crmTemplate)
+	vars := map[string]string{"region": "eu", "api_key": "sk-eu"}
+	clone := func(source, name string, opts ...requestOption) int {
+		body := map[string]any{"name": name, "variables": vars}
+		return call(t, s.handleProfileRoute, http.MethodPost, "/profiles/"+source+"/clone", body, opts...).Code
+	}
+
+	if code := clone("acme%2Fcrm", "acme/alice", withBearer(acme)); code != http.StatusCreated {
+		t.Errorf("clone within the tenant: %d", code)
+	}
+	for name, tc := range map[string]struct {
+		source, name string
+		opts         []requestOption
+		code         int
+	}{
+		"into another tenant":     {"acme%2Fcrm", "globex/bob", []requestOption{withBearer(acme)}, http.StatusForbidden},
+		"out of the tenant":       {"acme%2Fcrm", "bob", []requestOption{withBearer(acme)}, http.StatusForbidden},
+		"from outside the tenant": {"crm", "acme/bob", []requestOption{withBearer(acme)}, http.StatusUnauthorized},
+		"into a tenant":           {"crm", "acme/bob", []requestOption{withBearer("tok-crm")}, http.StatusUnauthorized},
+		"malformed tenant name":   {"crm", "acme/bob/x", []requestOption{asAdmin}, http.StatusBadRequest},
+	} {
+		if code := clone(tc.source, tc.name, tc.opts...); code != tc.code {
+			t.Errorf("%s: %d, want %d", name, code, tc.code)
+		}
+	}
+}
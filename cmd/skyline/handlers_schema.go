@@ -0,0 +1,41 @@
+package main
+
+import (
+	"io"
+	"net/http"
+
+	"skyline-mcp/internal/config"
+)
+
+// handleConfigSchema serves the JSON Schema for profile configs so UIs and CI
+// pipelines can validate a profile before uploading it.
+func (s *server) handleConfigSchema(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, config.JSONSchema())
+}
+
+// handleConfigValidate runs full semantic validation on a profile config
+// (YAML or JSON request body) and returns line/column-annotated diagnostics.
+func (s *server) handleConfigValidate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	limitBody(w, r)
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	diags := config.Diagnose(data)
+	if diags == nil {
+		diags = []config.Diagnostic{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"valid":       !config.HasErrors(diags),
+		"diagnostics": diags,
+	})
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"skyline-mcp/internal/spec"
+)
+
+// handleProfileSpecDiff re-fetches a profile's specs and compares them with
+// the services the profile is currently serving from the cache.
+// GET /profiles/{name}/spec-diff[?refresh=true]
+//
+// With refresh=true the cached registry is dropped after the comparison so
+// the next request serves the new specs.
+func (s *server) handleProfileSpecDiff(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := extractProfileName(r.URL.Path, "/profiles/", "/spec-diff")
+	if name == "" {
+		http.Error(w, "profile name required", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.RLock()
+	prof, ok := s.findProfile(name)
+	s.mu.RUnlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if err := s.authorizeProfile(r, prof); err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	if s.cache == nil {
+		http.Error(w, "spec diff needs the runtime cache (runtime.cache.enabled) to keep a snapshot", http.StatusConflict)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	snapshot, ok := s.cache.peek(prof.Name)
+	if !ok {
+		// Nothing served yet: take the snapshot now so the next call has a baseline.
+		entry, _, err := s.getOrBuildCache(ctx, prof)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("load services: %v", err), http.StatusBadGateway)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"profile":          prof.Name,
+			"snapshot_created": true,
+			"snapshot_at":      entry.createdAt,
+			"diff":             spec.DiffServices(entry.services, entry.services),
+		})
+		return
+	}
+
+	cfg, err := s.activeConfig(prof)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	current, err := spec.LoadServices(ctx, cfg, s.logger, s.redactor)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("load services: %v", err), http.StatusBadGateway)
+		return
+	}
+	diff := spec.DiffServices(snapshot.services, current)
+
+	refreshed := false
+	if strings.EqualFold(r.URL.Query().Get("refresh"), "true") && !diff.Empty() {
+		s.cache.evict(prof.Name)
+		refreshed = true
+	}
+
+	s.logger.Info("spec diff",
+		"component", "specdiff",
+		"profile", prof.Name,
+		"added", len(diff.Added),
+		"removed", len(diff.Removed),
+		"changed", len(diff.Changed),
+		"breaking", diff.Breaking,
+	)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"profile":     prof.Name,
+		"snapshot_at": snapshot.createdAt,
+		"checked_at":  time.Now(),
+		"refreshed":   refreshed,
+		"diff":        diff,
+	})
+}
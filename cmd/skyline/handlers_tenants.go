@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type tenantInfo struct {
+	Name      string    `json:"name"`
+	Profiles  int       `json:"profiles"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// handleTenants lists tenants (GET) and creates one (POST). The new
+// tenant's token is returned once and cannot be read back later.
+func (s *server) handleTenants(w http.ResponseWriter, r *http.Request) {
+	if !s.isAdminSession(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		s.mu.RLock()
+		tenants := make([]tenantInfo, 0, len(s.store.Tenants))
+		for _, t := range s.store.Tenants {
+			info := tenantInfo{Name: t.Name, CreatedAt: t.CreatedAt}
+			for _, p := range s.store.Profiles {
+				if tenantOf(p.Name) == t.Name {
+					info.Profiles++
+				}
+			}
+			tenants = append(tenants, info)
+		}
+		s.mu.RUnlock()
+		writeJSON(w, http.StatusOK, map[string]any{"tenants": tenants})
+	case http.MethodPost:
+		limitBody(w, r)
+		var req struct {
+			Name string `json:"name"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid json body", http.StatusBadRequest)
+			return
+		}
+		t, token, err := newTenant(strings.TrimSpace(req.Name), s.key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, exists := s.findTenant(t.Name); exists {
+			http.Error(w, "tenant already exists", http.StatusConflict)
+			return
+		}
+		s.store.Tenants = append(s.store.Tenants, t)
+		if err := s.save(); err != nil {
+			s.store.Tenants = s.store.Tenants[:len(s.store.Tenants)-1]
+			s.persistFailed(w, err)
+			return
+		}
+		s.tenants.reset(s.store.Tenants)
+		s.logger.Info("tenant created", "tenant", t.Name, "client", clientIP(r))
+		writeJSON(w, http.StatusCreated, map[string]any{
+			"name":       t.Name,
+			"token":      token,
+			"created_at": t.CreatedAt,
+		})
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleTenant deletes a tenant with all of its profiles. Its data key is
+// discarded, so backups of the store no longer decrypt its profiles.
+func (s *server) handleTenant(w http.ResponseWriter, r *http.Request) {
+	if !s.isAdminSession(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, "/admin/tenants/")
+	if name == "" || strings.Contains(name, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodDelete {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.findTenant(name); !ok {
+		http.Error(w, "tenant not found", http.StatusNotFound)
+		return
+	}
+	prev := s.store
+	next := profileStore{}
+	for _, t := range prev.Tenants {
+		if t.Name != name {
+			next.Tenants = append(next.Tenants, t)
+		}
+	}
+	var removed []string
+	for _, p := range prev.Profiles {
+		if tenantOf(p.Name) == name {
+			removed = append(removed, p.Name)
+			continue
+		}
+		next.Profiles = append(next.Profiles, p)
+	}
+	s.store = next
+	if err := s.save(); err != nil {
+		s.store = prev
+		s.persistFailed(w, err)
+		return
+	}
+	s.tenants.reset(s.store.Tenants)
+	for _, p := range removed {
+		if s.cache != nil {
+			s.cache.evict(p)
+		}
+		s.httpClients.Forget(p)
+		s.oauth2Tokens.Forget(p)
+	}
+	s.logger.Info("tenant deleted", "tenant", name, "profiles", len(removed), "client", clientIP(r))
+	w.WriteHeader(http.StatusNoContent)
+}
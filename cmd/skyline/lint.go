@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"skyline-mcp/internal/config"
+)
+
+// runLint implements `skyline lint`: it checks profile config files for
+// risky settings, such as write-capable APIs without a rate limit or secrets
+// written inline, and prints line/column-annotated findings. Errors that
+// make a config invalid are left to `skyline validate`.
+// Exit codes: 0 = no warnings, 1 = warnings (or info with --strict),
+// 2 = usage or I/O error.
+func runLint(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	format := fs.String("format", "text", "Output format: text, json")
+	strict := fs.Bool("strict", false, "Fail on info findings too")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: skyline lint [--format text|json] [--strict] <config-file>...\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(stderr, "unsupported format %q\n", *format)
+		return 2
+	}
+
+	type fileResult struct {
+		File     string              `json:"file"`
+		Passed   bool                `json:"passed"`
+		Findings []config.Diagnostic `json:"findings"`
+	}
+	results := make([]fileResult, 0, fs.NArg())
+	failed := false
+	for _, path := range fs.Args() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(stderr, "read %s: %v\n", path, err)
+			return 2
+		}
+		findings := config.Lint(data)
+		if findings == nil {
+			findings = []config.Diagnostic{}
+		}
+		passed := true
+		for _, d := range findings {
+			if d.Severity != config.SeverityInfo || *strict {
+				passed = false
+			}
+		}
+		if !passed {
+			failed = true
+		}
+		results = append(results, fileResult{File: path, Passed: passed, Findings: findings})
+	}
+
+	if *format == "json" {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(results)
+	} else {
+		for _, res := range results {
+			for _, d := range res.Findings {
+				sep := " "
+				if d.Line > 0 {
+					sep = ""
+				}
+				fmt.Fprintf(stdout, "%s:%s%s\n", res.File, sep, d)
+			}
+			if len(res.Findings) == 0 {
+				fmt.Fprintf(stdout, "%s: ok\n", res.File)
+			}
+		}
+	}
+
+	if failed {
+		return 1
+	}
+	return 0
+}
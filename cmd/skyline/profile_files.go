@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/serverconfig"
+)
+
+// checkProfileFiles refuses a stored profile config that names files on
+// the server's host outside the directory set aside for it: the
+// server.profileFilesDir directory, or <dir>/<tenant> for tenant profiles.
+// Without that setting stored profiles cannot use local files, since
+// anyone allowed to save a profile could otherwise read the server's keys
+// or overwrite its databases.
+func (s *server) checkProfileFiles(name string, cfg *config.Config) error {
+	paths := cfg.LocalPaths()
+	if len(paths) == 0 {
+		return nil
+	}
+	var dir string
+	if s.serverCfg != nil {
+		dir = s.serverCfg.Server.ProfileFilesDir
+	}
+	if dir == "" {
+		return fmt.Errorf("%s: stored profiles cannot use local files unless server.profileFilesDir is set", paths[0].Field)
+	}
+	root, err := profileFilesRoot(dir, tenantOf(name))
+	if err != nil {
+		return err
+	}
+	for _, p := range paths {
+		if !filepath.IsAbs(p.Path) {
+			return fmt.Errorf("%s: %q must be an absolute path under %s", p.Field, p.Path, root)
+		}
+		if !withinDir(root, resolvePath(filepath.Clean(p.Path))) {
+			return fmt.Errorf("%s: %q is outside %s", p.Field, p.Path, root)
+		}
+	}
+	return nil
+}
+
+// profileFilesRoot returns the directory a profile's files must be in.
+func profileFilesRoot(dir, tenant string) (string, error) {
+	dir, err := serverconfig.ExpandPath(dir)
+	if err != nil {
+		return "", fmt.Errorf("server.profileFilesDir: %w", err)
+	}
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("server.profileFilesDir: %w", err)
+	}
+	if tenant != "" {
+		dir = filepath.Join(dir, tenant)
+	}
+	return resolvePath(dir), nil
+}
+
+// resolvePath follows the symlinks in the deepest part of path that exists,
+// so a link inside the directory cannot point a profile outside it.
+func resolvePath(path string) string {
+	rest := ""
+	for dir := path; ; dir = filepath.Dir(dir) {
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+			return filepath.Join(resolved, rest)
+		} else if !os.IsNotExist(err) || filepath.Dir(dir) == dir {
+			return path
+		}
+		rest = filepath.Join(filepath.Base(dir), rest)
+	}
+}
+
+// withinDir reports whether path is dir or below it.
+func withinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// localFileConfigs returns profile configs that make the server read or
+// write path.
+func localFileConfigs(path string) map[string]string {
+	return map[string]string{
+		"sqlite dsn": fmt.Sprintf(`apis:
+  - name: shop
+    spec_type: sql
+    database:
+      driver: sqlite
+      dsn: file:%s?_busy_timeout=5000`, path),
+		"ssh key files": fmt.Sprintf(`apis:
+  - name: ops
+    spec_type: ssh
+    ssh:
+      user: deploy
+      private_key_file: %[1]s
+      known_hosts_file: %[1]s
+      hosts: [{name: web, address: 10.0.0.11}]
+      commands: [{name: uptime, command: uptime}]`, path),
+		"recording dir": fmt.Sprintf(`apis:
+  - name: petstore
+    spec_url: https://petstore.example.com/openapi.json
+recording:
+  mode: record
+  dir: %s`, path),
+	}
+}
+
+func TestTenantProfileLocalFiles(t *testing.T) {
+	s := newTestServer(t, nil)
+	acme := createTenant(t, s, "acme")
+	createTenant(t, s, "globex")
+	put := func(name, configYAML string) int {
+		body := map[string]string{"token": "tok", "config_yaml": configYAML}
+		return call(t, s.handleProfileRoute, http.MethodPut, "/profiles/"+name, body, withBearer(acme)).Code
+	}
+
+	// Without server.profileFilesDir, no stored profile names local files.
+	for name, configYAML := range localFileConfigs("/etc/skyline/server.key") {
+		if code := put("acme%2Fshop", configYAML); code != http.StatusBadRequest {
+			t.Errorf("%s: %d", name, code)
+		}
+	}
+
+	dir := t.TempDir()
+	s.serverCfg.Server.ProfileFilesDir = dir
+	for name, tc := range map[string]struct {
+		path string
+		code int
+	}{
+		"in the tenant directory":      {filepath.Join(dir, "acme", "shop.db"), http.StatusOK},
+		"in another tenant directory":  {filepath.Join(dir, "globex", "shop.db"), http.StatusBadRequest},
+		"in the shared directory":      {filepath.Join(dir, "shop.db"), http.StatusBadRequest},
+		"escaping with ..":             {filepath.Join(dir, "acme", "..", "globex", "shop.db"), http.StatusBadRequest},
+		"relative to the working dir":  {"shop.db", http.StatusBadRequest},
+		"in the server's own settings": {"/etc/skyline/server.key", http.StatusBadRequest},
+	} {
+		for kind, configYAML := range localFileConfigs(tc.path) {
+			if code := put("acme%2Fshop", configYAML); code != tc.code {
+				t.Errorf("%s, %s: %d, want %d", kind, name, code, tc.code)
+			}
+		}
+	}
+
+	// A symlink in the tenant directory does not lead out of it.
+	if err := os.MkdirAll(filepath.Join(dir, "acme"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("/etc", filepath.Join(dir, "acme", "etc")); err != nil {
+		t.Fatal(err)
+	}
+	if code := put("acme%2Fshop", localFileConfigs(filepath.Join(dir, "acme", "etc", "passwd"))["sqlite dsn"]); code != http.StatusBadRequest {
+		t.Errorf("through a symlink: %d", code)
+	}
+}
+
+func TestStoredProfileLocalFiles(t *testing.T) {
+	s := newTestServer(t, nil)
+	dir := t.TempDir()
+	s.serverCfg.Server.ProfileFilesDir = dir
+	outside := localFileConfigs("/var/lib/skyline/profiles.db")["sqlite dsn"]
+
+	// Untenanted profiles are limited to the directory itself.
+	putProfile(t, s, "shop", localFileConfigs(filepath.Join(dir, "shop.db"))["sqlite dsn"])
+	body := map[string]string{"token": "tok", "config_yaml": outside}
+	if code := call(t, s.handleProfileRoute, http.MethodPut, "/profiles/shop", body, asAdmin).Code; code != http.StatusBadRequest {
+		t.Errorf("admin put outside the directory: %d", code)
+	}
+	w := call(t, s.handleProfilesImport, http.MethodPost, "/admin/profiles/import", map[string]any{
+		"profiles": []map[string]string{{"name": "imported", "config_yaml": outside}},
+	}, asAdmin)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "is outside") {
+		t.Errorf("import: %d %s", w.Code, w.Body)
+	}
+	putProfile(t, s, "ops", petstoreConfig)
+	w = call(t, s.handleProfilesPatch, http.MethodPost, "/admin/profiles/patch", map[string]any{
+		"api": "petstore", "set": map[string]any{"spec_file": "/etc/shadow"},
+	}, asAdmin)
+	if w.Code != http.StatusBadRequest || profileConfig(t, s, "ops") != petstoreConfig {
+		t.Errorf("patch: %d %s", w.Code, w.Body)
+	}
+
+	// Clones are checked after their variables are filled in.
+	putProfile(t, s, "shop-template", localFileConfigs(filepath.Join(dir, "{{var.db}}"))["sqlite dsn"])
+	clone := func(name, db string) int {
+		body := map[string]any{"name": name, "variables": map[string]string{"db": db}}
+		return call(t, s.handleProfileRoute, http.MethodPost, "/profiles/shop-template/clone", body, asAdmin).Code
+	}
+	if code := clone("shop-eu", "eu.db"); code != http.StatusCreated {
+		t.Errorf("clone inside the directory: %d", code)
+	}
+	if code := clone("shop-etc", "../../etc/passwd"); code != http.StatusBadRequest {
+		t.Errorf("clone outside the directory: %d", code)
+	}
+
+	// A profile stored before the directory was set up does not load.
+	s.store.Profiles = append(s.store.Profiles, profile{Name: "legacy", ConfigYAML: outside})
+	legacy, _ := s.findProfile("legacy")
+	if _, _, err := s.getOrBuildCache(context.Background(), legacy); err == nil || !strings.Contains(err.Error(), "is outside") {
+		t.Errorf("building a stored profile: %v", err)
+	}
+}
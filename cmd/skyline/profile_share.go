@@ -0,0 +1,187 @@
+package main
+
+import (
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultShareTTL = 15 * time.Minute
+	maxShareTTL     = 24 * time.Hour
+)
+
+// usedShareLinks remembers the nonces of share links already fetched, until
+// they expire, so each link works once. Like admin session revocation it is
+// local: in distributed mode a link works once per replica.
+type usedShareLinks struct {
+	mu   sync.Mutex
+	used map[string]time.Time
+}
+
+// use marks a nonce used and reports whether it was unused.
+func (u *usedShareLinks) use(nonce string, expires time.Time) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	now := time.Now()
+	for n, exp := range u.used {
+		if !now.Before(exp) {
+			delete(u.used, n)
+		}
+	}
+	if _, ok := u.used[nonce]; ok {
+		return false
+	}
+	if u.used == nil {
+		u.used = map[string]time.Time{}
+	}
+	u.used[nonce] = expires
+	return true
+}
+
+// shareLinkKeyLabel is the HKDF info that derives the share link signing
+// key from the server key, so share link signatures cannot stand in for any
+// other MAC made with the server key.
+const shareLinkKeyLabel = "skyline profile share links v1"
+
+// signShareLink returns the signature of a share link. It covers the
+// profile's token hash too, so changing the token voids outstanding links.
+func (s *server) signShareLink(prof profile, expires, nonce string) string {
+	key, err := hkdf.Key(sha256.New, s.key, nil, shareLinkKeyLabel, sha256.Size)
+	if err != nil {
+		// Only reachable with an absurd key length; no link verifies.
+		return ""
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("skyline profile share\x00" + prof.Name + "\x00" + expires + "\x00" + nonce + "\x00" + prof.TokenHash))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// checkShareLink verifies the expires, nonce and sig query parameters of a
+// GET /profiles/{name} request and uses up the link.
+func (s *server) checkShareLink(r *http.Request, prof profile) error {
+	q := r.URL.Query()
+	expires, nonce := q.Get("expires"), q.Get("nonce")
+	sig := s.signShareLink(prof, expires, nonce)
+	if sig == "" || !hmac.Equal([]byte(q.Get("sig")), []byte(sig)) {
+		return errors.New("invalid link")
+	}
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return errors.New("invalid link")
+	}
+	exp := time.Unix(unix, 0)
+	if !time.Now().Before(exp) {
+		return errors.New("link expired")
+	}
+	if !s.shareLinks.use(nonce, exp) {
+		return errors.New("link already used")
+	}
+	return nil
+}
+
+// handleProfileShare mints a signed, single-use link that fetches the
+// profile's config without its token.
+// POST /profiles/{name}/share {"ttl": "15m"}
+func (s *server) handleProfileShare(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/profiles/"), "/share")
+	limitBody(w, r)
+	var req struct {
+		TTL string `json:"ttl"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid json body", http.StatusBadRequest)
+			return
+		}
+	}
+	ttl := defaultShareTTL
+	if req.TTL != "" {
+		d, err := time.ParseDuration(req.TTL)
+		if err != nil || d <= 0 {
+			http.Error(w, "ttl must be a positive duration such as 15m", http.StatusBadRequest)
+			return
+		}
+		if d > maxShareTTL {
+			http.Error(w, fmt.Sprintf("ttl must be at most %s", maxShareTTL), http.StatusBadRequest)
+			return
+		}
+		ttl = d
+	}
+
+	s.mu.RLock()
+	prof, ok := s.findProfile(name)
+	s.mu.RUnlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if err := s.authorizeProfile(r, prof); err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	nonceRaw := make([]byte, 12)
+	if _, err := rand.Read(nonceRaw); err != nil {
+		http.Error(w, "failed to create link", http.StatusInternalServerError)
+		return
+	}
+	expiresAt := time.Now().Add(ttl).Truncate(time.Second)
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+	nonce := hex.EncodeToString(nonceRaw)
+	q := url.Values{}
+	q.Set("expires", expires)
+	q.Set("nonce", nonce)
+	q.Set("sig", s.signShareLink(prof, expires, nonce))
+	s.logger.Info("profile share link created", "profile", prof.Name, "expires_at", expiresAt, "client", clientIP(r))
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"url":        s.shareLinkURL(prof.Name, q),
+		"expires_at": expiresAt,
+	})
+}
+
+// shareLinkURL returns the link to a profile with query q. It starts with
+// server.publicURL, never the request's Host header, which the caller
+// controls; without a publicURL the link is relative to the server.
+func (s *server) shareLinkURL(name string, q url.Values) string {
+	path := "/profiles/" + url.PathEscape(name) + "?" + q.Encode()
+	if s.serverCfg == nil || s.serverCfg.Server.PublicURL == "" {
+		return path
+	}
+	return strings.TrimSuffix(s.serverCfg.Server.PublicURL, "/") + path
+}
+
+// checkPublicURL validates server.publicURL: an absolute http or https URL
+// without a query or fragment.
+func checkPublicURL(raw string) error {
+	if raw == "" {
+		return nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%q is not an absolute http or https URL", raw)
+	}
+	if u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return fmt.Errorf("%q must not have credentials, a query or a fragment", raw)
+	}
+	return nil
+}
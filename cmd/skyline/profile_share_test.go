@@ -0,0 +1,100 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// shareLink creates a profile and returns a share link to it.
+func shareLink(t *testing.T, s *server, name, token string, opts ...requestOption) string {
+	t.Helper()
+	body := map[string]string{"token": token, "config_yaml": "apis: []"}
+	if w := call(t, s.handleProfileRoute, http.MethodPut, "/profiles/"+name, body, opts...); w.Code != http.StatusOK {
+		t.Fatalf("create %s: %d %s", name, w.Code, w.Body)
+	}
+	w := call(t, s.handleProfileRoute, http.MethodPost, "/profiles/"+name+"/share", nil, append(opts, withBearer(token))...)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("share %s: %d %s", name, w.Code, w.Body)
+	}
+	return decodeBody(t, w)["url"].(string)
+}
+
+// fetch GETs a share link, relative or absolute, from s.
+func fetch(t *testing.T, s *server, link string) int {
+	t.Helper()
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return call(t, s.handleProfileRoute, http.MethodGet, u.RequestURI(), nil).Code
+}
+
+func TestShareLink(t *testing.T) {
+	s := newTestServer(t, nil)
+	s.serverCfg.Server.PublicURL = "https://skyline.example.com/"
+	spoofHost := func(r *http.Request) { r.Host = "evil.example.net" }
+
+	link := shareLink(t, s, "github", "github-token", asAdmin, spoofHost)
+	if !strings.HasPrefix(link, "https://skyline.example.com/profiles/github?") {
+		t.Fatalf("link %s does not start with the public URL", link)
+	}
+	if code := fetch(t, s, link); code != http.StatusOK {
+		t.Fatalf("first fetch: %d", code)
+	}
+	if code := fetch(t, s, link); code != http.StatusForbidden {
+		t.Errorf("second fetch: %d, want 403", code)
+	}
+
+	link = shareLink(t, s, "other", "other-token", asAdmin)
+	if code := fetch(t, s, strings.Replace(link, "expires=", "expires=9", 1)); code != http.StatusForbidden {
+		t.Errorf("fetch with a changed expiry: %d, want 403", code)
+	}
+}
+
+func TestShareLinkEscapesTenantProfiles(t *testing.T) {
+	s := newTestServer(t, nil)
+	acme := createTenant(t, s, "acme")
+	link := shareLink(t, s, "acme/ops", "acme-ops-token", withBearer(acme))
+	if !strings.HasPrefix(link, "/profiles/acme%2Fops?") {
+		t.Fatalf("link %s, want a relative link with the name escaped", link)
+	}
+	if code := fetch(t, s, link); code != http.StatusOK {
+		t.Errorf("fetch: %d", code)
+	}
+}
+
+func TestShareLinkKeyIsDerived(t *testing.T) {
+	s := newTestServer(t, nil)
+	prof := profile{Name: "github", TokenHash: "hash"}
+	got := s.signShareLink(prof, "1", "nonce")
+
+	raw := hmac.New(sha256.New, s.key)
+	raw.Write([]byte("skyline profile share\x00github\x001\x00nonce\x00hash"))
+	if got == "" || got == base64.RawURLEncoding.EncodeToString(raw.Sum(nil)) {
+		t.Errorf("share links are signed with the server key itself")
+	}
+	if s.signShareLink(profile{Name: "github", TokenHash: "rotated"}, "1", "nonce") == got {
+		t.Error("the signature does not cover the token hash")
+	}
+}
+
+func TestCheckPublicURL(t *testing.T) {
+	for raw, ok := range map[string]bool{
+		"":                                true,
+		"https://skyline.example.com":     true,
+		"http://10.0.0.5:8191/skyline/":   true,
+		"skyline.example.com":             false,
+		"ftp://skyline.example.com":       false,
+		"https://skyline.example.com/?a=": false,
+		"https://user:pw@skyline.example": false,
+	} {
+		if err := checkPublicURL(raw); (err == nil) != ok {
+			t.Errorf("checkPublicURL(%q) = %v", raw, err)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/mcp"
+	"skyline-mcp/internal/redact"
+	"skyline-mcp/internal/runtime"
+	"skyline-mcp/internal/spec"
+)
+
+// profileSource is where subcommands that call tools outside the server,
+// such as `skyline test` and `skyline bench`, read a profile from: a stored
+// profile named by --profile, or a config file argument.
+type profileSource struct {
+	profile *string
+	storage *string
+	key     *string
+	keyEnv  *string
+}
+
+func addProfileSourceFlags(fs *flag.FlagSet) *profileSource {
+	return &profileSource{
+		profile: fs.String("profile", "", "Stored profile to use instead of a config file"),
+		storage: fs.String("storage", "", "Encrypted profiles storage path (default: ~/.skyline/profiles.enc.yaml)"),
+		key:     fs.String("key", "", "Encryption key (overrides env var)"),
+		keyEnv:  fs.String("key-env", "SKYLINE_PROFILES_KEY", "Env var name containing encryption key"),
+	}
+}
+
+// valid reports whether exactly one of --profile and a config file is given.
+func (p *profileSource) valid(fs *flag.FlagSet) bool {
+	if *p.profile != "" {
+		return fs.NArg() == 0
+	}
+	return fs.NArg() == 1
+}
+
+// profileTools is a profile's registry and executor, built as the server
+// builds them.
+type profileTools struct {
+	name       string // profile name, or the config file's base name
+	cfg        *config.Config
+	registry   *mcp.Registry
+	executor   *runtime.Executor
+	loadErrors []canonical.LoadError
+}
+
+func (p *profileSource) load(ctx context.Context, fs *flag.FlagSet, stderr io.Writer) (*profileTools, error) {
+	tools := &profileTools{name: *p.profile}
+	if *p.profile != "" {
+		cfg, err := loadStoredProfileConfig(*p.profile, *p.storage, *p.key, *p.keyEnv)
+		if err != nil {
+			return nil, fmt.Errorf("load profile: %w", err)
+		}
+		tools.cfg = cfg
+	} else {
+		cfg, err := config.Load(fs.Arg(0))
+		if err != nil {
+			return nil, fmt.Errorf("load config: %w", err)
+		}
+		tools.cfg, tools.name = cfg, filepath.Base(fs.Arg(0))
+	}
+	cfg := tools.cfg
+	redactor := redact.NewRedactor()
+	redactor.AddSecrets(cfg.Secrets())
+	logger := slog.New(slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+	loadCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+	services, loadErrors, err := spec.LoadServicesWithErrors(loadCtx, cfg, logger, redactor)
+	if err != nil {
+		return nil, fmt.Errorf("load services: %w", err)
+	}
+	tools.loadErrors = loadErrors
+	tools.registry, err = mcp.NewRegistryWithOptions(services, mcp.RegistryOptions{Examples: cfg.ToolExamples, Aliases: cfg.ToolAliases, Overrides: cfg.ToolOverrides})
+	if err != nil {
+		return nil, fmt.Errorf("build registry: %w", err)
+	}
+	tools.executor, err = runtime.NewExecutor(cfg, services, logger, redactor)
+	if err != nil {
+		return nil, fmt.Errorf("create executor: %w", err)
+	}
+	return tools, nil
+}
+
+// loadStoredProfileConfig decrypts the profiles file and returns the config
+// of one profile, with its disabled APIs left out as the server does.
+func loadStoredProfileConfig(name, storagePath, keyFlag, keyEnv string) (*config.Config, error) {
+	if storagePath == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		storagePath = filepath.Join(home, ".skyline", "profiles.enc.yaml")
+	}
+	keyRaw := keyFlag
+	if keyRaw == "" {
+		keyRaw = os.Getenv(keyEnv)
+	}
+	if keyRaw == "" {
+		return nil, fmt.Errorf("encryption key not provided; use --key or set %s", keyEnv)
+	}
+	key, err := decodeKey(keyRaw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	store, err := (&fileStorage{path: storagePath, key: key}).Load()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", storagePath, err)
+	}
+	for _, prof := range store.Profiles {
+		if prof.Name != name {
+			continue
+		}
+		if prof.Sealed != nil {
+			return nil, fmt.Errorf("profile %q belongs to a tenant; use its config file instead", name)
+		}
+		if vars := prof.Variables(); len(vars) > 0 {
+			return nil, fmt.Errorf("profile %q is a template", name)
+		}
+		cfg := prof.ToConfig()
+		active := cfg.APIs[:0]
+		for _, api := range cfg.APIs {
+			if !api.Disabled {
+				active = append(active, api)
+			}
+		}
+		cfg.APIs = active
+		return cfg, nil
+	}
+	return nil, fmt.Errorf("profile %q not found in %s", name, storagePath)
+}
@@ -2,10 +2,14 @@ package main
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"os"
-	"path/filepath"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 
@@ -24,13 +28,71 @@ func generateProfileToken() string {
 	return hex.EncodeToString(b)
 }
 
+// hashProfileToken returns the salted hash a profile token is stored as,
+// in the form "sha256$<salt>$<digest>". Tokens are random, so a fast hash
+// is enough; the salt keeps equal tokens from having equal hashes.
+func hashProfileToken(token string) string {
+	salt := make([]byte, 16)
+	_, _ = rand.Read(salt)
+	return "sha256$" + hex.EncodeToString(salt) + "$" + hex.EncodeToString(saltedDigest(salt, token))
+}
+
+func saltedDigest(salt []byte, token string) []byte {
+	h := sha256.New()
+	h.Write(salt)
+	h.Write([]byte(token))
+	return h.Sum(nil)
+}
+
+// checkToken reports whether token is the profile's token. The comparison
+// takes the same time wherever the digests differ.
+func (p profile) checkToken(token string) bool {
+	return checkTokenHash(p.TokenHash, token)
+}
+
+// checkTokenHash reports whether token matches a hash made by
+// hashProfileToken.
+func checkTokenHash(tokenHash, token string) bool {
+	algo, rest, _ := strings.Cut(tokenHash, "$")
+	saltHex, digestHex, ok := strings.Cut(rest, "$")
+	if algo != "sha256" || !ok || token == "" {
+		return false
+	}
+	salt, err := hex.DecodeString(saltHex)
+	if err != nil {
+		return false
+	}
+	digest, err := hex.DecodeString(digestHex)
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(saltedDigest(salt, token), digest) == 1
+}
+
+// hashTokens replaces the plaintext tokens of profiles saved by older
+// versions with hashes. It reports whether any profile changed.
+func hashTokens(profiles []profile) bool {
+	changed := false
+	for i := range profiles {
+		if profiles[i].Token != "" {
+			profiles[i].TokenHash = hashProfileToken(profiles[i].Token)
+			profiles[i].Token = ""
+			changed = true
+		}
+	}
+	return changed
+}
+
 // newDefaultProfile creates the seed "default" profile with an empty config.
-func newDefaultProfile() profile {
+// Only the token's hash is kept, so the token is returned for the caller to
+// show once.
+func newDefaultProfile() (profile, string) {
+	token := generateProfileToken()
 	return profile{
 		Name:       defaultProfileName,
-		Token:      generateProfileToken(),
+		TokenHash:  hashProfileToken(token),
 		ConfigYAML: "apis: []\n",
-	}
+	}, token
 }
 
 func (p profile) ToConfig() *config.Config {
@@ -69,57 +131,98 @@ func (s *server) deleteProfile(name string) {
 }
 
 func (s *server) load() error {
-	data, err := os.ReadFile(s.path)
-	if err != nil {
-		if os.IsNotExist(err) {
+	if err := s.reload(); err != nil {
+		if errors.Is(err, errStoreNotFound) {
+			def, token := newDefaultProfile()
 			s.store = profileStore{
-				Profiles: []profile{newDefaultProfile()},
+				Profiles: []profile{def},
+			}
+			if err := s.save(); err != nil {
+				return err
 			}
-			return s.save()
+			s.announceDefaultToken(token)
+			return nil
 		}
 		return err
 	}
-	var env envelope
-	if err := yaml.Unmarshal(data, &env); err != nil { //nolint:govet // intentional err shadow
-		return fmt.Errorf("parse storage: %w", err)
-	}
-	plain, err := decrypt(env, s.key)
-	if err != nil {
-		return fmt.Errorf("decryption failed (wrong key or corrupted data): %w", err)
-	}
-	var store profileStore
-	if err := yaml.Unmarshal(plain, &store); err != nil {
-		return fmt.Errorf("parse store: %w", err)
-	}
-	s.store = store
 
 	// Ensure the default profile exists (migration for pre-existing stores)
+	migrated := false
+	var defaultToken string
 	if _, ok := s.findProfile(defaultProfileName); !ok {
-		s.store.Profiles = append([]profile{newDefaultProfile()}, s.store.Profiles...)
-		return s.save()
+		var def profile
+		def, defaultToken = newDefaultProfile()
+		s.store.Profiles = append([]profile{def}, s.store.Profiles...)
+		migrated = true
+	}
+	for from, to := range renameUntenantedProfiles(&s.store) {
+		s.logger.Warn("renamed a profile whose name looked like a tenant profile; update its MCP clients",
+			"from", from, "to", to)
+		migrated = true
+	}
+	if hashTokens(s.store.Profiles) {
+		s.logger.Info("replaced stored profile tokens with hashes")
+		migrated = true
+	}
+	if rehashTenantTokens(s.store.Tenants) {
+		s.logger.Info("salted the stored tenant token hashes")
+		s.tenants.reset(s.store.Tenants)
+		migrated = true
+	}
+	if migrated {
+		if err := s.save(); err != nil {
+			return err
+		}
+	}
+	if defaultToken != "" {
+		s.announceDefaultToken(defaultToken)
 	}
 	return nil
 }
 
-func (s *server) save() error {
-	plain, err := yaml.Marshal(s.store)
-	if err != nil {
-		return err
+// announceDefaultToken prints the token of a newly created default profile
+// to stdout, once. It stays out of the logs, which are often shipped and
+// kept elsewhere.
+func (s *server) announceDefaultToken(token string) {
+	s.logger.Info("created the default profile; its token was printed to stdout and is not shown again", "profile", defaultProfileName)
+	out := s.stdout
+	if out == nil {
+		out = os.Stdout
 	}
-	env, err := encrypt(plain, s.key)
+	printDefaultToken(out, token)
+}
+
+func printDefaultToken(w io.Writer, token string) {
+	fmt.Fprintln(w, "")
+	fmt.Fprintf(w, "🔑 Token for the %q profile (shown once):\n", defaultProfileName)
+	fmt.Fprintln(w, "")
+	fmt.Fprintf(w, "    %s\n", token)
+	fmt.Fprintln(w, "")
+}
+
+// reload replaces the in-memory store with the one in storage.
+func (s *server) reload() error {
+	store, err := s.storage.Load()
 	if err != nil {
 		return err
 	}
-	data, err := yaml.Marshal(env)
-	if err != nil {
+	copies := sealedCopies{}
+	if store, err = openStore(store, s.key, copies); err != nil {
 		return err
 	}
-	tmp := s.path + ".tmp"
-	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
-		return err
+	s.store = store
+	s.sealed = copies
+	s.tenants.reset(store.Tenants)
+	return nil
+}
+
+func (s *server) save() error {
+	if s.sealed == nil {
+		s.sealed = sealedCopies{}
 	}
-	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+	sealed, err := sealStore(s.store, s.key, s.sealed)
+	if err != nil {
 		return err
 	}
-	return os.Rename(tmp, s.path)
+	return s.storage.Save(sealed)
 }
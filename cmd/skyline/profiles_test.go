@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var hexToken = regexp.MustCompile(`[0-9a-f]{32}`)
+
+func TestDefaultProfileTokenStaysOutOfLogs(t *testing.T) {
+	s := newTestServer(t, nil)
+	var logs, stdout bytes.Buffer
+	s.logger = slog.New(slog.NewTextHandler(&logs, nil))
+	s.stdout = &stdout
+
+	// A new store, and one saved before the default profile existed.
+	s.storage = &fileStorage{path: filepath.Join(t.TempDir(), "profiles.enc.yaml"), key: s.key}
+	if err := s.load(); err != nil {
+		t.Fatal(err)
+	}
+	s.deleteProfile(defaultProfileName)
+	if err := s.save(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.load(); err != nil {
+		t.Fatal(err)
+	}
+	def, ok := s.findProfile(defaultProfileName)
+	if !ok {
+		t.Fatal("the default profile was not recreated")
+	}
+	printed := hexToken.FindAllString(stdout.String(), -1)
+	if len(printed) != 2 || !def.checkToken(printed[1]) {
+		t.Errorf("stdout does not show the default profile's token:\n%s", stdout.String())
+	}
+	if strings.Count(logs.String(), "created the default profile") != 2 {
+		t.Errorf("logs do not report both default profiles:\n%s", logs.String())
+	}
+	if token := hexToken.FindString(logs.String()); token != "" {
+		t.Errorf("logs contain a token %s:\n%s", token, logs.String())
+	}
+
+	// An existing default profile is left alone.
+	logs.Reset()
+	stdout.Reset()
+	if err := s.load(); err != nil {
+		t.Fatal(err)
+	}
+	if logs.Len() != 0 || stdout.Len() != 0 {
+		t.Errorf("loading an up-to-date store logged:\n%s%s", logs.String(), stdout.String())
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// readinessCheckTimeout bounds each dependency check of /readyz.
+	readinessCheckTimeout = 2 * time.Second
+	// warmupConcurrency is how many profiles are built at once during warm-up.
+	warmupConcurrency = 4
+)
+
+// cacheWarmup tracks the startup pass that builds every profile's registry
+// so the first requests after a deploy do not pay for spec fetching.
+type cacheWarmup struct {
+	mu     sync.Mutex
+	done   bool
+	total  int
+	warmed int
+	failed []string
+}
+
+// warmCache builds the registries of all enabled profiles in the background.
+// Profiles whose specs cannot be loaded are recorded and retried on first
+// use as usual; they do not hold readiness back, because an upstream outage
+// should not take every replica out of rotation.
+func (s *server) warmCache(ctx context.Context) {
+	s.mu.RLock()
+	profiles := make([]profile, 0, len(s.store.Profiles))
+	for _, prof := range s.store.Profiles {
+		if !prof.ToConfig().Disabled && len(prof.Variables()) == 0 {
+			profiles = append(profiles, prof)
+		}
+	}
+	s.mu.RUnlock()
+
+	w := &cacheWarmup{total: len(profiles)}
+	s.warmup = w
+	go func() {
+		start := time.Now()
+		sem := make(chan struct{}, warmupConcurrency)
+		var wg sync.WaitGroup
+		for _, prof := range profiles {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(prof profile) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				buildCtx, cancel := context.WithTimeout(ctx, specRefreshTimeout)
+				defer cancel()
+				_, _, err := s.getOrBuildCache(buildCtx, prof)
+				w.mu.Lock()
+				defer w.mu.Unlock()
+				if err != nil {
+					w.failed = append(w.failed, prof.Name)
+					s.logger.Warn("cache warm-up failed", "component", "readiness", "profile", prof.Name, "error", err)
+					return
+				}
+				w.warmed++
+			}(prof)
+		}
+		wg.Wait()
+
+		w.mu.Lock()
+		w.done = true
+		sort.Strings(w.failed)
+		w.mu.Unlock()
+		s.logger.Info("cache warm-up complete", "component", "readiness",
+			"profiles", len(profiles), "failed", len(w.failed), "duration", time.Since(start).Round(time.Millisecond))
+	}()
+}
+
+// readinessCheck is one dependency's entry in the /readyz response.
+type readinessCheck struct {
+	Status string `json:"status"` // ok, pending, failed or disabled
+	Error  string `json:"error,omitempty"`
+	// Profiles check
+	Profiles *int `json:"profiles,omitempty"`
+	// Spec cache check
+	Warmed *int     `json:"warmed,omitempty"`
+	Total  *int     `json:"total,omitempty"`
+	Failed []string `json:"failed,omitempty"`
+}
+
+// handleLive answers liveness probes: the process is up and serving HTTP.
+// It checks no dependencies, so a failing database never gets the
+// process restarted.
+func (s *server) handleLive(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"status": "ok"})
+}
+
+// handleHealth is the original liveness endpoint, kept for existing probes.
+func (s *server) handleHealth(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("ok"))
+}
+
+// handleReady answers readiness probes. It reports 200 only when profiles
+// are decrypted and loaded, the audit database accepts writes and the spec
+// cache warm-up has finished, and 503 with the failing checks otherwise.
+func (s *server) handleReady(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]readinessCheck{
+		"profiles":   s.checkProfiles(),
+		"audit_db":   s.checkAuditDB(r.Context()),
+		"spec_cache": s.checkSpecCache(),
+	}
+	status, code := "ready", http.StatusOK
+	for _, check := range checks {
+		if check.Status == "pending" || check.Status == "failed" {
+			status, code = "not_ready", http.StatusServiceUnavailable
+		}
+	}
+	writeJSON(w, code, map[string]any{"status": status, "checks": checks})
+}
+
+func (s *server) checkProfiles() readinessCheck {
+	if !s.profilesLoaded.Load() {
+		return readinessCheck{Status: "pending", Error: "profiles not loaded"}
+	}
+	s.mu.RLock()
+	n := len(s.store.Profiles)
+	s.mu.RUnlock()
+	return readinessCheck{Status: "ok", Profiles: &n}
+}
+
+func (s *server) checkAuditDB(ctx context.Context) readinessCheck {
+	if s.auditLogger == nil {
+		return readinessCheck{Status: "disabled"}
+	}
+	ctx, cancel := context.WithTimeout(ctx, readinessCheckTimeout)
+	defer cancel()
+	if err := s.auditLogger.CheckWritable(ctx); err != nil {
+		return readinessCheck{Status: "failed", Error: err.Error()}
+	}
+	return readinessCheck{Status: "ok"}
+}
+
+func (s *server) checkSpecCache() readinessCheck {
+	w := s.warmup
+	if w == nil {
+		return readinessCheck{Status: "disabled"}
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	warmed, total := w.warmed, w.total
+	check := readinessCheck{Status: "ok", Warmed: &warmed, Total: &total, Failed: append([]string(nil), w.failed...)}
+	if !w.done {
+		check.Status = "pending"
+	}
+	return check
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"skyline-mcp/internal/audit"
+	"skyline-mcp/internal/redact"
+)
+
+// readyChecks calls /readyz and returns its status code and checks.
+func readyChecks(t *testing.T, s *server) (int, map[string]any) {
+	t.Helper()
+	w := call(t, s.handleReady, http.MethodGet, "/readyz", nil)
+	checks, _ := decodeBody(t, w)["checks"].(map[string]any)
+	return w.Code, checks
+}
+
+func checkStatus(checks map[string]any, name string) string {
+	check, _ := checks[name].(map[string]any)
+	status, _ := check["status"].(string)
+	return status
+}
+
+func TestLiveness(t *testing.T) {
+	s := newTestServer(t, nil)
+	// Liveness does not wait for the profiles, unlike readiness.
+	if w := call(t, s.handleLive, http.MethodGet, "/livez", nil); w.Code != http.StatusOK || decodeBody(t, w)["status"] != "ok" {
+		t.Errorf("/livez: %d %s", w.Code, w.Body)
+	}
+	if code, checks := readyChecks(t, s); code != http.StatusServiceUnavailable || checkStatus(checks, "profiles") != "pending" {
+		t.Errorf("/readyz before the profiles are loaded: %d %v", code, checks)
+	}
+}
+
+func TestReadinessAuditDB(t *testing.T) {
+	s := newTestServer(t, nil)
+	s.profilesLoaded.Store(true)
+	code, checks := readyChecks(t, s)
+	if code != http.StatusOK || checkStatus(checks, "audit_db") != "disabled" || checkStatus(checks, "spec_cache") != "disabled" {
+		t.Errorf("/readyz without audit or warm-up: %d %v", code, checks)
+	}
+	if profiles := checks["profiles"].(map[string]any)["profiles"]; profiles != float64(1) {
+		t.Errorf("profiles = %v", profiles)
+	}
+
+	logger, err := audit.NewLogger(filepath.Join(t.TempDir(), "audit.db"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.auditLogger = logger
+	if code, checks := readyChecks(t, s); code != http.StatusOK || checkStatus(checks, "audit_db") != "ok" {
+		t.Errorf("/readyz with a writable audit database: %d %v", code, checks)
+	}
+	// The probe row is rolled back.
+	if calls, err := logger.AddQuotaUsage(context.Background(), "", "", "", 0); err != nil || calls != 0 {
+		t.Errorf("the readiness probe left a row: %d %v", calls, err)
+	}
+
+	logger.Close()
+	code, checks = readyChecks(t, s)
+	if check := checks["audit_db"].(map[string]any); code != http.StatusServiceUnavailable || check["status"] != "failed" || check["error"] == "" {
+		t.Errorf("/readyz with a closed audit database: %d %v", code, checks)
+	}
+}
+
+func TestReadinessWaitsForWarmup(t *testing.T) {
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(detectSpec))
+	}))
+	defer upstream.Close()
+
+	s := newTestServer(t, nil)
+	s.profilesLoaded.Store(true)
+	s.cache = newProfileCache(time.Minute)
+	s.redactor = redact.NewRedactor()
+	putProfile(t, s, "pets", "apis:\n  - name: pets\n    spec_url: "+upstream.URL+"/openapi.json")
+	putProfile(t, s, "template", "apis:\n  - name: pets\n    spec_url: "+upstream.URL+"/openapi.json\n    base_url_override: https://{{ var.region }}.example.com")
+	putProfile(t, s, "down", "apis:\n  - name: pets\n    spec_url: http://127.0.0.1:1/openapi.json")
+	s.warmCache(context.Background())
+
+	code, checks := readyChecks(t, s)
+	if code != http.StatusServiceUnavailable || checkStatus(checks, "spec_cache") != "pending" {
+		t.Errorf("/readyz during warm-up: %d %v", code, checks)
+	}
+	close(release)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for checkStatus(checks, "spec_cache") != "ok" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		code, checks = readyChecks(t, s)
+	}
+	cache := checks["spec_cache"].(map[string]any)
+	// Templates are not warmed, and a profile whose spec cannot be loaded
+	// does not hold readiness back.
+	if code != http.StatusOK || cache["warmed"] != float64(2) || cache["total"] != float64(3) || !reflect.DeepEqual(cache["failed"], []any{"down"}) {
+		t.Errorf("/readyz after warm-up: %d %v", code, checks)
+	}
+	if _, ok := s.cache.peek("pets"); !ok {
+		t.Error("the warmed registry is not cached")
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"skyline-mcp/internal/mcp"
+	"skyline-mcp/internal/spec"
+)
+
+const (
+	// specRefreshTimeout bounds one profile's rebuild during a refresh pass.
+	specRefreshTimeout = 2 * time.Minute
+	// retiredExecutorGrace is how long a replaced executor stays open so tool
+	// calls that started before the swap can finish.
+	retiredExecutorGrace = 5 * time.Minute
+)
+
+// startSpecRefresh re-fetches specs for every profile that has a cached
+// registry every interval until ctx is cancelled. Profiles that were never
+// used are skipped; they are built from fresh specs on first use anyway.
+func (s *server) startSpecRefresh(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.refreshSpecs(ctx)
+			}
+		}
+	}()
+}
+
+// refreshSpecs runs one refresh pass over all profiles.
+func (s *server) refreshSpecs(ctx context.Context) {
+	s.mu.RLock()
+	profiles := append([]profile(nil), s.store.Profiles...)
+	s.mu.RUnlock()
+
+	for _, prof := range profiles {
+		if ctx.Err() != nil {
+			return
+		}
+		s.refreshProfile(ctx, prof)
+	}
+}
+
+// refreshProfile rebuilds one profile's registry from freshly fetched specs.
+// When the tool set changed, the new registry replaces the cached one, live
+// MCP sessions are switched over and notified with tools/list_changed.
+// Otherwise the existing entry (and its rate limit/breaker state) is kept.
+func (s *server) refreshProfile(ctx context.Context, prof profile) {
+	if _, err := s.reloadProfile(ctx, prof); err != nil {
+		s.logger.Warn("spec refresh failed, keeping cached registry", "component", "refresh", "profile", prof.Name, "error", err)
+	}
+}
+
+// reloadProfile does the work of refreshProfile and returns what changed.
+// The diff is nil when the profile has no current registry to compare
+// against.
+func (s *server) reloadProfile(ctx context.Context, prof profile) (*spec.SpecDiff, error) {
+	if prof.ToConfig().Disabled {
+		return nil, nil
+	}
+	hash := profileConfigHash(prof.ConfigYAML)
+	current, ok := s.cache.peek(prof.Name)
+	if !ok || current.configHash != hash {
+		return nil, nil // not built yet, or edited since: the next request rebuilds it
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, specRefreshTimeout)
+	defer cancel()
+	fresh, _, err := s.buildRegistryCacheFrom(ctx, prof, current, true)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := spec.DiffServices(current.services, fresh.services)
+	if diff.Empty() {
+		_ = fresh.executor.Close()
+		s.cache.touch(prof.Name)
+		s.logger.Debug("spec refresh: no changes", "component", "refresh", "profile", prof.Name)
+		return diff, nil
+	}
+
+	fresh.configHash = hash
+	s.cache.set(prof.Name, fresh)
+	if val, ok := s.mcpServers.Load(prof.Name + ":" + hash); ok {
+		streamable := val.(*mcp.StreamableHTTPServer)
+		streamable.Server().SetRegistry(fresh.registry, fresh.executor)
+		streamable.NotifyToolsListChanged()
+	}
+
+	s.logger.Info("spec refresh: tools changed",
+		"component", "refresh",
+		"profile", prof.Name,
+		"added", len(diff.Added),
+		"removed", len(diff.Removed),
+		"changed", len(diff.Changed),
+		"breaking", diff.Breaking,
+	)
+	return diff, nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// defaultMCPListen is the --mcp-listen address used with --serve-profile.
+const defaultMCPListen = "localhost:8192"
+
+// profileMCPHandler serves a single profile's MCP endpoint at /mcp, so
+// clients can be pointed at https://host:port/mcp without a profile path.
+// The probes are repeated here for deployments that only expose this port.
+func (s *server) profileMCPHandler(name string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mcp", func(w http.ResponseWriter, r *http.Request) {
+		s.serveProfileMCP(w, r, name)
+	})
+	mux.HandleFunc("/livez", s.handleLive)
+	mux.HandleFunc("/readyz", s.handleReady)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprintf(w, "Skyline MCP Server\n\nProfile: %s\nMCP Endpoint: /mcp\n", name)
+	})
+	return recoverMiddleware(logRequests(s.enforceAccess(mux), s.logger))
+}
+
+// newProfileMCPServer binds the --serve-profile listener. Like the control
+// plane it speaks TLS and redirects plain HTTP to HTTPS on the same port.
+func (s *server) newProfileMCPServer(name, addr string) (*http.Server, net.Listener, error) {
+	s.mu.RLock()
+	_, ok := s.findProfile(name)
+	s.mu.RUnlock()
+	if !ok {
+		return nil, nil, fmt.Errorf("profile %q not found", name)
+	}
+
+	tcpLn, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("listen: %w", err)
+	}
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           s.profileMCPHandler(name),
+		ReadTimeout:       15 * time.Second,
+		ReadHeaderTimeout: 5 * time.Second,
+		WriteTimeout:      15 * time.Second,
+		IdleTimeout:       60 * time.Second,
+		ErrorLog:          tlsHandshakeErrorLog(),
+	}
+	return srv, &tlsRedirectListener{Listener: tcpLn, httpsHost: addr}, nil
+}
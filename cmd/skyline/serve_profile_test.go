@@ -0,0 +1,107 @@
+package main
+
+import (
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"skyline-mcp/internal/redact"
+)
+
+func TestProfileMCPHandler(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(detectSpec))
+	}))
+	defer upstream.Close()
+	s := newTestServer(t, nil)
+	s.redactor = redact.NewRedactor()
+	putProfile(t, s, "ops", "apis:\n  - name: pets\n    spec_url: "+upstream.URL+"/openapi.json")
+	handler := s.profileMCPHandler("ops")
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+	if w := get("/"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Profile: ops") {
+		t.Errorf("/: %d %s", w.Code, w.Body)
+	}
+	if w := get("/profiles/ops/mcp"); w.Code != http.StatusNotFound {
+		t.Errorf("a control plane path: %d", w.Code)
+	}
+	if w := get("/livez"); w.Code != http.StatusOK {
+		t.Errorf("/livez: %d", w.Code)
+	}
+
+	post := func(token, sessionID, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(body))
+		r.Header.Set("Accept", "application/json, text/event-stream")
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		if sessionID != "" {
+			r.Header.Set("Mcp-Session-Id", sessionID)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+	const initialize = `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"clientInfo":{"name":"agent","version":"1.0"}}}`
+	if w := post("", "", initialize); w.Code != http.StatusUnauthorized {
+		t.Errorf("initialize without the profile's token: %d %s", w.Code, w.Body)
+	}
+	if w := post("tok-other", "", initialize); w.Code != http.StatusUnauthorized {
+		t.Errorf("initialize with another token: %d %s", w.Code, w.Body)
+	}
+	w := post("tok-ops", "", initialize)
+	sessionID := w.Header().Get("Mcp-Session-Id")
+	if sessionID == "" {
+		t.Fatalf("initialize returned no session: %d %s", w.Code, w.Body)
+	}
+	if w := post("tok-ops", sessionID, `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`); !strings.Contains(w.Body.String(), "listPets") {
+		t.Errorf("tools/list: %d %s", w.Code, w.Body)
+	}
+
+	// The profile is looked up per request.
+	s.deleteProfile("ops")
+	if w := post("tok-ops", "", initialize); w.Code != http.StatusNotFound {
+		t.Errorf("initialize after the profile was deleted: %d %s", w.Code, w.Body)
+	}
+}
+
+func TestNewProfileMCPServer(t *testing.T) {
+	s := newTestServer(t, nil)
+	if _, _, err := s.newProfileMCPServer("nope", "127.0.0.1:0"); err == nil || !strings.Contains(err.Error(), `profile "nope" not found`) {
+		t.Errorf("an unknown profile: %v", err)
+	}
+	srv, ln, err := s.newProfileMCPServer(defaultProfileName, "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	if srv.Handler == nil || srv.Addr != "127.0.0.1:0" {
+		t.Errorf("server = %+v", srv)
+	}
+}
+
+func TestServeProfileFlagsFromEnv(t *testing.T) {
+	fs := flag.NewFlagSet("skyline", flag.ContinueOnError)
+	serveProfile := fs.String("serve-profile", "", "")
+	mcpListen := fs.String("mcp-listen", defaultMCPListen, "")
+	for _, name := range envFlags {
+		if fs.Lookup(name) == nil {
+			fs.String(name, "", "")
+		}
+	}
+	env := map[string]string{"SKYLINE_SERVE_PROFILE": "github", "SKYLINE_MCP_LISTEN": "0.0.0.0:9000"}
+	lookup := func(name string) (string, bool) { v, ok := env[name]; return v, ok }
+	if err := applyFlagEnv(fs, lookup); err != nil {
+		t.Fatal(err)
+	}
+	if *serveProfile != "github" || *mcpListen != "0.0.0.0:9000" {
+		t.Errorf("--serve-profile %q --mcp-listen %q", *serveProfile, *mcpListen)
+	}
+}
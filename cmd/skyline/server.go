@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"crypto/ed25519"
 	"crypto/rand"
 	"embed"
 	"encoding/hex"
@@ -13,6 +14,7 @@ import (
 	"log/slog"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -20,15 +22,22 @@ import (
 
 	"golang.org/x/term"
 
+	"skyline-mcp/internal/accesspolicy"
+	"skyline-mcp/internal/alerting"
 	"skyline-mcp/internal/audit"
+	"skyline-mcp/internal/detect"
 	"skyline-mcp/internal/email"
+	"skyline-mcp/internal/lockout"
 	"skyline-mcp/internal/logging"
 	"skyline-mcp/internal/mcp"
 	"skyline-mcp/internal/metrics"
 	"skyline-mcp/internal/oauth"
+	"skyline-mcp/internal/oidc"
+	"skyline-mcp/internal/plugins"
 	"skyline-mcp/internal/polling"
 	"skyline-mcp/internal/ratelimit"
 	"skyline-mcp/internal/redact"
+	"skyline-mcp/internal/runtime"
 	"skyline-mcp/internal/serverconfig"
 )
 
@@ -40,7 +49,7 @@ func main() {
 	admin := flag.Bool("admin", true, "Enable Web UI and admin dashboard (only for http transport)")
 	bind := flag.String("bind", "localhost:8191", "Network interface and port to bind to (e.g., localhost:8191 or 0.0.0.0:8191)")
 	storagePath := flag.String("storage", "./profiles.enc.yaml", "Encrypted profiles storage path")
-	configPath := flag.String("config", "", "Server config.yaml path (default: ~/.skyline/config.yaml)")
+	configPath := flag.String("config", "", "Server config.yaml path (default: ~/.skyline/config.yaml); with --transport stdio, MCP config files or directories, comma-separated")
 	authMode := flag.String("auth-mode", "bearer", "Auth mode: none or bearer")
 	keyEnv := flag.String("key-env", "SKYLINE_PROFILES_KEY", "Env var name containing encryption key")
 	envFile := flag.String("env-file", "", "Optional env file to load before startup")
@@ -52,7 +61,13 @@ func main() {
 	logFormat := flag.String("log-format", "text", "Log output format: text, json")
 	logLevel := flag.String("log-level", "info", "Log level: debug, info, warn, error")
 	daemonFlag := flag.Bool("daemon", false, "Run as background daemon (internal, used by 'gateway start')")
+	serveProfile := flag.String("serve-profile", "", "Also serve this profile's MCP endpoint at /mcp on --mcp-listen")
+	mcpListen := flag.String("mcp-listen", defaultMCPListen, "Listen address for --serve-profile")
 	flag.Parse()
+	if err := applyFlagEnv(flag.CommandLine, os.LookupEnv); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid environment: %v\n", err)
+		os.Exit(2)
+	}
 
 	logger := logging.Setup(*logFormat, *logLevel)
 
@@ -64,7 +79,7 @@ func main() {
 
 	// Handle update command
 	if len(flag.Args()) > 0 && flag.Args()[0] == "update" {
-		if err := runUpdate(logger); err != nil {
+		if err := runUpdate(logger, flag.Args()[1:]); err != nil {
 			slog.Error("update failed", "error", err)
 			os.Exit(1)
 		}
@@ -80,6 +95,36 @@ func main() {
 		os.Exit(0)
 	}
 
+	// Handle validate command (profile config diagnostics / JSON Schema export)
+	if len(flag.Args()) > 0 && flag.Args()[0] == "validate" {
+		os.Exit(runValidateConfig(flag.Args()[1:], os.Stdout, os.Stderr))
+	}
+
+	// Handle lint command (best-practice findings for profile configs)
+	if len(flag.Args()) > 0 && flag.Args()[0] == "lint" {
+		os.Exit(runLint(flag.Args()[1:], os.Stdout, os.Stderr))
+	}
+
+	// Handle hash-password command (bcrypt hash for server.adminPasswordHash)
+	if len(flag.Args()) > 0 && flag.Args()[0] == "hash-password" {
+		os.Exit(runHashPassword(os.Stdin, os.Stdout, os.Stderr))
+	}
+
+	// Handle spec-diff command (compare upstream specs against a snapshot)
+	if len(flag.Args()) > 0 && flag.Args()[0] == "spec-diff" {
+		os.Exit(runSpecDiff(flag.Args()[1:], os.Stdout, os.Stderr))
+	}
+
+	// Handle test command (contract checks of a profile's read-only tools)
+	if len(flag.Args()) > 0 && flag.Args()[0] == "test" {
+		os.Exit(runContractTests(flag.Args()[1:], os.Stdout, os.Stderr))
+	}
+
+	// Handle bench command (load test of one tool through the executor)
+	if len(flag.Args()) > 0 && flag.Args()[0] == "bench" {
+		os.Exit(runBench(flag.Args()[1:], os.Stdout, os.Stderr))
+	}
+
 	// Handle --validate flag
 	if *validateFlag {
 		exitCode := runValidate(*storagePath, *keyFlag, *keyEnv, logger)
@@ -110,6 +155,11 @@ func main() {
 		}
 	}
 
+	if *serveProfile != "" && (*transport != "http" || *configPath != "") {
+		slog.Error("--serve-profile needs the profile-based HTTP server (no --transport stdio or --config)")
+		os.Exit(1)
+	}
+
 	// Handle STDIO transport mode early (before profile/encryption logic)
 	if *transport == "stdio" {
 		if err := runSTDIO(*configPath, logger); err != nil {
@@ -395,8 +445,25 @@ func main() {
 		}
 	}
 
-	// Check if profiles file exists at final path
-	profileExists := fileExists(profilesPath)
+	storage, err := newProfileStorage(serverCfg.Profiles, profilesPath, key)
+	if err != nil {
+		slog.Error("init profile storage failed", "error", err)
+		os.Exit(1)
+	}
+	defer storage.Close()
+
+	// First switch from the file backend: import the existing encrypted file
+	// so profiles carry over without a manual export.
+	if imported, err := importProfilesFile(storage, profilesPath, key); err != nil { //nolint:govet // intentional err shadow
+		slog.Error("import profiles file failed", "path", profilesPath, "error", err)
+		os.Exit(1)
+	} else if imported > 0 {
+		slog.Info("imported profiles into storage backend",
+			"from", profilesPath, "to", storage.Location(), "profiles", imported)
+	}
+
+	// Check if profiles already exist in the selected backend
+	profileExists := storage.Exists()
 
 	// Expand audit database path from config
 	auditDBPath := "./skyline-audit.db"
@@ -415,6 +482,21 @@ func main() {
 		os.Exit(1)
 	}
 	defer auditLogger.Close()
+	if serverCfg.Audit.SigningKey != "" {
+		seed, err := decodeKey(serverCfg.Audit.SigningKey) //nolint:govet // intentional err shadow
+		if err != nil {
+			slog.Error("invalid audit signing key", "error", err)
+			os.Exit(1)
+		}
+		if err := auditLogger.SetSigningKey(ed25519.NewKeyFromSeed(seed)); err != nil {
+			slog.Error("enable audit checkpoints failed", "error", err)
+			os.Exit(1)
+		}
+	}
+	if err := addAuditSinks(auditLogger, serverCfg.Audit.Sinks); err != nil {
+		slog.Error("invalid audit sink", "error", err)
+		os.Exit(1)
+	}
 
 	// Use persisted admin token from config, or generate and save one
 	adminToken := serverCfg.Server.AdminToken
@@ -433,12 +515,30 @@ func main() {
 		}
 	}
 
+	adminSessions, err := newAdminSessions(key, adminToken, serverCfg.Server)
+	if err != nil {
+		slog.Error("invalid admin login config", "error", err)
+		os.Exit(1)
+	}
+	var oidcVerifier *oidc.Verifier
+	if serverCfg.Security.OIDC != nil {
+		if oidcVerifier, err = oidc.New(*serverCfg.Security.OIDC, nil); err != nil {
+			slog.Error("invalid OIDC config", "error", err)
+			os.Exit(1)
+		}
+	}
+	access, err := accesspolicy.New(serverCfg.Security.Access)
+	if err != nil {
+		slog.Error("invalid access policy", "error", err)
+		os.Exit(1)
+	}
+
 	slog.Info("Skyline MCP Server starting",
 		"transport", *transport,
 		"admin", *admin,
 		"listen", listenAddr,
 		"config", serverConfigPath,
-		"profiles", profilesPath,
+		"profiles", storage.Location(),
 		"audit_db", auditDBPath,
 		"code_execution", serverCfg.Runtime.CodeExecution.Enabled,
 		"cache", serverCfg.Runtime.Cache.Enabled,
@@ -446,28 +546,101 @@ func main() {
 		"log_format", *logFormat,
 	)
 
+	if err := checkPublicURL(serverCfg.Server.PublicURL); err != nil {
+		slog.Error("invalid server.publicURL", "error", err)
+		os.Exit(1)
+	}
+
+	redactor, err := newRedactor(serverCfg.Security.Redaction)
+	if err != nil {
+		slog.Error("invalid redaction rules", "error", err)
+		os.Exit(1)
+	}
+	auditLogger.SetRedactor(redactor)
+	if mcpCfg := serverCfg.Security.MCP; mcpCfg != nil && mcpCfg.MaxMessageSize != "" {
+		if _, err := serverconfig.ParseByteSize(mcpCfg.MaxMessageSize); err != nil {
+			slog.Error("invalid security.mcp.maxMessageSize", "error", err)
+			os.Exit(1)
+		}
+	}
+	if mcpCfg := serverCfg.Security.MCP; mcpCfg != nil && mcpCfg.CompressMinSize != "" {
+		if _, err := serverconfig.ParseByteSize(mcpCfg.CompressMinSize); err != nil {
+			slog.Error("invalid security.mcp.compressMinSize", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	if len(serverCfg.Plugins) > 0 {
+		loaded, err := plugins.Load(context.Background(), serverCfg.Plugins) //nolint:govet // intentional err shadow
+		if err != nil {
+			slog.Error("load plugins failed", "error", err)
+			os.Exit(1)
+		}
+		plugins.Register(loaded, logger)
+	}
+
+	detectProbes, err := detect.NewRegistry(serverCfg.Detect)
+	if err != nil {
+		slog.Error("invalid detect probes", "error", err)
+		os.Exit(1)
+	}
+	alerts, err := alerting.New(serverCfg.Alerts, logger)
+	if err != nil {
+		slog.Error("invalid alerts config", "error", err)
+		os.Exit(1)
+	}
+
 	s := &server{
-		path:           profilesPath,
+		storage:        storage,
 		configPath:     serverConfigPath,
 		serverCfg:      serverCfg,
 		key:            key,
 		authMode:       mode,
 		adminToken:     adminToken,
+		adminSessions:  adminSessions,
+		oidc:           oidcVerifier,
+		access:         access,
+		lockout:        lockout.New(serverCfg.Security.Lockout, auditLogger, logger),
 		logger:         logger,
-		redactor:       redact.NewRedactor(),
+		redactor:       redactor,
 		auditLogger:    auditLogger,
 		metrics:        metricsCollector,
+		httpClients:    runtime.NewHTTPClients(),
+		oauth2Tokens:   runtime.NewOAuth2TokenManager(),
 		sessionTracker: mcp.NewSessionTracker(),
 		agentHub:       audit.NewGenericHub(),
 		oauthStore:     oauth.NewStore(),
 		detectLimiter:  ratelimit.New(5, 0, 0), // 5 requests per minute for detect endpoint
+		detectProbes:   detectProbes,
 		verifyLimiter:  ratelimit.New(5, 0, 0), // 5 requests per minute for verify endpoint
 	}
 
+	metricsCollector.SetUpstreamConnSource(s.upstreamConnStats)
+
+	// Join the cluster when distributed mode is configured
+	if serverCfg.Cluster.Redis != "" {
+		node, err := newClusterNode(serverCfg.Cluster, logger) //nolint:govet // intentional err shadow
+		if err != nil {
+			slog.Error("init cluster mode failed", "error", err)
+			os.Exit(1)
+		}
+		s.cluster = node
+		defer node.store.Close()
+		slog.Info("distributed mode enabled", "node_url", node.nodeURL)
+	}
+
 	// Initialize cache if enabled in config
 	if serverCfg.Runtime.Cache.Enabled {
 		s.cache = newProfileCache(serverCfg.Runtime.Cache.TTL)
 		slog.Info("cache enabled", "ttl", serverCfg.Runtime.Cache.TTL)
+		if interval := serverCfg.Runtime.Cache.RefreshInterval; interval > 0 {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			s.startSpecRefresh(ctx, interval)
+			slog.Info("background spec refresh enabled", "interval", interval)
+		}
+	} else if serverCfg.Runtime.Cache.RefreshInterval > 0 {
+		slog.Warn("runtime.cache.refreshInterval is ignored because the cache is disabled")
 	}
 
 	// Initialize polling engine (for email inbox polling, API tool polling, etc.)
@@ -476,6 +649,14 @@ func main() {
 	// Initialize persistent email manager (for connection pooling + IDLE push)
 	s.emailPersistent = email.NewPersistentManager(logger)
 
+	// Send alerts from the audit event stream if webhooks are configured
+	if alerts != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		alerts.Watch(ctx, auditLogger.EventHub())
+		slog.Info("alerting enabled", "webhooks", len(serverCfg.Alerts.Webhooks))
+	}
+
 	// Start metrics remote write if configured
 	if rw := serverCfg.Metrics.RemoteWrite; rw != nil && rw.Endpoint != "" {
 		ctx, cancel := context.WithCancel(context.Background())
@@ -488,7 +669,7 @@ func main() {
 	if err := s.load(); err != nil {
 		// If profile exists but decryption failed, show helpful error
 		if profileExists && keyRaw != "" {
-			absPath, _ := filepath.Abs(profilesPath)
+			absPath := storage.Location()
 			slog.Error("failed to decrypt profiles file",
 				"path", absPath,
 				"key_env", *keyEnv,
@@ -522,7 +703,7 @@ func main() {
 
 		// Show first-run status if key wasn't just generated (that case already showed its message)
 		if !keyGenerated {
-			absPath, _ := filepath.Abs(profilesPath)
+			absPath := storage.Location()
 			fmt.Println("")
 			fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 			fmt.Println("🚀 First run setup")
@@ -541,6 +722,14 @@ func main() {
 			fmt.Println("")
 		}
 	}
+	s.profilesLoaded.Store(true)
+
+	// Build every profile's registry before reporting ready
+	if s.cache != nil {
+		warmCtx, cancelWarm := context.WithCancel(context.Background())
+		defer cancelWarm()
+		s.warmCache(warmCtx)
+	}
 
 	// HTTP transport mode - profile-based system
 	mux := http.NewServeMux()
@@ -582,9 +771,19 @@ func main() {
 		mux.HandleFunc("/admin/auth", s.handleAdminAuth)
 		mux.HandleFunc("/admin/metrics", s.handleMetrics)
 		mux.HandleFunc("/admin/audit", s.handleAudit)
+		mux.HandleFunc("/admin/audit/verify", s.handleAuditVerify)
 		mux.HandleFunc("/admin/stats", s.handleStats)
+		mux.HandleFunc("/admin/analytics", s.handleAnalytics)
+		mux.HandleFunc("/admin/health", s.handleAdminHealth)
 		mux.HandleFunc("/admin/config", s.handleConfig)
+		mux.HandleFunc("/admin/version", s.handleAdminVersion)
 		mux.HandleFunc("/admin/sessions", s.handleSessions)
+		mux.HandleFunc("/admin/sessions/", s.handleSession)
+		mux.HandleFunc("/admin/tenants", s.handleTenants)
+		mux.HandleFunc("/admin/tenants/", s.handleTenant)
+		mux.HandleFunc("/admin/profiles/export", s.handleProfilesExport)
+		mux.HandleFunc("/admin/profiles/import", s.handleProfilesImport)
+		mux.HandleFunc("/admin/profiles/patch", s.handleProfilesPatch)
 		mux.HandleFunc("/admin/events", s.handleEventStream)
 	} else {
 		// Simple health check if no admin
@@ -599,18 +798,24 @@ func main() {
 	}
 	// API endpoints (always available)
 	mux.HandleFunc("/healthz", s.handleHealth)
+	mux.HandleFunc("/livez", s.handleLive)
 	mux.HandleFunc("/readyz", s.handleReady)
+	mux.HandleFunc("/capabilities", s.handleCapabilities)
 	mux.HandleFunc("/profiles", s.handleProfiles)
 	mux.HandleFunc("/profiles/", s.handleProfileRoute)
-	mux.HandleFunc("/detect", s.handleDetect)
-	mux.HandleFunc("/verify", s.handleVerify)
+	mux.HandleFunc("/detect", s.requireAdminLogin(s.handleDetect))
+	mux.HandleFunc("/detect/probes", s.requireAdminLogin(s.handleDetectProbes))
+	mux.HandleFunc("/detect/suggest", s.requireAdminLogin(s.handleDetectSuggest))
+	mux.HandleFunc("/verify", s.requireAdminLogin(s.handleVerify))
+	mux.HandleFunc("/config/schema", s.handleConfigSchema)
+	mux.HandleFunc("/config/validate", s.handleConfigValidate)
 	mux.HandleFunc("/oauth/start", s.handleOAuthStart)
 	mux.HandleFunc("/oauth/callback", s.handleOAuthCallback)
 	mux.HandleFunc("/oauth/exchange", s.handleOAuthExchange)
-	mux.HandleFunc("/test", s.handleTest)
-	mux.HandleFunc("/operations", s.handleOperations)
-	mux.HandleFunc("/email/lookup", s.handleEmailLookup)
-	mux.HandleFunc("/email/verify", s.handleEmailVerify)
+	mux.HandleFunc("/test", s.requireAdminLogin(s.handleTest))
+	mux.HandleFunc("/operations", s.requireAdminLogin(s.handleOperations))
+	mux.HandleFunc("/email/lookup", s.requireAdminLogin(s.handleEmailLookup))
+	mux.HandleFunc("/email/verify", s.requireAdminLogin(s.handleEmailVerify))
 	mux.HandleFunc("/metrics", s.handlePublicMetrics)
 
 	// OAuth 2.1 endpoints (for ChatGPT MCP compatibility)
@@ -622,7 +827,7 @@ func main() {
 
 	httpServer := &http.Server{
 		Addr:              listenAddr,
-		Handler:           recoverMiddleware(logRequests(mux, logger)),
+		Handler:           recoverMiddleware(logRequests(s.enforceAccess(mux), logger)),
 		ReadTimeout:       15 * time.Second,
 		ReadHeaderTimeout: 5 * time.Second,
 		WriteTimeout:      15 * time.Second,
@@ -640,10 +845,30 @@ func main() {
 		slog.Warn("could not write pid file", "error", err)
 	}
 
+	servers := []*http.Server{httpServer}
+
+	// Serve one profile's MCP endpoint on its own port
+	if *serveProfile != "" {
+		mcpServer, mcpLn, err := s.newProfileMCPServer(*serveProfile, *mcpListen)
+		if err != nil {
+			slog.Error("serve profile failed", "profile", *serveProfile, "addr", *mcpListen, "error", err)
+			os.Exit(1)
+		}
+		servers = append(servers, mcpServer)
+		go func() {
+			if err := mcpServer.ServeTLS(mcpLn, tlsCertPath, tlsKeyPath); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("profile MCP server error", "error", err)
+				os.Exit(1)
+			}
+		}()
+		slog.Info("serving profile over MCP", "profile", *serveProfile, "url", "https://"+*mcpListen+"/mcp")
+	}
+
 	// Start graceful-shutdown listener in the background.
-	go shutdownOnSignal([]*http.Server{httpServer}, func() {
+	go shutdownOnSignal(servers, func() {
 		removePID()
 		auditLogger.Close()
+		_ = storage.Close()
 		slog.Debug("audit logger closed")
 	})
 
@@ -698,25 +923,6 @@ func limitBody(w http.ResponseWriter, r *http.Request) {
 	r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
 }
 
-func (s *server) handleHealth(w http.ResponseWriter, _ *http.Request) {
-	w.WriteHeader(http.StatusOK)
-	_, _ = w.Write([]byte("ok"))
-}
-
-func (s *server) handleReady(w http.ResponseWriter, _ *http.Request) {
-	// Readiness: server has loaded profiles and is ready to serve traffic
-	s.mu.RLock()
-	ready := len(s.store.Profiles) >= 0 // Always ready once server is running
-	s.mu.RUnlock()
-	if ready {
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write([]byte("ok"))
-	} else {
-		w.WriteHeader(http.StatusServiceUnavailable)
-		_, _ = w.Write([]byte("not ready"))
-	}
-}
-
 func (s *server) handlePublicMetrics(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
@@ -758,3 +964,67 @@ func writeJSON(w http.ResponseWriter, status int, payload any) {
 	w.WriteHeader(status)
 	_ = json.NewEncoder(w).Encode(payload)
 }
+
+// newRedactor returns the server's redactor with the configured rules.
+// Profile credentials are added to it as profiles are loaded.
+func newRedactor(cfg *serverconfig.RedactionConfig) (*redact.Redactor, error) {
+	redactor := redact.NewRedactor()
+	if cfg == nil {
+		return redactor, nil
+	}
+	if err := redactor.AddRules(redactionRules(cfg.RedactionRules)); err != nil {
+		return nil, err
+	}
+	for api, rules := range cfg.APIs {
+		if err := redactor.AddAPIRules(api, redactionRules(rules)); err != nil {
+			return nil, err
+		}
+	}
+	return redactor, nil
+}
+
+// addAuditSinks makes the audit logger forward events to the configured
+// sinks.
+func addAuditSinks(auditLogger *audit.Logger, sinks []serverconfig.AuditSink) error {
+	for i, cfg := range sinks {
+		var (
+			sink audit.Sink
+			name string
+			err  error
+		)
+		switch cfg.Type {
+		case "file":
+			path, expandErr := serverconfig.ExpandPath(cfg.Path)
+			if cfg.Path == "" || expandErr != nil {
+				return fmt.Errorf("audit.sinks[%d]: file sinks need a path", i)
+			}
+			sink, err = audit.NewFileSink(path)
+			name = "file:" + path
+		case "syslog":
+			sink, err = audit.NewSyslogSink(cfg.Network, cfg.Address, cfg.Facility, cfg.Tag)
+			name = "syslog:" + cfg.Address
+		case "webhook":
+			sink, err = audit.NewWebhookSink(cfg.URL, cfg.Headers)
+			// The rest of the URL may hold a secret.
+			if u, parseErr := url.Parse(cfg.URL); parseErr == nil {
+				name = "webhook:" + u.Host
+			}
+		default:
+			return fmt.Errorf("audit.sinks[%d]: unknown type %q (want file, syslog or webhook)", i, cfg.Type)
+		}
+		if err != nil {
+			return fmt.Errorf("audit.sinks[%d]: %w", i, err)
+		}
+		auditLogger.AddSink(name, sink, cfg.EventTypes)
+		slog.Info("audit sink enabled", "sink", name)
+	}
+	return nil
+}
+
+func redactionRules(cfg serverconfig.RedactionRules) redact.Rules {
+	rules := redact.Rules{Fields: cfg.Fields}
+	for _, p := range cfg.Patterns {
+		rules.Patterns = append(rules.Patterns, redact.Pattern{Name: p.Name, Regex: p.Regex, Replacement: p.Replacement})
+	}
+	return rules
+}
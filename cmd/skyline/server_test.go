@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"skyline-mcp/internal/audit"
+	"skyline-mcp/internal/logging"
+	"skyline-mcp/internal/mcp"
+	"skyline-mcp/internal/metrics"
+	"skyline-mcp/internal/runtime"
+	"skyline-mcp/internal/serverconfig"
+)
+
+const testAdminToken = "test-admin-token"
+
+// newTestServer returns a server in bearer mode over storage, or over a
+// new profiles file when storage is nil, with its store loaded.
+func newTestServer(t *testing.T, storage profileStorage) *server {
+	t.Helper()
+	key := testStorageKey()
+	if storage == nil {
+		storage = &fileStorage{path: filepath.Join(t.TempDir(), "profiles.enc.yaml"), key: key}
+	}
+	sessions, err := newAdminSessions(key, testAdminToken, serverconfig.ServerSection{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &server{
+		storage:        storage,
+		serverCfg:      &serverconfig.ServerConfig{},
+		key:            key,
+		authMode:       "bearer",
+		adminToken:     testAdminToken,
+		adminSessions:  sessions,
+		logger:         logging.Discard(),
+		stdout:         io.Discard,
+		metrics:        metrics.NewCollector(),
+		httpClients:    runtime.NewHTTPClients(),
+		oauth2Tokens:   runtime.NewOAuth2TokenManager(),
+		sessionTracker: mcp.NewSessionTracker(),
+		agentHub:       audit.NewGenericHub(),
+	}
+	if err := s.load(); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// requestOption adds credentials to a test request.
+type requestOption func(*http.Request)
+
+func asAdmin(r *http.Request) {
+	r.AddCookie(&http.Cookie{Name: adminCookieName, Value: testAdminToken})
+}
+
+func withBearer(token string) requestOption {
+	return func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+token) }
+}
+
+// call sends method target to handler with body encoded as JSON, unless it
+// is nil or already a string.
+func call(t *testing.T, handler http.HandlerFunc, method, target string, body any, opts ...requestOption) *httptest.ResponseRecorder {
+	t.Helper()
+	var data []byte
+	switch b := body.(type) {
+	case nil:
+	case string:
+		data = []byte(b)
+	default:
+		var err error
+		if data, err = json.Marshal(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := httptest.NewRequest(method, target, bytes.NewReader(data))
+	r.Header.Set("Content-Type", "application/json")
+	for _, opt := range opts {
+		opt(r)
+	}
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
+
+// decodeBody decodes a JSON response into a map.
+func decodeBody(t *testing.T, w *httptest.ResponseRecorder) map[string]any {
+	t.Helper()
+	var out map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatalf("decode %q: %v", w.Body.String(), err)
+	}
+	return out
+}
+
+func TestAddAuditSinks(t *testing.T) {
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	defer slog.SetDefault(prev)
+
+	auditLogger, err := audit.NewLogger(filepath.Join(t.TempDir(), "audit.db"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		sink serverconfig.AuditSink
+		err  string
+	}{
+		{serverconfig.AuditSink{Type: "kafka"}, `audit.sinks[0]: unknown type "kafka"`},
+		{serverconfig.AuditSink{Type: "file"}, "audit.sinks[0]: file sinks need a path"},
+		{serverconfig.AuditSink{Type: "syslog"}, "audit.sinks[0]: syslog address is required"},
+		{serverconfig.AuditSink{Type: "webhook", URL: "ftp://logs.example.com"}, "audit.sinks[0]: webhook url must be http or https"},
+	} {
+		if err := addAuditSinks(auditLogger, []serverconfig.AuditSink{tc.sink}); err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%+v: %v, want %q", tc.sink, err, tc.err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	if err := addAuditSinks(auditLogger, []serverconfig.AuditSink{{Type: "file", Path: path, EventTypes: []string{"auth_failure"}}}); err != nil {
+		t.Fatal(err)
+	}
+	auditLogger.LogError("ops", "error", "boom", "")
+	auditLogger.LogError("ops", "auth_failure", "bad token", "203.0.113.7")
+	if err := auditLogger.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 1 || !strings.Contains(lines[0], `"event_type":"auth_failure"`) {
+		t.Errorf("file sink got:\n%s", data)
+	}
+}
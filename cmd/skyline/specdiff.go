@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/redact"
+	"skyline-mcp/internal/spec"
+)
+
+// specSnapshot is the on-disk baseline used by `skyline spec-diff`.
+type specSnapshot struct {
+	CreatedAt time.Time            `json:"created_at"`
+	Services  []*canonical.Service `json:"services"`
+}
+
+// runSpecDiff implements `skyline spec-diff`: it loads the specs referenced by
+// a profile config and compares them with a snapshot taken on a previous run.
+// The first run (or --update) writes the snapshot.
+// Exit codes: 0 = no breaking changes, 1 = breaking changes (any change with
+// --strict), 2 = usage, load or I/O error.
+func runSpecDiff(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("spec-diff", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	snapshotPath := fs.String("snapshot", "", "Snapshot file (default: <config-file>.specs.json)")
+	update := fs.Bool("update", false, "Overwrite the snapshot with the current specs after comparing")
+	format := fs.String("format", "text", "Output format: text, json")
+	strict := fs.Bool("strict", false, "Treat non-breaking changes as failures")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: skyline spec-diff [--snapshot file] [--update] [--format text|json] [--strict] <config-file>\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(stderr, "unsupported format %q\n", *format)
+		return 2
+	}
+
+	configPath := fs.Arg(0)
+	if *snapshotPath == "" {
+		*snapshotPath = configPath + ".specs.json"
+	}
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "load config: %v\n", err)
+		return 2
+	}
+	redactor := redact.NewRedactor()
+	redactor.AddSecrets(cfg.Secrets())
+	logger := slog.New(slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	current, err := spec.LoadServices(ctx, cfg, logger, redactor)
+	if err != nil {
+		fmt.Fprintf(stderr, "load services: %v\n", err)
+		return 2
+	}
+
+	previous, err := readSpecSnapshot(*snapshotPath)
+	if errors.Is(err, os.ErrNotExist) {
+		if err := writeSpecSnapshot(*snapshotPath, current); err != nil {
+			fmt.Fprintf(stderr, "write snapshot: %v\n", err)
+			return 2
+		}
+		fmt.Fprintf(stdout, "no snapshot found; saved %d services to %s\n", len(current), *snapshotPath)
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "read snapshot: %v\n", err)
+		return 2
+	}
+
+	diff := spec.DiffServices(previous.Services, current)
+	if *format == "json" {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(map[string]any{
+			"snapshot":    *snapshotPath,
+			"snapshot_at": previous.CreatedAt,
+			"diff":        diff,
+		})
+	} else {
+		printSpecDiff(stdout, diff)
+	}
+
+	if *update && !diff.Empty() {
+		if err := writeSpecSnapshot(*snapshotPath, current); err != nil {
+			fmt.Fprintf(stderr, "write snapshot: %v\n", err)
+			return 2
+		}
+	}
+
+	if diff.Breaking || (*strict && !diff.Empty()) {
+		return 1
+	}
+	return 0
+}
+
+func readSpecSnapshot(path string) (*specSnapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var snap specSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return &snap, nil
+}
+
+func writeSpecSnapshot(path string, services []*canonical.Service) error {
+	data, err := json.MarshalIndent(specSnapshot{CreatedAt: time.Now().UTC(), Services: services}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+// printSpecDiff renders a diff as one line per operation ("+" added,
+// "-" removed, "~" changed) followed by its individual changes.
+func printSpecDiff(w io.Writer, diff *spec.SpecDiff) {
+	if diff.Empty() {
+		fmt.Fprintln(w, "no changes")
+		return
+	}
+	mark := func(breaking bool) string {
+		if breaking {
+			return "  [breaking]"
+		}
+		return ""
+	}
+	endpoint := func(ref spec.OperationRef) string {
+		return strings.TrimSpace(ref.Method + " " + ref.Path)
+	}
+	for _, ref := range diff.Added {
+		fmt.Fprintf(w, "+ %s  %s\n", ref.Tool, endpoint(ref))
+	}
+	for _, ref := range diff.Removed {
+		fmt.Fprintf(w, "- %s  %s%s\n", ref.Tool, endpoint(ref), mark(true))
+	}
+	for _, op := range diff.Changed {
+		fmt.Fprintf(w, "~ %s  %s%s\n", op.Tool, endpoint(op.OperationRef), mark(op.Breaking))
+		for _, c := range op.Changes {
+			detail := ""
+			switch {
+			case c.Old != nil && c.New != nil:
+				detail = fmt.Sprintf(": %v -> %v", c.Old, c.New)
+			case c.Old != nil:
+				detail = fmt.Sprintf(": %v", c.Old)
+			case c.New != nil:
+				detail = fmt.Sprintf(": %v", c.New)
+			}
+			fmt.Fprintf(w, "    %s %s%s%s\n", c.Kind, c.Location, detail, mark(c.Breaking))
+		}
+	}
+	summary := fmt.Sprintf("%d added, %d removed, %d changed", len(diff.Added), len(diff.Removed), len(diff.Changed))
+	if diff.Breaking {
+		summary += " (breaking)"
+	}
+	fmt.Fprintln(w, summary)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"skyline-mcp/internal/serverconfig"
+)
+
+// errStoreNotFound is returned by profileStorage.Load when nothing has been
+// persisted yet (first run).
+var errStoreNotFound = errors.New("profile store not found")
+
+// errStoreConflict is returned by profileStorage.Save when another server
+// instance changed a profile or tenant this instance also changed.
+var errStoreConflict = errors.New("changed by another instance")
+
+// profileStorage persists the profile store. Implementations encrypt profile
+// data at rest with the server key.
+type profileStorage interface {
+	Load() (profileStore, error)
+	Save(store profileStore) error
+	// Exists reports whether the backend already holds a saved store.
+	Exists() bool
+	// Location describes where profiles are stored (for logs and errors).
+	Location() string
+	Close() error
+}
+
+// newProfileStorage returns the backend selected by profiles.backend in the
+// server config. filePath is the resolved location of the encrypted YAML file.
+func newProfileStorage(cfg serverconfig.ProfilesSection, filePath string, key []byte) (profileStorage, error) {
+	switch strings.ToLower(cfg.Backend) {
+	case "", "file":
+		return &fileStorage{path: filePath, key: key}, nil
+	case "sqlite":
+		dbPath, err := serverconfig.ExpandPath(cfg.Database)
+		if err != nil {
+			return nil, fmt.Errorf("expand profiles database path: %w", err)
+		}
+		return openSQLiteStorage(dbPath, key)
+	case "postgres", "postgresql":
+		if !strings.Contains(cfg.Database, "://") && !strings.Contains(cfg.Database, "=") {
+			return nil, fmt.Errorf("profiles.database must be a Postgres connection string for the postgres backend")
+		}
+		return openPostgresStorage(cfg.Database, key)
+	default:
+		return nil, fmt.Errorf("unsupported profiles backend %q (expected file, sqlite or postgres)", cfg.Backend)
+	}
+}
+
+// importProfilesFile copies the encrypted profiles file at path into an
+// empty database backend, so profiles carry over the first time the server
+// switches away from the file backend. It reports how many profiles were
+// imported; nothing is done when storage is the file backend, already holds
+// profiles or there is no file.
+func importProfilesFile(storage profileStorage, path string, key []byte) (int, error) {
+	if _, isFile := storage.(*fileStorage); isFile || storage.Exists() || !fileExists(path) {
+		return 0, nil
+	}
+	legacy, err := (&fileStorage{path: path, key: key}).Load()
+	if err != nil {
+		return 0, err
+	}
+	if err := storage.Save(legacy); err != nil {
+		return 0, err
+	}
+	return len(legacy.Profiles), nil
+}
+
+// fileStorage keeps the whole store in a single AES-GCM encrypted YAML file.
+type fileStorage struct {
+	path string
+	key  []byte
+}
+
+func (f *fileStorage) Load() (profileStore, error) {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return profileStore{}, errStoreNotFound
+		}
+		return profileStore{}, err
+	}
+	var env envelope
+	if err := yaml.Unmarshal(data, &env); err != nil { //nolint:govet // intentional err shadow
+		return profileStore{}, fmt.Errorf("parse storage: %w", err)
+	}
+	plain, err := decrypt(env, f.key)
+	if err != nil {
+		return profileStore{}, fmt.Errorf("decryption failed (wrong key or corrupted data): %w", err)
+	}
+	var store profileStore
+	if err := yaml.Unmarshal(plain, &store); err != nil {
+		return profileStore{}, fmt.Errorf("parse store: %w", err)
+	}
+	return store, nil
+}
+
+func (f *fileStorage) Save(store profileStore) error {
+	plain, err := yaml.Marshal(store)
+	if err != nil {
+		return err
+	}
+	env, err := encrypt(plain, f.key)
+	if err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(env)
+	if err != nil {
+		return err
+	}
+	tmp := f.path + ".tmp"
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.path)
+}
+
+func (f *fileStorage) Exists() bool { return fileExists(f.path) }
+
+func (f *fileStorage) Location() string {
+	if abs, err := filepath.Abs(f.path); err == nil {
+		return abs
+	}
+	return f.path
+}
+
+func (f *fileStorage) Close() error { return nil }
@@ -0,0 +1,385 @@
+package main
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"gopkg.in/yaml.v3"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteMigrations are applied in order; the index+1 is the schema version
+// recorded in schema_migrations. Append only — never edit a shipped entry.
+var sqliteMigrations = []string{
+	`CREATE TABLE profiles (
+		name TEXT PRIMARY KEY,
+		position INTEGER NOT NULL DEFAULT 0,
+		version INTEGER NOT NULL,
+		nonce TEXT NOT NULL,
+		ciphertext TEXT NOT NULL,
+		updated_at DATETIME NOT NULL
+	)`,
+	`CREATE TABLE tenants (
+		name TEXT PRIMARY KEY,
+		version INTEGER NOT NULL,
+		nonce TEXT NOT NULL,
+		ciphertext TEXT NOT NULL,
+		updated_at DATETIME NOT NULL
+	)`,
+	`ALTER TABLE profiles ADD COLUMN rev INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE tenants ADD COLUMN rev INTEGER NOT NULL DEFAULT 0`,
+}
+
+// postgresMigrations follow the same rules as sqliteMigrations.
+var postgresMigrations = []string{
+	`CREATE TABLE profiles (
+		name TEXT PRIMARY KEY,
+		position INTEGER NOT NULL DEFAULT 0,
+		version INTEGER NOT NULL,
+		nonce TEXT NOT NULL,
+		ciphertext TEXT NOT NULL,
+		updated_at TIMESTAMPTZ NOT NULL,
+		rev BIGINT NOT NULL DEFAULT 0
+	)`,
+	`CREATE TABLE tenants (
+		name TEXT PRIMARY KEY,
+		version INTEGER NOT NULL,
+		nonce TEXT NOT NULL,
+		ciphertext TEXT NOT NULL,
+		updated_at TIMESTAMPTZ NOT NULL,
+		rev BIGINT NOT NULL DEFAULT 0
+	)`,
+}
+
+// postgresMigrationLock is the advisory lock key that serializes schema
+// migrations when several instances start at once.
+const postgresMigrationLock = 0x736b796c // "skyl"
+
+// sqlStorage stores one encrypted blob per profile and tenant, so several
+// server instances can share a store. Each row carries a revision: Save
+// writes only the rows this instance changed, and only if nobody else
+// changed them since this instance last read or wrote them.
+type sqlStorage struct {
+	db         *sql.DB
+	postgres   bool
+	location   string
+	key        []byte
+	migrations []string
+
+	mu   sync.Mutex
+	rows map[rowKey]storedRow // as last loaded or saved by this instance
+}
+
+type rowKey struct {
+	table string // "profiles" or "tenants"
+	name  string
+}
+
+type storedRow struct {
+	rev int64
+	sum [32]byte // of the plaintext, to skip rows that did not change
+}
+
+func openSQLiteStorage(path string, key []byte) (*sqlStorage, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create profiles database dir: %w", err)
+	}
+	// WAL + busy timeout let concurrent instances read while one writes.
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("open profiles database: %w", err)
+	}
+	s := &sqlStorage{db: db, location: "sqlite:" + path, key: key, migrations: sqliteMigrations}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	_ = os.Chmod(path, 0o600)
+	return s, nil
+}
+
+func openPostgresStorage(dsn string, key []byte) (*sqlStorage, error) {
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open profiles database: %w", err)
+	}
+	location := "postgres"
+	if u, err := url.Parse(dsn); err == nil && u.Host != "" {
+		location = "postgres://" + u.Host + u.Path
+	}
+	s := &sqlStorage{db: db, postgres: true, location: location, key: key, migrations: postgresMigrations}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// rebind numbers ? placeholders for Postgres.
+func (s *sqlStorage) rebind(query string) string {
+	if !s.postgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// migrate brings the schema up to the latest version, one migration per
+// transaction. The version is read again inside each transaction, so
+// instances starting together apply every migration once.
+func (s *sqlStorage) migrate() error {
+	if _, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		applied_at TIMESTAMP NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+	for {
+		done, err := s.migrateOne()
+		if err != nil || done {
+			return err
+		}
+	}
+}
+
+func (s *sqlStorage) migrateOne() (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if s.postgres {
+		if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1)`, postgresMigrationLock); err != nil {
+			return false, fmt.Errorf("lock schema: %w", err)
+		}
+	}
+	var current int
+	if err := tx.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return false, fmt.Errorf("read schema version: %w", err)
+	}
+	if current >= len(s.migrations) {
+		return true, tx.Commit()
+	}
+	if _, err := tx.Exec(s.migrations[current]); err != nil {
+		return false, fmt.Errorf("apply migration %d: %w", current+1, err)
+	}
+	if _, err := tx.Exec(s.rebind(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`), current+1, time.Now().UTC()); err != nil {
+		return false, fmt.Errorf("record migration %d: %w", current+1, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("commit migration %d: %w", current+1, err)
+	}
+	return false, nil
+}
+
+func (s *sqlStorage) Load() (profileStore, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows := map[rowKey]storedRow{}
+	var store profileStore
+	err := s.loadRows("tenants", `SELECT name, version, nonce, ciphertext, rev FROM tenants ORDER BY name`, rows, func(plain []byte) error {
+		var t tenant
+		if err := yaml.Unmarshal(plain, &t); err != nil {
+			return err
+		}
+		store.Tenants = append(store.Tenants, t)
+		return nil
+	})
+	if err != nil {
+		return profileStore{}, err
+	}
+	err = s.loadRows("profiles", `SELECT name, version, nonce, ciphertext, rev FROM profiles ORDER BY position, name`, rows, func(plain []byte) error {
+		var p profile
+		if err := yaml.Unmarshal(plain, &p); err != nil {
+			return err
+		}
+		store.Profiles = append(store.Profiles, p)
+		return nil
+	})
+	if err != nil {
+		return profileStore{}, err
+	}
+	s.rows = rows
+	if len(store.Profiles) == 0 {
+		return profileStore{}, errStoreNotFound
+	}
+	return store, nil
+}
+
+// loadRows decrypts every row of table, records its revision and passes
+// the plaintext to add.
+func (s *sqlStorage) loadRows(table, query string, seen map[rowKey]storedRow, add func(plain []byte) error) error {
+	kind := strings.TrimSuffix(table, "s")
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return fmt.Errorf("query %s: %w", table, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		var env envelope
+		var rev int64
+		if err := rows.Scan(&name, &env.Version, &env.Nonce, &env.Ciphertext, &rev); err != nil {
+			return fmt.Errorf("scan %s: %w", kind, err)
+		}
+		plain, err := decrypt(env, s.key)
+		if err != nil {
+			return fmt.Errorf("decryption failed for %s %q (wrong key or corrupted data): %w", kind, name, err)
+		}
+		if err := add(plain); err != nil {
+			return fmt.Errorf("parse %s %q: %w", kind, name, err)
+		}
+		seen[rowKey{table, name}] = storedRow{rev: rev, sum: sha256.Sum256(plain)}
+	}
+	return rows.Err()
+}
+
+// Save writes the tenants and profiles that changed since this instance
+// last loaded or saved them, in a single transaction. Rows another
+// instance added are kept. If another instance changed or deleted a row
+// this instance also changed, nothing is written and the error wraps
+// errStoreConflict.
+func (s *sqlStorage) Save(store profileStore) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now().UTC()
+	next := make(map[rowKey]storedRow, len(store.Tenants)+len(store.Profiles))
+	tenants := make([]namedRow, len(store.Tenants))
+	for i, t := range store.Tenants {
+		tenants[i] = namedRow{t.Name, t}
+	}
+	if err := s.saveRows(tx, "tenants", tenants, next, now); err != nil {
+		return err
+	}
+	profiles := make([]namedRow, len(store.Profiles))
+	for i, p := range store.Profiles {
+		profiles[i] = namedRow{p.Name, p}
+	}
+	if err := s.saveRows(tx, "profiles", profiles, next, now); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.rows = next
+	return nil
+}
+
+type namedRow struct {
+	name  string
+	value any
+}
+
+func (s *sqlStorage) saveRows(tx *sql.Tx, table string, items []namedRow, next map[rowKey]storedRow, now time.Time) error {
+	kind := strings.TrimSuffix(table, "s")
+	position := -1
+	for _, item := range items {
+		plain, err := yaml.Marshal(item.value)
+		if err != nil {
+			return err
+		}
+		key := rowKey{table, item.name}
+		row := storedRow{rev: 1, sum: sha256.Sum256(plain)}
+		old, known := s.rows[key]
+		if known && old.sum == row.sum {
+			next[key] = old
+			continue
+		}
+		env, err := encrypt(plain, s.key)
+		if err != nil {
+			return err
+		}
+		var res sql.Result
+		switch {
+		case known:
+			row.rev = old.rev + 1
+			res, err = tx.Exec(s.rebind(`UPDATE `+table+` SET version = ?, nonce = ?, ciphertext = ?, updated_at = ?, rev = ? WHERE name = ? AND rev = ?`),
+				env.Version, env.Nonce, env.Ciphertext, now, row.rev, item.name, old.rev)
+		case table == "profiles":
+			// New profiles go after every stored one, wherever this
+			// instance lists them.
+			if position < 0 {
+				if err := tx.QueryRow(`SELECT COALESCE(MAX(position), -1) + 1 FROM profiles`).Scan(&position); err != nil {
+					return fmt.Errorf("read profile positions: %w", err)
+				}
+			}
+			res, err = tx.Exec(s.rebind(`INSERT INTO profiles (name, position, version, nonce, ciphertext, updated_at, rev) VALUES (?, ?, ?, ?, ?, ?, ?) ON CONFLICT (name) DO NOTHING`),
+				item.name, position, env.Version, env.Nonce, env.Ciphertext, now, row.rev)
+			position++
+		default:
+			res, err = tx.Exec(s.rebind(`INSERT INTO `+table+` (name, version, nonce, ciphertext, updated_at, rev) VALUES (?, ?, ?, ?, ?, ?) ON CONFLICT (name) DO NOTHING`),
+				item.name, env.Version, env.Nonce, env.Ciphertext, now, row.rev)
+		}
+		if err != nil {
+			return fmt.Errorf("save %s %q: %w", kind, item.name, err)
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			return fmt.Errorf("%w: %s %q", errStoreConflict, kind, item.name)
+		}
+		next[key] = row
+	}
+
+	kept := make(map[string]bool, len(items))
+	for _, item := range items {
+		kept[item.name] = true
+	}
+	for key, old := range s.rows {
+		if key.table != table || kept[key.name] {
+			continue
+		}
+		res, err := tx.Exec(s.rebind(`DELETE FROM `+table+` WHERE name = ? AND rev = ?`), key.name, old.rev)
+		if err != nil {
+			return fmt.Errorf("delete %s %q: %w", kind, key.name, err)
+		}
+		if n, err := res.RowsAffected(); err == nil && n == 1 {
+			continue
+		}
+		// Gone already is fine; changed since is a conflict.
+		var exists int
+		if err := tx.QueryRow(s.rebind(`SELECT COUNT(*) FROM `+table+` WHERE name = ?`), key.name).Scan(&exists); err != nil {
+			return fmt.Errorf("delete %s %q: %w", kind, key.name, err)
+		}
+		if exists > 0 {
+			return fmt.Errorf("%w: %s %q", errStoreConflict, kind, key.name)
+		}
+	}
+	return nil
+}
+
+func (s *sqlStorage) Exists() bool {
+	var n int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM profiles`).Scan(&n); err != nil {
+		return false
+	}
+	return n > 0
+}
+
+func (s *sqlStorage) Location() string { return s.location }
+func (s *sqlStorage) Close() error     { return s.db.Close() }
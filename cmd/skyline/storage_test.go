@@ -0,0 +1,361 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"skyline-mcp/internal/serverconfig"
+)
+
+func testStorageKey() []byte {
+	return []byte("0123456789abcdef0123456789abcdef")
+}
+
+func profileNames(store profileStore) []string {
+	var names []string
+	for _, p := range store.Profiles {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+func TestFileStorage(t *testing.T) {
+	f := &fileStorage{path: filepath.Join(t.TempDir(), "profiles.enc.yaml"), key: testStorageKey()}
+	if _, err := f.Load(); !errors.Is(err, errStoreNotFound) {
+		t.Fatalf("load before save: %v", err)
+	}
+	if f.Exists() {
+		t.Fatal("empty file storage exists")
+	}
+	store := profileStore{Profiles: []profile{{Name: "default", TokenHash: "h", ConfigYAML: "apis: []"}}}
+	if err := f.Save(store); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(f.path)
+	if len(data) == 0 || strings.Contains(string(data), "apis: []") {
+		t.Fatalf("profiles file is not encrypted:\n%s", data)
+	}
+	got, err := f.Load()
+	if err != nil || !reflect.DeepEqual(got, store) {
+		t.Fatalf("load = %+v, %v", got, err)
+	}
+	if _, err := (&fileStorage{path: f.path, key: []byte("fedcba9876543210fedcba9876543210")}).Load(); err == nil {
+		t.Fatal("load with the wrong key succeeded")
+	}
+}
+
+func TestSQLiteStorageSharedByInstances(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profiles.db")
+	testSharedStorage(t, func() *sqlStorage {
+		s, err := openSQLiteStorage(path, testStorageKey())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { s.Close() })
+		return s
+	})
+}
+
+// TestPostgresStorageSharedByInstances runs against the database in
+// SKYLINE_TEST_POSTGRES_URL; its profiles and tenants tables are dropped.
+func TestPostgresStorageSharedByInstances(t *testing.T) {
+	dsn := os.Getenv("SKYLINE_TEST_POSTGRES_URL")
+	if dsn == "" {
+		t.Skip("SKYLINE_TEST_POSTGRES_URL not set")
+	}
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`DROP TABLE IF EXISTS profiles, tenants, schema_migrations`); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+	testSharedStorage(t, func() *sqlStorage {
+		s, err := openPostgresStorage(dsn, testStorageKey())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { s.Close() })
+		return s
+	})
+}
+
+// testSharedStorage plays two server instances, a and b, against one
+// database.
+func testSharedStorage(t *testing.T, open func() *sqlStorage) {
+	a, b := open(), open()
+	if a.Exists() {
+		t.Fatal("new database has profiles")
+	}
+	if _, err := a.Load(); !errors.Is(err, errStoreNotFound) {
+		t.Fatalf("load of empty database: %v", err)
+	}
+	initial := profileStore{
+		Tenants: []tenant{{Name: "acme", TokenHash: "th"}},
+		Profiles: []profile{
+			{Name: "default", TokenHash: "h0"},
+			{Name: "ops", TokenHash: "h1", ConfigYAML: "apis: []"},
+		},
+	}
+	if err := a.Save(initial); err != nil {
+		t.Fatal(err)
+	}
+	storeB, err := b.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(storeB.Tenants, initial.Tenants) || !reflect.DeepEqual(profileNames(storeB), []string{"default", "ops"}) {
+		t.Fatalf("b loaded %+v", storeB)
+	}
+
+	// Edits to different profiles both land; neither instance writes a
+	// profile it did not change.
+	storeA := initial
+	storeA.Profiles = []profile{initial.Profiles[0], {Name: "ops", TokenHash: "h1", ConfigYAML: "apis: [a]"}}
+	if err := a.Save(storeA); err != nil {
+		t.Fatal(err)
+	}
+	storeB.Profiles = append(storeB.Profiles, profile{Name: "billing", TokenHash: "h2"})
+	if err := b.Save(storeB); err != nil {
+		t.Fatalf("save of b's new profile: %v", err)
+	}
+	fresh := open()
+	got, err := fresh.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(profileNames(got), []string{"default", "ops", "billing"}) || got.Profiles[1].ConfigYAML != "apis: [a]" {
+		t.Fatalf("after both saves: %+v", got)
+	}
+	if rev := fresh.rows[rowKey{"profiles", "default"}].rev; rev != 1 {
+		t.Errorf("unchanged profile was rewritten, rev %d", rev)
+	}
+
+	// b still has the old ops profile: changing it again is a conflict and
+	// writes nothing, not even b's other changes.
+	storeB.Profiles[1].ConfigYAML = "apis: [b]"
+	storeB.Profiles[0].TokenHash = "h0b"
+	if err := b.Save(storeB); !errors.Is(err, errStoreConflict) {
+		t.Fatalf("stale update: %v", err)
+	}
+	// So is deleting it.
+	if err := b.Save(profileStore{Tenants: storeB.Tenants, Profiles: []profile{initial.Profiles[0]}}); !errors.Is(err, errStoreConflict) {
+		t.Fatalf("stale delete: %v", err)
+	}
+	// Two instances creating the same profile: the second one conflicts.
+	storeA.Profiles = append(storeA.Profiles, profile{Name: "billing", TokenHash: "other"})
+	if err := a.Save(storeA); !errors.Is(err, errStoreConflict) {
+		t.Fatalf("duplicate create: %v", err)
+	}
+
+	// After reloading, b's changes apply.
+	if storeB, err = b.Load(); err != nil {
+		t.Fatal(err)
+	}
+	storeB.Profiles = storeB.Profiles[:2]
+	storeB.Tenants = nil
+	if err := b.Save(storeB); err != nil {
+		t.Fatal(err)
+	}
+	got, err = open().Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(profileNames(got), []string{"default", "ops"}) || len(got.Tenants) != 0 {
+		t.Fatalf("after delete: %+v", got)
+	}
+}
+
+func TestSQLiteStorageMigratesOlderSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profiles.db")
+	key := testStorageKey()
+
+	// A database written before profiles had revisions.
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`CREATE TABLE schema_migrations (version INTEGER PRIMARY KEY, applied_at DATETIME NOT NULL)`); err != nil {
+		t.Fatal(err)
+	}
+	for i, m := range sqliteMigrations[:2] {
+		if _, err := db.Exec(m); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Exec(`INSERT INTO schema_migrations VALUES (?, ?)`, i+1, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	plain, _ := yaml.Marshal(profile{Name: "default", TokenHash: "h0"})
+	env, err := encrypt(plain, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO profiles (name, position, version, nonce, ciphertext, updated_at) VALUES (?, 0, ?, ?, ?, ?)`,
+		"default", env.Version, env.Nonce, env.Ciphertext, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	s, err := openSQLiteStorage(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	var version int
+	if err := s.db.QueryRow(`SELECT MAX(version) FROM schema_migrations`).Scan(&version); err != nil || version != len(sqliteMigrations) {
+		t.Fatalf("schema version %d, %v", version, err)
+	}
+	store, err := s.Load()
+	if err != nil || !reflect.DeepEqual(profileNames(store), []string{"default"}) {
+		t.Fatalf("load = %+v, %v", store, err)
+	}
+	store.Profiles[0].ConfigYAML = "apis: []"
+	if err := s.Save(store); err != nil {
+		t.Fatalf("save over a migrated row: %v", err)
+	}
+
+	// Opening again applies nothing.
+	again, err := openSQLiteStorage(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again.Close()
+}
+
+func TestImportProfilesFile(t *testing.T) {
+	dir := t.TempDir()
+	key := testStorageKey()
+	filePath := filepath.Join(dir, "profiles.enc.yaml")
+	legacy := profileStore{Profiles: []profile{{Name: "default", TokenHash: "h0"}, {Name: "ops", TokenHash: "h1"}}}
+	if err := (&fileStorage{path: filePath, key: key}).Save(legacy); err != nil {
+		t.Fatal(err)
+	}
+
+	storage, err := newProfileStorage(serverconfig.ProfilesSection{Backend: "sqlite", Database: filepath.Join(dir, "profiles.db")}, filePath, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storage.Close()
+	n, err := importProfilesFile(storage, filePath, key)
+	if err != nil || n != 2 {
+		t.Fatalf("import = %d, %v", n, err)
+	}
+	got, err := storage.Load()
+	if err != nil || !reflect.DeepEqual(got, legacy) {
+		t.Fatalf("imported %+v, %v", got, err)
+	}
+	// The database now has profiles, so the file is not imported again.
+	if n, err := importProfilesFile(storage, filePath, key); err != nil || n != 0 {
+		t.Fatalf("second import = %d, %v", n, err)
+	}
+	if n, err := importProfilesFile(&fileStorage{path: filePath, key: key}, filePath, key); err != nil || n != 0 {
+		t.Fatalf("import into the file backend = %d, %v", n, err)
+	}
+}
+
+func TestNewProfileStorage(t *testing.T) {
+	if _, err := newProfileStorage(serverconfig.ProfilesSection{Backend: "postgres", Database: "~/.skyline/profiles.db"}, "", testStorageKey()); err == nil {
+		t.Error("postgres backend accepted a file path")
+	}
+	if _, err := newProfileStorage(serverconfig.ProfilesSection{Backend: "etcd"}, "", testStorageKey()); err == nil {
+		t.Error("unknown backend accepted")
+	}
+	s := &sqlStorage{postgres: true}
+	if got := s.rebind(`UPDATE t SET a = ? WHERE b = ? AND c = ?`); got != `UPDATE t SET a = $1 WHERE b = $2 AND c = $3` {
+		t.Errorf("rebind = %s", got)
+	}
+}
+
+func TestSealStoreReusesUnchangedBlobs(t *testing.T) {
+	master := testStorageKey()
+	acme, _, err := newTenant("acme", master)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := profileStore{
+		Tenants:  []tenant{acme},
+		Profiles: []profile{{Name: "default", TokenHash: "h0"}, {Name: "acme/ops", TokenHash: "h1", ConfigYAML: "apis: []"}},
+	}
+	copies := sealedCopies{}
+	first, err := sealStore(store, master, copies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Profiles[1].Sealed == nil || first.Profiles[1].ConfigYAML != "" || first.Profiles[1].TokenHash != "" {
+		t.Fatalf("tenant profile not sealed: %+v", first.Profiles[1])
+	}
+	second, _ := sealStore(store, master, copies)
+	if second.Profiles[1].Sealed != first.Profiles[1].Sealed {
+		t.Error("unchanged profile was sealed again")
+	}
+	store.Profiles[1].ConfigYAML = "apis: [x]"
+	third, _ := sealStore(store, master, copies)
+	if third.Profiles[1].Sealed == first.Profiles[1].Sealed {
+		t.Error("changed profile kept its old blob")
+	}
+
+	blob := third.Profiles[1].Sealed
+	reopened := sealedCopies{}
+	opened, err := openStore(third, master, reopened)
+	if err != nil || !reflect.DeepEqual(opened.Profiles, store.Profiles) {
+		t.Fatalf("open = %+v, %v", opened.Profiles, err)
+	}
+	if again, _ := sealStore(opened, master, reopened); again.Profiles[1].Sealed != blob {
+		t.Error("loaded profile was sealed again")
+	}
+}
+
+func TestServersSharingStorageReportConflicts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profiles.db")
+	open := func() *server {
+		storage, err := openSQLiteStorage(path, testStorageKey())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { storage.Close() })
+		return newTestServer(t, storage)
+	}
+	a := open()
+	put := func(s *server, name, config string) int {
+		body := map[string]string{"token": "tok-" + name, "config_yaml": config}
+		return call(t, s.handleProfileRoute, http.MethodPut, "/profiles/"+name, body, asAdmin).Code
+	}
+	if code := put(a, "ops", "apis: []"); code != http.StatusOK {
+		t.Fatalf("create: %d", code)
+	}
+	b := open()
+
+	// Different profiles: both changes are kept.
+	if code := put(a, "billing", "apis: []"); code != http.StatusOK {
+		t.Fatalf("a: %d", code)
+	}
+	if code := put(b, "ops", "timeout_seconds: 5"); code != http.StatusOK {
+		t.Fatalf("b: %d", code)
+	}
+	// The same profile: a's copy of ops is stale.
+	if code := put(a, "ops", "timeout_seconds: 7"); code != http.StatusConflict {
+		t.Fatalf("stale write: %d", code)
+	}
+	// a reloaded the store, so it now sees b's change and a retry works.
+	if prof, _ := a.findProfile("ops"); prof.ConfigYAML != "timeout_seconds: 5" {
+		t.Errorf("a did not reload: %q", prof.ConfigYAML)
+	}
+	if code := put(a, "ops", "timeout_seconds: 7"); code != http.StatusOK {
+		t.Fatalf("retry: %d", code)
+	}
+	got := listProfiles(t, open(), asAdmin)
+	if !reflect.DeepEqual(got, []any{"default", "ops", "billing"}) {
+		t.Errorf("stored profiles: %v", got)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"skyline-mcp/internal/config"
+)
+
+// templateVarRe matches the {{var.NAME}} placeholders of a template
+// profile. They are filled in when a profile is cloned from it.
+var templateVarRe = regexp.MustCompile(`\{\{\s*var\.([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// Variables lists the placeholders in the profile's config, sorted. A
+// profile with any is a template: it is not served, only cloned.
+func (p profile) Variables() []string {
+	seen := map[string]bool{}
+	for _, m := range templateVarRe.FindAllStringSubmatch(p.ConfigYAML, -1) {
+		seen[m[1]] = true
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// fillTemplate replaces the placeholders of configYAML with vars and
+// validates the result. Values are substituted in the parsed YAML, so
+// they cannot change its structure, which means placeholders belong in
+// string values. Every placeholder needs a value and every value a
+// placeholder.
+func fillTemplate(configYAML string, vars map[string]string) (string, error) {
+	var missing []string
+	used := map[string]bool{}
+	for _, name := range (profile{ConfigYAML: configYAML}).Variables() {
+		if _, ok := vars[name]; !ok {
+			missing = append(missing, name)
+		}
+		used[name] = true
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("missing variables: %s", strings.Join(missing, ", "))
+	}
+	var unknown []string
+	for name := range vars {
+		if !used[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		slices.Sort(unknown)
+		return "", fmt.Errorf("unknown variables: %s", strings.Join(unknown, ", "))
+	}
+	if len(vars) == 0 {
+		return configYAML, nil
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(configYAML), &doc); err != nil {
+		return "", fmt.Errorf("invalid stored config: %w", err)
+	}
+	replace := func(s string) string {
+		return templateVarRe.ReplaceAllStringFunc(s, func(m string) string {
+			return vars[templateVarRe.FindStringSubmatch(m)[1]]
+		})
+	}
+	var fill func(n *yaml.Node)
+	fill = func(n *yaml.Node) {
+		if n.Kind == yaml.ScalarNode {
+			n.Value = replace(n.Value)
+		}
+		n.HeadComment, n.LineComment, n.FootComment = replace(n.HeadComment), replace(n.LineComment), replace(n.FootComment)
+		for _, c := range n.Content {
+			fill(c)
+		}
+	}
+	fill(&doc)
+	data, err := yaml.Marshal(&doc)
+	if err != nil {
+		return "", err
+	}
+	if templateVarRe.Match(data) {
+		// A value contained a placeholder of its own.
+		return "", fmt.Errorf("variable values must not contain {{var.*}} placeholders")
+	}
+	if err := config.ValidateYAML(data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
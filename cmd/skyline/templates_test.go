@@ -0,0 +1,65 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const crmTemplate = `apis:
+  - name: crm
+    # serves {{var.region}}
+    spec_url: https://{{var.region}}.crm.example.com/openapi.json
+    auth:
+      type: bearer
+      token: "{{ var.api_key }}"`
+
+func TestProfileVariables(t *testing.T) {
+	if got := (profile{ConfigYAML: crmTemplate}).Variables(); !reflect.DeepEqual(got, []string{"api_key", "region"}) {
+		t.Errorf("Variables() = %v", got)
+	}
+	for _, configYAML := range []string{"apis: []", "token: '{{region}}'", "token: '{{var.}}'", "token: '{{var.1st}}'"} {
+		if got := (profile{ConfigYAML: configYAML}).Variables(); len(got) != 0 {
+			t.Errorf("Variables() of %q = %v", configYAML, got)
+		}
+	}
+}
+
+func TestFillTemplate(t *testing.T) {
+	got, err := fillTemplate(crmTemplate, map[string]string{"region": "eu", "api_key": `sk-"1": x`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"# serves eu",
+		"spec_url: https://eu.crm.example.com/openapi.json",
+		// A value that looks like YAML stays a string.
+		`token: "sk-\"1\": x"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("filled config lacks %q:\n%s", want, got)
+		}
+	}
+
+	// A profile without placeholders is copied as it is.
+	if got, err := fillTemplate(petstoreConfig, nil); err != nil || got != petstoreConfig {
+		t.Errorf("fillTemplate without variables = %q, %v", got, err)
+	}
+
+	for name, tc := range map[string]struct {
+		configYAML string
+		vars       map[string]string
+		err        string
+	}{
+		"missing":          {crmTemplate, map[string]string{"region": "eu"}, "missing variables: api_key"},
+		"unknown":          {crmTemplate, map[string]string{"region": "eu", "api_key": "k", "zone": "a", "env": "b"}, "unknown variables: env, zone"},
+		"nested":           {crmTemplate, map[string]string{"region": "{{var.api_key}}", "api_key": "k"}, "must not contain {{var.*}} placeholders"},
+		"no template":      {"apis: []", map[string]string{"region": "eu"}, "unknown variables: region"},
+		"invalid result":   {"apis:\n  - name: '{{var.name}}'\n    spec_url: https://api.example.com/openapi.json", map[string]string{"name": ""}, "name"},
+		"invalid template": {"apis: [{{var.x}}", map[string]string{"x": "1"}, "invalid stored config"},
+	} {
+		if _, err := fillTemplate(tc.configYAML, tc.vars); err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: %v, want %q", name, err, tc.err)
+		}
+	}
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// tenant owns the profiles named "<tenant>/<profile>". Its profiles are
+// encrypted with the tenant's own data key, which is stored wrapped by the
+// server's master key, so deleting a tenant destroys access to its data.
+type tenant struct {
+	Name      string    `yaml:"name" json:"name"`
+	TokenHash string    `yaml:"token_hash" json:"-"`
+	Key       envelope  `yaml:"key" json:"-"` // data key encrypted with the master key
+	CreatedAt time.Time `yaml:"created_at" json:"created_at"`
+}
+
+// sealedProfile is the part of a tenant profile encrypted with the tenant key.
+type sealedProfile struct {
+	Token      string `yaml:"token,omitempty"` // written by older versions
+	TokenHash  string `yaml:"token_hash,omitempty"`
+	ConfigYAML string `yaml:"config_yaml"`
+}
+
+var tenantNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// newTenant creates a tenant with a fresh data key and returns it with its
+// management token. Only the token's hash is kept.
+func newTenant(name string, masterKey []byte) (tenant, string, error) {
+	if !tenantNamePattern.MatchString(name) {
+		return tenant{}, "", fmt.Errorf("tenant name must be 1-63 lowercase letters, digits or dashes")
+	}
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return tenant{}, "", err
+	}
+	wrapped, err := encrypt(dataKey, masterKey)
+	if err != nil {
+		return tenant{}, "", err
+	}
+	token := "skt_" + generateProfileToken()
+	return tenant{
+		Name:      name,
+		TokenHash: hashProfileToken(token),
+		Key:       *wrapped,
+		CreatedAt: time.Now().UTC(),
+	}, token, nil
+}
+
+// legacyTokenHash is the unsalted hex SHA-256 that older versions stored
+// for tenant tokens.
+func legacyTokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// checkToken reports whether token is the tenant's token. Hashes from
+// older versions are accepted: rehashed ones ("legacy$...") are salted
+// hashes of the old digest, and a bare digest may still come from a peer
+// that has not been upgraded.
+func (t tenant) checkToken(token string) bool {
+	if token == "" {
+		return false
+	}
+	if rest, ok := strings.CutPrefix(t.TokenHash, "legacy$"); ok {
+		return checkTokenHash(rest, legacyTokenHash(token))
+	}
+	if !strings.Contains(t.TokenHash, "$") {
+		return subtle.ConstantTimeCompare([]byte(legacyTokenHash(token)), []byte(t.TokenHash)) == 1
+	}
+	return checkTokenHash(t.TokenHash, token)
+}
+
+// rehashTenantTokens salts the unsalted token hashes of tenants created by
+// older versions. The tokens themselves are not known, so the old digest is
+// hashed again. It reports whether any tenant changed.
+func rehashTenantTokens(tenants []tenant) bool {
+	changed := false
+	for i, t := range tenants {
+		if t.TokenHash != "" && !strings.Contains(t.TokenHash, "$") {
+			tenants[i].TokenHash = "legacy$" + hashProfileToken(t.TokenHash)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// tenantOf returns the tenant owning a profile name, or "" for profiles
+// outside any tenant.
+func tenantOf(profileName string) string {
+	owner, _, ok := strings.Cut(profileName, "/")
+	if !ok {
+		return ""
+	}
+	return owner
+}
+
+// renameUntenantedProfiles renames profiles with a "/" in their name that
+// no tenant owns, which versions before tenants allowed. Such a name could
+// not be saved, and a tenant created later under its prefix would take the
+// profile over. Each "/" becomes "-", with a numeric suffix if that name is
+// taken. It returns the new names by old name.
+func renameUntenantedProfiles(store *profileStore) map[string]string {
+	tenants := make(map[string]bool, len(store.Tenants))
+	for _, t := range store.Tenants {
+		tenants[t.Name] = true
+	}
+	taken := make(map[string]bool, len(store.Profiles))
+	for _, p := range store.Profiles {
+		taken[p.Name] = true
+	}
+	renamed := map[string]string{}
+	for i, p := range store.Profiles {
+		owner := tenantOf(p.Name)
+		if owner == "" || tenants[owner] {
+			continue
+		}
+		base := strings.ReplaceAll(p.Name, "/", "-")
+		name := base
+		for n := 2; taken[name]; n++ {
+			name = fmt.Sprintf("%s-%d", base, n)
+		}
+		taken[name] = true
+		renamed[p.Name] = name
+		store.Profiles[i].Name = name
+	}
+	return renamed
+}
+
+// sealedCopies remembers the blob each tenant profile was last sealed into,
+// keyed by profile name. An unchanged profile is persisted with the same
+// blob, so database backends see that it did not change.
+type sealedCopies map[string]sealedCopy
+
+type sealedCopy struct {
+	sum [32]byte // of the tenant key and the sealed plaintext
+	env *envelope
+}
+
+func sealSum(key, plain []byte) [32]byte {
+	h := sha256.New()
+	h.Write(key)
+	h.Write(plain)
+	var sum [32]byte
+	h.Sum(sum[:0])
+	return sum
+}
+
+// sealStore returns store as it is persisted: tenant profiles have their
+// token and config replaced by a blob encrypted with the tenant data key.
+// copies, when not nil, is used and updated to reuse unchanged blobs.
+func sealStore(store profileStore, masterKey []byte, copies sealedCopies) (profileStore, error) {
+	keys, err := tenantKeys(store.Tenants, masterKey)
+	if err != nil {
+		return profileStore{}, err
+	}
+	out := profileStore{Tenants: store.Tenants, Profiles: make([]profile, 0, len(store.Profiles))}
+	for _, p := range store.Profiles {
+		owner := tenantOf(p.Name)
+		if owner == "" {
+			out.Profiles = append(out.Profiles, p)
+			continue
+		}
+		key, ok := keys[owner]
+		if !ok {
+			return profileStore{}, fmt.Errorf("profile %q belongs to unknown tenant %q", p.Name, owner)
+		}
+		plain, err := yaml.Marshal(sealedProfile{Token: p.Token, TokenHash: p.TokenHash, ConfigYAML: p.ConfigYAML})
+		if err != nil {
+			return profileStore{}, err
+		}
+		sum := sealSum(key, plain)
+		if c, ok := copies[p.Name]; ok && c.sum == sum {
+			out.Profiles = append(out.Profiles, profile{Name: p.Name, Sealed: c.env})
+			continue
+		}
+		env, err := encrypt(plain, key)
+		if err != nil {
+			return profileStore{}, err
+		}
+		if copies != nil {
+			copies[p.Name] = sealedCopy{sum: sum, env: env}
+		}
+		out.Profiles = append(out.Profiles, profile{Name: p.Name, Sealed: env})
+	}
+	return out, nil
+}
+
+// openStore reverses sealStore after the store is loaded, recording each
+// blob in copies when it is not nil.
+func openStore(store profileStore, masterKey []byte, copies sealedCopies) (profileStore, error) {
+	keys, err := tenantKeys(store.Tenants, masterKey)
+	if err != nil {
+		return profileStore{}, err
+	}
+	for i, p := range store.Profiles {
+		if p.Sealed == nil {
+			continue
+		}
+		key, ok := keys[tenantOf(p.Name)]
+		if !ok {
+			return profileStore{}, fmt.Errorf("profile %q belongs to unknown tenant %q", p.Name, tenantOf(p.Name))
+		}
+		plain, err := decrypt(*p.Sealed, key)
+		if err != nil {
+			return profileStore{}, fmt.Errorf("decrypt profile %q: %w", p.Name, err)
+		}
+		var sealed sealedProfile
+		if err := yaml.Unmarshal(plain, &sealed); err != nil {
+			return profileStore{}, fmt.Errorf("parse profile %q: %w", p.Name, err)
+		}
+		if copies != nil {
+			// Sealing marshals the same struct, so the sums match while the
+			// profile is unchanged.
+			if again, err := yaml.Marshal(sealed); err == nil {
+				copies[p.Name] = sealedCopy{sum: sealSum(key, again), env: p.Sealed}
+			}
+		}
+		store.Profiles[i] = profile{Name: p.Name, Token: sealed.Token, TokenHash: sealed.TokenHash, ConfigYAML: sealed.ConfigYAML}
+	}
+	return store, nil
+}
+
+// tenantKeys unwraps every tenant's data key.
+func tenantKeys(tenants []tenant, masterKey []byte) (map[string][]byte, error) {
+	keys := make(map[string][]byte, len(tenants))
+	for _, t := range tenants {
+		key, err := decrypt(t.Key, masterKey)
+		if err != nil {
+			return nil, fmt.Errorf("unwrap key of tenant %q: %w", t.Name, err)
+		}
+		keys[t.Name] = key
+	}
+	return keys, nil
+}
+
+// tenantIndex holds the tenants' token hashes. It has its own lock so
+// requests can be scoped while s.mu is held.
+type tenantIndex struct {
+	mu      sync.RWMutex
+	tenants []tenant
+}
+
+func (ix *tenantIndex) reset(tenants []tenant) {
+	ix.mu.Lock()
+	ix.tenants = slices.Clone(tenants)
+	ix.mu.Unlock()
+}
+
+// lookup returns the tenant whose token this is. The hashes are salted, so
+// each tenant's is checked in turn.
+func (ix *tenantIndex) lookup(token string) (string, bool) {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	for _, t := range ix.tenants {
+		if t.checkToken(token) {
+			return t.Name, true
+		}
+	}
+	return "", false
+}
+
+// requestTenant returns the tenant whose token the request carries.
+func (s *server) requestTenant(r *http.Request) (string, bool) {
+	token := bearerToken(r.Header.Get("Authorization"))
+	if token == "" {
+		return "", false
+	}
+	return s.tenants.lookup(token)
+}
+
+func (s *server) findTenant(name string) (tenant, bool) {
+	for _, t := range s.store.Tenants {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return tenant{}, false
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func createTenant(t *testing.T, s *server, name string) string {
+	t.Helper()
+	w := call(t, s.handleTenants, http.MethodPost, "/admin/tenants", map[string]string{"name": name}, asAdmin)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create tenant %s: %d %s", name, w.Code, w.Body)
+	}
+	return decodeBody(t, w)["token"].(string)
+}
+
+func listProfiles(t *testing.T, s *server, opts ...requestOption) []any {
+	t.Helper()
+	w := call(t, s.handleProfiles, http.MethodGet, "/profiles", nil, opts...)
+	if w.Code != http.StatusOK {
+		t.Fatalf("list profiles: %d %s", w.Code, w.Body)
+	}
+	names, _ := decodeBody(t, w)["profiles"].([]any)
+	return names
+}
+
+func TestTenantIsolation(t *testing.T) {
+	s := newTestServer(t, nil)
+	acme := createTenant(t, s, "acme")
+	globex := createTenant(t, s, "globex")
+	put := func(name, token string, opts ...requestOption) int {
+		body := map[string]string{"token": token, "config_yaml": "apis: []"}
+		return call(t, s.handleProfileRoute, http.MethodPut, "/profiles/"+name, body, opts...).Code
+	}
+
+	if code := put("acme/ops", "acme-ops-token", withBearer(acme)); code != http.StatusOK {
+		t.Fatalf("acme creating its profile: %d", code)
+	}
+	if code := put("globex/ops", "globex-ops-token", withBearer(globex)); code != http.StatusOK {
+		t.Fatalf("globex creating its profile: %d", code)
+	}
+	for name, want := range map[string]int{
+		"globex/x": http.StatusForbidden,  // another tenant's namespace
+		"shared":   http.StatusForbidden,  // outside any tenant
+		"nobody/x": http.StatusForbidden,  // a tenant that does not exist
+		"acme/a/b": http.StatusBadRequest, // not <tenant>/<profile>
+	} {
+		if code := put(name, "t", withBearer(acme)); code != want {
+			t.Errorf("acme creating %s: %d, want %d", name, code, want)
+		}
+	}
+	if code := put("acme/y", "acme-y-token", withBearer("acme-y-token")); code != http.StatusUnauthorized {
+		t.Errorf("creating a tenant profile without the tenant token: %d", code)
+	}
+
+	if got := listProfiles(t, s, withBearer(acme)); !reflect.DeepEqual(got, []any{"acme/ops"}) {
+		t.Errorf("acme lists %v", got)
+	}
+	if got := listProfiles(t, s, asAdmin); len(got) != 3 {
+		t.Errorf("admin lists %v", got)
+	}
+	if got := listProfiles(t, s); !reflect.DeepEqual(got, []any{"default"}) {
+		t.Errorf("anonymous caller lists %v", got)
+	}
+
+	get := func(name string, opts ...requestOption) int {
+		return call(t, s.handleProfileRoute, http.MethodGet, "/profiles/"+name, nil, opts...).Code
+	}
+	if code := get("globex/ops", withBearer(acme)); code != http.StatusUnauthorized {
+		t.Errorf("acme reading globex's profile: %d", code)
+	}
+	if code := get("globex/ops", withBearer("acme-ops-token")); code != http.StatusUnauthorized {
+		t.Errorf("acme's profile token reading globex's profile: %d", code)
+	}
+	if code := get("globex/ops", withBearer(globex)); code != http.StatusOK {
+		t.Errorf("globex reading its profile: %d", code)
+	}
+	if code := get("globex/ops", withBearer("globex-ops-token")); code != http.StatusOK {
+		t.Errorf("profile token reading its profile: %d", code)
+	}
+	if code := call(t, s.handleProfileRoute, http.MethodDelete, "/profiles/globex/ops", nil, withBearer(acme)).Code; code != http.StatusUnauthorized {
+		t.Errorf("acme deleting globex's profile: %d", code)
+	}
+
+	// At rest, tenant profiles are sealed with their tenant's key.
+	stored, err := s.storage.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range stored.Profiles {
+		if tenantOf(p.Name) != "" && (p.Sealed == nil || p.ConfigYAML != "" || p.TokenHash != "") {
+			t.Errorf("profile %s stored unsealed: %+v", p.Name, p)
+		}
+	}
+
+	// Deleting a tenant deletes its profiles and retires its token.
+	if w := call(t, s.handleTenant, http.MethodDelete, "/admin/tenants/acme", nil, asAdmin); w.Code != http.StatusNoContent {
+		t.Fatalf("delete tenant: %d %s", w.Code, w.Body)
+	}
+	if code := get("acme/ops", withBearer(acme)); code != http.StatusNotFound {
+		t.Errorf("deleted tenant's profile: %d", code)
+	}
+	if _, ok := s.tenants.lookup(acme); ok {
+		t.Error("deleted tenant's token still resolves")
+	}
+	reloaded := newTestServer(t, s.storage)
+	if got := listProfiles(t, reloaded, asAdmin); !reflect.DeepEqual(got, []any{"default", "globex/ops"}) {
+		t.Errorf("after reload admin lists %v", got)
+	}
+	if code := call(t, reloaded.handleProfileRoute, http.MethodGet, "/profiles/globex/ops", nil, withBearer(globex)).Code; code != http.StatusOK {
+		t.Errorf("globex after reload: %d", code)
+	}
+}
+
+func TestLoadRenamesUntenantedSlashProfiles(t *testing.T) {
+	storage := &fileStorage{path: filepath.Join(t.TempDir(), "profiles.enc.yaml"), key: testStorageKey()}
+	// Written before tenants existed, when "/" was allowed in names.
+	legacy := profileStore{Profiles: []profile{
+		{Name: "default", TokenHash: hashProfileToken("d")},
+		{Name: "team/x", TokenHash: hashProfileToken("x"), ConfigYAML: "apis: []"},
+		{Name: "team-x", TokenHash: hashProfileToken("y"), ConfigYAML: "apis: []"},
+	}}
+	if err := storage.Save(legacy); err != nil {
+		t.Fatal(err)
+	}
+
+	s := newTestServer(t, storage)
+	if got := listProfiles(t, s, asAdmin); !reflect.DeepEqual(got, []any{"default", "team-x-2", "team-x"}) {
+		t.Fatalf("profiles after load: %v", got)
+	}
+	if prof, ok := s.findProfile("team-x-2"); !ok || !prof.checkToken("x") {
+		t.Errorf("renamed profile lost its token: %+v", prof)
+	}
+	// The store saves again, and a tenant named like the old prefix does
+	// not get the profile.
+	createTenant(t, s, "team")
+	stored, err := storage.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := profileNames(stored); !reflect.DeepEqual(got, []string{"default", "team-x-2", "team-x"}) {
+		t.Errorf("stored profiles: %v", got)
+	}
+}
+
+func TestLoadSaltsLegacyTenantTokenHashes(t *testing.T) {
+	storage := &fileStorage{path: filepath.Join(t.TempDir(), "profiles.enc.yaml"), key: testStorageKey()}
+	acme, token, err := newTenant("acme", testStorageKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Older versions stored an unsalted SHA-256 of the token.
+	acme.TokenHash = legacyTokenHash(token)
+	legacy := profileStore{
+		Profiles: []profile{{Name: "default", TokenHash: hashProfileToken("d")}},
+		Tenants:  []tenant{acme},
+	}
+	if err := storage.Save(legacy); err != nil {
+		t.Fatal(err)
+	}
+
+	s := newTestServer(t, storage)
+	if got := listProfiles(t, s, withBearer(token)); len(got) != 0 {
+		t.Errorf("tenant profiles: %v", got)
+	}
+	if name, ok := s.tenants.lookup(token); !ok || name != "acme" {
+		t.Errorf("lookup with the old token = %q, %v", name, ok)
+	}
+	if _, ok := s.tenants.lookup(acme.TokenHash); ok {
+		t.Error("the old digest works as a token")
+	}
+	stored, err := storage.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash := stored.Tenants[0].TokenHash; !strings.HasPrefix(hash, "legacy$sha256$") || strings.Contains(hash, acme.TokenHash) {
+		t.Errorf("stored hash %q", hash)
+	}
+
+	// New tenants get salted hashes.
+	globex := createTenant(t, s, "globex")
+	if g, ok := s.findTenant("globex"); !ok || !strings.HasPrefix(g.TokenHash, "sha256$") || !g.checkToken(globex) || g.checkToken(token) {
+		t.Errorf("new tenant %+v", g)
+	}
+}
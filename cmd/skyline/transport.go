@@ -17,28 +17,50 @@ import (
 
 	"skyline-mcp/internal/codegen"
 	"skyline-mcp/internal/config"
+	codeexec "skyline-mcp/internal/executor"
 	"skyline-mcp/internal/mcp"
 	"skyline-mcp/internal/redact"
 	"skyline-mcp/internal/runtime"
 	"skyline-mcp/internal/spec"
 )
 
+// expandConfigPaths splits a comma-separated --config value into files and
+// directories, expanding a leading ~/ in each.
+func expandConfigPaths(arg string) ([]string, error) {
+	var paths []string
+	for _, path := range strings.Split(arg, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		if strings.HasPrefix(path, "~/") {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, fmt.Errorf("get home dir: %w", err)
+			}
+			path = filepath.Join(home, path[2:])
+		}
+		paths = append(paths, path)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("--config: no config file given")
+	}
+	return paths, nil
+}
+
 // runHTTPWithConfig runs the MCP server in HTTP mode with direct config file (no profiles)
 func runHTTPWithConfig(configPathArg, listenAddr string, enableAdmin bool, logger *slog.Logger) error {
 	ctx := context.Background()
 
-	// Expand config path
-	configPath := configPathArg
-	if strings.HasPrefix(configPath, "~/") {
-		home, err := os.UserHomeDir()
-		if err != nil {
-			return fmt.Errorf("get home dir: %w", err)
-		}
-		configPath = filepath.Join(home, configPath[2:])
+	// Expand config paths
+	configPaths, err := expandConfigPaths(configPathArg)
+	if err != nil {
+		return err
 	}
+	configPath := strings.Join(configPaths, ",")
 
 	// Load config
-	cfg, err := config.Load(configPath)
+	cfg, err := config.LoadFiles(configPaths...)
 	if err != nil {
 		return fmt.Errorf("load config: %w", err)
 	}
@@ -57,6 +79,9 @@ func runHTTPWithConfig(configPathArg, listenAddr string, enableAdmin bool, logge
 				redactor.AddSecrets([]string{api.Auth.Value})
 			}
 		}
+		if api.Signing != nil && api.Signing.Secret != "" {
+			redactor.AddSecrets([]string{api.Signing.Secret})
+		}
 	}
 
 	// Log startup
@@ -64,15 +89,15 @@ func runHTTPWithConfig(configPathArg, listenAddr string, enableAdmin bool, logge
 
 	// Load services from API specs
 	logger.Info("📚 Loading API specifications...")
-	services, err := spec.LoadServices(ctx, cfg, logger, redactor)
+	services, loadErrors, err := spec.LoadServicesWithErrors(ctx, cfg, logger, redactor)
 	if err != nil {
 		return fmt.Errorf("load services: %w", err)
 	}
-	logger.Info("✓ Loaded services", "count", len(services))
+	logger.Info("✓ Loaded services", "count", len(services), "failed", len(loadErrors))
 
 	// Build MCP registry
 	logger.Info("🔨 Building MCP tool registry...")
-	registry, err := mcp.NewRegistry(services)
+	registry, err := mcp.NewRegistryWithOptions(services, mcp.RegistryOptions{Examples: cfg.ToolExamples, Aliases: cfg.ToolAliases, Overrides: cfg.ToolOverrides})
 	if err != nil {
 		return fmt.Errorf("build registry: %w", err)
 	}
@@ -84,9 +109,15 @@ func runHTTPWithConfig(configPathArg, listenAddr string, enableAdmin bool, logge
 		return fmt.Errorf("create executor: %w", err)
 	}
 	registerEmailProtocol(executor, cfg, logger, nil)
+	startHealthChecks(executor, cfg.HealthCheck)
+	go executor.WarmGRPCDescriptors(context.Background())
+	logDataPolicy(executor, logger)
+	logSSHCommands(executor, logger)
+	executor.SetLoadErrors(loadErrors)
 
 	// Create MCP server
 	mcpServer := mcp.NewServer(registry, executor, logger, redactor, Version)
+	mcpServer.SetCapabilities(newCapabilities(map[string]bool{"code_execution": false, "cache": false, "subscriptions": false}))
 
 	// Set up HTTP server
 	mux := http.NewServeMux()
@@ -120,6 +151,11 @@ func runHTTPWithConfig(configPathArg, listenAddr string, enableAdmin bool, logge
 		w.Write([]byte("ok"))
 	})
 
+	// Liveness check
+	mux.HandleFunc("/livez", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"status": "ok"})
+	})
+
 	// Readiness check
 	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -191,18 +227,15 @@ func runSTDIO(configPathArg string, logger *slog.Logger) error {
 		return fmt.Errorf("--config flag required for STDIO mode")
 	}
 
-	// Expand config path
-	configPath := configPathArg
-	if strings.HasPrefix(configPath, "~/") {
-		home, err := os.UserHomeDir()
-		if err != nil {
-			return fmt.Errorf("get home dir: %w", err)
-		}
-		configPath = filepath.Join(home, configPath[2:])
+	// Expand config paths
+	configPaths, err := expandConfigPaths(configPathArg)
+	if err != nil {
+		return err
 	}
+	configPath := strings.Join(configPaths, ",")
 
 	// Load config
-	cfg, err := config.Load(configPath)
+	cfg, err := config.LoadFiles(configPaths...)
 	if err != nil {
 		return fmt.Errorf("load config: %w", err)
 	}
@@ -221,6 +254,9 @@ func runSTDIO(configPathArg string, logger *slog.Logger) error {
 				redactor.AddSecrets([]string{api.Auth.Value})
 			}
 		}
+		if api.Signing != nil && api.Signing.Secret != "" {
+			redactor.AddSecrets([]string{api.Signing.Secret})
+		}
 	}
 
 	// Log startup (to stderr, not stdout - stdout is reserved for MCP protocol)
@@ -228,15 +264,15 @@ func runSTDIO(configPathArg string, logger *slog.Logger) error {
 
 	// Load services from API specs
 	logger.Info("📚 Loading API specifications...")
-	services, err := spec.LoadServices(ctx, cfg, logger, redactor)
+	services, loadErrors, err := spec.LoadServicesWithErrors(ctx, cfg, logger, redactor)
 	if err != nil {
 		return fmt.Errorf("load services: %w", err)
 	}
-	logger.Info("✓ Loaded services", "count", len(services))
+	logger.Info("✓ Loaded services", "count", len(services), "failed", len(loadErrors))
 
 	// Build MCP registry
 	logger.Info("🔨 Building MCP tool registry...")
-	registry, err := mcp.NewRegistry(services)
+	registry, err := mcp.NewRegistryWithOptions(services, mcp.RegistryOptions{Examples: cfg.ToolExamples, Aliases: cfg.ToolAliases, Overrides: cfg.ToolOverrides})
 	if err != nil {
 		return fmt.Errorf("build registry: %w", err)
 	}
@@ -248,12 +284,19 @@ func runSTDIO(configPathArg string, logger *slog.Logger) error {
 		return fmt.Errorf("create executor: %w", err)
 	}
 	registerEmailProtocol(executor, cfg, logger, nil)
-
-	// Create MCP server
+	startHealthChecks(executor, cfg.HealthCheck)
+	go executor.WarmGRPCDescriptors(context.Background())
+	logDataPolicy(executor, logger)
+	logSSHCommands(executor, logger)
+	executor.SetLoadErrors(loadErrors)
+
+	// Create MCP server. Its code executor serves no MCP method, so code
+	// execution is reported as off.
 	mcpServer := mcp.NewServer(registry, executor, logger, redactor, Version)
+	mcpServer.SetCapabilities(newCapabilities(map[string]bool{"code_execution": false, "cache": false, "subscriptions": false}))
 
-	// Set up code execution (goja — no external dependencies)
-	codeExec, err := codegen.SetupCodeExecution(registry, logger)
+	// Set up code execution (Deno when installed, embedded goja otherwise)
+	codeExec, err := codegen.SetupCodeExecution(registry, logger, "", codeexec.Options{})
 	if err != nil {
 		logger.Warn("code execution setup failed", "error", err)
 	} else if codeExec != nil {
@@ -263,6 +306,13 @@ func runSTDIO(configPathArg string, logger *slog.Logger) error {
 			if !ok || tool.Operation == nil {
 				return nil, fmt.Errorf("tool not found: %s", toolName)
 			}
+			ctx, err := runtime.WithTimeoutArgument(ctx, args)
+			if err != nil {
+				return nil, err
+			}
+			if err := tool.ValidateArguments(args); err != nil {
+				return nil, err
+			}
 			result, err := executor.Execute(ctx, tool.Operation, args)
 			if err != nil {
 				return nil, err
@@ -270,7 +320,7 @@ func runSTDIO(configPathArg string, logger *slog.Logger) error {
 			return result.Body, nil
 		})
 		mcpServer.SetCodeExecutor(codeExec)
-		logger.Info("✓ Code execution enabled", "runtime", "goja")
+		logger.Info("✓ Code execution enabled", "runtime", codeExec.Engine())
 	}
 
 	logger.Info("✅ Server initialized successfully", "mode", "stdio")
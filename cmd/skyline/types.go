@@ -2,58 +2,92 @@ package main
 
 import (
 	"encoding/json"
+	"io"
 	"log/slog"
 	"sync"
+	"sync/atomic"
 
+	"skyline-mcp/internal/accesspolicy"
 	"skyline-mcp/internal/audit"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/detect"
 	"skyline-mcp/internal/email"
+	"skyline-mcp/internal/lockout"
 	"skyline-mcp/internal/mcp"
 	"skyline-mcp/internal/metrics"
 	"skyline-mcp/internal/oauth"
+	"skyline-mcp/internal/oidc"
 	"skyline-mcp/internal/polling"
 	"skyline-mcp/internal/ratelimit"
 	"skyline-mcp/internal/redact"
+	"skyline-mcp/internal/runtime"
 	"skyline-mcp/internal/serverconfig"
 )
 
 type envelope struct {
-	Version    int    `yaml:"version"`
-	Nonce      string `yaml:"nonce"`
-	Ciphertext string `yaml:"ciphertext"`
+	Version    int    `yaml:"version" json:"version"`
+	Nonce      string `yaml:"nonce" json:"nonce"`
+	Ciphertext string `yaml:"ciphertext" json:"ciphertext"`
 }
 
 type profileStore struct {
+	Tenants  []tenant  `yaml:"tenants,omitempty"`
 	Profiles []profile `yaml:"profiles"`
 }
 
 type profile struct {
-	Name       string `yaml:"name" json:"name"`
-	Token      string `yaml:"token" json:"token"`
-	ConfigYAML string `yaml:"config_yaml" json:"config_yaml"`
+	Name string `yaml:"name" json:"name"`
+	// Token is the plaintext token of a profile saved by an older version
+	// or sent in an import; it is hashed into TokenHash when loaded.
+	Token string `yaml:"token,omitempty" json:"token"`
+	// TokenHash is the salted hash of the profile's bearer token. The
+	// token itself is never stored.
+	TokenHash  string `yaml:"token_hash,omitempty" json:"-"`
+	ConfigYAML string `yaml:"config_yaml,omitempty" json:"config_yaml"`
+	// Sealed holds the token and config of a tenant profile at rest,
+	// encrypted with the tenant's data key.
+	Sealed *envelope `yaml:"sealed,omitempty" json:"-"`
 }
 
 type server struct {
 	mu              sync.RWMutex
 	store           profileStore
-	path            string
+	storage         profileStorage
+	sealed          sealedCopies // tenant profile blobs as last loaded or saved
 	configPath      string
 	serverCfg       *serverconfig.ServerConfig
 	key             []byte
 	authMode        string
 	adminToken      string
+	adminSessions   *adminSessions
+	loginLimits     loginLimits
+	oidc            *oidc.Verifier // nil unless security.oidc is set
+	access          *accesspolicy.Policy
+	lockout         *lockout.Guard // nil when security.lockout is disabled
+	shareLinks      usedShareLinks
 	logger          *slog.Logger
+	stdout          io.Writer // where one-time secrets are printed; os.Stdout when nil
 	redactor        *redact.Redactor
 	auditLogger     *audit.Logger
 	metrics         *metrics.Collector
 	cache           *profileCache
-	mcpServers      sync.Map // map[profileName+configHash] → *mcp.StreamableHTTPServer
+	httpClients     *runtime.HTTPClients        // upstream connection pools, kept across registry rebuilds
+	oauth2Tokens    *runtime.OAuth2TokenManager // OAuth2 tokens and device flows, kept across registry rebuilds
+	mcpServers      sync.Map                    // map[profileName+configHash] → *mcp.StreamableHTTPServer
+	jobStores       sync.Map                    // map[profileName] → *jobs.Store, kept across registry rebuilds
+	authRenewals    sync.Map                    // map[profileName+"/"+apiName] → *sync.Mutex, one credential prompt at a time
 	sessionTracker  *mcp.SessionTracker
 	agentHub        *audit.GenericHub
 	oauthStore      *oauth.Store
 	detectLimiter   *ratelimit.Limiter
+	detectProbes    *detect.Registry
 	verifyLimiter   *ratelimit.Limiter
 	pollEngine      *polling.Engine
 	emailPersistent *email.PersistentManager
+	cluster         *clusterNode // nil unless distributed mode is configured
+	profilesLoaded  atomic.Bool  // set once the profile store is decrypted
+	tenants         tenantIndex  // tenant token hashes, for scoping management requests
+	warmup          *cacheWarmup // nil when the cache is disabled
 }
 
 type upsertRequest struct {
@@ -63,28 +97,60 @@ type upsertRequest struct {
 }
 
 type detectRequest struct {
-	BaseURL     string `json:"base_url"`
-	BearerToken string `json:"bearer_token,omitempty"`
+	BaseURL     string      `json:"base_url"`
+	BearerToken string      `json:"bearer_token,omitempty"`
+	Auth        *detectAuth `json:"auth,omitempty"`
+	// Probes, when set, replace the server's probe list for this request.
+	Probes []detect.Probe `json:"probes,omitempty"`
+	// TimeoutSeconds bounds the whole run (default 10, at most 12).
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+	// StopOnFirst skips the remaining probes once one finds a spec.
+	StopOnFirst bool `json:"stop_on_first,omitempty"`
 }
 
 type detectResponse struct {
+	BaseURL   string        `json:"base_url"`
+	Online    bool          `json:"online"`
+	Detected  []detectProbe `json:"detected"`
+	ElapsedMS int64         `json:"elapsed_ms"`
+}
+
+type detectProbe struct {
+	Type       string `json:"type"`
+	SpecURL    string `json:"spec_url"`
+	Method     string `json:"method"`
+	Status     int    `json:"status"`
+	Found      bool   `json:"found"`
+	Skipped    bool   `json:"skipped,omitempty"`
+	Error      string `json:"error,omitempty"`
+	Endpoint   string `json:"endpoint"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// suggestRequest is the body of /detect/suggest: the base URL and
+// detected list of a /detect response, plus how to name and store the API.
+type suggestRequest struct {
 	BaseURL  string        `json:"base_url"`
-	Online   bool          `json:"online"`
 	Detected []detectProbe `json:"detected"`
+	// Type picks the spec when probes found several; default the first.
+	Type    string      `json:"type,omitempty"`
+	Name    string      `json:"name,omitempty"`
+	Profile string      `json:"profile,omitempty"`
+	Auth    *detectAuth `json:"auth,omitempty"`
 }
 
-type detectProbe struct {
-	Type     string `json:"type"`
-	SpecURL  string `json:"spec_url"`
-	Method   string `json:"method"`
-	Status   int    `json:"status"`
-	Found    bool   `json:"found"`
-	Error    string `json:"error,omitempty"`
-	Endpoint string `json:"endpoint"`
+type suggestResponse struct {
+	API        config.APIConfig `json:"api"`
+	Type       string           `json:"type"`
+	Profile    string           `json:"profile,omitempty"`
+	ConfigYAML string           `json:"config_yaml"`
+	Operations int              `json:"operations"`
+	Warnings   []string         `json:"warnings,omitempty"`
 }
 
 type testRequest struct {
-	SpecURL string `json:"spec_url"`
+	SpecURL string      `json:"spec_url"`
+	Auth    *detectAuth `json:"auth,omitempty"`
 }
 
 type testResponse struct {
@@ -95,9 +161,24 @@ type testResponse struct {
 }
 
 type operationsRequest struct {
-	SpecURL  string `json:"spec_url"`
-	SpecType string `json:"spec_type,omitempty"`
-	Name     string `json:"name,omitempty"`
+	SpecURL  string      `json:"spec_url"`
+	SpecType string      `json:"spec_type,omitempty"`
+	Name     string      `json:"name,omitempty"`
+	Auth     *detectAuth `json:"auth,omitempty"`
+}
+
+// detectAuth is the credentials /detect, /test and /operations send with
+// probes and spec fetches: bearer, basic or api-key auth given inline, or
+// the auth of an API in a profile the caller may access.
+type detectAuth struct {
+	Type     string `json:"type,omitempty"`
+	Token    string `json:"token,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	Header   string `json:"header,omitempty"`
+	Value    string `json:"value,omitempty"`
+	Profile  string `json:"profile,omitempty"`
+	API      string `json:"api,omitempty"`
 }
 
 type operationsResponse struct {
@@ -157,4 +238,5 @@ type emailVerifyResponse struct {
 type executeRequest struct {
 	ToolName  string         `json:"tool_name"`
 	Arguments map[string]any `json:"arguments"`
+	Async     bool           `json:"async,omitempty"` // run as a background job; poll with skyline__get_job_result
 }
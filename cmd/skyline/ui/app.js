@@ -29,6 +29,7 @@ const typeIcons = {
   raml: "mdi:code-braces",
   apiblueprint: "mdi:file-document-outline",
   insomnia: "simple-icons:insomnia",
+  har: "mdi:record-rec",
 };
 
 const typeLabels = {
@@ -46,6 +47,7 @@ const typeLabels = {
   raml: "RAML",
   apiblueprint: "API Blueprint",
   insomnia: "Insomnia Collection",
+  har: "HAR Capture",
 };
 
 // Known service identification — overlays the generic spec-type icon/label
@@ -839,47 +841,50 @@ const skylineApp = createApp({
       const proto = window.location.protocol === "https:" ? "https:" : "http:";
       return `${proto}//${window.location.host}/profiles/${encodeURIComponent(form.profileName)}/mcp`;
     });
+    // The server stores only a hash of the profile token, so a loaded
+    // profile's token is unknown and snippets show a placeholder for it.
+    const clientToken = computed(() => form.profileToken || "YOUR_PROFILE_TOKEN");
     const claudeDesktopSnippet = computed(() => {
-      if (!form.profileName || !form.profileToken) return "";
+      if (!form.profileName) return "";
       return JSON.stringify({
         mcpServers: {
           [`skyline-${form.profileName}`]: {
             url: mcpUrl.value,
-            headers: { Authorization: `Bearer ${form.profileToken}` },
+            headers: { Authorization: `Bearer ${clientToken.value}` },
           },
         },
       }, null, 2);
     });
     const claudeCodeCmd = computed(() => {
-      if (!form.profileName || !form.profileToken) return "";
-      return `claude mcp add skyline-${form.profileName} --transport http ${mcpUrl.value} --header "Authorization: Bearer ${form.profileToken}"`;
+      if (!form.profileName) return "";
+      return `claude mcp add skyline-${form.profileName} --transport http ${mcpUrl.value} --header "Authorization: Bearer ${clientToken.value}"`;
     });
     const claudeCodeSettings = computed(() => {
-      if (!form.profileName || !form.profileToken) return "";
+      if (!form.profileName) return "";
       return JSON.stringify({
         mcpServers: {
           [`skyline-${form.profileName}`]: {
             url: mcpUrl.value,
-            headers: { Authorization: `Bearer ${form.profileToken}` },
+            headers: { Authorization: `Bearer ${clientToken.value}` },
           },
         },
       }, null, 2);
     });
     const clineSnippet = computed(() => {
-      if (!form.profileName || !form.profileToken) return "";
+      if (!form.profileName) return "";
       return JSON.stringify({
         mcpServers: {
           [`skyline-${form.profileName}`]: {
             url: mcpUrl.value,
-            headers: { Authorization: `Bearer ${form.profileToken}` },
+            headers: { Authorization: `Bearer ${clientToken.value}` },
           },
         },
       }, null, 2);
     });
     const codexSnippet = computed(() => {
-      if (!form.profileName || !form.profileToken) return "";
+      if (!form.profileName) return "";
       const key = `skyline-${form.profileName}`;
-      return `[mcp_servers.${key}]\nurl = "${mcpUrl.value}"\nhttp_headers = { Authorization = "Bearer ${form.profileToken}" }`;
+      return `[mcp_servers.${key}]\nurl = "${mcpUrl.value}"\nhttp_headers = { Authorization = "Bearer ${clientToken.value}" }`;
     });
     async function copySnippet(text) {
       try {
@@ -1044,7 +1049,7 @@ const skylineApp = createApp({
         // Profile tokens are needed by MCP clients for authentication
         const data = await apiClient.loadProfile(name);
         form.profileName = data.name || name;
-        form.profileToken = data.token || "";
+        form.profileToken = ""; // only its hash is stored
         form.profileDisabled = data.config?.disabled || false;
         originalProfileName.value = name;
         activeProfile.value = name;
@@ -1131,7 +1136,7 @@ const skylineApp = createApp({
       } catch (err) {
         isLoadingProfile = false;
         if (err.message.includes("401") || err.message.includes("unauthorized")) {
-          setStatus("error", `Authentication required. Run the server with "--auth-mode none" for UI access.`);
+          setStatus("error", `Authentication required. Sign in at /admin/, or run the server with "--auth-mode none".`);
         } else {
           setStatus("error", `Failed to load profile: ${err.message}`);
         }
@@ -1154,6 +1159,7 @@ const skylineApp = createApp({
       if (lower.includes(".raml")) return "raml";
       if (lower.includes(".apib") || lower.includes("apiblueprint")) return "apiblueprint";
       if (lower.includes("insomnia")) return "insomnia";
+      if (lower.endsWith(".har")) return "har";
       return "";
     }
 
@@ -1391,8 +1397,10 @@ const skylineApp = createApp({
         return;
       }
 
-      // Auto-generate token if not present
-      if (!form.profileToken) {
+      // Generate a token for new or renamed profiles. Existing profiles keep
+      // their token when none is sent.
+      const isNewProfile = !originalProfileName.value || form.profileName !== originalProfileName.value;
+      if (!form.profileToken && isNewProfile) {
         const array = new Uint8Array(32);
         crypto.getRandomValues(array);
         form.profileToken = btoa(String.fromCharCode.apply(null, array));
@@ -2201,10 +2209,9 @@ const skylineApp = createApp({
           try {
             const data = await apiClient.loadProfile(profileName);
             existingApis = data.config?.apis || [];
-            existingToken = data.token || '';
           } catch (_) { /* new */ }
         }
-        if (!existingToken) {
+        if (importFlow.targetProfile === '__new__') {
           const arr = new Uint8Array(32);
           crypto.getRandomValues(arr);
           existingToken = btoa(String.fromCharCode(...arr));
@@ -3254,7 +3261,8 @@ const skylineApp = createApp({
               </div>
               <div v-else class="token-placeholder" style="font-size:12px;">
                 <iconify-icon icon="mdi:information-outline"></iconify-icon>
-                <span>Save profile to generate token</span>
+                <span v-if="activeProfile">Stored hashed; shown only when created</span>
+                <span v-else>Save profile to generate token</span>
               </div>
             </div>
           </div>
@@ -3,28 +3,34 @@ package main
 import (
 	"archive/tar"
 	"archive/zip"
+	"bytes"
 	"compress/gzip"
 	"context"
+	"crypto/sha256"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"log/slog"
-	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strings"
 	"time"
-)
 
-const (
-	githubAPIURL = "https://api.github.com/repos/emadomedher/skyline-mcp/releases/latest"
+	"skyline-mcp/internal/selfupdate"
 )
 
 // Version is set via -ldflags at build time
 var Version = "dev"
 
+// UpdatePublicKey is the base64 Ed25519 key release checksums are signed
+// with, set via -ldflags. When it is set, `skyline update` refuses releases
+// without a valid signature.
+var UpdatePublicKey = ""
+
 func currentVersion() string {
 	if Version == "dev" {
 		return "v" + Version
@@ -35,120 +41,82 @@ func currentVersion() string {
 	return Version
 }
 
-type GitHubRelease struct {
-	TagName string `json:"tag_name"`
-	Assets  []struct {
-		Name               string `json:"name"`
-		BrowserDownloadURL string `json:"browser_download_url"`
-	} `json:"assets"`
-}
-
-func runUpdate(logger *slog.Logger) error {
-	logger.Info("checking for updates...")
-
-	// Get current executable path
-	exePath, err := os.Executable()
-	if err != nil {
-		return fmt.Errorf("get executable path: %w", err)
+// runUpdate implements `skyline update`: it looks up the newest release of
+// an update channel, verifies the downloaded archive against the release's
+// checksums (and their signature when the binary carries a release key) and
+// replaces the running binary. --check-only stops after the lookup.
+func runUpdate(logger *slog.Logger, args []string) error {
+	fs := flag.NewFlagSet("update", flag.ContinueOnError)
+	channel := fs.String("channel", selfupdate.ChannelStable, "Update channel: stable, beta")
+	checkOnly := fs.Bool("check-only", false, "Report whether an update is available without installing it")
+	format := fs.String("format", "text", "Output format of --check-only: text, json")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: skyline update [--channel stable|beta] [--check-only [--format text|json]]\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
 	}
-	exePath, err = filepath.EvalSymlinks(exePath)
-	if err != nil {
-		return fmt.Errorf("resolve symlinks: %w", err)
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unsupported format %q", *format)
 	}
 
-	logger.Info("current version info", "version", currentVersion(), "binary", exePath)
-
-	// Check for latest release
 	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
 	defer cancel()
-
-	req, err := http.NewRequestWithContext(ctx, "GET", githubAPIURL, nil)
+	client := &selfupdate.Client{}
+	logger.Info("checking for updates...", "channel", *channel)
+	status, release, err := client.Check(ctx, currentVersion(), *channel)
 	if err != nil {
-		return fmt.Errorf("create request: %w", err)
+		return err
 	}
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("fetch release info: %w", err)
+	if *checkOnly {
+		if *format == "json" {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(status)
+		}
+		if status.UpdateAvailable {
+			fmt.Printf("Update available on %s: %s -> %s\n%s\n", status.Channel, status.Current, status.Latest, status.ReleaseURL)
+		} else {
+			fmt.Printf("Up to date on %s: %s (latest %s)\n", status.Channel, status.Current, status.Latest)
+		}
+		return nil
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("github API returned %d", resp.StatusCode)
+	if !status.UpdateAvailable {
+		logger.Info("already up to date", "version", status.Current, "latest", status.Latest)
+		return nil
 	}
+	logger.Info("update available", "current", status.Current, "latest", status.Latest)
 
-	var release GitHubRelease
-	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil { //nolint:govet // intentional err shadow
-		return fmt.Errorf("decode release info: %w", err)
+	// Get current executable path
+	exePath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("get executable path: %w", err)
 	}
-
-	logger.Info("latest version found", "latest", release.TagName)
-
-	// Check if update needed
-	if release.TagName == currentVersion() {
-		logger.Info("already up to date")
-		return nil
+	exePath, err = filepath.EvalSymlinks(exePath)
+	if err != nil {
+		return fmt.Errorf("resolve symlinks: %w", err)
 	}
 
-	logger.Info("update available", "current", currentVersion(), "latest", release.TagName)
-
-	// Determine platform identifier
-	platformKey := runtime.GOOS + "-" + runtime.GOARCH
 	switch runtime.GOOS {
 	case "linux", "darwin", "windows":
 		// supported
 	default:
 		return fmt.Errorf("unsupported platform: %s/%s", runtime.GOOS, runtime.GOARCH)
 	}
-
-	// Find download URL — try archive format first (new), then bare binary (old)
-	var downloadURL, assetName string
-	for _, asset := range release.Assets {
-		// New format: skyline-v0.9.17-linux-amd64.tar.gz or .zip
-		if strings.Contains(asset.Name, platformKey) && (strings.HasSuffix(asset.Name, ".tar.gz") || strings.HasSuffix(asset.Name, ".zip")) {
-			downloadURL = asset.BrowserDownloadURL
-			assetName = asset.Name
-			break
-		}
-	}
-	if downloadURL == "" {
-		// Old format: skyline-linux-amd64 (bare binary)
-		bareName := "skyline-" + platformKey
-		if runtime.GOOS == "windows" {
-			bareName += ".exe"
-		}
-		for _, asset := range release.Assets {
-			if asset.Name == bareName {
-				downloadURL = asset.BrowserDownloadURL
-				assetName = asset.Name
-				break
-			}
-		}
-	}
-
-	if downloadURL == "" {
-		return fmt.Errorf("no binary found for %s", platformKey)
-	}
-
-	logger.Info("downloading update", "asset", assetName)
-
-	// Download asset
-	req, err = http.NewRequestWithContext(ctx, "GET", downloadURL, nil)
-	if err != nil {
-		return fmt.Errorf("create download request: %w", err)
+	asset := release.PlatformAsset(runtime.GOOS, runtime.GOARCH)
+	if asset == nil {
+		return fmt.Errorf("no binary found for %s-%s", runtime.GOOS, runtime.GOARCH)
 	}
-
-	resp, err = http.DefaultClient.Do(req)
+	checksums, err := releaseChecksums(ctx, client, release)
 	if err != nil {
-		return fmt.Errorf("download: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("download failed: status %d", resp.StatusCode)
+		return err
 	}
 
-	// Download to temp file
+	logger.Info("downloading update", "asset", asset.Name)
 	tmpArchive, err := os.CreateTemp("", "skyline-update-*")
 	if err != nil {
 		return fmt.Errorf("create temp file: %w", err)
@@ -156,16 +124,19 @@ func runUpdate(logger *slog.Logger) error {
 	tmpArchivePath := tmpArchive.Name()
 	defer os.Remove(tmpArchivePath)
 
-	written, err := io.Copy(tmpArchive, resp.Body)
+	hash := sha256.New()
+	written, err := client.Download(ctx, asset, io.MultiWriter(tmpArchive, hash))
+	tmpArchive.Close()
 	if err != nil {
-		tmpArchive.Close()
-		return fmt.Errorf("download: %w", err)
+		return err
 	}
-	tmpArchive.Close()
-
-	logger.Info("download complete", "bytes", written)
+	if err := selfupdate.VerifyChecksum(checksums, asset.Name, hash.Sum(nil)); err != nil {
+		return err
+	}
+	logger.Info("download complete and checksum verified", "bytes", written)
 
 	// Extract binary from archive (or use directly if bare binary)
+	assetName := asset.Name
 	var binaryPath string
 	if strings.HasSuffix(assetName, ".tar.gz") {
 		binaryPath, err = extractFromTarGz(tmpArchivePath, "skyline")
@@ -222,6 +193,37 @@ func runUpdate(logger *slog.Logger) error {
 	return nil
 }
 
+// releaseChecksums downloads the release's checksums file and, when the
+// binary carries UpdatePublicKey, verifies its signature.
+func releaseChecksums(ctx context.Context, client *selfupdate.Client, release *selfupdate.Release) ([]byte, error) {
+	download := func(name string) ([]byte, error) {
+		asset := release.Asset(name)
+		if asset == nil {
+			return nil, fmt.Errorf("release %s has no %s; refusing to install an unverified binary", release.TagName, name)
+		}
+		var buf bytes.Buffer
+		if _, err := client.Download(ctx, asset, &buf); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	checksums, err := download(selfupdate.ChecksumsAsset)
+	if err != nil {
+		return nil, err
+	}
+	if UpdatePublicKey == "" {
+		return checksums, nil
+	}
+	sig, err := download(selfupdate.SignatureAsset)
+	if err != nil {
+		return nil, err
+	}
+	if err := selfupdate.VerifySignature(UpdatePublicKey, checksums, sig); err != nil {
+		return nil, err
+	}
+	return checksums, nil
+}
+
 // extractFromTarGz extracts a named file from a .tar.gz archive and returns
 // the path to the extracted file in a temp directory.
 func extractFromTarGz(archivePath, targetName string) (string, error) {
@@ -329,9 +331,3 @@ func moveFile(src, dst string) error {
 
 	return os.Remove(src)
 }
-
-// showVersion prints version information
-func showVersion() {
-	fmt.Printf("Skyline MCP Server %s\n", currentVersion())
-	fmt.Printf("Platform: %s/%s\n", runtime.GOOS, runtime.GOARCH)
-}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"skyline-mcp/internal/config"
+)
+
+// runValidateConfig implements `skyline validate`: it checks profile config
+// files (YAML or JSON) and prints line/column-annotated diagnostics, or exports
+// the profile config JSON Schema with --schema.
+// Exit codes: 0 = valid, 1 = validation errors, 2 = usage or I/O error.
+func runValidateConfig(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	schema := fs.Bool("schema", false, "Print the profile config JSON Schema and exit")
+	format := fs.String("format", "text", "Output format: text, json")
+	strict := fs.Bool("strict", false, "Treat warnings as errors")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: skyline validate [--format text|json] [--strict] <config-file>...\n")
+		fmt.Fprintf(stderr, "       skyline validate --schema\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if *schema {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(config.JSONSchema()); err != nil {
+			fmt.Fprintf(stderr, "encode schema: %v\n", err)
+			return 2
+		}
+		return 0
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(stderr, "unsupported format %q\n", *format)
+		return 2
+	}
+
+	type fileResult struct {
+		File        string              `json:"file"`
+		Valid       bool                `json:"valid"`
+		Diagnostics []config.Diagnostic `json:"diagnostics"`
+	}
+	results := make([]fileResult, 0, fs.NArg())
+	failed := false
+	for _, path := range fs.Args() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(stderr, "read %s: %v\n", path, err)
+			return 2
+		}
+		diags := config.Diagnose(data)
+		if diags == nil {
+			diags = []config.Diagnostic{}
+		}
+		valid := !config.HasErrors(diags) && (!*strict || len(diags) == 0)
+		if !valid {
+			failed = true
+		}
+		results = append(results, fileResult{File: path, Valid: valid, Diagnostics: diags})
+	}
+
+	if *format == "json" {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(results)
+	} else {
+		for _, res := range results {
+			for _, d := range res.Diagnostics {
+				sep := " "
+				if d.Line > 0 {
+					sep = "" // d renders as "line:col: ...", giving file:line:col
+				}
+				fmt.Fprintf(stdout, "%s:%s%s\n", res.File, sep, d)
+			}
+			if res.Valid {
+				fmt.Fprintf(stdout, "%s: ok\n", res.File)
+			}
+		}
+	}
+
+	if failed {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"skyline-mcp/internal/selfupdate"
+)
+
+// Commit and BuildDate are set via -ldflags at build time. Builds without
+// them report the VCS information Go stamps into the binary.
+var (
+	Commit    = ""
+	BuildDate = ""
+)
+
+// buildInfo is the build metadata of the running binary.
+type buildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	Modified  bool   `json:"modified,omitempty"` // built from a dirty tree
+	BuildDate string `json:"build_date,omitempty"`
+	GoVersion string `json:"go_version"`
+	Platform  string `json:"platform"`
+}
+
+func currentBuild() buildInfo {
+	info := buildInfo{
+		Version:   currentVersion(),
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range bi.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.BuildDate == "" {
+					info.BuildDate = setting.Value
+				}
+			case "vcs.modified":
+				info.Modified = setting.Value == "true" && Commit == ""
+			}
+		}
+	}
+	return info
+}
+
+// showVersion prints version information
+func showVersion() {
+	info := currentBuild()
+	fmt.Printf("Skyline MCP Server %s\n", info.Version)
+	if info.Commit != "" {
+		modified := ""
+		if info.Modified {
+			modified = " (modified)"
+		}
+		fmt.Printf("Commit: %s%s\n", info.Commit, modified)
+	}
+	if info.BuildDate != "" {
+		fmt.Printf("Built: %s\n", info.BuildDate)
+	}
+	fmt.Printf("Go: %s\n", info.GoVersion)
+	fmt.Printf("Platform: %s\n", info.Platform)
+}
+
+// releaseChecker caches release checks per channel, so /admin/version does
+// not hit the GitHub API on every request.
+type releaseChecker struct {
+	mu       sync.Mutex
+	client   *selfupdate.Client
+	statuses map[string]*selfupdate.Status
+}
+
+var updateChecks = &releaseChecker{client: &selfupdate.Client{HTTP: &http.Client{Timeout: 15 * time.Second}}}
+
+func (c *releaseChecker) check(ctx context.Context, channel string, maxAge time.Duration, refresh bool) (*selfupdate.Status, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if st, ok := c.statuses[channel]; ok && !refresh && time.Since(st.CheckedAt) < maxAge {
+		return st, nil
+	}
+	st, _, err := c.client.Check(ctx, currentVersion(), channel)
+	if err != nil {
+		return nil, err
+	}
+	if c.statuses == nil {
+		c.statuses = map[string]*selfupdate.Status{}
+	}
+	c.statuses[channel] = st
+	return st, nil
+}
+
+// handleAdminVersion reports the build of the running server and whether
+// a newer release is available on the configured update channel.
+// GET /admin/version[?channel=beta][&refresh=true]
+//
+// A failed release check still returns the build, with the error under
+// "update".
+func (s *server) handleAdminVersion(w http.ResponseWriter, r *http.Request) {
+	if !s.isAdminSession(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	channel, maxAge := selfupdate.ChannelStable, time.Hour
+	if s.serverCfg != nil {
+		if s.serverCfg.Updates.Channel != "" {
+			channel = s.serverCfg.Updates.Channel
+		}
+		if s.serverCfg.Updates.CheckInterval > 0 {
+			maxAge = s.serverCfg.Updates.CheckInterval
+		}
+	}
+	if c := r.URL.Query().Get("channel"); c != "" {
+		channel = c
+	}
+	if channel != selfupdate.ChannelStable && channel != selfupdate.ChannelBeta {
+		http.Error(w, fmt.Sprintf("unknown channel %q (expected stable or beta)", channel), http.StatusBadRequest)
+		return
+	}
+
+	resp := map[string]any{"build": currentBuild()}
+	ctx, cancel := context.WithTimeout(r.Context(), 20*time.Second)
+	defer cancel()
+	status, err := updateChecks.check(ctx, channel, maxAge, r.URL.Query().Get("refresh") == "true")
+	if err != nil {
+		resp["update"] = map[string]any{"channel": channel, "error": err.Error()}
+	} else {
+		resp["update"] = status
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
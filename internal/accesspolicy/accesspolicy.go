@@ -0,0 +1,142 @@
+// Package accesspolicy restricts groups of HTTP endpoints to client
+// networks, so a server listening on all interfaces can still keep its
+// management API private.
+package accesspolicy
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"skyline-mcp/internal/serverconfig"
+)
+
+// Endpoint groups a policy can restrict.
+const (
+	Management = "management"
+	Gateway    = "gateway"
+	Metrics    = "metrics"
+)
+
+// Policy holds the allowed networks of each endpoint group.
+type Policy struct {
+	groups  map[string][]netip.Prefix
+	proxies []netip.Prefix
+}
+
+// New parses cfg. A nil cfg yields a policy that allows everything.
+func New(cfg *serverconfig.AccessConfig) (*Policy, error) {
+	p := &Policy{groups: map[string][]netip.Prefix{}}
+	if cfg == nil {
+		return p, nil
+	}
+	for group, entries := range map[string][]string{
+		Management: cfg.Management,
+		Gateway:    cfg.Gateway,
+		Metrics:    cfg.Metrics,
+	} {
+		prefixes, err := parsePrefixes(entries)
+		if err != nil {
+			return nil, fmt.Errorf("security.access.%s: %w", group, err)
+		}
+		if len(prefixes) > 0 {
+			p.groups[group] = prefixes
+		}
+	}
+	proxies, err := parsePrefixes(cfg.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("security.access.trustedProxies: %w", err)
+	}
+	p.proxies = proxies
+	return p, nil
+}
+
+func parsePrefixes(entries []string) ([]netip.Prefix, error) {
+	var out []netip.Prefix
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+	}
+	return out, nil
+}
+
+// Restricted reports whether any group has an allowlist.
+func (p *Policy) Restricted() bool {
+	return p != nil && len(p.groups) > 0
+}
+
+// Allow reports whether the request's client may reach an endpoint in
+// group. Groups without an allowlist are open.
+func (p *Policy) Allow(group string, r *http.Request) bool {
+	if p == nil {
+		return true
+	}
+	prefixes, ok := p.groups[group]
+	if !ok {
+		return true
+	}
+	addr, ok := p.ClientAddr(r)
+	return ok && contains(prefixes, addr)
+}
+
+// ClientAddr returns the address of the client that sent r. The peer
+// address is used unless it is a trusted proxy; then X-Forwarded-For is
+// read from the right, skipping trusted proxies, so a client cannot pick
+// its own address by sending the header itself.
+func (p *Policy) ClientAddr(r *http.Request) (netip.Addr, bool) {
+	addr, ok := parseHost(r.RemoteAddr)
+	if !ok || p == nil || !contains(p.proxies, addr) {
+		return addr, ok
+	}
+	forwarded := r.Header.Values("X-Forwarded-For")
+	if len(forwarded) == 0 {
+		return addr, true
+	}
+	hops := strings.Split(strings.Join(forwarded, ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, ok := parseHost(strings.TrimSpace(hops[i]))
+		if !ok {
+			return netip.Addr{}, false
+		}
+		addr = hop
+		if !contains(p.proxies, hop) {
+			break
+		}
+	}
+	return addr, true
+}
+
+func parseHost(hostport string) (netip.Addr, bool) {
+	host := hostport
+	if h, _, err := net.SplitHostPort(hostport); err == nil {
+		host = h
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+func contains(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package accesspolicy
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"skyline-mcp/internal/serverconfig"
+)
+
+func TestAllow(t *testing.T) {
+	p, err := New(&serverconfig.AccessConfig{
+		Management:     []string{"10.0.0.0/8", "192.168.1.5", "fd00::/8"},
+		Metrics:        []string{"127.0.0.1"},
+		TrustedProxies: []string{"172.16.0.1", "172.16.0.2"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.Restricted() {
+		t.Fatal("policy with allowlists should be restricted")
+	}
+
+	tests := []struct {
+		name, group, remote, forwarded string
+		want                           bool
+	}{
+		{"in cidr", Management, "10.1.2.3:5000", "", true},
+		{"single address", Management, "192.168.1.5:5000", "", true},
+		{"outside", Management, "192.168.1.6:5000", "", false},
+		{"ipv6", Management, "[fd12::1]:5000", "", true},
+		{"ipv4-mapped", Management, "[::ffff:10.0.0.1]:5000", "", true},
+		{"open group", Gateway, "203.0.113.9:5000", "", true},
+		{"metrics loopback", Metrics, "127.0.0.1:9000", "", true},
+		{"metrics remote", Metrics, "10.1.2.3:9000", "", false},
+		{"spoofed header from untrusted peer", Management, "203.0.113.9:5000", "10.1.2.3", false},
+		{"header from trusted proxy", Management, "172.16.0.1:443", "10.1.2.3", true},
+		{"client behind two proxies", Management, "172.16.0.1:443", "10.1.2.3, 172.16.0.2", true},
+		{"client prepends fake hop", Management, "172.16.0.1:443", "10.1.2.3, 203.0.113.9", false},
+		{"proxy without header", Management, "172.16.0.1:443", "", false},
+		{"garbage header", Management, "172.16.0.1:443", "not-an-ip", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = tt.remote
+		if tt.forwarded != "" {
+			r.Header.Set("X-Forwarded-For", tt.forwarded)
+		}
+		if got := p.Allow(tt.group, r); got != tt.want {
+			t.Errorf("%s: Allow = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNilPolicyAllowsEverything(t *testing.T) {
+	p, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest("GET", "/", nil)
+	if p.Restricted() || !p.Allow(Management, r) {
+		t.Fatal("empty policy should allow every request")
+	}
+}
+
+func TestNewRejectsInvalidEntries(t *testing.T) {
+	for _, cfg := range []*serverconfig.AccessConfig{
+		{Management: []string{"10.0.0.0/33"}},
+		{Gateway: []string{"example.com"}},
+		{TrustedProxies: []string{"10.0.0"}},
+	} {
+		if _, err := New(cfg); err == nil {
+			t.Errorf("New(%+v) accepted invalid entry", cfg)
+		}
+	}
+}
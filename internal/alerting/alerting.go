@@ -0,0 +1,348 @@
+// Package alerting watches audit events and notifies webhooks when an
+// API's error rate climbs, a circuit breaker opens, or a client keeps
+// failing to authenticate.
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"skyline-mcp/internal/audit"
+	"skyline-mcp/internal/serverconfig"
+)
+
+// Alert kinds.
+const (
+	KindErrorRate    = "error_rate"
+	KindBreakerOpen  = "breaker_open"
+	KindAuthFailures = "auth_failures"
+)
+
+const (
+	defaultCooldown      = 15 * time.Minute
+	defaultWindow        = 5 * time.Minute
+	defaultMinCalls      = 20
+	defaultAuthThreshold = 10
+	sendTimeout          = 10 * time.Second
+	// slotWidth is the granularity of the error rate window.
+	slotWidth = 10 * time.Second
+)
+
+// Alert is what webhooks receive.
+type Alert struct {
+	Kind    string         `json:"kind"`
+	Profile string         `json:"profile,omitempty"`
+	API     string         `json:"api,omitempty"`
+	Client  string         `json:"client,omitempty"`
+	Message string         `json:"message"`
+	Details map[string]any `json:"details,omitempty"`
+	Time    time.Time      `json:"time"`
+}
+
+// slot counts the calls to an API in one slotWidth of time.
+type slot struct {
+	start         time.Time
+	calls, errors int
+	lastErr       string
+}
+
+type apiKey struct{ profile, api string }
+
+type clientKey struct{ profile, client string }
+
+// Manager turns audit events into alerts. Its methods are safe for
+// concurrent use.
+type Manager struct {
+	cfg    serverconfig.AlertsSection
+	logger *slog.Logger
+	client *http.Client
+	now    func() time.Time
+
+	mu        sync.Mutex
+	calls     map[apiKey][]slot
+	authFails map[clientKey][]time.Time
+	lastSent  map[string]time.Time
+	wg        sync.WaitGroup
+}
+
+// New validates cfg and returns a Manager, or nil when no webhooks are
+// configured.
+func New(cfg serverconfig.AlertsSection, logger *slog.Logger) (*Manager, error) {
+	if len(cfg.Webhooks) == 0 {
+		return nil, nil
+	}
+	for i, wh := range cfg.Webhooks {
+		u, err := url.Parse(wh.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("alerts.webhooks[%d].url: must be an http or https URL", i)
+		}
+		switch wh.Format {
+		case "", "json", "slack":
+		default:
+			return nil, fmt.Errorf("alerts.webhooks[%d].format: must be json or slack, got %q", i, wh.Format)
+		}
+		for _, kind := range wh.Kinds {
+			switch kind {
+			case KindErrorRate, KindBreakerOpen, KindAuthFailures:
+			default:
+				return nil, fmt.Errorf("alerts.webhooks[%d].kinds: unknown kind %q", i, kind)
+			}
+		}
+	}
+	if er := cfg.ErrorRate; er != nil {
+		if er.Threshold <= 0 || er.Threshold > 100 {
+			return nil, fmt.Errorf("alerts.errorRate.threshold: must be a percentage above 0")
+		}
+		if er.Window < 0 || er.MinCalls < 0 {
+			return nil, fmt.Errorf("alerts.errorRate: window and minCalls must not be negative")
+		}
+	}
+	if af := cfg.AuthFailures; af != nil && (af.Threshold < 0 || af.Window < 0) {
+		return nil, fmt.Errorf("alerts.authFailures: threshold and window must not be negative")
+	}
+	if cfg.Cooldown < 0 {
+		return nil, fmt.Errorf("alerts.cooldown: must not be negative")
+	}
+	return &Manager{
+		cfg:       cfg,
+		logger:    logger,
+		client:    &http.Client{Timeout: sendTimeout},
+		now:       time.Now,
+		calls:     map[apiKey][]slot{},
+		authFails: map[clientKey][]time.Time{},
+		lastSent:  map[string]time.Time{},
+	}, nil
+}
+
+// Watch feeds the events published on hub to Observe until ctx is done.
+func (m *Manager) Watch(ctx context.Context, hub *audit.Hub) {
+	id, events := hub.Subscribe()
+	go func() {
+		defer hub.Unsubscribe(id)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e, ok := <-events:
+				if !ok {
+					return
+				}
+				m.Observe(e)
+			}
+		}
+	}()
+}
+
+// Observe updates the alert state with an audit event and sends the
+// alerts it triggers.
+func (m *Manager) Observe(e audit.Event) {
+	switch e.EventType {
+	case "execute":
+		m.observeCall(e)
+	case "breaker_open":
+		if m.cfg.BreakerOpen != nil && !*m.cfg.BreakerOpen {
+			return
+		}
+		m.fire(Alert{
+			Kind:    KindBreakerOpen,
+			Profile: e.Profile,
+			API:     e.APIName,
+			Message: fmt.Sprintf("Circuit breaker opened for API %s in profile %s: %s", e.APIName, e.Profile, e.ErrorMsg),
+			Details: map[string]any{"last_error": e.ErrorMsg},
+		})
+	case "auth_failure":
+		m.observeAuthFailure(e)
+	}
+}
+
+func (m *Manager) observeCall(e audit.Event) {
+	er := m.cfg.ErrorRate
+	if er == nil {
+		return
+	}
+	window := durationOr(er.Window, defaultWindow)
+	minCalls := er.MinCalls
+	if minCalls == 0 {
+		minCalls = defaultMinCalls
+	}
+	now := m.now()
+	key := apiKey{e.Profile, e.APIName}
+
+	m.mu.Lock()
+	slots := m.calls[key]
+	for len(slots) > 0 && now.Sub(slots[0].start) >= window {
+		slots = slots[1:]
+	}
+	start := now.Truncate(slotWidth)
+	if len(slots) == 0 || slots[len(slots)-1].start != start {
+		slots = append(slots, slot{start: start})
+	}
+	cur := &slots[len(slots)-1]
+	cur.calls++
+	if !e.Success {
+		cur.errors++
+		cur.lastErr = e.ErrorMsg
+	}
+	m.calls[key] = slots
+	var calls, errors int
+	lastErr := ""
+	for _, s := range slots {
+		calls += s.calls
+		errors += s.errors
+		if s.lastErr != "" {
+			lastErr = s.lastErr
+		}
+	}
+	m.mu.Unlock()
+
+	if calls < minCalls {
+		return
+	}
+	rate := float64(errors) / float64(calls) * 100
+	if rate < er.Threshold {
+		return
+	}
+	m.fire(Alert{
+		Kind:    KindErrorRate,
+		Profile: e.Profile,
+		API:     e.APIName,
+		Message: fmt.Sprintf("API %s in profile %s failed %d of %d calls (%.0f%%) in the last %s", e.APIName, e.Profile, errors, calls, rate, window),
+		Details: map[string]any{
+			"calls":      calls,
+			"errors":     errors,
+			"error_rate": rate,
+			"window":     window.String(),
+			"last_error": lastErr,
+		},
+	})
+}
+
+func (m *Manager) observeAuthFailure(e audit.Event) {
+	threshold, window := defaultAuthThreshold, defaultWindow
+	if af := m.cfg.AuthFailures; af != nil {
+		if af.Threshold > 0 {
+			threshold = af.Threshold
+		}
+		window = durationOr(af.Window, window)
+	}
+	now := m.now()
+	key := clientKey{e.Profile, e.ClientAddr}
+
+	m.mu.Lock()
+	times := append(m.authFails[key], now)
+	for len(times) > 0 && now.Sub(times[0]) >= window {
+		times = times[1:]
+	}
+	m.authFails[key] = times
+	count := len(times)
+	m.mu.Unlock()
+
+	if count < threshold {
+		return
+	}
+	m.fire(Alert{
+		Kind:    KindAuthFailures,
+		Profile: e.Profile,
+		Client:  e.ClientAddr,
+		Message: fmt.Sprintf("Client %s failed to authenticate to profile %s %d times in the last %s", e.ClientAddr, e.Profile, count, window),
+		Details: map[string]any{"failures": count, "window": window.String(), "reason": e.ErrorMsg},
+	})
+}
+
+// fire sends a to the webhooks that take its kind, unless an alert of the
+// same kind about the same API or client was sent within the cooldown.
+func (m *Manager) fire(a Alert) {
+	a.Time = m.now().UTC()
+	key := strings.Join([]string{a.Kind, a.Profile, a.API, a.Client}, "\x00")
+	m.mu.Lock()
+	if last, ok := m.lastSent[key]; ok && a.Time.Sub(last) < durationOr(m.cfg.Cooldown, defaultCooldown) {
+		m.mu.Unlock()
+		return
+	}
+	m.lastSent[key] = a.Time
+	m.mu.Unlock()
+
+	m.logger.Warn("alert", "kind", a.Kind, "profile", a.Profile, "api", a.API, "client", a.Client, "message", a.Message)
+	for _, wh := range m.cfg.Webhooks {
+		if !takesKind(wh, a.Kind) {
+			continue
+		}
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			if err := m.send(wh, a); err != nil {
+				m.logger.Warn("alert webhook failed", "kind", a.Kind, "host", hostOf(wh.URL), "error", err)
+			}
+		}()
+	}
+}
+
+// Wait blocks until the alerts being sent have been delivered or have
+// failed.
+func (m *Manager) Wait() {
+	m.wg.Wait()
+}
+
+func (m *Manager) send(wh serverconfig.AlertWebhook, a Alert) error {
+	var payload any = a
+	if wh.Format == "slack" {
+		payload = map[string]string{"text": ":rotating_light: *Skyline alert* (" + a.Kind + "): " + a.Message}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, wh.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range wh.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func takesKind(wh serverconfig.AlertWebhook, kind string) bool {
+	if len(wh.Kinds) == 0 {
+		return true
+	}
+	for _, k := range wh.Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// hostOf returns the host of a webhook URL for logs; the rest may hold a
+// secret, as Slack webhook URLs do.
+func hostOf(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil {
+		return u.Host
+	}
+	return ""
+}
+
+func durationOr(d, fallback time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return fallback
+}
@@ -0,0 +1,203 @@
+package alerting
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"skyline-mcp/internal/audit"
+	"skyline-mcp/internal/logging"
+	"skyline-mcp/internal/serverconfig"
+)
+
+// webhook records the bodies POSTed to it.
+type webhook struct {
+	*httptest.Server
+	mu     sync.Mutex
+	bodies []map[string]any
+	header http.Header
+}
+
+func newWebhook(t *testing.T) *webhook {
+	t.Helper()
+	wh := &webhook{}
+	wh.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode webhook body: %v", err)
+		}
+		wh.mu.Lock()
+		wh.bodies = append(wh.bodies, body)
+		wh.header = r.Header.Clone()
+		wh.mu.Unlock()
+	}))
+	t.Cleanup(wh.Close)
+	return wh
+}
+
+func (wh *webhook) received() []map[string]any {
+	wh.mu.Lock()
+	defer wh.mu.Unlock()
+	return append([]map[string]any(nil), wh.bodies...)
+}
+
+// newManager returns a Manager whose clock the returned func advances.
+func newManager(t *testing.T, cfg serverconfig.AlertsSection) (*Manager, func(time.Duration)) {
+	t.Helper()
+	m, err := New(cfg, logging.Discard())
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+	return m, func(d time.Duration) { now = now.Add(d) }
+}
+
+func call(api string, ok bool) audit.Event {
+	e := audit.Event{EventType: "execute", Profile: "dev", APIName: api, Success: ok}
+	if !ok {
+		e.ErrorMsg = "upstream returned 502"
+	}
+	return e
+}
+
+func TestErrorRate(t *testing.T) {
+	wh := newWebhook(t)
+	m, advance := newManager(t, serverconfig.AlertsSection{
+		Webhooks:  []serverconfig.AlertWebhook{{URL: wh.URL, Headers: map[string]string{"X-Token": "secret"}}},
+		ErrorRate: &serverconfig.ErrorRateAlert{Threshold: 50, MinCalls: 4},
+	})
+
+	// Three failures are below minCalls; a success brings the rate to 75%.
+	for i := 0; i < 3; i++ {
+		m.Observe(call("pets", false))
+	}
+	m.Wait()
+	if got := wh.received(); len(got) != 0 {
+		t.Fatalf("alerted below minCalls: %v", got)
+	}
+	m.Observe(call("pets", true))
+	m.Observe(call("other", false))
+	m.Wait()
+	got := wh.received()
+	if len(got) != 1 {
+		t.Fatalf("alerts = %v, want 1", got)
+	}
+	a := got[0]
+	details, _ := a["details"].(map[string]any)
+	if a["kind"] != KindErrorRate || a["api"] != "pets" || details["calls"] != float64(4) || details["last_error"] != "upstream returned 502" {
+		t.Fatalf("alert = %v", a)
+	}
+	if wh.header.Get("X-Token") != "secret" {
+		t.Fatalf("headers = %v", wh.header)
+	}
+
+	// The cooldown holds back the next alert about the API.
+	m.Observe(call("pets", false))
+	m.Wait()
+	if n := len(wh.received()); n != 1 {
+		t.Fatalf("alerts = %d during cooldown", n)
+	}
+
+	// Past the window and the cooldown, old calls no longer count.
+	advance(20 * time.Minute)
+	m.Observe(call("pets", false))
+	m.Wait()
+	if n := len(wh.received()); n != 1 {
+		t.Fatalf("alerts = %d; calls outside the window counted", n)
+	}
+	for i := 0; i < 3; i++ {
+		m.Observe(call("pets", false))
+	}
+	m.Wait()
+	if n := len(wh.received()); n != 2 {
+		t.Fatalf("alerts = %d after the cooldown, want 2", n)
+	}
+}
+
+func TestBreakerOpen(t *testing.T) {
+	all, slack := newWebhook(t), newWebhook(t)
+	m, _ := newManager(t, serverconfig.AlertsSection{Webhooks: []serverconfig.AlertWebhook{
+		{URL: all.URL, Kinds: []string{KindErrorRate}},
+		{URL: slack.URL, Format: "slack", Kinds: []string{KindBreakerOpen}},
+	}})
+
+	m.Observe(audit.Event{EventType: "breaker_closed", Profile: "dev", APIName: "pets"})
+	m.Observe(audit.Event{EventType: "breaker_open", Profile: "dev", APIName: "pets", ErrorMsg: "dial tcp: connection refused"})
+	m.Wait()
+	if got := all.received(); len(got) != 0 {
+		t.Fatalf("webhook without the kind got %v", got)
+	}
+	got := slack.received()
+	if len(got) != 1 {
+		t.Fatalf("alerts = %v, want 1", got)
+	}
+	text, _ := got[0]["text"].(string)
+	if !strings.Contains(text, "breaker opened for API pets") || !strings.Contains(text, "connection refused") {
+		t.Fatalf("slack text = %q", text)
+	}
+}
+
+func TestBreakerOpenDisabled(t *testing.T) {
+	wh := newWebhook(t)
+	off := false
+	m, _ := newManager(t, serverconfig.AlertsSection{
+		Webhooks:    []serverconfig.AlertWebhook{{URL: wh.URL}},
+		BreakerOpen: &off,
+	})
+	m.Observe(audit.Event{EventType: "breaker_open", Profile: "dev", APIName: "pets"})
+	m.Wait()
+	if got := wh.received(); len(got) != 0 {
+		t.Fatalf("alerts = %v", got)
+	}
+}
+
+func TestAuthFailures(t *testing.T) {
+	wh := newWebhook(t)
+	m, advance := newManager(t, serverconfig.AlertsSection{
+		Webhooks:     []serverconfig.AlertWebhook{{URL: wh.URL}},
+		AuthFailures: &serverconfig.AuthFailureAlert{Threshold: 3, Window: time.Minute},
+	})
+	fail := func(client string) {
+		m.Observe(audit.Event{EventType: "auth_failure", Profile: "dev", ClientAddr: client, ErrorMsg: "invalid token"})
+	}
+
+	fail("10.0.0.1")
+	fail("10.0.0.1")
+	fail("10.0.0.2")
+	advance(2 * time.Minute)
+	fail("10.0.0.1")
+	m.Wait()
+	if got := wh.received(); len(got) != 0 {
+		t.Fatalf("alerted below the threshold: %v", got)
+	}
+	fail("10.0.0.1")
+	fail("10.0.0.1")
+	m.Wait()
+	got := wh.received()
+	if len(got) != 1 || got[0]["kind"] != KindAuthFailures || got[0]["client"] != "10.0.0.1" {
+		t.Fatalf("alerts = %v", got)
+	}
+}
+
+func TestNew(t *testing.T) {
+	m, err := New(serverconfig.AlertsSection{ErrorRate: &serverconfig.ErrorRateAlert{Threshold: 10}}, logging.Discard())
+	if m != nil || err != nil {
+		t.Fatalf("New without webhooks = %v, %v", m, err)
+	}
+	tests := map[string]serverconfig.AlertsSection{
+		"url":       {Webhooks: []serverconfig.AlertWebhook{{URL: "ftp://example.com"}}},
+		"format":    {Webhooks: []serverconfig.AlertWebhook{{URL: "https://example.com", Format: "teams"}}},
+		"kinds":     {Webhooks: []serverconfig.AlertWebhook{{URL: "https://example.com", Kinds: []string{"latency"}}}},
+		"threshold": {Webhooks: []serverconfig.AlertWebhook{{URL: "https://example.com"}}, ErrorRate: &serverconfig.ErrorRateAlert{Threshold: 150}},
+	}
+	for field, cfg := range tests {
+		if _, err := New(cfg, logging.Discard()); err == nil || !strings.Contains(err.Error(), field) {
+			t.Errorf("%s: err = %v", field, err)
+		}
+	}
+}
@@ -0,0 +1,365 @@
+package audit
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Tool calls are rolled up per UTC hour, profile and tool as they are
+// flushed, so analytics do not scan the events and outlive their rotation.
+// Latencies go into a fixed histogram; percentiles are estimated from it.
+
+// latencyBounds are the upper bounds, in ms, of the histogram buckets; one
+// more bucket counts everything slower.
+var latencyBounds = []int64{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000, 30000, 60000}
+
+// rollupRetention is how long rollups are kept when events are rotated
+// sooner.
+const rollupRetention = 90 * 24 * time.Hour
+
+const rollupSchema = `
+	CREATE TABLE IF NOT EXISTS tool_rollups (
+		hour INTEGER NOT NULL,
+		profile TEXT NOT NULL,
+		api_name TEXT NOT NULL,
+		tool_name TEXT NOT NULL,
+		calls INTEGER NOT NULL,
+		errors INTEGER NOT NULL,
+		total_ms INTEGER NOT NULL,
+		max_ms INTEGER NOT NULL,
+		histogram TEXT NOT NULL,
+		PRIMARY KEY (hour, profile, api_name, tool_name)
+	);
+`
+
+// rollup is the aggregate of one hour of calls to one tool.
+type rollup struct {
+	calls, errors  int64
+	totalMs, maxMs int64
+	histogram      []int64
+}
+
+type rollupKey struct {
+	hour               int64
+	profile, api, tool string
+}
+
+func (r *rollup) add(durationMs int64, success bool) {
+	if r.histogram == nil {
+		r.histogram = make([]int64, len(latencyBounds)+1)
+	}
+	r.calls++
+	if !success {
+		r.errors++
+	}
+	r.totalMs += durationMs
+	r.maxMs = max(r.maxMs, durationMs)
+	i := sort.Search(len(latencyBounds), func(i int) bool { return durationMs <= latencyBounds[i] })
+	r.histogram[i]++
+}
+
+func (r *rollup) merge(o rollup) {
+	if r.histogram == nil {
+		r.histogram = make([]int64, len(latencyBounds)+1)
+	}
+	r.calls += o.calls
+	r.errors += o.errors
+	r.totalMs += o.totalMs
+	r.maxMs = max(r.maxMs, o.maxMs)
+	for i := 0; i < len(o.histogram) && i < len(r.histogram); i++ {
+		r.histogram[i] += o.histogram[i]
+	}
+}
+
+// percentile estimates the q-th quantile (0 < q < 1) by interpolating
+// within the histogram bucket it falls in. The open-ended last bucket
+// ends at the slowest call.
+func (r *rollup) percentile(q float64) int64 {
+	if r.calls == 0 {
+		return 0
+	}
+	rank := q * float64(r.calls)
+	var seen int64
+	for i, n := range r.histogram {
+		if n == 0 || float64(seen+n) < rank {
+			seen += n
+			continue
+		}
+		lower, upper := int64(0), r.maxMs
+		if i > 0 {
+			lower = latencyBounds[i-1]
+		}
+		if i < len(latencyBounds) {
+			upper = min(latencyBounds[i], r.maxMs)
+		}
+		if upper < lower {
+			return upper
+		}
+		frac := (rank - float64(seen)) / float64(n)
+		return lower + int64(frac*float64(upper-lower))
+	}
+	return r.maxMs
+}
+
+// rollupEvents aggregates the execute events of a flush.
+func rollupEvents(events []Event) map[rollupKey]*rollup {
+	rollups := map[rollupKey]*rollup{}
+	for _, e := range events {
+		if e.EventType != "execute" {
+			continue
+		}
+		key := rollupKey{e.Timestamp.UTC().Truncate(time.Hour).Unix(), e.Profile, e.APIName, e.ToolName}
+		r := rollups[key]
+		if r == nil {
+			r = &rollup{}
+			rollups[key] = r
+		}
+		r.add(e.DurationMs, e.Success)
+	}
+	return rollups
+}
+
+// saveRollups merges rollups into the stored ones.
+func saveRollups(tx *sql.Tx, rollups map[rollupKey]*rollup) error {
+	for key, r := range rollups {
+		var stored rollup
+		var histogram string
+		err := tx.QueryRow(`SELECT calls, errors, total_ms, max_ms, histogram FROM tool_rollups
+			WHERE hour = ? AND profile = ? AND api_name = ? AND tool_name = ?`,
+			key.hour, key.profile, key.api, key.tool).Scan(&stored.calls, &stored.errors, &stored.totalMs, &stored.maxMs, &histogram)
+		switch {
+		case err == sql.ErrNoRows:
+		case err != nil:
+			return fmt.Errorf("read rollup: %w", err)
+		default:
+			_ = json.Unmarshal([]byte(histogram), &stored.histogram)
+		}
+		stored.merge(*r)
+		data, _ := json.Marshal(stored.histogram)
+		if _, err := tx.Exec(`INSERT OR REPLACE INTO tool_rollups
+			(hour, profile, api_name, tool_name, calls, errors, total_ms, max_ms, histogram)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			key.hour, key.profile, key.api, key.tool, stored.calls, stored.errors, stored.totalMs, stored.maxMs, string(data)); err != nil {
+			return fmt.Errorf("write rollup: %w", err)
+		}
+	}
+	return nil
+}
+
+// backfillRollups builds the rollups of an audit database that predates
+// them from its stored execute events.
+func (l *Logger) backfillRollups() error {
+	var n int
+	if err := l.db.QueryRow(`SELECT COUNT(*) FROM tool_rollups`).Scan(&n); err != nil || n > 0 {
+		return err
+	}
+	rows, err := l.db.Query(`SELECT timestamp, profile, COALESCE(api_name, ''), COALESCE(tool_name, ''),
+		COALESCE(duration_ms, 0), success FROM audit_events WHERE event_type = 'execute'`)
+	if err != nil {
+		return fmt.Errorf("read events: %w", err)
+	}
+	var events []Event
+	for rows.Next() {
+		e := Event{EventType: "execute"}
+		if err := rows.Scan(&e.Timestamp, &e.Profile, &e.APIName, &e.ToolName, &e.DurationMs, &e.Success); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan event: %w", err)
+		}
+		events = append(events, e)
+	}
+	rows.Close()
+	if len(events) == 0 {
+		return nil
+	}
+	tx, err := l.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if err := saveRollups(tx, rollupEvents(events)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// AnalyticsOptions select the calls Analytics aggregates.
+type AnalyticsOptions struct {
+	Profile string
+	Tool    string
+	Since   time.Time
+	Until   time.Time // default now
+	// Bucket is the width of the series points: "hour" (default) or "day".
+	Bucket string
+	// Limit caps the tools listed, busiest first; default 20.
+	Limit int
+}
+
+// Analytics is the call volume, error rate and latency of tools over a
+// time window.
+type Analytics struct {
+	Since  time.Time `json:"since"`
+	Until  time.Time `json:"until"`
+	Bucket string    `json:"bucket"`
+	// Totals covers every call in the window.
+	Totals ToolAnalytics `json:"totals"`
+	// Tools are per profile and tool, busiest first.
+	Tools []ToolAnalytics `json:"tools"`
+	// Series has one point per bucket in the window, empty ones included.
+	Series []AnalyticsPoint `json:"series"`
+}
+
+// ToolAnalytics aggregates the calls to one tool of one profile. Latency
+// percentiles are estimates.
+type ToolAnalytics struct {
+	Profile   string  `json:"profile,omitempty"`
+	API       string  `json:"api,omitempty"`
+	Tool      string  `json:"tool,omitempty"`
+	Calls     int64   `json:"calls"`
+	Errors    int64   `json:"errors"`
+	ErrorRate float64 `json:"error_rate"`
+	AvgMs     int64   `json:"avg_ms"`
+	P50Ms     int64   `json:"p50_ms"`
+	P95Ms     int64   `json:"p95_ms"`
+	P99Ms     int64   `json:"p99_ms"`
+	MaxMs     int64   `json:"max_ms"`
+}
+
+// AnalyticsPoint aggregates the calls of one bucket.
+type AnalyticsPoint struct {
+	Start     time.Time `json:"start"`
+	Calls     int64     `json:"calls"`
+	Errors    int64     `json:"errors"`
+	ErrorRate float64   `json:"error_rate"`
+	P50Ms     int64     `json:"p50_ms"`
+	P95Ms     int64     `json:"p95_ms"`
+}
+
+// Analytics aggregates the tool calls of the window in opts from the
+// hourly rollups; the window is widened to whole buckets. Calls still
+// buffered are flushed first.
+func (l *Logger) Analytics(opts AnalyticsOptions) (*Analytics, error) {
+	var width time.Duration
+	switch opts.Bucket {
+	case "", "hour":
+		opts.Bucket, width = "hour", time.Hour
+	case "day":
+		width = 24 * time.Hour
+	default:
+		return nil, fmt.Errorf("bucket must be hour or day, got %q", opts.Bucket)
+	}
+	if opts.Until.IsZero() {
+		opts.Until = time.Now()
+	}
+	if opts.Since.IsZero() {
+		opts.Since = opts.Until.Add(-24 * time.Hour)
+	}
+	since := opts.Since.UTC().Truncate(width)
+	until := opts.Until.UTC()
+	if !until.After(since) {
+		return nil, fmt.Errorf("since must be before until")
+	}
+	if until.Sub(since)/width > 2000 {
+		return nil, fmt.Errorf("window has more than 2000 %s buckets", opts.Bucket)
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	if err := l.Flush(); err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	query := `SELECT hour, profile, api_name, tool_name, calls, errors, total_ms, max_ms, histogram
+		FROM tool_rollups WHERE hour >= ? AND hour < ?`
+	args := []any{since.Unix(), until.Unix()}
+	if opts.Profile != "" {
+		query += " AND profile = ?"
+		args = append(args, opts.Profile)
+	}
+	if opts.Tool != "" {
+		query += " AND tool_name = ?"
+		args = append(args, opts.Tool)
+	}
+	rows, err := l.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query rollups: %w", err)
+	}
+	defer rows.Close()
+
+	type toolKey struct{ profile, api, tool string }
+	var totals rollup
+	tools := map[toolKey]*rollup{}
+	points := map[int64]*rollup{}
+	for rows.Next() {
+		var key rollupKey
+		var r rollup
+		var histogram string
+		if err := rows.Scan(&key.hour, &key.profile, &key.api, &key.tool, &r.calls, &r.errors, &r.totalMs, &r.maxMs, &histogram); err != nil {
+			return nil, fmt.Errorf("scan rollup: %w", err)
+		}
+		_ = json.Unmarshal([]byte(histogram), &r.histogram)
+		totals.merge(r)
+		tk := toolKey{key.profile, key.api, key.tool}
+		if tools[tk] == nil {
+			tools[tk] = &rollup{}
+		}
+		tools[tk].merge(r)
+		start := time.Unix(key.hour, 0).UTC().Truncate(width).Unix()
+		if points[start] == nil {
+			points[start] = &rollup{}
+		}
+		points[start].merge(r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read rollups: %w", err)
+	}
+
+	a := &Analytics{Since: since, Until: until, Bucket: opts.Bucket, Totals: toolAnalytics(&totals)}
+	for key, r := range tools {
+		t := toolAnalytics(r)
+		t.Profile, t.API, t.Tool = key.profile, key.api, key.tool
+		a.Tools = append(a.Tools, t)
+	}
+	sort.Slice(a.Tools, func(i, j int) bool {
+		if a.Tools[i].Calls != a.Tools[j].Calls {
+			return a.Tools[i].Calls > a.Tools[j].Calls
+		}
+		if a.Tools[i].Profile != a.Tools[j].Profile {
+			return a.Tools[i].Profile < a.Tools[j].Profile
+		}
+		return a.Tools[i].Tool < a.Tools[j].Tool
+	})
+	if len(a.Tools) > limit {
+		a.Tools = a.Tools[:limit]
+	}
+	for start := since; start.Before(until); start = start.Add(width) {
+		p := AnalyticsPoint{Start: start}
+		if r := points[start.Unix()]; r != nil {
+			t := toolAnalytics(r)
+			p.Calls, p.Errors, p.ErrorRate, p.P50Ms, p.P95Ms = t.Calls, t.Errors, t.ErrorRate, t.P50Ms, t.P95Ms
+		}
+		a.Series = append(a.Series, p)
+	}
+	return a, nil
+}
+
+func toolAnalytics(r *rollup) ToolAnalytics {
+	t := ToolAnalytics{
+		Calls:  r.calls,
+		Errors: r.errors,
+		P50Ms:  r.percentile(0.50),
+		P95Ms:  r.percentile(0.95),
+		P99Ms:  r.percentile(0.99),
+		MaxMs:  r.maxMs,
+	}
+	if r.calls > 0 {
+		t.ErrorRate = float64(r.errors) / float64(r.calls) * 100
+		t.AvgMs = r.totalMs / r.calls
+	}
+	return t
+}
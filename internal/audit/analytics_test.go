@@ -0,0 +1,150 @@
+package audit
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var analyticsHour = time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+
+// logCalls flushes one execute event per call, each in its own flush so
+// the rollups are merged.
+func logCalls(t *testing.T, l *Logger, calls ...Event) {
+	t.Helper()
+	for _, e := range calls {
+		e.EventType = "execute"
+		l.bufferEvent(e)
+		if err := l.Flush(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func analyticsCalls() []Event {
+	return []Event{
+		{Timestamp: analyticsHour.Add(5 * time.Minute), Profile: "ops", APIName: "api", ToolName: "api__get", DurationMs: 8, Success: true},
+		{Timestamp: analyticsHour.Add(10 * time.Minute), Profile: "ops", APIName: "api", ToolName: "api__get", DurationMs: 400},
+		{Timestamp: analyticsHour.Add(61 * time.Minute), Profile: "billing", APIName: "api", ToolName: "api__list", DurationMs: 20, Success: true},
+		// Outside the window.
+		{Timestamp: analyticsHour.Add(-time.Minute), Profile: "ops", APIName: "api", ToolName: "api__get", DurationMs: 1, Success: true},
+	}
+}
+
+func TestRollupPercentile(t *testing.T) {
+	var r rollup
+	for i := 0; i < 90; i++ {
+		r.add(8, true)
+	}
+	for i := 0; i < 10; i++ {
+		r.add(400, false)
+	}
+	// p50 falls in (5, 10], p95 and p99 in (250, 500] which ends at the
+	// slowest call.
+	for q, want := range map[float64]int64{0.50: 7, 0.95: 325, 0.99: 385, 1: 400} {
+		if got := r.percentile(q); got != want {
+			t.Errorf("percentile(%v) = %d, want %d", q, got, want)
+		}
+	}
+	if got := (&rollup{}).percentile(0.5); got != 0 {
+		t.Errorf("percentile of no calls = %d", got)
+	}
+	if t1 := toolAnalytics(&r); t1.Calls != 100 || t1.Errors != 10 || t1.ErrorRate != 10 || t1.AvgMs != 47 || t1.MaxMs != 400 {
+		t.Errorf("toolAnalytics = %+v", t1)
+	}
+}
+
+func TestAnalytics(t *testing.T) {
+	l, err := NewLogger(filepath.Join(t.TempDir(), "audit.db"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	logCalls(t, l, analyticsCalls()...)
+	window := AnalyticsOptions{Since: analyticsHour, Until: analyticsHour.Add(3 * time.Hour)}
+
+	a, err := l.Analytics(window)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Bucket != "hour" || a.Totals.Calls != 3 || a.Totals.Errors != 1 || a.Totals.MaxMs != 400 {
+		t.Errorf("totals = %s %+v", a.Bucket, a.Totals)
+	}
+	if len(a.Tools) != 2 {
+		t.Fatalf("tools = %+v", a.Tools)
+	}
+	if ops := a.Tools[0]; ops.Profile != "ops" || ops.API != "api" || ops.Tool != "api__get" || ops.Calls != 2 || ops.ErrorRate != 50 || ops.AvgMs != 204 {
+		t.Errorf("busiest tool = %+v", ops)
+	}
+	if len(a.Series) != 3 || a.Series[0].Calls != 2 || a.Series[1].Calls != 1 || a.Series[2].Calls != 0 || !a.Series[2].Start.Equal(analyticsHour.Add(2*time.Hour)) {
+		t.Errorf("series = %+v", a.Series)
+	}
+
+	for name, tc := range map[string]struct {
+		opts  AnalyticsOptions
+		calls int64
+		tools int
+	}{
+		"profile": {AnalyticsOptions{Profile: "billing"}, 1, 1},
+		"tool":    {AnalyticsOptions{Tool: "api__get"}, 2, 1},
+		"limit":   {AnalyticsOptions{Limit: 1}, 3, 1},
+	} {
+		tc.opts.Since, tc.opts.Until = window.Since, window.Until
+		a, err := l.Analytics(tc.opts)
+		if err != nil || a.Totals.Calls != tc.calls || len(a.Tools) != tc.tools {
+			t.Errorf("%s: %+v, %v", name, a, err)
+		}
+	}
+
+	// Day buckets start at midnight UTC and take in the earlier call.
+	a, err = l.Analytics(AnalyticsOptions{Since: window.Since, Until: window.Until, Bucket: "day"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Series) != 1 || a.Series[0].Calls != 4 || !a.Since.Equal(analyticsHour.Truncate(24*time.Hour)) {
+		t.Errorf("daily = %+v", a)
+	}
+
+	for name, tc := range map[string]struct {
+		opts AnalyticsOptions
+		err  string
+	}{
+		"bucket":      {AnalyticsOptions{Bucket: "week"}, "bucket must be hour or day"},
+		"empty":       {AnalyticsOptions{Since: window.Until, Until: window.Since}, "since must be before until"},
+		"too long":    {AnalyticsOptions{Since: window.Since.AddDate(-1, 0, 0), Until: window.Since}, "more than 2000 hour buckets"},
+		"long enough": {AnalyticsOptions{Since: window.Since.AddDate(-1, 0, 0), Until: window.Since, Bucket: "day"}, ""},
+	} {
+		_, err := l.Analytics(tc.opts)
+		if tc.err == "" && err != nil || tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)) {
+			t.Errorf("%s: %v, want %q", name, err, tc.err)
+		}
+	}
+}
+
+func TestBackfillRollups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.db")
+	l, err := NewLogger(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logCalls(t, l, analyticsCalls()...)
+	// A database written before rollups existed.
+	if _, err := l.db.Exec(`DELETE FROM tool_rollups`); err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+
+	l, err = NewLogger(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	a, err := l.Analytics(AnalyticsOptions{Since: analyticsHour, Until: analyticsHour.Add(3 * time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Totals.Calls != 3 || a.Totals.Errors != 1 || a.Totals.MaxMs != 400 || a.Tools[0].P95Ms == 0 {
+		t.Errorf("backfilled analytics = %+v", a.Totals)
+	}
+}
@@ -2,8 +2,11 @@ package audit
 
 import (
 	"context"
+	"crypto/ed25519"
 	"database/sql"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"strings"
@@ -11,6 +14,10 @@ import (
 	"time"
 
 	_ "modernc.org/sqlite"
+
+	"skyline-mcp/internal/lockout"
+	"skyline-mcp/internal/redact"
+	"skyline-mcp/internal/schemalearn"
 )
 
 // Event represents an audit log entry
@@ -18,7 +25,7 @@ type Event struct {
 	ID           int64                  `json:"id"`
 	Timestamp    time.Time              `json:"timestamp"`
 	Profile      string                 `json:"profile"`
-	EventType    string                 `json:"event_type"` // "execute", "connect", "disconnect", "error"
+	EventType    string                 `json:"event_type"` // "execute", "code", "connect", "disconnect", "error", "auth_failure", "auth_lockout", "breaker_open", "breaker_closed", "ssh"
 	APIName      string                 `json:"api_name,omitempty"`
 	ToolName     string                 `json:"tool_name,omitempty"`
 	Arguments    map[string]interface{} `json:"arguments,omitempty"`
@@ -35,6 +42,7 @@ type Event struct {
 type Logger struct {
 	db           *sql.DB
 	mu           sync.Mutex
+	rotateMu     sync.Mutex // held by rotation and by Verify, outside mu
 	batchSize    int
 	flushTicker  *time.Ticker
 	buffer       []Event
@@ -42,6 +50,14 @@ type Logger struct {
 	hub          *Hub
 	rotateAfter  time.Duration
 	rotateTicker *time.Ticker
+	head         string             // hash of the newest event, guarded by mu
+	signer       ed25519.PrivateKey // signs checkpoints when set
+	// markBoundary is set when the log predates rotation checkpoints, so
+	// the first signing key records where the retained events start.
+	markBoundary bool
+	redactor     *redact.Redactor // applied to arguments and messages when set
+	sinks        []*sinkQueue     // receive events once stored
+	sinksMu      sync.Mutex
 }
 
 // NewLogger creates a new audit logger.
@@ -77,9 +93,44 @@ func NewLogger(dbPath string, rotateAfter time.Duration) (*Logger, error) {
 	CREATE INDEX IF NOT EXISTS idx_audit_profile ON audit_events(profile);
 	CREATE INDEX IF NOT EXISTS idx_audit_event_type ON audit_events(event_type);
 	CREATE INDEX IF NOT EXISTS idx_audit_tool_name ON audit_events(tool_name);
+
+	CREATE TABLE IF NOT EXISTS audit_checkpoints (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		event_id INTEGER NOT NULL,
+		hash TEXT NOT NULL,
+		created_at DATETIME NOT NULL,
+		signature TEXT NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS quota_usage (
+		profile TEXT NOT NULL,
+		scope TEXT NOT NULL,
+		period TEXT NOT NULL,
+		calls INTEGER NOT NULL,
+		PRIMARY KEY (profile, scope, period)
+	);
+
+	CREATE TABLE IF NOT EXISTS auth_failures (
+		profile TEXT NOT NULL,
+		client_addr TEXT NOT NULL,
+		failures INTEGER NOT NULL,
+		lockouts INTEGER NOT NULL,
+		last_failure INTEGER NOT NULL,
+		locked_until INTEGER NOT NULL,
+		PRIMARY KEY (profile, client_addr)
+	);
+
+	CREATE TABLE IF NOT EXISTS learned_schemas (
+		profile TEXT NOT NULL,
+		operation TEXT NOT NULL,
+		schema TEXT NOT NULL,
+		samples INTEGER NOT NULL,
+		updated_at INTEGER NOT NULL,
+		PRIMARY KEY (profile, operation)
+	);
 	`
 
-	if _, err := db.Exec(schema); err != nil {
+	if _, err := db.Exec(schema + rollupSchema); err != nil {
 		return nil, fmt.Errorf("create schema: %w", err)
 	}
 
@@ -87,13 +138,26 @@ func NewLogger(dbPath string, rotateAfter time.Duration) (*Logger, error) {
 	_, _ = db.Exec(`ALTER TABLE audit_events ADD COLUMN api_name TEXT`)
 	// Index after migration so the column is guaranteed to exist
 	_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS idx_audit_api_name ON audit_events(api_name)`)
+	// Migrate: hash chain columns; events logged before stay unchained
+	_, _ = db.Exec(`ALTER TABLE audit_events ADD COLUMN prev_hash TEXT`)
+	_, _ = db.Exec(`ALTER TABLE audit_events ADD COLUMN hash TEXT`)
+	// Migrate: checkpoint kinds; "rotation" rows mark where rotation cut
+	// the chain
+	_, kindErr := db.Exec(`ALTER TABLE audit_checkpoints ADD COLUMN kind TEXT NOT NULL DEFAULT 'head'`)
 
 	logger := &Logger{
-		db:          db,
-		batchSize:   100,
-		buffer:      make([]Event, 0, 100),
-		hub:         NewHub(),
-		rotateAfter: rotateAfter,
+		db:           db,
+		batchSize:    100,
+		buffer:       make([]Event, 0, 100),
+		hub:          NewHub(),
+		rotateAfter:  rotateAfter,
+		markBoundary: kindErr == nil,
+	}
+	if err := logger.loadHead(); err != nil {
+		return nil, err
+	}
+	if err := logger.backfillRollups(); err != nil {
+		return nil, fmt.Errorf("backfill tool rollups: %w", err)
 	}
 
 	// Start background flusher (every 5 seconds)
@@ -109,8 +173,19 @@ func NewLogger(dbPath string, rotateAfter time.Duration) (*Logger, error) {
 	return logger, nil
 }
 
+// SetRedactor makes the logger redact arguments, code and error messages
+// before events are stored or published.
+func (l *Logger) SetRedactor(r *redact.Redactor) {
+	l.redactor = r
+}
+
 // LogExecute logs a tool execution event
 func (l *Logger) LogExecute(ctx context.Context, profile, apiName, toolName string, args map[string]interface{}, duration time.Duration, statusCode int, success bool, errMsg, clientAddr string, requestSize, responseSize int64) {
+	if l.redactor != nil {
+		r := l.redactor.ForAPI(apiName)
+		args, _ = r.RedactValue(args).(map[string]interface{})
+		errMsg = r.Redact(errMsg)
+	}
 	event := Event{
 		Timestamp:    time.Now(),
 		Profile:      profile,
@@ -130,8 +205,132 @@ func (l *Logger) LogExecute(ctx context.Context, profile, apiName, toolName stri
 	l.bufferEvent(event)
 }
 
+// LogCodeExecution logs a code execution event: the script, the engine it
+// ran in and the tools it called are stored in Arguments. The tool calls
+// themselves are logged as execute events as well.
+func (l *Logger) LogCodeExecution(ctx context.Context, profile, engine, code string, toolsCalled []string, duration time.Duration, exitCode int, errMsg, clientAddr string) {
+	if toolsCalled == nil {
+		toolsCalled = []string{}
+	}
+	size := int64(len(code))
+	if l.redactor != nil {
+		code, errMsg = l.redactor.Redact(code), l.redactor.Redact(errMsg)
+	}
+	event := Event{
+		Timestamp: time.Now(),
+		Profile:   profile,
+		EventType: "code",
+		Arguments: map[string]interface{}{
+			"code":         code,
+			"engine":       engine,
+			"tools_called": toolsCalled,
+			"exit_code":    exitCode,
+		},
+		DurationMs:  duration.Milliseconds(),
+		Success:     exitCode == 0 && errMsg == "",
+		ErrorMsg:    errMsg,
+		ClientAddr:  clientAddr,
+		RequestSize: size,
+	}
+
+	l.bufferEvent(event)
+}
+
+// LogDataPolicy logs the response fields a data policy masked, hashed or
+// dropped from a tool result. Only field paths are recorded, never values.
+func (l *Logger) LogDataPolicy(ctx context.Context, profile, apiName, toolName string, filtered interface{}) {
+	event := Event{
+		Timestamp: time.Now(),
+		Profile:   profile,
+		EventType: "data_policy",
+		APIName:   apiName,
+		ToolName:  toolName,
+		Arguments: map[string]interface{}{"filtered": filtered},
+		Success:   true,
+	}
+
+	l.bufferEvent(event)
+}
+
+// RemoteCommand is a command an ssh tool ran on a host, with its output.
+type RemoteCommand struct {
+	Host      string
+	User      string
+	Command   string
+	ExitCode  int // -1 when the command ended without an exit status
+	Signal    string
+	Stdout    string
+	Stderr    string
+	Truncated bool
+}
+
+// LogRemoteCommand logs a command run over SSH: the command line, its exit
+// status and the output kept of it are stored in Arguments. The tool call
+// itself is logged as an execute event as well.
+func (l *Logger) LogRemoteCommand(ctx context.Context, profile, apiName, toolName string, cmd RemoteCommand, duration time.Duration, errMsg string) {
+	if l.redactor != nil {
+		r := l.redactor.ForAPI(apiName)
+		cmd.Command, cmd.Stdout, cmd.Stderr = r.Redact(cmd.Command), r.Redact(cmd.Stdout), r.Redact(cmd.Stderr)
+		errMsg = r.Redact(errMsg)
+	}
+	args := map[string]interface{}{
+		"host":      cmd.Host,
+		"user":      cmd.User,
+		"command":   cmd.Command,
+		"exit_code": cmd.ExitCode,
+		"stdout":    cmd.Stdout,
+		"stderr":    cmd.Stderr,
+	}
+	if cmd.Signal != "" {
+		args["signal"] = cmd.Signal
+	}
+	if cmd.Truncated {
+		args["truncated"] = true
+	}
+	event := Event{
+		Timestamp:    time.Now(),
+		Profile:      profile,
+		EventType:    "ssh",
+		APIName:      apiName,
+		ToolName:     toolName,
+		Arguments:    args,
+		DurationMs:   duration.Milliseconds(),
+		Success:      cmd.ExitCode == 0 && errMsg == "",
+		ErrorMsg:     errMsg,
+		RequestSize:  int64(len(cmd.Command)),
+		ResponseSize: int64(len(cmd.Stdout) + len(cmd.Stderr)),
+	}
+
+	l.bufferEvent(event)
+}
+
+// LogCircuitBreaker logs a circuit breaker of an API opening, after
+// errMsg, or closing again.
+func (l *Logger) LogCircuitBreaker(profile, apiName string, open bool, errMsg string) {
+	if l.redactor != nil {
+		errMsg = l.redactor.ForAPI(apiName).Redact(errMsg)
+	}
+	eventType := "breaker_closed"
+	if open {
+		eventType = "breaker_open"
+	}
+	event := Event{
+		Timestamp: time.Now(),
+		Profile:   profile,
+		EventType: eventType,
+		APIName:   apiName,
+		Success:   !open,
+		ErrorMsg:  errMsg,
+	}
+
+	l.bufferEvent(event)
+}
+
 // LogError logs an error event
 func (l *Logger) LogError(profile, eventType, errMsg, clientAddr string) {
+	if l.redactor != nil {
+		errMsg = l.redactor.Redact(errMsg)
+	}
 	event := Event{
 		Timestamp:  time.Now(),
 		Profile:    profile,
@@ -194,21 +393,25 @@ func (l *Logger) Flush() error {
 		INSERT INTO audit_events (
 			timestamp, profile, event_type, api_name, tool_name, arguments,
 			duration_ms, status_code, success, error_msg, client_addr,
-			request_size, response_size
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			request_size, response_size, prev_hash, hash
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`)
 	if err != nil {
 		return fmt.Errorf("prepare statement: %w", err)
 	}
 	defer stmt.Close()
 
-	for _, event := range events {
+	head := l.head
+	var lastID int64
+	for i, event := range events {
 		var argsJSON []byte
 		if event.Arguments != nil {
 			argsJSON, _ = json.Marshal(event.Arguments)
 		}
+		prev := head
+		head = chainHash(prev, event, string(argsJSON))
 
-		_, err := stmt.Exec(
+		res, err := stmt.Exec(
 			event.Timestamp,
 			event.Profile,
 			event.EventType,
@@ -222,13 +425,35 @@ func (l *Logger) Flush() error {
 			event.ClientAddr,
 			event.RequestSize,
 			event.ResponseSize,
+			prev,
+			head,
 		)
 		if err != nil {
 			return fmt.Errorf("insert event: %w", err)
 		}
+		lastID, _ = res.LastInsertId()
+		events[i].ID = lastID
+	}
+
+	if err := saveRollups(tx, rollupEvents(events)); err != nil {
+		return err
+	}
+
+	if l.signer != nil {
+		at := time.Now().UTC()
+		sig := ed25519.Sign(l.signer, checkpointMessage(lastID, head, at))
+		if _, err := tx.Exec(`INSERT INTO audit_checkpoints (event_id, hash, created_at, signature) VALUES (?, ?, ?, ?)`,
+			lastID, head, at, base64.StdEncoding.EncodeToString(sig)); err != nil {
+			return fmt.Errorf("insert checkpoint: %w", err)
+		}
 	}
 
-	return tx.Commit()
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	l.head = head
+	l.forward(events)
+	return nil
 }
 
 // backgroundFlush flushes the buffer periodically
@@ -245,24 +470,73 @@ func (l *Logger) backgroundFlush() {
 func (l *Logger) startRotation() {
 	for range l.rotateTicker.C {
 		threshold := time.Now().Add(-l.rotateAfter)
-
-		l.mu.Lock()
-		result, err := l.db.Exec(`DELETE FROM audit_events WHERE timestamp < ?`, threshold)
+		count, err := l.rotate(threshold)
 		if err != nil {
-			l.mu.Unlock()
 			slog.Error("audit log rotation failed", "error", err)
 			continue
 		}
-		count, _ := result.RowsAffected()
 		if count > 0 {
-			// Reclaim space in WAL mode
-			_, _ = l.db.Exec(`PRAGMA wal_checkpoint(TRUNCATE)`)
 			slog.Info("audit log rotated", "deleted", count, "older_than", threshold)
 		}
-		l.mu.Unlock()
 	}
 }
 
+// rotate deletes the events logged before threshold, up to the first one
+// that is kept, so the retained chain has no gaps. Checkpoints of rotated
+// events go with them and, with a signing key, a signed rotation checkpoint
+// records the last event removed; see Verify.
+func (l *Logger) rotate(threshold time.Time) (int64, error) {
+	l.rotateMu.Lock()
+	defer l.rotateMu.Unlock()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var lastDeleted sql.NullInt64
+	var lastHash sql.NullString
+	err := l.db.QueryRow(`
+		SELECT id, hash FROM audit_events
+		WHERE id < COALESCE((SELECT MIN(id) FROM audit_events WHERE timestamp >= ?), (SELECT MAX(id) + 1 FROM audit_events))
+		ORDER BY id DESC LIMIT 1`, threshold).Scan(&lastDeleted, &lastHash)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, fmt.Errorf("find rotated events: %w", err)
+	}
+
+	var count int64
+	if lastDeleted.Valid {
+		tx, err := l.db.Begin()
+		if err != nil {
+			return 0, fmt.Errorf("begin transaction: %w", err)
+		}
+		defer func() { _ = tx.Rollback() }()
+		result, err := tx.Exec(`DELETE FROM audit_events WHERE id <= ?`, lastDeleted.Int64)
+		if err != nil {
+			return 0, fmt.Errorf("delete events: %w", err)
+		}
+		count, _ = result.RowsAffected()
+		if _, err := tx.Exec(`DELETE FROM audit_checkpoints WHERE event_id <= ?`, lastDeleted.Int64); err != nil {
+			return 0, fmt.Errorf("delete checkpoints: %w", err)
+		}
+		if l.signer != nil {
+			if err := l.insertBoundary(tx, lastDeleted.Int64, lastHash.String); err != nil {
+				return 0, err
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			return 0, err
+		}
+	}
+
+	// Rollups are small and kept longer, so analytics still cover
+	// rotated events.
+	_, _ = l.db.Exec(`DELETE FROM tool_rollups WHERE hour < ?`, time.Now().Add(-max(l.rotateAfter, rollupRetention)).Unix())
+	_, _ = l.db.Exec(`DELETE FROM auth_failures WHERE last_failure < ? AND locked_until < ?`, threshold.UnixNano(), time.Now().UnixNano())
+	if count > 0 {
+		// Reclaim space in WAL mode
+		_, _ = l.db.Exec(`PRAGMA wal_checkpoint(TRUNCATE)`)
+	}
+	return count, nil
+}
+
 // QueryOptions represents query parameters for retrieving audit events
 type QueryOptions struct {
 	Profile   string
@@ -549,6 +823,140 @@ func (l *Logger) GetStats(profile string, since time.Time) (*Stats, error) {
 	return &stats, nil
 }
 
+// AddQuotaUsage adds n to a quota counter and returns its new value; n = 0
+// reads it. Counters are kept apart from the events so rotation does not
+// reset budgets.
+func (l *Logger) AddQuotaUsage(ctx context.Context, profile, scope, period string, n int64) (int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var calls int64
+	err := l.db.QueryRowContext(ctx, `
+		INSERT INTO quota_usage (profile, scope, period, calls) VALUES (?, ?, ?, ?)
+		ON CONFLICT (profile, scope, period) DO UPDATE SET calls = calls + excluded.calls
+		RETURNING calls`, profile, scope, period, n).Scan(&calls)
+	if err != nil {
+		return 0, fmt.Errorf("update quota usage: %w", err)
+	}
+	return calls, nil
+}
+
+// AuthFailures returns the failed-token record of a client for a profile,
+// or a zero record.
+func (l *Logger) AuthFailures(ctx context.Context, profile, clientAddr string) (lockout.Record, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var rec lockout.Record
+	var last, until int64
+	err := l.db.QueryRowContext(ctx, `SELECT failures, lockouts, last_failure, locked_until FROM auth_failures
+		WHERE profile = ? AND client_addr = ?`, profile, clientAddr).Scan(&rec.Failures, &rec.Lockouts, &last, &until)
+	if errors.Is(err, sql.ErrNoRows) {
+		return lockout.Record{}, nil
+	}
+	if err != nil {
+		return lockout.Record{}, fmt.Errorf("read auth failures: %w", err)
+	}
+	rec.LastFailure = time.Unix(0, last)
+	if until != 0 {
+		rec.LockedUntil = time.Unix(0, until)
+	}
+	return rec, nil
+}
+
+// SetAuthFailures stores a client's failed-token record; a zero record
+// deletes it. Like quota counters, records are kept apart from the events.
+func (l *Logger) SetAuthFailures(ctx context.Context, profile, clientAddr string, rec lockout.Record) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var err error
+	if rec == (lockout.Record{}) {
+		_, err = l.db.ExecContext(ctx, `DELETE FROM auth_failures WHERE profile = ? AND client_addr = ?`, profile, clientAddr)
+	} else {
+		var until int64
+		if !rec.LockedUntil.IsZero() {
+			until = rec.LockedUntil.UnixNano()
+		}
+		_, err = l.db.ExecContext(ctx, `INSERT INTO auth_failures (profile, client_addr, failures, lockouts, last_failure, locked_until)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT (profile, client_addr) DO UPDATE SET failures = excluded.failures, lockouts = excluded.lockouts,
+				last_failure = excluded.last_failure, locked_until = excluded.locked_until`,
+			profile, clientAddr, rec.Failures, rec.Lockouts, rec.LastFailure.UnixNano(), until)
+	}
+	if err != nil {
+		return fmt.Errorf("update auth failures: %w", err)
+	}
+	return nil
+}
+
+// LearnedSchemas returns the response schemas learned for a profile's
+// operations, keyed by operation.
+func (l *Logger) LearnedSchemas(ctx context.Context, profile string) (map[string]schemalearn.Learned, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	rows, err := l.db.QueryContext(ctx, `SELECT operation, schema, samples, updated_at FROM learned_schemas WHERE profile = ?`, profile)
+	if err != nil {
+		return nil, fmt.Errorf("read learned schemas: %w", err)
+	}
+	defer rows.Close()
+	out := map[string]schemalearn.Learned{}
+	for rows.Next() {
+		var op, schemaJSON string
+		var learned schemalearn.Learned
+		var updated int64
+		if err := rows.Scan(&op, &schemaJSON, &learned.Samples, &updated); err != nil {
+			return nil, fmt.Errorf("scan learned schema: %w", err)
+		}
+		if err := json.Unmarshal([]byte(schemaJSON), &learned.Schema); err != nil {
+			continue
+		}
+		learned.UpdatedAt = time.Unix(0, updated).UTC()
+		out[op] = learned
+	}
+	return out, rows.Err()
+}
+
+// SaveLearnedSchema stores the response schema learned for an operation.
+// Like quota counters, schemas are kept apart from the events.
+func (l *Logger) SaveLearnedSchema(ctx context.Context, profile, operation string, learned schemalearn.Learned) error {
+	schemaJSON, err := json.Marshal(learned.Schema)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, err = l.db.ExecContext(ctx, `INSERT INTO learned_schemas (profile, operation, schema, samples, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (profile, operation) DO UPDATE SET schema = excluded.schema, samples = excluded.samples,
+			updated_at = excluded.updated_at`,
+		profile, operation, string(schemaJSON), learned.Samples, learned.UpdatedAt.UnixNano())
+	if err != nil {
+		return fmt.Errorf("update learned schema: %w", err)
+	}
+	return nil
+}
+
+// CheckWritable verifies the database accepts writes by inserting a row in
+// a transaction that is rolled back, so nothing is kept.
+func (l *Logger) CheckWritable(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	tx, err := l.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	if _, err := tx.ExecContext(ctx, `INSERT INTO quota_usage (profile, scope, period, calls) VALUES ('', '', '', 0)
+		ON CONFLICT (profile, scope, period) DO NOTHING`); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+	return nil
+}
+
 // Stats represents aggregated statistics
 type Stats struct {
 	TotalRequests      int64      `json:"total_requests"`
@@ -586,7 +994,9 @@ func (l *Logger) Close() error {
 	}
 
 	// Final flush
-	if err := l.Flush(); err != nil {
+	err := l.Flush()
+	l.closeSinks()
+	if err != nil {
 		return err
 	}
 
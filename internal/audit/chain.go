@@ -0,0 +1,326 @@
+package audit
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Events are chained: each row stores the hash of the previous row and its
+// own hash over that value and its fields, so editing or deleting a row
+// breaks every hash after it. When a signing key is set, every flush also
+// records a checkpoint of the chain head signed with that key, which makes
+// truncating the tail of the log detectable as well. Rotation records a
+// signed checkpoint of the last event it removed, so deleting the oldest
+// retained events is caught too.
+
+// VerifyResult reports the outcome of checking the audit chain.
+type VerifyResult struct {
+	Valid    bool   `json:"valid"`
+	Events   int64  `json:"events"`
+	FirstID  int64  `json:"first_id,omitempty"`
+	LastID   int64  `json:"last_id,omitempty"`
+	HeadHash string `json:"head_hash,omitempty"`
+	// Unchained counts events logged before chaining was introduced.
+	Unchained int64 `json:"unchained,omitempty"`
+	// Checkpoints counts signed checkpoints checked against the chain.
+	Checkpoints int `json:"checkpoints"`
+	// PublicKey is the hex ed25519 key checkpoints are signed with.
+	PublicKey string `json:"public_key,omitempty"`
+	// RotatedThrough is the last event removed by rotation.
+	RotatedThrough int64 `json:"rotated_through,omitempty"`
+	// BrokenAt is the first event or checkpoint that fails verification.
+	BrokenAt int64  `json:"broken_at,omitempty"`
+	Problem  string `json:"problem,omitempty"`
+}
+
+// SetSigningKey enables signed checkpoints. A log without any, or from
+// before rotation checkpoints, starts its chain at the oldest event it
+// holds now.
+func (l *Logger) SetSigningKey(key ed25519.PrivateKey) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.signer = key
+
+	var checkpoints int64
+	if err := l.db.QueryRow(`SELECT COUNT(*) FROM audit_checkpoints`).Scan(&checkpoints); err != nil {
+		return fmt.Errorf("count checkpoints: %w", err)
+	}
+	if checkpoints > 0 && !l.markBoundary {
+		return nil
+	}
+	var first sql.NullInt64
+	if err := l.db.QueryRow(`SELECT MIN(id) FROM audit_events`).Scan(&first); err != nil {
+		return fmt.Errorf("read first event: %w", err)
+	}
+	prev := l.head
+	err := l.db.QueryRow(`SELECT prev_hash FROM audit_events WHERE hash IS NOT NULL ORDER BY id LIMIT 1`).Scan(&prev)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("read first chained event: %w", err)
+	}
+	if err := l.insertBoundary(l.db, max(first.Int64-1, 0), prev); err != nil {
+		return err
+	}
+	l.markBoundary = false
+	return nil
+}
+
+// insertBoundary records a signed rotation checkpoint: every event up to
+// eventID is gone, and the next chained event follows hash.
+func (l *Logger) insertBoundary(db interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}, eventID int64, hash string) error {
+	at := time.Now().UTC()
+	sig := ed25519.Sign(l.signer, boundaryMessage(eventID, hash, at))
+	if _, err := db.Exec(`INSERT INTO audit_checkpoints (event_id, hash, created_at, signature, kind) VALUES (?, ?, ?, ?, 'rotation')`,
+		eventID, hash, at, base64.StdEncoding.EncodeToString(sig)); err != nil {
+		return fmt.Errorf("insert rotation checkpoint: %w", err)
+	}
+	return nil
+}
+
+// chainHash returns the hash of an event given the previous event's hash.
+// argsJSON is the arguments column exactly as stored.
+func chainHash(prev string, e Event, argsJSON string) string {
+	fields, _ := json.Marshal([]string{
+		prev,
+		e.Timestamp.UTC().Format(time.RFC3339Nano),
+		e.Profile,
+		e.EventType,
+		e.APIName,
+		e.ToolName,
+		argsJSON,
+		strconv.FormatInt(e.DurationMs, 10),
+		strconv.Itoa(e.StatusCode),
+		strconv.FormatBool(e.Success),
+		e.ErrorMsg,
+		e.ClientAddr,
+		strconv.FormatInt(e.RequestSize, 10),
+		strconv.FormatInt(e.ResponseSize, 10),
+	})
+	sum := sha256.Sum256(fields)
+	return hex.EncodeToString(sum[:])
+}
+
+// checkpointMessage is the byte string a checkpoint signature covers.
+func checkpointMessage(eventID int64, hash string, at time.Time) []byte {
+	return []byte(fmt.Sprintf("skyline-audit-checkpoint:%d:%s:%s", eventID, hash, at.UTC().Format(time.RFC3339Nano)))
+}
+
+// boundaryMessage is the byte string a rotation checkpoint signature covers.
+func boundaryMessage(eventID int64, hash string, at time.Time) []byte {
+	return []byte(fmt.Sprintf("skyline-audit-rotation:%d:%s:%s", eventID, hash, at.UTC().Format(time.RFC3339Nano)))
+}
+
+// loadHead reads the hash of the newest chained event.
+func (l *Logger) loadHead() error {
+	var head sql.NullString
+	err := l.db.QueryRow(`SELECT hash FROM audit_events WHERE hash IS NOT NULL ORDER BY id DESC LIMIT 1`).Scan(&head)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("read chain head: %w", err)
+	}
+	l.head = head.String
+	return nil
+}
+
+// verifyPageSize is how many rows Verify reads per query.
+var verifyPageSize = 1000
+
+// chainRow is an event as Verify reads it.
+type chainRow struct {
+	event                    Event
+	argsJSON, prevHash, hash sql.NullString
+}
+
+// checkpointRow is a checkpoint as Verify reads it.
+type checkpointRow struct {
+	id, eventID           int64
+	hash, signature, kind string
+	at                    time.Time
+}
+
+// Verify recomputes the hash chain from the oldest retained event and
+// checks every signed checkpoint. Events removed by rotation are not an
+// error: with a signing key, the retained chain must start right after the
+// last rotation checkpoint.
+//
+// Verify covers the events and checkpoints stored when it starts. It reads
+// them a page at a time and holds the logger's lock only per page, so
+// events keep being logged during a long scan; rotation waits for it.
+func (l *Logger) Verify() (*VerifyResult, error) {
+	if err := l.Flush(); err != nil {
+		return nil, err
+	}
+	l.rotateMu.Lock()
+	defer l.rotateMu.Unlock()
+
+	l.mu.Lock()
+	signer := l.signer
+	var lastEvent, lastCheckpoint sql.NullInt64
+	err := l.db.QueryRow(`SELECT (SELECT MAX(id) FROM audit_events), (SELECT MAX(id) FROM audit_checkpoints)`).Scan(&lastEvent, &lastCheckpoint)
+	l.mu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("read the newest event: %w", err)
+	}
+
+	res := &VerifyResult{Valid: true}
+	if signer != nil {
+		res.PublicKey = hex.EncodeToString(signer.Public().(ed25519.PublicKey))
+	}
+	fail := func(id int64, problem string) (*VerifyResult, error) {
+		res.Valid = false
+		res.BrokenAt = id
+		res.Problem = problem
+		return res, nil
+	}
+
+	hashes := map[int64]string{}
+	var prev, firstPrev string
+	var firstRow int64
+	chained := false
+	for after := int64(0); after < lastEvent.Int64; {
+		page, err := l.eventPage(after, lastEvent.Int64)
+		if err != nil {
+			return nil, err
+		}
+		if len(page) == 0 {
+			break
+		}
+		after = page[len(page)-1].event.ID
+		for _, row := range page {
+			e, hash, prevHash := row.event, row.hash, row.prevHash
+			res.Events++
+			if firstRow == 0 {
+				firstRow = e.ID
+			}
+			if !hash.Valid {
+				if chained {
+					return fail(e.ID, "event has no hash")
+				}
+				res.Unchained++
+				continue
+			}
+			if chained && prevHash.String != prev {
+				return fail(e.ID, "previous hash does not match the preceding event")
+			}
+			if chainHash(prevHash.String, e, row.argsJSON.String) != hash.String {
+				return fail(e.ID, "event hash does not match its contents")
+			}
+			if !chained {
+				res.FirstID = e.ID
+				firstPrev = prevHash.String
+				chained = true
+			}
+			prev = hash.String
+			res.LastID = e.ID
+			hashes[e.ID] = hash.String
+		}
+	}
+	res.HeadHash = prev
+	if signer == nil {
+		return res, nil
+	}
+
+	pub := signer.Public().(ed25519.PublicKey)
+	// Without a rotation checkpoint the chain must start at its beginning.
+	var boundaryHash string
+	var mismatchID, mismatchEvent int64 // first checkpoint not found in the log
+	for after := int64(0); after < lastCheckpoint.Int64; {
+		page, err := l.checkpointPage(after, lastCheckpoint.Int64)
+		if err != nil {
+			return nil, err
+		}
+		if len(page) == 0 {
+			break
+		}
+		after = page[len(page)-1].id
+		for _, cp := range page {
+			message := checkpointMessage(cp.eventID, cp.hash, cp.at)
+			if cp.kind == "rotation" {
+				message = boundaryMessage(cp.eventID, cp.hash, cp.at)
+			}
+			sig, err := base64.StdEncoding.DecodeString(cp.signature)
+			if err != nil || !ed25519.Verify(pub, message, sig) {
+				return fail(cp.eventID, fmt.Sprintf("checkpoint %d has an invalid signature", cp.id))
+			}
+			if cp.kind == "rotation" {
+				res.RotatedThrough, boundaryHash = cp.eventID, cp.hash
+				continue
+			}
+			if got, ok := hashes[cp.eventID]; ok && got == cp.hash {
+				res.Checkpoints++
+				continue
+			}
+			if mismatchID == 0 {
+				mismatchID, mismatchEvent = cp.id, cp.eventID
+			}
+		}
+	}
+	if firstRow != 0 && firstRow <= res.RotatedThrough {
+		return fail(firstRow, "event precedes the last rotation checkpoint")
+	}
+	if chained && firstPrev != boundaryHash {
+		return fail(res.FirstID, "events before the oldest retained event were deleted outside rotation")
+	}
+	// Rotation deletes the checkpoints of the events it removes.
+	if mismatchID != 0 {
+		return fail(mismatchEvent, fmt.Sprintf("checkpoint %d does not match the event log", mismatchID))
+	}
+	return res, nil
+}
+
+// eventPage reads the events after id after, up to id last.
+func (l *Logger) eventPage(after, last int64) ([]chainRow, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	rows, err := l.db.Query(`
+		SELECT id, timestamp, profile, event_type, api_name, tool_name, arguments,
+		       duration_ms, status_code, success, error_msg, client_addr,
+		       request_size, response_size, prev_hash, hash
+		FROM audit_events WHERE id > ? AND id <= ? ORDER BY id LIMIT ?`, after, last, verifyPageSize)
+	if err != nil {
+		return nil, fmt.Errorf("query events: %w", err)
+	}
+	defer rows.Close()
+	var page []chainRow
+	for rows.Next() {
+		var row chainRow
+		e := &row.event
+		var apiName, toolName, errMsg, clientAddr sql.NullString
+		if err := rows.Scan(&e.ID, &e.Timestamp, &e.Profile, &e.EventType, &apiName, &toolName, &row.argsJSON,
+			&e.DurationMs, &e.StatusCode, &e.Success, &errMsg, &clientAddr,
+			&e.RequestSize, &e.ResponseSize, &row.prevHash, &row.hash); err != nil {
+			return nil, fmt.Errorf("scan event: %w", err)
+		}
+		e.APIName, e.ToolName, e.ErrorMsg, e.ClientAddr = apiName.String, toolName.String, errMsg.String, clientAddr.String
+		page = append(page, row)
+	}
+	return page, rows.Err()
+}
+
+// checkpointPage reads the checkpoints after id after, up to id last.
+func (l *Logger) checkpointPage(after, last int64) ([]checkpointRow, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	rows, err := l.db.Query(`SELECT id, event_id, hash, created_at, signature, kind FROM audit_checkpoints
+		WHERE id > ? AND id <= ? ORDER BY id LIMIT ?`, after, last, verifyPageSize)
+	if err != nil {
+		return nil, fmt.Errorf("query checkpoints: %w", err)
+	}
+	defer rows.Close()
+	var page []checkpointRow
+	for rows.Next() {
+		var cp checkpointRow
+		if err := rows.Scan(&cp.id, &cp.eventID, &cp.hash, &cp.at, &cp.signature, &cp.kind); err != nil {
+			return nil, fmt.Errorf("scan checkpoint: %w", err)
+		}
+		page = append(page, cp)
+	}
+	return page, rows.Err()
+}
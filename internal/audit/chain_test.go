@@ -0,0 +1,184 @@
+package audit
+
+import (
+	"crypto/ed25519"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testSigningKey = ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+
+// newChainLogger returns a signing logger holding one event per timestamp,
+// each written by its own flush and so covered by its own checkpoint.
+func newChainLogger(t *testing.T, path string, timestamps ...time.Time) *Logger {
+	t.Helper()
+	l, err := NewLogger(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	if err := l.SetSigningKey(testSigningKey); err != nil {
+		t.Fatal(err)
+	}
+	for _, ts := range timestamps {
+		l.bufferEvent(Event{Timestamp: ts, Profile: "default", EventType: "execute", ToolName: "api__get", Success: true})
+		if err := l.Flush(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return l
+}
+
+func verify(t *testing.T, l *Logger) *VerifyResult {
+	t.Helper()
+	res, err := l.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func exec(t *testing.T, l *Logger, query string, args ...any) {
+	t.Helper()
+	if _, err := l.db.Exec(query, args...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		tamper  string
+		problem string
+	}{
+		{"edited", `UPDATE audit_events SET profile = 'other' WHERE id = 2`, "does not match its contents"},
+		{"deleted", `DELETE FROM audit_events WHERE id = 2`, "previous hash does not match"},
+		{"oldest deleted", `DELETE FROM audit_events WHERE id = 1`, "deleted outside rotation"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newChainLogger(t, filepath.Join(t.TempDir(), "audit.db"), now, now, now, now)
+			if res := verify(t, l); !res.Valid || res.Events != 4 || res.Checkpoints != 4 {
+				t.Fatalf("before tampering: %+v", res)
+			}
+			exec(t, l, tt.tamper)
+			res := verify(t, l)
+			if res.Valid || !strings.Contains(res.Problem, tt.problem) {
+				t.Errorf("after tampering: %+v, want problem %q", res, tt.problem)
+			}
+		})
+	}
+}
+
+func TestVerifyTruncatedTail(t *testing.T) {
+	now := time.Now()
+	l := newChainLogger(t, filepath.Join(t.TempDir(), "audit.db"), now, now, now)
+	exec(t, l, `DELETE FROM audit_events WHERE id = 3`)
+	if res := verify(t, l); res.Valid || res.BrokenAt != 3 || !strings.Contains(res.Problem, "does not match the event log") {
+		t.Errorf("after truncating: %+v", res)
+	}
+}
+
+func TestVerifyAfterRotation(t *testing.T) {
+	now := time.Now()
+	old := now.Add(-2 * time.Hour)
+	l := newChainLogger(t, filepath.Join(t.TempDir(), "audit.db"), old, old, now, now)
+
+	count, err := l.rotate(now.Add(-time.Hour))
+	if err != nil || count != 2 {
+		t.Fatalf("rotate = %d, %v", count, err)
+	}
+	res := verify(t, l)
+	if !res.Valid || res.RotatedThrough != 2 || res.FirstID != 3 || res.Checkpoints != 2 {
+		t.Fatalf("after rotation: %+v", res)
+	}
+
+	// Later rotations move the boundary.
+	l.bufferEvent(Event{Timestamp: now.Add(time.Hour), Profile: "default", EventType: "execute", Success: true})
+	if _, err := l.rotate(now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if res := verify(t, l); !res.Valid || res.RotatedThrough != 4 || res.Events != 1 {
+		t.Fatalf("after second rotation: %+v", res)
+	}
+
+	// Removing the rotation checkpoint leaves a chain that starts midway.
+	exec(t, l, `DELETE FROM audit_checkpoints WHERE kind = 'rotation'`)
+	if res := verify(t, l); res.Valid || !strings.Contains(res.Problem, "deleted outside rotation") {
+		t.Errorf("without the rotation checkpoint: %+v", res)
+	}
+}
+
+func TestVerifyRejectsForgedRotation(t *testing.T) {
+	now := time.Now()
+	l := newChainLogger(t, filepath.Join(t.TempDir(), "audit.db"), now, now)
+	// A head checkpoint's signature does not cover a rotation.
+	exec(t, l, `DELETE FROM audit_events WHERE id = 1`)
+	exec(t, l, `UPDATE audit_checkpoints SET kind = 'rotation' WHERE kind = 'head' AND event_id = 1`)
+	if res := verify(t, l); res.Valid || !strings.Contains(res.Problem, "invalid signature") {
+		t.Errorf("forged rotation: %+v", res)
+	}
+}
+
+func TestSetSigningKeyStartsChainAtOldestEvent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.db")
+	now := time.Now()
+	// Logged and rotated without a signing key.
+	l, err := NewLogger(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		l.bufferEvent(Event{Timestamp: now, Profile: "default", EventType: "execute", Success: true})
+	}
+	if err := l.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	exec(t, l, `DELETE FROM audit_events WHERE id = 1`)
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	signed := newChainLogger(t, path, now)
+	if res := verify(t, signed); !res.Valid || res.RotatedThrough != 1 || res.Events != 3 {
+		t.Errorf("after enabling the key: %+v", res)
+	}
+}
+
+func TestVerifyInPagesWhileLogging(t *testing.T) {
+	prevSize := verifyPageSize
+	verifyPageSize = 2
+	t.Cleanup(func() { verifyPageSize = prevSize })
+	now := time.Now()
+	l := newChainLogger(t, filepath.Join(t.TempDir(), "audit.db"), now, now, now, now, now)
+
+	// Events logged during verification are left for the next run.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 20 {
+			l.LogError("default", "error", "boom", "")
+			if err := l.Flush(); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	res := verify(t, l)
+	<-done
+	if !res.Valid || res.Events < 5 || res.Checkpoints < 5 || res.Checkpoints != int(res.Events) {
+		t.Errorf("while logging: %+v", res)
+	}
+	if res := verify(t, l); !res.Valid || res.Events != 25 || res.Checkpoints != 25 {
+		t.Errorf("afterwards: %+v", res)
+	}
+
+	// Tampering past the first page is still found.
+	exec(t, l, `UPDATE audit_events SET profile = 'other' WHERE id = 4`)
+	if res := verify(t, l); res.Valid || res.BrokenAt != 4 {
+		t.Errorf("after tampering: %+v", res)
+	}
+}
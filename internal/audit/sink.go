@@ -0,0 +1,321 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sink receives audit events after they are stored in the database, to
+// forward them to systems that collect logs centrally. The database stays
+// the store that queries, analytics and chain verification read.
+type Sink interface {
+	// Write delivers a batch of events, in the order they were logged.
+	Write(events []Event) error
+	Close() error
+}
+
+// sinkQueueSize is how many batches may wait for a slow sink before new
+// ones are dropped.
+const sinkQueueSize = 64
+
+// sinkQueue feeds a sink from its own goroutine, so a slow or unreachable
+// sink never holds up the database.
+type sinkQueue struct {
+	name   string
+	sink   Sink
+	types  map[string]bool // nil forwards every event type
+	ch     chan []Event
+	done   chan struct{}
+	logger *slog.Logger
+}
+
+// AddSink forwards stored events to sink, or only those of eventTypes
+// when it is not empty. name identifies the sink in logs. Close closes
+// the sink.
+func (l *Logger) AddSink(name string, sink Sink, eventTypes []string) {
+	q := &sinkQueue{
+		name:   name,
+		sink:   sink,
+		ch:     make(chan []Event, sinkQueueSize),
+		done:   make(chan struct{}),
+		logger: slog.Default(),
+	}
+	if len(eventTypes) > 0 {
+		q.types = make(map[string]bool, len(eventTypes))
+		for _, t := range eventTypes {
+			q.types[t] = true
+		}
+	}
+	go q.run()
+
+	l.sinksMu.Lock()
+	l.sinks = append(l.sinks, q)
+	l.sinksMu.Unlock()
+}
+
+// forward hands a stored batch to every sink.
+func (l *Logger) forward(events []Event) {
+	l.sinksMu.Lock()
+	defer l.sinksMu.Unlock()
+	for _, q := range l.sinks {
+		batch := events
+		if q.types != nil {
+			batch = make([]Event, 0, len(events))
+			for _, e := range events {
+				if q.types[e.EventType] {
+					batch = append(batch, e)
+				}
+			}
+		}
+		if len(batch) == 0 {
+			continue
+		}
+		select {
+		case q.ch <- batch:
+		default:
+			q.logger.Warn("audit sink is behind; dropping events", "sink", q.name, "events", len(batch))
+		}
+	}
+}
+
+// closeSinks delivers the queued batches and closes every sink.
+func (l *Logger) closeSinks() {
+	l.sinksMu.Lock()
+	sinks := l.sinks
+	l.sinks = nil
+	l.sinksMu.Unlock()
+	for _, q := range sinks {
+		close(q.ch)
+		<-q.done
+		if err := q.sink.Close(); err != nil {
+			q.logger.Warn("close audit sink", "sink", q.name, "error", err)
+		}
+	}
+}
+
+func (q *sinkQueue) run() {
+	defer close(q.done)
+	for batch := range q.ch {
+		if err := q.sink.Write(batch); err != nil {
+			q.logger.Warn("audit sink write failed", "sink", q.name, "events", len(batch), "error", err)
+		}
+	}
+}
+
+// FileSink appends events to a file as newline-delimited JSON. The file is
+// opened in append mode, so it can be rotated with copytruncate.
+type FileSink struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// NewFileSink opens path for appending, creating it if needed.
+func NewFileSink(path string) (*FileSink, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open audit file: %w", err)
+	}
+	return &FileSink{f: f}, nil
+}
+
+func (s *FileSink) Write(events []Event) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.f.Write(buf.Bytes())
+	return err
+}
+
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.f.Close()
+}
+
+// SyslogSink sends each event as an RFC 5424 message whose body is the
+// event as JSON. Events with Success false are sent with warning severity,
+// the rest with info.
+type SyslogSink struct {
+	network, address string
+	tag, hostname    string
+	facility         int
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// syslogFacilities maps facility names to their codes.
+var syslogFacilities = map[string]int{
+	"user": 1, "daemon": 3, "auth": 4, "authpriv": 10,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// NewSyslogSink returns a sink for the syslog server at address. network
+// is udp (default), tcp, unix or unixgram; facility defaults to local0 and
+// tag to skyline. The connection is made on the first write and remade
+// after an error.
+func NewSyslogSink(network, address, facility, tag string) (*SyslogSink, error) {
+	switch network {
+	case "":
+		network = "udp"
+	case "udp", "tcp", "unix", "unixgram":
+	default:
+		return nil, fmt.Errorf("unsupported syslog network %q", network)
+	}
+	if address == "" {
+		return nil, fmt.Errorf("syslog address is required")
+	}
+	if facility == "" {
+		facility = "local0"
+	}
+	code, ok := syslogFacilities[facility]
+	if !ok {
+		return nil, fmt.Errorf("unknown syslog facility %q", facility)
+	}
+	if tag == "" {
+		tag = "skyline"
+	}
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "-"
+	}
+	return &SyslogSink{network: network, address: address, tag: tag, hostname: hostname, facility: code}, nil
+}
+
+func (s *SyslogSink) Write(events []Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range events {
+		msg, err := s.format(e)
+		if err != nil {
+			return err
+		}
+		if err := s.send(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SyslogSink) format(e Event) ([]byte, error) {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	severity := 6 // info
+	if !e.Success {
+		severity = 4 // warning
+	}
+	msg := fmt.Sprintf("<%d>1 %s %s %s %d %s - ",
+		s.facility*8+severity, e.Timestamp.UTC().Format("2006-01-02T15:04:05.000000Z07:00"), s.hostname, s.tag, os.Getpid(), e.EventType)
+	b := append([]byte(msg), body...)
+	if s.network == "tcp" || s.network == "unix" {
+		// Stream transports need framing; use non-transparent framing.
+		b = append(b, '\n')
+	}
+	return b, nil
+}
+
+// send writes msg, reconnecting once if the connection broke.
+func (s *SyslogSink) send(msg []byte) error {
+	for attempt := 0; ; attempt++ {
+		if s.conn == nil {
+			conn, err := net.DialTimeout(s.network, s.address, 5*time.Second)
+			if err != nil {
+				return fmt.Errorf("connect to syslog: %w", err)
+			}
+			s.conn = conn
+		}
+		_ = s.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		_, err := s.conn.Write(msg)
+		if err == nil {
+			return nil
+		}
+		_ = s.conn.Close()
+		s.conn = nil
+		if attempt > 0 {
+			return fmt.Errorf("write to syslog: %w", err)
+		}
+	}
+}
+
+func (s *SyslogSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// WebhookSink POSTs each batch of events to a URL as a JSON array. A batch
+// that fails is retried once.
+type WebhookSink struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+// NewWebhookSink returns a sink for url, sending headers with every
+// request.
+func NewWebhookSink(url string, headers map[string]string) (*WebhookSink, error) {
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return nil, fmt.Errorf("webhook url must be http or https")
+	}
+	return &WebhookSink{url: url, headers: headers, client: &http.Client{Timeout: 10 * time.Second}}, nil
+}
+
+func (s *WebhookSink) Write(events []Event) error {
+	body, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+	for attempt := 0; ; attempt++ {
+		err = s.post(body)
+		if err == nil || attempt > 0 {
+			return err
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+func (s *WebhookSink) post(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *WebhookSink) Close() error {
+	return nil
+}
@@ -0,0 +1,314 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingSink keeps the batches it receives. Writes block while gate
+// is set and open.
+type recordingSink struct {
+	mu      sync.Mutex
+	batches [][]Event
+	closed  bool
+	gate    chan struct{}
+}
+
+func (s *recordingSink) Write(events []Event) error {
+	if s.gate != nil {
+		<-s.gate
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches = append(s.batches, events)
+	return nil
+}
+
+func (s *recordingSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+// quietSinkLogs sends the warnings of sinks added during the test to the
+// returned buffer.
+func quietSinkLogs(t *testing.T) *bytes.Buffer {
+	var logs bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return &logs
+}
+
+func newSinkLogger(t *testing.T) *Logger {
+	t.Helper()
+	l, err := NewLogger(filepath.Join(t.TempDir(), "audit.db"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func TestSinksReceiveStoredEvents(t *testing.T) {
+	l := newSinkLogger(t)
+	all, executes := &recordingSink{}, &recordingSink{}
+	l.AddSink("all", all, nil)
+	l.AddSink("executes", executes, []string{"execute"})
+
+	l.LogError("ops", "error", "boom", "")
+	l.bufferEvent(Event{Timestamp: time.Now(), Profile: "ops", EventType: "execute", ToolName: "api__get", Success: true})
+	if err := l.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	// Close delivers what is queued before closing the sinks.
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(all.batches) != 1 || len(all.batches[0]) != 2 || all.batches[0][0].EventType != "error" || all.batches[0][1].ID != 2 || !all.closed {
+		t.Errorf("all = %+v", all)
+	}
+	if len(executes.batches) != 1 || len(executes.batches[0]) != 1 || executes.batches[0][0].ToolName != "api__get" || !executes.closed {
+		t.Errorf("executes = %+v", executes)
+	}
+}
+
+func TestSlowSinkDropsEvents(t *testing.T) {
+	logs := quietSinkLogs(t)
+	l := newSinkLogger(t)
+	slow := &recordingSink{gate: make(chan struct{})}
+	l.AddSink("slow", slow, nil)
+
+	// The database keeps up while the sink is stuck.
+	start := time.Now()
+	const flushes = sinkQueueSize + 10
+	for i := 0; i < flushes; i++ {
+		l.LogError("ops", "error", "boom", "")
+		if err := l.Flush(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("flushes waited for the sink: %v", elapsed)
+	}
+	if !strings.Contains(logs.String(), "audit sink is behind") {
+		t.Errorf("no warning about dropped events:\n%s", logs)
+	}
+
+	close(slow.gate)
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// One batch being written and a full queue.
+	if n := len(slow.batches); n < sinkQueueSize || n >= flushes {
+		t.Errorf("the sink got %d of %d batches", n, flushes)
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	for i, batch := range [][]Event{
+		{{ID: 1, Profile: "ops", EventType: "execute"}, {ID: 2, Profile: "ops", EventType: "error"}},
+		{{ID: 3, Profile: "billing", EventType: "execute"}},
+	} {
+		// Reopening appends.
+		sink, err := NewFileSink(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := sink.Write(batch); err != nil {
+			t.Fatalf("batch %d: %v", i, err)
+		}
+		if err := sink.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("file:\n%s", data)
+	}
+	var last Event
+	if err := json.Unmarshal([]byte(lines[2]), &last); err != nil || last.ID != 3 || last.Profile != "billing" {
+		t.Errorf("last line %q: %+v %v", lines[2], last, err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("mode = %v, %v", info.Mode(), err)
+	}
+	if _, err := NewFileSink(filepath.Join(path, "nested")); err == nil {
+		t.Error("opened a file below a regular file")
+	}
+}
+
+func TestSyslogSinkUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	sink, err := NewSyslogSink("", conn.LocalAddr().String(), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	ts := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := sink.Write([]Event{
+		{Timestamp: ts, Profile: "ops", EventType: "execute", Success: true},
+		{Timestamp: ts, Profile: "ops", EventType: "auth_failure"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	hostname, _ := os.Hostname()
+	buf := make([]byte, 4096)
+	for _, want := range []string{
+		// local0 (16) * 8 + info (6)
+		"<134>1 2026-01-02T03:04:05.000000Z " + hostname + " skyline ",
+		// local0 * 8 + warning (4)
+		"<132>1 2026-01-02T03:04:05.000000Z " + hostname + " skyline ",
+	} {
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg := string(buf[:n])
+		body := msg[strings.Index(msg, " - ")+3:]
+		var e Event
+		if !strings.HasPrefix(msg, want) || json.Unmarshal([]byte(body), &e) != nil || e.Profile != "ops" {
+			t.Errorf("message %q, want prefix %q and an event", msg, want)
+		}
+	}
+}
+
+func TestSyslogSinkTCPReconnects(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	lines := make(chan string, 4)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			// Read one message per connection, then hang up.
+			line, _ := bufio.NewReader(conn).ReadString('\n')
+			lines <- line
+			conn.Close()
+		}
+	}()
+	sink, err := NewSyslogSink("tcp", ln.Addr().String(), "auth", "gateway")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	for i := 0; i < 2; i++ {
+		// A write to a connection the server closed may succeed once, so
+		// keep writing until the reconnected connection delivers.
+		deadline := time.Now().Add(5 * time.Second)
+		var got string
+		for got == "" && time.Now().Before(deadline) {
+			if err := sink.Write([]Event{{Timestamp: time.Now(), Profile: "ops", EventType: "execute", Success: true}}); err != nil {
+				t.Fatal(err)
+			}
+			select {
+			case got = <-lines:
+			case <-time.After(100 * time.Millisecond):
+			}
+		}
+		// auth (4) * 8 + info (6); stream transports end messages with a
+		// newline.
+		if !strings.HasPrefix(got, "<38>1 ") || !strings.Contains(got, " gateway ") || !strings.HasSuffix(got, "}\n") {
+			t.Errorf("message %d = %q", i, got)
+		}
+	}
+}
+
+func TestNewSyslogSinkErrors(t *testing.T) {
+	for _, tc := range []struct{ network, address, facility, err string }{
+		{"sctp", "localhost:514", "", "unsupported syslog network"},
+		{"udp", "", "", "address is required"},
+		{"udp", "localhost:514", "local9", "unknown syslog facility"},
+	} {
+		if _, err := NewSyslogSink(tc.network, tc.address, tc.facility, ""); err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%+v: %v", tc, err)
+		}
+	}
+	sink, err := NewSyslogSink("udp", "127.0.0.1:1", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Closing before any write has no connection to close.
+	if err := sink.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWebhookSink(t *testing.T) {
+	var mu sync.Mutex
+	var requests int
+	var got []Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		if r.Header.Get("Authorization") != "Bearer hook" || r.Header.Get("Content-Type") != "application/json" {
+			http.Error(w, "bad headers", http.StatusUnauthorized)
+			return
+		}
+		// The first delivery fails and is retried.
+		if requests == 1 {
+			http.Error(w, "try again", http.StatusServiceUnavailable)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(data, &got)
+	}))
+	defer server.Close()
+
+	sink, err := NewWebhookSink(server.URL, map[string]string{"Authorization": "Bearer hook"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Write([]Event{{ID: 7, Profile: "ops", EventType: "execute"}, {ID: 8, Profile: "ops", EventType: "error"}}); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	if requests != 2 || len(got) != 2 || got[1].ID != 8 {
+		t.Errorf("%d requests, received %+v", requests, got)
+	}
+	mu.Unlock()
+
+	// A second failure is returned.
+	rejecting, err := NewWebhookSink(server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rejecting.Write([]Event{{ID: 9}}); err == nil || !strings.Contains(err.Error(), "status 401") {
+		t.Errorf("err = %v", err)
+	}
+	if _, err := NewWebhookSink("ftp://example.com/hook", nil); err == nil {
+		t.Error("accepted an ftp url")
+	}
+}
@@ -0,0 +1,169 @@
+package canonical
+
+// maxExampleDepth stops example synthesis on deeply nested or recursive
+// schemas.
+const maxExampleDepth = 6
+
+// ExampleValue synthesizes a sample value for a JSON schema. It prefers the
+// schema's own example, default, const or first enum value, then falls back
+// to a placeholder that fits the type and format. With requiredOnly, objects
+// only get their required properties.
+func ExampleValue(schema map[string]any, requiredOnly bool) any {
+	return exampleValue(schema, requiredOnly, 0)
+}
+
+func exampleValue(schema map[string]any, requiredOnly bool, depth int) any {
+	if schema == nil || depth > maxExampleDepth {
+		return nil
+	}
+	if v, ok := schema["example"]; ok {
+		return v
+	}
+	if examples := asList(schema["examples"]); len(examples) > 0 {
+		return examples[0]
+	}
+	if v, ok := schema["default"]; ok {
+		return v
+	}
+	if v, ok := schema["const"]; ok {
+		return v
+	}
+	if enum := asList(schema["enum"]); len(enum) > 0 {
+		return enum[0]
+	}
+	for _, key := range []string{"oneOf", "anyOf"} {
+		for _, alt := range asList(schema[key]) {
+			if sub, ok := alt.(map[string]any); ok && schemaType(sub) != "null" {
+				return exampleValue(sub, requiredOnly, depth+1)
+			}
+		}
+	}
+	if allOf := asList(schema["allOf"]); len(allOf) > 0 {
+		merged := map[string]any{}
+		for _, part := range allOf {
+			sub, _ := part.(map[string]any)
+			if obj, ok := exampleValue(sub, requiredOnly, depth+1).(map[string]any); ok {
+				for k, v := range obj {
+					merged[k] = v
+				}
+			}
+		}
+		return merged
+	}
+
+	switch schemaType(schema) {
+	case "object":
+		return exampleObject(schema, requiredOnly, depth)
+	case "array":
+		items, _ := schema["items"].(map[string]any)
+		if items == nil {
+			return []any{}
+		}
+		return []any{exampleValue(items, requiredOnly, depth+1)}
+	case "integer":
+		if v, ok := schema["minimum"]; ok {
+			return v
+		}
+		return 1
+	case "number":
+		if v, ok := schema["minimum"]; ok {
+			return v
+		}
+		return 1.5
+	case "boolean":
+		return true
+	case "null":
+		return nil
+	case "string":
+		return exampleString(schema)
+	}
+	if _, ok := schema["properties"]; ok {
+		return exampleObject(schema, requiredOnly, depth)
+	}
+	return "example"
+}
+
+func exampleObject(schema map[string]any, requiredOnly bool, depth int) map[string]any {
+	props, _ := schema["properties"].(map[string]any)
+	required := map[string]bool{}
+	for _, name := range asList(schema["required"]) {
+		if s, ok := name.(string); ok {
+			required[s] = true
+		}
+	}
+	out := map[string]any{}
+	for name, raw := range props {
+		if requiredOnly && !required[name] {
+			continue
+		}
+		sub, _ := raw.(map[string]any)
+		out[name] = exampleValue(sub, requiredOnly, depth+1)
+	}
+	return out
+}
+
+func exampleString(schema map[string]any) string {
+	switch format, _ := schema["format"].(string); format {
+	case "date-time":
+		return "2024-01-15T09:30:00Z"
+	case "date":
+		return "2024-01-15"
+	case "time":
+		return "09:30:00"
+	case "email":
+		return "user@example.com"
+	case "uri", "url":
+		return "https://example.com"
+	case "hostname":
+		return "example.com"
+	case "ipv4":
+		return "192.0.2.1"
+	case "ipv6":
+		return "2001:db8::1"
+	case "uuid":
+		return "3fa85f64-5717-4562-b3fc-2c963f66afa6"
+	case "byte":
+		return "ZXhhbXBsZQ=="
+	case "password":
+		return "********"
+	}
+	return "example"
+}
+
+// schemaType returns the schema's type, or its first non-null type when
+// "type" is a list.
+func schemaType(schema map[string]any) string {
+	switch t := schema["type"].(type) {
+	case string:
+		return t
+	case []string:
+		for _, s := range t {
+			if s != "null" {
+				return s
+			}
+		}
+	case []any:
+		for _, v := range t {
+			if s, ok := v.(string); ok && s != "null" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// asList normalizes the []any and []string forms schema keywords take
+// depending on whether the schema was decoded or built in code.
+func asList(v any) []any {
+	switch list := v.(type) {
+	case []any:
+		return list
+	case []string:
+		out := make([]any, len(list))
+		for i, s := range list {
+			out[i] = s
+		}
+		return out
+	}
+	return nil
+}
@@ -1,8 +1,11 @@
 package canonical
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
 // ToolName returns an MCP-safe tool name using allowed characters only.
@@ -32,3 +35,89 @@ func sanitizeName(input string) string {
 	}
 	return out
 }
+
+// SanitizeName replaces characters that are not allowed in tool names.
+func SanitizeName(input string) string {
+	return sanitizeName(input)
+}
+
+// nameWords splits an identifier into words at separators and case
+// changes: "getHTTPStatus_v2" → get, HTTP, Status, v2.
+func nameWords(input string) []string {
+	var words []string
+	var cur []rune
+	runes := []rune(input)
+	flush := func() {
+		if len(cur) > 0 {
+			words = append(words, string(cur))
+			cur = nil
+		}
+	}
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush()
+			continue
+		}
+		if unicode.IsUpper(r) && len(cur) > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				flush()
+			}
+		}
+		cur = append(cur, r)
+	}
+	flush()
+	return words
+}
+
+// SnakeCase converts an identifier to snake_case.
+func SnakeCase(input string) string {
+	words := nameWords(input)
+	for i, w := range words {
+		words[i] = strings.ToLower(w)
+	}
+	if len(words) == 0 {
+		return sanitizeName(input)
+	}
+	return strings.Join(words, "_")
+}
+
+// CamelCase converts an identifier to lowerCamelCase.
+func CamelCase(input string) string {
+	words := nameWords(input)
+	if len(words) == 0 {
+		return sanitizeName(input)
+	}
+	var b strings.Builder
+	for i, w := range words {
+		lower := strings.ToLower(w)
+		if i == 0 {
+			b.WriteString(lower)
+			continue
+		}
+		r := []rune(lower)
+		b.WriteString(strings.ToUpper(string(r[0])) + string(r[1:]))
+	}
+	return b.String()
+}
+
+// ShortenName truncates names longer than max, replacing the tail with a
+// hash of the full name so distinct long names stay distinct and a given
+// name always shortens the same way.
+func ShortenName(name string, max int) string {
+	if max <= 0 || len(name) <= max {
+		return name
+	}
+	sum := sha256.Sum256([]byte(name))
+	suffix := "_" + hex.EncodeToString(sum[:])[:8]
+	if max <= len(suffix) {
+		return suffix[1 : max+1]
+	}
+	keep := max - len(suffix)
+	// Do not cut a multi-byte rune in half.
+	for keep > 0 && !utf8.RuneStart(name[keep]) {
+		keep--
+	}
+	return name[:keep] + suffix
+}
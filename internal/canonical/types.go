@@ -1,5 +1,7 @@
 package canonical
 
+import "time"
+
 // Service is a canonical representation of an external API.
 type Service struct {
 	Name       string
@@ -7,6 +9,13 @@ type Service struct {
 	Operations []*Operation
 }
 
+// LoadError records why a configured API could not be loaded.
+type LoadError struct {
+	Service string    `json:"service"`
+	Error   string    `json:"error"`
+	At      time.Time `json:"at"`
+}
+
 // Operation is a canonical operation derived from a spec.
 type Operation struct {
 	ServiceName       string
@@ -15,24 +24,51 @@ type Operation struct {
 	Method            string // HTTP method (GET, POST, etc.)
 	HTTPMethod        string // Alias for Method (for clarity)
 	Path              string
+	FallbackPath      string // Used instead of Path when an optional path parameter is omitted (e.g. list across all namespaces)
 	Summary           string
 	Description       string // Detailed description
 	Parameters        []Parameter
 	RequestBody       *RequestBody
 	InputSchema       map[string]any
 	ResponseSchema    map[string]any
+	PIIFields         []string // JSON paths of response fields the spec classifies as PII, e.g. "$.items[*].email"
 	StaticHeaders     map[string]string
 	SoapNamespace     string
+	SoapVersion       string // "1.1" or "1.2"; empty means 1.1
 	DynamicURLParam   string
 	QueryParamsObject string
 	RequiresCrumb     bool
 	ContentType       string // Content-Type header
 	GraphQL           *GraphQLOperation
 	JSONRPC           *JSONRPCOperation
-	Protocol          string // "http" (default) or "grpc"
+	ODataBatch        *ODataBatchOperation // set on the $batch tool of an OData service
+	SQL               *SQLOperation
+	SOQL              *SOQLQuery           // Salesforce query tool whose SOQL is built from its arguments
+	AWS               *AWSOperation        // operation of an AWS service model
+	Prometheus        *PrometheusOperation // tool of a Prometheus-compatible query API
+	Messaging         *MessagingOperation  // produce or consume tool of a message broker
+	SSH               *SSHOperation        // allowlisted command run on hosts over SSH
+	Workflow          *Workflow            // multi-step tool defined in config
+	Protocol          string               // "http" (default), "grpc", "sql", "messaging", "ssh", "workflow", "builtin" or a custom protocol such as "email"
 	GRPCMeta          *GRPCOperationMeta
-	ActionHint        string         // Explicit action name for CRUD grouping (overrides method/path heuristics)
-	RESTComposite     *RESTComposite // REST CRUD composite metadata
+	ActionHint        string           // Explicit action name for CRUD grouping (overrides method/path heuristics)
+	RESTComposite     *RESTComposite   // REST CRUD composite metadata
+	Security          []SecurityScheme // Auth schemes the operation accepts (any one suffices); empty if none or optional
+	TimeoutSeconds    int              // Per-operation override from a config filter pattern; 0 uses the API's timeout
+	Projection        bool             // Accepts ProjectionArgument to trim the response to selected fields
+}
+
+// ProjectionArgument is the tool argument listing the response fields to
+// return, added to operations of APIs with a projection config.
+const ProjectionArgument = "_fields"
+
+// SecurityScheme describes one way an operation accepts credentials, taken
+// from OpenAPI securitySchemes or Swagger 2 securityDefinitions.
+type SecurityScheme struct {
+	Name      string // Scheme name in the spec (e.g. "petstore_auth")
+	Type      string // bearer, basic, api-key, oauth2 (matching auth.type) or openid
+	In        string // header, query or cookie (api-key only)
+	ParamName string // Header or query parameter name (api-key only)
 }
 
 // Parameter describes an operation input parameter.
@@ -41,6 +77,18 @@ type Parameter struct {
 	In       string // path, query, header
 	Required bool
 	Schema   map[string]any
+	// SegmentSeparator marks a path parameter that may name a nested path
+	// (e.g. a Jenkins job inside folders). The value may be an array of
+	// segments or a "/"-separated string; each segment is escaped and the
+	// segments are joined with the separator (e.g. "/job/").
+	SegmentSeparator string
+	// QueryName is the name sent in the query string or header when it
+	// differs from the argument name, e.g. "$filter" for an OData argument
+	// named filter.
+	QueryName string
+	// ListSeparator makes an array value one query parameter with its items
+	// joined by the separator, instead of one parameter per item.
+	ListSeparator string
 }
 
 // RequestBody describes a JSON request body.
@@ -49,6 +97,14 @@ type RequestBody struct {
 	ContentType string
 	Schema      map[string]any
 	Content     map[string]MediaType // OpenAPI-style content types
+	// Template is a raw body with {{name}} placeholders filled from the
+	// tool arguments (custom curl/.http operations) instead of a "body"
+	// argument.
+	Template string
+	// Constants are deep-merged over the "body" argument and Defaults under
+	// it (config body_templates).
+	Constants map[string]any
+	Defaults  map[string]any
 }
 
 // MediaType describes a media type schema
@@ -63,6 +119,10 @@ type GraphQLOperation struct {
 	ArgTypes          map[string]string
 	DefaultSelection  string
 	RequiresSelection bool
+	// Document is the full query of a persisted query tool, sent as is
+	// with the tool arguments as its variables.
+	Document      string
+	OperationName string
 	// Composite operation support (CRUD grouping)
 	Composite *GraphQLComposite
 }
@@ -90,8 +150,114 @@ type RESTComposite struct {
 	Actions      map[string]*Operation // Action name → original operation
 }
 
+// ODataBatchOperation describes how an OData service takes $batch requests.
+type ODataBatchOperation struct {
+	JSON bool // OData 4.01 JSON batch format instead of multipart/mixed
+}
+
 type JSONRPCOperation struct {
-	MethodName string
+	MethodName   string
+	ByPosition   bool // send params as an array in declaration order instead of an object
+	Notification bool // no result is expected; the request is sent without an id
+}
+
+// Workflow is a tool that runs other operations in sequence.
+type Workflow struct {
+	Steps  []WorkflowStep
+	Output any // result template; nil returns every step's result
+}
+
+// WorkflowStep runs one operation. Args, When and the workflow Output may
+// contain {{input.x}} and {{steps.<id>.body...}} templates.
+type WorkflowStep struct {
+	ID              string
+	Operation       *Operation
+	Args            map[string]any
+	When            string
+	ContinueOnError bool
+}
+
+// SQLOperation describes a generated database table tool.
+type SQLOperation struct {
+	Table      string
+	Action     string // list, get, insert or update
+	Columns    []SQLColumn
+	PrimaryKey []string
+}
+
+// SQLColumn describes a table column as reported by the database.
+type SQLColumn struct {
+	Name       string
+	Type       string // database type name (e.g. "integer", "character varying")
+	Nullable   bool
+	HasDefault bool // assigned by the database when omitted on insert
+}
+
+// MessagingOperation describes a tool that produces to or consumes from
+// a Kafka topic, NATS subject or RabbitMQ queue.
+type MessagingOperation struct {
+	Broker     string // kafka, nats or rabbitmq
+	Topic      string
+	Action     string // produce or consume
+	Exchange   string // RabbitMQ exchange produce publishes to; empty for the default exchange
+	RoutingKey string // RabbitMQ routing key used with Exchange; default: Topic
+}
+
+// SSHOperation describes a tool that runs one command template of a
+// spec_type: ssh API. The template and its parameters stay in the config.
+type SSHOperation struct {
+	Command string   // name of the command in the API's ssh config
+	Hosts   []string // hosts the command may run on
+}
+
+// SOQLQuery describes a Salesforce per-object query tool. The executor
+// builds "SELECT fields FROM Object WHERE ... ORDER BY ... LIMIT n" from
+// the fields, where, order_by and limit arguments and sends it as q.
+type SOQLQuery struct {
+	Object        string
+	Fields        []string // fields that may be selected
+	DefaultFields []string // selected when the call names none
+}
+
+// AWSOperation describes an operation of an AWS service model. The
+// executor builds its request body from the arguments named by Input's
+// members, or sends the Payload argument as the whole body.
+type AWSOperation struct {
+	Protocol    string    // json, rest-json, rest-xml, query or ec2
+	Action      string    // operation name, sent as Action by the query protocols
+	APIVersion  string    // sent as Version by the query protocols
+	SigningName string    // service name in SigV4 signatures
+	Input       *AWSShape // shape of the arguments sent in the body; nil when none are
+	Payload     string    // argument sent as the body, for operations with an explicit payload
+}
+
+// PrometheusOperation describes a tool of a Prometheus-compatible query
+// API. The executor resolves its time arguments, checks the step of range
+// queries and, when the API asks for it, lints the query before sending
+// them.
+type PrometheusOperation struct {
+	Query string   // argument holding a PromQL expression; "" when none
+	Times []string // time arguments: RFC 3339, Unix seconds, now or now-<duration>
+	Range bool     // a query_range tool, whose start, end and step are checked together
+}
+
+// AWSShape is the part of an AWS model shape needed to serialize
+// arguments. Recursive shapes point back to themselves.
+type AWSShape struct {
+	Type       string               // structure, list, map, timestamp, blob, boolean or another scalar type
+	Members    map[string]AWSMember // structure members by argument name
+	Member     *AWSShape            // list items
+	MemberName string               // element name of list items in query requests; default "member"
+	Key, Value *AWSShape            // map entries
+	KeyName    string               // default "key"
+	ValueName  string               // default "value"
+	Flattened  bool                 // list or map serialized without a wrapping element
+}
+
+// AWSMember is a structure member and its serialized name.
+type AWSMember struct {
+	Name  string
+	Shape *AWSShape
 }
 
 type GRPCOperationMeta struct {
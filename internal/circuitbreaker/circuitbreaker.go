@@ -60,6 +60,8 @@ type Stats struct {
 	ConsecutiveFails int    `json:"consecutive_failures"`
 	TotalFailures    int64  `json:"total_failures"`
 	TotalSuccesses   int64  `json:"total_successes"`
+	// Trips counts the times the breaker opened, including failed probes.
+	Trips            int64  `json:"trips"`
 	LastFailureTime  string `json:"last_failure_time,omitempty"`
 	LastFailureError string `json:"last_failure_error,omitempty"`
 }
@@ -76,12 +78,17 @@ type Breaker struct {
 	consecutiveFails int
 	totalFailures    int64
 	totalSuccesses   int64
+	trips            int64
 	lastFailureTime  time.Time
 	lastFailureErr   string
 	openedAt         time.Time
 
 	// nowFunc allows tests to inject a fake clock.
 	nowFunc func() time.Time
+
+	// Shared mode (see Share); nil means local-only.
+	shared    SharedState
+	sharedKey string
 }
 
 // New creates a circuit breaker.
@@ -100,6 +107,15 @@ func New(name string, failureThreshold int, cooldown time.Duration) *Breaker {
 // Allow checks if a request should be allowed through.
 // Returns nil if the request is allowed, or *ErrCircuitOpen if the circuit is open.
 func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	shared, key, state := b.shared, b.sharedKey, b.state
+	b.mu.Unlock()
+	if shared != nil && state == Closed && b.failureThreshold > 0 {
+		if err := b.sharedCheck(shared, key); err != nil {
+			return err
+		}
+	}
+
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
@@ -152,18 +168,37 @@ func (b *Breaker) Allow() error {
 // RecordSuccess records a successful request. Resets failure count and closes the circuit.
 func (b *Breaker) RecordSuccess() {
 	b.mu.Lock()
-	defer b.mu.Unlock()
-
+	recovered := b.state != Closed
+	shared, key := b.shared, b.sharedKey
 	b.consecutiveFails = 0
 	b.totalSuccesses++
 	b.state = Closed
+	b.mu.Unlock()
+
+	if recovered && shared != nil {
+		clearOpen(shared, key)
+	}
 }
 
 // RecordFailure records a failed request. May trip the circuit to Open.
 func (b *Breaker) RecordFailure(err error) {
 	b.mu.Lock()
-	defer b.mu.Unlock()
+	wasOpen := b.state == Open
+	b.recordFailureLocked(err)
+	tripped := !wasOpen && b.state == Open
+	if tripped {
+		b.trips++
+	}
+	shared, key := b.shared, b.sharedKey
+	open := sharedOpen{OpenedAt: b.openedAt, LastErr: b.lastFailureErr}
+	b.mu.Unlock()
+
+	if tripped && shared != nil {
+		publishOpen(shared, key, open, b.cooldown)
+	}
+}
 
+func (b *Breaker) recordFailureLocked(err error) {
 	now := b.nowFunc()
 	b.consecutiveFails++
 	b.totalFailures++
@@ -208,6 +243,7 @@ func (b *Breaker) Stats() Stats {
 		ConsecutiveFails: b.consecutiveFails,
 		TotalFailures:    b.totalFailures,
 		TotalSuccesses:   b.totalSuccesses,
+		Trips:            b.trips,
 	}
 	if !b.lastFailureTime.IsZero() {
 		s.LastFailureTime = b.lastFailureTime.Format(time.RFC3339)
@@ -1,6 +1,7 @@
 package circuitbreaker
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
@@ -164,6 +165,9 @@ func TestFailedProbeReopensCircuit(t *testing.T) {
 	if err == nil {
 		t.Fatal("expected rejection after failed probe")
 	}
+	if trips := b.Stats().Trips; trips != 2 {
+		t.Fatalf("expected 2 trips, got %d", trips)
+	}
 }
 
 func TestSuccessResetsFailureCount(t *testing.T) {
@@ -336,3 +340,70 @@ func TestNilErrorInRecordFailure(t *testing.T) {
 		t.Fatalf("expected 'unknown error', got %q", stats.LastFailureError)
 	}
 }
+
+// memState is an in-memory SharedState (TTL is ignored).
+type memState struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func (m *memState) Get(_ context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.data[key]
+	return v, ok, nil
+}
+
+func (m *memState) Set(_ context.Context, key string, value []byte, _ time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.data == nil {
+		m.data = map[string][]byte{}
+	}
+	m.data[key] = value
+	return nil
+}
+
+func (m *memState) Del(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.data, key)
+	return nil
+}
+
+func TestSharedTripAffectsOtherReplicas(t *testing.T) {
+	now := time.Now()
+	state := &memState{}
+	a := New("test-api", 2, 10*time.Second)
+	b := New("test-api", 2, 10*time.Second)
+	a.nowFunc = func() time.Time { return now }
+	b.nowFunc = func() time.Time { return now }
+	a.Share(state, "breaker:p:test-api")
+	b.Share(state, "breaker:p:test-api")
+
+	for i := 0; i < 2; i++ {
+		_ = a.Allow()
+		a.RecordFailure(fmt.Errorf("upstream 503"))
+	}
+
+	var circuitErr *ErrCircuitOpen
+	if err := b.Allow(); !errors.As(err, &circuitErr) {
+		t.Fatalf("replica b should see the shared trip, got %v", err)
+	}
+	if circuitErr.LastErr != "upstream 503" {
+		t.Errorf("LastErr = %q, want upstream 503", circuitErr.LastErr)
+	}
+
+	// After the cooldown the shared trip no longer blocks b.
+	now = now.Add(11 * time.Second)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("shared trip should expire after cooldown: %v", err)
+	}
+
+	// A successful probe on a clears the shared state.
+	_ = a.Allow()
+	a.RecordSuccess()
+	if _, ok, _ := state.Get(context.Background(), "breaker:p:test-api"); ok {
+		t.Fatal("expected shared state to be cleared after recovery")
+	}
+}
@@ -0,0 +1,80 @@
+package circuitbreaker
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// sharedTimeout bounds every round-trip to the shared state store so a slow
+// store degrades to local-only behaviour instead of delaying requests.
+const sharedTimeout = 500 * time.Millisecond
+
+// SharedState stores the open state of breakers so that replicas of the same
+// API trip together (e.g. Redis). Get reports ok=false for a missing key.
+type SharedState interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Del(ctx context.Context, key string) error
+}
+
+// sharedOpen is the value stored while a breaker is open on any replica.
+type sharedOpen struct {
+	OpenedAt time.Time `json:"opened_at"`
+	LastErr  string    `json:"last_err"`
+}
+
+// Share publishes this breaker's trips to state under key and makes Allow
+// honour trips published by other replicas. Failure counting stays local;
+// only the open state (and its cooldown) is shared. Store errors are ignored
+// so an outage of the shared store falls back to per-replica breakers.
+func (b *Breaker) Share(state SharedState, key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.shared = state
+	b.sharedKey = key
+}
+
+// sharedCheck returns an *ErrCircuitOpen if another replica has tripped the
+// breaker and its cooldown has not yet elapsed.
+func (b *Breaker) sharedCheck(state SharedState, key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), sharedTimeout)
+	defer cancel()
+	raw, ok, err := state.Get(ctx, key)
+	if err != nil || !ok {
+		return nil
+	}
+	var open sharedOpen
+	if err := json.Unmarshal(raw, &open); err != nil {
+		return nil
+	}
+	now := b.nowFunc()
+	remaining := b.cooldown - now.Sub(open.OpenedAt)
+	if remaining <= 0 {
+		return nil
+	}
+	return &ErrCircuitOpen{
+		Name:    b.name,
+		LastErr: open.LastErr,
+		Since:   now.Sub(open.OpenedAt),
+		RetryIn: remaining,
+	}
+}
+
+// publishOpen records a local trip in the shared store.
+func publishOpen(state SharedState, key string, open sharedOpen, ttl time.Duration) {
+	raw, err := json.Marshal(open)
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sharedTimeout)
+	defer cancel()
+	_ = state.Set(ctx, key, raw, ttl)
+}
+
+// clearOpen removes a published trip once the API has recovered.
+func clearOpen(state SharedState, key string) {
+	ctx, cancel := context.WithTimeout(context.Background(), sharedTimeout)
+	defer cancel()
+	_ = state.Del(ctx, key)
+}
@@ -0,0 +1,76 @@
+// Package cluster provides the shared state used when several Skyline
+// replicas run behind a load balancer: a Redis-backed Store for distributed
+// rate limit counters, circuit breaker state, cached responses and MCP
+// session ownership.
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// incrWindow increments a counter and sets its expiry on creation in one
+// atomic step, so a crash between INCR and PEXPIRE cannot leak a key.
+var incrWindow = redis.NewScript(`local v = redis.call('INCR', KEYS[1])
+if v == 1 then redis.call('PEXPIRE', KEYS[1], ARGV[1]) end
+return v`)
+
+// Store is the shared key/value state used across replicas. All keys are
+// namespaced with the configured prefix.
+type Store struct {
+	client *redis.Client
+	prefix string
+}
+
+// Open parses a redis:// or rediss:// URL
+// (redis://[user:password@]host:port[/db]) and returns a Store that prefixes
+// every key with prefix. No connection is made until the first command.
+func Open(rawURL, prefix string) (*Store, error) {
+	opts, err := redis.ParseURL(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse redis url: %w", err)
+	}
+	return &Store{client: redis.NewClient(opts), prefix: prefix}, nil
+}
+
+// Ping checks that Redis is reachable with the configured credentials.
+func (s *Store) Ping(ctx context.Context) error {
+	return s.client.Ping(ctx).Err()
+}
+
+// Close closes the connections to Redis.
+func (s *Store) Close() error {
+	return s.client.Close()
+}
+
+// Incr increments the counter at key and returns its new value. The key
+// expires ttl after it was first created (fixed-window semantics).
+func (s *Store) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return incrWindow.Run(ctx, s.client, []string{s.prefix + key}, ttl.Milliseconds()).Int64()
+}
+
+// Get returns the value at key; ok is false when the key does not exist.
+func (s *Store) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	b, err := s.client.Get(ctx, s.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return b, true, nil
+}
+
+// Set stores value at key. A ttl of 0 keeps the key until deleted.
+func (s *Store) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return s.client.Set(ctx, s.prefix+key, value, ttl).Err()
+}
+
+// Del removes key.
+func (s *Store) Del(ctx context.Context, key string) error {
+	return s.client.Del(ctx, s.prefix+key).Err()
+}
@@ -0,0 +1,110 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func openTestStore(t *testing.T, rawURL string) *Store {
+	t.Helper()
+	store, err := Open(rawURL, "test:")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	srv := miniredis.RunT(t)
+	srv.RequireAuth("s3cret")
+	store := openTestStore(t, "redis://:s3cret@"+srv.Addr())
+	ctx := context.Background()
+
+	if err := store.Ping(ctx); err != nil {
+		t.Fatalf("ping: %v", err)
+	}
+	if _, ok, err := store.Get(ctx, "missing"); err != nil || ok {
+		t.Fatalf("Get(missing) = ok %v, err %v", ok, err)
+	}
+	if err := store.Set(ctx, "k", []byte("v"), time.Minute); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if v, ok, err := store.Get(ctx, "k"); err != nil || !ok || string(v) != "v" {
+		t.Fatalf("Get(k) = %q, %v, %v", v, ok, err)
+	}
+	if !srv.Exists("test:k") {
+		t.Error("key not stored under the prefix")
+	}
+	for want := int64(1); want <= 3; want++ {
+		n, err := store.Incr(ctx, "c", time.Minute)
+		if err != nil || n != want {
+			t.Fatalf("Incr = %d, %v; want %d", n, err, want)
+		}
+	}
+	if err := store.Del(ctx, "k"); err != nil {
+		t.Fatalf("Del: %v", err)
+	}
+	if _, ok, _ := store.Get(ctx, "k"); ok {
+		t.Error("key still present after Del")
+	}
+}
+
+func TestStoreExpiry(t *testing.T) {
+	srv := miniredis.RunT(t)
+	store := openTestStore(t, "redis://"+srv.Addr())
+	ctx := context.Background()
+
+	if _, err := store.Incr(ctx, "window", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set(ctx, "short", []byte("v"), time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set(ctx, "kept", []byte("v"), 0); err != nil {
+		t.Fatal(err)
+	}
+	// Later increments keep the expiry of the window's first one.
+	srv.FastForward(30 * time.Second)
+	if n, err := store.Incr(ctx, "window", time.Minute); err != nil || n != 2 {
+		t.Fatalf("Incr = %d, %v", n, err)
+	}
+	srv.FastForward(31 * time.Second)
+	if n, err := store.Incr(ctx, "window", time.Minute); err != nil || n != 1 {
+		t.Errorf("Incr after the window = %d, %v; want a new window", n, err)
+	}
+	if _, ok, _ := store.Get(ctx, "short"); ok {
+		t.Error("key outlived its ttl")
+	}
+	if _, ok, _ := store.Get(ctx, "kept"); !ok {
+		t.Error("key without ttl expired")
+	}
+}
+
+func TestStoreRejectsBadCredentials(t *testing.T) {
+	srv := miniredis.RunT(t)
+	srv.RequireAuth("s3cret")
+	store := openTestStore(t, "redis://:wrong@"+srv.Addr())
+	if err := store.Ping(context.Background()); err == nil {
+		t.Fatal("ping with a wrong password succeeded")
+	}
+}
+
+func TestOpenURL(t *testing.T) {
+	for _, rawURL := range []string{"http://redis", "redis://redis/abc", "://"} {
+		if _, err := Open(rawURL, ""); err == nil {
+			t.Errorf("%s: expected error", rawURL)
+		}
+	}
+	for _, rawURL := range []string{"redis://localhost", "redis://:pw@redis:6380/2", "rediss://app:pw@redis:6379"} {
+		store, err := Open(rawURL, "")
+		if err != nil {
+			t.Errorf("%s: %v", rawURL, err)
+			continue
+		}
+		store.Close()
+	}
+}
@@ -10,19 +10,20 @@ import (
 	"skyline-mcp/internal/mcp"
 )
 
-// SetupCodeExecution sets up code execution for the MCP server
+// SetupCodeExecution sets up code execution for the MCP server in
+// workspaceDir (default: skyline-workspace under the temp dir).
 // Returns the code executor if successful, or nil if code execution is not available
-func SetupCodeExecution(registry *mcp.Registry, logger *slog.Logger) (*executor.Executor, error) {
+func SetupCodeExecution(registry *mcp.Registry, logger *slog.Logger, workspaceDir string, opts executor.Options) (*executor.Executor, error) {
 	// Validate runtime (goja is always available since it's embedded)
 	if err := executor.ValidateRuntime(); err != nil {
 		logger.Debug("runtime not available, code execution disabled", "component", "codegen", "error", err)
 		return nil, nil // Not an error, just disabled
 	}
 
-	logger.Debug("goja runtime available, enabling code execution", "component", "codegen")
-
 	// Create workspace directory
-	workspaceDir := filepath.Join(os.TempDir(), "skyline-workspace")
+	if workspaceDir == "" {
+		workspaceDir = filepath.Join(os.TempDir(), "skyline-workspace")
+	}
 	if err := os.MkdirAll(workspaceDir, 0755); err != nil {
 		return nil, fmt.Errorf("create workspace dir: %w", err)
 	}
@@ -51,6 +52,17 @@ func SetupCodeExecution(registry *mcp.Registry, logger *slog.Logger) (*executor.
 	// Create executor
 	mcpEndpoint := "http://localhost:8191/internal/call-tool"
 	exec := executor.NewExecutor(workspaceDir, mcpEndpoint)
+	if err := exec.SetOptions(opts); err != nil {
+		return nil, err
+	}
+	switch {
+	case exec.Engine() == executor.EngineDeno:
+		logger.Debug("deno found, using it as the code execution sandbox", "component", "codegen")
+	case opts.Engine == executor.EngineDeno:
+		logger.Warn("deno not found, falling back to the embedded goja sandbox", "component", "codegen", "deno_path", opts.DenoPath)
+	default:
+		logger.Debug("using the embedded goja sandbox", "component", "codegen")
+	}
 
 	// Setup workspace with generated files
 	if err := exec.SetupWorkspace(serviceFiles); err != nil {
@@ -203,9 +203,10 @@ func generateIndexFile(toolNames []string) string {
 }
 
 // generateClientFile generates the MCP client.ts
-// Host functions __callMCPTool and __searchTools are injected by the Go executor (goja).
+// Host functions __callMCPTool and __searchTools are injected by the Go executor
+// (natively in goja, through a loopback bridge in Deno).
 func generateClientFile() string {
-	return `// MCP Tool Client for embedded goja runtime
+	return `// MCP Tool Client for the code execution sandbox (Deno or embedded goja)
 // Host functions are provided as globals by the Go executor
 
 export function callMCPTool(toolName: string, args: any): any {
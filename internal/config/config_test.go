@@ -1,6 +1,7 @@
 package config
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -192,3 +193,302 @@ func containsHelper(s, substr string) bool {
 	}
 	return false
 }
+
+func TestConfig_Validate_Workflows(t *testing.T) {
+	step := []WorkflowStep{{ID: "one", Tool: "api__get"}}
+	tests := []struct {
+		name      string
+		cfg       Config
+		wantError string
+	}{
+		{
+			name: "valid",
+			cfg: Config{
+				APIs:      []APIConfig{{Name: "api", SpecURL: "https://api.example.com/openapi.json"}},
+				Workflows: []WorkflowConfig{{Name: "sync", Steps: step}},
+			},
+		},
+		{
+			name:      "no steps",
+			cfg:       Config{Workflows: []WorkflowConfig{{Name: "sync"}}},
+			wantError: "at least one step",
+		},
+		{
+			name:      "duplicate step id",
+			cfg:       Config{Workflows: []WorkflowConfig{{Name: "sync", Steps: append(step, step[0])}}},
+			wantError: "duplicate id",
+		},
+		{
+			name:      "bad input type",
+			cfg:       Config{Workflows: []WorkflowConfig{{Name: "sync", Steps: step, Inputs: []WorkflowInput{{Name: "n", Type: "date"}}}}},
+			wantError: "unsupported type",
+		},
+		{
+			name:      "duplicate workflow",
+			cfg:       Config{Workflows: []WorkflowConfig{{Name: "sync", Steps: step}, {Name: "sync", Steps: step}}},
+			wantError: "duplicate",
+		},
+		{
+			name: "reserved api name",
+			cfg: Config{
+				APIs:      []APIConfig{{Name: "workflows", SpecURL: "https://api.example.com/openapi.json"}},
+				Workflows: []WorkflowConfig{{Name: "sync", Steps: step}},
+			},
+			wantError: "reserved",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if tt.wantError == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !contains(err.Error(), tt.wantError) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantError, err)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate_ToolNaming(t *testing.T) {
+	yes := true
+	api := func(mod func(*APIConfig)) []APIConfig {
+		a := APIConfig{Name: "api", SpecURL: "https://api.example.com/openapi.json"}
+		mod(&a)
+		return []APIConfig{a}
+	}
+	tests := []struct {
+		name      string
+		cfg       Config
+		wantError string
+	}{
+		{
+			name: "valid",
+			cfg: Config{
+				APIs:       api(func(a *APIConfig) { a.ToolPrefix = "gh"; a.ToolNames = map[string]string{"repos/get": "get_repo"} }),
+				ToolNaming: &ToolNamingConfig{Prefix: "none", Case: "snake", MaxLength: 64},
+			},
+		},
+		{name: "bad case", cfg: Config{APIs: api(func(*APIConfig) {}), ToolNaming: &ToolNamingConfig{Case: "kebab"}}, wantError: "tool_naming.case"},
+		{name: "short max length", cfg: Config{APIs: api(func(*APIConfig) {}), ToolNaming: &ToolNamingConfig{MaxLength: 8}}, wantError: "max_length"},
+		{name: "bad rename", cfg: Config{APIs: api(func(a *APIConfig) { a.ToolNames = map[string]string{"op": "get repo"} })}, wantError: "tool_names"},
+		{name: "bad prefix", cfg: Config{APIs: api(func(a *APIConfig) { a.ToolPrefix = "git.hub" })}, wantError: "tool_prefix"},
+		{name: "tool alias", cfg: Config{ToolAliases: map[string]string{"api__old": "api__new"}}},
+		{name: "read-only destructive override", cfg: Config{ToolOverrides: map[string]ToolOverride{"api__op": {ReadOnly: &yes, Destructive: &yes}}}, wantError: "both read_only and destructive"},
+		{name: "chained tool alias", cfg: Config{ToolAliases: map[string]string{"api__v1": "api__v2", "api__v2": "api__v3"}}, wantError: "is an alias itself"},
+		{name: "negative quota", cfg: Config{Quota: &QuotaConfig{Daily: -1}}, wantError: "quota: daily"},
+		{name: "empty response header", cfg: Config{APIs: api(func(a *APIConfig) { a.ResponseHeaders = []string{"ETag", " "} })}, wantError: "apis[0].response_headers[1]"},
+		{name: "bad http version", cfg: Config{APIs: api(func(a *APIConfig) { a.Transport = &TransportConfig{HTTPVersion: "2"} })}, wantError: "apis[0].transport.http_version"},
+		{name: "negative keepalive", cfg: Config{APIs: api(func(a *APIConfig) { a.Transport = &TransportConfig{TCPKeepAliveSeconds: -5} })}, wantError: "tcp_keepalive_seconds"},
+		{name: "hosts override", cfg: Config{APIs: api(func(a *APIConfig) {
+			a.Transport = &TransportConfig{Hosts: map[string]string{"api.example.com": "10.0.0.5", "auth.example.com:443": "127.0.0.1:8443"}}
+		})}},
+		{name: "bad hosts port", cfg: Config{APIs: api(func(a *APIConfig) {
+			a.Transport = &TransportConfig{Hosts: map[string]string{"api.example.com": "10.0.0.5:https"}}
+		})}, wantError: "apis[0].transport.hosts[api.example.com]"},
+		{name: "hmac signing", cfg: Config{APIs: api(func(a *APIConfig) {
+			a.Signing = &SigningConfig{Type: "hmac", Secret: "736563726574", SecretEncoding: "hex", Payload: "{{method}}\n{{ path }}\n{{header.X-Date}}\n{{body_sha256}}"}
+		})}},
+		{name: "unknown signing placeholder", cfg: Config{APIs: api(func(a *APIConfig) {
+			a.Signing = &SigningConfig{Type: "hmac", Secret: "s", Payload: "{{verb}} {{path}}"}
+		})}, wantError: "apis[0].signing.payload: unknown placeholder {{verb}}"},
+		{name: "bad signing secret encoding", cfg: Config{APIs: api(func(a *APIConfig) {
+			a.Signing = &SigningConfig{Type: "hmac", Secret: "not hex", SecretEncoding: "hex"}
+		})}, wantError: "apis[0].signing.secret"},
+		{name: "hooks", cfg: Config{APIs: api(func(a *APIConfig) {
+			a.Hooks = &HooksConfig{Before: `request.args.limit = 10`, TimeoutMS: 200}
+		})}},
+		{name: "empty hooks", cfg: Config{APIs: api(func(a *APIConfig) {
+			a.Hooks = &HooksConfig{TimeoutMS: 200}
+		})}, wantError: "apis[0].hooks: before or after is required"},
+		{name: "hook timeout too long", cfg: Config{APIs: api(func(a *APIConfig) {
+			a.Hooks = &HooksConfig{After: `response.status = 200`, TimeoutMS: 60000}
+		})}, wantError: "apis[0].hooks.timeout_ms"},
+		{name: "salesforce", cfg: Config{APIs: []APIConfig{{
+			Name: "crm", SpecType: "salesforce", BaseURLOverride: "https://acme.my.salesforce.com",
+			Salesforce: &SalesforceConfig{Objects: []string{"Account", "Invoice__c"}, APIVersion: "61.0"},
+		}}}},
+		{name: "salesforce without base url", cfg: Config{APIs: []APIConfig{{Name: "crm", SpecType: "salesforce"}}}, wantError: "base_url_override (the org's My Domain URL) is required"},
+		{name: "bad salesforce object", cfg: Config{APIs: []APIConfig{{
+			Name: "crm", SpecType: "salesforce", BaseURLOverride: "https://acme.my.salesforce.com",
+			Salesforce: &SalesforceConfig{Objects: []string{"Account WHERE"}},
+		}}}, wantError: "apis[0].salesforce.objects"},
+		{name: "prometheus", cfg: Config{APIs: []APIConfig{{
+			Name: "metrics", SpecType: "prometheus", BaseURLOverride: "http://prometheus:9090",
+			Prometheus: &PrometheusConfig{Lint: true, MaxPoints: 30000},
+		}}}},
+		{name: "prometheus without base url", cfg: Config{APIs: []APIConfig{{Name: "metrics", SpecType: "prometheus"}}}, wantError: "base_url_override (the server URL) is required for prometheus"},
+		{name: "kafka", cfg: Config{APIs: []APIConfig{{
+			Name: "events", SpecType: "messaging", Auth: &AuthConfig{Type: "basic", Username: "svc", Password: "secret"},
+			Messaging: &MessagingConfig{Broker: "kafka", Servers: []string{"kafka-1:9092", "kafka-2:9092"}, TLS: true, SASLMechanism: "SCRAM-SHA-512",
+				Topics: []MessagingTopic{{Name: "orders"}, {Name: "audit.log", ReadOnly: true}}},
+		}}}},
+		{name: "messaging without config", cfg: Config{APIs: []APIConfig{{Name: "events", SpecType: "messaging"}}}, wantError: "messaging config is required"},
+		{name: "writable nats wildcard", cfg: Config{APIs: []APIConfig{{
+			Name: "events", SpecType: "messaging",
+			Messaging: &MessagingConfig{Broker: "nats", Servers: []string{"nats://nats:4222"}, Topics: []MessagingTopic{{Name: "orders.*"}}},
+		}}}, wantError: `apis[0].messaging.topics[0]: wildcard subject "orders.*" must be read_only`},
+		{name: "kafka bearer auth", cfg: Config{APIs: []APIConfig{{
+			Name: "events", SpecType: "messaging", Auth: &AuthConfig{Type: "bearer", Token: "t"},
+			Messaging: &MessagingConfig{Broker: "kafka", Servers: []string{"kafka:9092"}, Topics: []MessagingTopic{{Name: "orders"}}},
+		}}}, wantError: "apis[0].auth.type: kafka takes basic auth"},
+		{name: "exchange outside rabbitmq", cfg: Config{APIs: []APIConfig{{
+			Name: "events", SpecType: "messaging",
+			Messaging: &MessagingConfig{Broker: "kafka", Servers: []string{"kafka:9092"}, Topics: []MessagingTopic{{Name: "orders", Exchange: "x"}}},
+		}}}, wantError: "exchange and routing_key are only for rabbitmq"},
+		{name: "ssh", cfg: Config{APIs: []APIConfig{{
+			Name: "ops", SpecType: "ssh",
+			SSH: &SSHConfig{User: "deploy", PrivateKey: "${OPS_KEY}", KnownHostsFile: "/etc/ssh/ssh_known_hosts",
+				Hosts: []SSHHost{{Name: "web-1", Address: "10.0.0.11"}, {Name: "web-2", Address: "10.0.0.12:2222", HostKey: testHostKey}},
+				Commands: []SSHCommand{
+					{Name: "restart_service", Command: "sudo systemctl restart {{service}}", Params: []SSHParam{{Name: "service", Enum: []string{"nginx", "api"}}}},
+					{Name: "tail_logs", Command: "tail -n {{ lines }} /var/log/app/{{file}}", Hosts: []string{"web-1"}, Params: []SSHParam{
+						{Name: "lines", Type: "integer", Minimum: intRef(1), Maximum: intRef(1000), Default: 100},
+						{Name: "file", Pattern: `[a-z0-9_.-]+\.log`},
+					}},
+				}},
+		}}}},
+		{name: "ssh without config", cfg: Config{APIs: []APIConfig{{Name: "ops", SpecType: "ssh"}}}, wantError: "ssh config is required"},
+		{name: "ssh quoted placeholder", cfg: Config{APIs: []APIConfig{{
+			Name: "ops", SpecType: "ssh",
+			SSH: &SSHConfig{User: "deploy", PrivateKeyFile: "/keys/ops", Hosts: []SSHHost{{Name: "web-1", Address: "web-1", HostKey: testHostKey}},
+				Commands: []SSHCommand{{Name: "grep_logs", Command: "grep '{{q}}' /var/log/app.log", Params: []SSHParam{{Name: "q", Pattern: "[a-z]+"}}}}},
+		}}}, wantError: "apis[0].ssh.commands[0].command: placeholder {{q}} is quoted"},
+		{name: "ssh free-form string", cfg: Config{APIs: []APIConfig{{
+			Name: "ops", SpecType: "ssh",
+			SSH: &SSHConfig{User: "deploy", PrivateKeyFile: "/keys/ops", Hosts: []SSHHost{{Name: "web-1", Address: "web-1", HostKey: testHostKey}},
+				Commands: []SSHCommand{{Name: "run", Command: "sh -c {{script}}", Params: []SSHParam{{Name: "script"}}}}},
+		}}}, wantError: "string parameter script needs an enum or a pattern"},
+		{name: "ssh undeclared placeholder", cfg: Config{APIs: []APIConfig{{
+			Name: "ops", SpecType: "ssh",
+			SSH: &SSHConfig{User: "deploy", PrivateKeyFile: "/keys/ops", Hosts: []SSHHost{{Name: "web-1", Address: "web-1", HostKey: testHostKey}},
+				Commands: []SSHCommand{{Name: "restart", Command: "systemctl restart {{unit}}"}}},
+		}}}, wantError: "placeholder {{unit}} has no entry in params"},
+		{name: "ssh unverified host", cfg: Config{APIs: []APIConfig{{
+			Name: "ops", SpecType: "ssh",
+			SSH: &SSHConfig{User: "deploy", PrivateKeyFile: "/keys/ops", Hosts: []SSHHost{{Name: "web-1", Address: "web-1"}},
+				Commands: []SSHCommand{{Name: "uptime", Command: "uptime"}}},
+		}}}, wantError: "apis[0].ssh.hosts[0]: host_key is required without ssh.known_hosts_file"},
+		{name: "negative max timeout", cfg: Config{MaxTimeoutSeconds: -1}, wantError: "max_timeout_seconds"},
+		{name: "contract test", cfg: Config{ContractTests: []ContractTest{{Tool: "api__get_pet", Args: map[string]any{"id": 1}, ExpectStatus: []int{200, 404}}}}},
+		{name: "contract test without tool", cfg: Config{ContractTests: []ContractTest{{Name: "pets"}}}, wantError: "contract_tests[0]: tool is required"},
+		{name: "bad contract status", cfg: Config{ContractTests: []ContractTest{{Tool: "api__get_pet", ExpectStatus: []int{2000}}}}, wantError: "contract_tests[0].expect_status"},
+		{name: "filter timeout in blocklist", cfg: Config{APIs: api(func(a *APIConfig) {
+			a.Filter = &OperationFilterEnhanced{Mode: "blocklist", Operations: []OperationPattern{{Method: "DELETE", TimeoutSeconds: 60}}}
+		})}, wantError: "only allowed in allowlist mode"},
+		{name: "bad data policy action", cfg: Config{APIs: api(func(a *APIConfig) {
+			a.DataPolicy = &DataPolicyConfig{Rules: []DataPolicyRule{{Fields: []string{"ssn"}, Action: "encrypt"}}}
+		})}, wantError: "data_policy.rules[0].action"},
+		{name: "bad data policy pattern", cfg: Config{APIs: api(func(a *APIConfig) {
+			a.DataPolicy = &DataPolicyConfig{Rules: []DataPolicyRule{{Fields: []string{"[ssn"}, Action: "drop"}}}
+		})}, wantError: "data_policy.rules[0].fields[0]"},
+		{name: "bad api quota action", cfg: Config{APIs: api(func(a *APIConfig) { a.Quota = &QuotaConfig{Daily: 5, OnExceed: "block"} })}, wantError: "apis[0].quota.on_exceed"},
+		{name: "wsse auth", cfg: Config{APIs: api(func(a *APIConfig) {
+			a.Auth = &AuthConfig{Type: "wsse", Username: "svc", Password: "secret", PasswordType: "digest"}
+			a.SOAPHeaders = []string{`<t:Tenant xmlns:t="urn:t">{{mcp.profile}}</t:Tenant>`}
+		})}},
+		{name: "bad wsse password type", cfg: Config{APIs: api(func(a *APIConfig) {
+			a.Auth = &AuthConfig{Type: "wsse", Username: "svc", Password: "secret", PasswordType: "hash"}
+		})}, wantError: "auth.password_type"},
+		{name: "oauth2 device flow", cfg: Config{APIs: api(func(a *APIConfig) {
+			a.Auth = &AuthConfig{Type: "oauth2", Flow: "device_code", ClientID: "cli",
+				DeviceAuthURL: "https://github.com/login/device/code", TokenURL: "https://github.com/login/oauth/access_token"}
+		})}},
+		{name: "oauth2 device flow without endpoint", cfg: Config{APIs: api(func(a *APIConfig) {
+			a.Auth = &AuthConfig{Type: "oauth2", Flow: "device_code", ClientID: "cli", TokenURL: "https://github.com/login/oauth/access_token"}
+		})}, wantError: "auth.device_authorization_url"},
+		{name: "bad oauth2 flow", cfg: Config{APIs: api(func(a *APIConfig) {
+			a.Auth = &AuthConfig{Type: "oauth2", Flow: "implicit", ClientID: "cli"}
+		})}, wantError: "auth.flow"},
+		{name: "aws sigv4 auth", cfg: Config{APIs: api(func(a *APIConfig) {
+			a.Auth = &AuthConfig{Type: "aws-sigv4", AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret", Region: "eu-west-1"}
+		})}},
+		{name: "aws sigv4 without secret key", cfg: Config{APIs: api(func(a *APIConfig) {
+			a.Auth = &AuthConfig{Type: "aws-sigv4", AccessKeyID: "AKIDEXAMPLE"}
+		})}, wantError: "auth.secret_access_key"},
+		{name: "malformed soap header", cfg: Config{APIs: api(func(a *APIConfig) { a.SOAPHeaders = []string{"<Tenant>acme"} })}, wantError: "apis[0].soap_headers[0]"},
+		{name: "text soap header", cfg: Config{APIs: api(func(a *APIConfig) { a.SOAPHeaders = []string{"acme"} })}, wantError: "must contain an XML element"},
+		{name: "persisted queries", cfg: Config{APIs: api(func(a *APIConfig) {
+			a.GraphQL = &GraphQLConfig{
+				PersistedQueries: []PersistedQuery{{Name: "viewer", Query: "query Viewer { viewer { ...Who } }"}},
+				Fragments:        []string{"fragment Who on User { login }"},
+				APQ:              true,
+			}
+		})}},
+		{name: "persisted query with two operations", cfg: Config{APIs: api(func(a *APIConfig) {
+			a.GraphQL = &GraphQLConfig{PersistedQueries: []PersistedQuery{{Name: "two", Query: "query A { a } query B { b }"}}}
+		})}, wantError: "apis[0].graphql.persisted_queries[0].query"},
+		{name: "duplicate persisted query", cfg: Config{APIs: api(func(a *APIConfig) {
+			a.GraphQL = &GraphQLConfig{PersistedQueries: []PersistedQuery{{Name: "q", Query: "{ a }"}, {Name: "q", Query: "{ b }"}}}
+		})}, wantError: "duplicate name"},
+		{name: "fragment that is a query", cfg: Config{APIs: api(func(a *APIConfig) {
+			a.GraphQL = &GraphQLConfig{Fragments: []string{"{ a }"}}
+		})}, wantError: "apis[0].graphql.fragments[0]"},
+		{name: "pinned graphql schema", cfg: Config{APIs: api(func(a *APIConfig) {
+			a.SpecURL = "https://api.example.com/graphql"
+			a.GraphQL = &GraphQLConfig{Schema: "./schema.graphql"}
+		})}},
+		{name: "pinned graphql schema with spec_file", cfg: Config{APIs: api(func(a *APIConfig) {
+			a.SpecURL, a.SpecFile = "", "./schema.graphql"
+			a.GraphQL = &GraphQLConfig{Schema: "./schema.graphql"}
+		})}, wantError: "replaces spec_file"},
+		{name: "pinned graphql schema without endpoint", cfg: Config{APIs: api(func(a *APIConfig) {
+			a.SpecURL = ""
+			a.GraphQL = &GraphQLConfig{Schema: "https://cdn.example.com/schema.graphql"}
+		})}, wantError: "apis[0].graphql.schema: spec_url or base_url_override"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if tt.wantError == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !contains(err.Error(), tt.wantError) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantError, err)
+			}
+		})
+	}
+}
+
+// testHostKey is the public key of an ed25519 host for the ssh cases.
+const testHostKey = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIAhLrR5nFq/ceOzc6PtQ8k7R8g/8dwpPdMnXhNvD78nG"
+
+func intRef(n int) *int { return &n }
+
+func TestConfig_LocalPaths(t *testing.T) {
+	cfg := Config{
+		APIs: []APIConfig{
+			{Name: "specs", SpecFile: "/srv/specs/*.yaml", CustomOperations: []CustomOperation{{HTTPFile: "/srv/ops.http"}}},
+			{Name: "remote", SpecURL: "https://api.example.com/openapi.json", GraphQL: &GraphQLConfig{Schema: "FILE:///srv/schema.graphql"}},
+			{Name: "shop", SpecType: "sql", Database: &DatabaseConfig{Driver: "sqlite", DSN: "file:/srv/shop.db?_busy_timeout=5000"}},
+			{Name: "cache", SpecType: "sql", Database: &DatabaseConfig{Driver: "sqlite3", DSN: "file::memory:?cache=shared"}},
+			{Name: "pg", SpecType: "sql", Database: &DatabaseConfig{Driver: "postgres", DSN: "postgres://db/shop"}},
+			{Name: "ops", SpecType: "ssh", SSH: &SSHConfig{PrivateKeyFile: "~/.ssh/id_ed25519", KnownHostsFile: "/srv/known_hosts"}},
+		},
+		Recording:      &RecordingConfig{Dir: "./recordings"},
+		SchemaLearning: &SchemaLearningConfig{File: "/srv/schemas.json"},
+	}
+	var got []string
+	for _, p := range cfg.LocalPaths() {
+		got = append(got, p.Field+"="+p.Path)
+	}
+	want := []string{
+		"apis[0].spec_file=/srv/specs/*.yaml",
+		"apis[0].custom_operations[0].http_file=/srv/ops.http",
+		"apis[1].graphql.schema=/srv/schema.graphql",
+		"apis[2].database.dsn=/srv/shop.db",
+		"apis[5].ssh.private_key_file=~/.ssh/id_ed25519",
+		"apis[5].ssh.known_hosts_file=/srv/known_hosts",
+		"recording.dir=./recordings",
+		"schema_learning.file=/srv/schemas.json",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("LocalPaths() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,31 @@
+package config
+
+import "fmt"
+
+// ContractTest is one check run by `skyline test`: a tool called with fixed
+// arguments whose response must have an expected status and match the
+// tool's output schema.
+type ContractTest struct {
+	// Name identifies the check in reports; default the tool name.
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	Tool string `json:"tool" yaml:"tool"`
+	// Args are the tool arguments; default an example built from the
+	// tool's input schema.
+	Args map[string]any `json:"args,omitempty" yaml:"args,omitempty"`
+	// ExpectStatus lists the accepted HTTP statuses; default any 2xx.
+	ExpectStatus []int `json:"expect_status,omitempty" yaml:"expect_status,omitempty"`
+	// SkipSchema accepts responses that do not match the output schema.
+	SkipSchema bool `json:"skip_schema,omitempty" yaml:"skip_schema,omitempty"`
+}
+
+func (t ContractTest) validate(i int) error {
+	if t.Tool == "" {
+		return fmt.Errorf("contract_tests[%d]: tool is required", i)
+	}
+	for _, status := range t.ExpectStatus {
+		if status < 100 || status > 599 {
+			return fmt.Errorf("contract_tests[%d].expect_status: %d is not an HTTP status", i, status)
+		}
+	}
+	return nil
+}
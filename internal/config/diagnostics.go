@@ -0,0 +1,455 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Diagnostic severities.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Diagnostic is a single validation finding, annotated with the location of
+// the offending node in the source document. Line and Column are 1-based and
+// zero when the position is unknown.
+type Diagnostic struct {
+	Severity string `json:"severity"`
+	Path     string `json:"path,omitempty"` // e.g. "apis[0].auth.token"
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Message  string `json:"message"`
+	// Rule names the lint rule behind the finding; empty for validation.
+	Rule string `json:"rule,omitempty"`
+}
+
+func (d Diagnostic) String() string {
+	var b strings.Builder
+	if d.Line > 0 {
+		fmt.Fprintf(&b, "%d:%d: ", d.Line, d.Column)
+	}
+	b.WriteString(d.Severity)
+	b.WriteString(": ")
+	if d.Path != "" {
+		b.WriteString(d.Path)
+		b.WriteString(": ")
+	}
+	b.WriteString(d.Message)
+	if d.Rule != "" {
+		fmt.Fprintf(&b, " (%s)", d.Rule)
+	}
+	return b.String()
+}
+
+// HasErrors reports whether any diagnostic has error severity.
+func HasErrors(diags []Diagnostic) bool {
+	for _, d := range diags {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Diagnose validates raw profile config bytes (YAML or JSON) and returns all
+// findings instead of stopping at the first one. On top of Validate it reports
+// unknown fields, auth settings that do not apply to the selected auth type,
+// malformed URLs and filter settings that the selected mode never reads.
+// Environment variables are not expanded, so "${VAR}" values are accepted.
+func Diagnose(data []byte) []Diagnostic {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return []Diagnostic{syntaxDiagnostic(err)}
+	}
+	if len(root.Content) == 0 {
+		return nil
+	}
+	doc := root.Content[0]
+
+	var diags []Diagnostic
+	checkUnknownFields(doc, reflect.TypeOf(Config{}), "", &diags)
+
+	var cfg Config
+	if err := doc.Decode(&cfg); err != nil {
+		var typeErr *yaml.TypeError
+		if errors.As(err, &typeErr) {
+			for _, msg := range typeErr.Errors {
+				diags = append(diags, syntaxDiagnostic(errors.New(msg)))
+			}
+		} else {
+			diags = append(diags, syntaxDiagnostic(err))
+		}
+		sortDiagnostics(diags)
+		return diags
+	}
+
+	d := diagnoser{doc: doc}
+	seen := map[string]int{}
+	for i := range cfg.APIs {
+		api := &cfg.APIs[i]
+		if err := api.validate(i); err != nil {
+			d.fromError(err)
+		}
+		if first, ok := seen[api.Name]; ok && api.Name != "" {
+			d.add(SeverityError, fmt.Sprintf("apis[%d].name", i),
+				fmt.Sprintf("duplicate name %q (first defined in apis[%d])", api.Name, first))
+		} else {
+			seen[api.Name] = i
+		}
+		d.checkAPI(i, api)
+	}
+
+	diags = append(diags, d.diags...)
+	sortDiagnostics(diags)
+	return diags
+}
+
+type diagnoser struct {
+	doc   *yaml.Node
+	diags []Diagnostic
+}
+
+func (d *diagnoser) add(severity, path, msg string) {
+	line, col, _ := locate(d.doc, path)
+	d.diags = append(d.diags, Diagnostic{
+		Severity: severity,
+		Path:     path,
+		Line:     line,
+		Column:   col,
+		Message:  msg,
+	})
+}
+
+var (
+	errPathRE   = regexp.MustCompile(`^(apis\[\d+\][\w.\[\]]*): (.*)$`)
+	fieldPathRE = regexp.MustCompile(`[a-z_]+(?:\.[a-z_]+|\[\d+\])+`)
+)
+
+// fromError converts a Validate error such as "apis[0]: auth.token is required"
+// into a diagnostic pointing at the most specific node that exists.
+func (d *diagnoser) fromError(err error) {
+	m := errPathRE.FindStringSubmatch(err.Error())
+	if m == nil {
+		d.add(SeverityError, "", err.Error())
+		return
+	}
+	path, msg := m[1], m[2]
+	// Narrow to the first dotted field the message mentions, trimming
+	// segments that are absent (e.g. a missing auth.token points at auth).
+	field := fieldPathRE.FindString(msg)
+	for field != "" {
+		if _, _, exact := locate(d.doc, path+"."+field); exact {
+			path += "." + field
+			break
+		}
+		i := strings.LastIndexAny(field, ".[")
+		if i < 0 {
+			break
+		}
+		field = field[:i]
+	}
+	d.add(SeverityError, path, msg)
+}
+
+func (d *diagnoser) checkAPI(i int, api *APIConfig) {
+	prefix := fmt.Sprintf("apis[%d]", i)
+
+	if api.SpecURL != "" {
+		d.checkURL(prefix+".spec_url", api.SpecURL, "http", "https", "file")
+	}
+	// gRPC targets are host:port, not URLs.
+	if api.BaseURLOverride != "" && api.SpecType != "grpc" {
+		d.checkURL(prefix+".base_url_override", api.BaseURLOverride, "http", "https", "ws", "wss")
+	}
+	for name, value := range api.Headers {
+		if strings.ContainsAny(name, " \t:\r\n") {
+			d.add(SeverityError, prefix+".headers."+name, fmt.Sprintf("invalid header name %q", name))
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			d.add(SeverityError, prefix+".headers."+name, "header value cannot contain line breaks")
+		}
+	}
+	if api.Auth != nil {
+		d.checkAuth(prefix+".auth", api.Auth)
+	}
+	if api.Filter != nil {
+		d.checkFilter(prefix+".filter", api.Filter)
+	}
+	if api.Email != nil && api.SpecType != "email" {
+		d.add(SeverityWarning, prefix+".email", "email is ignored unless spec_type is \"email\"")
+	}
+}
+
+// checkURL reports values that do not parse as absolute URLs with one of the
+// given schemes. Values containing unexpanded ${VAR} references are skipped.
+func (d *diagnoser) checkURL(path, raw string, schemes ...string) {
+	if strings.Contains(raw, "${") {
+		return
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		d.add(SeverityError, path, fmt.Sprintf("invalid URL: %v", err))
+		return
+	}
+	if u.Scheme == "" || (u.Host == "" && u.Scheme != "file") {
+		d.add(SeverityError, path, fmt.Sprintf("invalid URL %q: must be absolute (scheme://host)", raw))
+		return
+	}
+	for _, s := range schemes {
+		if strings.EqualFold(u.Scheme, s) {
+			return
+		}
+	}
+	d.add(SeverityError, path, fmt.Sprintf("unsupported URL scheme %q (expected %s)", u.Scheme, strings.Join(schemes, ", ")))
+}
+
+// authFields lists the auth fields each auth type reads.
+var authFields = map[string][]string{
+	"bearer":    {"token"},
+	"basic":     {"username", "password"},
+	"api-key":   {"header", "value"},
+	"oauth2":    {"client_id", "client_secret", "refresh_token", "token_url", "flow", "device_authorization_url", "scope"},
+	"wsse":      {"username", "password", "password_type"},
+	"aws-sigv4": {"access_key_id", "secret_access_key", "session_token", "region", "service"},
+}
+
+func (d *diagnoser) checkAuth(path string, a *AuthConfig) {
+	used, ok := authFields[a.Type]
+	if !ok {
+		return // unknown or missing type is reported by Validate
+	}
+	set := map[string]string{
+		"token":                    a.Token,
+		"username":                 a.Username,
+		"password":                 a.Password,
+		"header":                   a.Header,
+		"value":                    a.Value,
+		"client_id":                a.ClientID,
+		"client_secret":            a.ClientSecret,
+		"refresh_token":            a.RefreshToken,
+		"token_url":                a.TokenURL,
+		"password_type":            a.PasswordType,
+		"flow":                     a.Flow,
+		"scope":                    a.Scope,
+		"device_authorization_url": a.DeviceAuthURL,
+		"access_key_id":            a.AccessKeyID,
+		"secret_access_key":        a.SecretAccessKey,
+		"session_token":            a.SessionToken,
+		"region":                   a.Region,
+		"service":                  a.Service,
+	}
+	for _, field := range used {
+		delete(set, field)
+	}
+	fields := make([]string, 0, len(set))
+	for field, value := range set {
+		if value != "" {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		d.add(SeverityWarning, path+"."+field,
+			fmt.Sprintf("auth.%s conflicts with auth.type %q and will be ignored", field, a.Type))
+	}
+	if a.Type == "oauth2" && a.TokenURL != "" {
+		d.checkURL(path+".token_url", a.TokenURL, "http", "https")
+	}
+	if a.Type == "oauth2" && a.DeviceAuthURL != "" {
+		d.checkURL(path+".device_authorization_url", a.DeviceAuthURL, "http", "https")
+	}
+}
+
+func (d *diagnoser) checkFilter(path string, f *OperationFilterEnhanced) {
+	switch strings.ToLower(f.Mode) {
+	case "type-based":
+		if len(f.Operations) > 0 {
+			d.add(SeverityWarning, path+".operations", "operations are ignored when filter.mode is \"type-based\"")
+		}
+	case "allowlist", "blocklist":
+		if f.TypeBased != nil {
+			d.add(SeverityWarning, path+".type_based",
+				fmt.Sprintf("type_based is ignored when filter.mode is %q", f.Mode))
+		}
+	}
+}
+
+// checkUnknownFields walks a YAML node alongside the Go type it decodes into
+// and reports mapping keys that do not correspond to any field.
+func checkUnknownFields(node *yaml.Node, t reflect.Type, path string, diags *[]Diagnostic) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, val := node.Content[i], node.Content[i+1]
+			child := joinPath(path, key.Value)
+			ft, ok := fields[key.Value]
+			if !ok {
+				msg := fmt.Sprintf("unknown field %q", key.Value)
+				if hint := closestField(key.Value, fields); hint != "" {
+					msg += fmt.Sprintf(" (did you mean %q?)", hint)
+				}
+				*diags = append(*diags, Diagnostic{
+					Severity: SeverityError,
+					Path:     child,
+					Line:     key.Line,
+					Column:   key.Column,
+					Message:  msg,
+				})
+				continue
+			}
+			checkUnknownFields(val, ft, child, diags)
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			return
+		}
+		for i, item := range node.Content {
+			checkUnknownFields(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i), diags)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			checkUnknownFields(node.Content[i+1], t.Elem(), joinPath(path, node.Content[i].Value), diags)
+		}
+	}
+}
+
+// yamlFields maps yaml keys to field types for a struct type.
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// closestField suggests a known field for a misspelled key, if one is close.
+func closestField(key string, fields map[string]reflect.Type) string {
+	best, bestDist := "", 3
+	for name := range fields {
+		if dist := levenshtein(strings.ToLower(key), name); dist < bestDist || (dist == bestDist && name < best) {
+			best, bestDist = name, dist
+		}
+	}
+	return best
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+var pathSegmentRE = regexp.MustCompile(`([^.\[\]]+)|\[(\d+)\]`)
+
+// locate resolves a path such as "apis[1].auth.token" against the document
+// and returns the position of the deepest node found. exact reports whether
+// the whole path resolved.
+func locate(doc *yaml.Node, path string) (line, col int, exact bool) {
+	node := doc
+	line, col = doc.Line, doc.Column
+	for _, m := range pathSegmentRE.FindAllStringSubmatch(path, -1) {
+		var next *yaml.Node
+		switch {
+		case m[1] != "" && node.Kind == yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == m[1] {
+					// Point at the key so the reported column lines up with the field name.
+					line, col = node.Content[i].Line, node.Content[i].Column
+					next = node.Content[i+1]
+					break
+				}
+			}
+		case m[2] != "" && node.Kind == yaml.SequenceNode:
+			idx, _ := strconv.Atoi(m[2])
+			if idx < len(node.Content) {
+				next = node.Content[idx]
+				line, col = next.Line, next.Column
+			}
+		}
+		if next == nil {
+			return line, col, false
+		}
+		node = next
+	}
+	return line, col, true
+}
+
+var yamlLineRE = regexp.MustCompile(`line (\d+)(?:, column (\d+))?: `)
+
+// syntaxDiagnostic converts a yaml.v3 error into a diagnostic, extracting the
+// line (and column, when present) from its message.
+func syntaxDiagnostic(err error) Diagnostic {
+	msg := strings.TrimPrefix(err.Error(), "yaml: ")
+	d := Diagnostic{Severity: SeverityError, Message: msg}
+	if m := yamlLineRE.FindStringSubmatchIndex(msg); m != nil {
+		d.Line, _ = strconv.Atoi(msg[m[2]:m[3]])
+		if m[4] >= 0 {
+			d.Column, _ = strconv.Atoi(msg[m[4]:m[5]])
+		}
+		d.Message = msg[:m[0]] + msg[m[1]:]
+	}
+	return d
+}
+
+func sortDiagnostics(diags []Diagnostic) {
+	sort.SliceStable(diags, func(i, j int) bool {
+		if diags[i].Line != diags[j].Line {
+			return diags[i].Line < diags[j].Line
+		}
+		return diags[i].Column < diags[j].Column
+	})
+}
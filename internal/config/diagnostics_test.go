@@ -0,0 +1,214 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+func TestDiagnose(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		severity string
+		path     string
+		line     int
+		column   int
+		msg      string
+	}{
+		{
+			name: "unknown field with suggestion",
+			input: `apis:
+  - name: petstore
+    spec_url: https://petstore.example.com/openapi.json
+    base_ulr_override: https://petstore.example.com
+`,
+			severity: SeverityError,
+			path:     "apis[0].base_ulr_override",
+			line:     4,
+			column:   5,
+			msg:      `did you mean "base_url_override"?`,
+		},
+		{
+			name: "validate error points at field",
+			input: `apis:
+  - name: petstore
+    spec_url: https://petstore.example.com/openapi.json
+    auth:
+      type: bearer
+      username: alice
+`,
+			severity: SeverityError,
+			path:     "apis[0].auth",
+			line:     4,
+			column:   5,
+			msg:      "auth.token is required for bearer",
+		},
+		{
+			name: "conflicting auth settings",
+			input: `apis:
+  - name: petstore
+    spec_url: https://petstore.example.com/openapi.json
+    auth:
+      type: bearer
+      token: abc
+      password: secret
+`,
+			severity: SeverityWarning,
+			path:     "apis[0].auth.password",
+			line:     7,
+			column:   7,
+			msg:      `conflicts with auth.type "bearer"`,
+		},
+		{
+			name: "invalid url",
+			input: `apis:
+  - name: petstore
+    spec_url: petstore.example.com/openapi.json
+`,
+			severity: SeverityError,
+			path:     "apis[0].spec_url",
+			line:     3,
+			column:   5,
+			msg:      "must be absolute",
+		},
+		{
+			name: "unused filter settings",
+			input: `apis:
+  - name: graph
+    spec_url: https://graph.example.com/graphql
+    filter:
+      mode: type-based
+      type_based:
+        include_types: [User]
+      operations:
+        - operation_id: "get*"
+`,
+			severity: SeverityWarning,
+			path:     "apis[0].filter.operations",
+			line:     8,
+			column:   7,
+			msg:      "ignored",
+		},
+		{
+			name: "duplicate name",
+			input: `apis:
+  - name: petstore
+    spec_url: https://a.example.com/openapi.json
+  - name: petstore
+    spec_url: https://b.example.com/openapi.json
+`,
+			severity: SeverityError,
+			path:     "apis[1].name",
+			line:     4,
+			column:   5,
+			msg:      "duplicate name",
+		},
+		{
+			name:     "syntax error",
+			input:    "apis:\n  - name: [unterminated\n",
+			severity: SeverityError,
+			line:     1,
+			msg:      "did not find expected",
+		},
+		{
+			name:     "type error",
+			input:    "timeout_seconds: soon\napis: []\n",
+			severity: SeverityError,
+			line:     1,
+			msg:      "cannot unmarshal",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diags := Diagnose([]byte(tt.input))
+			for _, d := range diags {
+				if d.Severity == tt.severity && d.Path == tt.path && strings.Contains(d.Message, tt.msg) {
+					if d.Line != tt.line || (tt.column != 0 && d.Column != tt.column) {
+						t.Errorf("position = %d:%d, want %d:%d", d.Line, d.Column, tt.line, tt.column)
+					}
+					return
+				}
+			}
+			t.Errorf("no %s diagnostic at %q containing %q; got %v", tt.severity, tt.path, tt.msg, diags)
+		})
+	}
+}
+
+func TestDiagnose_ValidConfig(t *testing.T) {
+	input := `{
+  "timeout_seconds": 30,
+  "apis": [
+    {
+      "name": "petstore",
+      "spec_url": "https://petstore.example.com/openapi.json",
+      "base_url_override": "${PETSTORE_URL}",
+      "auth": {"type": "api-key", "header": "X-API-Key", "value": "${PETSTORE_KEY}"},
+      "headers": {"X-Request-ID": "{{uuid}}"}
+    }
+  ]
+}`
+	if diags := Diagnose([]byte(input)); len(diags) != 0 {
+		t.Fatalf("expected no diagnostics, got %v", diags)
+	}
+}
+
+func TestDiagnose_ReportsAllAPIs(t *testing.T) {
+	input := `apis:
+  - name: one
+  - name: two
+    spec_url: https://two.example.com
+    auth:
+      type: magic
+`
+	diags := Diagnose([]byte(input))
+	if len(diags) != 2 {
+		t.Fatalf("expected 2 diagnostics, got %v", diags)
+	}
+	if diags[0].Path != "apis[0]" || diags[1].Path != "apis[1].auth.type" {
+		t.Errorf("unexpected paths: %q, %q", diags[0].Path, diags[1].Path)
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	raw, err := json.Marshal(JSONSchema())
+	if err != nil {
+		t.Fatalf("marshal schema: %v", err)
+	}
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(SchemaID, bytes.NewReader(raw)); err != nil {
+		t.Fatalf("add schema: %v", err)
+	}
+	schema, err := compiler.Compile(SchemaID)
+	if err != nil {
+		t.Fatalf("compile schema: %v", err)
+	}
+
+	valid := map[string]any{
+		"apis": []any{map[string]any{
+			"name":     "petstore",
+			"spec_url": "https://petstore.example.com/openapi.json",
+			"auth":     map[string]any{"type": "bearer", "token": "abc"},
+			"headers":  map[string]any{"X-Trace": "{{uuid}}"},
+		}},
+	}
+	if err := schema.Validate(valid); err != nil {
+		t.Errorf("valid config rejected: %v", err)
+	}
+
+	invalid := []map[string]any{
+		{"apis": []any{map[string]any{"spec_url": "https://x"}}},
+		{"apis": []any{map[string]any{"name": "x", "auth": map[string]any{"type": "magic"}}}},
+		{"apis": []any{map[string]any{"name": "x", "bogus": true}}},
+		{"timeout_seconds": -1},
+	}
+	for i, doc := range invalid {
+		if err := schema.Validate(doc); err == nil {
+			t.Errorf("invalid[%d] accepted", i)
+		}
+	}
+}
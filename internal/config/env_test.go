@@ -18,6 +18,26 @@ func TestExpandEnvStrict(t *testing.T) {
 	}
 }
 
+func TestConfigExpandEnvSOAPHeaders(t *testing.T) {
+	t.Setenv("TEST_TENANT", "acme")
+	cfg := Config{APIs: []APIConfig{{
+		Name:        "plants",
+		SOAPHeaders: []string{`<t:Tenant xmlns:t="urn:acme">${TEST_TENANT}</t:Tenant>`, `<t:Trace>{{uuid}}</t:Trace>`},
+	}}}
+	if err := cfg.ExpandEnv(); err != nil {
+		t.Fatal(err)
+	}
+	got := cfg.APIs[0].SOAPHeaders
+	if got[0] != `<t:Tenant xmlns:t="urn:acme">acme</t:Tenant>` || got[1] != `<t:Trace>{{uuid}}</t:Trace>` {
+		t.Errorf("soap_headers = %q", got)
+	}
+
+	cfg.APIs[0].SOAPHeaders = []string{"${TEST_MISSING_TENANT}"}
+	if err := cfg.ExpandEnv(); err == nil || !contains(err.Error(), "apis[0].soap_headers[0]") {
+		t.Errorf("missing variable: %v", err)
+	}
+}
+
 func TestExpandEnvStrictMissing(t *testing.T) {
 	_, err := ExpandEnvStrict("${MISSING_VAR}")
 	if err == nil {
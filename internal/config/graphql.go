@@ -1,5 +1,12 @@
 package config
 
+import (
+	"fmt"
+
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+)
+
 // GraphQLOptimization holds configuration for GraphQL-specific optimizations
 type GraphQLOptimization struct {
 	EnableCRUDGrouping bool                    `json:"enable_crud_grouping,omitempty" yaml:"enable_crud_grouping,omitempty"`
@@ -27,3 +34,68 @@ type OperationFilterEnhanced struct {
 	Operations []OperationPattern `json:"operations,omitempty" yaml:"operations,omitempty"`
 	TypeBased  *TypeBasedFilter   `json:"type_based,omitempty" yaml:"type_based,omitempty"`
 }
+
+// GraphQLConfig adds hand-written operations to a GraphQL API.
+type GraphQLConfig struct {
+	// Schema is a local path or URL of the API's SDL (or a saved
+	// introspection result), for servers with introspection disabled. The
+	// schema is read from here while calls go to spec_url or
+	// base_url_override.
+	Schema string `json:"schema,omitempty" yaml:"schema,omitempty"`
+	// PersistedQueries each become a tool whose arguments are the
+	// operation's variables, typed from the schema.
+	PersistedQueries []PersistedQuery `json:"persisted_queries,omitempty" yaml:"persisted_queries,omitempty"`
+	// Fragments are fragment definitions, one per entry, that persisted
+	// queries can spread; each query is sent with the ones it uses.
+	Fragments []string `json:"fragments,omitempty" yaml:"fragments,omitempty"`
+	// APQ sends Automatic Persisted Queries: the query's SHA-256 hash
+	// first, and the full query only when the server asks for it.
+	APQ bool `json:"apq,omitempty" yaml:"apq,omitempty"`
+}
+
+// PersistedQuery is a GraphQL operation exposed as its own tool.
+type PersistedQuery struct {
+	// Name is the operation ID (the tool is named <api>__<name>).
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	// Query is a document holding exactly one query or mutation.
+	Query string `json:"query" yaml:"query"`
+}
+
+// validate checks the persisted queries and fragments parse; whether they
+// match the API's schema is checked when the spec is loaded.
+func (g *GraphQLConfig) validate(path string) error {
+	if g == nil {
+		return nil
+	}
+	seen := map[string]bool{}
+	for j, pq := range g.PersistedQueries {
+		if !toolNameRe.MatchString(pq.Name) {
+			return fmt.Errorf("%s.persisted_queries[%d].name: must be 1-128 letters, digits, _ or -", path, j)
+		}
+		if seen[pq.Name] {
+			return fmt.Errorf("%s.persisted_queries[%d]: duplicate name %q", path, j, pq.Name)
+		}
+		seen[pq.Name] = true
+		doc, err := parser.ParseQuery(&ast.Source{Input: pq.Query})
+		if err != nil {
+			return fmt.Errorf("%s.persisted_queries[%d].query: %w", path, j, err)
+		}
+		if len(doc.Operations) != 1 || len(doc.Fragments) != 0 {
+			return fmt.Errorf("%s.persisted_queries[%d].query: must hold exactly one operation and no fragments (declare those under fragments)", path, j)
+		}
+		if doc.Operations[0].Operation == ast.Subscription {
+			return fmt.Errorf("%s.persisted_queries[%d].query: subscriptions are not supported", path, j)
+		}
+	}
+	for j, fragment := range g.Fragments {
+		doc, err := parser.ParseQuery(&ast.Source{Input: fragment})
+		if err != nil {
+			return fmt.Errorf("%s.fragments[%d]: %w", path, j, err)
+		}
+		if len(doc.Fragments) != 1 || len(doc.Operations) != 0 {
+			return fmt.Errorf("%s.fragments[%d]: must hold exactly one fragment definition", path, j)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package config
+
+import "fmt"
+
+// Hook limits.
+const (
+	DefaultHookTimeoutMS = 100
+	MaxHookTimeoutMS     = 5000
+	MaxHookScriptBytes   = 64 << 10
+)
+
+// HooksConfig runs JavaScript around an API's calls to work around its
+// quirks without new Go code. Scripts run in an embedded sandbox with no
+// network, file or process access.
+type HooksConfig struct {
+	// Before runs before each request. It may change request.args and
+	// request.headers, or refuse the call with veto(reason).
+	Before string `json:"before,omitempty" yaml:"before,omitempty"`
+	// After runs on each response. It may change response.body and
+	// response.status.
+	After string `json:"after,omitempty" yaml:"after,omitempty"`
+	// Operations limits the hooks to these operation IDs or tool names;
+	// empty runs them for every operation of the API.
+	Operations []string `json:"operations,omitempty" yaml:"operations,omitempty"`
+	// TimeoutMS bounds each run of a hook; default 100, at most 5000.
+	TimeoutMS int `json:"timeout_ms,omitempty" yaml:"timeout_ms,omitempty"`
+}
+
+func (h *HooksConfig) validate() error {
+	if h == nil {
+		return nil
+	}
+	if h.Before == "" && h.After == "" {
+		return fmt.Errorf("hooks: before or after is required")
+	}
+	if len(h.Before) > MaxHookScriptBytes {
+		return fmt.Errorf("hooks.before: script exceeds %d bytes", MaxHookScriptBytes)
+	}
+	if len(h.After) > MaxHookScriptBytes {
+		return fmt.Errorf("hooks.after: script exceeds %d bytes", MaxHookScriptBytes)
+	}
+	if h.TimeoutMS < 0 || h.TimeoutMS > MaxHookTimeoutMS {
+		return fmt.Errorf("hooks.timeout_ms: must be between 0 and %d", MaxHookTimeoutMS)
+	}
+	return nil
+}
@@ -0,0 +1,250 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SeverityInfo marks lint findings that are worth knowing but not risky.
+const SeverityInfo = "info"
+
+// Lint rules, reported in Diagnostic.Rule.
+const (
+	LintWriteWithoutRateLimit = "write-without-rate-limit"
+	LintNoAuthExternalHost    = "no-auth-external-host"
+	LintBroadFilter           = "broad-filter"
+	LintNoTimeout             = "no-timeout"
+	LintSecretLiteral         = "secret-literal"
+)
+
+// Lint checks raw profile config bytes (YAML or JSON) for risky but valid
+// settings: APIs that can write without a rate limit, unauthenticated APIs
+// on external hosts, filters that match every operation, APIs without a
+// timeout and secrets written inline instead of as ${VAR} references. Run
+// Diagnose for errors; a config that does not parse yields its syntax
+// error only.
+func Lint(data []byte) []Diagnostic {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return []Diagnostic{syntaxDiagnostic(err)}
+	}
+	if len(root.Content) == 0 {
+		return nil
+	}
+	var cfg Config
+	if err := root.Content[0].Decode(&cfg); err != nil {
+		return []Diagnostic{syntaxDiagnostic(err)}
+	}
+	l := linter{diagnoser{doc: root.Content[0]}}
+	for i := range cfg.APIs {
+		l.lintAPI(fmt.Sprintf("apis[%d]", i), &cfg.APIs[i], cfg.TimeoutSeconds > 0)
+	}
+	sortDiagnostics(l.diags)
+	return l.diags
+}
+
+type linter struct {
+	diagnoser
+}
+
+func (l *linter) report(severity, rule, path, msg string) {
+	l.add(severity, path, msg)
+	l.diags[len(l.diags)-1].Rule = rule
+}
+
+func (l *linter) lintAPI(prefix string, api *APIConfig, globalTimeout bool) {
+	if api.Disabled {
+		return
+	}
+	if api.RateLimitRPM == nil && api.RateLimitRPH == nil && api.RateLimitRPD == nil && api.Quota == nil && canWrite(api) {
+		l.report(SeverityWarning, LintWriteWithoutRateLimit, prefix,
+			"API can run write operations but has no rate_limit_rpm, rate_limit_rph, rate_limit_rpd or quota; an agent in a loop can call it without bound")
+	}
+	if host := externalHost(api); host != "" && !hasCredentials(api) {
+		l.report(SeverityWarning, LintNoAuthExternalHost, prefix,
+			fmt.Sprintf("API calls external host %s without auth; set auth, or signing or an auth header if the upstream needs them", host))
+	}
+	if f := api.Filter; f != nil {
+		mode := strings.ToLower(f.Mode)
+		for j, op := range f.Operations {
+			if !matchesEverything(op) {
+				continue
+			}
+			opPath := fmt.Sprintf("%s.filter.operations[%d]", prefix, j)
+			switch mode {
+			case "allowlist":
+				l.report(SeverityWarning, LintBroadFilter, opPath, "pattern matches every operation, so the allowlist keeps all of them; list the operations agents need")
+			case "blocklist":
+				l.report(SeverityWarning, LintBroadFilter, opPath, "pattern matches every operation, so the blocklist removes all of them")
+			}
+		}
+	}
+	if api.TimeoutSeconds == nil && !globalTimeout {
+		l.report(SeverityInfo, LintNoTimeout, prefix, "no timeout_seconds here or at the top level; the 10 second default applies")
+	}
+	l.lintSecrets(prefix, api)
+}
+
+// lintSecrets reports credentials written into the config rather than
+// referenced from the environment.
+func (l *linter) lintSecrets(prefix string, api *APIConfig) {
+	literal := func(path, value string) {
+		if value != "" && !strings.Contains(value, "${") {
+			l.report(SeverityWarning, LintSecretLiteral, path,
+				"secret is written in the config; reference an environment variable instead, e.g. ${API_TOKEN}")
+		}
+	}
+	if a := api.Auth; a != nil {
+		literal(prefix+".auth.token", a.Token)
+		literal(prefix+".auth.password", a.Password)
+		literal(prefix+".auth.value", a.Value)
+		literal(prefix+".auth.client_secret", a.ClientSecret)
+		literal(prefix+".auth.refresh_token", a.RefreshToken)
+	}
+	if api.Signing != nil {
+		literal(prefix+".signing.secret", api.Signing.Secret)
+	}
+	for name, value := range api.Headers {
+		if isCredentialHeader(name) {
+			literal(prefix+".headers."+name, value)
+		}
+	}
+	if db := api.Database; db != nil {
+		if u, err := url.Parse(db.DSN); err == nil && u.User != nil {
+			if password, ok := u.User.Password(); ok {
+				literal(prefix+".database.dsn", password)
+			}
+		}
+	}
+	if e := api.Email; e != nil {
+		literal(prefix+".email.password", e.Password)
+	}
+	if m := api.Messaging; m != nil {
+		for i, server := range m.Servers {
+			if u, err := url.Parse(server); err == nil && u.User != nil {
+				if password, ok := u.User.Password(); ok {
+					literal(fmt.Sprintf("%s.messaging.servers[%d]", prefix, i), password)
+				}
+			}
+		}
+	}
+	if s := api.SSH; s != nil {
+		literal(prefix+".ssh.private_key", s.PrivateKey)
+		literal(prefix+".ssh.passphrase", s.Passphrase)
+	}
+}
+
+// readMethods are the methods an allowlist may keep without the API being
+// able to write.
+var readMethods = map[string]bool{"GET": true, "HEAD": true, "OPTIONS": true}
+
+// canWrite reports whether the API may expose operations that change data.
+// Only an allowlist of read methods, or a GraphQL type filter that drops
+// mutations, rules that out.
+func canWrite(api *APIConfig) bool {
+	if api.Mock {
+		return false
+	}
+	f := api.Filter
+	if f == nil {
+		return true
+	}
+	switch strings.ToLower(f.Mode) {
+	case "allowlist":
+		for _, op := range f.Operations {
+			if !readMethods[strings.ToUpper(op.Method)] {
+				return true
+			}
+		}
+		return len(api.CustomOperations) > 0
+	case "type-based":
+		if tb := f.TypeBased; tb != nil {
+			for _, t := range tb.ExcludeTypes {
+				if strings.EqualFold(t, "mutation") {
+					return false
+				}
+			}
+			if len(tb.IncludeTypes) > 0 {
+				for _, t := range tb.IncludeTypes {
+					if strings.EqualFold(t, "mutation") {
+						return true
+					}
+				}
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// externalHost returns the host an HTTP API is called on when it is not a
+// loopback, private or cluster-internal address.
+func externalHost(api *APIConfig) string {
+	switch api.SpecType {
+	case "grpc", "sql", "email", "kubernetes", "messaging", "ssh":
+		// Credentials live in their own settings or the connection string.
+		return ""
+	}
+	raw := api.BaseURLOverride
+	if raw == "" {
+		raw = api.SpecURL
+	}
+	if raw == "" || strings.Contains(raw, "${") {
+		return ""
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	host := strings.ToLower(u.Hostname())
+	if ip := net.ParseIP(host); ip != nil {
+		if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() {
+			return ""
+		}
+		return host
+	}
+	if !strings.Contains(host, ".") {
+		return "" // single-label names resolve on the local network
+	}
+	for _, suffix := range []string{".localhost", ".local", ".internal", ".lan", ".svc", ".cluster.local"} {
+		if strings.HasSuffix(host, suffix) {
+			return ""
+		}
+	}
+	return host
+}
+
+func hasCredentials(api *APIConfig) bool {
+	if api.Auth != nil || api.Signing != nil || api.Jenkins != nil {
+		return true
+	}
+	for name := range api.Headers {
+		if isCredentialHeader(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// isCredentialHeader reports whether a header name looks like it carries
+// credentials, such as Authorization or X-Api-Key.
+func isCredentialHeader(name string) bool {
+	name = strings.ToLower(name)
+	for _, part := range []string{"authorization", "token", "key", "secret", "cookie"} {
+		if strings.Contains(name, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesEverything reports whether a filter pattern selects every
+// operation.
+func matchesEverything(op OperationPattern) bool {
+	wild := func(s string) bool { return s == "" || s == "*" || s == "**" }
+	return wild(op.OperationID) && wild(op.Method) && (wild(op.Path) || op.Path == "/**")
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string // "rule path" per finding
+	}{
+		{
+			name: "risky API",
+			input: `apis:
+  - name: petstore
+    spec_url: https://petstore.example.com/openapi.json
+    headers:
+      X-Api-Key: abc123
+    filter:
+      mode: blocklist
+      operations:
+        - method: "*"
+          path: /**
+`,
+			want: []string{
+				"write-without-rate-limit apis[0]",
+				"no-timeout apis[0]",
+				"secret-literal apis[0].headers.X-Api-Key",
+				"broad-filter apis[0].filter.operations[0]",
+			},
+		},
+		{
+			name: "unauthenticated external host",
+			input: `timeout_seconds: 20
+apis:
+  - name: weather
+    spec_url: https://api.weather.example.com/openapi.json
+    rate_limit_rpm: 60
+`,
+			want: []string{"no-auth-external-host apis[0]"},
+		},
+		{
+			name: "inline bearer token",
+			input: `apis:
+  - name: crm
+    spec_url: https://crm.example.com/openapi.json
+    timeout_seconds: 15
+    rate_limit_rph: 1000
+    auth:
+      type: bearer
+      token: sk-live-123
+`,
+			want: []string{"secret-literal apis[0].auth.token"},
+		},
+		{
+			name: "read-only allowlist on an internal host",
+			input: `apis:
+  - name: billing
+    spec_url: http://billing.svc/openapi.json
+    timeout_seconds: 15
+    auth:
+      type: bearer
+      token: ${BILLING_TOKEN}
+    filter:
+      mode: allowlist
+      operations:
+        - method: GET
+          path: /invoices/**
+`,
+		},
+		{
+			name: "allowlist keeping everything",
+			input: `apis:
+  - name: local
+    spec_url: http://localhost:8080/openapi.json
+    timeout_seconds: 15
+    rate_limit_rpm: 60
+    filter:
+      mode: allowlist
+      operations:
+        - operation_id: "*"
+`,
+			want: []string{"broad-filter apis[0].filter.operations[0]"},
+		},
+		{
+			name: "disabled API",
+			input: `apis:
+  - name: old
+    spec_url: https://old.example.com/openapi.json
+    disabled: true
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, d := range Lint([]byte(tt.input)) {
+				if d.Line == 0 {
+					t.Errorf("%s has no position", d)
+				}
+				got = append(got, d.Rule+" "+d.Path)
+			}
+			slices.Sort(got)
+			want := slices.Clone(tt.want)
+			slices.Sort(want)
+			if !slices.Equal(got, want) {
+				t.Errorf("findings:\n  %s\nwant:\n  %s", strings.Join(got, "\n  "), strings.Join(want, "\n  "))
+			}
+		})
+	}
+}
+
+func TestLintSeverities(t *testing.T) {
+	diags := Lint([]byte(`apis:
+  - name: petstore
+    spec_url: https://petstore.example.com/openapi.json
+`))
+	severities := map[string]string{}
+	for _, d := range diags {
+		severities[d.Rule] = d.Severity
+	}
+	if severities[LintNoTimeout] != SeverityInfo || severities[LintNoAuthExternalHost] != SeverityWarning || severities[LintWriteWithoutRateLimit] != SeverityWarning {
+		t.Fatalf("severities = %v", severities)
+	}
+	if HasErrors(diags) {
+		t.Fatal("lint findings must not be errors")
+	}
+	if s := diags[0].String(); !strings.HasPrefix(s, "2:5: ") || !strings.HasSuffix(s, ")") {
+		t.Errorf("String() = %q, want position and rule", s)
+	}
+}
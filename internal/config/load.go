@@ -1,22 +1,183 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 )
 
+// Load reads a config file, or every config file in a directory, along
+// with the files it includes. See LoadFiles.
 func Load(path string) (*Config, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("read config: %w", err)
+	return LoadFiles(path)
+}
+
+// fileConfig is a config file on disk. Besides the config it may include
+// other files, which profiles stored by the server cannot.
+type fileConfig struct {
+	// Include lists files, directories or glob patterns, relative to the
+	// including file, whose APIs and workflows are merged in.
+	Include []string `json:"include,omitempty" yaml:"include,omitempty"`
+	Config  `yaml:",inline"`
+}
+
+// LoadFiles reads config files and directories, follows their include
+// directives and merges everything into one config, then expands env vars,
+// applies defaults and validates it. A directory contributes its *.yaml,
+// *.yml and *.json files in name order; a file reached twice is read once.
+// APIs and workflows must have unique names across files, and other
+// settings may be set in several files only with the same value.
+func LoadFiles(paths ...string) (*Config, error) {
+	m := &merger{
+		seen:    map[string]bool{},
+		sources: map[string]string{},
 	}
-	cfg, err := LoadFromBytes(data)
-	if err != nil {
+	for _, path := range paths {
+		if err := m.loadPath(path); err != nil {
+			return nil, err
+		}
+	}
+	if len(m.seen) == 0 {
+		return nil, fmt.Errorf("read config: no config files in %s", strings.Join(paths, ", "))
+	}
+	cfg := &m.cfg
+	if err := cfg.ExpandEnv(); err != nil {
+		return nil, err
+	}
+	cfg.ApplyDefaults()
+	if err := cfg.Validate(); err != nil {
 		return nil, err
 	}
 	return cfg, nil
 }
 
+type merger struct {
+	cfg     Config
+	seen    map[string]bool   // absolute paths already read
+	sources map[string]string // "api:<name>", "workflow:<name>" or a setting → file that set it
+}
+
+func (m *merger) loadPath(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("read config: %w", err)
+	}
+	if !info.IsDir() {
+		return m.loadFile(path)
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return fmt.Errorf("read config: %w", err)
+	}
+	for _, entry := range entries {
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".yaml", ".yml", ".json":
+			if !entry.IsDir() {
+				if err := m.loadFile(filepath.Join(path, entry.Name())); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (m *merger) loadFile(path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("read config: %w", err)
+	}
+	if m.seen[abs] {
+		return nil
+	}
+	m.seen[abs] = true
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config: %w", err)
+	}
+	var fc fileConfig
+	if err := decodeConfig(data, &fc); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if err := m.merge(fc.Config, path); err != nil {
+		return err
+	}
+	for _, include := range fc.Include {
+		if include == "" {
+			continue
+		}
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(filepath.Dir(path), include)
+		}
+		matches, err := filepath.Glob(include)
+		if err != nil {
+			return fmt.Errorf("%s: include %q: %w", path, include, err)
+		}
+		if len(matches) == 0 {
+			return fmt.Errorf("%s: include %q matches no files", path, include)
+		}
+		sort.Strings(matches)
+		for _, match := range matches {
+			if err := m.loadPath(match); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// merge adds one file's config to the merged one.
+func (m *merger) merge(src Config, from string) error {
+	for _, api := range src.APIs {
+		if err := m.claim("api:"+api.Name, from); err != nil {
+			return fmt.Errorf("api %q %w", api.Name, err)
+		}
+		m.cfg.APIs = append(m.cfg.APIs, api)
+	}
+	for _, wf := range src.Workflows {
+		if err := m.claim("workflow:"+wf.Name, from); err != nil {
+			return fmt.Errorf("workflow %q %w", wf.Name, err)
+		}
+		m.cfg.Workflows = append(m.cfg.Workflows, wf)
+	}
+
+	dst := reflect.ValueOf(&m.cfg).Elem()
+	sv := reflect.ValueOf(src)
+	for i := 0; i < sv.NumField(); i++ {
+		field := sv.Type().Field(i)
+		if field.Name == "APIs" || field.Name == "Workflows" || sv.Field(i).IsZero() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if dst.Field(i).IsZero() {
+			dst.Field(i).Set(sv.Field(i))
+			m.sources[name] = from
+			continue
+		}
+		if !reflect.DeepEqual(dst.Field(i).Interface(), sv.Field(i).Interface()) {
+			return fmt.Errorf("%s is set differently in %s and %s", name, m.sources[name], from)
+		}
+	}
+	return nil
+}
+
+// claim records that from defines key, failing if another file did.
+func (m *merger) claim(key, from string) error {
+	if prev, ok := m.sources[key]; ok {
+		if prev == from {
+			return errors.New("is defined twice in " + from)
+		}
+		return fmt.Errorf("is defined in both %s and %s", prev, from)
+	}
+	m.sources[key] = from
+	return nil
+}
+
 func (c *Config) ExpandEnv() error {
 	for i := range c.APIs {
 		var err error
@@ -32,6 +193,24 @@ func (c *Config) ExpandEnv() error {
 		if err != nil {
 			return fmt.Errorf("apis[%d].base_url_override: %w", i, err)
 		}
+		for name, value := range c.APIs[i].Headers {
+			c.APIs[i].Headers[name], err = ExpandEnvStrict(value)
+			if err != nil {
+				return fmt.Errorf("apis[%d].headers.%s: %w", i, name, err)
+			}
+		}
+		for j, block := range c.APIs[i].SOAPHeaders {
+			c.APIs[i].SOAPHeaders[j], err = ExpandEnvStrict(block)
+			if err != nil {
+				return fmt.Errorf("apis[%d].soap_headers[%d]: %w", i, j, err)
+			}
+		}
+		if c.APIs[i].Signing != nil && c.APIs[i].Signing.Secret != "" {
+			c.APIs[i].Signing.Secret, err = ExpandEnvStrict(c.APIs[i].Signing.Secret)
+			if err != nil {
+				return fmt.Errorf("apis[%d].signing.secret: %w", i, err)
+			}
+		}
 		if c.APIs[i].Auth != nil {
 			if c.APIs[i].Auth.Token != "" {
 				c.APIs[i].Auth.Token, err = ExpandEnvStrict(c.APIs[i].Auth.Token)
@@ -87,6 +266,12 @@ func (c *Config) ExpandEnv() error {
 					return fmt.Errorf("apis[%d].auth.token_url: %w", i, err)
 				}
 			}
+			if c.APIs[i].Auth.DeviceAuthURL != "" {
+				c.APIs[i].Auth.DeviceAuthURL, err = ExpandEnvStrict(c.APIs[i].Auth.DeviceAuthURL)
+				if err != nil {
+					return fmt.Errorf("apis[%d].auth.device_authorization_url: %w", i, err)
+				}
+			}
 		}
 	}
 	return nil
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfigFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func apiNames(cfg *Config) string {
+	names := make([]string, len(cfg.APIs))
+	for i, api := range cfg.APIs {
+		names[i] = api.Name
+	}
+	return strings.Join(names, ",")
+}
+
+func TestLoadFilesMergesIncludes(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"config.yaml": `
+include: [teams/*.yaml, shared.json]
+timeout_seconds: 20
+apis:
+  - name: root
+    spec_url: https://root.example.com/openapi.json
+`,
+		"teams/a.yaml": `
+timeout_seconds: 20
+apis:
+  - name: team-a
+    spec_url: https://a.example.com/openapi.json
+`,
+		"teams/b.yaml": `
+include: [../config.yaml]
+apis:
+  - name: team-b
+    spec_url: https://b.example.com/openapi.json
+`,
+		"shared.json": `{"retries": 2, "apis": [{"name": "shared", "spec_url": "https://shared.example.com/openapi.json"}]}`,
+	})
+
+	cfg, err := Load(filepath.Join(dir, "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := apiNames(cfg); got != "root,team-a,team-b,shared" {
+		t.Fatalf("apis = %s", got)
+	}
+	if cfg.TimeoutSeconds != 20 || cfg.Retries != 2 {
+		t.Fatalf("settings not merged: timeout %d, retries %d", cfg.TimeoutSeconds, cfg.Retries)
+	}
+}
+
+func TestLoadFilesDirectoriesAndLists(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"conf.d/10-github.yaml": "apis:\n  - name: github\n    spec_url: https://github.example.com/openapi.json\n",
+		"conf.d/20-jira.yml":    "apis:\n  - name: jira\n    spec_url: https://jira.example.com/openapi.json\n",
+		"conf.d/README.md":      "not a config",
+		"extra.yaml":            "apis:\n  - name: extra\n    spec_url: https://extra.example.com/openapi.json\n",
+	})
+
+	cfg, err := LoadFiles(filepath.Join(dir, "conf.d"), filepath.Join(dir, "extra.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := apiNames(cfg); got != "github,jira,extra" {
+		t.Fatalf("apis = %s", got)
+	}
+
+	if _, err := LoadFiles(t.TempDir()); err == nil || !strings.Contains(err.Error(), "no config files") {
+		t.Fatalf("empty directory: err = %v", err)
+	}
+}
+
+func TestLoadFilesConflicts(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			name: "duplicate api",
+			files: map[string]string{
+				"config.yaml": "include: [b.yaml]\napis:\n  - name: crm\n    spec_url: https://a.example.com/openapi.json\n",
+				"b.yaml":      "apis:\n  - name: crm\n    spec_url: https://b.example.com/openapi.json\n",
+			},
+			want: `api "crm" is defined in both`,
+		},
+		{
+			name: "conflicting setting",
+			files: map[string]string{
+				"config.yaml": "include: [b.yaml]\ntimeout_seconds: 10\n",
+				"b.yaml":      "timeout_seconds: 30\n",
+			},
+			want: "timeout_seconds is set differently",
+		},
+		{
+			name: "duplicate workflow",
+			files: map[string]string{
+				"config.yaml": "include: [b.yaml]\nworkflows:\n  - name: sync\n    steps: [{id: a, tool: x}]\n",
+				"b.yaml":      "workflows:\n  - name: sync\n    steps: [{id: a, tool: x}]\n",
+			},
+			want: `workflow "sync" is defined in both`,
+		},
+		{
+			name:  "missing include",
+			files: map[string]string{"config.yaml": "include: [teams/*.yaml]\n"},
+			want:  "matches no files",
+		},
+	}
+	for _, tt := range tests {
+		dir := writeConfigFiles(t, tt.files)
+		_, err := Load(filepath.Join(dir, "config.yaml"))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LocalPath is a setting that makes the server read or write a file or
+// directory of its own.
+type LocalPath struct {
+	Field string // e.g. apis[0].ssh.private_key_file
+	Path  string
+}
+
+// LocalPaths lists the settings of c that name files or directories on the
+// host running Skyline: local specs and GraphQL schemas, .http files, SQLite databases, SSH keys
+// and known_hosts files, recording directories and schema files. Profiles
+// stored on a shared server must keep them in a directory set aside for
+// them.
+func (c *Config) LocalPaths() []LocalPath {
+	var paths []LocalPath
+	add := func(field, path string) {
+		if path != "" {
+			paths = append(paths, LocalPath{Field: field, Path: path})
+		}
+	}
+	for i, api := range c.APIs {
+		prefix := fmt.Sprintf("apis[%d].", i)
+		add(prefix+"spec_file", api.SpecFile)
+		add(prefix+"spec_url", filePath(api.SpecURL))
+		if gql := api.GraphQL; gql != nil {
+			lower := strings.ToLower(gql.Schema)
+			switch {
+			case strings.HasPrefix(lower, "file://"):
+				add(prefix+"graphql.schema", filePath(gql.Schema))
+			case !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://"):
+				add(prefix+"graphql.schema", gql.Schema)
+			}
+		}
+		for j, op := range api.CustomOperations {
+			add(fmt.Sprintf("%scustom_operations[%d].http_file", prefix, j), op.HTTPFile)
+		}
+		if db := api.Database; db != nil && api.SpecType == "sql" {
+			switch strings.ToLower(strings.TrimSpace(db.Driver)) {
+			case "sqlite", "sqlite3":
+				add(prefix+"database.dsn", SQLitePath(db.DSN))
+			}
+		}
+		if ssh := api.SSH; ssh != nil {
+			add(prefix+"ssh.private_key_file", ssh.PrivateKeyFile)
+			add(prefix+"ssh.known_hosts_file", ssh.KnownHostsFile)
+		}
+	}
+	if rc := c.Recording; rc != nil {
+		add("recording.dir", rc.Dir)
+	}
+	if sl := c.SchemaLearning; sl != nil {
+		add("schema_learning.file", sl.File)
+	}
+	return paths
+}
+
+// filePath returns the path of a file:// URL, or "" for other URLs.
+func filePath(rawURL string) string {
+	if len(rawURL) < len("file://") || !strings.EqualFold(rawURL[:len("file://")], "file://") {
+		return ""
+	}
+	return rawURL[len("file://"):]
+}
+
+// SQLitePath returns the database file a SQLite DSN opens, or "" for an
+// in-memory database. DSNs may be a plain path or a file: URI, either
+// with query parameters.
+func SQLitePath(dsn string) string {
+	path := strings.TrimSpace(dsn)
+	if rest, ok := strings.CutPrefix(path, "file:"); ok {
+		path = strings.TrimPrefix(rest, "//")
+	}
+	path, query, _ := strings.Cut(path, "?")
+	if path == "" || path == ":memory:" || strings.Contains(query, "mode=memory") {
+		return ""
+	}
+	return path
+}
@@ -0,0 +1,150 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Limits of consume calls on spec_type: messaging APIs that set none.
+const (
+	DefaultMessagingMaxMessages    = 100
+	DefaultMessagingMaxWaitSeconds = 30
+)
+
+// MessagingConfig connects a spec_type: messaging API to a Kafka cluster,
+// NATS servers or a RabbitMQ broker. Each topic gets a produce tool and a
+// consume tool that reads a bounded batch. Credentials come from auth:
+// basic for Kafka SASL, NATS and RabbitMQ users, bearer for NATS tokens.
+type MessagingConfig struct {
+	// Broker is kafka, nats or rabbitmq.
+	Broker string `json:"broker" yaml:"broker"`
+	// Servers are the Kafka bootstrap brokers (host:port), the NATS server
+	// URLs (nats://host:4222) or the single RabbitMQ URL
+	// (amqp://host:5672/vhost).
+	Servers []string `json:"servers" yaml:"servers"`
+	// TLS connects to Kafka brokers over TLS. NATS and RabbitMQ take it
+	// from the URL scheme: tls:// and amqps://.
+	TLS bool `json:"tls,omitempty" yaml:"tls,omitempty"`
+	// SASLMechanism is PLAIN (default), SCRAM-SHA-256 or SCRAM-SHA-512,
+	// used with basic auth. Kafka only.
+	SASLMechanism string `json:"sasl_mechanism,omitempty" yaml:"sasl_mechanism,omitempty"`
+	// Topics are the Kafka topics, NATS subjects or RabbitMQ queues
+	// exposed as tools.
+	Topics []MessagingTopic `json:"topics" yaml:"topics"`
+	// ReadOnly drops every produce tool.
+	ReadOnly bool `json:"read_only,omitempty" yaml:"read_only,omitempty"`
+	// MaxMessages caps the messages a consume call returns. Default: 100.
+	MaxMessages int `json:"max_messages,omitempty" yaml:"max_messages,omitempty"`
+	// MaxWaitSeconds caps how long a consume call waits for messages.
+	// Default: 30.
+	MaxWaitSeconds int `json:"max_wait_seconds,omitempty" yaml:"max_wait_seconds,omitempty"`
+}
+
+// MessagingTopic is one destination of a messaging API.
+type MessagingTopic struct {
+	// Name is the Kafka topic, NATS subject or RabbitMQ queue. NATS
+	// subjects may contain wildcards (orders.*, events.>) when read_only.
+	Name string `json:"name" yaml:"name"`
+	// Description is added to the tool descriptions, e.g. what the
+	// messages contain.
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	// ReadOnly drops the produce tool of this topic.
+	ReadOnly bool `json:"read_only,omitempty" yaml:"read_only,omitempty"`
+	// Exchange is the RabbitMQ exchange produce publishes to. Default: the
+	// default exchange, which routes to the queue by name.
+	Exchange string `json:"exchange,omitempty" yaml:"exchange,omitempty"`
+	// RoutingKey is the RabbitMQ routing key used with exchange. Default:
+	// the queue name; calls may override it.
+	RoutingKey string `json:"routing_key,omitempty" yaml:"routing_key,omitempty"`
+}
+
+// Writable reports whether the topic gets a produce tool.
+func (m *MessagingConfig) Writable(t MessagingTopic) bool {
+	return !m.ReadOnly && !t.ReadOnly
+}
+
+var kafkaTopicRE = regexp.MustCompile(`^[a-zA-Z0-9._-]{1,249}$`)
+
+func (m *MessagingConfig) validate(auth *AuthConfig) error {
+	if m == nil {
+		return nil
+	}
+	broker := strings.ToLower(m.Broker)
+	switch broker {
+	case "kafka", "nats", "rabbitmq":
+	default:
+		return fmt.Errorf("messaging.broker: must be kafka, nats or rabbitmq")
+	}
+	if len(m.Servers) == 0 {
+		return fmt.Errorf("messaging.servers: at least one server is required")
+	}
+	if broker == "rabbitmq" && len(m.Servers) > 1 {
+		return fmt.Errorf("messaging.servers: rabbitmq takes a single amqp:// URL")
+	}
+	for i, s := range m.Servers {
+		if strings.TrimSpace(s) == "" {
+			return fmt.Errorf("messaging.servers[%d]: must not be empty", i)
+		}
+		if broker == "kafka" && strings.Contains(s, "://") {
+			return fmt.Errorf("messaging.servers[%d]: kafka brokers are host:port, got %q", i, s)
+		}
+	}
+	if m.TLS && broker != "kafka" {
+		return fmt.Errorf("messaging.tls: only for kafka; use a tls:// or amqps:// URL")
+	}
+	switch strings.ToUpper(m.SASLMechanism) {
+	case "":
+	case "PLAIN", "SCRAM-SHA-256", "SCRAM-SHA-512":
+		if broker != "kafka" {
+			return fmt.Errorf("messaging.sasl_mechanism: only for kafka")
+		}
+	default:
+		return fmt.Errorf("messaging.sasl_mechanism: must be PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512")
+	}
+	if m.MaxMessages < 0 {
+		return fmt.Errorf("messaging.max_messages: must not be negative")
+	}
+	if m.MaxWaitSeconds < 0 {
+		return fmt.Errorf("messaging.max_wait_seconds: must not be negative")
+	}
+	if auth != nil && auth.Type != "basic" && (auth.Type != "bearer" || broker != "nats") {
+		if broker == "nats" {
+			return fmt.Errorf("auth.type: nats takes basic or bearer auth")
+		}
+		return fmt.Errorf("auth.type: %s takes basic auth", broker)
+	}
+	if len(m.Topics) == 0 {
+		return fmt.Errorf("messaging.topics: at least one topic is required")
+	}
+	seen := map[string]bool{}
+	for i, t := range m.Topics {
+		if t.Name == "" {
+			return fmt.Errorf("messaging.topics[%d].name: is required", i)
+		}
+		if seen[t.Name] {
+			return fmt.Errorf("messaging.topics[%d].name: duplicate %q", i, t.Name)
+		}
+		seen[t.Name] = true
+		switch broker {
+		case "kafka":
+			if !kafkaTopicRE.MatchString(t.Name) {
+				return fmt.Errorf("messaging.topics[%d].name: %q is not a valid Kafka topic name", i, t.Name)
+			}
+		case "nats":
+			if strings.ContainsAny(t.Name, " \t\r\n") {
+				return fmt.Errorf("messaging.topics[%d].name: NATS subjects cannot contain whitespace", i)
+			}
+			if strings.ContainsAny(t.Name, "*>") && m.Writable(t) {
+				return fmt.Errorf("messaging.topics[%d]: wildcard subject %q must be read_only", i, t.Name)
+			}
+		}
+		if broker != "rabbitmq" && (t.Exchange != "" || t.RoutingKey != "") {
+			return fmt.Errorf("messaging.topics[%d]: exchange and routing_key are only for rabbitmq", i)
+		}
+		if t.RoutingKey != "" && t.Exchange == "" {
+			return fmt.Errorf("messaging.topics[%d].routing_key: requires exchange", i)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// toolNameRe matches the tool names MCP clients accept.
+var toolNameRe = regexp.MustCompile(`^[A-Za-z0-9_-]{1,128}$`)
+
+// ToolNamingConfig controls how tool names are built. By default a tool is
+// named <api>__<operationId>.
+type ToolNamingConfig struct {
+	// Prefix is "api" (default) for <api>__<operation> or "none" for the
+	// bare operation name.
+	Prefix string `json:"prefix,omitempty" yaml:"prefix,omitempty"`
+	// Case rewrites the operation part: "snake" or "camel". Empty keeps the
+	// operation ID as is.
+	Case string `json:"case,omitempty" yaml:"case,omitempty"`
+	// MaxLength shortens longer names, replacing their tail with a stable
+	// hash. 0 means no limit.
+	MaxLength int `json:"max_length,omitempty" yaml:"max_length,omitempty"`
+}
+
+func (n *ToolNamingConfig) validate() error {
+	if n == nil {
+		return nil
+	}
+	switch n.Prefix {
+	case "", "api", "none":
+	default:
+		return fmt.Errorf("tool_naming.prefix: must be api or none, got %q", n.Prefix)
+	}
+	switch n.Case {
+	case "", "snake", "camel":
+	default:
+		return fmt.Errorf("tool_naming.case: must be snake or camel, got %q", n.Case)
+	}
+	if n.MaxLength != 0 && n.MaxLength < 16 {
+		return fmt.Errorf("tool_naming.max_length: must be at least 16")
+	}
+	return nil
+}
+
+// ToolOverride adjusts how one tool is presented to clients. Nil hints keep
+// the ones derived from the operation.
+type ToolOverride struct {
+	// Title is a human-readable display name.
+	Title string `json:"title,omitempty" yaml:"title,omitempty"`
+	// Description replaces the generated description.
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	// Hint is appended to the description, e.g. when to use the tool.
+	Hint        string `json:"hint,omitempty" yaml:"hint,omitempty"`
+	ReadOnly    *bool  `json:"read_only,omitempty" yaml:"read_only,omitempty"`
+	Destructive *bool  `json:"destructive,omitempty" yaml:"destructive,omitempty"`
+	Idempotent  *bool  `json:"idempotent,omitempty" yaml:"idempotent,omitempty"`
+	OpenWorld   *bool  `json:"open_world,omitempty" yaml:"open_world,omitempty"`
+}
+
+func (o ToolOverride) validate(tool string) error {
+	if o.ReadOnly != nil && o.Destructive != nil && *o.ReadOnly && *o.Destructive {
+		return fmt.Errorf("tool_overrides.%s: a tool cannot be both read_only and destructive", tool)
+	}
+	return nil
+}
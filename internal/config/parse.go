@@ -12,26 +12,9 @@ import (
 // Auto-detects format: JSON if content starts with { or [, otherwise YAML.
 func LoadFromBytes(data []byte) (*Config, error) {
 	var cfg Config
-
-	// Auto-detect format
-	trimmed := bytes.TrimSpace(data)
-	isJSON := len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[')
-
-	var err error
-	if isJSON {
-		// Parse as JSON
-		err = json.Unmarshal(data, &cfg)
-		if err != nil {
-			return nil, fmt.Errorf("parse config (JSON): %w", err)
-		}
-	} else {
-		// Parse as YAML (also handles JSON since YAML is superset)
-		err = yaml.Unmarshal(data, &cfg)
-		if err != nil {
-			return nil, fmt.Errorf("parse config (YAML): %w", err)
-		}
+	if err := decodeConfig(data, &cfg); err != nil {
+		return nil, err
 	}
-
 	if err := cfg.ExpandEnv(); err != nil {
 		return nil, err
 	}
@@ -42,6 +25,23 @@ func LoadFromBytes(data []byte) (*Config, error) {
 	return &cfg, nil
 }
 
+// decodeConfig parses YAML or JSON into out, choosing JSON when the content
+// starts with { or [.
+func decodeConfig(data []byte, out any) error {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("parse config (JSON): %w", err)
+		}
+		return nil
+	}
+	// YAML also handles JSON since YAML is a superset
+	if err := yaml.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("parse config (YAML): %w", err)
+	}
+	return nil
+}
+
 // ValidateYAML parses YAML config bytes, applies defaults, and validates without env expansion.
 // Deprecated: Use ValidateConfig for format-agnostic validation.
 func ValidateYAML(data []byte) error {
@@ -51,27 +51,9 @@ func ValidateYAML(data []byte) error {
 // ValidateConfig parses YAML or JSON config bytes, applies defaults, and validates without env expansion.
 func ValidateConfig(data []byte) error {
 	var cfg Config
-
-	// Auto-detect format
-	trimmed := bytes.TrimSpace(data)
-	isJSON := len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[')
-
-	var err error
-	if isJSON {
-		err = json.Unmarshal(data, &cfg)
-		if err != nil {
-			return fmt.Errorf("parse config (JSON): %w", err)
-		}
-	} else {
-		err = yaml.Unmarshal(data, &cfg)
-		if err != nil {
-			return fmt.Errorf("parse config (YAML): %w", err)
-		}
-	}
-
-	cfg.ApplyDefaults()
-	if err := cfg.Validate(); err != nil {
+	if err := decodeConfig(data, &cfg); err != nil {
 		return err
 	}
-	return nil
+	cfg.ApplyDefaults()
+	return cfg.Validate()
 }
@@ -0,0 +1,29 @@
+package config
+
+import "fmt"
+
+// DefaultPrometheusMaxPoints is the most points per series Prometheus
+// returns for a range query.
+const DefaultPrometheusMaxPoints = 11000
+
+// PrometheusConfig tunes a spec_type: prometheus API.
+type PrometheusConfig struct {
+	// Lint checks PromQL queries before sending them, so syntax errors and
+	// unknown functions come back with a hint instead of a server parse
+	// error. Leave it off for VictoriaMetrics, whose MetricsQL extends
+	// PromQL.
+	Lint bool `json:"lint,omitempty" yaml:"lint,omitempty"`
+	// MaxPoints caps the points per series of a range query. Default:
+	// 11000, Prometheus' limit; VictoriaMetrics allows 30000 by default.
+	MaxPoints int `json:"max_points,omitempty" yaml:"max_points,omitempty"`
+}
+
+func (p *PrometheusConfig) validate() error {
+	if p == nil {
+		return nil
+	}
+	if p.MaxPoints < 0 {
+		return fmt.Errorf("prometheus.max_points: must not be negative")
+	}
+	return nil
+}
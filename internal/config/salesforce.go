@@ -0,0 +1,48 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// SalesforceTokenURL is the token endpoint of production and developer
+// orgs, used by spec_type: salesforce APIs with oauth2 auth and no
+// token_url. Sandboxes use https://test.salesforce.com/services/oauth2/token.
+const SalesforceTokenURL = "https://login.salesforce.com/services/oauth2/token" //nolint:gosec // not actual credentials
+
+// DefaultSalesforceObjects are the sObjects tools are generated for when
+// salesforce.objects is not set.
+var DefaultSalesforceObjects = []string{"Account", "Contact", "Lead", "Opportunity", "Case", "Task"}
+
+var (
+	salesforceObjectRE  = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+	salesforceVersionRE = regexp.MustCompile(`^\d+\.\d$`)
+)
+
+// SalesforceConfig selects what a spec_type: salesforce API exposes. Tools
+// are generated from the org's describe results for each object.
+type SalesforceConfig struct {
+	// Objects lists the sObjects to generate tools for, e.g. Account or
+	// Invoice__c. Default: Account, Contact, Lead, Opportunity, Case, Task.
+	Objects []string `json:"objects,omitempty" yaml:"objects,omitempty"`
+	// APIVersion pins the REST API version, e.g. "61.0". Default: the
+	// newest version the org serves.
+	APIVersion string `json:"api_version,omitempty" yaml:"api_version,omitempty"`
+	// ReadOnly drops the create, update and delete tools.
+	ReadOnly bool `json:"read_only,omitempty" yaml:"read_only,omitempty"`
+}
+
+func (s *SalesforceConfig) validate() error {
+	if s == nil {
+		return nil
+	}
+	for _, name := range s.Objects {
+		if !salesforceObjectRE.MatchString(name) {
+			return fmt.Errorf("salesforce.objects: invalid object name %q", name)
+		}
+	}
+	if s.APIVersion != "" && !salesforceVersionRE.MatchString(s.APIVersion) {
+		return fmt.Errorf("salesforce.api_version: must look like 61.0, got %q", s.APIVersion)
+	}
+	return nil
+}
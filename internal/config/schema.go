@@ -0,0 +1,92 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// SchemaID is the $id of the profile config JSON Schema.
+const SchemaID = "urn:skyline-mcp:profile-config"
+
+// schemaRequired lists required properties per struct type. Requirements that
+// depend on other fields (e.g. auth.token for bearer) are left to Diagnose.
+var schemaRequired = map[reflect.Type][]string{
+	reflect.TypeOf(APIConfig{}):               {"name"},
+	reflect.TypeOf(AuthConfig{}):              {"type"},
+	reflect.TypeOf(OperationFilterEnhanced{}): {"mode"},
+	reflect.TypeOf(JenkinsWrite{}):            {"name", "method", "path"},
+	reflect.TypeOf(PersistedQuery{}):          {"name", "query"},
+	reflect.TypeOf(WorkflowConfig{}):          {"name", "steps"},
+	reflect.TypeOf(WorkflowInput{}):           {"name"},
+	reflect.TypeOf(WorkflowStep{}):            {"id", "tool"},
+	reflect.TypeOf(ContractTest{}):            {"tool"},
+}
+
+// schemaEnums restricts string properties, keyed by "<Type>.<json name>".
+var schemaEnums = map[string][]string{
+	"AuthConfig.type":                   {"bearer", "basic", "api-key", "oauth2", "wsse", "aws-sigv4"},
+	"AuthConfig.password_type":          {"text", "digest"},
+	"AuthConfig.flow":                   {"refresh_token", "device_code"},
+	"GraphQLOptimization.response_mode": {"essential", "full", "auto"},
+	"TypeProfile.response_mode":         {"essential", "full", "auto"},
+	"EmailConfig.smtp_tls":              {"starttls", "ssl", "none"},
+	"EmailConfig.connection_mode":       {"basic", "persistent"},
+	"WorkflowInput.type":                {"string", "integer", "number", "boolean", "object", "array"},
+}
+
+// JSONSchema returns a JSON Schema (draft 2020-12) describing the profile
+// config format, derived from the Config struct so it never drifts from what
+// the server accepts. Unknown properties are rejected.
+func JSONSchema() map[string]any {
+	schema := typeSchema(reflect.TypeOf(Config{}))
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["$id"] = SchemaID
+	schema["title"] = "Skyline MCP profile configuration"
+	return schema
+}
+
+func typeSchema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		props := map[string]any{}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" || name == "" {
+				continue
+			}
+			prop := typeSchema(f.Type)
+			if enum, ok := schemaEnums[t.Name()+"."+name]; ok {
+				prop["enum"] = enum
+			}
+			props[name] = prop
+		}
+		s := map[string]any{
+			"type":                 "object",
+			"properties":           props,
+			"additionalProperties": false,
+		}
+		if req, ok := schemaRequired[t]; ok {
+			s["required"] = req
+		}
+		return s
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		// Every integer setting (timeouts, retries, limits, ports) is a non-negative count.
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	}
+	return map[string]any{}
+}
@@ -0,0 +1,110 @@
+package config
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// SigningConfig signs every request to an API, after its auth is applied,
+// for partners that require a signature header.
+type SigningConfig struct {
+	// Type is the signature scheme; only "hmac" is built in.
+	Type string `json:"type" yaml:"type"`
+	// Secret is the HMAC key. It may reference environment variables.
+	Secret string `json:"secret" yaml:"secret"`
+	// SecretEncoding is how Secret is written: "raw" (the default), "hex"
+	// or "base64".
+	SecretEncoding string `json:"secret_encoding,omitempty" yaml:"secret_encoding,omitempty"`
+	// Algorithm is "sha256" (the default), "sha1" or "sha512".
+	Algorithm string `json:"algorithm,omitempty" yaml:"algorithm,omitempty"`
+	// Header receives the signature; default X-Signature.
+	Header string `json:"header,omitempty" yaml:"header,omitempty"`
+	// Encoding is how the signature is written: "hex" (the default) or
+	// "base64".
+	Encoding string `json:"encoding,omitempty" yaml:"encoding,omitempty"`
+	// Prefix is put before the signature, e.g. "sha256=".
+	Prefix string `json:"prefix,omitempty" yaml:"prefix,omitempty"`
+	// Payload is the signed string, with placeholders for parts of the
+	// request such as {{method}}, {{path}} and {{body}}; default "{{body}}".
+	Payload string `json:"payload,omitempty" yaml:"payload,omitempty"`
+	// TimestampHeader, when set, sends the Unix time the request was
+	// signed at, which {{timestamp}} puts in the payload.
+	TimestampHeader string `json:"timestamp_header,omitempty" yaml:"timestamp_header,omitempty"`
+}
+
+// signingPlaceholders are the placeholders a signing payload may use.
+var signingPlaceholders = []string{"method", "path", "query", "url", "host", "body", "body_sha256", "timestamp"}
+
+func (s *SigningConfig) validate() error {
+	if s == nil {
+		return nil
+	}
+	if s.Type != "hmac" {
+		return fmt.Errorf("signing.type: must be hmac, got %q", s.Type)
+	}
+	if s.Secret == "" {
+		return fmt.Errorf("signing.secret: required")
+	}
+	if _, err := s.Key(); err != nil {
+		return fmt.Errorf("signing.secret: %w", err)
+	}
+	switch s.Algorithm {
+	case "", "sha1", "sha256", "sha512":
+	default:
+		return fmt.Errorf("signing.algorithm: must be sha1, sha256 or sha512, got %q", s.Algorithm)
+	}
+	switch s.Encoding {
+	case "", "hex", "base64":
+	default:
+		return fmt.Errorf("signing.encoding: must be hex or base64, got %q", s.Encoding)
+	}
+	for field, name := range map[string]string{"header": s.Header, "timestamp_header": s.TimestampHeader} {
+		if strings.ContainsAny(name, " \t:") {
+			return fmt.Errorf("signing.%s: %q is not a valid header name", field, name)
+		}
+	}
+	rest := s.Payload
+	for {
+		start := strings.Index(rest, "{{")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(rest[start:], "}}")
+		if end < 0 {
+			return fmt.Errorf("signing.payload: unclosed placeholder")
+		}
+		name := strings.TrimSpace(rest[start+2 : start+end])
+		if !isSigningPlaceholder(name) {
+			return fmt.Errorf("signing.payload: unknown placeholder {{%s}}", name)
+		}
+		rest = rest[start+end+2:]
+	}
+	return nil
+}
+
+func isSigningPlaceholder(name string) bool {
+	if header, ok := strings.CutPrefix(name, "header."); ok {
+		return header != ""
+	}
+	for _, p := range signingPlaceholders {
+		if name == p {
+			return true
+		}
+	}
+	return false
+}
+
+// Key returns the HMAC key, decoded per SecretEncoding.
+func (s *SigningConfig) Key() ([]byte, error) {
+	switch s.SecretEncoding {
+	case "", "raw":
+		return []byte(s.Secret), nil
+	case "hex":
+		return hex.DecodeString(s.Secret)
+	case "base64":
+		return base64.StdEncoding.DecodeString(s.Secret)
+	}
+	return nil, fmt.Errorf("secret_encoding: must be raw, hex or base64, got %q", s.SecretEncoding)
+}
@@ -0,0 +1,363 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// DefaultSSHMaxOutputBytes caps the stdout and stderr each kept of a
+// command on spec_type: ssh APIs that set no max_output_bytes.
+const DefaultSSHMaxOutputBytes = 64 << 10
+
+// SSHConfig runs an allowlist of command templates on hosts over SSH for a
+// spec_type: ssh API. Each command becomes a tool whose arguments fill the
+// template's placeholders; nothing else can be run.
+type SSHConfig struct {
+	// User is the login user on every host that sets none.
+	User string `json:"user,omitempty" yaml:"user,omitempty"`
+	// PrivateKeyFile is the path of the private key to log in with.
+	PrivateKeyFile string `json:"private_key_file,omitempty" yaml:"private_key_file,omitempty"`
+	// PrivateKey is the PEM-encoded private key, usually ${ENV_VAR}, used
+	// instead of PrivateKeyFile.
+	PrivateKey string `json:"private_key,omitempty" yaml:"private_key,omitempty"`
+	// Passphrase decrypts an encrypted private key.
+	Passphrase string `json:"passphrase,omitempty" yaml:"passphrase,omitempty"`
+	// KnownHostsFile verifies the keys of hosts without a host_key, e.g.
+	// ~/.ssh/known_hosts.
+	KnownHostsFile string `json:"known_hosts_file,omitempty" yaml:"known_hosts_file,omitempty"`
+	// Hosts are the machines commands may run on.
+	Hosts []SSHHost `json:"hosts" yaml:"hosts"`
+	// Commands are the command templates exposed as tools.
+	Commands []SSHCommand `json:"commands" yaml:"commands"`
+	// MaxOutputBytes caps the stdout and stderr kept of each command.
+	// Default: 64 KiB.
+	MaxOutputBytes int `json:"max_output_bytes,omitempty" yaml:"max_output_bytes,omitempty"`
+}
+
+// SSHHost is one machine of an ssh API.
+type SSHHost struct {
+	// Name identifies the host in tool arguments, e.g. web-1.
+	Name string `json:"name" yaml:"name"`
+	// Address is host or host:port. Default port: 22.
+	Address string `json:"address" yaml:"address"`
+	// User overrides ssh.user for this host.
+	User string `json:"user,omitempty" yaml:"user,omitempty"`
+	// HostKey pins the host's public key in authorized_keys format, e.g.
+	// "ssh-ed25519 AAAA...". Required without known_hosts_file.
+	HostKey string `json:"host_key,omitempty" yaml:"host_key,omitempty"`
+}
+
+// SSHCommand is one allowlisted command template.
+type SSHCommand struct {
+	// Name is the tool name, e.g. restart_service.
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	// Command is the shell command run on the host. {{param}} placeholders
+	// are replaced by the quoted argument values and may not appear inside
+	// quotes, e.g. "sudo systemctl restart {{service}}".
+	Command string `json:"command" yaml:"command"`
+	// Params declares every placeholder of Command.
+	Params []SSHParam `json:"params,omitempty" yaml:"params,omitempty"`
+	// Hosts limits the command to these host names. Default: every host.
+	Hosts []string `json:"hosts,omitempty" yaml:"hosts,omitempty"`
+	// TimeoutSeconds overrides the API's timeout for this command.
+	TimeoutSeconds int `json:"timeout_seconds,omitempty" yaml:"timeout_seconds,omitempty"`
+	// MaxOutputBytes overrides ssh.max_output_bytes for this command.
+	MaxOutputBytes int `json:"max_output_bytes,omitempty" yaml:"max_output_bytes,omitempty"`
+}
+
+// SSHParam constrains the values of one placeholder. String parameters
+// need an enum or a pattern.
+type SSHParam struct {
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	// Type is string (the default) or integer.
+	Type string `json:"type,omitempty" yaml:"type,omitempty"`
+	// Enum lists the allowed string values.
+	Enum []string `json:"enum,omitempty" yaml:"enum,omitempty"`
+	// Pattern is a regular expression the whole string value must match.
+	Pattern string `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+	// Minimum and Maximum bound integer values.
+	Minimum *int `json:"minimum,omitempty" yaml:"minimum,omitempty"`
+	Maximum *int `json:"maximum,omitempty" yaml:"maximum,omitempty"`
+	// Default is used when a call omits the argument. Parameters without a
+	// default are required.
+	Default any `json:"default,omitempty" yaml:"default,omitempty"`
+}
+
+// SSHPlaceholder matches a {{param}} placeholder of an SSH command
+// template.
+var SSHPlaceholder = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+var (
+	sshNameRE    = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+	sshCommandRE = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+)
+
+// HostUser returns the login user of h.
+func (s *SSHConfig) HostUser(h SSHHost) string {
+	if h.User != "" {
+		return h.User
+	}
+	return s.User
+}
+
+// Host returns the host named name.
+func (s *SSHConfig) Host(name string) (SSHHost, bool) {
+	for _, h := range s.Hosts {
+		if h.Name == name {
+			return h, true
+		}
+	}
+	return SSHHost{}, false
+}
+
+// Command returns the command named name.
+func (s *SSHConfig) Command(name string) (SSHCommand, bool) {
+	for _, c := range s.Commands {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return SSHCommand{}, false
+}
+
+// CommandHosts returns the names of the hosts c may run on.
+func (s *SSHConfig) CommandHosts(c SSHCommand) []string {
+	if len(c.Hosts) > 0 {
+		return c.Hosts
+	}
+	names := make([]string, len(s.Hosts))
+	for i, h := range s.Hosts {
+		names[i] = h.Name
+	}
+	return names
+}
+
+// OutputLimit returns the bytes of stdout and of stderr kept for c.
+func (s *SSHConfig) OutputLimit(c SSHCommand) int {
+	if c.MaxOutputBytes > 0 {
+		return c.MaxOutputBytes
+	}
+	if s.MaxOutputBytes > 0 {
+		return s.MaxOutputBytes
+	}
+	return DefaultSSHMaxOutputBytes
+}
+
+// Value checks v against the parameter's constraints and returns it as
+// the text to quote into the command.
+func (p SSHParam) Value(v any) (string, error) {
+	if p.Type == "integer" {
+		var n float64
+		switch x := v.(type) {
+		case int:
+			n = float64(x)
+		case int64:
+			n = float64(x)
+		case float64:
+			n = x
+		default:
+			return "", fmt.Errorf("%s must be an integer", p.Name)
+		}
+		if n != math.Trunc(n) || math.Abs(n) > 1<<53 {
+			return "", fmt.Errorf("%s must be an integer", p.Name)
+		}
+		if p.Minimum != nil && n < float64(*p.Minimum) {
+			return "", fmt.Errorf("%s must be at least %d", p.Name, *p.Minimum)
+		}
+		if p.Maximum != nil && n > float64(*p.Maximum) {
+			return "", fmt.Errorf("%s must be at most %d", p.Name, *p.Maximum)
+		}
+		return strconv.FormatInt(int64(n), 10), nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("%s must be a string", p.Name)
+	}
+	if len(p.Enum) > 0 {
+		if !slices.Contains(p.Enum, s) {
+			return "", fmt.Errorf("%s must be one of %s", p.Name, strings.Join(p.Enum, ", "))
+		}
+		return s, nil
+	}
+	re, err := regexp.Compile(`^(?:` + p.Pattern + `)$`)
+	if err != nil {
+		return "", fmt.Errorf("%s: pattern: %w", p.Name, err)
+	}
+	if !re.MatchString(s) {
+		return "", fmt.Errorf("%s must match %s", p.Name, p.Pattern)
+	}
+	// Values are quoted, but a command could still read a leading dash as
+	// an option.
+	if strings.HasPrefix(s, "-") {
+		return "", fmt.Errorf("%s must not start with -", p.Name)
+	}
+	if strings.ContainsAny(s, "\x00\r\n") {
+		return "", fmt.Errorf("%s must be a single line", p.Name)
+	}
+	return s, nil
+}
+
+func (s *SSHConfig) validate(auth *AuthConfig) error {
+	if s == nil {
+		return nil
+	}
+	if auth != nil {
+		return fmt.Errorf("auth: ssh APIs log in with ssh.private_key_file or ssh.private_key")
+	}
+	if (s.PrivateKeyFile == "") == (s.PrivateKey == "") {
+		return fmt.Errorf("ssh: exactly one of private_key_file and private_key is required")
+	}
+	if s.MaxOutputBytes < 0 {
+		return fmt.Errorf("ssh.max_output_bytes: must not be negative")
+	}
+	if len(s.Hosts) == 0 {
+		return fmt.Errorf("ssh.hosts: at least one host is required")
+	}
+	hosts := map[string]bool{}
+	for i, h := range s.Hosts {
+		if !sshNameRE.MatchString(h.Name) {
+			return fmt.Errorf("ssh.hosts[%d].name: %q must be letters, digits, '.', '_' or '-'", i, h.Name)
+		}
+		if hosts[h.Name] {
+			return fmt.Errorf("ssh.hosts[%d].name: duplicate %q", i, h.Name)
+		}
+		hosts[h.Name] = true
+		if h.Address == "" || strings.Contains(h.Address, "://") {
+			return fmt.Errorf("ssh.hosts[%d].address: must be host or host:port", i)
+		}
+		if s.HostUser(h) == "" {
+			return fmt.Errorf("ssh.hosts[%d]: no user; set ssh.user or user", i)
+		}
+		if h.HostKey != "" {
+			if _, _, _, _, err := ssh.ParseAuthorizedKey([]byte(h.HostKey)); err != nil {
+				return fmt.Errorf("ssh.hosts[%d].host_key: %w", i, err)
+			}
+		} else if s.KnownHostsFile == "" {
+			return fmt.Errorf("ssh.hosts[%d]: host_key is required without ssh.known_hosts_file", i)
+		}
+	}
+	if len(s.Commands) == 0 {
+		return fmt.Errorf("ssh.commands: at least one command is required")
+	}
+	commands := map[string]bool{}
+	for i, c := range s.Commands {
+		if err := s.validateCommand(c, hosts); err != nil {
+			return fmt.Errorf("ssh.commands[%d]%w", i, err)
+		}
+		if commands[c.Name] {
+			return fmt.Errorf("ssh.commands[%d].name: duplicate %q", i, c.Name)
+		}
+		commands[c.Name] = true
+	}
+	return nil
+}
+
+// validateCommand checks one command; errors start with the field path
+// below the command.
+func (s *SSHConfig) validateCommand(c SSHCommand, hosts map[string]bool) error {
+	if !sshCommandRE.MatchString(c.Name) {
+		return fmt.Errorf(".name: %q must start with a letter and hold letters, digits or '_'", c.Name)
+	}
+	if strings.TrimSpace(c.Command) == "" {
+		return fmt.Errorf(".command: is required")
+	}
+	if c.TimeoutSeconds < 0 {
+		return fmt.Errorf(".timeout_seconds: must not be negative")
+	}
+	if c.MaxOutputBytes < 0 {
+		return fmt.Errorf(".max_output_bytes: must not be negative")
+	}
+	for j, name := range c.Hosts {
+		if !hosts[name] {
+			return fmt.Errorf(".hosts[%d]: unknown host %q", j, name)
+		}
+	}
+	used, err := templateParams(c.Command)
+	if err != nil {
+		return fmt.Errorf(".command: %w", err)
+	}
+	declared := map[string]bool{}
+	for j, p := range c.Params {
+		if !sshCommandRE.MatchString(p.Name) || p.Name == "host" {
+			return fmt.Errorf(".params[%d].name: %q is not a valid parameter name", j, p.Name)
+		}
+		if declared[p.Name] {
+			return fmt.Errorf(".params[%d].name: duplicate %q", j, p.Name)
+		}
+		declared[p.Name] = true
+		if !used[p.Name] {
+			return fmt.Errorf(".params[%d]: %s is not used in the command", j, p.Name)
+		}
+		switch p.Type {
+		case "", "string":
+			if len(p.Enum) == 0 && p.Pattern == "" {
+				return fmt.Errorf(".params[%d]: string parameter %s needs an enum or a pattern", j, p.Name)
+			}
+			if p.Minimum != nil || p.Maximum != nil {
+				return fmt.Errorf(".params[%d]: minimum and maximum are only for integers", j)
+			}
+			if _, err := regexp.Compile(p.Pattern); err != nil {
+				return fmt.Errorf(".params[%d].pattern: %w", j, err)
+			}
+		case "integer":
+			if len(p.Enum) > 0 || p.Pattern != "" {
+				return fmt.Errorf(".params[%d]: enum and pattern are only for strings", j)
+			}
+			if p.Minimum != nil && p.Maximum != nil && *p.Minimum > *p.Maximum {
+				return fmt.Errorf(".params[%d]: minimum is greater than maximum", j)
+			}
+		default:
+			return fmt.Errorf(".params[%d].type: must be string or integer", j)
+		}
+		if p.Default != nil {
+			if _, err := p.Value(p.Default); err != nil {
+				return fmt.Errorf(".params[%d].default: %w", j, err)
+			}
+		}
+	}
+	for name := range used {
+		if !declared[name] {
+			return fmt.Errorf(".command: placeholder {{%s}} has no entry in params", name)
+		}
+	}
+	return nil
+}
+
+// templateParams returns the placeholders of an SSH command template. A
+// placeholder inside quotes would end the quotes of its value, so it is
+// an error, as is an unmatched "{{".
+func templateParams(command string) (map[string]bool, error) {
+	used := map[string]bool{}
+	var quote byte
+	pos := 0
+	for _, m := range SSHPlaceholder.FindAllStringSubmatchIndex(command, -1) {
+		for ; pos < m[0]; pos++ {
+			switch c := command[pos]; {
+			case quote == 0 && (c == '\'' || c == '"'):
+				quote = c
+			case c == quote:
+				quote = 0
+			case c == '\\' && quote != '\'':
+				pos++
+			}
+		}
+		name := command[m[2]:m[3]]
+		if quote != 0 || pos > m[0] {
+			return nil, fmt.Errorf("placeholder {{%s}} is quoted; values are quoted for the shell already", name)
+		}
+		used[name] = true
+		pos = m[1]
+	}
+	if strings.Contains(SSHPlaceholder.ReplaceAllString(command, ""), "{{") {
+		return nil, fmt.Errorf(`"{{" starts no valid placeholder`)
+	}
+	return used, nil
+}
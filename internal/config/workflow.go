@@ -0,0 +1,79 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// WorkflowConfig defines a multi-step tool. Steps call existing tools in
+// order; their arguments may reference the workflow input and earlier step
+// results with templates such as "{{input.summary}}" or
+// "{{steps.issue.body.key}}".
+type WorkflowConfig struct {
+	// Name becomes the tool name workflows__<name>.
+	Name        string          `json:"name" yaml:"name"`
+	Description string          `json:"description,omitempty" yaml:"description,omitempty"`
+	Inputs      []WorkflowInput `json:"inputs,omitempty" yaml:"inputs,omitempty"`
+	Steps       []WorkflowStep  `json:"steps" yaml:"steps"`
+	// Output shapes the tool result (templates allowed). Default: the
+	// status and body of every step, keyed by step ID.
+	Output any `json:"output,omitempty" yaml:"output,omitempty"`
+}
+
+// WorkflowInput is one argument of a workflow tool.
+type WorkflowInput struct {
+	Name        string `json:"name" yaml:"name"`
+	Type        string `json:"type,omitempty" yaml:"type,omitempty"` // JSON schema type; default string
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	Required    bool   `json:"required,omitempty" yaml:"required,omitempty"`
+}
+
+// WorkflowStep calls one tool.
+type WorkflowStep struct {
+	// ID names the step's result for later templates ("steps.<id>").
+	ID string `json:"id" yaml:"id"`
+	// Tool is the full tool name, e.g. "jira__createIssue".
+	Tool string         `json:"tool" yaml:"tool"`
+	Args map[string]any `json:"args,omitempty" yaml:"args,omitempty"`
+	// When skips the step unless it renders truthy. It may compare two
+	// values with == or !=, e.g. "{{steps.lookup.status}} == 404".
+	When string `json:"when,omitempty" yaml:"when,omitempty"`
+	// ContinueOnError records a failed step and runs the next one instead
+	// of failing the workflow.
+	ContinueOnError bool `json:"continue_on_error,omitempty" yaml:"continue_on_error,omitempty"`
+}
+
+var workflowIDRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+func (w *WorkflowConfig) validate(i int) error {
+	if !workflowIDRe.MatchString(w.Name) {
+		return fmt.Errorf("workflows[%d]: name must be letters, digits, _ or -", i)
+	}
+	if len(w.Steps) == 0 {
+		return fmt.Errorf("workflows[%d]: at least one step is required", i)
+	}
+	for j, in := range w.Inputs {
+		if in.Name == "" {
+			return fmt.Errorf("workflows[%d].inputs[%d]: name is required", i, j)
+		}
+		switch in.Type {
+		case "", "string", "integer", "number", "boolean", "object", "array":
+		default:
+			return fmt.Errorf("workflows[%d].inputs[%d]: unsupported type %q", i, j, in.Type)
+		}
+	}
+	ids := map[string]bool{}
+	for j, step := range w.Steps {
+		if !workflowIDRe.MatchString(step.ID) {
+			return fmt.Errorf("workflows[%d].steps[%d]: id must be letters, digits, _ or -", i, j)
+		}
+		if ids[step.ID] {
+			return fmt.Errorf("workflows[%d].steps[%d]: duplicate id %q", i, j, step.ID)
+		}
+		ids[step.ID] = true
+		if step.Tool == "" {
+			return fmt.Errorf("workflows[%d].steps[%d]: tool is required", i, j)
+		}
+	}
+	return nil
+}
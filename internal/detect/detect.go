@@ -0,0 +1,298 @@
+// Package detect holds the probes /detect sends to a base URL to find the
+// spec of the API behind it, and the matchers that decide whether a
+// response is one.
+package detect
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"skyline-mcp/internal/parsers/asyncapi"
+	"skyline-mcp/internal/parsers/ckan"
+	"skyline-mcp/internal/parsers/graphql"
+	"skyline-mcp/internal/parsers/insomnia"
+	"skyline-mcp/internal/parsers/openrpc"
+	"skyline-mcp/internal/parsers/postman"
+	"skyline-mcp/internal/parsers/prometheus"
+	"skyline-mcp/internal/parsers/raml"
+	"skyline-mcp/internal/serverconfig"
+	"skyline-mcp/internal/spec"
+)
+
+// MaxProbes caps the probes one detection may send.
+const MaxProbes = 64
+
+// Probe is a request sent to the base URL plus Path. See
+// serverconfig.DetectProbe for the fields.
+type Probe struct {
+	Type         string            `json:"type"`
+	Path         string            `json:"path"`
+	Method       string            `json:"method"`
+	Body         string            `json:"body,omitempty"`
+	Headers      map[string]string `json:"headers,omitempty"`
+	Match        string            `json:"match,omitempty"`
+	AllowUnauth  bool              `json:"allow_unauth,omitempty"`
+	BaseContains string            `json:"base_contains,omitempty"`
+	// Source is "builtin", "config" or the name of the plugin file the
+	// probe came from.
+	Source string `json:"source,omitempty"`
+
+	matcher func([]byte) bool
+}
+
+// Matches reports whether raw, the body of a 2xx response, is the spec
+// the probe looks for.
+func (p Probe) Matches(raw []byte) bool {
+	return p.matcher != nil && p.matcher(raw)
+}
+
+// Compile validates p, fills in its defaults and builds its matcher.
+// Probes sent in a /detect request go through it too.
+func Compile(p Probe) (Probe, error) {
+	p.Type = strings.TrimSpace(p.Type)
+	if p.Type == "" {
+		return p, fmt.Errorf("type is required")
+	}
+	if p.Path != "" && !strings.HasPrefix(p.Path, "/") {
+		return p, fmt.Errorf("%s probe: path %q must start with /", p.Type, p.Path)
+	}
+	p.Method = strings.ToUpper(strings.TrimSpace(p.Method))
+	switch p.Method {
+	case "":
+		p.Method = http.MethodGet
+	case http.MethodGet, http.MethodPost:
+	default:
+		return p, fmt.Errorf("%s probe %s: method must be GET or POST", p.Type, p.Path)
+	}
+	switch kind, arg, _ := strings.Cut(p.Match, ":"); {
+	case p.Match == "":
+		detector, ok := detectors[p.Type]
+		if !ok {
+			return p, fmt.Errorf("%s probe %s: no built-in detector for type %s; set match", p.Type, p.Path, p.Type)
+		}
+		p.matcher = detector
+	case p.Match == "status":
+		p.matcher = func([]byte) bool { return true }
+	case kind == "contains" && arg != "":
+		p.matcher = func(raw []byte) bool { return strings.Contains(string(raw), arg) }
+	case kind == "regex" && arg != "":
+		re, err := regexp.Compile(arg)
+		if err != nil {
+			return p, fmt.Errorf("%s probe %s: match: %w", p.Type, p.Path, err)
+		}
+		p.matcher = re.Match
+	default:
+		return p, fmt.Errorf("%s probe %s: match must be empty, status, contains:<text> or regex:<pattern>", p.Type, p.Path)
+	}
+	return p, nil
+}
+
+// Registry is the set of probes /detect tries.
+type Registry struct {
+	probes []Probe
+}
+
+// NewRegistry returns the built-in probes followed by those of cfg and of
+// the files in its plugins directory.
+func NewRegistry(cfg serverconfig.DetectSection) (*Registry, error) {
+	r := &Registry{probes: Builtin()}
+	if err := r.add(cfg.Probes, "config"); err != nil {
+		return nil, err
+	}
+	if cfg.PluginsDir == "" {
+		return r, nil
+	}
+	dir := cfg.PluginsDir
+	if rest, ok := strings.CutPrefix(dir, "~"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("get home dir: %w", err)
+		}
+		dir = filepath.Join(home, rest)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read detect plugins: %w", err)
+	}
+	names := []string{}
+	for _, e := range entries {
+		switch strings.ToLower(filepath.Ext(e.Name())) {
+		case ".yaml", ".yml", ".json":
+			if !e.IsDir() {
+				names = append(names, e.Name())
+			}
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("read detect plugin: %w", err)
+		}
+		var plugin struct {
+			Probes []serverconfig.DetectProbe `yaml:"probes"`
+		}
+		if err := yaml.Unmarshal(data, &plugin); err != nil {
+			return nil, fmt.Errorf("detect plugin %s: %w", name, err)
+		}
+		if err := r.add(plugin.Probes, name); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func (r *Registry) add(probes []serverconfig.DetectProbe, source string) error {
+	for i, cp := range probes {
+		p, err := Compile(Probe{
+			Type:         cp.Type,
+			Path:         cp.Path,
+			Method:       cp.Method,
+			Body:         cp.Body,
+			Headers:      cp.Headers,
+			Match:        cp.Match,
+			AllowUnauth:  cp.AllowUnauth,
+			BaseContains: cp.BaseContains,
+			Source:       source,
+		})
+		if err != nil {
+			return fmt.Errorf("%s: detect probe %d: %w", source, i, err)
+		}
+		r.probes = append(r.probes, p)
+	}
+	return nil
+}
+
+// Probes returns every registered probe.
+func (r *Registry) Probes() []Probe {
+	return append([]Probe(nil), r.probes...)
+}
+
+// ForBaseURL returns the probes to try against baseURL, in order: those
+// limited to base URLs like it first, then the unconditional ones.
+func ForBaseURL(probes []Probe, baseURL string) []Probe {
+	lower := strings.ToLower(baseURL)
+	var first, rest []Probe
+	for _, p := range probes {
+		switch {
+		case p.BaseContains == "":
+			rest = append(rest, p)
+		case strings.Contains(lower, strings.ToLower(p.BaseContains)):
+			first = append(first, p)
+		}
+	}
+	return append(first, rest...)
+}
+
+const rpcDiscoverPayload = `{"jsonrpc":"2.0","method":"rpc.discover","id":1,"params":[]}`
+
+var graphqlIntrospectionPayload = func() string {
+	b, _ := json.Marshal(map[string]string{"query": spec.GraphQLIntrospectionQuery})
+	return string(b)
+}()
+
+var jsonHeaders = map[string]string{"Content-Type": "application/json"}
+
+// Builtin returns the probes every server tries.
+func Builtin() []Probe {
+	probes := []Probe{
+		{Type: "graphql", Path: "", Method: http.MethodPost, Body: graphqlIntrospectionPayload, Headers: jsonHeaders, BaseContains: "/graphql"},
+		{Type: "graphql", Path: "/schema", Method: http.MethodGet, BaseContains: "/graphql"},
+		// Jira's server info is not a spec; finding it is enough.
+		{Type: "jira-rest", Path: "/rest/api/3/serverInfo", Method: http.MethodGet, Match: "status"},
+		// Prometheus-compatible servers have no spec either; build info
+		// identifies them.
+		{Type: "prometheus", Path: "/api/v1/status/buildinfo", Method: http.MethodGet},
+		// Kubernetes-specific paths — probe these first and allow 401 so we can show
+		// the kubeconfig upload helper even when no token has been supplied yet.
+		{Type: "swagger2", Path: "/openapi/v2", Method: http.MethodGet, AllowUnauth: true},
+		{Type: "openapi", Path: "/openapi/v3", Method: http.MethodGet, AllowUnauth: true},
+		{Type: "openapi", Path: "/openapi.json", Method: http.MethodGet},
+		{Type: "openapi", Path: "/openapi.yaml", Method: http.MethodGet},
+		{Type: "openapi", Path: "/openapi/openapi.json", Method: http.MethodGet},
+		{Type: "openapi", Path: "/openapi/openapi.yaml", Method: http.MethodGet},
+		{Type: "openapi", Path: "/v3/api-docs", Method: http.MethodGet},
+		{Type: "swagger2", Path: "/swagger.json", Method: http.MethodGet},
+		{Type: "swagger2", Path: "/swagger.yaml", Method: http.MethodGet},
+		{Type: "swagger2", Path: "/swagger/swagger.json", Method: http.MethodGet},
+		{Type: "swagger2", Path: "/v2/api-docs", Method: http.MethodGet},
+		{Type: "wsdl", Path: "/wsdl", Method: http.MethodGet},
+		{Type: "wsdl", Path: "/wsdl?wsdl", Method: http.MethodGet},
+		{Type: "wsdl", Path: "/wdsl/wsdl", Method: http.MethodGet},
+		{Type: "odata", Path: "/$metadata", Method: http.MethodGet},
+		{Type: "odata", Path: "/odata/$metadata", Method: http.MethodGet},
+		{Type: "ckan", Path: "/api/3/action/package_list", Method: http.MethodGet},
+		{Type: "openrpc", Path: "/jsonrpc/openrpc.json", Method: http.MethodGet},
+		{Type: "openrpc", Path: "/openrpc.json", Method: http.MethodGet},
+		{Type: "openrpc", Path: "/jsonrpc", Method: http.MethodPost, Body: rpcDiscoverPayload, Headers: jsonHeaders},
+		{Type: "openrpc", Path: "/rpc", Method: http.MethodPost, Body: rpcDiscoverPayload, Headers: jsonHeaders},
+		{Type: "graphql", Path: "/graphql/schema", Method: http.MethodGet},
+		{Type: "graphql", Path: "/graphql/schema.graphql", Method: http.MethodGet},
+		{Type: "graphql", Path: "/schema.graphql", Method: http.MethodGet},
+		{Type: "graphql", Path: "/graphql", Method: http.MethodPost, Body: graphqlIntrospectionPayload, Headers: jsonHeaders},
+		{Type: "graphql", Path: "/api/graphql", Method: http.MethodPost, Body: graphqlIntrospectionPayload, Headers: jsonHeaders},
+		{Type: "asyncapi", Path: "/asyncapi.json", Method: http.MethodGet},
+		{Type: "asyncapi", Path: "/asyncapi.yaml", Method: http.MethodGet},
+		{Type: "asyncapi", Path: "/asyncapi.yml", Method: http.MethodGet},
+		{Type: "insomnia", Path: "/insomnia.json", Method: http.MethodGet},
+	}
+	for i := range probes {
+		p, err := Compile(probes[i])
+		if err != nil {
+			panic(err)
+		}
+		p.Source = "builtin"
+		probes[i] = p
+	}
+	return probes
+}
+
+// detectors recognize the spec types probes may report.
+var detectors = map[string]func([]byte) bool{
+	"openapi":  spec.NewOpenAPIAdapter().Detect,
+	"swagger2": spec.NewSwagger2Adapter().Detect,
+	"graphql": func(raw []byte) bool {
+		return graphql.LooksLikeGraphQLSDL(raw) || graphql.LooksLikeGraphQLIntrospection(raw)
+	},
+	"wsdl":  spec.NewWSDLAdapter().Detect,
+	"odata": looksLikeODataMetadata,
+	// LooksLikeCKAN accepts an empty body, which proves nothing here.
+	"ckan": func(raw []byte) bool {
+		return len(bytes.TrimSpace(raw)) > 0 && ckan.LooksLikeCKAN(raw)
+	},
+	// rpc.discover answers with the document in a JSON-RPC result.
+	"openrpc": func(raw []byte) bool {
+		return openrpc.LooksLikeOpenRPC(raw) || openrpc.LooksLikeOpenRPC(UnwrapJSONRPCResult(raw))
+	},
+	"postman":    postman.LooksLikePostmanCollection,
+	"asyncapi":   asyncapi.LooksLikeAsyncAPI,
+	"insomnia":   insomnia.LooksLikeInsomniaCollection,
+	"raml":       raml.LooksLikeRAML,
+	"prometheus": prometheus.LooksLikeBuildInfo,
+}
+
+// UnwrapJSONRPCResult returns the result of a JSON-RPC response, or raw
+// when it is not one.
+func UnwrapJSONRPCResult(raw []byte) []byte {
+	var rpcResp struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(raw, &rpcResp); err == nil && len(rpcResp.Result) > 0 {
+		return []byte(rpcResp.Result)
+	}
+	return raw
+}
+
+func looksLikeODataMetadata(raw []byte) bool {
+	s := string(raw)
+	return strings.Contains(s, "edmx:Edmx") || strings.Contains(s, "<edmx:DataServices") || strings.Contains(s, "oasis-open.org/odata")
+}
@@ -0,0 +1,98 @@
+package detect
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"skyline-mcp/internal/serverconfig"
+)
+
+func TestNewRegistry(t *testing.T) {
+	dir := t.TempDir()
+	plugin := "probes:\n  - type: openapi\n    path: /internal/docs.json\n"
+	if err := os.WriteFile(filepath.Join(dir, "internal.yaml"), []byte(plugin), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a plugin"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewRegistry(serverconfig.DetectSection{
+		Probes:     []serverconfig.DetectProbe{{Type: "custom", Path: "/health", Match: "status", Method: "post"}},
+		PluginsDir: dir,
+	})
+	if err != nil {
+		t.Fatalf("NewRegistry: %v", err)
+	}
+	probes := r.Probes()
+	if len(probes) != len(Builtin())+2 {
+		t.Fatalf("got %d probes", len(probes))
+	}
+	cfg, plug := probes[len(probes)-2], probes[len(probes)-1]
+	if cfg.Source != "config" || cfg.Method != "POST" || !cfg.Matches([]byte("anything")) {
+		t.Fatalf("config probe = %+v", cfg)
+	}
+	if plug.Source != "internal.yaml" || plug.Method != "GET" || !plug.Matches([]byte(`{"openapi":"3.0.0","info":{"title":"x","version":"1"},"paths":{}}`)) {
+		t.Fatalf("plugin probe = %+v", plug)
+	}
+}
+
+func TestNewRegistryRejectsBadProbes(t *testing.T) {
+	tests := []struct {
+		probe serverconfig.DetectProbe
+		want  string
+	}{
+		{serverconfig.DetectProbe{Path: "/x"}, "type is required"},
+		{serverconfig.DetectProbe{Type: "custom", Path: "/x"}, "no built-in detector"},
+		{serverconfig.DetectProbe{Type: "openapi", Path: "x"}, "must start with /"},
+		{serverconfig.DetectProbe{Type: "openapi", Path: "/x", Method: "DELETE"}, "GET or POST"},
+		{serverconfig.DetectProbe{Type: "openapi", Path: "/x", Match: "regex:("}, "match"},
+		{serverconfig.DetectProbe{Type: "openapi", Path: "/x", Match: "jsonpath:$.a"}, "match must be"},
+	}
+	for _, tt := range tests {
+		_, err := NewRegistry(serverconfig.DetectSection{Probes: []serverconfig.DetectProbe{tt.probe}})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%+v: err = %v, want %q", tt.probe, err, tt.want)
+		}
+	}
+}
+
+func TestCompileMatchers(t *testing.T) {
+	tests := []struct {
+		probe Probe
+		raw   string
+		want  bool
+	}{
+		{Probe{Type: "x", Match: "contains:\"version\""}, `{"version":"1"}`, true},
+		{Probe{Type: "x", Match: "contains:\"version\""}, `{}`, false},
+		{Probe{Type: "x", Match: "regex:^ok\\b"}, "ok then", true},
+		{Probe{Type: "openrpc", Method: "POST"}, `{"jsonrpc":"2.0","id":1,"result":{"openrpc":"1.2.6","info":{"title":"x","version":"1"},"methods":[]}}`, true},
+		{Probe{Type: "ckan"}, "", false},
+	}
+	for _, tt := range tests {
+		p, err := Compile(tt.probe)
+		if err != nil {
+			t.Fatalf("Compile(%+v): %v", tt.probe, err)
+		}
+		if got := p.Matches([]byte(tt.raw)); got != tt.want {
+			t.Errorf("%s %q matches %q = %v, want %v", p.Type, p.Match, tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestForBaseURL(t *testing.T) {
+	probes := []Probe{
+		{Type: "openapi", Path: "/openapi.json"},
+		{Type: "graphql", Path: "", BaseContains: "/graphql"},
+	}
+	got := ForBaseURL(probes, "https://api.example.com/GraphQL")
+	if len(got) != 2 || got[0].Type != "graphql" {
+		t.Fatalf("graphql base: %+v", got)
+	}
+	got = ForBaseURL(probes, "https://api.example.com")
+	if len(got) != 1 || got[0].Type != "openapi" {
+		t.Fatalf("plain base: %+v", got)
+	}
+}
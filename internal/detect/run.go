@@ -0,0 +1,176 @@
+package detect
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"skyline-mcp/internal/parsers/graphql"
+)
+
+const (
+	defaultConcurrency  = 8
+	defaultProbeTimeout = 8 * time.Second
+	// maxResponseSize caps how much of a response is read to match it.
+	maxResponseSize = 10 << 20
+)
+
+// RunOptions control a detection run. Its overall deadline is that of the
+// context passed to Run.
+type RunOptions struct {
+	// Headers are sent with every probe, after the probe's own (auth).
+	Headers map[string]string
+	// Concurrency is the number of probes in flight; default 8.
+	Concurrency int
+	// ProbeTimeout bounds each probe; default 8s.
+	ProbeTimeout time.Duration
+	// StopOnFirst cancels the remaining probes once one finds a spec.
+	StopOnFirst bool
+}
+
+// Result is the outcome of one probe.
+type Result struct {
+	Probe Probe
+	URL   string
+	// Status is the HTTP status, 0 when no response arrived.
+	Status int
+	// Reached is set when the server answered 2xx, or 401 to a probe
+	// that allows it, whether or not the body was a spec.
+	Reached bool
+	Found   bool
+	Error   string
+	// Skipped is set for probes cut short by the run's deadline or by
+	// StopOnFirst.
+	Skipped  bool
+	Duration time.Duration
+}
+
+// Run sends probes to baseURL, at most opts.Concurrency at a time, and
+// returns their results in the order of probes.
+func Run(ctx context.Context, client *http.Client, baseURL string, probes []Probe, opts RunOptions) []Result {
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+	timeout := opts.ProbeTimeout
+	if timeout <= 0 {
+		timeout = defaultProbeTimeout
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var stopped bool // set under mu when StopOnFirst cancels ctx
+	var mu sync.Mutex
+
+	results := make([]Result, len(probes))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency && w < len(probes); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				results[i] = runProbe(ctx, client, baseURL, probes[i], opts.Headers, timeout)
+				if results[i].Found && opts.StopOnFirst {
+					mu.Lock()
+					stopped = true
+					mu.Unlock()
+					cancel()
+				}
+			}
+		}()
+	}
+	for i := range probes {
+		if ctx.Err() != nil {
+			results[i] = Result{Probe: probes[i], URL: probeURL(baseURL, probes[i]), Skipped: true}
+			continue
+		}
+		select {
+		case next <- i:
+		case <-ctx.Done():
+			results[i] = Result{Probe: probes[i], URL: probeURL(baseURL, probes[i]), Skipped: true}
+		}
+	}
+	close(next)
+	wg.Wait()
+
+	reason := "skipped: detection deadline reached"
+	if stopped {
+		reason = "skipped: a spec was already found"
+	}
+	for i := range results {
+		if results[i].Skipped {
+			results[i].Error = reason
+		}
+	}
+	return results
+}
+
+func probeURL(baseURL string, p Probe) string {
+	return strings.TrimRight(baseURL, "/") + p.Path
+}
+
+// runProbe sends p and matches the response in one request. A probe that
+// fails because run was cancelled is marked skipped.
+func runProbe(run context.Context, client *http.Client, baseURL string, p Probe, headers map[string]string, timeout time.Duration) (res Result) {
+	res = Result{Probe: p, URL: probeURL(baseURL, p)}
+	start := time.Now()
+	defer func() { res.Duration = time.Since(start) }()
+
+	ctx, cancel := context.WithTimeout(run, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, p.Method, res.URL, strings.NewReader(p.Body))
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	req.Header.Set("Accept", "application/json, text/yaml, application/yaml, application/xml, text/xml, */*")
+	for k, v := range p.Headers {
+		req.Header.Set(k, v)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		switch {
+		case run.Err() != nil:
+			res.Skipped = true
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			res.Error = "probe timed out"
+		default:
+			res.Error = err.Error()
+		}
+		return res
+	}
+	defer resp.Body.Close()
+	res.Status = resp.StatusCode
+	// A 401 shows the server exists; the spec cannot be read without
+	// credentials, so the body is not checked.
+	if resp.StatusCode == http.StatusUnauthorized && p.AllowUnauth {
+		res.Reached, res.Found = true, true
+		return res
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return res
+	}
+	res.Reached = true
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		res.Skipped = run.Err() != nil
+		res.Error = err.Error()
+		return res
+	}
+	if !p.Matches(raw) {
+		res.Error = "content did not match detected type"
+		if msg := graphql.IntrospectionErrors(raw); p.Type == "graphql" && msg != "" {
+			res.Error = "introspection disabled (" + msg + "): set graphql.schema to the SDL file or URL"
+		}
+		return res
+	}
+	res.Found = true
+	return res
+}
@@ -0,0 +1,110 @@
+package detect
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const openAPIDoc = `{"openapi":"3.0.0","info":{"title":"x","version":"1"},"paths":{}}`
+
+// slowServer serves an OpenAPI document at /spec.json and 404s elsewhere,
+// after delay.
+func slowServer(t *testing.T, delay time.Duration) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+		if r.URL.Path != "/spec.json" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(openAPIDoc))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func openAPIProbes(t *testing.T, paths ...string) []Probe {
+	t.Helper()
+	probes := make([]Probe, 0, len(paths))
+	for _, path := range paths {
+		p, err := Compile(Probe{Type: "openapi", Path: path})
+		if err != nil {
+			t.Fatal(err)
+		}
+		probes = append(probes, p)
+	}
+	return probes
+}
+
+func TestRunConcurrent(t *testing.T) {
+	server := slowServer(t, 200*time.Millisecond)
+	probes := openAPIProbes(t, "/a", "/b", "/c", "/spec.json", "/d", "/e")
+
+	start := time.Now()
+	results := Run(context.Background(), server.Client(), server.URL+"/", probes, RunOptions{})
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("run took %v; probes did not run concurrently", elapsed)
+	}
+	for i, res := range results {
+		if res.Probe.Path != probes[i].Path || res.URL != server.URL+probes[i].Path {
+			t.Fatalf("result %d = %+v, out of order", i, res)
+		}
+		if res.Duration < 200*time.Millisecond {
+			t.Errorf("%s: duration %v", res.Probe.Path, res.Duration)
+		}
+		if want := res.Probe.Path == "/spec.json"; res.Found != want || res.Skipped {
+			t.Errorf("%s: found=%v skipped=%v", res.Probe.Path, res.Found, res.Skipped)
+		}
+	}
+}
+
+func TestRunStopOnFirst(t *testing.T) {
+	server := slowServer(t, 50*time.Millisecond)
+	probes := openAPIProbes(t, "/spec.json", "/a", "/b", "/c")
+
+	results := Run(context.Background(), server.Client(), server.URL, probes, RunOptions{Concurrency: 1, StopOnFirst: true})
+	if !results[0].Found {
+		t.Fatalf("first probe = %+v", results[0])
+	}
+	for _, res := range results[1:] {
+		if !res.Skipped || !strings.Contains(res.Error, "already found") {
+			t.Errorf("%s = %+v, want skipped", res.Probe.Path, res)
+		}
+	}
+}
+
+func TestRunDeadline(t *testing.T) {
+	server := slowServer(t, time.Second)
+	probes := openAPIProbes(t, "/a", "/b", "/c")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	results := Run(ctx, server.Client(), server.URL, probes, RunOptions{Concurrency: 1})
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("run took %v, past its deadline", elapsed)
+	}
+	for _, res := range results {
+		if !res.Skipped || !strings.Contains(res.Error, "deadline") {
+			t.Errorf("%s = %+v, want skipped", res.Probe.Path, res)
+		}
+	}
+}
+
+func TestRunProbeTimeout(t *testing.T) {
+	server := slowServer(t, time.Second)
+	probes := openAPIProbes(t, "/spec.json")
+
+	results := Run(context.Background(), server.Client(), server.URL, probes, RunOptions{ProbeTimeout: 50 * time.Millisecond})
+	if res := results[0]; res.Skipped || res.Error != "probe timed out" {
+		t.Fatalf("result = %+v", res)
+	}
+}
@@ -0,0 +1,184 @@
+package detect
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/redact"
+	"skyline-mcp/internal/spec"
+)
+
+// Found is a probe that found a spec, as /detect reports it.
+type Found struct {
+	Type   string `json:"type"`
+	URL    string `json:"spec_url"`
+	Method string `json:"method"`
+	Status int    `json:"status"`
+}
+
+// Suggestion is an API config built from the probes of a detection run.
+type Suggestion struct {
+	API  config.APIConfig
+	Type string
+	// Operations is the number of operations the spec loaded with; 0 when
+	// it was not loaded.
+	Operations int
+	Warnings   []string
+}
+
+// Suggest picks the spec of found to configure an API for baseURL:
+// preferType first if given, otherwise the first probe in found. An
+// empty name is derived from the host; auth may be nil.
+func Suggest(baseURL string, found []Found, preferType, name string, auth *config.AuthConfig) (*Suggestion, error) {
+	baseURL = strings.TrimRight(strings.TrimSpace(baseURL), "/")
+	if len(found) == 0 {
+		return nil, fmt.Errorf("no probe found a spec at %s", baseURL)
+	}
+	pick := -1
+	for i, f := range found {
+		if preferType == "" || f.Type == preferType {
+			pick = i
+			break
+		}
+	}
+	if pick < 0 {
+		return nil, fmt.Errorf("no probe found a %s spec", preferType)
+	}
+	if name == "" {
+		name = SuggestName(baseURL)
+	}
+	f := found[pick]
+	s := &Suggestion{
+		Type: f.Type,
+		API:  config.APIConfig{Name: name, SpecURL: f.URL, Auth: auth},
+	}
+	specPath := ""
+	if u, err := url.Parse(f.URL); err == nil {
+		specPath = u.Path
+	}
+
+	switch f.Type {
+	case "graphql":
+		suggestGraphQL(s, baseURL, found, f, specPath)
+	case "odata":
+		s.API.BaseURLOverride = strings.TrimSuffix(strings.TrimRight(f.URL, "/"), "/$metadata")
+	case "ckan":
+		s.API.BaseURLOverride = baseURL
+	case "jira-rest":
+		// The curated jira type works for Cloud, Server and Data Center.
+		s.API.SpecType, s.API.SpecURL, s.API.BaseURLOverride = "jira", "", baseURL
+	case "prometheus":
+		s.API.SpecType, s.API.SpecURL, s.API.BaseURLOverride = "prometheus", "", baseURL
+	case "openrpc":
+		if f.Method == http.MethodPost {
+			s.Warnings = append(s.Warnings, "the OpenRPC document is only served by rpc.discover; save the result to a file and set spec_file to it")
+		}
+	}
+	if f.Status == http.StatusUnauthorized && auth == nil {
+		s.Warnings = append(s.Warnings, "the spec needs credentials; add auth before loading it")
+	}
+	return s, nil
+}
+
+// suggestGraphQL points spec_url at the endpoint. When only a schema
+// file was found, it is pinned with graphql.schema, since the server may
+// not answer introspection.
+func suggestGraphQL(s *Suggestion, baseURL string, found []Found, f Found, specPath string) {
+	for _, other := range found {
+		if other.Type == "graphql" && other.Method == http.MethodPost {
+			s.API.SpecURL = other.URL
+			return
+		}
+	}
+	if f.Method == http.MethodPost {
+		return
+	}
+	endpoint := baseURL
+	if strings.HasPrefix(specPath, "/graphql/") || !strings.Contains(strings.ToLower(baseURL), "graphql") {
+		endpoint = baseURL + "/graphql"
+	}
+	s.API.SpecURL = endpoint
+	s.API.GraphQL = &config.GraphQLConfig{Schema: f.URL}
+	s.Warnings = append(s.Warnings, fmt.Sprintf("only the schema file was found; check that %s is the GraphQL endpoint", endpoint))
+}
+
+var nameCleanRe = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// SuggestName derives an API name from the host of baseURL, e.g. "github"
+// for https://api.github.com.
+func SuggestName(baseURL string) string {
+	host := strings.ToLower(hostOf(baseURL))
+	if host == "" || net.ParseIP(host) != nil {
+		return "api"
+	}
+	labels := strings.Split(host, ".")
+	if len(labels) > 1 {
+		labels = labels[:len(labels)-1] // the TLD
+	}
+	for _, label := range labels {
+		switch label {
+		case "api", "www", "rest", "graphql", "gql":
+			continue
+		}
+		if name := strings.Trim(nameCleanRe.ReplaceAllString(label, "_"), "_"); name != "" {
+			if name == config.BuiltinServiceName || name == config.WorkflowServiceName {
+				return name + "_api"
+			}
+			return name
+		}
+	}
+	return "api"
+}
+
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+// Verify loads the suggested API the way a profile would. When the spec
+// gives no base URL, or a relative one, base_url_override is set from
+// baseURL. A spec that fails to load adds a warning rather than an error,
+// so the suggestion can still be edited.
+func Verify(ctx context.Context, s *Suggestion, baseURL string, logger *slog.Logger, redactor *redact.Redactor) {
+	cfg := &config.Config{APIs: []config.APIConfig{s.API}}
+	services, loadErrs, err := spec.LoadServicesWithErrors(ctx, cfg, logger, redactor)
+	if len(loadErrs) > 0 {
+		s.Warnings = append(s.Warnings, "spec did not load: "+loadErrs[0].Error)
+		return
+	}
+	if err != nil {
+		s.Warnings = append(s.Warnings, "spec did not load: "+err.Error())
+		return
+	}
+	var svc *canonical.Service
+	for _, loaded := range services {
+		if loaded.Name == s.API.Name {
+			svc = loaded
+		}
+	}
+	if svc == nil {
+		return
+	}
+	s.Operations = len(svc.Operations)
+	if s.API.BaseURLOverride != "" {
+		return
+	}
+	base := strings.TrimRight(strings.TrimSpace(baseURL), "/")
+	switch {
+	case svc.BaseURL == "":
+		s.API.BaseURLOverride = base
+	case strings.HasPrefix(svc.BaseURL, "/"):
+		s.API.BaseURLOverride = base + strings.TrimRight(svc.BaseURL, "/")
+	}
+}
@@ -0,0 +1,157 @@
+package detect
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/logging"
+	"skyline-mcp/internal/redact"
+)
+
+func TestSuggest(t *testing.T) {
+	const base = "https://api.example.com"
+	tests := []struct {
+		name       string
+		found      []Found
+		preferType string
+		wantSpec   string
+		wantBase   string
+		wantSchema string
+		wantWarn   string
+	}{
+		{
+			name:     "first found",
+			found:    []Found{{Type: "openapi", URL: base + "/openapi.json", Method: "GET", Status: 200}, {Type: "swagger2", URL: base + "/swagger.json", Method: "GET", Status: 200}},
+			wantSpec: base + "/openapi.json",
+		},
+		{
+			name:       "preferred type",
+			found:      []Found{{Type: "openapi", URL: base + "/openapi.json", Method: "GET"}, {Type: "swagger2", URL: base + "/swagger.json", Method: "GET"}},
+			preferType: "swagger2",
+			wantSpec:   base + "/swagger.json",
+		},
+		{
+			name:     "odata service root",
+			found:    []Found{{Type: "odata", URL: base + "/odata/$metadata", Method: "GET"}},
+			wantSpec: base + "/odata/$metadata",
+			wantBase: base + "/odata",
+		},
+		{
+			name:     "graphql endpoint over schema file",
+			found:    []Found{{Type: "graphql", URL: base + "/graphql/schema", Method: "GET"}, {Type: "graphql", URL: base + "/api/graphql", Method: "POST"}},
+			wantSpec: base + "/api/graphql",
+		},
+		{
+			name:       "graphql schema file only",
+			found:      []Found{{Type: "graphql", URL: base + "/schema.graphql", Method: "GET"}},
+			wantSpec:   base + "/graphql",
+			wantSchema: base + "/schema.graphql",
+			wantWarn:   "GraphQL endpoint",
+		},
+		{
+			name:     "openrpc discover",
+			found:    []Found{{Type: "openrpc", URL: base + "/rpc", Method: "POST"}},
+			wantSpec: base + "/rpc",
+			wantWarn: "spec_file",
+		},
+		{
+			name:     "jira curated type",
+			found:    []Found{{Type: "jira-rest", URL: base + "/rest/api/3/serverInfo", Method: "GET"}},
+			wantBase: base,
+		},
+		{
+			name:     "prometheus curated type",
+			found:    []Found{{Type: "prometheus", URL: base + "/api/v1/status/buildinfo", Method: "GET"}},
+			wantBase: base,
+		},
+		{
+			name:     "unauthorized",
+			found:    []Found{{Type: "openapi", URL: base + "/openapi/v3", Method: "GET", Status: 401}},
+			wantSpec: base + "/openapi/v3",
+			wantWarn: "credentials",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Suggest(base+"/", tt.found, tt.preferType, "", nil)
+			if err != nil {
+				t.Fatalf("Suggest: %v", err)
+			}
+			if s.API.Name != "example" || s.API.SpecURL != tt.wantSpec || s.API.BaseURLOverride != tt.wantBase {
+				t.Fatalf("api = %+v", s.API)
+			}
+			schema := ""
+			if s.API.GraphQL != nil {
+				schema = s.API.GraphQL.Schema
+			}
+			if schema != tt.wantSchema {
+				t.Fatalf("graphql.schema = %q, want %q", schema, tt.wantSchema)
+			}
+			warnings := strings.Join(s.Warnings, "\n")
+			if (tt.wantWarn == "") != (warnings == "") || !strings.Contains(warnings, tt.wantWarn) {
+				t.Fatalf("warnings = %q, want %q", warnings, tt.wantWarn)
+			}
+			cfg := config.Config{APIs: []config.APIConfig{s.API}}
+			if err := cfg.Validate(); err != nil {
+				t.Fatalf("suggested config is invalid: %v", err)
+			}
+		})
+	}
+}
+
+func TestSuggestErrors(t *testing.T) {
+	if _, err := Suggest("https://api.example.com", nil, "", "", nil); err == nil {
+		t.Fatal("expected an error when nothing was found")
+	}
+	found := []Found{{Type: "openapi", URL: "https://api.example.com/openapi.json"}}
+	if _, err := Suggest("https://api.example.com", found, "graphql", "", nil); err == nil || !strings.Contains(err.Error(), "graphql") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestSuggestName(t *testing.T) {
+	tests := map[string]string{
+		"https://api.github.com":         "github",
+		"https://petstore3.swagger.io/":  "petstore3",
+		"http://localhost:8080":          "localhost",
+		"http://10.0.0.5:3000":           "api",
+		"https://my-service.example.com": "my_service",
+		"https://skyline.internal":       "skyline_api",
+	}
+	for in, want := range tests {
+		if got := SuggestName(in); got != want {
+			t.Errorf("SuggestName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/openapi.json" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"openapi":"3.0.0","info":{"title":"x","version":"1"},"servers":[{"url":"/v1"}],
+			"paths":{"/pets":{"get":{"operationId":"listPets","responses":{"200":{"description":"ok"}}}}}}`))
+	}))
+	defer server.Close()
+
+	s := &Suggestion{API: config.APIConfig{Name: "pets", SpecURL: server.URL + "/openapi.json"}}
+	Verify(context.Background(), s, server.URL, logging.Discard(), redact.NewRedactor())
+	if len(s.Warnings) > 0 || s.Operations != 1 {
+		t.Fatalf("suggestion = %+v", s)
+	}
+	if s.API.BaseURLOverride != server.URL+"/v1" {
+		t.Fatalf("base_url_override = %q", s.API.BaseURLOverride)
+	}
+
+	s = &Suggestion{API: config.APIConfig{Name: "pets", SpecURL: server.URL + "/missing.json"}}
+	Verify(context.Background(), s, server.URL, logging.Discard(), redact.NewRedactor())
+	if len(s.Warnings) != 1 || !strings.Contains(s.Warnings[0], "did not load") || s.API.BaseURLOverride != "" {
+		t.Fatalf("suggestion = %+v", s)
+	}
+}
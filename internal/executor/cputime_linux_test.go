@@ -0,0 +1,20 @@
+package executor
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestProcessCPUTime(t *testing.T) {
+	deadline := time.Now().Add(50 * time.Millisecond)
+	for time.Now().Before(deadline) {
+	}
+	used, err := processCPUTime(os.Getpid())
+	if err != nil {
+		t.Fatalf("processCPUTime: %v", err)
+	}
+	if used <= 0 {
+		t.Fatalf("expected some CPU time, got %s", used)
+	}
+}
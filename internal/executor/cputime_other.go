@@ -0,0 +1,14 @@
+//go:build !linux
+
+package executor
+
+import (
+	"errors"
+	"time"
+)
+
+// processCPUTime is not available on this platform; CPU limits of Deno
+// scripts are enforced after they exit.
+func processCPUTime(int) (time.Duration, error) {
+	return 0, errors.New("process CPU time not available")
+}
@@ -0,0 +1,256 @@
+package executor
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// denoPrelude defines the host functions of the generated client on top of
+// a loopback bridge. Deno's fetch is limited by --allow-net to the bridge
+// and the allowed hosts, and the script gets no file, env or subprocess
+// access.
+const denoPrelude = `(() => {
+  const bridge = async (path, payload) => {
+    const resp = await fetch(%q + path, {
+      method: "POST",
+      headers: { "Content-Type": "application/json", "Authorization": "Bearer " + %q },
+      body: JSON.stringify(payload),
+    });
+    const out = await resp.json();
+    if (out.error) throw new Error(out.error);
+    return out.data;
+  };
+  globalThis.__callMCPTool = (toolName, argsJSON) => bridge("/call-tool", { toolName, args: JSON.parse(argsJSON) });
+  globalThis.__searchTools = (query, detail) => bridge("/search-tools", { query, detail });
+  globalThis.__interfaces = %s;
+})();
+`
+
+// executeDeno runs bundled JavaScript in a deno subprocess. Tool calls come
+// back through a bridge server on 127.0.0.1 that lives for this execution
+// and only accepts requests bearing its one-time token.
+func (e *Executor) executeDeno(ctx context.Context, js string, timeout time.Duration) (*ExecuteResult, error) {
+	bridge, err := e.startBridge(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer bridge.close()
+
+	interfaces, _ := json.Marshal(e.interfaces)
+	if e.interfaces == nil {
+		interfaces = []byte("[]")
+	}
+	script := fmt.Sprintf(denoPrelude, bridge.url, bridge.token, interfaces) + js
+
+	scriptFile, err := os.CreateTemp(e.workspaceDir, "user_code_*.js")
+	if err != nil {
+		return nil, fmt.Errorf("create script file: %w", err)
+	}
+	defer os.Remove(scriptFile.Name())
+	_, err = scriptFile.WriteString(script)
+	if closeErr := scriptFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("write script file: %w", err)
+	}
+
+	allowNet := append([]string{bridge.addr}, e.opts.AllowedHosts...)
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	cmd := exec.CommandContext(runCtx, e.denoPath, "run",
+		"--quiet",
+		"--no-prompt",
+		"--no-config",
+		"--no-remote",
+		"--allow-net="+strings.Join(allowNet, ","),
+		"--v8-flags=--max-old-space-size="+strconv.FormatInt(e.opts.MemoryLimit>>20, 10),
+		scriptFile.Name(),
+	)
+	cmd.Dir = e.workspaceDir
+	cmd.Env = []string{
+		"NO_COLOR=1",
+		"DENO_NO_UPDATE_CHECK=1",
+		"DENO_DIR=" + filepath.Join(e.workspaceDir, ".deno"),
+		"PATH=" + os.Getenv("PATH"),
+	}
+	stdout := &cappedBuffer{max: e.opts.MaxOutput}
+	stderr := &cappedBuffer{max: e.opts.MaxOutput}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.WaitDelay = time.Second
+
+	startTime := time.Now()
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("run deno: %w", err)
+	}
+	var cpuExceeded atomic.Bool
+	stopWatch := watchProcessCPU(cmd.Process, e.opts.CPUTime, &cpuExceeded)
+	runErr := cmd.Wait()
+	stopWatch()
+	if e.opts.CPUTime > 0 && cmd.ProcessState != nil && cmd.ProcessState.UserTime()+cmd.ProcessState.SystemTime() > e.opts.CPUTime {
+		cpuExceeded.Store(true)
+	}
+	result := &ExecuteResult{
+		Stdout:        stdout.String(),
+		Stderr:        stderr.String(),
+		ExecutionTime: time.Since(startTime).Seconds(),
+		ToolsCalled:   bridge.toolsCalled(),
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case cpuExceeded.Load():
+		result.Error = fmt.Sprintf("cpu time limit of %s exceeded", e.opts.CPUTime)
+		result.ExitCode = 152
+	case runErr == nil:
+	case errors.Is(runCtx.Err(), context.DeadlineExceeded):
+		result.Error = fmt.Sprintf("execution timeout after %s", timeout)
+		result.ExitCode = 124
+	case errors.As(runErr, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+		if result.ExitCode < 0 {
+			result.ExitCode = 1
+		}
+		result.Error = fmt.Sprintf("script exited with code %d", result.ExitCode)
+	default:
+		return nil, fmt.Errorf("run deno: %w", runErr)
+	}
+	return result, nil
+}
+
+// watchProcessCPU kills proc once its CPU time passes limit, where the
+// platform can report it while the process runs (see processCPUTime).
+// Elsewhere the limit is checked after exit only.
+func watchProcessCPU(proc *os.Process, limit time.Duration, exceeded *atomic.Bool) func() {
+	if limit <= 0 {
+		return func() {}
+	}
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(50 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				used, err := processCPUTime(proc.Pid)
+				if err != nil {
+					return
+				}
+				if used > limit {
+					exceeded.Store(true)
+					_ = proc.Kill()
+					return
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+type denoBridge struct {
+	addr   string
+	url    string
+	token  string
+	server *http.Server
+
+	mu    sync.Mutex
+	tools []string
+}
+
+func (e *Executor) startBridge(ctx context.Context) (*denoBridge, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("start tool bridge: %w", err)
+	}
+	tokenBytes := make([]byte, 16)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("generate bridge token: %w", err)
+	}
+	b := &denoBridge{
+		addr:  ln.Addr().String(),
+		url:   "http://" + ln.Addr().String(),
+		token: hex.EncodeToString(tokenBytes),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/call-tool", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ToolName string         `json:"toolName"`
+			Args     map[string]any `json:"args"`
+		}
+		if !b.decode(w, r, &req) {
+			return
+		}
+		b.mu.Lock()
+		b.tools = append(b.tools, req.ToolName)
+		b.mu.Unlock()
+		data, err := e.callTool(ctx, req.ToolName, req.Args)
+		writeBridgeResult(w, data, err)
+	})
+	mux.HandleFunc("/search-tools", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Query  string `json:"query"`
+			Detail string `json:"detail"`
+		}
+		if !b.decode(w, r, &req) {
+			return
+		}
+		if req.Detail == "" {
+			req.Detail = "name-and-description"
+		}
+		data, err := e.httpSearchTools(ctx, req.Query, req.Detail)
+		writeBridgeResult(w, data, err)
+	})
+	b.server = &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go func() { _ = b.server.Serve(ln) }()
+	return b, nil
+}
+
+func (b *denoBridge) decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	if r.Method != http.MethodPost || r.Header.Get("Authorization") != "Bearer "+b.token {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return false
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeBridgeResult(w, nil, fmt.Errorf("invalid request: %w", err))
+		return false
+	}
+	return true
+}
+
+func writeBridgeResult(w http.ResponseWriter, data any, err error) {
+	result := ToolCallResult{Data: data}
+	if err != nil {
+		result = ToolCallResult{Error: err.Error()}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+func (b *denoBridge) toolsCalled() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string(nil), b.tools...)
+}
+
+func (b *denoBridge) close() {
+	_ = b.server.Close()
+}
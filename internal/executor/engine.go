@@ -0,0 +1,148 @@
+package executor
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Sandbox engines.
+const (
+	EngineAuto = "auto" // Deno when installed, goja otherwise
+	EngineDeno = "deno"
+	EngineGoja = "goja"
+)
+
+// Default sandbox limits.
+const (
+	DefaultTimeout     = 30 * time.Second
+	DefaultMemoryLimit = 512 << 20
+	DefaultMaxOutput   = 1 << 20
+)
+
+// Options configures the code execution sandbox.
+type Options struct {
+	// Engine is EngineAuto (default), EngineDeno or EngineGoja. Deno is the
+	// preferred sandbox; goja is embedded and always available, so a missing
+	// Deno binary falls back to goja instead of disabling code execution.
+	Engine string
+	// DenoPath is the deno binary. Default: "deno" looked up on PATH.
+	DenoPath string
+	// Timeout is the wall-clock limit of a script that sets none.
+	Timeout time.Duration
+	// MemoryLimit caps the script's heap, in bytes. Deno enforces it per
+	// script. goja cannot account for one VM's allocations, so it checks
+	// the growth of the process heap, which every running goja script
+	// shares; see maxGojaScripts.
+	MemoryLimit int64
+	// MaxOutput caps the captured stdout and stderr, in bytes each.
+	MaxOutput int
+	// CPUTime caps the time the script spends computing; time waiting on
+	// tool calls does not count. 0 leaves only the wall-clock Timeout.
+	CPUTime time.Duration
+	// AllowedHosts lists the hosts scripts may fetch from ("api.example.com",
+	// "api.example.com:8443" or "*.example.com"). Empty: no network access
+	// beyond tool calls.
+	AllowedHosts []string
+}
+
+func (o Options) withDefaults() Options {
+	if o.Engine == "" {
+		o.Engine = EngineAuto
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = DefaultTimeout
+	}
+	if o.MemoryLimit <= 0 {
+		o.MemoryLimit = DefaultMemoryLimit
+	}
+	if o.MaxOutput <= 0 {
+		o.MaxOutput = DefaultMaxOutput
+	}
+	return o
+}
+
+// SetOptions applies sandbox options and selects the engine. Deno is used
+// when requested or preferred and its binary is found; otherwise scripts run
+// in goja. Engine reports the choice.
+func (e *Executor) SetOptions(opts Options) error {
+	opts = opts.withDefaults()
+	switch opts.Engine {
+	case EngineAuto, EngineDeno, EngineGoja:
+	default:
+		return fmt.Errorf("unknown code execution engine %q (want auto, deno or goja)", opts.Engine)
+	}
+	e.opts = opts
+	e.engine = EngineGoja
+	e.denoPath = ""
+	if opts.Engine != EngineGoja {
+		if path, err := findDeno(opts.DenoPath); err == nil {
+			e.engine = EngineDeno
+			e.denoPath = path
+		}
+	}
+	return nil
+}
+
+// Engine returns the engine scripts run in: EngineDeno or EngineGoja.
+func (e *Executor) Engine() string {
+	return e.engine
+}
+
+// hostAllowed reports whether a URL host (with optional port) matches the
+// allowlist.
+func hostAllowed(host string, allowed []string) bool {
+	hostname := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		hostname = h
+	}
+	for _, pattern := range allowed {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		switch {
+		case pattern == strings.ToLower(host), pattern == strings.ToLower(hostname):
+			return true
+		case strings.HasPrefix(pattern, "*.") && strings.HasSuffix(strings.ToLower(hostname), pattern[1:]):
+			return true
+		}
+	}
+	return false
+}
+
+func findDeno(path string) (string, error) {
+	if path == "" {
+		path = "deno"
+	}
+	return exec.LookPath(path)
+}
+
+// cappedBuffer keeps the first max bytes written to it and drops the rest.
+type cappedBuffer struct {
+	buf       bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.buf.Len(); room < len(p) {
+		b.truncated = true
+		if room > 0 {
+			b.buf.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+func (b *cappedBuffer) WriteString(s string) (int, error) {
+	return b.Write([]byte(s))
+}
+
+func (b *cappedBuffer) String() string {
+	if b.truncated {
+		return b.buf.String() + "\n[output truncated]\n"
+	}
+	return b.buf.String()
+}
@@ -4,12 +4,16 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"runtime/metrics"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/dop251/goja"
@@ -33,19 +37,26 @@ type ExecuteResult struct {
 	Error         string   `json:"error,omitempty"`
 }
 
-// Executor runs user code in a sandboxed goja JavaScript runtime
+// Executor runs user code in a sandbox: Deno when available, otherwise the
+// embedded goja JavaScript runtime.
 type Executor struct {
 	workspaceDir string
 	mcpEndpoint  string
 	interfaces   []string
 	callToolFn   func(ctx context.Context, toolName string, args map[string]any) (any, error)
+	opts         Options
+	engine       string
+	denoPath     string
 }
 
-// NewExecutor creates a new code executor
+// NewExecutor creates a new code executor. It runs scripts in goja with
+// default limits until SetOptions selects an engine.
 func NewExecutor(workspaceDir, mcpEndpoint string) *Executor {
 	return &Executor{
 		workspaceDir: workspaceDir,
 		mcpEndpoint:  mcpEndpoint,
+		opts:         Options{Engine: EngineGoja}.withDefaults(),
+		engine:       EngineGoja,
 	}
 }
 
@@ -88,12 +99,14 @@ func transpileAndBundle(entryPoint string) (string, error) {
 	return string(result.OutputFiles[0].Contents), nil
 }
 
-// Execute runs user code with security constraints using goja.
-// TypeScript is transpiled via esbuild, then executed in a sandboxed goja VM
-// with only console, callMCPTool, searchTools, and restricted fetch available.
+// Execute runs user code with security constraints. TypeScript is
+// transpiled and bundled via esbuild, then run in Deno when available (see
+// SetOptions) or in a sandboxed goja VM. Either way the script only reaches
+// the tools: console, callMCPTool and searchTools are the whole API surface,
+// plus fetch to the hosts in Options.AllowedHosts.
 func (e *Executor) Execute(ctx context.Context, req ExecuteRequest) (*ExecuteResult, error) {
-	if req.Timeout == 0 {
-		req.Timeout = 30
+	if req.Timeout <= 0 {
+		req.Timeout = int(e.opts.Timeout / time.Second)
 	}
 
 	if req.Language != "typescript" && req.Language != "" {
@@ -103,20 +116,26 @@ func (e *Executor) Execute(ctx context.Context, req ExecuteRequest) (*ExecuteRes
 		}, nil
 	}
 
-	// Wrap user code in async IIFE for top-level await support.
-	// Even though our host functions are synchronous, user code may use
-	// await syntax which requires an async context.
-	wrappedCode := "(async () => {\n" + req.Code + "\n})();\n"
+	// Wrap user code in async IIFE for top-level await support. The promise
+	// is kept on globalThis so a rejection can be reported as the error.
+	wrappedCode := "(globalThis as any).__main = (async () => {\n" + req.Code + "\n})();\n"
 
 	// Write to temp file for esbuild bundling (resolves imports from workspace)
-	codeFile := filepath.Join(e.workspaceDir, "user_code.ts")
-	if err := os.WriteFile(codeFile, []byte(wrappedCode), 0600); err != nil {
+	codeFile, err := os.CreateTemp(e.workspaceDir, "user_code_*.ts")
+	if err != nil {
+		return nil, fmt.Errorf("create code file: %w", err)
+	}
+	defer os.Remove(codeFile.Name())
+	_, err = codeFile.WriteString(wrappedCode)
+	if closeErr := codeFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
 		return nil, fmt.Errorf("write code file: %w", err)
 	}
-	defer os.Remove(codeFile)
 
 	// Bundle with esbuild (resolves imports, transpiles TS→JS)
-	js, err := transpileAndBundle(codeFile)
+	js, err := transpileAndBundle(codeFile.Name())
 	if err != nil {
 		return &ExecuteResult{
 			Error:    fmt.Sprintf("transpile error: %v", err),
@@ -124,14 +143,63 @@ func (e *Executor) Execute(ctx context.Context, req ExecuteRequest) (*ExecuteRes
 		}, nil
 	}
 
-	// Create goja runtime (fresh VM per request — no shared state)
+	timeout := time.Duration(req.Timeout) * time.Second
+	if e.engine == EngineDeno {
+		return e.executeDeno(ctx, js, timeout)
+	}
+	return e.executeGoja(ctx, js, timeout), nil
+}
+
+// maxCallStackSize bounds recursion in the goja sandbox.
+const maxCallStackSize = 4096
+
+// Interrupt values for the goja VM.
+const (
+	interruptTimeout = "execution timeout"
+	interruptMemory  = "memory limit exceeded"
+	interruptCPU     = "cpu time limit exceeded"
+)
+
+// maxGojaScripts caps the goja scripts running at once in the process. The
+// memory limit is checked against the heap they share with each other and
+// the rest of the server, so a script can be stopped for memory another one
+// holds; the cap keeps the scripts sharing it few.
+const maxGojaScripts = 4
+
+var gojaSlots = make(chan struct{}, maxGojaScripts)
+
+// executeGoja runs bundled JavaScript in a fresh goja VM (no shared state)
+// with the wall-clock, heap and output limits of the executor's options.
+// Waiting for one of the maxGojaScripts slots counts against the timeout.
+func (e *Executor) executeGoja(ctx context.Context, js string, timeout time.Duration) *ExecuteResult {
+	deadline := time.Now().Add(timeout)
+	wait := time.NewTimer(timeout)
+	select {
+	case gojaSlots <- struct{}{}:
+		wait.Stop()
+	case <-wait.C:
+		return &ExecuteResult{
+			Error:    fmt.Sprintf("execution timeout after %s waiting for one of %d script slots", timeout, maxGojaScripts),
+			ExitCode: 124,
+		}
+	case <-ctx.Done():
+		wait.Stop()
+		return &ExecuteResult{Error: ctx.Err().Error(), ExitCode: 1}
+	}
+	defer func() { <-gojaSlots }()
+
 	vm := goja.New()
+	vm.SetMaxCallStackSize(maxCallStackSize)
 
-	var stdout, stderr bytes.Buffer
+	stdout := &cappedBuffer{max: e.opts.MaxOutput}
+	stderr := &cappedBuffer{max: e.opts.MaxOutput}
 	var toolsCalled []string
+	// Time spent in host calls, which does not count as script CPU time.
+	var hostTime atomic.Int64
+	host := func(start time.Time) { hostTime.Add(int64(time.Since(start))) }
 
 	// Register console.log/warn/error
-	registerConsole(vm, &stdout, &stderr)
+	registerConsole(vm, stdout, stderr)
 
 	// Register __callMCPTool (synchronous Go function called from JS)
 	_ = vm.Set("__callMCPTool", func(call goja.FunctionCall) goja.Value {
@@ -145,17 +213,11 @@ func (e *Executor) Execute(ctx context.Context, req ExecuteRequest) (*ExecuteRes
 			panic(vm.NewGoError(fmt.Errorf("invalid args JSON: %w", err)))
 		}
 
-		var result any
-		var callErr error
-
-		if e.callToolFn != nil {
-			result, callErr = e.callToolFn(ctx, toolName, args)
-		} else {
-			result, callErr = e.httpCallTool(ctx, toolName, args)
-		}
-
-		if callErr != nil {
-			panic(vm.NewGoError(callErr))
+		start := time.Now()
+		result, err := e.callTool(ctx, toolName, args)
+		host(start)
+		if err != nil {
+			panic(vm.NewGoError(err))
 		}
 
 		return vm.ToValue(result)
@@ -169,7 +231,9 @@ func (e *Executor) Execute(ctx context.Context, req ExecuteRequest) (*ExecuteRes
 			detail = call.Argument(1).String()
 		}
 
+		start := time.Now()
 		result, err := e.httpSearchTools(ctx, query, detail)
+		host(start)
 		if err != nil {
 			panic(vm.NewGoError(err))
 		}
@@ -180,20 +244,29 @@ func (e *Executor) Execute(ctx context.Context, req ExecuteRequest) (*ExecuteRes
 	// Set __interfaces
 	_ = vm.Set("__interfaces", e.interfaces)
 
-	// Register restricted fetch (localhost only)
-	registerFetch(vm, ctx)
+	// fetch exists only when the egress policy allows some hosts.
+	if len(e.opts.AllowedHosts) > 0 {
+		registerFetch(ctx, vm, e.opts.AllowedHosts, host)
+	}
 
 	// Set execution timeout via interrupt (runs in a separate goroutine)
-	timer := time.AfterFunc(time.Duration(req.Timeout)*time.Second, func() {
-		vm.Interrupt("execution timeout")
+	timer := time.AfterFunc(time.Until(deadline), func() {
+		vm.Interrupt(interruptTimeout)
 	})
 	defer timer.Stop()
-
 	// Execute the bundled JavaScript
 	startTime := time.Now()
+	stopWatch := watchLimits(vm, e.opts, startTime, &hostTime)
+	defer stopWatch()
 	_, runErr := vm.RunString(js)
 	executionTime := time.Since(startTime).Seconds()
 
+	if runErr == nil {
+		if p, ok := vm.Get("__main").Export().(*goja.Promise); ok && p.State() == goja.PromiseStateRejected {
+			runErr = fmt.Errorf("%v", p.Result())
+		}
+	}
+
 	result := &ExecuteResult{
 		Stdout:        stdout.String(),
 		Stderr:        stderr.String(),
@@ -202,57 +275,120 @@ func (e *Executor) Execute(ctx context.Context, req ExecuteRequest) (*ExecuteRes
 	}
 
 	if runErr != nil {
-		if strings.Contains(runErr.Error(), "execution timeout") {
-			result.Error = fmt.Sprintf("execution timeout after %ds", req.Timeout)
+		var interrupted *goja.InterruptedError
+		switch {
+		case errors.As(runErr, &interrupted) && interrupted.Value() == interruptTimeout:
+			result.Error = fmt.Sprintf("execution timeout after %s", timeout)
 			result.ExitCode = 124
-		} else {
+		case errors.As(runErr, &interrupted) && interrupted.Value() == interruptMemory:
+			result.Error = fmt.Sprintf("memory limit of %d MB exceeded", e.opts.MemoryLimit>>20)
+			result.ExitCode = 137
+		case errors.As(runErr, &interrupted) && interrupted.Value() == interruptCPU:
+			result.Error = fmt.Sprintf("cpu time limit of %s exceeded", e.opts.CPUTime)
+			result.ExitCode = 152
+		default:
 			result.Error = runErr.Error()
 			result.ExitCode = 1
 		}
 	}
 
-	return result, nil
+	return result
 }
 
-// registerConsole sets up console.log/warn/error on the goja runtime
-func registerConsole(vm *goja.Runtime, stdout, stderr *bytes.Buffer) {
-	console := vm.NewObject()
-	_ = console.Set("log", func(call goja.FunctionCall) goja.Value {
-		stdout.WriteString(formatJSArgs(call) + "\n")
-		return goja.Undefined()
-	})
-	_ = console.Set("warn", func(call goja.FunctionCall) goja.Value {
-		stderr.WriteString(formatJSArgs(call) + "\n")
-		return goja.Undefined()
-	})
-	_ = console.Set("error", func(call goja.FunctionCall) goja.Value {
-		stderr.WriteString(formatJSArgs(call) + "\n")
-		return goja.Undefined()
-	})
-	_ = vm.Set("console", console)
+// watchLimits interrupts vm when the script exceeds its CPU time or heap
+// limit. CPU time is the time since start minus the time spent in host
+// calls (goja runs the script on one goroutine). The returned func stops
+// the watch.
+func watchLimits(vm *goja.Runtime, opts Options, start time.Time, hostTime *atomic.Int64) func() {
+	stopMemory := WatchMemory(vm, opts.MemoryLimit, interruptMemory)
+	if opts.CPUTime <= 0 {
+		return stopMemory
+	}
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(20 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if time.Since(start)-time.Duration(hostTime.Load()) > opts.CPUTime {
+					vm.Interrupt(interruptCPU)
+					return
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		stopMemory()
+	}
+}
+
+// WatchMemory interrupts vm with value when the Go heap, where goja objects
+// live, grows by more than limit bytes while the VM runs. Growth is read
+// from the live heap measured by the last garbage collection; collections
+// happen as the heap grows, so the watch never forces one. The heap is the
+// process's, so the growth includes what other goroutines hold. The
+// returned func stops the watch.
+func WatchMemory(vm *goja.Runtime, limit int64, value any) func() {
+	sample := []metrics.Sample{{Name: "/gc/heap/live:bytes"}}
+	heap := func() int64 {
+		metrics.Read(sample)
+		return int64(sample[0].Value.Uint64())
+	}
+	base := heap()
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(20 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if heap()-base > limit {
+					vm.Interrupt(value)
+					return
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
 }
 
-// registerFetch sets up a restricted fetch function (localhost only).
-// Returns a synchronous response object with .text() and .json() methods.
-func registerFetch(vm *goja.Runtime, ctx context.Context) {
+// registerFetch sets up a fetch restricted to the allowed hosts, including
+// redirect targets. It returns a synchronous response object with .text()
+// and .json() methods.
+func registerFetch(ctx context.Context, vm *goja.Runtime, allowed []string, host func(time.Time)) {
+	client := &http.Client{
+		Timeout: 30 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if !hostAllowed(req.URL.Host, allowed) {
+				return fmt.Errorf("fetch: redirect to %s is not allowed", req.URL.Host)
+			}
+			if len(via) >= 10 {
+				return fmt.Errorf("fetch: too many redirects")
+			}
+			return nil
+		},
+	}
 	_ = vm.Set("fetch", func(call goja.FunctionCall) goja.Value {
-		url := call.Argument(0).String()
-
-		// Security: restrict to localhost only
-		if !strings.HasPrefix(url, "http://localhost") &&
-			!strings.HasPrefix(url, "http://127.0.0.1") &&
-			!strings.HasPrefix(url, "https://localhost") &&
-			!strings.HasPrefix(url, "https://127.0.0.1") {
-			panic(vm.NewGoError(fmt.Errorf("fetch restricted to localhost, got: %s", url)))
+		rawURL := call.Argument(0).String()
+		u, err := url.Parse(rawURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			panic(vm.NewGoError(fmt.Errorf("fetch: invalid URL %q", rawURL)))
+		}
+		if !hostAllowed(u.Host, allowed) {
+			panic(vm.NewGoError(fmt.Errorf("fetch: host %s is not allowed", u.Host)))
 		}
 
 		method := "GET"
 		var body io.Reader
 		headers := make(map[string]string)
-
 		if len(call.Arguments) > 1 && !goja.IsUndefined(call.Argument(1)) {
-			opts := call.Argument(1).Export()
-			if optsMap, ok := opts.(map[string]any); ok {
+			if optsMap, ok := call.Argument(1).Export().(map[string]any); ok {
 				if m, ok := optsMap["method"].(string); ok {
 					method = strings.ToUpper(m)
 				}
@@ -269,7 +405,7 @@ func registerFetch(vm *goja.Runtime, ctx context.Context) {
 			}
 		}
 
-		req, err := http.NewRequestWithContext(ctx, method, url, body)
+		req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
 		if err != nil {
 			panic(vm.NewGoError(err))
 		}
@@ -277,20 +413,22 @@ func registerFetch(vm *goja.Runtime, ctx context.Context) {
 			req.Header.Set(k, v)
 		}
 
-		resp, err := http.DefaultClient.Do(req)
+		start := time.Now()
+		resp, err := client.Do(req)
 		if err != nil {
+			host(start)
 			panic(vm.NewGoError(err))
 		}
-		defer resp.Body.Close()
 		respBody, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		host(start)
 
 		respObj := vm.NewObject()
 		_ = respObj.Set("ok", resp.StatusCode >= 200 && resp.StatusCode < 300)
 		_ = respObj.Set("status", resp.StatusCode)
 		_ = respObj.Set("statusText", http.StatusText(resp.StatusCode))
-		bodyStr := string(respBody)
 		_ = respObj.Set("text", func(goja.FunctionCall) goja.Value {
-			return vm.ToValue(bodyStr)
+			return vm.ToValue(string(respBody))
 		})
 		_ = respObj.Set("json", func(goja.FunctionCall) goja.Value {
 			var v any
@@ -299,11 +437,36 @@ func registerFetch(vm *goja.Runtime, ctx context.Context) {
 			}
 			return vm.ToValue(v)
 		})
-
 		return respObj
 	})
 }
 
+// callTool calls a tool directly when a call func is set, else over HTTP.
+func (e *Executor) callTool(ctx context.Context, toolName string, args map[string]any) (any, error) {
+	if e.callToolFn != nil {
+		return e.callToolFn(ctx, toolName, args)
+	}
+	return e.httpCallTool(ctx, toolName, args)
+}
+
+// registerConsole sets up console.log/warn/error on the goja runtime
+func registerConsole(vm *goja.Runtime, stdout, stderr *cappedBuffer) {
+	console := vm.NewObject()
+	_ = console.Set("log", func(call goja.FunctionCall) goja.Value {
+		_, _ = stdout.WriteString(formatJSArgs(call) + "\n")
+		return goja.Undefined()
+	})
+	_ = console.Set("warn", func(call goja.FunctionCall) goja.Value {
+		_, _ = stderr.WriteString(formatJSArgs(call) + "\n")
+		return goja.Undefined()
+	})
+	_ = console.Set("error", func(call goja.FunctionCall) goja.Value {
+		_, _ = stderr.WriteString(formatJSArgs(call) + "\n")
+		return goja.Undefined()
+	})
+	_ = vm.Set("console", console)
+}
+
 // formatJSArgs formats goja function call arguments for console output
 func formatJSArgs(call goja.FunctionCall) string {
 	parts := make([]string, len(call.Arguments))
@@ -417,8 +580,9 @@ func (e *Executor) SetupWorkspace(serviceFiles map[string]map[string]string) err
 	return nil
 }
 
-// ValidateRuntime checks if the execution runtime is available.
-// Always returns nil since goja is embedded in the binary.
+// ValidateRuntime checks if an execution runtime is available.
+// Always returns nil since goja is embedded in the binary and serves as the
+// fallback when Deno is not installed.
 func ValidateRuntime() error {
 	return nil
 }
@@ -0,0 +1,218 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"runtime/metrics"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestExecutor(t *testing.T, opts Options) *Executor {
+	t.Helper()
+	dir := t.TempDir()
+	exec := NewExecutor(dir, "http://127.0.0.1:1/internal/call-tool")
+	if err := exec.SetupWorkspace(map[string]map[string]string{}); err != nil {
+		t.Fatal(err)
+	}
+	opts.Engine = EngineGoja
+	if err := exec.SetOptions(opts); err != nil {
+		t.Fatal(err)
+	}
+	exec.SetDirectCallFunc(func(_ context.Context, toolName string, args map[string]any) (any, error) {
+		if toolName == "api__fail" {
+			return nil, fmt.Errorf("upstream said no")
+		}
+		return map[string]any{"tool": toolName, "items": []any{args["n"], 2, 3}}, nil
+	})
+	return exec
+}
+
+func TestGojaExecuteCallsTools(t *testing.T) {
+	exec := newTestExecutor(t, Options{})
+	res, err := exec.Execute(context.Background(), ExecuteRequest{Code: `
+const r = await (globalThis as any).__callMCPTool("api__list", JSON.stringify({ n: 1 }));
+console.log(r.items.reduce((a: number, b: number) => a + b, 0));
+console.warn(typeof fetch);
+`})
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if res.Error != "" || res.ExitCode != 0 {
+		t.Fatalf("unexpected error: %s", res.Error)
+	}
+	if strings.TrimSpace(res.Stdout) != "6" {
+		t.Fatalf("stdout = %q", res.Stdout)
+	}
+	if strings.TrimSpace(res.Stderr) != "undefined" {
+		t.Fatalf("expected no fetch in the sandbox, got %q", res.Stderr)
+	}
+	if len(res.ToolsCalled) != 1 || res.ToolsCalled[0] != "api__list" {
+		t.Fatalf("tools called = %v", res.ToolsCalled)
+	}
+}
+
+func TestGojaExecuteReportsRejection(t *testing.T) {
+	exec := newTestExecutor(t, Options{})
+	res, err := exec.Execute(context.Background(), ExecuteRequest{Code: `await (globalThis as any).__callMCPTool("api__fail", "{}");`})
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if res.ExitCode != 1 || !strings.Contains(res.Error, "upstream said no") {
+		t.Fatalf("expected the tool error, got %d %q", res.ExitCode, res.Error)
+	}
+}
+
+func TestGojaExecuteLimits(t *testing.T) {
+	exec := newTestExecutor(t, Options{Timeout: time.Second, MemoryLimit: 64 << 20, MaxOutput: 64})
+
+	res, err := exec.Execute(context.Background(), ExecuteRequest{Code: `while (true) {}`})
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if res.ExitCode != 124 || !strings.Contains(res.Error, "timeout") {
+		t.Fatalf("expected timeout, got %d %q", res.ExitCode, res.Error)
+	}
+
+	res, err = exec.Execute(context.Background(), ExecuteRequest{Timeout: 20, Code: `
+const hog: number[][] = [];
+while (true) { hog.push(new Array(100000).fill(1)); }
+`})
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if res.ExitCode != 137 || !strings.Contains(res.Error, "memory limit") {
+		t.Fatalf("expected memory limit, got %d %q", res.ExitCode, res.Error)
+	}
+
+	res, err = exec.Execute(context.Background(), ExecuteRequest{Code: `for (let i = 0; i < 100; i++) console.log("line " + i);`})
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if !strings.HasSuffix(res.Stdout, "[output truncated]\n") || len(res.Stdout) > 100 {
+		t.Fatalf("expected truncated output, got %q", res.Stdout)
+	}
+}
+
+func TestGojaScriptSlots(t *testing.T) {
+	exec := newTestExecutor(t, Options{})
+	for i := 0; i < maxGojaScripts; i++ {
+		gojaSlots <- struct{}{}
+	}
+	res, err := exec.Execute(context.Background(), ExecuteRequest{Timeout: 1, Code: `console.log("ran");`})
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if res.ExitCode != 124 || !strings.Contains(res.Error, "waiting for one of 4 script slots") || res.Stdout != "" {
+		t.Errorf("with every slot taken: %d %q %q", res.ExitCode, res.Stdout, res.Error)
+	}
+
+	// A script runs once a slot frees up.
+	time.AfterFunc(100*time.Millisecond, func() { <-gojaSlots })
+	res, err = exec.Execute(context.Background(), ExecuteRequest{Timeout: 5, Code: `console.log("ran");`})
+	for i := 1; i < maxGojaScripts; i++ {
+		<-gojaSlots
+	}
+	if err != nil || res.ExitCode != 0 || res.Stdout != "ran\n" {
+		t.Errorf("after a slot freed up: %+v %v", res, err)
+	}
+}
+
+func TestGojaGarbageIsNotCounted(t *testing.T) {
+	exec := newTestExecutor(t, Options{MemoryLimit: 16 << 20})
+	forced := []metrics.Sample{{Name: "/gc/cycles/forced:gc-cycles"}}
+	metrics.Read(forced)
+	before := forced[0].Value.Uint64()
+
+	// Far more than the limit is allocated, but little of it at a time.
+	res, err := exec.Execute(context.Background(), ExecuteRequest{Code: `
+let total = 0;
+for (let i = 0; i < 40; i++) { total += new Array(100000).fill(1).length; }
+console.log(total);
+`})
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if res.ExitCode != 0 || res.Stdout != "4000000\n" {
+		t.Fatalf("got %d %q %q", res.ExitCode, res.Stdout, res.Error)
+	}
+	metrics.Read(forced)
+	if n := forced[0].Value.Uint64() - before; n != 0 {
+		t.Errorf("the limit watch forced %d collections", n)
+	}
+}
+
+func TestSetOptions(t *testing.T) {
+	exec := NewExecutor(t.TempDir(), "")
+	if err := exec.SetOptions(Options{Engine: "python"}); err == nil {
+		t.Fatal("expected unknown engine error")
+	}
+	if err := exec.SetOptions(Options{Engine: EngineDeno, DenoPath: "/nonexistent/deno"}); err != nil {
+		t.Fatal(err)
+	}
+	if exec.Engine() != EngineGoja {
+		t.Fatalf("expected goja fallback, got %s", exec.Engine())
+	}
+}
+
+func TestGojaExecuteCPUTime(t *testing.T) {
+	exec := newTestExecutor(t, Options{CPUTime: 200 * time.Millisecond})
+	res, err := exec.Execute(context.Background(), ExecuteRequest{Timeout: 10, Code: `while (true) {}`})
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if res.ExitCode != 152 || !strings.Contains(res.Error, "cpu time") {
+		t.Fatalf("expected cpu time limit, got %d %q", res.ExitCode, res.Error)
+	}
+	if res.ExecutionTime > 5 {
+		t.Fatalf("cpu limit took %.1fs to trigger", res.ExecutionTime)
+	}
+}
+
+func TestGojaExecuteEgress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"ok":true}`)
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+
+	exec := newTestExecutor(t, Options{AllowedHosts: []string{u.Host}})
+	res, err := exec.Execute(context.Background(), ExecuteRequest{Code: fmt.Sprintf(`
+const r = fetch(%q);
+console.log(r.json().ok);
+fetch("http://example.invalid/");
+`, server.URL)})
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if strings.TrimSpace(res.Stdout) != "true" {
+		t.Fatalf("stdout = %q (error %q)", res.Stdout, res.Error)
+	}
+	if !strings.Contains(res.Error, "example.invalid is not allowed") {
+		t.Fatalf("expected egress denial, got %q", res.Error)
+	}
+}
+
+func TestHostAllowed(t *testing.T) {
+	allowed := []string{"api.example.com", "*.internal.example.com", "localhost:8443"}
+	for host, want := range map[string]bool{
+		"api.example.com":            true,
+		"API.example.com:443":        true,
+		"svc.internal.example.com":   true,
+		"internal.example.com":       false,
+		"localhost:8443":             true,
+		"localhost:9000":             false,
+		"evil.com":                   false,
+		"api.example.com.evil.com":   false,
+		"x.svc.internal.example.com": true,
+	} {
+		if got := hostAllowed(host, allowed); got != want {
+			t.Errorf("hostAllowed(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+// Package jobs runs tool calls in the background for async execution.
+// A caller gets a job ID back immediately and later reads the result with
+// the get_job_result built-in tool, or is notified when the job finishes.
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultRetention is how long finished jobs stay readable.
+	DefaultRetention = time.Hour
+	// DefaultMaxJobs bounds the jobs a Store tracks, running or retained.
+	DefaultMaxJobs = 1000
+)
+
+// Job status values.
+const (
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Job is a snapshot of one background execution.
+type Job struct {
+	ID         string     `json:"job_id"`
+	Tool       string     `json:"tool"`
+	Status     string     `json:"status"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	DurationMs int64      `json:"duration_ms,omitempty"`
+	Result     any        `json:"result,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	// Owner is the MCP session (or other caller) that started the job and
+	// is notified when it finishes.
+	Owner string `json:"-"`
+}
+
+// Done reports whether the job has finished.
+func (j Job) Done() bool {
+	return j.Status != StatusRunning
+}
+
+type entry struct {
+	job  Job
+	done chan struct{}
+}
+
+// Store tracks the jobs of one profile. It outlives registry rebuilds so
+// a job started before a spec refresh can still be read afterwards.
+type Store struct {
+	mu        sync.Mutex
+	jobs      map[string]*entry
+	retention time.Duration
+	maxJobs   int
+	now       func() time.Time
+}
+
+// NewStore returns a Store keeping finished jobs for retention and at most
+// maxJobs jobs in total. Zero values use the defaults.
+func NewStore(retention time.Duration, maxJobs int) *Store {
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
+	if maxJobs <= 0 {
+		maxJobs = DefaultMaxJobs
+	}
+	return &Store{jobs: map[string]*entry{}, retention: retention, maxJobs: maxJobs, now: time.Now}
+}
+
+// Start runs run in the background and returns the new job. The job's
+// context keeps ctx's values but not its cancellation, so the job survives
+// the request that started it. onDone, if set, is called with the finished
+// job.
+func (s *Store) Start(ctx context.Context, tool, owner string, run func(ctx context.Context) (any, error), onDone func(Job)) (Job, error) {
+	id, err := newID()
+	if err != nil {
+		return Job{}, err
+	}
+	s.mu.Lock()
+	s.pruneLocked()
+	if len(s.jobs) >= s.maxJobs {
+		s.mu.Unlock()
+		return Job{}, fmt.Errorf("too many jobs: at most %d can be tracked", s.maxJobs)
+	}
+	e := &entry{
+		job:  Job{ID: id, Tool: tool, Status: StatusRunning, StartedAt: s.now(), Owner: owner},
+		done: make(chan struct{}),
+	}
+	s.jobs[id] = e
+	job := e.job
+	s.mu.Unlock()
+
+	go func() {
+		result, err := run(context.WithoutCancel(ctx))
+		s.mu.Lock()
+		finished := s.now()
+		e.job.FinishedAt = &finished
+		e.job.DurationMs = finished.Sub(e.job.StartedAt).Milliseconds()
+		if err != nil {
+			e.job.Status = StatusFailed
+			e.job.Error = err.Error()
+		} else {
+			e.job.Status = StatusSucceeded
+			e.job.Result = result
+		}
+		job := e.job
+		close(e.done)
+		s.mu.Unlock()
+		if onDone != nil {
+			onDone(job)
+		}
+	}()
+	return job, nil
+}
+
+// Get returns the job with the given ID.
+func (s *Store) Get(id string) (Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneLocked()
+	e, ok := s.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return e.job, true
+}
+
+// Wait returns the job once it has finished or ctx is done, whichever
+// comes first. The returned job may still be running.
+func (s *Store) Wait(ctx context.Context, id string) (Job, bool) {
+	s.mu.Lock()
+	e, ok := s.jobs[id]
+	s.mu.Unlock()
+	if !ok {
+		return Job{}, false
+	}
+	select {
+	case <-e.done:
+	case <-ctx.Done():
+	}
+	return s.Get(id)
+}
+
+// pruneLocked drops finished jobs older than the retention period.
+func (s *Store) pruneLocked() {
+	cutoff := s.now().Add(-s.retention)
+	for id, e := range s.jobs {
+		if e.job.FinishedAt != nil && e.job.FinishedAt.Before(cutoff) {
+			delete(s.jobs, id)
+		}
+	}
+}
+
+func newID() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate job id: %w", err)
+	}
+	return "job_" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,105 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStoreRunsJobs(t *testing.T) {
+	store := NewStore(0, 0)
+	release := make(chan struct{})
+	done := make(chan Job, 1)
+	job, err := store.Start(context.Background(), "reports__generate", "session-1", func(context.Context) (any, error) {
+		<-release
+		return "report.pdf", nil
+	}, func(job Job) { done <- job })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(job.ID, "job_") || job.Status != StatusRunning || job.Owner != "session-1" {
+		t.Fatalf("unexpected job %+v", job)
+	}
+
+	// A short wait returns the job still running.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if got, ok := store.Wait(ctx, job.ID); !ok || got.Done() {
+		t.Fatalf("expected a running job, got %+v", got)
+	}
+
+	close(release)
+	finished := <-done
+	if finished.Status != StatusSucceeded || finished.Result != "report.pdf" || finished.FinishedAt == nil {
+		t.Fatalf("unexpected finished job %+v", finished)
+	}
+	if got, _ := store.Wait(context.Background(), job.ID); got.Status != StatusSucceeded {
+		t.Fatalf("Wait = %+v", got)
+	}
+	if _, ok := store.Get("job_missing"); ok {
+		t.Fatal("unknown job found")
+	}
+}
+
+func TestStoreRecordsFailures(t *testing.T) {
+	store := NewStore(0, 0)
+	done := make(chan Job, 1)
+	_, err := store.Start(context.Background(), "batch__run", "", func(context.Context) (any, error) {
+		return nil, errors.New("upstream unavailable")
+	}, func(job Job) { done <- job })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job := <-done; job.Status != StatusFailed || job.Error != "upstream unavailable" {
+		t.Fatalf("unexpected job %+v", job)
+	}
+}
+
+func TestStoreSurvivesRequestCancellation(t *testing.T) {
+	store := NewStore(0, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	_, err := store.Start(ctx, "slow__op", "", func(jobCtx context.Context) (any, error) {
+		cancel()
+		time.Sleep(10 * time.Millisecond)
+		return nil, jobCtx.Err()
+	}, func(job Job) {
+		if job.Error != "" {
+			done <- errors.New(job.Error)
+			return
+		}
+		done <- nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("job context was cancelled with the request: %v", err)
+	}
+}
+
+func TestStoreLimitAndRetention(t *testing.T) {
+	store := NewStore(time.Minute, 1)
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	done := make(chan Job, 1)
+	first, err := store.Start(context.Background(), "a", "", func(context.Context) (any, error) { return nil, nil }, func(job Job) { done <- job })
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-done
+	if _, err := store.Start(context.Background(), "b", "", func(context.Context) (any, error) { return nil, nil }, nil); err == nil {
+		t.Fatal("expected the store to be full")
+	}
+
+	// Once the finished job has expired it is dropped and makes room.
+	now = now.Add(2 * time.Minute)
+	if _, ok := store.Get(first.ID); ok {
+		t.Fatal("expired job still readable")
+	}
+	if _, err := store.Start(context.Background(), "b", "", func(context.Context) (any, error) { return nil, nil }, nil); err != nil {
+		t.Fatal(err)
+	}
+}
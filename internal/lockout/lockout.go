@@ -0,0 +1,184 @@
+// Package lockout blocks clients that keep presenting invalid tokens. Each
+// profile and client address has its own failure count; too many failures
+// within a window lock the client out of the profile for a while, and
+// repeated lockouts grow longer. Counts live in a Store, which the server
+// backs with the audit database so they survive restarts.
+package lockout
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"skyline-mcp/internal/serverconfig"
+)
+
+const (
+	defaultMaxFailures = 10
+	defaultWindow      = 15 * time.Minute
+	defaultDuration    = 5 * time.Minute
+	defaultMaxDuration = time.Hour
+)
+
+// Record is the failure state of one client for one profile.
+type Record struct {
+	Failures    int       // since the last lockout
+	Lockouts    int       // consecutive lockouts, for backoff
+	LastFailure time.Time // zero when there is no record
+	LockedUntil time.Time
+}
+
+// Store keeps records. SetAuthFailures with a zero Record deletes it.
+type Store interface {
+	AuthFailures(ctx context.Context, profile, clientAddr string) (Record, error)
+	SetAuthFailures(ctx context.Context, profile, clientAddr string, rec Record) error
+}
+
+// MemoryStore is a Store for a single process; records reset on restart.
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]Record
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: map[string]Record{}}
+}
+
+func (m *MemoryStore) AuthFailures(_ context.Context, profile, clientAddr string) (Record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.records[profile+"\x00"+clientAddr], nil
+}
+
+func (m *MemoryStore) SetAuthFailures(_ context.Context, profile, clientAddr string, rec Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := profile + "\x00" + clientAddr
+	if rec == (Record{}) {
+		delete(m.records, key)
+	} else {
+		m.records[key] = rec
+	}
+	return nil
+}
+
+// Guard applies a lockout policy. A nil Guard never locks anyone out.
+type Guard struct {
+	maxFailures int
+	window      time.Duration
+	duration    time.Duration
+	maxDuration time.Duration
+	store       Store
+	logger      *slog.Logger
+	now         func() time.Time
+
+	mu sync.Mutex // serializes read-modify-write of records
+}
+
+// New returns a Guard for cfg with defaults applied, or nil when lockout is
+// disabled.
+func New(cfg *serverconfig.LockoutConfig, store Store, logger *slog.Logger) *Guard {
+	if cfg == nil {
+		cfg = &serverconfig.LockoutConfig{}
+	}
+	if cfg.Enabled != nil && !*cfg.Enabled {
+		return nil
+	}
+	g := &Guard{
+		maxFailures: cfg.MaxFailures,
+		window:      cfg.Window,
+		duration:    cfg.Duration,
+		maxDuration: cfg.MaxDuration,
+		store:       store,
+		logger:      logger,
+		now:         time.Now,
+	}
+	if g.maxFailures <= 0 {
+		g.maxFailures = defaultMaxFailures
+	}
+	if g.window <= 0 {
+		g.window = defaultWindow
+	}
+	if g.duration <= 0 {
+		g.duration = defaultDuration
+	}
+	if g.maxDuration <= 0 {
+		g.maxDuration = defaultMaxDuration
+	}
+	g.maxDuration = max(g.maxDuration, g.duration)
+	return g
+}
+
+// Locked reports whether the client is locked out of the profile and for
+// how much longer. Store errors let the request through: the token check
+// still applies.
+func (g *Guard) Locked(ctx context.Context, profile, clientAddr string) (time.Duration, bool) {
+	if g == nil {
+		return 0, false
+	}
+	rec, err := g.store.AuthFailures(ctx, profile, clientAddr)
+	if err != nil {
+		g.logger.Warn("read auth failures", "profile", profile, "error", err)
+		return 0, false
+	}
+	if left := rec.LockedUntil.Sub(g.now()); left > 0 {
+		return left, true
+	}
+	return 0, false
+}
+
+// Fail counts a failed attempt. When it starts a lockout, Fail returns its
+// length and the number of failures that led to it.
+func (g *Guard) Fail(ctx context.Context, profile, clientAddr string) (time.Duration, int) {
+	if g == nil {
+		return 0, 0
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	rec, err := g.store.AuthFailures(ctx, profile, clientAddr)
+	if err != nil {
+		g.logger.Warn("read auth failures", "profile", profile, "error", err)
+		return 0, 0
+	}
+	now := g.now()
+	if now.Sub(rec.LastFailure) > g.window {
+		// Quiet for a whole window: start over, backoff included.
+		rec = Record{}
+	}
+	rec.Failures++
+	rec.LastFailure = now
+	var lockedFor time.Duration
+	failures := rec.Failures
+	if rec.Failures >= g.maxFailures {
+		lockedFor = g.duration
+		for i := 0; i < rec.Lockouts && lockedFor < g.maxDuration; i++ {
+			lockedFor *= 2
+		}
+		lockedFor = min(lockedFor, g.maxDuration)
+		rec.Lockouts++
+		rec.Failures = 0
+		rec.LockedUntil = now.Add(lockedFor)
+	}
+	if err := g.store.SetAuthFailures(ctx, profile, clientAddr, rec); err != nil {
+		g.logger.Warn("record auth failure", "profile", profile, "error", err)
+	}
+	return lockedFor, failures
+}
+
+// Succeed clears the client's failures for the profile.
+func (g *Guard) Succeed(ctx context.Context, profile, clientAddr string) {
+	if g == nil {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	rec, err := g.store.AuthFailures(ctx, profile, clientAddr)
+	if err != nil || rec == (Record{}) {
+		return
+	}
+	if err := g.store.SetAuthFailures(ctx, profile, clientAddr, Record{}); err != nil {
+		g.logger.Warn("clear auth failures", "profile", profile, "error", err)
+	}
+}
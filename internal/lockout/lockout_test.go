@@ -0,0 +1,109 @@
+package lockout
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"skyline-mcp/internal/logging"
+	"skyline-mcp/internal/serverconfig"
+)
+
+func newTestGuard(cfg *serverconfig.LockoutConfig) (*Guard, *time.Time) {
+	g := New(cfg, NewMemoryStore(), logging.Discard())
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	g.now = func() time.Time { return now }
+	return g, &now
+}
+
+func TestLockoutAfterMaxFailures(t *testing.T) {
+	g, now := newTestGuard(&serverconfig.LockoutConfig{MaxFailures: 3})
+	ctx := context.Background()
+
+	for i := 1; i <= 2; i++ {
+		if d, _ := g.Fail(ctx, "github", "10.0.0.1"); d != 0 {
+			t.Fatalf("failure %d locked out for %v", i, d)
+		}
+	}
+	if _, locked := g.Locked(ctx, "github", "10.0.0.1"); locked {
+		t.Fatal("locked before MaxFailures")
+	}
+	d, n := g.Fail(ctx, "github", "10.0.0.1")
+	if d != 5*time.Minute || n != 3 {
+		t.Fatalf("third failure: lockout %v after %d failures, want 5m after 3", d, n)
+	}
+	if left, locked := g.Locked(ctx, "github", "10.0.0.1"); !locked || left != 5*time.Minute {
+		t.Fatalf("Locked = %v, %v; want 5m, true", left, locked)
+	}
+	// Other clients and other profiles are unaffected.
+	if _, locked := g.Locked(ctx, "github", "10.0.0.2"); locked {
+		t.Fatal("other client locked out")
+	}
+	if _, locked := g.Locked(ctx, "jira", "10.0.0.1"); locked {
+		t.Fatal("other profile locked out")
+	}
+
+	*now = now.Add(5 * time.Minute)
+	if _, locked := g.Locked(ctx, "github", "10.0.0.1"); locked {
+		t.Fatal("still locked after the lockout ended")
+	}
+}
+
+func TestLockoutBackoff(t *testing.T) {
+	g, now := newTestGuard(&serverconfig.LockoutConfig{MaxFailures: 2, Duration: time.Minute, MaxDuration: 3 * time.Minute})
+	ctx := context.Background()
+
+	for _, want := range []time.Duration{time.Minute, 2 * time.Minute, 3 * time.Minute, 3 * time.Minute} {
+		g.Fail(ctx, "github", "10.0.0.1")
+		d, _ := g.Fail(ctx, "github", "10.0.0.1")
+		if d != want {
+			t.Fatalf("lockout = %v, want %v", d, want)
+		}
+		*now = now.Add(d)
+	}
+
+	// A quiet window resets the backoff.
+	*now = now.Add(time.Hour)
+	g.Fail(ctx, "github", "10.0.0.1")
+	if d, _ := g.Fail(ctx, "github", "10.0.0.1"); d != time.Minute {
+		t.Fatalf("lockout after quiet window = %v, want 1m", d)
+	}
+}
+
+func TestFailuresOutsideWindowStartOver(t *testing.T) {
+	g, now := newTestGuard(&serverconfig.LockoutConfig{MaxFailures: 3, Window: time.Minute})
+	ctx := context.Background()
+
+	g.Fail(ctx, "github", "10.0.0.1")
+	g.Fail(ctx, "github", "10.0.0.1")
+	*now = now.Add(2 * time.Minute)
+	if d, n := g.Fail(ctx, "github", "10.0.0.1"); d != 0 || n != 1 {
+		t.Fatalf("failure after window: lockout %v, count %d; want 0, 1", d, n)
+	}
+}
+
+func TestSucceedClearsFailures(t *testing.T) {
+	g, _ := newTestGuard(&serverconfig.LockoutConfig{MaxFailures: 2})
+	ctx := context.Background()
+
+	g.Fail(ctx, "github", "10.0.0.1")
+	g.Succeed(ctx, "github", "10.0.0.1")
+	if d, n := g.Fail(ctx, "github", "10.0.0.1"); d != 0 || n != 1 {
+		t.Fatalf("failure after success: lockout %v, count %d; want 0, 1", d, n)
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	off := false
+	g := New(&serverconfig.LockoutConfig{Enabled: &off}, NewMemoryStore(), logging.Discard())
+	if g != nil {
+		t.Fatal("disabled lockout returned a guard")
+	}
+	ctx := context.Background()
+	for range 20 {
+		g.Fail(ctx, "github", "10.0.0.1")
+	}
+	if _, locked := g.Locked(ctx, "github", "10.0.0.1"); locked {
+		t.Fatal("nil guard locked a client out")
+	}
+}
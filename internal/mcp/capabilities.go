@@ -0,0 +1,124 @@
+package mcp
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"skyline-mcp/internal/config"
+)
+
+// capabilitiesMetaKey is the initialize result _meta key that carries the
+// server's Capabilities.
+const capabilitiesMetaKey = "skyline/capabilities"
+
+// Capabilities describes what a Skyline server supports, so clients and
+// automation can adapt to what is enabled. The control plane serves it at
+// GET /capabilities; MCP clients get it in the _meta of the initialize
+// result, with Profiles holding only their own profile.
+type Capabilities struct {
+	Version string `json:"version"`
+	// SpecFormats lists the spec_type values APIs can use.
+	SpecFormats []string `json:"spec_formats"`
+	// Transports lists the MCP transports the binary can serve.
+	Transports []string `json:"transports"`
+	// Features reports optional features by name, e.g. code_execution,
+	// cache and subscriptions, and whether each is enabled.
+	Features map[string]bool       `json:"features"`
+	Profiles []ProfileCapabilities `json:"profiles,omitempty"`
+}
+
+// ProfileCapabilities counts a profile's tools. The counts are only known
+// once the profile's specs are loaded.
+type ProfileCapabilities struct {
+	Name   string `json:"name,omitempty"` // empty outside server mode
+	Loaded bool   `json:"loaded"`
+	Tools  int    `json:"tools,omitempty"`
+	// APIs counts the tools of each API, leaving out Skyline's own tools.
+	APIs map[string]int `json:"apis,omitempty"`
+}
+
+// ProfileCapabilitiesOf counts the tools registry holds for profile name.
+func ProfileCapabilitiesOf(name string, registry *Registry) ProfileCapabilities {
+	pc := ProfileCapabilities{Name: name, Loaded: registry != nil}
+	if registry == nil {
+		return pc
+	}
+	pc.Tools = len(registry.Tools)
+	for _, tool := range registry.Tools {
+		if tool.Operation == nil || tool.Operation.ServiceName == config.BuiltinServiceName {
+			continue
+		}
+		if pc.APIs == nil {
+			pc.APIs = map[string]int{}
+		}
+		pc.APIs[tool.Operation.ServiceName]++
+	}
+	return pc
+}
+
+// SetCapabilities sets what the initialize result reports about the
+// server. The tool counts of the server's own registry are filled in per
+// initialize request.
+func (s *Server) SetCapabilities(c *Capabilities) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.capabilities = c
+}
+
+// initializeResult builds the response to initialize: protocol
+// capabilities, serverInfo, instructions for the agent and, when set, the
+// server's Capabilities under _meta.
+func (s *Server) initializeResult() map[string]any {
+	s.mu.RLock()
+	registry, capabilities := s.registry, s.capabilities
+	s.mu.RUnlock()
+	result := map[string]any{
+		"protocolVersion": protocolVersion,
+		"capabilities": map[string]any{
+			"tools":     map[string]any{"list": true, "call": true, "listChanged": true},
+			"resources": map[string]any{"list": true, "read": true, "subscribe": s.subscribeHook != nil},
+		},
+		"serverInfo": map[string]any{
+			"name":        "Skyline MCP",
+			"title":       "Skyline MCP",
+			"version":     s.version,
+			"description": "Exposes REST, GraphQL, gRPC and other APIs as MCP tools",
+		},
+		"instructions": s.instructions(registry, capabilities),
+	}
+	if capabilities != nil {
+		caps := *capabilities
+		caps.Profiles = []ProfileCapabilities{ProfileCapabilitiesOf(s.profile, registry)}
+		result["_meta"] = map[string]any{capabilitiesMetaKey: caps}
+	}
+	return result
+}
+
+// instructions tells the agent which APIs the tools call and which optional
+// features it can use.
+func (s *Server) instructions(registry *Registry, capabilities *Capabilities) string {
+	pc := ProfileCapabilitiesOf(s.profile, registry)
+	var b strings.Builder
+	if len(pc.APIs) == 0 {
+		b.WriteString("No API tools are loaded.")
+	} else {
+		apis := make([]string, 0, len(pc.APIs))
+		for _, name := range slices.Sorted(maps.Keys(pc.APIs)) {
+			apis = append(apis, fmt.Sprintf("%s (%d)", name, pc.APIs[name]))
+		}
+		fmt.Fprintf(&b, "The tools call these APIs: %s.", strings.Join(apis, ", "))
+	}
+	b.WriteString(" Tools annotated readOnlyHint only read data; the others can change it.")
+	if s.subscribeHook != nil {
+		b.WriteString(" Resources can be subscribed to for update notifications.")
+	}
+	if s.refreshHook != nil {
+		b.WriteString(" Call registry/refresh to pick up changed API specs.")
+	}
+	if capabilities != nil && capabilities.Features["code_execution"] {
+		b.WriteString(" Code execution is enabled for scripts that chain several tool calls.")
+	}
+	return b.String()
+}
@@ -0,0 +1,127 @@
+package mcp
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// acceptsGzip reports whether the client accepts gzip-encoded responses.
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(name), "gzip") {
+			continue
+		}
+		if q, ok := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q="); ok {
+			weight, err := strconv.ParseFloat(q, 64)
+			return err == nil && weight > 0
+		}
+		return true
+	}
+	return false
+}
+
+// gzipResponseWriter gzips responses of at least minSize bytes, and event
+// streams from the start, since they carry tool results of any size for as
+// long as the session lasts. Smaller responses are buffered until the
+// handler finishes and sent as they are.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	minSize int
+	status  int
+	buf     []byte
+	gz      *gzip.Writer
+	plain   bool // decided against compressing
+}
+
+func newGzipResponseWriter(w http.ResponseWriter, minSize int64) *gzipResponseWriter {
+	w.Header().Add("Vary", "Accept-Encoding")
+	return &gzipResponseWriter{ResponseWriter: w, minSize: int(minSize)}
+}
+
+func (g *gzipResponseWriter) WriteHeader(status int) {
+	if g.status != 0 || g.gz != nil || g.plain {
+		return
+	}
+	g.status = status
+	switch {
+	case status < 200 || status == http.StatusNoContent || status == http.StatusNotModified || g.Header().Get("Content-Encoding") != "":
+		g.sendPlain()
+	case strings.HasPrefix(g.Header().Get("Content-Type"), "text/event-stream"):
+		g.startGzip()
+	}
+}
+
+func (g *gzipResponseWriter) Write(p []byte) (int, error) {
+	if g.status == 0 {
+		g.WriteHeader(http.StatusOK)
+	}
+	switch {
+	case g.gz != nil:
+		return g.gz.Write(p)
+	case g.plain:
+		return g.ResponseWriter.Write(p)
+	}
+	g.buf = append(g.buf, p...)
+	if len(g.buf) >= g.minSize {
+		g.startGzip()
+	}
+	return len(p), nil
+}
+
+// Flush sends what was written so far. A response flushed before it was
+// large enough is sent uncompressed.
+func (g *gzipResponseWriter) Flush() {
+	if g.gz != nil {
+		_ = g.gz.Flush()
+	} else {
+		if g.status == 0 {
+			g.status = http.StatusOK
+		}
+		g.sendPlain()
+	}
+	if f, ok := g.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Close finishes the response; the handler must not write after it.
+func (g *gzipResponseWriter) Close() {
+	switch {
+	case g.gz != nil:
+		_ = g.gz.Close()
+	case !g.plain && g.status != 0:
+		g.sendPlain()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (g *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return g.ResponseWriter
+}
+
+func (g *gzipResponseWriter) startGzip() {
+	h := g.Header()
+	h.Set("Content-Encoding", "gzip")
+	h.Del("Content-Length")
+	g.ResponseWriter.WriteHeader(g.status)
+	g.gz = gzip.NewWriter(g.ResponseWriter)
+	if len(g.buf) > 0 {
+		_, _ = g.gz.Write(g.buf)
+		g.buf = nil
+	}
+}
+
+func (g *gzipResponseWriter) sendPlain() {
+	if g.plain {
+		return
+	}
+	g.plain = true
+	g.ResponseWriter.WriteHeader(g.status)
+	if len(g.buf) > 0 {
+		_, _ = g.ResponseWriter.Write(g.buf)
+		g.buf = nil
+	}
+}
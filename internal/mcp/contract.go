@@ -0,0 +1,263 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/runtime"
+)
+
+// ContractResult is the outcome of one contract test.
+type ContractResult struct {
+	Name     string   `json:"name"`
+	Tool     string   `json:"tool"`
+	Service  string   `json:"service,omitempty"`
+	Status   int      `json:"status,omitempty"`
+	Passed   bool     `json:"passed"`
+	Failures []string `json:"failures,omitempty"`
+	Seconds  float64  `json:"seconds"`
+}
+
+// ContractReport collects the results of a contract test run.
+type ContractReport struct {
+	Passed  int              `json:"passed"`
+	Failed  int              `json:"failed"`
+	Seconds float64          `json:"seconds"`
+	Results []ContractResult `json:"results"`
+}
+
+// RunContractTests calls the tools named by tests and checks each response's
+// status and its match with the tool's output schema. Without tests, every
+// read-only tool of the registry is called with an example built from its
+// input schema. Only read-only tools are ever called; timeout bounds each
+// call.
+func RunContractTests(ctx context.Context, registry *Registry, executor Executor, tests []config.ContractTest, timeout time.Duration) *ContractReport {
+	if len(tests) == 0 {
+		for _, tool := range registry.SortedTools() {
+			op := tool.Operation
+			if op == nil || op.Protocol == "builtin" || op.Workflow != nil || tool.AliasOf != "" {
+				continue
+			}
+			if op.RESTComposite != nil {
+				// Grouped REST tools are tested once per read-only action.
+				for _, action := range slices.Sorted(maps.Keys(op.RESTComposite.Actions)) {
+					sub := op.RESTComposite.Actions[action]
+					if !methodAnnotations(sub.Method).readOnly {
+						continue
+					}
+					args, _ := canonical.ExampleValue(sub.InputSchema, true).(map[string]any)
+					if args == nil {
+						args = map[string]any{}
+					}
+					args["action"] = action
+					tests = append(tests, config.ContractTest{Name: tool.Name + " " + action, Tool: tool.Name, Args: args})
+				}
+				continue
+			}
+			if isReadOnly(tool) {
+				tests = append(tests, config.ContractTest{Tool: tool.Name})
+			}
+		}
+	}
+	report := &ContractReport{Results: make([]ContractResult, 0, len(tests))}
+	start := time.Now()
+	for _, test := range tests {
+		res := runContractTest(ctx, registry, executor, test, timeout)
+		if res.Passed {
+			report.Passed++
+		} else {
+			report.Failed++
+		}
+		report.Results = append(report.Results, res)
+	}
+	report.Seconds = time.Since(start).Seconds()
+	return report
+}
+
+func runContractTest(ctx context.Context, registry *Registry, executor Executor, test config.ContractTest, timeout time.Duration) ContractResult {
+	res := ContractResult{Name: test.Name, Tool: test.Tool}
+	if res.Name == "" {
+		res.Name = test.Tool
+	}
+	fail := func(format string, args ...any) ContractResult {
+		res.Failures = append(res.Failures, fmt.Sprintf(format, args...))
+		return res
+	}
+	tool := registry.Tools[test.Tool]
+	if tool == nil || tool.Operation == nil {
+		return fail("tool not found")
+	}
+	res.Service = tool.Operation.ServiceName
+	args := test.Args
+	if args == nil {
+		args, _ = canonical.ExampleValue(tool.InputSchema, true).(map[string]any)
+	}
+	schema := ToolOutputSchema(tool, executor)
+	if comp := tool.Operation.RESTComposite; comp != nil {
+		action, _ := args["action"].(string)
+		sub := comp.Actions[action]
+		if sub == nil {
+			return fail("action argument must name one of %s", strings.Join(slices.Sorted(maps.Keys(comp.Actions)), ", "))
+		}
+		schema = operationOutputSchema(sub, executor)
+	}
+	if !tool.ReadOnlyCall(args) {
+		return fail("tool is not read-only; contract tests only call read-only tools")
+	}
+	if err := tool.ValidateArguments(args); err != nil {
+		return fail("%v", err)
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	started := time.Now()
+	result, err := executor.Execute(callCtx, tool.Operation, args)
+	res.Seconds = time.Since(started).Seconds()
+	var upErr *runtime.UpstreamError
+	switch {
+	case errors.As(err, &upErr):
+		res.Status = upErr.Status
+	case err != nil:
+		return fail("call failed: %v", err)
+	default:
+		res.Status = result.Status
+	}
+
+	if !expectedStatus(test.ExpectStatus, res.Status) {
+		want := "2xx"
+		if len(test.ExpectStatus) > 0 {
+			want = strings.Trim(fmt.Sprint(test.ExpectStatus), "[]")
+		}
+		fail("status %d, expected %s", res.Status, want)
+	}
+	// Output schemas describe successful responses only.
+	if result != nil && !test.SkipSchema && res.Status < 300 {
+		for _, problem := range outputProblems(schema, result) {
+			fail("response does not match output schema: %s", problem)
+		}
+	}
+	res.Passed = len(res.Failures) == 0
+	return res
+}
+
+func isReadOnly(tool *Tool) bool {
+	readOnly, _ := tool.Annotations["readOnlyHint"].(bool)
+	return readOnly
+}
+
+// ReadOnlyCall reports whether calling the tool with args only reads. A
+// grouped REST tool is as safe to call as the action args select.
+func (t *Tool) ReadOnlyCall(args map[string]any) bool {
+	if t.Operation != nil && t.Operation.RESTComposite != nil {
+		action, _ := args["action"].(string)
+		sub := t.Operation.RESTComposite.Actions[action]
+		return sub != nil && methodAnnotations(sub.Method).readOnly
+	}
+	return isReadOnly(t)
+}
+
+// operationOutputSchema is ToolOutputSchema for an operation without a tool
+// of its own, such as an action of a grouped REST tool.
+func operationOutputSchema(op *canonical.Operation, executor Executor) map[string]any {
+	if learner, ok := executor.(SchemaLearner); ok && op.ResponseSchema == nil {
+		if learned := learner.LearnedResponseSchema(op); learned != nil {
+			return outputSchema(learned)
+		}
+	}
+	return outputSchema(op.ResponseSchema)
+}
+
+func expectedStatus(expected []int, status int) bool {
+	if len(expected) == 0 {
+		return status >= 200 && status < 300
+	}
+	return slices.Contains(expected, status)
+}
+
+// outputProblems validates the JSON form of result against schema and
+// returns one message per mismatch.
+func outputProblems(schema map[string]any, result *runtime.Result) []string {
+	validator, err := compileSchema(schema)
+	if err != nil {
+		return nil
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	var instance any
+	if err := json.Unmarshal(data, &instance); err != nil {
+		return []string{err.Error()}
+	}
+	err = validator.Validate(instance)
+	var ve *jsonschema.ValidationError
+	if !errors.As(err, &ve) {
+		if err != nil {
+			return []string{err.Error()}
+		}
+		return nil
+	}
+	var problems []string
+	for _, leaf := range leafErrors(ve) {
+		location := leaf.InstanceLocation
+		if location == "" {
+			location = "/"
+		}
+		problems = append(problems, location+": "+leaf.Message)
+	}
+	return problems
+}
+
+// WriteJUnit writes the report as a JUnit XML test suite, one test case
+// per contract test, for CI systems to display.
+func (r *ContractReport) WriteJUnit(w io.Writer, suite string) error {
+	type failure struct {
+		Message string `xml:"message,attr"`
+		Text    string `xml:",chardata"`
+	}
+	type testCase struct {
+		Name      string   `xml:"name,attr"`
+		ClassName string   `xml:"classname,attr"`
+		Time      string   `xml:"time,attr"`
+		Failure   *failure `xml:"failure,omitempty"`
+	}
+	type testSuite struct {
+		XMLName  xml.Name   `xml:"testsuite"`
+		Name     string     `xml:"name,attr"`
+		Tests    int        `xml:"tests,attr"`
+		Failures int        `xml:"failures,attr"`
+		Time     string     `xml:"time,attr"`
+		Cases    []testCase `xml:"testcase"`
+	}
+	seconds := func(s float64) string { return fmt.Sprintf("%.3f", s) }
+	out := testSuite{Name: suite, Tests: len(r.Results), Failures: r.Failed, Time: seconds(r.Seconds)}
+	for _, res := range r.Results {
+		tc := testCase{Name: res.Name, ClassName: res.Service, Time: seconds(res.Seconds)}
+		if !res.Passed {
+			tc.Failure = &failure{Message: res.Failures[0], Text: strings.Join(res.Failures, "\n")}
+		}
+		out.Cases = append(out.Cases, tc)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
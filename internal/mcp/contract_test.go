@@ -0,0 +1,145 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/runtime"
+)
+
+type funcExecutor func(op *canonical.Operation, args map[string]any) (*runtime.Result, error)
+
+func (f funcExecutor) Execute(_ context.Context, op *canonical.Operation, args map[string]any) (*runtime.Result, error) {
+	return f(op, args)
+}
+
+func TestRunContractTests(t *testing.T) {
+	ops := []*canonical.Operation{
+		{ServiceName: "pets", ID: "listPets", ToolName: "pets__listPets", Method: "get", Path: "/pets",
+			InputSchema:    map[string]any{"type": "object", "properties": map[string]any{}},
+			ResponseSchema: map[string]any{"type": "array"}},
+		{ServiceName: "pets", ID: "getPet", ToolName: "pets__getPet", Method: "get", Path: "/pets/{id}",
+			InputSchema: map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"id": map[string]any{"type": "integer"}},
+				"required":   []any{"id"},
+			},
+			ResponseSchema: map[string]any{"type": "object", "properties": map[string]any{"name": map[string]any{"type": "string"}}}},
+		{ServiceName: "pets", ID: "createPet", ToolName: "pets__createPet", Method: "post", Path: "/pets",
+			InputSchema: map[string]any{"type": "object", "properties": map[string]any{}}},
+	}
+	registry, err := NewRegistry([]*canonical.Service{{Name: "pets", Operations: ops}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var called []string
+	executor := funcExecutor(func(op *canonical.Operation, args map[string]any) (*runtime.Result, error) {
+		called = append(called, op.ToolName)
+		switch op.ID {
+		case "listPets":
+			return &runtime.Result{Status: 200, ContentType: "application/json", Body: []any{}}, nil
+		case "getPet":
+			if args["id"] == 404 {
+				return nil, &runtime.UpstreamError{Status: 404, Code: "not_found"}
+			}
+			return &runtime.Result{Status: 200, ContentType: "application/json", Body: map[string]any{"name": 5}}, nil
+		}
+		return &runtime.Result{Status: 201, ContentType: "application/json", Body: map[string]any{}}, nil
+	})
+
+	// Without configured tests every read-only tool is called with example
+	// arguments.
+	report := RunContractTests(context.Background(), registry, executor, nil, time.Second)
+	if strings.Join(called, ",") != "pets__getPet,pets__listPets" {
+		t.Fatalf("called %v, want only the read-only tools", called)
+	}
+	if report.Passed != 1 || report.Failed != 1 {
+		t.Fatalf("report = %+v", report)
+	}
+	getPet := report.Results[0]
+	if getPet.Passed || len(getPet.Failures) != 1 || !strings.Contains(getPet.Failures[0], "/body/name") {
+		t.Fatalf("getPet result = %+v, want a schema failure at /body/name", getPet)
+	}
+
+	called = nil
+	report = RunContractTests(context.Background(), registry, executor, []config.ContractTest{
+		{Name: "missing pet", Tool: "pets__getPet", Args: map[string]any{"id": 404}, ExpectStatus: []int{404}},
+		{Tool: "pets__listPets", ExpectStatus: []int{204}},
+		{Tool: "pets__createPet"},
+		{Tool: "pets__deletePet"},
+	}, time.Second)
+	if strings.Join(called, ",") != "pets__getPet,pets__listPets" {
+		t.Fatalf("called %v; write tools must never be called", called)
+	}
+	want := []struct {
+		passed  bool
+		failure string
+	}{
+		{passed: true},
+		{failure: "status 200, expected 204"},
+		{failure: "not read-only"},
+		{failure: "tool not found"},
+	}
+	for i, w := range want {
+		res := report.Results[i]
+		if res.Passed != w.passed || (w.failure != "" && (len(res.Failures) == 0 || !strings.Contains(res.Failures[0], w.failure))) {
+			t.Errorf("result %d = %+v, want passed=%v failure %q", i, res, w.passed, w.failure)
+		}
+	}
+	if report.Results[0].Name != "missing pet" || report.Results[0].Status != 404 {
+		t.Errorf("first result = %+v", report.Results[0])
+	}
+
+	var junit bytes.Buffer
+	if err := report.WriteJUnit(&junit, "petstore"); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{`<testsuite name="petstore" tests="4" failures="3"`, `<testcase name="missing pet" classname="pets"`, `<failure message="status 200, expected 204">`} {
+		if !strings.Contains(junit.String(), s) {
+			t.Errorf("JUnit report lacks %s:\n%s", s, junit.String())
+		}
+	}
+}
+
+func TestRunContractTestsGroupedTools(t *testing.T) {
+	list := &canonical.Operation{ServiceName: "pets", ID: "listPets", Method: "get", Path: "/pets", ResponseSchema: map[string]any{"type": "array"}}
+	create := &canonical.Operation{ServiceName: "pets", ID: "createPet", Method: "post", Path: "/pets"}
+	op := &canonical.Operation{
+		ServiceName: "pets", ID: "pets_manage", ToolName: "pets__manage",
+		InputSchema: map[string]any{"type": "object", "properties": map[string]any{
+			"action": map[string]any{"type": "string", "enum": []any{"create", "list"}},
+		}},
+		RESTComposite: &canonical.RESTComposite{ResourceName: "pets", Actions: map[string]*canonical.Operation{"list": list, "create": create}},
+	}
+	registry, err := NewRegistry([]*canonical.Service{{Name: "pets", Operations: []*canonical.Operation{op}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actions []any
+	executor := funcExecutor(func(_ *canonical.Operation, args map[string]any) (*runtime.Result, error) {
+		actions = append(actions, args["action"])
+		return &runtime.Result{Status: 200, ContentType: "application/json", Body: map[string]any{}}, nil
+	})
+
+	report := RunContractTests(context.Background(), registry, executor, nil, time.Second)
+	if len(report.Results) != 1 || report.Results[0].Name != "pets__manage list" {
+		t.Fatalf("results = %+v, want only the list action", report.Results)
+	}
+	// The list action's own response schema applies.
+	if res := report.Results[0]; res.Passed || !strings.Contains(strings.Join(res.Failures, "\n"), "/body: expected array") {
+		t.Fatalf("result = %+v, want a schema failure", res)
+	}
+
+	report = RunContractTests(context.Background(), registry, executor, []config.ContractTest{{Tool: "pets__manage", Args: map[string]any{"action": "create"}}}, time.Second)
+	if report.Failed != 1 || !strings.Contains(report.Results[0].Failures[0], "not read-only") {
+		t.Fatalf("results = %+v", report.Results)
+	}
+	if len(actions) != 1 {
+		t.Fatalf("actions called = %v, want only list", actions)
+	}
+}
@@ -12,6 +12,18 @@ type ToolSearchResult struct {
 	Interface   string `json:"interface,omitempty"` // Full TypeScript interface (optional)
 }
 
+// toolService returns the service a tool belongs to. Names may lack the
+// <service>__ prefix when tool_naming.prefix is none.
+func toolService(tool *Tool) string {
+	if tool.Operation != nil && tool.Operation.ServiceName != "" {
+		return tool.Operation.ServiceName
+	}
+	if idx := strings.Index(tool.Name, "__"); idx >= 0 {
+		return tool.Name[:idx]
+	}
+	return "default"
+}
+
 // SearchTools searches for tools matching a query
 func SearchTools(registry *Registry, query string, detail string) []ToolSearchResult {
 	query = strings.ToLower(query)
@@ -19,10 +31,7 @@ func SearchTools(registry *Registry, query string, detail string) []ToolSearchRe
 
 	for _, tool := range registry.Tools {
 		// Extract service name
-		serviceName := "default"
-		if idx := strings.Index(tool.Name, "__"); idx >= 0 {
-			serviceName = tool.Name[:idx]
-		}
+		serviceName := toolService(tool)
 
 		// Check if query matches tool name or description
 		nameMatch := strings.Contains(strings.ToLower(tool.Name), query)
@@ -56,10 +65,7 @@ func GetTruncatedToolList(registry *Registry) string {
 	// Group by service
 	serviceTools := make(map[string][]*Tool)
 	for _, tool := range registry.Tools {
-		serviceName := "default"
-		if idx := strings.Index(tool.Name, "__"); idx >= 0 {
-			serviceName = tool.Name[:idx]
-		}
+		serviceName := toolService(tool)
 		serviceTools[serviceName] = append(serviceTools[serviceName], tool)
 	}
 
@@ -100,10 +106,7 @@ func GetInterfacesList(registry *Registry) []string {
 	services := make(map[string]bool)
 
 	for _, tool := range registry.Tools {
-		serviceName := "default"
-		if idx := strings.Index(tool.Name, "__"); idx >= 0 {
-			serviceName = tool.Name[:idx]
-		}
+		serviceName := toolService(tool)
 		services[serviceName] = true
 	}
 
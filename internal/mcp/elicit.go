@@ -0,0 +1,120 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrElicitationUnsupported is returned by Elicit when the client of the
+// request did not declare the elicitation capability, or the transport
+// cannot send requests to it.
+var ErrElicitationUnsupported = errors.New("the MCP client does not support elicitation")
+
+// elicitTimeout bounds how long a tool call waits for the user to answer.
+const elicitTimeout = 5 * time.Minute
+
+// ElicitResult is the client's answer to an elicitation/create request.
+type ElicitResult struct {
+	// Action is "accept", "decline" or "cancel".
+	Action  string         `json:"action"`
+	Content map[string]any `json:"content,omitempty"`
+}
+
+type elicitorKey struct{}
+
+// elicitor sends an elicitation/create request to the client of a session.
+type elicitor func(ctx context.Context, params map[string]any) (json.RawMessage, error)
+
+// Elicit asks the user of the MCP client that made the request ctx belongs
+// to for input, in the shape of requestedSchema (a flat object schema of
+// primitive properties), and waits for the answer.
+func Elicit(ctx context.Context, message string, requestedSchema map[string]any) (*ElicitResult, error) {
+	elicit, ok := ctx.Value(elicitorKey{}).(elicitor)
+	if !ok {
+		return nil, ErrElicitationUnsupported
+	}
+	ctx, cancel := context.WithTimeout(ctx, elicitTimeout)
+	defer cancel()
+	raw, err := elicit(ctx, map[string]any{"message": message, "requestedSchema": requestedSchema})
+	if err != nil {
+		return nil, err
+	}
+	var result ElicitResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, fmt.Errorf("decode elicitation result: %w", err)
+	}
+	return &result, nil
+}
+
+// clientCapabilities holds the capabilities a client declares at
+// initialize that the server acts on.
+type clientCapabilities struct {
+	Elicitation *json.RawMessage `json:"elicitation"`
+}
+
+// request sends a JSON-RPC request to the client on the session's event
+// stream and waits for the response the client POSTs back.
+func (sess *streamableSession) request(ctx context.Context, method string, params any) (json.RawMessage, error) {
+	sess.mu.Lock()
+	sess.requestSeq++
+	id := fmt.Sprintf("req-%d", sess.requestSeq)
+	ch := make(chan *rpcRequest, 1)
+	if sess.pending == nil {
+		sess.pending = make(map[string]chan *rpcRequest)
+	}
+	sess.pending[id] = ch
+	sess.mu.Unlock()
+	defer func() {
+		sess.mu.Lock()
+		delete(sess.pending, id)
+		sess.mu.Unlock()
+	}()
+
+	data, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": id, "method": method, "params": params})
+	if err != nil {
+		return nil, err
+	}
+	sess.addEvent(&sseEvent{id: fmt.Sprintf("%s-%d", id, time.Now().UnixNano()), name: "message", data: data})
+
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("no answer to %s: %w", method, ctx.Err())
+	case resp := <-ch:
+		if resp.Error != nil {
+			return nil, fmt.Errorf("%s failed: %s", method, resp.Error.Message)
+		}
+		return resp.Result, nil
+	}
+}
+
+// answer delivers a client response to the request waiting for it, and
+// reports whether one was.
+func (sess *streamableSession) answer(msg *rpcRequest) bool {
+	var id string
+	if json.Unmarshal(msg.ID, &id) != nil {
+		return false
+	}
+	sess.mu.Lock()
+	ch, ok := sess.pending[id]
+	sess.mu.Unlock()
+	if ok {
+		select {
+		case ch <- msg:
+		default: // a duplicate answer
+		}
+	}
+	return ok
+}
+
+// withElicitor lets tool calls of sess ask its client for input.
+func (sess *streamableSession) withElicitor(ctx context.Context) context.Context {
+	if !sess.canElicit {
+		return ctx
+	}
+	return context.WithValue(ctx, elicitorKey{}, elicitor(func(ctx context.Context, params map[string]any) (json.RawMessage, error) {
+		return sess.request(ctx, "elicitation/create", params)
+	}))
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 
 	"skyline-mcp/internal/executor"
+	"skyline-mcp/internal/runtime"
 )
 
 // HandleExecute handles POST /execute requests
@@ -70,7 +71,8 @@ func (s *Server) HandleInternalToolCall(w http.ResponseWriter, r *http.Request)
 	slog.Debug("internal tool call", "component", "execute", "tool", req.ToolName)
 
 	// Find tool
-	tool, exists := s.registry.Tools[req.ToolName]
+	registry, exec := s.current()
+	tool, exists := registry.Tools[req.ToolName]
 	if !exists || tool == nil || tool.Operation == nil {
 		result := executor.ToolCallResult{
 			Error: fmt.Sprintf("tool not found: %s", req.ToolName),
@@ -92,8 +94,22 @@ func (s *Server) HandleInternalToolCall(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	ctx, err := runtime.WithTimeoutArgument(r.Context(), args)
+	if err != nil {
+		result := executor.ToolCallResult{Error: err.Error()}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(result)
+		return
+	}
+	if err := tool.ValidateArguments(args); err != nil {
+		result := executor.ToolCallResult{Error: s.redactor.Redact(err.Error())}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(result)
+		return
+	}
+
 	// Execute tool via runtime executor
-	runtimeResult, err := s.executor.Execute(r.Context(), op, args)
+	runtimeResult, err := exec.Execute(ctx, op, args)
 	if err != nil {
 		result := executor.ToolCallResult{
 			Error: fmt.Sprintf("tool execution failed: %v", err),
@@ -133,7 +149,8 @@ func (s *Server) HandleSearchTools(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Search tools
-	results := SearchTools(s.registry, req.Query, req.Detail)
+	registry, _ := s.current()
+	results := SearchTools(registry, req.Query, req.Detail)
 
 	slog.Debug("search tools completed", "component", "execute", "results", len(results))
 
@@ -150,7 +167,8 @@ func (s *Server) HandleAgentPrompt(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Generate agent prompt template
-	prompt := GenerateAgentPromptTemplate(s.registry)
+	registry, _ := s.current()
+	prompt := GenerateAgentPromptTemplate(registry)
 
 	// Return as plain text
 	w.Header().Set("Content-Type", "text/plain")
@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"net"
 	"net/http"
 	"net/url"
 	"strings"
@@ -78,7 +79,7 @@ func (h *HTTPServer) handleStreamableHTTPGet(w http.ResponseWriter, r *http.Requ
 		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
-	if !validateOrigin(r) {
+	if !validateOrigin(r, nil) {
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
 	}
@@ -94,7 +95,7 @@ func (h *HTTPServer) handleStreamableHTTPPost(w http.ResponseWriter, r *http.Req
 		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
-	if !validateOrigin(r) {
+	if !validateOrigin(r, nil) {
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
 	}
@@ -392,7 +393,12 @@ func authorizeRequest(r *http.Request, auth *config.AuthConfig) bool {
 	}
 }
 
-func validateOrigin(r *http.Request) bool {
+// validateOrigin reports whether a browser may use the server given the
+// request's Origin: a local origin, or one on the host the request was
+// sent to when that host is local or in allowedHosts. A matching Host
+// header alone proves nothing, since a page whose DNS name was rebound to
+// this server's address sends its own name as both Origin and Host.
+func validateOrigin(r *http.Request, allowedHosts []string) bool {
 	origin := strings.TrimSpace(r.Header.Get("Origin"))
 	if origin == "" {
 		return true
@@ -409,9 +415,20 @@ func validateOrigin(r *http.Request) bool {
 		return true
 	}
 	reqHost := r.Host
-	if reqHost != "" {
-		reqHost = strings.Split(reqHost, ":")[0]
-		if strings.EqualFold(reqHost, host) {
+	if h, _, err := net.SplitHostPort(reqHost); err == nil {
+		reqHost = h
+	}
+	reqHost = strings.Trim(reqHost, "[]")
+	return strings.EqualFold(reqHost, host) && hostPermitted(reqHost, allowedHosts)
+}
+
+// hostPermitted reports whether host is a name the server is known by.
+func hostPermitted(host string, allowedHosts []string) bool {
+	if isLocalHost(host) {
+		return true
+	}
+	for _, allowed := range allowedHosts {
+		if strings.EqualFold(allowed, host) {
 			return true
 		}
 	}
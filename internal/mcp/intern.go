@@ -0,0 +1,157 @@
+package mcp
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// Specs reuse the same schema fragments (pagination parameters, error
+// bodies, common objects) across hundreds of operations, and parsers
+// expand $refs into separate copies of each. The registry interns them:
+// identical object subtrees are replaced by one shared map, which is only
+// expanded again when a schema is encoded for tools/list. Shared schemas
+// must therefore be treated as read-only once the registry is built.
+
+// RegistryStats describes the memory a registry holds for schemas.
+type RegistryStats struct {
+	Tools     int `json:"tools"`
+	Resources int `json:"resources"`
+	// SchemaNodes counts the schema objects of all tools before interning;
+	// UniqueSchemaNodes those left after identical ones were shared.
+	SchemaNodes       int `json:"schema_nodes"`
+	UniqueSchemaNodes int `json:"unique_schema_nodes"`
+	// CompiledValidators counts tools whose argument validator has been
+	// built; validators are compiled on a tool's first call.
+	CompiledValidators int `json:"compiled_validators"`
+}
+
+// Stats reports the registry's schema statistics.
+func (r *Registry) Stats() RegistryStats {
+	stats := r.schemaStats
+	stats.Tools = len(r.Tools)
+	stats.Resources = len(r.Resources)
+	for _, tool := range r.Tools {
+		if tool.lazy != nil && tool.lazy.compiled.Load() {
+			stats.CompiledValidators++
+		}
+	}
+	return stats
+}
+
+type schemaHash [sha256.Size]byte
+
+// schemaInterner shares identical schema objects. Nodes are keyed by a
+// hash built from their children's hashes, so each node is hashed once.
+type schemaInterner struct {
+	nodes    map[schemaHash]map[string]any
+	visiting map[uintptr]bool // guards against schemas that contain themselves
+	done     map[uintptr]schemaHash
+	total    int
+	unique   int
+}
+
+func newSchemaInterner() *schemaInterner {
+	return &schemaInterner{nodes: map[schemaHash]map[string]any{}, visiting: map[uintptr]bool{}, done: map[uintptr]schemaHash{}}
+}
+
+// intern returns the shared equivalent of schema; its subtrees are
+// replaced in place.
+func (in *schemaInterner) intern(schema map[string]any) map[string]any {
+	if schema == nil {
+		return nil
+	}
+	out, _ := in.value(schema)
+	return out.(map[string]any)
+}
+
+func (in *schemaInterner) value(v any) (any, schemaHash) {
+	switch node := v.(type) {
+	case map[string]any:
+		ptr := reflect.ValueOf(node).Pointer()
+		if sum, ok := in.done[ptr]; ok {
+			in.total++
+			return in.nodes[sum], sum
+		}
+		if in.visiting[ptr] {
+			var sum schemaHash
+			binary.LittleEndian.PutUint64(sum[:], uint64(ptr)) // never shared
+			return node, sum
+		}
+		in.visiting[ptr] = true
+		defer delete(in.visiting, ptr)
+		keys := make([]string, 0, len(node))
+		for k := range node {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		h := sha256.New()
+		h.Write([]byte{'{'})
+		for _, k := range keys {
+			child, sum := in.value(node[k])
+			node[k] = child
+			writeString(h, k)
+			h.Write(sum[:])
+		}
+		var sum schemaHash
+		h.Sum(sum[:0])
+		in.done[ptr] = sum
+		in.total++
+		if shared, ok := in.nodes[sum]; ok {
+			return shared, sum
+		}
+		in.nodes[sum] = node
+		in.unique++
+		return node, sum
+	case []any:
+		h := sha256.New()
+		h.Write([]byte{'['})
+		for i, item := range node {
+			child, sum := in.value(item)
+			node[i] = child
+			h.Write(sum[:])
+		}
+		var sum schemaHash
+		h.Sum(sum[:0])
+		return node, sum
+	default:
+		// Leaves hash by their JSON form, so 1 and 1.0 are the same value.
+		data, _ := json.Marshal(node)
+		return node, sha256.Sum256(append([]byte{'='}, data...))
+	}
+}
+
+func writeString(h interface{ Write([]byte) (int, error) }, s string) {
+	var n [8]byte
+	binary.LittleEndian.PutUint64(n[:], uint64(len(s)))
+	h.Write(n[:])
+	h.Write([]byte(s))
+}
+
+// lazyValidator compiles a tool's input schema on first use; most tools of
+// a large spec are never called.
+type lazyValidator struct {
+	once     sync.Once
+	compiled atomic.Bool
+}
+
+func (t *Tool) validator() *jsonschema.Schema {
+	if t.lazy != nil {
+		t.lazy.once.Do(func() {
+			validator, err := compileSchema(t.InputSchema)
+			if err != nil {
+				// Best-effort: a schema that does not compile is not validated.
+				validator = nil
+			}
+			t.Validator = validator
+			t.lazy.compiled.Store(true)
+		})
+	}
+	return t.Validator
+}
@@ -0,0 +1,95 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/jobs"
+)
+
+// JobCompletedMethod is the notification sent to the session that started
+// an async tool call once the job has finished.
+const JobCompletedMethod = "notifications/jobs/completed"
+
+// JobExecutor is implemented by executors that can run tool calls in the
+// background, such as *runtime.Executor.
+type JobExecutor interface {
+	StartJob(ctx context.Context, op *canonical.Operation, args map[string]any, owner string, onDone func(jobs.Job)) (jobs.Job, error)
+}
+
+// Notifier delivers a JSON-RPC notification to one session. It reports
+// false when the session is gone.
+type Notifier func(sessionID, method string, params map[string]any) bool
+
+// SetNotifier sets how job completion notifications reach sessions. The
+// Streamable HTTP transport installs itself here.
+func (s *Server) SetNotifier(notifier Notifier) {
+	s.notifier = notifier
+}
+
+// startJob answers a tools/call made with _async: true. The call runs in
+// the background; the result names the job to poll with get_job_result.
+func (s *Server) startJob(ctx context.Context, id json.RawMessage, executor Executor, tool *Tool, args map[string]any, sessionID string) *rpcResponse {
+	jobExec, ok := executor.(JobExecutor)
+	if !ok {
+		return rpcErrorResponse(id, -32601, "async execution is not supported", nil)
+	}
+	reqBytes, _ := json.Marshal(args)
+	reqSize := int64(len(reqBytes))
+	if s.toolCallStartHook != nil {
+		s.toolCallStartHook(ctx, ToolCallStartEvent{
+			SessionID: sessionID,
+			ToolName:  tool.Name,
+			APIName:   tool.Operation.ServiceName,
+		})
+	}
+
+	job, err := jobExec.StartJob(ctx, tool.Operation, args, sessionID, func(job jobs.Job) {
+		s.jobFinished(context.WithoutCancel(ctx), job, tool, args, reqSize)
+	})
+	if err != nil {
+		return rpcErrorResponse(id, -32000, s.redactor.Redact(err.Error()), nil)
+	}
+	encoded, _ := json.Marshal(map[string]any{
+		"job_id":    job.ID,
+		"tool":      job.Tool,
+		"status":    job.Status,
+		"poll_with": canonical.ToolName(config.BuiltinServiceName, "get_job_result"),
+	})
+	return rpcSuccess(id, map[string]any{
+		"content": []map[string]any{{"type": "text", "text": string(encoded)}},
+		"isError": false,
+	})
+}
+
+// jobFinished reports a finished async call to the audit hook and to the
+// session that started it.
+func (s *Server) jobFinished(ctx context.Context, job jobs.Job, tool *Tool, args map[string]any, reqSize int64) {
+	if s.toolCallHook != nil {
+		resBytes, _ := json.Marshal(job.Result)
+		s.toolCallHook(ctx, ToolCallEvent{
+			SessionID:    job.Owner,
+			ToolName:     tool.Name,
+			APIName:      tool.Operation.ServiceName,
+			Arguments:    args,
+			Duration:     time.Duration(job.DurationMs) * time.Millisecond,
+			Success:      job.Status == jobs.StatusSucceeded,
+			ErrorMsg:     job.Error,
+			RequestSize:  reqSize,
+			ResponseSize: int64(len(resBytes)),
+		})
+	}
+	if s.notifier == nil || job.Owner == "" {
+		return
+	}
+	params := map[string]any{"job_id": job.ID, "tool": job.Tool, "status": job.Status}
+	if job.Error != "" {
+		params["error"] = s.redactor.Redact(job.Error)
+	}
+	if !s.notifier(job.Owner, JobCompletedMethod, params) {
+		s.logger.Debug("job finished after its session ended", "component", "mcp", "job_id", job.ID)
+	}
+}
@@ -4,22 +4,30 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"reflect"
 	"sort"
 	"strings"
 
 	"github.com/santhosh-tekuri/jsonschema/v5"
 
 	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
 )
 
 type Tool struct {
 	Name         string
+	Title        string
 	Description  string
 	InputSchema  map[string]any
 	OutputSchema map[string]any
 	Annotations  map[string]any
 	Operation    *canonical.Operation
 	Validator    *jsonschema.Schema
+	Examples     []any  // synthesized example arguments, see RegistryOptions
+	AliasOf      string // the tool this deprecated alias stands for, see RegistryOptions
+
+	lazy    *lazyValidator // set when Validator is compiled on first use
+	service string         // the service the tool was registered for
 }
 
 type Resource struct {
@@ -34,20 +42,68 @@ type Resource struct {
 type Registry struct {
 	Tools     map[string]*Tool
 	Resources map[string]*Resource
+
+	schemaStats RegistryStats
+}
+
+// RegistryOptions tunes how tools are presented to clients.
+type RegistryOptions struct {
+	// Examples adds example arguments synthesized from each input schema:
+	// "description" appends the required-only example (or the full one when
+	// nothing is required) to the description,
+	// "field" lists the required-only and full examples in the tool's _meta.
+	Examples string
+	// Aliases maps extra tool names to existing tools. Each alias is
+	// listed as a deprecated copy of its tool and calls run the tool.
+	Aliases map[string]string
+	// Overrides replaces or extends the description, title and annotations
+	// of the tools they name.
+	Overrides map[string]config.ToolOverride
 }
 
 func NewRegistry(services []*canonical.Service) (*Registry, error) {
+	return NewRegistryWithOptions(services, RegistryOptions{})
+}
+
+func NewRegistryWithOptions(services []*canonical.Service, opts RegistryOptions) (*Registry, error) {
+	return NewRegistryReusing(nil, services, nil, opts)
+}
+
+// NewRegistryReusing builds a registry like NewRegistryWithOptions but
+// takes the tools of the services named in unchanged from prev instead of
+// building them again, so an update that touched one API leaves the
+// others' tools, and their compiled validators, as they were. prev must
+// have been built with the same options.
+func NewRegistryReusing(prev *Registry, services []*canonical.Service, unchanged map[string]bool, opts RegistryOptions) (*Registry, error) {
 	registry := &Registry{
 		Tools:     map[string]*Tool{},
 		Resources: map[string]*Resource{},
 	}
+	var reusable map[string][]*Tool
+	if prev != nil && len(unchanged) > 0 {
+		reusable = map[string][]*Tool{}
+		for _, tool := range prev.Tools {
+			if unchanged[tool.service] && tool.AliasOf == "" {
+				reusable[tool.service] = append(reusable[tool.service], tool)
+			}
+		}
+	}
+	interner := newSchemaInterner()
 	for _, svc := range services {
-		for _, op := range svc.Operations {
-			validator, err := compileSchema(op.InputSchema)
-			if err != nil {
-				// Best-effort: keep tool registration even if schema compilation fails.
-				validator = nil
+		if tools, ok := reusable[svc.Name]; ok {
+			for _, tool := range tools {
+				// Seed the interner so new tools share the reused schemas.
+				interner.intern(tool.Operation.InputSchema)
+				interner.intern(tool.Operation.ResponseSchema)
+				if err := registry.add(svc.Name, tool); err != nil {
+					return nil, err
+				}
 			}
+			continue
+		}
+		for _, op := range svc.Operations {
+			op.InputSchema = interner.intern(op.InputSchema)
+			op.ResponseSchema = interner.intern(op.ResponseSchema)
 			tool := &Tool{
 				Name:         op.ToolName,
 				Description:  buildDescription(op),
@@ -55,22 +111,135 @@ func NewRegistry(services []*canonical.Service) (*Registry, error) {
 				OutputSchema: outputSchema(op.ResponseSchema),
 				Annotations:  buildAnnotations(op),
 				Operation:    op,
-				Validator:    validator,
+				lazy:         &lazyValidator{},
+				service:      svc.Name,
+			}
+			if override, ok := opts.Overrides[tool.Name]; ok {
+				applyOverride(tool, override)
 			}
-			registry.Tools[tool.Name] = tool
-			resource := &Resource{
-				URI:         fmt.Sprintf("api://%s/%s", svc.Name, op.ID),
-				Name:        tool.Name,
-				MimeType:    "application/json",
-				Description: op.Summary,
-				ToolName:    tool.Name,
+			applyExamples(tool, opts.Examples)
+			if err := registry.add(svc.Name, tool); err != nil {
+				return nil, err
 			}
-			registry.Resources[resource.URI] = resource
 		}
 	}
+	for name := range opts.Overrides {
+		if _, ok := registry.Tools[name]; !ok {
+			return nil, fmt.Errorf("tool_overrides: unknown tool %s", name)
+		}
+	}
+	if err := registry.addAliases(opts.Aliases); err != nil {
+		return nil, err
+	}
+	registry.schemaStats.SchemaNodes = interner.total
+	registry.schemaStats.UniqueSchemaNodes = interner.unique
 	return registry, nil
 }
 
+// addAliases registers a deprecated copy of the target tool under each
+// alias. Aliases get no resource of their own.
+func (r *Registry) addAliases(aliases map[string]string) error {
+	names := make([]string, 0, len(aliases))
+	for alias := range aliases {
+		names = append(names, alias)
+	}
+	sort.Strings(names)
+	for _, alias := range names {
+		target := aliases[alias]
+		if existing, dup := r.Tools[alias]; dup {
+			return fmt.Errorf("tool alias %s clashes with the tool of operation %s/%s; remove it from tool_aliases",
+				alias, existing.Operation.ServiceName, existing.Operation.ID)
+		}
+		tool, ok := r.Tools[target]
+		if !ok || tool.AliasOf != "" {
+			return fmt.Errorf("tool alias %s points to unknown tool %s", alias, target)
+		}
+		copied := *tool
+		copied.Name = alias
+		copied.Description = fmt.Sprintf("Deprecated: use %s instead. %s", target, tool.Description)
+		copied.AliasOf = target
+		r.Tools[alias] = &copied
+	}
+	return nil
+}
+
+// add registers tool and its resource.
+func (r *Registry) add(service string, tool *Tool) error {
+	op := tool.Operation
+	if existing, dup := r.Tools[tool.Name]; dup {
+		return fmt.Errorf("duplicate tool name %s (operations %s/%s and %s/%s); rename one with tool_names or tool_prefix",
+			tool.Name, existing.Operation.ServiceName, existing.Operation.ID, op.ServiceName, op.ID)
+	}
+	r.Tools[tool.Name] = tool
+	resource := &Resource{
+		URI:         fmt.Sprintf("api://%s/%s", service, op.ID),
+		Name:        tool.Name,
+		MimeType:    "application/json",
+		Description: op.Summary,
+		ToolName:    tool.Name,
+	}
+	r.Resources[resource.URI] = resource
+	return nil
+}
+
+// applyOverride applies the configured description, title and hints.
+func applyOverride(tool *Tool, override config.ToolOverride) {
+	if override.Description != "" {
+		tool.Description = override.Description
+	}
+	if override.Hint != "" {
+		tool.Description = strings.TrimSpace(tool.Description + " " + override.Hint)
+	}
+	if override.Title != "" {
+		tool.Title = override.Title
+		tool.Annotations["title"] = override.Title
+	}
+	for key, hint := range map[string]*bool{
+		"readOnlyHint":    override.ReadOnly,
+		"destructiveHint": override.Destructive,
+		"idempotentHint":  override.Idempotent,
+		"openWorldHint":   override.OpenWorld,
+	} {
+		if hint != nil {
+			tool.Annotations[key] = *hint
+		}
+	}
+	// A tool marked read-only is not destructive unless said so.
+	if override.ReadOnly != nil && *override.ReadOnly && override.Destructive == nil {
+		tool.Annotations["destructiveHint"] = false
+	}
+}
+
+// maxDescriptionExample keeps examples embedded in descriptions short; a
+// larger example is left for the examples field or describe_tool.
+const maxDescriptionExample = 400
+
+func applyExamples(tool *Tool, mode string) {
+	if mode != "description" && mode != "field" {
+		return
+	}
+	if props, _ := tool.InputSchema["properties"].(map[string]any); len(props) == 0 {
+		return
+	}
+	minimal := canonical.ExampleValue(tool.InputSchema, true)
+	if mode == "description" {
+		example := minimal
+		if m, ok := minimal.(map[string]any); ok && len(m) == 0 {
+			example = canonical.ExampleValue(tool.InputSchema, false)
+		}
+		encoded, err := json.Marshal(example)
+		if err != nil || len(encoded) > maxDescriptionExample {
+			return
+		}
+		tool.Description += " Example: " + string(encoded)
+		return
+	}
+	tool.Examples = []any{minimal}
+	if full := canonical.ExampleValue(tool.InputSchema, false); !reflect.DeepEqual(full, minimal) {
+		tool.Examples = append(tool.Examples, full)
+	}
+}
+
 func outputSchema(bodySchema map[string]any) map[string]any {
 	body := bodySchema
 	if body == nil {
@@ -178,8 +347,8 @@ func buildAnnotations(op *canonical.Operation) map[string]any {
 		}
 	} else if op.GraphQL != nil {
 		readOnly = op.GraphQL.OperationType == "query"
-	} else if op.Protocol == "grpc" || op.SoapNamespace != "" || op.JSONRPC != nil {
-		// gRPC, SOAP, JSON-RPC: can't infer safely, use conservative defaults
+	} else if op.Protocol == "grpc" || op.SoapNamespace != "" || op.JSONRPC != nil || op.ODataBatch != nil {
+		// gRPC, SOAP, JSON-RPC, OData $batch: can't infer safely, use conservative defaults
 		readOnly = false
 		destructive = false
 	} else {
@@ -189,12 +358,20 @@ func buildAnnotations(op *canonical.Operation) map[string]any {
 		idempotent = a.idempotent
 	}
 
-	return map[string]any{
+	annotations := map[string]any{
 		"readOnlyHint":    readOnly,
 		"destructiveHint": destructive,
 		"idempotentHint":  idempotent,
 		"openWorldHint":   true,
 	}
+	if len(op.Security) > 0 {
+		schemes := make([]string, 0, len(op.Security))
+		for _, s := range op.Security {
+			schemes = append(schemes, s.Type)
+		}
+		annotations["authSchemes"] = schemes
+	}
+	return annotations
 }
 
 type methodHints struct {
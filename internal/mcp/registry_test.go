@@ -0,0 +1,217 @@
+package mcp
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/logging"
+	"skyline-mcp/internal/redact"
+)
+
+func TestRegistryExamples(t *testing.T) {
+	op := &canonical.Operation{
+		ServiceName: "pets",
+		ID:          "listPets",
+		ToolName:    "pets__listPets",
+		Method:      "get",
+		Path:        "/pets",
+		Summary:     "List pets",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"status": map[string]any{"type": "string", "enum": []any{"available", "sold"}},
+				"limit":  map[string]any{"type": "integer", "default": 20},
+				"since":  map[string]any{"type": "string", "format": "date-time"},
+			},
+			"required": []any{"status"},
+		},
+	}
+	services := []*canonical.Service{{Name: "pets", Operations: []*canonical.Operation{op}}}
+
+	plain, err := NewRegistry(services)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tool := plain.Tools["pets__listPets"]; strings.Contains(tool.Description, "Example") || tool.Examples != nil {
+		t.Fatalf("examples added without the option: %+v", tool)
+	}
+
+	described, _ := NewRegistryWithOptions(services, RegistryOptions{Examples: "description"})
+	if desc := described.Tools["pets__listPets"].Description; !strings.HasSuffix(desc, ` Example: {"status":"available"}`) {
+		t.Fatalf("description = %q", desc)
+	}
+
+	fielded, _ := NewRegistryWithOptions(services, RegistryOptions{Examples: "field"})
+	tool := fielded.Tools["pets__listPets"]
+	if len(tool.Examples) != 2 {
+		t.Fatalf("examples = %#v, want required-only and full", tool.Examples)
+	}
+	full, _ := json.Marshal(tool.Examples[1])
+	if string(full) != `{"limit":20,"since":"2024-01-15T09:30:00Z","status":"available"}` {
+		t.Fatalf("full example = %s", full)
+	}
+	server := NewServer(fielded, nil, logging.Discard(), redact.NewRedactor(), "test")
+	resp := server.handleListTools(json.RawMessage(`1`))
+	tools, _ := resp.Result.(map[string]any)["tools"].([]map[string]any)
+	if meta, _ := tools[0]["_meta"].(map[string]any); len(meta["examples"].([]any)) != 2 {
+		t.Fatalf("tools/list entry = %#v, want _meta.examples", tools[0])
+	}
+}
+
+func TestRegistryRejectsDuplicateToolNames(t *testing.T) {
+	services := []*canonical.Service{
+		{Name: "a", Operations: []*canonical.Operation{{ServiceName: "a", ID: "list", ToolName: "list"}}},
+		{Name: "b", Operations: []*canonical.Operation{{ServiceName: "b", ID: "list", ToolName: "list"}}},
+	}
+	_, err := NewRegistry(services)
+	if err == nil || !strings.Contains(err.Error(), "duplicate tool name list") || !strings.Contains(err.Error(), "a/list and b/list") {
+		t.Fatalf("err = %v, want duplicate tool name error", err)
+	}
+}
+
+func TestRegistryAliases(t *testing.T) {
+	services := []*canonical.Service{{Name: "crm", Operations: []*canonical.Operation{
+		{ServiceName: "crm", ID: "listContacts", ToolName: "crm__listContacts", Summary: "List contacts"},
+	}}}
+	registry, err := NewRegistryWithOptions(services, RegistryOptions{Aliases: map[string]string{"crm__contacts_list": "crm__listContacts"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	alias, ok := registry.Tools["crm__contacts_list"]
+	if !ok || alias.AliasOf != "crm__listContacts" || alias.Operation != registry.Tools["crm__listContacts"].Operation {
+		t.Fatalf("alias = %+v, want a copy of crm__listContacts", alias)
+	}
+	if !strings.HasPrefix(alias.Description, "Deprecated: use crm__listContacts instead.") {
+		t.Fatalf("alias description = %q", alias.Description)
+	}
+	if len(registry.Resources) != 1 {
+		t.Fatalf("resources = %d, want the alias to add none", len(registry.Resources))
+	}
+	server := NewServer(registry, nil, logging.Discard(), redact.NewRedactor(), "test")
+	resp := server.handleListTools(json.RawMessage(`1`))
+	tools, _ := resp.Result.(map[string]any)["tools"].([]map[string]any)
+	if meta, _ := tools[0]["_meta"].(map[string]any); tools[0]["name"] != "crm__contacts_list" || meta["deprecated"] != true || meta["replacedBy"] != "crm__listContacts" {
+		t.Fatalf("tools/list entry = %#v, want deprecated alias", tools[0])
+	}
+
+	for _, tt := range []struct{ alias, target, want string }{
+		{"crm__listContacts", "crm__listContacts", "clashes with the tool of operation crm/listContacts"},
+		{"crm__old", "crm__gone", "points to unknown tool crm__gone"},
+	} {
+		_, err := NewRegistryWithOptions(services, RegistryOptions{Aliases: map[string]string{tt.alias: tt.target}})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("alias %s: err = %v, want %q", tt.alias, err, tt.want)
+		}
+	}
+}
+
+func TestRegistryOverrides(t *testing.T) {
+	services := []*canonical.Service{{Name: "crm", Operations: []*canonical.Operation{
+		{ServiceName: "crm", ID: "purgeCache", ToolName: "crm__purgeCache", Method: "delete", Path: "/cache", Summary: "Purge"},
+	}}}
+	readOnly := true
+	registry, err := NewRegistryWithOptions(services, RegistryOptions{Overrides: map[string]config.ToolOverride{
+		"crm__purgeCache": {Title: "Purge CRM cache", Description: "Drops cached CRM lookups.", Hint: "Only use after bulk imports.", ReadOnly: &readOnly},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tool := registry.Tools["crm__purgeCache"]
+	if tool.Description != "Drops cached CRM lookups. Only use after bulk imports." || tool.Title != "Purge CRM cache" {
+		t.Fatalf("tool = %q / %q", tool.Title, tool.Description)
+	}
+	if tool.Annotations["readOnlyHint"] != true || tool.Annotations["destructiveHint"] != false || tool.Annotations["title"] != "Purge CRM cache" {
+		t.Fatalf("annotations = %#v", tool.Annotations)
+	}
+
+	_, err = NewRegistryWithOptions(services, RegistryOptions{Overrides: map[string]config.ToolOverride{"crm__purge": {Hint: "x"}}})
+	if err == nil || !strings.Contains(err.Error(), "unknown tool crm__purge") {
+		t.Fatalf("err = %v, want unknown tool", err)
+	}
+}
+
+func TestRegistryInternsSchemas(t *testing.T) {
+	page := func() map[string]any {
+		return map[string]any{"type": "integer", "minimum": 1}
+	}
+	newOp := func(id string, extra map[string]any) *canonical.Operation {
+		props := map[string]any{"page": page(), "per_page": page()}
+		for k, v := range extra {
+			props[k] = v
+		}
+		return &canonical.Operation{
+			ServiceName: "gh", ID: id, ToolName: "gh__" + id, Method: "get", Path: "/" + id,
+			InputSchema: map[string]any{"type": "object", "properties": props, "required": []any{"page"}},
+		}
+	}
+	services := []*canonical.Service{{Name: "gh", Operations: []*canonical.Operation{
+		newOp("listRepos", nil),
+		newOp("listIssues", map[string]any{"state": map[string]any{"type": "string"}}),
+	}}}
+	registry, err := NewRegistry(services)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repos, issues := registry.Tools["gh__listRepos"], registry.Tools["gh__listIssues"]
+	reposPage := repos.InputSchema["properties"].(map[string]any)["page"].(map[string]any)
+	issuesPage := issues.InputSchema["properties"].(map[string]any)["per_page"].(map[string]any)
+	if reflect.ValueOf(reposPage).Pointer() != reflect.ValueOf(issuesPage).Pointer() {
+		t.Fatal("identical schema fragments are not shared")
+	}
+	encoded, _ := json.Marshal(issues.InputSchema)
+	if string(encoded) != `{"properties":{"page":{"minimum":1,"type":"integer"},"per_page":{"minimum":1,"type":"integer"},"state":{"type":"string"}},"required":["page"],"type":"object"}` {
+		t.Fatalf("interned schema = %s", encoded)
+	}
+
+	stats := registry.Stats()
+	// 2 root + 2 properties + 4 page + 1 state schema objects; the page
+	// schemas collapse to one.
+	if stats.SchemaNodes != 9 || stats.UniqueSchemaNodes != 6 || stats.CompiledValidators != 0 {
+		t.Fatalf("stats = %+v", stats)
+	}
+	if err := issues.ValidateArguments(map[string]any{}); err == nil {
+		t.Fatal("missing required page accepted")
+	}
+	if err := repos.ValidateArguments(map[string]any{"page": 2}); err != nil {
+		t.Fatalf("valid arguments rejected: %v", err)
+	}
+	if got := registry.Stats().CompiledValidators; got != 2 {
+		t.Fatalf("compiled validators = %d, want 2", got)
+	}
+}
+
+func TestRegistryReusesUnchangedServices(t *testing.T) {
+	op := func(service, id string) *canonical.Operation {
+		return &canonical.Operation{
+			ServiceName: service, ID: id, ToolName: service + "__" + id, Method: "get", Path: "/" + id,
+			InputSchema: map[string]any{"type": "object"},
+		}
+	}
+	prev, err := NewRegistry([]*canonical.Service{
+		{Name: "pets", Operations: []*canonical.Operation{op("pets", "listPets")}},
+		{Name: "owners", Operations: []*canonical.Operation{op("owners", "listOwners")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	next, err := NewRegistryReusing(prev, []*canonical.Service{
+		{Name: "pets", Operations: []*canonical.Operation{op("pets", "listPets")}},
+		{Name: "owners", Operations: []*canonical.Operation{op("owners", "listAllOwners")}},
+	}, map[string]bool{"pets": true}, RegistryOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.Tools["pets__listPets"] != prev.Tools["pets__listPets"] {
+		t.Fatal("tool of an unchanged service was rebuilt")
+	}
+	if _, ok := next.Tools["owners__listOwners"]; ok || next.Tools["owners__listAllOwners"] == nil {
+		t.Fatalf("tools = %v", next.Tools)
+	}
+	if next.Resources["api://pets/listPets"] == nil || next.Resources["api://owners/listAllOwners"] == nil || len(next.Resources) != 2 {
+		t.Fatalf("resources = %v", next.Resources)
+	}
+}
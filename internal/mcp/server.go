@@ -6,9 +6,12 @@ import (
 	"errors"
 	"io"
 	"log/slog"
+	"net/http"
+	"sync"
 	"time"
 
 	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/quota"
 	"skyline-mcp/internal/redact"
 	"skyline-mcp/internal/runtime"
 )
@@ -46,11 +49,41 @@ type Executor interface {
 	Execute(ctx context.Context, op *canonical.Operation, args map[string]any) (*runtime.Result, error)
 }
 
+// SchemaLearner is implemented by executors that infer response schemas
+// from the responses of operations whose specs declare none.
+type SchemaLearner interface {
+	LearnedResponseSchema(op *canonical.Operation) map[string]any
+}
+
+// ToolOutputSchema returns the tool's output schema, using the response
+// schema executor learned when the spec declares none.
+func ToolOutputSchema(tool *Tool, executor Executor) map[string]any {
+	learner, ok := executor.(SchemaLearner)
+	if !ok || tool.Operation == nil {
+		return tool.OutputSchema
+	}
+	if learned := learner.LearnedResponseSchema(tool.Operation); learned != nil {
+		return outputSchema(learned)
+	}
+	return tool.OutputSchema
+}
+
+// RefreshHook reloads the registry on a client's registry/refresh request
+// and returns the result to send back.
+type RefreshHook func(ctx context.Context) (any, error)
+
+// AuthRenewHook is called when an upstream rejects the credentials of a
+// tool call with 401 or 403. It returns true when it renewed the API's
+// credentials, and the call is then retried once. Otherwise it may return
+// instructions for the agent, which replace the error's hint.
+type AuthRenewHook func(ctx context.Context, apiName string, upErr *runtime.UpstreamError) (renewed bool, instructions string)
+
 // SubscribeHook is called when a client subscribes or unsubscribes to a resource.
 // subscribe=true for subscribe, false for unsubscribe. Returns true if successful.
 type SubscribeHook func(sessionID, uri string, subscribe bool) bool
 
 type Server struct {
+	mu                sync.RWMutex // guards registry, executor, maxResponseByAPI and capabilities, which change at runtime
 	registry          *Registry
 	executor          Executor    // Runtime executor for tool calls
 	codeExecutor      interface{} // Code executor for /execute endpoint (optional)
@@ -60,8 +93,13 @@ type Server struct {
 	toolCallHook      ToolCallHook      // Optional hook for audit/metrics on tool calls
 	toolCallStartHook ToolCallStartHook // Optional hook fired before tool execution
 	subscribeHook     SubscribeHook     // Optional hook for resource subscriptions
+	refreshHook       RefreshHook       // Optional; serves registry/refresh
+	authRenewHook     AuthRenewHook     // Optional; renews rejected credentials
 	maxResponseBytes  int               // Default max response size in bytes (0 = no limit)
 	maxResponseByAPI  map[string]int    // Per-API max response bytes (overrides default)
+	profile           string            // Profile name exposed to header templates ({{mcp.profile}})
+	notifier          Notifier          // Optional; delivers job completion notifications to sessions
+	capabilities      *Capabilities     // Optional; reported in the initialize result
 }
 
 func NewServer(registry *Registry, executor Executor, logger *slog.Logger, redactor *redact.Redactor, version string) *Server {
@@ -77,6 +115,29 @@ func NewServer(registry *Registry, executor Executor, logger *slog.Logger, redac
 	}
 }
 
+// SetRegistry atomically replaces the registry and executor serving tool
+// calls, e.g. after upstream specs were re-fetched. Calls already in flight
+// finish against the previous pair.
+func (s *Server) SetRegistry(registry *Registry, executor Executor) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.registry = registry
+	s.executor = executor
+}
+
+// current returns the registry and executor to use for one request.
+func (s *Server) current() (*Registry, Executor) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.registry, s.executor
+}
+
+// Registry returns the registry currently serving tool calls.
+func (s *Server) Registry() *Registry {
+	registry, _ := s.current()
+	return registry
+}
+
 // SetCodeExecutor sets the code executor for /execute endpoint
 func (s *Server) SetCodeExecutor(exec interface{}) {
 	s.codeExecutor = exec
@@ -97,6 +158,24 @@ func (s *Server) SetSubscribeHook(hook SubscribeHook) {
 	s.subscribeHook = hook
 }
 
+// SetRefreshHook enables the registry/refresh method, which lets clients
+// reload the tools after upstream specs changed without waiting for the
+// periodic refresh.
+func (s *Server) SetRefreshHook(hook RefreshHook) {
+	s.refreshHook = hook
+}
+
+// SetAuthRenewHook sets a callback that renews an API's credentials when
+// the upstream rejects them.
+func (s *Server) SetAuthRenewHook(hook AuthRenewHook) {
+	s.authRenewHook = hook
+}
+
+// SetProfile sets the profile name this server is serving.
+func (s *Server) SetProfile(name string) {
+	s.profile = name
+}
+
 // SetMaxResponseBytes sets the default maximum response size for tool call results.
 func (s *Server) SetMaxResponseBytes(maxBytes int) {
 	s.maxResponseBytes = maxBytes
@@ -104,6 +183,8 @@ func (s *Server) SetMaxResponseBytes(maxBytes int) {
 
 // SetMaxResponseBytesByAPI sets per-API maximum response sizes, overriding the default.
 func (s *Server) SetMaxResponseBytesByAPI(m map[string]int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.maxResponseByAPI = m
 }
 
@@ -119,6 +200,16 @@ func (s *Server) Serve(ctx context.Context, in io.Reader, out io.Writer) error {
 			}
 			return err
 		}
+		// STDIO has a single client per process; remember its clientInfo
+		// for the rest of the connection.
+		if req.Method == "initialize" && req.Params != nil {
+			var initParams struct {
+				ClientInfo *ClientInfo `json:"clientInfo"`
+			}
+			if err := json.Unmarshal(req.Params, &initParams); err == nil && initParams.ClientInfo != nil {
+				ctx = context.WithValue(ctx, ClientInfoKey, initParams.ClientInfo)
+			}
+		}
 		resp := s.handleRequest(ctx, &req)
 		if resp == nil {
 			continue
@@ -145,17 +236,7 @@ func (s *Server) handleRequest(ctx context.Context, req *rpcRequest) *rpcRespons
 
 	switch req.Method {
 	case "initialize":
-		return rpcSuccess(req.ID, map[string]any{
-			"protocolVersion": protocolVersion,
-			"capabilities": map[string]any{
-				"tools":     map[string]any{"list": true, "call": true},
-				"resources": map[string]any{"list": true, "read": true, "subscribe": true},
-			},
-			"serverInfo": map[string]any{
-				"name":    "Skyline MCP",
-				"version": s.version,
-			},
-		})
+		return rpcSuccess(req.ID, s.initializeResult())
 	case "tools/list":
 		return s.handleListTools(req.ID)
 	case "tools/call":
@@ -172,31 +253,56 @@ func (s *Server) handleRequest(ctx context.Context, req *rpcRequest) *rpcRespons
 		return s.handleListResourceTemplates(req.ID)
 	case "ping":
 		return rpcSuccess(req.ID, map[string]any{})
+	case "registry/refresh":
+		if s.refreshHook == nil {
+			return rpcErrorResponse(req.ID, -32601, "method not found", nil)
+		}
+		result, err := s.refreshHook(ctx)
+		if err != nil {
+			return rpcErrorResponse(req.ID, -32603, s.redactor.Redact(err.Error()), nil)
+		}
+		return rpcSuccess(req.ID, result)
 	default:
 		return rpcErrorResponse(req.ID, -32601, "method not found", nil)
 	}
 }
 
 func (s *Server) handleListTools(id json.RawMessage) *rpcResponse {
-	tools := s.registry.SortedTools()
+	registry, executor := s.current()
+	tools := registry.SortedTools()
 	result := make([]map[string]any, 0, len(tools))
 	for _, tool := range tools {
 		entry := map[string]any{
 			"name":         tool.Name,
 			"description":  tool.Description,
 			"inputSchema":  tool.InputSchema,
-			"outputSchema": tool.OutputSchema,
+			"outputSchema": ToolOutputSchema(tool, executor),
+		}
+		if tool.Title != "" {
+			entry["title"] = tool.Title
 		}
 		if tool.Annotations != nil {
 			entry["annotations"] = tool.Annotations
 		}
+		meta := map[string]any{}
+		if len(tool.Examples) > 0 {
+			meta["examples"] = tool.Examples
+		}
+		if tool.AliasOf != "" {
+			meta["deprecated"] = true
+			meta["replacedBy"] = tool.AliasOf
+		}
+		if len(meta) > 0 {
+			entry["_meta"] = meta
+		}
 		result = append(result, entry)
 	}
 	return rpcSuccess(id, map[string]any{"tools": result})
 }
 
 func (s *Server) handleListResources(id json.RawMessage) *rpcResponse {
-	resources := s.registry.SortedResources()
+	registry, _ := s.current()
+	resources := registry.SortedResources()
 	result := make([]map[string]any, 0, len(resources))
 	for _, res := range resources {
 		result = append(result, map[string]any{
@@ -209,6 +315,16 @@ func (s *Server) handleListResources(id json.RawMessage) *rpcResponse {
 	return rpcSuccess(id, map[string]any{"resources": result})
 }
 
+// errorResult is a tool result with isError set whose text is
+// {"error": detail}.
+func (s *Server) errorResult(id json.RawMessage, detail any) *rpcResponse {
+	encoded, _ := json.Marshal(map[string]any{"error": detail})
+	return rpcSuccess(id, map[string]any{
+		"content": []map[string]any{{"type": "text", "text": s.redactor.Redact(string(encoded))}},
+		"isError": true,
+	})
+}
+
 func (s *Server) handleCallTool(ctx context.Context, id json.RawMessage, params json.RawMessage) *rpcResponse {
 	var payload toolCallParams
 	if err := json.Unmarshal(params, &payload); err != nil {
@@ -217,22 +333,39 @@ func (s *Server) handleCallTool(ctx context.Context, id json.RawMessage, params
 	if payload.Name == "" {
 		return rpcErrorResponse(id, -32602, "missing tool name", nil)
 	}
-	tool, ok := s.registry.Tools[payload.Name]
+	registry, executor := s.current()
+	tool, ok := registry.Tools[payload.Name]
 	if !ok {
 		return rpcErrorResponse(id, -32601, "unknown tool", nil)
 	}
+	if tool.AliasOf != "" {
+		s.logger.Warn("deprecated tool alias called", "component", "mcp", "alias", payload.Name, "tool", tool.AliasOf)
+	}
 	args := payload.Arguments
 	if args == nil {
 		args = map[string]any{}
 	}
-	if tool.Validator != nil {
-		if err := tool.Validator.Validate(args); err != nil {
-			return rpcErrorResponse(id, -32602, s.redactor.Redact(err.Error()), nil)
-		}
+	ctx, err := runtime.WithTimeoutArgument(ctx, args)
+	if err != nil {
+		return rpcErrorResponse(id, -32602, err.Error(), nil)
+	}
+	async, err := runtime.TakeAsyncArgument(args)
+	if err != nil {
+		return rpcErrorResponse(id, -32602, err.Error(), nil)
+	}
+	if err := tool.ValidateArguments(args); err != nil {
+		return invalidArgumentsResponse(id, err, s.redactor)
 	}
 
 	// Extract session ID from context
 	sessionID, _ := ctx.Value(SessionIDKey).(string)
+	ctx = s.withRequestMeta(ctx, sessionID)
+	if token := payload.Meta.ProgressToken; token != nil && s.notifier != nil && sessionID != "" {
+		ctx = runtime.WithStreamHandler(ctx, s.streamProgress(sessionID, token))
+	}
+	if async {
+		return s.startJob(ctx, id, executor, tool, args, sessionID)
+	}
 
 	// Measure request size for audit
 	reqBytes, _ := json.Marshal(args)
@@ -248,7 +381,10 @@ func (s *Server) handleCallTool(ctx context.Context, id json.RawMessage, params
 	}
 
 	startTime := time.Now()
-	result, err := s.executor.Execute(ctx, tool.Operation, args)
+	result, err := executor.Execute(ctx, tool.Operation, args)
+	if err != nil {
+		result, err = s.retryWithRenewedAuth(ctx, payload.Name, args, result, err)
+	}
 	duration := time.Since(startTime)
 
 	if err != nil {
@@ -264,12 +400,46 @@ func (s *Server) handleCallTool(ctx context.Context, id json.RawMessage, params
 				RequestSize: reqSize,
 			})
 		}
+		// Upstream rejections are tool results, so the agent sees the
+		// upstream's explanation and can fix its arguments.
+		var upErr *runtime.UpstreamError
+		if errors.As(err, &upErr) {
+			return s.errorResult(id, upErr)
+		}
+		// So is an exhausted budget, so the agent stops instead of retrying.
+		var quotaErr *quota.ExceededError
+		if errors.As(err, &quotaErr) {
+			return s.errorResult(id, map[string]any{
+				"code":      "quota_exceeded",
+				"message":   quotaErr.Error(),
+				"scope":     quotaErr.Scope,
+				"period":    quotaErr.Period,
+				"limit":     quotaErr.Limit,
+				"resets_at": quotaErr.ResetsAt,
+			})
+		}
+		// And an API waiting for a user to authorize it, so the agent can
+		// pass on the code.
+		var pendingErr *runtime.DeviceAuthPendingError
+		if errors.As(err, &pendingErr) {
+			return s.errorResult(id, map[string]any{
+				"code":                      "authorization_pending",
+				"message":                   pendingErr.Error(),
+				"user_code":                 pendingErr.Code.UserCode,
+				"verification_uri":          pendingErr.Code.VerificationURI,
+				"verification_uri_complete": pendingErr.Code.VerificationURIComplete,
+				"expires_at":                pendingErr.Code.ExpiresAt,
+			})
+		}
 		return rpcErrorResponse(id, -32000, s.redactor.Redact(err.Error()), nil)
 	}
 
 	// Apply response truncation — per-API limit takes precedence over default
 	maxBytes := s.maxResponseBytes
-	if apiLimit, ok := s.maxResponseByAPI[tool.Operation.ServiceName]; ok {
+	s.mu.RLock()
+	apiLimit, ok := s.maxResponseByAPI[tool.Operation.ServiceName]
+	s.mu.RUnlock()
+	if ok {
 		maxBytes = apiLimit
 	}
 	if maxBytes > 0 {
@@ -300,6 +470,70 @@ func (s *Server) handleCallTool(ctx context.Context, id json.RawMessage, params
 	})
 }
 
+// retryWithRenewedAuth lets the AuthRenewHook renew credentials the
+// upstream rejected, and runs the call again against the registry that
+// holds them. The result and err of the first call are returned otherwise.
+func (s *Server) retryWithRenewedAuth(ctx context.Context, toolName string, args map[string]any, result *runtime.Result, err error) (*runtime.Result, error) {
+	var upErr *runtime.UpstreamError
+	if s.authRenewHook == nil || !errors.As(err, &upErr) ||
+		(upErr.Status != http.StatusUnauthorized && upErr.Status != http.StatusForbidden) {
+		return result, err
+	}
+	registry, _ := s.current()
+	tool, ok := registry.Tools[toolName]
+	if !ok {
+		return result, err
+	}
+	renewed, instructions := s.authRenewHook(ctx, tool.Operation.ServiceName, upErr)
+	if !renewed {
+		if instructions != "" {
+			withHint := *upErr
+			withHint.Hint = instructions
+			return result, &withHint
+		}
+		return result, err
+	}
+	registry, executor := s.current()
+	if tool, ok = registry.Tools[toolName]; !ok {
+		return result, err
+	}
+	return executor.Execute(ctx, tool.Operation, args)
+}
+
+// withRequestMeta exposes session and client details to the runtime executor
+// so per-request header templates ({{mcp.client_name}} etc.) can be evaluated.
+func (s *Server) withRequestMeta(ctx context.Context, sessionID string) context.Context {
+	meta := runtime.RequestMeta{
+		SessionID: sessionID,
+		Profile:   s.profile,
+	}
+	if info, ok := ctx.Value(ClientInfoKey).(*ClientInfo); ok && info != nil {
+		meta.ClientName = info.Name
+		meta.ClientVersion = info.Version
+	}
+	return runtime.WithRequestMeta(ctx, meta)
+}
+
+// ProgressMethod is the notification carrying the values of a streamed
+// response to the session that asked for progress on a tool call.
+const ProgressMethod = "notifications/progress"
+
+// streamProgress forwards each value of a line-delimited upstream response
+// to the session as a progress notification, while the call is running.
+func (s *Server) streamProgress(sessionID string, token any) runtime.StreamHandler {
+	return func(index int, item any) {
+		encoded, err := json.Marshal(item)
+		if err != nil {
+			return
+		}
+		s.notifier(sessionID, ProgressMethod, map[string]any{
+			"progressToken": token,
+			"progress":      index + 1,
+			"message":       s.redactor.Redact(string(encoded)),
+		})
+	}
+}
+
 func (s *Server) handleSubscribe(ctx context.Context, id json.RawMessage, params json.RawMessage, subscribe bool) *rpcResponse {
 	var payload struct {
 		URI string `json:"uri"`
@@ -329,7 +563,8 @@ func (s *Server) handleSubscribe(ctx context.Context, id json.RawMessage, params
 }
 
 func (s *Server) handleListResourceTemplates(id json.RawMessage) *rpcResponse {
-	templates := s.registry.BuildResourceTemplates()
+	registry, _ := s.current()
+	templates := registry.BuildResourceTemplates()
 	return rpcSuccess(id, map[string]any{"resourceTemplates": templates})
 }
 
@@ -341,11 +576,12 @@ func (s *Server) handleReadResource(ctx context.Context, id json.RawMessage, par
 	if payload.URI == "" {
 		return rpcErrorResponse(id, -32602, "missing uri", nil)
 	}
-	res, ok := s.registry.Resources[payload.URI]
+	registry, executor := s.current()
+	res, ok := registry.Resources[payload.URI]
 	if !ok {
 		return rpcErrorResponse(id, -32601, "unknown resource", nil)
 	}
-	tool, ok := s.registry.Tools[res.ToolName]
+	tool, ok := registry.Tools[res.ToolName]
 	if !ok {
 		return rpcErrorResponse(id, -32601, "unknown tool", nil)
 	}
@@ -364,12 +600,16 @@ func (s *Server) handleReadResource(ctx context.Context, id json.RawMessage, par
 		}
 		args = merged
 	}
-	if tool.Validator != nil {
-		if err := tool.Validator.Validate(args); err != nil {
-			return rpcErrorResponse(id, -32602, s.redactor.Redact(err.Error()), nil)
-		}
+	ctx, err := runtime.WithTimeoutArgument(ctx, args)
+	if err != nil {
+		return rpcErrorResponse(id, -32602, err.Error(), nil)
+	}
+	if err := tool.ValidateArguments(args); err != nil {
+		return invalidArgumentsResponse(id, err, s.redactor)
 	}
-	result, err := s.executor.Execute(ctx, tool.Operation, args)
+	sessionID, _ := ctx.Value(SessionIDKey).(string)
+	ctx = s.withRequestMeta(ctx, sessionID)
+	result, err := executor.Execute(ctx, tool.Operation, args)
 	if err != nil {
 		return rpcErrorResponse(id, -32000, s.redactor.Redact(err.Error()), nil)
 	}
@@ -396,6 +636,10 @@ type RPCRequest struct {
 	ID      json.RawMessage `json:"id"`
 	Method  string          `json:"method"`
 	Params  json.RawMessage `json:"params"`
+	// Result and Error are set on responses the client sends to requests
+	// from the server.
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *RPCError       `json:"error,omitempty"`
 }
 
 // RPCResponse represents an MCP JSON-RPC response
@@ -421,6 +665,9 @@ type rpcError = RPCError
 type toolCallParams struct {
 	Name      string         `json:"name"`
 	Arguments map[string]any `json:"arguments"`
+	Meta      struct {
+		ProgressToken any `json:"progressToken"`
+	} `json:"_meta"`
 }
 
 type resourceReadParams struct {
@@ -436,6 +683,16 @@ func rpcSuccess(id json.RawMessage, result any) *rpcResponse {
 	}
 }
 
+// invalidArgumentsResponse reports failed argument validation as an
+// invalid-params error whose data lists each problem field.
+func invalidArgumentsResponse(id json.RawMessage, err error, redactor *redact.Redactor) *rpcResponse {
+	var argErr *ArgumentsError
+	if errors.As(err, &argErr) {
+		return rpcErrorResponse(id, -32602, redactor.Redact(argErr.Error()), argErr)
+	}
+	return rpcErrorResponse(id, -32602, redactor.Redact(err.Error()), nil)
+}
+
 func rpcErrorResponse(id json.RawMessage, code int, message string, data any) *rpcResponse {
 	return &rpcResponse{
 		Jsonrpc: "2.0",
@@ -0,0 +1,245 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/logging"
+	"skyline-mcp/internal/redact"
+	"skyline-mcp/internal/runtime"
+)
+
+type failingExecutor struct{ err error }
+
+func (f failingExecutor) Execute(context.Context, *canonical.Operation, map[string]any) (*runtime.Result, error) {
+	return nil, f.err
+}
+
+func TestCallToolReportsUpstreamErrorsAsToolResults(t *testing.T) {
+	op := &canonical.Operation{ServiceName: "pets", ID: "createPet", ToolName: "pets__createPet", Method: "post", Path: "/pets"}
+	registry, err := NewRegistry([]*canonical.Service{{Name: "pets", Operations: []*canonical.Operation{op}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	upErr := &runtime.UpstreamError{Status: 422, Code: "validation_failed", Message: "name must not be empty", Upstream: map[string]any{"field": "name"}}
+	server := NewServer(registry, failingExecutor{err: upErr}, logging.Discard(), redact.NewRedactor(), "test")
+
+	resp := server.handleCallTool(context.Background(), json.RawMessage(`1`), json.RawMessage(`{"name":"pets__createPet","arguments":{}}`))
+	if resp.Error != nil {
+		t.Fatalf("expected a tool result, got rpc error %+v", resp.Error)
+	}
+	result, _ := resp.Result.(map[string]any)
+	content, _ := result["content"].([]map[string]any)
+	if result["isError"] != true || len(content) != 1 {
+		t.Fatalf("unexpected result: %#v", result)
+	}
+	text, _ := content[0]["text"].(string)
+	if !strings.Contains(text, `"code":"validation_failed"`) || !strings.Contains(text, `"upstream_error":{"field":"name"}`) {
+		t.Fatalf("envelope missing from %s", text)
+	}
+}
+
+type recordingExecutor struct {
+	args map[string]any
+}
+
+func (r *recordingExecutor) Execute(_ context.Context, _ *canonical.Operation, args map[string]any) (*runtime.Result, error) {
+	r.args = args
+	return &runtime.Result{Status: 200, Body: map[string]any{}}, nil
+}
+
+func TestCallToolTakesTimeoutArgument(t *testing.T) {
+	op := &canonical.Operation{ServiceName: "ci", ID: "build", ToolName: "ci__build", Method: "post", Path: "/build"}
+	registry, err := NewRegistry([]*canonical.Service{{Name: "ci", Operations: []*canonical.Operation{op}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exec := &recordingExecutor{}
+	server := NewServer(registry, exec, logging.Discard(), redact.NewRedactor(), "test")
+
+	resp := server.handleCallTool(context.Background(), json.RawMessage(`1`), json.RawMessage(`{"name":"ci__build","arguments":{"_timeout_seconds":120}}`))
+	if resp.Error != nil {
+		t.Fatalf("unexpected rpc error %+v", resp.Error)
+	}
+	if _, ok := exec.args[runtime.TimeoutArgument]; ok {
+		t.Fatalf("timeout argument passed to the executor: %v", exec.args)
+	}
+
+	resp = server.handleCallTool(context.Background(), json.RawMessage(`2`), json.RawMessage(`{"name":"ci__build","arguments":{"_timeout_seconds":"long"}}`))
+	if resp.Error == nil || resp.Error.Code != -32602 {
+		t.Fatalf("expected invalid params, got %+v", resp)
+	}
+}
+
+func TestCallToolAsync(t *testing.T) {
+	op := &canonical.Operation{ServiceName: "ci", ID: "build", ToolName: "ci__build", Method: "post", Path: "/build"}
+	services := []*canonical.Service{{Name: "ci", BaseURL: "http://127.0.0.1:1", Operations: []*canonical.Operation{op}}}
+	registry, err := NewRegistry(services)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{APIs: []config.APIConfig{{Name: "ci", SpecURL: "http://127.0.0.1:1/openapi.json"}}}
+	cfg.ApplyDefaults()
+	exec, err := runtime.NewExecutor(cfg, services, logging.Discard(), redact.NewRedactor())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(registry, exec, logging.Discard(), redact.NewRedactor(), "test")
+	notified := make(chan map[string]any, 1)
+	server.SetNotifier(func(sessionID, method string, params map[string]any) bool {
+		if sessionID == "session-1" && method == JobCompletedMethod {
+			notified <- params
+		}
+		return true
+	})
+
+	ctx := context.WithValue(context.Background(), SessionIDKey, "session-1")
+	resp := server.handleCallTool(ctx, json.RawMessage(`1`), json.RawMessage(`{"name":"ci__build","arguments":{"_async":true}}`))
+	if resp.Error != nil {
+		t.Fatalf("unexpected rpc error %+v", resp.Error)
+	}
+	result, _ := resp.Result.(map[string]any)
+	content, _ := result["content"].([]map[string]any)
+	var started map[string]any
+	if err := json.Unmarshal([]byte(content[0]["text"].(string)), &started); err != nil {
+		t.Fatal(err)
+	}
+	if started["status"] != "running" || started["poll_with"] != "skyline__get_job_result" {
+		t.Fatalf("unexpected job handle %v", started)
+	}
+
+	// The upstream is unreachable, so the job fails and the session hears so.
+	params := <-notified
+	if params["job_id"] != started["job_id"] || params["status"] != "failed" {
+		t.Fatalf("unexpected notification %v", params)
+	}
+}
+
+func TestCallToolStreamsProgress(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		_, _ = w.Write([]byte("{\"event\":\"queued\",\"email\":\"ada@example.com\"}\n{\"event\":\"done\"}\n"))
+	}))
+	defer upstream.Close()
+
+	op := &canonical.Operation{ServiceName: "ci", ID: "watch", ToolName: "ci__watch", Method: "get", Path: "/watch"}
+	services := []*canonical.Service{{Name: "ci", BaseURL: upstream.URL, Operations: []*canonical.Operation{op}}}
+	registry, err := NewRegistry(services)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{APIs: []config.APIConfig{{
+		Name: "ci", SpecURL: upstream.URL + "/openapi.json", BaseURLOverride: upstream.URL,
+		DataPolicy: &config.DataPolicyConfig{Rules: []config.DataPolicyRule{{Fields: []string{"email"}, Action: config.DataPolicyDrop}}},
+	}}}
+	cfg.ApplyDefaults()
+	exec, err := runtime.NewExecutor(cfg, services, logging.Discard(), redact.NewRedactor())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(registry, exec, logging.Discard(), redact.NewRedactor(), "test")
+	var progress []map[string]any
+	server.SetNotifier(func(sessionID, method string, params map[string]any) bool {
+		if sessionID == "session-1" && method == ProgressMethod {
+			progress = append(progress, params)
+		}
+		return true
+	})
+
+	ctx := context.WithValue(context.Background(), SessionIDKey, "session-1")
+	resp := server.handleCallTool(ctx, json.RawMessage(`1`), json.RawMessage(`{"name":"ci__watch","arguments":{},"_meta":{"progressToken":"watch-1"}}`))
+	if resp.Error != nil {
+		t.Fatalf("unexpected rpc error %+v", resp.Error)
+	}
+	if len(progress) != 2 {
+		t.Fatalf("progress = %v, want one notification per line", progress)
+	}
+	if progress[0]["progressToken"] != "watch-1" || progress[0]["progress"] != 1 || progress[0]["message"] != `{"event":"queued"}` {
+		t.Fatalf("first notification = %v", progress[0])
+	}
+
+	// Without a progress token the lines only arrive with the result.
+	progress = nil
+	server.handleCallTool(ctx, json.RawMessage(`2`), json.RawMessage(`{"name":"ci__watch","arguments":{}}`))
+	if len(progress) != 0 {
+		t.Fatalf("progress sent without a token: %v", progress)
+	}
+}
+
+func TestRegistryRefresh(t *testing.T) {
+	empty := &Registry{Tools: map[string]*Tool{}, Resources: map[string]*Resource{}}
+	server := NewServer(empty, nil, logging.Discard(), redact.NewRedactor(), "test")
+	req := &rpcRequest{Jsonrpc: "2.0", ID: json.RawMessage(`1`), Method: "registry/refresh"}
+
+	if resp := server.handleRequest(context.Background(), req); resp.Error == nil || resp.Error.Code != -32601 {
+		t.Fatalf("without a hook: %+v", resp)
+	}
+
+	calls := 0
+	server.SetRefreshHook(func(context.Context) (any, error) {
+		calls++
+		if calls > 1 {
+			return nil, errors.New("spec unreachable")
+		}
+		return map[string]any{"changed": true}, nil
+	})
+	resp := server.handleRequest(context.Background(), req)
+	if resp.Error != nil || resp.Result.(map[string]any)["changed"] != true {
+		t.Fatalf("refresh: %+v", resp)
+	}
+	resp = server.handleRequest(context.Background(), req)
+	if resp.Error == nil || resp.Error.Code != -32603 || !strings.Contains(resp.Error.Message, "spec unreachable") {
+		t.Fatalf("failed refresh: %+v", resp)
+	}
+}
+
+func TestInitializeDescribesServer(t *testing.T) {
+	ops := []*canonical.Operation{
+		{ServiceName: "pets", ID: "listPets", ToolName: "pets__listPets", Method: "get", Path: "/pets"},
+		{ServiceName: "pets", ID: "getPet", ToolName: "pets__getPet", Method: "get", Path: "/pets/{id}"},
+		{ServiceName: "store", ID: "getInventory", ToolName: "store__getInventory", Method: "get", Path: "/inventory"},
+		{ServiceName: config.BuiltinServiceName, ID: "api_health", ToolName: "api_health", Protocol: "builtin"},
+	}
+	registry, err := NewRegistry([]*canonical.Service{
+		{Name: "pets", Operations: ops[:2]},
+		{Name: "store", Operations: ops[2:3]},
+		{Name: config.BuiltinServiceName, Operations: ops[3:]},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(registry, nil, logging.Discard(), redact.NewRedactor(), "1.2.3")
+	server.SetProfile("petshop")
+	req := &rpcRequest{Jsonrpc: "2.0", ID: json.RawMessage(`1`), Method: "initialize"}
+
+	result := server.handleRequest(context.Background(), req).Result.(map[string]any)
+	if _, ok := result["_meta"]; ok {
+		t.Fatal("_meta sent without capabilities")
+	}
+	if got := result["instructions"].(string); !strings.HasPrefix(got, "The tools call these APIs: pets (2), store (1).") || strings.Contains(got, "registry/refresh") {
+		t.Fatalf("instructions = %q", got)
+	}
+
+	server.SetCapabilities(&Capabilities{Version: "1.2.3", Features: map[string]bool{"code_execution": true}})
+	server.SetRefreshHook(func(context.Context) (any, error) { return nil, nil })
+	result = server.handleRequest(context.Background(), req).Result.(map[string]any)
+	caps := result["_meta"].(map[string]any)[capabilitiesMetaKey].(Capabilities)
+	want := ProfileCapabilities{Name: "petshop", Loaded: true, Tools: 4, APIs: map[string]int{"pets": 2, "store": 1}}
+	if !reflect.DeepEqual(caps.Profiles, []ProfileCapabilities{want}) {
+		t.Fatalf("profiles = %+v, want %+v", caps.Profiles, want)
+	}
+	if got := result["instructions"].(string); !strings.Contains(got, "registry/refresh") || !strings.Contains(got, "Code execution") {
+		t.Fatalf("instructions = %q", got)
+	}
+	if server.capabilities.Profiles != nil {
+		t.Fatal("initialize modified the shared capabilities")
+	}
+}
@@ -12,15 +12,26 @@ type ClientInfo struct {
 	Version string `json:"version"`
 }
 
+// SessionControl is the transport side of a session: the tracker asks it
+// for subscription counts and to force-disconnect. StreamableHTTPServer
+// implements it.
+type SessionControl interface {
+	Subscriptions(sessionID string) int
+	CloseSession(sessionID string) bool
+}
+
 // ActiveSession represents a live MCP session with per-session stats.
 type ActiveSession struct {
 	ID            string      `json:"id"`
 	Profile       string      `json:"profile"`
 	ClientInfo    *ClientInfo `json:"client_info"`
+	ClientAddr    string      `json:"client_addr"`
 	ConnectedAt   time.Time   `json:"connected_at"`
 	CurrentTool   string      `json:"current_tool"`
 	ToolStartedAt *time.Time  `json:"tool_started_at,omitempty"`
 
+	control      SessionControl // nil when the transport cannot be controlled
+	inFlight     atomic.Int64   // tool calls executing now
 	requestCount atomic.Int64
 	errorCount   atomic.Int64
 	bytesIn      atomic.Int64
@@ -33,9 +44,12 @@ type SessionSnapshot struct {
 	ID            string      `json:"id"`
 	Profile       string      `json:"profile"`
 	ClientInfo    *ClientInfo `json:"client_info"`
+	ClientAddr    string      `json:"client_addr"`
 	ConnectedAt   time.Time   `json:"connected_at"`
 	CurrentTool   string      `json:"current_tool"`
 	ToolStartedAt *time.Time  `json:"tool_started_at,omitempty"`
+	InFlight      int64       `json:"in_flight"`
+	Subscriptions int         `json:"subscriptions"`
 	RequestCount  int64       `json:"request_count"`
 	ErrorCount    int64       `json:"error_count"`
 	BytesIn       int64       `json:"bytes_in"`
@@ -48,13 +62,20 @@ func (s *ActiveSession) snapshot() SessionSnapshot {
 	toolStartedAt := s.ToolStartedAt
 	s.mu.Unlock()
 
+	subscriptions := 0
+	if s.control != nil {
+		subscriptions = s.control.Subscriptions(s.ID)
+	}
 	return SessionSnapshot{
 		ID:            s.ID,
 		Profile:       s.Profile,
 		ClientInfo:    s.ClientInfo,
+		ClientAddr:    s.ClientAddr,
 		ConnectedAt:   s.ConnectedAt,
 		CurrentTool:   currentTool,
 		ToolStartedAt: toolStartedAt,
+		InFlight:      s.inFlight.Load(),
+		Subscriptions: subscriptions,
 		RequestCount:  s.requestCount.Load(),
 		ErrorCount:    s.errorCount.Load(),
 		BytesIn:       s.bytesIn.Load(),
@@ -75,16 +96,32 @@ func NewSessionTracker() *SessionTracker {
 	}
 }
 
-// Register adds a new active session.
-func (t *SessionTracker) Register(id, profile string, clientInfo *ClientInfo) {
+// Register adds a new active session from its "connected" event. control
+// may be nil.
+func (t *SessionTracker) Register(event SessionEvent, control SessionControl) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
-	t.sessions[id] = &ActiveSession{
-		ID:          id,
-		Profile:     profile,
-		ClientInfo:  clientInfo,
+	t.sessions[event.SessionID] = &ActiveSession{
+		ID:          event.SessionID,
+		Profile:     event.Profile,
+		ClientInfo:  event.ClientInfo,
+		ClientAddr:  event.ClientAddr,
 		ConnectedAt: time.Now(),
+		control:     control,
+	}
+}
+
+// Disconnect force-closes a session through its transport. It returns false
+// when the session is unknown or cannot be closed.
+func (t *SessionTracker) Disconnect(id string) bool {
+	t.mu.RLock()
+	sess, ok := t.sessions[id]
+	t.mu.RUnlock()
+	if !ok || sess.control == nil {
+		return false
 	}
+	// The transport reports the disconnect, which unregisters the session.
+	return sess.control.CloseSession(id)
 }
 
 // Unregister removes a session. Returns true if it existed.
@@ -107,6 +144,7 @@ func (t *SessionTracker) RecordToolStart(sessionID, toolName string) {
 		return
 	}
 	now := time.Now()
+	sess.inFlight.Add(1)
 	sess.mu.Lock()
 	sess.CurrentTool = toolName
 	sess.ToolStartedAt = &now
@@ -121,6 +159,7 @@ func (t *SessionTracker) RecordToolEnd(sessionID, toolName string, success bool,
 	if !ok {
 		return
 	}
+	sess.inFlight.Add(-1)
 	sess.requestCount.Add(1)
 	if !success {
 		sess.errorCount.Add(1)
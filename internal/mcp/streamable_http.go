@@ -9,8 +9,10 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"path"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"skyline-mcp/internal/config"
@@ -21,12 +23,20 @@ type contextKey string
 // SessionIDKey is the context key used to propagate the MCP session ID.
 const SessionIDKey contextKey = "mcp-session-id"
 
+// ClientInfoKey is the context key used to propagate the *ClientInfo sent in initialize.
+const ClientInfoKey contextKey = "mcp-client-info"
+
 // SessionEvent describes an MCP session lifecycle event.
 type SessionEvent struct {
 	Type       string      `json:"type"` // "connected" | "disconnected"
 	SessionID  string      `json:"session_id"`
 	Profile    string      `json:"profile,omitempty"`     // filled by caller
 	ClientInfo *ClientInfo `json:"client_info,omitempty"` // from initialize params
+	ClientAddr string      `json:"client_addr,omitempty"` // address that sent initialize
+	// Reason tells why the server closed a session: "idle" or
+	// "unresponsive" when it was reaped, "terminated" when closed by an
+	// admin. Empty for clients that disconnect themselves.
+	Reason string `json:"reason,omitempty"`
 }
 
 // SessionHook is called when MCP sessions are created or destroyed.
@@ -35,15 +45,65 @@ type SessionHook func(event SessionEvent)
 // StreamableHTTPServer implements MCP Streamable HTTP transport (spec 2025-11-25)
 // Single /mcp endpoint for both POST (requests) and GET (notifications/subscriptions)
 type StreamableHTTPServer struct {
-	server         *Server
-	logger         *slog.Logger
-	auth           *config.AuthConfig
-	store          *streamableSessionStore
-	sessionHook    SessionHook
-	AllowedOrigins []string // CORS allowed origins; if contains "*", all origins are allowed
+	server      *Server
+	logger      *slog.Logger
+	auth        *config.AuthConfig
+	store       *streamableSessionStore
+	sessionHook SessionHook
+	// AllowedOrigins lists browser origins, besides localhost and the
+	// server's own host, that may use the endpoint. Entries are glob
+	// patterns ("https://*.example.com", "http://localhost:*"); "*"
+	// allows every origin. Requests from other origins are rejected.
+	AllowedOrigins []string
+	// AllowedHosts lists the host names, besides localhost, that the
+	// server is reached at, such as the host of its public URL. An origin
+	// on the request's own host is accepted only for these.
+	AllowedHosts   []string
 	OAuthValidator func(token string) (profileToken string, ok bool)
+	// VerifyToken, when set, checks bearer tokens in place of the auth
+	// the server was created with, for callers that keep only a hash of
+	// the token.
+	VerifyToken func(token string) bool
+	// ClientAddr returns the client address recorded for new sessions;
+	// defaults to the request's RemoteAddr.
+	ClientAddr func(r *http.Request) string
+	// OnUnauthorized, when set, is called for every request rejected for
+	// lack of a valid token.
+	OnUnauthorized func(r *http.Request)
+	// ResourceMetadata, when set, returns the URL of the OAuth protected
+	// resource metadata (RFC 9728) named in 401 challenges, so clients can
+	// find the authorization server to get a token from.
+	ResourceMetadata func(r *http.Request) string
+	// QueryToken accepts the bearer token in an access_token query
+	// parameter, for browser clients such as EventSource that cannot set
+	// headers.
+	QueryToken bool
+	// MaxMessageSize caps the size of a request body; 0 means 10MB.
+	MaxMessageSize int64
+	// KeepaliveInterval is how often an idle event stream gets an SSE
+	// comment, so proxies keep it open and dead connections are noticed
+	// on write; 0 means 15s.
+	KeepaliveInterval time.Duration
+	// PingInterval sends a JSON-RPC ping over each open event stream at
+	// this interval; 0 disables pings. A session whose client does not
+	// answer within PingTimeout (0 means twice the interval) is closed.
+	PingInterval time.Duration
+	PingTimeout  time.Duration
+	// CompressMinSize gzips responses of at least this many bytes, and
+	// event streams, for clients that accept gzip; 0 disables compression.
+	CompressMinSize int64
+	idleTimeout     atomic.Int64 // nanoseconds; see SetIdleTimeout
 }
 
+const (
+	defaultMaxMessageSize    = 10 << 20
+	defaultIdleTimeout       = time.Hour
+	defaultKeepaliveInterval = 15 * time.Second
+	// streamWriteTimeout bounds each write to an event stream; a client
+	// that stops reading is disconnected when it expires.
+	streamWriteTimeout = 30 * time.Second
+)
+
 // streamableSession represents an active MCP session with event history for resumability
 type streamableSession struct {
 	id            string
@@ -53,6 +113,13 @@ type streamableSession struct {
 	events        []*sseEvent // Ring buffer for resumability
 	maxEvents     int
 	subscriptions map[string]bool // URIs this session is subscribed to
+	clientInfo    *ClientInfo     // From initialize params; immutable after creation
+	pingID        string          // ID of the unanswered ping, if any
+	pingSent      time.Time
+	pingSeq       int
+	canElicit     bool                        // declared at initialize
+	requestSeq    int                         // numbers requests to the client
+	pending       map[string]chan *rpcRequest // requests awaiting the client's answer
 	mu            sync.RWMutex
 }
 
@@ -66,11 +133,15 @@ type sseEvent struct {
 type streamableSessionStore struct {
 	mu       sync.RWMutex
 	sessions map[string]*streamableSession
+	// terminated holds sessions closed by the server, so requests that
+	// still carry their ID get 404 and the client re-initializes.
+	terminated map[string]time.Time
 }
 
 func newStreamableSessionStore() *streamableSessionStore {
 	return &streamableSessionStore{
-		sessions: make(map[string]*streamableSession),
+		sessions:   make(map[string]*streamableSession),
+		terminated: make(map[string]time.Time),
 	}
 }
 
@@ -112,6 +183,27 @@ func (s *streamableSessionStore) remove(id string) bool {
 	return false
 }
 
+// terminate removes a session and remembers that it was closed.
+func (s *streamableSessionStore) terminate(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.sessions[id]
+	if !ok {
+		return false
+	}
+	close(sess.ch)
+	delete(s.sessions, id)
+	s.terminated[id] = time.Now()
+	return true
+}
+
+func (s *streamableSessionStore) isTerminated(id string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.terminated[id]
+	return ok
+}
+
 // subscribedSessions returns all sessions subscribed to the given URI.
 func (s *streamableSessionStore) subscribedSessions(uri string) []*streamableSession {
 	s.mu.RLock()
@@ -126,6 +218,18 @@ func (s *streamableSessionStore) subscribedSessions(uri string) []*streamableSes
 	return result
 }
 
+// all returns every active session.
+func (s *streamableSessionStore) all() []*streamableSession {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*streamableSession, 0, len(s.sessions))
+	for _, sess := range s.sessions {
+		result = append(result, sess)
+	}
+	return result
+}
+
 func (s *streamableSessionStore) cleanup(maxAge time.Duration) []string {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -139,6 +243,11 @@ func (s *streamableSessionStore) cleanup(maxAge time.Duration) []string {
 			removedIDs = append(removedIDs, id)
 		}
 	}
+	for id, at := range s.terminated {
+		if now.Sub(at) > maxAge {
+			delete(s.terminated, id)
+		}
+	}
 	return removedIDs
 }
 
@@ -181,6 +290,41 @@ func (sess *streamableSession) isSubscribed(uri string) bool {
 	return sess.subscriptions[uri]
 }
 
+func (sess *streamableSession) subscriptionCount() int {
+	sess.mu.RLock()
+	defer sess.mu.RUnlock()
+	return len(sess.subscriptions)
+}
+
+// nextPing returns a ping request to send, or nil while one is still
+// unanswered. overdue reports that the unanswered ping is older than
+// timeout.
+func (sess *streamableSession) nextPing(timeout time.Duration) (ping []byte, overdue bool) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	if sess.pingID != "" {
+		return nil, time.Since(sess.pingSent) > timeout
+	}
+	sess.pingSeq++
+	sess.pingID = fmt.Sprintf("ping-%d", sess.pingSeq)
+	sess.pingSent = time.Now()
+	ping, _ = json.Marshal(map[string]any{"jsonrpc": "2.0", "id": sess.pingID, "method": "ping"})
+	return ping, false
+}
+
+// ackPing records the client's answer to a ping.
+func (sess *streamableSession) ackPing(id json.RawMessage) {
+	var pingID string
+	if json.Unmarshal(id, &pingID) != nil {
+		return
+	}
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	if pingID == sess.pingID {
+		sess.pingID = ""
+	}
+}
+
 func (sess *streamableSession) replayFrom(lastEventID string) []*sseEvent {
 	sess.mu.RLock()
 	defer sess.mu.RUnlock()
@@ -209,6 +353,8 @@ func NewStreamableHTTPServer(server *Server, logger *slog.Logger, auth *config.A
 		auth:   auth,
 		store:  newStreamableSessionStore(),
 	}
+	s.idleTimeout.Store(int64(defaultIdleTimeout))
+	server.SetNotifier(s.notifySession)
 
 	// Start cleanup goroutine
 	go s.cleanupLoop()
@@ -216,37 +362,99 @@ func NewStreamableHTTPServer(server *Server, logger *slog.Logger, auth *config.A
 	return s
 }
 
+// AuthConfig returns the auth the server was created with.
+func (h *StreamableHTTPServer) AuthConfig() *config.AuthConfig {
+	return h.auth
+}
+
+// HasSession reports whether sessionID is a live session on this server.
+func (h *StreamableHTTPServer) HasSession(sessionID string) bool {
+	h.store.mu.RLock()
+	defer h.store.mu.RUnlock()
+	_, ok := h.store.sessions[sessionID]
+	return ok
+}
+
+// CloseSession force-disconnects a session: its notification stream ends
+// and later requests carrying its ID get 404, prompting the client to
+// re-initialize. Requests already executing finish normally.
+func (h *StreamableHTTPServer) CloseSession(sessionID string) bool {
+	if !h.store.terminate(sessionID) {
+		return false
+	}
+	h.logger.Info("session terminated by server", "component", "streamable", "session_id", sessionID)
+	if h.sessionHook != nil {
+		h.sessionHook(SessionEvent{Type: "disconnected", SessionID: sessionID, Reason: "terminated"})
+	}
+	return true
+}
+
+// reap closes a session whose client stopped answering pings.
+func (h *StreamableHTTPServer) reap(sessionID string) {
+	if !h.store.terminate(sessionID) {
+		return
+	}
+	h.logger.Warn("session reaped: client stopped answering pings", "component", "streamable", "session_id", sessionID)
+	if h.sessionHook != nil {
+		h.sessionHook(SessionEvent{Type: "disconnected", SessionID: sessionID, Reason: "unresponsive"})
+	}
+}
+
+// Subscriptions returns the number of resources a session is subscribed
+// to.
+func (h *StreamableHTTPServer) Subscriptions(sessionID string) int {
+	h.store.mu.RLock()
+	sess := h.store.sessions[sessionID]
+	h.store.mu.RUnlock()
+	if sess == nil {
+		return 0
+	}
+	return sess.subscriptionCount()
+}
+
 // SetSessionHook sets a callback that fires when sessions are created or destroyed.
 func (h *StreamableHTTPServer) SetSessionHook(hook SessionHook) {
 	h.sessionHook = hook
 }
 
-func (h *StreamableHTTPServer) cleanupLoop() {
-	ticker := time.NewTicker(5 * time.Minute)
-	defer ticker.Stop()
+// SetIdleTimeout sets how long a session may go without requests before
+// it is closed; 0 restores the default of one hour.
+func (h *StreamableHTTPServer) SetIdleTimeout(d time.Duration) {
+	if d <= 0 {
+		d = defaultIdleTimeout
+	}
+	h.idleTimeout.Store(int64(d))
+}
 
-	for range ticker.C {
-		removedIDs := h.store.cleanup(1 * time.Hour) // Remove sessions inactive for 1 hour
+func (h *StreamableHTTPServer) cleanupLoop() {
+	for {
+		idle := time.Duration(h.idleTimeout.Load())
+		time.Sleep(min(5*time.Minute, max(idle/4, time.Second)))
+		removedIDs := h.store.cleanup(idle)
 		if len(removedIDs) > 0 && h.sessionHook != nil {
 			for _, id := range removedIDs {
-				h.sessionHook(SessionEvent{Type: "disconnected", SessionID: id})
+				h.sessionHook(SessionEvent{Type: "disconnected", SessionID: id, Reason: "idle"})
 			}
 		}
 	}
 }
 
-// isOriginAllowed checks whether the given origin is permitted by the
-// configured AllowedOrigins list. If AllowedOrigins is empty or contains "*",
-// all origins are allowed (security is enforced via bearer token authentication).
+// originPermitted reports whether a request may use the endpoint given its
+// Origin header. Requests without one come from non-browser clients.
+func (h *StreamableHTTPServer) originPermitted(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	return origin == "" || validateOrigin(r, h.AllowedHosts) || h.isOriginAllowed(origin)
+}
+
+// isOriginAllowed checks whether the given origin matches the configured
+// AllowedOrigins list.
 func (h *StreamableHTTPServer) isOriginAllowed(origin string) bool {
-	if len(h.AllowedOrigins) == 0 {
-		return true // no restrictions configured — allow all
-	}
+	origin = strings.ToLower(origin)
 	for _, allowed := range h.AllowedOrigins {
 		if allowed == "*" {
 			return true
 		}
-		if strings.EqualFold(allowed, origin) {
+		if ok, _ := path.Match(strings.ToLower(allowed), origin); ok {
 			return true
 		}
 	}
@@ -270,13 +478,24 @@ func (h *StreamableHTTPServer) ServeHTTP(w http.ResponseWriter, r *http.Request)
 }
 
 func (h *StreamableHTTPServer) handleMCP(w http.ResponseWriter, r *http.Request) {
+	// Browsers send Origin; rejecting unknown ones stops other sites (and
+	// DNS rebinding) from driving the endpoint with the user's network
+	// access.
+	if !h.originPermitted(r) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
 	// Set CORS headers on all responses (not just OPTIONS)
-	origin := r.Header.Get("Origin")
-	if origin != "" && h.isOriginAllowed(origin) {
+	if origin := r.Header.Get("Origin"); origin != "" {
 		w.Header().Set("Access-Control-Allow-Origin", origin)
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 		w.Header().Set("Access-Control-Expose-Headers", "Mcp-Session-Id")
 	}
+	if h.CompressMinSize > 0 && acceptsGzip(r) {
+		gw := newGzipResponseWriter(w, h.CompressMinSize)
+		defer gw.Close()
+		w = gw
+	}
 
 	// Route based on method
 	switch r.Method {
@@ -313,6 +532,10 @@ func (h *StreamableHTTPServer) handleGET(w http.ResponseWriter, r *http.Request)
 
 	// Get or create session
 	sess := h.store.get(sessionID)
+	if sess == nil && h.store.isTerminated(sessionID) {
+		http.Error(w, "session terminated", http.StatusNotFound)
+		return
+	}
 	if sess == nil {
 		// Session doesn't exist - client should initialize first
 		http.Error(w, "session not found - initialize first", http.StatusNotFound)
@@ -341,7 +564,7 @@ func (h *StreamableHTTPServer) handleGET(w http.ResponseWriter, r *http.Request)
 	w.WriteHeader(http.StatusOK)
 
 	// Write an initial SSE comment + flush to force headers out to the client
-	_ = rc.SetWriteDeadline(time.Now().Add(30 * time.Second))
+	_ = rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
 	if _, err := io.WriteString(w, ": connected\n\n"); err != nil {
 		h.logger.Warn("SSE GET: failed to write initial comment", "error", err, "session_id", sessionID)
 		return
@@ -355,7 +578,7 @@ func (h *StreamableHTTPServer) handleGET(w http.ResponseWriter, r *http.Request)
 		// Replay missed events
 		replayEvents := sess.replayFrom(lastEventID)
 		for _, evt := range replayEvents {
-			_ = rc.SetWriteDeadline(time.Now().Add(30 * time.Second))
+			_ = rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
 			if err := h.writeSSEWithID(w, evt.name, evt.data, evt.id); err != nil {
 				return
 			}
@@ -363,9 +586,23 @@ func (h *StreamableHTTPServer) handleGET(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
-	// Send heartbeat pings and notifications
-	ticker := time.NewTicker(15 * time.Second)
+	// Send keepalive comments, pings and notifications
+	keepalive := h.KeepaliveInterval
+	if keepalive <= 0 {
+		keepalive = defaultKeepaliveInterval
+	}
+	ticker := time.NewTicker(keepalive)
 	defer ticker.Stop()
+	var pings <-chan time.Time
+	pingTimeout := h.PingTimeout
+	if h.PingInterval > 0 {
+		pingTicker := time.NewTicker(h.PingInterval)
+		defer pingTicker.Stop()
+		pings = pingTicker.C
+		if pingTimeout <= 0 {
+			pingTimeout = 2 * h.PingInterval
+		}
+	}
 
 	h.logger.Info("SSE GET stream opened", "session_id", sessionID)
 
@@ -377,15 +614,35 @@ func (h *StreamableHTTPServer) handleGET(w http.ResponseWriter, r *http.Request)
 
 		case <-ticker.C:
 			// Send heartbeat comment (keeps connection alive + resets write deadline)
-			_ = rc.SetWriteDeadline(time.Now().Add(30 * time.Second))
+			_ = rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
 			if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
 				return
 			}
 			flusher.Flush()
 
-		case event := <-sess.ch:
+		case <-pings:
+			ping, overdue := sess.nextPing(pingTimeout)
+			if overdue {
+				h.reap(sessionID)
+				return
+			}
+			if ping == nil {
+				continue
+			}
+			_ = rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+			if err := h.writeSSEWithID(w, "message", ping, ""); err != nil {
+				return
+			}
+			flusher.Flush()
+
+		case event, ok := <-sess.ch:
+			if !ok {
+				// The session was closed.
+				h.logger.Info("SSE GET stream closed", "session_id", sessionID)
+				return
+			}
 			// Send notification/request from server
-			_ = rc.SetWriteDeadline(time.Now().Add(30 * time.Second))
+			_ = rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
 			if err := h.writeSSEWithID(w, event.name, event.data, event.id); err != nil {
 				return
 			}
@@ -408,9 +665,17 @@ func (h *StreamableHTTPServer) handlePOST(w http.ResponseWriter, r *http.Request
 		http.Error(w, "unsupported protocol version", http.StatusBadRequest)
 		return
 	}
+	if sessionID := r.Header.Get("Mcp-Session-Id"); sessionID != "" && h.store.isTerminated(sessionID) {
+		http.Error(w, "session terminated", http.StatusNotFound)
+		return
+	}
 
 	// Read request body
-	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 10*1024*1024)) // 10MB limit
+	limit := h.MaxMessageSize
+	if limit <= 0 {
+		limit = defaultMaxMessageSize
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, limit))
 	if err != nil {
 		http.Error(w, "request too large", http.StatusRequestEntityTooLarge)
 		return
@@ -422,6 +687,9 @@ func (h *StreamableHTTPServer) handlePOST(w http.ResponseWriter, r *http.Request
 	}
 
 	ctx := r.Context()
+	// Tool calls may outlast the server's WriteTimeout when callers raise
+	// _timeout_seconds; the executor's deadline bounds them instead.
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
 
 	// Handle batch requests
 	if body[0] == '[' {
@@ -433,6 +701,9 @@ func (h *StreamableHTTPServer) handlePOST(w http.ResponseWriter, r *http.Request
 
 		var responses []*rpcResponse
 		for i := range batch {
+			if h.acceptClientResponse(r, &batch[i]) {
+				continue
+			}
 			resp := h.server.handleRequest(ctx, &batch[i])
 			if resp != nil {
 				responses = append(responses, resp)
@@ -458,6 +729,11 @@ func (h *StreamableHTTPServer) handlePOST(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	if h.acceptClientResponse(r, &req) {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
 	// Special handling for initialize - create session and return session ID
 	if req.Method == "initialize" {
 		sessionID := newSessionID()
@@ -465,11 +741,18 @@ func (h *StreamableHTTPServer) handlePOST(w http.ResponseWriter, r *http.Request
 
 		// Parse clientInfo from initialize params
 		var initParams struct {
-			ClientInfo *ClientInfo `json:"clientInfo"`
+			ClientInfo   *ClientInfo        `json:"clientInfo"`
+			Capabilities clientCapabilities `json:"capabilities"`
 		}
 		if req.Params != nil {
 			_ = json.Unmarshal(req.Params, &initParams)
 		}
+		sess.clientInfo = initParams.ClientInfo
+		sess.canElicit = initParams.Capabilities.Elicitation != nil
+		clientAddr := r.RemoteAddr
+		if h.ClientAddr != nil {
+			clientAddr = h.ClientAddr(r)
+		}
 
 		h.logger.Info("session created", "component", "streamable", "session_id", sessionID, "client_info", initParams.ClientInfo)
 		if h.sessionHook != nil {
@@ -477,6 +760,7 @@ func (h *StreamableHTTPServer) handlePOST(w http.ResponseWriter, r *http.Request
 				Type:       "connected",
 				SessionID:  sessionID,
 				ClientInfo: initParams.ClientInfo,
+				ClientAddr: clientAddr,
 			})
 		}
 
@@ -499,9 +783,16 @@ func (h *StreamableHTTPServer) handlePOST(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// Inject session ID into context for tool call tracking
+	// Inject session ID (and the client info captured at initialize) into
+	// context for tool call tracking and header templating
 	if sessionID := r.Header.Get("Mcp-Session-Id"); sessionID != "" {
 		ctx = context.WithValue(ctx, SessionIDKey, sessionID)
+		if sess := h.store.get(sessionID); sess != nil {
+			if sess.clientInfo != nil {
+				ctx = context.WithValue(ctx, ClientInfoKey, sess.clientInfo)
+			}
+			ctx = sess.withElicitor(ctx)
+		}
 	}
 
 	// For other requests, handle normally
@@ -529,6 +820,19 @@ func (h *StreamableHTTPServer) handlePOST(w http.ResponseWriter, r *http.Request
 	}
 }
 
+// acceptClientResponse consumes a JSON-RPC response from the client,
+// such as the answer to a ping or an elicitation, and reports whether msg
+// was one.
+func (h *StreamableHTTPServer) acceptClientResponse(r *http.Request, msg *rpcRequest) bool {
+	if msg.Method != "" || len(msg.ID) == 0 {
+		return false
+	}
+	if sess := h.store.get(r.Header.Get("Mcp-Session-Id")); sess != nil && !sess.answer(msg) {
+		sess.ackPing(msg.ID)
+	}
+	return true
+}
+
 // handleDELETE implements DELETE /mcp for explicit session termination
 func (h *StreamableHTTPServer) handleDELETE(w http.ResponseWriter, r *http.Request) {
 	if !h.authorizeWithOAuthFallback(w, r) {
@@ -560,6 +864,11 @@ func (h *StreamableHTTPServer) handleOPTIONS(w http.ResponseWriter, r *http.Requ
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// Server returns the MCP server handling this transport's requests.
+func (h *StreamableHTTPServer) Server() *Server {
+	return h.server
+}
+
 // NotifyResourceUpdated pushes a notifications/resources/updated event to all
 // sessions subscribed to the given URI. This is the main entry point for
 // wiring external events (e.g. IDLE new-email) into MCP resource subscriptions.
@@ -568,35 +877,69 @@ func (h *StreamableHTTPServer) NotifyResourceUpdated(uri string) {
 	if len(sessions) == 0 {
 		return
 	}
+	h.notify(sessions, "notifications/resources/updated", map[string]any{"uri": uri})
+	h.logger.Debug("pushed resource update notification",
+		"uri", uri,
+		"sessions", len(sessions),
+	)
+}
+
+// NotifyToolsListChanged pushes a notifications/tools/list_changed event to
+// every session so clients re-fetch tools/list after the registry changed.
+func (h *StreamableHTTPServer) NotifyToolsListChanged() {
+	sessions := h.store.all()
+	if len(sessions) == 0 {
+		return
+	}
+	h.notify(sessions, "notifications/tools/list_changed", nil)
+	h.logger.Debug("pushed tools list changed notification", "sessions", len(sessions))
+}
 
+// NotifyMessage pushes a notifications/message log message to every
+// session, for something the user must act on, such as authorizing an API.
+// level is an RFC 5424 severity name, as in logging/setLevel.
+func (h *StreamableHTTPServer) NotifyMessage(level, logger string, data any) {
+	sessions := h.store.all()
+	if len(sessions) == 0 {
+		return
+	}
+	h.notify(sessions, "notifications/message", map[string]any{"level": level, "logger": logger, "data": data})
+}
+
+// notifySession queues a JSON-RPC notification on one session's event
+// stream, e.g. when an async tool call it started has finished.
+func (h *StreamableHTTPServer) notifySession(sessionID, method string, params map[string]any) bool {
+	sess := h.store.get(sessionID)
+	if sess == nil {
+		return false
+	}
+	h.notify([]*streamableSession{sess}, method, params)
+	return true
+}
+
+// notify queues a JSON-RPC notification on each session's event stream.
+func (h *StreamableHTTPServer) notify(sessions []*streamableSession, method string, params map[string]any) {
 	notification := map[string]any{
 		"jsonrpc": "2.0",
-		"method":  "notifications/resources/updated",
-		"params": map[string]any{
-			"uri": uri,
-		},
+		"method":  method,
+	}
+	if params != nil {
+		notification["params"] = params
 	}
 	data, err := json.Marshal(notification)
 	if err != nil {
-		h.logger.Error("failed to marshal resource notification", "error", err, "uri", uri)
+		h.logger.Error("failed to marshal notification", "error", err, "method", method)
 		return
 	}
 
-	eventID := fmt.Sprintf("notify-%d", time.Now().UnixNano())
 	event := &sseEvent{
-		id:   eventID,
+		id:   fmt.Sprintf("notify-%d", time.Now().UnixNano()),
 		name: "message",
 		data: data,
 	}
-
 	for _, sess := range sessions {
 		sess.addEvent(event)
 	}
-
-	h.logger.Debug("pushed resource update notification",
-		"uri", uri,
-		"sessions", len(sessions),
-	)
 }
 
 // SubscribeSession subscribes a session to a resource URI.
@@ -623,9 +966,8 @@ func (h *StreamableHTTPServer) UnsubscribeSession(sessionID, uri string) bool {
 
 // sendInitialNotifications sends any initial server notifications after session creation
 func (h *StreamableHTTPServer) sendInitialNotifications(sess *streamableSession) {
-	// Example: Send tools/list_changed notification if server supports dynamic tool updates
-	// This would be called whenever tools change, not just on initialization
-	// For now, this is a placeholder for future notification support
+	// tools/list_changed is pushed later via NotifyToolsListChanged when the
+	// registry is rebuilt; nothing needs to be sent at session start.
 }
 
 // writeSSEWithID writes an SSE event with ID (for resumability)
@@ -671,7 +1013,16 @@ func (h *StreamableHTTPServer) writeSSEWithID(w io.Writer, eventName string, dat
 // authorizeWithOAuthFallback checks bearer auth first, then falls back to OAuth token validation.
 // Returns true if the request is authorized.
 func (h *StreamableHTTPServer) authorizeWithOAuthFallback(w http.ResponseWriter, r *http.Request) bool {
-	if authorizeRequest(r, h.auth) {
+	if h.QueryToken && r.Header.Get("Authorization") == "" {
+		if token := r.URL.Query().Get("access_token"); token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+	if h.VerifyToken != nil {
+		if bearer := extractBearerToken(r); bearer != "" && h.VerifyToken(bearer) {
+			return true
+		}
+	} else if authorizeRequest(r, h.auth) {
 		return true
 	}
 	// Try OAuth bearer token
@@ -682,7 +1033,14 @@ func (h *StreamableHTTPServer) authorizeWithOAuthFallback(w http.ResponseWriter,
 			}
 		}
 	}
-	w.Header().Set("WWW-Authenticate", `Bearer`)
+	if h.OnUnauthorized != nil {
+		h.OnUnauthorized(r)
+	}
+	challenge := `Bearer`
+	if h.ResourceMetadata != nil {
+		challenge += fmt.Sprintf(` resource_metadata=%q`, h.ResourceMetadata(r))
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
 	http.Error(w, "unauthorized", http.StatusUnauthorized)
 	return false
 }
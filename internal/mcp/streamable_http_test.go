@@ -0,0 +1,410 @@
+package mcp
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/logging"
+	"skyline-mcp/internal/redact"
+	"skyline-mcp/internal/runtime"
+)
+
+func TestStreamableToolsListChanged(t *testing.T) {
+	logger := logging.Discard()
+	empty := &Registry{Tools: map[string]*Tool{}, Resources: map[string]*Resource{}}
+	server := NewServer(empty, nil, logger, redact.NewRedactor(), "test")
+	streamable := NewStreamableHTTPServer(server, logger, nil)
+	sess := streamable.store.create("session-1")
+
+	updated := &Registry{
+		Tools:     map[string]*Tool{"svc__ping": {Name: "svc__ping", InputSchema: map[string]any{"type": "object"}}},
+		Resources: map[string]*Resource{},
+	}
+	streamable.Server().SetRegistry(updated, nil)
+	streamable.NotifyToolsListChanged()
+
+	select {
+	case event := <-sess.ch:
+		var msg map[string]any
+		if err := json.Unmarshal(event.data, &msg); err != nil {
+			t.Fatalf("decode notification: %v", err)
+		}
+		if msg["method"] != "notifications/tools/list_changed" {
+			t.Fatalf("method = %v, want notifications/tools/list_changed", msg["method"])
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no notification queued")
+	}
+
+	resp := server.handleListTools(json.RawMessage(`1`))
+	result, _ := resp.Result.(map[string]any)
+	tools, _ := result["tools"].([]map[string]any)
+	if len(tools) != 1 || tools[0]["name"] != "svc__ping" {
+		t.Fatalf("tools/list after SetRegistry = %v, want svc__ping", result["tools"])
+	}
+}
+
+func TestStreamableAdminDisconnect(t *testing.T) {
+	logger := logging.Discard()
+	empty := &Registry{Tools: map[string]*Tool{}, Resources: map[string]*Resource{}}
+	server := NewServer(empty, nil, logger, redact.NewRedactor(), "test")
+	streamable := NewStreamableHTTPServer(server, logger, nil)
+	streamable.ClientAddr = func(*http.Request) string { return "203.0.113.7" }
+	tracker := NewSessionTracker()
+	streamable.SetSessionHook(func(event SessionEvent) {
+		event.Profile = "default"
+		if event.Type == "connected" {
+			tracker.Register(event, streamable)
+		} else {
+			tracker.Unregister(event.SessionID)
+		}
+	})
+
+	post := func(sessionID, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(body))
+		req.Header.Set("Accept", "application/json, text/event-stream")
+		if sessionID != "" {
+			req.Header.Set("Mcp-Session-Id", sessionID)
+		}
+		rec := httptest.NewRecorder()
+		streamable.ServeHTTP(rec, req)
+		return rec
+	}
+	rec := post("", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"clientInfo":{"name":"agent","version":"1.0"}}}`)
+	sessionID := rec.Header().Get("Mcp-Session-Id")
+	if sessionID == "" {
+		t.Fatalf("initialize returned no session: %d %s", rec.Code, rec.Body)
+	}
+	streamable.SubscribeSession(sessionID, "email://inbox")
+	tracker.RecordToolStart(sessionID, "svc__slow")
+
+	snap := tracker.Get(sessionID)
+	if snap == nil || snap.ClientAddr != "203.0.113.7" || snap.Subscriptions != 1 || snap.InFlight != 1 || snap.ClientInfo.Name != "agent" {
+		t.Fatalf("unexpected snapshot: %+v", snap)
+	}
+
+	if !tracker.Disconnect(sessionID) {
+		t.Fatal("Disconnect returned false")
+	}
+	if tracker.Get(sessionID) != nil || streamable.HasSession(sessionID) {
+		t.Fatal("session still registered after disconnect")
+	}
+	if rec := post(sessionID, `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`); rec.Code != http.StatusNotFound {
+		t.Fatalf("request on closed session = %d, want 404", rec.Code)
+	}
+	if tracker.Disconnect(sessionID) {
+		t.Fatal("second Disconnect should report an unknown session")
+	}
+}
+
+func TestStreamableEndpointHardening(t *testing.T) {
+	logger := logging.Discard()
+	empty := &Registry{Tools: map[string]*Tool{}, Resources: map[string]*Resource{}}
+	server := NewServer(empty, nil, logger, redact.NewRedactor(), "test")
+	streamable := NewStreamableHTTPServer(server, logger, &config.AuthConfig{Type: "bearer", Token: "secret"})
+	streamable.AllowedOrigins = []string{"https://*.example.com"}
+	streamable.AllowedHosts = []string{"mcp.internal"}
+	streamable.MaxMessageSize = 256
+
+	initialize := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`
+	host := "mcp.internal:8191"
+	post := func(target, origin, token, body string) int {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		req.Host = host
+		req.Header.Set("Accept", "application/json, text/event-stream")
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		streamable.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	tests := []struct {
+		name, target, origin, token, body string
+		want                              int
+	}{
+		{"no origin", "/mcp", "", "secret", initialize, http.StatusOK},
+		{"own host", "/mcp", "https://mcp.internal:8191", "secret", initialize, http.StatusOK},
+		{"localhost", "/mcp", "http://localhost:3000", "secret", initialize, http.StatusOK},
+		{"allowed origin", "/mcp", "https://App.Example.com", "secret", initialize, http.StatusOK},
+		{"foreign origin", "/mcp", "https://evil.test", "secret", initialize, http.StatusForbidden},
+		{"query token disabled", "/mcp?access_token=secret", "", "", initialize, http.StatusUnauthorized},
+		{"oversized message", "/mcp", "", "secret", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"pad":"` + strings.Repeat("x", 300) + `"}}`, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		if got := post(tt.target, tt.origin, tt.token, tt.body); got != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+
+	// DNS rebinding: a page at rebind.evil.test whose name now resolves to
+	// this server sends its own host as both Origin and Host.
+	for origin, want := range map[string]int{
+		"http://rebind.evil.test:8191": http.StatusForbidden,
+		"http://localhost:3000":        http.StatusOK,
+	} {
+		host = "rebind.evil.test:8191"
+		if got := post("/mcp", origin, "secret", initialize); got != want {
+			t.Errorf("Host %s, Origin %s: status = %d, want %d", host, origin, got, want)
+		}
+	}
+	host = "[::1]:8191"
+	if got := post("/mcp", "http://[::1]:8191", "secret", initialize); got != http.StatusOK {
+		t.Errorf("IPv6 loopback: status = %d, want 200", got)
+	}
+	host = "mcp.internal:8191"
+
+	streamable.QueryToken = true
+	if got := post("/mcp?access_token=secret", "", "", initialize); got != http.StatusOK {
+		t.Errorf("query token: status = %d, want 200", got)
+	}
+	if got := post("/mcp?access_token=wrong", "", "", initialize); got != http.StatusUnauthorized {
+		t.Errorf("wrong query token: status = %d, want 401", got)
+	}
+
+	// With VerifyToken set, the configured token no longer applies.
+	streamable.VerifyToken = func(token string) bool { return token == "hashed-secret" }
+	if got := post("/mcp", "", "hashed-secret", initialize); got != http.StatusOK {
+		t.Errorf("verified token: status = %d, want 200", got)
+	}
+	if got := post("/mcp", "", "secret", initialize); got != http.StatusUnauthorized {
+		t.Errorf("token bypassing VerifyToken: status = %d, want 401", got)
+	}
+
+	streamable.ResourceMetadata = func(r *http.Request) string {
+		return "https://" + r.Host + "/.well-known/oauth-protected-resource"
+	}
+	req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(initialize))
+	req.Host = "mcp.internal:8191"
+	rec := httptest.NewRecorder()
+	streamable.ServeHTTP(rec, req)
+	want := `Bearer resource_metadata="https://mcp.internal:8191/.well-known/oauth-protected-resource"`
+	if got := rec.Header().Get("WWW-Authenticate"); rec.Code != http.StatusUnauthorized || got != want {
+		t.Errorf("challenge = %d %q, want 401 %q", rec.Code, got, want)
+	}
+}
+
+func TestStreamableCompression(t *testing.T) {
+	logger := logging.Discard()
+	registry := &Registry{
+		Tools: map[string]*Tool{"svc__report": {
+			Name:        "svc__report",
+			Description: strings.Repeat("Returns the quarterly report. ", 100),
+			InputSchema: map[string]any{"type": "object"},
+		}},
+		Resources: map[string]*Resource{},
+	}
+	server := NewServer(registry, nil, logger, redact.NewRedactor(), "test")
+	streamable := NewStreamableHTTPServer(server, logger, nil)
+	streamable.CompressMinSize = 1024
+
+	post := func(sessionID, acceptEncoding, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(body))
+		req.Header.Set("Accept", "application/json, text/event-stream")
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+		if sessionID != "" {
+			req.Header.Set("Mcp-Session-Id", sessionID)
+		}
+		rec := httptest.NewRecorder()
+		streamable.ServeHTTP(rec, req)
+		return rec
+	}
+	rec := post("", "gzip, br", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`)
+	sessionID := rec.Header().Get("Mcp-Session-Id")
+	if rec.Header().Get("Content-Encoding") != "" || !json.Valid(rec.Body.Bytes()) {
+		t.Fatalf("small response compressed: %q %s", rec.Header().Get("Content-Encoding"), rec.Body)
+	}
+
+	listTools := `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`
+	rec = post(sessionID, "gzip, br", listTools)
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("large response not compressed: %v", rec.Header())
+	}
+	gz, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(gz)
+	if err != nil || !strings.Contains(string(body), "svc__report") {
+		t.Fatalf("decoded body = %s, %v", body, err)
+	}
+
+	for _, acceptEncoding := range []string{"", "br", "gzip;q=0"} {
+		rec = post(sessionID, acceptEncoding, listTools)
+		if rec.Header().Get("Content-Encoding") != "" || !strings.Contains(rec.Body.String(), "svc__report") {
+			t.Fatalf("Accept-Encoding %q: response compressed", acceptEncoding)
+		}
+	}
+}
+
+func TestStreamableReapsUnresponsiveSessions(t *testing.T) {
+	logger := logging.Discard()
+	empty := &Registry{Tools: map[string]*Tool{}, Resources: map[string]*Resource{}}
+	server := NewServer(empty, nil, logger, redact.NewRedactor(), "test")
+	streamable := NewStreamableHTTPServer(server, logger, nil)
+	streamable.PingInterval = 20 * time.Millisecond
+	streamable.PingTimeout = 100 * time.Millisecond
+	reaped := make(chan SessionEvent, 1)
+	streamable.SetSessionHook(func(event SessionEvent) {
+		if event.Type == "disconnected" {
+			reaped <- event
+		}
+	})
+	ts := httptest.NewServer(streamable)
+	defer ts.Close()
+
+	open := func(sessionID string) (*bufio.Reader, func()) {
+		req, _ := http.NewRequest(http.MethodGet, ts.URL, nil)
+		req.Header.Set("Accept", "text/event-stream")
+		req.Header.Set("Mcp-Session-Id", sessionID)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil || resp.StatusCode != http.StatusOK {
+			t.Fatalf("open stream: %v %v", resp, err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return bufio.NewReader(resp.Body), func() { resp.Body.Close() }
+	}
+	nextPing := func(stream *bufio.Reader) string {
+		for {
+			line, err := stream.ReadString('\n')
+			if err != nil {
+				return ""
+			}
+			if data, ok := strings.CutPrefix(line, "data: "); ok {
+				var msg struct {
+					ID     string `json:"id"`
+					Method string `json:"method"`
+				}
+				if json.Unmarshal([]byte(data), &msg) == nil && msg.Method == "ping" {
+					return msg.ID
+				}
+			}
+		}
+	}
+
+	// A client that answers its pings keeps the session.
+	streamable.store.create("alive")
+	alive, hangUp := open("alive")
+	for i := 0; i < 8; i++ {
+		id := nextPing(alive)
+		if id == "" {
+			t.Fatal("stream of responsive client ended")
+		}
+		req, _ := http.NewRequest(http.MethodPost, ts.URL, strings.NewReader(`{"jsonrpc":"2.0","id":"`+id+`","result":{}}`))
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("Mcp-Session-Id", "alive")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil || resp.StatusCode != http.StatusAccepted {
+			t.Fatalf("ping response: %v %v", resp, err)
+		}
+		resp.Body.Close()
+	}
+	hangUp() // without a stream there are no pings to miss
+
+	// One that does not is closed.
+	streamable.store.create("dead")
+	dead, _ := open("dead")
+	if nextPing(dead) == "" {
+		t.Fatal("no ping sent")
+	}
+	select {
+	case event := <-reaped:
+		if event.SessionID != "dead" || event.Reason != "unresponsive" {
+			t.Fatalf("reaped %+v", event)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("unresponsive session not reaped")
+	}
+	if streamable.HasSession("dead") || !streamable.HasSession("alive") {
+		t.Fatal("wrong sessions remain")
+	}
+}
+
+func TestStreamableElicitsRenewedCredentials(t *testing.T) {
+	op := &canonical.Operation{ServiceName: "pets", ID: "listPets", ToolName: "pets__listPets", Method: "get", Path: "/pets"}
+	registry, err := NewRegistry([]*canonical.Service{{Name: "pets", Operations: []*canonical.Operation{op}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := logging.Discard()
+	rejected := failingExecutor{err: &runtime.UpstreamError{Status: 401, Code: "unauthorized", Message: "token expired"}}
+	server := NewServer(registry, rejected, logger, redact.NewRedactor(), "test")
+	streamable := NewStreamableHTTPServer(server, logger, nil)
+
+	var token string
+	server.SetAuthRenewHook(func(ctx context.Context, apiName string, upErr *runtime.UpstreamError) (bool, string) {
+		res, err := Elicit(ctx, "Enter a new token for "+apiName, map[string]any{"type": "object"})
+		if err != nil {
+			return false, "ask the user for a new token"
+		}
+		if res.Action != "accept" {
+			return false, ""
+		}
+		token, _ = res.Content["token"].(string)
+		server.SetRegistry(registry, &recordingExecutor{})
+		return true, ""
+	})
+
+	post := func(sessionID, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(body))
+		req.Header.Set("Accept", "application/json, text/event-stream")
+		if sessionID != "" {
+			req.Header.Set("Mcp-Session-Id", sessionID)
+		}
+		rec := httptest.NewRecorder()
+		streamable.ServeHTTP(rec, req)
+		return rec
+	}
+	call := `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"pets__listPets","arguments":{}}}`
+
+	// Without the capability the agent gets instructions instead.
+	plain := post("", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`).Header().Get("Mcp-Session-Id")
+	if body := post(plain, call).Body.String(); !strings.Contains(body, "ask the user for a new token") {
+		t.Fatalf("tools/call = %s", body)
+	}
+
+	sessionID := post("", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"capabilities":{"elicitation":{}}}}`).Header().Get("Mcp-Session-Id")
+	sess := streamable.store.get(sessionID)
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- post(sessionID, call) }()
+
+	var request struct {
+		ID     string         `json:"id"`
+		Method string         `json:"method"`
+		Params map[string]any `json:"params"`
+	}
+	select {
+	case event := <-sess.ch:
+		if err := json.Unmarshal(event.data, &request); err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no elicitation request queued")
+	}
+	if request.Method != "elicitation/create" || request.Params["message"] != "Enter a new token for pets" {
+		t.Fatalf("request = %+v", request)
+	}
+	answer := `{"jsonrpc":"2.0","id":"` + request.ID + `","result":{"action":"accept","content":{"token":"fresh"}}}`
+	if rec := post(sessionID, answer); rec.Code != http.StatusAccepted {
+		t.Fatalf("answer = %d", rec.Code)
+	}
+
+	rec := <-done
+	if token != "fresh" || !strings.Contains(rec.Body.String(), `"isError":false`) {
+		t.Fatalf("token = %q, tools/call = %s", token, rec.Body)
+	}
+}
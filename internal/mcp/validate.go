@@ -0,0 +1,165 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// ArgumentError describes one problem with a tool call's arguments.
+type ArgumentError struct {
+	Field    string `json:"field"`              // e.g. "body.items[0].name"; empty for the arguments object
+	Problem  string `json:"problem"`            // missing, unexpected, wrong_type or invalid
+	Expected string `json:"expected,omitempty"` // expected type, for wrong_type
+	Got      string `json:"got,omitempty"`      // actual type, for wrong_type
+	Message  string `json:"message"`
+}
+
+// ArgumentsError is returned when arguments do not match a tool's input
+// schema. Errors lists every problem found.
+type ArgumentsError struct {
+	Tool   string          `json:"tool"`
+	Errors []ArgumentError `json:"errors"`
+}
+
+func (e *ArgumentsError) Error() string {
+	parts := make([]string, 0, len(e.Errors))
+	for _, ae := range e.Errors {
+		switch {
+		case ae.Problem == "missing":
+			parts = append(parts, "missing "+ae.Field)
+		case ae.Problem == "unexpected":
+			parts = append(parts, "unexpected "+ae.Field)
+		case ae.Field != "":
+			parts = append(parts, ae.Field+": "+ae.Message)
+		default:
+			parts = append(parts, ae.Message)
+		}
+	}
+	return fmt.Sprintf("invalid arguments for %s: %s", e.Tool, strings.Join(parts, "; "))
+}
+
+var (
+	quotedNameRe = regexp.MustCompile(`'((?:[^'\\]|\\.)*)'`)
+	wrongTypeRe  = regexp.MustCompile(`^expected (.+), but got (\S+)$`)
+	additionalRe = regexp.MustCompile(`^additionalProperties (.+) not allowed$`)
+)
+
+// ValidateArguments checks args against the tool's input schema and returns
+// an *ArgumentsError listing the missing and invalid fields, or nil. Tools
+// whose schema did not compile are not validated.
+func (t *Tool) ValidateArguments(args map[string]any) error {
+	validator := t.validator()
+	if validator == nil {
+		return nil
+	}
+	err := validator.Validate(args)
+	if _, ok := err.(jsonschema.InvalidJSONTypeError); ok {
+		// Arguments built in Go (e.g. by the code sandbox) may hold types
+		// such as []string; validate their JSON form instead.
+		var normalized any
+		if data, merr := json.Marshal(args); merr == nil && json.Unmarshal(data, &normalized) == nil {
+			err = validator.Validate(normalized)
+		}
+	}
+	if err == nil {
+		return nil
+	}
+	var ve *jsonschema.ValidationError
+	if !errors.As(err, &ve) {
+		return &ArgumentsError{Tool: t.Name, Errors: []ArgumentError{{Problem: "invalid", Message: err.Error()}}}
+	}
+	out := &ArgumentsError{Tool: t.Name}
+	seen := map[string]bool{}
+	for _, leaf := range leafErrors(ve) {
+		for _, ae := range argumentErrors(leaf) {
+			key := ae.Field + "\x00" + ae.Problem + "\x00" + ae.Message
+			if !seen[key] {
+				seen[key] = true
+				out.Errors = append(out.Errors, ae)
+			}
+		}
+	}
+	return out
+}
+
+func leafErrors(ve *jsonschema.ValidationError) []*jsonschema.ValidationError {
+	if len(ve.Causes) == 0 {
+		return []*jsonschema.ValidationError{ve}
+	}
+	var leaves []*jsonschema.ValidationError
+	for _, cause := range ve.Causes {
+		leaves = append(leaves, leafErrors(cause)...)
+	}
+	return leaves
+}
+
+func argumentErrors(ve *jsonschema.ValidationError) []ArgumentError {
+	field := fieldPath(ve.InstanceLocation)
+	keyword := ve.KeywordLocation[strings.LastIndex(ve.KeywordLocation, "/")+1:]
+	switch keyword {
+	case "required":
+		var out []ArgumentError
+		for _, m := range quotedNameRe.FindAllStringSubmatch(ve.Message, -1) {
+			name := joinField(field, m[1])
+			out = append(out, ArgumentError{Field: name, Problem: "missing", Message: "required field is missing"})
+		}
+		if len(out) > 0 {
+			return out
+		}
+	case "type":
+		if m := wrongTypeRe.FindStringSubmatch(ve.Message); m != nil {
+			return []ArgumentError{{
+				Field:    field,
+				Problem:  "wrong_type",
+				Expected: m[1],
+				Got:      m[2],
+				Message:  "expected " + m[1] + ", got " + m[2],
+			}}
+		}
+	case "additionalProperties":
+		if m := additionalRe.FindStringSubmatch(ve.Message); m != nil {
+			var out []ArgumentError
+			for _, n := range quotedNameRe.FindAllStringSubmatch(m[1], -1) {
+				out = append(out, ArgumentError{Field: joinField(field, n[1]), Problem: "unexpected", Message: "field is not allowed"})
+			}
+			if len(out) > 0 {
+				return out
+			}
+		}
+	}
+	return []ArgumentError{{Field: field, Problem: "invalid", Message: ve.Message}}
+}
+
+// fieldPath turns a JSON pointer ("/body/items/0/name") into the dotted
+// form agents write ("body.items[0].name").
+func fieldPath(pointer string) string {
+	var b strings.Builder
+	for _, seg := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		if seg == "" {
+			continue
+		}
+		seg = strings.NewReplacer("~1", "/", "~0", "~").Replace(seg)
+		if _, err := strconv.Atoi(seg); err == nil && b.Len() > 0 {
+			b.WriteString("[" + seg + "]")
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(seg)
+	}
+	return b.String()
+}
+
+func joinField(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
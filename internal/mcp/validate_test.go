@@ -0,0 +1,77 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/logging"
+	"skyline-mcp/internal/redact"
+)
+
+func TestCallToolRejectsInvalidArguments(t *testing.T) {
+	op := &canonical.Operation{
+		ServiceName: "pets",
+		ID:          "createPet",
+		ToolName:    "pets__createPet",
+		Method:      "post",
+		Path:        "/pets",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"body": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"name": map[string]any{"type": "string"},
+						"age":  map[string]any{"type": "integer"},
+						"tags": map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+					},
+					"required": []any{"name"},
+				},
+			},
+			"required":             []any{"body"},
+			"additionalProperties": false,
+		},
+	}
+	registry, err := NewRegistry([]*canonical.Service{{Name: "pets", Operations: []*canonical.Operation{op}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(registry, nil, logging.Discard(), redact.NewRedactor(), "test")
+
+	params := json.RawMessage(`{"name":"pets__createPet","arguments":{"body":{"age":"three","tags":["a",2]},"verbose":true}}`)
+	resp := server.handleCallTool(context.Background(), json.RawMessage(`1`), params)
+	if resp.Error == nil || resp.Error.Code != -32602 {
+		t.Fatalf("expected invalid params error, got %+v", resp)
+	}
+	argErr, ok := resp.Error.Data.(*ArgumentsError)
+	if !ok {
+		t.Fatalf("error data = %#v, want *ArgumentsError", resp.Error.Data)
+	}
+	got := map[string]ArgumentError{}
+	for _, ae := range argErr.Errors {
+		got[ae.Field] = ae
+	}
+	if got["body.name"].Problem != "missing" {
+		t.Errorf("body.name = %+v, want missing", got["body.name"])
+	}
+	if ae := got["body.age"]; ae.Problem != "wrong_type" || ae.Expected != "integer" || ae.Got != "string" {
+		t.Errorf("body.age = %+v, want wrong_type integer/string", ae)
+	}
+	if ae := got["body.tags[1]"]; ae.Problem != "wrong_type" || ae.Expected != "string" {
+		t.Errorf("body.tags[1] = %+v, want wrong_type string", ae)
+	}
+	if got["verbose"].Problem != "unexpected" {
+		t.Errorf("verbose = %+v, want unexpected", got["verbose"])
+	}
+	if len(argErr.Errors) != 4 {
+		t.Errorf("errors = %+v, want 4", argErr.Errors)
+	}
+
+	// Go-typed arguments (as built by the code sandbox) validate by their JSON form.
+	tool := registry.Tools["pets__createPet"]
+	if err := tool.ValidateArguments(map[string]any{"body": map[string]any{"name": "Rex", "tags": []string{"good"}}}); err != nil {
+		t.Fatalf("valid Go-typed arguments rejected: %v", err)
+	}
+}
@@ -20,6 +20,16 @@ type Collector struct {
 	totalConnections  atomic.Int64
 	activeConnections atomic.Int64
 
+	// Sessions closed by the server, by reason (idle, unresponsive)
+	reapedConnections map[string]*atomic.Int64
+	reapedMu          sync.RWMutex
+
+	// Brute-force protection: rejected tokens, lockouts started and
+	// requests refused while locked out
+	authFailures atomic.Int64
+	authLockouts atomic.Int64
+	authBlocked  atomic.Int64
+
 	// Cache counters
 	cacheHits   atomic.Int64
 	cacheMisses atomic.Int64
@@ -38,17 +48,36 @@ type Collector struct {
 	durationCount   atomic.Int64
 	durationMu      sync.RWMutex
 
+	// Upstream connection pools, read when metrics are exported
+	upstreamConns   func() []UpstreamConnStats
+	upstreamConnsMu sync.RWMutex
+
 	// Start time
 	startTime time.Time
 }
 
+// UpstreamConnStats describes the HTTP connection pool of one API.
+type UpstreamConnStats struct {
+	Profile    string  `json:"profile"`
+	API        string  `json:"api"`
+	Open       int64   `json:"open"`
+	Idle       int64   `json:"idle"`
+	Active     int64   `json:"active"`
+	Dials      int64   `json:"dials"`
+	DialErrors int64   `json:"dial_errors"`
+	Reused     int64   `json:"reused"`
+	DNSLookups int64   `json:"dns_lookups"`
+	DNSSeconds float64 `json:"dns_seconds"`
+}
+
 // NewCollector creates a new metrics collector
 func NewCollector() *Collector {
 	return &Collector{
-		profileRequests: make(map[string]*atomic.Int64),
-		toolRequests:    make(map[string]*atomic.Int64),
-		durationBuckets: initDurationBuckets(),
-		startTime:       time.Now(),
+		profileRequests:   make(map[string]*atomic.Int64),
+		toolRequests:      make(map[string]*atomic.Int64),
+		reapedConnections: make(map[string]*atomic.Int64),
+		durationBuckets:   initDurationBuckets(),
+		startTime:         time.Now(),
 	}
 }
 
@@ -123,6 +152,58 @@ func (c *Collector) RecordConnection(connected bool) {
 	}
 }
 
+// RecordReapedConnection records a session the server closed because it
+// was idle or its client stopped answering pings.
+func (c *Collector) RecordReapedConnection(reason string) {
+	c.reapedMu.RLock()
+	counter, ok := c.reapedConnections[reason]
+	c.reapedMu.RUnlock()
+	if !ok {
+		c.reapedMu.Lock()
+		if counter, ok = c.reapedConnections[reason]; !ok {
+			counter = &atomic.Int64{}
+			c.reapedConnections[reason] = counter
+		}
+		c.reapedMu.Unlock()
+	}
+	counter.Add(1)
+}
+
+// RecordAuthFailure records a request rejected for an invalid token.
+func (c *Collector) RecordAuthFailure() {
+	c.authFailures.Add(1)
+}
+
+// RecordAuthLockout records a client locked out of a profile after
+// repeated failures.
+func (c *Collector) RecordAuthLockout() {
+	c.authLockouts.Add(1)
+}
+
+// RecordAuthBlocked records a request refused because its client is
+// locked out.
+func (c *Collector) RecordAuthBlocked() {
+	c.authBlocked.Add(1)
+}
+
+// SetUpstreamConnSource registers fn to report the upstream connection
+// pools whenever metrics are exported.
+func (c *Collector) SetUpstreamConnSource(fn func() []UpstreamConnStats) {
+	c.upstreamConnsMu.Lock()
+	c.upstreamConns = fn
+	c.upstreamConnsMu.Unlock()
+}
+
+func (c *Collector) upstreamConnStats() []UpstreamConnStats {
+	c.upstreamConnsMu.RLock()
+	fn := c.upstreamConns
+	c.upstreamConnsMu.RUnlock()
+	if fn == nil {
+		return nil
+	}
+	return fn()
+}
+
 // PrometheusFormat exports metrics in Prometheus text format
 func (c *Collector) PrometheusFormat() string {
 	var output string
@@ -172,6 +253,29 @@ func (c *Collector) PrometheusFormat() string {
 	output += "# TYPE skyline_connections_total counter\n"
 	output += fmt.Sprintf("skyline_connections_total %d\n\n", c.totalConnections.Load())
 
+	// Reaped connections
+	output += "# HELP skyline_connections_reaped_total MCP sessions closed by the server, by reason\n"
+	output += "# TYPE skyline_connections_reaped_total counter\n"
+	c.reapedMu.RLock()
+	for reason, counter := range c.reapedConnections {
+		output += fmt.Sprintf("skyline_connections_reaped_total{reason=\"%s\"} %d\n", reason, counter.Load())
+	}
+	c.reapedMu.RUnlock()
+	output += "\n"
+
+	// Brute-force protection
+	output += "# HELP skyline_auth_failures_total Requests rejected for an invalid profile or tenant token\n"
+	output += "# TYPE skyline_auth_failures_total counter\n"
+	output += fmt.Sprintf("skyline_auth_failures_total %d\n\n", c.authFailures.Load())
+
+	output += "# HELP skyline_auth_lockouts_total Clients locked out of a profile after repeated auth failures\n"
+	output += "# TYPE skyline_auth_lockouts_total counter\n"
+	output += fmt.Sprintf("skyline_auth_lockouts_total %d\n\n", c.authLockouts.Load())
+
+	output += "# HELP skyline_auth_blocked_total Requests refused because their client was locked out\n"
+	output += "# TYPE skyline_auth_blocked_total counter\n"
+	output += fmt.Sprintf("skyline_auth_blocked_total %d\n\n", c.authBlocked.Load())
+
 	// Duration histogram
 	output += "# HELP skyline_request_duration_milliseconds Request duration in milliseconds\n"
 	output += "# TYPE skyline_request_duration_milliseconds histogram\n"
@@ -197,6 +301,30 @@ func (c *Collector) PrometheusFormat() string {
 	output += "# TYPE skyline_cache_misses_total counter\n"
 	output += fmt.Sprintf("skyline_cache_misses_total %d\n\n", c.cacheMisses.Load())
 
+	// Upstream connection pools
+	if pools := c.upstreamConnStats(); len(pools) > 0 {
+		for _, m := range []struct {
+			name, help, kind string
+			value            func(UpstreamConnStats) string
+		}{
+			{"skyline_upstream_connections_open", "Open connections to upstream APIs", "gauge", func(p UpstreamConnStats) string { return fmt.Sprint(p.Open) }},
+			{"skyline_upstream_connections_idle", "Open connections to upstream APIs not serving a request", "gauge", func(p UpstreamConnStats) string { return fmt.Sprint(p.Idle) }},
+			{"skyline_upstream_requests_active", "Requests to upstream APIs in flight", "gauge", func(p UpstreamConnStats) string { return fmt.Sprint(p.Active) }},
+			{"skyline_upstream_dials_total", "Connections dialed to upstream APIs", "counter", func(p UpstreamConnStats) string { return fmt.Sprint(p.Dials) }},
+			{"skyline_upstream_dial_errors_total", "Failed dials to upstream APIs", "counter", func(p UpstreamConnStats) string { return fmt.Sprint(p.DialErrors) }},
+			{"skyline_upstream_connections_reused_total", "Upstream requests sent over a kept-alive connection", "counter", func(p UpstreamConnStats) string { return fmt.Sprint(p.Reused) }},
+			{"skyline_upstream_dns_lookups_total", "DNS lookups for upstream API hosts", "counter", func(p UpstreamConnStats) string { return fmt.Sprint(p.DNSLookups) }},
+			{"skyline_upstream_dns_seconds_total", "Time spent on DNS lookups for upstream API hosts", "counter", func(p UpstreamConnStats) string { return fmt.Sprintf("%.6f", p.DNSSeconds) }},
+		} {
+			output += fmt.Sprintf("# HELP %s %s\n", m.name, m.help)
+			output += fmt.Sprintf("# TYPE %s %s\n", m.name, m.kind)
+			for _, p := range pools {
+				output += fmt.Sprintf("%s{profile=\"%s\",api=\"%s\"} %s\n", m.name, p.Profile, p.API, m.value(p))
+			}
+			output += "\n"
+		}
+	}
+
 	// Uptime
 	uptime := time.Since(c.startTime).Seconds()
 	output += "# HELP skyline_uptime_seconds Uptime in seconds\n"
@@ -213,12 +341,18 @@ type Snapshot struct {
 	FailedRequests    int64            `json:"failed_requests"`
 	ActiveConnections int64            `json:"active_connections"`
 	TotalConnections  int64            `json:"total_connections"`
+	ReapedConnections map[string]int64 `json:"reaped_connections"`
+	AuthFailures      int64            `json:"auth_failures"`
+	AuthLockouts      int64            `json:"auth_lockouts"`
+	AuthBlocked       int64            `json:"auth_blocked"`
 	AvgDurationMs     float64          `json:"avg_duration_ms"`
 	CacheHits         int64            `json:"cache_hits"`
 	CacheMisses       int64            `json:"cache_misses"`
 	ProfileRequests   map[string]int64 `json:"profile_requests"`
 	ToolRequests      map[string]int64 `json:"tool_requests"`
 	UptimeSeconds     float64          `json:"uptime_seconds"`
+
+	UpstreamConnections []UpstreamConnStats `json:"upstream_connections,omitempty"`
 }
 
 // Snapshot returns a snapshot of current metrics
@@ -231,6 +365,10 @@ func (c *Collector) Snapshot() *Snapshot {
 		TotalConnections:  c.totalConnections.Load(),
 		ProfileRequests:   make(map[string]int64),
 		ToolRequests:      make(map[string]int64),
+		ReapedConnections: make(map[string]int64),
+		AuthFailures:      c.authFailures.Load(),
+		AuthLockouts:      c.authLockouts.Load(),
+		AuthBlocked:       c.authBlocked.Load(),
 		UptimeSeconds:     time.Since(c.startTime).Seconds(),
 	}
 
@@ -250,6 +388,12 @@ func (c *Collector) Snapshot() *Snapshot {
 	}
 	c.profileMu.RUnlock()
 
+	c.reapedMu.RLock()
+	for reason, counter := range c.reapedConnections {
+		snap.ReapedConnections[reason] = counter.Load()
+	}
+	c.reapedMu.RUnlock()
+
 	// Copy tool requests
 	c.toolMu.RLock()
 	for tool, counter := range c.toolRequests {
@@ -257,6 +401,8 @@ func (c *Collector) Snapshot() *Snapshot {
 	}
 	c.toolMu.RUnlock()
 
+	snap.UpstreamConnections = c.upstreamConnStats()
+
 	return snap
 }
 
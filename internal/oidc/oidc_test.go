@@ -0,0 +1,207 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"skyline-mcp/internal/serverconfig"
+)
+
+type provider struct {
+	srv     *httptest.Server
+	rsaKey  *rsa.PrivateKey
+	ecKey   *ecdsa.PrivateKey
+	fetches atomic.Int32
+}
+
+func newProvider(t *testing.T) *provider {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &provider{rsaKey: rsaKey, ecKey: ecKey}
+	b64 := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{"issuer": p.srv.URL, "jwks_uri": p.srv.URL + "/keys"})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		p.fetches.Add(1)
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{
+			{"kty": "RSA", "kid": "rsa1", "use": "sig", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+			{"kty": "EC", "kid": "ec1", "crv": "P-256", "x": b64(ecKey.X.FillBytes(make([]byte, 32))), "y": b64(ecKey.Y.FillBytes(make([]byte, 32)))},
+			{"kty": "oct", "kid": "hmac", "k": "c2VjcmV0"},
+		}})
+	})
+	p.srv = httptest.NewServer(mux)
+	t.Cleanup(p.srv.Close)
+	return p
+}
+
+func (p *provider) sign(t *testing.T, alg, kid string, claims map[string]any) string {
+	t.Helper()
+	enc := func(v any) string {
+		data, _ := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signed := enc(map[string]string{"alg": alg, "kid": kid}) + "." + enc(claims)
+	digest := sha256.Sum256([]byte(signed))
+	var sig []byte
+	var err error
+	switch alg {
+	case "RS256":
+		sig, err = rsa.SignPKCS1v15(rand.Reader, p.rsaKey, crypto.SHA256, digest[:])
+	case "PS256":
+		sig, err = rsa.SignPSS(rand.Reader, p.rsaKey, crypto.SHA256, digest[:], nil)
+	case "ES256":
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, p.ecKey, digest[:])
+		if err == nil {
+			sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+		}
+	default:
+		sig = []byte("x")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func (p *provider) claims(extra map[string]any) map[string]any {
+	c := map[string]any{
+		"iss":   p.srv.URL,
+		"aud":   "https://skyline.example",
+		"sub":   "alice",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"scope": "openid skyline:mcp",
+	}
+	for k, v := range extra {
+		c[k] = v
+	}
+	return c
+}
+
+func newTestVerifier(t *testing.T, p *provider) *Verifier {
+	t.Helper()
+	v, err := New(serverconfig.OIDCConfig{Issuer: p.srv.URL + "/", Audience: "https://skyline.example"}, p.srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestVerify(t *testing.T) {
+	p := newProvider(t)
+	v := newTestVerifier(t, p)
+	ctx := context.Background()
+
+	for _, alg := range []string{"RS256", "PS256"} {
+		claims, err := v.Verify(ctx, p.sign(t, alg, "rsa1", p.claims(nil)))
+		if err != nil {
+			t.Fatalf("%s: %v", alg, err)
+		}
+		if claims.Subject != "alice" || !claims.HasScope("skyline:mcp") || claims.HasScope("skyline:admin") {
+			t.Fatalf("%s: claims = %+v", alg, claims)
+		}
+	}
+	claims, err := v.Verify(ctx, p.sign(t, "ES256", "ec1", p.claims(map[string]any{
+		"aud":      []string{"other", "https://skyline.example"},
+		"scope":    nil,
+		"scp":      []string{"skyline:admin"},
+		"profiles": []string{"github", "jira"},
+	})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !claims.HasScope("skyline:admin") {
+		t.Fatalf("scp not read: %+v", claims.Scopes)
+	}
+	if got := claims.Strings("profiles"); len(got) != 2 || got[1] != "jira" {
+		t.Fatalf("profiles claim = %v", got)
+	}
+	if n := p.fetches.Load(); n != 1 {
+		t.Fatalf("jwks fetched %d times, want 1", n)
+	}
+	if cfg := v.Config(); cfg.AdminScope != DefaultAdminScope || cfg.MCPScope != DefaultMCPScope {
+		t.Fatalf("defaults not applied: %+v", cfg)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	p := newProvider(t)
+	v := newTestVerifier(t, p)
+	ctx := context.Background()
+
+	valid := p.sign(t, "RS256", "rsa1", p.claims(nil))
+	parts := strings.Split(valid, ".")
+	forged := parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"iss":"x"}`)) + "." + parts[2]
+
+	cases := map[string]string{
+		"malformed":      "not-a-jwt",
+		"bad signature":  forged,
+		"wrong key type": p.sign(t, "ES256", "rsa1", p.claims(nil)),
+		"alg none":       p.sign(t, "none", "rsa1", p.claims(nil)),
+		"hmac key":       p.sign(t, "HS256", "hmac", p.claims(nil)),
+		"issuer":         p.sign(t, "RS256", "rsa1", p.claims(map[string]any{"iss": "https://evil.example"})),
+		"audience":       p.sign(t, "RS256", "rsa1", p.claims(map[string]any{"aud": "https://other.example"})),
+		"expired":        p.sign(t, "RS256", "rsa1", p.claims(map[string]any{"exp": time.Now().Add(-time.Hour).Unix()})),
+		"no expiry":      p.sign(t, "RS256", "rsa1", p.claims(map[string]any{"exp": nil})),
+		"not yet valid":  p.sign(t, "RS256", "rsa1", p.claims(map[string]any{"nbf": time.Now().Add(time.Hour).Unix()})),
+	}
+	for name, token := range cases {
+		if _, err := v.Verify(ctx, token); err == nil {
+			t.Errorf("%s: token accepted", name)
+		}
+	}
+}
+
+func TestUnknownKeyRefetchIsThrottled(t *testing.T) {
+	p := newProvider(t)
+	v := newTestVerifier(t, p)
+	now := time.Now()
+	v.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for range 3 {
+		if _, err := v.Verify(ctx, p.sign(t, "RS256", "rotated", p.claims(nil))); err == nil {
+			t.Fatal("unknown kid accepted")
+		}
+	}
+	if n := p.fetches.Load(); n != 1 {
+		t.Fatalf("jwks fetched %d times, want 1", n)
+	}
+	now = now.Add(2 * refetchInterval)
+	_, _ = v.Verify(ctx, p.sign(t, "RS256", "rotated", p.claims(nil)))
+	if n := p.fetches.Load(); n != 2 {
+		t.Fatalf("jwks fetched %d times after interval, want 2", n)
+	}
+}
+
+func TestNewRequiresIssuerAndAudience(t *testing.T) {
+	if _, err := New(serverconfig.OIDCConfig{Audience: "a"}, nil); err == nil {
+		t.Fatal("missing issuer accepted")
+	}
+	if _, err := New(serverconfig.OIDCConfig{Issuer: "https://idp"}, nil); err == nil {
+		t.Fatal("missing audience accepted")
+	}
+}
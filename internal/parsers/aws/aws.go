@@ -0,0 +1,427 @@
+// Package aws turns AWS service models in the botocore JSON format
+// (botocore/data/<service>/<version>/service-2.json) into tools whose
+// requests are signed with SigV4.
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+
+	"skyline-mcp/internal/canonical"
+)
+
+// maxSchemaDepth bounds the input schema of deeply nested or recursive
+// shapes; deeper values are plain objects.
+const maxSchemaDepth = 6
+
+type model struct {
+	Metadata   metadata             `json:"metadata"`
+	Operations map[string]operation `json:"operations"`
+	Shapes     map[string]shape     `json:"shapes"`
+}
+
+type metadata struct {
+	APIVersion     string   `json:"apiVersion"`
+	EndpointPrefix string   `json:"endpointPrefix"`
+	JSONVersion    string   `json:"jsonVersion"`
+	Protocol       string   `json:"protocol"`
+	Protocols      []string `json:"protocols"`
+	SigningName    string   `json:"signingName"`
+	TargetPrefix   string   `json:"targetPrefix"`
+}
+
+type operation struct {
+	Name string `json:"name"`
+	HTTP struct {
+		Method     string `json:"method"`
+		RequestURI string `json:"requestUri"`
+	} `json:"http"`
+	Input         *shapeRef `json:"input"`
+	Documentation string    `json:"documentation"`
+	Deprecated    bool      `json:"deprecated"`
+	Endpoint      *struct {
+		HostPrefix string `json:"hostPrefix"`
+	} `json:"endpoint"`
+}
+
+type shapeRef struct {
+	Shape         string `json:"shape"`
+	Location      string `json:"location"`
+	LocationName  string `json:"locationName"`
+	QueryName     string `json:"queryName"`
+	Flattened     bool   `json:"flattened"`
+	Documentation string `json:"documentation"`
+}
+
+type shape struct {
+	Type        string              `json:"type"`
+	Required    []string            `json:"required"`
+	Members     map[string]shapeRef `json:"members"`
+	Member      *shapeRef           `json:"member"`
+	Key         *shapeRef           `json:"key"`
+	Value       *shapeRef           `json:"value"`
+	Enum        []string            `json:"enum"`
+	Payload     string              `json:"payload"`
+	Flattened   bool                `json:"flattened"`
+	EventStream bool                `json:"eventstream"`
+}
+
+var supportedProtocols = map[string]bool{"json": true, "rest-json": true, "rest-xml": true, "query": true, "ec2": true}
+
+// LooksLikeServiceModel reports whether raw is a botocore service model.
+func LooksLikeServiceModel(raw []byte) bool {
+	var doc struct {
+		Metadata   metadata                   `json:"metadata"`
+		Operations map[string]json.RawMessage `json:"operations"`
+		Shapes     map[string]json.RawMessage `json:"shapes"`
+	}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return false
+	}
+	hasProtocol := doc.Metadata.Protocol != "" || len(doc.Metadata.Protocols) > 0
+	return hasProtocol && doc.Metadata.EndpointPrefix != "" && len(doc.Operations) > 0 && doc.Shapes != nil
+}
+
+// ParseToCanonical builds one tool per operation of a botocore service
+// model. The model names no endpoint, so baseURLOverride is required.
+// Operations Skyline cannot send are left out: those with a host prefix or
+// an event stream input, and rest-xml operations with an XML body.
+func ParseToCanonical(ctx context.Context, raw []byte, apiName, baseURLOverride string) (*canonical.Service, error) {
+	_ = ctx
+	var m model
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, fmt.Errorf("aws model: parse failed: %w", err)
+	}
+	protocol := m.Metadata.Protocol
+	for _, p := range m.Metadata.Protocols {
+		if supportedProtocols[p] {
+			protocol = p
+			break
+		}
+	}
+	if !supportedProtocols[protocol] {
+		return nil, fmt.Errorf("aws model: protocol %q is not supported", protocol)
+	}
+	baseURL := strings.TrimRight(strings.TrimSpace(baseURLOverride), "/")
+	if baseURL == "" {
+		return nil, fmt.Errorf("aws model: base_url_override is required, e.g. https://%s.us-east-1.amazonaws.com", m.Metadata.EndpointPrefix)
+	}
+
+	p := &parser{model: &m, protocol: protocol, apiName: apiName, shapes: map[string]*canonical.AWSShape{}}
+	names := make([]string, 0, len(m.Operations))
+	for name := range m.Operations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	svc := &canonical.Service{Name: apiName, BaseURL: baseURL}
+	for _, name := range names {
+		if op := p.operation(name, m.Operations[name]); op != nil {
+			svc.Operations = append(svc.Operations, op)
+		}
+	}
+	if len(svc.Operations) == 0 {
+		return nil, fmt.Errorf("aws model: no supported operations")
+	}
+	return svc, nil
+}
+
+type parser struct {
+	model    *model
+	protocol string
+	apiName  string
+	shapes   map[string]*canonical.AWSShape // serialization shapes by name
+}
+
+func (p *parser) operation(name string, o operation) *canonical.Operation {
+	if o.Endpoint != nil && o.Endpoint.HostPrefix != "" {
+		return nil
+	}
+	input := shape{Type: "structure"}
+	if o.Input != nil {
+		input = p.model.Shapes[o.Input.Shape]
+	}
+	for _, ref := range input.Members {
+		if p.model.Shapes[ref.Shape].EventStream {
+			return nil
+		}
+	}
+
+	meta := p.model.Metadata
+	signingName := meta.SigningName
+	if signingName == "" {
+		signingName = meta.EndpointPrefix
+	}
+	op := &canonical.Operation{
+		ServiceName: p.apiName,
+		ID:          name,
+		ToolName:    canonical.ToolName(p.apiName, name),
+		Summary:     summary(o.Documentation, name),
+		AWS: &canonical.AWSOperation{
+			Protocol:    p.protocol,
+			Action:      name,
+			APIVersion:  meta.APIVersion,
+			SigningName: signingName,
+		},
+	}
+	props := map[string]any{}
+	var required []string
+	addArg := func(member string, ref shapeRef) {
+		props[member] = p.schema(ref, 0, nil)
+		for _, r := range input.Required {
+			if r == member {
+				required = append(required, member)
+			}
+		}
+	}
+
+	switch p.protocol {
+	case "json", "query", "ec2":
+		op.Method = "post"
+		op.Path = "/"
+		for member, ref := range input.Members {
+			addArg(member, ref)
+		}
+		op.AWS.Input = p.inputShape(input, nil)
+		op.StaticHeaders = map[string]string{"Content-Type": "application/x-www-form-urlencoded; charset=utf-8"}
+		if p.protocol == "json" {
+			version := meta.JSONVersion
+			if version == "" {
+				version = "1.0"
+			}
+			op.StaticHeaders = map[string]string{
+				"Content-Type": "application/x-amz-json-" + version,
+				"X-Amz-Target": meta.TargetPrefix + "." + name,
+			}
+		}
+	default:
+		op.Method = strings.ToLower(o.HTTP.Method)
+		path := o.HTTP.RequestURI
+		var body []string
+		for member, ref := range input.Members {
+			locationName := ref.LocationName
+			if locationName == "" {
+				locationName = member
+			}
+			switch ref.Location {
+			case "uri":
+				param := canonical.Parameter{Name: member, In: "path", Required: true, Schema: p.schema(ref, 0, nil)}
+				if strings.Contains(path, "{"+locationName+"+}") {
+					path = strings.ReplaceAll(path, "{"+locationName+"+}", "{"+member+"}")
+					param.SegmentSeparator = "/"
+				} else {
+					path = strings.ReplaceAll(path, "{"+locationName+"}", "{"+member+"}")
+				}
+				op.Parameters = append(op.Parameters, param)
+				props[member] = param.Schema
+				required = append(required, member)
+			case "querystring":
+				addArg(member, ref)
+				if p.model.Shapes[ref.Shape].Type == "map" {
+					op.QueryParamsObject = member
+					continue
+				}
+				op.Parameters = append(op.Parameters, canonical.Parameter{Name: member, In: "query", QueryName: locationName, Schema: props[member].(map[string]any)})
+			case "header":
+				if p.model.Shapes[ref.Shape].Type == "map" {
+					continue
+				}
+				addArg(member, ref)
+				op.Parameters = append(op.Parameters, canonical.Parameter{Name: member, In: "header", QueryName: locationName, Schema: props[member].(map[string]any)})
+			case "headers":
+				// Prefixed header maps such as S3 object metadata.
+			default:
+				body = append(body, member)
+			}
+		}
+		op.Path = path
+		sort.Slice(op.Parameters, func(i, j int) bool { return op.Parameters[i].Name < op.Parameters[j].Name })
+
+		if input.Payload != "" {
+			ref := input.Members[input.Payload]
+			payloadType := p.model.Shapes[ref.Shape].Type
+			if payloadType == "structure" && p.protocol == "rest-xml" {
+				return nil
+			}
+			addArg(input.Payload, ref)
+			if payloadType == "blob" || payloadType == "string" {
+				props[input.Payload] = map[string]any{"type": "string", "description": "Request body, sent as is."}
+			} else {
+				op.StaticHeaders = map[string]string{"Content-Type": "application/json"}
+			}
+			op.AWS.Payload = input.Payload
+		} else if len(body) > 0 {
+			if p.protocol == "rest-xml" {
+				return nil
+			}
+			for _, member := range body {
+				addArg(member, input.Members[member])
+			}
+			op.AWS.Input = p.inputShape(input, body)
+			op.StaticHeaders = map[string]string{"Content-Type": "application/json"}
+		}
+	}
+
+	op.InputSchema = map[string]any{"type": "object", "properties": props, "additionalProperties": false}
+	if len(required) > 0 {
+		sort.Strings(required)
+		op.InputSchema["required"] = required
+	}
+	if o.Deprecated {
+		op.Summary = "Deprecated. " + op.Summary
+	}
+	return op
+}
+
+// inputShape is the serialization shape of the input members named by
+// only, or of all of them when only is nil.
+func (p *parser) inputShape(input shape, only []string) *canonical.AWSShape {
+	out := &canonical.AWSShape{Type: "structure", Members: map[string]canonical.AWSMember{}}
+	for member, ref := range input.Members {
+		if only != nil && !contains(only, member) {
+			continue
+		}
+		out.Members[member] = canonical.AWSMember{Name: p.serializedName(member, ref), Shape: p.awsShape(ref)}
+	}
+	return out
+}
+
+// awsShape converts the shape of ref, sharing one value per shape so
+// recursive shapes terminate.
+func (p *parser) awsShape(ref shapeRef) *canonical.AWSShape {
+	out, ok := p.shapes[ref.Shape]
+	if !ok {
+		s := p.model.Shapes[ref.Shape]
+		out = &canonical.AWSShape{Type: s.Type, Flattened: s.Flattened}
+		p.shapes[ref.Shape] = out
+		switch s.Type {
+		case "structure":
+			out.Members = map[string]canonical.AWSMember{}
+			for member, mref := range s.Members {
+				out.Members[member] = canonical.AWSMember{Name: p.serializedName(member, mref), Shape: p.awsShape(mref)}
+			}
+		case "list":
+			if s.Member != nil {
+				out.Member = p.awsShape(*s.Member)
+				out.MemberName = s.Member.LocationName
+			}
+		case "map":
+			if s.Key != nil && s.Value != nil {
+				out.Key, out.Value = p.awsShape(*s.Key), p.awsShape(*s.Value)
+				out.KeyName, out.ValueName = s.Key.LocationName, s.Value.LocationName
+			}
+		}
+	}
+	if ref.Flattened && !out.Flattened {
+		flattened := *out
+		flattened.Flattened = true
+		return &flattened
+	}
+	return out
+}
+
+// serializedName is the name a member is sent under by the query
+// protocols; the JSON protocols use the member name.
+func (p *parser) serializedName(member string, ref shapeRef) string {
+	switch p.protocol {
+	case "query":
+		if ref.LocationName != "" {
+			return ref.LocationName
+		}
+	case "ec2":
+		if ref.QueryName != "" {
+			return ref.QueryName
+		}
+		if ref.LocationName != "" {
+			return strings.ToUpper(ref.LocationName[:1]) + ref.LocationName[1:]
+		}
+	}
+	return member
+}
+
+// schema is the JSON schema of the value of ref. path holds the shapes
+// being expanded, to stop at recursive ones.
+func (p *parser) schema(ref shapeRef, depth int, path []string) map[string]any {
+	s := p.model.Shapes[ref.Shape]
+	out := map[string]any{}
+	if doc := summary(ref.Documentation, ""); doc != "" {
+		out["description"] = doc
+	}
+	switch s.Type {
+	case "structure":
+		out["type"] = "object"
+		if depth >= maxSchemaDepth || contains(path, ref.Shape) {
+			return out
+		}
+		props := map[string]any{}
+		for member, mref := range s.Members {
+			props[member] = p.schema(mref, depth+1, append(path, ref.Shape))
+		}
+		out["properties"] = props
+		if len(s.Required) > 0 {
+			out["required"] = s.Required
+		}
+	case "list":
+		out["type"] = "array"
+		if s.Member != nil && depth < maxSchemaDepth {
+			out["items"] = p.schema(*s.Member, depth+1, path)
+		}
+	case "map":
+		out["type"] = "object"
+		if s.Value != nil && depth < maxSchemaDepth {
+			out["additionalProperties"] = p.schema(*s.Value, depth+1, path)
+		}
+	case "string":
+		out["type"] = "string"
+		if len(s.Enum) > 0 {
+			out["enum"] = s.Enum
+		}
+	case "blob":
+		out["type"] = "string"
+		out["contentEncoding"] = "base64"
+	case "timestamp":
+		out["type"] = "string"
+		out["format"] = "date-time"
+	case "integer", "long":
+		out["type"] = "integer"
+	case "float", "double":
+		out["type"] = "number"
+	case "boolean":
+		out["type"] = "boolean"
+	}
+	return out
+}
+
+var (
+	htmlTagRE  = regexp.MustCompile(`<[^>]*>`)
+	sentenceRE = regexp.MustCompile(`^.*?[.!?](\s|$)`)
+)
+
+// summary is the first sentence of an HTML documentation string, or
+// fallback when there is none.
+func summary(doc, fallback string) string {
+	text := strings.Join(strings.Fields(html.UnescapeString(htmlTagRE.ReplaceAllString(doc, " "))), " ")
+	if first := sentenceRE.FindString(text); first != "" {
+		text = strings.TrimSpace(first)
+	}
+	if runes := []rune(text); len(runes) > 300 {
+		text = strings.TrimSpace(string(runes[:297])) + "..."
+	}
+	if text == "" {
+		return fallback
+	}
+	return text
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
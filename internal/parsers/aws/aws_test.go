@@ -0,0 +1,219 @@
+package aws
+
+import (
+	"context"
+	"testing"
+)
+
+// s3Model is a trimmed botocore model of S3 (rest-xml).
+const s3Model = `{
+  "version": "2.0",
+  "metadata": {"apiVersion": "2006-03-01", "endpointPrefix": "s3", "protocol": "rest-xml", "signatureVersion": "s3", "serviceId": "S3"},
+  "operations": {
+    "ListObjectsV2": {
+      "name": "ListObjectsV2",
+      "http": {"method": "GET", "requestUri": "/{Bucket}?list-type=2"},
+      "input": {"shape": "ListObjectsV2Request"},
+      "documentation": "<p>Returns some or all (up to 1,000) of the objects in a bucket with each request. You can use the request parameters as selection criteria.</p>"
+    },
+    "PutObject": {
+      "name": "PutObject",
+      "http": {"method": "PUT", "requestUri": "/{Bucket}/{Key+}?x-id=PutObject"},
+      "input": {"shape": "PutObjectRequest"}
+    },
+    "PutBucketTagging": {
+      "name": "PutBucketTagging",
+      "http": {"method": "PUT", "requestUri": "/{Bucket}?tagging"},
+      "input": {"shape": "PutBucketTaggingRequest"}
+    },
+    "GetBucketAccelerateConfiguration": {
+      "name": "GetBucketAccelerateConfiguration",
+      "http": {"method": "GET", "requestUri": "/{Bucket}?accelerate"},
+      "input": {"shape": "BucketRequest"},
+      "endpoint": {"hostPrefix": "{Bucket}."}
+    }
+  },
+  "shapes": {
+    "ListObjectsV2Request": {
+      "type": "structure", "required": ["Bucket"],
+      "members": {
+        "Bucket": {"shape": "BucketName", "location": "uri", "locationName": "Bucket"},
+        "Prefix": {"shape": "Prefix", "location": "querystring", "locationName": "prefix", "documentation": "<p>Limits the response to keys that begin with the specified prefix.</p>"},
+        "MaxKeys": {"shape": "MaxKeys", "location": "querystring", "locationName": "max-keys"},
+        "ExpectedBucketOwner": {"shape": "AccountId", "location": "header", "locationName": "x-amz-expected-bucket-owner"}
+      }
+    },
+    "PutObjectRequest": {
+      "type": "structure", "required": ["Bucket", "Key"], "payload": "Body",
+      "members": {
+        "Body": {"shape": "Body", "streaming": true},
+        "Bucket": {"shape": "BucketName", "location": "uri", "locationName": "Bucket"},
+        "Key": {"shape": "ObjectKey", "location": "uri", "locationName": "Key"},
+        "ContentType": {"shape": "ContentType", "location": "header", "locationName": "Content-Type"},
+        "Metadata": {"shape": "Metadata", "location": "headers", "locationName": "x-amz-meta-"}
+      }
+    },
+    "PutBucketTaggingRequest": {
+      "type": "structure", "required": ["Bucket", "Tagging"], "payload": "Tagging",
+      "members": {
+        "Bucket": {"shape": "BucketName", "location": "uri", "locationName": "Bucket"},
+        "Tagging": {"shape": "Tagging", "locationName": "Tagging"}
+      }
+    },
+    "BucketRequest": {"type": "structure", "members": {"Bucket": {"shape": "BucketName", "location": "uri", "locationName": "Bucket"}}},
+    "Tagging": {"type": "structure", "members": {"TagSet": {"shape": "TagSet"}}},
+    "TagSet": {"type": "list", "member": {"shape": "Tag", "locationName": "Tag"}},
+    "Tag": {"type": "structure", "members": {"Key": {"shape": "ObjectKey"}, "Value": {"shape": "ContentType"}}},
+    "Metadata": {"type": "map", "key": {"shape": "ContentType"}, "value": {"shape": "ContentType"}},
+    "BucketName": {"type": "string"},
+    "ObjectKey": {"type": "string", "min": 1},
+    "Prefix": {"type": "string"},
+    "MaxKeys": {"type": "integer"},
+    "AccountId": {"type": "string"},
+    "ContentType": {"type": "string"},
+    "Body": {"type": "blob", "streaming": true}
+  }
+}`
+
+// monitoringModel is a trimmed botocore model of CloudWatch (query), with
+// a recursive shape.
+const monitoringModel = `{
+  "version": "2.0",
+  "metadata": {"apiVersion": "2010-08-01", "endpointPrefix": "monitoring", "protocol": "query", "protocols": ["query"], "serviceId": "CloudWatch", "xmlNamespace": "http://monitoring.amazonaws.com/doc/2010-08-01/"},
+  "operations": {
+    "GetMetricData": {
+      "name": "GetMetricData",
+      "http": {"method": "POST", "requestUri": "/"},
+      "input": {"shape": "GetMetricDataInput"},
+      "documentation": "<p>You can use the <code>GetMetricData</code> API to retrieve CloudWatch metric values.</p>"
+    },
+    "ListDashboards": {"name": "ListDashboards", "http": {"method": "POST", "requestUri": "/"}, "deprecated": true}
+  },
+  "shapes": {
+    "GetMetricDataInput": {
+      "type": "structure", "required": ["MetricDataQueries", "StartTime", "EndTime"],
+      "members": {
+        "MetricDataQueries": {"shape": "MetricDataQueries"},
+        "StartTime": {"shape": "Timestamp"},
+        "EndTime": {"shape": "Timestamp"},
+        "ScanBy": {"shape": "ScanBy"},
+        "Filter": {"shape": "Filter"}
+      }
+    },
+    "MetricDataQueries": {"type": "list", "member": {"shape": "MetricDataQuery"}},
+    "MetricDataQuery": {"type": "structure", "required": ["Id"], "members": {"Id": {"shape": "MetricId"}, "Expression": {"shape": "MetricId"}}},
+    "Filter": {"type": "structure", "members": {"And": {"shape": "Filters"}, "Name": {"shape": "MetricId"}}},
+    "Filters": {"type": "list", "member": {"shape": "Filter"}},
+    "MetricId": {"type": "string"},
+    "ScanBy": {"type": "string", "enum": ["TimestampDescending", "TimestampAscending"]},
+    "Timestamp": {"type": "timestamp"}
+  }
+}`
+
+func TestLooksLikeServiceModel(t *testing.T) {
+	if !LooksLikeServiceModel([]byte(s3Model)) || !LooksLikeServiceModel([]byte(monitoringModel)) {
+		t.Error("botocore models not detected")
+	}
+	if LooksLikeServiceModel([]byte(`{"openapi":"3.0.0","paths":{}}`)) {
+		t.Error("OpenAPI document detected as a botocore model")
+	}
+}
+
+func TestParseRESTXML(t *testing.T) {
+	svc, err := ParseToCanonical(context.Background(), []byte(s3Model), "s3", "https://s3.us-west-2.amazonaws.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if svc.BaseURL != "https://s3.us-west-2.amazonaws.com" {
+		t.Errorf("BaseURL = %q", svc.BaseURL)
+	}
+	if len(svc.Operations) != 2 {
+		var ids []string
+		for _, op := range svc.Operations {
+			ids = append(ids, op.ID)
+		}
+		t.Fatalf("operations = %v, want ListObjectsV2 and PutObject (XML bodies and host prefixes are skipped)", ids)
+	}
+
+	list := svc.Operations[0]
+	if list.ID != "ListObjectsV2" || list.Method != "get" || list.Path != "/{Bucket}?list-type=2" {
+		t.Errorf("ListObjectsV2 = %s %s %s", list.ID, list.Method, list.Path)
+	}
+	if list.Summary != "Returns some or all (up to 1,000) of the objects in a bucket with each request." {
+		t.Errorf("summary = %q", list.Summary)
+	}
+	params := map[string]string{}
+	for _, p := range list.Parameters {
+		params[p.Name] = p.In + ":" + p.QueryName
+	}
+	if params["Prefix"] != "query:prefix" || params["MaxKeys"] != "query:max-keys" || params["ExpectedBucketOwner"] != "header:x-amz-expected-bucket-owner" {
+		t.Errorf("parameters = %v", params)
+	}
+	if req := list.InputSchema["required"].([]string); len(req) != 1 || req[0] != "Bucket" {
+		t.Errorf("required = %v", req)
+	}
+	if list.AWS.Input != nil || list.AWS.SigningName != "s3" {
+		t.Errorf("AWS = %+v", list.AWS)
+	}
+
+	put := svc.Operations[1]
+	if put.Path != "/{Bucket}/{Key}?x-id=PutObject" || put.AWS.Payload != "Body" {
+		t.Errorf("PutObject path %q payload %q", put.Path, put.AWS.Payload)
+	}
+	for _, p := range put.Parameters {
+		if p.Name == "Key" && p.SegmentSeparator != "/" {
+			t.Error("greedy Key label does not keep its slashes")
+		}
+	}
+	props := put.InputSchema["properties"].(map[string]any)
+	if _, ok := props["Metadata"]; ok {
+		t.Error("prefixed header map exposed as an argument")
+	}
+	if _, ok := put.StaticHeaders["Content-Type"]; ok {
+		t.Error("raw payload operation sets a Content-Type")
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	if _, err := ParseToCanonical(context.Background(), []byte(monitoringModel), "cloudwatch", ""); err == nil {
+		t.Fatal("expected an error without base_url_override")
+	}
+	svc, err := ParseToCanonical(context.Background(), []byte(monitoringModel), "cloudwatch", "https://monitoring.eu-west-1.amazonaws.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := svc.Operations[0]
+	if get.ID != "GetMetricData" || get.Method != "post" || get.Path != "/" {
+		t.Fatalf("GetMetricData = %s %s %s", get.ID, get.Method, get.Path)
+	}
+	if get.Summary != "You can use the GetMetricData API to retrieve CloudWatch metric values." {
+		t.Errorf("summary = %q", get.Summary)
+	}
+	if get.AWS.Action != "GetMetricData" || get.AWS.APIVersion != "2010-08-01" || get.AWS.SigningName != "monitoring" {
+		t.Errorf("AWS = %+v", get.AWS)
+	}
+	queries := get.AWS.Input.Members["MetricDataQueries"].Shape
+	if queries.Type != "list" || queries.Member.Members["Id"].Name != "Id" {
+		t.Errorf("MetricDataQueries shape = %+v", queries)
+	}
+	filter := get.AWS.Input.Members["Filter"].Shape
+	if filter.Members["And"].Shape.Member != filter {
+		t.Error("recursive shape is not shared")
+	}
+	props := get.InputSchema["properties"].(map[string]any)
+	if props["StartTime"].(map[string]any)["format"] != "date-time" {
+		t.Errorf("StartTime = %v", props["StartTime"])
+	}
+	if enum := props["ScanBy"].(map[string]any)["enum"].([]string); len(enum) != 2 {
+		t.Errorf("ScanBy enum = %v", enum)
+	}
+	if req := get.InputSchema["required"].([]string); len(req) != 3 {
+		t.Errorf("required = %v", req)
+	}
+	if svc.Operations[1].Summary != "Deprecated. ListDashboards" {
+		t.Errorf("deprecated summary = %q", svc.Operations[1].Summary)
+	}
+	if svc.Operations[1].AWS.Input == nil {
+		t.Error("query operation without input has no input shape; Action and Version would not be sent")
+	}
+}
@@ -0,0 +1,642 @@
+// Package github implements a curated Skyline adapter for GitHub. Most
+// tools call the REST API (repositories, issues, pull requests, Actions);
+// discussions and Projects, which REST covers poorly or not at all, go
+// through the GraphQL API. Both share the API's base URL and auth, so one
+// configured API covers github.com, GHE.com and GitHub Enterprise Server.
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"skyline-mcp/internal/canonical"
+)
+
+// DefaultBaseURL is the API of github.com, used without base_url_override.
+const DefaultBaseURL = "https://api.github.com"
+
+// apiVersion is the REST API version the tools are written against.
+const apiVersion = "2022-11-28"
+
+// LooksLikeGitHub reports whether raw is the root document of the GitHub
+// REST API, as served at https://api.github.com/.
+func LooksLikeGitHub(raw []byte) bool {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || raw[0] != '{' {
+		return false
+	}
+	var root map[string]any
+	if err := json.Unmarshal(raw, &root); err != nil {
+		return false
+	}
+	_, user := root["current_user_url"].(string)
+	_, repo := root["repository_url"].(string)
+	return user && repo
+}
+
+// ParseToCanonical returns the curated GitHub tools. baseURLOverride is
+// the REST API root: empty for github.com, https://api.<sub>.ghe.com for
+// GHE.com, or the server URL (with or without /api/v3) for GitHub
+// Enterprise Server.
+func ParseToCanonical(_ context.Context, _ []byte, apiName, baseURLOverride string) (*canonical.Service, error) {
+	baseURL, restPrefix, err := endpoints(baseURLOverride)
+	if err != nil {
+		return nil, err
+	}
+	svc := &canonical.Service{Name: apiName, BaseURL: baseURL}
+	for _, t := range restTools() {
+		t.path = restPrefix + t.path
+		svc.Operations = append(svc.Operations, t.operation(apiName))
+	}
+	for _, q := range graphQLTools() {
+		svc.Operations = append(svc.Operations, q.operation(apiName))
+	}
+	return svc, nil
+}
+
+// endpoints returns the service base URL and the prefix of REST paths.
+// github.com and GHE.com serve REST at the root and GraphQL at /graphql;
+// Enterprise Server serves them at /api/v3 and /api/graphql.
+func endpoints(override string) (string, string, error) {
+	base := strings.TrimRight(strings.TrimSpace(override), "/")
+	if base == "" {
+		return DefaultBaseURL, "", nil
+	}
+	u, err := url.Parse(base)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", "", fmt.Errorf("github: invalid base_url_override %q", override)
+	}
+	if u.Path == "" && strings.HasPrefix(strings.ToLower(u.Hostname()), "api.") {
+		return base, "", nil
+	}
+	for _, suffix := range []string{"/api/v3", "/api/graphql", "/api"} {
+		if strings.HasSuffix(base, suffix) {
+			base = strings.TrimSuffix(base, suffix)
+			break
+		}
+	}
+	return base + "/api", "/v3", nil
+}
+
+// param is a tool argument. Arguments with in set are sent as path or
+// query parameters; REST tools with a body take the others in "body".
+type param struct {
+	name      string
+	in        string
+	schema    map[string]any
+	required  bool
+	separator string // SegmentSeparator of a path parameter
+}
+
+// restTool is a curated REST operation.
+type restTool struct {
+	id          string
+	method      string
+	path        string
+	summary     string
+	description string
+	params      []param
+	// body is the schema of a "body" argument sent as the JSON body.
+	body map[string]any
+	// template is a JSON body with {{name}} placeholders for the params
+	// without in.
+	template string
+}
+
+func (t restTool) operation(api string) *canonical.Operation {
+	op := &canonical.Operation{
+		ServiceName: api,
+		ID:          t.id,
+		ToolName:    canonical.ToolName(api, t.id),
+		Method:      t.method,
+		Path:        t.path,
+		Summary:     t.summary,
+		Description: t.description,
+		StaticHeaders: map[string]string{
+			"Accept":               "application/vnd.github+json",
+			"X-GitHub-Api-Version": apiVersion,
+		},
+	}
+	props := map[string]any{}
+	required := []string{}
+	for _, p := range t.params {
+		props[p.name] = p.schema
+		if p.required {
+			required = append(required, p.name)
+		}
+		if p.in != "" {
+			op.Parameters = append(op.Parameters, canonical.Parameter{Name: p.name, In: p.in, Required: p.required, Schema: p.schema, SegmentSeparator: p.separator})
+		}
+	}
+	switch {
+	case t.body != nil:
+		props["body"] = t.body
+		required = append(required, "body")
+		op.RequestBody = &canonical.RequestBody{Required: true, ContentType: "application/json", Schema: t.body}
+	case t.template != "":
+		op.RequestBody = &canonical.RequestBody{Required: true, ContentType: "application/json", Template: t.template}
+	}
+	op.InputSchema = objectSchema(props, required)
+	return op
+}
+
+// graphQLTool is a curated GraphQL query or mutation. Its arguments are
+// the document's variables.
+type graphQLTool struct {
+	id          string
+	summary     string
+	description string
+	kind        string // query or mutation
+	field       string // root field, for the operation metadata
+	document    string
+	vars        []graphQLVar
+}
+
+type graphQLVar struct {
+	name     string
+	typ      string // GraphQL type, e.g. "Int" or "String!"
+	schema   map[string]any
+	required bool
+}
+
+func (q graphQLTool) operation(api string) *canonical.Operation {
+	props := map[string]any{}
+	required := []string{}
+	argTypes := map[string]string{}
+	var params []canonical.Parameter
+	for _, v := range q.vars {
+		props[v.name] = v.schema
+		argTypes[v.name] = v.typ
+		params = append(params, canonical.Parameter{Name: v.name, In: "argument", Required: v.required, Schema: v.schema})
+		if v.required {
+			required = append(required, v.name)
+		}
+	}
+	return &canonical.Operation{
+		ServiceName:   api,
+		ID:            q.id,
+		ToolName:      canonical.ToolName(api, q.id),
+		Method:        "post",
+		Path:          "/graphql",
+		Summary:       q.summary,
+		Description:   q.description,
+		Parameters:    params,
+		RequestBody:   &canonical.RequestBody{Required: true, ContentType: "application/json", Schema: map[string]any{"type": "object"}},
+		InputSchema:   objectSchema(props, required),
+		StaticHeaders: map[string]string{"Accept": "application/json"},
+		GraphQL: &canonical.GraphQLOperation{
+			OperationType: q.kind,
+			FieldName:     q.field,
+			ArgTypes:      argTypes,
+			Document:      q.document,
+			OperationName: q.id,
+		},
+	}
+}
+
+func objectSchema(props map[string]any, required []string) map[string]any {
+	schema := map[string]any{"type": "object", "properties": props, "additionalProperties": false}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func str(desc string) map[string]any { return described(map[string]any{"type": "string"}, desc) }
+func integer(desc string) map[string]any {
+	return described(map[string]any{"type": "integer"}, desc)
+}
+func enum(desc string, values ...string) map[string]any {
+	return described(map[string]any{"type": "string", "enum": values}, desc)
+}
+func stringList(desc string) map[string]any {
+	return described(map[string]any{"type": "array", "items": map[string]any{"type": "string"}}, desc)
+}
+
+func described(schema map[string]any, desc string) map[string]any {
+	if desc != "" {
+		schema["description"] = desc
+	}
+	return schema
+}
+
+var (
+	owner       = param{name: "owner", in: "path", required: true, schema: str("Account that owns the repository, e.g. octocat.")}
+	repo        = param{name: "repo", in: "path", required: true, schema: str("Repository name without the owner, e.g. hello-world.")}
+	issueNumber = param{name: "issue_number", in: "path", required: true, schema: integer("Issue or pull request number.")}
+	pullNumber  = param{name: "pull_number", in: "path", required: true, schema: integer("Pull request number.")}
+	runID       = param{name: "run_id", in: "path", required: true, schema: integer("Workflow run ID from listWorkflowRuns.")}
+	perPage     = param{name: "per_page", in: "query", schema: integer("Page size, default 30, at most 100.")}
+	page        = param{name: "page", in: "query", schema: integer("Page number, starting at 1.")}
+)
+
+// restTools returns the curated REST tools with paths relative to the REST
+// API root.
+func restTools() []restTool {
+	return []restTool{
+		{
+			id: "getAuthenticatedUser", method: "get", path: "/user",
+			summary:     "Get the current user",
+			description: "Returns the user Skyline authenticates as.",
+		},
+		{
+			id: "searchRepositories", method: "get", path: "/search/repositories",
+			summary:     "Search repositories",
+			description: "Finds repositories with GitHub search syntax, e.g. `org:acme language:go topic:cli` or `user:octocat archived:false`.",
+			params:      []param{{name: "q", in: "query", required: true, schema: str("Search query.")}, {name: "sort", in: "query", schema: enum("Default: best match.", "stars", "forks", "updated")}, perPage, page},
+		},
+		{
+			id: "getRepository", method: "get", path: "/repos/{owner}/{repo}",
+			summary:     "Get a repository",
+			description: "Returns a repository with its default branch, visibility, topics and counts.",
+			params:      []param{owner, repo},
+		},
+		{
+			id: "getFileContents", method: "get", path: "/repos/{owner}/{repo}/contents/{path}",
+			summary:     "Read a file or list a directory",
+			description: "Returns a file (content is base64-encoded) or a directory listing of a repository.",
+			params: []param{
+				owner, repo,
+				{name: "path", in: "path", required: true, separator: "/", schema: str("Path in the repository, e.g. docs/README.md.")},
+				{name: "ref", in: "query", schema: str("Branch, tag or commit SHA. Default: the default branch.")},
+			},
+		},
+		{
+			id: "searchIssues", method: "get", path: "/search/issues",
+			summary: "Search issues and pull requests",
+			description: "Finds issues and pull requests across repositories with GitHub search syntax, e.g. " +
+				"`repo:acme/api is:issue is:open label:bug` or `is:pr author:octocat review:required`. The query must include is:issue or is:pr.",
+			params: []param{{name: "q", in: "query", required: true, schema: str("Search query.")}, {name: "sort", in: "query", schema: enum("Default: best match.", "comments", "created", "updated")}, perPage, page},
+		},
+		{
+			id: "listIssues", method: "get", path: "/repos/{owner}/{repo}/issues",
+			summary:     "List a repository's issues",
+			description: "Lists issues of a repository, newest first. Pull requests are included and carry a pull_request key.",
+			params: []param{
+				owner, repo,
+				{name: "state", in: "query", schema: enum("Default: open.", "open", "closed", "all")},
+				{name: "labels", in: "query", schema: str("Comma-separated label names; issues must have all of them.")},
+				{name: "assignee", in: "query", schema: str("Login, none or *.")},
+				perPage, page,
+			},
+		},
+		{
+			id: "getIssue", method: "get", path: "/repos/{owner}/{repo}/issues/{issue_number}",
+			summary:     "Get an issue",
+			description: "Returns an issue or pull request with its labels and assignees. node_id is the content ID for addProjectItem.",
+			params:      []param{owner, repo, issueNumber},
+		},
+		{
+			id: "createIssue", method: "post", path: "/repos/{owner}/{repo}/issues",
+			summary:     "Create an issue",
+			description: `Opens an issue. Example body: {"title": "Login fails on Safari", "body": "Steps: ...", "labels": ["bug"]}.`,
+			params:      []param{owner, repo},
+			body: objectSchema(map[string]any{
+				"title":     str("Title."),
+				"body":      str("Markdown text."),
+				"labels":    stringList("Label names."),
+				"assignees": stringList("Logins."),
+				"milestone": integer("Milestone number."),
+			}, []string{"title"}),
+		},
+		{
+			id: "updateIssue", method: "patch", path: "/repos/{owner}/{repo}/issues/{issue_number}",
+			summary:     "Update or close an issue",
+			description: `Changes an issue or pull request; fields left out keep their value. Close with {"state": "closed", "state_reason": "completed"}. labels and assignees replace the current ones.`,
+			params:      []param{owner, repo, issueNumber},
+			body: objectSchema(map[string]any{
+				"title":        str("Title."),
+				"body":         str("Markdown text."),
+				"state":        enum("", "open", "closed"),
+				"state_reason": enum("Why the issue was closed.", "completed", "not_planned", "reopened"),
+				"labels":       stringList("Label names."),
+				"assignees":    stringList("Logins."),
+			}, nil),
+		},
+		{
+			id: "listIssueComments", method: "get", path: "/repos/{owner}/{repo}/issues/{issue_number}/comments",
+			summary:     "List comments on an issue",
+			description: "Lists the conversation comments of an issue or pull request, oldest first. Review comments on code are not included.",
+			params:      []param{owner, repo, issueNumber, perPage, page},
+		},
+		{
+			id: "addIssueComment", method: "post", path: "/repos/{owner}/{repo}/issues/{issue_number}/comments",
+			summary:     "Comment on an issue or pull request",
+			description: "Adds a comment to the conversation of an issue or pull request.",
+			params:      []param{owner, repo, issueNumber, {name: "text", required: true, schema: str("Markdown text.")}},
+			template:    `{"body": {{text}}}`,
+		},
+		{
+			id: "listPullRequests", method: "get", path: "/repos/{owner}/{repo}/pulls",
+			summary:     "List pull requests",
+			description: "Lists pull requests of a repository, newest first.",
+			params: []param{
+				owner, repo,
+				{name: "state", in: "query", schema: enum("Default: open.", "open", "closed", "all")},
+				{name: "head", in: "query", schema: str("Source branch as user:branch.")},
+				{name: "base", in: "query", schema: str("Target branch.")},
+				perPage, page,
+			},
+		},
+		{
+			id: "getPullRequest", method: "get", path: "/repos/{owner}/{repo}/pulls/{pull_number}",
+			summary:     "Get a pull request",
+			description: "Returns a pull request with its branches, mergeability and review state.",
+			params:      []param{owner, repo, pullNumber},
+		},
+		{
+			id: "listPullRequestFiles", method: "get", path: "/repos/{owner}/{repo}/pulls/{pull_number}/files",
+			summary:     "List a pull request's changed files",
+			description: "Lists the files a pull request changes, with additions, deletions and the patch of each.",
+			params:      []param{owner, repo, pullNumber, perPage, page},
+		},
+		{
+			id: "createPullRequest", method: "post", path: "/repos/{owner}/{repo}/pulls",
+			summary:     "Open a pull request",
+			description: `Opens a pull request. Example body: {"title": "Fix login", "head": "fix-login", "base": "main", "body": "Closes #12"}.`,
+			params:      []param{owner, repo},
+			body: objectSchema(map[string]any{
+				"title": str("Title."),
+				"head":  str("Branch with the changes; user:branch for a fork."),
+				"base":  str("Branch to merge into."),
+				"body":  str("Markdown description."),
+				"draft": map[string]any{"type": "boolean"},
+			}, []string{"title", "head", "base"}),
+		},
+		{
+			id: "reviewPullRequest", method: "post", path: "/repos/{owner}/{repo}/pulls/{pull_number}/reviews",
+			summary:     "Review a pull request",
+			description: "Approves a pull request, requests changes or leaves a review comment.",
+			params: []param{
+				owner, repo, pullNumber,
+				{name: "event", required: true, schema: enum("", "APPROVE", "REQUEST_CHANGES", "COMMENT")},
+				{name: "text", schema: str("Review text in Markdown; required unless approving.")},
+			},
+			template: `{"event": {{event}}, "body": "{{text}}"}`,
+		},
+		{
+			id: "mergePullRequest", method: "put", path: "/repos/{owner}/{repo}/pulls/{pull_number}/merge",
+			summary:     "Merge a pull request",
+			description: "Merges a pull request. Check mergeable_state with getPullRequest first.",
+			params: []param{
+				owner, repo, pullNumber,
+				{name: "merge_method", required: true, schema: enum("", "merge", "squash", "rebase")},
+			},
+			template: `{"merge_method": {{merge_method}}}`,
+		},
+		{
+			id: "listWorkflowRuns", method: "get", path: "/repos/{owner}/{repo}/actions/runs",
+			summary:     "List workflow runs",
+			description: "Lists GitHub Actions runs of a repository, newest first.",
+			params: []param{
+				owner, repo,
+				{name: "branch", in: "query", schema: str("Only runs for this branch.")},
+				{name: "status", in: "query", schema: str("e.g. failure, success, in_progress, queued.")},
+				{name: "event", in: "query", schema: str("Triggering event, e.g. push or pull_request.")},
+				perPage, page,
+			},
+		},
+		{
+			id: "getWorkflowRun", method: "get", path: "/repos/{owner}/{repo}/actions/runs/{run_id}",
+			summary:     "Get a workflow run",
+			description: "Returns a GitHub Actions run with its status, conclusion and triggering commit.",
+			params:      []param{owner, repo, runID},
+		},
+		{
+			id: "listWorkflowRunJobs", method: "get", path: "/repos/{owner}/{repo}/actions/runs/{run_id}/jobs",
+			summary:     "List a workflow run's jobs",
+			description: "Lists the jobs of a run with the status of each step, to find what failed.",
+			params:      []param{owner, repo, runID, perPage, page},
+		},
+		{
+			id: "rerunFailedJobs", method: "post", path: "/repos/{owner}/{repo}/actions/runs/{run_id}/rerun-failed-jobs",
+			summary:     "Re-run failed jobs",
+			description: "Re-runs the failed jobs of a workflow run and the jobs that depend on them.",
+			params:      []param{owner, repo, runID},
+		},
+		{
+			id: "dispatchWorkflow", method: "post", path: "/repos/{owner}/{repo}/actions/workflows/{workflow_id}/dispatches",
+			summary:     "Start a workflow",
+			description: `Starts a workflow that has a workflow_dispatch trigger. Example body: {"ref": "main", "inputs": {"environment": "staging"}}.`,
+			params:      []param{owner, repo, {name: "workflow_id", in: "path", required: true, schema: str("Workflow file name, e.g. deploy.yml, or its ID.")}},
+			body: objectSchema(map[string]any{
+				"ref":    str("Branch or tag to run on."),
+				"inputs": map[string]any{"type": "object", "description": "Workflow inputs by name.", "additionalProperties": true},
+			}, []string{"ref"}),
+		},
+	}
+}
+
+func intVar(name, desc string) graphQLVar {
+	return graphQLVar{name: name, typ: "Int", schema: integer(desc)}
+}
+
+func stringVar(name, desc string) graphQLVar {
+	return graphQLVar{name: name, typ: "String!", schema: str(desc), required: true}
+}
+
+func idVar(name, desc string) graphQLVar {
+	return graphQLVar{name: name, typ: "ID!", schema: str(desc), required: true}
+}
+
+var (
+	ownerVar = stringVar("owner", "Account that owns the repository.")
+	repoVar  = stringVar("repo", "Repository name without the owner.")
+	firstVar = intVar("first", "Page size, default 20, at most 100.")
+	afterVar = graphQLVar{name: "after", typ: "String", schema: str("endCursor of the previous page.")}
+)
+
+// projectItemFields selects a Projects item with its issue, pull request or
+// draft and its field values.
+const projectItemFields = `totalCount
+      pageInfo { hasNextPage endCursor }
+      nodes {
+        id
+        type
+        content {
+          ... on Issue { number title url state repository { nameWithOwner } }
+          ... on PullRequest { number title url state repository { nameWithOwner } }
+          ... on DraftIssue { title }
+        }
+        fieldValues(first: 20) {
+          nodes {
+            ... on ProjectV2ItemFieldSingleSelectValue { name field { ... on ProjectV2FieldCommon { name } } }
+            ... on ProjectV2ItemFieldTextValue { text field { ... on ProjectV2FieldCommon { name } } }
+            ... on ProjectV2ItemFieldNumberValue { number field { ... on ProjectV2FieldCommon { name } } }
+            ... on ProjectV2ItemFieldDateValue { date field { ... on ProjectV2FieldCommon { name } } }
+            ... on ProjectV2ItemFieldIterationValue { title field { ... on ProjectV2FieldCommon { name } } }
+          }
+        }
+      }`
+
+// graphQLTools returns the tools for discussions and Projects, which the
+// REST API lacks.
+func graphQLTools() []graphQLTool {
+	return []graphQLTool{
+		{
+			id: "listDiscussions", kind: "query", field: "repository",
+			summary:     "List discussions",
+			description: "Lists the discussions of a repository, most recently updated first.",
+			vars:        []graphQLVar{ownerVar, repoVar, firstVar, afterVar, {name: "categoryId", typ: "ID", schema: str("Only this category; IDs come from listDiscussionCategories.")}},
+			document: `query listDiscussions($owner: String!, $repo: String!, $first: Int = 20, $after: String, $categoryId: ID) {
+  repository(owner: $owner, name: $repo) {
+    discussions(first: $first, after: $after, categoryId: $categoryId, orderBy: {field: UPDATED_AT, direction: DESC}) {
+      totalCount
+      pageInfo { hasNextPage endCursor }
+      nodes { id number title url author { login } category { name } createdAt updatedAt isAnswered comments { totalCount } }
+    }
+  }
+}`,
+		},
+		{
+			id: "getDiscussion", kind: "query", field: "repository",
+			summary:     "Get a discussion",
+			description: "Returns a discussion with its answer and comments. id is the discussionId for addDiscussionComment.",
+			vars:        []graphQLVar{ownerVar, repoVar, {name: "number", typ: "Int!", schema: integer("Discussion number."), required: true}},
+			document: `query getDiscussion($owner: String!, $repo: String!, $number: Int!) {
+  repository(owner: $owner, name: $repo) {
+    discussion(number: $number) {
+      id number title body url createdAt
+      author { login }
+      category { name }
+      answer { author { login } body }
+      comments(first: 50) { totalCount nodes { id author { login } body createdAt } }
+    }
+  }
+}`,
+		},
+		{
+			id: "listDiscussionCategories", kind: "query", field: "repository",
+			summary:     "List discussion categories",
+			description: "Lists a repository's discussion categories, and returns the repository ID, both needed by createDiscussion.",
+			vars:        []graphQLVar{ownerVar, repoVar},
+			document: `query listDiscussionCategories($owner: String!, $repo: String!) {
+  repository(owner: $owner, name: $repo) {
+    id
+    discussionCategories(first: 50) { nodes { id name description isAnswerable } }
+  }
+}`,
+		},
+		{
+			id: "createDiscussion", kind: "mutation", field: "createDiscussion",
+			summary:     "Start a discussion",
+			description: "Starts a discussion. Get repositoryId and categoryId from listDiscussionCategories.",
+			vars: []graphQLVar{
+				idVar("repositoryId", "Repository node ID."),
+				idVar("categoryId", "Discussion category ID."),
+				stringVar("title", "Title."),
+				stringVar("body", "Markdown text."),
+			},
+			document: `mutation createDiscussion($repositoryId: ID!, $categoryId: ID!, $title: String!, $body: String!) {
+  createDiscussion(input: {repositoryId: $repositoryId, categoryId: $categoryId, title: $title, body: $body}) {
+    discussion { id number url }
+  }
+}`,
+		},
+		{
+			id: "addDiscussionComment", kind: "mutation", field: "addDiscussionComment",
+			summary:     "Comment on a discussion",
+			description: "Adds a comment to a discussion, or a reply when replyToId is set.",
+			vars: []graphQLVar{
+				idVar("discussionId", "Discussion ID from getDiscussion."),
+				stringVar("body", "Markdown text."),
+				{name: "replyToId", typ: "ID", schema: str("Comment to reply to.")},
+			},
+			document: `mutation addDiscussionComment($discussionId: ID!, $body: String!, $replyToId: ID) {
+  addDiscussionComment(input: {discussionId: $discussionId, body: $body, replyToId: $replyToId}) {
+    comment { id url }
+  }
+}`,
+		},
+		{
+			id: "listProjects", kind: "query", field: "repositoryOwner",
+			summary:     "List Projects",
+			description: "Lists the Projects of an organization or user, with their numbers and IDs.",
+			vars:        []graphQLVar{stringVar("owner", "Organization or user login."), firstVar, afterVar},
+			document: `query listProjects($owner: String!, $first: Int = 20, $after: String) {
+  repositoryOwner(login: $owner) {
+    ... on Organization { projectsV2(first: $first, after: $after) { pageInfo { hasNextPage endCursor } nodes { id number title url closed } } }
+    ... on User { projectsV2(first: $first, after: $after) { pageInfo { hasNextPage endCursor } nodes { id number title url closed } } }
+  }
+}`,
+		},
+		{
+			id: "getProjectItems", kind: "query", field: "repositoryOwner",
+			summary:     "List a project's items",
+			description: "Lists the issues, pull requests and drafts of a Project with their field values, such as Status and Iteration.",
+			vars: []graphQLVar{
+				stringVar("owner", "Organization or user login."),
+				{name: "number", typ: "Int!", schema: integer("Project number from listProjects."), required: true},
+				firstVar, afterVar,
+			},
+			document: `query getProjectItems($owner: String!, $number: Int!, $first: Int = 20, $after: String) {
+  repositoryOwner(login: $owner) {
+    ... on Organization { projectV2(number: $number) { id title items(first: $first, after: $after) { ` + projectItemFields + ` } } }
+    ... on User { projectV2(number: $number) { id title items(first: $first, after: $after) { ` + projectItemFields + ` } } }
+  }
+}`,
+		},
+		{
+			id: "getProjectFields", kind: "query", field: "node",
+			summary:     "List a project's fields",
+			description: "Lists the fields of a Project with their IDs, the options of single-select fields and the iterations, for updateProjectItemField.",
+			vars:        []graphQLVar{idVar("projectId", "Project ID from listProjects.")},
+			document: `query getProjectFields($projectId: ID!) {
+  node(id: $projectId) {
+    ... on ProjectV2 {
+      fields(first: 50) {
+        nodes {
+          ... on ProjectV2FieldCommon { id name dataType }
+          ... on ProjectV2SingleSelectField { options { id name } }
+          ... on ProjectV2IterationField { configuration { iterations { id title startDate } } }
+        }
+      }
+    }
+  }
+}`,
+		},
+		{
+			id: "addProjectItem", kind: "mutation", field: "addProjectV2ItemById",
+			summary:     "Add an issue or pull request to a project",
+			description: "Adds an issue or pull request to a Project. contentId is the node_id returned by getIssue or getPullRequest.",
+			vars:        []graphQLVar{idVar("projectId", "Project ID from listProjects."), idVar("contentId", "Issue or pull request node ID.")},
+			document: `mutation addProjectItem($projectId: ID!, $contentId: ID!) {
+  addProjectV2ItemById(input: {projectId: $projectId, contentId: $contentId}) {
+    item { id }
+  }
+}`,
+		},
+		{
+			id: "updateProjectItemField", kind: "mutation", field: "updateProjectV2ItemFieldValue",
+			summary:     "Set a field of a project item",
+			description: `Sets a field of a Project item, e.g. moves it to another Status column. value holds one of {"singleSelectOptionId": "..."}, {"iterationId": "..."}, {"text": "..."}, {"number": 3} or {"date": "2026-01-31"}; get the IDs from getProjectFields.`,
+			vars: []graphQLVar{
+				idVar("projectId", "Project ID."),
+				idVar("itemId", "Item ID from getProjectItems or addProjectItem."),
+				idVar("fieldId", "Field ID from getProjectFields."),
+				{name: "value", typ: "ProjectV2FieldValue!", required: true, schema: map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"singleSelectOptionId": str(""),
+						"iterationId":          str(""),
+						"text":                 str(""),
+						"number":               map[string]any{"type": "number"},
+						"date":                 str("YYYY-MM-DD."),
+					},
+					"minProperties":        1,
+					"maxProperties":        1,
+					"additionalProperties": false,
+				}},
+			},
+			document: `mutation updateProjectItemField($projectId: ID!, $itemId: ID!, $fieldId: ID!, $value: ProjectV2FieldValue!) {
+  updateProjectV2ItemFieldValue(input: {projectId: $projectId, itemId: $itemId, fieldId: $fieldId, value: $value}) {
+    projectV2Item { id }
+  }
+}`,
+		},
+	}
+}
@@ -0,0 +1,161 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/logging"
+	"skyline-mcp/internal/redact"
+	"skyline-mcp/internal/runtime"
+)
+
+func TestLooksLikeGitHub(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want bool
+	}{
+		{"api root", `{"current_user_url":"https://api.github.com/user","repository_url":"https://api.github.com/repos/{owner}/{repo}"}`, true},
+		{"empty", "", false},
+		{"openapi doc", `{"openapi":"3.0.0"}`, false},
+		{"not json", "hello world", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LooksLikeGitHub([]byte(tt.raw)); got != tt.want {
+				t.Errorf("LooksLikeGitHub() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEndpoints(t *testing.T) {
+	tests := []struct {
+		override, wantBase, wantPrefix string
+	}{
+		{"", "https://api.github.com", ""},
+		{"https://api.github.com/", "https://api.github.com", ""},
+		{"https://api.acme.ghe.com", "https://api.acme.ghe.com", ""},
+		{"https://github.acme.com", "https://github.acme.com/api", "/v3"},
+		{"https://github.acme.com/api/v3/", "https://github.acme.com/api", "/v3"},
+	}
+	for _, tt := range tests {
+		base, prefix, err := endpoints(tt.override)
+		if err != nil || base != tt.wantBase || prefix != tt.wantPrefix {
+			t.Errorf("endpoints(%q) = %q, %q, %v; want %q, %q", tt.override, base, prefix, err, tt.wantBase, tt.wantPrefix)
+		}
+	}
+	if _, _, err := endpoints("github.acme.com"); err == nil {
+		t.Error("base URL without a scheme accepted")
+	}
+}
+
+func TestParseToCanonical(t *testing.T) {
+	svc, err := ParseToCanonical(context.Background(), nil, "gh", "")
+	if err != nil {
+		t.Fatalf("ParseToCanonical: %v", err)
+	}
+	if svc.BaseURL != DefaultBaseURL {
+		t.Errorf("BaseURL = %q", svc.BaseURL)
+	}
+	seen := map[string]bool{}
+	for _, op := range svc.Operations {
+		if seen[op.ID] || op.ToolName != "gh__"+op.ID || op.Summary == "" || op.Description == "" {
+			t.Errorf("operation %s = %+v", op.ID, op)
+		}
+		seen[op.ID] = true
+		if op.GraphQL == nil {
+			continue
+		}
+		// The documents are sent as is, so they must parse and declare
+		// exactly the variables the tool takes.
+		doc, err := parser.ParseQuery(&ast.Source{Name: op.ID, Input: op.GraphQL.Document})
+		if err != nil {
+			t.Errorf("%s: %v", op.ID, err)
+			continue
+		}
+		if len(doc.Operations) != 1 || doc.Operations[0].Name != op.GraphQL.OperationName {
+			t.Errorf("%s: document must hold one operation named %s", op.ID, op.GraphQL.OperationName)
+			continue
+		}
+		vars := doc.Operations[0].VariableDefinitions
+		if len(vars) != len(op.GraphQL.ArgTypes) {
+			t.Errorf("%s: document declares %d variables, tool has %d arguments", op.ID, len(vars), len(op.GraphQL.ArgTypes))
+		}
+		for _, v := range vars {
+			if typ := op.GraphQL.ArgTypes[v.Variable]; typ != v.Type.String() {
+				t.Errorf("%s: $%s is %s in the document, %q in the tool", op.ID, v.Variable, v.Type, typ)
+			}
+		}
+	}
+	for _, id := range []string{"searchIssues", "createPullRequest", "listWorkflowRuns", "listDiscussions", "updateProjectItemField"} {
+		if !seen[id] {
+			t.Errorf("missing operation %s", id)
+		}
+	}
+}
+
+func TestRESTAndGraphQLShareAuth(t *testing.T) {
+	type request struct{ method, path, auth, body string }
+	var got request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = request{r.Method, r.URL.Path, r.Header.Get("Authorization"), string(body)}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	// A plain server URL is a GitHub Enterprise Server.
+	svc, err := ParseToCanonical(context.Background(), nil, "gh", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{APIs: []config.APIConfig{{
+		Name: "gh", SpecType: "github", BaseURLOverride: server.URL,
+		Auth: &config.AuthConfig{Type: "bearer", Token: "secret"},
+	}}}
+	cfg.ApplyDefaults()
+	exec, err := runtime.NewExecutor(cfg, []*canonical.Service{svc}, logging.Discard(), redact.NewRedactor())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops := map[string]*canonical.Operation{}
+	for _, op := range svc.Operations {
+		ops[op.ID] = op
+	}
+
+	if _, err := exec.Execute(context.Background(), ops["getFileContents"], map[string]any{"owner": "acme", "repo": "api", "path": "docs/a b.md"}); err != nil {
+		t.Fatal(err)
+	}
+	if got.method != "GET" || got.path != "/api/v3/repos/acme/api/contents/docs/a b.md" || got.auth != "Bearer secret" {
+		t.Errorf("REST request = %+v", got)
+	}
+
+	if _, err := exec.Execute(context.Background(), ops["getDiscussion"], map[string]any{"owner": "acme", "repo": "api", "number": 7.0}); err != nil {
+		t.Fatal(err)
+	}
+	var payload struct {
+		Query         string         `json:"query"`
+		OperationName string         `json:"operationName"`
+		Variables     map[string]any `json:"variables"`
+	}
+	if err := json.Unmarshal([]byte(got.body), &payload); err != nil {
+		t.Fatal(err)
+	}
+	if got.method != "POST" || got.path != "/api/graphql" || got.auth != "Bearer secret" {
+		t.Errorf("GraphQL request = %+v", got)
+	}
+	if payload.OperationName != "getDiscussion" || payload.Variables["number"] != 7.0 || payload.Variables["owner"] != "acme" {
+		t.Errorf("GraphQL payload = %+v", payload)
+	}
+}
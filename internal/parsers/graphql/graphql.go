@@ -15,7 +15,10 @@ import (
 	gql "skyline-mcp/internal/graphql"
 )
 
-var sdlSignature = regexp.MustCompile(`(?im)\b(type|extend)\s+query\b|\b(type|extend)\s+mutation\b|\bschema\s*\{`)
+// sdlSignature matches a root type definition or a schema definition; the
+// latter may carry directives (schema @link(...) { query: Query }), as
+// SDL exported from federated graphs does.
+var sdlSignature = regexp.MustCompile(`(?im)\b(type|extend)\s+query\b|\b(type|extend)\s+mutation\b|\bschema\s*\{|\bschema\s+@[^{}]*\{\s*(query|mutation)\s*:`)
 
 type graphQLOptKey struct{}
 
@@ -43,7 +46,19 @@ func LooksLikeGraphQLSDL(raw []byte) bool {
 // ParseToCanonical parses GraphQL SDL or introspection JSON into a canonical Service.
 func ParseToCanonical(ctx context.Context, raw []byte, apiName, baseURLOverride string) (*canonical.Service, error) {
 	if LooksLikeGraphQLIntrospection(raw) {
-		return ParseIntrospectionToCanonicalWithContext(ctx, raw, apiName, baseURLOverride)
+		service, err := ParseIntrospectionToCanonicalWithContext(ctx, raw, apiName, baseURLOverride)
+		cfg := getConfigFromContext(ctx)
+		if err != nil || cfg == nil {
+			return service, err
+		}
+		schema, err := introspectionSchemaFromRaw(raw)
+		if err != nil {
+			return nil, err
+		}
+		if err := appendPersistedQueries(service, schema, cfg, false); err != nil {
+			return nil, err
+		}
+		return service, nil
 	}
 	if !LooksLikeGraphQLSDL(raw) {
 		return nil, fmt.Errorf("graphql: unsupported schema payload")
@@ -98,6 +113,11 @@ func ParseToCanonical(ctx context.Context, raw []byte, apiName, baseURLOverride
 	if len(service.Operations) == 0 {
 		return nil, fmt.Errorf("graphql sdl: no query or mutation fields found")
 	}
+	if cfg := getConfigFromContext(ctx); cfg != nil {
+		if err := appendPersistedQueries(service, schema, cfg, true); err != nil {
+			return nil, err
+		}
+	}
 
 	sort.Slice(service.Operations, func(i, j int) bool {
 		return service.Operations[i].ToolName < service.Operations[j].ToolName
@@ -52,6 +52,7 @@ func TestLooksLikeGraphQLSDL(t *testing.T) {
 		{"type Mutation", "type Mutation { createUser(name: String!): User }", true},
 		{"extend query", "extend type Query { newField: String }", true},
 		{"schema block", "schema { query: Query }", true},
+		{"schema with directives", "schema @link(url: \"https://specs.apollo.dev/federation/v2.3\") { query: RootQuery }", true},
 		{"json doc", `{"openapi":"3.0.0"}`, false},
 		{"plain text", "hello world", false},
 		{"empty", "", false},
@@ -88,6 +89,27 @@ func TestLooksLikeGraphQLIntrospection(t *testing.T) {
 	}
 }
 
+func TestIntrospectionErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{"disabled", `{"errors":[{"message":"GraphQL introspection is not allowed"}]}`, "GraphQL introspection is not allowed"},
+		{"null data", `{"data":null,"errors":[{"message":"a"},{"message":"b"}]}`, "a; b"},
+		{"schema with warnings", `{"data":{"__schema":{"types":[]}},"errors":[{"message":"deprecated"}]}`, ""},
+		{"schema", introspectionJSON, ""},
+		{"sdl", "type Query { hello: String }", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IntrospectionErrors([]byte(tt.raw)); got != tt.want {
+				t.Errorf("IntrospectionErrors() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestParseToCanonical_SDL(t *testing.T) {
 	svc, err := ParseToCanonical(context.Background(), []byte(minimalSDL), "myapi", "https://api.example.com/graphql")
 	if err != nil {
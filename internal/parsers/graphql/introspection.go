@@ -69,6 +69,34 @@ func LooksLikeGraphQLIntrospection(raw []byte) bool {
 	return payload.Data.Schema.Types != nil
 }
 
+// IntrospectionErrors returns the error messages of an introspection
+// response that carries no schema, as servers with introspection disabled
+// answer, joined with "; ". It returns "" for anything else.
+func IntrospectionErrors(raw []byte) string {
+	var payload struct {
+		Data   *introspectionData `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(raw, &payload); err != nil || len(payload.Errors) == 0 {
+		return ""
+	}
+	if payload.Data != nil && payload.Data.Schema.Types != nil {
+		return ""
+	}
+	messages := make([]string, 0, len(payload.Errors))
+	for _, e := range payload.Errors {
+		if e.Message != "" {
+			messages = append(messages, e.Message)
+		}
+	}
+	if len(messages) == 0 {
+		return "introspection failed"
+	}
+	return strings.Join(messages, "; ")
+}
+
 func ParseIntrospectionToCanonical(raw []byte, apiName, baseURLOverride string) (*canonical.Service, error) {
 	var payload introspectionResponse
 	if err := json.Unmarshal(raw, &payload); err != nil {
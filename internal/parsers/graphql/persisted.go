@@ -0,0 +1,193 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+	"github.com/vektah/gqlparser/v2/validator"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+)
+
+type graphQLConfigKey struct{}
+
+// SetConfigInContext adds an API's graphql config (persisted queries and
+// fragments) to context.
+func SetConfigInContext(ctx context.Context, cfg *config.GraphQLConfig) context.Context {
+	return context.WithValue(ctx, graphQLConfigKey{}, cfg)
+}
+
+func getConfigFromContext(ctx context.Context) *config.GraphQLConfig {
+	if cfg, ok := ctx.Value(graphQLConfigKey{}).(*config.GraphQLConfig); ok && cfg != nil && len(cfg.PersistedQueries) > 0 {
+		return cfg
+	}
+	return nil
+}
+
+var (
+	fragmentNameRE   = regexp.MustCompile(`^\s*fragment\s+([_A-Za-z][_0-9A-Za-z]*)`)
+	fragmentSpreadRE = regexp.MustCompile(`\.\.\.\s*([_A-Za-z][_0-9A-Za-z]*)`)
+)
+
+// appendPersistedQueries adds one operation per persisted query, with the
+// query's variables as typed arguments. Queries are validated against
+// schema when validate is set; schemas rebuilt from introspection lack the
+// input fields and directives validation needs.
+func appendPersistedQueries(service *canonical.Service, schema *ast.Schema, cfg *config.GraphQLConfig, validate bool) error {
+	fragments := map[string]string{}
+	for _, fragment := range cfg.Fragments {
+		if m := fragmentNameRE.FindStringSubmatch(fragment); m != nil {
+			fragments[m[1]] = strings.TrimSpace(fragment)
+		}
+	}
+	for _, pq := range cfg.PersistedQueries {
+		op, err := buildPersistedOperation(schema, service.Name, pq, fragments, validate)
+		if err != nil {
+			return fmt.Errorf("graphql: persisted query %s: %w", pq.Name, err)
+		}
+		service.Operations = append(service.Operations, op)
+	}
+	sort.Slice(service.Operations, func(i, j int) bool {
+		return service.Operations[i].ToolName < service.Operations[j].ToolName
+	})
+	return nil
+}
+
+func buildPersistedOperation(schema *ast.Schema, apiName string, pq config.PersistedQuery, fragments map[string]string, validate bool) (*canonical.Operation, error) {
+	document := strings.TrimSpace(pq.Query)
+	for _, name := range usedFragments(document, fragments) {
+		document += "\n\n" + fragments[name]
+	}
+	doc, err := parser.ParseQuery(&ast.Source{Name: pq.Name, Input: document})
+	if err != nil {
+		return nil, err
+	}
+	if validate {
+		if errs := validator.Validate(schema, doc); errs != nil {
+			return nil, errs
+		}
+	}
+	if len(doc.Operations) != 1 {
+		return nil, fmt.Errorf("must hold exactly one operation")
+	}
+	def := doc.Operations[0]
+
+	properties := map[string]any{}
+	required := []string{}
+	params := []canonical.Parameter{}
+	argTypes := map[string]string{}
+	for _, v := range def.VariableDefinitions {
+		varSchema := inputSchemaForType(schema, v.Type, 0)
+		if v.DefaultValue != nil {
+			if value, err := v.DefaultValue.Value(nil); err == nil {
+				varSchema["default"] = value
+			}
+		}
+		requiredVar := v.Type.NonNull && v.DefaultValue == nil
+		argTypes[v.Variable] = formatType(v.Type)
+		params = append(params, canonical.Parameter{Name: v.Variable, In: "argument", Required: requiredVar, Schema: varSchema})
+		properties[v.Variable] = varSchema
+		if requiredVar {
+			required = append(required, v.Variable)
+		}
+	}
+	inputSchema := map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		inputSchema["required"] = uniqueSorted(required)
+	}
+
+	opType := string(def.Operation)
+	summary := strings.TrimSpace(pq.Description)
+	if summary == "" {
+		summary = fmt.Sprintf("GraphQL persisted %s %s", opType, pq.Name)
+	}
+	root := schema.Query
+	if def.Operation == ast.Mutation {
+		root = schema.Mutation
+	}
+	fieldName, returnType := "", ""
+	if len(def.SelectionSet) == 1 && root != nil {
+		if field, ok := def.SelectionSet[0].(*ast.Field); ok {
+			fieldName = field.Name
+			if fd := root.Fields.ForName(field.Name); fd != nil {
+				returnType = baseTypeName(fd.Type)
+			}
+		}
+	}
+
+	return &canonical.Operation{
+		ServiceName: apiName,
+		ID:          pq.Name,
+		ToolName:    canonical.ToolName(apiName, pq.Name),
+		Method:      "post",
+		Summary:     summary,
+		Parameters:  params,
+		RequestBody: &canonical.RequestBody{Required: true, ContentType: "application/json", Schema: map[string]any{"type": "object"}},
+		InputSchema: inputSchema,
+		ResponseSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"data":   map[string]any{"type": "object"},
+				"errors": map[string]any{"type": "array", "items": map[string]any{"type": "object"}},
+			},
+		},
+		StaticHeaders: map[string]string{"Accept": "application/json"},
+		GraphQL: &canonical.GraphQLOperation{
+			OperationType:  opType,
+			FieldName:      fieldName,
+			ReturnTypeName: returnType,
+			ArgTypes:       argTypes,
+			Document:       document,
+			OperationName:  def.Name,
+		},
+	}, nil
+}
+
+// usedFragments returns the names of the fragments document spreads,
+// directly or through other fragments, in a stable order. Unused fragments
+// must not be sent: GraphQL servers reject them.
+func usedFragments(document string, fragments map[string]string) []string {
+	used := map[string]bool{}
+	queue := []string{document}
+	for len(queue) > 0 {
+		src := queue[0]
+		queue = queue[1:]
+		for _, m := range fragmentSpreadRE.FindAllStringSubmatch(src, -1) {
+			name := m[1]
+			if name == "on" || used[name] {
+				continue
+			}
+			if fragment, ok := fragments[name]; ok {
+				used[name] = true
+				queue = append(queue, fragment)
+			}
+		}
+	}
+	names := make([]string, 0, len(used))
+	for name := range used {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// introspectionSchemaFromRaw converts an introspection result to an AST
+// schema the variables of persisted queries are typed from.
+func introspectionSchemaFromRaw(raw []byte) (*ast.Schema, error) {
+	var payload introspectionResponse
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return nil, fmt.Errorf("graphql introspection: parse failed: %w", err)
+	}
+	return introspectionToASTSchema(&payload.Data.Schema)
+}
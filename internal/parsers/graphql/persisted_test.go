@@ -0,0 +1,93 @@
+package graphql
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+)
+
+func TestParseToCanonical_PersistedQueries(t *testing.T) {
+	cfg := &config.GraphQLConfig{
+		PersistedQueries: []config.PersistedQuery{{
+			Name:        "userCard",
+			Description: "A user's card",
+			Query:       `query UserCard($id: ID!) { user(id: $id) { ...Card } }`,
+		}, {
+			Name:  "me",
+			Query: `query Me($id: ID = "42") { user(id: $id) { name } }`,
+		}},
+		Fragments: []string{
+			`fragment Card on User { id ...Contact }`,
+			`fragment Contact on User { email }`,
+			`fragment Unused on User { name }`,
+		},
+	}
+	ctx := SetConfigInContext(context.Background(), cfg)
+	svc, err := ParseToCanonical(ctx, []byte(minimalSDL), "myapi", "https://api.example.com/graphql")
+	if err != nil {
+		t.Fatalf("ParseToCanonical failed: %v", err)
+	}
+	ops := map[string]*canonical.Operation{}
+	for _, candidate := range svc.Operations {
+		ops[candidate.ID] = candidate
+	}
+	op := ops["userCard"]
+	if op == nil || ops["me"] == nil {
+		t.Fatal("missing persisted query operations")
+	}
+	if op.ToolName != "myapi__userCard" || op.Summary != "A user's card" {
+		t.Fatalf("tool %q, summary %q", op.ToolName, op.Summary)
+	}
+	gql := op.GraphQL
+	if gql.OperationName != "UserCard" || gql.FieldName != "user" || gql.ReturnTypeName != "User" {
+		t.Fatalf("unexpected graphql metadata: %+v", gql)
+	}
+	if !strings.Contains(gql.Document, "fragment Card") || !strings.Contains(gql.Document, "fragment Contact") || strings.Contains(gql.Document, "Unused") {
+		t.Fatalf("document should carry exactly the used fragments:\n%s", gql.Document)
+	}
+	if got := op.InputSchema["required"]; !reflect.DeepEqual(got, []string{"id"}) {
+		t.Fatalf("required = %v", got)
+	}
+	me := ops["me"].InputSchema
+	if _, ok := me["required"]; ok {
+		t.Fatalf("a variable with a default should be optional: %v", me)
+	}
+	if id := me["properties"].(map[string]any)["id"].(map[string]any); id["default"] != "42" {
+		t.Fatalf("id schema = %v", id)
+	}
+}
+
+func TestParseToCanonical_PersistedQueryValidatedAgainstSDL(t *testing.T) {
+	cfg := &config.GraphQLConfig{PersistedQueries: []config.PersistedQuery{{
+		Name: "broken", Query: `query { user(id: "1") { nickname } }`,
+	}}}
+	ctx := SetConfigInContext(context.Background(), cfg)
+	_, err := ParseToCanonical(ctx, []byte(minimalSDL), "myapi", "https://api.example.com/graphql")
+	if err == nil || !strings.Contains(err.Error(), "persisted query broken") || !strings.Contains(err.Error(), "nickname") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestParseToCanonical_PersistedQueryFromIntrospection(t *testing.T) {
+	cfg := &config.GraphQLConfig{PersistedQueries: []config.PersistedQuery{{
+		Name: "greet", Query: `query Greet { hello }`,
+	}}}
+	ctx := SetConfigInContext(context.Background(), cfg)
+	svc, err := ParseToCanonical(ctx, []byte(introspectionJSON), "introapi", "https://api.example.com/graphql")
+	if err != nil {
+		t.Fatalf("ParseToCanonical failed: %v", err)
+	}
+	for _, op := range svc.Operations {
+		if op.ID == "greet" {
+			if op.GraphQL.ReturnTypeName != "String" {
+				t.Fatalf("return type = %q", op.GraphQL.ReturnTypeName)
+			}
+			return
+		}
+	}
+	t.Fatal("missing persisted query operation")
+}
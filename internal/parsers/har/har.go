@@ -0,0 +1,526 @@
+package har
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"skyline-mcp/internal/canonical"
+)
+
+// LooksLikeHAR reports whether raw is an HTTP Archive (a browser "Save all
+// as HAR" export).
+func LooksLikeHAR(raw []byte) bool {
+	var doc struct {
+		Log *struct {
+			Version string            `json:"version"`
+			Entries []json.RawMessage `json:"entries"`
+		} `json:"log"`
+	}
+	if err := json.Unmarshal(raw, &doc); err != nil || doc.Log == nil {
+		return false
+	}
+	return doc.Log.Version != "" || doc.Log.Entries != nil
+}
+
+// ParseToCanonical turns the API calls recorded in a HAR capture into
+// operations. Requests for pages and static assets are dropped; the rest are
+// grouped by method and path template, where path segments that look like
+// identifiers (numbers, UUIDs, hashes) become path parameters. Query
+// parameters seen in every sample of an operation are required. JSON request
+// and response bodies are turned into schemas from the recorded samples.
+// Only requests to the base URL (base_url_override, or the origin most
+// requests went to) are kept.
+func ParseToCanonical(ctx context.Context, raw []byte, apiName, baseURLOverride string) (*canonical.Service, error) {
+	_ = ctx
+
+	var doc Document
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("har: decode failed: %w", err)
+	}
+
+	var calls []Entry
+	for _, e := range doc.Log.Entries {
+		if isAPICall(e) {
+			calls = append(calls, e)
+		}
+	}
+	if len(calls) == 0 {
+		return nil, fmt.Errorf("har: no API requests found (pages and static assets are ignored)")
+	}
+
+	baseURL := strings.TrimRight(strings.TrimSpace(baseURLOverride), "/")
+	if baseURL == "" {
+		baseURL = dominantOrigin(calls)
+	}
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("har: invalid base URL %q: %w", baseURL, err)
+	}
+	basePath := strings.TrimRight(base.Path, "/")
+
+	groups := map[string]*group{}
+	var order []string
+	for _, e := range calls {
+		u, err := url.Parse(e.Request.URL)
+		if err != nil || u.Scheme != base.Scheme || u.Host != base.Host {
+			continue
+		}
+		rel := u.Path
+		if basePath != "" {
+			if rel != basePath && !strings.HasPrefix(rel, basePath+"/") {
+				continue
+			}
+			rel = strings.TrimPrefix(rel, basePath)
+		}
+		tmpl, params := templatePath(rel)
+		method := strings.ToUpper(e.Request.Method)
+		key := method + " " + tmpl
+		g, ok := groups[key]
+		if !ok {
+			g = &group{method: method, path: tmpl, pathParams: params, query: map[string]*queryStats{}}
+			groups[key] = g
+			order = append(order, key)
+		}
+		g.add(e, u.Query())
+	}
+	if len(groups) == 0 {
+		return nil, fmt.Errorf("har: no requests to %s", baseURL)
+	}
+
+	service := &canonical.Service{Name: apiName, BaseURL: baseURL}
+	usedIDs := map[string]int{}
+	for _, key := range order {
+		op := groups[key].operation(apiName)
+		if n := usedIDs[op.ID]; n > 0 {
+			op.ID = fmt.Sprintf("%s_%d", op.ID, n+1)
+			op.ToolName = canonical.ToolName(apiName, op.ID)
+		}
+		usedIDs[op.ID]++
+		service.Operations = append(service.Operations, op)
+	}
+	sort.Slice(service.Operations, func(i, j int) bool {
+		return service.Operations[i].ToolName < service.Operations[j].ToolName
+	})
+	return service, nil
+}
+
+var staticExtensions = map[string]bool{
+	".js": true, ".mjs": true, ".css": true, ".map": true, ".html": true, ".htm": true,
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".svg": true, ".ico": true, ".webp": true,
+	".woff": true, ".woff2": true, ".ttf": true, ".otf": true, ".eot": true,
+	".mp4": true, ".webm": true, ".mp3": true,
+}
+
+// isAPICall filters out navigations, assets and browser-internal requests.
+func isAPICall(e Entry) bool {
+	u, err := url.Parse(e.Request.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	method := strings.ToUpper(e.Request.Method)
+	if method == "OPTIONS" || method == "HEAD" || method == "CONNECT" {
+		return false
+	}
+	if staticExtensions[strings.ToLower(path.Ext(u.Path))] {
+		return false
+	}
+	switch rt := strings.ToLower(e.ResourceType); rt {
+	case "", "xhr", "fetch":
+	default:
+		return false
+	}
+	mime := strings.ToLower(e.Response.Content.MimeType)
+	for _, prefix := range []string{"text/html", "text/css", "image/", "font/", "video/", "audio/", "application/javascript", "text/javascript"} {
+		if strings.HasPrefix(mime, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+func dominantOrigin(calls []Entry) string {
+	counts := map[string]int{}
+	best := ""
+	for _, e := range calls {
+		u, err := url.Parse(e.Request.URL)
+		if err != nil {
+			continue
+		}
+		origin := u.Scheme + "://" + u.Host
+		counts[origin]++
+		if counts[origin] > counts[best] || (counts[origin] == counts[best] && origin < best) {
+			best = origin
+		}
+	}
+	return best
+}
+
+var (
+	uuidRe   = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	hexRe    = regexp.MustCompile(`^[0-9a-fA-F]{16,}$`)
+	numberRe = regexp.MustCompile(`^\d+$`)
+	// Opaque tokens such as "a1B2c3D4e5": long, mixing letters and digits.
+	tokenRe = regexp.MustCompile(`^[A-Za-z0-9_-]{20,}$`)
+	digitRe = regexp.MustCompile(`\d`)
+)
+
+// isIdentifier reports whether a path segment is a value rather than part of
+// the route.
+func isIdentifier(seg string) bool {
+	return numberRe.MatchString(seg) || uuidRe.MatchString(seg) || hexRe.MatchString(seg) ||
+		(tokenRe.MatchString(seg) && digitRe.MatchString(seg))
+}
+
+// templatePath replaces identifier segments with {name} placeholders, named
+// after the preceding segment ("/users/42" → "/users/{user_id}").
+func templatePath(p string) (string, []string) {
+	segs := strings.Split(strings.Trim(p, "/"), "/")
+	var params []string
+	used := map[string]int{}
+	for i, seg := range segs {
+		if seg == "" {
+			continue
+		}
+		if unescaped, err := url.PathUnescape(seg); err == nil {
+			seg = unescaped
+		}
+		if !isIdentifier(seg) {
+			continue
+		}
+		name := "id"
+		if i > 0 && !strings.HasPrefix(segs[i-1], "{") {
+			name = singular(sanitizeName(segs[i-1])) + "_id"
+		}
+		if used[name]++; used[name] > 1 {
+			name = fmt.Sprintf("%s%d", name, used[name])
+		}
+		segs[i] = "{" + name + "}"
+		params = append(params, name)
+	}
+	return "/" + strings.Join(segs, "/"), params
+}
+
+func singular(word string) string {
+	switch {
+	case strings.HasSuffix(word, "ies") && len(word) > 3:
+		return word[:len(word)-3] + "y"
+	case strings.HasSuffix(word, "sses"), strings.HasSuffix(word, "xes"):
+		return word[:len(word)-2]
+	case strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") && len(word) > 1:
+		return word[:len(word)-1]
+	}
+	return word
+}
+
+type queryStats struct {
+	seen   int
+	values []string
+}
+
+// group collects the samples of one method and path template.
+type group struct {
+	method     string
+	path       string
+	pathParams []string
+	samples    int
+	query      map[string]*queryStats
+	queryOrder []string
+	bodyType   string
+	bodySchema map[string]any
+	formFields []string
+	respSchema map[string]any
+}
+
+func (g *group) add(e Entry, query url.Values) {
+	g.samples++
+	recorded := e.Request.QueryString
+	// Browsers record queryString; fall back to the URL when it is missing.
+	if len(recorded) == 0 {
+		names := make([]string, 0, len(query))
+		for name := range query {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			recorded = append(recorded, NameVal{Name: name, Value: query.Get(name)})
+		}
+	}
+	counted := map[string]bool{}
+	for _, q := range recorded {
+		if _, ok := query[q.Name]; !ok {
+			continue
+		}
+		stats, ok := g.query[q.Name]
+		if !ok {
+			stats = &queryStats{}
+			g.query[q.Name] = stats
+			g.queryOrder = append(g.queryOrder, q.Name)
+		}
+		if !counted[q.Name] {
+			counted[q.Name] = true
+			stats.seen++
+		}
+		stats.values = append(stats.values, q.Value)
+	}
+
+	if pd := e.Request.PostData; pd != nil && g.bodyType == "" {
+		mime := strings.TrimSpace(strings.SplitN(pd.MimeType, ";", 2)[0])
+		switch {
+		case strings.Contains(mime, "json"):
+			var sample any
+			if json.Unmarshal([]byte(pd.Text), &sample) == nil {
+				g.bodyType = mime
+				g.bodySchema = inferSchema(sample, 0)
+			}
+		case mime == "application/x-www-form-urlencoded" || mime == "multipart/form-data":
+			g.bodyType = mime
+			for _, p := range pd.Params {
+				g.formFields = append(g.formFields, p.Name)
+			}
+			if len(g.formFields) == 0 && mime == "application/x-www-form-urlencoded" {
+				if values, err := url.ParseQuery(pd.Text); err == nil {
+					for name := range values {
+						g.formFields = append(g.formFields, name)
+					}
+					sort.Strings(g.formFields)
+				}
+			}
+		case mime != "":
+			g.bodyType = mime
+		}
+	}
+
+	if g.respSchema == nil && e.Response.Status >= 200 && e.Response.Status < 300 &&
+		strings.Contains(e.Response.Content.MimeType, "json") && e.Response.Content.Encoding == "" {
+		var sample any
+		if json.Unmarshal([]byte(e.Response.Content.Text), &sample) == nil {
+			g.respSchema = inferSchema(sample, 0)
+		}
+	}
+}
+
+func (g *group) operation(apiName string) *canonical.Operation {
+	id := operationID(g.method, g.path)
+	properties := map[string]any{}
+	var required []string
+	var params []canonical.Parameter
+
+	for _, name := range g.pathParams {
+		schema := map[string]any{"type": "string"}
+		params = append(params, canonical.Parameter{Name: name, In: "path", Required: true, Schema: schema})
+		properties[name] = schema
+		required = append(required, name)
+	}
+	for _, name := range g.queryOrder {
+		stats := g.query[name]
+		schema := map[string]any{"type": valueType(stats.values)}
+		if ex := firstNonEmpty(stats.values); ex != "" {
+			schema["description"] = "Recorded value: " + ex
+		}
+		req := stats.seen == g.samples
+		params = append(params, canonical.Parameter{Name: name, In: "query", Required: req, Schema: schema})
+		if _, clash := properties[name]; !clash {
+			properties[name] = schema
+			if req {
+				required = append(required, name)
+			}
+		}
+	}
+
+	var body *canonical.RequestBody
+	if g.bodyType != "" {
+		schema := g.bodySchema
+		switch {
+		case schema != nil:
+		case len(g.formFields) > 0:
+			props := map[string]any{}
+			for _, f := range g.formFields {
+				props[f] = map[string]any{"type": "string"}
+			}
+			schema = map[string]any{"type": "object", "properties": props}
+		case strings.Contains(g.bodyType, "json") || strings.Contains(g.bodyType, "form"):
+			schema = map[string]any{"type": "object", "additionalProperties": true}
+		default:
+			schema = map[string]any{"type": "string"}
+		}
+		body = &canonical.RequestBody{Required: true, ContentType: g.bodyType, Schema: schema}
+		properties["body"] = schema
+		required = append(required, "body")
+	}
+
+	inputSchema := map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		inputSchema["required"] = required
+	}
+	samples := "1 recorded request"
+	if g.samples > 1 {
+		samples = fmt.Sprintf("%d recorded requests", g.samples)
+	}
+	return &canonical.Operation{
+		ServiceName:    apiName,
+		ID:             id,
+		ToolName:       canonical.ToolName(apiName, id),
+		Method:         strings.ToLower(g.method),
+		Path:           g.path,
+		Summary:        fmt.Sprintf("%s %s (from %s)", g.method, g.path, samples),
+		Parameters:     params,
+		RequestBody:    body,
+		InputSchema:    inputSchema,
+		ResponseSchema: g.respSchema,
+	}
+}
+
+// operationID names an operation after its method and static path segments,
+// e.g. GET /users/{user_id}/orders → get_users_orders.
+func operationID(method, p string) string {
+	parts := []string{strings.ToLower(method)}
+	for _, seg := range strings.Split(p, "/") {
+		if seg == "" || strings.HasPrefix(seg, "{") {
+			continue
+		}
+		if s := sanitizeName(seg); s != "" {
+			parts = append(parts, s)
+		}
+	}
+	if len(parts) == 1 {
+		parts = append(parts, "root")
+	}
+	return strings.Join(parts, "_")
+}
+
+func sanitizeName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_':
+			b.WriteRune(r)
+		case r == '-' || r == '.' || r == ' ':
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
+
+// valueType infers a JSON type shared by all recorded values of a parameter.
+func valueType(values []string) string {
+	typ := ""
+	for _, v := range values {
+		t := "string"
+		if _, err := strconv.ParseInt(v, 10, 64); err == nil {
+			t = "integer"
+		} else if _, err := strconv.ParseFloat(v, 64); err == nil {
+			t = "number"
+		} else if v == "true" || v == "false" {
+			t = "boolean"
+		}
+		switch {
+		case typ == "":
+			typ = t
+		case typ == t:
+		case (typ == "integer" && t == "number") || (typ == "number" && t == "integer"):
+			typ = "number"
+		default:
+			return "string"
+		}
+	}
+	if typ == "" {
+		return "string"
+	}
+	return typ
+}
+
+func firstNonEmpty(values []string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// maxSchemaDepth bounds schemas inferred from deeply nested samples.
+const maxSchemaDepth = 6
+
+// inferSchema derives a JSON schema from a decoded sample value.
+func inferSchema(v any, depth int) map[string]any {
+	switch val := v.(type) {
+	case map[string]any:
+		if depth >= maxSchemaDepth {
+			return map[string]any{"type": "object"}
+		}
+		props := make(map[string]any, len(val))
+		for k, item := range val {
+			props[k] = inferSchema(item, depth+1)
+		}
+		return map[string]any{"type": "object", "properties": props}
+	case []any:
+		schema := map[string]any{"type": "array"}
+		if len(val) > 0 && depth < maxSchemaDepth {
+			schema["items"] = inferSchema(val[0], depth+1)
+		}
+		return schema
+	case string:
+		return map[string]any{"type": "string"}
+	case bool:
+		return map[string]any{"type": "boolean"}
+	case float64:
+		if val == float64(int64(val)) {
+			return map[string]any{"type": "integer"}
+		}
+		return map[string]any{"type": "number"}
+	}
+	return map[string]any{}
+}
+
+// HAR 1.2 structures (only the fields used here).
+
+type Document struct {
+	Log struct {
+		Version string  `json:"version"`
+		Entries []Entry `json:"entries"`
+	} `json:"log"`
+}
+
+type Entry struct {
+	ResourceType string   `json:"_resourceType"` // Chrome extension field: xhr, fetch, document, ...
+	Request      Request  `json:"request"`
+	Response     Response `json:"response"`
+}
+
+type Request struct {
+	Method      string    `json:"method"`
+	URL         string    `json:"url"`
+	QueryString []NameVal `json:"queryString"`
+	PostData    *PostData `json:"postData"`
+}
+
+type PostData struct {
+	MimeType string    `json:"mimeType"`
+	Text     string    `json:"text"`
+	Params   []NameVal `json:"params"`
+}
+
+type Response struct {
+	Status  int `json:"status"`
+	Content struct {
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text"`
+		Encoding string `json:"encoding"`
+	} `json:"content"`
+}
+
+type NameVal struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
@@ -0,0 +1,103 @@
+package har
+
+import (
+	"context"
+	"testing"
+)
+
+const capture = `{
+  "log": {
+    "version": "1.2",
+    "creator": {"name": "WebInspector", "version": "537.36"},
+    "entries": [
+      {"_resourceType": "document", "request": {"method": "GET", "url": "https://app.example.com/dashboard", "queryString": []},
+       "response": {"status": 200, "content": {"mimeType": "text/html", "text": "<html></html>"}}},
+      {"_resourceType": "script", "request": {"method": "GET", "url": "https://app.example.com/static/app.js", "queryString": []},
+       "response": {"status": 200, "content": {"mimeType": "application/javascript"}}},
+      {"_resourceType": "xhr", "request": {"method": "GET", "url": "https://app.example.com/api/users/42?include=teams&page=1",
+        "queryString": [{"name": "include", "value": "teams"}, {"name": "page", "value": "1"}]},
+       "response": {"status": 200, "content": {"mimeType": "application/json", "text": "{\"id\": 42, \"name\": \"Ada\", \"teams\": [{\"id\": 1}]}"}}},
+      {"_resourceType": "xhr", "request": {"method": "GET", "url": "https://app.example.com/api/users/7?include=teams",
+        "queryString": [{"name": "include", "value": "teams"}]},
+       "response": {"status": 200, "content": {"mimeType": "application/json", "text": "{\"id\": 7}"}}},
+      {"_resourceType": "fetch", "request": {"method": "POST", "url": "https://app.example.com/api/users/7/notes", "queryString": [],
+        "postData": {"mimeType": "application/json; charset=utf-8", "text": "{\"body\": \"hi\", \"pinned\": false}"}},
+       "response": {"status": 201, "content": {"mimeType": "application/json", "text": "{}"}}},
+      {"_resourceType": "fetch", "request": {"method": "GET", "url": "https://app.example.com/api/orders/3f2504e0-4f89-11d3-9a0c-0305e82c3301", "queryString": []},
+       "response": {"status": 200, "content": {"mimeType": "application/json", "text": "{}"}}},
+      {"_resourceType": "xhr", "request": {"method": "GET", "url": "https://telemetry.example.net/collect?e=1", "queryString": [{"name": "e", "value": "1"}]},
+       "response": {"status": 204, "content": {"mimeType": ""}}}
+    ]
+  }
+}`
+
+func TestLooksLikeHAR(t *testing.T) {
+	if !LooksLikeHAR([]byte(capture)) {
+		t.Fatal("expected capture to be detected")
+	}
+	for _, raw := range []string{`{"openapi":"3.0.0"}`, `{"log":"text"}`, "not json"} {
+		if LooksLikeHAR([]byte(raw)) {
+			t.Errorf("LooksLikeHAR(%s) = true", raw)
+		}
+	}
+}
+
+func TestParseToCanonical(t *testing.T) {
+	svc, err := ParseToCanonical(context.Background(), []byte(capture), "app", "")
+	if err != nil {
+		t.Fatalf("ParseToCanonical: %v", err)
+	}
+	if svc.BaseURL != "https://app.example.com" {
+		t.Fatalf("base URL = %q", svc.BaseURL)
+	}
+	if len(svc.Operations) != 3 {
+		for _, op := range svc.Operations {
+			t.Logf("%s %s", op.Method, op.Path)
+		}
+		t.Fatalf("expected 3 operations, got %d", len(svc.Operations))
+	}
+	ops := map[string]int{}
+	for i, op := range svc.Operations {
+		ops[op.ID] = i
+	}
+
+	get := svc.Operations[ops["get_api_users"]]
+	if get.Path != "/api/users/{user_id}" || get.Method != "get" {
+		t.Fatalf("unexpected user operation: %s %s", get.Method, get.Path)
+	}
+	required := map[string]bool{}
+	for _, p := range get.Parameters {
+		required[p.Name] = p.Required
+	}
+	if !required["user_id"] || !required["include"] || required["page"] {
+		t.Errorf("required flags = %v (include is in every sample, page is not)", required)
+	}
+	if typ := get.InputSchema["properties"].(map[string]any)["page"].(map[string]any)["type"]; typ != "integer" {
+		t.Errorf("page type = %v", typ)
+	}
+	if get.ResponseSchema == nil || get.ResponseSchema["properties"].(map[string]any)["teams"].(map[string]any)["type"] != "array" {
+		t.Errorf("response schema = %v", get.ResponseSchema)
+	}
+
+	post := svc.Operations[ops["post_api_users_notes"]]
+	if post.Path != "/api/users/{user_id}/notes" || post.RequestBody == nil || post.RequestBody.ContentType != "application/json" {
+		t.Fatalf("unexpected notes operation: %s %+v", post.Path, post.RequestBody)
+	}
+	if props := post.RequestBody.Schema["properties"].(map[string]any); props["pinned"].(map[string]any)["type"] != "boolean" {
+		t.Errorf("body schema = %v", post.RequestBody.Schema)
+	}
+
+	if order := svc.Operations[ops["get_api_orders"]]; order.Path != "/api/orders/{order_id}" {
+		t.Errorf("uuid segment not templated: %s", order.Path)
+	}
+}
+
+func TestParseToCanonicalBaseURLOverride(t *testing.T) {
+	svc, err := ParseToCanonical(context.Background(), []byte(capture), "app", "https://app.example.com/api/users")
+	if err != nil {
+		t.Fatalf("ParseToCanonical: %v", err)
+	}
+	if len(svc.Operations) != 2 || svc.Operations[0].Path != "/{id}" {
+		t.Fatalf("expected paths relative to the override, got %+v", svc.Operations[0])
+	}
+}
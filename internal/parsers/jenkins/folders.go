@@ -0,0 +1,55 @@
+package jenkins
+
+import (
+	"strings"
+
+	"skyline-mcp/internal/canonical"
+)
+
+// folderSeparators are the path prefixes Jenkins repeats once per folder
+// level: /job/a/job/b for the classic API and /pipelines/a/pipelines/b for
+// Blue Ocean.
+var folderSeparators = []string{"/job/", "/pipelines/"}
+
+// ApplyFolderPaths makes job path parameters folder-aware. A {jobName}
+// placeholder that follows /job/ (or /pipelines/ for Blue Ocean) accepts a
+// folder path such as "team/app", the full form "team/job/app", or a list of
+// segments, and is expanded to /job/team/job/app at request time.
+func ApplyFolderPaths(ops []*canonical.Operation) {
+	for _, op := range ops {
+		for i, param := range op.Parameters {
+			if param.In != "path" {
+				continue
+			}
+			sep := ""
+			for _, candidate := range folderSeparators {
+				if strings.Contains(op.Path, candidate+"{"+param.Name+"}") {
+					sep = candidate
+					break
+				}
+			}
+			if sep == "" {
+				continue
+			}
+			op.Parameters[i].SegmentSeparator = sep
+			op.Parameters[i].Schema = folderPathSchema(param.Schema)
+			if props, ok := op.InputSchema["properties"].(map[string]any); ok {
+				if prop, ok := props[param.Name].(map[string]any); ok {
+					props[param.Name] = folderPathSchema(prop)
+				}
+			}
+		}
+	}
+}
+
+func folderPathSchema(schema map[string]any) map[string]any {
+	desc, _ := schema["description"].(string)
+	if desc == "" {
+		desc = "Job name"
+	}
+	return map[string]any{
+		"type":        []any{"string", "array"},
+		"items":       map[string]any{"type": "string"},
+		"description": desc + ". For jobs in folders use the folder path (e.g. \"team/app\") or a list of segments ([\"team\", \"app\"]).",
+	}
+}
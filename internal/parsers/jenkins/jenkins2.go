@@ -45,6 +45,7 @@ func ParseJenkins2ToCanonical(ctx context.Context, raw []byte, apiName, baseURLO
 	service.Operations = append(service.Operations, getPluginOperations(apiName)...)
 	service.Operations = append(service.Operations, getBlueOceanOperations(apiName)...)
 	service.Operations = append(service.Operations, getUserOperations(apiName)...)
+	ApplyFolderPaths(service.Operations)
 
 	return service, nil
 }
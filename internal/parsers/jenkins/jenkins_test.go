@@ -70,3 +70,27 @@ func TestParseToCanonicalJenkins1x(t *testing.T) {
 		t.Fatalf("expected at least 2 operations, got %d", len(service.Operations))
 	}
 }
+
+func TestApplyFolderPaths(t *testing.T) {
+	raw := []byte(`{"_class":"jenkins.model.Jenkins","url":"https://ci.example.com/","mode":"NORMAL","numExecutors":2}`)
+	service, err := jenkins.ParseToCanonical(context.Background(), raw, "jenkins", "")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	for _, op := range service.Operations {
+		for _, p := range op.Parameters {
+			switch {
+			case op.ID == "getJob" && p.Name == "jobName":
+				if p.SegmentSeparator != "/job/" {
+					t.Errorf("getJob jobName separator = %q, want /job/", p.SegmentSeparator)
+				}
+				props := op.InputSchema["properties"].(map[string]any)
+				if _, ok := props["jobName"].(map[string]any)["items"]; !ok {
+					t.Error("getJob jobName schema should accept a list of segments")
+				}
+			case p.Name == "nodeName" && p.SegmentSeparator != "":
+				t.Errorf("%s nodeName should not be folder-aware", op.ID)
+			}
+		}
+	}
+}
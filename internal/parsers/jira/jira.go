@@ -0,0 +1,356 @@
+// Package jira implements a curated Skyline adapter for Jira. The generic
+// Jira OpenAPI spec yields over 600 tools; this adapter maps a small set of
+// well-described tools (JQL search, issues, transitions, comments, boards
+// and sprints) onto the Jira REST and Agile APIs, for Jira Cloud as well as
+// Jira Server and Data Center.
+package jira
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"skyline-mcp/internal/canonical"
+)
+
+// Deployments, which differ in their search, user and project endpoints.
+const (
+	DeploymentCloud  = "cloud"
+	DeploymentServer = "server" // Server and Data Center
+)
+
+// serverInfo is the response of /rest/api/2/serverInfo.
+type serverInfo struct {
+	BaseURL        string `json:"baseUrl"`
+	Version        string `json:"version"`
+	DeploymentType string `json:"deploymentType"`
+	ServerTitle    string `json:"serverTitle"`
+}
+
+// LooksLikeJira reports whether raw is a Jira serverInfo response, as
+// served at /rest/api/2/serverInfo or /rest/api/3/serverInfo.
+func LooksLikeJira(raw []byte) bool {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || raw[0] != '{' {
+		return false
+	}
+	var info serverInfo
+	if err := json.Unmarshal(raw, &info); err != nil {
+		return false
+	}
+	return info.BaseURL != "" && info.Version != "" && (info.DeploymentType != "" || info.ServerTitle != "")
+}
+
+// Deployment tells Jira Cloud from Server and Data Center, by the
+// serverInfo response when there is one and by the host otherwise.
+func Deployment(raw []byte, baseURL string) string {
+	var info serverInfo
+	if json.Unmarshal(bytes.TrimSpace(raw), &info) == nil && info.DeploymentType != "" {
+		if strings.EqualFold(info.DeploymentType, "cloud") {
+			return DeploymentCloud
+		}
+		return DeploymentServer
+	}
+	if u, err := url.Parse(baseURL); err == nil && strings.HasSuffix(strings.ToLower(u.Hostname()), ".atlassian.net") {
+		return DeploymentCloud
+	}
+	return DeploymentServer
+}
+
+// ParseToCanonical returns the curated Jira tools. raw is a serverInfo
+// response or empty, as with spec_type: jira; the base URL comes from
+// baseURLOverride or the serverInfo baseUrl.
+func ParseToCanonical(_ context.Context, raw []byte, apiName, baseURLOverride string) (*canonical.Service, error) {
+	baseURL := strings.TrimRight(strings.TrimSpace(baseURLOverride), "/")
+	if baseURL == "" {
+		var info serverInfo
+		if json.Unmarshal(bytes.TrimSpace(raw), &info) == nil {
+			baseURL = strings.TrimRight(info.BaseURL, "/")
+		}
+	}
+	if baseURL == "" {
+		return nil, fmt.Errorf("jira: base_url_override is required (e.g. https://your-domain.atlassian.net)")
+	}
+
+	svc := &canonical.Service{Name: apiName, BaseURL: baseURL}
+	for _, t := range tools(Deployment(raw, baseURL)) {
+		svc.Operations = append(svc.Operations, t.operation(apiName))
+	}
+	return svc, nil
+}
+
+// param is a tool argument. Arguments with in set are sent as path or
+// query parameters; the others fill the body template.
+type param struct {
+	name     string
+	in       string
+	schema   map[string]any
+	required bool
+}
+
+// tool is one curated operation.
+type tool struct {
+	id          string
+	method      string
+	path        string
+	summary     string
+	description string
+	params      []param
+	// body is the schema of a "body" argument sent as the JSON body.
+	body map[string]any
+	// template is a JSON body with {{name}} placeholders for the params
+	// without in.
+	template string
+}
+
+func (t tool) operation(api string) *canonical.Operation {
+	op := &canonical.Operation{
+		ServiceName:   api,
+		ID:            t.id,
+		ToolName:      canonical.ToolName(api, t.id),
+		Method:        t.method,
+		Path:          t.path,
+		Summary:       t.summary,
+		Description:   t.description,
+		StaticHeaders: map[string]string{"Accept": "application/json"},
+	}
+	props := map[string]any{}
+	required := []string{}
+	for _, p := range t.params {
+		props[p.name] = p.schema
+		if p.required {
+			required = append(required, p.name)
+		}
+		if p.in != "" {
+			op.Parameters = append(op.Parameters, canonical.Parameter{Name: p.name, In: p.in, Required: p.required, Schema: p.schema})
+		}
+	}
+	switch {
+	case t.body != nil:
+		props["body"] = t.body
+		required = append(required, "body")
+		op.RequestBody = &canonical.RequestBody{Required: true, ContentType: "application/json", Schema: t.body}
+	case t.template != "":
+		op.RequestBody = &canonical.RequestBody{Required: true, ContentType: "application/json", Template: t.template}
+	}
+	op.InputSchema = map[string]any{"type": "object", "properties": props, "additionalProperties": false}
+	if len(required) > 0 {
+		op.InputSchema["required"] = required
+	}
+	return op
+}
+
+func str(desc string) map[string]any { return map[string]any{"type": "string", "description": desc} }
+func integer(desc string) map[string]any {
+	return map[string]any{"type": "integer", "description": desc}
+}
+
+var issueKey = param{name: "issueKey", in: "path", required: true, schema: str("Issue key or ID, e.g. PROJ-123.")}
+
+var fieldsParam = param{name: "fields", in: "query", schema: str("Comma-separated fields to return, e.g. summary,status,assignee. Default: all navigable fields; fewer fields keep responses small.")}
+
+// issueFields describes the fields object of create and update requests.
+func issueFields(create bool) map[string]any {
+	props := map[string]any{
+		"summary":     str("One-line title."),
+		"description": str("Body text in Jira wiki markup."),
+		"labels":      map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		"priority":    map[string]any{"type": "object", "description": `{"name": "High"}`},
+		"assignee":    map[string]any{"type": "object", "description": `{"accountId": "..."} on Cloud, {"name": "username"} on Server`},
+		"components":  map[string]any{"type": "array", "description": `[{"name": "Backend"}]`},
+		"duedate":     str("Due date, YYYY-MM-DD."),
+	}
+	schema := map[string]any{
+		"type":                 "object",
+		"description":          "Issue fields. Custom fields use their IDs, e.g. customfield_10010; list an issue's fields with getIssue.",
+		"properties":           props,
+		"additionalProperties": true,
+	}
+	if create {
+		props["project"] = map[string]any{"type": "object", "description": `{"key": "PROJ"}`}
+		props["issuetype"] = map[string]any{"type": "object", "description": `{"name": "Task"}; listIssueTypes shows the project's types`}
+		props["parent"] = map[string]any{"type": "object", "description": `{"key": "PROJ-1"} for a subtask or an issue under an epic`}
+		schema["required"] = []string{"project", "issuetype", "summary"}
+	}
+	return schema
+}
+
+// tools returns the curated tools of a deployment.
+func tools(deployment string) []tool {
+	cloud := deployment == DeploymentCloud
+
+	search := tool{
+		id: "searchIssues", method: "get", path: "/rest/api/2/search",
+		summary: "Search issues with JQL",
+		description: "Finds issues matching a JQL query, e.g. `project = PROJ AND status = \"In Progress\" AND assignee = currentUser() ORDER BY updated DESC`. " +
+			"Ask only for the fields you need.",
+		params: []param{
+			{name: "jql", in: "query", required: true, schema: str("JQL query.")},
+			fieldsParam,
+			{name: "maxResults", in: "query", schema: integer("Page size, default 50.")},
+			{name: "startAt", in: "query", schema: integer("Index of the first result, for the next page.")},
+		},
+	}
+	if cloud {
+		// Jira Cloud removed /search in favor of token-paged /search/jql.
+		search.path = "/rest/api/3/search/jql"
+		search.params[3] = param{name: "nextPageToken", in: "query", schema: str("nextPageToken of the previous page.")}
+	}
+
+	assign := tool{
+		id: "assignIssue", method: "put", path: "/rest/api/2/issue/{issueKey}/assignee",
+		summary:     "Assign an issue",
+		description: "Assigns an issue to a user; find users with searchUsers. Pass null to unassign.",
+		params:      []param{issueKey, {name: "accountId", required: true, schema: map[string]any{"type": []string{"string", "null"}, "description": "Account ID of the assignee."}}},
+		template:    `{"accountId": {{accountId}}}`,
+	}
+	users := tool{
+		id: "searchUsers", method: "get", path: "/rest/api/2/user/search",
+		summary:     "Find users",
+		description: "Finds users by name or email, to get the accountId used to assign issues and in JQL.",
+		params:      []param{{name: "query", in: "query", required: true, schema: str("Name or email, or a prefix of them.")}, {name: "maxResults", in: "query", schema: integer("Default 50.")}},
+	}
+	projects := tool{
+		id: "listProjects", method: "get", path: "/rest/api/2/project/search",
+		summary:     "List projects",
+		description: "Lists the projects the user can browse, with their keys.",
+		params:      []param{{name: "query", in: "query", schema: str("Filter by project name or key.")}, {name: "maxResults", in: "query", schema: integer("Default 50.")}, {name: "startAt", in: "query", schema: integer("Index of the first result.")}},
+	}
+	if !cloud {
+		assign.description = "Assigns an issue to a user by username; find users with searchUsers. Pass null to unassign."
+		assign.params[1] = param{name: "name", required: true, schema: map[string]any{"type": []string{"string", "null"}, "description": "Username of the assignee."}}
+		assign.template = `{"name": {{name}}}`
+		users.description = "Finds users by username, name or email, to get the username used to assign issues and in JQL."
+		users.params[0] = param{name: "username", in: "query", required: true, schema: str("Username, name or email, or a prefix of them.")}
+		projects.path = "/rest/api/2/project"
+		projects.params = nil
+	}
+
+	return []tool{
+		search,
+		{
+			id: "getIssue", method: "get", path: "/rest/api/2/issue/{issueKey}",
+			summary:     "Get an issue",
+			description: "Returns an issue with its fields. Use expand=renderedFields,names to get HTML text and field names.",
+			params:      []param{issueKey, fieldsParam, {name: "expand", in: "query", schema: str("e.g. renderedFields,names,changelog.")}},
+		},
+		{
+			id: "createIssue", method: "post", path: "/rest/api/2/issue",
+			summary:     "Create an issue",
+			description: `Creates an issue. Example body: {"fields": {"project": {"key": "PROJ"}, "issuetype": {"name": "Bug"}, "summary": "Login fails", "description": "Steps: ..."}}. Returns the new issue's key.`,
+			body: map[string]any{
+				"type":                 "object",
+				"properties":           map[string]any{"fields": issueFields(true)},
+				"required":             []string{"fields"},
+				"additionalProperties": false,
+			},
+		},
+		{
+			id: "updateIssue", method: "put", path: "/rest/api/2/issue/{issueKey}",
+			summary:     "Update an issue",
+			description: `Sets issue fields; fields left out keep their value. Example body: {"fields": {"summary": "New title", "labels": ["urgent"]}}. Status changes need transitionIssue.`,
+			params:      []param{issueKey},
+			body: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"fields": issueFields(false),
+					"update": map[string]any{"type": "object", "description": `Operations on multi-value fields, e.g. {"labels": [{"add": "urgent"}]}.`},
+				},
+				"additionalProperties": false,
+			},
+		},
+		{
+			id: "getTransitions", method: "get", path: "/rest/api/2/issue/{issueKey}/transitions",
+			summary:     "List an issue's transitions",
+			description: "Lists the workflow transitions available for an issue now, with their IDs and target statuses.",
+			params:      []param{issueKey},
+		},
+		{
+			id: "transitionIssue", method: "post", path: "/rest/api/2/issue/{issueKey}/transitions",
+			summary:     "Move an issue to another status",
+			description: "Performs a workflow transition, e.g. to In Progress or Done. Get the transition ID from getTransitions first.",
+			params:      []param{issueKey, {name: "transitionId", required: true, schema: str("ID of a transition listed by getTransitions.")}},
+			template:    `{"transition": {"id": "{{transitionId}}"}}`,
+		},
+		assign,
+		{
+			id: "listComments", method: "get", path: "/rest/api/2/issue/{issueKey}/comment",
+			summary:     "List an issue's comments",
+			description: "Returns the comments of an issue, oldest first unless orderBy=-created.",
+			params: []param{
+				issueKey,
+				{name: "orderBy", in: "query", schema: map[string]any{"type": "string", "enum": []string{"created", "-created"}}},
+				{name: "maxResults", in: "query", schema: integer("Default 50.")},
+				{name: "startAt", in: "query", schema: integer("Index of the first comment.")},
+			},
+		},
+		{
+			id: "addComment", method: "post", path: "/rest/api/2/issue/{issueKey}/comment",
+			summary:     "Comment on an issue",
+			description: "Adds a comment to an issue.",
+			params:      []param{issueKey, {name: "text", required: true, schema: str("Comment text in Jira wiki markup.")}},
+			template:    `{"body": {{text}}}`,
+		},
+		{
+			id: "listIssueTypes", method: "get", path: "/rest/api/2/issue/createmeta/{projectKey}/issuetypes",
+			summary:     "List a project's issue types",
+			description: "Lists the issue types that can be created in a project, for createIssue.",
+			params:      []param{{name: "projectKey", in: "path", required: true, schema: str("Project key or ID.")}},
+		},
+		projects,
+		users,
+		{
+			id: "getMyself", method: "get", path: "/rest/api/2/myself",
+			summary:     "Get the current user",
+			description: "Returns the user Skyline authenticates as.",
+		},
+		{
+			id: "listBoards", method: "get", path: "/rest/agile/1.0/board",
+			summary:     "List boards",
+			description: "Lists Scrum and Kanban boards, for their IDs.",
+			params: []param{
+				{name: "projectKeyOrId", in: "query", schema: str("Only boards of this project.")},
+				{name: "type", in: "query", schema: map[string]any{"type": "string", "enum": []string{"scrum", "kanban", "simple"}}},
+				{name: "name", in: "query", schema: str("Boards whose name contains this text.")},
+				{name: "maxResults", in: "query", schema: integer("Default 50.")},
+				{name: "startAt", in: "query", schema: integer("Index of the first board.")},
+			},
+		},
+		{
+			id: "listSprints", method: "get", path: "/rest/agile/1.0/board/{boardId}/sprint",
+			summary:     "List a board's sprints",
+			description: "Lists the sprints of a Scrum board.",
+			params: []param{
+				{name: "boardId", in: "path", required: true, schema: integer("Board ID from listBoards.")},
+				{name: "state", in: "query", schema: str("Comma-separated states: future, active, closed.")},
+				{name: "maxResults", in: "query", schema: integer("Default 50.")},
+				{name: "startAt", in: "query", schema: integer("Index of the first sprint.")},
+			},
+		},
+		{
+			id: "getSprintIssues", method: "get", path: "/rest/agile/1.0/sprint/{sprintId}/issue",
+			summary:     "List a sprint's issues",
+			description: "Returns the issues of a sprint, optionally narrowed with JQL.",
+			params: []param{
+				{name: "sprintId", in: "path", required: true, schema: integer("Sprint ID from listSprints.")},
+				{name: "jql", in: "query", schema: str("Further JQL filter, e.g. status != Done.")},
+				fieldsParam,
+				{name: "maxResults", in: "query", schema: integer("Default 50.")},
+				{name: "startAt", in: "query", schema: integer("Index of the first issue.")},
+			},
+		},
+		{
+			id: "moveIssuesToSprint", method: "post", path: "/rest/agile/1.0/sprint/{sprintId}/issue",
+			summary:     "Move issues into a sprint",
+			description: "Moves up to 50 issues into an open or future sprint.",
+			params: []param{
+				{name: "sprintId", in: "path", required: true, schema: integer("Sprint ID from listSprints.")},
+				{name: "issues", required: true, schema: map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "maxItems": 50, "description": "Issue keys, e.g. [\"PROJ-1\", \"PROJ-2\"]."}},
+			},
+			template: `{"issues": {{issues}}}`,
+		},
+	}
+}
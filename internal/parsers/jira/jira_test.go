@@ -0,0 +1,131 @@
+package jira
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/logging"
+	"skyline-mcp/internal/redact"
+	"skyline-mcp/internal/runtime"
+)
+
+func TestLooksLikeJira(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want bool
+	}{
+		{"cloud serverInfo", `{"baseUrl":"https://acme.atlassian.net","version":"1001.0.0-SNAPSHOT","deploymentType":"Cloud","serverTitle":"Jira"}`, true},
+		{"data center serverInfo", `{"baseUrl":"https://jira.acme.com","version":"9.12.2","deploymentType":"Server","serverTitle":"ACME Jira"}`, true},
+		{"empty", "", false},
+		{"openapi doc", `{"openapi":"3.0.0"}`, false},
+		{"other baseUrl json", `{"baseUrl":"https://example.com"}`, false},
+		{"not json", "hello world", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LooksLikeJira([]byte(tt.raw)); got != tt.want {
+				t.Errorf("LooksLikeJira() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseToCanonicalDeployments(t *testing.T) {
+	tests := []struct {
+		name, raw, base             string
+		wantBase, wantSearch        string
+		wantAssignArg, wantProjects string
+	}{
+		{"cloud by host", "", "https://acme.atlassian.net/", "https://acme.atlassian.net", "/rest/api/3/search/jql", "accountId", "/rest/api/2/project/search"},
+		{"server by host", "", "https://jira.acme.com", "https://jira.acme.com", "/rest/api/2/search", "name", "/rest/api/2/project"},
+		{"serverInfo", `{"baseUrl":"https://jira.acme.com/jira","version":"9.12.2","deploymentType":"Server"}`, "", "https://jira.acme.com/jira", "/rest/api/2/search", "name", "/rest/api/2/project"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, err := ParseToCanonical(context.Background(), []byte(tt.raw), "jira", tt.base)
+			if err != nil {
+				t.Fatalf("ParseToCanonical: %v", err)
+			}
+			if svc.BaseURL != tt.wantBase {
+				t.Errorf("BaseURL = %q, want %q", svc.BaseURL, tt.wantBase)
+			}
+			ops := map[string]*canonical.Operation{}
+			for _, op := range svc.Operations {
+				if op.ToolName != "jira__"+op.ID || op.Summary == "" || op.Description == "" {
+					t.Errorf("operation %s = %+v", op.ID, op)
+				}
+				ops[op.ID] = op
+			}
+			if len(ops) != 17 {
+				t.Errorf("got %d operations, want 17", len(ops))
+			}
+			if ops["searchIssues"].Path != tt.wantSearch {
+				t.Errorf("searchIssues path = %q, want %q", ops["searchIssues"].Path, tt.wantSearch)
+			}
+			if ops["listProjects"].Path != tt.wantProjects {
+				t.Errorf("listProjects path = %q, want %q", ops["listProjects"].Path, tt.wantProjects)
+			}
+			props := ops["assignIssue"].InputSchema["properties"].(map[string]any)
+			if _, ok := props[tt.wantAssignArg]; !ok {
+				t.Errorf("assignIssue arguments = %v, want %s", props, tt.wantAssignArg)
+			}
+		})
+	}
+
+	if _, err := ParseToCanonical(context.Background(), nil, "jira", ""); err == nil {
+		t.Error("expected error without a base URL")
+	}
+}
+
+func TestToolsRequestBodies(t *testing.T) {
+	var gotMethod, gotPath, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotMethod, gotPath, gotBody = r.Method, r.URL.Path, string(body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	svc, err := ParseToCanonical(context.Background(), nil, "jira", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{APIs: []config.APIConfig{{Name: "jira", SpecType: "jira", BaseURLOverride: server.URL}}}
+	cfg.ApplyDefaults()
+	exec, err := runtime.NewExecutor(cfg, []*canonical.Service{svc}, logging.Discard(), redact.NewRedactor())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops := map[string]*canonical.Operation{}
+	for _, op := range svc.Operations {
+		ops[op.ID] = op
+	}
+
+	tests := []struct {
+		op         string
+		args       map[string]any
+		method     string
+		path, body string
+	}{
+		{"transitionIssue", map[string]any{"issueKey": "PROJ-1", "transitionId": "31"}, "POST", "/rest/api/2/issue/PROJ-1/transitions", `{"transition": {"id": "31"}}`},
+		{"addComment", map[string]any{"issueKey": "PROJ-1", "text": "Fixed in \"main\""}, "POST", "/rest/api/2/issue/PROJ-1/comment", `{"body": "Fixed in \"main\""}`},
+		{"moveIssuesToSprint", map[string]any{"sprintId": 7.0, "issues": []any{"PROJ-1", "PROJ-2"}}, "POST", "/rest/agile/1.0/sprint/7/issue", `{"issues": ["PROJ-1","PROJ-2"]}`},
+		{"updateIssue", map[string]any{"issueKey": "PROJ-1", "body": map[string]any{"fields": map[string]any{"summary": "New"}}}, "PUT", "/rest/api/2/issue/PROJ-1", `{"fields":{"summary":"New"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.op, func(t *testing.T) {
+			if _, err := exec.Execute(context.Background(), ops[tt.op], tt.args); err != nil {
+				t.Fatalf("Execute: %v", err)
+			}
+			if gotMethod != tt.method || gotPath != tt.path || gotBody != tt.body {
+				t.Errorf("request = %s %s %s, want %s %s %s", gotMethod, gotPath, gotBody, tt.method, tt.path, tt.body)
+			}
+		})
+	}
+}
@@ -0,0 +1,323 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"skyline-mcp/internal/canonical"
+)
+
+// APIResource is one entry of a discovery APIResourceList
+// (GET /api/v1 or /apis/{group}/{version}).
+type APIResource struct {
+	Name         string   `json:"name"`
+	SingularName string   `json:"singularName"`
+	Namespaced   bool     `json:"namespaced"`
+	Kind         string   `json:"kind"`
+	Verbs        []string `json:"verbs"`
+}
+
+// APIGroup is one entry of the discovery APIGroupList (GET /apis).
+type APIGroup struct {
+	Name             string `json:"name"`
+	PreferredVersion struct {
+		GroupVersion string `json:"groupVersion"`
+		Version      string `json:"version"`
+	} `json:"preferredVersion"`
+}
+
+// GroupVersion is an API group at the version tools are generated for,
+// together with its resources. Group is empty for the core ("v1") group.
+type GroupVersion struct {
+	Group     string
+	Version   string
+	Resources []APIResource
+}
+
+// GVK identifies a Kubernetes object schema.
+type GVK struct {
+	Group   string
+	Version string
+	Kind    string
+}
+
+// Options narrows the generated tools.
+type Options struct {
+	Resources []string // plural names or kinds to include; empty means all
+	ReadOnly  bool     // only list, get and pod logs
+}
+
+// ParseGroupList decodes the discovery APIGroupList served at /apis.
+func ParseGroupList(raw []byte) ([]APIGroup, error) {
+	var list struct {
+		Groups []APIGroup `json:"groups"`
+	}
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, fmt.Errorf("kubernetes: decode group list: %w", err)
+	}
+	return list.Groups, nil
+}
+
+// ParseResourceList decodes a discovery APIResourceList.
+func ParseResourceList(raw []byte) ([]APIResource, error) {
+	var list struct {
+		Resources []APIResource `json:"resources"`
+	}
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, fmt.Errorf("kubernetes: decode resource list: %w", err)
+	}
+	return list.Resources, nil
+}
+
+// BuildService generates one composite tool per resource. Each tool takes an
+// action (list, get, create, replace, patch, delete, plus logs for pods)
+// chosen from the verbs the resource supports. Namespaced resources take
+// namespace as a parameter; list without a namespace covers all namespaces.
+// schemas supplies request body schemas by kind and may be nil.
+func BuildService(apiName, baseURL string, gvs []GroupVersion, schemas map[GVK]map[string]any, opts Options) (*canonical.Service, error) {
+	baseURL = strings.TrimRight(strings.TrimSpace(baseURL), "/")
+	if baseURL == "" {
+		return nil, fmt.Errorf("kubernetes: API server URL is required (spec_url or base_url_override)")
+	}
+
+	type candidate struct {
+		gv       GroupVersion
+		resource APIResource
+		logs     bool
+	}
+	var candidates []candidate
+	nameCount := map[string]int{}
+	for _, gv := range gvs {
+		subresources := map[string]bool{}
+		for _, r := range gv.Resources {
+			if parent, sub, ok := strings.Cut(r.Name, "/"); ok {
+				subresources[parent+"/"+sub] = true
+			}
+		}
+		for _, r := range gv.Resources {
+			if strings.Contains(r.Name, "/") || !wantResource(opts.Resources, r) {
+				continue
+			}
+			candidates = append(candidates, candidate{
+				gv:       gv,
+				resource: r,
+				logs:     gv.Group == "" && r.Name == "pods" && subresources["pods/log"],
+			})
+			nameCount[r.Name]++
+		}
+	}
+
+	service := &canonical.Service{Name: apiName, BaseURL: baseURL}
+	for _, c := range candidates {
+		id := c.resource.Name
+		if nameCount[id] > 1 && c.gv.Group != "" {
+			// e.g. events exists in both the core and events.k8s.io groups.
+			id += "_" + sanitize(c.gv.Group)
+		}
+		gvk := GVK{Group: c.gv.Group, Version: c.gv.Version, Kind: c.resource.Kind}
+		if op := buildResourceTool(apiName, id, c.gv, c.resource, schemas[gvk], c.logs, opts.ReadOnly); op != nil {
+			service.Operations = append(service.Operations, op)
+		}
+	}
+	if len(service.Operations) == 0 {
+		return nil, fmt.Errorf("kubernetes: no resources matched the selected groups")
+	}
+	sort.Slice(service.Operations, func(i, j int) bool {
+		return service.Operations[i].ToolName < service.Operations[j].ToolName
+	})
+	return service, nil
+}
+
+func wantResource(filter []string, r APIResource) bool {
+	if len(filter) == 0 {
+		return true
+	}
+	for _, f := range filter {
+		if strings.EqualFold(f, r.Name) || strings.EqualFold(f, r.Kind) || strings.EqualFold(f, r.SingularName) {
+			return true
+		}
+	}
+	return false
+}
+
+func buildResourceTool(apiName, id string, gv GroupVersion, r APIResource, bodySchema map[string]any, logs, readOnly bool) *canonical.Operation {
+	apiVersion := gv.Version
+	prefix := "/api/" + gv.Version
+	if gv.Group != "" {
+		apiVersion = gv.Group + "/" + gv.Version
+		prefix = "/apis/" + gv.Group + "/" + gv.Version
+	}
+	collection := prefix + "/" + r.Name
+	allNamespaces := ""
+	if r.Namespaced {
+		allNamespaces = collection
+		collection = prefix + "/namespaces/{namespace}/" + r.Name
+	}
+	item := collection + "/{name}"
+
+	namespaceParam := canonical.Parameter{Name: "namespace", In: "path", Required: true, Schema: map[string]any{"type": "string", "description": "Namespace"}}
+	nameParam := canonical.Parameter{Name: "name", In: "path", Required: true, Schema: map[string]any{"type": "string", "description": r.Kind + " name"}}
+	scoped := func(params ...canonical.Parameter) []canonical.Parameter {
+		if r.Namespaced {
+			return append([]canonical.Parameter{namespaceParam}, params...)
+		}
+		return params
+	}
+
+	if bodySchema == nil {
+		bodySchema = map[string]any{"type": "object"}
+	}
+	bodySchema = copyMap(bodySchema)
+	bodySchema["description"] = fmt.Sprintf("%s manifest (apiVersion: %s, kind: %s)", r.Kind, apiVersion, r.Kind)
+
+	verbs := map[string]bool{}
+	for _, v := range r.Verbs {
+		verbs[v] = true
+	}
+
+	actions := map[string]*canonical.Operation{}
+	add := func(action, method, path, summary string, params []canonical.Parameter, body *canonical.RequestBody) {
+		actions[action] = &canonical.Operation{
+			ServiceName: apiName,
+			ID:          id + "_" + action,
+			ToolName:    canonical.ToolName(apiName, id+"_"+action),
+			Method:      method,
+			Path:        path,
+			Summary:     summary,
+			Parameters:  params,
+			RequestBody: body,
+			ActionHint:  action,
+		}
+	}
+
+	if verbs["list"] {
+		params := []canonical.Parameter{
+			{Name: "labelSelector", In: "query", Schema: map[string]any{"type": "string", "description": "Label selector, e.g. app=web,tier!=cache"}},
+			{Name: "fieldSelector", In: "query", Schema: map[string]any{"type": "string", "description": "Field selector, e.g. status.phase=Running"}},
+			{Name: "limit", In: "query", Schema: map[string]any{"type": "integer", "description": "Maximum number of items to return"}},
+			{Name: "continue", In: "query", Schema: map[string]any{"type": "string", "description": "Continue token from a previous list's metadata.continue"}},
+		}
+		summary := "List " + r.Name
+		if r.Namespaced {
+			ns := namespaceParam
+			ns.Required = false
+			ns.Schema = map[string]any{"type": "string", "description": "Namespace; omit to list across all namespaces"}
+			params = append([]canonical.Parameter{ns}, params...)
+			summary += " in a namespace, or across all namespaces when namespace is omitted"
+		}
+		add("list", "get", collection, summary, params, nil)
+		if r.Namespaced {
+			actions["list"].FallbackPath = allNamespaces
+		}
+	}
+	if verbs["get"] {
+		add("get", "get", item, "Get a "+r.Kind+" by name", scoped(nameParam), nil)
+	}
+	if logs {
+		add("logs", "get", item+"/log", "Read a pod's container log", scoped(nameParam,
+			canonical.Parameter{Name: "container", In: "query", Schema: map[string]any{"type": "string", "description": "Container name (required for multi-container pods)"}},
+			canonical.Parameter{Name: "tailLines", In: "query", Schema: map[string]any{"type": "integer", "description": "Number of lines from the end of the log"}},
+			canonical.Parameter{Name: "sinceSeconds", In: "query", Schema: map[string]any{"type": "integer", "description": "Only return logs newer than this many seconds"}},
+			canonical.Parameter{Name: "previous", In: "query", Schema: map[string]any{"type": "boolean", "description": "Return the log of the previous (terminated) container instance"}},
+		), nil)
+	}
+	if !readOnly {
+		if verbs["create"] {
+			add("create", "post", collection, "Create a "+r.Kind+" from a manifest", scoped(),
+				&canonical.RequestBody{Required: true, ContentType: "application/json", Schema: bodySchema})
+		}
+		if verbs["update"] {
+			add("replace", "put", item, "Replace a "+r.Kind+" with a full manifest", scoped(nameParam),
+				&canonical.RequestBody{Required: true, ContentType: "application/json", Schema: bodySchema})
+		}
+		if verbs["patch"] {
+			add("patch", "patch", item, "Patch a "+r.Kind+" with a JSON merge patch", scoped(nameParam),
+				&canonical.RequestBody{Required: true, ContentType: "application/merge-patch+json", Schema: map[string]any{
+					"type":        "object",
+					"description": "JSON merge patch: only the fields to change, e.g. {\"spec\":{\"replicas\":3}}",
+				}})
+		}
+		if verbs["delete"] {
+			add("delete", "delete", item, "Delete a "+r.Kind, scoped(nameParam,
+				canonical.Parameter{Name: "propagationPolicy", In: "query", Schema: map[string]any{"type": "string", "enum": []string{"Foreground", "Background", "Orphan"}, "description": "How dependents are deleted"}},
+			), nil)
+		}
+	}
+	if len(actions) == 0 {
+		return nil
+	}
+	return compositeTool(apiName, id, apiVersion, r, actions, bodySchema)
+}
+
+// compositeTool merges a resource's actions into one tool with an action
+// parameter, in the same shape as REST CRUD grouping produces.
+func compositeTool(apiName, id, apiVersion string, r APIResource, actions map[string]*canonical.Operation, bodySchema map[string]any) *canonical.Operation {
+	names := make([]string, 0, len(actions))
+	for name := range actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	actionSchema := map[string]any{"type": "string", "enum": names, "description": "Operation to perform on " + r.Name}
+	properties := map[string]any{"action": actionSchema}
+	params := []canonical.Parameter{{Name: "action", In: "action", Required: true, Schema: actionSchema}}
+	seen := map[string]bool{"action": true}
+	var lines []string
+	for _, name := range names {
+		op := actions[name]
+		lines = append(lines, fmt.Sprintf("- %s: %s", name, op.Summary))
+		for _, p := range op.Parameters {
+			if seen[p.Name] {
+				continue
+			}
+			seen[p.Name] = true
+			schema := p.Schema
+			if p.Name == "namespace" {
+				schema = map[string]any{"type": "string", "description": "Namespace (required except for list, where omitting it lists all namespaces)"}
+			}
+			params = append(params, canonical.Parameter{Name: p.Name, In: p.In, Schema: schema})
+			properties[p.Name] = schema
+		}
+		if op.RequestBody != nil && !seen["body"] {
+			seen["body"] = true
+			body := map[string]any{
+				"type":        "object",
+				"description": fmt.Sprintf("create/replace: full %s manifest (apiVersion: %s, kind: %s). patch: JSON merge patch with only the fields to change.", r.Kind, apiVersion, r.Kind),
+			}
+			if props, ok := bodySchema["properties"]; ok {
+				body["properties"] = props
+			}
+			properties["body"] = body
+		}
+	}
+
+	return &canonical.Operation{
+		ServiceName: apiName,
+		ID:          id,
+		ToolName:    canonical.ToolName(apiName, id),
+		Summary:     fmt.Sprintf("Manage %s (%s %s)", r.Name, apiVersion, r.Kind),
+		Description: fmt.Sprintf("Manage Kubernetes %s (%s). Available actions:\n%s", r.Name, apiVersion, strings.Join(lines, "\n")),
+		Parameters:  params,
+		InputSchema: map[string]any{
+			"type":                 "object",
+			"properties":           properties,
+			"required":             []string{"action"},
+			"additionalProperties": false,
+		},
+		RESTComposite: &canonical.RESTComposite{ResourceName: r.Name, Actions: actions},
+	}
+}
+
+func sanitize(s string) string {
+	return strings.NewReplacer(".", "_", "-", "_", "/", "_").Replace(s)
+}
+
+func copyMap(m map[string]any) map[string]any {
+	out := make(map[string]any, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,144 @@
+package kubernetes
+
+import (
+	"testing"
+)
+
+var testGroupVersions = []GroupVersion{
+	{Version: "v1", Resources: []APIResource{
+		{Name: "pods", SingularName: "pod", Namespaced: true, Kind: "Pod", Verbs: []string{"create", "delete", "get", "list", "patch", "update", "watch"}},
+		{Name: "pods/log", Namespaced: true, Kind: "Pod", Verbs: []string{"get"}},
+		{Name: "nodes", SingularName: "node", Kind: "Node", Verbs: []string{"get", "list", "patch"}},
+		{Name: "events", SingularName: "event", Namespaced: true, Kind: "Event", Verbs: []string{"get", "list"}},
+	}},
+	{Group: "events.k8s.io", Version: "v1", Resources: []APIResource{
+		{Name: "events", SingularName: "event", Namespaced: true, Kind: "Event", Verbs: []string{"get", "list"}},
+	}},
+	{Group: "stable.example.com", Version: "v1", Resources: []APIResource{
+		{Name: "crontabs", SingularName: "crontab", Namespaced: true, Kind: "CronTab", Verbs: []string{"get", "list", "create"}},
+	}},
+}
+
+func TestBuildService(t *testing.T) {
+	schemas := map[GVK]map[string]any{
+		{Version: "v1", Kind: "Pod"}: {"type": "object", "properties": map[string]any{"spec": map[string]any{"type": "object"}}},
+	}
+	svc, err := BuildService("k8s", "https://cluster.example.com/", testGroupVersions, schemas, Options{})
+	if err != nil {
+		t.Fatalf("BuildService: %v", err)
+	}
+	tools := map[string]int{}
+	for i, op := range svc.Operations {
+		tools[op.ToolName] = i
+	}
+	for _, name := range []string{"k8s__pods", "k8s__nodes", "k8s__events", "k8s__events_events_k8s_io", "k8s__crontabs"} {
+		if _, ok := tools[name]; !ok {
+			t.Errorf("missing tool %s (have %v)", name, tools)
+		}
+	}
+	if len(svc.Operations) != 5 {
+		t.Errorf("got %d tools, want one per resource", len(svc.Operations))
+	}
+
+	pods := svc.Operations[tools["k8s__pods"]].RESTComposite.Actions
+	for _, action := range []string{"list", "get", "logs", "create", "replace", "patch", "delete"} {
+		if _, ok := pods[action]; !ok {
+			t.Errorf("pods missing action %s", action)
+		}
+	}
+	if got := pods["list"].Path; got != "/api/v1/namespaces/{namespace}/pods" {
+		t.Errorf("list path = %s", got)
+	}
+	if got := pods["list"].FallbackPath; got != "/api/v1/pods" {
+		t.Errorf("list fallback path = %s", got)
+	}
+	if got := pods["logs"].Path; got != "/api/v1/namespaces/{namespace}/pods/{name}/log" {
+		t.Errorf("logs path = %s", got)
+	}
+	if got := pods["patch"].RequestBody.ContentType; got != "application/merge-patch+json" {
+		t.Errorf("patch content type = %s", got)
+	}
+	if _, ok := pods["create"].RequestBody.Schema["properties"].(map[string]any)["spec"]; !ok {
+		t.Error("create body should use the Pod schema")
+	}
+
+	nodes := svc.Operations[tools["k8s__nodes"]].RESTComposite.Actions
+	if got := nodes["get"].Path; got != "/api/v1/nodes/{name}" {
+		t.Errorf("cluster-scoped get path = %s", got)
+	}
+	if _, ok := nodes["delete"]; ok {
+		t.Error("nodes should not offer delete without the verb")
+	}
+
+	crontabs := svc.Operations[tools["k8s__crontabs"]].RESTComposite.Actions
+	if got := crontabs["create"].Path; got != "/apis/stable.example.com/v1/namespaces/{namespace}/crontabs" {
+		t.Errorf("CRD create path = %s", got)
+	}
+}
+
+func TestBuildServiceOptions(t *testing.T) {
+	svc, err := BuildService("k8s", "https://cluster", testGroupVersions, nil, Options{Resources: []string{"Pod"}, ReadOnly: true})
+	if err != nil {
+		t.Fatalf("BuildService: %v", err)
+	}
+	if len(svc.Operations) != 1 {
+		t.Fatalf("got %d tools, want pods only", len(svc.Operations))
+	}
+	actions := svc.Operations[0].RESTComposite.Actions
+	if len(actions) != 3 {
+		t.Errorf("read-only pods actions = %v, want list, get, logs", actions)
+	}
+	if _, err := BuildService("k8s", "https://cluster", testGroupVersions, nil, Options{Resources: []string{"widgets"}}); err == nil {
+		t.Error("expected an error when no resources match")
+	}
+}
+
+func TestIndexSchemas(t *testing.T) {
+	v2 := []byte(`{"definitions": {
+		"io.k8s.api.apps.v1.Deployment": {
+			"description": "Deployment enables declarative updates. More text here.",
+			"properties": {
+				"apiVersion": {"type": "string", "description": "APIVersion defines the versioned schema. Long explanation."},
+				"metadata": {"$ref": "#/definitions/io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta"},
+				"spec": {"$ref": "#/definitions/io.k8s.api.apps.v1.DeploymentSpec"}
+			},
+			"x-kubernetes-group-version-kind": [{"group": "apps", "kind": "Deployment", "version": "v1"}]
+		},
+		"io.k8s.api.apps.v1.DeploymentList": {
+			"x-kubernetes-group-version-kind": [{"group": "apps", "kind": "DeploymentList", "version": "v1"}]
+		},
+		"io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta": {"type": "object", "description": "ObjectMeta is metadata."},
+		"io.k8s.api.apps.v1.DeploymentSpec": {"type": "object"}
+	}}`)
+	schemas, err := IndexSchemas(v2)
+	if err != nil {
+		t.Fatalf("IndexSchemas: %v", err)
+	}
+	if len(schemas) != 1 {
+		t.Fatalf("got %d schemas, want Deployment only", len(schemas))
+	}
+	props := schemas[GVK{Group: "apps", Version: "v1", Kind: "Deployment"}]["properties"].(map[string]any)
+	meta := props["metadata"].(map[string]any)
+	if meta["type"] != "object" || meta["description"] != "ObjectMeta is metadata." {
+		t.Errorf("metadata = %v, want a shallow object placeholder", meta)
+	}
+	if desc := props["apiVersion"].(map[string]any)["description"]; desc != "APIVersion defines the versioned schema." {
+		t.Errorf("apiVersion description = %q, want first sentence", desc)
+	}
+
+	v3 := []byte(`{"components": {"schemas": {
+		"io.k8s.api.batch.v1.Job": {
+			"properties": {"spec": {"allOf": [{"$ref": "#/components/schemas/io.k8s.api.batch.v1.JobSpec"}], "description": "Spec of the job."}},
+			"x-kubernetes-group-version-kind": [{"group": "batch", "kind": "Job", "version": "v1"}]
+		},
+		"io.k8s.api.batch.v1.JobSpec": {"type": "object"}
+	}}}`)
+	schemas, err = IndexSchemas(v3)
+	if err != nil {
+		t.Fatalf("IndexSchemas v3: %v", err)
+	}
+	spec := schemas[GVK{Group: "batch", Version: "v1", Kind: "Job"}]["properties"].(map[string]any)["spec"].(map[string]any)
+	if spec["type"] != "object" || spec["description"] != "Spec of the job." {
+		t.Errorf("v3 spec = %v", spec)
+	}
+}
@@ -0,0 +1,128 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// OpenAPIV3Paths decodes the /openapi/v3 index into group-version path
+// ("api/v1", "apis/apps/v1") → server-relative document URL.
+func OpenAPIV3Paths(raw []byte) (map[string]string, error) {
+	var index struct {
+		Paths map[string]struct {
+			ServerRelativeURL string `json:"serverRelativeURL"`
+		} `json:"paths"`
+	}
+	if err := json.Unmarshal(raw, &index); err != nil {
+		return nil, fmt.Errorf("kubernetes: decode openapi v3 index: %w", err)
+	}
+	out := make(map[string]string, len(index.Paths))
+	for path, entry := range index.Paths {
+		out[path] = entry.ServerRelativeURL
+	}
+	return out, nil
+}
+
+// IndexSchemas extracts object schemas keyed by group/version/kind from an
+// OpenAPI v2 (/openapi/v2) or v3 (/openapi/v3/...) document, using the
+// x-kubernetes-group-version-kind extension. Schemas are shallow: nested
+// $refs become a typed placeholder so a tool does not carry the whole
+// Kubernetes type graph.
+func IndexSchemas(raw []byte) (map[GVK]map[string]any, error) {
+	var doc struct {
+		Definitions map[string]map[string]any `json:"definitions"`
+		Components  struct {
+			Schemas map[string]map[string]any `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("kubernetes: decode openapi: %w", err)
+	}
+	defs := doc.Definitions
+	if len(defs) == 0 {
+		defs = doc.Components.Schemas
+	}
+
+	out := map[GVK]map[string]any{}
+	for _, def := range defs {
+		gvks, _ := def["x-kubernetes-group-version-kind"].([]any)
+		for _, raw := range gvks {
+			entry, _ := raw.(map[string]any)
+			group, _ := entry["group"].(string)
+			version, _ := entry["version"].(string)
+			kind, _ := entry["kind"].(string)
+			if version == "" || kind == "" || strings.HasSuffix(kind, "List") {
+				continue
+			}
+			out[GVK{Group: group, Version: version, Kind: kind}] = shallowSchema(def, defs)
+		}
+	}
+	return out, nil
+}
+
+func shallowSchema(def map[string]any, defs map[string]map[string]any) map[string]any {
+	schema := map[string]any{"type": "object"}
+	if req, ok := def["required"]; ok {
+		schema["required"] = req
+	}
+	props, _ := def["properties"].(map[string]any)
+	if len(props) == 0 {
+		return schema
+	}
+	out := make(map[string]any, len(props))
+	for name, raw := range props {
+		prop, _ := raw.(map[string]any)
+		out[name] = shallowProperty(prop, defs)
+	}
+	schema["properties"] = out
+	return schema
+}
+
+func shallowProperty(prop map[string]any, defs map[string]map[string]any) map[string]any {
+	out := map[string]any{}
+	if desc, ok := prop["description"].(string); ok {
+		out["description"] = firstSentence(desc)
+	}
+	ref, _ := prop["$ref"].(string)
+	if all, ok := prop["allOf"].([]any); ok && ref == "" && len(all) == 1 {
+		// OpenAPI v3 wraps $refs with siblings in allOf.
+		if m, ok := all[0].(map[string]any); ok {
+			ref, _ = m["$ref"].(string)
+		}
+	}
+	if ref != "" {
+		target := defs[ref[strings.LastIndex(ref, "/")+1:]]
+		out["type"] = "object"
+		if t, ok := target["type"].(string); ok {
+			out["type"] = t
+		}
+		if _, ok := out["description"]; !ok {
+			if desc, ok := target["description"].(string); ok {
+				out["description"] = firstSentence(desc)
+			}
+		}
+		return out
+	}
+	for _, key := range []string{"type", "format", "enum"} {
+		if v, ok := prop[key]; ok {
+			out[key] = v
+		}
+	}
+	if items, ok := prop["items"].(map[string]any); ok {
+		out["items"] = shallowProperty(items, defs)
+		delete(out["items"].(map[string]any), "description")
+	}
+	if _, ok := out["type"]; !ok {
+		out["type"] = "object"
+	}
+	return out
+}
+
+// firstSentence trims Kubernetes' long field docs to their first sentence.
+func firstSentence(s string) string {
+	if i := strings.Index(s, ". "); i > 0 {
+		return s[:i+1]
+	}
+	return s
+}
@@ -0,0 +1,198 @@
+// Package messaging builds the tools of a spec_type: messaging API from
+// its config: for each Kafka topic, NATS subject or RabbitMQ queue, a
+// produce tool that sends one message and a consume tool that reads a
+// bounded batch.
+package messaging
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+)
+
+// Defaults of consume calls that set no max_messages or wait_seconds.
+const (
+	DefaultMessages    = 10
+	DefaultWaitSeconds = 5
+)
+
+var nonIdent = regexp.MustCompile(`[^a-zA-Z0-9_]+`)
+
+// BuildService generates the tools of each configured topic. Operations
+// carry canonical.MessagingOperation and are executed by the runtime's
+// messaging branch.
+func BuildService(apiName string, cfg *config.MessagingConfig) (*canonical.Service, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("messaging: config is required")
+	}
+	broker := strings.ToLower(cfg.Broker)
+	maxMessages := cfg.MaxMessages
+	if maxMessages <= 0 {
+		maxMessages = config.DefaultMessagingMaxMessages
+	}
+	maxWait := cfg.MaxWaitSeconds
+	if maxWait <= 0 {
+		maxWait = config.DefaultMessagingMaxWaitSeconds
+	}
+
+	service := &canonical.Service{Name: apiName}
+	ids := map[string]string{}
+	for _, t := range cfg.Topics {
+		id := strings.Trim(nonIdent.ReplaceAllString(strings.ToLower(t.Name), "_"), "_")
+		if id == "" {
+			return nil, fmt.Errorf("messaging: topic %q has no letters or digits to name its tools", t.Name)
+		}
+		if other, ok := ids[id]; ok {
+			return nil, fmt.Errorf("messaging: topics %q and %q would both be named %s", other, t.Name, id)
+		}
+		ids[id] = t.Name
+
+		add := func(action, method, summary, description string, schema map[string]any) {
+			if t.Description != "" {
+				description = strings.TrimSpace(t.Description) + "\n\n" + description
+			}
+			service.Operations = append(service.Operations, &canonical.Operation{
+				ServiceName: apiName,
+				ID:          id + "_" + action,
+				ToolName:    canonical.ToolName(apiName, id+"_"+action),
+				Method:      method,
+				Summary:     summary,
+				Description: description,
+				InputSchema: schema,
+				Protocol:    "messaging",
+				ActionHint:  action,
+				Messaging: &canonical.MessagingOperation{
+					Broker:     broker,
+					Topic:      t.Name,
+					Action:     action,
+					Exchange:   t.Exchange,
+					RoutingKey: t.RoutingKey,
+				},
+			})
+		}
+
+		if cfg.Writable(t) {
+			add("produce", "POST", fmt.Sprintf("Send a message to %s %s", noun(broker), t.Name),
+				produceDescription(broker, t), produceSchema(broker, t))
+		}
+		// Reading from a RabbitMQ queue takes messages off it, even when
+		// they are put back, so it is not a GET.
+		method := "GET"
+		if broker == "rabbitmq" {
+			method = "POST"
+		}
+		add("consume", method, fmt.Sprintf("Read messages from %s %s", noun(broker), t.Name),
+			consumeDescription(broker, maxMessages, maxWait), consumeSchema(broker, maxMessages, maxWait))
+	}
+	if len(service.Operations) == 0 {
+		return nil, fmt.Errorf("messaging: no topics configured")
+	}
+	return service, nil
+}
+
+func noun(broker string) string {
+	switch broker {
+	case "nats":
+		return "NATS subject"
+	case "rabbitmq":
+		return "RabbitMQ queue"
+	}
+	return "Kafka topic"
+}
+
+func produceDescription(broker string, t config.MessagingTopic) string {
+	switch broker {
+	case "kafka":
+		return "Appends one record to the topic and returns its partition and offset. " +
+			"Records with the same key go to the same partition, so they stay in order."
+	case "nats":
+		return "Publishes one message on the subject. Only subscribers listening at that moment receive it."
+	}
+	if t.Exchange != "" {
+		return fmt.Sprintf("Publishes one message to exchange %s and waits for the broker to confirm it.", t.Exchange)
+	}
+	return "Publishes one message to the queue and waits for the broker to confirm it."
+}
+
+func consumeDescription(broker string, maxMessages, maxWait int) string {
+	limits := fmt.Sprintf(" Returns at most %d messages and waits at most %d seconds.", maxMessages, maxWait)
+	switch broker {
+	case "kafka":
+		return "Reads records without joining a consumer group, so no offsets are committed and other consumers are not affected. " +
+			"By default returns the most recent records; use position to read from the start, from a time, or to wait for new records." + limits
+	case "nats":
+		return "Subscribes to the subject and collects the messages published while waiting; core NATS keeps no history, " +
+			"so earlier messages are not returned. Returns as soon as max_messages arrive." + limits
+	}
+	return "Takes messages off the queue. By default they are put back afterwards (and marked redelivered); " +
+		"set ack to remove them for good. Returns once the queue is drained, waiting up to wait_seconds for a first message." + limits
+}
+
+func produceSchema(broker string, t config.MessagingTopic) map[string]any {
+	props := map[string]any{
+		"value": map[string]any{
+			"description": "Message payload. Strings are sent as they are; any other JSON value is sent JSON-encoded.",
+		},
+		"headers": map[string]any{
+			"type":                 "object",
+			"description":          "Message headers",
+			"additionalProperties": map[string]any{"type": "string"},
+		},
+	}
+	switch broker {
+	case "kafka":
+		props["key"] = map[string]any{"type": "string", "description": "Record key; picks the partition unless partition is set"}
+		props["partition"] = map[string]any{"type": "integer", "minimum": 0, "description": "Partition to write to"}
+	case "rabbitmq":
+		if t.Exchange != "" {
+			def := t.RoutingKey
+			if def == "" {
+				def = t.Name
+			}
+			props["routing_key"] = map[string]any{"type": "string", "description": fmt.Sprintf("Routing key (default: %s)", def)}
+		}
+	}
+	return map[string]any{
+		"type":                 "object",
+		"properties":           props,
+		"required":             []string{"value"},
+		"additionalProperties": false,
+	}
+}
+
+func consumeSchema(broker string, maxMessages, maxWait int) map[string]any {
+	props := map[string]any{
+		"max_messages": map[string]any{
+			"type":        "integer",
+			"description": fmt.Sprintf("Maximum messages to return (default: %d)", min(DefaultMessages, maxMessages)),
+			"minimum":     1,
+			"maximum":     maxMessages,
+		},
+		"wait_seconds": map[string]any{
+			"type":        "number",
+			"description": fmt.Sprintf("How long to wait for messages (default: %d)", min(DefaultWaitSeconds, maxWait)),
+			"minimum":     0,
+			"maximum":     maxWait,
+		},
+	}
+	switch broker {
+	case "kafka":
+		props["position"] = map[string]any{
+			"type": "string",
+			"description": "Where to read: latest (the most recent records, default), earliest (the oldest retained records), " +
+				"new (records produced while waiting), or an RFC 3339 time to read from",
+		}
+		props["partition"] = map[string]any{"type": "integer", "minimum": 0, "description": "Read only this partition"}
+		props["offset"] = map[string]any{"type": "integer", "minimum": 0, "description": "Read from this offset; requires partition"}
+	case "rabbitmq":
+		props["ack"] = map[string]any{"type": "boolean", "description": "Remove the returned messages from the queue (default: false)"}
+	}
+	return map[string]any{
+		"type":                 "object",
+		"properties":           props,
+		"additionalProperties": false,
+	}
+}
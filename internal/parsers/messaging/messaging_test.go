@@ -0,0 +1,82 @@
+package messaging
+
+import (
+	"strings"
+	"testing"
+
+	"skyline-mcp/internal/config"
+)
+
+func TestBuildService(t *testing.T) {
+	svc, err := BuildService("events", &config.MessagingConfig{
+		Broker:      "Kafka",
+		Servers:     []string{"kafka:9092"},
+		MaxMessages: 50,
+		Topics: []config.MessagingTopic{
+			{Name: "orders.v1", Description: "Order lifecycle events."},
+			{Name: "audit", ReadOnly: true},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, op := range svc.Operations {
+		names = append(names, op.ToolName+" "+op.Method)
+		if op.Protocol != "messaging" || op.Messaging == nil || op.Messaging.Broker != "kafka" {
+			t.Errorf("%s: protocol %q, messaging %+v", op.ToolName, op.Protocol, op.Messaging)
+		}
+	}
+	if got := strings.Join(names, ", "); got != "events__orders_v1_produce POST, events__orders_v1_consume GET, events__audit_consume GET" {
+		t.Errorf("tools = %s", got)
+	}
+
+	consume := svc.Operations[1]
+	if consume.Messaging.Topic != "orders.v1" || consume.Messaging.Action != "consume" {
+		t.Errorf("consume = %+v", consume.Messaging)
+	}
+	if !strings.HasPrefix(consume.Description, "Order lifecycle events.\n\n") || !strings.Contains(consume.Description, "at most 50 messages and waits at most 30 seconds") {
+		t.Errorf("description = %q", consume.Description)
+	}
+	props := consume.InputSchema["properties"].(map[string]any)
+	if props["max_messages"].(map[string]any)["maximum"] != 50 || props["position"] == nil {
+		t.Errorf("consume schema = %v", props)
+	}
+	if _, ok := svc.Operations[0].InputSchema["properties"].(map[string]any)["key"]; !ok {
+		t.Error("produce has no key argument")
+	}
+}
+
+func TestBuildServiceRabbitMQ(t *testing.T) {
+	svc, err := BuildService("jobs", &config.MessagingConfig{
+		Broker:  "rabbitmq",
+		Servers: []string{"amqp://rabbit:5672/"},
+		Topics:  []config.MessagingTopic{{Name: "emails", Exchange: "notifications", RoutingKey: "email.send"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	produce, consume := svc.Operations[0], svc.Operations[1]
+	if produce.Messaging.Exchange != "notifications" || produce.Messaging.RoutingKey != "email.send" {
+		t.Errorf("produce = %+v", produce.Messaging)
+	}
+	if _, ok := produce.InputSchema["properties"].(map[string]any)["routing_key"]; !ok {
+		t.Error("produce to an exchange has no routing_key argument")
+	}
+	if consume.Method != "POST" {
+		t.Errorf("consume method = %s", consume.Method)
+	}
+	if _, ok := consume.InputSchema["properties"].(map[string]any)["ack"]; !ok {
+		t.Error("consume has no ack argument")
+	}
+}
+
+func TestBuildServiceNameCollision(t *testing.T) {
+	_, err := BuildService("events", &config.MessagingConfig{
+		Broker: "nats", Servers: []string{"nats://nats:4222"},
+		Topics: []config.MessagingTopic{{Name: "orders.created"}, {Name: "orders-created"}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "would both be named orders_created") {
+		t.Errorf("err = %v", err)
+	}
+}
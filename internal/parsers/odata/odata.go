@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/xml"
 	"fmt"
+	"regexp"
 	"sort"
 	"strings"
 
@@ -64,6 +65,9 @@ func ParseToCanonical(ctx context.Context, raw []byte, apiName, baseURLOverride
 	if len(service.Operations) == 0 {
 		return nil, fmt.Errorf("odata: no entity sets found in metadata")
 	}
+	// OData 4.01 services also take JSON batches, which are easier to read
+	// back than multipart ones; 4.0 services (Dynamics 365, SAP) need multipart.
+	service.Operations = append(service.Operations, buildBatchOperation(apiName, edmx.Version == "4.01"))
 
 	sort.Slice(service.Operations, func(i, j int) bool {
 		return service.Operations[i].ToolName < service.Operations[j].ToolName
@@ -97,28 +101,22 @@ func buildEntitySetOperations(apiName, setName string, et EntityType) []*canonic
 		bodySchema["required"] = required
 	}
 
+	// queryOptions carries query options without a typed argument, e.g.
+	// $search, $apply or service-specific ones.
 	queryDesc := map[string]any{
-		"type": "object",
-		"properties": map[string]any{
-			"$filter":  map[string]any{"type": "string", "description": "OData filter expression (e.g. Year gt 2000)"},
-			"$top":     map[string]any{"type": "integer", "description": "Maximum number of results to return"},
-			"$skip":    map[string]any{"type": "integer", "description": "Number of results to skip"},
-			"$orderby": map[string]any{"type": "string", "description": "Sort expression (e.g. Rating desc)"},
-			"$select":  map[string]any{"type": "string", "description": "Comma-separated list of properties to return"},
-			"$count":   map[string]any{"type": "string", "description": "Set to 'true' to include total count"},
-		},
-		"additionalProperties": false,
+		"type":                 "object",
+		"description":          "Other query options by their query string name, e.g. {\"$search\": \"blue\"}",
+		"additionalProperties": map[string]any{"type": []any{"string", "number", "boolean"}},
 	}
 
 	var ops []*canonical.Operation
 
 	// List
 	listID := "list" + setName
+	listParams := queryOptionParams(et, true)
 	listInputSchema := map[string]any{
-		"type": "object",
-		"properties": map[string]any{
-			"queryOptions": queryDesc,
-		},
+		"type":                 "object",
+		"properties":           paramProperties(listParams, map[string]any{"queryOptions": queryDesc}),
 		"additionalProperties": false,
 	}
 	ops = append(ops, &canonical.Operation{
@@ -127,7 +125,8 @@ func buildEntitySetOperations(apiName, setName string, et EntityType) []*canonic
 		ToolName:          canonical.ToolName(apiName, listID),
 		Method:            "get",
 		Path:              "/" + setName,
-		Summary:           fmt.Sprintf("List %s. Supports OData query options: $filter, $top, $skip, $orderby, $select, $count.", setName),
+		Summary:           fmt.Sprintf("List %s. Supports OData query options: filter, select, orderby, top, skip, expand, count.", setName),
+		Parameters:        listParams,
 		InputSchema:       listInputSchema,
 		QueryParamsObject: "queryOptions",
 	})
@@ -137,11 +136,10 @@ func buildEntitySetOperations(apiName, setName string, et EntityType) []*canonic
 
 		// Get by key
 		getID := "get" + setName
+		getParams := append([]canonical.Parameter{{Name: keyName, In: "path", Required: true, Schema: keySchema}}, queryOptionParams(et, false)...)
 		getInputSchema := map[string]any{
-			"type": "object",
-			"properties": map[string]any{
-				keyName: keySchema,
-			},
+			"type":                 "object",
+			"properties":           paramProperties(getParams, nil),
 			"required":             []string{keyName},
 			"additionalProperties": false,
 		}
@@ -151,8 +149,8 @@ func buildEntitySetOperations(apiName, setName string, et EntityType) []*canonic
 			ToolName:    canonical.ToolName(apiName, getID),
 			Method:      "get",
 			Path:        fmt.Sprintf("/%s({%s})", setName, keyName),
-			Summary:     fmt.Sprintf("Get a single %s by %s.", setName, keyName),
-			Parameters:  []canonical.Parameter{{Name: keyName, In: "path", Required: true, Schema: keySchema}},
+			Summary:     fmt.Sprintf("Get a single %s by %s. Supports OData query options: select, expand.", setName, keyName),
+			Parameters:  getParams,
 			InputSchema: getInputSchema,
 		})
 
@@ -225,6 +223,146 @@ func buildEntitySetOperations(apiName, setName string, et EntityType) []*canonic
 	return ops
 }
 
+// buildBatchOperation returns the tool that sends several requests in one
+// $batch call, so bulk changes don't take one tool call per entity.
+func buildBatchOperation(apiName string, jsonFormat bool) *canonical.Operation {
+	contentType := "multipart/mixed"
+	if jsonFormat {
+		contentType = "application/json"
+	}
+	request := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"id": map[string]any{
+				"type":        "string",
+				"description": "Request ID, by default its position (1, 2, …). A later request in the same changeset can address an entity this one creates as $<id>",
+			},
+			"method": map[string]any{"type": "string", "enum": []string{"GET", "POST", "PUT", "PATCH", "DELETE"}},
+			"url": map[string]any{
+				"type":        "string",
+				"minLength":   1,
+				"description": "Resource path relative to the service root, e.g. Movies(1) or Movies?$top=5",
+			},
+			"headers": map[string]any{"type": "object", "additionalProperties": map[string]any{"type": "string"}},
+			"body":    map[string]any{"type": "object", "description": "JSON body of a POST, PUT or PATCH"},
+			"changeset": map[string]any{
+				"type":        "string",
+				"description": "Consecutive write requests with the same changeset name succeed or fail together",
+			},
+		},
+		"required":             []string{"method", "url"},
+		"additionalProperties": false,
+	}
+	return &canonical.Operation{
+		ServiceName: apiName,
+		ID:          "batch",
+		ToolName:    canonical.ToolName(apiName, "batch"),
+		Method:      "post",
+		Path:        "/$batch",
+		Summary:     "Send several requests in one OData $batch call. Returns one response per request, in order.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"requests": map[string]any{"type": "array", "minItems": 1, "items": request},
+				"continueOnError": map[string]any{
+					"type":        "boolean",
+					"description": "Keep going after a failed request instead of stopping the batch",
+				},
+			},
+			"required":             []string{"requests"},
+			"additionalProperties": false,
+		},
+		RequestBody: &canonical.RequestBody{Required: true, ContentType: contentType},
+		ODataBatch:  &canonical.ODataBatchOperation{JSON: jsonFormat},
+	}
+}
+
+// queryOptionParams returns the OData system query options an entity set
+// accepts as typed arguments, named without the "$" they are sent with.
+// Single-entity reads take only select and expand.
+func queryOptionParams(et EntityType, collection bool) []canonical.Parameter {
+	props := make([]string, 0, len(et.Properties))
+	for _, prop := range et.Properties {
+		props = append(props, prop.Name)
+	}
+	sort.Strings(props)
+	navs := make([]string, 0, len(et.NavigationProperties))
+	for _, nav := range et.NavigationProperties {
+		navs = append(navs, nav.Name)
+	}
+	sort.Strings(navs)
+
+	selectItems := map[string]any{"type": "string"}
+	if len(props) > 0 {
+		selectItems["enum"] = props
+	}
+	expandItems := map[string]any{"type": "string"}
+	if len(navs) > 0 {
+		expandItems["enum"] = navs
+	}
+	params := []canonical.Parameter{
+		{Name: "select", QueryName: "$select", ListSeparator: ",", Schema: map[string]any{
+			"type": "array", "items": selectItems, "uniqueItems": true,
+			"description": "Properties to return; all when omitted",
+		}},
+		{Name: "expand", QueryName: "$expand", ListSeparator: ",", Schema: map[string]any{
+			"type": "array", "items": expandItems, "uniqueItems": true,
+			"description": "Related entities to include inline",
+		}},
+	}
+	if !collection {
+		return withQueryIn(params)
+	}
+
+	orderItems := map[string]any{"type": "string"}
+	if len(props) > 0 {
+		quoted := make([]string, len(props))
+		for i, p := range props {
+			quoted[i] = regexp.QuoteMeta(p)
+		}
+		orderItems["pattern"] = "^(" + strings.Join(quoted, "|") + ")( (asc|desc))?$"
+	}
+	params = append(params,
+		canonical.Parameter{Name: "filter", QueryName: "$filter", Schema: map[string]any{
+			"type": "string", "minLength": 1,
+			"description": "Filter expression, e.g. Year gt 2000 and contains(Title,'Star')",
+		}},
+		canonical.Parameter{Name: "orderby", QueryName: "$orderby", ListSeparator: ",", Schema: map[string]any{
+			"type": "array", "items": orderItems,
+			"description": "Sort keys, each a property optionally followed by asc or desc, e.g. [\"Rating desc\"]",
+		}},
+		canonical.Parameter{Name: "top", QueryName: "$top", Schema: map[string]any{
+			"type": "integer", "minimum": 0, "description": "Maximum number of results to return",
+		}},
+		canonical.Parameter{Name: "skip", QueryName: "$skip", Schema: map[string]any{
+			"type": "integer", "minimum": 0, "description": "Number of results to skip",
+		}},
+		canonical.Parameter{Name: "count", QueryName: "$count", Schema: map[string]any{
+			"type": "boolean", "description": "Include the total number of matching results",
+		}},
+	)
+	return withQueryIn(params)
+}
+
+func withQueryIn(params []canonical.Parameter) []canonical.Parameter {
+	for i := range params {
+		params[i].In = "query"
+	}
+	return params
+}
+
+// paramProperties builds input schema properties from params, plus extra.
+func paramProperties(params []canonical.Parameter, extra map[string]any) map[string]any {
+	props := make(map[string]any, len(params)+len(extra))
+	for _, p := range params {
+		props[p.Name] = p.Schema
+	}
+	for name, schema := range extra {
+		props[name] = schema
+	}
+	return props
+}
+
 func edmTypeToJSONSchema(edmType string, nullable bool) map[string]any {
 	schema := map[string]any{}
 	switch edmType {
@@ -279,6 +417,7 @@ func keyPropertyType(keyName string, et EntityType) string {
 
 type Edmx struct {
 	XMLName      xml.Name     `xml:"Edmx"`
+	Version      string       `xml:"Version,attr"`
 	DataServices DataServices `xml:"DataServices"`
 }
 
@@ -293,9 +432,15 @@ type Schema struct {
 }
 
 type EntityType struct {
-	Name       string     `xml:"Name,attr"`
-	Key        Key        `xml:"Key"`
-	Properties []Property `xml:"Property"`
+	Name                 string               `xml:"Name,attr"`
+	Key                  Key                  `xml:"Key"`
+	Properties           []Property           `xml:"Property"`
+	NavigationProperties []NavigationProperty `xml:"NavigationProperty"`
+}
+
+type NavigationProperty struct {
+	Name string `xml:"Name,attr"`
+	Type string `xml:"Type,attr"`
 }
 
 type Key struct {
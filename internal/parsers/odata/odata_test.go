@@ -2,7 +2,12 @@ package odata
 
 import (
 	"context"
+	"reflect"
+	"regexp"
+	"strings"
 	"testing"
+
+	"skyline-mcp/internal/canonical"
 )
 
 const testCSDL = `<?xml version="1.0" encoding="utf-8"?>
@@ -51,16 +56,16 @@ func TestParseToCanonical(t *testing.T) {
 		t.Fatalf("unexpected base URL: %s", svc.BaseURL)
 	}
 
-	// Should have 5 operations: list, get, create, update, delete
-	if len(svc.Operations) != 5 {
-		t.Fatalf("expected 5 operations, got %d", len(svc.Operations))
+	// Should have 6 operations: list, get, create, update, delete, and $batch
+	if len(svc.Operations) != 6 {
+		t.Fatalf("expected 6 operations, got %d", len(svc.Operations))
 	}
 
 	opMap := map[string]struct{}{}
 	for _, op := range svc.Operations {
 		opMap[op.ID] = struct{}{}
 	}
-	for _, id := range []string{"listMovies", "getMovies", "createMovies", "updateMovies", "deleteMovies"} {
+	for _, id := range []string{"listMovies", "getMovies", "createMovies", "updateMovies", "deleteMovies", "batch"} {
 		if _, ok := opMap[id]; !ok {
 			t.Fatalf("missing operation: %s", id)
 		}
@@ -86,7 +91,7 @@ func TestParseToCanonical(t *testing.T) {
 			if op.Path != "/Movies({ID})" {
 				t.Fatalf("get path: %s", op.Path)
 			}
-			if len(op.Parameters) != 1 || op.Parameters[0].Name != "ID" {
+			if len(op.Parameters) != 3 || op.Parameters[0].Name != "ID" || op.Parameters[0].In != "path" {
 				t.Fatalf("get params: %v", op.Parameters)
 			}
 		}
@@ -108,7 +113,91 @@ func TestParseToCanonical(t *testing.T) {
 				t.Fatalf("delete method: %s", op.Method)
 			}
 		}
+		if op.ID == "batch" {
+			if op.Path != "/$batch" || op.ODataBatch == nil || op.ODataBatch.JSON {
+				t.Fatalf("batch: path %s, %+v", op.Path, op.ODataBatch)
+			}
+			if op.RequestBody == nil || op.RequestBody.ContentType != "multipart/mixed" {
+				t.Fatalf("batch request body: %+v", op.RequestBody)
+			}
+		}
+	}
+}
+
+func TestParseToCanonical_QueryOptions(t *testing.T) {
+	csdl := strings.Replace(testCSDL, `<Property Name="Director" Type="Edm.String" Nullable="false"/>`,
+		`<Property Name="Director" Type="Edm.String" Nullable="false"/>
+        <NavigationProperty Name="Cast" Type="Collection(MockMovies.Actor)"/>`, 1)
+	svc, err := ParseToCanonical(context.Background(), []byte(csdl), "movies", "http://localhost:9999/odata")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	ops := map[string]*canonical.Operation{}
+	for _, op := range svc.Operations {
+		ops[op.ID] = op
+	}
+
+	list := ops["listMovies"]
+	params := map[string]canonical.Parameter{}
+	for _, p := range list.Parameters {
+		params[p.Name] = p
+	}
+	for _, name := range []string{"filter", "select", "orderby", "top", "skip", "expand", "count"} {
+		p, ok := params[name]
+		if !ok {
+			t.Fatalf("list is missing %s", name)
+		}
+		if p.In != "query" || p.QueryName != "$"+name {
+			t.Fatalf("%s: in=%q queryName=%q", name, p.In, p.QueryName)
+		}
+		if _, ok := list.InputSchema["properties"].(map[string]any)[name]; !ok {
+			t.Fatalf("input schema is missing %s", name)
+		}
+	}
+	if params["select"].ListSeparator != "," {
+		t.Fatalf("select separator = %q", params["select"].ListSeparator)
+	}
+	if got := params["select"].Schema["items"].(map[string]any)["enum"]; !reflect.DeepEqual(got, []string{"Director", "Genre", "ID", "Rating", "Title", "Year"}) {
+		t.Fatalf("select enum = %v", got)
+	}
+	if got := params["expand"].Schema["items"].(map[string]any)["enum"]; !reflect.DeepEqual(got, []string{"Cast"}) {
+		t.Fatalf("expand enum = %v", got)
+	}
+	if params["top"].Schema["type"] != "integer" || params["count"].Schema["type"] != "boolean" {
+		t.Fatalf("top/count schemas: %v %v", params["top"].Schema, params["count"].Schema)
+	}
+	pattern := regexp.MustCompile(params["orderby"].Schema["items"].(map[string]any)["pattern"].(string))
+	for value, want := range map[string]bool{"Rating": true, "Rating desc": true, "Year asc": true, "Rating sideways": false, "Budget": false} {
+		if pattern.MatchString(value) != want {
+			t.Fatalf("orderby pattern on %q: want %v", value, want)
+		}
+	}
+
+	get := ops["getMovies"]
+	var names []string
+	for _, p := range get.Parameters {
+		names = append(names, p.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"ID", "select", "expand"}) {
+		t.Fatalf("get params = %v", names)
+	}
+}
+
+func TestParseToCanonical_JSONBatchFor401(t *testing.T) {
+	csdl := strings.Replace(testCSDL, `Version="4.0"`, `Version="4.01"`, 1)
+	svc, err := ParseToCanonical(context.Background(), []byte(csdl), "movies", "http://localhost:9999/odata")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	for _, op := range svc.Operations {
+		if op.ID == "batch" {
+			if !op.ODataBatch.JSON || op.RequestBody.ContentType != "application/json" {
+				t.Fatalf("4.01 service should batch as JSON: %+v %+v", op.ODataBatch, op.RequestBody)
+			}
+			return
+		}
 	}
+	t.Fatal("missing batch operation")
 }
 
 func TestParseToCanonical_NoBaseURL(t *testing.T) {
@@ -0,0 +1,188 @@
+package openapi
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// schemaExtensions are the vendor extensions enrichSchema understands.
+// Parameters may carry them too, and some specs put them there instead of
+// on the parameter's schema.
+var schemaExtensions = []string{"x-nullable", "x-example", "x-examples", "x-enum-descriptions", "x-enumDescriptions"}
+
+// withParameterExtensions copies the vendor extensions of a parameter onto
+// its schema, unless the schema has its own.
+func withParameterExtensions(schema map[string]any, p *openapi3.Parameter) {
+	for _, key := range schemaExtensions {
+		if v, ok := p.Extensions[key]; ok {
+			if _, has := schema[key]; !has {
+				schema[key] = v
+			}
+		}
+	}
+	if p.Example != nil {
+		if _, has := schema["example"]; !has {
+			schema["example"] = p.Example
+		}
+	}
+}
+
+// enrichSchema folds common vendor extensions into standard JSON Schema,
+// in place and recursively: nullable and x-nullable allow null,
+// x-enum-descriptions are appended to the description and x-example and
+// x-examples become example and examples. The extensions are removed.
+func enrichSchema(schema map[string]any) {
+	if schema == nil {
+		return
+	}
+	nullable, _ := schema["nullable"].(bool)
+	if xNullable, ok := schema["x-nullable"].(bool); ok {
+		nullable = nullable || xNullable
+	}
+	delete(schema, "nullable")
+	delete(schema, "x-nullable")
+	if nullable {
+		allowNull(schema)
+	}
+
+	for _, key := range []string{"x-enum-descriptions", "x-enumDescriptions"} {
+		if v, ok := schema[key]; ok {
+			delete(schema, key)
+			if values := describeEnum(schema["enum"], v); values != "" {
+				desc, _ := schema["description"].(string)
+				desc = strings.TrimSpace(desc)
+				if desc != "" && !strings.HasSuffix(desc, ".") {
+					desc += "."
+				}
+				schema["description"] = strings.TrimSpace(desc + " Values: " + values)
+			}
+		}
+	}
+
+	if v, ok := schema["x-example"]; ok {
+		delete(schema, "x-example")
+		if _, has := schema["example"]; !has {
+			schema["example"] = v
+		}
+	}
+	if v, ok := schema["x-examples"]; ok {
+		delete(schema, "x-examples")
+		if examples := exampleValues(v); len(examples) > 0 {
+			if _, has := schema["examples"]; !has {
+				schema["examples"] = examples
+			}
+		}
+	}
+
+	if props, ok := schema["properties"].(map[string]any); ok {
+		for _, prop := range props {
+			sub, _ := prop.(map[string]any)
+			enrichSchema(sub)
+		}
+	}
+	for _, key := range []string{"items", "additionalProperties", "not"} {
+		sub, _ := schema[key].(map[string]any)
+		enrichSchema(sub)
+	}
+	for _, key := range []string{"allOf", "oneOf", "anyOf"} {
+		list, _ := schema[key].([]any)
+		for _, item := range list {
+			sub, _ := item.(map[string]any)
+			enrichSchema(sub)
+		}
+	}
+}
+
+// allowNull adds null to the schema's type and enum. A schema without a
+// type accepts null already.
+func allowNull(schema map[string]any) {
+	switch t := schema["type"].(type) {
+	case string:
+		if t != "null" {
+			schema["type"] = []any{t, "null"}
+		}
+	case []any:
+		if !containsNull(t, "null") {
+			schema["type"] = append(t, "null")
+		}
+	}
+	if enum, ok := schema["enum"].([]any); ok && !containsNull(enum, nil) {
+		schema["enum"] = append(enum, nil)
+	}
+}
+
+// describeEnum renders enum values with their descriptions, given either as
+// a map from value to description or as a list parallel to the enum.
+func describeEnum(enum any, descriptions any) string {
+	values, _ := enum.([]any)
+	var parts []string
+	switch d := descriptions.(type) {
+	case map[string]any:
+		if len(values) == 0 {
+			for value := range d {
+				values = append(values, value)
+			}
+			sort.Slice(values, func(i, j int) bool { return fmt.Sprint(values[i]) < fmt.Sprint(values[j]) })
+		}
+		for _, value := range values {
+			if desc, ok := d[fmt.Sprint(value)].(string); ok && strings.TrimSpace(desc) != "" {
+				parts = append(parts, fmt.Sprintf("%v (%s)", value, strings.TrimSpace(desc)))
+			}
+		}
+	case []any:
+		for i, value := range values {
+			if i >= len(d) {
+				break
+			}
+			if desc, ok := d[i].(string); ok && strings.TrimSpace(desc) != "" {
+				parts = append(parts, fmt.Sprintf("%v (%s)", value, strings.TrimSpace(desc)))
+			}
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// exampleValues returns the examples of an x-examples extension: a list of
+// values, or a map of named examples whose entries may be example objects
+// with a value field.
+func exampleValues(v any) []any {
+	switch ex := v.(type) {
+	case []any:
+		return ex
+	case map[string]any:
+		names := make([]string, 0, len(ex))
+		for name := range ex {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		out := make([]any, 0, len(names))
+		for _, name := range names {
+			value := ex[name]
+			if obj, ok := value.(map[string]any); ok {
+				if inner, ok := obj["value"]; ok {
+					value = inner
+				}
+			}
+			out = append(out, value)
+		}
+		return out
+	}
+	return nil
+}
+
+// containsNull reports whether list holds null, the value nil for enums or
+// the type name "null" for type lists.
+func containsNull(list []any, null any) bool {
+	for _, v := range list {
+		switch v.(type) {
+		case nil, string:
+			if v == null {
+				return true
+			}
+		}
+	}
+	return false
+}
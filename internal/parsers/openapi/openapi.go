@@ -75,7 +75,7 @@ func ParseToCanonical(ctx context.Context, raw []byte, apiName, baseURLOverride
 		sort.Strings(methodKeys)
 		for _, method := range methodKeys {
 			op := ops[method]
-			operation := buildOperation(apiName, path, method, item, op)
+			operation := buildOperation(doc, apiName, path, method, item, op)
 			service.Operations = append(service.Operations, operation)
 		}
 	}
@@ -187,13 +187,14 @@ func collectOperations(item *openapi3.PathItem) map[string]*openapi3.Operation {
 	return ops
 }
 
-func buildOperation(apiName, path, method string, item *openapi3.PathItem, op *openapi3.Operation) *canonical.Operation {
+func buildOperation(doc *openapi3.T, apiName, path, method string, item *openapi3.PathItem, op *openapi3.Operation) *canonical.Operation {
 	operationID := op.OperationID
 	if operationID == "" {
 		operationID = normalizeOperationID(method, path)
 	}
 	toolName := canonical.ToolName(apiName, operationID)
 
+	security := operationSecurity(doc, op)
 	parameters := mergeParameters(item.Parameters, op.Parameters)
 	params := make([]canonical.Parameter, 0, len(parameters))
 
@@ -205,7 +206,7 @@ func buildOperation(apiName, path, method string, item *openapi3.PathItem, op *o
 			continue
 		}
 		p := param.Value
-		if isAuthHeader(p.In, p.Name) {
+		if isAuthHeader(p.In, p.Name) || isSecurityParam(security, p.In, p.Name) {
 			continue
 		}
 		paramSchema := schemaToMap(p.Schema)
@@ -217,6 +218,8 @@ func buildOperation(apiName, path, method string, item *openapi3.PathItem, op *o
 		if p.Description != "" {
 			paramSchema["description"] = p.Description
 		}
+		withParameterExtensions(paramSchema, p)
+		enrichSchema(paramSchema)
 		requiredParam := p.Required || p.In == "path"
 		params = append(params, canonical.Parameter{
 			Name:     p.Name,
@@ -233,15 +236,19 @@ func buildOperation(apiName, path, method string, item *openapi3.PathItem, op *o
 	var requestBody *canonical.RequestBody
 	if op.RequestBody != nil && op.RequestBody.Value != nil {
 		body := op.RequestBody.Value
-		if media := body.Content.Get("application/json"); media != nil {
+		if contentType, media := requestMedia(body.Content); media != nil {
 			requestBody = &canonical.RequestBody{
 				Required:    body.Required,
-				ContentType: "application/json",
+				ContentType: contentType,
 				Schema:      schemaToMap(media.Schema),
 			}
+			if contentType == "multipart/form-data" {
+				describeFileFields(requestBody.Schema)
+			}
 			if body.Description != "" {
 				requestBody.Schema["description"] = body.Description
 			}
+			enrichSchema(requestBody.Schema)
 			properties["body"] = requestBody.Schema
 			if body.Required {
 				required = append(required, "body")
@@ -269,7 +276,115 @@ func buildOperation(apiName, path, method string, item *openapi3.PathItem, op *o
 		RequestBody:    requestBody,
 		InputSchema:    inputSchema,
 		ResponseSchema: extractResponseSchema(op),
+		PIIFields:      piiFields(op),
+		Security:       security,
+	}
+}
+
+// requestMedia picks the request body representation to expose: JSON when
+// offered, otherwise a form encoding (as produced for Swagger 2 formData).
+func requestMedia(content openapi3.Content) (string, *openapi3.MediaType) {
+	if media := content["application/json"]; media != nil {
+		return "application/json", media
+	}
+	for _, contentType := range []string{"application/x-www-form-urlencoded", "multipart/form-data"} {
+		if media := content[contentType]; media != nil {
+			return contentType, media
+		}
+	}
+	if media := content.Get("application/json"); media != nil {
+		return "application/json", media
+	}
+	return "", nil
+}
+
+// describeFileFields marks binary multipart fields so agents know to pass
+// the file contents as a string.
+func describeFileFields(schema map[string]any) {
+	props, _ := schema["properties"].(map[string]any)
+	for _, raw := range props {
+		field, ok := raw.(map[string]any)
+		if !ok || field["format"] != "binary" {
+			continue
+		}
+		if desc, _ := field["description"].(string); desc != "" {
+			field["description"] = desc + " (file contents)"
+		} else {
+			field["description"] = "File contents"
+		}
+	}
+}
+
+// operationSecurity lists the auth schemes an operation accepts, from its
+// own security requirements or the document's. It returns nil when the spec
+// declares none or also allows anonymous access (an empty requirement).
+func operationSecurity(doc *openapi3.T, op *openapi3.Operation) []canonical.SecurityScheme {
+	requirements := doc.Security
+	if op.Security != nil {
+		requirements = *op.Security
+	}
+	if len(requirements) == 0 || doc.Components == nil {
+		return nil
+	}
+	var schemes []canonical.SecurityScheme
+	seen := map[string]bool{}
+	for _, req := range requirements {
+		if len(req) == 0 {
+			return nil
+		}
+		names := make([]string, 0, len(req))
+		for name := range req {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			ref := doc.Components.SecuritySchemes[name]
+			if seen[name] || ref == nil || ref.Value == nil {
+				continue
+			}
+			seen[name] = true
+			schemes = append(schemes, convertSecurityScheme(name, ref.Value))
+		}
 	}
+	return schemes
+}
+
+func convertSecurityScheme(name string, s *openapi3.SecurityScheme) canonical.SecurityScheme {
+	scheme := canonical.SecurityScheme{Name: name}
+	switch s.Type {
+	case "http":
+		switch strings.ToLower(s.Scheme) {
+		case "bearer":
+			scheme.Type = "bearer"
+		case "basic":
+			scheme.Type = "basic"
+		default:
+			scheme.Type = "http-" + strings.ToLower(s.Scheme)
+		}
+	case "apiKey":
+		scheme.Type = "api-key"
+		scheme.In = s.In
+		scheme.ParamName = s.Name
+	case "oauth2":
+		scheme.Type = "oauth2"
+	case "openIdConnect":
+		scheme.Type = "openid"
+	default:
+		scheme.Type = s.Type
+	}
+	return scheme
+}
+
+// isSecurityParam reports whether a parameter carries an API key declared by
+// one of the operation's security schemes; credentials come from the
+// profile's auth config, not from tool arguments.
+func isSecurityParam(schemes []canonical.SecurityScheme, in, name string) bool {
+	for _, s := range schemes {
+		if s.Type == "api-key" && s.In == in && strings.EqualFold(s.ParamName, name) {
+			return true
+		}
+	}
+	return false
 }
 
 func mergeParameters(pathParams, opParams openapi3.Parameters) openapi3.Parameters {
@@ -336,6 +451,16 @@ func uniqueSorted(values []string) []string {
 }
 
 func extractResponseSchema(op *openapi3.Operation) map[string]any {
+	media := responseMedia(op)
+	if media == nil {
+		return nil
+	}
+	return mediaSchema(media)
+}
+
+// responseMedia returns the JSON body of the first 2xx response, falling
+// back to the default response.
+func responseMedia(op *openapi3.Operation) *openapi3.MediaType {
 	if op.Responses == nil {
 		return nil
 	}
@@ -353,18 +478,93 @@ func extractResponseSchema(op *openapi3.Operation) map[string]any {
 		code := fmt.Sprintf("%d", statusKeys[0])
 		if ref := responses[code]; ref != nil && ref.Value != nil {
 			if media := ref.Value.Content.Get("application/json"); media != nil {
-				return schemaToMap(media.Schema)
+				return media
 			}
 		}
 	}
 	if ref := responses["default"]; ref != nil && ref.Value != nil {
 		if media := ref.Value.Content.Get("application/json"); media != nil {
-			return schemaToMap(media.Schema)
+			return media
 		}
 	}
 	return nil
 }
 
+// piiFields lists the JSON paths of response fields the spec classifies
+// as PII with "x-pii: true" or "x-classification: pii". Referenced
+// schemas are followed, so annotations on shared components apply
+// wherever they are used.
+func piiFields(op *openapi3.Operation) []string {
+	media := responseMedia(op)
+	if media == nil {
+		return nil
+	}
+	var out []string
+	collectPII(media.Schema, "$", map[*openapi3.Schema]bool{}, &out)
+	return out
+}
+
+func collectPII(ref *openapi3.SchemaRef, path string, visiting map[*openapi3.Schema]bool, out *[]string) {
+	if ref == nil || ref.Value == nil || visiting[ref.Value] {
+		return
+	}
+	schema := ref.Value
+	if path != "$" && isPII(schema.Extensions) {
+		*out = append(*out, path)
+		return
+	}
+	visiting[schema] = true
+	defer delete(visiting, schema)
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		collectPII(schema.Properties[name], path+"."+name, visiting, out)
+	}
+	collectPII(schema.Items, path+"[*]", visiting, out)
+	for _, group := range []openapi3.SchemaRefs{schema.AllOf, schema.OneOf, schema.AnyOf} {
+		for _, sub := range group {
+			collectPII(sub, path, visiting, out)
+		}
+	}
+}
+
+func isPII(ext map[string]any) bool {
+	if v, ok := ext["x-pii"].(bool); ok && v {
+		return true
+	}
+	class, _ := ext["x-classification"].(string)
+	return strings.EqualFold(class, "pii")
+}
+
+// mediaSchema converts a response body schema, carrying over a media-level
+// example so mock responses and tool examples can use it.
+func mediaSchema(media *openapi3.MediaType) map[string]any {
+	schema := schemaToMap(media.Schema)
+	enrichSchema(schema)
+	if _, ok := schema["example"]; ok {
+		return schema
+	}
+	if media.Example != nil {
+		schema["example"] = media.Example
+		return schema
+	}
+	names := make([]string, 0, len(media.Examples))
+	for name := range media.Examples {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if ex := media.Examples[name]; ex != nil && ex.Value != nil && ex.Value.Value != nil {
+			schema["example"] = ex.Value.Value
+			break
+		}
+	}
+	return schema
+}
+
 func parseStatus(code string) (int, error) {
 	var v int
 	_, err := fmt.Sscanf(code, "%d", &v)
@@ -84,3 +84,123 @@ func TestParseToCanonicalParameters(t *testing.T) {
 		t.Fatalf("expected body in input schema")
 	}
 }
+
+func TestParseToCanonicalPIIFields(t *testing.T) {
+	spec := []byte(`{
+  "openapi": "3.0.0",
+  "info": {"title": "Test", "version": "1.0"},
+  "paths": {
+    "/employees": {
+      "get": {
+        "operationId": "listEmployees",
+        "responses": {
+          "200": {
+            "description": "ok",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "properties": {
+                    "items": {"type": "array", "items": {"$ref": "#/components/schemas/Employee"}},
+                    "total": {"type": "integer"}
+                  }
+                }
+              }
+            }
+          }
+        }
+      }
+    }
+  },
+  "components": {
+    "schemas": {
+      "Employee": {
+        "type": "object",
+        "properties": {
+          "name": {"type": "string"},
+          "ssn": {"type": "string", "x-pii": true},
+          "email": {"type": "string", "x-classification": "PII"},
+          "manager": {"$ref": "#/components/schemas/Employee"}
+        }
+      }
+    }
+  }
+}`)
+
+	service, err := ParseToCanonical(context.Background(), spec, "hr", "")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	got := service.Operations[0].PIIFields
+	want := []string{"$.items[*].email", "$.items[*].ssn"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("PIIFields = %v, want %v", got, want)
+	}
+}
+
+func TestParseToCanonicalVendorExtensions(t *testing.T) {
+	spec := []byte(`{
+  "openapi": "3.0.0",
+  "info": {"title": "Test", "version": "1.0"},
+  "paths": {
+    "/issues": {
+      "post": {
+        "operationId": "createIssue",
+        "parameters": [
+          {"name": "state", "in": "query", "description": "Issue state",
+           "schema": {"type": "string", "enum": ["open", "closed"]},
+           "x-enum-descriptions": {"open": "Still being worked on", "closed": "Resolved or rejected"}},
+          {"name": "label", "in": "query", "schema": {"type": "string"}, "x-example": "bug"}
+        ],
+        "requestBody": {
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "assignee": {"type": "string", "x-nullable": true},
+                  "priority": {"type": "integer", "enum": [1, 2], "x-enum-descriptions": ["Urgent", "Normal"], "nullable": true},
+                  "due": {"type": "string", "x-examples": {"soon": {"value": "2026-01-01"}}}
+                }
+              }
+            }
+          }
+        },
+        "responses": {"201": {"description": "created"}}
+      }
+    }
+  }
+}`)
+
+	service, err := ParseToCanonical(context.Background(), spec, "test", "")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	props := service.Operations[0].InputSchema["properties"].(map[string]any)
+	state := props["state"].(map[string]any)
+	if state["description"] != "Issue state. Values: open (Still being worked on); closed (Resolved or rejected)" {
+		t.Fatalf("state description = %q", state["description"])
+	}
+	if _, ok := state["x-enum-descriptions"]; ok {
+		t.Fatal("extension left in the schema")
+	}
+	if label := props["label"].(map[string]any); label["example"] != "bug" {
+		t.Fatalf("label = %#v, want example bug", label)
+	}
+
+	body := props["body"].(map[string]any)["properties"].(map[string]any)
+	assignee := body["assignee"].(map[string]any)
+	if types, _ := assignee["type"].([]any); len(types) != 2 || types[1] != "null" {
+		t.Fatalf("assignee type = %#v, want string or null", assignee["type"])
+	}
+	priority := body["priority"].(map[string]any)
+	if enum, _ := priority["enum"].([]any); len(enum) != 3 || enum[2] != nil {
+		t.Fatalf("priority enum = %#v, want null appended", priority["enum"])
+	}
+	if priority["description"] != "Values: 1 (Urgent); 2 (Normal)" || priority["nullable"] != nil {
+		t.Fatalf("priority = %#v", priority)
+	}
+	if examples, _ := body["due"].(map[string]any)["examples"].([]any); len(examples) != 1 || examples[0] != "2026-01-01" {
+		t.Fatalf("due examples = %#v", examples)
+	}
+}
@@ -82,6 +82,9 @@ func buildOperation(apiName string, method Method) *canonical.Operation {
 	if method.Description != "" && method.Summary != "" {
 		summary = method.Summary + ": " + method.Description
 	}
+	if method.Result == nil {
+		summary += " (notification: no result is returned)"
+	}
 
 	properties := map[string]any{}
 	requiredFields := []string{}
@@ -131,6 +134,10 @@ func buildOperation(apiName string, method Method) *canonical.Operation {
 		InputSchema: inputSchema,
 		JSONRPC: &canonical.JSONRPCOperation{
 			MethodName: method.Name,
+			// "either" (the default) keeps by-name params, which most servers accept.
+			ByPosition: method.ParamStructure == "by-position",
+			// OpenRPC 1.2 declares notifications by omitting the result.
+			Notification: method.Result == nil,
 		},
 	}
 }
@@ -168,11 +175,12 @@ type Server struct {
 }
 
 type Method struct {
-	Name        string        `json:"name"`
-	Summary     string        `json:"summary"`
-	Description string        `json:"description"`
-	Params      []Param       `json:"params"`
-	Result      *MethodResult `json:"result"`
+	Name           string        `json:"name"`
+	Summary        string        `json:"summary"`
+	Description    string        `json:"description"`
+	Params         []Param       `json:"params"`
+	Result         *MethodResult `json:"result"`
+	ParamStructure string        `json:"paramStructure"` // by-name, by-position or either
 }
 
 type Param struct {
@@ -3,6 +3,8 @@ package openrpc
 import (
 	"context"
 	"testing"
+
+	"skyline-mcp/internal/canonical"
 )
 
 const calculatorSpec = `{
@@ -165,3 +167,39 @@ func TestSanitizeName(t *testing.T) {
 		})
 	}
 }
+
+func TestParseToCanonical_ParamStructureAndNotifications(t *testing.T) {
+	spec := `{
+		"openrpc": "1.2.6",
+		"info": { "title": "T", "version": "1.0" },
+		"methods": [
+			{
+				"name": "eth_getBalance",
+				"paramStructure": "by-position",
+				"params": [
+					{ "name": "address", "required": true, "schema": { "type": "string" } },
+					{ "name": "block", "schema": { "type": "string" } }
+				],
+				"result": { "name": "balance", "schema": { "type": "string" } }
+			},
+			{
+				"name": "log",
+				"params": [{ "name": "message", "required": true, "schema": { "type": "string" } }]
+			}
+		]
+	}`
+	svc, err := ParseToCanonical(context.Background(), []byte(spec), "rpc", "http://localhost/rpc")
+	if err != nil {
+		t.Fatalf("ParseToCanonical failed: %v", err)
+	}
+	ops := map[string]*canonical.JSONRPCOperation{}
+	for _, op := range svc.Operations {
+		ops[op.ID] = op.JSONRPC
+	}
+	if rpc := ops["eth_getBalance"]; !rpc.ByPosition || rpc.Notification {
+		t.Errorf("eth_getBalance = %+v, want by-position request", rpc)
+	}
+	if rpc := ops["log"]; rpc.ByPosition || !rpc.Notification {
+		t.Errorf("log = %+v, want by-name notification", rpc)
+	}
+}
@@ -0,0 +1,181 @@
+// Package prometheus implements a curated Skyline adapter for the query
+// API of Prometheus and of compatible servers such as VictoriaMetrics,
+// Thanos and Mimir: instant and range queries, series and label lookups.
+package prometheus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"skyline-mcp/internal/canonical"
+)
+
+// buildInfo is the response of /api/v1/status/buildinfo. Prometheus also
+// reports its revision and Go version; VictoriaMetrics only the version.
+type buildInfo struct {
+	Status string `json:"status"`
+	Data   struct {
+		Version string `json:"version"`
+	} `json:"data"`
+}
+
+// LooksLikeBuildInfo reports whether raw is the response of
+// /api/v1/status/buildinfo.
+func LooksLikeBuildInfo(raw []byte) bool {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || raw[0] != '{' {
+		return false
+	}
+	var info buildInfo
+	if err := json.Unmarshal(raw, &info); err != nil {
+		return false
+	}
+	return info.Status == "success" && info.Data.Version != ""
+}
+
+// ParseToCanonical returns the query tools. raw is a buildinfo response or
+// empty, as with spec_type: prometheus; neither names the server, so the
+// base URL comes from baseURLOverride.
+func ParseToCanonical(_ context.Context, _ []byte, apiName, baseURLOverride string) (*canonical.Service, error) {
+	baseURL := strings.TrimRight(strings.TrimSpace(baseURLOverride), "/")
+	if baseURL == "" {
+		return nil, fmt.Errorf("prometheus: base_url_override is required (e.g. http://prometheus:9090, or http://victoriametrics:8428 for VictoriaMetrics)")
+	}
+	svc := &canonical.Service{Name: apiName, BaseURL: baseURL}
+	for _, t := range tools() {
+		svc.Operations = append(svc.Operations, t.operation(apiName))
+	}
+	return svc, nil
+}
+
+// param is a tool argument, sent as a path or query parameter.
+type param struct {
+	name      string
+	in        string
+	queryName string
+	schema    map[string]any
+	required  bool
+}
+
+// tool is one curated operation.
+type tool struct {
+	id          string
+	path        string
+	summary     string
+	description string
+	params      []param
+	prometheus  *canonical.PrometheusOperation
+}
+
+func (t tool) operation(api string) *canonical.Operation {
+	op := &canonical.Operation{
+		ServiceName:   api,
+		ID:            t.id,
+		ToolName:      canonical.ToolName(api, t.id),
+		Method:        "get",
+		Path:          t.path,
+		Summary:       t.summary,
+		Description:   t.description,
+		StaticHeaders: map[string]string{"Accept": "application/json"},
+		Prometheus:    t.prometheus,
+	}
+	props := map[string]any{}
+	required := []string{}
+	for _, p := range t.params {
+		props[p.name] = p.schema
+		if p.required {
+			required = append(required, p.name)
+		}
+		op.Parameters = append(op.Parameters, canonical.Parameter{Name: p.name, In: p.in, QueryName: p.queryName, Required: p.required, Schema: p.schema})
+	}
+	op.InputSchema = map[string]any{"type": "object", "properties": props, "additionalProperties": false}
+	if len(required) > 0 {
+		op.InputSchema["required"] = required
+	}
+	return op
+}
+
+func str(desc string) map[string]any { return map[string]any{"type": "string", "description": desc} }
+
+const timeFormats = "RFC 3339 (2024-05-01T12:00:00Z), Unix seconds, `now` or `now-<duration>` such as now-1h or now-2d12h"
+
+var (
+	queryParam = param{name: "query", in: "query", required: true,
+		schema: str("PromQL expression, e.g. `sum by (job) (rate(http_requests_total[5m]))`.")}
+	timeoutParam = param{name: "timeout", in: "query", schema: str("Evaluation timeout, e.g. 30s. Default: the server's query timeout.")}
+	limitParam   = param{name: "limit", in: "query", schema: map[string]any{"type": "integer", "minimum": 0,
+		"description": "Maximum number of series, names or values returned; 0 means no limit. Ignored by servers that predate it."}}
+	matchParam = param{name: "match", in: "query", queryName: "match[]",
+		schema: map[string]any{"type": "array", "items": map[string]any{"type": "string"},
+			"description": "Series selectors, e.g. [\"up{job=\\\"api\\\"}\"]; series matching any of them count."}}
+	startParam = param{name: "start", in: "query", schema: str("Start of the time range: " + timeFormats + ".")}
+	endParam   = param{name: "end", in: "query", schema: str("End of the time range, in the same formats as start. Default: now.")}
+)
+
+// rangeTimes are the time arguments of tools that take a time range.
+var rangeTimes = []string{"start", "end"}
+
+// tools returns the curated tools.
+func tools() []tool {
+	required := func(p param) param {
+		p.required = true
+		return p
+	}
+	return []tool{
+		{
+			id: "query", path: "/api/v1/query",
+			summary: "Evaluate a PromQL query at one instant",
+			description: "Returns the value of each series at a single time, e.g. the current error rate per service. " +
+				"Use queryRange for values over time.",
+			params: []param{
+				queryParam,
+				{name: "time", in: "query", schema: str("Evaluation time: " + timeFormats + ". Default: now.")},
+				timeoutParam, limitParam,
+			},
+			prometheus: &canonical.PrometheusOperation{Query: "query", Times: []string{"time"}},
+		},
+		{
+			id: "queryRange", path: "/api/v1/query_range",
+			summary: "Evaluate a PromQL query over a time range",
+			description: "Returns one value per step for each series between start and end. " +
+				"Responses grow with series × points: aggregate with sum by (...) and pick a step that gives a few hundred points at most.",
+			params: []param{
+				queryParam,
+				required(startParam),
+				endParam,
+				{name: "step", in: "query", schema: str("Resolution: a duration such as 30s, 5m or 1h, or seconds as a number. " +
+					"Default: the range divided into 250 points. Servers cap the points per series, 11,000 for Prometheus.")},
+				timeoutParam, limitParam,
+			},
+			prometheus: &canonical.PrometheusOperation{Query: "query", Times: rangeTimes, Range: true},
+		},
+		{
+			id: "series", path: "/api/v1/series",
+			summary:     "Find series by label matchers",
+			description: "Lists the label sets of the series that match the selectors, e.g. to see which instances report a metric.",
+			params:      []param{required(matchParam), startParam, endParam, limitParam},
+			prometheus:  &canonical.PrometheusOperation{Times: rangeTimes},
+		},
+		{
+			id: "labelNames", path: "/api/v1/labels",
+			summary:     "List label names",
+			description: "Lists label names, optionally only those of series matching the selectors.",
+			params:      []param{matchParam, startParam, endParam, limitParam},
+			prometheus:  &canonical.PrometheusOperation{Times: rangeTimes},
+		},
+		{
+			id: "labelValues", path: "/api/v1/label/{label}/values",
+			summary: "List the values of a label",
+			description: "Lists the values of one label, e.g. job or namespace. " +
+				"Use label __name__ to list metric names, with match to narrow them down.",
+			params: []param{
+				{name: "label", in: "path", required: true, schema: str("Label name, e.g. job, or __name__ for metric names.")},
+				matchParam, startParam, endParam, limitParam,
+			},
+			prometheus: &canonical.PrometheusOperation{Times: rangeTimes},
+		},
+	}
+}
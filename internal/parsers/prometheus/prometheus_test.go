@@ -0,0 +1,67 @@
+package prometheus
+
+import (
+	"context"
+	"testing"
+)
+
+func TestLooksLikeBuildInfo(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want bool
+	}{
+		{`{"status":"success","data":{"version":"2.53.0","revision":"4c35b9250afefede41c5f5acd76191f90f625898","branch":"HEAD","goVersion":"go1.22.4"}}`, true},
+		{`{"status":"success","data":{"version":"2.24.0"}}`, true},
+		{`{"status":"error","errorType":"bad_data","error":"unknown"}`, false},
+		{`{"openapi":"3.0.0","paths":{}}`, false},
+		{``, false},
+	}
+	for _, tt := range tests {
+		if got := LooksLikeBuildInfo([]byte(tt.raw)); got != tt.want {
+			t.Errorf("LooksLikeBuildInfo(%s) = %v, want %v", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestParseToCanonical(t *testing.T) {
+	if _, err := ParseToCanonical(context.Background(), nil, "metrics", ""); err == nil {
+		t.Fatal("expected an error without base_url_override")
+	}
+	svc, err := ParseToCanonical(context.Background(), nil, "metrics", "http://prometheus:9090/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if svc.BaseURL != "http://prometheus:9090" {
+		t.Errorf("BaseURL = %q", svc.BaseURL)
+	}
+	want := []string{"query", "queryRange", "series", "labelNames", "labelValues"}
+	if len(svc.Operations) != len(want) {
+		t.Fatalf("operations = %d, want %d", len(svc.Operations), len(want))
+	}
+	for i, op := range svc.Operations {
+		if op.ID != want[i] || op.ToolName != "metrics__"+want[i] || op.Method != "get" || op.Prometheus == nil {
+			t.Errorf("operation %d = %s %s %s", i, op.ToolName, op.Method, op.Path)
+		}
+	}
+
+	queryRange := svc.Operations[1]
+	if queryRange.Path != "/api/v1/query_range" || !queryRange.Prometheus.Range || queryRange.Prometheus.Query != "query" {
+		t.Errorf("queryRange = %s %+v", queryRange.Path, queryRange.Prometheus)
+	}
+	if req := queryRange.InputSchema["required"].([]string); len(req) != 2 || req[0] != "query" || req[1] != "start" {
+		t.Errorf("queryRange required = %v", req)
+	}
+
+	series := svc.Operations[2]
+	if series.Prometheus.Range || series.Prometheus.Query != "" {
+		t.Errorf("series = %+v", series.Prometheus)
+	}
+	for _, p := range series.Parameters {
+		if p.Name == "match" && (p.QueryName != "match[]" || !p.Required) {
+			t.Errorf("match parameter = %+v", p)
+		}
+	}
+	if values := svc.Operations[4]; values.Path != "/api/v1/label/{label}/values" || values.Parameters[0].In != "path" {
+		t.Errorf("labelValues = %s %+v", values.Path, values.Parameters[0])
+	}
+}
@@ -0,0 +1,213 @@
+package rawhttp
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// curlFlagsWithValue are curl options that consume the following argument
+// but do not affect the generated operation.
+var curlFlagsWithValue = map[string]bool{
+	"-u": true, "--user": true, "-A": true, "--user-agent": true, "-e": true, "--referer": true,
+	"-o": true, "--output": true, "-m": true, "--max-time": true, "--connect-timeout": true,
+	"-x": true, "--proxy": true, "--cacert": true, "--cert": true, "--key": true,
+	"-b": true, "--cookie": true, "-c": true, "--cookie-jar": true, "-w": true, "--write-out": true,
+	"--retry": true, "--resolve": true, "--limit-rate": true,
+}
+
+// ParseCurl parses a curl command line (as copied from browser dev tools or
+// API docs). -u/--user and cookies are ignored; credentials come from the
+// API's auth config.
+func ParseCurl(command string) (*Request, error) {
+	args, err := splitShell(command)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 0 || args[0] != "curl" {
+		return nil, fmt.Errorf("curl: command must start with curl")
+	}
+
+	req := &Request{}
+	var data []string
+	getData := false
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		value := func() (string, error) {
+			if i+1 >= len(args) {
+				return "", fmt.Errorf("curl: %s needs a value", arg)
+			}
+			i++
+			return args[i], nil
+		}
+		// --flag=value form.
+		if strings.HasPrefix(arg, "--") {
+			if name, v, ok := strings.Cut(arg, "="); ok {
+				arg = name
+				args = append(args[:i+1], append([]string{v}, args[i+1:]...)...)
+			}
+		}
+
+		switch arg {
+		case "-X", "--request":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			req.Method = strings.ToUpper(v)
+		case "-H", "--header":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			name, hv, ok := strings.Cut(v, ":")
+			if !ok {
+				return nil, fmt.Errorf("curl: invalid header %q", v)
+			}
+			req.Headers = append(req.Headers, [2]string{strings.TrimSpace(name), strings.TrimSpace(hv)})
+		case "-d", "--data", "--data-raw", "--data-binary", "--data-ascii":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			if strings.HasPrefix(v, "@") && arg != "--data-raw" {
+				return nil, fmt.Errorf("curl: reading the body from a file (%s) is not supported", v)
+			}
+			data = append(data, v)
+		case "--data-urlencode":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			if name, content, ok := strings.Cut(v, "="); ok {
+				v = name + "=" + escapeOutsidePlaceholders(content)
+			} else {
+				v = escapeOutsidePlaceholders(v)
+			}
+			data = append(data, v)
+		case "--json":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			data = append(data, v)
+			req.Headers = append(req.Headers, [2]string{"Content-Type", "application/json"}, [2]string{"Accept", "application/json"})
+		case "-F", "--form":
+			return nil, fmt.Errorf("curl: multipart forms (-F) are not supported; use -d")
+		case "-G", "--get":
+			getData = true
+		case "-I", "--head":
+			req.Method = "HEAD"
+		case "--url":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			req.URL = v
+		default:
+			if curlFlagsWithValue[arg] {
+				if _, err := value(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if strings.HasPrefix(arg, "-") {
+				continue // boolean flags such as -s, -L, -k, --compressed
+			}
+			if req.URL != "" {
+				return nil, fmt.Errorf("curl: more than one URL (%s, %s)", req.URL, arg)
+			}
+			req.URL = arg
+		}
+	}
+	if req.URL == "" {
+		return nil, fmt.Errorf("curl: no URL")
+	}
+
+	if len(data) > 0 {
+		joined := strings.Join(data, "&")
+		if getData {
+			sep := "?"
+			if strings.Contains(req.URL, "?") {
+				sep = "&"
+			}
+			req.URL += sep + joined
+		} else {
+			req.Body = joined
+		}
+	}
+	if req.Method == "" {
+		req.Method = "GET"
+		if req.Body != "" {
+			req.Method = "POST"
+		}
+	}
+	return req, nil
+}
+
+// escapeOutsidePlaceholders URL-encodes s but leaves {{name}} placeholders
+// for the runtime, which encodes substituted values itself.
+func escapeOutsidePlaceholders(s string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range placeholderRe.FindAllStringIndex(s, -1) {
+		b.WriteString(url.QueryEscape(s[last:loc[0]]))
+		b.WriteString(s[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	b.WriteString(url.QueryEscape(s[last:]))
+	return b.String()
+}
+
+// splitShell splits a POSIX shell command line into words, handling single
+// and double quotes, backslash escapes and line continuations.
+func splitShell(s string) ([]string, error) {
+	var words []string
+	var cur strings.Builder
+	inWord := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s):
+			i++
+			if s[i] == '\n' {
+				continue // line continuation
+			}
+			cur.WriteByte(s[i])
+			inWord = true
+		case c == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("curl: unterminated single quote")
+			}
+			cur.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+			inWord = true
+		case c == '"':
+			i++
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte("\"\\$`\n", s[i+1]) >= 0 {
+					i++
+				}
+				cur.WriteByte(s[i])
+			}
+			if i >= len(s) {
+				return nil, fmt.Errorf("curl: unterminated double quote")
+			}
+			inWord = true
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inWord {
+				words = append(words, cur.String())
+				cur.Reset()
+				inWord = false
+			}
+		default:
+			cur.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, cur.String())
+	}
+	return words, nil
+}
@@ -0,0 +1,146 @@
+package rawhttp
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	httpMethods   = map[string]bool{"GET": true, "POST": true, "PUT": true, "PATCH": true, "DELETE": true, "HEAD": true, "OPTIONS": true}
+	fileVarRe     = regexp.MustCompile(`^@([A-Za-z_][A-Za-z0-9_]*)\s*=\s*(.*)$`)
+	nameCommentRe = regexp.MustCompile(`^(?:#|//)\s*@name\s+(\S+)`)
+)
+
+// ParseHTTP parses requests in the .http/.rest format used by the VS Code
+// REST Client and JetBrains HTTP Client: requests separated by "###" lines,
+// each a request line ("POST {{host}}/items"), headers, a blank line and an
+// optional body. File variables ("@host = https://api.example.com") are
+// substituted; other {{name}} placeholders are left for the caller.
+func ParseHTTP(text string) ([]Request, error) {
+	vars := map[string]string{}
+	var reqs []Request
+	for _, block := range splitBlocks(strings.ReplaceAll(text, "\r\n", "\n")) {
+		req, ok, err := parseBlock(block.lines, vars)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			req.Title = block.title
+			reqs = append(reqs, req)
+		}
+	}
+	if len(reqs) == 0 {
+		return nil, fmt.Errorf("http file: no requests found")
+	}
+	return reqs, nil
+}
+
+type httpBlock struct {
+	title string
+	lines []string
+}
+
+func splitBlocks(text string) []httpBlock {
+	blocks := []httpBlock{{}}
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(line, "###") {
+			blocks = append(blocks, httpBlock{title: strings.TrimSpace(strings.TrimLeft(line, "#"))})
+			continue
+		}
+		last := &blocks[len(blocks)-1]
+		last.lines = append(last.lines, line)
+	}
+	return blocks
+}
+
+func parseBlock(lines []string, vars map[string]string) (Request, bool, error) {
+	var req Request
+	i := 0
+	// Preamble: comments, @name and file variables.
+	for ; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		switch {
+		case line == "":
+			continue
+		case nameCommentRe.MatchString(line):
+			req.Name = nameCommentRe.FindStringSubmatch(line)[1]
+			continue
+		case strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//"):
+			continue
+		case fileVarRe.MatchString(line):
+			m := fileVarRe.FindStringSubmatch(line)
+			vars[m[1]] = substituteVars(strings.TrimSpace(m[2]), vars)
+			continue
+		}
+		break
+	}
+	if i >= len(lines) {
+		return req, false, nil
+	}
+
+	requestLine := strings.Fields(substituteVars(strings.TrimSpace(lines[i]), vars))
+	i++
+	if httpMethods[strings.ToUpper(requestLine[0])] {
+		req.Method = strings.ToUpper(requestLine[0])
+		requestLine = requestLine[1:]
+	} else {
+		req.Method = "GET"
+	}
+	if len(requestLine) == 0 {
+		return req, false, fmt.Errorf("http file: request line without URL")
+	}
+	req.URL = requestLine[0]
+	// Query continuation lines ("    &page=2") follow the request line.
+	for ; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if !strings.HasPrefix(line, "?") && !strings.HasPrefix(line, "&") {
+			break
+		}
+		req.URL += substituteVars(line, vars)
+	}
+
+	for ; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			i++
+			break
+		}
+		if strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return req, false, fmt.Errorf("http file: invalid header line %q", line)
+		}
+		req.Headers = append(req.Headers, [2]string{strings.TrimSpace(name), substituteVars(strings.TrimSpace(value), vars)})
+	}
+
+	var body []string
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		// Response handler scripts and redirects belong to the client.
+		if strings.HasPrefix(trimmed, "> ") || strings.HasPrefix(trimmed, "<> ") || strings.HasPrefix(trimmed, ">> ") {
+			break
+		}
+		if strings.HasPrefix(trimmed, "< ") && len(body) == 0 {
+			return req, false, fmt.Errorf("http file: bodies read from files (%s) are not supported", trimmed)
+		}
+		body = append(body, line)
+	}
+	req.Body = strings.TrimSpace(substituteVars(strings.Join(body, "\n"), vars))
+	return req, true, nil
+}
+
+// substituteVars replaces {{name}} for file variables, leaving other
+// placeholders in place.
+func substituteVars(s string, vars map[string]string) string {
+	return placeholderRe.ReplaceAllStringFunc(s, func(m string) string {
+		name := placeholderRe.FindStringSubmatch(m)[1]
+		if v, ok := vars[name]; ok {
+			return v
+		}
+		return m
+	})
+}
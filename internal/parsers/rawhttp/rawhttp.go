@@ -0,0 +1,211 @@
+// Package rawhttp turns hand-written requests — curl command lines and
+// .http/.rest file snippets — into canonical operations. {{name}}
+// placeholders mark the values a caller supplies.
+package rawhttp
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"skyline-mcp/internal/canonical"
+)
+
+// Request is one parsed request. URL, header values and Body may contain
+// {{name}} placeholders.
+type Request struct {
+	Name    string // from "# @name" in .http files
+	Title   string // text after "###" in .http files
+	Method  string
+	URL     string
+	Headers [][2]string
+	Body    string
+}
+
+var placeholderRe = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// Placeholders returns the distinct placeholder names in s, in order of
+// first appearance.
+func Placeholders(s string) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, m := range placeholderRe.FindAllStringSubmatch(s, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			names = append(names, m[1])
+		}
+	}
+	return names
+}
+
+// headers that describe the body or are set by auth config; they never
+// become tool arguments.
+var managedHeaders = map[string]bool{
+	"content-type":   true,
+	"content-length": true,
+	"authorization":  true,
+	"cookie":         true,
+	"host":           true,
+}
+
+// BuildOperation converts a request into an operation of a service whose
+// base URL is baseURL. An absolute request URL must start with baseURL; when
+// baseURL is empty the request URL's origin is returned as the base to use.
+//
+// Placeholders in the path become path parameters. A query parameter or
+// header whose value contains a placeholder becomes an argument named after
+// the query key or header; fixed ones are sent as recorded. Placeholders in
+// the body become arguments substituted into the body template at call
+// time.
+func BuildOperation(apiName, id, summary string, req *Request, baseURL string) (*canonical.Operation, string, error) {
+	rawURL := strings.TrimSpace(req.URL)
+	if rawURL == "" {
+		return nil, "", fmt.Errorf("%s: request has no URL", id)
+	}
+	base := strings.TrimRight(baseURL, "/")
+	// Keep placeholders intact through URL parsing.
+	masked := placeholderRe.ReplaceAllString(rawURL, "\x00$1\x01")
+	if strings.HasPrefix(masked, "\x00") {
+		// A leading variable such as {{host}} stands for the base URL.
+		if end := strings.IndexByte(masked, '\x01'); end > 0 {
+			masked = masked[end+1:]
+		}
+	} else if strings.Contains(masked, "://") {
+		u, err := url.Parse(strings.NewReplacer("\x00", "{{", "\x01", "}}").Replace(masked))
+		if err != nil {
+			return nil, "", fmt.Errorf("%s: invalid URL %q: %w", id, rawURL, err)
+		}
+		origin := u.Scheme + "://" + u.Host
+		if base == "" {
+			base = origin
+		}
+		if !strings.HasPrefix(rawURL, base) {
+			return nil, "", fmt.Errorf("%s: URL %s is not under the API base URL %s", id, rawURL, base)
+		}
+		masked = placeholderRe.ReplaceAllString(strings.TrimPrefix(rawURL, base), "\x00$1\x01")
+	}
+	pathPart, rawQuery, _ := strings.Cut(masked, "?")
+	unmask := strings.NewReplacer("\x00", "{{", "\x01", "}}").Replace
+
+	properties := map[string]any{}
+	var required []string
+	var params []canonical.Parameter
+	addArg := func(name, in, desc string) {
+		schema := map[string]any{"type": "string"}
+		if desc != "" {
+			schema["description"] = desc
+		}
+		params = append(params, canonical.Parameter{Name: name, In: in, Required: true, Schema: schema})
+		if _, dup := properties[name]; !dup {
+			properties[name] = schema
+			required = append(required, name)
+		}
+	}
+
+	path := "/" + strings.TrimLeft(unmask(pathPart), "/")
+	for _, name := range Placeholders(path) {
+		addArg(name, "path", "")
+	}
+	path = placeholderRe.ReplaceAllString(path, "{$1}")
+
+	var fixed []string
+	if rawQuery != "" {
+		for _, pair := range strings.Split(rawQuery, "&") {
+			if pair == "" {
+				continue
+			}
+			key, value, _ := strings.Cut(pair, "=")
+			value = unmask(value)
+			if len(Placeholders(value)) == 0 {
+				fixed = append(fixed, unmask(key)+"="+value)
+				continue
+			}
+			if k, err := url.QueryUnescape(unmask(key)); err == nil {
+				key = k
+			}
+			addArg(key, "query", "Query parameter (template: "+value+")")
+		}
+	}
+	if len(fixed) > 0 {
+		// Fixed query values stay in the path and are sent on every call.
+		path += "?" + strings.Join(fixed, "&")
+	}
+
+	static := map[string]string{}
+	contentType := ""
+	for _, h := range req.Headers {
+		name, value := h[0], h[1]
+		lower := strings.ToLower(name)
+		if lower == "content-type" {
+			contentType = value
+		}
+		if managedHeaders[lower] {
+			continue
+		}
+		if len(Placeholders(value)) > 0 {
+			addArg(name, "header", "Header value (template: "+value+")")
+			continue
+		}
+		static[name] = value
+	}
+
+	var body *canonical.RequestBody
+	if strings.TrimSpace(req.Body) != "" {
+		if contentType == "" {
+			contentType = guessContentType(req.Body)
+		}
+		body = &canonical.RequestBody{
+			Required:    true,
+			ContentType: contentType,
+			Template:    req.Body,
+		}
+		for _, name := range Placeholders(req.Body) {
+			if _, dup := properties[name]; dup {
+				continue
+			}
+			properties[name] = map[string]any{"description": "Substituted into the request body"}
+			required = append(required, name)
+		}
+	}
+
+	if summary == "" {
+		summary = fmt.Sprintf("%s %s", strings.ToUpper(req.Method), path)
+	}
+	inputSchema := map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		sort.Strings(required)
+		inputSchema["required"] = required
+	}
+	op := &canonical.Operation{
+		ServiceName: apiName,
+		ID:          id,
+		ToolName:    canonical.ToolName(apiName, id),
+		Method:      strings.ToLower(req.Method),
+		Path:        path,
+		Summary:     summary,
+		Parameters:  params,
+		RequestBody: body,
+		InputSchema: inputSchema,
+	}
+	if len(static) > 0 {
+		op.StaticHeaders = static
+	}
+	return op, base, nil
+}
+
+func guessContentType(body string) string {
+	trimmed := strings.TrimSpace(body)
+	if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		return "application/json"
+	}
+	if strings.HasPrefix(trimmed, "<") {
+		return "application/xml"
+	}
+	return "application/x-www-form-urlencoded"
+}
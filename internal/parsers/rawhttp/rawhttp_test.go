@@ -0,0 +1,125 @@
+package rawhttp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCurl(t *testing.T) {
+	req, err := ParseCurl(`curl -sS -X PATCH 'https://api.example.com/v1/items/{{item_id}}?expand=owner&tag={{tag}}' \
+  -H 'Content-Type: application/json' \
+  -H "X-Tenant: {{tenant}}" \
+  -H 'Authorization: Bearer abc' \
+  --data-raw '{"name": "{{name}}", "count": {{count}}}'`)
+	if err != nil {
+		t.Fatalf("ParseCurl: %v", err)
+	}
+	if req.Method != "PATCH" || req.URL != "https://api.example.com/v1/items/{{item_id}}?expand=owner&tag={{tag}}" {
+		t.Fatalf("unexpected request line: %s %s", req.Method, req.URL)
+	}
+	if len(req.Headers) != 3 || req.Body != `{"name": "{{name}}", "count": {{count}}}` {
+		t.Fatalf("unexpected headers/body: %v %q", req.Headers, req.Body)
+	}
+
+	op, base, err := BuildOperation("api", "rename_item", "", req, "")
+	if err != nil {
+		t.Fatalf("BuildOperation: %v", err)
+	}
+	if base != "https://api.example.com" {
+		t.Errorf("base = %q", base)
+	}
+	if op.Path != "/v1/items/{item_id}?expand=owner" || op.Method != "patch" {
+		t.Errorf("path = %s %s", op.Method, op.Path)
+	}
+	in := map[string]string{}
+	for _, p := range op.Parameters {
+		in[p.Name] = p.In
+	}
+	if !reflect.DeepEqual(in, map[string]string{"item_id": "path", "tag": "query", "X-Tenant": "header"}) {
+		t.Errorf("parameters = %v", in)
+	}
+	if _, ok := op.StaticHeaders["Authorization"]; ok {
+		t.Errorf("authorization must come from auth config, got static headers %v", op.StaticHeaders)
+	}
+	if op.RequestBody == nil || op.RequestBody.ContentType != "application/json" || op.RequestBody.Template == "" {
+		t.Fatalf("request body = %+v", op.RequestBody)
+	}
+	want := []string{"X-Tenant", "count", "item_id", "name", "tag"}
+	if got := op.InputSchema["required"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("required = %v, want %v", got, want)
+	}
+}
+
+func TestParseCurlDefaults(t *testing.T) {
+	req, err := ParseCurl(`curl https://api.example.com/search -G --data-urlencode "q={{query}} now" -d limit=5`)
+	if err != nil {
+		t.Fatalf("ParseCurl: %v", err)
+	}
+	if req.Method != "GET" || req.URL != "https://api.example.com/search?q={{query}}+now&limit=5" || req.Body != "" {
+		t.Fatalf("unexpected -G request: %s %s %q", req.Method, req.URL, req.Body)
+	}
+
+	req, err = ParseCurl(`curl --json '{"a":1}' https://api.example.com/things`)
+	if err != nil {
+		t.Fatalf("ParseCurl: %v", err)
+	}
+	if req.Method != "POST" {
+		t.Errorf("data implies POST, got %s", req.Method)
+	}
+
+	for _, bad := range []string{`wget https://x`, `curl -F file=@a.png https://x`, `curl 'https://x`, `curl -d @body.json https://x`} {
+		if _, err := ParseCurl(bad); err == nil {
+			t.Errorf("expected error for %s", bad)
+		}
+	}
+}
+
+func TestParseHTTP(t *testing.T) {
+	reqs, err := ParseHTTP(`@host = https://api.example.com
+@version = v2
+
+### List orders
+GET {{host}}/{{version}}/orders
+    ?status={{status}}
+Accept: application/json
+
+### Create an order
+# @name create_order
+POST {{host}}/{{version}}/orders HTTP/1.1
+Content-Type: application/json
+
+{
+  "sku": "{{sku}}",
+  "note": "created by {{user}}"
+}
+
+> {% client.global.set("id", response.body.id); %}
+`)
+	if err != nil {
+		t.Fatalf("ParseHTTP: %v", err)
+	}
+	if len(reqs) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(reqs))
+	}
+	if reqs[0].Title != "List orders" || reqs[0].URL != "https://api.example.com/v2/orders?status={{status}}" {
+		t.Errorf("first request = %+v", reqs[0])
+	}
+	create := reqs[1]
+	if create.Name != "create_order" || create.Method != "POST" {
+		t.Errorf("second request = %+v", create)
+	}
+	if create.Body != "{\n  \"sku\": \"{{sku}}\",\n  \"note\": \"created by {{user}}\"\n}" {
+		t.Errorf("body = %q", create.Body)
+	}
+
+	op, _, err := BuildOperation("shop", "create_order", "", &create, "https://api.example.com/v2")
+	if err != nil {
+		t.Fatalf("BuildOperation: %v", err)
+	}
+	if op.Path != "/orders" {
+		t.Errorf("path relative to base = %q", op.Path)
+	}
+	if _, _, err := BuildOperation("shop", "x", "", &create, "https://other.example.com"); err == nil {
+		t.Errorf("expected error for a URL outside the base URL")
+	}
+}
@@ -0,0 +1,354 @@
+// Package salesforce builds Skyline tools for a Salesforce org from its
+// describe results. Salesforce publishes no usable OpenAPI document; the
+// REST API describes each sObject instead, with its fields and their types,
+// which give typed create and update bodies and per-object SOQL queries.
+package salesforce
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"skyline-mcp/internal/canonical"
+)
+
+// maxListedFields bounds the fields named in a query tool's description.
+const maxListedFields = 80
+
+// Version is an entry of /services/data.
+type Version struct {
+	Version string `json:"version"`
+	URL     string `json:"url"`
+	Label   string `json:"label"`
+}
+
+// LatestVersion returns the newest API version in a /services/data
+// response, e.g. "61.0".
+func LatestVersion(raw []byte) (string, error) {
+	var versions []Version
+	if err := json.Unmarshal(raw, &versions); err != nil {
+		return "", fmt.Errorf("salesforce: parse versions: %w", err)
+	}
+	latest, latestNum := "", -1.0
+	for _, v := range versions {
+		n, err := strconv.ParseFloat(v.Version, 64)
+		if err == nil && n > latestNum {
+			latest, latestNum = v.Version, n
+		}
+	}
+	if latest == "" {
+		return "", fmt.Errorf("salesforce: no API versions listed")
+	}
+	return latest, nil
+}
+
+// SObject is the describe result of an object.
+type SObject struct {
+	Name        string  `json:"name"`
+	Label       string  `json:"label"`
+	LabelPlural string  `json:"labelPlural"`
+	Custom      bool    `json:"custom"`
+	Queryable   bool    `json:"queryable"`
+	Retrievable bool    `json:"retrieveable"`
+	Createable  bool    `json:"createable"`
+	Updateable  bool    `json:"updateable"`
+	Deletable   bool    `json:"deletable"`
+	Fields      []Field `json:"fields"`
+}
+
+// Field is a field of a describe result.
+type Field struct {
+	Name              string          `json:"name"`
+	Label             string          `json:"label"`
+	Type              string          `json:"type"`
+	Length            int             `json:"length"`
+	Nillable          bool            `json:"nillable"`
+	Createable        bool            `json:"createable"`
+	Updateable        bool            `json:"updateable"`
+	DefaultedOnCreate bool            `json:"defaultedOnCreate"`
+	NameField         bool            `json:"nameField"`
+	Restricted        bool            `json:"restrictedPicklist"`
+	PicklistValues    []PicklistValue `json:"picklistValues"`
+	ReferenceTo       []string        `json:"referenceTo"`
+	RelationshipName  string          `json:"relationshipName"`
+	HelpText          string          `json:"inlineHelpText"`
+}
+
+// PicklistValue is an allowed value of a picklist field.
+type PicklistValue struct {
+	Value  string `json:"value"`
+	Active bool   `json:"active"`
+}
+
+// ParseDescribe parses the response of /sobjects/{name}/describe.
+func ParseDescribe(raw []byte) (*SObject, error) {
+	var obj SObject
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return nil, fmt.Errorf("salesforce: parse describe: %w", err)
+	}
+	if obj.Name == "" {
+		return nil, fmt.Errorf("salesforce: describe result has no object name")
+	}
+	return &obj, nil
+}
+
+// BuildService returns the tools of an org: SOQL and SOSL search, describe,
+// and per object a query tool plus the CRUD tools the object and readOnly
+// allow. baseURL is the org's My Domain URL and version an API version
+// such as "61.0".
+func BuildService(apiName, baseURL, version string, objects []*SObject, readOnly bool) *canonical.Service {
+	root := "/services/data/v" + version
+	svc := &canonical.Service{Name: apiName, BaseURL: strings.TrimRight(baseURL, "/")}
+	svc.Operations = append(svc.Operations, generalOperations(apiName, root)...)
+	for _, obj := range objects {
+		svc.Operations = append(svc.Operations, objectOperations(apiName, root, obj, readOnly)...)
+	}
+	return svc
+}
+
+func newOperation(api, id, method, path, summary, description string) *canonical.Operation {
+	return &canonical.Operation{
+		ServiceName:   api,
+		ID:            id,
+		ToolName:      canonical.ToolName(api, id),
+		Method:        method,
+		Path:          path,
+		Summary:       summary,
+		Description:   description,
+		StaticHeaders: map[string]string{"Accept": "application/json"},
+	}
+}
+
+func objectSchema(props map[string]any, required ...string) map[string]any {
+	schema := map[string]any{"type": "object", "properties": props, "additionalProperties": false}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func stringSchema(desc string) map[string]any {
+	return map[string]any{"type": "string", "description": desc}
+}
+
+func generalOperations(api, root string) []*canonical.Operation {
+	qParam := func(desc string) canonical.Parameter {
+		return canonical.Parameter{Name: "q", In: "query", Required: true, Schema: stringSchema(desc)}
+	}
+
+	query := newOperation(api, "query", "get", root+"/query", "Run a SOQL query",
+		"Runs any SOQL query, e.g. `SELECT Id, Name, Account.Name FROM Contact WHERE Email LIKE '%@acme.com' LIMIT 20`. "+
+			"When done is false, pass nextRecordsUrl to queryMore for the next page.")
+	query.Parameters = []canonical.Parameter{qParam("SOQL query.")}
+	query.InputSchema = objectSchema(map[string]any{"q": query.Parameters[0].Schema}, "q")
+
+	more := newOperation(api, "queryMore", "get", "", "Get the next page of a query",
+		"Returns the next page of a query result.")
+	more.DynamicURLParam = "next_records_url"
+	more.InputSchema = objectSchema(map[string]any{
+		"next_records_url": stringSchema("nextRecordsUrl of the previous page, e.g. /services/data/v61.0/query/01gxx-2000."),
+	}, "next_records_url")
+
+	search := newOperation(api, "search", "get", root+"/search", "Search records with SOSL",
+		"Searches text across objects with SOSL, e.g. `FIND {Acme*} IN NAME FIELDS RETURNING Account(Id, Name), Contact(Id, Name, Email)`.")
+	search.Parameters = []canonical.Parameter{qParam("SOSL search.")}
+	search.InputSchema = objectSchema(map[string]any{"q": search.Parameters[0].Schema}, "q")
+
+	describe := newOperation(api, "describeObject", "get", root+"/sobjects/{sobject}/describe", "Describe an object",
+		"Returns the fields, relationships and picklist values of any object, including ones without generated tools.")
+	describe.Parameters = []canonical.Parameter{{Name: "sobject", In: "path", Required: true, Schema: stringSchema("Object API name, e.g. Account or Invoice__c.")}}
+	describe.InputSchema = objectSchema(map[string]any{"sobject": describe.Parameters[0].Schema}, "sobject")
+
+	return []*canonical.Operation{query, more, search, describe}
+}
+
+func objectOperations(api, root string, obj *SObject, readOnly bool) []*canonical.Operation {
+	var ops []*canonical.Operation
+	name := obj.Name
+	label := obj.Label
+	if label == "" {
+		label = name
+	}
+	idParam := canonical.Parameter{Name: "id", In: "path", Required: true, Schema: stringSchema(label + " record ID (15 or 18 characters).")}
+	recordPath := root + "/sobjects/" + name + "/{id}"
+
+	if obj.Queryable {
+		op := newOperation(api, "query"+name, "get", root+"/query", "Query "+plural(obj),
+			fmt.Sprintf("Finds %s records. The query is built as SELECT fields FROM %s WHERE where ORDER BY order_by LIMIT limit. Fields: %s.",
+				label, name, fieldList(obj.Fields)))
+		op.SOQL = &canonical.SOQLQuery{Object: name, Fields: fieldNames(obj.Fields), DefaultFields: defaultFields(obj.Fields)}
+		op.InputSchema = objectSchema(map[string]any{
+			"fields":   map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "description": "Fields to return; relationship fields such as Owner.Name work too. Default: " + strings.Join(op.SOQL.DefaultFields, ", ") + "."},
+			"where":    stringSchema("SOQL condition, e.g. Industry = 'Energy' AND CreatedDate = LAST_N_DAYS:30."),
+			"order_by": stringSchema("e.g. CreatedDate DESC."),
+			"limit":    map[string]any{"type": "integer", "minimum": 1, "maximum": 2000, "description": "Default 100."},
+		})
+		ops = append(ops, op)
+	}
+	if obj.Retrievable {
+		op := newOperation(api, "get"+name, "get", recordPath, "Get a "+label, "Returns a "+label+" record by ID.")
+		fieldsParam := canonical.Parameter{Name: "fields", In: "query", ListSeparator: ",", Schema: map[string]any{
+			"type": "array", "items": map[string]any{"type": "string"}, "description": "Fields to return. Default: all.",
+		}}
+		op.Parameters = []canonical.Parameter{idParam, fieldsParam}
+		op.InputSchema = objectSchema(map[string]any{"id": idParam.Schema, "fields": fieldsParam.Schema}, "id")
+		ops = append(ops, op)
+	}
+	if readOnly {
+		return ops
+	}
+	if obj.Createable {
+		body, required := bodySchema(obj.Fields, true)
+		op := newOperation(api, "create"+name, "post", root+"/sobjects/"+name, "Create a "+label,
+			"Creates a "+label+" record and returns its ID.")
+		op.RequestBody = &canonical.RequestBody{Required: true, ContentType: "application/json", Schema: objectSchema(body, required...)}
+		op.InputSchema = objectSchema(map[string]any{"body": op.RequestBody.Schema}, "body")
+		ops = append(ops, op)
+	}
+	if obj.Updateable {
+		body, _ := bodySchema(obj.Fields, false)
+		op := newOperation(api, "update"+name, "patch", recordPath, "Update a "+label,
+			"Sets fields of a "+label+" record; fields left out keep their value.")
+		op.Parameters = []canonical.Parameter{idParam}
+		op.RequestBody = &canonical.RequestBody{Required: true, ContentType: "application/json", Schema: objectSchema(body)}
+		op.InputSchema = objectSchema(map[string]any{"id": idParam.Schema, "body": op.RequestBody.Schema}, "id", "body")
+		ops = append(ops, op)
+	}
+	if obj.Deletable {
+		op := newOperation(api, "delete"+name, "delete", recordPath, "Delete a "+label,
+			"Deletes a "+label+" record; it stays in the Recycle Bin for 15 days.")
+		op.Parameters = []canonical.Parameter{idParam}
+		op.InputSchema = objectSchema(map[string]any{"id": idParam.Schema}, "id")
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+func plural(obj *SObject) string {
+	if obj.LabelPlural != "" {
+		return obj.LabelPlural
+	}
+	return obj.Name + " records"
+}
+
+func fieldNames(fields []Field) []string {
+	names := make([]string, len(fields))
+	for i, f := range fields {
+		names[i] = f.Name
+	}
+	return names
+}
+
+// defaultFields selects Id, the name field and LastModifiedDate.
+func defaultFields(fields []Field) []string {
+	names := []string{"Id"}
+	for _, f := range fields {
+		if f.NameField && f.Name != "Id" {
+			names = append(names, f.Name)
+		}
+	}
+	for _, f := range fields {
+		if f.Name == "LastModifiedDate" {
+			names = append(names, f.Name)
+		}
+	}
+	return names
+}
+
+// fieldList names fields with their types for a tool description.
+func fieldList(fields []Field) string {
+	parts := make([]string, 0, min(len(fields), maxListedFields))
+	for i, f := range fields {
+		if i == maxListedFields {
+			parts = append(parts, fmt.Sprintf("and %d more (see describeObject)", len(fields)-i))
+			break
+		}
+		typ := f.Type
+		if f.Type == "reference" && len(f.ReferenceTo) > 0 {
+			typ = "reference to " + strings.Join(f.ReferenceTo, "/")
+		}
+		parts = append(parts, f.Name+" ("+typ+")")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// bodySchema returns the properties of a create or update body and, for
+// create, the fields that must be set.
+func bodySchema(fields []Field, create bool) (map[string]any, []string) {
+	props := map[string]any{}
+	var required []string
+	for _, f := range fields {
+		if create && !f.Createable || !create && !f.Updateable {
+			continue
+		}
+		schema := fieldSchema(f)
+		if schema == nil {
+			continue
+		}
+		props[f.Name] = schema
+		if create && !f.Nillable && !f.DefaultedOnCreate && f.Type != "boolean" {
+			required = append(required, f.Name)
+		}
+	}
+	sort.Strings(required)
+	return props, required
+}
+
+// fieldSchema maps a field type to JSON Schema; nil for types that cannot
+// be written as JSON, such as compound address fields.
+func fieldSchema(f Field) map[string]any {
+	desc := f.Label
+	if f.HelpText != "" {
+		desc += ". " + f.HelpText
+	}
+	var schema map[string]any
+	switch f.Type {
+	case "id", "reference":
+		schema = map[string]any{"type": "string"}
+		if len(f.ReferenceTo) > 0 {
+			desc += " (ID of a " + strings.Join(f.ReferenceTo, " or ") + ")"
+		}
+	case "string", "textarea", "url", "email", "phone", "encryptedstring", "combobox":
+		schema = map[string]any{"type": "string"}
+		if f.Length > 0 {
+			schema["maxLength"] = f.Length
+		}
+	case "picklist", "multipicklist":
+		schema = map[string]any{"type": "string"}
+		var values []string
+		for _, v := range f.PicklistValues {
+			if v.Active {
+				values = append(values, v.Value)
+			}
+		}
+		switch {
+		case f.Type == "multipicklist":
+			desc += "; values separated by ; from: " + strings.Join(values, ", ")
+		case f.Restricted && len(values) > 0:
+			schema["enum"] = values
+		case len(values) > 0:
+			desc += "; usually one of: " + strings.Join(values, ", ")
+		}
+	case "boolean":
+		schema = map[string]any{"type": "boolean"}
+	case "int":
+		schema = map[string]any{"type": "integer"}
+	case "double", "currency", "percent":
+		schema = map[string]any{"type": "number"}
+	case "date":
+		schema = map[string]any{"type": "string", "format": "date"}
+	case "datetime":
+		schema = map[string]any{"type": "string", "format": "date-time"}
+	case "time":
+		schema = map[string]any{"type": "string", "format": "time"}
+	default:
+		return nil
+	}
+	if f.Nillable {
+		schema["type"] = []any{schema["type"], "null"}
+	}
+	schema["description"] = desc
+	return schema
+}
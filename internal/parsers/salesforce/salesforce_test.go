@@ -0,0 +1,92 @@
+package salesforce
+
+import (
+	"testing"
+)
+
+const accountDescribe = `{
+  "name": "Account", "label": "Account", "labelPlural": "Accounts",
+  "queryable": true, "retrieveable": true, "createable": true, "updateable": true, "deletable": true,
+  "fields": [
+    {"name": "Id", "label": "Account ID", "type": "id", "nillable": false},
+    {"name": "Name", "label": "Account Name", "type": "string", "length": 255, "nameField": true, "createable": true, "updateable": true},
+    {"name": "Type", "label": "Account Type", "type": "picklist", "nillable": true, "createable": true, "updateable": true, "restrictedPicklist": true,
+     "picklistValues": [{"value": "Customer", "active": true}, {"value": "Legacy", "active": false}]},
+    {"name": "OwnerId", "label": "Owner ID", "type": "reference", "referenceTo": ["User"], "createable": true, "updateable": true, "defaultedOnCreate": true},
+    {"name": "AnnualRevenue", "label": "Annual Revenue", "type": "currency", "nillable": true, "createable": true, "updateable": true},
+    {"name": "IsPartner", "label": "Partner", "type": "boolean", "createable": true, "updateable": false},
+    {"name": "BillingAddress", "label": "Billing Address", "type": "address", "nillable": true},
+    {"name": "LastModifiedDate", "label": "Last Modified Date", "type": "datetime"}
+  ]
+}`
+
+func TestLatestVersion(t *testing.T) {
+	got, err := LatestVersion([]byte(`[{"version":"9.0"},{"version":"61.0"},{"version":"60.0"}]`))
+	if err != nil || got != "61.0" {
+		t.Errorf("LatestVersion() = %q, %v; want 61.0", got, err)
+	}
+	if _, err := LatestVersion([]byte(`[]`)); err == nil {
+		t.Error("expected error for an empty version list")
+	}
+}
+
+func TestBuildService(t *testing.T) {
+	obj, err := ParseDescribe([]byte(accountDescribe))
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := BuildService("crm", "https://acme.my.salesforce.com/", "61.0", []*SObject{obj}, false)
+	if svc.BaseURL != "https://acme.my.salesforce.com" {
+		t.Errorf("BaseURL = %q", svc.BaseURL)
+	}
+	ops := map[string]int{}
+	for i, op := range svc.Operations {
+		ops[op.ID] = i
+	}
+	for _, id := range []string{"query", "queryMore", "search", "describeObject", "queryAccount", "getAccount", "createAccount", "updateAccount", "deleteAccount"} {
+		if _, ok := ops[id]; !ok {
+			t.Errorf("missing operation %s", id)
+		}
+	}
+
+	query := svc.Operations[ops["queryAccount"]]
+	if query.Path != "/services/data/v61.0/query" || query.SOQL == nil || query.SOQL.Object != "Account" {
+		t.Fatalf("queryAccount = %+v", query)
+	}
+	if got := query.SOQL.DefaultFields; len(got) != 3 || got[1] != "Name" || got[2] != "LastModifiedDate" {
+		t.Errorf("default fields = %v", got)
+	}
+
+	create := svc.Operations[ops["createAccount"]].RequestBody.Schema
+	props := create["properties"].(map[string]any)
+	if req := create["required"].([]string); len(req) != 1 || req[0] != "Name" {
+		t.Errorf("create required = %v, want [Name]", req)
+	}
+	if _, ok := props["BillingAddress"]; ok {
+		t.Error("compound address field in create body")
+	}
+	if _, ok := props["Id"]; ok {
+		t.Error("non-createable Id in create body")
+	}
+	if enum := props["Type"].(map[string]any)["enum"].([]string); len(enum) != 1 || enum[0] != "Customer" {
+		t.Errorf("Type enum = %v, want only active values", enum)
+	}
+	if typ := props["AnnualRevenue"].(map[string]any)["type"].([]any); typ[0] != "number" || typ[1] != "null" {
+		t.Errorf("AnnualRevenue type = %v", typ)
+	}
+
+	update := svc.Operations[ops["updateAccount"]].RequestBody.Schema
+	if _, ok := update["properties"].(map[string]any)["IsPartner"]; ok {
+		t.Error("non-updateable field in update body")
+	}
+	if _, ok := update["required"]; ok {
+		t.Error("update body has required fields")
+	}
+
+	readOnly := BuildService("crm", "https://acme.my.salesforce.com", "61.0", []*SObject{obj}, true)
+	for _, op := range readOnly.Operations {
+		if op.Method != "get" {
+			t.Errorf("read-only service has %s %s", op.Method, op.ID)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"skyline-mcp/internal/canonical"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+)
+
+// Dialect names a supported database engine.
+type Dialect string
+
+const (
+	Postgres Dialect = "postgres"
+	MySQL    Dialect = "mysql"
+	SQLite   Dialect = "sqlite"
+)
+
+// ParseDialect maps a configured driver name to a Dialect.
+func ParseDialect(driver string) (Dialect, error) {
+	switch strings.ToLower(strings.TrimSpace(driver)) {
+	case "postgres", "postgresql", "pgx":
+		return Postgres, nil
+	case "mysql", "mariadb":
+		return MySQL, nil
+	case "sqlite", "sqlite3":
+		return SQLite, nil
+	}
+	return "", fmt.Errorf("sqldb: unsupported driver %q (use postgres, mysql or sqlite)", driver)
+}
+
+// Open connects to a database. The caller owns the returned handle.
+func Open(ctx context.Context, dialect Dialect, dsn string) (*sql.DB, error) {
+	driverName := map[Dialect]string{Postgres: "pgx", MySQL: "mysql", SQLite: "sqlite"}[dialect]
+	if driverName == "" {
+		return nil, fmt.Errorf("sqldb: unsupported dialect %q", dialect)
+	}
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("sqldb: open: %w", err)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("sqldb: connect: %w", err)
+	}
+	return db, nil
+}
+
+// Table describes an introspected table.
+type Table struct {
+	Name       string
+	Columns    []canonical.SQLColumn
+	PrimaryKey []string
+}
+
+// Introspect lists the tables of a schema (the connection's default when
+// empty) with their columns and primary keys. When include is non-empty only
+// those tables are returned.
+func Introspect(ctx context.Context, db *sql.DB, dialect Dialect, schema string, include []string) ([]Table, error) {
+	names, err := tableNames(ctx, db, dialect, schema)
+	if err != nil {
+		return nil, fmt.Errorf("sqldb: list tables: %w", err)
+	}
+	wanted := map[string]bool{}
+	for _, t := range include {
+		wanted[strings.ToLower(t)] = true
+	}
+
+	var tables []Table
+	for _, name := range names {
+		if len(wanted) > 0 && !wanted[strings.ToLower(name)] {
+			continue
+		}
+		table := Table{Name: name}
+		if dialect == SQLite {
+			err = sqliteColumns(ctx, db, &table)
+		} else {
+			err = infoSchemaColumns(ctx, db, dialect, schema, &table)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("sqldb: describe %s: %w", name, err)
+		}
+		tables = append(tables, table)
+	}
+	return tables, nil
+}
+
+func tableNames(ctx context.Context, db *sql.DB, dialect Dialect, schema string) ([]string, error) {
+	var rows *sql.Rows
+	var err error
+	switch dialect {
+	case SQLite:
+		rows, err = db.QueryContext(ctx, `SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name`)
+	case Postgres:
+		rows, err = db.QueryContext(ctx, `SELECT table_name FROM information_schema.tables
+			WHERE table_type = 'BASE TABLE' AND table_schema = COALESCE(NULLIF($1, ''), current_schema()) ORDER BY table_name`, schema)
+	default:
+		rows, err = db.QueryContext(ctx, `SELECT table_name FROM information_schema.tables
+			WHERE table_type = 'BASE TABLE' AND table_schema = COALESCE(NULLIF(?, ''), DATABASE()) ORDER BY table_name`, schema)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+func sqliteColumns(ctx context.Context, db *sql.DB, table *Table) error {
+	rows, err := db.QueryContext(ctx, `SELECT name, type, "notnull", dflt_value IS NOT NULL, pk FROM pragma_table_info(?) ORDER BY cid`, table.Name)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	type pkCol struct {
+		name string
+		pos  int
+	}
+	var pks []pkCol
+	for rows.Next() {
+		var col canonical.SQLColumn
+		var notNull, hasDefault bool
+		var pk int
+		if err := rows.Scan(&col.Name, &col.Type, &notNull, &hasDefault, &pk); err != nil {
+			return err
+		}
+		col.Nullable = !notNull
+		// INTEGER PRIMARY KEY is SQLite's rowid alias and is assigned on insert.
+		col.HasDefault = hasDefault || (pk > 0 && strings.EqualFold(col.Type, "integer"))
+		if pk > 0 {
+			pks = append(pks, pkCol{col.Name, pk})
+		}
+		table.Columns = append(table.Columns, col)
+	}
+	sort.Slice(pks, func(i, j int) bool { return pks[i].pos < pks[j].pos })
+	for _, pk := range pks {
+		table.PrimaryKey = append(table.PrimaryKey, pk.name)
+	}
+	return rows.Err()
+}
+
+func infoSchemaColumns(ctx context.Context, db *sql.DB, dialect Dialect, schema string, table *Table) error {
+	current := "current_schema()"
+	ph := func(n int) string { return fmt.Sprintf("$%d", n) }
+	if dialect == MySQL {
+		current = "DATABASE()"
+		ph = func(int) string { return "?" }
+	}
+	schemaExpr := fmt.Sprintf("COALESCE(NULLIF(%s, ''), %s)", ph(1), current)
+
+	// Identity (Postgres) and auto_increment (MySQL) columns are assigned
+	// by the database, like columns with a default.
+	generated := "is_identity = 'YES'"
+	if dialect == MySQL {
+		generated = "extra LIKE '%auto_increment%'"
+	}
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(`SELECT column_name, data_type, is_nullable = 'YES',
+		column_default IS NOT NULL OR %s
+		FROM information_schema.columns WHERE table_schema = %s AND table_name = %s ORDER BY ordinal_position`,
+		generated, schemaExpr, ph(2)), schema, table.Name)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var col canonical.SQLColumn
+		if err := rows.Scan(&col.Name, &col.Type, &col.Nullable, &col.HasDefault); err != nil {
+			return err
+		}
+		table.Columns = append(table.Columns, col)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	pkRows, err := db.QueryContext(ctx, fmt.Sprintf(`SELECT kcu.column_name
+		FROM information_schema.table_constraints tc
+		JOIN information_schema.key_column_usage kcu
+		  ON tc.constraint_name = kcu.constraint_name AND tc.table_schema = kcu.table_schema AND tc.table_name = kcu.table_name
+		WHERE tc.constraint_type = 'PRIMARY KEY' AND tc.table_schema = %s AND tc.table_name = %s
+		ORDER BY kcu.ordinal_position`, schemaExpr, ph(2)), schema, table.Name)
+	if err != nil {
+		return err
+	}
+	defer pkRows.Close()
+	for pkRows.Next() {
+		var name string
+		if err := pkRows.Scan(&name); err != nil {
+			return err
+		}
+		table.PrimaryKey = append(table.PrimaryKey, name)
+	}
+	return pkRows.Err()
+}
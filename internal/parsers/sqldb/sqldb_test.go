@@ -0,0 +1,161 @@
+package sqldb
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func openTestDB(t *testing.T) string {
+	t.Helper()
+	dsn := filepath.Join(t.TempDir(), "test.db")
+	db, err := Open(context.Background(), SQLite, dsn)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer db.Close()
+	for _, stmt := range []string{
+		`CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT NOT NULL, name TEXT, active BOOLEAN NOT NULL DEFAULT 1)`,
+		`CREATE TABLE memberships (user_id INTEGER NOT NULL, team_id INTEGER NOT NULL, role TEXT, PRIMARY KEY (team_id, user_id))`,
+		`CREATE TABLE audit_log (message TEXT)`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	return dsn
+}
+
+func TestIntrospectSQLite(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(ctx, SQLite, openTestDB(t))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer db.Close()
+
+	tables, err := Introspect(ctx, db, SQLite, "", nil)
+	if err != nil {
+		t.Fatalf("Introspect: %v", err)
+	}
+	if len(tables) != 3 {
+		t.Fatalf("expected 3 tables, got %d", len(tables))
+	}
+	byName := map[string]Table{}
+	for _, tbl := range tables {
+		byName[tbl.Name] = tbl
+	}
+
+	users := byName["users"]
+	if !reflect.DeepEqual(users.PrimaryKey, []string{"id"}) {
+		t.Fatalf("users primary key = %v", users.PrimaryKey)
+	}
+	cols := map[string]bool{}
+	for _, c := range users.Columns {
+		cols[c.Name] = true
+		switch c.Name {
+		case "id":
+			if !c.HasDefault {
+				t.Errorf("INTEGER PRIMARY KEY should be database-assigned")
+			}
+		case "email":
+			if c.Nullable || c.HasDefault {
+				t.Errorf("email: nullable=%v default=%v", c.Nullable, c.HasDefault)
+			}
+		case "active":
+			if !c.HasDefault {
+				t.Errorf("active should have a default")
+			}
+		}
+	}
+	if len(cols) != 4 {
+		t.Fatalf("users columns = %v", users.Columns)
+	}
+	if pk := byName["memberships"].PrimaryKey; !reflect.DeepEqual(pk, []string{"team_id", "user_id"}) {
+		t.Fatalf("memberships primary key should follow key order, got %v", pk)
+	}
+
+	only, err := Introspect(ctx, db, SQLite, "", []string{"USERS"})
+	if err != nil || len(only) != 1 || only[0].Name != "users" {
+		t.Fatalf("table filter: %v %v", only, err)
+	}
+}
+
+func TestBuildService(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(ctx, SQLite, openTestDB(t))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer db.Close()
+	tables, err := Introspect(ctx, db, SQLite, "", nil)
+	if err != nil {
+		t.Fatalf("Introspect: %v", err)
+	}
+
+	svc, err := BuildService("db", tables, Options{MaxRows: 25})
+	if err != nil {
+		t.Fatalf("BuildService: %v", err)
+	}
+	ops := map[string]map[string]any{}
+	for _, op := range svc.Operations {
+		if op.Protocol != "sql" || op.SQL == nil {
+			t.Fatalf("%s: missing sql metadata", op.ID)
+		}
+		ops[op.ID] = op.InputSchema
+	}
+	for _, id := range []string{"users_list", "users_get", "users_insert", "users_update", "memberships_get", "audit_log_list", "audit_log_insert"} {
+		if ops[id] == nil {
+			t.Errorf("missing tool %s", id)
+		}
+	}
+	if ops["audit_log_get"] != nil || ops["audit_log_update"] != nil {
+		t.Errorf("table without primary key should not get get/update tools")
+	}
+
+	if req := ops["users_insert"]["required"]; !reflect.DeepEqual(req, []string{"email"}) {
+		t.Errorf("insert required = %v", req)
+	}
+	if req := ops["memberships_get"]["required"]; !reflect.DeepEqual(req, []string{"team_id", "user_id"}) {
+		t.Errorf("get required = %v", req)
+	}
+	limit := ops["users_list"]["properties"].(map[string]any)["limit"].(map[string]any)
+	if limit["maximum"] != 25 {
+		t.Errorf("limit maximum = %v", limit["maximum"])
+	}
+	set := ops["users_update"]["properties"].(map[string]any)["set"].(map[string]any)
+	if _, ok := set["properties"].(map[string]any)["id"]; ok {
+		t.Errorf("primary key should not be settable")
+	}
+
+	ro, err := BuildService("db", tables, Options{ReadOnly: true})
+	if err != nil {
+		t.Fatalf("BuildService read-only: %v", err)
+	}
+	for _, op := range ro.Operations {
+		if op.SQL.Action != "list" && op.SQL.Action != "get" {
+			t.Errorf("read-only service has %s", op.ID)
+		}
+	}
+}
+
+func TestJSONType(t *testing.T) {
+	cases := map[string]string{
+		"INTEGER":           "integer",
+		"bigint":            "integer",
+		"character varying": "string",
+		"numeric":           "number",
+		"double precision":  "number",
+		"boolean":           "boolean",
+		"jsonb":             "object",
+		"ARRAY":             "array",
+		"interval":          "string",
+		"timestamp":         "string",
+	}
+	for in, want := range cases {
+		if got := JSONType(in); got != want {
+			t.Errorf("JSONType(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package sqldb
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"skyline-mcp/internal/canonical"
+)
+
+// Options narrows the generated tools.
+type Options struct {
+	ReadOnly bool // only list and get
+	MaxRows  int  // cap on list results, advertised in the limit parameter
+}
+
+// DefaultMaxRows is used when Options.MaxRows is not set.
+const DefaultMaxRows = 100
+
+var nonIdent = regexp.MustCompile(`[^a-zA-Z0-9_]+`)
+
+// BuildService generates tools for each table: list (filter by column
+// equality, ordered and paged), get by primary key, insert and update by
+// primary key. Tables without a primary key only get list and insert.
+// Operations carry canonical.SQLOperation and are executed by the runtime's
+// SQL branch with parameterized statements.
+func BuildService(apiName string, tables []Table, opts Options) (*canonical.Service, error) {
+	if len(tables) == 0 {
+		return nil, fmt.Errorf("sqldb: no tables found")
+	}
+	maxRows := opts.MaxRows
+	if maxRows <= 0 {
+		maxRows = DefaultMaxRows
+	}
+	service := &canonical.Service{Name: apiName}
+	for _, t := range tables {
+		if len(t.Columns) == 0 {
+			continue
+		}
+		id := strings.Trim(nonIdent.ReplaceAllString(strings.ToLower(t.Name), "_"), "_")
+		add := func(action, method, summary string, schema map[string]any) {
+			service.Operations = append(service.Operations, &canonical.Operation{
+				ServiceName: apiName,
+				ID:          id + "_" + action,
+				ToolName:    canonical.ToolName(apiName, id+"_"+action),
+				Method:      method,
+				Summary:     summary,
+				InputSchema: schema,
+				Protocol:    "sql",
+				ActionHint:  action,
+				SQL: &canonical.SQLOperation{
+					Table:      t.Name,
+					Action:     action,
+					Columns:    t.Columns,
+					PrimaryKey: t.PrimaryKey,
+				},
+			})
+		}
+
+		add("list", "GET", fmt.Sprintf("List rows of table %s", t.Name), listSchema(t, maxRows))
+		if len(t.PrimaryKey) > 0 {
+			add("get", "GET", fmt.Sprintf("Get one row of table %s by primary key", t.Name), getSchema(t))
+		}
+		if opts.ReadOnly {
+			continue
+		}
+		add("insert", "POST", fmt.Sprintf("Insert a row into table %s", t.Name), insertSchema(t))
+		if len(t.PrimaryKey) > 0 && len(t.PrimaryKey) < len(t.Columns) {
+			add("update", "PATCH", fmt.Sprintf("Update columns of one row of table %s by primary key", t.Name), updateSchema(t))
+		}
+	}
+	if len(service.Operations) == 0 {
+		return nil, fmt.Errorf("sqldb: no tables with columns found")
+	}
+	return service, nil
+}
+
+func listSchema(t Table, maxRows int) map[string]any {
+	names := make([]string, 0, len(t.Columns))
+	for _, c := range t.Columns {
+		names = append(names, c.Name)
+	}
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"where": map[string]any{
+				"type":                 "object",
+				"description":          "Return rows whose columns equal these values (null matches NULL)",
+				"properties":           columnProperties(t.Columns, nil, true),
+				"additionalProperties": false,
+			},
+			"limit": map[string]any{
+				"type":        "integer",
+				"description": fmt.Sprintf("Maximum rows to return (default and maximum: %d)", maxRows),
+				"minimum":     1,
+				"maximum":     maxRows,
+			},
+			"offset":     map[string]any{"type": "integer", "description": "Rows to skip", "minimum": 0},
+			"order_by":   map[string]any{"type": "string", "description": "Column to sort by (default: primary key)", "enum": names},
+			"descending": map[string]any{"type": "boolean", "description": "Sort in descending order"},
+		},
+	}
+}
+
+func getSchema(t Table) map[string]any {
+	return map[string]any{
+		"type":       "object",
+		"properties": columnProperties(t.Columns, t.PrimaryKey, false),
+		"required":   t.PrimaryKey,
+	}
+}
+
+func insertSchema(t Table) map[string]any {
+	var required []string
+	for _, c := range t.Columns {
+		if !c.Nullable && !c.HasDefault {
+			required = append(required, c.Name)
+		}
+	}
+	schema := map[string]any{
+		"type":                 "object",
+		"properties":           columnProperties(t.Columns, nil, false),
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func updateSchema(t Table) map[string]any {
+	pk := map[string]bool{}
+	for _, k := range t.PrimaryKey {
+		pk[k] = true
+	}
+	var settable []canonical.SQLColumn
+	for _, c := range t.Columns {
+		if !pk[c.Name] {
+			settable = append(settable, c)
+		}
+	}
+	props := columnProperties(t.Columns, t.PrimaryKey, false)
+	props["set"] = map[string]any{
+		"type":                 "object",
+		"description":          "Columns to change and their new values",
+		"properties":           columnProperties(settable, nil, false),
+		"additionalProperties": false,
+		"minProperties":        1,
+	}
+	return map[string]any{
+		"type":       "object",
+		"properties": props,
+		"required":   append(append([]string{}, t.PrimaryKey...), "set"),
+	}
+}
+
+// columnProperties returns JSON schemas for the named columns (all columns
+// when only is empty). Nullable columns also accept null; filter schemas
+// always do.
+func columnProperties(cols []canonical.SQLColumn, only []string, filter bool) map[string]any {
+	keep := map[string]bool{}
+	for _, name := range only {
+		keep[name] = true
+	}
+	props := map[string]any{}
+	for _, c := range cols {
+		if len(keep) > 0 && !keep[c.Name] {
+			continue
+		}
+		typ := JSONType(c.Type)
+		prop := map[string]any{"type": typ, "description": c.Type}
+		if c.Nullable || filter {
+			prop["type"] = []string{typ, "null"}
+		}
+		props[c.Name] = prop
+	}
+	return props
+}
+
+// JSONType maps a database column type to the closest JSON schema type.
+func JSONType(dbType string) string {
+	t := strings.ToLower(dbType)
+	switch {
+	case strings.HasSuffix(t, "[]") || strings.HasPrefix(t, "array"):
+		return "array"
+	case strings.Contains(t, "interval"), strings.Contains(t, "point"):
+		return "string"
+	case strings.Contains(t, "bool"), t == "bit", t == "tinyint(1)":
+		return "boolean"
+	case strings.Contains(t, "int") || t == "serial" || t == "bigserial" || t == "smallserial":
+		return "integer"
+	case strings.Contains(t, "numeric"), strings.Contains(t, "decimal"), strings.Contains(t, "real"),
+		strings.Contains(t, "double"), strings.Contains(t, "float"):
+		return "number"
+	case strings.HasPrefix(t, "json"):
+		return "object"
+	}
+	return "string"
+}
@@ -0,0 +1,95 @@
+// Package ssh builds the tools of a spec_type: ssh API from its config:
+// one tool per allowlisted command template, whose arguments are the
+// template's parameters and, with several hosts, the host to run on.
+package ssh
+
+import (
+	"fmt"
+	"strings"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+)
+
+// BuildService generates a tool for each configured command. Operations
+// carry canonical.SSHOperation and are executed by the runtime's ssh
+// branch, which renders the template from the config again.
+func BuildService(apiName string, cfg *config.SSHConfig) (*canonical.Service, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("ssh: config is required")
+	}
+	service := &canonical.Service{Name: apiName}
+	for _, c := range cfg.Commands {
+		hosts := cfg.CommandHosts(c)
+		summary := strings.TrimSpace(c.Description)
+		if summary == "" {
+			summary = fmt.Sprintf("Run %s over SSH", c.Name)
+		}
+		description := fmt.Sprintf("Runs `%s` over SSH on %s. Returns the exit code and up to %d bytes each of stdout and stderr; "+
+			"a non-zero exit code is reported in the result, not as an error.",
+			c.Command, strings.Join(hosts, ", "), cfg.OutputLimit(c))
+		if c.Description != "" {
+			description = strings.TrimSpace(c.Description) + "\n\n" + description
+		}
+		service.Operations = append(service.Operations, &canonical.Operation{
+			ServiceName:    apiName,
+			ID:             c.Name,
+			ToolName:       canonical.ToolName(apiName, c.Name),
+			Method:         "POST",
+			Summary:        summary,
+			Description:    description,
+			InputSchema:    inputSchema(c, hosts),
+			Protocol:       "ssh",
+			TimeoutSeconds: c.TimeoutSeconds,
+			SSH:            &canonical.SSHOperation{Command: c.Name, Hosts: hosts},
+		})
+	}
+	if len(service.Operations) == 0 {
+		return nil, fmt.Errorf("ssh: no commands configured")
+	}
+	return service, nil
+}
+
+func inputSchema(c config.SSHCommand, hosts []string) map[string]any {
+	props := map[string]any{}
+	required := []string{}
+	// With one host there is nothing to choose.
+	if len(hosts) > 1 {
+		props["host"] = map[string]any{"type": "string", "enum": hosts, "description": "Host to run the command on"}
+		required = append(required, "host")
+	}
+	for _, p := range c.Params {
+		schema := map[string]any{}
+		if p.Description != "" {
+			schema["description"] = p.Description
+		}
+		if p.Type == "integer" {
+			schema["type"] = "integer"
+			if p.Minimum != nil {
+				schema["minimum"] = *p.Minimum
+			}
+			if p.Maximum != nil {
+				schema["maximum"] = *p.Maximum
+			}
+		} else {
+			schema["type"] = "string"
+			if len(p.Enum) > 0 {
+				schema["enum"] = p.Enum
+			} else {
+				schema["pattern"] = "^(?:" + p.Pattern + ")$"
+			}
+		}
+		if p.Default != nil {
+			schema["default"] = p.Default
+		} else {
+			required = append(required, p.Name)
+		}
+		props[p.Name] = schema
+	}
+	return map[string]any{
+		"type":                 "object",
+		"properties":           props,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
@@ -0,0 +1,69 @@
+package ssh
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"skyline-mcp/internal/config"
+)
+
+func TestBuildService(t *testing.T) {
+	maxLines := 1000
+	svc, err := BuildService("ops", &config.SSHConfig{
+		Hosts: []config.SSHHost{{Name: "web-1"}, {Name: "web-2"}, {Name: "db-1"}},
+		Commands: []config.SSHCommand{
+			{
+				Name:        "restart_service",
+				Description: "Restart a systemd service.",
+				Command:     "sudo systemctl restart {{service}}",
+				Hosts:       []string{"web-1", "web-2"},
+				Params:      []config.SSHParam{{Name: "service", Enum: []string{"nginx", "api"}}},
+			},
+			{
+				Name:           "tail_logs",
+				Command:        "tail -n {{lines}} /var/log/app/{{file}}",
+				Hosts:          []string{"db-1"},
+				TimeoutSeconds: 20,
+				MaxOutputBytes: 4096,
+				Params: []config.SSHParam{
+					{Name: "lines", Type: "integer", Maximum: &maxLines, Default: 100},
+					{Name: "file", Pattern: `[a-z]+\.log`},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(svc.Operations) != 2 {
+		t.Fatalf("operations = %d", len(svc.Operations))
+	}
+
+	restart := svc.Operations[0]
+	if restart.ToolName != "ops__restart_service" || restart.Protocol != "ssh" || restart.Method != "POST" ||
+		restart.SSH.Command != "restart_service" || !reflect.DeepEqual(restart.SSH.Hosts, []string{"web-1", "web-2"}) {
+		t.Errorf("restart = %+v, ssh %+v", restart, restart.SSH)
+	}
+	if !strings.HasPrefix(restart.Description, "Restart a systemd service.\n\nRuns `sudo systemctl restart {{service}}` over SSH on web-1, web-2.") {
+		t.Errorf("description = %q", restart.Description)
+	}
+	props := restart.InputSchema["properties"].(map[string]any)
+	if !reflect.DeepEqual(props["host"].(map[string]any)["enum"], []string{"web-1", "web-2"}) ||
+		!reflect.DeepEqual(restart.InputSchema["required"], []string{"host", "service"}) {
+		t.Errorf("restart schema = %v", restart.InputSchema)
+	}
+
+	tail := svc.Operations[1]
+	props = tail.InputSchema["properties"].(map[string]any)
+	if _, ok := props["host"]; ok {
+		t.Error("single-host command takes a host argument")
+	}
+	if props["lines"].(map[string]any)["maximum"] != 1000 || props["file"].(map[string]any)["pattern"] != `^(?:[a-z]+\.log)$` ||
+		!reflect.DeepEqual(tail.InputSchema["required"], []string{"file"}) {
+		t.Errorf("tail schema = %v", tail.InputSchema)
+	}
+	if tail.TimeoutSeconds != 20 || !strings.Contains(tail.Description, "up to 4096 bytes") {
+		t.Errorf("tail timeout %d, description %q", tail.TimeoutSeconds, tail.Description)
+	}
+}
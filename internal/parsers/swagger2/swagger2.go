@@ -34,6 +34,7 @@ func ParseToCanonical(ctx context.Context, raw []byte, apiName, baseURLOverride
 	if doc2.Swagger == "" {
 		return nil, fmt.Errorf("swagger2: missing swagger version")
 	}
+	normalizeFormConsumes(&doc2)
 	v3, err := openapi2conv.ToV3(&doc2)
 	if err != nil {
 		return nil, fmt.Errorf("swagger2: convert to v3 failed: %w", err)
@@ -48,6 +49,51 @@ func ParseToCanonical(ctx context.Context, raw []byte, apiName, baseURLOverride
 	return openapi.ParseToCanonical(ctx, data, apiName, baseURLOverride)
 }
 
+// normalizeFormConsumes makes every operation with formData parameters
+// consume a form media type, so the converted request body is sent as a form
+// instead of JSON. Swagger 2 requires this, but many specs omit consumes or
+// inherit a JSON-only global one. File parameters force multipart.
+func normalizeFormConsumes(doc *openapi2.T) {
+	for _, item := range doc.Paths {
+		if item == nil {
+			continue
+		}
+		for _, op := range item.Operations() {
+			hasForm, hasFile := false, false
+			for _, param := range op.Parameters {
+				if param == nil || param.In != "formData" {
+					continue
+				}
+				hasForm = true
+				if param.Type == "file" {
+					hasFile = true
+				}
+			}
+			if !hasForm {
+				continue
+			}
+			consumes := op.Consumes
+			if len(consumes) == 0 {
+				consumes = doc.Consumes
+			}
+			var forms []string
+			for _, mediaType := range consumes {
+				mt := strings.ToLower(strings.TrimSpace(mediaType))
+				if strings.HasPrefix(mt, "multipart/form-data") || (!hasFile && strings.HasPrefix(mt, "application/x-www-form-urlencoded")) {
+					forms = append(forms, mediaType)
+				}
+			}
+			if len(forms) == 0 {
+				forms = []string{"application/x-www-form-urlencoded"}
+				if hasFile {
+					forms = []string{"multipart/form-data"}
+				}
+			}
+			op.Consumes = forms
+		}
+	}
+}
+
 // normalizeArrayItems walks a JSON tree and converts any "items": [array]
 // into "items": {single schema}, picking the first non-null entry.
 func normalizeArrayItems(raw []byte) []byte {
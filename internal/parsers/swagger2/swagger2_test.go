@@ -46,3 +46,107 @@ func TestParseSwagger2ToCanonical(t *testing.T) {
 		t.Fatalf("expected query param limit")
 	}
 }
+
+func TestParseSwagger2VendorExtensions(t *testing.T) {
+	spec := []byte(`{
+  "swagger": "2.0",
+  "info": {"title": "Pets", "version": "1.0"},
+  "host": "example.com",
+  "paths": {
+    "/pets": {
+      "post": {
+        "operationId": "addPet",
+        "parameters": [
+          {"name": "status", "in": "query", "type": "string", "x-example": "available"},
+          {"name": "body", "in": "body", "schema": {
+            "type": "object",
+            "properties": {"owner": {"type": "string", "x-nullable": true}}
+          }}
+        ],
+        "responses": {"200": {"description": "ok"}}
+      }
+    }
+  }
+}`)
+
+	service, err := ParseToCanonical(context.Background(), spec, "pets", "")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	props := service.Operations[0].InputSchema["properties"].(map[string]any)
+	if status := props["status"].(map[string]any); status["example"] != "available" {
+		t.Fatalf("status = %#v, want example from x-example", status)
+	}
+	owner := props["body"].(map[string]any)["properties"].(map[string]any)["owner"].(map[string]any)
+	if types, _ := owner["type"].([]any); len(types) != 2 || types[1] != "null" {
+		t.Fatalf("owner = %#v, want string or null", owner)
+	}
+}
+
+func TestParseSwagger2FormDataAndSecurity(t *testing.T) {
+	spec := []byte(`{
+  "swagger": "2.0",
+  "info": {"title": "Pets", "version": "1.0"},
+  "host": "example.com",
+  "consumes": ["application/json"],
+  "securityDefinitions": {
+    "api_key": {"type": "apiKey", "name": "api_key", "in": "header"},
+    "basicAuth": {"type": "basic"}
+  },
+  "security": [{"basicAuth": []}],
+  "paths": {
+    "/pets/{id}": {
+      "post": {
+        "operationId": "updatePetWithForm",
+        "parameters": [
+          {"name": "id", "in": "path", "required": true, "type": "string"},
+          {"name": "api_key", "in": "header", "type": "string"},
+          {"name": "name", "in": "formData", "type": "string"},
+          {"name": "status", "in": "formData", "type": "string"}
+        ],
+        "security": [{"api_key": []}],
+        "responses": {"200": {"description": "ok"}}
+      }
+    },
+    "/pets/{id}/image": {
+      "post": {
+        "operationId": "uploadImage",
+        "parameters": [
+          {"name": "id", "in": "path", "required": true, "type": "string"},
+          {"name": "file", "in": "formData", "type": "file"}
+        ],
+        "responses": {"200": {"description": "ok"}}
+      }
+    }
+  }
+}`)
+
+	service, err := ParseToCanonical(context.Background(), spec, "pets", "")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	ops := map[string]int{}
+	for i, op := range service.Operations {
+		ops[op.ID] = i
+	}
+
+	update := service.Operations[ops["updatePetWithForm"]]
+	if update.RequestBody == nil || update.RequestBody.ContentType != "application/x-www-form-urlencoded" {
+		t.Fatalf("updatePetWithForm body = %+v, want urlencoded form", update.RequestBody)
+	}
+	if len(update.Security) != 1 || update.Security[0].Type != "api-key" || update.Security[0].ParamName != "api_key" {
+		t.Fatalf("updatePetWithForm security = %+v, want api_key header", update.Security)
+	}
+	props := update.InputSchema["properties"].(map[string]any)
+	if _, ok := props["api_key"]; ok {
+		t.Fatal("api key header should not be exposed as a tool argument")
+	}
+
+	upload := service.Operations[ops["uploadImage"]]
+	if upload.RequestBody == nil || upload.RequestBody.ContentType != "multipart/form-data" {
+		t.Fatalf("uploadImage body = %+v, want multipart form", upload.RequestBody)
+	}
+	if len(upload.Security) != 1 || upload.Security[0].Type != "basic" {
+		t.Fatalf("uploadImage security = %+v, want inherited basic", upload.Security)
+	}
+}
@@ -86,9 +86,16 @@ func ParseToCanonical(ctx context.Context, raw []byte, apiName, baseURLOverride
 			},
 			"additionalProperties": false,
 		}
+		// SOAP 1.1 sends the action in the SOAPAction header, SOAP 1.2 as
+		// the action parameter of the content type.
 		staticHeaders := map[string]string{}
-		if op.SoapOperation.SoapAction != "" {
-			staticHeaders["SOAPAction"] = op.SoapOperation.SoapAction
+		opContentType := contentType
+		if action := op.SoapOperation.SoapAction; action != "" {
+			if soapVersion == "1.2" {
+				opContentType += fmt.Sprintf("; action=%q", action)
+			} else {
+				staticHeaders["SOAPAction"] = action
+			}
 		}
 		serviceModel.Operations = append(serviceModel.Operations, &canonical.Operation{
 			ServiceName:    apiName,
@@ -98,11 +105,12 @@ func ParseToCanonical(ctx context.Context, raw []byte, apiName, baseURLOverride
 			Path:           "",
 			Summary:        op.Name + " (SOAP). Use arguments.parameters for key/value inputs, or arguments.body for raw XML.",
 			Parameters:     nil,
-			RequestBody:    &canonical.RequestBody{Required: false, ContentType: contentType, Schema: map[string]any{"type": "string"}},
+			RequestBody:    &canonical.RequestBody{Required: false, ContentType: opContentType, Schema: map[string]any{"type": "string"}},
 			InputSchema:    inputSchema,
 			ResponseSchema: nil,
 			StaticHeaders:  staticHeaders,
 			SoapNamespace:  def.TargetNamespace,
+			SoapVersion:    soapVersion,
 		})
 	}
 
@@ -47,3 +47,38 @@ func TestParseToCanonical(t *testing.T) {
 		t.Fatalf("missing SOAPAction")
 	}
 }
+
+func TestParseToCanonicalSOAP12(t *testing.T) {
+	wsdlDoc := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<definitions xmlns="http://schemas.xmlsoap.org/wsdl/"
+  xmlns:soap12="http://schemas.xmlsoap.org/wsdl/soap12/"
+  xmlns:tns="http://example.com/tns"
+  targetNamespace="http://example.com/tns">
+  <service name="TestService">
+    <port name="TestPort" binding="tns:TestBinding">
+      <soap12:address location="http://example.com/soap12" />
+    </port>
+  </service>
+  <binding name="TestBinding" type="tns:TestPortType">
+    <soap12:binding style="document" transport="http://schemas.xmlsoap.org/soap/http" />
+    <operation name="Echo">
+      <soap12:operation soapAction="urn:Echo" />
+    </operation>
+  </binding>
+</definitions>`)
+
+	service, err := ParseToCanonical(context.Background(), wsdlDoc, "api", "")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	op := service.Operations[0]
+	if op.SoapVersion != "1.2" {
+		t.Fatalf("soap version = %q", op.SoapVersion)
+	}
+	if want := `application/soap+xml; charset=utf-8; action="urn:Echo"`; op.RequestBody.ContentType != want {
+		t.Fatalf("content type = %q, want %q", op.RequestBody.ContentType, want)
+	}
+	if _, ok := op.StaticHeaders["SOAPAction"]; ok {
+		t.Fatalf("SOAP 1.2 operations must not send SOAPAction")
+	}
+}
@@ -0,0 +1,249 @@
+// Package plugins runs external programs that add spec formats and
+// protocols to Skyline, so proprietary API formats need no fork.
+//
+// A plugin is any executable. Skyline starts it once per call, writes one
+// JSON request to its stdin and reads one JSON response from its stdout:
+//
+//	{"method": "describe" | "parse" | "execute", "params": {...}}
+//	{"result": {...}} or {"error": "message"}
+//
+// describe reports the spec formats and protocols the plugin provides,
+// parse turns a spec document into a Service and execute runs an
+// operation of a plugin protocol.
+package plugins
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/runtime"
+	"skyline-mcp/internal/serverconfig"
+	"skyline-mcp/internal/spec"
+)
+
+// defaultTimeout bounds a call to a plugin without a configured timeout.
+const defaultTimeout = 30 * time.Second
+
+// maxStderr is how much of a failed plugin's stderr ends up in the error.
+const maxStderr = 512
+
+// Info is a plugin's answer to describe.
+type Info struct {
+	Name      string        `json:"name"`
+	Adapters  []AdapterInfo `json:"adapters,omitempty"`
+	Protocols []string      `json:"protocols,omitempty"`
+}
+
+// AdapterInfo describes a spec format of a plugin. A fetched spec is given
+// to the plugin when it contains one of the Detect markers, or when an
+// API's spec_type is Name.
+type AdapterInfo struct {
+	Name   string   `json:"name"`
+	Detect []string `json:"detect,omitempty"`
+}
+
+// Plugin is a configured plugin program.
+type Plugin struct {
+	cfg  serverconfig.PluginConfig
+	Info Info
+}
+
+// New returns the plugin of cfg; Describe must be called before it is
+// registered.
+func New(cfg serverconfig.PluginConfig) (*Plugin, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("plugin name is required")
+	}
+	if cfg.Command == "" {
+		return nil, fmt.Errorf("plugin %s: command is required", cfg.Name)
+	}
+	return &Plugin{cfg: cfg}, nil
+}
+
+// Name returns the configured name of the plugin.
+func (p *Plugin) Name() string { return p.cfg.Name }
+
+// Describe asks the plugin what it provides.
+func (p *Plugin) Describe(ctx context.Context) error {
+	var info Info
+	if err := p.call(ctx, "describe", map[string]any{}, &info); err != nil {
+		return err
+	}
+	if len(info.Adapters) == 0 && len(info.Protocols) == 0 {
+		return fmt.Errorf("plugin %s provides no adapters or protocols", p.cfg.Name)
+	}
+	for _, a := range info.Adapters {
+		if a.Name == "" {
+			return fmt.Errorf("plugin %s: adapter without a name", p.cfg.Name)
+		}
+	}
+	p.Info = info
+	return nil
+}
+
+// Load describes the plugins of cfgs. It fails when a plugin cannot be
+// run or provides a spec format or protocol that Skyline or another
+// plugin already has.
+func Load(ctx context.Context, cfgs []serverconfig.PluginConfig) ([]*Plugin, error) {
+	taken := map[string]string{}
+	for _, format := range spec.BuiltinSpecFormats() {
+		taken[format] = "built-in"
+	}
+	for _, protocol := range []string{"http", "grpc", "sql", "messaging", "ssh", "workflow", "builtin", "email"} {
+		taken[protocol] = "built-in"
+	}
+	var loaded []*Plugin
+	for _, cfg := range cfgs {
+		p, err := New(cfg)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.Describe(ctx); err != nil {
+			return nil, err
+		}
+		names := slices.Clone(p.Info.Protocols)
+		for _, a := range p.Info.Adapters {
+			names = append(names, a.Name)
+		}
+		for _, name := range names {
+			if owner, ok := taken[name]; ok {
+				return nil, fmt.Errorf("plugin %s: %s is already provided by %s", p.cfg.Name, name, owner)
+			}
+			taken[name] = "plugin " + p.cfg.Name
+		}
+		loaded = append(loaded, p)
+	}
+	return loaded, nil
+}
+
+// Register makes the adapters and protocols of the plugins available to
+// every spec load and executor.
+func Register(plugins []*Plugin, logger *slog.Logger) {
+	for _, p := range plugins {
+		for _, a := range p.Info.Adapters {
+			spec.RegisterAdapter(&adapter{plugin: p, info: a})
+		}
+		for _, protocol := range p.Info.Protocols {
+			runtime.RegisterProtocol(protocol, p.executor(protocol))
+		}
+		logger.Info("plugin registered", "plugin", p.cfg.Name, "adapters", len(p.Info.Adapters), "protocols", p.Info.Protocols)
+	}
+}
+
+// adapter is a spec format provided by a plugin.
+type adapter struct {
+	plugin *Plugin
+	info   AdapterInfo
+}
+
+func (a *adapter) Name() string { return a.info.Name }
+
+func (a *adapter) Detect(raw []byte) bool {
+	for _, marker := range a.info.Detect {
+		if marker != "" && bytes.Contains(raw, []byte(marker)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *adapter) Parse(ctx context.Context, raw []byte, apiName, baseURLOverride string) (*canonical.Service, error) {
+	var svc Service
+	err := a.plugin.call(ctx, "parse", map[string]any{
+		"format":   a.info.Name,
+		"spec":     string(raw),
+		"api_name": apiName,
+		"base_url": baseURLOverride,
+	}, &svc)
+	if err != nil {
+		return nil, err
+	}
+	return svc.canonical(apiName, a.info.Name)
+}
+
+// executor returns the handler of a protocol provided by the plugin.
+func (p *Plugin) executor(protocol string) runtime.ProtocolHandler {
+	return func(ctx context.Context, op *canonical.Operation, args map[string]any) (*runtime.Result, error) {
+		var res runtime.Result
+		err := p.call(ctx, "execute", map[string]any{
+			"protocol":  protocol,
+			"service":   op.ServiceName,
+			"operation": wireOperation(op),
+			"arguments": args,
+		}, &res)
+		if err != nil {
+			return nil, err
+		}
+		if res.Status == 0 {
+			res.Status = 200
+		}
+		if res.ContentType == "" {
+			res.ContentType = "application/json"
+		}
+		return &res, nil
+	}
+}
+
+// call runs the plugin with one request and decodes the result into out.
+func (p *Plugin) call(ctx context.Context, method string, params any, out any) error {
+	req, err := json.Marshal(map[string]any{"method": method, "params": params})
+	if err != nil {
+		return err
+	}
+	timeout := p.cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, p.cfg.Command, p.cfg.Args...)
+	cmd.Env = os.Environ()
+	for k, v := range p.cfg.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdin = bytes.NewReader(append(req, '\n'))
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("plugin %s: %s timed out after %s", p.cfg.Name, method, timeout)
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if len(msg) > maxStderr {
+			msg = msg[:maxStderr] + "..."
+		}
+		if msg != "" {
+			return fmt.Errorf("plugin %s: %s: %w: %s", p.cfg.Name, method, err, msg)
+		}
+		return fmt.Errorf("plugin %s: %s: %w", p.cfg.Name, method, err)
+	}
+
+	var resp struct {
+		Result json.RawMessage `json:"result"`
+		Error  string          `json:"error"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return fmt.Errorf("plugin %s: %s: invalid response: %w", p.cfg.Name, method, err)
+	}
+	if resp.Error != "" {
+		return fmt.Errorf("plugin %s: %s", p.cfg.Name, resp.Error)
+	}
+	if len(resp.Result) == 0 {
+		return fmt.Errorf("plugin %s: %s: response has no result", p.cfg.Name, method)
+	}
+	if err := json.Unmarshal(resp.Result, out); err != nil {
+		return fmt.Errorf("plugin %s: %s: invalid result: %w", p.cfg.Name, method, err)
+	}
+	return nil
+}
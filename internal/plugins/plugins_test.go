@@ -0,0 +1,134 @@
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/logging"
+	"skyline-mcp/internal/redact"
+	"skyline-mcp/internal/runtime"
+	"skyline-mcp/internal/serverconfig"
+	"skyline-mcp/internal/spec"
+)
+
+// TestMain lets the test binary act as a plugin when started by a test.
+func TestMain(m *testing.M) {
+	if os.Getenv("SKYLINE_TEST_PLUGIN") == "1" {
+		servePlugin()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// servePlugin answers one request as a plugin for the "acme" spec format
+// and the "acme-rpc" protocol.
+func servePlugin() {
+	var req struct {
+		Method string         `json:"method"`
+		Params map[string]any `json:"params"`
+	}
+	if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	var result any
+	switch req.Method {
+	case "describe":
+		result = Info{
+			Name:      "acme",
+			Adapters:  []AdapterInfo{{Name: "acme", Detect: []string{"acme-idl 1"}}},
+			Protocols: []string{"acme-rpc"},
+		}
+	case "parse":
+		if !strings.HasPrefix(req.Params["spec"].(string), "acme-idl 1") {
+			json.NewEncoder(os.Stdout).Encode(map[string]any{"error": "not an acme document"})
+			return
+		}
+		result = Service{BaseURL: "https://acme.example/", Operations: []Operation{
+			{ID: "getWidget", Path: "/widgets/{id}", Parameters: []Parameter{{Name: "id", In: "path", Required: true}}},
+			{ID: "ping", Protocol: "acme-rpc"},
+		}}
+	case "execute":
+		op := req.Params["operation"].(map[string]any)
+		result = map[string]any{"body": map[string]any{"op": op["id"], "args": req.Params["arguments"]}}
+	default:
+		fmt.Fprintln(os.Stderr, "unknown method", req.Method)
+		os.Exit(3)
+	}
+	json.NewEncoder(os.Stdout).Encode(map[string]any{"result": result})
+}
+
+func testPluginConfig(name string) serverconfig.PluginConfig {
+	return serverconfig.PluginConfig{
+		Name:    name,
+		Command: os.Args[0],
+		Args:    []string{"-test.run=^$"},
+		Env:     map[string]string{"SKYLINE_TEST_PLUGIN": "1"},
+	}
+}
+
+func TestPluginAdapterAndProtocol(t *testing.T) {
+	ctx := context.Background()
+	plugins, err := Load(ctx, []serverconfig.PluginConfig{testPluginConfig("acme")})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	Register(plugins, logging.Discard())
+
+	a := &adapter{plugin: plugins[0], info: plugins[0].Info.Adapters[0]}
+	if !a.Detect([]byte("acme-idl 1\nwidget Widget")) || a.Detect([]byte(`{"openapi":"3.0.0"}`)) {
+		t.Error("Detect does not follow the plugin's markers")
+	}
+	svc, err := a.Parse(ctx, []byte("acme-idl 1\n"), "shop", "")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if svc.BaseURL != "https://acme.example" || len(svc.Operations) != 2 {
+		t.Fatalf("service = %+v", svc)
+	}
+	get := svc.Operations[0]
+	if get.ToolName != "shop__getWidget" || get.Method != "GET" || get.InputSchema["required"].([]string)[0] != "id" {
+		t.Errorf("getWidget = %+v", get)
+	}
+	if _, err := a.Parse(ctx, []byte("something else"), "shop", ""); err == nil || !strings.Contains(err.Error(), "not an acme document") {
+		t.Errorf("plugin error not reported: %v", err)
+	}
+	if !slices.Contains(spec.SpecFormats(), "acme") {
+		t.Errorf("SpecFormats() = %v, want acme listed", spec.SpecFormats())
+	}
+
+	cfg := &config.Config{APIs: []config.APIConfig{{Name: "shop", SpecType: "acme", SpecFile: "shop.acme"}}}
+	cfg.ApplyDefaults()
+	exec, err := runtime.NewExecutor(cfg, []*canonical.Service{svc}, logging.Discard(), redact.NewRedactor())
+	if err != nil {
+		t.Fatalf("NewExecutor: %v", err)
+	}
+	res, err := exec.Execute(ctx, svc.Operations[1], map[string]any{"n": 1.0})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	body := res.Body.(map[string]any)
+	if res.Status != 200 || body["op"] != "ping" || body["args"].(map[string]any)["n"] != 1.0 {
+		t.Errorf("result = %+v", res)
+	}
+}
+
+func TestLoadRejectsTakenNames(t *testing.T) {
+	ctx := context.Background()
+	if _, err := Load(ctx, []serverconfig.PluginConfig{testPluginConfig("one"), testPluginConfig("two")}); err == nil || !strings.Contains(err.Error(), "already provided by plugin one") {
+		t.Errorf("duplicate plugin formats: %v", err)
+	}
+	if _, err := Load(ctx, []serverconfig.PluginConfig{{Name: "missing", Command: "/nonexistent/plugin"}}); err == nil {
+		t.Error("plugin that cannot run accepted")
+	}
+	if _, err := Load(ctx, []serverconfig.PluginConfig{{Name: "nocmd"}}); err == nil {
+		t.Error("plugin without command accepted")
+	}
+}
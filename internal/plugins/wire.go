@@ -0,0 +1,147 @@
+package plugins
+
+import (
+	"fmt"
+	"strings"
+
+	"skyline-mcp/internal/canonical"
+)
+
+// Service is the result of parse: the operations a spec describes. Plugins
+// produce this stable shape rather than Skyline's internal types.
+type Service struct {
+	Name       string      `json:"name,omitempty"` // defaults to the API name
+	BaseURL    string      `json:"base_url,omitempty"`
+	Operations []Operation `json:"operations"`
+}
+
+// Operation is one tool of a plugin service. Operations without a protocol
+// are HTTP requests Skyline sends itself; those with one of the plugin's
+// protocols are sent back to the plugin with execute.
+type Operation struct {
+	ID             string            `json:"id"`
+	ToolName       string            `json:"tool_name,omitempty"` // defaults to the ID
+	Summary        string            `json:"summary,omitempty"`
+	Description    string            `json:"description,omitempty"`
+	Method         string            `json:"method,omitempty"`
+	Path           string            `json:"path,omitempty"`
+	Protocol       string            `json:"protocol,omitempty"`
+	Parameters     []Parameter       `json:"parameters,omitempty"`
+	RequestBody    *RequestBody      `json:"request_body,omitempty"`
+	InputSchema    map[string]any    `json:"input_schema,omitempty"`
+	ResponseSchema map[string]any    `json:"response_schema,omitempty"`
+	StaticHeaders  map[string]string `json:"static_headers,omitempty"`
+	ContentType    string            `json:"content_type,omitempty"`
+}
+
+// Parameter is an HTTP parameter of an operation.
+type Parameter struct {
+	Name     string         `json:"name"`
+	In       string         `json:"in"` // path, query or header
+	Required bool           `json:"required,omitempty"`
+	Schema   map[string]any `json:"schema,omitempty"`
+}
+
+// RequestBody is the JSON body of an HTTP operation, sent from the body
+// argument.
+type RequestBody struct {
+	Required    bool           `json:"required,omitempty"`
+	ContentType string         `json:"content_type,omitempty"`
+	Schema      map[string]any `json:"schema,omitempty"`
+}
+
+// canonical converts a parsed service, checking what Skyline relies on.
+func (s *Service) canonical(apiName, format string) (*canonical.Service, error) {
+	svc := &canonical.Service{Name: apiName, BaseURL: strings.TrimRight(s.BaseURL, "/")}
+	seen := map[string]bool{}
+	for i, wop := range s.Operations {
+		if wop.ID == "" {
+			return nil, fmt.Errorf("%s parse: operations[%d]: id is required", format, i)
+		}
+		if seen[wop.ID] {
+			return nil, fmt.Errorf("%s parse: duplicate operation id %s", format, wop.ID)
+		}
+		seen[wop.ID] = true
+		op := &canonical.Operation{
+			ServiceName:    apiName,
+			ID:             wop.ID,
+			ToolName:       wop.ToolName,
+			Method:         strings.ToUpper(wop.Method),
+			Path:           wop.Path,
+			Summary:        wop.Summary,
+			Description:    wop.Description,
+			Protocol:       wop.Protocol,
+			InputSchema:    wop.InputSchema,
+			ResponseSchema: wop.ResponseSchema,
+			StaticHeaders:  wop.StaticHeaders,
+			ContentType:    wop.ContentType,
+		}
+		if op.ToolName == "" {
+			op.ToolName = canonical.ToolName(apiName, wop.ID)
+		}
+		if op.Protocol == "" {
+			if op.Method == "" {
+				op.Method = "GET"
+			}
+			if op.Path == "" {
+				return nil, fmt.Errorf("%s parse: operation %s: an HTTP operation needs a path", format, wop.ID)
+			}
+		}
+		op.HTTPMethod = op.Method
+		for _, p := range wop.Parameters {
+			op.Parameters = append(op.Parameters, canonical.Parameter{Name: p.Name, In: p.In, Required: p.Required, Schema: p.Schema})
+		}
+		if rb := wop.RequestBody; rb != nil {
+			op.RequestBody = &canonical.RequestBody{Required: rb.Required, ContentType: rb.ContentType, Schema: rb.Schema}
+		}
+		if op.InputSchema == nil {
+			op.InputSchema = inputSchema(op)
+		}
+		svc.Operations = append(svc.Operations, op)
+	}
+	return svc, nil
+}
+
+// inputSchema builds the tool input schema of an operation from its
+// parameters and body, as the built-in adapters do.
+func inputSchema(op *canonical.Operation) map[string]any {
+	props := map[string]any{}
+	required := []string{}
+	for _, p := range op.Parameters {
+		schema := p.Schema
+		if schema == nil {
+			schema = map[string]any{"type": "string"}
+		}
+		props[p.Name] = schema
+		if p.Required {
+			required = append(required, p.Name)
+		}
+	}
+	if rb := op.RequestBody; rb != nil {
+		schema := rb.Schema
+		if schema == nil {
+			schema = map[string]any{"type": "object"}
+		}
+		props["body"] = schema
+		if rb.Required {
+			required = append(required, "body")
+		}
+	}
+	schema := map[string]any{"type": "object", "properties": props, "additionalProperties": false}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// wireOperation is the operation sent with execute.
+func wireOperation(op *canonical.Operation) Operation {
+	return Operation{
+		ID:            op.ID,
+		ToolName:      op.ToolName,
+		Method:        op.Method,
+		Path:          op.Path,
+		Protocol:      op.Protocol,
+		StaticHeaders: op.StaticHeaders,
+	}
+}
@@ -101,6 +101,13 @@ func ApplyProviderOverrides(services []*canonical.Service, apiConfigs []config.A
 			continue
 		}
 
+		// The curated jira tools already pick the endpoints of the
+		// deployment; Jira Server still serves /rest/api/2/search.
+		if apiCfg.SpecType == "jira" {
+			result = append(result, svc)
+			continue
+		}
+
 		overrides := DetectOverrides(apiCfg.Name, apiCfg.SpecURL)
 		if len(overrides) == 0 {
 			result = append(result, svc)
@@ -0,0 +1,234 @@
+// Package quota enforces call budgets per profile and per API. Budgets are
+// counted per UTC day and per UTC month in a Store, which the server backs
+// with the audit database so usage survives restarts.
+package quota
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"skyline-mcp/internal/config"
+)
+
+// Store keeps usage counters. AddQuotaUsage adds n to the counter of scope
+// and period within profile and returns the new value; n = 0 reads it.
+type Store interface {
+	AddQuotaUsage(ctx context.Context, profile, scope, period string, n int64) (int64, error)
+}
+
+// MemoryStore is a Store for a single process; usage resets on restart.
+type MemoryStore struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{counts: map[string]int64{}}
+}
+
+func (m *MemoryStore) AddQuotaUsage(_ context.Context, profile, scope, period string, n int64) (int64, error) {
+	key := profile + "\x00" + scope + "\x00" + period
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counts[key] += n
+	return m.counts[key], nil
+}
+
+// ProfileScope is the scope of the profile-wide budget; API budgets use the
+// API name.
+const ProfileScope = "profile"
+
+// ExceededError is returned for calls over a budget with on_exceed reject.
+type ExceededError struct {
+	Scope    string    `json:"scope"`
+	Period   string    `json:"period"` // daily or monthly
+	Limit    int       `json:"limit"`
+	ResetsAt time.Time `json:"resets_at"`
+}
+
+func (e *ExceededError) Error() string {
+	owner := "profile"
+	if e.Scope != ProfileScope {
+		owner = "API " + e.Scope
+	}
+	return fmt.Sprintf("%s quota of %d calls for %s exceeded; resets at %s", e.Period, e.Limit, owner, e.ResetsAt.Format(time.RFC3339))
+}
+
+// Usage reports one budget.
+type Usage struct {
+	Scope     string    `json:"scope"`
+	Period    string    `json:"period"`
+	Limit     int       `json:"limit"`
+	Used      int64     `json:"used"`
+	Remaining int64     `json:"remaining"`
+	ResetsAt  time.Time `json:"resets_at"`
+	OnExceed  string    `json:"on_exceed"`
+}
+
+type budget struct {
+	scope    string
+	daily    int
+	monthly  int
+	onExceed string
+}
+
+// Tracker checks and counts calls against the budgets of one profile.
+type Tracker struct {
+	mu      sync.RWMutex
+	store   Store
+	profile string
+	budgets map[string]budget // by scope
+	logger  *slog.Logger
+	now     func() time.Time
+}
+
+// New returns a Tracker for the budgets in cfg, counting in memory until
+// UseStore is called, or nil when cfg has no budgets.
+func New(cfg *config.Config, logger *slog.Logger) *Tracker {
+	budgets := map[string]budget{}
+	add := func(scope string, q *config.QuotaConfig) {
+		if q == nil || (q.Daily == 0 && q.Monthly == 0) {
+			return
+		}
+		onExceed := q.OnExceed
+		if onExceed == "" {
+			onExceed = "reject"
+		}
+		budgets[scope] = budget{scope: scope, daily: q.Daily, monthly: q.Monthly, onExceed: onExceed}
+	}
+	add(ProfileScope, cfg.Quota)
+	for _, api := range cfg.APIs {
+		add(api.Name, api.Quota)
+	}
+	if len(budgets) == 0 {
+		return nil
+	}
+	return &Tracker{store: NewMemoryStore(), budgets: budgets, logger: logger, now: time.Now}
+}
+
+// UseStore moves counting to store under the given profile name.
+func (t *Tracker) UseStore(store Store, profile string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.store = store
+	t.profile = profile
+}
+
+type period struct {
+	name     string
+	key      string
+	limit    int
+	resetsAt time.Time
+}
+
+func periods(b budget, now time.Time) []period {
+	now = now.UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	var out []period
+	if b.daily > 0 {
+		out = append(out, period{"daily", day.Format("2006-01-02"), b.daily, day.AddDate(0, 0, 1)})
+	}
+	if b.monthly > 0 {
+		out = append(out, period{"monthly", month.Format("2006-01"), b.monthly, month.AddDate(0, 1, 0)})
+	}
+	return out
+}
+
+type counted struct {
+	scope string
+	key   string
+}
+
+// Reserve counts one call to api against the profile's and the API's
+// budgets. Over a rejecting budget the call is not counted and an
+// *ExceededError is returned; over a warning budget it is counted and
+// logged. Store failures are logged and let the call through, so an
+// unavailable store never blocks upstream calls.
+func (t *Tracker) Reserve(ctx context.Context, api string) error {
+	t.mu.RLock()
+	store, profile := t.store, t.profile
+	t.mu.RUnlock()
+
+	var done []counted
+	var exceeded *ExceededError
+	now := t.now()
+	for _, scope := range []string{ProfileScope, api} {
+		b, ok := t.budgets[scope]
+		if !ok {
+			continue
+		}
+		for _, p := range periods(b, now) {
+			used, err := store.AddQuotaUsage(ctx, profile, scope, p.key, 1)
+			if err != nil {
+				t.logger.Warn("quota store unavailable; call not counted", "component", "quota", "scope", scope, "error", err)
+				continue
+			}
+			done = append(done, counted{scope, p.key})
+			if used <= int64(p.limit) {
+				continue
+			}
+			if b.onExceed == "warn" {
+				t.logger.Warn("quota exceeded", "component", "quota", "profile", profile, "scope", scope, "period", p.name, "limit", p.limit, "used", used)
+				continue
+			}
+			if exceeded == nil {
+				exceeded = &ExceededError{Scope: scope, Period: p.name, Limit: p.limit, ResetsAt: p.resetsAt}
+			}
+		}
+	}
+	if exceeded == nil {
+		return nil
+	}
+	// Rejected calls do not use up budget.
+	for _, c := range done {
+		_, _ = store.AddQuotaUsage(ctx, profile, c.scope, c.key, -1)
+	}
+	return exceeded
+}
+
+// Usage reports every budget with its current usage, the profile's first
+// and then the APIs' by name.
+func (t *Tracker) Usage(ctx context.Context) ([]Usage, error) {
+	t.mu.RLock()
+	store, profile := t.store, t.profile
+	t.mu.RUnlock()
+
+	scopes := make([]string, 0, len(t.budgets))
+	for scope := range t.budgets {
+		if scope != ProfileScope {
+			scopes = append(scopes, scope)
+		}
+	}
+	sort.Strings(scopes)
+	if _, ok := t.budgets[ProfileScope]; ok {
+		scopes = append([]string{ProfileScope}, scopes...)
+	}
+
+	now := t.now()
+	out := []Usage{}
+	for _, scope := range scopes {
+		b := t.budgets[scope]
+		for _, p := range periods(b, now) {
+			used, err := store.AddQuotaUsage(ctx, profile, scope, p.key, 0)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, Usage{
+				Scope:     scope,
+				Period:    p.name,
+				Limit:     p.limit,
+				Used:      used,
+				Remaining: max(int64(p.limit)-used, 0),
+				ResetsAt:  p.resetsAt,
+				OnExceed:  b.onExceed,
+			})
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,98 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/logging"
+)
+
+func TestNewWithoutBudgets(t *testing.T) {
+	cfg := &config.Config{APIs: []config.APIConfig{{Name: "github"}}}
+	if New(cfg, logging.Discard()) != nil {
+		t.Fatal("expected no tracker without budgets")
+	}
+}
+
+func TestReserveRejectsOverBudget(t *testing.T) {
+	cfg := &config.Config{
+		Quota: &config.QuotaConfig{Monthly: 10},
+		APIs: []config.APIConfig{
+			{Name: "github", Quota: &config.QuotaConfig{Daily: 2}},
+			{Name: "slack"},
+		},
+	}
+	tr := New(cfg, logging.Discard())
+	now := time.Date(2026, 3, 31, 23, 0, 0, 0, time.UTC)
+	tr.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := tr.Reserve(ctx, "github"); err != nil {
+			t.Fatalf("call %d: %v", i+1, err)
+		}
+	}
+	err := tr.Reserve(ctx, "github")
+	var exceeded *ExceededError
+	if !errors.As(err, &exceeded) {
+		t.Fatalf("err = %v, want *ExceededError", err)
+	}
+	if exceeded.Scope != "github" || exceeded.Period != "daily" || exceeded.Limit != 2 || !exceeded.ResetsAt.Equal(time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected error: %+v", exceeded)
+	}
+	// Other APIs only count against the profile budget.
+	if err := tr.Reserve(ctx, "slack"); err != nil {
+		t.Fatalf("slack call rejected: %v", err)
+	}
+
+	usage, err := tr.Usage(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(usage) != 2 || usage[0].Scope != ProfileScope || usage[1].Scope != "github" {
+		t.Fatalf("unexpected usage: %+v", usage)
+	}
+	// The rejected call was not counted.
+	if usage[0].Used != 3 || usage[0].Remaining != 7 || usage[1].Used != 2 || usage[1].Remaining != 0 {
+		t.Fatalf("unexpected counts: %+v", usage)
+	}
+
+	// A new day resets the daily budget but not the monthly one.
+	now = now.Add(2 * time.Hour)
+	if err := tr.Reserve(ctx, "github"); err != nil {
+		t.Fatalf("call after reset: %v", err)
+	}
+}
+
+func TestReserveWarnAllowsCalls(t *testing.T) {
+	cfg := &config.Config{Quota: &config.QuotaConfig{Daily: 1, OnExceed: "warn"}}
+	tr := New(cfg, logging.Discard())
+	for i := 0; i < 3; i++ {
+		if err := tr.Reserve(context.Background(), "github"); err != nil {
+			t.Fatalf("warn budget rejected call %d: %v", i+1, err)
+		}
+	}
+	usage, _ := tr.Usage(context.Background())
+	if usage[0].Used != 3 || usage[0].Remaining != 0 || usage[0].OnExceed != "warn" {
+		t.Fatalf("unexpected usage: %+v", usage)
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) AddQuotaUsage(context.Context, string, string, string, int64) (int64, error) {
+	return 0, errors.New("database locked")
+}
+
+func TestReserveFailsOpen(t *testing.T) {
+	tr := New(&config.Config{Quota: &config.QuotaConfig{Daily: 1}}, logging.Discard())
+	tr.UseStore(failingStore{}, "default")
+	for i := 0; i < 2; i++ {
+		if err := tr.Reserve(context.Background(), "github"); err != nil {
+			t.Fatalf("store failure should not reject: %v", err)
+		}
+	}
+}
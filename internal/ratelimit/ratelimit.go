@@ -5,6 +5,7 @@ package ratelimit
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -32,6 +33,10 @@ type Limiter struct {
 	// Fixed window state (per-day)
 	dayCount int
 	dayStart time.Time
+
+	// Shared mode (see Share); nil counter means local-only.
+	counter   Counter
+	sharedKey string
 }
 
 // New creates a rate limiter with the given per-minute, per-hour, and per-day limits.
@@ -81,7 +86,7 @@ func (l *Limiter) Wait(ctx context.Context) error {
 	}
 
 	for {
-		retryAfter, err := l.tryAcquire()
+		retryAfter, err := l.acquire(ctx)
 		if err != nil {
 			return err // hourly/daily quota exhausted — don't wait
 		}
@@ -98,6 +103,21 @@ func (l *Limiter) Wait(ctx context.Context) error {
 	}
 }
 
+// Allow takes a token without waiting. It returns 0 when the request is
+// allowed, or how long the caller should wait before trying again. Allow
+// ignores Share: it counts only this replica's requests.
+func (l *Limiter) Allow() time.Duration {
+	if l.rpm == 0 && l.rph == 0 && l.rpd == 0 {
+		return 0
+	}
+	retryAfter, err := l.tryAcquire()
+	var limited *ErrRateLimited
+	if errors.As(err, &limited) {
+		return limited.RetryAfter
+	}
+	return retryAfter
+}
+
 // tryAcquire attempts to take a token. Returns (0, nil) on success,
 // (retryAfter, nil) if the per-minute bucket is empty but can be waited on,
 // or (0, ErrRateLimited) if the per-hour or per-day quota is exhausted.
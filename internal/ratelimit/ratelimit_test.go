@@ -3,6 +3,7 @@ package ratelimit
 import (
 	"context"
 	"errors"
+	"sync"
 	"testing"
 	"time"
 )
@@ -317,3 +318,75 @@ func containsStr(s, sub string) bool {
 	}
 	return false
 }
+
+// memCounter is an in-memory Counter; failing makes every Incr return an error.
+type memCounter struct {
+	mu      sync.Mutex
+	counts  map[string]int64
+	failing bool
+}
+
+func (m *memCounter) Incr(_ context.Context, key string, _ time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.failing {
+		return 0, errors.New("store down")
+	}
+	if m.counts == nil {
+		m.counts = map[string]int64{}
+	}
+	m.counts[key]++
+	return m.counts[key], nil
+}
+
+func TestSharedLimiterAcrossReplicas(t *testing.T) {
+	counter := &memCounter{}
+	a := New(0, 3, 0)
+	b := New(0, 3, 0)
+	a.Share(counter, "profile:api")
+	b.Share(counter, "profile:api")
+
+	ctx := context.Background()
+	for i, l := range []*Limiter{a, b, a} {
+		if err := l.Wait(ctx); err != nil {
+			t.Fatalf("request %d should be allowed: %v", i+1, err)
+		}
+	}
+	// Each replica has only used 1-2 of its local quota, but the shared hour is spent.
+	var rlErr *ErrRateLimited
+	if err := b.Wait(ctx); !errors.As(err, &rlErr) || rlErr.Tier != "rph" {
+		t.Fatalf("expected shared rph limit, got %v", err)
+	}
+}
+
+func TestSharedLimiterFallsBackToLocal(t *testing.T) {
+	counter := &memCounter{failing: true}
+	l := New(0, 1, 0)
+	l.Share(counter, "profile:api")
+
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatalf("first request should fall back to local limiter: %v", err)
+	}
+	var rlErr *ErrRateLimited
+	if err := l.Wait(context.Background()); !errors.As(err, &rlErr) {
+		t.Fatalf("local limit should still apply, got %v", err)
+	}
+}
+
+func TestAllowDoesNotBlock(t *testing.T) {
+	l := New(2, 0, 0)
+	for i := 0; i < 2; i++ {
+		if wait := l.Allow(); wait != 0 {
+			t.Fatalf("request %d should be allowed, got wait %s", i+1, wait)
+		}
+	}
+	if wait := l.Allow(); wait <= 0 || wait > 30*time.Second {
+		t.Fatalf("request beyond burst: wait %s, want a refill delay", wait)
+	}
+
+	hourly := New(0, 1, 0)
+	hourly.Allow()
+	if wait := hourly.Allow(); wait <= 0 || wait > time.Hour {
+		t.Fatalf("request beyond the hourly quota: wait %s", wait)
+	}
+}
@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Counter is a fixed-window counter store shared between replicas (e.g.
+// Redis). Incr increments key and returns the new value; the key must expire
+// ttl after it was created.
+type Counter interface {
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+}
+
+// Share makes the limiter enforce its limits together with every other
+// limiter sharing the same counter and key. In shared mode all tiers use
+// fixed windows (the per-minute token bucket cannot be shared cheaply). If
+// the counter is unreachable the limiter falls back to its local state so an
+// outage of the shared store never blocks upstream calls.
+func (l *Limiter) Share(counter Counter, key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.counter = counter
+	l.sharedKey = key
+}
+
+// acquire takes a slot from the shared counter when the limiter is shared,
+// falling back to local state otherwise or if the counter is unavailable.
+func (l *Limiter) acquire(ctx context.Context) (time.Duration, error) {
+	l.mu.Lock()
+	counter, key := l.counter, l.sharedKey
+	l.mu.Unlock()
+	if counter != nil {
+		if retryAfter, ok, err := l.sharedAcquire(ctx, counter, key); ok {
+			return retryAfter, err
+		}
+	}
+	return l.tryAcquire()
+}
+
+// sharedAcquire is tryAcquire for shared mode. ok is false when the counter
+// store failed and the caller should fall back to local limiting.
+func (l *Limiter) sharedAcquire(ctx context.Context, counter Counter, key string) (retryAfter time.Duration, ok bool, err error) {
+	now := time.Now().UTC() // UTC so every replica agrees on window boundaries
+
+	// Per-minute first: it is the only tier worth waiting on, and checking it
+	// first avoids charging hour/day quotas for requests that then wait.
+	if l.rpm > 0 {
+		start := now.Truncate(time.Minute)
+		n, cerr := counter.Incr(ctx, fmt.Sprintf("%s:rpm:%d", key, start.Unix()), time.Minute)
+		if cerr != nil {
+			return 0, false, nil
+		}
+		if n > int64(l.rpm) {
+			return start.Add(time.Minute).Sub(now), true, nil
+		}
+	}
+	if l.rph > 0 {
+		start := now.Truncate(time.Hour)
+		n, cerr := counter.Incr(ctx, fmt.Sprintf("%s:rph:%d", key, start.Unix()), time.Hour)
+		if cerr != nil {
+			return 0, false, nil
+		}
+		if n > int64(l.rph) {
+			return 0, true, &ErrRateLimited{Tier: "rph", Limit: l.rph, RetryAfter: start.Add(time.Hour).Sub(now)}
+		}
+	}
+	if l.rpd > 0 {
+		start := truncateToDay(now)
+		n, cerr := counter.Incr(ctx, fmt.Sprintf("%s:rpd:%d", key, start.Unix()), 24*time.Hour)
+		if cerr != nil {
+			return 0, false, nil
+		}
+		if n > int64(l.rpd) {
+			return 0, true, &ErrRateLimited{Tier: "rpd", Limit: l.rpd, RetryAfter: start.Add(24 * time.Hour).Sub(now)}
+		}
+	}
+	return 0, true, nil
+}
@@ -1,12 +1,55 @@
 package redact
 
 import (
+	"encoding/json"
+	"fmt"
+	"regexp"
 	"strings"
+	"sync"
 )
 
-// Redactor replaces configured secrets in strings.
+// Placeholder replaces redacted values.
+const Placeholder = "[REDACTED]"
+
+// Redactor replaces configured secrets, pattern matches and JSON fields in
+// strings and values.
 type Redactor struct {
-	secrets []string
+	mu       sync.RWMutex
+	secrets  []string
+	patterns []pattern
+	fields   [][]segment
+	apis     map[string]*Redactor
+	parent   *Redactor // set on per-API redactors
+}
+
+// Pattern is a regular expression whose matches are redacted. A pattern
+// with only a Name refers to a built-in pattern.
+type Pattern struct {
+	Name        string
+	Regex       string
+	Replacement string // defaults to [REDACTED]
+}
+
+// Rules are redaction rules beyond literal secrets.
+type Rules struct {
+	Patterns []Pattern
+	// Fields are JSON paths whose values are replaced wholesale, e.g.
+	// "$.card.number", "$.items[*].ssn" or "$..password". A bare name is
+	// matched at any depth. Names match case-insensitively.
+	Fields []string
+}
+
+// builtinPatterns can be referenced by name in rules.
+var builtinPatterns = map[string]string{
+	"credit_card": `\b(?:\d[ -]?){12,18}\d\b`,
+	"email":       `[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`,
+	"jwt":         `\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`,
+}
+
+type pattern struct {
+	name        string
+	re          *regexp.Regexp
+	replacement string
 }
 
 func NewRedactor() *Redactor {
@@ -14,6 +57,8 @@ func NewRedactor() *Redactor {
 }
 
 func (r *Redactor) AddSecrets(secrets []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	for _, s := range secrets {
 		if s == "" {
 			continue
@@ -22,13 +67,300 @@ func (r *Redactor) AddSecrets(secrets []string) {
 	}
 }
 
+// AddRules compiles rules and applies them to everything r redacts.
+func (r *Redactor) AddRules(rules Rules) error {
+	patterns, fields, err := compileRules(rules)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.patterns = append(r.patterns, patterns...)
+	r.fields = append(r.fields, fields...)
+	return nil
+}
+
+// AddAPIRules adds rules that apply, on top of r's own, only to what is
+// redacted through ForAPI(api).
+func (r *Redactor) AddAPIRules(api string, rules Rules) error {
+	patterns, fields, err := compileRules(rules)
+	if err != nil {
+		return fmt.Errorf("api %s: %w", api, err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.apis == nil {
+		r.apis = map[string]*Redactor{}
+	}
+	sub, ok := r.apis[api]
+	if !ok {
+		sub = &Redactor{parent: r}
+		r.apis[api] = sub
+	}
+	sub.patterns = append(sub.patterns, patterns...)
+	sub.fields = append(sub.fields, fields...)
+	return nil
+}
+
+// ForAPI returns the redactor for values of the named API: r itself unless
+// the API has rules of its own.
+func (r *Redactor) ForAPI(api string) *Redactor {
+	if r.parent != nil {
+		return r
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if sub, ok := r.apis[api]; ok {
+		return sub
+	}
+	return r
+}
+
 func (r *Redactor) Redact(input string) string {
+	root := r
+	if r.parent != nil {
+		root = r.parent
+	}
+	root.mu.RLock()
 	out := input
-	for _, secret := range r.secrets {
+	for _, secret := range root.secrets {
 		if secret == "" {
 			continue
 		}
-		out = strings.ReplaceAll(out, secret, "[REDACTED]")
+		out = strings.ReplaceAll(out, secret, Placeholder)
+	}
+	out = applyPatterns(out, root.patterns)
+	if root != r {
+		out = applyPatterns(out, r.patterns) // guarded by the root's lock
 	}
+	root.mu.RUnlock()
 	return out
 }
+
+// RedactValue returns a copy of a JSON-compatible value with configured
+// fields replaced and every string redacted. It returns nil for values that
+// cannot be encoded as JSON.
+func (r *Redactor) RedactValue(v any) any {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var out any
+	if json.Unmarshal(data, &out) != nil {
+		return nil
+	}
+	for _, path := range r.allFields() {
+		scrub(out, path)
+	}
+	return r.redactStrings(out)
+}
+
+// RedactJSON redacts a body that may be JSON: configured fields are
+// scrubbed when it parses, and the text is redacted either way.
+func (r *Redactor) RedactJSON(body string) string {
+	if len(r.allFields()) > 0 {
+		var v any
+		if json.Unmarshal([]byte(body), &v) == nil {
+			if data, err := json.Marshal(r.RedactValue(v)); err == nil {
+				return string(data)
+			}
+		}
+	}
+	return r.Redact(body)
+}
+
+func (r *Redactor) allFields() [][]segment {
+	if r.parent == nil {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		return r.fields
+	}
+	r.parent.mu.RLock()
+	defer r.parent.mu.RUnlock()
+	return append(append([][]segment(nil), r.parent.fields...), r.fields...)
+}
+
+func (r *Redactor) redactStrings(v any) any {
+	switch node := v.(type) {
+	case string:
+		return r.Redact(node)
+	case map[string]any:
+		for k, child := range node {
+			node[k] = r.redactStrings(child)
+		}
+	case []any:
+		for i, child := range node {
+			node[i] = r.redactStrings(child)
+		}
+	}
+	return v
+}
+
+func applyPatterns(s string, patterns []pattern) string {
+	for _, p := range patterns {
+		if p.name == "credit_card" {
+			s = p.re.ReplaceAllStringFunc(s, func(m string) string {
+				if luhnValid(m) {
+					return p.replacement
+				}
+				return m
+			})
+			continue
+		}
+		s = p.re.ReplaceAllString(s, p.replacement)
+	}
+	return s
+}
+
+// luhnValid reports whether the digits in s pass the Luhn check, so
+// order numbers and timestamps are not mistaken for card numbers.
+func luhnValid(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && sum%10 == 0
+}
+
+func compileRules(rules Rules) ([]pattern, [][]segment, error) {
+	patterns := make([]pattern, 0, len(rules.Patterns))
+	for i, p := range rules.Patterns {
+		expr := p.Regex
+		if expr == "" {
+			builtin, ok := builtinPatterns[p.Name]
+			if !ok {
+				return nil, nil, fmt.Errorf("patterns[%d]: regex is required (built-in patterns: credit_card, email, jwt)", i)
+			}
+			expr = builtin
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("patterns[%d]: %w", i, err)
+		}
+		replacement := p.Replacement
+		if replacement == "" {
+			replacement = Placeholder
+		}
+		name := p.Name
+		if p.Regex != "" {
+			name = "" // a custom regex never gets the Luhn check
+		}
+		patterns = append(patterns, pattern{name: name, re: re, replacement: replacement})
+	}
+	fields := make([][]segment, 0, len(rules.Fields))
+	for i, f := range rules.Fields {
+		path, err := parsePath(f)
+		if err != nil {
+			return nil, nil, fmt.Errorf("fields[%d]: %w", i, err)
+		}
+		fields = append(fields, path)
+	}
+	return patterns, fields, nil
+}
+
+// segment is one step of a JSON path: a member name ("*" for any), or
+// any array element. recursive segments match at any depth below.
+type segment struct {
+	name      string
+	index     bool
+	recursive bool
+}
+
+// parsePath parses the JSON path subset used for field rules: $.a.b,
+// $.a[*].b, $..b, $.a.* and bare names, which mean $..name.
+func parsePath(path string) ([]segment, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return nil, fmt.Errorf("empty path")
+	}
+	if !strings.HasPrefix(path, "$") {
+		if strings.ContainsAny(path, ".[]") {
+			return nil, fmt.Errorf("path %q must start with $", path)
+		}
+		return []segment{{name: path, recursive: true}}, nil
+	}
+	rest := path[1:]
+	var segs []segment
+	for rest != "" {
+		var seg segment
+		switch {
+		case strings.HasPrefix(rest, "[*]"):
+			seg.index = true
+			rest = rest[3:]
+			segs = append(segs, seg)
+			continue
+		case strings.HasPrefix(rest, ".."):
+			seg.recursive = true
+			rest = rest[2:]
+		case strings.HasPrefix(rest, "."):
+			rest = rest[1:]
+		default:
+			return nil, fmt.Errorf("path %q: unexpected %q", path, rest)
+		}
+		end := strings.IndexAny(rest, ".[")
+		if end < 0 {
+			end = len(rest)
+		}
+		seg.name = rest[:end]
+		if seg.name == "" {
+			return nil, fmt.Errorf("path %q: empty member name", path)
+		}
+		rest = rest[end:]
+		segs = append(segs, seg)
+	}
+	if len(segs) == 0 {
+		return nil, fmt.Errorf("path %q selects the whole document", path)
+	}
+	return segs, nil
+}
+
+// scrub replaces the values selected by path in v, which is modified in
+// place. Arrays are looked through when a member name is expected.
+func scrub(v any, path []segment) {
+	seg := path[0]
+	switch node := v.(type) {
+	case map[string]any:
+		if seg.index {
+			return
+		}
+		for k, child := range node {
+			if seg.name == "*" || strings.EqualFold(seg.name, k) {
+				if len(path) == 1 {
+					node[k] = Placeholder
+					continue
+				}
+				scrub(child, path[1:])
+			}
+			if seg.recursive {
+				scrub(child, path)
+			}
+		}
+	case []any:
+		for i, child := range node {
+			if seg.index {
+				if len(path) == 1 {
+					node[i] = Placeholder
+					continue
+				}
+				scrub(child, path[1:])
+				continue
+			}
+			scrub(child, path)
+		}
+	}
+}
@@ -15,3 +15,86 @@ func TestRedact(t *testing.T) {
 		t.Fatalf("unexpected redaction: %s", got)
 	}
 }
+
+func TestRedactPatterns(t *testing.T) {
+	redactor := NewRedactor()
+	if err := redactor.AddRules(Rules{Patterns: []Pattern{
+		{Name: "credit_card"},
+		{Name: "email"},
+		{Name: "iban", Regex: `\bDE\d{20}\b`, Replacement: "[IBAN]"},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	got := redactor.Redact("card 4111 1111 1111 1111, order 1234567890123, mail jo@example.com, DE89370400440532013000")
+	want := "card [REDACTED], order 1234567890123, mail [REDACTED], [IBAN]"
+	if got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	if err := redactor.AddRules(Rules{Patterns: []Pattern{{Name: "phone"}}}); err == nil {
+		t.Fatal("expected an error for an unknown built-in pattern")
+	}
+	if err := redactor.AddRules(Rules{Patterns: []Pattern{{Regex: "("}}}); err == nil {
+		t.Fatal("expected an error for an invalid regex")
+	}
+}
+
+func TestRedactValueFields(t *testing.T) {
+	redactor := NewRedactor()
+	redactor.AddSecrets([]string{"tok-123"})
+	if err := redactor.AddRules(Rules{Fields: []string{"password", "$.card.number", "$.items[*].ssn"}}); err != nil {
+		t.Fatal(err)
+	}
+	in := map[string]any{
+		"user":  map[string]any{"Password": "hunter2", "name": "jo"},
+		"card":  map[string]any{"number": "4111", "brand": "visa"},
+		"items": []any{map[string]any{"ssn": "123-45-6789", "id": 1}},
+		"note":  "uses tok-123",
+	}
+	got := redactor.RedactValue(in).(map[string]any)
+	if got["user"].(map[string]any)["Password"] != Placeholder || got["user"].(map[string]any)["name"] != "jo" {
+		t.Fatalf("user = %v", got["user"])
+	}
+	if got["card"].(map[string]any)["number"] != Placeholder || got["card"].(map[string]any)["brand"] != "visa" {
+		t.Fatalf("card = %v", got["card"])
+	}
+	if got["items"].([]any)[0].(map[string]any)["ssn"] != Placeholder {
+		t.Fatalf("items = %v", got["items"])
+	}
+	if got["note"] != "uses [REDACTED]" {
+		t.Fatalf("note = %v", got["note"])
+	}
+	if in["card"].(map[string]any)["number"] != "4111" {
+		t.Fatal("input was modified")
+	}
+	if got := redactor.RedactJSON(`{"password":"x","ok":true}`); got != `{"ok":true,"password":"[REDACTED]"}` {
+		t.Fatalf("RedactJSON = %s", got)
+	}
+
+	for _, bad := range []string{"", "a.b", "$", "$.a..", "$x"} {
+		if err := redactor.AddRules(Rules{Fields: []string{bad}}); err == nil {
+			t.Fatalf("expected an error for path %q", bad)
+		}
+	}
+}
+
+func TestRedactForAPI(t *testing.T) {
+	redactor := NewRedactor()
+	redactor.AddSecrets([]string{"tok-123"})
+	if err := redactor.AddAPIRules("crm", Rules{Patterns: []Pattern{{Name: "email"}}, Fields: []string{"dob"}}); err != nil {
+		t.Fatal(err)
+	}
+	if redactor.ForAPI("billing") != redactor {
+		t.Fatal("APIs without rules should share the root redactor")
+	}
+	crm := redactor.ForAPI("crm")
+	if got := crm.Redact("tok-123 jo@example.com"); got != "[REDACTED] [REDACTED]" {
+		t.Fatalf("crm redact = %q", got)
+	}
+	if got := redactor.Redact("jo@example.com"); got != "jo@example.com" {
+		t.Fatalf("root redact = %q", got)
+	}
+	if got := crm.RedactValue(map[string]any{"dob": "1990-01-01"}).(map[string]any); got["dob"] != Placeholder {
+		t.Fatalf("crm value = %v", got)
+	}
+}
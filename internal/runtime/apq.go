@@ -0,0 +1,65 @@
+package runtime
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// apqBodies turns a GraphQL request body into an Automatic Persisted Query:
+// hashOnly carries the query's SHA-256 hash instead of the query, and full
+// carries both so the server can register the hash when it doesn't know it.
+func apqBodies(body []byte) (hashOnly, full []byte, err error) {
+	var payload map[string]any
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, nil, fmt.Errorf("build persisted query: %w", err)
+	}
+	query, _ := payload["query"].(string)
+	sum := sha256.Sum256([]byte(query))
+	payload["extensions"] = map[string]any{
+		"persistedQuery": map[string]any{"version": 1, "sha256Hash": hex.EncodeToString(sum[:])},
+	}
+	if full, err = json.Marshal(payload); err != nil {
+		return nil, nil, fmt.Errorf("build persisted query: %w", err)
+	}
+	delete(payload, "query")
+	if hashOnly, err = json.Marshal(payload); err != nil {
+		return nil, nil, fmt.Errorf("build persisted query: %w", err)
+	}
+	return hashOnly, full, nil
+}
+
+// persistedQueryMissing reports whether a GraphQL server answered a hash-only
+// request by asking for the full query: it has not seen the hash yet, or
+// does not support persisted queries at all. Servers report this with a 200
+// or a 400, so both results and upstream errors are checked.
+func persistedQueryMissing(result *Result, err error) bool {
+	var body any
+	var upErr *UpstreamError
+	switch {
+	case err == nil && result != nil:
+		body = result.Body
+	case errors.As(err, &upErr):
+		body = upErr.Upstream
+	default:
+		return false
+	}
+	payload, _ := body.(map[string]any)
+	gqlErrors, _ := payload["errors"].([]any)
+	for _, item := range gqlErrors {
+		entry, _ := item.(map[string]any)
+		if entry == nil {
+			continue
+		}
+		ext, _ := entry["extensions"].(map[string]any)
+		switch {
+		case entry["message"] == "PersistedQueryNotFound", entry["message"] == "PersistedQueryNotSupported":
+			return true
+		case ext["code"] == "PERSISTED_QUERY_NOT_FOUND", ext["code"] == "PERSISTED_QUERY_NOT_SUPPORTED":
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package runtime_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/logging"
+	"skyline-mcp/internal/redact"
+	"skyline-mcp/internal/runtime"
+)
+
+func TestExecutorGraphQLAutomaticPersistedQueries(t *testing.T) {
+	const document = "query Viewer($id: ID!) { user(id: $id) { name } }"
+	sum := sha256.Sum256([]byte(document))
+	wantHash := hex.EncodeToString(sum[:])
+
+	known := map[string]bool{}
+	var requests []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		requests = append(requests, payload)
+		ext, _ := payload["extensions"].(map[string]any)
+		pq, _ := ext["persistedQuery"].(map[string]any)
+		hash, _ := pq["sha256Hash"].(string)
+		w.Header().Set("Content-Type", "application/json")
+		if query, ok := payload["query"].(string); ok {
+			known[hash] = true
+			if query != document {
+				t.Errorf("query = %q", query)
+			}
+		} else if !known[hash] {
+			_, _ = w.Write([]byte(`{"errors":[{"message":"PersistedQueryNotFound","extensions":{"code":"PERSISTED_QUERY_NOT_FOUND"}}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"user":{"name":"Ada"}}}`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{APIs: []config.APIConfig{{
+		Name: "gql", SpecURL: server.URL, BaseURLOverride: server.URL,
+		GraphQL: &config.GraphQLConfig{APQ: true},
+	}}}
+	cfg.ApplyDefaults()
+	exec, err := runtime.NewExecutor(cfg, []*canonical.Service{{Name: "gql", BaseURL: server.URL}}, logging.Discard(), redact.NewRedactor())
+	if err != nil {
+		t.Fatalf("executor init failed: %v", err)
+	}
+	op := &canonical.Operation{
+		ServiceName: "gql", ToolName: "gql__viewer", Method: "post",
+		RequestBody: &canonical.RequestBody{Required: true, ContentType: "application/json"},
+		GraphQL: &canonical.GraphQLOperation{
+			OperationType: "query", FieldName: "user", ArgTypes: map[string]string{"id": "ID!"},
+			Document: document, OperationName: "Viewer",
+		},
+	}
+	for i := 0; i < 2; i++ {
+		result, err := exec.Execute(context.Background(), op, map[string]any{"id": "7"})
+		if err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+		if _, hasErrors := result.Body.(map[string]any)["errors"]; hasErrors {
+			t.Fatalf("call %d returned errors: %v", i, result.Body)
+		}
+	}
+
+	// Hash only, then hash with query, then hash only once the server knows it.
+	if len(requests) != 3 {
+		t.Fatalf("server got %d requests, want 3", len(requests))
+	}
+	for i, withQuery := range []bool{false, true, false} {
+		_, hasQuery := requests[i]["query"]
+		hash := requests[i]["extensions"].(map[string]any)["persistedQuery"].(map[string]any)["sha256Hash"]
+		if hasQuery != withQuery || hash != wantHash {
+			t.Fatalf("request %d: query sent %v, hash %v", i, hasQuery, hash)
+		}
+		if requests[i]["operationName"] != "Viewer" || requests[i]["variables"].(map[string]any)["id"] != "7" {
+			t.Fatalf("request %d: %v", i, requests[i])
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"skyline-mcp/internal/canonical"
+)
+
+// awsSigningName returns the SigV4 service name of a service loaded from an
+// AWS model, or "" for other services.
+func awsSigningName(svc *canonical.Service) string {
+	for _, op := range svc.Operations {
+		if op.AWS != nil {
+			return op.AWS.SigningName
+		}
+	}
+	return ""
+}
+
+// buildAWSBody builds the request body of an AWS model operation from its
+// arguments; nil when the request has none.
+func buildAWSBody(op *canonical.AWSOperation, args map[string]any) ([]byte, error) {
+	if op.Payload != "" {
+		value, ok := args[op.Payload]
+		if !ok || value == nil {
+			return nil, nil
+		}
+		if s, ok := value.(string); ok {
+			return []byte(s), nil
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("encode %s: %w", op.Payload, err)
+		}
+		return encoded, nil
+	}
+	if op.Input == nil {
+		return nil, nil
+	}
+	names := make([]string, 0, len(op.Input.Members))
+	for name := range op.Input.Members {
+		if v, ok := args[name]; ok && v != nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	switch op.Protocol {
+	case "query", "ec2":
+		values := url.Values{"Action": {op.Action}, "Version": {op.APIVersion}}
+		for _, name := range names {
+			member := op.Input.Members[name]
+			if err := addAWSQueryValue(values, member.Name, member.Shape, args[name], op.Protocol == "ec2"); err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+		}
+		return []byte(values.Encode()), nil
+	default:
+		if len(names) == 0 && op.Protocol == "rest-json" {
+			return nil, nil
+		}
+		body := make(map[string]any, len(names))
+		for _, name := range names {
+			body[name] = awsJSONValue(op.Input.Members[name].Shape, args[name])
+		}
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("encode request body: %w", err)
+		}
+		return encoded, nil
+	}
+}
+
+// awsJSONValue converts timestamps given as ISO 8601 strings to the Unix
+// seconds the JSON protocols expect.
+func awsJSONValue(shape *canonical.AWSShape, value any) any {
+	if shape == nil {
+		return value
+	}
+	switch shape.Type {
+	case "structure":
+		m, ok := value.(map[string]any)
+		if !ok {
+			return value
+		}
+		out := make(map[string]any, len(m))
+		for k, v := range m {
+			if member, ok := shape.Members[k]; ok {
+				v = awsJSONValue(member.Shape, v)
+			}
+			out[k] = v
+		}
+		return out
+	case "list":
+		items, ok := value.([]any)
+		if !ok {
+			return value
+		}
+		out := make([]any, len(items))
+		for i, item := range items {
+			out[i] = awsJSONValue(shape.Member, item)
+		}
+		return out
+	case "map":
+		m, ok := value.(map[string]any)
+		if !ok {
+			return value
+		}
+		out := make(map[string]any, len(m))
+		for k, v := range m {
+			out[k] = awsJSONValue(shape.Value, v)
+		}
+		return out
+	case "timestamp":
+		if s, ok := value.(string); ok {
+			if t, err := time.Parse(time.RFC3339, s); err == nil {
+				return float64(t.UnixMilli()) / 1000
+			}
+		}
+	}
+	return value
+}
+
+// addAWSQueryValue adds value to a query protocol request under prefix,
+// e.g. MetricDataQueries.member.1.Id. EC2 numbers list items without a
+// member element.
+func addAWSQueryValue(values url.Values, prefix string, shape *canonical.AWSShape, value any, ec2 bool) error {
+	switch shape.Type {
+	case "structure":
+		m, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s must be an object", prefix)
+		}
+		names := make([]string, 0, len(m))
+		for name := range m {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			member, ok := shape.Members[name]
+			if !ok {
+				return fmt.Errorf("%s has no member %s", prefix, name)
+			}
+			if m[name] == nil {
+				continue
+			}
+			if err := addAWSQueryValue(values, prefix+"."+member.Name, member.Shape, m[name], ec2); err != nil {
+				return err
+			}
+		}
+	case "list":
+		items, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("%s must be an array", prefix)
+		}
+		if len(items) == 0 {
+			if !ec2 {
+				values.Set(prefix, "")
+			}
+			return nil
+		}
+		listPrefix := prefix
+		switch {
+		case ec2:
+		case shape.Flattened:
+			if shape.MemberName != "" {
+				listPrefix = replaceLastSegment(prefix, shape.MemberName)
+			}
+		default:
+			name := shape.MemberName
+			if name == "" {
+				name = "member"
+			}
+			listPrefix = prefix + "." + name
+		}
+		for i, item := range items {
+			if err := addAWSQueryValue(values, fmt.Sprintf("%s.%d", listPrefix, i+1), shape.Member, item, ec2); err != nil {
+				return err
+			}
+		}
+	case "map":
+		m, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s must be an object", prefix)
+		}
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		entryPrefix := prefix + ".entry"
+		if shape.Flattened {
+			entryPrefix = prefix
+		}
+		keyName, valueName := shape.KeyName, shape.ValueName
+		if keyName == "" {
+			keyName = "key"
+		}
+		if valueName == "" {
+			valueName = "value"
+		}
+		for i, k := range keys {
+			entry := fmt.Sprintf("%s.%d", entryPrefix, i+1)
+			values.Set(entry+"."+keyName, k)
+			if err := addAWSQueryValue(values, entry+"."+valueName, shape.Value, m[k], ec2); err != nil {
+				return err
+			}
+		}
+	case "timestamp":
+		if n, ok := value.(float64); ok {
+			values.Set(prefix, time.UnixMilli(int64(n*1000)).UTC().Format(time.RFC3339))
+			return nil
+		}
+		values.Set(prefix, valueToString(value))
+	default:
+		values.Set(prefix, valueToString(value))
+	}
+	return nil
+}
+
+func replaceLastSegment(prefix, name string) string {
+	if i := strings.LastIndex(prefix, "."); i >= 0 {
+		return prefix[:i+1] + name
+	}
+	return name
+}
+
+// tryParseAWSXML turns the XML response of a rest-xml or query protocol
+// operation into JSON. Query responses are unwrapped from their
+// <Action>Result element.
+func tryParseAWSXML(result *Result, op *canonical.AWSOperation) *Result {
+	if result == nil {
+		return result
+	}
+	body, ok := result.Body.(string)
+	if !ok || !strings.HasPrefix(strings.TrimSpace(body), "<") {
+		return result
+	}
+	root, err := parseXMLTree(body)
+	if err != nil {
+		return result
+	}
+	var value any = map[string]any{root.name: buildNodeValue(root)}
+	if op.Protocol == "query" {
+		for _, child := range root.children {
+			if child.name == op.Action+"Result" {
+				value = buildBodyValue(child)
+				break
+			}
+		}
+	}
+	out := *result
+	out.ContentType = "application/json"
+	out.Body = value
+	return &out
+}
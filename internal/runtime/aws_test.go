@@ -0,0 +1,171 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/logging"
+	"skyline-mcp/internal/redact"
+)
+
+func TestAddAWSQueryValue(t *testing.T) {
+	str := &canonical.AWSShape{Type: "string"}
+	dimension := &canonical.AWSShape{Type: "structure", Members: map[string]canonical.AWSMember{
+		"Name": {Name: "Name", Shape: str}, "Value": {Name: "Value", Shape: str},
+	}}
+	input := map[string]canonical.AWSMember{
+		"Dimensions":  {Name: "Dimensions", Shape: &canonical.AWSShape{Type: "list", Member: dimension}},
+		"Tags":        {Name: "Tags", Shape: &canonical.AWSShape{Type: "map", Key: str, Value: str}},
+		"StartTime":   {Name: "StartTime", Shape: &canonical.AWSShape{Type: "timestamp"}},
+		"InstanceIds": {Name: "InstanceId", Shape: &canonical.AWSShape{Type: "list", Member: str, MemberName: "InstanceId"}},
+	}
+	args := map[string]any{
+		"Dimensions":  []any{map[string]any{"Name": "InstanceId", "Value": "i-1"}},
+		"Tags":        map[string]any{"team": "core", "env": "prod"},
+		"StartTime":   float64(1700000000),
+		"InstanceIds": []any{"i-1", "i-2"},
+	}
+
+	query, err := buildAWSBody(&canonical.AWSOperation{Protocol: "query", Action: "GetMetricStatistics", APIVersion: "2010-08-01",
+		Input: &canonical.AWSShape{Type: "structure", Members: input}}, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := url.ParseQuery(string(query))
+	want := url.Values{
+		"Action": {"GetMetricStatistics"}, "Version": {"2010-08-01"},
+		"Dimensions.member.1.Name": {"InstanceId"}, "Dimensions.member.1.Value": {"i-1"},
+		"Tags.entry.1.key": {"env"}, "Tags.entry.1.value": {"prod"},
+		"Tags.entry.2.key": {"team"}, "Tags.entry.2.value": {"core"},
+		"StartTime":               {"2023-11-14T22:13:20Z"},
+		"InstanceId.InstanceId.1": {"i-1"},
+		"InstanceId.InstanceId.2": {"i-2"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("query body =\n%v\nwant\n%v", got, want)
+	}
+
+	ec2, err := buildAWSBody(&canonical.AWSOperation{Protocol: "ec2", Action: "DescribeInstances", APIVersion: "2016-11-15",
+		Input: &canonical.AWSShape{Type: "structure", Members: input}}, map[string]any{"InstanceIds": []any{"i-1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(ec2) != "Action=DescribeInstances&InstanceId.1=i-1&Version=2016-11-15" {
+		t.Errorf("ec2 body = %s", ec2)
+	}
+
+	if _, err := buildAWSBody(&canonical.AWSOperation{Protocol: "query", Input: &canonical.AWSShape{Type: "structure", Members: input}},
+		map[string]any{"Dimensions": []any{map[string]any{"Nmae": "x"}}}); err == nil || !strings.Contains(err.Error(), "no member Nmae") {
+		t.Errorf("unknown member error = %v", err)
+	}
+}
+
+func TestExecuteAWSOperations(t *testing.T) {
+	type seen struct {
+		method, path, target, auth, body string
+	}
+	var requests []seen
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, seen{r.Method, r.URL.RequestURI(), r.Header.Get("X-Amz-Target"), r.Header.Get("Authorization"), string(body)})
+		switch {
+		case r.Header.Get("X-Amz-Target") != "":
+			w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+			_, _ = w.Write([]byte(`{"TableNames":["orders"]}`))
+		case r.Method == http.MethodPost:
+			w.Header().Set("Content-Type", "text/xml")
+			_, _ = w.Write([]byte(`<GetMetricDataResponse xmlns="http://monitoring.amazonaws.com/doc/2010-08-01/">
+  <GetMetricDataResult><MetricDataResults><member><Id>cpu</Id><StatusCode>Complete</StatusCode></member></MetricDataResults></GetMetricDataResult>
+  <ResponseMetadata><RequestId>abc</RequestId></ResponseMetadata>
+</GetMetricDataResponse>`))
+		default:
+			w.Header().Set("Content-Type", "application/xml")
+			_, _ = w.Write([]byte(`<ListBucketResult><Name>logs</Name><KeyCount>0</KeyCount></ListBucketResult>`))
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{APIs: []config.APIConfig{{
+		Name: "aws", SpecURL: server.URL + "/service-2.json", BaseURLOverride: server.URL,
+		Auth: &config.AuthConfig{Type: "aws-sigv4", AccessKeyID: "AKID", SecretAccessKey: "secret", Region: "eu-west-1"},
+	}}}
+	cfg.ApplyDefaults()
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	timestamp := &canonical.AWSShape{Type: "timestamp"}
+	query := &canonical.Operation{
+		ServiceName: "aws", ToolName: "aws__GetMetricData", Method: "post", Path: "/",
+		StaticHeaders: map[string]string{"Content-Type": "application/x-www-form-urlencoded; charset=utf-8"},
+		AWS: &canonical.AWSOperation{Protocol: "query", Action: "GetMetricData", APIVersion: "2010-08-01", SigningName: "monitoring",
+			Input: &canonical.AWSShape{Type: "structure", Members: map[string]canonical.AWSMember{"StartTime": {Name: "StartTime", Shape: timestamp}}}},
+	}
+	jsonOp := &canonical.Operation{
+		ServiceName: "aws", ToolName: "aws__ListTables", Method: "post", Path: "/",
+		StaticHeaders: map[string]string{"Content-Type": "application/x-amz-json-1.0", "X-Amz-Target": "DynamoDB_20120810.ListTables"},
+		AWS: &canonical.AWSOperation{Protocol: "json", Action: "ListTables", SigningName: "monitoring",
+			Input: &canonical.AWSShape{Type: "structure", Members: map[string]canonical.AWSMember{"Since": {Name: "Since", Shape: timestamp}}}},
+	}
+	restXML := &canonical.Operation{
+		ServiceName: "aws", ToolName: "aws__ListObjectsV2", Method: "get", Path: "/{Bucket}/{Key}?list-type=2",
+		Parameters: []canonical.Parameter{
+			{Name: "Bucket", In: "path", Required: true},
+			{Name: "Key", In: "path", Required: true, SegmentSeparator: "/"},
+			{Name: "Prefix", In: "query", QueryName: "prefix"},
+			{Name: "ExpectedBucketOwner", In: "header", QueryName: "x-amz-expected-bucket-owner"},
+		},
+		AWS: &canonical.AWSOperation{Protocol: "rest-xml", Action: "ListObjectsV2", SigningName: "monitoring"},
+	}
+	exec, err := NewExecutor(cfg, []*canonical.Service{{Name: "aws", BaseURL: server.URL, Operations: []*canonical.Operation{query, jsonOp, restXML}}}, logging.Discard(), redact.NewRedactor())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := exec.Execute(context.Background(), query, map[string]any{"StartTime": "2024-05-01T00:00:00Z"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, _ := json.Marshal(res.Body)
+	if string(result) != `{"MetricDataResults":{"member":{"Id":"cpu","StatusCode":"Complete"}}}` {
+		t.Errorf("query result = %s", result)
+	}
+	if _, err := exec.Execute(context.Background(), jsonOp, map[string]any{"Since": "2024-05-01T00:00:00Z"}); err != nil {
+		t.Fatal(err)
+	}
+	res, err = exec.Execute(context.Background(), restXML, map[string]any{"Bucket": "logs", "Key": "2024/05/a b.log", "Prefix": "x", "ExpectedBucketOwner": "123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, _ = json.Marshal(res.Body)
+	if string(result) != `{"ListBucketResult":{"KeyCount":"0","Name":"logs"}}` {
+		t.Errorf("rest-xml result = %s", result)
+	}
+
+	if len(requests) != 3 {
+		t.Fatalf("requests = %d", len(requests))
+	}
+	if requests[0].body != "Action=GetMetricData&StartTime=2024-05-01T00%3A00%3A00Z&Version=2010-08-01" {
+		t.Errorf("query body = %s", requests[0].body)
+	}
+	if requests[1].target != "DynamoDB_20120810.ListTables" || requests[1].body != `{"Since":1714521600}` {
+		t.Errorf("json request = %+v", requests[1])
+	}
+	if requests[2].path != "/logs/2024/05/a%20b.log?list-type=2&prefix=x" {
+		t.Errorf("rest-xml path = %s", requests[2].path)
+	}
+	for _, r := range requests {
+		// The host names no service, so the model's signing name is used.
+		if !strings.Contains(r.auth, "/eu-west-1/monitoring/aws4_request") {
+			t.Errorf("Authorization = %q", r.auth)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/circuitbreaker"
+	"skyline-mcp/internal/quota"
+	"skyline-mcp/internal/ratelimit"
+)
+
+// BenchOptions configures a load test of one operation.
+type BenchOptions struct {
+	// Concurrency is the number of calls kept in flight; default 1.
+	Concurrency int
+	// Duration is how long new calls are started for.
+	Duration time.Duration
+	// Requests stops the run after this many calls; 0 runs for Duration.
+	Requests int
+}
+
+// BenchReport describes a load test: how many calls completed, how fast,
+// how they ended and what the API's rate limiter and circuit breaker did.
+type BenchReport struct {
+	Tool        string  `json:"tool"`
+	Concurrency int     `json:"concurrency"`
+	Seconds     float64 `json:"seconds"`
+	Requests    int64   `json:"requests"`
+	// Errors counts calls that failed or got a 4xx or 5xx status.
+	Errors     int64          `json:"errors"`
+	Throughput float64        `json:"requests_per_second"`
+	Latency    LatencySummary `json:"latency_ms"`
+	// Outcomes counts calls by HTTP status, or by why they failed:
+	// rate_limited, circuit_open, quota_exceeded, timeout or error.
+	Outcomes map[string]int64 `json:"outcomes"`
+	// ErrorSamples holds the first message of each failure outcome.
+	ErrorSamples map[string]string `json:"error_samples,omitempty"`
+	// Throttled counts calls that waited for the API's rate limiter, for
+	// ThrottledSeconds in total.
+	Throttled        int64   `json:"throttled"`
+	ThrottledSeconds float64 `json:"throttled_seconds"`
+	// BreakerTrips counts the times the API's circuit breaker opened
+	// during the run; BreakerState is its state at the end.
+	BreakerTrips int64  `json:"breaker_trips"`
+	BreakerState string `json:"breaker_state,omitempty"`
+}
+
+// LatencySummary holds call latencies in milliseconds, waits for the rate
+// limiter included.
+type LatencySummary struct {
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P90  float64 `json:"p90"`
+	P95  float64 `json:"p95"`
+	P99  float64 `json:"p99"`
+	Max  float64 `json:"max"`
+}
+
+// limiterWaitKey carries a *time.Duration that Execute adds the time spent
+// waiting for a rate limiter to.
+type limiterWaitKey struct{}
+
+// Bench calls op with args from opts.Concurrency goroutines until
+// opts.Duration has passed or opts.Requests calls were started, and reports
+// what happened. Calls take the full executor path, with rate limits,
+// circuit breakers, retries and quotas. Calls in flight when the time is up
+// are waited for; cancelling ctx stops them.
+func (e *Executor) Bench(ctx context.Context, op *canonical.Operation, args map[string]any, opts BenchOptions) *BenchReport {
+	concurrency := max(opts.Concurrency, 1)
+	breaker := e.breakers[op.ServiceName]
+	var tripsBefore int64
+	if breaker != nil {
+		tripsBefore = breaker.Stats().Trips
+	}
+
+	type sample struct {
+		latency time.Duration
+		waited  time.Duration
+		outcome string
+		failed  bool
+		message string
+	}
+	var (
+		mu      sync.Mutex
+		samples []sample
+		started atomic.Int64
+		wg      sync.WaitGroup
+	)
+	start := time.Now()
+	deadline := start.Add(opts.Duration)
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil && time.Now().Before(deadline) {
+				if opts.Requests > 0 && started.Add(1) > int64(opts.Requests) {
+					return
+				}
+				var waited time.Duration
+				callStart := time.Now()
+				result, err := e.Execute(context.WithValue(ctx, limiterWaitKey{}, &waited), op, args)
+				s := sample{latency: time.Since(callStart), waited: waited}
+				s.outcome, s.failed = benchOutcome(result, err)
+				if err != nil {
+					s.message = e.redactor.Redact(err.Error())
+				}
+				mu.Lock()
+				samples = append(samples, s)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	report := &BenchReport{
+		Tool:        op.ToolName,
+		Concurrency: concurrency,
+		Seconds:     elapsed.Seconds(),
+		Requests:    int64(len(samples)),
+		Outcomes:    map[string]int64{},
+	}
+	latencies := make([]time.Duration, 0, len(samples))
+	var total time.Duration
+	for _, s := range samples {
+		latencies = append(latencies, s.latency)
+		total += s.latency
+		report.Outcomes[s.outcome]++
+		if s.failed {
+			report.Errors++
+		}
+		if s.message != "" {
+			if report.ErrorSamples == nil {
+				report.ErrorSamples = map[string]string{}
+			}
+			if _, ok := report.ErrorSamples[s.outcome]; !ok {
+				report.ErrorSamples[s.outcome] = s.message
+			}
+		}
+		// Waits below a millisecond are the limiter's own bookkeeping.
+		if s.waited >= time.Millisecond {
+			report.Throttled++
+			report.ThrottledSeconds += s.waited.Seconds()
+		}
+	}
+	if elapsed > 0 {
+		report.Throughput = float64(len(samples)) / elapsed.Seconds()
+	}
+	if len(latencies) > 0 {
+		slices.Sort(latencies)
+		ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+		percentile := func(p float64) float64 {
+			return ms(latencies[int(p*float64(len(latencies)-1))])
+		}
+		report.Latency = LatencySummary{
+			Mean: ms(total / time.Duration(len(latencies))),
+			P50:  percentile(0.50),
+			P90:  percentile(0.90),
+			P95:  percentile(0.95),
+			P99:  percentile(0.99),
+			Max:  ms(latencies[len(latencies)-1]),
+		}
+	}
+	if breaker != nil {
+		stats := breaker.Stats()
+		report.BreakerTrips = stats.Trips - tripsBefore
+		report.BreakerState = stats.State
+	}
+	return report
+}
+
+// benchOutcome names how a call ended and whether it failed.
+func benchOutcome(result *Result, err error) (string, bool) {
+	var (
+		upErr     *UpstreamError
+		limited   *ratelimit.ErrRateLimited
+		open      *circuitbreaker.ErrCircuitOpen
+		exhausted *quota.ExceededError
+	)
+	switch {
+	case err == nil:
+		return strconv.Itoa(result.Status), result.Status >= 400
+	case errors.As(err, &upErr):
+		return strconv.Itoa(upErr.Status), true
+	case errors.As(err, &limited):
+		return "rate_limited", true
+	case errors.As(err, &open):
+		return "circuit_open", true
+	case errors.As(err, &exhausted):
+		return "quota_exceeded", true
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout", true
+	}
+	return "error", true
+}
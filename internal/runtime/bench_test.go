@@ -0,0 +1,56 @@
+package runtime
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/logging"
+	"skyline-mcp/internal/redact"
+)
+
+func TestBenchReportsLimiterAndBreaker(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	perHour := 8
+	cfg := &config.Config{APIs: []config.APIConfig{{
+		Name: "flaky", SpecURL: server.URL + "/openapi.json", BaseURLOverride: server.URL,
+		RateLimitRPH: &perHour,
+	}}}
+	cfg.ApplyDefaults()
+	exec, err := NewExecutor(cfg, []*canonical.Service{{Name: "flaky", BaseURL: server.URL}}, logging.Discard(), redact.NewRedactor())
+	if err != nil {
+		t.Fatal(err)
+	}
+	op := &canonical.Operation{ServiceName: "flaky", ID: "status", ToolName: "flaky__status", Method: "get", Path: "/status"}
+
+	report := exec.Bench(context.Background(), op, map[string]any{}, BenchOptions{Concurrency: 1, Duration: time.Minute, Requests: 12})
+	// Five failures open the breaker, which rejects the next calls until
+	// the hourly limit rejects them first.
+	want := map[string]int64{"503": 5, "circuit_open": 3, "rate_limited": 4}
+	for outcome, n := range want {
+		if report.Outcomes[outcome] != n {
+			t.Errorf("outcomes = %v, want %v", report.Outcomes, want)
+			break
+		}
+	}
+	if report.Requests != 12 || report.Errors != 12 {
+		t.Errorf("requests = %d, errors = %d, want 12 and 12", report.Requests, report.Errors)
+	}
+	if report.BreakerTrips != 1 || report.BreakerState != "open" {
+		t.Errorf("breaker trips = %d, state = %q", report.BreakerTrips, report.BreakerState)
+	}
+	if report.ErrorSamples["circuit_open"] == "" {
+		t.Errorf("no error sample for circuit_open: %v", report.ErrorSamples)
+	}
+	if report.Latency.Max < report.Latency.P50 || report.Throughput <= 0 {
+		t.Errorf("latency = %+v, throughput = %v", report.Latency, report.Throughput)
+	}
+}
@@ -0,0 +1,155 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"skyline-mcp/internal/canonical"
+)
+
+var bodyPlaceholderRe = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// renderBodyTemplate fills the {{name}} placeholders of a raw body template
+// from args. In JSON bodies a placeholder that is a whole string ("{{x}}")
+// or a bare value ({{x}}) takes the argument's JSON encoding, so numbers,
+// booleans and objects keep their type; inside a longer string the value is
+// string-escaped. Form bodies get URL-encoded values and anything else the
+// plain text. Missing arguments render as null in JSON and as empty text
+// elsewhere.
+func renderBodyTemplate(contentType, tmpl string, args map[string]any) ([]byte, error) {
+	switch {
+	case strings.Contains(contentType, "json"):
+		return renderJSONTemplate(tmpl, args)
+	case strings.Contains(contentType, "x-www-form-urlencoded"):
+		return []byte(bodyPlaceholderRe.ReplaceAllStringFunc(tmpl, func(m string) string {
+			return url.QueryEscape(templateText(args, placeholderName(m)))
+		})), nil
+	}
+	return []byte(bodyPlaceholderRe.ReplaceAllStringFunc(tmpl, func(m string) string {
+		return templateText(args, placeholderName(m))
+	})), nil
+}
+
+func renderJSONTemplate(tmpl string, args map[string]any) ([]byte, error) {
+	var out strings.Builder
+	inString := false
+	stringStart := 0 // output offset just after the current string's opening quote
+	for i := 0; i < len(tmpl); {
+		c := tmpl[i]
+		if inString && c == '\\' && i+1 < len(tmpl) {
+			out.WriteString(tmpl[i : i+2])
+			i += 2
+			continue
+		}
+		var loc []int
+		if strings.HasPrefix(tmpl[i:], "{{") {
+			loc = bodyPlaceholderRe.FindStringSubmatchIndex(tmpl[i:])
+		}
+		if loc == nil || loc[0] != 0 {
+			out.WriteByte(c)
+			if c == '"' {
+				inString = !inString
+				stringStart = out.Len()
+			}
+			i++
+			continue
+		}
+		name := tmpl[i+loc[2] : i+loc[3]]
+		end := i + loc[1]
+		value, ok := args[name]
+		switch {
+		case inString && out.Len() == stringStart && end < len(tmpl) && tmpl[end] == '"':
+			// "{{x}}": replace the quotes too.
+			encoded, err := jsonTemplateValue(name, value, ok)
+			if err != nil {
+				return nil, err
+			}
+			s := out.String()
+			out.Reset()
+			out.WriteString(s[:len(s)-1])
+			out.Write(encoded)
+			inString = false
+			i = end + 1
+			continue
+		case inString:
+			encoded, err := json.Marshal(templateText(args, name))
+			if err != nil {
+				return nil, err
+			}
+			out.Write(encoded[1 : len(encoded)-1])
+		default:
+			encoded, err := jsonTemplateValue(name, value, ok)
+			if err != nil {
+				return nil, err
+			}
+			out.Write(encoded)
+		}
+		i = end
+	}
+	body := []byte(out.String())
+	if !json.Valid(body) {
+		return nil, fmt.Errorf("request body template did not render to valid JSON")
+	}
+	return body, nil
+}
+
+func jsonTemplateValue(name string, value any, ok bool) ([]byte, error) {
+	if !ok {
+		return []byte("null"), nil
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("encode %s: %w", name, err)
+	}
+	return encoded, nil
+}
+
+func templateText(args map[string]any, name string) string {
+	value, ok := args[name]
+	if !ok || value == nil {
+		return ""
+	}
+	return valueToString(value)
+}
+
+func placeholderName(m string) string {
+	return bodyPlaceholderRe.FindStringSubmatch(m)[1]
+}
+
+// mergeBodyTemplate deep-merges the "body" argument over the configured
+// defaults of body, then the configured constants over the result, so
+// constants always win.
+func mergeBodyTemplate(body *canonical.RequestBody, arg any) (map[string]any, error) {
+	var given map[string]any
+	if arg != nil {
+		m, ok := arg.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("body must be an object to merge the configured body template")
+		}
+		given = m
+	}
+	return deepMerge(deepMerge(body.Defaults, given), body.Constants), nil
+}
+
+// deepMerge returns base with over applied: nested objects are merged key
+// by key and any other value in over replaces the one in base. Neither
+// input is modified.
+func deepMerge(base, over map[string]any) map[string]any {
+	out := make(map[string]any, len(base)+len(over))
+	for k, v := range base {
+		out[k] = v
+	}
+	for k, v := range over {
+		if nested, ok := v.(map[string]any); ok {
+			if prev, ok := out[k].(map[string]any); ok {
+				out[k] = deepMerge(prev, nested)
+				continue
+			}
+		}
+		out[k] = v
+	}
+	return out
+}
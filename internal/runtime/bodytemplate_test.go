@@ -0,0 +1,74 @@
+package runtime
+
+import (
+	"reflect"
+	"testing"
+
+	"skyline-mcp/internal/canonical"
+)
+
+func TestRenderBodyTemplate(t *testing.T) {
+	args := map[string]any{
+		"name":  `Ada "the first"`,
+		"count": float64(3),
+		"tags":  []any{"a", "b"},
+		"ok":    true,
+	}
+	cases := []struct {
+		contentType, tmpl, want string
+	}{
+		{"application/json", `{"name": "{{name}}", "count": {{count}}, "tags": "{{tags}}", "missing": "{{nope}}"}`,
+			`{"name": "Ada \"the first\"", "count": 3, "tags": ["a","b"], "missing": null}`},
+		{"application/json; charset=utf-8", `{"greeting": "hi {{name}}!", "flag": {{ ok }}, "esc": "\"{{count}}\""}`,
+			`{"greeting": "hi Ada \"the first\"!", "flag": true, "esc": "\"3\""}`},
+		{"application/x-www-form-urlencoded", `name={{name}}&n={{count}}`, `name=Ada+%22the+first%22&n=3`},
+		{"text/plain", `Hello {{name}}{{nope}}`, `Hello Ada "the first"`},
+	}
+	for _, tc := range cases {
+		got, err := renderBodyTemplate(tc.contentType, tc.tmpl, args)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.tmpl, err)
+		}
+		if string(got) != tc.want {
+			t.Errorf("renderBodyTemplate(%s)\n got: %s\nwant: %s", tc.tmpl, got, tc.want)
+		}
+	}
+
+	if _, err := renderBodyTemplate("application/json", `{"a": {{count}`, args); err == nil {
+		t.Errorf("expected invalid JSON error")
+	}
+}
+
+func TestMergeBodyTemplate(t *testing.T) {
+	body := &canonical.RequestBody{
+		Constants: map[string]any{"project": "XYZ", "fields": map[string]any{"source": "mcp"}},
+		Defaults:  map[string]any{"priority": "medium", "fields": map[string]any{"labels": []any{"bot"}}},
+	}
+	got, err := mergeBodyTemplate(body, map[string]any{
+		"summary": "Broken build",
+		"project": "ABC",
+		"fields":  map[string]any{"source": "web", "component": "ci"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"project":  "XYZ",
+		"summary":  "Broken build",
+		"priority": "medium",
+		"fields":   map[string]any{"source": "mcp", "component": "ci", "labels": []any{"bot"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("merged = %v\nwant %v", got, want)
+	}
+	if _, ok := body.Defaults["fields"].(map[string]any)["component"]; ok {
+		t.Fatalf("defaults were modified")
+	}
+
+	if got, err := mergeBodyTemplate(body, nil); err != nil || got["project"] != "XYZ" || got["priority"] != "medium" {
+		t.Fatalf("without a body = %v, %v", got, err)
+	}
+	if _, err := mergeBodyTemplate(body, "text"); err == nil {
+		t.Fatal("merged a string body")
+	}
+}
@@ -0,0 +1,162 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+)
+
+// executeBuiltin answers the tools of the built-in "skyline" service.
+func (e *Executor) executeBuiltin(ctx context.Context, op *canonical.Operation, args map[string]any) (*Result, error) {
+	var body any
+	switch op.ID {
+	case "describe_tool":
+		target, err := e.lookupTool(args)
+		if err != nil {
+			return toolError(err), nil
+		}
+		body = e.describeTool(target)
+	case "list_services":
+		includeTools, _ := args["include_tools"].(bool)
+		body = e.listServices(includeTools)
+	case "get_operation_examples":
+		target, err := e.lookupTool(args)
+		if err != nil {
+			return toolError(err), nil
+		}
+		body = operationExamples(target)
+	case "api_health":
+		return e.executeAPIHealth(ctx, args), nil
+	case "get_remaining_quota":
+		usage, err := e.QuotaUsage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		body = map[string]any{"budgets": usage}
+	case "get_job_result":
+		return e.executeGetJobResult(ctx, args), nil
+	case "list_service_errors":
+		errs := e.loadErrors
+		if errs == nil {
+			errs = []canonical.LoadError{}
+		}
+		body = map[string]any{"errors": errs}
+	default:
+		return nil, fmt.Errorf("unknown built-in tool %s", op.ToolName)
+	}
+	return &Result{Status: 200, ContentType: "application/json", Body: body}, nil
+}
+
+// SetLoadErrors records the APIs that failed to load, reported by the
+// list_service_errors tool.
+func (e *Executor) SetLoadErrors(errs []canonical.LoadError) {
+	e.loadErrors = errs
+}
+
+// toolError is a 404 result naming an unknown tool, so agents see a
+// normal tool result they can correct rather than a protocol error.
+func toolError(err error) *Result {
+	return &Result{Status: 404, ContentType: "application/json", Body: map[string]any{"error": err.Error()}}
+}
+
+func (e *Executor) lookupTool(args map[string]any) (*canonical.Operation, error) {
+	name, _ := args["tool"].(string)
+	if name == "" {
+		return nil, fmt.Errorf("tool is required")
+	}
+	for _, svc := range e.catalog {
+		for _, op := range svc.Operations {
+			if op.ToolName == name {
+				return op, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("unknown tool %s", name)
+}
+
+func (e *Executor) describeTool(op *canonical.Operation) map[string]any {
+	out := map[string]any{
+		"name":         op.ToolName,
+		"service":      op.ServiceName,
+		"summary":      op.Summary,
+		"input_schema": op.InputSchema,
+	}
+	if op.Description != "" {
+		out["description"] = op.Description
+	}
+	if op.ResponseSchema != nil {
+		out["output_schema"] = op.ResponseSchema
+	} else if learned := e.LearnedResponseSchema(op); learned != nil {
+		out["output_schema"] = learned
+	}
+	protocol := op.Protocol
+	if protocol == "" {
+		protocol = "http"
+	}
+	out["protocol"] = protocol
+	if op.Workflow != nil {
+		steps := make([]string, 0, len(op.Workflow.Steps))
+		for _, step := range op.Workflow.Steps {
+			steps = append(steps, step.Operation.ToolName)
+		}
+		out["steps"] = steps
+		return out
+	}
+	if protocol == "http" {
+		out["method"] = strings.ToUpper(op.Method)
+		out["path"] = op.Path
+		if base := e.services[op.ServiceName].BaseURL; base != "" {
+			out["upstream"] = strings.ToUpper(op.Method) + " " + strings.TrimRight(base, "/") + op.Path
+		}
+		if op.RequestBody != nil && op.RequestBody.ContentType != "" {
+			out["content_type"] = op.RequestBody.ContentType
+		}
+	}
+	return out
+}
+
+func (e *Executor) listServices(includeTools bool) map[string]any {
+	var services []map[string]any
+	total := 0
+	for _, svc := range e.catalog {
+		if svc.Name == config.BuiltinServiceName {
+			continue
+		}
+		entry := map[string]any{
+			"name":       svc.Name,
+			"tool_count": len(svc.Operations),
+		}
+		if base := e.services[svc.Name].BaseURL; base != "" {
+			entry["base_url"] = base
+		}
+		if includeTools {
+			tools := make([]string, 0, len(svc.Operations))
+			for _, op := range svc.Operations {
+				tools = append(tools, op.ToolName)
+			}
+			sort.Strings(tools)
+			entry["tools"] = tools
+		}
+		total += len(svc.Operations)
+		services = append(services, entry)
+	}
+	sort.Slice(services, func(i, j int) bool { return services[i]["name"].(string) < services[j]["name"].(string) })
+	return map[string]any{"services": services, "service_count": len(services), "tool_count": total}
+}
+
+// operationExamples returns a minimal payload (required arguments only)
+// and, when it differs, a full one with every argument.
+func operationExamples(op *canonical.Operation) map[string]any {
+	minimal := canonical.ExampleValue(op.InputSchema, true)
+	full := canonical.ExampleValue(op.InputSchema, false)
+	examples := []map[string]any{{"name": "required", "arguments": minimal}}
+	if !reflect.DeepEqual(full, minimal) {
+		examples = append(examples, map[string]any{"name": "full", "arguments": full})
+	}
+	return map[string]any{"tool": op.ToolName, "examples": examples}
+}
@@ -0,0 +1,136 @@
+package runtime_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/logging"
+	"skyline-mcp/internal/quota"
+	"skyline-mcp/internal/redact"
+	"skyline-mcp/internal/runtime"
+	"skyline-mcp/internal/spec"
+)
+
+func TestExecutorMetaTools(t *testing.T) {
+	createPet := &canonical.Operation{
+		ServiceName: "pets", ID: "createPet", ToolName: "pets__createPet", Method: "post", Path: "/pets",
+		RequestBody: &canonical.RequestBody{ContentType: "application/json"},
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"name":    map[string]any{"type": "string"},
+				"kind":    map[string]any{"type": "string", "enum": []any{"dog", "cat"}},
+				"born":    map[string]any{"type": "string", "format": "date"},
+				"tags":    map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+				"age":     map[string]any{"type": "integer", "default": 3},
+				"contact": map[string]any{"type": "object", "properties": map[string]any{"email": map[string]any{"type": "string", "format": "email"}}},
+			},
+			"required": []string{"name", "kind"},
+		},
+		ResponseSchema: map[string]any{"type": "object"},
+	}
+	services := spec.ApplyBuiltinTools([]*canonical.Service{
+		{Name: "pets", BaseURL: "https://pets.example.com/v1", Operations: []*canonical.Operation{createPet}},
+	}, &config.Config{})
+	cfg := &config.Config{APIs: []config.APIConfig{{Name: "pets", SpecURL: "https://pets.example.com/openapi.json"}}}
+	cfg.ApplyDefaults()
+	exec, err := runtime.NewExecutor(cfg, services, logging.Discard(), redact.NewRedactor())
+	if err != nil {
+		t.Fatalf("executor init failed: %v", err)
+	}
+	builtin := map[string]*canonical.Operation{}
+	for _, op := range services[1].Operations {
+		builtin[op.ID] = op
+	}
+	call := func(id string, args map[string]any) (int, map[string]any) {
+		t.Helper()
+		res, err := exec.Execute(context.Background(), builtin[id], args)
+		if err != nil {
+			t.Fatalf("%s failed: %v", id, err)
+		}
+		body, _ := res.Body.(map[string]any)
+		return res.Status, body
+	}
+
+	_, desc := call("describe_tool", map[string]any{"tool": "pets__createPet"})
+	if desc["upstream"] != "POST https://pets.example.com/v1/pets" || desc["content_type"] != "application/json" || desc["output_schema"] == nil {
+		t.Fatalf("unexpected description: %#v", desc)
+	}
+	if status, body := call("describe_tool", map[string]any{"tool": "nope"}); status != 404 || body["error"] != "unknown tool nope" {
+		t.Fatalf("expected unknown tool error, got %d %#v", status, body)
+	}
+
+	_, list := call("list_services", map[string]any{"include_tools": true})
+	svcs, _ := list["services"].([]map[string]any)
+	if len(svcs) != 1 || svcs[0]["name"] != "pets" || svcs[0]["tool_count"] != 1 || list["tool_count"] != 1 {
+		t.Fatalf("unexpected services: %#v", list)
+	}
+
+	_, ex := call("get_operation_examples", map[string]any{"tool": "pets__createPet"})
+	examples, _ := ex["examples"].([]map[string]any)
+	if len(examples) != 2 {
+		t.Fatalf("expected required and full examples, got %#v", ex)
+	}
+	minimal, _ := examples[0]["arguments"].(map[string]any)
+	if len(minimal) != 2 || minimal["kind"] != "dog" {
+		t.Fatalf("unexpected minimal example: %#v", minimal)
+	}
+	full, _ := examples[1]["arguments"].(map[string]any)
+	contact, _ := full["contact"].(map[string]any)
+	if full["born"] != "2024-01-15" || full["age"] != 3 || contact["email"] != "user@example.com" {
+		t.Fatalf("unexpected full example: %#v", full)
+	}
+}
+
+func TestExecutorQuota(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{}`)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{APIs: []config.APIConfig{{
+		Name: "pets", SpecURL: server.URL + "/openapi.json", BaseURLOverride: server.URL,
+		Quota: &config.QuotaConfig{Daily: 1},
+	}}}
+	cfg.ApplyDefaults()
+	services := spec.ApplyBuiltinTools([]*canonical.Service{{Name: "pets", BaseURL: server.URL}}, cfg)
+	exec, err := runtime.NewExecutor(cfg, services, logging.Discard(), redact.NewRedactor())
+	if err != nil {
+		t.Fatalf("executor init failed: %v", err)
+	}
+
+	list := &canonical.Operation{ServiceName: "pets", ID: "listPets", ToolName: "pets__listPets", Method: "get", Path: "/pets"}
+	if _, err := exec.Execute(context.Background(), list, map[string]any{}); err != nil {
+		t.Fatalf("first call failed: %v", err)
+	}
+	_, err = exec.Execute(context.Background(), list, map[string]any{})
+	var exceeded *quota.ExceededError
+	if !errors.As(err, &exceeded) || exceeded.Scope != "pets" {
+		t.Fatalf("err = %v, want daily quota exceeded for pets", err)
+	}
+
+	var remaining *canonical.Operation
+	for _, op := range services[1].Operations {
+		if op.ID == "get_remaining_quota" {
+			remaining = op
+		}
+	}
+	if remaining == nil {
+		t.Fatal("get_remaining_quota not registered")
+	}
+	res, err := exec.Execute(context.Background(), remaining, map[string]any{})
+	if err != nil {
+		t.Fatalf("get_remaining_quota failed: %v", err)
+	}
+	budgets, _ := res.Body.(map[string]any)["budgets"].([]quota.Usage)
+	if len(budgets) != 1 || budgets[0].Used != 1 || budgets[0].Remaining != 0 {
+		t.Fatalf("unexpected budgets: %#v", res.Body)
+	}
+}
@@ -0,0 +1,87 @@
+package runtime
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+
+	"skyline-mcp/internal/config"
+)
+
+func TestCompressedResponses(t *testing.T) {
+	const payload = `{"items":[1,2,3]}`
+	encode := map[string]func(io.Writer) io.WriteCloser{
+		"gzip":     func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+		"zlib":     func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) },
+		"raw":      func(w io.Writer) io.WriteCloser { fw, _ := flate.NewWriter(w, flate.DefaultCompression); return fw },
+		"br":       func(w io.Writer) io.WriteCloser { return brotli.NewWriter(w) },
+		"identity": func(w io.Writer) io.WriteCloser { return nopWriteCloser{w} },
+	}
+	encodingHeader := map[string]string{"gzip": "gzip", "zlib": "deflate", "raw": "deflate", "br": "br", "identity": ""}
+	var accepted string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accepted = r.Header.Get("Accept-Encoding")
+		kind := r.URL.Query().Get("as")
+		if kind == "zstd" {
+			w.Header().Set("Content-Encoding", "zstd")
+			_, _ = w.Write([]byte{0x28, 0xb5, 0x2f, 0xfd})
+			return
+		}
+		var buf bytes.Buffer
+		enc := encode[kind](&buf)
+		_, _ = enc.Write([]byte(payload))
+		enc.Close()
+		if h := encodingHeader[kind]; h != "" {
+			w.Header().Set("Content-Encoding", h)
+		}
+		_, _ = w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+	client := newPooledClient(&connCounters{}, config.TransportConfig{})
+
+	for kind := range encode {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"?as="+kind, nil)
+		// A custom Accept-Encoding turns off Go's own gzip handling.
+		req.Header.Set("Accept-Encoding", "gzip, deflate, br")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("%s: %v", kind, err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil || string(body) != payload {
+			t.Fatalf("%s: body = %q, %v", kind, body, err)
+		}
+		if resp.Header.Get("Content-Encoding") != "" {
+			t.Fatalf("%s: Content-Encoding kept after decoding", kind)
+		}
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"?as=gzip", nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if accepted != acceptEncoding {
+		t.Fatalf("Accept-Encoding = %q, want %q", accepted, acceptEncoding)
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, server.URL+"?as=zstd", nil)
+	req.Header.Set("Accept-Encoding", "zstd")
+	if _, err := client.Do(req); err == nil || !strings.Contains(err.Error(), `unsupported content encoding "zstd"`) {
+		t.Fatalf("zstd response: %v", err)
+	}
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
@@ -0,0 +1,174 @@
+package runtime
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"path"
+	"sort"
+	"strings"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+)
+
+// FilteredField reports response fields a data policy masked, hashed or
+// dropped. Path uses [*] for array elements, so one entry covers a field
+// across all items of a list.
+type FilteredField struct {
+	Path           string `json:"path"`
+	Classification string `json:"classification"`
+	Action         string `json:"action"`
+	Count          int    `json:"count"`
+}
+
+// DataPolicyHook is called with the fields filtered from a result, e.g. to
+// audit them.
+type DataPolicyHook func(ctx context.Context, op *canonical.Operation, filtered []FilteredField)
+
+// SetDataPolicyHook registers fn to be called whenever a data policy
+// filters fields from a result.
+func (e *Executor) SetDataPolicyHook(fn DataPolicyHook) {
+	e.dataPolicyHook = fn
+}
+
+// applyDataPolicy filters result in place according to the API's data
+// policy and records what was filtered on it.
+func (e *Executor) applyDataPolicy(ctx context.Context, op *canonical.Operation, result *Result) {
+	if result == nil {
+		return
+	}
+	policy := e.services[op.ServiceName].DataPolicy
+	if policy == nil {
+		return
+	}
+	f := dataFilter{policy: policy, counts: map[FilteredField]int{}}
+	if policy.SchemaAction != "" && len(op.PIIFields) > 0 {
+		f.schemaPaths = make(map[string]bool, len(op.PIIFields))
+		for _, p := range op.PIIFields {
+			f.schemaPaths[p] = true
+		}
+	}
+	result.Body = f.walk(result.Body, "$")
+	if len(f.counts) == 0 {
+		return
+	}
+	filtered := make([]FilteredField, 0, len(f.counts))
+	for field, n := range f.counts {
+		field.Count = n
+		filtered = append(filtered, field)
+	}
+	sort.Slice(filtered, func(i, j int) bool { return filtered[i].Path < filtered[j].Path })
+	result.Filtered = filtered
+	if e.dataPolicyHook != nil {
+		e.dataPolicyHook(ctx, op, filtered)
+	}
+}
+
+// filterStreamItem applies the API's data policy to one value of a
+// line-delimited response as it is streamed. The fields it filters are
+// reported with the whole result.
+func (e *Executor) filterStreamItem(op *canonical.Operation, item any) any {
+	policy := e.services[op.ServiceName].DataPolicy
+	if policy == nil {
+		return item
+	}
+	f := dataFilter{policy: policy, counts: map[FilteredField]int{}}
+	if policy.SchemaAction != "" && len(op.PIIFields) > 0 {
+		f.schemaPaths = make(map[string]bool, len(op.PIIFields))
+		for _, p := range op.PIIFields {
+			f.schemaPaths[p] = true
+		}
+	}
+	return f.walk(item, "$[*]")
+}
+
+type dataFilter struct {
+	policy      *config.DataPolicyConfig
+	schemaPaths map[string]bool
+	counts      map[FilteredField]int // keyed with Count unset
+}
+
+// walk returns a filtered copy of v; the original may be shared, e.g. a
+// mock response taken from the spec's examples.
+func (f *dataFilter) walk(v any, at string) any {
+	switch node := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(node))
+		for k, child := range node {
+			childPath := at + "." + k
+			class, action, ok := f.classify(childPath, k)
+			if !ok {
+				out[k] = f.walk(child, childPath)
+				continue
+			}
+			f.counts[FilteredField{Path: childPath, Classification: class, Action: action}]++
+			if action != config.DataPolicyDrop {
+				out[k] = filterValue(child, action)
+			}
+		}
+		return out
+	case []any:
+		out := make([]any, len(node))
+		for i, child := range node {
+			out[i] = f.walk(child, at+"[*]")
+		}
+		return out
+	}
+	return v
+}
+
+// classify decides what happens to the field at path: annotations in the
+// spec win over name rules, which are tried in order.
+func (f *dataFilter) classify(at, name string) (string, string, bool) {
+	if f.schemaPaths[at] {
+		return "pii", f.policy.SchemaAction, true
+	}
+	name = strings.ToLower(name)
+	for _, rule := range f.policy.Rules {
+		for _, pattern := range rule.Fields {
+			if ok, _ := path.Match(strings.ToLower(pattern), name); ok {
+				class := rule.Classification
+				if class == "" {
+					class = "pii"
+				}
+				return class, rule.Action, true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// filterValue masks or hashes a field value. Objects and arrays are
+// replaced as a whole, by their JSON encoding when hashed.
+func filterValue(v any, action string) any {
+	if v == nil {
+		return nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		data, _ := json.Marshal(v)
+		s = string(data)
+	}
+	if action == config.DataPolicyHash {
+		sum := sha256.Sum256([]byte(s))
+		return "sha256:" + hex.EncodeToString(sum[:16])
+	}
+	return maskValue(s)
+}
+
+// maskValue hides a value, keeping the last four characters of long
+// numbers (phone, card and account numbers) so they stay recognisable.
+func maskValue(s string) string {
+	digits := 0
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			digits++
+		}
+	}
+	if len(s) >= 8 && digits*2 > len(s) {
+		return "****" + s[len(s)-4:]
+	}
+	return "****"
+}
@@ -0,0 +1,88 @@
+package runtime_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/logging"
+	"skyline-mcp/internal/redact"
+	"skyline-mcp/internal/runtime"
+)
+
+func TestExecutorDataPolicy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"items":[
+			{"name":"Ada","ssn":"123-45-6789","email":"ada@example.com","phone":"+1 555 010 9999","home_address":{"city":"London"}},
+			{"name":"Alan","ssn":"987-65-4321","email":"alan@example.com","phone":"555"}
+		],"total":2}`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{APIs: []config.APIConfig{{
+		Name: "hr", SpecURL: server.URL + "/openapi.json", BaseURLOverride: server.URL,
+		DataPolicy: &config.DataPolicyConfig{
+			SchemaAction: config.DataPolicyDrop,
+			Rules: []config.DataPolicyRule{
+				{Fields: []string{"EMAIL"}, Action: config.DataPolicyHash},
+				{Fields: []string{"phone", "*_address"}, Classification: "contact", Action: config.DataPolicyMask},
+			},
+		},
+	}}}
+	cfg.ApplyDefaults()
+	exec, err := runtime.NewExecutor(cfg, []*canonical.Service{{Name: "hr", BaseURL: server.URL}}, logging.Discard(), redact.NewRedactor())
+	if err != nil {
+		t.Fatalf("executor init failed: %v", err)
+	}
+	var hooked []runtime.FilteredField
+	exec.SetDataPolicyHook(func(ctx context.Context, op *canonical.Operation, filtered []runtime.FilteredField) {
+		hooked = filtered
+	})
+
+	op := &canonical.Operation{
+		ServiceName: "hr", ToolName: "hr__listEmployees", Method: "get", Path: "/employees",
+		PIIFields: []string{"$.items[*].ssn"},
+	}
+	result, err := exec.Execute(context.Background(), op, map[string]any{})
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	items := result.Body.(map[string]any)["items"].([]any)
+	ada, alan := items[0].(map[string]any), items[1].(map[string]any)
+	if _, ok := ada["ssn"]; ok {
+		t.Fatalf("ssn not dropped: %v", ada)
+	}
+	if ada["name"] != "Ada" {
+		t.Fatalf("unclassified field changed: %v", ada["name"])
+	}
+	email, _ := ada["email"].(string)
+	if !strings.HasPrefix(email, "sha256:") || email == alan["email"] {
+		t.Fatalf("email not hashed: %v", ada["email"])
+	}
+	if ada["phone"] != "****9999" || alan["phone"] != "****" || ada["home_address"] != "****" {
+		t.Fatalf("contact fields not masked: %v, %v", ada, alan)
+	}
+
+	want := map[string]runtime.FilteredField{
+		"$.items[*].email":        {Path: "$.items[*].email", Classification: "pii", Action: "hash", Count: 2},
+		"$.items[*].home_address": {Path: "$.items[*].home_address", Classification: "contact", Action: "mask", Count: 1},
+		"$.items[*].phone":        {Path: "$.items[*].phone", Classification: "contact", Action: "mask", Count: 2},
+		"$.items[*].ssn":          {Path: "$.items[*].ssn", Classification: "pii", Action: "drop", Count: 2},
+	}
+	if len(result.Filtered) != len(want) {
+		t.Fatalf("filtered = %+v", result.Filtered)
+	}
+	for _, f := range result.Filtered {
+		if want[f.Path] != f {
+			t.Fatalf("filtered %+v, want %+v", f, want[f.Path])
+		}
+	}
+	if len(hooked) != len(want) {
+		t.Fatalf("hook got %+v", hooked)
+	}
+}
@@ -4,14 +4,17 @@ import (
 	"bytes"
 	"context"
 	"crypto/tls"
+	"database/sql"
 	"encoding/base64"
 	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"math"
 	"math/rand/v2"
+	"net"
 	"net/http"
 	"net/url"
 	"regexp"
@@ -24,16 +27,23 @@ import (
 	"skyline-mcp/internal/canonical"
 	"skyline-mcp/internal/circuitbreaker"
 	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/jobs"
+	"skyline-mcp/internal/quota"
 	"skyline-mcp/internal/ratelimit"
 	"skyline-mcp/internal/redact"
+	"skyline-mcp/internal/schemalearn"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/types/dynamicpb"
 
-	"github.com/jhump/protoreflect/grpcreflect"
+	"github.com/jhump/protoreflect/desc"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/sync/singleflight"
 )
 
 // ProtocolHandler handles execution for a custom protocol (e.g., "email").
@@ -41,42 +51,165 @@ import (
 type ProtocolHandler func(ctx context.Context, op *canonical.Operation, args map[string]any) (*Result, error)
 
 type Executor struct {
-	client    *http.Client
+	clients   *HTTPClients // one long-lived client per API
+	profile   string       // scopes clients and tokens when they are shared between executors
 	logger    *slog.Logger
 	redactor  *redact.Redactor
 	services  map[string]serviceConfig
 	limiters  map[string]*ratelimit.Limiter
 	breakers  map[string]*circuitbreaker.Breaker
+	responses *responseCache // results of APIs with response_cache_seconds
 	crumbMu   sync.Mutex
 	crumbs    map[string]*crumbState
 	grpcMu    sync.Mutex
 	grpcConns map[string]*grpc.ClientConn
-	oauth2Mgr *OAuth2TokenManager
-	protocols map[string]ProtocolHandler // custom protocol handlers (keyed by protocol name)
+	// grpcDescs caches service descriptors resolved through reflection.
+	grpcDescMu sync.Mutex
+	grpcDescs  map[grpcDescKey]*desc.ServiceDescriptor
+	sqlMu      sync.Mutex
+	sqlDBs     map[string]*sql.DB // connection pools of spec_type: sql services
+	brokerMu   sync.Mutex
+	brokers    map[string]brokerClient // broker clients of spec_type: messaging services
+	sshMu      sync.Mutex
+	sshClients map[string]*ssh.Client // connections of spec_type: ssh services, keyed by service/host
+	sshGen     uint64                 // bumped by Close, so dials in flight discard their connection
+	sshDials   singleflight.Group
+	oauth2Mgr  *OAuth2TokenManager
+	protocols  map[string]ProtocolHandler // custom protocol handlers (keyed by protocol name)
+	signers    map[string]RequestSigner   // request signers (keyed by API name)
+	health     healthState
+	recorder   *recorder            // set when the config enables recording or replay
+	quota      *quota.Tracker       // nil without budgets
+	learner    *schemalearn.Learner // nil without schema learning
+	catalog    []*canonical.Service // every loaded service, for the built-in meta tools
+	// loadErrors lists the configured APIs that failed to load.
+	loadErrors []canonical.LoadError
+	// maxTimeout caps the timeout callers request with TimeoutArgument.
+	maxTimeout time.Duration
+	jobs       *jobs.Store // background executions started with AsyncArgument
+	// dataPolicyHook is told about fields filtered by data policies.
+	dataPolicyHook DataPolicyHook
+	// breakerHook is told when a circuit breaker opens or closes.
+	breakerHook BreakerHook
+	// deviceCodeHook is told when an API starts an OAuth device flow.
+	deviceCodeHook DeviceCodeHook
+	// sshHook is told about every command ssh tools run.
+	sshHook SSHHook
 }
 
 type serviceConfig struct {
-	BaseURL string
-	Auth    *config.AuthConfig
-	Timeout time.Duration
-	Retries int
+	BaseURL     string
+	Auth        *config.AuthConfig
+	Timeout     time.Duration
+	Retries     int
+	Headers     map[string]string // Per-API headers from config (may contain {{...}} templates)
+	RespHeaders []string          // Upstream response headers copied into results
+	SOAPHeaders []string          // XML blocks added to the Header of SOAP envelopes
+	Crumb       *config.JenkinsCrumb
+	Database    *config.DatabaseConfig
+	Messaging   *config.MessagingConfig
+	SSH         *config.SSHConfig
+	Probe       healthProbe
+	Mock        bool
+	DataPolicy  *config.DataPolicyConfig
+	APQ         bool // send GraphQL queries as Automatic Persisted Queries
+	Prometheus  *config.PrometheusConfig
+	// IdempotencyHeader carries the key of requests that are not
+	// idempotent; empty when disabled.
+	IdempotencyHeader string
+	// Transport tunes the API's HTTP client.
+	Transport config.TransportConfig
+	// Hooks are the API's compiled hook scripts; nil without hooks.
+	Hooks *apiHooks
+	// ResponseCache is how long GET results are cached; 0 when disabled.
+	ResponseCache time.Duration
 }
 
 type Result struct {
-	Status      int    `json:"status"`
-	ContentType string `json:"content_type"`
-	Body        any    `json:"body"`
+	Status      int         `json:"status"`
+	ContentType string      `json:"content_type"`
+	Body        any         `json:"body"`
+	Pagination  *Pagination `json:"pagination,omitempty"` // next-page metadata from the upstream, if any
+	// Headers holds the upstream response headers allowed by the API's
+	// response_headers config.
+	Headers map[string]string `json:"headers,omitempty"`
+	// Filtered lists the fields the API's data policy removed or altered.
+	Filtered []FilteredField `json:"filtered,omitempty"`
+	// IdempotencyKey is the key sent with the request, for tracing it in
+	// the upstream's records.
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+	// Stream is set for line-delimited JSON responses, whose Body is the
+	// list of values read.
+	Stream *StreamInfo `json:"stream,omitempty"`
 }
 
 func NewExecutor(cfg *config.Config, services []*canonical.Service, logger *slog.Logger, redactor *redact.Redactor) (*Executor, error) {
 	serviceMap := map[string]serviceConfig{}
 	limiterMap := map[string]*ratelimit.Limiter{}
 	breakerMap := map[string]*circuitbreaker.Breaker{}
+	signers := map[string]RequestSigner{}
 	for _, api := range cfg.APIs {
+		if api.Signing != nil {
+			signer, err := newSigner(api.Signing)
+			if err != nil {
+				return nil, fmt.Errorf("api %s: signing: %w", api.Name, err)
+			}
+			signers[api.Name] = signer
+		}
+		var hooks *apiHooks
+		if api.Hooks != nil {
+			var err error
+			if hooks, err = newHooks(api.Hooks); err != nil {
+				return nil, fmt.Errorf("api %s: %w", api.Name, err)
+			}
+		}
 		serviceMap[api.Name] = serviceConfig{
-			Auth:    api.Auth,
-			Timeout: time.Duration(derefInt(api.TimeoutSeconds, cfg.TimeoutSeconds)) * time.Second,
-			Retries: derefInt(api.Retries, cfg.Retries),
+			Auth:              api.Auth,
+			Timeout:           time.Duration(derefInt(api.TimeoutSeconds, cfg.TimeoutSeconds)) * time.Second,
+			Retries:           derefInt(api.Retries, cfg.Retries),
+			Headers:           api.Headers,
+			RespHeaders:       api.ResponseHeaders,
+			SOAPHeaders:       api.SOAPHeaders,
+			Mock:              api.Mock,
+			DataPolicy:        api.DataPolicy,
+			IdempotencyHeader: idempotencyHeader(api.Idempotency),
+			Hooks:             hooks,
+			ResponseCache:     time.Duration(derefInt(api.ResponseCacheSeconds, 0)) * time.Second,
+		}
+		if api.Transport != nil {
+			entry := serviceMap[api.Name]
+			entry.Transport = *api.Transport
+			serviceMap[api.Name] = entry
+		}
+		if api.SpecType == "sql" {
+			entry := serviceMap[api.Name]
+			entry.Database = api.Database
+			serviceMap[api.Name] = entry
+		}
+		if api.SpecType == "messaging" {
+			entry := serviceMap[api.Name]
+			entry.Messaging = api.Messaging
+			serviceMap[api.Name] = entry
+		}
+		if api.SpecType == "ssh" {
+			entry := serviceMap[api.Name]
+			entry.SSH = api.SSH
+			serviceMap[api.Name] = entry
+		}
+		if api.Jenkins != nil {
+			entry := serviceMap[api.Name]
+			entry.Crumb = api.Jenkins.Crumb
+			serviceMap[api.Name] = entry
+		}
+		if api.GraphQL != nil {
+			entry := serviceMap[api.Name]
+			entry.APQ = api.GraphQL.APQ
+			serviceMap[api.Name] = entry
+		}
+		if api.Prometheus != nil {
+			entry := serviceMap[api.Name]
+			entry.Prometheus = api.Prometheus
+			serviceMap[api.Name] = entry
 		}
 		rpm := derefInt(api.RateLimitRPM, 0)
 		rph := derefInt(api.RateLimitRPH, 0)
@@ -90,46 +223,199 @@ func NewExecutor(cfg *config.Config, services []*canonical.Service, logger *slog
 	}
 	for _, svc := range services {
 		cfgEntry, ok := serviceMap[svc.Name]
+		if !ok && (svc.Name == config.WorkflowServiceName || svc.Name == config.BuiltinServiceName) {
+			// Workflow tools run their steps against the other services;
+			// built-in tools are answered by the executor itself.
+			continue
+		}
 		if !ok {
 			return nil, fmt.Errorf("service %s missing config", svc.Name)
 		}
 		cfgEntry.BaseURL = svc.BaseURL
+		if a := cfgEntry.Auth; a != nil && a.Type == "aws-sigv4" && a.Service == "" {
+			// The model's signing name can differ from the host's service
+			// label, as with SES at email.*.amazonaws.com.
+			if name := awsSigningName(svc); name != "" {
+				signed := *a
+				signed.Service = name
+				cfgEntry.Auth = &signed
+			}
+		}
+		cfgEntry.Probe = probeFor(svc)
+		if cfgEntry.Mock {
+			// Mock APIs never contact their upstream, not even to probe it.
+			cfgEntry.Probe = healthProbe{kind: "none"}
+		}
 		serviceMap[svc.Name] = cfgEntry
 	}
 
-	transport := &http.Transport{
-		MaxIdleConns:          100,
-		MaxIdleConnsPerHost:   10,
-		IdleConnTimeout:       90 * time.Second,
-		TLSHandshakeTimeout:   10 * time.Second,
-		ResponseHeaderTimeout: 30 * time.Second,
-	}
-
 	return &Executor{
-		client: &http.Client{
-			Transport: transport,
-			Timeout:   60 * time.Second,
-		},
-		logger:    logger,
-		redactor:  redactor,
-		services:  serviceMap,
-		limiters:  limiterMap,
-		breakers:  breakerMap,
-		crumbs:    map[string]*crumbState{},
-		grpcConns: map[string]*grpc.ClientConn{},
-		oauth2Mgr: NewOAuth2TokenManager(),
-		protocols: map[string]ProtocolHandler{},
+		clients:    NewHTTPClients(),
+		logger:     logger,
+		redactor:   redactor,
+		services:   serviceMap,
+		limiters:   limiterMap,
+		breakers:   breakerMap,
+		responses:  newResponseCache(),
+		crumbs:     map[string]*crumbState{},
+		grpcConns:  map[string]*grpc.ClientConn{},
+		grpcDescs:  map[grpcDescKey]*desc.ServiceDescriptor{},
+		sqlDBs:     map[string]*sql.DB{},
+		brokers:    map[string]brokerClient{},
+		sshClients: map[string]*ssh.Client{},
+		oauth2Mgr:  NewOAuth2TokenManager(),
+		protocols:  map[string]ProtocolHandler{},
+		signers:    signers,
+		catalog:    services,
+		maxTimeout: time.Duration(cfg.MaxTimeoutSeconds) * time.Second,
+		jobs:       jobs.NewStore(0, 0),
+		recorder:   newRecorder(cfg.Recording, redactor),
+		quota:      quota.New(cfg, logger),
+		learner:    schemalearn.New(cfg.SchemaLearning, logger),
 	}, nil
 }
 
+// UseHTTPClients makes the executor take its HTTP clients from clients,
+// under profile, instead of its own. Executors sharing clients reuse each
+// other's upstream connections.
+func (e *Executor) UseHTTPClients(clients *HTTPClients, profile string) {
+	e.clients = clients
+	e.profile = profile
+}
+
+// UseOAuth2Tokens makes the executor cache OAuth2 tokens in tokens, under
+// profile, instead of its own manager, so they survive registry rebuilds
+// and a device flow is authorized only once.
+func (e *Executor) UseOAuth2Tokens(tokens *OAuth2TokenManager, profile string) {
+	e.oauth2Mgr = tokens
+	e.profile = profile
+}
+
+// DeviceCodeHook is called when an API using the OAuth device flow needs a
+// user to authorize it.
+type DeviceCodeHook func(api string, code DeviceCode)
+
+// SetDeviceCodeHook registers fn to be called with the user code and
+// verification URL whenever a device flow starts.
+func (e *Executor) SetDeviceCodeHook(fn DeviceCodeHook) {
+	e.deviceCodeHook = fn
+}
+
+// ConnStats reports the connection pools of the executor's HTTP clients.
+func (e *Executor) ConnStats() []ConnStats {
+	return e.clients.Stats()
+}
+
+// httpClient returns the client for calls to an API.
+func (e *Executor) httpClient(api string) *http.Client {
+	return e.clients.Client(e.profile, api, e.services[api].Transport)
+}
+
 // RegisterProtocol registers a custom protocol handler for a given protocol name.
 // Operations with op.Protocol matching the name will be dispatched to this handler.
 func (e *Executor) RegisterProtocol(name string, handler ProtocolHandler) {
 	e.protocols[name] = handler
 }
 
+var (
+	sharedProtocolsMu sync.RWMutex
+	sharedProtocols   = map[string]ProtocolHandler{}
+)
+
+// RegisterProtocol registers a protocol handler for every executor, such
+// as one backed by a plugin. Handlers registered on an executor take
+// precedence.
+func RegisterProtocol(name string, handler ProtocolHandler) {
+	sharedProtocolsMu.Lock()
+	defer sharedProtocolsMu.Unlock()
+	sharedProtocols[name] = handler
+}
+
+// protocolHandler returns the handler of a custom protocol, or nil.
+func (e *Executor) protocolHandler(name string) ProtocolHandler {
+	if name == "" {
+		return nil
+	}
+	if handler, ok := e.protocols[name]; ok {
+		return handler
+	}
+	sharedProtocolsMu.RLock()
+	defer sharedProtocolsMu.RUnlock()
+	return sharedProtocols[name]
+}
+
+// SharedStore backs state that must be consistent across replicas in
+// distributed mode: rate limit counters, circuit breaker trips and cached
+// responses.
+type SharedStore interface {
+	ratelimit.Counter
+	circuitbreaker.SharedState
+}
+
+// UseSharedState moves this executor's rate limiters, circuit breakers and
+// response cache to store. namespace scopes the keys (typically the profile name) so that
+// replicas serving the same profile share limits while profiles stay isolated.
+func (e *Executor) UseSharedState(store SharedStore, namespace string) {
+	for name, limiter := range e.limiters {
+		limiter.Share(store, "ratelimit:"+namespace+":"+name)
+	}
+	for name, breaker := range e.breakers {
+		breaker.Share(store, "breaker:"+namespace+":"+name)
+	}
+	e.responses.share(store, "response:"+namespace+":")
+}
+
+// UseQuotaStore counts this executor's calls in store under profile
+// instead of in memory, so budgets survive restarts and are shared by every
+// executor of the profile.
+func (e *Executor) UseQuotaStore(store quota.Store, profile string) {
+	if e.quota != nil {
+		e.quota.UseStore(store, profile)
+	}
+}
+
+// UseSchemaStore keeps the response schemas this executor learns in store
+// under profile, and serves those learned before.
+func (e *Executor) UseSchemaStore(store schemalearn.Store, profile string) {
+	e.learner.UseStore(store, profile)
+}
+
+// LearnedResponseSchema returns the response body schema learned for an
+// operation whose spec declares none, or nil.
+func (e *Executor) LearnedResponseSchema(op *canonical.Operation) map[string]any {
+	if len(op.ResponseSchema) > 0 {
+		return nil
+	}
+	learned, ok := e.learner.Schema(learnedSchemaKey(op))
+	if !ok {
+		return nil
+	}
+	schema := make(map[string]any, len(learned.Schema)+1)
+	for k, v := range learned.Schema {
+		schema[k] = v
+	}
+	schema["description"] = fmt.Sprintf("Inferred from %d observed responses; may be incomplete.", learned.Samples)
+	return schema
+}
+
+// learnedSchemaKey identifies an operation in the schema store. It does
+// not depend on tool naming, so renaming tools keeps what was learned.
+func learnedSchemaKey(op *canonical.Operation) string {
+	return op.ServiceName + "/" + op.ID
+}
+
+// QuotaUsage reports the configured budgets and their usage; it is empty
+// without budgets.
+func (e *Executor) QuotaUsage(ctx context.Context) ([]quota.Usage, error) {
+	if e.quota == nil {
+		return []quota.Usage{}, nil
+	}
+	return e.quota.Usage(ctx)
+}
+
 // Close releases resources held by the Executor, including gRPC connections.
 func (e *Executor) Close() error {
+	e.stopHealthChecks()
 	e.grpcMu.Lock()
 	defer e.grpcMu.Unlock()
 	var firstErr error
@@ -140,6 +426,40 @@ func (e *Executor) Close() error {
 		e.logger.Debug("closed gRPC connection", "addr", addr)
 	}
 	e.grpcConns = map[string]*grpc.ClientConn{}
+	e.grpcDescMu.Lock()
+	e.grpcDescs = map[grpcDescKey]*desc.ServiceDescriptor{}
+	e.grpcDescMu.Unlock()
+
+	e.sqlMu.Lock()
+	defer e.sqlMu.Unlock()
+	for name, db := range e.sqlDBs {
+		if err := db.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		e.logger.Debug("closed database connection", "api", name)
+	}
+	e.sqlDBs = map[string]*sql.DB{}
+
+	e.brokerMu.Lock()
+	defer e.brokerMu.Unlock()
+	for name, client := range e.brokers {
+		if err := client.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		e.logger.Debug("closed broker connections", "api", name)
+	}
+	e.brokers = map[string]brokerClient{}
+
+	e.sshMu.Lock()
+	defer e.sshMu.Unlock()
+	for key, client := range e.sshClients {
+		if err := client.Close(); err != nil && !errors.Is(err, net.ErrClosed) && firstErr == nil {
+			firstErr = err
+		}
+		e.logger.Debug("closed ssh connection", "host", key)
+	}
+	e.sshClients = map[string]*ssh.Client{}
+	e.sshGen++
 	return firstErr
 }
 
@@ -150,6 +470,26 @@ func derefInt(v *int, fallback int) int {
 	return *v
 }
 
+// BreakerHook is called when the circuit breaker of an API opens, with
+// the failure that tripped it, or closes again.
+type BreakerHook func(api string, open bool, lastErr error)
+
+// SetBreakerHook registers fn to be called whenever a circuit breaker
+// changes between open and closed.
+func (e *Executor) SetBreakerHook(fn BreakerHook) {
+	e.breakerHook = fn
+}
+
+// SetHookMemoryLimit caps the heap growth of each hook run, in bytes. The
+// default is the code execution default, executor.DefaultMemoryLimit.
+func (e *Executor) SetHookMemoryLimit(limit int64) {
+	for _, svc := range e.services {
+		if svc.Hooks != nil {
+			svc.Hooks.memoryLimit = limit
+		}
+	}
+}
+
 // recordBreakerOutcome records a success or failure on the circuit breaker
 // based on the upstream call result. 5xx status codes, timeouts, and connection
 // errors count as failures. 4xx errors are valid API responses and do not
@@ -159,40 +499,144 @@ func (e *Executor) recordBreakerOutcome(breaker *circuitbreaker.Breaker, result
 		return
 	}
 	prevState := breaker.State()
+	if err == nil && result != nil && result.Status >= 500 {
+		err = fmt.Errorf("HTTP %d", result.Status)
+	}
 	if err != nil {
 		breaker.RecordFailure(err)
 		if prevState != circuitbreaker.Open && breaker.State() == circuitbreaker.Open {
 			stats := breaker.Stats()
 			e.logger.Warn("circuit breaker tripped", "component", "executor", "api", apiName, "failures", stats.ConsecutiveFails, "last_error", err)
-		}
-		return
-	}
-	if result != nil && result.Status >= 500 {
-		serverErr := fmt.Errorf("HTTP %d", result.Status)
-		breaker.RecordFailure(serverErr)
-		if prevState != circuitbreaker.Open && breaker.State() == circuitbreaker.Open {
-			stats := breaker.Stats()
-			e.logger.Warn("circuit breaker tripped", "component", "executor", "api", apiName, "failures", stats.ConsecutiveFails, "last_error", serverErr)
+			if e.breakerHook != nil {
+				e.breakerHook(apiName, true, err)
+			}
 		}
 		return
 	}
 	breaker.RecordSuccess()
 	if prevState != circuitbreaker.Closed && breaker.State() == circuitbreaker.Closed {
 		e.logger.Info("circuit breaker recovered", "component", "executor", "api", apiName)
+		if e.breakerHook != nil {
+			e.breakerHook(apiName, false, nil)
+		}
 	}
 }
 
 func (e *Executor) Execute(ctx context.Context, op *canonical.Operation, args map[string]any) (*Result, error) {
+	// Workflows have no upstream of their own; each step goes through
+	// Execute with its target service's limiter and breaker.
+	if op.Workflow != nil {
+		return e.executeWorkflow(ctx, op, args)
+	}
+	if op.Protocol == "builtin" {
+		return e.executeBuiltin(ctx, op, args)
+	}
+	// Composites are recorded and filtered through their sub-operation's
+	// Execute.
+	if op.RESTComposite != nil {
+		return e.executeOperation(ctx, op, args)
+	}
+	var fields projection
+	if op.Projection {
+		var err error
+		if fields, err = takeProjectionArgument(args); err != nil {
+			return nil, err
+		}
+	}
+	if handler := streamHandler(ctx); handler != nil {
+		// Streamed values reach the caller before the result does, so
+		// filter them the same way.
+		ctx = WithStreamHandler(ctx, func(index int, item any) {
+			item = e.filterStreamItem(op, item)
+			if fields != nil {
+				item = fields.apply(item)
+			}
+			handler(index, item)
+		})
+	}
+	hooks := e.services[op.ServiceName].Hooks
+	if !hooks.appliesTo(op) {
+		hooks = nil
+	}
+	if hooks != nil && hooks.before != nil {
+		var headers map[string]string
+		var err error
+		if args, headers, err = hooks.runBefore(ctx, op, args); err != nil {
+			return nil, err
+		}
+		ctx = withHookHeaders(ctx, headers)
+	}
+	var result *Result
+	var err error
+	var cacheKey string
+	var cached bool
+	cacheTTL := e.responseCacheTTL(op, hooks)
+	if cacheTTL > 0 {
+		var ok bool
+		if cacheKey, ok = responseCacheKey(op, args); !ok {
+			cacheTTL = 0
+		} else {
+			result, cached = e.responses.get(ctx, cacheKey)
+		}
+	}
+	switch {
+	case cached:
+		// Served without using the API's rate limit or quota.
+	case e.recorder != nil:
+		result, err = e.recorder.execute(ctx, op, args, func(ctx context.Context) (*Result, error) {
+			return e.executeOperation(ctx, op, args)
+		})
+	default:
+		result, err = e.executeOperation(ctx, op, args)
+	}
+	if err == nil && cacheTTL > 0 && !cached && cacheable(result) {
+		// Stored before the data policy and projection, which apply to
+		// every caller's copy.
+		e.responses.set(ctx, cacheKey, result, cacheTTL)
+	}
+	if err == nil && hooks != nil && hooks.after != nil {
+		err = hooks.runAfter(ctx, op, args, result)
+	}
+	if err == nil {
+		e.applyDataPolicy(ctx, op, result)
+		// Learn from what callers see, after the data policy.
+		if len(op.ResponseSchema) == 0 && result.Status >= 200 && result.Status < 300 {
+			e.learner.Observe(ctx, learnedSchemaKey(op), result.Body)
+		}
+		if fields != nil {
+			result.Body = fields.apply(result.Body)
+		}
+	}
+	return result, err
+}
+
+// executeOperation calls the upstream of an API operation.
+func (e *Executor) executeOperation(ctx context.Context, op *canonical.Operation, args map[string]any) (*Result, error) {
+
 	cfg, ok := e.services[op.ServiceName]
 	if !ok {
 		return nil, fmt.Errorf("unknown service %s", op.ServiceName)
 	}
+	// Composites are mocked and counted through their sub-operation's
+	// Execute.
+	if cfg.Mock && op.RESTComposite == nil {
+		return mockResult(op), nil
+	}
+	if e.quota != nil && op.RESTComposite == nil {
+		if err := e.quota.Reserve(ctx, op.ServiceName); err != nil {
+			return nil, err
+		}
+	}
 
 	// Check rate limit before any upstream call.
 	if limiter, ok := e.limiters[op.ServiceName]; ok {
+		waitStart := time.Now()
 		if err := limiter.Wait(ctx); err != nil {
 			return nil, err
 		}
+		if wait, ok := ctx.Value(limiterWaitKey{}).(*time.Duration); ok {
+			*wait += time.Since(waitStart)
+		}
 	}
 
 	// Check circuit breaker before any upstream call.
@@ -220,9 +664,30 @@ func (e *Executor) Execute(ctx context.Context, op *canonical.Operation, args ma
 		return result, err
 	}
 
-	// Dispatch custom protocols (email, etc.) to registered handlers.
+	// Dispatch database table tools to the SQL handler.
+	if op.Protocol == "sql" {
+		result, err := e.executeSQL(ctx, op, args, cfg)
+		e.recordBreakerOutcome(breaker, result, err, op.ServiceName)
+		return result, err
+	}
+
+	// Dispatch message broker tools to the messaging handler.
+	if op.Protocol == "messaging" {
+		result, err := e.executeMessaging(ctx, op, args, cfg)
+		e.recordBreakerOutcome(breaker, result, err, op.ServiceName)
+		return result, err
+	}
+
+	// Dispatch remote commands to the ssh handler.
+	if op.Protocol == "ssh" {
+		result, err := e.executeSSH(ctx, op, args, cfg)
+		e.recordBreakerOutcome(breaker, result, err, op.ServiceName)
+		return result, err
+	}
+
+	// Dispatch custom protocols (email, plugins, etc.) to registered handlers.
 	// These don't require BaseURL since they use their own protocol connections.
-	if handler, ok := e.protocols[op.Protocol]; ok && op.Protocol != "" {
+	if handler := e.protocolHandler(op.Protocol); handler != nil {
 		result, err := handler(ctx, op, args)
 		e.recordBreakerOutcome(breaker, result, err, op.ServiceName)
 		return result, err
@@ -233,15 +698,23 @@ func (e *Executor) Execute(ctx context.Context, op *canonical.Operation, args ma
 		return nil, fmt.Errorf("base URL is missing for service %s", op.ServiceName)
 	}
 
-	e.logger.Info("executing tool", "component", "executor", "tool", op.ToolName, "timeout", cfg.Timeout)
-	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	timeout := e.timeout(ctx, op, cfg)
+	e.logger.Info("executing tool", "component", "executor", "tool", op.ToolName, "timeout", timeout)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
+	if op.Prometheus != nil {
+		var err error
+		if args, err = preparePrometheusArgs(op.Prometheus, cfg.Prometheus, args, time.Now()); err != nil {
+			return nil, err
+		}
+	}
 	fullURL, err := resolveURL(cfg.BaseURL, op, args)
 	if err != nil {
 		return nil, err
 	}
-	e.logger.Debug("resolved URL", "component", "executor", "url", e.redactor.Redact(fullURL))
+	redactor := e.redactor.ForAPI(op.ServiceName)
+	e.logger.Debug("resolved URL", "component", "executor", "url", redactor.Redact(fullURL))
 	parsedURL, err := url.Parse(fullURL)
 	if err != nil {
 		return nil, fmt.Errorf("invalid URL: %w", err)
@@ -265,31 +738,99 @@ func (e *Executor) Execute(ctx context.Context, op *canonical.Operation, args ma
 		}
 		switch param.In {
 		case "query":
-			addQueryParam(query, param.Name, value)
+			name := param.Name
+			if param.QueryName != "" {
+				name = param.QueryName
+			}
+			if items, ok := value.([]any); ok && param.ListSeparator != "" {
+				parts := make([]string, len(items))
+				for i, item := range items {
+					parts[i] = valueToString(item)
+				}
+				value = strings.Join(parts, param.ListSeparator)
+			}
+			addQueryParam(query, name, value)
 		case "header":
-			headers.Set(param.Name, valueToString(value))
+			name := param.Name
+			if param.QueryName != "" {
+				name = param.QueryName
+			}
+			headers.Set(name, valueToString(value))
 		}
 	}
+	if op.SOQL != nil {
+		soql, err := buildSOQL(op.SOQL, args)
+		if err != nil {
+			return nil, err
+		}
+		query.Set("q", soql)
+	}
+	// Static headers from the spec, then per-API config headers (config wins),
+	// then those set by a before hook.
+	// Both may contain {{...}} templates evaluated fresh for every request.
 	for name, value := range op.StaticHeaders {
+		headers.Set(name, expandHeaderTemplate(ctx, value, op.ToolName))
+	}
+	for name, value := range cfg.Headers {
+		headers.Set(name, expandHeaderTemplate(ctx, value, op.ToolName))
+	}
+	for name, value := range hookHeaders(ctx) {
 		headers.Set(name, value)
 	}
-	parsedURL.RawQuery = query.Encode()
+	parsedURL.RawQuery = encodeQuery(query)
 
 	var bodyBytes []byte
+	var contentType string
+	var apqFull []byte // the query to register when the server lacks its hash
+	if op.RequestBody != nil {
+		contentType = op.RequestBody.ContentType
+	}
 	if op.JSONRPC != nil {
 		var err error
 		bodyBytes, err = buildJSONRPCBody(op, args)
 		if err != nil {
 			return nil, err
 		}
+	} else if op.ODataBatch != nil {
+		var err error
+		bodyBytes, contentType, err = buildODataBatchBody(op, cfg.BaseURL, args)
+		if err != nil {
+			return nil, err
+		}
+		if continueOnError, _ := args["continueOnError"].(bool); continueOnError {
+			headers.Set("Prefer", "odata.continue-on-error")
+		}
 	} else if op.GraphQL != nil {
 		var err error
 		bodyBytes, err = buildGraphQLBody(op, args)
 		if err != nil {
 			return nil, err
 		}
+		if cfg.APQ {
+			if bodyBytes, apqFull, err = apqBodies(bodyBytes); err != nil {
+				return nil, err
+			}
+		}
+	} else if op.AWS != nil {
+		var err error
+		if bodyBytes, err = buildAWSBody(op.AWS, args); err != nil {
+			return nil, err
+		}
+	} else if op.RequestBody != nil && op.RequestBody.Template != "" {
+		var err error
+		bodyBytes, err = renderBodyTemplate(contentType, op.RequestBody.Template, args)
+		if err != nil {
+			return nil, err
+		}
 	} else if op.RequestBody != nil {
 		bodyVal, ok := args["body"]
+		if op.RequestBody.Constants != nil || op.RequestBody.Defaults != nil {
+			var err error
+			if bodyVal, err = mergeBodyTemplate(op.RequestBody, bodyVal); err != nil {
+				return nil, err
+			}
+			ok = true
+		}
 		if !ok {
 			if op.SoapNamespace != "" {
 				params := map[string]string{}
@@ -300,7 +841,7 @@ func (e *Executor) Execute(ctx context.Context, op *canonical.Operation, args ma
 						return nil, fmt.Errorf("invalid parameters: %w", err)
 					}
 				}
-				soapBody, err := buildSOAPEnvelope(op.SoapNamespace, op.ID, params)
+				soapBody, err := buildSOAPEnvelope(op.SoapVersion, op.SoapNamespace, op.ID, soapHeaders(ctx, cfg, op.ToolName), params)
 				if err != nil {
 					return nil, fmt.Errorf("build soap: %w", err)
 				}
@@ -308,6 +849,12 @@ func (e *Executor) Execute(ctx context.Context, op *canonical.Operation, args ma
 			} else if op.RequestBody.Required {
 				return nil, fmt.Errorf("missing required request body")
 			}
+		} else if isFormContentType(contentType) {
+			var err error
+			bodyBytes, contentType, err = encodeFormBody(contentType, op.RequestBody.Schema, bodyVal)
+			if err != nil {
+				return nil, err
+			}
 		} else {
 			if strings.Contains(op.RequestBody.ContentType, "json") || op.RequestBody.ContentType == "" {
 				encoded, err := json.Marshal(bodyVal)
@@ -329,6 +876,15 @@ func (e *Executor) Execute(ctx context.Context, op *canonical.Operation, args ma
 	}
 
 	method := strings.ToUpper(op.Method)
+	// Every attempt sends the same key, so the upstream can tell a retry
+	// from a new request. A key from the arguments or headers config wins.
+	var idempotencyKey string
+	if cfg.IdempotencyHeader != "" && needsIdempotencyKey(op, method) {
+		if idempotencyKey = headers.Get(cfg.IdempotencyHeader); idempotencyKey == "" {
+			idempotencyKey = newUUID()
+			headers.Set(cfg.IdempotencyHeader, idempotencyKey)
+		}
+	}
 	attempts := cfg.Retries + 1
 	for attempt := 0; attempt < attempts; attempt++ {
 		req, err := http.NewRequestWithContext(ctx, method, parsedURL.String(), bytes.NewReader(bodyBytes))
@@ -341,21 +897,26 @@ func (e *Executor) Execute(ctx context.Context, op *canonical.Operation, args ma
 			}
 		}
 		if op.RequestBody != nil {
-			req.Header.Set("Content-Type", op.RequestBody.ContentType)
+			req.Header.Set("Content-Type", contentType)
 		}
 		if op.RequiresCrumb {
-			if field, crumb, ok, err := e.getCrumb(ctx, op.ServiceName, cfg); err != nil { //nolint:govet // intentional err shadow
+			if state, err := e.getCrumb(ctx, op.ServiceName, cfg); err != nil { //nolint:govet // intentional err shadow
 				return nil, err
-			} else if ok {
-				req.Header.Set(field, crumb)
+			} else if state != nil {
+				req.Header.Set(state.field, state.crumb)
+				// Crumbs are bound to the web session that issued them.
+				for _, c := range state.cookies {
+					req.AddCookie(c)
+				}
 			}
 		}
 		if err := e.applyAuth(req, op.ServiceName, cfg.Auth); err != nil { //nolint:govet // intentional err shadow
 			return nil, fmt.Errorf("apply auth: %w", err)
 		}
 
-		e.logger.Debug("HTTP request", "component", "executor", "method", method, "url", e.redactor.Redact(parsedURL.String()), "attempt", attempt+1, "max_attempts", attempts)
-		resp, err := e.client.Do(req)
+		e.logger.Debug("HTTP request", "component", "executor", "method", method, "url", redactor.Redact(parsedURL.String()), "attempt", attempt+1, "max_attempts", attempts)
+		resp, err := e.httpClient(op.ServiceName).Do(req)
+		e.recordExchange(ctx, redactor, req, bodyBytes, resp)
 		statusCode := 0
 		if resp != nil {
 			statusCode = resp.StatusCode
@@ -366,7 +927,7 @@ func (e *Executor) Execute(ctx context.Context, op *canonical.Operation, args ma
 		if err != nil {
 			if attempt < attempts-1 && isRetryable(method, 0, err) {
 				delay := retryDelay(attempt, 0)
-				e.logger.Warn("retrying request", "component", "executor", "api", op.ServiceName, "attempt", attempt+1, "delay", delay, "status", 0, "error", e.redactor.Redact(err.Error()))
+				e.logger.Warn("retrying request", "component", "executor", "api", op.ServiceName, "attempt", attempt+1, "delay", delay, "status", 0, "error", redactor.Redact(err.Error()))
 				if sleepErr := sleepContext(ctx, delay); sleepErr != nil {
 					failErr := fmt.Errorf("request failed: %w", err)
 					e.recordBreakerOutcome(breaker, nil, failErr, op.ServiceName)
@@ -379,8 +940,23 @@ func (e *Executor) Execute(ctx context.Context, op *canonical.Operation, args ma
 			return nil, failErr
 		}
 
+		if op.RequiresCrumb && resp.StatusCode == http.StatusForbidden {
+			e.dropCrumb(op.ServiceName)
+		}
 		result, retry, retryAfter, err := normalizeResponse(resp)
+		if apqFull != nil && persistedQueryMissing(result, err) {
+			// Send the query along with its hash; this doesn't use up a retry.
+			bodyBytes, apqFull = apqFull, nil
+			attempt--
+			continue
+		}
 		if err != nil {
+			if upErr, ok := err.(*UpstreamError); ok {
+				upErr.Hint = authHint(op, cfg.Auth, resp.StatusCode)
+				upErr.Headers = responseHeaders(resp, cfg.RespHeaders, redactor)
+				upErr.IdempotencyKey = idempotencyKey
+				upErr.redact(redactor)
+			}
 			return nil, err
 		}
 		if retry && attempt < attempts-1 && isRetryable(method, result.Status, nil) {
@@ -400,9 +976,27 @@ func (e *Executor) Execute(ctx context.Context, op *canonical.Operation, args ma
 				result = parsed
 			}
 		}
+		if op.AWS != nil && op.AWS.Protocol != "json" && op.AWS.Protocol != "rest-json" {
+			result = tryParseAWSXML(result, op.AWS)
+		}
 		if op.JSONRPC != nil {
 			result = tryUnwrapJSONRPC(result)
 		}
+		if op.ODataBatch != nil {
+			batched, err := unwrapODataBatch(result)
+			if err != nil {
+				return nil, err
+			}
+			result = batched
+		}
+		result.Headers = responseHeaders(resp, cfg.RespHeaders, redactor)
+		result.IdempotencyKey = idempotencyKey
+		if p := result.Pagination; p != nil {
+			p.NextArguments = nextArguments(op, args, p)
+			// Page links may echo query credentials back.
+			p.Next, p.Prev = redactor.Redact(p.Next), redactor.Redact(p.Prev)
+			p.First, p.Last = redactor.Redact(p.First), redactor.Redact(p.Last)
+		}
 		e.recordBreakerOutcome(breaker, result, nil, op.ServiceName)
 		return result, nil
 	}
@@ -416,17 +1010,36 @@ func buildJSONRPCBody(op *canonical.Operation, args map[string]any) ([]byte, err
 	if rpc == nil {
 		return nil, nil
 	}
+	payload := map[string]any{
+		"jsonrpc": "2.0",
+		"method":  rpc.MethodName,
+	}
+	if !rpc.Notification {
+		payload["id"] = 1
+	}
+	if rpc.ByPosition {
+		// Positional params keep declaration order; gaps are sent as null and
+		// trailing omitted params are dropped.
+		var params []any
+		last := -1
+		for i, p := range op.Parameters {
+			val, ok := args[p.Name]
+			params = append(params, val)
+			if ok {
+				last = i
+			}
+		}
+		if last >= 0 {
+			payload["params"] = params[:last+1]
+		}
+		return json.Marshal(payload)
+	}
 	params := map[string]any{}
 	for _, p := range op.Parameters {
 		if val, ok := args[p.Name]; ok {
 			params[p.Name] = val
 		}
 	}
-	payload := map[string]any{
-		"jsonrpc": "2.0",
-		"method":  rpc.MethodName,
-		"id":      1,
-	}
 	if len(params) > 0 {
 		payload["params"] = params
 	}
@@ -468,6 +1081,22 @@ func buildGraphQLBody(op *canonical.Operation, args map[string]any) ([]byte, err
 	if gql.Composite != nil {
 		return buildCompositeGraphQLBody(op, args)
 	}
+	if gql.Document != "" {
+		payload := map[string]any{"query": gql.Document}
+		if gql.OperationName != "" {
+			payload["operationName"] = gql.OperationName
+		}
+		vars := map[string]any{}
+		for name := range gql.ArgTypes {
+			if value, ok := args[name]; ok {
+				vars[name] = value
+			}
+		}
+		if len(vars) > 0 {
+			payload["variables"] = vars
+		}
+		return json.Marshal(payload)
+	}
 
 	selection := ""
 	if val, ok := args["selection"]; ok {
@@ -703,23 +1332,43 @@ func isAuthParam(in, name string) bool {
 }
 
 func (e *Executor) applyAuth(req *http.Request, apiName string, auth *config.AuthConfig) error {
+	if err := e.setAuthHeaders(req.Header, apiName, auth); err != nil {
+		return err
+	}
+	// Signatures may cover the auth headers, so they come last.
+	if err := e.signRequest(req, apiName); err != nil {
+		return err
+	}
+	if auth != nil && auth.Type == "aws-sigv4" {
+		// SigV4 signs the headers set above, any other signature included.
+		return signSigV4(req, auth, time.Now())
+	}
+	return nil
+}
+
+// setAuthHeaders sets the credentials of auth in headers.
+func (e *Executor) setAuthHeaders(headers http.Header, apiName string, auth *config.AuthConfig) error {
 	if auth == nil {
 		return nil
 	}
 	switch auth.Type {
 	case "bearer":
-		req.Header.Set("Authorization", "Bearer "+auth.Token)
+		headers.Set("Authorization", "Bearer "+auth.Token)
 	case "basic":
 		cred := base64.StdEncoding.EncodeToString([]byte(auth.Username + ":" + auth.Password))
-		req.Header.Set("Authorization", "Basic "+cred)
+		headers.Set("Authorization", "Basic "+cred)
 	case "api-key":
-		req.Header.Set(auth.Header, auth.Value)
+		headers.Set(auth.Header, auth.Value)
 	case "oauth2":
-		token, err := e.oauth2Mgr.GetAccessToken(apiName, auth)
+		var onCode func(DeviceCode)
+		if e.deviceCodeHook != nil {
+			onCode = func(code DeviceCode) { e.deviceCodeHook(apiName, code) }
+		}
+		token, err := e.oauth2Mgr.GetAccessToken(e.profile+"/"+apiName, auth, onCode)
 		if err != nil {
 			return err
 		}
-		req.Header.Set("Authorization", "Bearer "+token)
+		headers.Set("Authorization", "Bearer "+token)
 	}
 	return nil
 }
@@ -739,6 +1388,26 @@ func addQueryParam(values url.Values, name string, value any) {
 	}
 }
 
+// encodeQuery is url.Values.Encode, except that "$" stays literal in
+// parameter names: OData services recognise system query options such as
+// $filter only by their unescaped name.
+func encodeQuery(values url.Values) string {
+	encoded := values.Encode()
+	if !strings.Contains(encoded, "%24") {
+		return encoded
+	}
+	pairs := strings.Split(encoded, "&")
+	for i, pair := range pairs {
+		key, value, found := strings.Cut(pair, "=")
+		key = strings.ReplaceAll(key, "%24", "$")
+		if found {
+			key += "=" + value
+		}
+		pairs[i] = key
+	}
+	return strings.Join(pairs, "&")
+}
+
 func addQueryParamsFromObject(values url.Values, params any) {
 	switch v := params.(type) {
 	case map[string]any:
@@ -768,6 +1437,9 @@ func valueToString(value any) string {
 		return v
 	case fmt.Stringer:
 		return v.String()
+	case float64:
+		// JSON numbers arrive as float64; avoid exponent notation like 1e+06.
+		return strconv.FormatFloat(v, 'f', -1, 64)
 	default:
 		return fmt.Sprint(value)
 	}
@@ -775,7 +1447,7 @@ func valueToString(value any) string {
 
 var pathParamRE = regexp.MustCompile(`\{([^}]+)\}`)
 
-func fillPath(path string, args map[string]any) (string, error) {
+func fillPath(path string, params []canonical.Parameter, args map[string]any) (string, error) {
 	matches := pathParamRE.FindAllStringSubmatchIndex(path, -1)
 	if len(matches) == 0 {
 		return path, nil
@@ -789,17 +1461,71 @@ func fillPath(path string, args map[string]any) (string, error) {
 		if !ok {
 			return "", fmt.Errorf("missing required path parameter %s", name)
 		}
-		b.WriteString(url.PathEscape(valueToString(val)))
+		if sep := segmentSeparator(params, name); sep != "" {
+			b.WriteString(joinPathSegments(val, sep))
+		} else {
+			b.WriteString(url.PathEscape(valueToString(val)))
+		}
 		last = m[1]
 	}
 	b.WriteString(path[last:])
 	return b.String(), nil
 }
 
+// missingPathParam reports whether any placeholder in path has no argument.
+func missingPathParam(path string, args map[string]any) bool {
+	for _, m := range pathParamRE.FindAllStringSubmatch(path, -1) {
+		if val, ok := args[m[1]]; !ok || valueToString(val) == "" {
+			return true
+		}
+	}
+	return false
+}
+
+func segmentSeparator(params []canonical.Parameter, name string) string {
+	for _, p := range params {
+		if p.Name == name && p.In == "path" {
+			return p.SegmentSeparator
+		}
+	}
+	return ""
+}
+
+// joinPathSegments renders a nested path parameter. The value may be a list
+// of segments, a "/"-separated path, or a path already joined with sep
+// (e.g. "team/job/app" for sep "/job/"). Segments that literally equal the
+// separator's name are only preserved when passed as a list.
+func joinPathSegments(val any, sep string) string {
+	var segments []string
+	if list, ok := val.([]any); ok {
+		for _, item := range list {
+			segments = append(segments, valueToString(item))
+		}
+	} else {
+		raw := "/" + strings.Trim(valueToString(val), "/") + "/"
+		raw = strings.ReplaceAll(raw, sep, "/")
+		segments = strings.Split(strings.Trim(raw, "/"), "/")
+	}
+	escaped := make([]string, 0, len(segments))
+	for _, seg := range segments {
+		if seg = strings.TrimSpace(seg); seg != "" {
+			escaped = append(escaped, url.PathEscape(seg))
+		}
+	}
+	return strings.Join(escaped, sep)
+}
+
 func resolveURL(base string, op *canonical.Operation, args map[string]any) (string, error) {
 	base = strings.TrimRight(base, "/")
+	if op.FallbackPath != "" && missingPathParam(op.Path, args) {
+		path, err := fillPath(op.FallbackPath, op.Parameters, args)
+		if err != nil {
+			return "", err
+		}
+		return base + path, nil
+	}
 	if op.DynamicURLParam == "" {
-		path, err := fillPath(op.Path, args)
+		path, err := fillPath(op.Path, op.Parameters, args)
 		if err != nil {
 			return "", err
 		}
@@ -811,7 +1537,7 @@ func resolveURL(base string, op *canonical.Operation, args map[string]any) (stri
 		target = strings.TrimSpace(valueToString(val))
 	}
 	if target == "" {
-		path, err := fillPath(op.Path, args)
+		path, err := fillPath(op.Path, op.Parameters, args)
 		if err != nil {
 			return "", err
 		}
@@ -867,6 +1593,30 @@ func sleepContext(ctx context.Context, d time.Duration) error {
 	}
 }
 
+// idempotencyHeader returns the header that carries an API's idempotency
+// keys, or "" when it does not want them.
+func idempotencyHeader(idem *config.IdempotencyConfig) string {
+	switch {
+	case idem == nil:
+		return config.DefaultIdempotencyHeader
+	case idem.Disabled:
+		return ""
+	case idem.Header != "":
+		return idem.Header
+	}
+	return config.DefaultIdempotencyHeader
+}
+
+// needsIdempotencyKey reports whether a call may have side effects that a
+// retry would repeat. GraphQL sends every operation as a POST, so only its
+// mutations count.
+func needsIdempotencyKey(op *canonical.Operation, method string) bool {
+	if op.GraphQL != nil {
+		return op.GraphQL.OperationType == "mutation"
+	}
+	return !isIdempotent(method)
+}
+
 // isIdempotent returns true for HTTP methods that are safe to retry on any
 // server error.
 func isIdempotent(method string) bool {
@@ -975,6 +1725,10 @@ func parseRetryAfter(value string) time.Duration {
 // request may be retried (5xx or 429). The third return value carries the
 // parsed Retry-After header duration (0 if absent/unparseable).
 func normalizeResponse(resp *http.Response) (*Result, bool, time.Duration, error) {
+	if resp.StatusCode < 300 && isJSONStream(resp.Header.Get("Content-Type")) {
+		result, err := normalizeStream(resp, resp.Header.Get("Content-Type"))
+		return result, false, 0, err
+	}
 	defer resp.Body.Close()
 	bodyBytes, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
 	if err != nil {
@@ -982,12 +1736,12 @@ func normalizeResponse(resp *http.Response) (*Result, bool, time.Duration, error
 	}
 	contentType := resp.Header.Get("Content-Type")
 	retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"))
-
-	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
-		return &Result{Status: resp.StatusCode, ContentType: contentType}, true, retryAfter, nil
-	}
-	if resp.StatusCode >= 400 {
-		return nil, false, 0, fmt.Errorf("http error status %d", resp.StatusCode)
+	// SOAP services return binary documents as MTOM attachments.
+	if msg, rootType, ok, err := resolveXOP(contentType, bodyBytes); ok {
+		if err != nil {
+			return nil, false, 0, err
+		}
+		bodyBytes, contentType = msg, rootType
 	}
 
 	var body any
@@ -996,6 +1750,10 @@ func normalizeResponse(resp *http.Response) (*Result, bool, time.Duration, error
 	} else if strings.Contains(contentType, "application/json") {
 		if err := json.Unmarshal(bodyBytes, &body); err != nil {
 			body = string(bodyBytes)
+			// Streaming endpoints may send concatenated objects as JSON.
+			if items, ok := splitJSONValues(bodyBytes); ok {
+				body = items
+			}
 		}
 	} else if json.Unmarshal(bodyBytes, &body) == nil {
 		// Some APIs return JSON with incorrect content-type; accept it.
@@ -1003,13 +1761,47 @@ func normalizeResponse(resp *http.Response) (*Result, bool, time.Duration, error
 		body = string(bodyBytes)
 	}
 
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+		return &Result{Status: resp.StatusCode, ContentType: contentType, Body: body}, true, retryAfter, nil
+	}
+	if resp.StatusCode >= 400 {
+		return nil, false, 0, newUpstreamError(resp, body)
+	}
+
 	return &Result{
 		Status:      resp.StatusCode,
 		ContentType: contentType,
 		Body:        body,
+		Pagination:  parsePagination(resp, body),
 	}, false, 0, nil
 }
 
+// responseHeaders returns the response headers named in allowed, keyed by
+// their canonical name. Location headers are resolved against the request
+// URL so agents can follow them directly.
+func responseHeaders(resp *http.Response, allowed []string, redactor *redact.Redactor) map[string]string {
+	if len(allowed) == 0 {
+		return nil
+	}
+	out := map[string]string{}
+	for _, name := range allowed {
+		name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+		values := resp.Header.Values(name)
+		if len(values) == 0 {
+			continue
+		}
+		value := strings.Join(values, ", ")
+		if (name == "Location" || name == "Content-Location") && resp.Request != nil {
+			value = resolveReference(resp.Request.URL, value)
+		}
+		out[name] = redactor.Redact(value)
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
 func tryParseSOAP(result *Result) (*Result, bool) {
 	if result == nil || result.Body == nil {
 		return result, false
@@ -1039,6 +1831,18 @@ type xmlNode struct {
 }
 
 func parseSOAPXML(input string) (any, error) {
+	root, err := parseXMLTree(input)
+	if err != nil {
+		return nil, err
+	}
+	if body := findSOAPBody(root); body != nil {
+		return buildBodyValue(body), nil
+	}
+	return map[string]any{root.name: buildNodeValue(root)}, nil
+}
+
+// parseXMLTree reads an XML document into a tree of elements.
+func parseXMLTree(input string) (*xmlNode, error) {
 	decoder := xml.NewDecoder(strings.NewReader(input))
 	var stack []*xmlNode
 	var root *xmlNode
@@ -1074,12 +1878,9 @@ func parseSOAPXML(input string) (any, error) {
 		}
 	}
 	if root == nil {
-		return nil, fmt.Errorf("soap: empty document")
-	}
-	if body := findSOAPBody(root); body != nil {
-		return buildBodyValue(body), nil
+		return nil, fmt.Errorf("empty XML document")
 	}
-	return map[string]any{root.name: buildNodeValue(root)}, nil
+	return root, nil
 }
 
 func findSOAPBody(node *xmlNode) *xmlNode {
@@ -1136,13 +1937,33 @@ func addChildValue(out map[string]any, name string, value any) {
 	out[name] = value
 }
 
-func buildSOAPEnvelope(namespace, operation string, params map[string]string) (string, error) {
+// SOAP envelope namespaces by SOAP version.
+const (
+	soap11EnvelopeNS = "http://schemas.xmlsoap.org/soap/envelope/"
+	soap12EnvelopeNS = "http://www.w3.org/2003/05/soap-envelope"
+)
+
+// buildSOAPEnvelope wraps the operation's parameters in a SOAP 1.1 or 1.2
+// envelope. headers are XML blocks written to the envelope's Header as
+// they are.
+func buildSOAPEnvelope(version, namespace, operation string, headers []string, params map[string]string) (string, error) {
 	if operation == "" {
 		return "", fmt.Errorf("missing operation")
 	}
+	envelopeNS := soap11EnvelopeNS
+	if version == "1.2" {
+		envelopeNS = soap12EnvelopeNS
+	}
 	var b strings.Builder
 	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>`)
-	b.WriteString(`<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/">`)
+	b.WriteString(`<soapenv:Envelope xmlns:soapenv="` + envelopeNS + `">`)
+	if len(headers) > 0 {
+		b.WriteString(`<soapenv:Header>`)
+		for _, block := range headers {
+			b.WriteString(block)
+		}
+		b.WriteString(`</soapenv:Header>`)
+	}
 	b.WriteString(`<soapenv:Body>`)
 	if namespace != "" {
 		b.WriteString("<")
@@ -1273,6 +2094,9 @@ func TruncateResult(result *Result, maxBytes int) *Result {
 				"_truncated": true,
 				"_total":     total,
 			},
+			Pagination: result.Pagination,
+			Headers:    result.Headers,
+			Filtered:   result.Filtered,
 		}
 	}
 
@@ -1285,76 +2109,113 @@ func TruncateResult(result *Result, maxBytes int) *Result {
 			"data":                truncated,
 			"_truncated_at_bytes": maxBytes,
 		},
+		Pagination: result.Pagination,
+		Headers:    result.Headers,
+		Filtered:   result.Filtered,
 	}
 }
 
 type crumbState struct {
 	field     string
 	crumb     string
+	cookies   []*http.Cookie
 	expiresAt time.Time
 	disabled  bool
 }
 
-func (e *Executor) getCrumb(ctx context.Context, serviceName string, cfg serviceConfig) (string, string, bool, error) {
+const defaultCrumbIssuerPath = "/crumbIssuer/api/json"
+
+// getCrumb returns the Jenkins CSRF crumb to send with a write, or nil when
+// none is needed (crumb mode "never", or no crumb issuer in "auto" mode).
+func (e *Executor) getCrumb(ctx context.Context, serviceName string, cfg serviceConfig) (*crumbState, error) {
+	mode, issuerPath, ttl := "auto", defaultCrumbIssuerPath, 10*time.Minute
+	if c := cfg.Crumb; c != nil {
+		if c.Mode != "" {
+			mode = c.Mode
+		}
+		if c.IssuerPath != "" {
+			issuerPath = "/" + strings.TrimLeft(c.IssuerPath, "/")
+		}
+		if c.TTLSeconds > 0 {
+			ttl = time.Duration(c.TTLSeconds) * time.Second
+		}
+	}
+	if mode == "never" {
+		return nil, nil
+	}
+
 	now := time.Now()
 	e.crumbMu.Lock()
 	state := e.crumbs[serviceName]
 	if state != nil {
 		if state.disabled {
 			e.crumbMu.Unlock()
-			return "", "", false, nil
+			return nil, nil
 		}
 		if now.Before(state.expiresAt) && state.field != "" && state.crumb != "" {
-			field := state.field
-			crumb := state.crumb
 			e.crumbMu.Unlock()
-			return field, crumb, true, nil
+			return state, nil
 		}
 	}
 	e.crumbMu.Unlock()
 
-	crumbURL := strings.TrimRight(cfg.BaseURL, "/") + "/crumbIssuer/api/json"
+	crumbURL := strings.TrimRight(cfg.BaseURL, "/") + issuerPath
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, crumbURL, nil)
 	if err != nil {
-		return "", "", false, fmt.Errorf("crumb request failed")
+		return nil, fmt.Errorf("crumb request failed")
 	}
 	req.Header.Set("Accept", "application/json")
 	if err := e.applyAuth(req, serviceName, cfg.Auth); err != nil { //nolint:govet // intentional err shadow
-		return "", "", false, fmt.Errorf("crumb auth: %w", err)
+		return nil, fmt.Errorf("crumb auth: %w", err)
 	}
 
-	resp, err := e.client.Do(req)
+	resp, err := e.httpClient(serviceName).Do(req)
 	if err != nil {
-		return "", "", false, fmt.Errorf("crumb request failed")
+		return nil, fmt.Errorf("crumb request failed")
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode == http.StatusNotFound {
+		if mode == "always" {
+			return nil, fmt.Errorf("crumb issuer %s not found", issuerPath)
+		}
 		e.crumbMu.Lock()
 		e.crumbs[serviceName] = &crumbState{disabled: true}
 		e.crumbMu.Unlock()
-		return "", "", false, nil
+		return nil, nil
 	}
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return "", "", false, fmt.Errorf("crumb request failed with status %d", resp.StatusCode)
+		return nil, fmt.Errorf("crumb request failed with status %d", resp.StatusCode)
 	}
 	var payload struct {
 		Field string `json:"crumbRequestField"`
 		Crumb string `json:"crumb"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
-		return "", "", false, fmt.Errorf("crumb response parse failed")
+		return nil, fmt.Errorf("crumb response parse failed")
 	}
 	if payload.Field == "" || payload.Crumb == "" {
-		return "", "", false, fmt.Errorf("crumb response missing fields")
+		return nil, fmt.Errorf("crumb response missing fields")
 	}
-	e.crumbMu.Lock()
-	e.crumbs[serviceName] = &crumbState{
+	state = &crumbState{
 		field:     payload.Field,
 		crumb:     payload.Crumb,
-		expiresAt: now.Add(10 * time.Minute),
+		cookies:   resp.Cookies(),
+		expiresAt: now.Add(ttl),
+	}
+	e.crumbMu.Lock()
+	e.crumbs[serviceName] = state
+	e.crumbMu.Unlock()
+	return state, nil
+}
+
+// dropCrumb forgets a cached crumb after Jenkins rejected it, so the next
+// write fetches a fresh one.
+func (e *Executor) dropCrumb(serviceName string) {
+	e.crumbMu.Lock()
+	if state := e.crumbs[serviceName]; state != nil && !state.disabled {
+		delete(e.crumbs, serviceName)
 	}
 	e.crumbMu.Unlock()
-	return payload.Field, payload.Crumb, true, nil
 }
 
 // gRPC execution
@@ -1388,8 +2249,15 @@ func (e *Executor) executeGRPC(ctx context.Context, op *canonical.Operation, arg
 		return nil, fmt.Errorf("grpc operation %s missing GRPCMeta", op.ID)
 	}
 
-	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	// The deadline travels to the server as grpc-timeout, so it stops
+	// working on calls the executor has given up on.
+	timeout := e.timeout(ctx, op, cfg)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
+	ctx, err := e.grpcContext(ctx, op.ServiceName, op, cfg)
+	if err != nil {
+		return nil, err
+	}
 
 	// Pass full URL to getGRPCConn which handles scheme-based TLS selection.
 	target := cfg.BaseURL
@@ -1399,17 +2267,9 @@ func (e *Executor) executeGRPC(ctx context.Context, op *canonical.Operation, arg
 		return nil, err
 	}
 
-	// Use reflection to get the method descriptor.
-	refClient := grpcreflect.NewClientAuto(ctx, conn)
-	defer refClient.Reset()
-
-	svcDesc, err := refClient.ResolveService(op.GRPCMeta.ServiceFullName)
+	methodDesc, err := e.grpcMethod(ctx, target, op.GRPCMeta.ServiceFullName, op.GRPCMeta.MethodName)
 	if err != nil {
-		return nil, fmt.Errorf("grpc: resolve service %s: %w", op.GRPCMeta.ServiceFullName, err)
-	}
-	methodDesc := svcDesc.FindMethodByName(op.GRPCMeta.MethodName)
-	if methodDesc == nil {
-		return nil, fmt.Errorf("grpc: method %s not found in %s", op.GRPCMeta.MethodName, op.GRPCMeta.ServiceFullName)
+		return nil, err
 	}
 
 	// Build request message from args using dynamic protobuf.
@@ -1429,6 +2289,10 @@ func (e *Executor) executeGRPC(ctx context.Context, op *canonical.Operation, arg
 	respMsg := dynamicpb.NewMessage(outputDesc)
 	fullMethod := fmt.Sprintf("/%s/%s", op.GRPCMeta.ServiceFullName, op.GRPCMeta.MethodName)
 	if err := conn.Invoke(ctx, fullMethod, reqMsg, respMsg); err != nil { //nolint:govet // intentional err shadow
+		e.invalidateGRPCService(target, op.GRPCMeta.ServiceFullName, err)
+		if status.Code(err) == codes.DeadlineExceeded {
+			return nil, fmt.Errorf("grpc: %s did not finish within %s; pass %s to allow more time", fullMethod, timeout, TimeoutArgument)
+		}
 		return nil, fmt.Errorf("grpc: invoke %s: %w", fullMethod, err)
 	}
 
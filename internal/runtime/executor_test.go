@@ -8,6 +8,7 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 
@@ -156,6 +157,75 @@ func TestExecutorRetriesOn500(t *testing.T) {
 	}
 }
 
+func TestExecutorIdempotencyKeyReusedAcrossRetries(t *testing.T) {
+	var keys []string
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		attempt := len(keys)
+		mu.Unlock()
+		if r.Method == http.MethodPost && attempt == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"ok": true})
+	}))
+	defer server.Close()
+
+	exec := newExecutor(t, server.URL, nil, 1)
+	op := &canonical.Operation{ServiceName: "api", Method: "post", Path: "/orders"}
+	result, err := exec.Execute(context.Background(), op, map[string]any{})
+	if err != nil {
+		t.Fatalf("execute failed: %v", err)
+	}
+	if len(keys) != 2 || keys[0] == "" || keys[0] != keys[1] {
+		t.Fatalf("keys across attempts = %q", keys)
+	}
+	if result.IdempotencyKey != keys[0] {
+		t.Fatalf("result key = %q, sent %q", result.IdempotencyKey, keys[0])
+	}
+
+	// A new call is a new logical request; idempotent methods get no key.
+	if _, err := exec.Execute(context.Background(), op, map[string]any{}); err != nil {
+		t.Fatalf("execute failed: %v", err)
+	}
+	if _, err := exec.Execute(context.Background(), &canonical.Operation{ServiceName: "api", Method: "put", Path: "/orders/1"}, map[string]any{}); err != nil {
+		t.Fatalf("execute failed: %v", err)
+	}
+	if len(keys) != 4 || keys[2] == "" || keys[2] == keys[0] || keys[3] != "" {
+		t.Fatalf("keys = %q", keys)
+	}
+}
+
+func TestExecutorIdempotencyKeyOnlyForGraphQLMutations(t *testing.T) {
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":{}}`))
+	}))
+	defer server.Close()
+
+	exec := newExecutor(t, server.URL, nil, 0)
+	// GraphQL posts queries too; they are reads and get no key.
+	for _, opType := range []string{"query", "mutation"} {
+		op := &canonical.Operation{
+			ServiceName: "api", Method: "post", Path: "/graphql",
+			RequestBody: &canonical.RequestBody{ContentType: "application/json"},
+			GraphQL:     &canonical.GraphQLOperation{OperationType: opType, FieldName: "ping"},
+		}
+		if _, err := exec.Execute(context.Background(), op, map[string]any{}); err != nil {
+			t.Fatalf("%s: %v", opType, err)
+		}
+	}
+	if len(keys) != 2 || keys[0] != "" || keys[1] == "" {
+		t.Fatalf("keys = %q, want none for the query and one for the mutation", keys)
+	}
+}
+
 func TestExecutorDynamicURL(t *testing.T) {
 	infoCh := make(chan string, 1)
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -216,6 +286,46 @@ func TestExecutorQueryParamsObject(t *testing.T) {
 	}
 }
 
+func TestExecutorODataQueryOptions(t *testing.T) {
+	queryCh := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queryCh <- r.URL.RawQuery
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"value": []any{}})
+	}))
+	defer server.Close()
+
+	exec := newExecutor(t, server.URL, nil, 0)
+	op := &canonical.Operation{
+		ServiceName: "api",
+		Method:      "get",
+		Path:        "/Movies",
+		Parameters: []canonical.Parameter{
+			{Name: "filter", In: "query", QueryName: "$filter"},
+			{Name: "select", In: "query", QueryName: "$select", ListSeparator: ","},
+			{Name: "top", In: "query", QueryName: "$top"},
+			{Name: "count", In: "query", QueryName: "$count"},
+		},
+		QueryParamsObject: "queryOptions",
+	}
+	_, err := exec.Execute(context.Background(), op, map[string]any{
+		"filter":       "Year gt 2000",
+		"select":       []any{"Title", "Year"},
+		"top":          float64(1000000),
+		"count":        true,
+		"queryOptions": map[string]any{"$search": "star"},
+	})
+	if err != nil {
+		t.Fatalf("execute failed: %v", err)
+	}
+	raw := <-queryCh
+	for _, want := range []string{"$filter=Year+gt+2000", "$select=Title%2CYear", "$top=1000000", "$count=true", "$search=star"} {
+		if !strings.Contains(raw, want) {
+			t.Fatalf("query %q is missing %q", raw, want)
+		}
+	}
+}
+
 func TestExecutorCrumbForWrite(t *testing.T) {
 	crumbCh := make(chan string, 1)
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -284,3 +394,187 @@ func newExecutor(t *testing.T, baseURL string, auth *config.AuthConfig, retries
 func intPtr(val int) *int {
 	return &val
 }
+
+func TestExecutorHeaderTemplates(t *testing.T) {
+	headerCh := make(chan http.Header, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headerCh <- r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"ok": true})
+	}))
+	defer server.Close()
+
+	exec := newExecutor(t, server.URL, nil, 0)
+	op := &canonical.Operation{
+		ServiceName: "api",
+		ToolName:    "api__listItems",
+		Method:      "get",
+		Path:        "/items",
+		StaticHeaders: map[string]string{
+			"X-Request-ID":   "{{uuid}}",
+			"X-On-Behalf-Of": "{{mcp.client_name}}/{{mcp.client_version}}",
+			"X-Tool":         "{{tool}}",
+			"X-Unknown":      "{{nope}}",
+			"X-Env":          "{{env.HOME}}",
+		},
+	}
+	ctx := runtime.WithRequestMeta(context.Background(), runtime.RequestMeta{
+		SessionID:     "sess-1",
+		ClientName:    "claude",
+		ClientVersion: "1.2",
+	})
+	for i := 0; i < 2; i++ {
+		if _, err := exec.Execute(ctx, op, map[string]any{}); err != nil {
+			t.Fatalf("execute failed: %v", err)
+		}
+	}
+	first, second := <-headerCh, <-headerCh
+	if first.Get("X-On-Behalf-Of") != "claude/1.2" {
+		t.Fatalf("unexpected actor header: %q", first.Get("X-On-Behalf-Of"))
+	}
+	if first.Get("X-Tool") != "api__listItems" {
+		t.Fatalf("unexpected tool header: %q", first.Get("X-Tool"))
+	}
+	if first.Get("X-Unknown") != "{{nope}}" {
+		t.Fatalf("unknown placeholder should be left untouched, got %q", first.Get("X-Unknown"))
+	}
+	if first.Get("X-Env") != "{{env.HOME}}" {
+		t.Fatalf("environment must not be readable from headers, got %q", first.Get("X-Env"))
+	}
+	id1, id2 := first.Get("X-Request-ID"), second.Get("X-Request-ID")
+	if len(id1) != 36 || id1 == id2 {
+		t.Fatalf("expected fresh uuid per request, got %q and %q", id1, id2)
+	}
+}
+
+func TestExecutorFallbackPath(t *testing.T) {
+	pathCh := make(chan string, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pathCh <- r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"items": []any{}})
+	}))
+	defer server.Close()
+
+	exec := newExecutor(t, server.URL, nil, 0)
+	op := &canonical.Operation{
+		ServiceName:  "api",
+		Method:       "get",
+		Path:         "/api/v1/namespaces/{namespace}/pods",
+		FallbackPath: "/api/v1/pods",
+		Parameters:   []canonical.Parameter{{Name: "namespace", In: "path"}},
+	}
+	if _, err := exec.Execute(context.Background(), op, map[string]any{"namespace": "prod"}); err != nil {
+		t.Fatalf("execute failed: %v", err)
+	}
+	if got := <-pathCh; got != "/api/v1/namespaces/prod/pods" {
+		t.Errorf("with namespace: path = %s", got)
+	}
+	if _, err := exec.Execute(context.Background(), op, map[string]any{}); err != nil {
+		t.Fatalf("execute failed: %v", err)
+	}
+	if got := <-pathCh; got != "/api/v1/pods" {
+		t.Errorf("without namespace: path = %s", got)
+	}
+}
+
+func TestExecutorReportsPagination(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Link", `<`+"http://"+r.Host+`/items?page=3>; rel="next"`)
+		_, _ = io.WriteString(w, `[{"id":1}]`)
+	}))
+	defer server.Close()
+
+	exec := newExecutor(t, server.URL, nil, 0)
+	op := &canonical.Operation{
+		ServiceName: "api",
+		ToolName:    "api__listItems",
+		Method:      "get",
+		Path:        "/items",
+		Parameters:  []canonical.Parameter{{Name: "page", In: "query", Schema: map[string]any{"type": "integer"}}},
+	}
+	result, err := exec.Execute(context.Background(), op, map[string]any{"page": float64(2)})
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	p := result.Pagination
+	if p == nil || p.Source != "link_header" || p.Next != server.URL+"/items?page=3" || p.NextArguments["page"] != int64(3) {
+		t.Fatalf("unexpected pagination %+v", p)
+	}
+}
+
+func TestExecutorResponseHeaderAllowlist(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", "/pets/42")
+		w.Header().Set("X-RateLimit-Remaining", "17")
+		w.Header().Set("Set-Cookie", "session=abc")
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{APIs: []config.APIConfig{{
+		Name: "api", SpecURL: server.URL + "/openapi.json", BaseURLOverride: server.URL,
+		ResponseHeaders: []string{"location", "X-RateLimit-Remaining", "ETag"},
+	}}}
+	cfg.ApplyDefaults()
+	exec, err := runtime.NewExecutor(cfg, []*canonical.Service{{Name: "api", BaseURL: server.URL}}, logging.Discard(), redact.NewRedactor())
+	if err != nil {
+		t.Fatalf("executor init failed: %v", err)
+	}
+	create := &canonical.Operation{ServiceName: "api", ToolName: "api__createPet", Method: "post", Path: "/pets"}
+	result, err := exec.Execute(context.Background(), create, map[string]any{})
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	want := map[string]string{"Location": server.URL + "/pets/42", "X-Ratelimit-Remaining": "17"}
+	if len(result.Headers) != 2 || result.Headers["Location"] != want["Location"] || result.Headers["X-Ratelimit-Remaining"] != "17" {
+		t.Fatalf("headers = %v, want %v", result.Headers, want)
+	}
+
+	remove := &canonical.Operation{ServiceName: "api", ToolName: "api__deletePet", Method: "delete", Path: "/pets"}
+	_, err = exec.Execute(context.Background(), remove, map[string]any{})
+	upErr, ok := err.(*runtime.UpstreamError)
+	if !ok || upErr.Headers["X-Ratelimit-Remaining"] != "17" {
+		t.Fatalf("expected upstream error with headers, got %#v", err)
+	}
+}
+
+func TestExecutorLearnsResponseSchema(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"id": 7, "name": "Ada"})
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		APIs:           []config.APIConfig{{Name: "api", SpecURL: "http://example.com/spec", BaseURLOverride: server.URL}},
+		SchemaLearning: &config.SchemaLearningConfig{SampleRate: 1},
+	}
+	cfg.ApplyDefaults()
+	exec, err := runtime.NewExecutor(cfg, []*canonical.Service{{Name: "api", BaseURL: server.URL}}, logging.Discard(), redact.NewRedactor())
+	if err != nil {
+		t.Fatal(err)
+	}
+	op := &canonical.Operation{ServiceName: "api", ID: "getUser", Method: "get", Path: "/users/7"}
+	if exec.LearnedResponseSchema(op) != nil {
+		t.Fatal("schema learned before any call")
+	}
+	if _, err := exec.Execute(context.Background(), op, map[string]any{}); err != nil {
+		t.Fatal(err)
+	}
+	schema := exec.LearnedResponseSchema(op)
+	props, _ := schema["properties"].(map[string]any)
+	if schema["type"] != "object" || props["id"] == nil || props["name"] == nil {
+		t.Fatalf("learned schema = %#v", schema)
+	}
+
+	declared := &canonical.Operation{ServiceName: "api", ID: "getUser", ResponseSchema: map[string]any{"type": "object"}}
+	if exec.LearnedResponseSchema(declared) != nil {
+		t.Fatal("learned schema served for an operation with a declared one")
+	}
+}
@@ -0,0 +1,134 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/url"
+	"sort"
+	"strings"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+)
+
+// isFormContentType reports whether a request body is sent as a form.
+func isFormContentType(contentType string) bool {
+	ct := strings.ToLower(contentType)
+	return strings.HasPrefix(ct, "application/x-www-form-urlencoded") || strings.HasPrefix(ct, "multipart/form-data")
+}
+
+// encodeFormBody encodes a body object as application/x-www-form-urlencoded
+// or multipart/form-data. For multipart, properties declared with
+// format: binary are sent as file parts. It returns the encoded body and the
+// Content-Type header to send (including the multipart boundary).
+func encodeFormBody(contentType string, schema map[string]any, body any) ([]byte, string, error) {
+	fields, ok := body.(map[string]any)
+	if !ok {
+		return nil, "", fmt.Errorf("request body must be an object for content type %s", contentType)
+	}
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if !strings.HasPrefix(strings.ToLower(contentType), "multipart/form-data") {
+		values := url.Values{}
+		for _, name := range names {
+			for _, v := range formValues(fields[name]) {
+				values.Add(name, v)
+			}
+		}
+		return []byte(values.Encode()), contentType, nil
+	}
+
+	props, _ := schema["properties"].(map[string]any)
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for _, name := range names {
+		prop, _ := props[name].(map[string]any)
+		for _, v := range formValues(fields[name]) {
+			var err error
+			if prop["format"] == "binary" {
+				var part io.Writer
+				if part, err = w.CreateFormFile(name, name); err == nil {
+					_, err = io.WriteString(part, v)
+				}
+			} else {
+				err = w.WriteField(name, v)
+			}
+			if err != nil {
+				return nil, "", fmt.Errorf("encode form field %s: %w", name, err)
+			}
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", fmt.Errorf("encode form: %w", err)
+	}
+	return buf.Bytes(), w.FormDataContentType(), nil
+}
+
+// formValues renders a form field value; arrays become repeated fields and
+// objects are sent as JSON.
+func formValues(value any) []string {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		return []string{v}
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			out = append(out, formValues(item)...)
+		}
+		return out
+	case map[string]any:
+		encoded, _ := json.Marshal(v)
+		return []string{string(encoded)}
+	default:
+		return []string{fmt.Sprint(v)}
+	}
+}
+
+// authHint explains an authentication failure using the security schemes
+// the spec declares for the operation: either no auth is configured for the
+// API, or the configured auth type is not one the operation accepts.
+func authHint(op *canonical.Operation, auth *config.AuthConfig, status int) string {
+	if (status != 401 && status != 403) || len(op.Security) == 0 {
+		return ""
+	}
+	expected := make([]string, 0, len(op.Security))
+	for _, s := range op.Security {
+		desc := s.Type
+		if s.Type == "api-key" && s.ParamName != "" {
+			desc = fmt.Sprintf("api-key (%s %s)", s.In, s.ParamName)
+		}
+		expected = append(expected, desc)
+	}
+	want := strings.Join(expected, " or ")
+	if auth == nil || auth.Type == "" {
+		return fmt.Sprintf("%s expects %s auth but no auth is configured for API %s", op.ToolName, want, op.ServiceName)
+	}
+	for _, s := range op.Security {
+		if authSatisfies(auth.Type, s.Type) {
+			if s.Type == "api-key" && s.ParamName != "" && auth.Header != "" && !strings.EqualFold(auth.Header, s.ParamName) {
+				return fmt.Sprintf("%s expects the API key in %s %s but auth sends header %s", op.ToolName, s.In, s.ParamName, auth.Header)
+			}
+			return ""
+		}
+	}
+	return fmt.Sprintf("%s expects %s auth but API %s is configured with %s", op.ToolName, want, op.ServiceName, auth.Type)
+}
+
+// authSatisfies reports whether a configured auth type can serve a declared
+// scheme. Bearer tokens and OAuth 2 access tokens are interchangeable here.
+func authSatisfies(configured, declared string) bool {
+	if configured == declared {
+		return true
+	}
+	tokenTypes := map[string]bool{"bearer": true, "oauth2": true, "openid": true}
+	return tokenTypes[configured] && tokenTypes[declared]
+}
@@ -0,0 +1,135 @@
+package runtime_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+)
+
+func TestExecutorURLEncodedForm(t *testing.T) {
+	type captured struct {
+		contentType string
+		form        url.Values
+	}
+	ch := make(chan captured, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		form, _ := url.ParseQuery(string(raw))
+		ch <- captured{contentType: r.Header.Get("Content-Type"), form: form}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	exec := newExecutor(t, server.URL, nil, 0)
+	op := &canonical.Operation{
+		ServiceName: "api",
+		Method:      "post",
+		Path:        "/pets",
+		RequestBody: &canonical.RequestBody{ContentType: "application/x-www-form-urlencoded"},
+	}
+	_, err := exec.Execute(context.Background(), op, map[string]any{
+		"body": map[string]any{"name": "rex", "tags": []any{"a", "b"}, "age": 3},
+	})
+	if err != nil {
+		t.Fatalf("execute failed: %v", err)
+	}
+	got := <-ch
+	if got.contentType != "application/x-www-form-urlencoded" {
+		t.Fatalf("content type = %q", got.contentType)
+	}
+	if got.form.Get("name") != "rex" || got.form.Get("age") != "3" || len(got.form["tags"]) != 2 {
+		t.Fatalf("unexpected form: %v", got.form)
+	}
+}
+
+func TestExecutorMultipartForm(t *testing.T) {
+	type captured struct {
+		name, file, filename string
+	}
+	ch := make(chan captured, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("parse multipart: %v", err)
+		}
+		var c captured
+		c.name = r.FormValue("name")
+		if f, header, err := r.FormFile("file"); err == nil {
+			data, _ := io.ReadAll(f)
+			c.file, c.filename = string(data), header.Filename
+		}
+		ch <- c
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	exec := newExecutor(t, server.URL, nil, 0)
+	op := &canonical.Operation{
+		ServiceName: "api",
+		Method:      "post",
+		Path:        "/pets/1/image",
+		RequestBody: &canonical.RequestBody{
+			ContentType: "multipart/form-data",
+			Schema: map[string]any{"type": "object", "properties": map[string]any{
+				"name": map[string]any{"type": "string"},
+				"file": map[string]any{"type": "string", "format": "binary"},
+			}},
+		},
+	}
+	_, err := exec.Execute(context.Background(), op, map[string]any{
+		"body": map[string]any{"name": "rex", "file": "PNGDATA"},
+	})
+	if err != nil {
+		t.Fatalf("execute failed: %v", err)
+	}
+	got := <-ch
+	if got.name != "rex" || got.file != "PNGDATA" || got.filename != "file" {
+		t.Fatalf("unexpected multipart fields: %+v", got)
+	}
+}
+
+func TestExecutorAuthHint(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	op := &canonical.Operation{
+		ServiceName: "api",
+		ToolName:    "api__getPet",
+		Method:      "get",
+		Path:        "/pets/1",
+		Security:    []canonical.SecurityScheme{{Name: "petstore_auth", Type: "oauth2"}},
+	}
+
+	tests := []struct {
+		name string
+		auth *config.AuthConfig
+		want string
+	}{
+		{"missing", nil, "no auth is configured"},
+		{"mismatch", &config.AuthConfig{Type: "basic", Username: "u", Password: "p"}, "configured with basic"},
+		{"compatible", &config.AuthConfig{Type: "bearer", Token: "t"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exec := newExecutor(t, server.URL, tt.auth, 0)
+			_, err := exec.Execute(context.Background(), op, map[string]any{})
+			if err == nil {
+				t.Fatal("expected an error for 401")
+			}
+			if tt.want == "" && err.Error() != "http error status 401" {
+				t.Fatalf("unexpected hint: %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
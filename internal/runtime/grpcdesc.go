@@ -0,0 +1,112 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/grpcreflect"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// grpcWarmTimeout bounds how long WarmGRPCDescriptors waits for servers.
+const grpcWarmTimeout = 10 * time.Second
+
+// grpcDescKey identifies a service as resolved from one server.
+type grpcDescKey struct {
+	target  string
+	service string
+}
+
+// grpcMethod returns the descriptor of a method, resolving its service
+// through server reflection only when it is not cached. A cached service
+// that lacks the method is resolved again, in case the server was upgraded.
+func (e *Executor) grpcMethod(ctx context.Context, target, service, method string) (*desc.MethodDescriptor, error) {
+	key := grpcDescKey{target, service}
+	e.grpcDescMu.Lock()
+	svcDesc := e.grpcDescs[key]
+	e.grpcDescMu.Unlock()
+	if svcDesc != nil {
+		if methodDesc := svcDesc.FindMethodByName(method); methodDesc != nil {
+			return methodDesc, nil
+		}
+	}
+
+	svcDesc, err := e.resolveGRPCService(ctx, target, service)
+	if err != nil {
+		return nil, err
+	}
+	methodDesc := svcDesc.FindMethodByName(method)
+	if methodDesc == nil {
+		return nil, fmt.Errorf("grpc: method %s not found in %s", method, service)
+	}
+	return methodDesc, nil
+}
+
+// resolveGRPCService asks the server for a service's descriptor and caches it.
+func (e *Executor) resolveGRPCService(ctx context.Context, target, service string) (*desc.ServiceDescriptor, error) {
+	conn, err := e.getGRPCConn(target)
+	if err != nil {
+		return nil, err
+	}
+	refClient := grpcreflect.NewClientAuto(ctx, conn)
+	defer refClient.Reset()
+
+	svcDesc, err := refClient.ResolveService(service)
+	if err != nil {
+		return nil, fmt.Errorf("grpc: resolve service %s: %w", service, err)
+	}
+	e.grpcDescMu.Lock()
+	e.grpcDescs[grpcDescKey{target, service}] = svcDesc
+	e.grpcDescMu.Unlock()
+	return svcDesc, nil
+}
+
+// invalidateGRPCService drops a cached service after the server reported
+// it or one of its methods as unknown, so the next call resolves it again.
+func (e *Executor) invalidateGRPCService(target, service string, err error) {
+	switch status.Code(err) {
+	case codes.Unimplemented, codes.NotFound:
+	default:
+		return
+	}
+	e.grpcDescMu.Lock()
+	delete(e.grpcDescs, grpcDescKey{target, service})
+	e.grpcDescMu.Unlock()
+}
+
+// WarmGRPCDescriptors resolves the services of every gRPC API up front, so
+// the first call of a tool does not pay for server reflection. Services
+// that cannot be resolved are left to be resolved on first use.
+func (e *Executor) WarmGRPCDescriptors(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, grpcWarmTimeout)
+	defer cancel()
+
+	seen := map[grpcDescKey]bool{}
+	for _, svc := range e.catalog {
+		cfg, ok := e.services[svc.Name]
+		if !ok || cfg.Mock {
+			continue
+		}
+		for _, op := range svc.Operations {
+			if op.Protocol == "grpc" && op.GRPCMeta != nil {
+				seen[grpcDescKey{cfg.BaseURL, op.GRPCMeta.ServiceFullName}] = true
+			}
+		}
+	}
+
+	var wg sync.WaitGroup
+	for key := range seen {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := e.resolveGRPCService(ctx, key.target, key.service); err != nil {
+				e.logger.Debug("grpc descriptor warm-up failed", "component", "executor", "service", key.service, "error", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
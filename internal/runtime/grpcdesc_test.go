@@ -0,0 +1,146 @@
+package runtime_test
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/logging"
+	"skyline-mcp/internal/redact"
+	"skyline-mcp/internal/runtime"
+)
+
+func TestGRPCDescriptorCache(t *testing.T) {
+	var reflections atomic.Int32
+	server := grpc.NewServer(grpc.StreamInterceptor(func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if strings.Contains(info.FullMethod, "ServerReflection") {
+			reflections.Add(1)
+		}
+		return handler(srv, ss)
+	}))
+	grpc_health_v1.RegisterHealthServer(server, health.NewServer())
+	reflection.Register(server)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = server.Serve(ln) }()
+	defer server.Stop()
+
+	baseURL := "http://" + ln.Addr().String()
+	op := &canonical.Operation{
+		ServiceName: "hc", ToolName: "hc__check", Protocol: "grpc",
+		GRPCMeta: &canonical.GRPCOperationMeta{ServiceFullName: "grpc.health.v1.Health", MethodName: "Check"},
+	}
+	cfg := &config.Config{APIs: []config.APIConfig{{Name: "hc", SpecURL: baseURL, BaseURLOverride: baseURL}}}
+	cfg.ApplyDefaults()
+	exec, err := runtime.NewExecutor(cfg, []*canonical.Service{{Name: "hc", BaseURL: baseURL, Operations: []*canonical.Operation{op}}}, logging.Discard(), redact.NewRedactor())
+	if err != nil {
+		t.Fatalf("executor init failed: %v", err)
+	}
+	defer exec.Close()
+
+	exec.WarmGRPCDescriptors(context.Background())
+	if n := reflections.Load(); n != 1 {
+		t.Fatalf("warm-up made %d reflection calls, want 1", n)
+	}
+	for i := 0; i < 2; i++ {
+		result, err := exec.Execute(context.Background(), op, map[string]any{})
+		if err != nil {
+			t.Fatalf("execute: %v", err)
+		}
+		if body, _ := result.Body.(map[string]any); body["status"] != "SERVING" {
+			t.Fatalf("body = %v", result.Body)
+		}
+	}
+	if n := reflections.Load(); n != 1 {
+		t.Fatalf("cached descriptor not reused: %d reflection calls", n)
+	}
+
+	// NOT_FOUND from the server drops the cached descriptor.
+	if _, err := exec.Execute(context.Background(), op, map[string]any{"service": "gone"}); err == nil {
+		t.Fatal("expected NOT_FOUND for an unknown health service")
+	}
+	if _, err := exec.Execute(context.Background(), op, map[string]any{}); err != nil {
+		t.Fatalf("execute after invalidation: %v", err)
+	}
+	if n := reflections.Load(); n != 2 {
+		t.Fatalf("descriptor not resolved again after NOT_FOUND: %d reflection calls", n)
+	}
+}
+
+func TestGRPCMetadataAndDeadline(t *testing.T) {
+	var reflectionAuth atomic.Value
+	var callMD metadata.MD
+	var callDeadline time.Duration
+	server := grpc.NewServer(
+		grpc.StreamInterceptor(func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			md, _ := metadata.FromIncomingContext(ss.Context())
+			reflectionAuth.Store(md.Get("authorization"))
+			return handler(srv, ss)
+		}),
+		grpc.UnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			callMD, _ = metadata.FromIncomingContext(ctx)
+			if deadline, ok := ctx.Deadline(); ok {
+				callDeadline = time.Until(deadline)
+			}
+			return handler(ctx, req)
+		}),
+	)
+	grpc_health_v1.RegisterHealthServer(server, health.NewServer())
+	reflection.Register(server)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = server.Serve(ln) }()
+	defer server.Stop()
+
+	baseURL := "http://" + ln.Addr().String()
+	timeout := 7
+	cfg := &config.Config{APIs: []config.APIConfig{{
+		Name: "hc", SpecURL: baseURL, BaseURLOverride: baseURL, TimeoutSeconds: &timeout,
+		Auth:    &config.AuthConfig{Type: "bearer", Token: "s3cret"},
+		Headers: map[string]string{"X-Tenant": "acme", "X-Tool": "{{tool}}"},
+	}}}
+	cfg.ApplyDefaults()
+	op := &canonical.Operation{
+		ServiceName: "hc", ToolName: "hc__check", Protocol: "grpc",
+		GRPCMeta: &canonical.GRPCOperationMeta{ServiceFullName: "grpc.health.v1.Health", MethodName: "Check"},
+	}
+	exec, err := runtime.NewExecutor(cfg, []*canonical.Service{{Name: "hc", BaseURL: baseURL, Operations: []*canonical.Operation{op}}}, logging.Discard(), redact.NewRedactor())
+	if err != nil {
+		t.Fatalf("executor init failed: %v", err)
+	}
+	defer exec.Close()
+
+	if _, err := exec.Execute(context.Background(), op, map[string]any{}); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if got := reflectionAuth.Load().([]string); len(got) != 1 || got[0] != "Bearer s3cret" {
+		t.Fatalf("reflection authorization = %v", got)
+	}
+	if got := callMD.Get("authorization"); len(got) != 1 || got[0] != "Bearer s3cret" {
+		t.Fatalf("call authorization = %v", got)
+	}
+	if got := callMD.Get("x-tenant"); len(got) != 1 || got[0] != "acme" {
+		t.Fatalf("x-tenant = %v", got)
+	}
+	if got := callMD.Get("x-tool"); len(got) != 1 || got[0] != "hc__check" {
+		t.Fatalf("x-tool = %v", got)
+	}
+	if callDeadline <= 6*time.Second || callDeadline > 7*time.Second {
+		t.Fatalf("server saw a deadline %s away, want the 7s operation timeout", callDeadline)
+	}
+}
@@ -0,0 +1,45 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+
+	"skyline-mcp/internal/canonical"
+)
+
+// grpcContext returns ctx carrying the API's auth and configured headers as
+// outgoing gRPC metadata: bearer, basic and OAuth 2.0 auth become an
+// "authorization" entry, an api-key its header name. Header templates are
+// expanded as for HTTP; without an operation (health probes), headers that
+// use templates are left out.
+func (e *Executor) grpcContext(ctx context.Context, name string, op *canonical.Operation, cfg serviceConfig) (context.Context, error) {
+	headers := http.Header{}
+	if op != nil {
+		for key, value := range op.StaticHeaders {
+			headers.Set(key, expandHeaderTemplate(ctx, value, op.ToolName))
+		}
+	}
+	for key, value := range cfg.Headers {
+		switch {
+		case op != nil:
+			headers.Set(key, expandHeaderTemplate(ctx, value, op.ToolName))
+		case !strings.Contains(value, "{{"):
+			headers.Set(key, value)
+		}
+	}
+	if err := e.setAuthHeaders(headers, name, cfg.Auth); err != nil {
+		return nil, fmt.Errorf("apply auth: %w", err)
+	}
+	if len(headers) == 0 {
+		return ctx, nil
+	}
+	md := metadata.MD{}
+	for key, values := range headers {
+		md.Append(key, values...) // keys are lower-cased, as gRPC requires
+	}
+	return metadata.NewOutgoingContext(ctx, md), nil
+}
@@ -0,0 +1,102 @@
+package runtime
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RequestMeta carries per-call context from the MCP layer into the executor.
+// It is used to evaluate header templates such as {{mcp.client_name}}.
+type RequestMeta struct {
+	SessionID     string
+	ClientName    string
+	ClientVersion string
+	Profile       string
+}
+
+type requestMetaKey struct{}
+
+// WithRequestMeta attaches MCP request metadata to the context.
+func WithRequestMeta(ctx context.Context, meta RequestMeta) context.Context {
+	return context.WithValue(ctx, requestMetaKey{}, meta)
+}
+
+// RequestMetaFromContext returns the MCP request metadata stored in ctx, if any.
+func RequestMetaFromContext(ctx context.Context) RequestMeta {
+	meta, _ := ctx.Value(requestMetaKey{}).(RequestMeta)
+	return meta
+}
+
+var headerTemplateRE = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.]+)\s*\}\}`)
+
+// expandHeaderTemplate evaluates {{...}} placeholders in a header value.
+// Supported placeholders:
+//
+//	{{uuid}}                random UUIDv4 (new value per request)
+//	{{timestamp}}           current time in RFC 3339 (UTC)
+//	{{unix}}                current Unix time in seconds
+//	{{tool}}                MCP tool name being executed
+//	{{mcp.client_name}}     clientInfo.name from the MCP initialize request
+//	{{mcp.client_version}}  clientInfo.version from the MCP initialize request
+//	{{mcp.session_id}}      MCP session ID
+//	{{mcp.profile}}         profile name serving the request
+//
+// Unknown placeholders are left untouched so typos are visible upstream.
+// There is deliberately no environment placeholder: headers come from
+// server-stored profiles too, and must not read the server's secrets.
+// Config files use ${VAR}, which is expanded when the file is loaded.
+func expandHeaderTemplate(ctx context.Context, value, toolName string) string {
+	return expandTemplate(ctx, value, toolName, func(s string) string { return s })
+}
+
+// expandXMLTemplate is expandHeaderTemplate for SOAP header blocks. The
+// values are XML-escaped: the client name and version come from the MCP
+// client and must not add elements to the envelope.
+func expandXMLTemplate(ctx context.Context, value, toolName string) string {
+	return expandTemplate(ctx, value, toolName, escapeXML)
+}
+
+func expandTemplate(ctx context.Context, value, toolName string, quote func(string) string) string {
+	if !strings.Contains(value, "{{") {
+		return value
+	}
+	meta := RequestMetaFromContext(ctx)
+	return headerTemplateRE.ReplaceAllStringFunc(value, func(match string) string {
+		name := headerTemplateRE.FindStringSubmatch(match)[1]
+		switch name {
+		case "uuid":
+			return newUUID()
+		case "timestamp":
+			return time.Now().UTC().Format(time.RFC3339)
+		case "unix":
+			return strconv.FormatInt(time.Now().Unix(), 10)
+		case "tool":
+			return quote(toolName)
+		case "mcp.client_name":
+			return quote(meta.ClientName)
+		case "mcp.client_version":
+			return quote(meta.ClientVersion)
+		case "mcp.session_id":
+			return quote(meta.SessionID)
+		case "mcp.profile":
+			return quote(meta.Profile)
+		}
+		return match
+	})
+}
+
+// newUUID returns a random RFC 4122 version 4 UUID.
+func newUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return ""
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
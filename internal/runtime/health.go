@@ -0,0 +1,305 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/parsers/sqldb"
+)
+
+// API health statuses.
+const (
+	HealthUp      = "up"
+	HealthDown    = "down"
+	HealthUnknown = "unknown" // not probed yet, or no probe for the protocol
+)
+
+// APIHealth is the latest health probe result of one API.
+type APIHealth struct {
+	API        string    `json:"api"`
+	Probe      string    `json:"probe"` // http, graphql, grpc, sql or none
+	Status     string    `json:"status"`
+	LatencyMs  int64     `json:"latency_ms"`
+	HTTPStatus int       `json:"http_status,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Breaker    string    `json:"breaker,omitempty"` // circuit breaker state
+	CheckedAt  time.Time `json:"checked_at,omitempty"`
+}
+
+// healthProbe says how to check one service: a lightweight request per
+// protocol that needs no arguments.
+type healthProbe struct {
+	kind string // "http", "graphql", "grpc", "sql" or "none"
+	path string // GraphQL endpoint path
+}
+
+func probeFor(svc *canonical.Service) healthProbe {
+	for _, op := range svc.Operations {
+		switch {
+		case op.Protocol == "grpc":
+			return healthProbe{kind: "grpc"}
+		case op.SQL != nil:
+			return healthProbe{kind: "sql"}
+		case op.GraphQL != nil:
+			return healthProbe{kind: "graphql", path: op.Path}
+		case op.Protocol != "" && op.Protocol != "http":
+			// Custom protocols (email, ...) have no generic probe.
+			return healthProbe{kind: "none"}
+		}
+	}
+	if svc.BaseURL == "" {
+		return healthProbe{kind: "none"}
+	}
+	return healthProbe{kind: "http"}
+}
+
+// defaultProbeTimeout bounds a probe when StartHealthChecks set none.
+const defaultProbeTimeout = 5 * time.Second
+
+type healthState struct {
+	mu      sync.RWMutex
+	results map[string]APIHealth
+	timeout time.Duration
+	stop    chan struct{}
+	once    sync.Once
+}
+
+// StartHealthChecks probes every API now and then every interval in the
+// background, until Close. timeout bounds each probe.
+func (e *Executor) StartHealthChecks(interval, timeout time.Duration) {
+	e.health.once.Do(func() {
+		e.health.mu.Lock()
+		e.health.timeout = timeout
+		e.health.mu.Unlock()
+		e.health.stop = make(chan struct{})
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				e.CheckHealth(context.Background())
+				select {
+				case <-e.health.stop:
+					return
+				case <-ticker.C:
+				}
+			}
+		}()
+	})
+}
+
+func (e *Executor) stopHealthChecks() {
+	if e.health.stop != nil {
+		select {
+		case <-e.health.stop:
+		default:
+			close(e.health.stop)
+		}
+	}
+}
+
+// CheckHealth probes every API concurrently, stores the results for Health
+// and returns them sorted by API name. Probes bypass rate limits and do not
+// count towards circuit breakers.
+func (e *Executor) CheckHealth(ctx context.Context) []APIHealth {
+	e.health.mu.RLock()
+	timeout := e.health.timeout
+	e.health.mu.RUnlock()
+	if timeout <= 0 {
+		timeout = defaultProbeTimeout
+	}
+	var wg sync.WaitGroup
+	results := make([]APIHealth, 0, len(e.services))
+	var mu sync.Mutex
+	for name, cfg := range e.services {
+		wg.Add(1)
+		go func(name string, cfg serviceConfig) {
+			defer wg.Done()
+			probeCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			h := e.probe(probeCtx, name, cfg)
+			mu.Lock()
+			results = append(results, h)
+			mu.Unlock()
+		}(name, cfg)
+	}
+	wg.Wait()
+	sort.Slice(results, func(i, j int) bool { return results[i].API < results[j].API })
+
+	e.health.mu.Lock()
+	if e.health.results == nil {
+		e.health.results = map[string]APIHealth{}
+	}
+	for _, h := range results {
+		e.health.results[h.API] = h
+	}
+	e.health.mu.Unlock()
+	return results
+}
+
+// Health returns the latest probe results, sorted by API name. APIs that
+// were not probed yet are reported as unknown. The breaker state is
+// current.
+func (e *Executor) Health() []APIHealth {
+	e.health.mu.RLock()
+	defer e.health.mu.RUnlock()
+	out := make([]APIHealth, 0, len(e.services))
+	for name, cfg := range e.services {
+		h, ok := e.health.results[name]
+		if !ok {
+			h = APIHealth{API: name, Probe: cfg.Probe.kind, Status: HealthUnknown}
+		}
+		h.Breaker = e.breakerState(name)
+		out = append(out, h)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].API < out[j].API })
+	return out
+}
+
+func (e *Executor) breakerState(name string) string {
+	if breaker := e.breakers[name]; breaker != nil {
+		return breaker.State().String()
+	}
+	return ""
+}
+
+func (e *Executor) probe(ctx context.Context, name string, cfg serviceConfig) APIHealth {
+	h := APIHealth{API: name, Probe: cfg.Probe.kind, Status: HealthUnknown, Breaker: e.breakerState(name)}
+	if cfg.Probe.kind == "" || cfg.Probe.kind == "none" {
+		h.Probe = "none"
+		return h
+	}
+	start := time.Now()
+	var err error
+	switch cfg.Probe.kind {
+	case "grpc":
+		err = e.probeGRPC(ctx, name, cfg)
+	case "sql":
+		err = e.probeSQL(ctx, name, cfg)
+	default:
+		h.HTTPStatus, err = e.probeHTTP(ctx, name, cfg)
+	}
+	h.LatencyMs = time.Since(start).Milliseconds()
+	h.CheckedAt = time.Now()
+	if err != nil {
+		h.Status = HealthDown
+		h.Error = e.redactor.Redact(err.Error())
+		return h
+	}
+	h.Status = HealthUp
+	return h
+}
+
+// probeHTTP GETs the base URL, or POSTs a {__typename} query to a GraphQL
+// endpoint. Any response below 500 means the API is reachable.
+func (e *Executor) probeHTTP(ctx context.Context, name string, cfg serviceConfig) (int, error) {
+	if cfg.BaseURL == "" {
+		return 0, fmt.Errorf("no base URL")
+	}
+	method, target, body := http.MethodGet, cfg.BaseURL, []byte(nil)
+	if cfg.Probe.kind == "graphql" {
+		method = http.MethodPost
+		target = strings.TrimRight(cfg.BaseURL, "/") + cfg.Probe.path
+		body = []byte(`{"query":"query { __typename }"}`)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range cfg.Headers {
+		if !strings.Contains(v, "{{") {
+			req.Header.Set(k, v)
+		}
+	}
+	if err := e.applyAuth(req, name, cfg.Auth); err != nil {
+		return 0, err
+	}
+	resp, err := e.httpClient(name).Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return resp.StatusCode, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// probeGRPC calls the standard grpc.health.v1 Check. Servers that do not
+// implement the health service count as up if they answer at all.
+func (e *Executor) probeGRPC(ctx context.Context, name string, cfg serviceConfig) error {
+	conn, err := e.getGRPCConn(cfg.BaseURL)
+	if err != nil {
+		return err
+	}
+	ctx, err = e.grpcContext(ctx, name, nil, cfg)
+	if err != nil {
+		return err
+	}
+	resp, err := grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	if err != nil {
+		if status.Code(err) == codes.Unimplemented {
+			return nil
+		}
+		return err
+	}
+	if resp.GetStatus() != grpc_health_v1.HealthCheckResponse_SERVING {
+		return fmt.Errorf("grpc health: %s", resp.GetStatus())
+	}
+	return nil
+}
+
+func (e *Executor) probeSQL(ctx context.Context, name string, cfg serviceConfig) error {
+	if cfg.Database == nil {
+		return fmt.Errorf("no database config")
+	}
+	dialect, err := sqldb.ParseDialect(cfg.Database.Driver)
+	if err != nil {
+		return err
+	}
+	db, err := e.getSQLDB(ctx, name, dialect, cfg.Database.DSN)
+	if err != nil {
+		return err
+	}
+	return db.PingContext(ctx)
+}
+
+func (e *Executor) executeAPIHealth(ctx context.Context, args map[string]any) *Result {
+	if refresh, _ := args["refresh"].(bool); refresh {
+		e.CheckHealth(ctx)
+	}
+	apis := e.Health()
+	if name, _ := args["api"].(string); name != "" {
+		filtered := apis[:0]
+		for _, h := range apis {
+			if h.API == name {
+				filtered = append(filtered, h)
+			}
+		}
+		apis = filtered
+	}
+	up := 0
+	for _, h := range apis {
+		if h.Status == HealthUp {
+			up++
+		}
+	}
+	return &Result{Status: 200, ContentType: "application/json", Body: map[string]any{
+		"apis":  apis,
+		"up":    up,
+		"total": len(apis),
+	}}
+}
@@ -0,0 +1,92 @@
+package runtime_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/logging"
+	"skyline-mcp/internal/redact"
+	"skyline-mcp/internal/runtime"
+)
+
+func TestExecutorHealth(t *testing.T) {
+	var graphqlBody string
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			data, _ := io.ReadAll(r.Body)
+			graphqlBody = string(data)
+		}
+		w.WriteHeader(http.StatusNotFound) // reachable, even without a root route
+	}))
+	defer up.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	services := []*canonical.Service{
+		{Name: "rest", BaseURL: up.URL, Operations: []*canonical.Operation{{ServiceName: "rest", ID: "list", ToolName: "rest__list", Method: "get", Path: "/items"}}},
+		{Name: "gql", BaseURL: up.URL, Operations: []*canonical.Operation{{ServiceName: "gql", ID: "user", ToolName: "gql__user", Method: "post", Path: "/graphql",
+			GraphQL: &canonical.GraphQLOperation{OperationType: "query", FieldName: "user"}}}},
+		{Name: "broken", BaseURL: down.URL},
+		{Name: config.BuiltinServiceName},
+	}
+	cfg := &config.Config{
+		APIs: []config.APIConfig{
+			{Name: "rest", SpecURL: up.URL + "/openapi.json"},
+			{Name: "gql", SpecURL: up.URL + "/graphql"},
+			{Name: "broken", SpecURL: down.URL + "/openapi.json"},
+		},
+		HealthCheck: &config.HealthCheckConfig{},
+	}
+	cfg.ApplyDefaults()
+	exec, err := runtime.NewExecutor(cfg, services, logging.Discard(), redact.NewRedactor())
+	if err != nil {
+		t.Fatalf("executor init failed: %v", err)
+	}
+	defer exec.Close()
+
+	for _, h := range exec.Health() {
+		if h.Status != runtime.HealthUnknown {
+			t.Fatalf("expected unknown before the first probe, got %+v", h)
+		}
+	}
+
+	results := exec.CheckHealth(context.Background())
+	byAPI := map[string]runtime.APIHealth{}
+	for _, h := range results {
+		byAPI[h.API] = h
+	}
+	if len(byAPI) != 3 {
+		t.Fatalf("expected 3 probed APIs, got %+v", results)
+	}
+	if h := byAPI["rest"]; h.Status != runtime.HealthUp || h.Probe != "http" || h.HTTPStatus != http.StatusNotFound {
+		t.Fatalf("unexpected rest health: %+v", h)
+	}
+	if h := byAPI["gql"]; h.Status != runtime.HealthUp || h.Probe != "graphql" {
+		t.Fatalf("unexpected graphql health: %+v", h)
+	}
+	if !strings.Contains(graphqlBody, "__typename") {
+		t.Fatalf("expected a __typename probe query, got %q", graphqlBody)
+	}
+	if h := byAPI["broken"]; h.Status != runtime.HealthDown || h.HTTPStatus != http.StatusServiceUnavailable || h.Breaker != "closed" {
+		t.Fatalf("unexpected broken health: %+v", h)
+	}
+
+	tool := &canonical.Operation{ServiceName: config.BuiltinServiceName, ID: "api_health", ToolName: "skyline__api_health", Protocol: "builtin"}
+	res, err := exec.Execute(context.Background(), tool, map[string]any{"api": "broken"})
+	if err != nil {
+		t.Fatalf("api_health failed: %v", err)
+	}
+	body, _ := res.Body.(map[string]any)
+	apis, _ := body["apis"].([]runtime.APIHealth)
+	if len(apis) != 1 || apis[0].API != "broken" || apis[0].Status != runtime.HealthDown || body["up"] != 0 {
+		t.Fatalf("unexpected api_health result: %#v", res.Body)
+	}
+}
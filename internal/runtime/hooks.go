@@ -0,0 +1,248 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/dop251/goja"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/executor"
+)
+
+// hookCallStackSize bounds recursion in hook scripts.
+const hookCallStackSize = 256
+
+// Interrupt values of a hook VM.
+const (
+	hookInterruptTimeout = "hook timeout"
+	hookInterruptVeto    = "hook veto"
+	hookInterruptMemory  = "hook memory limit"
+)
+
+// ErrVetoed is returned when a before hook refuses a call.
+var ErrVetoed = errors.New("call vetoed by hook")
+
+// apiHooks are the compiled hook scripts of an API. Programs are immutable
+// and shared by the fresh VM each run gets.
+type apiHooks struct {
+	before     *goja.Program
+	after      *goja.Program
+	operations []string
+	timeout    time.Duration
+	// memoryLimit caps the heap growth of a run, like the code execution
+	// memory limit it defaults to.
+	memoryLimit int64
+}
+
+// newHooks compiles the hook scripts of an API.
+func newHooks(cfg *config.HooksConfig) (*apiHooks, error) {
+	h := &apiHooks{
+		operations:  cfg.Operations,
+		timeout:     time.Duration(cfg.TimeoutMS) * time.Millisecond,
+		memoryLimit: executor.DefaultMemoryLimit,
+	}
+	if h.timeout <= 0 {
+		h.timeout = config.DefaultHookTimeoutMS * time.Millisecond
+	}
+	var err error
+	if h.before, err = compileHook("before", cfg.Before); err != nil {
+		return nil, err
+	}
+	if h.after, err = compileHook("after", cfg.After); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// compileHook wraps a script in a function of request, response and veto
+// so that it can return early.
+func compileHook(name, script string) (*goja.Program, error) {
+	if script == "" {
+		return nil, nil
+	}
+	prog, err := goja.Compile("hooks."+name, "(function (request, response, veto) {\n"+script+"\n})", true)
+	if err != nil {
+		return nil, fmt.Errorf("hooks.%s: %w", name, err)
+	}
+	return prog, nil
+}
+
+// appliesTo reports whether the hooks run for op.
+func (h *apiHooks) appliesTo(op *canonical.Operation) bool {
+	return h != nil && (len(h.operations) == 0 || slices.Contains(h.operations, op.ID) || slices.Contains(h.operations, op.ToolName))
+}
+
+// hookRequest is what hook scripts see as request.
+type hookRequest struct {
+	API       string            `json:"api"`
+	Tool      string            `json:"tool"`
+	Operation string            `json:"operation"`
+	Args      map[string]any    `json:"args"`
+	Headers   map[string]string `json:"headers"`
+}
+
+// hookResponse is what after hooks see as response.
+type hookResponse struct {
+	Status      int               `json:"status"`
+	ContentType string            `json:"content_type"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Body        any               `json:"body"`
+}
+
+// runBefore runs the before hook, returning the arguments to call with and
+// the headers the hook set.
+func (h *apiHooks) runBefore(ctx context.Context, op *canonical.Operation, args map[string]any) (map[string]any, map[string]string, error) {
+	req := &hookRequest{API: op.ServiceName, Tool: op.ToolName, Operation: op.ID, Args: args, Headers: map[string]string{}}
+	if req.Args == nil {
+		req.Args = map[string]any{}
+	}
+	if err := h.run(ctx, "before", h.before, req, nil); err != nil {
+		return nil, nil, err
+	}
+	if req.Args == nil {
+		req.Args = map[string]any{}
+	}
+	return req.Args, req.Headers, nil
+}
+
+// runAfter runs the after hook on a result.
+func (h *apiHooks) runAfter(ctx context.Context, op *canonical.Operation, args map[string]any, result *Result) error {
+	req := &hookRequest{API: op.ServiceName, Tool: op.ToolName, Operation: op.ID, Args: args}
+	resp := &hookResponse{Status: result.Status, ContentType: result.ContentType, Headers: result.Headers, Body: result.Body}
+	if err := h.run(ctx, "after", h.after, req, resp); err != nil {
+		return err
+	}
+	result.Status, result.Body = resp.Status, resp.Body
+	return nil
+}
+
+// run calls a hook in a fresh VM. request and response cross into the VM
+// as JSON and are read back the same way, so scripts never hold Go
+// values; resp is nil for before hooks.
+func (h *apiHooks) run(ctx context.Context, name string, prog *goja.Program, req *hookRequest, resp *hookResponse) error {
+	vm := goja.New()
+	vm.SetMaxCallStackSize(hookCallStackSize)
+	var vetoed bool
+	var vetoReason string
+	veto := func(call goja.FunctionCall) goja.Value {
+		vetoed = true
+		vetoReason = "no reason given"
+		if reason := call.Argument(0); !goja.IsUndefined(reason) && reason.String() != "" {
+			vetoReason = reason.String()
+		}
+		// An interrupt cannot be caught by the script.
+		vm.Interrupt(hookInterruptVeto)
+		return goja.Undefined()
+	}
+
+	timer := time.AfterFunc(h.timeout, func() { vm.Interrupt(hookInterruptTimeout) })
+	defer timer.Stop()
+	stop := context.AfterFunc(ctx, func() { vm.Interrupt(context.Cause(ctx)) })
+	defer stop()
+	stopMemory := executor.WatchMemory(vm, h.memoryLimit, hookInterruptMemory)
+	defer stopMemory()
+
+	err := func() error {
+		fn, err := vm.RunProgram(prog)
+		if err != nil {
+			return err
+		}
+		call, ok := goja.AssertFunction(fn)
+		if !ok {
+			return fmt.Errorf("not a function")
+		}
+		reqVal, err := toVM(vm, req)
+		if err != nil {
+			return err
+		}
+		respVal := goja.Null()
+		if resp != nil {
+			if respVal, err = toVM(vm, resp); err != nil {
+				return err
+			}
+		}
+		if _, err := call(goja.Undefined(), reqVal, respVal, vm.ToValue(veto)); err != nil {
+			return err
+		}
+		// Decode into fresh values: decoding into req would merge maps
+		// rather than drop the keys the script deleted.
+		var gotReq hookRequest
+		if err := fromVM(vm, reqVal, &gotReq); err != nil {
+			return err
+		}
+		req.Args, req.Headers = gotReq.Args, gotReq.Headers
+		if resp != nil {
+			var gotResp hookResponse
+			if err := fromVM(vm, respVal, &gotResp); err != nil {
+				return err
+			}
+			*resp = gotResp
+		}
+		return nil
+	}()
+	if vetoed {
+		return fmt.Errorf("%w: %s", ErrVetoed, vetoReason)
+	}
+	if err != nil {
+		var interrupted *goja.InterruptedError
+		if errors.As(err, &interrupted) && interrupted.Value() == hookInterruptTimeout {
+			return fmt.Errorf("hooks.%s: timed out after %s", name, h.timeout)
+		}
+		if errors.As(err, &interrupted) && interrupted.Value() == hookInterruptMemory {
+			return fmt.Errorf("hooks.%s: memory limit of %d MB exceeded", name, h.memoryLimit>>20)
+		}
+		var overflow *goja.StackOverflowError
+		if errors.As(err, &overflow) {
+			return fmt.Errorf("hooks.%s: call stack exceeded %d frames", name, hookCallStackSize)
+		}
+		return fmt.Errorf("hooks.%s: %w", name, err)
+	}
+	return nil
+}
+
+// toVM copies v into vm through JSON.
+func toVM(vm *goja.Runtime, v any) (goja.Value, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	parse, _ := goja.AssertFunction(vm.Get("JSON").ToObject(vm).Get("parse"))
+	return parse(goja.Undefined(), vm.ToValue(string(data)))
+}
+
+// fromVM copies a VM value back into v through JSON.
+func fromVM(vm *goja.Runtime, val goja.Value, v any) error {
+	stringify, _ := goja.AssertFunction(vm.Get("JSON").ToObject(vm).Get("stringify"))
+	out, err := stringify(goja.Undefined(), val)
+	if err != nil {
+		return err
+	}
+	if goja.IsUndefined(out) {
+		return fmt.Errorf("value is not JSON")
+	}
+	if err := json.Unmarshal([]byte(out.String()), v); err != nil {
+		return fmt.Errorf("invalid value: %w", err)
+	}
+	return nil
+}
+
+type hookHeadersKey struct{}
+
+// withHookHeaders carries the headers a before hook set to the request.
+func withHookHeaders(ctx context.Context, headers map[string]string) context.Context {
+	if len(headers) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, hookHeadersKey{}, headers)
+}
+
+func hookHeaders(ctx context.Context) map[string]string {
+	headers, _ := ctx.Value(hookHeadersKey{}).(map[string]string)
+	return headers
+}
@@ -0,0 +1,126 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/executor"
+	"skyline-mcp/internal/logging"
+	"skyline-mcp/internal/redact"
+)
+
+func TestExecutorRunsHooks(t *testing.T) {
+	var gotQuery, gotTenant string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery, gotTenant = r.URL.RawQuery, r.Header.Get("X-Tenant")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":{"items":[{"id":1},{"id":2}]},"meta":{"page":1}}`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{APIs: []config.APIConfig{{
+		Name: "quirky", SpecURL: server.URL + "/openapi.json", BaseURLOverride: server.URL,
+		Hooks: &config.HooksConfig{
+			Before: `
+				if (request.args.limit > 50) request.args.limit = 50;
+				if (request.args.status === "deleted") veto("deleted items are off limits");
+				delete request.args.debug;
+				request.headers["X-Tenant"] = "acme";`,
+			After: `
+				if (response.status === 200) response.body = response.body.data.items;`,
+			Operations: []string{"listItems"},
+		},
+	}}}
+	cfg.ApplyDefaults()
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	exec, err := NewExecutor(cfg, []*canonical.Service{{Name: "quirky", BaseURL: server.URL}}, logging.Discard(), redact.NewRedactor())
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := []canonical.Parameter{{Name: "limit", In: "query"}, {Name: "status", In: "query"}, {Name: "debug", In: "query"}}
+	list := &canonical.Operation{ServiceName: "quirky", ID: "listItems", ToolName: "quirky__listItems", Method: "get", Path: "/items", Parameters: params}
+
+	args := map[string]any{"limit": 500.0, "debug": true}
+	result, err := exec.Execute(context.Background(), list, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotQuery != "limit=50" || gotTenant != "acme" {
+		t.Errorf("upstream got query %q, X-Tenant %q", gotQuery, gotTenant)
+	}
+	if items, ok := result.Body.([]any); !ok || len(items) != 2 {
+		t.Errorf("body = %#v, want the items list", result.Body)
+	}
+	if args["limit"] != 500.0 || args["debug"] != true {
+		t.Errorf("caller's args changed: %v", args)
+	}
+
+	_, err = exec.Execute(context.Background(), list, map[string]any{"status": "deleted"})
+	if !errors.Is(err, ErrVetoed) || !strings.Contains(err.Error(), "off limits") {
+		t.Errorf("vetoed call: err = %v", err)
+	}
+
+	// Hooks limited to listItems leave other operations alone.
+	get := &canonical.Operation{ServiceName: "quirky", ID: "getItem", ToolName: "quirky__getItem", Method: "get", Path: "/items/1"}
+	result, err = exec.Execute(context.Background(), get, map[string]any{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := result.Body.(map[string]any); !ok || gotTenant != "" {
+		t.Errorf("hooks ran for getItem: body %#v, X-Tenant %q", result.Body, gotTenant)
+	}
+}
+
+func TestHookSandbox(t *testing.T) {
+	op := &canonical.Operation{ServiceName: "api", ID: "op", ToolName: "api__op"}
+	tests := []struct {
+		name, script string
+		want         string
+	}{
+		{"infinite loop", `while (true) {}`, "timed out"},
+		{"caught veto", `try { veto("no") } catch (e) {} request.args.x = 1;`, "vetoed by hook: no"},
+		{"no network", `fetch("https://example.com")`, "fetch is not defined"},
+		{"no modules", `require("fs")`, "require is not defined"},
+		{"deep recursion", `function f() { return f() } f()`, "stack"},
+		{"throws", `throw new Error("bad args")`, "bad args"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hooks, err := newHooks(&config.HooksConfig{Before: tt.script, TimeoutMS: 50})
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, _, err = hooks.runBefore(context.Background(), op, map[string]any{})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want substring %q", err, tt.want)
+			}
+		})
+	}
+
+	// Hooks share the code execution memory limit.
+	hooks, err := newHooks(&config.HooksConfig{Before: `
+		const hog = [];
+		while (true) { hog.push(new Array(100000).fill(1)); }`, TimeoutMS: 5000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hooks.memoryLimit != executor.DefaultMemoryLimit {
+		t.Errorf("default memory limit = %d", hooks.memoryLimit)
+	}
+	hooks.memoryLimit = 16 << 20
+	if _, _, err := hooks.runBefore(context.Background(), op, map[string]any{}); err == nil || !strings.Contains(err.Error(), "hooks.before: memory limit of 16 MB exceeded") {
+		t.Errorf("memory hog: %v", err)
+	}
+
+	if _, err := newHooks(&config.HooksConfig{After: `response.body = (`}); err == nil || !strings.Contains(err.Error(), "hooks.after") {
+		t.Errorf("syntax error not reported: %v", err)
+	}
+}
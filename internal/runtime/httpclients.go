@@ -0,0 +1,235 @@
+package runtime
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"skyline-mcp/internal/config"
+)
+
+// HTTPClients hands out one long-lived HTTP client per profile and API, so
+// executors rebuilt for the same profile keep the upstream connections
+// (and keep-alives) of the ones they replace. It counts what every client's
+// connection pool does.
+type HTTPClients struct {
+	mu      sync.Mutex
+	clients map[clientKey]*pooledClient
+}
+
+type clientKey struct {
+	profile string
+	api     string
+}
+
+type pooledClient struct {
+	client    *http.Client
+	transport config.TransportConfig
+	stats     connCounters
+}
+
+type connCounters struct {
+	open       atomic.Int64 // dialed connections not yet closed
+	active     atomic.Int64 // requests whose response is still being read
+	dials      atomic.Int64
+	dialErrors atomic.Int64
+	reused     atomic.Int64 // requests served over a kept-alive connection
+	dnsLookups atomic.Int64
+	dnsNanos   atomic.Int64
+}
+
+// ConnStats describes the connection pool of one API's client.
+type ConnStats struct {
+	Profile string `json:"profile,omitempty"`
+	API     string `json:"api"`
+	Open    int64  `json:"open"`
+	// Idle estimates the open connections not serving a request.
+	Idle       int64 `json:"idle"`
+	Active     int64 `json:"active"`
+	Dials      int64 `json:"dials"`
+	DialErrors int64 `json:"dial_errors"`
+	Reused     int64 `json:"reused"`
+	DNSLookups int64 `json:"dns_lookups"`
+	// DNSTime is the total time spent resolving upstream host names.
+	DNSTime time.Duration `json:"dns_time_ns"`
+}
+
+func NewHTTPClients() *HTTPClients {
+	return &HTTPClients{clients: map[clientKey]*pooledClient{}}
+}
+
+// Client returns the client for an API of a profile, creating it on first
+// use. A client whose transport settings changed is replaced, and its idle
+// connections are closed.
+func (p *HTTPClients) Client(profile, api string, transport config.TransportConfig) *http.Client {
+	key := clientKey{profile, api}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if pc, ok := p.clients[key]; ok {
+		if reflect.DeepEqual(pc.transport, transport) {
+			return pc.client
+		}
+		pc.client.CloseIdleConnections()
+	}
+	pc := &pooledClient{transport: transport}
+	pc.client = newPooledClient(&pc.stats, transport)
+	p.clients[key] = pc
+	return pc.client
+}
+
+// Stats reports every client's pool, ordered by profile and API.
+func (p *HTTPClients) Stats() []ConnStats {
+	p.mu.Lock()
+	out := make([]ConnStats, 0, len(p.clients))
+	for key, pc := range p.clients {
+		open, active := pc.stats.open.Load(), pc.stats.active.Load()
+		out = append(out, ConnStats{
+			Profile:    key.profile,
+			API:        key.api,
+			Open:       open,
+			Idle:       max(open-active, 0),
+			Active:     active,
+			Dials:      pc.stats.dials.Load(),
+			DialErrors: pc.stats.dialErrors.Load(),
+			Reused:     pc.stats.reused.Load(),
+			DNSLookups: pc.stats.dnsLookups.Load(),
+			DNSTime:    time.Duration(pc.stats.dnsNanos.Load()),
+		})
+	}
+	p.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Profile != out[j].Profile {
+			return out[i].Profile < out[j].Profile
+		}
+		return out[i].API < out[j].API
+	})
+	return out
+}
+
+// Forget drops the clients of a profile, e.g. when it is deleted. Their
+// idle connections are closed.
+func (p *HTTPClients) Forget(profile string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for key, pc := range p.clients {
+		if key.profile == profile {
+			pc.client.CloseIdleConnections()
+			delete(p.clients, key)
+		}
+	}
+}
+
+func newPooledClient(stats *connCounters, tuning config.TransportConfig) *http.Client {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if tuning.DialTimeoutSeconds > 0 {
+		dialer.Timeout = time.Duration(tuning.DialTimeoutSeconds) * time.Second
+	}
+	switch {
+	case tuning.TCPKeepAliveSeconds < 0:
+		dialer.KeepAlive = -1
+	case tuning.TCPKeepAliveSeconds > 0:
+		dialer.KeepAlive = time.Duration(tuning.TCPKeepAliveSeconds) * time.Second
+	}
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			stats.dials.Add(1)
+			conn, err := dialer.DialContext(ctx, network, tuning.DialAddress(addr))
+			if err != nil {
+				stats.dialErrors.Add(1)
+				return nil, err
+			}
+			stats.open.Add(1)
+			return &countedConn{Conn: conn, open: &stats.open}, nil
+		},
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     90 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+		// compressionTransport negotiates encodings itself.
+		DisableCompression: true,
+		MaxConnsPerHost:    tuning.MaxConnsPerHost,
+	}
+	if tuning.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = tuning.MaxIdleConnsPerHost
+	}
+	switch tuning.HTTPVersion {
+	case config.HTTPVersion1:
+		transport.Protocols = new(http.Protocols)
+		transport.Protocols.SetHTTP1(true)
+	case config.HTTPVersionH2C:
+		// Without HTTP/1 in the set, http:// URLs use HTTP/2 with prior
+		// knowledge.
+		transport.Protocols = new(http.Protocols)
+		transport.Protocols.SetHTTP2(true)
+		transport.Protocols.SetUnencryptedHTTP2(true)
+	}
+	// Requests carry no client-wide timeout: each execution's context
+	// deadline comes from the operation's resolved timeout, which callers
+	// may raise up to max_timeout_seconds.
+	return &http.Client{Transport: &compressionTransport{base: &tracedTransport{base: transport, stats: stats}}}
+}
+
+// tracedTransport counts requests in flight, reused connections and DNS
+// lookups.
+type tracedTransport struct {
+	base  http.RoundTripper
+	stats *connCounters
+}
+
+func (t *tracedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var dnsStart time.Time
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			if !dnsStart.IsZero() {
+				t.stats.dnsLookups.Add(1)
+				t.stats.dnsNanos.Add(int64(time.Since(dnsStart)))
+			}
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			if info.Reused {
+				t.stats.reused.Add(1)
+			}
+		},
+	}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+	t.stats.active.Add(1)
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.Body == nil {
+		t.stats.active.Add(-1)
+		return resp, err
+	}
+	resp.Body = &activeBody{ReadCloser: resp.Body, active: &t.stats.active}
+	return resp, nil
+}
+
+// activeBody ends a request's active period when its body is closed.
+type activeBody struct {
+	io.ReadCloser
+	active *atomic.Int64
+	once   sync.Once
+}
+
+func (b *activeBody) Close() error {
+	b.once.Do(func() { b.active.Add(-1) })
+	return b.ReadCloser.Close()
+}
+
+// countedConn keeps the open connection count of its pool.
+type countedConn struct {
+	net.Conn
+	open *atomic.Int64
+	once sync.Once
+}
+
+func (c *countedConn) Close() error {
+	c.once.Do(func() { c.open.Add(-1) })
+	return c.Conn.Close()
+}
@@ -0,0 +1,146 @@
+package runtime_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/logging"
+	"skyline-mcp/internal/redact"
+	"skyline-mcp/internal/runtime"
+)
+
+func TestHTTPClientsSharedAcrossExecutors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{APIs: []config.APIConfig{{Name: "svc", SpecURL: server.URL + "/openapi.json", BaseURLOverride: server.URL}}}
+	cfg.ApplyDefaults()
+	op := &canonical.Operation{ServiceName: "svc", ToolName: "svc__ping", Method: "get", Path: "/ping"}
+
+	clients := runtime.NewHTTPClients()
+	// Each executor stands for a rebuild of the profile's registry.
+	for i := 0; i < 3; i++ {
+		exec, err := runtime.NewExecutor(cfg, []*canonical.Service{{Name: "svc", BaseURL: server.URL}}, logging.Discard(), redact.NewRedactor())
+		if err != nil {
+			t.Fatalf("executor init failed: %v", err)
+		}
+		exec.UseHTTPClients(clients, "team")
+		if _, err := exec.Execute(context.Background(), op, map[string]any{}); err != nil {
+			t.Fatalf("execute %d: %v", i, err)
+		}
+	}
+
+	stats := clients.Stats()
+	if len(stats) != 1 {
+		t.Fatalf("stats = %+v", stats)
+	}
+	got := stats[0]
+	if got.Profile != "team" || got.API != "svc" {
+		t.Fatalf("pool keyed as %s/%s", got.Profile, got.API)
+	}
+	if got.Dials != 1 || got.Reused != 2 {
+		t.Fatalf("dials = %d, reused = %d; want one connection kept alive", got.Dials, got.Reused)
+	}
+	if got.Open != 1 || got.Idle != 1 || got.Active != 0 {
+		t.Fatalf("open = %d, idle = %d, active = %d", got.Open, got.Idle, got.Active)
+	}
+
+	clients.Forget("team")
+	if stats := clients.Stats(); len(stats) != 0 {
+		t.Fatalf("stats after forget = %+v", stats)
+	}
+}
+
+func TestHTTPClientTransportTuning(t *testing.T) {
+	var protos []string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		protos = append(protos, r.Proto)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	server.Config.Protocols = new(http.Protocols)
+	server.Config.Protocols.SetHTTP1(true)
+	server.Config.Protocols.SetUnencryptedHTTP2(true)
+	server.Start()
+	defer server.Close()
+
+	op := &canonical.Operation{ServiceName: "svc", ToolName: "svc__ping", Method: "get", Path: "/ping"}
+	clients := runtime.NewHTTPClients()
+	call := func(transport *config.TransportConfig) {
+		t.Helper()
+		cfg := &config.Config{APIs: []config.APIConfig{{Name: "svc", SpecURL: server.URL + "/openapi.json", BaseURLOverride: server.URL, Transport: transport}}}
+		cfg.ApplyDefaults()
+		if err := cfg.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		exec, err := runtime.NewExecutor(cfg, []*canonical.Service{{Name: "svc", BaseURL: server.URL}}, logging.Discard(), redact.NewRedactor())
+		if err != nil {
+			t.Fatal(err)
+		}
+		exec.UseHTTPClients(clients, "team")
+		if _, err := exec.Execute(context.Background(), op, map[string]any{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	call(nil)
+	call(&config.TransportConfig{HTTPVersion: config.HTTPVersionH2C, MaxConnsPerHost: 4, DialTimeoutSeconds: 5, TCPKeepAliveSeconds: -1})
+	call(&config.TransportConfig{HTTPVersion: config.HTTPVersion1})
+	if want := []string{"HTTP/1.1", "HTTP/2.0", "HTTP/1.1"}; len(protos) != 3 || protos[0] != want[0] || protos[1] != want[1] || protos[2] != want[2] {
+		t.Fatalf("protocols = %v, want %v", protos, want)
+	}
+	// Each change of transport settings replaced the client.
+	if stats := clients.Stats(); len(stats) != 1 || stats[0].Dials != 1 {
+		t.Fatalf("stats = %+v, want the latest client only", stats)
+	}
+}
+
+func TestHTTPClientHostsOverride(t *testing.T) {
+	var hosts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hosts = append(hosts, r.Host)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	op := &canonical.Operation{ServiceName: "svc", ToolName: "svc__ping", Method: "get", Path: "/ping"}
+	for _, tt := range []struct {
+		baseURL string
+		hosts   map[string]string
+	}{
+		// The bare IP keeps the URL's port.
+		{"http://Billing.Internal:" + port, map[string]string{"billing.internal": "127.0.0.1"}},
+		// host:port entries replace the port too.
+		{"http://billing.internal", map[string]string{"billing.internal:80": server.Listener.Addr().String()}},
+	} {
+		cfg := &config.Config{APIs: []config.APIConfig{{
+			Name: "svc", SpecURL: tt.baseURL + "/openapi.json", BaseURLOverride: tt.baseURL,
+			Transport: &config.TransportConfig{Hosts: tt.hosts},
+		}}}
+		cfg.ApplyDefaults()
+		if err := cfg.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		exec, err := runtime.NewExecutor(cfg, []*canonical.Service{{Name: "svc", BaseURL: tt.baseURL}}, logging.Discard(), redact.NewRedactor())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := exec.Execute(context.Background(), op, map[string]any{}); err != nil {
+			t.Fatalf("%s: %v", tt.baseURL, err)
+		}
+	}
+	// The upstream still sees the configured host name.
+	if len(hosts) != 2 || hosts[0] != "Billing.Internal:"+port || hosts[1] != "billing.internal" {
+		t.Fatalf("Host headers = %v", hosts)
+	}
+}
@@ -0,0 +1,113 @@
+package runtime_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/logging"
+	"skyline-mcp/internal/redact"
+	"skyline-mcp/internal/runtime"
+)
+
+func newJenkinsExecutor(t *testing.T, baseURL string, crumb *config.JenkinsCrumb) *runtime.Executor {
+	t.Helper()
+	cfg := &config.Config{
+		APIs: []config.APIConfig{{
+			Name:            "api",
+			SpecURL:         "http://example.com/spec",
+			BaseURLOverride: baseURL,
+			Jenkins:         &config.JenkinsConfig{Crumb: crumb},
+		}},
+	}
+	cfg.ApplyDefaults()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("config invalid: %v", err)
+	}
+	exec, err := runtime.NewExecutor(cfg, []*canonical.Service{{Name: "api", BaseURL: baseURL}}, logging.Discard(), redact.NewRedactor())
+	if err != nil {
+		t.Fatalf("executor init failed: %v", err)
+	}
+	return exec
+}
+
+func TestExecutorJenkinsFolderPath(t *testing.T) {
+	pathCh := make(chan string, 3)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pathCh <- r.URL.EscapedPath()
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"ok": true})
+	}))
+	defer server.Close()
+
+	exec := newJenkinsExecutor(t, server.URL, nil)
+	op := &canonical.Operation{
+		ServiceName: "api",
+		Method:      "get",
+		Path:        "/job/{jobName}/api/json",
+		Parameters:  []canonical.Parameter{{Name: "jobName", In: "path", Required: true, SegmentSeparator: "/job/"}},
+	}
+	for _, jobName := range []any{"team/my app", "job/team/job/my app/", []any{"team", "my app"}} {
+		if _, err := exec.Execute(context.Background(), op, map[string]any{"jobName": jobName}); err != nil {
+			t.Fatalf("execute failed: %v", err)
+		}
+		if got := <-pathCh; got != "/job/team/job/my%20app/api/json" {
+			t.Errorf("jobName %v: path = %s", jobName, got)
+		}
+	}
+}
+
+func TestExecutorJenkinsCrumbConfig(t *testing.T) {
+	type seen struct{ crumb, cookie string }
+	writes := make(chan seen, 1)
+	issuerHits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/custom/crumb":
+			issuerHits++
+			http.SetCookie(w, &http.Cookie{Name: "JSESSIONID", Value: "s1"})
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]any{"crumbRequestField": "Jenkins-Crumb", "crumb": "abc"})
+		case "/job/demo/build":
+			s := seen{crumb: r.Header.Get("Jenkins-Crumb")}
+			if c, err := r.Cookie("JSESSIONID"); err == nil {
+				s.cookie = c.Value
+			}
+			writes <- s
+			w.WriteHeader(http.StatusCreated)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	op := &canonical.Operation{ServiceName: "api", Method: "post", Path: "/job/demo/build", RequiresCrumb: true}
+
+	exec := newJenkinsExecutor(t, server.URL, &config.JenkinsCrumb{IssuerPath: "custom/crumb"})
+	if _, err := exec.Execute(context.Background(), op, map[string]any{}); err != nil {
+		t.Fatalf("execute failed: %v", err)
+	}
+	if got := <-writes; got.crumb != "abc" || got.cookie != "s1" {
+		t.Fatalf("write saw crumb=%q cookie=%q, want abc and the issuer session", got.crumb, got.cookie)
+	}
+
+	exec = newJenkinsExecutor(t, server.URL, &config.JenkinsCrumb{Mode: "never", IssuerPath: "custom/crumb"})
+	if _, err := exec.Execute(context.Background(), op, map[string]any{}); err != nil {
+		t.Fatalf("execute failed: %v", err)
+	}
+	if got := <-writes; got.crumb != "" {
+		t.Fatalf("mode never sent crumb %q", got.crumb)
+	}
+	if issuerHits != 1 {
+		t.Fatalf("crumb issuer called %d times, want 1", issuerHits)
+	}
+
+	exec = newJenkinsExecutor(t, server.URL, &config.JenkinsCrumb{Mode: "always"})
+	if _, err := exec.Execute(context.Background(), op, map[string]any{}); err == nil {
+		t.Fatal("mode always should fail when the issuer is missing")
+	}
+}
@@ -0,0 +1,81 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/jobs"
+)
+
+// AsyncArgument is the reserved tool argument that runs a call as a
+// background job: the caller gets a job ID at once and reads the result
+// with the get_job_result built-in tool.
+const AsyncArgument = "_async"
+
+// maxJobWait caps how long get_job_result waits for a running job.
+const maxJobWait = 60 * time.Second
+
+// TakeAsyncArgument removes AsyncArgument from args and reports whether
+// the caller asked for async execution. Like WithTimeoutArgument it must
+// run before args are validated.
+func TakeAsyncArgument(args map[string]any) (bool, error) {
+	raw, ok := args[AsyncArgument]
+	if !ok {
+		return false, nil
+	}
+	delete(args, AsyncArgument)
+	async, ok := raw.(bool)
+	if !ok {
+		return false, fmt.Errorf("%s must be a boolean", AsyncArgument)
+	}
+	return async, nil
+}
+
+// SetJobStore replaces the executor's job store, so a rebuilt executor
+// keeps serving the jobs its predecessor started.
+func (e *Executor) SetJobStore(store *jobs.Store) {
+	e.jobs = store
+}
+
+// StartJob executes op in the background and returns the running job.
+// owner identifies the caller, e.g. an MCP session; onDone, if set, is
+// called with the finished job.
+func (e *Executor) StartJob(ctx context.Context, op *canonical.Operation, args map[string]any, owner string, onDone func(jobs.Job)) (jobs.Job, error) {
+	job, err := e.jobs.Start(ctx, op.ToolName, owner, func(ctx context.Context) (any, error) {
+		result, err := e.Execute(ctx, op, args)
+		if err != nil {
+			return nil, err
+		}
+		return result, nil
+	}, onDone)
+	if err != nil {
+		return jobs.Job{}, err
+	}
+	e.logger.Info("started async job", "component", "executor", "tool", op.ToolName, "job_id", job.ID)
+	return job, nil
+}
+
+// executeGetJobResult answers the get_job_result built-in tool. With
+// wait_seconds it waits up to that long for a running job to finish.
+func (e *Executor) executeGetJobResult(ctx context.Context, args map[string]any) *Result {
+	id, _ := args["job_id"].(string)
+	wait := time.Duration(0)
+	if seconds, ok := args["wait_seconds"].(float64); ok && seconds > 0 {
+		wait = min(time.Duration(seconds*float64(time.Second)), maxJobWait)
+	}
+	var job jobs.Job
+	var ok bool
+	if wait > 0 {
+		waitCtx, cancel := context.WithTimeout(ctx, wait)
+		defer cancel()
+		job, ok = e.jobs.Wait(waitCtx, id)
+	} else {
+		job, ok = e.jobs.Get(id)
+	}
+	if !ok {
+		return toolError(fmt.Errorf("unknown job %s", id))
+	}
+	return &Result{Status: 200, ContentType: "application/json", Body: job}
+}
@@ -0,0 +1,92 @@
+package runtime_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/jobs"
+	"skyline-mcp/internal/logging"
+	"skyline-mcp/internal/redact"
+	"skyline-mcp/internal/runtime"
+	"skyline-mcp/internal/spec"
+)
+
+func TestTakeAsyncArgument(t *testing.T) {
+	args := map[string]any{"id": 1, runtime.AsyncArgument: true}
+	async, err := runtime.TakeAsyncArgument(args)
+	if err != nil || !async {
+		t.Fatalf("async = %v, err = %v", async, err)
+	}
+	if _, ok := args[runtime.AsyncArgument]; ok {
+		t.Fatal("async argument should be removed from args")
+	}
+	if async, _ := runtime.TakeAsyncArgument(map[string]any{}); async {
+		t.Fatal("async without the argument")
+	}
+	if _, err := runtime.TakeAsyncArgument(map[string]any{runtime.AsyncArgument: "yes"}); err == nil {
+		t.Fatal("expected an error for a non-boolean value")
+	}
+}
+
+func TestExecutorAsyncJob(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"report":"ready"}`)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{APIs: []config.APIConfig{{Name: "reports", SpecURL: server.URL + "/openapi.json", BaseURLOverride: server.URL}}}
+	cfg.ApplyDefaults()
+	services := spec.ApplyBuiltinTools([]*canonical.Service{{Name: "reports", BaseURL: server.URL}}, cfg)
+	exec, err := runtime.NewExecutor(cfg, services, logging.Discard(), redact.NewRedactor())
+	if err != nil {
+		t.Fatalf("executor init failed: %v", err)
+	}
+	var getResult *canonical.Operation
+	for _, op := range services[1].Operations {
+		if op.ID == "get_job_result" {
+			getResult = op
+		}
+	}
+	if getResult == nil {
+		t.Fatal("get_job_result not registered")
+	}
+
+	generate := &canonical.Operation{ServiceName: "reports", ID: "generate", ToolName: "reports__generate", Method: "post", Path: "/reports"}
+	done := make(chan jobs.Job, 1)
+	job, err := exec.StartJob(context.Background(), generate, map[string]any{}, "session-1", func(job jobs.Job) { done <- job })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	poll := func(args map[string]any) (int, any) {
+		t.Helper()
+		res, err := exec.Execute(context.Background(), getResult, args)
+		if err != nil {
+			t.Fatalf("get_job_result failed: %v", err)
+		}
+		return res.Status, res.Body
+	}
+	if _, body := poll(map[string]any{"job_id": job.ID}); body.(jobs.Job).Status != jobs.StatusRunning {
+		t.Fatalf("expected a running job, got %#v", body)
+	}
+	if status, _ := poll(map[string]any{"job_id": "job_unknown"}); status != 404 {
+		t.Fatalf("unknown job status = %d, want 404", status)
+	}
+
+	close(release)
+	<-done
+	_, body := poll(map[string]any{"job_id": job.ID, "wait_seconds": float64(5)})
+	finished := body.(jobs.Job)
+	result, ok := finished.Result.(*runtime.Result)
+	if finished.Status != jobs.StatusSucceeded || !ok || result.Status != 200 {
+		t.Fatalf("unexpected finished job %#v", finished)
+	}
+}
@@ -0,0 +1,60 @@
+package runtime
+
+import (
+	"encoding/json"
+	"testing"
+
+	"skyline-mcp/internal/canonical"
+)
+
+func TestBuildJSONRPCBody(t *testing.T) {
+	params := []canonical.Parameter{{Name: "address"}, {Name: "block"}, {Name: "full"}}
+	tests := []struct {
+		name string
+		rpc  canonical.JSONRPCOperation
+		args map[string]any
+		want string
+	}{
+		{
+			name: "by-name",
+			rpc:  canonical.JSONRPCOperation{MethodName: "get"},
+			args: map[string]any{"address": "0x1", "full": true},
+			want: `{"id":1,"jsonrpc":"2.0","method":"get","params":{"address":"0x1","full":true}}`,
+		},
+		{
+			name: "by-position with gap",
+			rpc:  canonical.JSONRPCOperation{MethodName: "get", ByPosition: true},
+			args: map[string]any{"address": "0x1", "full": true},
+			want: `{"id":1,"jsonrpc":"2.0","method":"get","params":["0x1",null,true]}`,
+		},
+		{
+			name: "by-position drops trailing",
+			rpc:  canonical.JSONRPCOperation{MethodName: "get", ByPosition: true},
+			args: map[string]any{"address": "0x1"},
+			want: `{"id":1,"jsonrpc":"2.0","method":"get","params":["0x1"]}`,
+		},
+		{
+			name: "notification",
+			rpc:  canonical.JSONRPCOperation{MethodName: "log", Notification: true},
+			args: map[string]any{},
+			want: `{"jsonrpc":"2.0","method":"log"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rpc := tt.rpc
+			body, err := buildJSONRPCBody(&canonical.Operation{Parameters: params, JSONRPC: &rpc}, tt.args)
+			if err != nil {
+				t.Fatalf("build failed: %v", err)
+			}
+			var got, want any
+			_ = json.Unmarshal(body, &got)
+			_ = json.Unmarshal([]byte(tt.want), &want)
+			gotJSON, _ := json.Marshal(got)
+			wantJSON, _ := json.Marshal(want)
+			if string(gotJSON) != string(wantJSON) {
+				t.Errorf("body = %s, want %s", gotJSON, wantJSON)
+			}
+		})
+	}
+}
@@ -0,0 +1,405 @@
+package runtime
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+	"github.com/twmb/franz-go/pkg/sasl/scram"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+)
+
+// kafkaClient produces through one long-lived franz-go client. It joins no
+// consumer group: each consume call reads partitions directly with a client
+// of its own and commits no offsets.
+type kafkaClient struct {
+	opts   []kgo.Opt // connection options shared by every client
+	client *kgo.Client
+	// next spreads records without a key over the partitions.
+	next atomic.Uint32
+}
+
+func newKafkaClient(m *config.MessagingConfig, username, password string) (*kafkaClient, error) {
+	opts := []kgo.Opt{
+		kgo.SeedBrokers(m.Servers...),
+		kgo.ClientID(brokerClientName),
+	}
+	if m.TLS {
+		opts = append(opts, kgo.DialTLSConfig(&tls.Config{MinVersion: tls.VersionTLS12}))
+	}
+	if username != "" {
+		switch mechanism := strings.ToUpper(m.SASLMechanism); mechanism {
+		case "", "PLAIN":
+			opts = append(opts, kgo.SASL(plain.Auth{User: username, Pass: password}.AsMechanism()))
+		case "SCRAM-SHA-256":
+			opts = append(opts, kgo.SASL(scram.Auth{User: username, Pass: password}.AsSha256Mechanism()))
+		case "SCRAM-SHA-512":
+			opts = append(opts, kgo.SASL(scram.Auth{User: username, Pass: password}.AsSha512Mechanism()))
+		default:
+			return nil, fmt.Errorf("kafka: unsupported SASL mechanism %q", m.SASLMechanism)
+		}
+	}
+	client, err := kgo.NewClient(append(slices.Clone(opts),
+		// Partitions are picked in Produce, like the Java client does.
+		kgo.RecordPartitioner(kgo.ManualPartitioner()),
+		kgo.DisableIdempotentWrite(),
+		kgo.RequiredAcks(kgo.AllISRAcks()),
+	)...)
+	if err != nil {
+		return nil, fmt.Errorf("kafka: %w", err)
+	}
+	return &kafkaClient{opts: opts, client: client}, nil
+}
+
+func (k *kafkaClient) Close() error {
+	k.client.Close()
+	return nil
+}
+
+// partitions returns the partitions of topic, sorted. Topics are never
+// created by the lookup.
+func (k *kafkaClient) partitions(ctx context.Context, topic string) ([]int32, error) {
+	req := kmsg.NewPtrMetadataRequest()
+	t := kmsg.NewMetadataRequestTopic()
+	t.Topic = kmsg.StringPtr(topic)
+	req.Topics = append(req.Topics, t)
+	req.AllowAutoTopicCreation = false
+	resp, err := req.RequestWith(ctx, k.client)
+	if err != nil {
+		return nil, fmt.Errorf("kafka: %w", err)
+	}
+	for _, t := range resp.Topics {
+		if t.Topic == nil || *t.Topic != topic {
+			continue
+		}
+		if err := kerr.ErrorForCode(t.ErrorCode); err != nil {
+			if errors.Is(err, kerr.UnknownTopicOrPartition) {
+				return nil, fmt.Errorf("kafka: topic %s does not exist", topic)
+			}
+			return nil, fmt.Errorf("kafka: topic %s: %w", topic, err)
+		}
+		partitions := make([]int32, 0, len(t.Partitions))
+		for _, p := range t.Partitions {
+			partitions = append(partitions, p.Partition)
+		}
+		if len(partitions) > 0 {
+			slices.Sort(partitions)
+			return partitions, nil
+		}
+	}
+	return nil, fmt.Errorf("kafka: topic %s does not exist", topic)
+}
+
+// checkPartition fails unless partition is one of partitions.
+func checkPartition(partitions []int32, partition int32) error {
+	if !slices.Contains(partitions, partition) {
+		return fmt.Errorf("kafka: partition %d does not exist; the topic has %d", partition, len(partitions))
+	}
+	return nil
+}
+
+func (k *kafkaClient) Produce(ctx context.Context, op *canonical.MessagingOperation, msg outMessage) (map[string]any, error) {
+	partitions, err := k.partitions(ctx, op.Topic)
+	if err != nil {
+		return nil, err
+	}
+	var partition int32
+	switch {
+	case msg.Partition >= 0:
+		partition = int32(msg.Partition)
+		if err := checkPartition(partitions, partition); err != nil {
+			return nil, err
+		}
+	case msg.Key != nil:
+		partition = kafkaKeyPartition(msg.Key, len(partitions))
+	default:
+		partition = partitions[int(k.next.Add(1))%len(partitions)]
+	}
+
+	record := &kgo.Record{Topic: op.Topic, Partition: partition, Key: msg.Key, Value: msg.Value}
+	for _, name := range sortedKeys(msg.Headers) {
+		record.Headers = append(record.Headers, kgo.RecordHeader{Key: name, Value: []byte(msg.Headers[name])})
+	}
+	if err := k.client.ProduceSync(ctx, record).FirstErr(); err != nil {
+		return nil, fmt.Errorf("kafka: produce to %s partition %d: %w", op.Topic, partition, err)
+	}
+	return map[string]any{"topic": op.Topic, "partition": record.Partition, "offset": record.Offset}, nil
+}
+
+// Special ListOffsets timestamps.
+const (
+	kafkaLatest   = -1
+	kafkaEarliest = -2
+)
+
+// listOffsets returns the offset of each partition at timestamp: the
+// first record at or after it, or the latest or earliest offset. A time
+// after the last record gives -1.
+func (k *kafkaClient) listOffsets(ctx context.Context, topic string, partitions []int32, timestamp int64) (map[int32]int64, error) {
+	req := kmsg.NewPtrListOffsetsRequest()
+	req.ReplicaID = -1
+	t := kmsg.NewListOffsetsRequestTopic()
+	t.Topic = topic
+	for _, p := range partitions {
+		rp := kmsg.NewListOffsetsRequestTopicPartition()
+		rp.Partition = p
+		rp.Timestamp = timestamp
+		rp.CurrentLeaderEpoch = -1
+		t.Partitions = append(t.Partitions, rp)
+	}
+	req.Topics = append(req.Topics, t)
+	resp, err := req.RequestWith(ctx, k.client)
+	if err != nil {
+		return nil, fmt.Errorf("kafka: %w", err)
+	}
+	offsets := map[int32]int64{}
+	for _, t := range resp.Topics {
+		for _, p := range t.Partitions {
+			if err := kerr.ErrorForCode(p.ErrorCode); err != nil {
+				return nil, fmt.Errorf("kafka: offsets of %s partition %d: %w", topic, p.Partition, err)
+			}
+			offsets[p.Partition] = p.Offset
+		}
+	}
+	return offsets, nil
+}
+
+// startOffsets resolves where req starts reading each of partitions.
+func (k *kafkaClient) startOffsets(ctx context.Context, topic string, partitions []int32, req consumeRequest) (map[int32]int64, error) {
+	switch {
+	case req.Offset >= 0:
+		low, err := k.listOffsets(ctx, topic, partitions, kafkaEarliest)
+		if err != nil {
+			return nil, err
+		}
+		if p := int32(req.Partition); req.Offset < low[p] {
+			return nil, fmt.Errorf("kafka: offset %d of %s partition %d is not retained; use position earliest", req.Offset, topic, p)
+		}
+		return map[int32]int64{int32(req.Partition): req.Offset}, nil
+	case req.Position == "earliest":
+		return k.listOffsets(ctx, topic, partitions, kafkaEarliest)
+	case req.Position == "new":
+		return k.listOffsets(ctx, topic, partitions, kafkaLatest)
+	case req.Position == "latest":
+		start, err := k.listOffsets(ctx, topic, partitions, kafkaLatest)
+		if err != nil {
+			return nil, err
+		}
+		low, err := k.listOffsets(ctx, topic, partitions, kafkaEarliest)
+		if err != nil {
+			return nil, err
+		}
+		for p, hw := range start {
+			start[p] = max(low[p], hw-int64(req.Max))
+		}
+		return start, nil
+	}
+	start, err := k.listOffsets(ctx, topic, partitions, req.Since.UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	// Partitions without records since then are read from the end.
+	latest, err := k.listOffsets(ctx, topic, partitions, kafkaLatest)
+	if err != nil {
+		return nil, err
+	}
+	for p, offset := range start {
+		if offset < 0 {
+			start[p] = latest[p]
+		}
+	}
+	return start, nil
+}
+
+// kafkaPartitionRead tracks a partition while consuming.
+type kafkaPartitionRead struct {
+	next  int64
+	end   int64 // high watermark when last seen
+	count int
+}
+
+func (r *kafkaPartitionRead) done(max int) bool {
+	return r.next >= r.end || r.count >= max
+}
+
+func (k *kafkaClient) Consume(ctx context.Context, op *canonical.MessagingOperation, req consumeRequest) ([]brokerMessage, error) {
+	partitions, err := k.partitions(ctx, op.Topic)
+	if err != nil {
+		return nil, err
+	}
+	if req.Partition >= 0 {
+		if err := checkPartition(partitions, int32(req.Partition)); err != nil {
+			return nil, err
+		}
+		partitions = []int32{int32(req.Partition)}
+	}
+	start, err := k.startOffsets(ctx, op.Topic, partitions, req)
+	if err != nil {
+		return nil, err
+	}
+	end, err := k.listOffsets(ctx, op.Topic, partitions, kafkaLatest)
+	if err != nil {
+		return nil, err
+	}
+
+	reads := map[int32]*kafkaPartitionRead{}
+	offsets := map[int32]kgo.Offset{}
+	for p, offset := range start {
+		reads[p] = &kafkaPartitionRead{next: offset, end: end[p]}
+		offsets[p] = kgo.NewOffset().At(offset)
+	}
+	consumer, err := kgo.NewClient(append(slices.Clone(k.opts),
+		kgo.ConsumePartitions(map[string]map[int32]kgo.Offset{op.Topic: offsets}),
+		kgo.FetchMaxPartitionBytes(1<<20),
+		kgo.FetchMaxBytes(4<<20),
+	)...)
+	if err != nil {
+		return nil, fmt.Errorf("kafka: %w", err)
+	}
+	defer consumer.Close()
+
+	// Every partition is read until it catches up or gives max records,
+	// waiting for the first records if there are none yet; "new" instead
+	// waits for max records in total. Either way the call returns at the
+	// deadline.
+	pollCtx, cancel := context.WithTimeout(ctx, req.Wait)
+	defer cancel()
+	var records []brokerMessage
+	for {
+		if req.Position == "new" {
+			if len(records) >= req.Max {
+				break
+			}
+		} else if len(records) > 0 && allRead(reads, req.Max) {
+			break
+		}
+		fetches := consumer.PollFetches(pollCtx)
+		for _, fe := range fetches.Errors() {
+			if errors.Is(fe.Err, context.Canceled) || errors.Is(fe.Err, context.DeadlineExceeded) {
+				continue
+			}
+			return nil, fmt.Errorf("kafka: fetch %s partition %d: %w", fe.Topic, fe.Partition, fe.Err)
+		}
+		fetches.EachPartition(func(fp kgo.FetchTopicPartition) {
+			r, ok := reads[fp.Partition]
+			if !ok {
+				return
+			}
+			r.end = max(r.end, fp.HighWatermark)
+			for _, rec := range fp.Records {
+				if rec.Offset < r.next {
+					continue
+				}
+				r.next = rec.Offset + 1
+				if req.Position != "new" && r.count >= req.Max {
+					continue
+				}
+				records = append(records, kafkaMessage(rec))
+				r.count++
+			}
+		})
+		if pollCtx.Err() != nil {
+			break
+		}
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		a, b := records[i], records[j]
+		if !a.Timestamp.Equal(b.Timestamp) {
+			return a.Timestamp.Before(b.Timestamp)
+		}
+		if a.Partition != b.Partition {
+			return a.Partition < b.Partition
+		}
+		return a.Offset < b.Offset
+	})
+	if len(records) > req.Max {
+		if req.Position == "latest" && req.Offset < 0 {
+			records = records[len(records)-req.Max:]
+		} else {
+			records = records[:req.Max]
+		}
+	}
+	return records, nil
+}
+
+func allRead(reads map[int32]*kafkaPartitionRead, max int) bool {
+	for _, r := range reads {
+		if !r.done(max) {
+			return false
+		}
+	}
+	return true
+}
+
+func kafkaMessage(rec *kgo.Record) brokerMessage {
+	m := brokerMessage{
+		Partition: rec.Partition,
+		Offset:    rec.Offset,
+		Timestamp: rec.Timestamp,
+		Key:       rec.Key,
+		Value:     rec.Value,
+	}
+	if len(rec.Headers) > 0 {
+		m.Headers = map[string]string{}
+		for _, h := range rec.Headers {
+			m.Headers[h.Key] = string(h.Value)
+		}
+	}
+	return m
+}
+
+// kafkaMurmur2 is the hash the Java client's default partitioner applies
+// to record keys, so keyed records land on the same partition whichever
+// client produced them.
+func kafkaMurmur2(data []byte) int32 {
+	const (
+		seed uint32 = 0x9747b28c
+		m    uint32 = 0x5bd1e995
+		r           = 24
+	)
+	length := len(data)
+	h := seed ^ uint32(length)
+	for i := 0; i+4 <= length; i += 4 {
+		k := binary.LittleEndian.Uint32(data[i:])
+		k *= m
+		k ^= k >> r
+		k *= m
+		h *= m
+		h ^= k
+	}
+	tail := data[length&^3:]
+	switch len(tail) {
+	case 3:
+		h ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(tail[0])
+		h *= m
+	}
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+	return int32(h)
+}
+
+// kafkaKeyPartition picks the partition of a keyed record like the Java
+// client: the positive murmur2 hash modulo the partition count.
+func kafkaKeyPartition(key []byte, partitions int) int32 {
+	return int32(int(kafkaMurmur2(key)&0x7fffffff) % partitions)
+}
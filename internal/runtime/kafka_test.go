@@ -0,0 +1,20 @@
+package runtime
+
+import "testing"
+
+func TestKafkaMurmur2(t *testing.T) {
+	// Vectors from the Java client's tests.
+	tests := map[string]int32{
+		"21":     -973932308,
+		"foobar": -790332482,
+		"abc":    479470107,
+	}
+	for key, want := range tests {
+		if got := kafkaMurmur2([]byte(key)); got != want {
+			t.Errorf("murmur2(%q) = %d, want %d", key, got, want)
+		}
+	}
+	if p := kafkaKeyPartition([]byte("foobar"), 6); p < 0 || p >= 6 {
+		t.Errorf("partition = %d", p)
+	}
+}
@@ -0,0 +1,369 @@
+package runtime
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/parsers/messaging"
+)
+
+const (
+	// brokerClientName identifies Skyline's connections to brokers.
+	brokerClientName = "skyline-mcp"
+	// brokerDefaultTimeout bounds broker calls without a deadline.
+	brokerDefaultTimeout = 30 * time.Second
+)
+
+// maxMessageValueBytes caps each consumed value in results; longer values
+// are cut and marked truncated.
+const maxMessageValueBytes = 64 << 10
+
+// brokerClient is a connection to the brokers of one messaging API.
+type brokerClient interface {
+	Produce(ctx context.Context, op *canonical.MessagingOperation, msg outMessage) (map[string]any, error)
+	Consume(ctx context.Context, op *canonical.MessagingOperation, req consumeRequest) ([]brokerMessage, error)
+	Close() error
+}
+
+// outMessage is a message to produce.
+type outMessage struct {
+	Key         []byte // nil without a key
+	Value       []byte
+	ContentType string
+	Headers     map[string]string
+	Partition   int    // Kafka partition; -1 to pick one
+	RoutingKey  string // RabbitMQ routing key; empty for the default
+}
+
+// consumeRequest bounds a consume call.
+type consumeRequest struct {
+	Max      int
+	Wait     time.Duration
+	Position string    // Kafka: latest, earliest, new, or empty to read from Since
+	Since    time.Time // Kafka: read records from this time
+	// Partition and Offset pick a Kafka partition and offset; -1 when unset.
+	Partition int
+	Offset    int64
+	Ack       bool // RabbitMQ: remove the messages from the queue
+}
+
+// brokerMessage is a consumed message.
+type brokerMessage struct {
+	Subject     string // NATS subject, or RabbitMQ routing key
+	Partition   int32
+	Offset      int64
+	Timestamp   time.Time
+	Key         []byte
+	Value       []byte
+	Headers     map[string]string
+	Redelivered bool
+}
+
+// executeMessaging runs a produce or consume tool of a spec_type: messaging
+// API. Consume calls get wait_seconds on top of the API's timeout.
+func (e *Executor) executeMessaging(ctx context.Context, op *canonical.Operation, args map[string]any, cfg serviceConfig) (*Result, error) {
+	m := op.Messaging
+	if m == nil {
+		return nil, fmt.Errorf("messaging operation %s missing broker metadata", op.ID)
+	}
+	if cfg.Messaging == nil {
+		return nil, fmt.Errorf("messaging: service %s has no messaging config", op.ServiceName)
+	}
+	timeout := e.timeout(ctx, op, cfg)
+
+	switch m.Action {
+	case "produce":
+		msg, err := produceArgs(m, args)
+		if err != nil {
+			return nil, err
+		}
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		client, err := e.getBrokerClient(op.ServiceName, cfg)
+		if err != nil {
+			return nil, err
+		}
+		body, err := client.Produce(ctx, m, msg)
+		if err != nil {
+			return nil, fmt.Errorf("%s", e.redactor.Redact(err.Error()))
+		}
+		return &Result{Status: 200, ContentType: "application/json", Body: body}, nil
+	case "consume":
+		req, err := consumeArgs(m, cfg.Messaging, args, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout+req.Wait)
+			defer cancel()
+		}
+		client, err := e.getBrokerClient(op.ServiceName, cfg)
+		if err != nil {
+			return nil, err
+		}
+		msgs, err := client.Consume(ctx, m, req)
+		if err != nil {
+			return nil, fmt.Errorf("%s", e.redactor.Redact(err.Error()))
+		}
+		list := make([]any, 0, len(msgs))
+		for _, msg := range msgs {
+			list = append(list, messageJSON(m.Broker, msg))
+		}
+		body := map[string]any{"topic": m.Topic, "count": len(list), "messages": list}
+		return &Result{Status: 200, ContentType: "application/json", Body: body}, nil
+	}
+	return nil, fmt.Errorf("messaging: unknown action %q", m.Action)
+}
+
+// getBrokerClient returns the service's broker client, creating it on
+// first use. Clients connect lazily and reconnect after failures.
+func (e *Executor) getBrokerClient(service string, cfg serviceConfig) (brokerClient, error) {
+	e.brokerMu.Lock()
+	defer e.brokerMu.Unlock()
+	if client, ok := e.brokers[service]; ok {
+		return client, nil
+	}
+	client, err := newBrokerClient(cfg.Messaging, cfg.Auth)
+	if err != nil {
+		return nil, err
+	}
+	e.brokers[service] = client
+	return client, nil
+}
+
+func newBrokerClient(m *config.MessagingConfig, auth *config.AuthConfig) (brokerClient, error) {
+	var username, password, token string
+	if auth != nil {
+		switch auth.Type {
+		case "basic":
+			username, password = auth.Username, auth.Password
+		case "bearer":
+			token = auth.Token
+		}
+	}
+	switch strings.ToLower(m.Broker) {
+	case "kafka":
+		return newKafkaClient(m, username, password)
+	case "nats":
+		return &natsClient{servers: m.Servers, username: username, password: password, token: token}, nil
+	case "rabbitmq":
+		return &rabbitClient{url: m.Servers[0], username: username, password: password}, nil
+	}
+	return nil, fmt.Errorf("messaging: unsupported broker %q", m.Broker)
+}
+
+func produceArgs(m *canonical.MessagingOperation, args map[string]any) (outMessage, error) {
+	msg := outMessage{Partition: -1}
+	value, ok := args["value"]
+	if !ok {
+		return msg, fmt.Errorf("value is required")
+	}
+	if s, isString := value.(string); isString {
+		msg.Value = []byte(s)
+		msg.ContentType = "text/plain"
+	} else {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return msg, fmt.Errorf("value: %w", err)
+		}
+		msg.Value = raw
+		msg.ContentType = "application/json"
+	}
+	if key, ok := args["key"]; ok && key != nil {
+		s, isString := key.(string)
+		if !isString {
+			return msg, fmt.Errorf("key must be a string")
+		}
+		msg.Key = []byte(s)
+	}
+	if headers, ok := args["headers"]; ok && headers != nil {
+		obj, isObject := headers.(map[string]any)
+		if !isObject {
+			return msg, fmt.Errorf("headers must be an object of strings")
+		}
+		msg.Headers = map[string]string{}
+		for name, v := range obj {
+			s, isString := v.(string)
+			if !isString {
+				return msg, fmt.Errorf("headers.%s must be a string", name)
+			}
+			msg.Headers[name] = s
+		}
+	}
+	if p, ok := args["partition"]; ok && p != nil {
+		n, isInt := intArg(p)
+		if !isInt || n < 0 {
+			return msg, fmt.Errorf("partition must be a non-negative integer")
+		}
+		msg.Partition = n
+	}
+	if rk, ok := args["routing_key"]; ok && rk != nil {
+		s, isString := rk.(string)
+		if !isString || m.Exchange == "" {
+			return msg, fmt.Errorf("routing_key must be a string, and only applies to queues published through an exchange")
+		}
+		msg.RoutingKey = s
+	}
+	return msg, nil
+}
+
+func consumeArgs(m *canonical.MessagingOperation, cfg *config.MessagingConfig, args map[string]any, now time.Time) (consumeRequest, error) {
+	maxMessages := cmp.Or(max(cfg.MaxMessages, 0), config.DefaultMessagingMaxMessages)
+	maxWait := cmp.Or(max(cfg.MaxWaitSeconds, 0), config.DefaultMessagingMaxWaitSeconds)
+	req := consumeRequest{
+		Max:       min(messaging.DefaultMessages, maxMessages),
+		Wait:      time.Duration(min(messaging.DefaultWaitSeconds, maxWait)) * time.Second,
+		Position:  "latest",
+		Partition: -1,
+		Offset:    -1,
+	}
+	if v, ok := args["max_messages"]; ok && v != nil {
+		n, isInt := intArg(v)
+		if !isInt || n < 1 {
+			return req, fmt.Errorf("max_messages must be a positive integer")
+		}
+		req.Max = min(n, maxMessages)
+	}
+	if v, ok := args["wait_seconds"]; ok && v != nil {
+		seconds, isNumber := v.(float64)
+		if !isNumber || seconds < 0 {
+			return req, fmt.Errorf("wait_seconds must be a non-negative number")
+		}
+		req.Wait = min(time.Duration(seconds*float64(time.Second)), time.Duration(maxWait)*time.Second)
+	}
+	if v, ok := args["ack"]; ok && v != nil {
+		b, isBool := v.(bool)
+		if !isBool {
+			return req, fmt.Errorf("ack must be a boolean")
+		}
+		req.Ack = b
+	}
+	if m.Broker != "kafka" {
+		return req, nil
+	}
+
+	if v, ok := args["partition"]; ok && v != nil {
+		n, isInt := intArg(v)
+		if !isInt || n < 0 {
+			return req, fmt.Errorf("partition must be a non-negative integer")
+		}
+		req.Partition = n
+	}
+	if v, ok := args["offset"]; ok && v != nil {
+		n, isInt := intArg(v)
+		if !isInt || n < 0 {
+			return req, fmt.Errorf("offset must be a non-negative integer")
+		}
+		if req.Partition < 0 {
+			return req, fmt.Errorf("offset requires partition")
+		}
+		req.Offset = int64(n)
+	}
+	if v, ok := args["position"]; ok && v != nil {
+		s, _ := v.(string)
+		switch s = strings.ToLower(strings.TrimSpace(s)); s {
+		case "latest", "earliest", "new":
+			req.Position = s
+		default:
+			t, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(v.(string)))
+			if err != nil {
+				return req, fmt.Errorf("position must be latest, earliest, new or an RFC 3339 time, got %v", v)
+			}
+			if t.After(now) {
+				return req, fmt.Errorf("position %s is in the future; use new to wait for records", t.UTC().Format(time.RFC3339))
+			}
+			req.Position, req.Since = "", t
+		}
+		if req.Offset >= 0 {
+			return req, fmt.Errorf("position and offset are mutually exclusive")
+		}
+	}
+	return req, nil
+}
+
+// messageJSON renders a consumed message for the result. JSON values are
+// decoded, other UTF-8 text returned as a string and binary data as
+// base64.
+func messageJSON(broker string, msg brokerMessage) map[string]any {
+	out := map[string]any{}
+	switch broker {
+	case "kafka":
+		out["partition"] = msg.Partition
+		out["offset"] = msg.Offset
+	case "nats":
+		out["subject"] = msg.Subject
+	case "rabbitmq":
+		if msg.Subject != "" {
+			out["routing_key"] = msg.Subject
+		}
+		if msg.Redelivered {
+			out["redelivered"] = true
+		}
+	}
+	if !msg.Timestamp.IsZero() {
+		out["timestamp"] = msg.Timestamp.UTC().Format(time.RFC3339Nano)
+	}
+	if msg.Key != nil {
+		if utf8.Valid(msg.Key) {
+			out["key"] = string(msg.Key)
+		} else {
+			out["key"] = base64.StdEncoding.EncodeToString(msg.Key)
+			out["key_encoding"] = "base64"
+		}
+	}
+	if len(msg.Headers) > 0 {
+		out["headers"] = msg.Headers
+	}
+
+	value, truncated := msg.Value, len(msg.Value) > maxMessageValueBytes
+	if truncated {
+		value = trimPartialRune(value[:maxMessageValueBytes])
+		out["truncated"] = true
+		out["size"] = len(msg.Value)
+	}
+	var decoded any
+	switch {
+	case msg.Value == nil:
+		out["value"] = nil
+	case !truncated && json.Valid(value) && json.Unmarshal(value, &decoded) == nil:
+		out["value"] = decoded
+	case utf8.Valid(value):
+		out["value"] = string(value)
+	default:
+		out["value"] = base64.StdEncoding.EncodeToString(value)
+		out["value_encoding"] = "base64"
+	}
+	return out
+}
+
+// trimPartialRune drops a rune cut in half at the end of text.
+func trimPartialRune(b []byte) []byte {
+	for i := 0; i < utf8.UTFMax && i < len(b); i++ {
+		if utf8.Valid(b[:len(b)-i]) {
+			return b[:len(b)-i]
+		}
+	}
+	return b
+}
+
+func sortedKeys[K cmp.Ordered, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
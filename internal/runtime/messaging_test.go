@@ -0,0 +1,376 @@
+package runtime
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/logging"
+	"skyline-mcp/internal/parsers/messaging"
+	"skyline-mcp/internal/redact"
+)
+
+func TestConsumeArgs(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	kafka := &canonical.MessagingOperation{Broker: "kafka", Topic: "orders", Action: "consume"}
+	cfg := &config.MessagingConfig{MaxMessages: 20, MaxWaitSeconds: 10}
+
+	req, err := consumeArgs(kafka, cfg, map[string]any{}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Max != 10 || req.Wait != 5*time.Second || req.Position != "latest" || req.Partition != -1 || req.Offset != -1 {
+		t.Errorf("defaults = %+v", req)
+	}
+	req, err = consumeArgs(kafka, cfg, map[string]any{"max_messages": float64(500), "wait_seconds": 60.0, "position": "2024-05-01T10:00:00Z"}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Max != 20 || req.Wait != 10*time.Second || req.Position != "" || !req.Since.Equal(now.Add(-2*time.Hour)) {
+		t.Errorf("capped = %+v", req)
+	}
+
+	tests := []struct {
+		args map[string]any
+		want string
+	}{
+		{map[string]any{"max_messages": float64(0)}, "max_messages must be a positive integer"},
+		{map[string]any{"offset": float64(5)}, "offset requires partition"},
+		{map[string]any{"partition": float64(0), "offset": float64(5), "position": "earliest"}, "mutually exclusive"},
+		{map[string]any{"position": "yesterday"}, "position must be latest, earliest, new or an RFC 3339 time"},
+		{map[string]any{"position": "2024-05-02T00:00:00Z"}, "is in the future"},
+	}
+	for _, tt := range tests {
+		if _, err := consumeArgs(kafka, cfg, tt.args, now); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%v: err = %v, want %q", tt.args, err, tt.want)
+		}
+	}
+}
+
+func TestMessageJSON(t *testing.T) {
+	got := messageJSON("kafka", brokerMessage{Partition: 1, Offset: 7, Key: []byte("k"), Value: []byte(`{"id":1}`)})
+	if got["partition"] != int32(1) || got["offset"] != int64(7) || got["key"] != "k" || got["value"].(map[string]any)["id"] != float64(1) {
+		t.Errorf("json = %v", got)
+	}
+	if got := messageJSON("nats", brokerMessage{Subject: "a.b", Value: []byte("hello")}); got["value"] != "hello" || got["subject"] != "a.b" {
+		t.Errorf("text = %v", got)
+	}
+	if got := messageJSON("nats", brokerMessage{Value: []byte{0xff, 0x00}}); got["value"] != "/wA=" || got["value_encoding"] != "base64" {
+		t.Errorf("binary = %v", got)
+	}
+	long := messageJSON("nats", brokerMessage{Value: []byte(`"` + strings.Repeat("é", maxMessageValueBytes) + `"`)})
+	if long["truncated"] != true || len(long["value"].(string)) > maxMessageValueBytes || !strings.HasSuffix(long["value"].(string), "é") {
+		t.Errorf("truncated value: %d bytes, truncated %v", len(long["value"].(string)), long["truncated"])
+	}
+}
+
+// newKafkaCluster starts an in-process Kafka cluster with topic.
+func newKafkaCluster(t *testing.T, topic string, partitions int32, opts ...kfake.Opt) *kfake.Cluster {
+	t.Helper()
+	cluster, err := kfake.NewCluster(append([]kfake.Opt{kfake.NumBrokers(1), kfake.SeedTopics(partitions, topic)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(cluster.Close)
+	return cluster
+}
+
+// kafkaExecutor returns an executor for a messaging API on the brokers at
+// servers, and its operations by ID.
+func kafkaExecutor(t *testing.T, m *config.MessagingConfig, auth *config.AuthConfig) (*Executor, map[string]*canonical.Operation) {
+	t.Helper()
+	cfg := &config.Config{APIs: []config.APIConfig{{Name: "events", SpecType: "messaging", Messaging: m, Auth: auth}}}
+	cfg.ApplyDefaults()
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	svc, err := messaging.BuildService("events", cfg.APIs[0].Messaging)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exec, err := NewExecutor(cfg, []*canonical.Service{svc}, logging.Discard(), redact.NewRedactor())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { exec.Close() })
+	ops := map[string]*canonical.Operation{}
+	for _, op := range svc.Operations {
+		ops[op.ID] = op
+	}
+	return exec, ops
+}
+
+func TestExecuteKafka(t *testing.T) {
+	broker := newKafkaCluster(t, "orders", 2)
+	exec, ops := kafkaExecutor(t, &config.MessagingConfig{
+		Broker: "kafka", Servers: broker.ListenAddrs(), Topics: []config.MessagingTopic{{Name: "orders"}, {Name: "missing"}},
+	}, nil)
+	ctx := context.Background()
+
+	for i := range 3 {
+		res, err := exec.Execute(ctx, ops["orders_produce"], map[string]any{
+			"value": map[string]any{"id": float64(i)}, "partition": float64(0), "headers": map[string]any{"source": "test"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		body := res.Body.(map[string]any)
+		if body["partition"] != int32(0) || body["offset"] != int64(i) {
+			t.Errorf("produce %d = %v", i, body)
+		}
+	}
+
+	consume := func(args map[string]any) []any {
+		t.Helper()
+		res, err := exec.Execute(ctx, ops["orders_consume"], args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.Body.(map[string]any)["messages"].([]any)
+	}
+	ids := func(msgs []any) string {
+		var out []string
+		for _, m := range msgs {
+			m := m.(map[string]any)
+			if v, ok := m["value"].(map[string]any); ok {
+				out = append(out, fmt.Sprintf("%d:%v", m["offset"], v["id"]))
+			} else {
+				out = append(out, fmt.Sprintf("%d:%v", m["offset"], m["value"]))
+			}
+		}
+		return strings.Join(out, " ")
+	}
+
+	if got := ids(consume(map[string]any{"partition": float64(0), "max_messages": float64(2)})); got != "1:1 2:2" {
+		t.Errorf("latest = %s", got)
+	}
+	if got := ids(consume(map[string]any{"partition": float64(0), "position": "earliest", "max_messages": float64(2)})); got != "0:0 1:1" {
+		t.Errorf("earliest = %s", got)
+	}
+	if got := ids(consume(map[string]any{"partition": float64(0), "offset": float64(2)})); got != "2:2" {
+		t.Errorf("offset = %s", got)
+	}
+	res, err := exec.Execute(ctx, ops["orders_produce"], map[string]any{"value": "keyed", "key": "foobar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := kafkaKeyPartition([]byte("foobar"), 2); res.Body.(map[string]any)["partition"] != want {
+		t.Errorf("keyed produce = %v, want partition %d", res.Body, want)
+	}
+
+	all := consume(map[string]any{"position": "earliest"})
+	if len(all) != 4 {
+		t.Errorf("all partitions = %s", ids(all))
+	}
+	first := all[0].(map[string]any)
+	if first["headers"].(map[string]string)["source"] != "test" || first["timestamp"] == nil {
+		t.Errorf("message = %v", first)
+	}
+
+	start := time.Now()
+	if got := consume(map[string]any{"position": "new", "wait_seconds": 0.2}); len(got) != 0 {
+		t.Errorf("new = %s", ids(got))
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("new returned after %s, before the wait", elapsed)
+	}
+
+	if _, err := exec.Execute(ctx, ops["missing_consume"], map[string]any{}); err == nil || !strings.Contains(err.Error(), "topic missing does not exist") {
+		t.Errorf("missing topic err = %v", err)
+	}
+	if _, err := exec.Execute(ctx, ops["orders_produce"], map[string]any{"value": "x", "partition": float64(5)}); err == nil || !strings.Contains(err.Error(), "partition 5 does not exist") {
+		t.Errorf("bad partition err = %v", err)
+	}
+}
+
+func TestExecuteKafkaSASL(t *testing.T) {
+	for _, mechanism := range []string{"PLAIN", "SCRAM-SHA-256", "SCRAM-SHA-512"} {
+		t.Run(mechanism, func(t *testing.T) {
+			broker := newKafkaCluster(t, "orders", 1, kfake.EnableSASL(), kfake.Superuser(mechanism, "app", "s3cret"))
+			m := &config.MessagingConfig{Broker: "kafka", Servers: broker.ListenAddrs(), SASLMechanism: mechanism, Topics: []config.MessagingTopic{{Name: "orders"}}}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			exec, ops := kafkaExecutor(t, m, &config.AuthConfig{Type: "basic", Username: "app", Password: "s3cret"})
+			if _, err := exec.Execute(ctx, ops["orders_produce"], map[string]any{"value": "hello"}); err != nil {
+				t.Fatalf("produce: %v", err)
+			}
+			// kfake drops the connection on failed logins, which the client
+			// retries until the deadline.
+			deniedCtx, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
+			defer cancel()
+			denied, ops := kafkaExecutor(t, m, &config.AuthConfig{Type: "basic", Username: "app", Password: "wrong"})
+			if _, err := denied.Execute(deniedCtx, ops["orders_produce"], map[string]any{"value": "hello"}); err == nil {
+				t.Error("produce with a wrong password succeeded")
+			}
+		})
+	}
+}
+
+func TestExecuteKafkaCompressedBatches(t *testing.T) {
+	broker := newKafkaCluster(t, "orders", 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	// Other producers may compress their batches with any codec.
+	codecs := []kgo.CompressionCodec{kgo.GzipCompression(), kgo.SnappyCompression(), kgo.Lz4Compression(), kgo.ZstdCompression()}
+	for i, codec := range codecs {
+		producer, err := kgo.NewClient(kgo.SeedBrokers(broker.ListenAddrs()...), kgo.ProducerBatchCompression(codec), kgo.DefaultProduceTopic("orders"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = producer.ProduceSync(ctx, &kgo.Record{Value: []byte(strconv.Itoa(i))}).FirstErr()
+		producer.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	exec, ops := kafkaExecutor(t, &config.MessagingConfig{Broker: "kafka", Servers: broker.ListenAddrs(), Topics: []config.MessagingTopic{{Name: "orders"}}}, nil)
+	res, err := exec.Execute(ctx, ops["orders_consume"], map[string]any{"position": "earliest"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var values []string
+	for _, m := range res.Body.(map[string]any)["messages"].([]any) {
+		values = append(values, fmt.Sprint(m.(map[string]any)["value"]))
+	}
+	if got := strings.Join(values, " "); got != "0 1 2 3" {
+		t.Errorf("values = %s", got)
+	}
+}
+
+// fakeNATS speaks enough of the NATS text protocol for one client:
+// CONNECT, PING, SUB, UNSUB and PUB, delivering messages to its own
+// subscriptions.
+func fakeNATS(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				fmt.Fprintf(conn, "INFO {\"server_id\":\"fake\",\"version\":\"2.10.0\",\"max_payload\":1048576,\"proto\":1}\r\n")
+				r := bufio.NewReader(conn)
+				subs := map[string]string{} // sid to subject
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					fields := strings.Fields(line)
+					if len(fields) == 0 {
+						continue
+					}
+					switch strings.ToUpper(fields[0]) {
+					case "PING":
+						fmt.Fprint(conn, "PONG\r\n")
+					case "SUB":
+						subs[fields[len(fields)-1]] = fields[1]
+					case "UNSUB":
+						delete(subs, fields[1])
+					case "PUB":
+						n, _ := strconv.Atoi(fields[len(fields)-1])
+						payload := make([]byte, n+2)
+						if _, err := io.ReadFull(r, payload); err != nil {
+							return
+						}
+						for sid, subject := range subs {
+							if subject == fields[1] || strings.HasSuffix(subject, ".>") && strings.HasPrefix(fields[1], strings.TrimSuffix(subject, ">")) {
+								fmt.Fprintf(conn, "MSG %s %s %d\r\n%s", fields[1], sid, n, payload)
+							}
+						}
+					}
+				}
+			}()
+		}
+	}()
+	return "nats://" + ln.Addr().String()
+}
+
+func TestExecuteNATS(t *testing.T) {
+	url := fakeNATS(t)
+	cfg := &config.Config{APIs: []config.APIConfig{{
+		Name: "bus", SpecType: "messaging",
+		Messaging: &config.MessagingConfig{Broker: "nats", Servers: []string{url}, Topics: []config.MessagingTopic{
+			{Name: "orders.created"}, {Name: "orders.>", ReadOnly: true},
+		}},
+	}}}
+	cfg.ApplyDefaults()
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	svc, err := messaging.BuildService("bus", cfg.APIs[0].Messaging)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exec, err := NewExecutor(cfg, []*canonical.Service{svc}, logging.Discard(), redact.NewRedactor())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer exec.Close()
+	ops := map[string]*canonical.Operation{}
+	for _, op := range svc.Operations {
+		ops[op.ID] = op
+	}
+	ctx := context.Background()
+
+	type outcome struct {
+		res *Result
+		err error
+	}
+	done := make(chan outcome)
+	go func() {
+		res, err := exec.Execute(ctx, ops["orders_consume"], map[string]any{"max_messages": float64(2), "wait_seconds": 5.0})
+		done <- outcome{res, err}
+	}()
+	// Publish until the subscriber, which only sees messages sent after it
+	// subscribed, has two.
+	var got outcome
+	for i := 0; got.res == nil && got.err == nil; i++ {
+		if _, err := exec.Execute(ctx, ops["orders_created_produce"], map[string]any{"value": map[string]any{"n": float64(i)}}); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case got = <-done:
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+	if got.err != nil {
+		t.Fatal(got.err)
+	}
+	msgs := got.res.Body.(map[string]any)["messages"].([]any)
+	if len(msgs) != 2 {
+		t.Fatalf("messages = %v", msgs)
+	}
+	first := msgs[0].(map[string]any)
+	if first["subject"] != "orders.created" || first["value"].(map[string]any)["n"] == nil {
+		t.Errorf("message = %v", first)
+	}
+
+	start := time.Now()
+	res, err := exec.Execute(ctx, ops["orders_consume"], map[string]any{"wait_seconds": 0.1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := res.Body.(map[string]any)["count"]; n != 0 || time.Since(start) < 100*time.Millisecond {
+		t.Errorf("idle consume = %v after %s", n, time.Since(start))
+	}
+}
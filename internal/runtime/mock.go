@@ -0,0 +1,14 @@
+package runtime
+
+import "skyline-mcp/internal/canonical"
+
+// mockResult answers a call to a mock API with a body synthesized from the
+// operation's response schema, preferring the examples the spec provides.
+// Operations without a response schema return an empty object.
+func mockResult(op *canonical.Operation) *Result {
+	var body any = map[string]any{}
+	if op.ResponseSchema != nil {
+		body = canonical.ExampleValue(op.ResponseSchema, false)
+	}
+	return &Result{Status: 200, ContentType: "application/json", Body: body}
+}
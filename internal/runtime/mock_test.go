@@ -0,0 +1,89 @@
+package runtime_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/logging"
+	"skyline-mcp/internal/parsers/openapi"
+	"skyline-mcp/internal/redact"
+	"skyline-mcp/internal/runtime"
+)
+
+func TestExecutorMockAPI(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer server.Close()
+
+	spec := []byte(`{
+  "openapi": "3.0.0",
+  "info": {"title": "Pets", "version": "1.0"},
+  "paths": {
+    "/pets/{id}": {
+      "get": {
+        "operationId": "getPet",
+        "parameters": [{"name": "id", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {"200": {"description": "ok", "content": {"application/json": {
+          "schema": {"type": "object", "properties": {"name": {"type": "string"}}},
+          "example": {"name": "rex", "age": 3}
+        }}}}
+      }
+    },
+    "/pets": {
+      "get": {
+        "operationId": "listPets",
+        "responses": {"200": {"description": "ok", "content": {"application/json": {
+          "schema": {"type": "array", "items": {"type": "object", "properties": {
+            "id": {"type": "integer"},
+            "born": {"type": "string", "format": "date"}
+          }}}
+        }}}}
+      }
+    }
+  }
+}`)
+	svc, err := openapi.ParseToCanonical(context.Background(), spec, "pets", server.URL)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	cfg := &config.Config{APIs: []config.APIConfig{{Name: "pets", SpecURL: server.URL, Mock: true}}}
+	cfg.ApplyDefaults()
+	exec, err := runtime.NewExecutor(cfg, []*canonical.Service{svc}, logging.Discard(), redact.NewRedactor())
+	if err != nil {
+		t.Fatalf("executor init failed: %v", err)
+	}
+	ops := map[string]*canonical.Operation{}
+	for _, op := range svc.Operations {
+		ops[op.ID] = op
+	}
+
+	res, err := exec.Execute(context.Background(), ops["getPet"], map[string]any{"id": "1"})
+	if err != nil {
+		t.Fatalf("mock call failed: %v", err)
+	}
+	if body, _ := res.Body.(map[string]any); res.Status != 200 || body["name"] != "rex" {
+		t.Fatalf("expected the spec example, got %+v", res)
+	}
+
+	res, err = exec.Execute(context.Background(), ops["listPets"], map[string]any{})
+	if err != nil {
+		t.Fatalf("mock call failed: %v", err)
+	}
+	items, _ := res.Body.([]any)
+	if len(items) != 1 {
+		t.Fatalf("expected one synthesized item, got %#v", res.Body)
+	}
+	if item, _ := items[0].(map[string]any); item["id"] != 1 || item["born"] != "2024-01-15" {
+		t.Fatalf("unexpected synthesized item: %#v", item)
+	}
+	if calls.Load() != 0 {
+		t.Fatalf("mock API called the upstream %d times", calls.Load())
+	}
+}
@@ -0,0 +1,123 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"skyline-mcp/internal/canonical"
+)
+
+// natsClient publishes and subscribes on core NATS subjects. Core NATS
+// keeps no messages, so consume only sees what is published while it
+// waits.
+type natsClient struct {
+	servers  []string
+	username string
+	password string
+	token    string
+
+	mu   sync.Mutex
+	conn *nats.Conn
+}
+
+func (n *natsClient) connection(ctx context.Context) (*nats.Conn, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.conn != nil && !n.conn.IsClosed() {
+		return n.conn, nil
+	}
+	opts := []nats.Option{nats.Name(brokerClientName), nats.MaxReconnects(-1)}
+	if deadline, ok := ctx.Deadline(); ok {
+		opts = append(opts, nats.Timeout(max(time.Until(deadline), time.Millisecond)))
+	}
+	if n.username != "" {
+		opts = append(opts, nats.UserInfo(n.username, n.password))
+	}
+	if n.token != "" {
+		opts = append(opts, nats.Token(n.token))
+	}
+	conn, err := nats.Connect(strings.Join(n.servers, ","), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("nats: %w", err)
+	}
+	n.conn = conn
+	return conn, nil
+}
+
+func (n *natsClient) Produce(ctx context.Context, op *canonical.MessagingOperation, msg outMessage) (map[string]any, error) {
+	conn, err := n.connection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := &nats.Msg{Subject: op.Topic, Data: msg.Value}
+	if len(msg.Headers) > 0 {
+		if !conn.HeadersSupported() {
+			return nil, fmt.Errorf("nats: the server does not support headers (NATS 2.2 or later)")
+		}
+		out.Header = nats.Header{}
+		for name, value := range msg.Headers {
+			out.Header.Set(name, value)
+		}
+	}
+	if err := conn.PublishMsg(out); err != nil {
+		return nil, fmt.Errorf("nats: publish to %s: %w", op.Topic, err)
+	}
+	// A flush round trip makes sure the server has the message.
+	if err := conn.FlushWithContext(ctx); err != nil {
+		return nil, fmt.Errorf("nats: publish to %s: %w", op.Topic, err)
+	}
+	return map[string]any{"subject": op.Topic, "published": true}, nil
+}
+
+func (n *natsClient) Consume(ctx context.Context, op *canonical.MessagingOperation, req consumeRequest) ([]brokerMessage, error) {
+	conn, err := n.connection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sub, err := conn.SubscribeSync(op.Topic)
+	if err != nil {
+		return nil, fmt.Errorf("nats: subscribe to %s: %w", op.Topic, err)
+	}
+	defer sub.Unsubscribe()
+	if err := conn.FlushWithContext(ctx); err != nil {
+		return nil, fmt.Errorf("nats: subscribe to %s: %w", op.Topic, err)
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, req.Wait)
+	defer cancel()
+	var msgs []brokerMessage
+	for len(msgs) < req.Max {
+		m, err := sub.NextMsgWithContext(waitCtx)
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+				break
+			}
+			return nil, fmt.Errorf("nats: %s: %w", op.Topic, err)
+		}
+		msg := brokerMessage{Subject: m.Subject, Value: m.Data}
+		if len(m.Header) > 0 {
+			msg.Headers = map[string]string{}
+			for name := range m.Header {
+				msg.Headers[name] = m.Header.Get(name)
+			}
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
+
+func (n *natsClient) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.conn != nil {
+		n.conn.Close()
+		n.conn = nil
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// maxStreamItems bounds the values read from a line-delimited response;
+// the rest of the stream is dropped.
+const maxStreamItems = 1000
+
+// StreamInfo describes a result read from a line-delimited JSON stream.
+type StreamInfo struct {
+	Items int `json:"items"`
+	// Truncated is set when the stream was cut short: it had more than
+	// maxStreamItems values or was still open when the call timed out.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// StreamHandler is told about each value of a line-delimited response as
+// it arrives, e.g. to forward it to the client before the call finishes.
+type StreamHandler func(index int, item any)
+
+type streamHandlerKey struct{}
+
+// WithStreamHandler attaches a StreamHandler to the context of a tool call.
+func WithStreamHandler(ctx context.Context, handler StreamHandler) context.Context {
+	return context.WithValue(ctx, streamHandlerKey{}, handler)
+}
+
+func streamHandler(ctx context.Context) StreamHandler {
+	handler, _ := ctx.Value(streamHandlerKey{}).(StreamHandler)
+	return handler
+}
+
+// isJSONStream reports whether a content type is line-delimited JSON.
+func isJSONStream(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch mediaType {
+	case "application/x-ndjson", "application/ndjson", "application/jsonl", "application/jsonlines",
+		"application/x-jsonlines", "application/json-seq", "application/stream+json", "application/x-json-stream":
+		return true
+	}
+	return false
+}
+
+// normalizeStream reads a successful line-delimited JSON response as it
+// arrives into a list of its values. Values are passed to the call's
+// StreamHandler, if any. A stream still open when the call times out
+// returns what arrived so far.
+func normalizeStream(resp *http.Response, contentType string) (*Result, error) {
+	defer resp.Body.Close()
+	var handler StreamHandler
+	ctx := context.Background()
+	if resp.Request != nil {
+		ctx = resp.Request.Context()
+		handler = streamHandler(ctx)
+	}
+	items, truncated, err := decodeJSONStream(io.LimitReader(resp.Body, maxResponseSize), handler)
+	if err != nil {
+		if len(items) == 0 || ctx.Err() == nil {
+			return nil, fmt.Errorf("read response: %w", err)
+		}
+		truncated = true
+	}
+	return &Result{
+		Status:      resp.StatusCode,
+		ContentType: contentType,
+		Body:        items,
+		Stream:      &StreamInfo{Items: len(items), Truncated: truncated},
+	}, nil
+}
+
+// decodeJSONStream decodes consecutive JSON values, separated by newlines,
+// other whitespace or JSON text sequence record separators, up to
+// maxStreamItems.
+func decodeJSONStream(r io.Reader, handler StreamHandler) ([]any, bool, error) {
+	dec := json.NewDecoder(&recordSeparatorReader{r: r})
+	items := []any{}
+	for {
+		var item any
+		err := dec.Decode(&item)
+		if errors.Is(err, io.EOF) {
+			return items, false, nil
+		}
+		if err != nil {
+			return items, false, err
+		}
+		if len(items) == maxStreamItems {
+			return items, true, nil
+		}
+		items = append(items, item)
+		if handler != nil {
+			handler(len(items)-1, item)
+		}
+	}
+}
+
+// splitJSONValues decodes a buffered body holding several concatenated
+// JSON values, as some streaming endpoints send under application/json.
+// It reports false unless the whole body is two or more values.
+func splitJSONValues(body []byte) ([]any, bool) {
+	items, truncated, err := decodeJSONStream(bytes.NewReader(body), nil)
+	if err != nil || truncated || len(items) < 2 {
+		return nil, false
+	}
+	return items, true
+}
+
+// recordSeparatorReader turns the RS characters that start records of
+// application/json-seq into spaces, so a json.Decoder skips them.
+type recordSeparatorReader struct {
+	r io.Reader
+}
+
+func (rs *recordSeparatorReader) Read(p []byte) (int, error) {
+	n, err := rs.r.Read(p)
+	for i := range p[:n] {
+		if p[i] == 0x1e {
+			p[i] = ' '
+		}
+	}
+	return n, err
+}
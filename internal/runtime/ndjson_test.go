@@ -0,0 +1,94 @@
+package runtime
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func streamResponse(ctx context.Context, contentType string, body io.Reader) *http.Response {
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://upstream/events", nil)
+	return &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{"Content-Type": {contentType}},
+		Body:       io.NopCloser(body),
+		Request:    req,
+	}
+}
+
+func TestNormalizeNDJSON(t *testing.T) {
+	var streamed []any
+	ctx := WithStreamHandler(context.Background(), func(index int, item any) {
+		if index != len(streamed) {
+			t.Errorf("item %d streamed at position %d", index, len(streamed))
+		}
+		streamed = append(streamed, item)
+	})
+	body := "{\"id\":1}\n\n{\"id\":2}\r\n{\"id\":3}\n"
+	result, _, _, err := normalizeResponse(streamResponse(ctx, "application/x-ndjson; charset=utf-8", strings.NewReader(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []any{map[string]any{"id": float64(1)}, map[string]any{"id": float64(2)}, map[string]any{"id": float64(3)}}
+	if !reflect.DeepEqual(result.Body, want) || !reflect.DeepEqual(streamed, want) {
+		t.Fatalf("body = %v, streamed = %v", result.Body, streamed)
+	}
+	if result.Stream == nil || result.Stream.Items != 3 || result.Stream.Truncated {
+		t.Fatalf("stream = %+v", result.Stream)
+	}
+
+	// JSON text sequences start each record with RS.
+	result, _, _, err = normalizeResponse(streamResponse(context.Background(), "application/json-seq", strings.NewReader("\x1e{\"id\":1}\n\x1e{\"id\":2}\n")))
+	if err != nil || len(result.Body.([]any)) != 2 {
+		t.Fatalf("json-seq: %v, %v", result, err)
+	}
+
+	// Long streams are cut at maxStreamItems.
+	result, _, _, err = normalizeResponse(streamResponse(context.Background(), "application/x-ndjson", strings.NewReader(strings.Repeat("{}\n", maxStreamItems+5))))
+	if err != nil || len(result.Body.([]any)) != maxStreamItems || !result.Stream.Truncated {
+		t.Fatalf("long stream: %d items, %+v, %v", len(result.Body.([]any)), result.Stream, err)
+	}
+
+	// A malformed line fails the call.
+	if _, _, _, err := normalizeResponse(streamResponse(context.Background(), "application/x-ndjson", strings.NewReader("{\"id\":1}\n{oops\n"))); err == nil {
+		t.Fatal("malformed stream accepted")
+	}
+}
+
+func TestNormalizeStreamKeepsItemsOnTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	pr, pw := io.Pipe()
+	go func() {
+		_, _ = pw.Write([]byte("{\"event\":\"started\"}\n"))
+		<-ctx.Done()
+		pw.CloseWithError(ctx.Err())
+	}()
+	result, _, _, err := normalizeResponse(streamResponse(ctx, "application/x-ndjson", pr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Body.([]any)) != 1 || !result.Stream.Truncated {
+		t.Fatalf("body = %v, stream = %+v", result.Body, result.Stream)
+	}
+}
+
+func TestNormalizeConcatenatedJSON(t *testing.T) {
+	resp := streamResponse(context.Background(), "application/json", strings.NewReader(`{"id":1}{"id":2}`))
+	result, _, _, err := normalizeResponse(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if items, ok := result.Body.([]any); !ok || len(items) != 2 {
+		t.Fatalf("body = %#v", result.Body)
+	}
+
+	resp = streamResponse(context.Background(), "application/json", strings.NewReader(`{"id":1} trailing`))
+	if result, _, _, _ := normalizeResponse(resp); result.Body != `{"id":1} trailing` {
+		t.Fatalf("body = %#v, want the raw text", result.Body)
+	}
+}
@@ -1,10 +1,12 @@
 package runtime
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
 	"sync"
 	"time"
 
@@ -14,6 +16,10 @@ import (
 const (
 	defaultGoogleTokenURL = "https://oauth2.googleapis.com/token" //nolint:gosec // not actual credentials
 	tokenExpiryBuffer     = 5 * time.Minute
+	deviceCodeGrant       = "urn:ietf:params:oauth:grant-type:device_code"
+	// defaultDevicePollInterval is how often the token endpoint is polled
+	// when the device authorization response gives no interval.
+	defaultDevicePollInterval = 5
 )
 
 // OAuth2TokenManager caches OAuth2 access tokens per API and refreshes
@@ -21,34 +27,194 @@ const (
 type OAuth2TokenManager struct {
 	mu     sync.Mutex
 	tokens map[string]*cachedToken
+	flows  map[string]*deviceFlow // device authorizations waiting for the user
 	client *http.Client
+	// pollUnit scales the poll interval of device flows; a second except
+	// in tests.
+	pollUnit time.Duration
 }
 
 type cachedToken struct {
 	accessToken string
-	expiresAt   time.Time
+	// refreshToken is the refresh token issued with a device flow token.
+	refreshToken string
+	expiresAt    time.Time
+	// source identifies the auth config the token was issued for, so an
+	// edited config does not keep using it.
+	source string
+}
+
+// DeviceCode is what the user needs to authorize a device flow.
+type DeviceCode struct {
+	UserCode        string `json:"user_code"`
+	VerificationURI string `json:"verification_uri"`
+	// VerificationURIComplete embeds the user code, when the
+	// authorization server provides it.
+	VerificationURIComplete string    `json:"verification_uri_complete,omitempty"`
+	ExpiresAt               time.Time `json:"expires_at"`
+}
+
+// DeviceAuthPendingError is returned for an API using the device flow
+// until the user has authorized it.
+type DeviceAuthPendingError struct {
+	Code DeviceCode
+}
+
+func (e *DeviceAuthPendingError) Error() string {
+	return fmt.Sprintf("waiting for the user to authorize access: open %s and enter code %s before %s, then retry",
+		e.Code.VerificationURI, e.Code.UserCode, e.Code.ExpiresAt.UTC().Format(time.RFC3339))
+}
+
+type deviceFlow struct {
+	code   DeviceCode
+	source string
+	// err is set when the flow ended without a token, e.g. the user
+	// denied access; the next call reports it and starts over.
+	err error
 }
 
 // NewOAuth2TokenManager creates a new token manager.
 func NewOAuth2TokenManager() *OAuth2TokenManager {
 	return &OAuth2TokenManager{
-		tokens: make(map[string]*cachedToken),
-		client: &http.Client{Timeout: 10 * time.Second},
+		tokens:   make(map[string]*cachedToken),
+		flows:    make(map[string]*deviceFlow),
+		client:   &http.Client{Timeout: 10 * time.Second},
+		pollUnit: time.Second,
 	}
 }
 
 // GetAccessToken returns a valid access token for the given API,
 // refreshing from the token endpoint if the cached token is expired.
-func (m *OAuth2TokenManager) GetAccessToken(apiName string, auth *config.AuthConfig) (string, error) {
+// key identifies the API among those sharing the manager.
+//
+// With the device_code flow, the first call starts a device authorization
+// and returns a *DeviceAuthPendingError after passing the code to
+// onDeviceCode, which may be nil and is called with the manager locked.
+// The token endpoint is polled in the background until the user has
+// authorized the device.
+func (m *OAuth2TokenManager) GetAccessToken(key string, auth *config.AuthConfig, onDeviceCode func(DeviceCode)) (string, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if cached, ok := m.tokens[apiName]; ok {
-		if time.Now().Before(cached.expiresAt.Add(-tokenExpiryBuffer)) {
-			return cached.accessToken, nil
+	source := tokenSource(auth)
+	cached, ok := m.tokens[key]
+	if ok && cached.source != source {
+		delete(m.tokens, key)
+		ok = false
+	}
+	if ok && time.Now().Before(cached.expiresAt.Add(-tokenExpiryBuffer)) {
+		return cached.accessToken, nil
+	}
+
+	if auth.Flow != "device_code" {
+		tok, err := m.refresh(auth, auth.RefreshToken)
+		if err != nil {
+			return "", err
 		}
+		tok.source = source
+		m.tokens[key] = tok
+		return tok.accessToken, nil
 	}
 
+	if ok && cached.refreshToken != "" {
+		tok, err := m.refresh(auth, cached.refreshToken)
+		if err == nil {
+			if tok.refreshToken == "" {
+				tok.refreshToken = cached.refreshToken
+			}
+			tok.source = source
+			m.tokens[key] = tok
+			return tok.accessToken, nil
+		}
+		// The refresh token expired or was revoked: authorize again.
+		delete(m.tokens, key)
+	}
+
+	if flow, pending := m.flows[key]; pending && flow.source == source {
+		if flow.err != nil {
+			delete(m.flows, key)
+			return "", flow.err
+		}
+		if time.Now().Before(flow.code.ExpiresAt) {
+			return "", &DeviceAuthPendingError{Code: flow.code}
+		}
+	}
+	flow, deviceCode, interval, err := m.startDeviceFlow(auth)
+	if err != nil {
+		return "", err
+	}
+	flow.source = source
+	m.flows[key] = flow
+	go m.pollDeviceFlow(key, flow, auth, deviceCode, interval)
+	if onDeviceCode != nil {
+		onDeviceCode(flow.code)
+	}
+	return "", &DeviceAuthPendingError{Code: flow.code}
+}
+
+// Forget drops the tokens and device flows cached under profile, e.g. when
+// it is deleted.
+func (m *OAuth2TokenManager) Forget(profile string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key := range m.tokens {
+		if strings.HasPrefix(key, profile+"/") {
+			delete(m.tokens, key)
+		}
+	}
+	for key := range m.flows {
+		if strings.HasPrefix(key, profile+"/") {
+			delete(m.flows, key)
+		}
+	}
+}
+
+// tokenSource fingerprints the parts of auth a token depends on.
+func tokenSource(auth *config.AuthConfig) string {
+	return strings.Join([]string{auth.Flow, auth.TokenURL, auth.DeviceAuthURL, auth.ClientID, auth.ClientSecret, auth.RefreshToken, auth.Scope}, "\x00")
+}
+
+// tokenResponse is the answer of a token or device authorization
+// endpoint.
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	TokenType    string `json:"token_type"`
+	Error        string `json:"error"`
+	ErrorDesc    string `json:"error_description"`
+	// Device authorization responses
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURL         string `json:"verification_url"` // Google's name for it
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	Interval                int    `json:"interval"`
+}
+
+// postForm posts data to endpoint and decodes the JSON answer. Some
+// servers, such as GitHub's, only answer in JSON when asked to.
+func (m *OAuth2TokenManager) postForm(ctx context.Context, endpoint string, data url.Values) (*tokenResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(data.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var tokenResp tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return nil, fmt.Errorf("parse response: %w", err)
+	}
+	return &tokenResp, nil
+}
+
+// refresh exchanges refreshToken for an access token.
+func (m *OAuth2TokenManager) refresh(auth *config.AuthConfig, refreshToken string) (*cachedToken, error) {
 	tokenURL := auth.TokenURL
 	if tokenURL == "" {
 		tokenURL = defaultGoogleTokenURL
@@ -56,43 +222,129 @@ func (m *OAuth2TokenManager) GetAccessToken(apiName string, auth *config.AuthCon
 
 	data := url.Values{
 		"client_id":     {auth.ClientID},
-		"client_secret": {auth.ClientSecret},
-		"refresh_token": {auth.RefreshToken},
+		"refresh_token": {refreshToken},
 		"grant_type":    {"refresh_token"},
 	}
-
-	resp, err := m.client.PostForm(tokenURL, data)
-	if err != nil {
-		return "", fmt.Errorf("oauth2 token refresh: %w", err)
+	if auth.ClientSecret != "" {
+		data.Set("client_secret", auth.ClientSecret)
 	}
-	defer resp.Body.Close()
 
-	var tokenResp struct {
-		AccessToken string `json:"access_token"`
-		ExpiresIn   int    `json:"expires_in"`
-		TokenType   string `json:"token_type"`
-		Error       string `json:"error"`
-		ErrorDesc   string `json:"error_description"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
-		return "", fmt.Errorf("oauth2 token parse: %w", err)
+	tokenResp, err := m.postForm(context.Background(), tokenURL, data)
+	if err != nil {
+		return nil, fmt.Errorf("oauth2 token refresh: %w", err)
 	}
 	if tokenResp.Error != "" {
-		return "", fmt.Errorf("oauth2: %s — %s", tokenResp.Error, tokenResp.ErrorDesc)
+		return nil, fmt.Errorf("oauth2: %s — %s", tokenResp.Error, tokenResp.ErrorDesc)
 	}
 	if tokenResp.AccessToken == "" {
-		return "", fmt.Errorf("oauth2 token refresh: empty access_token")
+		return nil, fmt.Errorf("oauth2 token refresh: empty access_token")
 	}
+	return newCachedToken(tokenResp), nil
+}
 
+func newCachedToken(tokenResp *tokenResponse) *cachedToken {
 	expiresIn := time.Duration(tokenResp.ExpiresIn) * time.Second
 	if expiresIn <= 0 {
 		expiresIn = 3600 * time.Second
 	}
+	return &cachedToken{
+		accessToken:  tokenResp.AccessToken,
+		refreshToken: tokenResp.RefreshToken,
+		expiresAt:    time.Now().Add(expiresIn),
+	}
+}
+
+// startDeviceFlow requests a device and user code (RFC 8628).
+func (m *OAuth2TokenManager) startDeviceFlow(auth *config.AuthConfig) (*deviceFlow, string, time.Duration, error) {
+	data := url.Values{"client_id": {auth.ClientID}}
+	if auth.Scope != "" {
+		data.Set("scope", auth.Scope)
+	}
+	resp, err := m.postForm(context.Background(), auth.DeviceAuthURL, data)
+	if err != nil {
+		return nil, "", 0, fmt.Errorf("oauth2 device authorization: %w", err)
+	}
+	if resp.Error != "" {
+		return nil, "", 0, fmt.Errorf("oauth2 device authorization: %s — %s", resp.Error, resp.ErrorDesc)
+	}
+	if resp.VerificationURI == "" {
+		resp.VerificationURI = resp.VerificationURL
+	}
+	if resp.DeviceCode == "" || resp.UserCode == "" || resp.VerificationURI == "" {
+		return nil, "", 0, fmt.Errorf("oauth2 device authorization: incomplete response")
+	}
+	expiresIn := time.Duration(resp.ExpiresIn) * time.Second
+	if expiresIn <= 0 {
+		expiresIn = 15 * time.Minute
+	}
+	interval := resp.Interval
+	if interval <= 0 {
+		interval = defaultDevicePollInterval
+	}
+	flow := &deviceFlow{code: DeviceCode{
+		UserCode:                resp.UserCode,
+		VerificationURI:         resp.VerificationURI,
+		VerificationURIComplete: resp.VerificationURIComplete,
+		ExpiresAt:               time.Now().Add(expiresIn),
+	}}
+	return flow, resp.DeviceCode, time.Duration(interval) * m.pollUnit, nil
+}
 
-	m.tokens[apiName] = &cachedToken{
-		accessToken: tokenResp.AccessToken,
-		expiresAt:   time.Now().Add(expiresIn),
+// pollDeviceFlow polls the token endpoint until the user authorized or
+// denied the device, or the code expired, and caches the token.
+func (m *OAuth2TokenManager) pollDeviceFlow(key string, flow *deviceFlow, auth *config.AuthConfig, deviceCode string, interval time.Duration) {
+	data := url.Values{
+		"client_id":   {auth.ClientID},
+		"device_code": {deviceCode},
+		"grant_type":  {deviceCodeGrant},
 	}
+	if auth.ClientSecret != "" {
+		data.Set("client_secret", auth.ClientSecret)
+	}
+	ctx, cancel := context.WithDeadline(context.Background(), flow.code.ExpiresAt)
+	defer cancel()
 
-	return tokenResp.AccessToken, nil
+	fail := func(err error) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if m.flows[key] == flow {
+			flow.err = err
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			fail(fmt.Errorf("oauth2 device authorization expired before the user authorized it"))
+			return
+		case <-time.After(interval):
+		}
+		resp, err := m.postForm(ctx, auth.TokenURL, data)
+		if err != nil {
+			continue // transient; the deadline bounds the retries
+		}
+		switch resp.Error {
+		case "":
+		case "authorization_pending":
+			continue
+		case "slow_down":
+			interval += 5 * m.pollUnit
+			continue
+		default:
+			fail(fmt.Errorf("oauth2 device authorization: %s — %s", resp.Error, resp.ErrorDesc))
+			return
+		}
+		if resp.AccessToken == "" {
+			fail(fmt.Errorf("oauth2 device authorization: empty access_token"))
+			return
+		}
+		tok := newCachedToken(resp)
+		tok.source = flow.source
+		m.mu.Lock()
+		if m.flows[key] == flow {
+			delete(m.flows, key)
+			m.tokens[key] = tok
+		}
+		m.mu.Unlock()
+		return
+	}
 }
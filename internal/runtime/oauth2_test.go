@@ -0,0 +1,156 @@
+package runtime
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"skyline-mcp/internal/config"
+)
+
+// deviceServer is an authorization server whose user authorizes the device
+// after approveAfter polls.
+type deviceServer struct {
+	*httptest.Server
+	mu           sync.Mutex
+	polls        int
+	approveAfter int
+	deny         bool
+}
+
+func newDeviceServer(t *testing.T, approveAfter int) *deviceServer {
+	t.Helper()
+	ds := &deviceServer{approveAfter: approveAfter}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/device", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("client_id") != "cli" || r.FormValue("scope") != "repo read:org" {
+			t.Errorf("device request form = %v", r.Form)
+		}
+		writeJSONBody(w, map[string]any{
+			"device_code":      "dev-123",
+			"user_code":        "WDJB-MJHT",
+			"verification_uri": "https://example.com/device",
+			"expires_in":       900,
+			"interval":         1,
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "application/json" {
+			t.Errorf("Accept = %q", r.Header.Get("Accept"))
+		}
+		ds.mu.Lock()
+		defer ds.mu.Unlock()
+		switch r.FormValue("grant_type") {
+		case deviceCodeGrant:
+			if r.FormValue("device_code") != "dev-123" {
+				t.Errorf("device_code = %q", r.FormValue("device_code"))
+			}
+			ds.polls++
+			switch {
+			case ds.deny:
+				writeJSONBody(w, map[string]any{"error": "access_denied", "error_description": "the user said no"})
+			case ds.polls == 1:
+				writeJSONBody(w, map[string]any{"error": "slow_down"})
+			case ds.polls < ds.approveAfter:
+				writeJSONBody(w, map[string]any{"error": "authorization_pending"})
+			default:
+				writeJSONBody(w, map[string]any{"access_token": "first", "refresh_token": "rt-1", "expires_in": 3600})
+			}
+		case "refresh_token":
+			if r.FormValue("refresh_token") != "rt-1" {
+				t.Errorf("refresh_token = %q", r.FormValue("refresh_token"))
+			}
+			writeJSONBody(w, map[string]any{"access_token": "second", "expires_in": 3600})
+		}
+	})
+	ds.Server = httptest.NewServer(mux)
+	t.Cleanup(ds.Close)
+	return ds
+}
+
+func writeJSONBody(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func deviceAuth(ds *deviceServer) *config.AuthConfig {
+	return &config.AuthConfig{
+		Type:          "oauth2",
+		Flow:          "device_code",
+		ClientID:      "cli",
+		DeviceAuthURL: ds.URL + "/device",
+		TokenURL:      ds.URL + "/token",
+		Scope:         "repo read:org",
+	}
+}
+
+func TestDeviceFlow(t *testing.T) {
+	ds := newDeviceServer(t, 3)
+	m := NewOAuth2TokenManager()
+	m.pollUnit = time.Millisecond
+	auth := deviceAuth(ds)
+
+	var codes []DeviceCode
+	onCode := func(code DeviceCode) { codes = append(codes, code) }
+	_, err := m.GetAccessToken("dev/github", auth, onCode)
+	var pending *DeviceAuthPendingError
+	if !errors.As(err, &pending) || pending.Code.UserCode != "WDJB-MJHT" || pending.Code.VerificationURI != "https://example.com/device" {
+		t.Fatalf("first call err = %v", err)
+	}
+	if len(codes) != 1 {
+		t.Fatalf("onDeviceCode called %d times", len(codes))
+	}
+
+	var token string
+	deadline := time.Now().Add(5 * time.Second)
+	for token == "" && time.Now().Before(deadline) {
+		token, err = m.GetAccessToken("dev/github", auth, onCode)
+		if err != nil && !errors.As(err, &pending) {
+			t.Fatalf("while pending: %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if token != "first" {
+		t.Fatalf("token = %q, err = %v", token, err)
+	}
+	if len(codes) != 1 {
+		t.Fatalf("a pending flow was started again: %d codes", len(codes))
+	}
+
+	// Once the token expires, the refresh token it came with renews it.
+	m.mu.Lock()
+	m.tokens["dev/github"].expiresAt = time.Now()
+	m.mu.Unlock()
+	if token, err = m.GetAccessToken("dev/github", auth, onCode); err != nil || token != "second" {
+		t.Fatalf("after expiry token = %q, err = %v", token, err)
+	}
+}
+
+func TestDeviceFlowDenied(t *testing.T) {
+	ds := newDeviceServer(t, 3)
+	ds.deny = true
+	m := NewOAuth2TokenManager()
+	m.pollUnit = time.Millisecond
+	auth := deviceAuth(ds)
+
+	if _, err := m.GetAccessToken("dev/github", auth, nil); err == nil {
+		t.Fatal("first call succeeded")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		_, err := m.GetAccessToken("dev/github", auth, nil)
+		var pending *DeviceAuthPendingError
+		if !errors.As(err, &pending) {
+			if err == nil || err.Error() != "oauth2 device authorization: access_denied — the user said no" {
+				t.Fatalf("err = %v", err)
+			}
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("denial was never reported")
+}
@@ -0,0 +1,283 @@
+package runtime
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+
+	"skyline-mcp/internal/canonical"
+)
+
+// odataBatchItem is one request of an OData $batch tool call.
+type odataBatchItem struct {
+	ID        string            `json:"id"`
+	Method    string            `json:"method"`
+	URL       string            `json:"url"`
+	Headers   map[string]string `json:"headers"`
+	Body      any               `json:"body"`
+	Changeset string            `json:"changeset"`
+}
+
+// odataBatchItems reads the requests argument of a $batch call, giving
+// requests without an id their 1-based position.
+func odataBatchItems(args map[string]any) ([]odataBatchItem, error) {
+	raw, err := json.Marshal(args["requests"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid requests: %w", err)
+	}
+	var items []odataBatchItem
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, fmt.Errorf("invalid requests: %w", err)
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("requests must contain at least one request")
+	}
+	for i := range items {
+		item := &items[i]
+		item.Method = strings.ToUpper(item.Method)
+		if item.ID == "" {
+			item.ID = strconv.Itoa(i + 1)
+		}
+		if item.URL == "" {
+			return nil, fmt.Errorf("request %s: url is required", item.ID)
+		}
+		if item.Changeset != "" && item.Method == http.MethodGet {
+			return nil, fmt.Errorf("request %s: GET requests cannot be part of a changeset", item.ID)
+		}
+	}
+	return items, nil
+}
+
+// buildODataBatchBody encodes the requests of a $batch call in the format
+// the service takes and returns the body with its content type.
+func buildODataBatchBody(op *canonical.Operation, baseURL string, args map[string]any) ([]byte, string, error) {
+	items, err := odataBatchItems(args)
+	if err != nil {
+		return nil, "", err
+	}
+	if op.ODataBatch.JSON {
+		return buildODataJSONBatch(items)
+	}
+	return buildODataMultipartBatch(baseURL, items)
+}
+
+// buildODataJSONBatch encodes an OData 4.01 JSON batch; changesets become
+// atomicity groups.
+func buildODataJSONBatch(items []odataBatchItem) ([]byte, string, error) {
+	requests := make([]map[string]any, 0, len(items))
+	for _, item := range items {
+		req := map[string]any{"id": item.ID, "method": item.Method, "url": item.URL}
+		headers := map[string]string{}
+		for name, value := range item.Headers {
+			headers[strings.ToLower(name)] = value
+		}
+		if item.Body != nil {
+			req["body"] = item.Body
+			if _, ok := headers["content-type"]; !ok {
+				headers["content-type"] = "application/json"
+			}
+		}
+		if len(headers) > 0 {
+			req["headers"] = headers
+		}
+		if item.Changeset != "" {
+			req["atomicityGroup"] = item.Changeset
+		}
+		requests = append(requests, req)
+	}
+	body, err := json.Marshal(map[string]any{"requests": requests})
+	if err != nil {
+		return nil, "", fmt.Errorf("encode batch: %w", err)
+	}
+	return body, "application/json", nil
+}
+
+// buildODataMultipartBatch encodes a multipart/mixed batch. Consecutive
+// requests of the same changeset share a nested multipart part. Request
+// URLs are made absolute, except references to earlier requests ($1/...).
+func buildODataMultipartBatch(baseURL string, items []odataBatchItem) ([]byte, string, error) {
+	var buf bytes.Buffer
+	batch := multipart.NewWriter(&buf)
+	for i := 0; i < len(items); {
+		if items[i].Changeset == "" {
+			if err := writeODataBatchPart(batch, baseURL, items[i]); err != nil {
+				return nil, "", err
+			}
+			i++
+			continue
+		}
+		var cs bytes.Buffer
+		changeset := multipart.NewWriter(&cs)
+		name := items[i].Changeset
+		for ; i < len(items) && items[i].Changeset == name; i++ {
+			if err := writeODataBatchPart(changeset, baseURL, items[i]); err != nil {
+				return nil, "", err
+			}
+		}
+		if err := changeset.Close(); err != nil {
+			return nil, "", fmt.Errorf("encode batch: %w", err)
+		}
+		part, err := batch.CreatePart(textproto.MIMEHeader{
+			"Content-Type": {"multipart/mixed; boundary=" + changeset.Boundary()},
+		})
+		if err != nil {
+			return nil, "", fmt.Errorf("encode batch: %w", err)
+		}
+		_, _ = part.Write(cs.Bytes())
+	}
+	if err := batch.Close(); err != nil {
+		return nil, "", fmt.Errorf("encode batch: %w", err)
+	}
+	return buf.Bytes(), "multipart/mixed; boundary=" + batch.Boundary(), nil
+}
+
+func writeODataBatchPart(w *multipart.Writer, baseURL string, item odataBatchItem) error {
+	part, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"application/http"},
+		"Content-Transfer-Encoding": {"binary"},
+		"Content-Id":                {item.ID},
+	})
+	if err != nil {
+		return fmt.Errorf("encode batch: %w", err)
+	}
+	target := item.URL
+	if !strings.HasPrefix(target, "$") && !strings.Contains(target, "://") {
+		target = strings.TrimRight(baseURL, "/") + "/" + strings.TrimLeft(target, "/")
+	}
+	var b strings.Builder
+	b.WriteString(item.Method + " " + target + " HTTP/1.1\r\n")
+	b.WriteString("Accept: application/json\r\n")
+	headers := http.Header{}
+	for name, value := range item.Headers {
+		headers.Set(name, value)
+	}
+	var body []byte
+	if item.Body != nil {
+		if body, err = json.Marshal(item.Body); err != nil {
+			return fmt.Errorf("request %s: encode body: %w", item.ID, err)
+		}
+		if headers.Get("Content-Type") == "" {
+			headers.Set("Content-Type", "application/json")
+		}
+		headers.Set("Content-Length", strconv.Itoa(len(body)))
+	}
+	_ = headers.Write(&b)
+	b.WriteString("\r\n")
+	_, _ = io.WriteString(part, b.String())
+	_, _ = part.Write(body)
+	return nil
+}
+
+// unwrapODataBatch replaces the raw $batch response with one entry per
+// response, {"id", "status", "headers", "body"}, in the order the service
+// returned them, plus the number that failed.
+func unwrapODataBatch(result *Result) (*Result, error) {
+	var responses []map[string]any
+	mediaType, params, _ := mime.ParseMediaType(result.ContentType)
+	switch {
+	case strings.HasPrefix(mediaType, "multipart/"):
+		raw, _ := result.Body.(string)
+		var err error
+		if responses, err = readODataMultipartBatch(strings.NewReader(raw), params["boundary"]); err != nil {
+			return nil, fmt.Errorf("read batch response: %w", err)
+		}
+	default:
+		body, _ := result.Body.(map[string]any)
+		items, ok := body["responses"].([]any)
+		if !ok {
+			return result, nil
+		}
+		for _, item := range items {
+			entry, _ := item.(map[string]any)
+			if entry == nil {
+				continue
+			}
+			status, _ := entry["status"].(float64)
+			out := map[string]any{"id": entry["id"], "status": int(status)}
+			if headers, ok := entry["headers"].(map[string]any); ok && len(headers) > 0 {
+				out["headers"] = headers
+			}
+			if entry["body"] != nil {
+				out["body"] = entry["body"]
+			}
+			responses = append(responses, out)
+		}
+	}
+
+	failed := 0
+	for _, r := range responses {
+		if r["status"].(int) >= 400 {
+			failed++
+		}
+	}
+	return &Result{
+		Status:      result.Status,
+		ContentType: "application/json",
+		Body:        map[string]any{"responses": responses, "failed": failed},
+	}, nil
+}
+
+func readODataMultipartBatch(r io.Reader, boundary string) ([]map[string]any, error) {
+	if boundary == "" {
+		return nil, fmt.Errorf("multipart response without boundary")
+	}
+	var responses []map[string]any
+	reader := multipart.NewReader(r, boundary)
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return responses, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		mediaType, params, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		if mediaType == "multipart/mixed" {
+			nested, err := readODataMultipartBatch(part, params["boundary"])
+			if err != nil {
+				return nil, err
+			}
+			responses = append(responses, nested...)
+			continue
+		}
+		resp, err := http.ReadResponse(bufio.NewReader(part), nil)
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		out := map[string]any{"status": resp.StatusCode}
+		if id := part.Header.Get("Content-ID"); id != "" {
+			out["id"] = id
+		}
+		headers := map[string]any{}
+		for _, name := range []string{"Location", "OData-EntityId", "ETag"} {
+			if value := resp.Header.Get(name); value != "" {
+				headers[name] = value
+			}
+		}
+		if len(headers) > 0 {
+			out["headers"] = headers
+		}
+		if len(bytes.TrimSpace(data)) > 0 {
+			var body any
+			if json.Unmarshal(data, &body) != nil {
+				body = string(data)
+			}
+			out["body"] = body
+		}
+		responses = append(responses, out)
+	}
+}
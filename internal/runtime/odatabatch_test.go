@@ -0,0 +1,173 @@
+package runtime_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"skyline-mcp/internal/canonical"
+)
+
+func TestExecutorODataMultipartBatch(t *testing.T) {
+	type received struct {
+		contentID string
+		line      string
+		body      string
+	}
+	var got []received
+	var changesets int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/odata/$batch" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		var read func(io.Reader, string)
+		read = func(body io.Reader, contentType string) {
+			_, params, _ := mime.ParseMediaType(contentType)
+			reader := multipart.NewReader(body, params["boundary"])
+			for {
+				part, err := reader.NextPart()
+				if err != nil {
+					return
+				}
+				if ct := part.Header.Get("Content-Type"); strings.HasPrefix(ct, "multipart/mixed") {
+					changesets++
+					read(part, ct)
+					continue
+				}
+				req, err := http.ReadRequest(bufio.NewReader(part))
+				if err != nil {
+					t.Errorf("part is not an HTTP request: %v", err)
+					return
+				}
+				data, _ := io.ReadAll(req.Body)
+				got = append(got, received{part.Header.Get("Content-ID"), req.Method + " " + req.RequestURI, string(data)})
+			}
+		}
+		read(r.Body, r.Header.Get("Content-Type"))
+
+		w.Header().Set("Content-Type", "multipart/mixed; boundary=batchresponse")
+		_, _ = io.WriteString(w, "--batchresponse\r\n"+
+			"Content-Type: application/http\r\nContent-ID: 1\r\n\r\n"+
+			"HTTP/1.1 200 OK\r\nContent-Type: application/json\r\n\r\n{\"ID\":1,\"Title\":\"Alien\"}\r\n"+
+			"--batchresponse\r\n"+
+			"Content-Type: multipart/mixed; boundary=changesetresponse\r\n\r\n"+
+			"--changesetresponse\r\n"+
+			"Content-Type: application/http\r\nContent-ID: 2\r\n\r\n"+
+			"HTTP/1.1 204 No Content\r\nOData-EntityId: http://svc/Movies(1)\r\n\r\n\r\n"+
+			"--changesetresponse\r\n"+
+			"Content-Type: application/http\r\nContent-ID: 3\r\n\r\n"+
+			"HTTP/1.1 400 Bad Request\r\nContent-Type: application/json\r\n\r\n{\"error\":{\"message\":\"bad year\"}}\r\n"+
+			"--changesetresponse--\r\n"+
+			"--batchresponse--\r\n")
+	}))
+	defer server.Close()
+
+	exec := newExecutor(t, server.URL+"/odata", nil, 0)
+	op := &canonical.Operation{
+		ServiceName: "api", ToolName: "api__batch", Method: "post", Path: "/$batch",
+		RequestBody: &canonical.RequestBody{Required: true, ContentType: "multipart/mixed"},
+		ODataBatch:  &canonical.ODataBatchOperation{},
+	}
+	result, err := exec.Execute(context.Background(), op, map[string]any{"requests": []any{
+		map[string]any{"method": "get", "url": "Movies(1)"},
+		map[string]any{"method": "PATCH", "url": "Movies(1)", "body": map[string]any{"Title": "Aliens"}, "changeset": "cs"},
+		map[string]any{"method": "PATCH", "url": "Movies(2)", "body": map[string]any{"Year": -1}, "changeset": "cs"},
+	}})
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+
+	if changesets != 1 || len(got) != 3 {
+		t.Fatalf("server saw %d changesets and %d requests: %+v", changesets, len(got), got)
+	}
+	if got[0].contentID != "1" || got[0].line != "GET "+server.URL+"/odata/Movies(1)" {
+		t.Fatalf("first request = %+v", got[0])
+	}
+	if got[1].contentID != "2" || got[1].line != "PATCH "+server.URL+"/odata/Movies(1)" || got[1].body != `{"Title":"Aliens"}` {
+		t.Fatalf("second request = %+v", got[1])
+	}
+
+	body := result.Body.(map[string]any)
+	responses := body["responses"].([]map[string]any)
+	if len(responses) != 3 || body["failed"] != 1 {
+		t.Fatalf("unexpected batch result: %v", body)
+	}
+	if responses[0]["id"] != "1" || responses[0]["status"] != 200 || responses[0]["body"].(map[string]any)["Title"] != "Alien" {
+		t.Fatalf("first response = %v", responses[0])
+	}
+	if responses[1]["status"] != 204 || responses[1]["headers"].(map[string]any)["OData-EntityId"] != "http://svc/Movies(1)" {
+		t.Fatalf("second response = %v", responses[1])
+	}
+	if responses[2]["id"] != "3" || responses[2]["status"] != 400 {
+		t.Fatalf("third response = %v", responses[2])
+	}
+}
+
+func TestExecutorODataJSONBatch(t *testing.T) {
+	var prefer string
+	var sent map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prefer = r.Header.Get("Prefer")
+		_ = json.NewDecoder(r.Body).Decode(&sent)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"responses":[
+			{"id":"a","status":201,"headers":{"location":"http://svc/Movies(7)"},"body":{"ID":7}},
+			{"id":"2","status":204}]}`)
+	}))
+	defer server.Close()
+
+	exec := newExecutor(t, server.URL, nil, 0)
+	op := &canonical.Operation{
+		ServiceName: "api", ToolName: "api__batch", Method: "post", Path: "/$batch",
+		RequestBody: &canonical.RequestBody{Required: true, ContentType: "application/json"},
+		ODataBatch:  &canonical.ODataBatchOperation{JSON: true},
+	}
+	result, err := exec.Execute(context.Background(), op, map[string]any{
+		"continueOnError": true,
+		"requests": []any{
+			map[string]any{"id": "a", "method": "POST", "url": "Movies", "body": map[string]any{"Title": "Heat"}, "changeset": "g"},
+			map[string]any{"method": "DELETE", "url": "$a", "changeset": "g"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if prefer != "odata.continue-on-error" {
+		t.Fatalf("Prefer = %q", prefer)
+	}
+	requests := sent["requests"].([]any)
+	first, second := requests[0].(map[string]any), requests[1].(map[string]any)
+	if first["atomicityGroup"] != "g" || second["atomicityGroup"] != "g" || second["id"] != "2" || second["url"] != "$a" {
+		t.Fatalf("unexpected JSON batch: %v", sent)
+	}
+	if first["headers"].(map[string]any)["content-type"] != "application/json" {
+		t.Fatalf("missing content type: %v", first)
+	}
+	body := result.Body.(map[string]any)
+	responses := body["responses"].([]map[string]any)
+	if len(responses) != 2 || body["failed"] != 0 || responses[0]["status"] != 201 || responses[1]["id"] != "2" {
+		t.Fatalf("unexpected batch result: %v", body)
+	}
+}
+
+func TestExecutorODataBatchRejectsGETInChangeset(t *testing.T) {
+	exec := newExecutor(t, "http://127.0.0.1:1", nil, 0)
+	op := &canonical.Operation{
+		ServiceName: "api", ToolName: "api__batch", Method: "post", Path: "/$batch",
+		RequestBody: &canonical.RequestBody{Required: true, ContentType: "multipart/mixed"},
+		ODataBatch:  &canonical.ODataBatchOperation{},
+	}
+	_, err := exec.Execute(context.Background(), op, map[string]any{"requests": []any{
+		map[string]any{"method": "GET", "url": "Movies", "changeset": "cs"},
+	}})
+	if err == nil || !strings.Contains(err.Error(), "cannot be part of a changeset") {
+		t.Fatalf("err = %v", err)
+	}
+}
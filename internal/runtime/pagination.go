@@ -0,0 +1,199 @@
+package runtime
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"skyline-mcp/internal/canonical"
+)
+
+// Pagination is an upstream's next-page metadata in one shape, so agents
+// can page through results without knowing each API's convention.
+type Pagination struct {
+	// Source names the convention found: link_header, next_page_token,
+	// odata_next_link or next_cursor.
+	Source string `json:"source"`
+	// Next, Prev, First and Last are absolute page URLs, from a Link header
+	// or @odata.nextLink.
+	Next  string `json:"next,omitempty"`
+	Prev  string `json:"prev,omitempty"`
+	First string `json:"first,omitempty"`
+	Last  string `json:"last,omitempty"`
+	// NextPageToken is the token or cursor to send back for the next page.
+	NextPageToken string `json:"next_page_token,omitempty"`
+	// NextArguments are this call's arguments updated for the next page,
+	// when the next page maps onto the tool's own parameters.
+	NextArguments map[string]any `json:"next_arguments,omitempty"`
+}
+
+// pageTokenParams are the query parameter names APIs use to accept the
+// token or cursor of the next page.
+var pageTokenParams = []string{"pageToken", "page_token", "nextPageToken", "next_page_token", "cursor", "after", "$skiptoken"}
+
+// parsePagination reads pagination metadata from a successful response's
+// Link header and from well-known body fields. It returns nil when the
+// response has no next page information.
+func parsePagination(resp *http.Response, body any) *Pagination {
+	p := &Pagination{}
+	var base *url.URL
+	if resp.Request != nil {
+		base = resp.Request.URL
+	}
+	if links := parseLinkHeader(resp.Header.Values("Link"), base); len(links) > 0 {
+		p.Source = "link_header"
+		p.Next, p.Prev, p.First, p.Last = links["next"], links["prev"], links["first"], links["last"]
+		if p.Prev == "" {
+			p.Prev = links["previous"]
+		}
+	}
+	if m, ok := body.(map[string]any); ok {
+		if token := firstString(m, "nextPageToken", "next_page_token"); token != "" {
+			p.NextPageToken = token
+			if p.Source == "" {
+				p.Source = "next_page_token"
+			}
+		}
+		if next, _ := m["@odata.nextLink"].(string); next != "" && p.Next == "" {
+			p.Next = resolveReference(base, next)
+			if p.Source == "" {
+				p.Source = "odata_next_link"
+			}
+		}
+		// Slack and similar cursor APIs nest the cursor in response metadata.
+		if meta, ok := m["response_metadata"].(map[string]any); ok && p.NextPageToken == "" {
+			if cursor, _ := meta["next_cursor"].(string); cursor != "" {
+				p.NextPageToken = cursor
+				if p.Source == "" {
+					p.Source = "next_cursor"
+				}
+			}
+		}
+	}
+	if p.Source == "" {
+		return nil
+	}
+	return p
+}
+
+// parseLinkHeader parses RFC 8288 Link header values into a map of
+// relation to absolute URL.
+func parseLinkHeader(values []string, base *url.URL) map[string]string {
+	links := map[string]string{}
+	for _, value := range values {
+		for value != "" {
+			start := strings.IndexByte(value, '<')
+			end := strings.IndexByte(value, '>')
+			if start < 0 || end < start {
+				break
+			}
+			target := value[start+1 : end]
+			value = value[end+1:]
+			params := value
+			if next := strings.IndexByte(value, '<'); next >= 0 {
+				params, value = value[:next], value[next:]
+			} else {
+				value = ""
+			}
+			for _, param := range strings.Split(params, ";") {
+				name, val, ok := strings.Cut(strings.TrimSpace(param), "=")
+				if !ok || !strings.EqualFold(strings.TrimSpace(name), "rel") {
+					continue
+				}
+				val = strings.Trim(strings.TrimSpace(strings.TrimRight(strings.TrimSpace(val), ",")), `"`)
+				for _, rel := range strings.Fields(strings.ToLower(val)) {
+					if _, seen := links[rel]; !seen {
+						links[rel] = resolveReference(base, target)
+					}
+				}
+			}
+		}
+	}
+	return links
+}
+
+func resolveReference(base *url.URL, ref string) string {
+	if base == nil {
+		return ref
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return base.ResolveReference(u).String()
+}
+
+func firstString(m map[string]any, keys ...string) string {
+	for _, key := range keys {
+		if s, _ := m[key].(string); s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// nextArguments maps the next page onto op's parameters: query parameters
+// of the next URL that the tool accepts, or the page token for the tool's
+// token parameter. It returns nil when the next page cannot be expressed
+// as tool arguments.
+func nextArguments(op *canonical.Operation, args map[string]any, p *Pagination) map[string]any {
+	query := map[string]canonical.Parameter{}
+	for _, param := range op.Parameters {
+		if param.In == "query" {
+			query[param.Name] = param
+		}
+	}
+	if len(query) == 0 {
+		return nil
+	}
+	updates := map[string]any{}
+	if p.Next != "" {
+		if u, err := url.Parse(p.Next); err == nil {
+			for name, values := range u.Query() {
+				if param, ok := query[name]; ok && len(values) > 0 {
+					updates[name] = typedValue(param.Schema, values[0])
+				}
+			}
+		}
+	}
+	if p.NextPageToken != "" {
+		for _, name := range pageTokenParams {
+			if _, ok := query[name]; ok {
+				updates[name] = p.NextPageToken
+				break
+			}
+		}
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	next := make(map[string]any, len(args)+len(updates))
+	for k, v := range args {
+		next[k] = v
+	}
+	for k, v := range updates {
+		next[k] = v
+	}
+	return next
+}
+
+// typedValue converts a query string value to the parameter's schema type,
+// so a page number read from a URL passes the tool's validation.
+func typedValue(schema map[string]any, value string) any {
+	switch schema["type"] {
+	case "integer":
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n
+		}
+	case "number":
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
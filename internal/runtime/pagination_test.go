@@ -0,0 +1,103 @@
+package runtime
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"skyline-mcp/internal/canonical"
+)
+
+func TestParseLinkHeader(t *testing.T) {
+	base, _ := url.Parse("https://api.github.com/repos/acme/api/issues?page=2")
+	links := parseLinkHeader([]string{
+		`<https://api.github.com/repositories/1/issues?page=3>; rel="next", <https://api.github.com/repositories/1/issues?page=9>; rel="last"`,
+		`</repositories/1/issues?page=1>; rel="first prev"`,
+	}, base)
+	want := map[string]string{
+		"next":  "https://api.github.com/repositories/1/issues?page=3",
+		"last":  "https://api.github.com/repositories/1/issues?page=9",
+		"first": "https://api.github.com/repositories/1/issues?page=1",
+		"prev":  "https://api.github.com/repositories/1/issues?page=1",
+	}
+	if !reflect.DeepEqual(links, want) {
+		t.Fatalf("links = %v, want %v", links, want)
+	}
+}
+
+func TestParsePagination(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "https://graph.example.com/v1.0/users", nil)
+	tests := []struct {
+		name   string
+		header http.Header
+		body   any
+		want   *Pagination
+	}{
+		{
+			name: "none",
+			body: map[string]any{"items": []any{}},
+		},
+		{
+			name: "page token",
+			body: map[string]any{"items": []any{}, "nextPageToken": "CAIQAA"},
+			want: &Pagination{Source: "next_page_token", NextPageToken: "CAIQAA"},
+		},
+		{
+			name: "odata next link",
+			body: map[string]any{"value": []any{}, "@odata.nextLink": "users?$skiptoken=X1"},
+			want: &Pagination{Source: "odata_next_link", Next: "https://graph.example.com/v1.0/users?$skiptoken=X1"},
+		},
+		{
+			name: "slack cursor",
+			body: map[string]any{"ok": true, "response_metadata": map[string]any{"next_cursor": "dXNlcjpVMEc5V0ZYTlo="}},
+			want: &Pagination{Source: "next_cursor", NextPageToken: "dXNlcjpVMEc5V0ZYTlo="},
+		},
+		{
+			name:   "link header wins",
+			header: http.Header{"Link": {`<https://graph.example.com/v1.0/users?page=2>; rel="next"`}},
+			body:   []any{},
+			want:   &Pagination{Source: "link_header", Next: "https://graph.example.com/v1.0/users?page=2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := tt.header
+			if header == nil {
+				header = http.Header{}
+			}
+			got := parsePagination(&http.Response{Header: header, Request: req}, tt.body)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("pagination = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextArguments(t *testing.T) {
+	op := &canonical.Operation{Parameters: []canonical.Parameter{
+		{Name: "owner", In: "path"},
+		{Name: "page", In: "query", Schema: map[string]any{"type": "integer"}},
+		{Name: "per_page", In: "query", Schema: map[string]any{"type": "integer"}},
+		{Name: "pageToken", In: "query", Schema: map[string]any{"type": "string"}},
+	}}
+	args := map[string]any{"owner": "acme", "per_page": float64(50)}
+
+	got := nextArguments(op, args, &Pagination{Next: "https://api.example.com/orgs/acme/repos?page=2&per_page=50&access_token=x"})
+	want := map[string]any{"owner": "acme", "page": int64(2), "per_page": int64(50)}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("next arguments = %v, want %v", got, want)
+	}
+
+	got = nextArguments(op, args, &Pagination{NextPageToken: "CAIQAA"})
+	if got["pageToken"] != "CAIQAA" || got["owner"] != "acme" {
+		t.Fatalf("next arguments = %v", got)
+	}
+	if _, ok := args["pageToken"]; ok {
+		t.Fatal("the call's own arguments were modified")
+	}
+
+	if got := nextArguments(&canonical.Operation{}, args, &Pagination{NextPageToken: "t"}); got != nil {
+		t.Fatalf("expected no arguments for a tool without query parameters, got %v", got)
+	}
+}
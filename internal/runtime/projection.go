@@ -0,0 +1,99 @@
+package runtime
+
+import (
+	"fmt"
+	"strings"
+
+	"skyline-mcp/internal/canonical"
+)
+
+// projection is a tree of selected fields; a nil subtree keeps the whole
+// value.
+type projection map[string]projection
+
+// takeProjectionArgument removes canonical.ProjectionArgument from args and
+// returns the fields it selects, or nil when the caller selected none.
+func takeProjectionArgument(args map[string]any) (projection, error) {
+	raw, ok := args[canonical.ProjectionArgument]
+	if !ok {
+		return nil, nil
+	}
+	delete(args, canonical.ProjectionArgument)
+	list, ok := raw.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%s must be a list of field paths", canonical.ProjectionArgument)
+	}
+	var tree projection
+	for _, item := range list {
+		path, ok := item.(string)
+		if !ok || strings.TrimSpace(path) == "" {
+			return nil, fmt.Errorf("%s must be a list of field paths", canonical.ProjectionArgument)
+		}
+		if tree == nil {
+			tree = projection{}
+		}
+		tree.add(strings.Split(strings.TrimSpace(path), "."))
+	}
+	return tree, nil
+}
+
+func (p projection) add(segments []string) {
+	sub, seen := p[segments[0]]
+	if len(segments) == 1 {
+		// Selecting a field keeps all of it, whatever else was selected
+		// below it.
+		p[segments[0]] = nil
+		return
+	}
+	if seen && sub == nil {
+		return
+	}
+	if sub == nil {
+		sub = projection{}
+		p[segments[0]] = sub
+	}
+	sub.add(segments[1:])
+}
+
+// apply returns the selected fields of v. Lists are projected item by item.
+// An object with none of the selected fields is taken for an envelope such
+// as {"items": [...], "total": 3}: its lists are projected and its other
+// scalar fields kept.
+func (p projection) apply(v any) any {
+	switch v := v.(type) {
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = p.apply(item)
+		}
+		return out
+	case map[string]any:
+		out := map[string]any{}
+		for key, sub := range p {
+			value, ok := v[key]
+			if !ok {
+				continue
+			}
+			if sub == nil {
+				out[key] = value
+			} else {
+				out[key] = sub.apply(value)
+			}
+		}
+		if len(out) > 0 {
+			return out
+		}
+		for key, value := range v {
+			switch value.(type) {
+			case []any:
+				out[key] = p.apply(value)
+			case map[string]any:
+			default:
+				out[key] = value
+			}
+		}
+		return out
+	default:
+		return v
+	}
+}
@@ -0,0 +1,47 @@
+package runtime
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestProjection(t *testing.T) {
+	var body any
+	_ = json.Unmarshal([]byte(`{
+		"total": 2,
+		"next": "abc",
+		"meta": {"took": 3},
+		"items": [
+			{"id": 1, "name": "skyline", "owner": {"login": "ada", "id": 9}, "stars": 5},
+			{"id": 2, "name": "mcp", "owner": {"login": "bob", "id": 8}, "stars": 1}
+		]
+	}`), &body)
+	args := map[string]any{"_fields": []any{"id", "owner.login"}, "q": "x"}
+	fields, err := takeProjectionArgument(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := args["_fields"]; ok || args["q"] != "x" {
+		t.Fatalf("args = %v, want only _fields removed", args)
+	}
+
+	got, _ := json.Marshal(fields.apply(body))
+	want := `{"items":[{"id":1,"owner":{"login":"ada"}},{"id":2,"owner":{"login":"bob"}}],"next":"abc","total":2}`
+	if string(got) != want {
+		t.Fatalf("projected = %s\nwant %s", got, want)
+	}
+
+	// Selecting a whole field wins over selecting inside it.
+	fields, _ = takeProjectionArgument(map[string]any{"_fields": []any{"owner.login", "owner"}})
+	if !reflect.DeepEqual(fields, projection{"owner": nil}) {
+		t.Fatalf("fields = %#v", fields)
+	}
+
+	if _, err := takeProjectionArgument(map[string]any{"_fields": "id,name"}); err == nil {
+		t.Fatal("string accepted for _fields")
+	}
+	if fields, err := takeProjectionArgument(map[string]any{}); fields != nil || err != nil {
+		t.Fatalf("no argument: %v, %v", fields, err)
+	}
+}
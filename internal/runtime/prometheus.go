@@ -0,0 +1,185 @@
+package runtime
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+)
+
+// defaultRangePoints is how many points a range query returns when the
+// call sets no step.
+const defaultRangePoints = 250
+
+var promDurationRE = regexp.MustCompile(`^(?:(\d+)y)?(?:(\d+)w)?(?:(\d+)d)?(?:(\d+)h)?(?:(\d+)m)?(?:(\d+)s)?(?:(\d+)ms)?$`)
+
+var promDurationUnits = []time.Duration{365 * 24 * time.Hour, 7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second, time.Millisecond}
+
+// parsePromDuration parses a Prometheus duration such as 5m, 1h30m or 2d.
+func parsePromDuration(s string) (time.Duration, error) {
+	m := promDurationRE.FindStringSubmatch(s)
+	if s == "" || m == nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	var d time.Duration
+	for i, unit := range promDurationUnits {
+		if m[i+1] == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(m[i+1], 10, 64)
+		if err != nil || n > int64(math.MaxInt64/unit) {
+			return 0, fmt.Errorf("duration %q is too long", s)
+		}
+		d += time.Duration(n) * unit
+	}
+	return d, nil
+}
+
+// parsePromTime parses a time argument: RFC 3339, Unix seconds, now or
+// now-<duration>.
+func parsePromTime(value any, now time.Time) (time.Time, error) {
+	switch v := value.(type) {
+	case float64:
+		return unixSeconds(v), nil
+	case string:
+		s := strings.TrimSpace(v)
+		if rest, ok := strings.CutPrefix(s, "now"); ok {
+			if rest == "" {
+				return now, nil
+			}
+			sign := rest[0]
+			d, err := parsePromDuration(rest[1:])
+			if (sign != '-' && sign != '+') || err != nil {
+				return time.Time{}, fmt.Errorf("relative time must look like now-1h, got %q", v)
+			}
+			if sign == '-' {
+				d = -d
+			}
+			return now.Add(d), nil
+		}
+		if n, ok := parseFinite(s); ok {
+			return unixSeconds(n), nil
+		}
+		if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("must be RFC 3339, Unix seconds, now or now-<duration>, got %v", value)
+}
+
+// parseFinite parses a number, refusing NaN and infinities.
+func parseFinite(s string) (float64, bool) {
+	n, err := strconv.ParseFloat(s, 64)
+	return n, err == nil && !math.IsNaN(n) && !math.IsInf(n, 0)
+}
+
+func unixSeconds(s float64) time.Time {
+	return time.UnixMilli(int64(math.Round(s * 1000)))
+}
+
+// formatPromTime formats a time as the Unix seconds the API takes.
+func formatPromTime(t time.Time) string {
+	return strconv.FormatFloat(float64(t.UnixMilli())/1000, 'f', -1, 64)
+}
+
+// parsePromStep parses a range query step: a duration or seconds.
+func parsePromStep(value any) (time.Duration, error) {
+	var d time.Duration
+	switch v := value.(type) {
+	case float64:
+		d = time.Duration(v * float64(time.Second))
+	case string:
+		s := strings.TrimSpace(v)
+		var err error
+		if n, ok := parseFinite(s); ok {
+			d = time.Duration(n * float64(time.Second))
+		} else if d, err = parsePromDuration(s); err != nil {
+			return 0, fmt.Errorf("must be a duration such as 30s or 5m, or seconds, got %q", v)
+		}
+	default:
+		return 0, fmt.Errorf("must be a duration such as 30s or 5m, or seconds")
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("must be positive")
+	}
+	return d, nil
+}
+
+// preparePrometheusArgs resolves the time arguments of a Prometheus tool
+// to Unix seconds, checks the step of range queries against the points
+// limit and lints the query when the API asks for it.
+func preparePrometheusArgs(op *canonical.PrometheusOperation, cfg *config.PrometheusConfig, args map[string]any, now time.Time) (map[string]any, error) {
+	out := maps.Clone(args)
+	if out == nil {
+		out = map[string]any{}
+	}
+	times := make(map[string]time.Time, len(op.Times))
+	for _, name := range op.Times {
+		value, ok := out[name]
+		if !ok || value == nil {
+			continue
+		}
+		t, err := parsePromTime(value, now)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		times[name] = t
+		out[name] = formatPromTime(t)
+	}
+	start, hasStart := times["start"]
+	end, hasEnd := times["end"]
+	if hasStart && hasEnd && end.Before(start) {
+		return nil, fmt.Errorf("end %s is before start %s", end.UTC().Format(time.RFC3339), start.UTC().Format(time.RFC3339))
+	}
+
+	if op.Range {
+		if !hasStart {
+			return nil, fmt.Errorf("start is required")
+		}
+		if !hasEnd {
+			end = now
+			if end.Before(start) {
+				return nil, fmt.Errorf("start %s is in the future", start.UTC().Format(time.RFC3339))
+			}
+			out["end"] = formatPromTime(end)
+		}
+		span := end.Sub(start)
+		var step time.Duration
+		if value, ok := out["step"]; ok && value != nil {
+			var err error
+			if step, err = parsePromStep(value); err != nil {
+				return nil, fmt.Errorf("step: %w", err)
+			}
+		} else {
+			step = max(time.Second, (span / defaultRangePoints).Round(time.Second))
+		}
+		maxPoints := config.DefaultPrometheusMaxPoints
+		if cfg != nil && cfg.MaxPoints > 0 {
+			maxPoints = cfg.MaxPoints
+		}
+		if points := int64(span / step); points > int64(maxPoints) {
+			minStep := span / time.Duration(maxPoints)
+			if minStep%time.Second != 0 {
+				minStep = minStep.Truncate(time.Second) + time.Second
+			}
+			return nil, fmt.Errorf("a step of %s gives %d points per series, over the limit of %d; use a step of at least %s or a shorter range",
+				step, points, maxPoints, minStep)
+		}
+		out["step"] = strconv.FormatFloat(step.Seconds(), 'f', -1, 64)
+	}
+
+	if cfg != nil && cfg.Lint && op.Query != "" {
+		if query, _ := out[op.Query].(string); query != "" {
+			if err := lintPromQL(query); err != nil {
+				return nil, fmt.Errorf("%s: invalid PromQL: %w", op.Query, err)
+			}
+		}
+	}
+	return out, nil
+}
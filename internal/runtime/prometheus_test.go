@@ -0,0 +1,206 @@
+package runtime
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/logging"
+	"skyline-mcp/internal/redact"
+)
+
+func TestParsePromTime(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value any
+		want  time.Time
+	}{
+		{"now", now},
+		{"now-1h30m", now.Add(-90 * time.Minute)},
+		{"now-2d", now.Add(-48 * time.Hour)},
+		{"now+5m", now.Add(5 * time.Minute)},
+		{"2024-05-01T10:00:00Z", now.Add(-2 * time.Hour)},
+		{"2024-05-01T14:00:00+02:00", now},
+		{"1714564800.5", now.Add(500 * time.Millisecond)},
+		{float64(1714564800), now},
+	}
+	for _, tt := range tests {
+		got, err := parsePromTime(tt.value, now)
+		if err != nil {
+			t.Errorf("%v: %v", tt.value, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("%v = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+	for _, value := range []any{"yesterday", "now-1x", "now-", "NaN", "2024-05-01", true} {
+		if _, err := parsePromTime(value, now); err == nil {
+			t.Errorf("%v: expected an error", value)
+		}
+	}
+}
+
+func TestPreparePrometheusArgs(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	queryRange := &canonical.PrometheusOperation{Query: "query", Times: []string{"start", "end"}, Range: true}
+
+	out, err := preparePrometheusArgs(queryRange, nil, map[string]any{"query": "up", "start": "now-1h"}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out["start"] != "1714561200" || out["end"] != "1714564800" || out["step"] != "14" {
+		t.Errorf("defaults = %v", out)
+	}
+	out, err = preparePrometheusArgs(queryRange, nil, map[string]any{"query": "up", "start": "now-1d", "end": "now-1h", "step": "5m"}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out["step"] != "300" {
+		t.Errorf("step = %v", out["step"])
+	}
+
+	tests := []struct {
+		name string
+		cfg  *config.PrometheusConfig
+		args map[string]any
+		want string
+	}{
+		{"too many points", nil, map[string]any{"start": "now-7d", "step": "15s"}, "a step of 15s gives 40320 points per series, over the limit of 11000; use a step of at least 55s"},
+		{"points under a raised limit", &config.PrometheusConfig{MaxPoints: 50000}, map[string]any{"start": "now-7d", "step": "15s"}, ""},
+		{"end before start", nil, map[string]any{"start": "now-1h", "end": "now-2h"}, "is before start"},
+		{"start in the future", nil, map[string]any{"start": "now+1h"}, "is in the future"},
+		{"missing start", nil, map[string]any{"end": "now"}, "start is required"},
+		{"zero step", nil, map[string]any{"start": "now-1h", "step": "0s"}, "step: must be positive"},
+		{"bad step", nil, map[string]any{"start": "now-1h", "step": "five minutes"}, "step: must be a duration"},
+		{"bad time", nil, map[string]any{"start": "last tuesday"}, "start: must be RFC 3339"},
+		{"lint", &config.PrometheusConfig{Lint: true}, map[string]any{"start": "now-1h", "query": "rate(http_requests_total)"}, "query: invalid PromQL: rate needs a range vector"},
+		{"lint off", nil, map[string]any{"start": "now-1h", "query": "rate(http_requests_total)"}, ""},
+	}
+	for _, tt := range tests {
+		_, err := preparePrometheusArgs(queryRange, tt.cfg, tt.args, now)
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestLintPromQL(t *testing.T) {
+	valid := []string{
+		`up`,
+		`sum by (job) (rate(http_requests_total{job="api", code=~"5.."}[5m])) / sum by (job) (rate(http_requests_total[5m]))`,
+		`histogram_quantile(0.99, sum by (le) (rate(http_request_duration_seconds_bucket[5m])))`,
+		`max_over_time(deriv(node_memory_MemAvailable_bytes[1h])[1d:5m])`,
+		`rate(sum(x)[5m:])`,
+		`job:http_requests:rate5m offset 1h`,
+		`http_requests_total @ end()`,
+		`quantile_over_time(0.9, latency_seconds{path!~"/health|/metrics"}[10m])`,
+		`{__name__=~"node_.*", instance="a:9100",}`,
+		`{"http.server.duration", "service.name"="api"}`,
+		`count(up == 1) > bool 0`,
+		`up{job='api'} * on (instance) group_left (version) build_info`,
+		`label_replace(up, "host", "$1", "instance", "(.*):\\d+")`,
+		`1e-3 * -5 + .5`,
+	}
+	for _, q := range valid {
+		if err := lintPromQL(q); err != nil {
+			t.Errorf("%s: %v", q, err)
+		}
+	}
+
+	invalid := []struct {
+		query, want string
+	}{
+		{``, "empty"},
+		{`rate(http_requests_total)`, "rate needs a range vector argument"},
+		{`rate(sum(http_requests_total[5m]))`, "rate needs a range vector argument"},
+		{`rates(http_requests_total[5m])`, `unknown function "rates"`},
+		{`up{job=api}`, `the value of label job must be quoted, e.g. job="api"`},
+		{`up{job}`, "label job needs a matcher"},
+		{`up{job="a" instance="b"}`, "expected , or }"},
+		{`up{job=~"(api"}`, "invalid regex for label job"},
+		{`up{job="api"`, "unclosed {"},
+		{`sum(rate(x[5m])`, "unclosed ("},
+		{`rate(x[5m]))`, "unexpected )"},
+		{`rate(x[5 minutes])`, "a range must be a duration"},
+		{`rate(x[5q])`, "5q at offset 7 is not a duration"},
+		{`rate(x[])`, "empty range"},
+		{`[5m]`, "must follow a selector"},
+		{`up{job="api}`, "unterminated string"},
+		{`up +`, "ends with the operator +"},
+		{`up ! down`, `unexpected '!'`},
+	}
+	for _, tt := range invalid {
+		err := lintPromQL(tt.query)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.query, err, tt.want)
+		}
+	}
+}
+
+func TestExecutePrometheusQueryRange(t *testing.T) {
+	var got url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/query_range" {
+			http.NotFound(w, r)
+			return
+		}
+		got = r.URL.Query()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[]}}`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{APIs: []config.APIConfig{{
+		Name: "metrics", SpecType: "prometheus", BaseURLOverride: server.URL,
+		Prometheus: &config.PrometheusConfig{Lint: true},
+	}}}
+	cfg.ApplyDefaults()
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	op := &canonical.Operation{
+		ServiceName: "metrics", ToolName: "metrics__queryRange", Method: "get", Path: "/api/v1/query_range",
+		Parameters: []canonical.Parameter{
+			{Name: "query", In: "query", Required: true}, {Name: "start", In: "query", Required: true},
+			{Name: "end", In: "query"}, {Name: "step", In: "query"},
+		},
+		Prometheus: &canonical.PrometheusOperation{Query: "query", Times: []string{"start", "end"}, Range: true},
+	}
+	exec, err := NewExecutor(cfg, []*canonical.Service{{Name: "metrics", BaseURL: server.URL, Operations: []*canonical.Operation{op}}}, logging.Discard(), redact.NewRedactor())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	args := map[string]any{"query": `sum(rate(http_requests_total[5m]))`, "start": "2024-05-01T00:00:00Z", "end": "2024-05-01T06:00:00Z", "step": "1m"}
+	if _, err := exec.Execute(context.Background(), op, args); err != nil {
+		t.Fatal(err)
+	}
+	if got.Get("start") != "1714521600" || got.Get("end") != "1714543200" || got.Get("step") != "60" || got.Get("query") != args["query"] {
+		t.Errorf("query = %v", got)
+	}
+	if args["start"] != "2024-05-01T00:00:00Z" {
+		t.Error("the caller's arguments were modified")
+	}
+
+	got = nil
+	_, err = exec.Execute(context.Background(), op, map[string]any{"query": `rate(http_requests_total)`, "start": "now-1h"})
+	if err == nil || !strings.Contains(err.Error(), "rate needs a range vector") {
+		t.Errorf("lint err = %v", err)
+	}
+	if got != nil {
+		t.Error("a query that failed lint was sent")
+	}
+}
@@ -0,0 +1,298 @@
+package runtime
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// promFunctions are the PromQL functions, aggregations and keywords that
+// may be followed by a parenthesis.
+var promFunctions = map[string]bool{}
+
+// promRangeFunctions take a range vector, e.g. rate(metric[5m]).
+var promRangeFunctions = map[string]bool{}
+
+func init() {
+	for _, name := range strings.Fields(`abs absent acos acosh asin asinh atan atanh ceil clamp clamp_max clamp_min cos cosh
+		day_of_month day_of_week day_of_year days_in_month deg end exp floor histogram_avg histogram_count
+		histogram_fraction histogram_quantile histogram_stddev histogram_stdvar histogram_sum hour info label_join
+		label_replace ln log10 log2 minute month pi rad round scalar sgn sin sinh sort sort_by_label
+		sort_by_label_desc sort_desc sqrt start tan tanh time timestamp vector year
+		sum avg count min max stddev stdvar topk bottomk quantile count_values group limitk limit_ratio
+		by without on ignoring group_left group_right and or unless bool atan2`) {
+		promFunctions[name] = true
+	}
+	for _, name := range strings.Fields(`absent_over_time avg_over_time changes count_over_time delta deriv
+		double_exponential_smoothing holt_winters idelta increase irate last_over_time mad_over_time
+		max_over_time min_over_time predict_linear present_over_time quantile_over_time rate resets
+		stddev_over_time stdvar_over_time sum_over_time`) {
+		promFunctions[name] = true
+		promRangeFunctions[name] = true
+	}
+}
+
+// promToken is a PromQL token. kind is 'i' for identifiers, 'n' for
+// numbers and durations, 's' for strings, 'o' for operators and the
+// character itself for brackets, commas, colons and @.
+type promToken struct {
+	kind byte
+	text string
+	pos  int
+}
+
+var promOperators = []string{"==", "!=", "<=", ">=", "=~", "!~", "=", "<", ">", "+", "-", "*", "/", "%", "^"}
+
+func lexPromQL(q string) ([]promToken, error) {
+	var tokens []promToken
+	for i := 0; i < len(q); {
+		c := q[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '#':
+			for i < len(q) && q[i] != '\n' {
+				i++
+			}
+		case isPromIdentStart(c) && (c != ':' || i+1 < len(q) && isPromIdentStart(q[i+1]) && q[i+1] != ':'):
+			// A colon starts a name like :rate5m, or separates the range
+			// and resolution of a subquery.
+			start := i
+			for i < len(q) && (isPromIdentStart(q[i]) || q[i] >= '0' && q[i] <= '9') {
+				i++
+			}
+			tokens = append(tokens, promToken{'i', q[start:i], start})
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(q) && q[i+1] >= '0' && q[i+1] <= '9':
+			start := i
+			for i < len(q) && (q[i] != ':' && isPromIdentStart(q[i]) || q[i] >= '0' && q[i] <= '9' || q[i] == '.' ||
+				(q[i] == '+' || q[i] == '-') && (q[i-1] == 'e' || q[i-1] == 'E')) {
+				i++
+			}
+			tokens = append(tokens, promToken{'n', q[start:i], start})
+		case c == '"' || c == '\'' || c == '`':
+			start := i
+			i++
+			for i < len(q) && q[i] != c {
+				if q[i] == '\\' && c != '`' {
+					i++
+				}
+				i++
+			}
+			if i >= len(q) {
+				return nil, fmt.Errorf("unterminated string at offset %d", start)
+			}
+			i++
+			tokens = append(tokens, promToken{'s', q[start:i], start})
+		case strings.IndexByte("(){}[],:@", c) >= 0:
+			tokens = append(tokens, promToken{c, string(c), i})
+			i++
+		default:
+			op := ""
+			for _, candidate := range promOperators {
+				if strings.HasPrefix(q[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected %q at offset %d", c, i)
+			}
+			tokens = append(tokens, promToken{'o', op, i})
+			i += len(op)
+		}
+	}
+	return tokens, nil
+}
+
+func isPromIdentStart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c == ':'
+}
+
+// lintPromQL checks the mistakes PromQL servers reject with terse parse
+// errors: unbalanced brackets, unquoted label values, invalid regexes and
+// durations, unknown functions and range functions without a range. It is
+// not a full parser; valid queries always pass.
+func lintPromQL(q string) error {
+	tokens, err := lexPromQL(q)
+	if err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		return fmt.Errorf("the query is empty")
+	}
+	var open []promToken
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		switch tok.kind {
+		case '(':
+			open = append(open, tok)
+		case ')':
+			if len(open) == 0 {
+				return fmt.Errorf("unexpected ) at offset %d", tok.pos)
+			}
+			open = open[:len(open)-1]
+		case '{':
+			end, err := lintPromMatchers(tokens, i)
+			if err != nil {
+				return err
+			}
+			i = end
+		case '[':
+			end, err := lintPromRange(tokens, i)
+			if err != nil {
+				return err
+			}
+			i = end
+		case '}', ']':
+			return fmt.Errorf("unexpected %s at offset %d", tok.text, tok.pos)
+		case 'i':
+			if i+1 >= len(tokens) || tokens[i+1].kind != '(' {
+				continue
+			}
+			name := tok.text
+			if !promFunctions[name] {
+				return fmt.Errorf("unknown function %q at offset %d", name, tok.pos)
+			}
+			if promRangeFunctions[name] && !promCallHasRange(tokens, i+1) {
+				return fmt.Errorf("%s needs a range vector argument, e.g. %s(http_requests_total[5m])", name, name)
+			}
+		}
+	}
+	if len(open) > 0 {
+		return fmt.Errorf("unclosed ( at offset %d", open[len(open)-1].pos)
+	}
+	if last := tokens[len(tokens)-1]; last.kind == 'o' {
+		return fmt.Errorf("the query ends with the operator %s", last.text)
+	}
+	return nil
+}
+
+// lintPromMatchers checks the label matchers of the selector opened at
+// tokens[i] and returns the index of its closing brace.
+func lintPromMatchers(tokens []promToken, i int) (int, error) {
+	brace := tokens[i]
+	for i++; i < len(tokens); i++ {
+		if tokens[i].kind == '}' {
+			return i, nil
+		}
+		label := tokens[i]
+		if label.kind != 'i' && label.kind != 's' {
+			return 0, fmt.Errorf("expected a label name at offset %d, got %s", label.pos, label.text)
+		}
+		i++
+		if i < len(tokens) && label.kind == 's' && (tokens[i].kind == ',' || tokens[i].kind == '}') {
+			// A quoted metric name, as in {"http.requests"}.
+			if tokens[i].kind == '}' {
+				return i, nil
+			}
+			continue
+		}
+		if i >= len(tokens) {
+			break
+		}
+		op := tokens[i]
+		if op.kind != 'o' || (op.text != "=" && op.text != "!=" && op.text != "=~" && op.text != "!~") {
+			return 0, fmt.Errorf("label %s needs a matcher such as %s=\"value\"", label.text, label.text)
+		}
+		i++
+		if i >= len(tokens) {
+			break
+		}
+		value := tokens[i]
+		if value.kind != 's' {
+			return 0, fmt.Errorf("the value of label %s must be quoted, e.g. %s%s\"%s\"", label.text, label.text, op.text, value.text)
+		}
+		if op.text == "=~" || op.text == "!~" {
+			pattern := promUnquote(value.text)
+			if _, err := regexp.Compile("^(?:" + pattern + ")$"); err != nil {
+				return 0, fmt.Errorf("invalid regex for label %s: %w", label.text, err)
+			}
+		}
+		if i+1 < len(tokens) {
+			switch tokens[i+1].kind {
+			case ',':
+				i++
+			case '}':
+			default:
+				return 0, fmt.Errorf("expected , or } after the matcher of label %s at offset %d", label.text, tokens[i+1].pos)
+			}
+		}
+	}
+	return 0, fmt.Errorf("unclosed { at offset %d", brace.pos)
+}
+
+// lintPromRange checks the range or subquery opened at tokens[i], e.g.
+// [5m] or [1h:1m], and returns the index of its closing bracket.
+func lintPromRange(tokens []promToken, i int) (int, error) {
+	bracket := tokens[i]
+	if i == 0 || (tokens[i-1].kind != 'i' && tokens[i-1].kind != '}' && tokens[i-1].kind != ')') {
+		return 0, fmt.Errorf("a range at offset %d must follow a selector or a subexpression", bracket.pos)
+	}
+	end := i + 1
+	for end < len(tokens) && tokens[end].kind != ']' {
+		end++
+	}
+	if end == len(tokens) {
+		return 0, fmt.Errorf("unclosed [ at offset %d", bracket.pos)
+	}
+	inner := tokens[i+1 : end]
+	colon := 0
+	for j, tok := range inner {
+		switch {
+		case tok.kind == ':' && colon == 0 && j > 0:
+			colon++
+		case tok.kind == 'n' && (j == 0 || inner[j-1].kind == ':'):
+			if _, err := parsePromDuration(tok.text); err != nil {
+				if _, ok := parseFinite(tok.text); !ok {
+					return 0, fmt.Errorf("%s at offset %d is not a duration such as 5m or 1h30m", tok.text, tok.pos)
+				}
+			}
+		default:
+			return 0, fmt.Errorf("a range must be a duration such as [5m], or [1h:1m] for a subquery; got %s at offset %d", tok.text, tok.pos)
+		}
+	}
+	if len(inner) == 0 {
+		return 0, fmt.Errorf("empty range at offset %d", bracket.pos)
+	}
+	return end, nil
+}
+
+// promCallHasRange reports whether the call whose parenthesis opens at
+// tokens[i] has a range among its own arguments.
+func promCallHasRange(tokens []promToken, i int) bool {
+	depth := 0
+	for _, tok := range tokens[i:] {
+		switch tok.kind {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return false
+			}
+		case '[':
+			if depth == 1 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// promUnquote returns the content of a PromQL string token. Escaped
+// quotes and backslashes are unescaped; other escapes such as \d are kept
+// for the regex check.
+func promUnquote(s string) string {
+	quote, s := s[0], s[1:len(s)-1]
+	if quote == '`' {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && strings.IndexByte(`\\"'`, s[i+1]) >= 0 {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
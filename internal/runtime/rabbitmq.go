@@ -0,0 +1,178 @@
+package runtime
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"skyline-mcp/internal/canonical"
+)
+
+// rabbitPollInterval is how often consume looks at an empty queue.
+const rabbitPollInterval = 250 * time.Millisecond
+
+// rabbitClient publishes to and reads from RabbitMQ queues over one
+// connection, with a channel per call. Reads use basic.get, so no consumer
+// stays registered on the queue between calls.
+type rabbitClient struct {
+	url      string
+	username string
+	password string
+
+	mu   sync.Mutex
+	conn *amqp.Connection
+}
+
+func (r *rabbitClient) channel(ctx context.Context) (*amqp.Channel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.conn == nil || r.conn.IsClosed() {
+		timeout := brokerDefaultTimeout
+		if deadline, ok := ctx.Deadline(); ok {
+			timeout = max(time.Until(deadline), time.Millisecond)
+		}
+		cfg := amqp.Config{
+			Dial:       amqp.DefaultDial(timeout),
+			Properties: amqp.Table{"connection_name": brokerClientName},
+		}
+		// Without auth, credentials come from the URL.
+		if r.username != "" {
+			cfg.SASL = []amqp.Authentication{&amqp.PlainAuth{Username: r.username, Password: r.password}}
+		}
+		conn, err := amqp.DialConfig(r.url, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("rabbitmq: %w", err)
+		}
+		r.conn = conn
+	}
+	ch, err := r.conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("rabbitmq: %w", err)
+	}
+	return ch, nil
+}
+
+func (r *rabbitClient) Produce(ctx context.Context, op *canonical.MessagingOperation, msg outMessage) (map[string]any, error) {
+	ch, err := r.channel(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer ch.Close()
+	if err := ch.Confirm(false); err != nil {
+		return nil, fmt.Errorf("rabbitmq: %w", err)
+	}
+	returns := ch.NotifyReturn(make(chan amqp.Return, 1))
+
+	routingKey := op.Topic
+	if op.Exchange != "" {
+		routingKey = cmp.Or(msg.RoutingKey, op.RoutingKey, op.Topic)
+	}
+	publishing := amqp.Publishing{
+		ContentType:  msg.ContentType,
+		DeliveryMode: amqp.Persistent,
+		Timestamp:    time.Now(),
+		Body:         msg.Value,
+	}
+	if len(msg.Headers) > 0 {
+		publishing.Headers = amqp.Table{}
+		for name, value := range msg.Headers {
+			publishing.Headers[name] = value
+		}
+	}
+	// Mandatory publishing returns messages no queue takes, instead of
+	// dropping them.
+	confirm, err := ch.PublishWithDeferredConfirmWithContext(ctx, op.Exchange, routingKey, true, false, publishing)
+	if err != nil {
+		return nil, fmt.Errorf("rabbitmq: publish: %w", err)
+	}
+	acked, err := confirm.WaitContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("rabbitmq: publish: %w", err)
+	}
+	if !acked {
+		return nil, fmt.Errorf("rabbitmq: the broker rejected the message")
+	}
+	// The broker sends the return before the confirm.
+	select {
+	case ret := <-returns:
+		return nil, fmt.Errorf("rabbitmq: the message was not routed to any queue (%d %s)", ret.ReplyCode, ret.ReplyText)
+	default:
+	}
+
+	body := map[string]any{"routing_key": routingKey, "confirmed": true}
+	if op.Exchange != "" {
+		body["exchange"] = op.Exchange
+	} else {
+		body["queue"] = op.Topic
+	}
+	return body, nil
+}
+
+func (r *rabbitClient) Consume(ctx context.Context, op *canonical.MessagingOperation, req consumeRequest) ([]brokerMessage, error) {
+	ch, err := r.channel(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer ch.Close()
+
+	deadline := time.Now().Add(req.Wait)
+	var msgs []brokerMessage
+	var lastTag uint64
+	for len(msgs) < req.Max {
+		d, ok, err := ch.Get(op.Topic, false)
+		if err != nil {
+			return nil, fmt.Errorf("rabbitmq: %s: %w", op.Topic, err)
+		}
+		if !ok {
+			// Return once the queue is drained, or wait for a first message.
+			if len(msgs) > 0 || time.Until(deadline) <= 0 {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(min(rabbitPollInterval, time.Until(deadline))):
+			}
+			continue
+		}
+		lastTag = d.DeliveryTag
+		msg := brokerMessage{Subject: d.RoutingKey, Timestamp: d.Timestamp, Value: d.Body, Redelivered: d.Redelivered}
+		if len(d.Headers) > 0 {
+			msg.Headers = map[string]string{}
+			for name, value := range d.Headers {
+				msg.Headers[name] = fmt.Sprint(value)
+			}
+		}
+		msgs = append(msgs, msg)
+	}
+	if lastTag == 0 {
+		return msgs, nil
+	}
+	if req.Ack {
+		err = ch.Ack(lastTag, true)
+	} else {
+		err = ch.Nack(lastTag, true, true)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("rabbitmq: %s: %w", op.Topic, err)
+	}
+	return msgs, nil
+}
+
+func (r *rabbitClient) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.conn == nil {
+		return nil
+	}
+	err := r.conn.Close()
+	r.conn = nil
+	if err == amqp.ErrClosed {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,216 @@
+package runtime
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/redact"
+)
+
+// maxRecordedBody caps request bodies kept in recordings.
+const maxRecordedBody = 64 << 10
+
+// Recording is one recorded tool call as stored on disk.
+type Recording struct {
+	Tool       string         `json:"tool"`
+	Service    string         `json:"service"`
+	Arguments  any            `json:"arguments"`
+	RecordedAt time.Time      `json:"recorded_at"`
+	DurationMs int64          `json:"duration_ms"`
+	Result     *Result        `json:"result,omitempty"`
+	Error      *RecordedError `json:"error,omitempty"`
+	Exchanges  []HTTPExchange `json:"exchanges,omitempty"`
+}
+
+// RecordedError is a failed call. Upstream is set for 4xx rejections so
+// replay returns the same *UpstreamError.
+type RecordedError struct {
+	Message  string         `json:"message"`
+	Upstream *UpstreamError `json:"upstream,omitempty"`
+}
+
+// HTTPExchange is one upstream request and the response status and headers.
+// Credentials are masked; the response body is the call's result.
+type HTTPExchange struct {
+	Method          string              `json:"method"`
+	URL             string              `json:"url"`
+	RequestHeaders  map[string][]string `json:"request_headers,omitempty"`
+	RequestBody     string              `json:"request_body,omitempty"`
+	Status          int                 `json:"status"`
+	ResponseHeaders map[string][]string `json:"response_headers,omitempty"`
+}
+
+// recorder writes or replays recordings under dir. Calls are keyed by tool
+// name and a hash of their arguments, so a new recording of the same call
+// replaces the old one.
+type recorder struct {
+	mode        string
+	dir         string
+	passthrough bool
+	redactor    *redact.Redactor
+}
+
+func newRecorder(cfg *config.RecordingConfig, redactor *redact.Redactor) *recorder {
+	if cfg == nil {
+		return nil
+	}
+	return &recorder{mode: cfg.Mode, dir: cfg.Dir, passthrough: cfg.Passthrough, redactor: redactor}
+}
+
+// recordingPath returns where a call's recording lives.
+func (r *recorder) recordingPath(op *canonical.Operation, args map[string]any) string {
+	data, _ := json.Marshal(args) // map keys are sorted, so equal args hash equally
+	sum := sha256.Sum256(append([]byte(op.ToolName+"\x00"), data...))
+	return filepath.Join(r.dir, canonical.SanitizeName(op.ToolName), hex.EncodeToString(sum[:])[:16]+".json")
+}
+
+type exchangesKey struct{}
+
+type exchangeLog struct {
+	mu        sync.Mutex
+	exchanges []HTTPExchange
+}
+
+func (r *recorder) execute(ctx context.Context, op *canonical.Operation, args map[string]any, call func(context.Context) (*Result, error)) (*Result, error) {
+	path := r.recordingPath(op, args)
+	if r.mode == "replay" {
+		rec, err := readRecording(path)
+		if err == nil {
+			return rec.replay()
+		}
+		if !errors.Is(err, os.ErrNotExist) || !r.passthrough {
+			return nil, fmt.Errorf("replay %s: no usable recording for these arguments: %w", op.ToolName, err)
+		}
+		return call(ctx)
+	}
+
+	log := &exchangeLog{}
+	start := time.Now()
+	result, err := call(context.WithValue(ctx, exchangesKey{}, log))
+	rec := &Recording{
+		Tool:       op.ToolName,
+		Service:    op.ServiceName,
+		Arguments:  r.redactor.ForAPI(op.ServiceName).RedactValue(args),
+		RecordedAt: start.UTC(),
+		DurationMs: time.Since(start).Milliseconds(),
+		Exchanges:  log.exchanges,
+	}
+	if result != nil {
+		rec.Result = &Result{Status: result.Status, ContentType: result.ContentType, Body: r.redactor.ForAPI(op.ServiceName).RedactValue(result.Body), Pagination: result.Pagination}
+	}
+	if err != nil {
+		rec.Error = &RecordedError{Message: r.redactor.Redact(err.Error())}
+		var upErr *UpstreamError
+		if errors.As(err, &upErr) {
+			rec.Error.Upstream = upErr
+		}
+	}
+	// Recording is a debugging aid; never fail the call because of it.
+	_ = writeRecording(path, rec)
+	return result, err
+}
+
+func (rec *Recording) replay() (*Result, error) {
+	if rec.Error != nil {
+		if rec.Error.Upstream != nil {
+			return nil, rec.Error.Upstream
+		}
+		return nil, errors.New(rec.Error.Message)
+	}
+	if rec.Result == nil {
+		return nil, fmt.Errorf("recording has neither result nor error")
+	}
+	return rec.Result, nil
+}
+
+func readRecording(path string) (*Recording, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rec Recording
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+func writeRecording(path string, rec *Recording) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// credentialHeaders are masked in recorded exchanges whatever their value.
+var credentialHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+	"X-Api-Key":           true,
+	"Api-Key":             true,
+}
+
+// recordExchange adds an upstream request to the recording in progress, if
+// any.
+func (e *Executor) recordExchange(ctx context.Context, redactor *redact.Redactor, req *http.Request, body []byte, resp *http.Response) {
+	log, ok := ctx.Value(exchangesKey{}).(*exchangeLog)
+	if !ok {
+		return
+	}
+	if len(body) > maxRecordedBody {
+		body = body[:maxRecordedBody]
+	}
+	ex := HTTPExchange{
+		Method:         req.Method,
+		URL:            redactor.Redact(req.URL.String()),
+		RequestHeaders: maskHeaders(req.Header, redactor),
+		RequestBody:    redactor.RedactJSON(string(body)),
+	}
+	if resp != nil {
+		ex.Status = resp.StatusCode
+		ex.ResponseHeaders = maskHeaders(resp.Header, redactor)
+	}
+	log.mu.Lock()
+	log.exchanges = append(log.exchanges, ex)
+	log.mu.Unlock()
+}
+
+func maskHeaders(h http.Header, redactor *redact.Redactor) map[string][]string {
+	if len(h) == 0 {
+		return nil
+	}
+	out := make(map[string][]string, len(h))
+	for name, values := range h {
+		masked := make([]string, len(values))
+		for i, v := range values {
+			if credentialHeaders[http.CanonicalHeaderKey(name)] {
+				masked[i] = "[REDACTED]"
+			} else {
+				masked[i] = redactor.Redact(v)
+			}
+		}
+		out[name] = masked
+	}
+	return out
+}
@@ -0,0 +1,93 @@
+package runtime_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/logging"
+	"skyline-mcp/internal/redact"
+	"skyline-mcp/internal/runtime"
+)
+
+func TestExecutorRecordAndReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, `{"message":"no such pet"}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"name":"rex","token":"s3cret-token"}`)
+	}))
+	dir := t.TempDir()
+
+	newExec := func(rec *config.RecordingConfig) *runtime.Executor {
+		cfg := &config.Config{
+			APIs:      []config.APIConfig{{Name: "api", SpecURL: server.URL + "/openapi.json", BaseURLOverride: server.URL}},
+			Recording: rec,
+		}
+		cfg.ApplyDefaults()
+		redactor := redact.NewRedactor()
+		redactor.AddSecrets([]string{"s3cret-token"})
+		exec, err := runtime.NewExecutor(cfg, []*canonical.Service{{Name: "api", BaseURL: server.URL}}, logging.Discard(), redactor)
+		if err != nil {
+			t.Fatalf("executor init failed: %v", err)
+		}
+		return exec
+	}
+	get := &canonical.Operation{ServiceName: "api", ID: "getPet", ToolName: "api__getPet", Method: "get", Path: "/pets"}
+	missing := &canonical.Operation{ServiceName: "api", ID: "missing", ToolName: "api__missing", Method: "get", Path: "/missing"}
+	args := map[string]any{"id": "1"}
+
+	recorder := newExec(&config.RecordingConfig{Mode: "record", Dir: dir})
+	if _, err := recorder.Execute(context.Background(), get, args); err != nil {
+		t.Fatalf("record call failed: %v", err)
+	}
+	if _, err := recorder.Execute(context.Background(), missing, args); err == nil {
+		t.Fatal("expected upstream error while recording")
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "api__getPet", "*.json"))
+	if len(files) != 1 {
+		t.Fatalf("recordings = %v, want one file", files)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "s3cret-token") || !strings.Contains(string(data), `"method": "GET"`) {
+		t.Fatalf("recording not redacted or missing exchange:\n%s", data)
+	}
+
+	server.Close()
+	replayer := newExec(&config.RecordingConfig{Mode: "replay", Dir: dir})
+	res, err := replayer.Execute(context.Background(), get, args)
+	if err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if body, _ := res.Body.(map[string]any); res.Status != 200 || body["name"] != "rex" {
+		t.Fatalf("unexpected replayed result: %+v", res)
+	}
+	_, err = replayer.Execute(context.Background(), missing, args)
+	var upErr *runtime.UpstreamError
+	if !errors.As(err, &upErr) || upErr.Status != 404 || upErr.Message != "no such pet" {
+		t.Fatalf("replayed error = %v, want 404 UpstreamError", err)
+	}
+
+	if _, err := replayer.Execute(context.Background(), get, map[string]any{"id": "2"}); err == nil || !strings.Contains(err.Error(), "no usable recording") {
+		t.Fatalf("expected missing recording error, got %v", err)
+	}
+	passthrough := newExec(&config.RecordingConfig{Mode: "replay", Dir: dir, Passthrough: true})
+	if _, err := passthrough.Execute(context.Background(), get, map[string]any{"id": "2"}); err == nil || strings.Contains(err.Error(), "no usable recording") {
+		t.Fatalf("passthrough should call the (closed) upstream, got %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+package runtime
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/circuitbreaker"
+)
+
+// maxLocalResponses bounds the results an executor keeps in memory.
+const maxLocalResponses = 1024
+
+// responseCache keeps the results of APIs with response_cache_seconds, in
+// memory or, in distributed mode, in the store shared by every replica.
+type responseCache struct {
+	mu     sync.Mutex
+	local  map[string]cachedResponse
+	shared circuitbreaker.SharedState // nil unless UseSharedState was called
+	prefix string
+}
+
+type cachedResponse struct {
+	value   []byte
+	expires time.Time
+}
+
+func newResponseCache() *responseCache {
+	return &responseCache{local: map[string]cachedResponse{}}
+}
+
+// share moves the cache to store under prefix.
+func (c *responseCache) share(store circuitbreaker.SharedState, prefix string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.shared, c.prefix = store, prefix
+}
+
+// get returns the result stored at key. A shared store that cannot be
+// reached counts as a miss.
+func (c *responseCache) get(ctx context.Context, key string) (*Result, bool) {
+	c.mu.Lock()
+	shared, prefix := c.shared, c.prefix
+	var value []byte
+	if shared == nil {
+		if entry, ok := c.local[key]; ok && time.Now().Before(entry.expires) {
+			value = entry.value
+		}
+	}
+	c.mu.Unlock()
+	if shared != nil {
+		var ok bool
+		var err error
+		if value, ok, err = shared.Get(ctx, prefix+key); err != nil || !ok {
+			return nil, false
+		}
+	}
+	if value == nil {
+		return nil, false
+	}
+	var result Result
+	if err := json.Unmarshal(value, &result); err != nil {
+		return nil, false
+	}
+	return &result, true
+}
+
+// set stores result at key for ttl. Failures are ignored: the next call
+// goes to the upstream again.
+func (c *responseCache) set(ctx context.Context, key string, result *Result, ttl time.Duration) {
+	value, err := json.Marshal(result)
+	if err != nil {
+		return
+	}
+	c.mu.Lock()
+	shared, prefix := c.shared, c.prefix
+	if shared == nil {
+		now := time.Now()
+		if len(c.local) >= maxLocalResponses {
+			for k, entry := range c.local {
+				if !now.Before(entry.expires) {
+					delete(c.local, k)
+				}
+			}
+		}
+		if len(c.local) < maxLocalResponses {
+			c.local[key] = cachedResponse{value: value, expires: now.Add(ttl)}
+		}
+	}
+	c.mu.Unlock()
+	if shared != nil {
+		_ = shared.Set(ctx, prefix+key, value, ttl)
+	}
+}
+
+// responseCacheTTL returns how long the result of op may be cached, or 0.
+// Only plain HTTP GETs are cached, and not for APIs with hooks or with
+// headers that change per request.
+func (e *Executor) responseCacheTTL(op *canonical.Operation, hooks *apiHooks) time.Duration {
+	cfg := e.services[op.ServiceName]
+	if cfg.ResponseCache <= 0 || hooks != nil || op.RESTComposite != nil {
+		return 0
+	}
+	if (op.Protocol != "" && op.Protocol != "http") || !strings.EqualFold(op.Method, "GET") {
+		return 0
+	}
+	for _, value := range cfg.Headers {
+		if strings.Contains(value, "{{") {
+			return 0
+		}
+	}
+	return cfg.ResponseCache
+}
+
+// responseCacheKey identifies a call by its tool and arguments.
+func responseCacheKey(op *canonical.Operation, args map[string]any) (string, bool) {
+	encoded, err := json.Marshal(args)
+	if err != nil {
+		return "", false
+	}
+	sum := sha256.Sum256(append([]byte(op.ToolName+"\x00"), encoded...))
+	return op.ServiceName + ":" + hex.EncodeToString(sum[:]), true
+}
+
+// cacheable reports whether result may be served to later callers.
+func cacheable(result *Result) bool {
+	if result.Status < 200 || result.Status >= 300 || result.Stream != nil {
+		return false
+	}
+	_, raw := result.Body.([]byte)
+	return !raw
+}
@@ -0,0 +1,159 @@
+package runtime_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/cluster"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/logging"
+	"skyline-mcp/internal/redact"
+	"skyline-mcp/internal/runtime"
+)
+
+// countingUpstream answers every request with the number of requests it
+// has seen.
+func countingUpstream(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		if r.URL.Query().Get("fail") != "" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"call": n})
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func newCachingExecutor(t *testing.T, baseURL string, seconds int, headers map[string]string) *runtime.Executor {
+	t.Helper()
+	cfg := &config.Config{APIs: []config.APIConfig{{
+		Name:                 "api",
+		SpecURL:              "http://example.com/spec",
+		BaseURLOverride:      baseURL,
+		ResponseCacheSeconds: intPtr(seconds),
+		Headers:              headers,
+	}}}
+	cfg.ApplyDefaults()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("config invalid: %v", err)
+	}
+	exec, err := runtime.NewExecutor(cfg, []*canonical.Service{{Name: "api", BaseURL: baseURL}}, logging.Discard(), redact.NewRedactor())
+	if err != nil {
+		t.Fatalf("executor: %v", err)
+	}
+	return exec
+}
+
+var cachedGet = &canonical.Operation{
+	ServiceName: "api",
+	ToolName:    "api__listItems",
+	Method:      "get",
+	Path:        "/items",
+	Parameters: []canonical.Parameter{
+		{Name: "q", In: "query"},
+		{Name: "fail", In: "query"},
+	},
+}
+
+func callNumber(t *testing.T, exec *runtime.Executor, op *canonical.Operation, args map[string]any) any {
+	t.Helper()
+	result, err := exec.Execute(context.Background(), op, args)
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	body, _ := result.Body.(map[string]any)
+	return body["call"]
+}
+
+func TestResponseCache(t *testing.T) {
+	server, calls := countingUpstream(t)
+	exec := newCachingExecutor(t, server.URL, 60, nil)
+
+	first := callNumber(t, exec, cachedGet, map[string]any{"q": "a"})
+	if again := callNumber(t, exec, cachedGet, map[string]any{"q": "a"}); again != first {
+		t.Errorf("identical call got %v, want the cached %v", again, first)
+	}
+	if other := callNumber(t, exec, cachedGet, map[string]any{"q": "b"}); other == first {
+		t.Error("call with other arguments was served from the cache")
+	}
+	if calls.Load() != 2 {
+		t.Errorf("upstream saw %d calls, want 2", calls.Load())
+	}
+
+	// Errors are not cached.
+	for range 2 {
+		result, err := exec.Execute(context.Background(), cachedGet, map[string]any{"fail": "1"})
+		if err == nil && result.Status < 500 {
+			t.Fatalf("expected the upstream error, got %+v", result)
+		}
+	}
+	if calls.Load() != 4 {
+		t.Errorf("failed calls reached the upstream %d times, want 2", calls.Load()-2)
+	}
+
+	// Nor are writes.
+	post := *cachedGet
+	post.Method, post.ToolName = "post", "api__createItem"
+	callNumber(t, exec, &post, nil)
+	callNumber(t, exec, &post, nil)
+	if calls.Load() != 6 {
+		t.Errorf("POST calls reached the upstream %d times, want 2", calls.Load()-4)
+	}
+}
+
+func TestResponseCacheSkipsPerRequestHeaders(t *testing.T) {
+	server, calls := countingUpstream(t)
+	exec := newCachingExecutor(t, server.URL, 60, map[string]string{"X-Session": "{{mcp.session_id}}"})
+	callNumber(t, exec, cachedGet, nil)
+	callNumber(t, exec, cachedGet, nil)
+	if calls.Load() != 2 {
+		t.Errorf("upstream saw %d calls, want 2", calls.Load())
+	}
+}
+
+func TestResponseCacheSharedBetweenExecutors(t *testing.T) {
+	server, calls := countingUpstream(t)
+	redis := miniredis.RunT(t)
+	store, err := cluster.Open("redis://"+redis.Addr(), "skyline:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	// Two replicas serving the same profile.
+	a := newCachingExecutor(t, server.URL, 60, nil)
+	b := newCachingExecutor(t, server.URL, 60, nil)
+	a.UseSharedState(store, "default")
+	b.UseSharedState(store, "default")
+	first := callNumber(t, a, cachedGet, nil)
+	if got := callNumber(t, b, cachedGet, nil); got != first || calls.Load() != 1 {
+		t.Errorf("second replica got %v after %d upstream calls, want the cached %v", got, calls.Load(), first)
+	}
+
+	// Other profiles have their own entries.
+	c := newCachingExecutor(t, server.URL, 60, nil)
+	c.UseSharedState(store, "other")
+	callNumber(t, c, cachedGet, nil)
+	if calls.Load() != 2 {
+		t.Errorf("another profile was served the cached result")
+	}
+
+	// A replica that cannot reach Redis calls the upstream.
+	redis.Close()
+	callNumber(t, a, cachedGet, nil)
+	if calls.Load() != 3 {
+		t.Errorf("upstream saw %d calls, want 3", calls.Load())
+	}
+}
@@ -0,0 +1,136 @@
+package runtime
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"skyline-mcp/internal/config"
+)
+
+// RequestSigner adds a signature to an upstream request once its headers,
+// auth included, are set. body is the request body, nil when there is none.
+type RequestSigner interface {
+	SignRequest(req *http.Request, body []byte) error
+}
+
+// RegisterSigner signs every request to api with signer, in place of the
+// API's signing config.
+func (e *Executor) RegisterSigner(api string, signer RequestSigner) {
+	e.signers[api] = signer
+}
+
+// signRequest applies the API's signer, if any, to req.
+func (e *Executor) signRequest(req *http.Request, apiName string) error {
+	signer := e.signers[apiName]
+	if signer == nil {
+		return nil
+	}
+	body, err := requestBody(req)
+	if err != nil {
+		return fmt.Errorf("sign request: %w", err)
+	}
+	if err := signer.SignRequest(req, body); err != nil {
+		return fmt.Errorf("sign request: %w", err)
+	}
+	return nil
+}
+
+// requestBody returns a copy of the body of req, nil when there is none.
+func requestBody(req *http.Request) ([]byte, error) {
+	if req.GetBody == nil {
+		return nil, nil
+	}
+	rc, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// hmacSigner puts an HMAC of a payload built from the request in a header.
+type hmacSigner struct {
+	cfg  config.SigningConfig
+	key  []byte
+	hash func() hash.Hash
+	now  func() time.Time
+}
+
+// newSigner returns the built-in signer for a signing config.
+func newSigner(cfg *config.SigningConfig) (RequestSigner, error) {
+	key, err := cfg.Key()
+	if err != nil {
+		return nil, err
+	}
+	s := &hmacSigner{cfg: *cfg, key: key, hash: sha256.New, now: time.Now}
+	switch cfg.Algorithm {
+	case "sha1":
+		s.hash = sha1.New
+	case "sha512":
+		s.hash = sha512.New
+	}
+	if s.cfg.Header == "" {
+		s.cfg.Header = "X-Signature"
+	}
+	if s.cfg.Payload == "" {
+		s.cfg.Payload = "{{body}}"
+	}
+	return s, nil
+}
+
+var signingPlaceholderRE = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.-]+)\s*\}\}`)
+
+func (s *hmacSigner) SignRequest(req *http.Request, body []byte) error {
+	timestamp := strconv.FormatInt(s.now().Unix(), 10)
+	if s.cfg.TimestampHeader != "" {
+		req.Header.Set(s.cfg.TimestampHeader, timestamp)
+	}
+	payload := signingPlaceholderRE.ReplaceAllStringFunc(s.cfg.Payload, func(match string) string {
+		name := signingPlaceholderRE.FindStringSubmatch(match)[1]
+		switch name {
+		case "method":
+			return req.Method
+		case "path":
+			return req.URL.EscapedPath()
+		case "query":
+			// Sorted by name, so the upstream can rebuild it.
+			return req.URL.Query().Encode()
+		case "url":
+			return req.URL.String()
+		case "host":
+			return req.URL.Host
+		case "body":
+			return string(body)
+		case "body_sha256":
+			sum := sha256.Sum256(body)
+			return hex.EncodeToString(sum[:])
+		case "timestamp":
+			return timestamp
+		}
+		if header, ok := strings.CutPrefix(name, "header."); ok {
+			return req.Header.Get(header)
+		}
+		return match
+	})
+	mac := hmac.New(s.hash, s.key)
+	mac.Write([]byte(payload))
+	sum := mac.Sum(nil)
+	signature := hex.EncodeToString(sum)
+	if s.cfg.Encoding == "base64" {
+		signature = base64.StdEncoding.EncodeToString(sum)
+	}
+	req.Header.Set(s.cfg.Header, s.cfg.Prefix+signature)
+	return nil
+}
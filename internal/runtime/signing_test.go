@@ -0,0 +1,91 @@
+package runtime
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/logging"
+	"skyline-mcp/internal/redact"
+)
+
+func TestExecutorSignsRequests(t *testing.T) {
+	const secret = "partner-secret"
+	var errs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		// The partner rebuilds the payload the same way.
+		payload := r.Method + "\n" + r.URL.Path + "\n" + r.URL.Query().Encode() + "\n" + r.Header.Get("X-Timestamp") + "\n" + r.Header.Get("Authorization") + "\n" + string(body)
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(payload))
+		if want := "v1=" + hex.EncodeToString(mac.Sum(nil)); r.Header.Get("X-Partner-Signature") != want {
+			errs = append(errs, "signature "+r.Header.Get("X-Partner-Signature")+" does not match "+want)
+		}
+		if r.Header.Get("X-Timestamp") == "" {
+			errs = append(errs, "no timestamp header")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{APIs: []config.APIConfig{{
+		Name: "partner", SpecURL: server.URL + "/openapi.json", BaseURLOverride: server.URL,
+		Auth: &config.AuthConfig{Type: "bearer", Token: "token-1"},
+		Signing: &config.SigningConfig{
+			Type:            "hmac",
+			Secret:          secret,
+			Header:          "X-Partner-Signature",
+			Prefix:          "v1=",
+			TimestampHeader: "X-Timestamp",
+			Payload:         "{{method}}\n{{path}}\n{{query}}\n{{timestamp}}\n{{header.Authorization}}\n{{body}}",
+		},
+	}}}
+	cfg.ApplyDefaults()
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	exec, err := NewExecutor(cfg, []*canonical.Service{{Name: "partner", BaseURL: server.URL}}, logging.Discard(), redact.NewRedactor())
+	if err != nil {
+		t.Fatal(err)
+	}
+	op := &canonical.Operation{
+		ServiceName: "partner", ToolName: "partner__createOrder", Method: "post", Path: "/orders",
+		Parameters:  []canonical.Parameter{{Name: "b", In: "query"}, {Name: "a", In: "query"}},
+		RequestBody: &canonical.RequestBody{ContentType: "application/json"},
+	}
+	if _, err := exec.Execute(context.Background(), op, map[string]any{"b": "2", "a": "1", "body": map[string]any{"sku": "X1"}}); err != nil {
+		t.Fatal(err)
+	}
+	get := &canonical.Operation{ServiceName: "partner", ToolName: "partner__listOrders", Method: "get", Path: "/orders"}
+	if _, err := exec.Execute(context.Background(), get, map[string]any{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+}
+
+func TestHMACSignerDefaults(t *testing.T) {
+	signer, err := newSigner(&config.SigningConfig{Type: "hmac", Secret: "c2VjcmV0", SecretEncoding: "base64", Algorithm: "sha256", Encoding: "base64"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer.(*hmacSigner).now = func() time.Time { return time.Unix(1700000000, 0) }
+	req, _ := http.NewRequest(http.MethodPost, "https://api.example.com/hooks", nil)
+	if err := signer.SignRequest(req, []byte(`{"event":"ping"}`)); err != nil {
+		t.Fatal(err)
+	}
+	// HMAC-SHA256 of the body with key "secret", base64-encoded.
+	if got, want := req.Header.Get("X-Signature"), "T0uzpU6ZxKIOJDSFIp+bCMZuCRBLpvecI85kckKkzoQ="; got != want {
+		t.Fatalf("X-Signature = %q, want %q", got, want)
+	}
+}
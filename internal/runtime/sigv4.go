@@ -0,0 +1,210 @@
+package runtime
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"skyline-mcp/internal/config"
+)
+
+const (
+	sigV4Algorithm = "AWS4-HMAC-SHA256"
+	// defaultAWSRegion signs requests to global endpoints such as
+	// iam.amazonaws.com.
+	defaultAWSRegion = "us-east-1"
+)
+
+var awsRegionRE = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-\d+$`)
+
+// signSigV4 signs req with AWS Signature Version 4 using the credentials
+// of auth. It signs the host, Content-Type and X-Amz-* headers, so it must
+// run after every other header is set.
+func signSigV4(req *http.Request, auth *config.AuthConfig, now time.Time) error {
+	body, err := requestBody(req)
+	if err != nil {
+		return fmt.Errorf("sign request: %w", err)
+	}
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	region, service := awsHostScope(host)
+	if auth.Region != "" {
+		region = auth.Region
+	}
+	if auth.Service != "" {
+		service = auth.Service
+	}
+	if service == "" {
+		return fmt.Errorf("sign request: auth.service is required for host %s", host)
+	}
+	if region == "" {
+		region = defaultAWSRegion
+	}
+
+	t := now.UTC()
+	amzDate := t.Format("20060102T150405Z")
+	scope := t.Format("20060102") + "/" + region + "/" + service + "/aws4_request"
+	sum := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(sum[:])
+	req.Header.Set("X-Amz-Date", amzDate)
+	if auth.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", auth.SessionToken)
+	}
+	if service == "s3" {
+		// S3 rejects requests without the payload hash.
+		req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if lower == "content-type" || strings.HasPrefix(lower, "x-amz-") {
+			trimmed := make([]string, len(values))
+			for i, v := range values {
+				trimmed[i] = strings.Join(strings.Fields(v), " ")
+			}
+			headers[lower] = strings.Join(trimmed, ",")
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		sigV4Path(req.URL.EscapedPath(), service),
+		sigV4Query(req.URL.RawQuery),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := sigV4Algorithm + "\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := []byte("AWS4" + auth.SecretAccessKey)
+	for _, part := range []string{t.Format("20060102"), region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigV4Algorithm, auth.AccessKeyID, scope, signedHeaders, signature))
+	return nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// awsHostScope returns the region and service of an AWS endpoint host such
+// as monitoring.us-east-1.amazonaws.com. Either is empty when the host does
+// not name it.
+func awsHostScope(host string) (region, service string) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+	var prefix string
+	for _, suffix := range []string{".amazonaws.com", ".amazonaws.com.cn"} {
+		if p, ok := strings.CutSuffix(host, suffix); ok {
+			prefix = p
+			break
+		}
+	}
+	if prefix == "" {
+		return "", ""
+	}
+	labels := strings.Split(prefix, ".")
+	last := labels[len(labels)-1]
+	if !awsRegionRE.MatchString(last) {
+		return "", last
+	}
+	if len(labels) < 2 {
+		return last, ""
+	}
+	return last, labels[len(labels)-2]
+}
+
+// sigV4Path is the canonical URI of an escaped path. S3 expects each
+// segment escaped once, other services twice.
+func sigV4Path(escaped, service string) string {
+	if escaped == "" {
+		return "/"
+	}
+	segments := strings.Split(escaped, "/")
+	for i, seg := range segments {
+		if service == "s3" {
+			if unescaped, err := url.PathUnescape(seg); err == nil {
+				seg = unescaped
+			}
+		}
+		segments[i] = sigV4Escape(seg)
+	}
+	return strings.Join(segments, "/")
+}
+
+// sigV4Query is the canonical query string: every name and value escaped
+// per RFC 3986, sorted by name and then value.
+func sigV4Query(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	var pairs [][2]string
+	for _, part := range strings.Split(raw, "&") {
+		if part == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(part, "=")
+		pairs = append(pairs, [2]string{sigV4Escape(queryUnescape(name)), sigV4Escape(queryUnescape(value))})
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+	encoded := make([]string, len(pairs))
+	for i, p := range pairs {
+		encoded[i] = p[0] + "=" + p[1]
+	}
+	return strings.Join(encoded, "&")
+}
+
+func queryUnescape(s string) string {
+	if u, err := url.QueryUnescape(s); err == nil {
+		return u
+	}
+	return s
+}
+
+// sigV4Escape escapes every byte except the RFC 3986 unreserved characters.
+func sigV4Escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
@@ -0,0 +1,92 @@
+package runtime
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+
+	"skyline-mcp/internal/config"
+)
+
+// The cases come from the AWS Signature Version 4 test suite.
+func TestSignSigV4(t *testing.T) {
+	auth := &config.AuthConfig{
+		Type:            "aws-sigv4",
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		Region:          "us-east-1",
+		Service:         "service",
+	}
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		method, url string
+		contentType string
+		body        string
+		want        string
+	}{
+		{
+			name: "get-vanilla", method: "GET", url: "https://example.amazonaws.com/",
+			want: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			name: "get-vanilla-query-order-key-case", method: "GET", url: "https://example.amazonaws.com/?Param2=value2&Param1=value1",
+			want: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500",
+		},
+		{
+			name: "post-x-www-form-urlencoded", method: "POST", url: "https://example.amazonaws.com/",
+			contentType: "application/x-www-form-urlencoded", body: "Param1=value1",
+			want: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=content-type;host;x-amz-date, Signature=ff11897932ad3f4e8b18135d722051e5ac45fc38421b1da7b9d196a0fe09473a",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.url, bytes.NewReader([]byte(tt.body)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			if err := signSigV4(req, auth, now); err != nil {
+				t.Fatal(err)
+			}
+			if got := req.Header.Get("Authorization"); got != tt.want {
+				t.Errorf("Authorization =\n%s\nwant\n%s", got, tt.want)
+			}
+			if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+				t.Errorf("X-Amz-Date = %q", got)
+			}
+		})
+	}
+}
+
+func TestSignSigV4Scope(t *testing.T) {
+	auth := &config.AuthConfig{Type: "aws-sigv4", AccessKeyID: "AKID", SecretAccessKey: "secret", SessionToken: "session"}
+	tests := []struct {
+		url, scope string
+	}{
+		{"https://monitoring.eu-west-1.amazonaws.com/", "eu-west-1/monitoring"},
+		{"https://s3.us-west-2.amazonaws.com/bucket/a%20b.txt", "us-west-2/s3"},
+		{"https://iam.amazonaws.com/", "us-east-1/iam"},
+		{"https://abc123.execute-api.ap-southeast-2.amazonaws.com/prod", "ap-southeast-2/execute-api"},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest("GET", tt.url, nil)
+		if err := signSigV4(req, auth, time.Now()); err != nil {
+			t.Fatalf("%s: %v", tt.url, err)
+		}
+		if !bytes.Contains([]byte(req.Header.Get("Authorization")), []byte("/"+tt.scope+"/aws4_request")) {
+			t.Errorf("%s: Authorization %q, want scope %s", tt.url, req.Header.Get("Authorization"), tt.scope)
+		}
+		if req.Header.Get("X-Amz-Security-Token") != "session" {
+			t.Errorf("%s: no session token header", tt.url)
+		}
+	}
+
+	req, _ := http.NewRequest("GET", "https://storage.example.com/", nil)
+	if err := signSigV4(req, auth, time.Now()); err == nil {
+		t.Error("expected an error signing for a non-AWS host without auth.service")
+	}
+}
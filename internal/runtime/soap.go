@@ -0,0 +1,134 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha1" //nolint:gosec // WS-Security UsernameToken digests are defined over SHA-1
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"skyline-mcp/internal/config"
+)
+
+const (
+	wsseNS         = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd"
+	wsuNS          = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd"
+	wsseTokenNS    = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-username-token-profile-1.0"
+	wsseBase64Type = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-soap-message-security-1.0#Base64Binary"
+)
+
+// soapHeaders returns the SOAP header blocks of a call: the WS-Security
+// UsernameToken when the API uses wsse auth, then the configured blocks
+// with their templates expanded and the values XML-escaped.
+func soapHeaders(ctx context.Context, cfg serviceConfig, toolName string) []string {
+	var blocks []string
+	if cfg.Auth != nil && cfg.Auth.Type == "wsse" {
+		blocks = append(blocks, wsseUsernameToken(cfg.Auth, time.Now()))
+	}
+	for _, block := range cfg.SOAPHeaders {
+		blocks = append(blocks, expandXMLTemplate(ctx, block, toolName))
+	}
+	return blocks
+}
+
+// wsseUsernameToken builds a WS-Security header carrying a UsernameToken.
+// Each token has a fresh nonce and creation time; with password_type
+// digest the password is sent as Base64(SHA-1(nonce + created + password)).
+func wsseUsernameToken(auth *config.AuthConfig, now time.Time) string {
+	nonce := make([]byte, 16)
+	_, _ = rand.Read(nonce)
+	created := now.UTC().Format("2006-01-02T15:04:05.000Z")
+
+	passwordType, password := "#PasswordText", auth.Password
+	if auth.PasswordType == "digest" {
+		h := sha1.New() //nolint:gosec // required by the UsernameToken profile
+		h.Write(nonce)
+		h.Write([]byte(created))
+		h.Write([]byte(auth.Password))
+		passwordType, password = "#PasswordDigest", base64.StdEncoding.EncodeToString(h.Sum(nil))
+	}
+
+	var b strings.Builder
+	b.WriteString(`<wsse:Security soapenv:mustUnderstand="1" xmlns:wsse="` + wsseNS + `" xmlns:wsu="` + wsuNS + `">`)
+	b.WriteString(`<wsse:UsernameToken>`)
+	b.WriteString(`<wsse:Username>` + escapeXML(auth.Username) + `</wsse:Username>`)
+	b.WriteString(`<wsse:Password Type="` + wsseTokenNS + passwordType + `">` + escapeXML(password) + `</wsse:Password>`)
+	b.WriteString(`<wsse:Nonce EncodingType="` + wsseBase64Type + `">` + base64.StdEncoding.EncodeToString(nonce) + `</wsse:Nonce>`)
+	b.WriteString(`<wsu:Created>` + created + `</wsu:Created>`)
+	b.WriteString(`</wsse:UsernameToken></wsse:Security>`)
+	return b.String()
+}
+
+// xopIncludeRE matches an xop:Include element, whatever its prefix, and
+// captures the content ID it references.
+var xopIncludeRE = regexp.MustCompile(`<(?:[A-Za-z_][\w.-]*:)?Include\s[^>]*?href=["']cid:([^"']+)["'][^>]*?(?:/>|>\s*</(?:[A-Za-z_][\w.-]*:)?Include>)`)
+
+// resolveXOP turns an MTOM response, a multipart/related package whose
+// root is an XOP document, into the SOAP message it stands for: each
+// xop:Include is replaced by the base64 content of the attachment it
+// references, as if the service had not used MTOM. ok is false when the
+// body is not an MTOM package.
+func resolveXOP(contentType string, body []byte) (msg []byte, rootType string, ok bool, err error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "multipart/related" || !strings.Contains(params["type"], "xop+xml") {
+		return nil, "", false, nil
+	}
+	start := strings.Trim(params["start"], "<>")
+	reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	attachments := map[string][]byte{}
+	var root []byte
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, "", true, fmt.Errorf("read MTOM part: %w", err)
+		}
+		data, err := io.ReadAll(part)
+		if err != nil {
+			return nil, "", true, fmt.Errorf("read MTOM part: %w", err)
+		}
+		if strings.EqualFold(part.Header.Get("Content-Transfer-Encoding"), "base64") {
+			if data, err = base64.StdEncoding.DecodeString(string(bytes.Join(bytes.Fields(data), nil))); err != nil {
+				return nil, "", true, fmt.Errorf("decode MTOM part: %w", err)
+			}
+		}
+		id := strings.Trim(part.Header.Get("Content-ID"), "<>")
+		if root == nil && (start == "" || id == start) {
+			root, rootType = data, part.Header.Get("Content-Type")
+			continue
+		}
+		attachments[id] = data
+	}
+	if root == nil {
+		return nil, "", true, fmt.Errorf("MTOM response has no root part")
+	}
+
+	var missing string
+	msg = xopIncludeRE.ReplaceAllFunc(root, func(include []byte) []byte {
+		cid := string(xopIncludeRE.FindSubmatch(include)[1])
+		if unescaped, err := url.PathUnescape(cid); err == nil {
+			cid = unescaped
+		}
+		data, ok := attachments[cid]
+		if !ok {
+			missing = cid
+			return include
+		}
+		return []byte(base64.StdEncoding.EncodeToString(data))
+	})
+	if missing != "" {
+		return nil, "", true, fmt.Errorf("MTOM response references missing attachment %s", missing)
+	}
+	return msg, rootType, true, nil
+}
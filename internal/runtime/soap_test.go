@@ -0,0 +1,157 @@
+package runtime_test
+
+import (
+	"context"
+	"crypto/sha1" //nolint:gosec // the UsernameToken profile digests with SHA-1
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/logging"
+	"skyline-mcp/internal/redact"
+	"skyline-mcp/internal/runtime"
+)
+
+type soapEnvelope struct {
+	Header struct {
+		Security struct {
+			MustUnderstand string `xml:"mustUnderstand,attr"`
+			Token          struct {
+				Username string `xml:"Username"`
+				Password struct {
+					Type  string `xml:"Type,attr"`
+					Value string `xml:",chardata"`
+				} `xml:"Password"`
+				Nonce   string `xml:"Nonce"`
+				Created string `xml:"Created"`
+			} `xml:"UsernameToken"`
+		} `xml:"Security"`
+		Tenant string `xml:"Tenant"`
+		Client struct {
+			Name  string `xml:"name,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"Client"`
+		Admin string `xml:"Admin"`
+	} `xml:"Header"`
+}
+
+func TestExecutorSOAPWSSecurity(t *testing.T) {
+	for _, passwordType := range []string{"text", "digest"} {
+		t.Run(passwordType, func(t *testing.T) {
+			bodyCh := make(chan string, 1)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				data, _ := io.ReadAll(r.Body)
+				bodyCh <- string(data)
+				w.Header().Set("Content-Type", "text/xml")
+				_, _ = w.Write([]byte("<ok>true</ok>"))
+			}))
+			defer server.Close()
+
+			cfg := &config.Config{APIs: []config.APIConfig{{
+				Name: "api", SpecURL: server.URL + "/service.wsdl", BaseURLOverride: server.URL,
+				Auth:        &config.AuthConfig{Type: "wsse", Username: "svc", Password: "p<w>d", PasswordType: passwordType},
+				SOAPHeaders: []string{`<t:Tenant xmlns:t="urn:tenant">{{tool}}</t:Tenant>`, `<t:Client xmlns:t="urn:tenant" name="{{mcp.client_name}}">{{mcp.client_name}}</t:Client>`},
+			}}}
+			cfg.ApplyDefaults()
+			exec, err := runtime.NewExecutor(cfg, []*canonical.Service{{Name: "api", BaseURL: server.URL}}, logging.Discard(), redact.NewRedactor())
+			if err != nil {
+				t.Fatalf("executor init failed: %v", err)
+			}
+			op := &canonical.Operation{
+				ServiceName: "api", ToolName: "api__ListPlants", Method: "post", ID: "ListPlants",
+				RequestBody:   &canonical.RequestBody{ContentType: "text/xml; charset=utf-8"},
+				SoapNamespace: "http://example.com/plants",
+			}
+			// A client name cannot add elements to the envelope.
+			client := `a"</t:Client><t:Admin>1</t:Admin><t:Client>&amp;`
+			ctx := runtime.WithRequestMeta(context.Background(), runtime.RequestMeta{ClientName: client})
+			if _, err := exec.Execute(ctx, op, map[string]any{}); err != nil {
+				t.Fatalf("execute: %v", err)
+			}
+
+			raw := <-bodyCh
+			var env soapEnvelope
+			if err := xml.Unmarshal([]byte(raw), &env); err != nil {
+				t.Fatalf("envelope is not XML: %v\n%s", err, raw)
+			}
+			sec := env.Header.Security
+			if sec.MustUnderstand != "1" || sec.Token.Username != "svc" || sec.Token.Nonce == "" || sec.Token.Created == "" {
+				t.Fatalf("unexpected security header: %s", raw)
+			}
+			want := "p<w>d"
+			if passwordType == "digest" {
+				nonce, _ := base64.StdEncoding.DecodeString(sec.Token.Nonce)
+				h := sha1.New() //nolint:gosec // see import
+				h.Write(nonce)
+				h.Write([]byte(sec.Token.Created))
+				h.Write([]byte("p<w>d"))
+				want = base64.StdEncoding.EncodeToString(h.Sum(nil))
+			}
+			if sec.Token.Password.Value != want {
+				t.Fatalf("password = %q, want %q", sec.Token.Password.Value, want)
+			}
+			suffix := map[string]string{"text": "#PasswordText", "digest": "#PasswordDigest"}[passwordType]
+			if !strings.HasSuffix(sec.Token.Password.Type, suffix) {
+				t.Fatalf("password type = %q", sec.Token.Password.Type)
+			}
+			if env.Header.Tenant != "api__ListPlants" {
+				t.Fatalf("custom header block = %q", env.Header.Tenant)
+			}
+			if env.Header.Client.Name != client || env.Header.Client.Value != client || env.Header.Admin != "" {
+				t.Fatalf("client header block = %+v, admin %q\n%s", env.Header.Client, env.Header.Admin, raw)
+			}
+		})
+	}
+}
+
+func TestExecutorSOAP12WithMTOMResponse(t *testing.T) {
+	pdf := []byte("%PDF-1.7 \x00\x01binary")
+	var gotContentType, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		gotContentType, gotBody = r.Header.Get("Content-Type"), string(data)
+		w.Header().Set("Content-Type", `multipart/related; type="application/xop+xml"; start="<root@example>"; start-info="application/soap+xml"; boundary="MIMEBoundary"`)
+		_, _ = w.Write([]byte("--MIMEBoundary\r\n" +
+			"Content-Type: application/xop+xml; charset=UTF-8; type=\"application/soap+xml\"\r\n" +
+			"Content-ID: <root@example>\r\n\r\n" +
+			`<soap:Envelope xmlns:soap="http://www.w3.org/2003/05/soap-envelope"><soap:Body><GetDocumentResponse>` +
+			`<name>permit.pdf</name><content><xop:Include xmlns:xop="http://www.w3.org/2004/08/xop/include" href="cid:doc%40example"/></content>` +
+			`</GetDocumentResponse></soap:Body></soap:Envelope>` + "\r\n" +
+			"--MIMEBoundary\r\n" +
+			"Content-Type: application/pdf\r\n" +
+			"Content-Transfer-Encoding: binary\r\n" +
+			"Content-ID: <doc@example>\r\n\r\n" +
+			string(pdf) + "\r\n" +
+			"--MIMEBoundary--\r\n"))
+	}))
+	defer server.Close()
+
+	exec := newExecutor(t, server.URL, nil, 0)
+	op := &canonical.Operation{
+		ServiceName: "api", ToolName: "api__GetDocument", Method: "post", ID: "GetDocument",
+		RequestBody:   &canonical.RequestBody{ContentType: `application/soap+xml; charset=utf-8; action="urn:GetDocument"`},
+		SoapNamespace: "http://example.com/docs",
+		SoapVersion:   "1.2",
+	}
+	result, err := exec.Execute(context.Background(), op, map[string]any{"parameters": map[string]any{"id": "42"}})
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if !strings.Contains(gotContentType, `action="urn:GetDocument"`) {
+		t.Fatalf("content type = %q", gotContentType)
+	}
+	if !strings.Contains(gotBody, `xmlns:soapenv="http://www.w3.org/2003/05/soap-envelope"`) {
+		t.Fatalf("request is not a SOAP 1.2 envelope: %s", gotBody)
+	}
+	data, _ := json.Marshal(result.Body)
+	if !strings.Contains(string(data), base64.StdEncoding.EncodeToString(pdf)) || !strings.Contains(string(data), "permit.pdf") {
+		t.Fatalf("attachment not inlined: %s", data)
+	}
+}
@@ -0,0 +1,85 @@
+package runtime
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"skyline-mcp/internal/canonical"
+)
+
+const (
+	// defaultSOQLLimit applies when a query call sets no limit.
+	defaultSOQLLimit = 100
+	// maxSOQLLimit is the largest page Salesforce returns for a query.
+	maxSOQLLimit = 2000
+)
+
+var (
+	soqlFieldRE   = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*(\.[A-Za-z][A-Za-z0-9_]*)*$`)
+	soqlOrderByRE = regexp.MustCompile(`(?i)^[A-Za-z][A-Za-z0-9_.]*(\s+(ASC|DESC))?(\s+NULLS\s+(FIRST|LAST))?$`)
+)
+
+// buildSOQL builds the query of a Salesforce per-object query tool. Field
+// names are checked against the object's fields, or for relationship
+// fields such as Owner.Name against the identifier syntax, so only where
+// is free text.
+func buildSOQL(q *canonical.SOQLQuery, args map[string]any) (string, error) {
+	known := make(map[string]string, len(q.Fields))
+	for _, f := range q.Fields {
+		known[strings.ToLower(f)] = f
+	}
+	fields := q.DefaultFields
+	if raw, ok := args["fields"]; ok && raw != nil {
+		items, ok := raw.([]any)
+		if !ok {
+			return "", fmt.Errorf("fields must be an array of field names")
+		}
+		fields = make([]string, 0, len(items))
+		for _, item := range items {
+			name, _ := item.(string)
+			name = strings.TrimSpace(name)
+			if !soqlFieldRE.MatchString(name) {
+				return "", fmt.Errorf("invalid field name %q", name)
+			}
+			if !strings.Contains(name, ".") {
+				canonicalName, ok := known[strings.ToLower(name)]
+				if !ok {
+					return "", fmt.Errorf("%s has no field %s", q.Object, name)
+				}
+				name = canonicalName
+			}
+			fields = append(fields, name)
+		}
+	}
+	if len(fields) == 0 {
+		fields = []string{"Id"}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "SELECT %s FROM %s", strings.Join(fields, ", "), q.Object)
+	if where, _ := args["where"].(string); strings.TrimSpace(where) != "" {
+		b.WriteString(" WHERE " + strings.TrimSpace(where))
+	}
+	if orderBy, _ := args["order_by"].(string); strings.TrimSpace(orderBy) != "" {
+		var terms []string
+		for _, term := range strings.Split(orderBy, ",") {
+			term = strings.TrimSpace(term)
+			if !soqlOrderByRE.MatchString(term) {
+				return "", fmt.Errorf("invalid order_by term %q", term)
+			}
+			terms = append(terms, term)
+		}
+		b.WriteString(" ORDER BY " + strings.Join(terms, ", "))
+	}
+	limit := defaultSOQLLimit
+	if raw, ok := args["limit"]; ok && raw != nil {
+		n, ok := raw.(float64)
+		if !ok || n < 1 || n != float64(int(n)) {
+			return "", fmt.Errorf("limit must be a positive integer")
+		}
+		limit = min(int(n), maxSOQLLimit)
+	}
+	fmt.Fprintf(&b, " LIMIT %d", limit)
+	return b.String(), nil
+}
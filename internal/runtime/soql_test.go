@@ -0,0 +1,43 @@
+package runtime
+
+import (
+	"strings"
+	"testing"
+
+	"skyline-mcp/internal/canonical"
+)
+
+func TestBuildSOQL(t *testing.T) {
+	q := &canonical.SOQLQuery{Object: "Account", Fields: []string{"Id", "Name", "Industry", "CreatedDate"}, DefaultFields: []string{"Id", "Name"}}
+	tests := []struct {
+		name    string
+		args    map[string]any
+		want    string
+		wantErr string
+	}{
+		{"defaults", map[string]any{}, "SELECT Id, Name FROM Account LIMIT 100", ""},
+		{
+			"all clauses",
+			map[string]any{"fields": []any{"id", "Industry", "Owner.Name"}, "where": " Industry = 'Energy' ", "order_by": "CreatedDate DESC NULLS LAST, Name", "limit": 5000.0},
+			"SELECT Id, Industry, Owner.Name FROM Account WHERE Industry = 'Energy' ORDER BY CreatedDate DESC NULLS LAST, Name LIMIT 2000", "",
+		},
+		{"unknown field", map[string]any{"fields": []any{"Revenue"}}, "", "Account has no field Revenue"},
+		{"injected field", map[string]any{"fields": []any{"Id FROM User"}}, "", "invalid field name"},
+		{"injected order", map[string]any{"order_by": "Name; DELETE"}, "", "invalid order_by term"},
+		{"bad limit", map[string]any{"limit": 2.5}, "", "limit must be a positive integer"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildSOQL(q, tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("buildSOQL() = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+}
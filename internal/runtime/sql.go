@@ -0,0 +1,428 @@
+package runtime
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/parsers/sqldb"
+)
+
+// executeSQL runs a generated table tool as a single parameterized
+// statement. Identifiers come only from the introspected columns; every
+// value is bound as a parameter.
+func (e *Executor) executeSQL(ctx context.Context, op *canonical.Operation, args map[string]any, cfg serviceConfig) (*Result, error) {
+	if op.SQL == nil {
+		return nil, fmt.Errorf("sql operation %s missing SQL metadata", op.ID)
+	}
+	if cfg.Database == nil {
+		return nil, fmt.Errorf("sql: service %s has no database config", op.ServiceName)
+	}
+	if timeout := e.timeout(ctx, op, cfg); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	dialect, err := sqldb.ParseDialect(cfg.Database.Driver)
+	if err != nil {
+		return nil, err
+	}
+	db, err := e.getSQLDB(ctx, op.ServiceName, dialect, cfg.Database.DSN)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &sqlQuery{op: op.SQL, dialect: dialect}
+	switch op.SQL.Action {
+	case "list":
+		return q.list(ctx, db, args, cfg.Database.MaxRows)
+	case "get":
+		return q.get(ctx, db, args)
+	case "insert":
+		return q.insert(ctx, db, args)
+	case "update":
+		return q.update(ctx, db, args)
+	}
+	return nil, fmt.Errorf("sql: unknown action %q", op.SQL.Action)
+}
+
+// getSQLDB returns the service's connection pool, opening it on first use.
+func (e *Executor) getSQLDB(ctx context.Context, service string, dialect sqldb.Dialect, dsn string) (*sql.DB, error) {
+	e.sqlMu.Lock()
+	defer e.sqlMu.Unlock()
+	if db, ok := e.sqlDBs[service]; ok {
+		return db, nil
+	}
+	db, err := sqldb.Open(ctx, dialect, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%s", e.redactor.Redact(err.Error()))
+	}
+	e.sqlDBs[service] = db
+	return db, nil
+}
+
+type sqlQuery struct {
+	op      *canonical.SQLOperation
+	dialect sqldb.Dialect
+	args    []any
+}
+
+func (q *sqlQuery) ident(name string) string {
+	if q.dialect == sqldb.MySQL {
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// bind records a parameter value and returns its placeholder.
+func (q *sqlQuery) bind(col canonical.SQLColumn, value any) (string, error) {
+	v, err := sqlValue(col, value)
+	if err != nil {
+		return "", err
+	}
+	q.args = append(q.args, v)
+	if q.dialect == sqldb.Postgres {
+		return "$" + strconv.Itoa(len(q.args)), nil
+	}
+	return "?", nil
+}
+
+func (q *sqlQuery) column(name string) (canonical.SQLColumn, bool) {
+	for _, c := range q.op.Columns {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return canonical.SQLColumn{}, false
+}
+
+func (q *sqlQuery) selectList() string {
+	cols := make([]string, len(q.op.Columns))
+	for i, c := range q.op.Columns {
+		cols[i] = q.ident(c.Name)
+	}
+	return strings.Join(cols, ", ")
+}
+
+// conditions builds "col = ?" terms joined by AND, in column order so the
+// statement text is stable. A nil value matches NULL.
+func (q *sqlQuery) conditions(values map[string]any) (string, error) {
+	for name := range values {
+		if _, ok := q.column(name); !ok {
+			return "", fmt.Errorf("sql: unknown column %q in table %s", name, q.op.Table)
+		}
+	}
+	var terms []string
+	for _, c := range q.op.Columns {
+		value, ok := values[c.Name]
+		if !ok {
+			continue
+		}
+		if value == nil {
+			terms = append(terms, q.ident(c.Name)+" IS NULL")
+			continue
+		}
+		ph, err := q.bind(c, value)
+		if err != nil {
+			return "", err
+		}
+		terms = append(terms, q.ident(c.Name)+" = "+ph)
+	}
+	return strings.Join(terms, " AND "), nil
+}
+
+// keyConditions matches the primary key columns, all of which are required.
+func (q *sqlQuery) keyConditions(args map[string]any) (string, error) {
+	key := map[string]any{}
+	for _, name := range q.op.PrimaryKey {
+		value, ok := args[name]
+		if !ok || value == nil {
+			return "", fmt.Errorf("sql: missing primary key column %q", name)
+		}
+		key[name] = value
+	}
+	return q.conditions(key)
+}
+
+func (q *sqlQuery) list(ctx context.Context, db *sql.DB, args map[string]any, maxRows int) (*Result, error) {
+	if maxRows <= 0 {
+		maxRows = sqldb.DefaultMaxRows
+	}
+	stmt := "SELECT " + q.selectList() + " FROM " + q.ident(q.op.Table)
+	if where, ok := args["where"].(map[string]any); ok && len(where) > 0 {
+		cond, err := q.conditions(where)
+		if err != nil {
+			return nil, err
+		}
+		stmt += " WHERE " + cond
+	}
+
+	order := q.op.PrimaryKey
+	if name, ok := args["order_by"].(string); ok && name != "" {
+		if _, known := q.column(name); !known {
+			return nil, fmt.Errorf("sql: unknown order_by column %q in table %s", name, q.op.Table)
+		}
+		order = []string{name}
+	}
+	if len(order) > 0 {
+		dir := " ASC"
+		if desc, _ := args["descending"].(bool); desc {
+			dir = " DESC"
+		}
+		terms := make([]string, len(order))
+		for i, name := range order {
+			terms[i] = q.ident(name) + dir
+		}
+		stmt += " ORDER BY " + strings.Join(terms, ", ")
+	}
+
+	limit := maxRows
+	if n, ok := intArg(args["limit"]); ok && n > 0 && n < maxRows {
+		limit = n
+	}
+	offset, _ := intArg(args["offset"])
+	if offset < 0 {
+		offset = 0
+	}
+	// Limit and offset are validated integers, so they are inlined.
+	stmt += fmt.Sprintf(" LIMIT %d OFFSET %d", limit, offset)
+
+	rows, err := q.query(ctx, db, stmt)
+	if err != nil {
+		return nil, err
+	}
+	return sqlResult(map[string]any{"rows": rows, "count": len(rows), "limit": limit, "offset": offset}), nil
+}
+
+func (q *sqlQuery) get(ctx context.Context, db *sql.DB, args map[string]any) (*Result, error) {
+	cond, err := q.keyConditions(args)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.query(ctx, db, "SELECT "+q.selectList()+" FROM "+q.ident(q.op.Table)+" WHERE "+cond)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return notFound(q.op.Table), nil
+	}
+	return sqlResult(rows[0]), nil
+}
+
+func (q *sqlQuery) insert(ctx context.Context, db *sql.DB, args map[string]any) (*Result, error) {
+	var cols, phs []string
+	for _, c := range q.op.Columns {
+		value, ok := args[c.Name]
+		if !ok {
+			continue
+		}
+		ph, err := q.bind(c, value)
+		if err != nil {
+			return nil, err
+		}
+		cols = append(cols, q.ident(c.Name))
+		phs = append(phs, ph)
+	}
+	for name := range args {
+		if _, ok := q.column(name); !ok {
+			return nil, fmt.Errorf("sql: unknown column %q in table %s", name, q.op.Table)
+		}
+	}
+
+	stmt := "INSERT INTO " + q.ident(q.op.Table)
+	switch {
+	case len(cols) == 0 && q.dialect == sqldb.MySQL:
+		stmt += " () VALUES ()"
+	case len(cols) == 0:
+		stmt += " DEFAULT VALUES"
+	default:
+		stmt += " (" + strings.Join(cols, ", ") + ") VALUES (" + strings.Join(phs, ", ") + ")"
+	}
+
+	if q.dialect != sqldb.MySQL {
+		rows, err := q.query(ctx, db, stmt+" RETURNING "+q.selectList())
+		if err != nil {
+			return nil, err
+		}
+		body := map[string]any{"rows_affected": len(rows)}
+		if len(rows) > 0 {
+			body["row"] = rows[0]
+		}
+		return sqlResult(body), nil
+	}
+
+	res, err := db.ExecContext(ctx, stmt, q.args...)
+	if err != nil {
+		return nil, fmt.Errorf("sql: insert into %s: %w", q.op.Table, err)
+	}
+	affected, _ := res.RowsAffected()
+	body := map[string]any{"rows_affected": affected}
+	if id, err := res.LastInsertId(); err == nil && id != 0 {
+		body["last_insert_id"] = id
+	}
+	return sqlResult(body), nil
+}
+
+func (q *sqlQuery) update(ctx context.Context, db *sql.DB, args map[string]any) (*Result, error) {
+	set, _ := args["set"].(map[string]any)
+	if len(set) == 0 {
+		return nil, fmt.Errorf("sql: set must name at least one column")
+	}
+	pk := map[string]bool{}
+	for _, name := range q.op.PrimaryKey {
+		pk[name] = true
+	}
+	var assignments []string
+	for _, c := range q.op.Columns {
+		value, ok := set[c.Name]
+		if !ok {
+			continue
+		}
+		if pk[c.Name] {
+			return nil, fmt.Errorf("sql: primary key column %q cannot be updated", c.Name)
+		}
+		ph, err := q.bind(c, value)
+		if err != nil {
+			return nil, err
+		}
+		assignments = append(assignments, q.ident(c.Name)+" = "+ph)
+	}
+	for name := range set {
+		if _, ok := q.column(name); !ok {
+			return nil, fmt.Errorf("sql: unknown column %q in table %s", name, q.op.Table)
+		}
+	}
+	cond, err := q.keyConditions(args)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := "UPDATE " + q.ident(q.op.Table) + " SET " + strings.Join(assignments, ", ") + " WHERE " + cond
+	res, err := db.ExecContext(ctx, stmt, q.args...)
+	if err != nil {
+		return nil, fmt.Errorf("sql: update %s: %w", q.op.Table, err)
+	}
+	affected, _ := res.RowsAffected()
+	if affected == 0 && q.dialect != sqldb.MySQL {
+		// MySQL reports 0 when the values were already set, so only the
+		// other dialects can tell a missing row apart.
+		return notFound(q.op.Table), nil
+	}
+	return sqlResult(map[string]any{"rows_affected": affected}), nil
+}
+
+// query runs stmt with the bound arguments and returns rows as column maps.
+func (q *sqlQuery) query(ctx context.Context, db *sql.DB, stmt string) ([]map[string]any, error) {
+	rows, err := db.QueryContext(ctx, stmt, q.args...)
+	if err != nil {
+		return nil, fmt.Errorf("sql: %s %s: %w", q.op.Action, q.op.Table, err)
+	}
+	defer rows.Close()
+	names, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	out := []map[string]any{}
+	for rows.Next() {
+		values := make([]any, len(names))
+		ptrs := make([]any, len(names))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, fmt.Errorf("sql: scan %s: %w", q.op.Table, err)
+		}
+		row := make(map[string]any, len(names))
+		for i, name := range names {
+			col, _ := q.column(name)
+			row[name] = jsonValue(col, values[i])
+		}
+		out = append(out, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sql: %s %s: %w", q.op.Action, q.op.Table, err)
+	}
+	return out, nil
+}
+
+// sqlValue converts a JSON argument to a driver value for col: whole
+// numbers become int64 for integer columns and objects/arrays are encoded
+// as JSON text.
+func sqlValue(col canonical.SQLColumn, value any) (any, error) {
+	switch v := value.(type) {
+	case float64:
+		if sqldb.JSONType(col.Type) == "integer" {
+			if v != math.Trunc(v) {
+				return nil, fmt.Errorf("sql: column %q expects an integer, got %v", col.Name, v)
+			}
+			return int64(v), nil
+		}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i, nil
+		}
+		return v.Float64()
+	case map[string]any, []any:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("sql: encode column %q: %w", col.Name, err)
+		}
+		return string(data), nil
+	}
+	return value, nil
+}
+
+// jsonValue converts a scanned value for the tool result. Drivers return
+// text as []byte; JSON columns are decoded.
+func jsonValue(col canonical.SQLColumn, value any) any {
+	var text string
+	switch v := value.(type) {
+	case []byte:
+		text = string(v)
+	case string:
+		text = v
+	default:
+		return value
+	}
+	if sqldb.JSONType(col.Type) == "object" {
+		var decoded any
+		if err := json.Unmarshal([]byte(text), &decoded); err == nil {
+			return decoded
+		}
+	}
+	return text
+}
+
+func intArg(value any) (int, bool) {
+	switch v := value.(type) {
+	case float64:
+		return int(v), v == math.Trunc(v)
+	case int:
+		return v, true
+	case int64:
+		return int(v), true
+	case json.Number:
+		i, err := v.Int64()
+		return int(i), err == nil
+	}
+	return 0, false
+}
+
+func sqlResult(body any) *Result {
+	return &Result{Status: 200, ContentType: "application/json", Body: body}
+}
+
+// notFound is a result rather than an error so a lookup miss does not count
+// against the service's circuit breaker.
+func notFound(table string) *Result {
+	return &Result{
+		Status:      404,
+		ContentType: "application/json",
+		Body:        map[string]any{"error": fmt.Sprintf("no matching row in %s", table)},
+	}
+}
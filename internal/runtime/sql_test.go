@@ -0,0 +1,104 @@
+package runtime_test
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/logging"
+	"skyline-mcp/internal/parsers/sqldb"
+	"skyline-mcp/internal/redact"
+	"skyline-mcp/internal/runtime"
+)
+
+func TestExecutorSQL(t *testing.T) {
+	ctx := context.Background()
+	dsn := filepath.Join(t.TempDir(), "app.db")
+	db, err := sqldb.Open(ctx, sqldb.SQLite, dsn)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	for _, stmt := range []string{
+		`CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT NOT NULL, qty INTEGER NOT NULL DEFAULT 0, note TEXT)`,
+		`INSERT INTO items (name, qty) VALUES ('apple', 3), ('pear', 5), ('plum', 5)`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	tables, err := sqldb.Introspect(ctx, db, sqldb.SQLite, "", nil)
+	db.Close()
+	if err != nil {
+		t.Fatalf("introspect: %v", err)
+	}
+	svc, err := sqldb.BuildService("db", tables, sqldb.Options{MaxRows: 2})
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	ops := map[string]*canonical.Operation{}
+	for _, op := range svc.Operations {
+		ops[op.ID] = op
+	}
+
+	cfg := &config.Config{APIs: []config.APIConfig{{
+		Name:     "db",
+		SpecType: "sql",
+		Database: &config.DatabaseConfig{Driver: "sqlite", DSN: dsn, MaxRows: 2},
+	}}}
+	cfg.ApplyDefaults()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("config invalid: %v", err)
+	}
+	exec, err := runtime.NewExecutor(cfg, []*canonical.Service{svc}, logging.Discard(), redact.NewRedactor())
+	if err != nil {
+		t.Fatalf("executor init failed: %v", err)
+	}
+	defer exec.Close()
+
+	// list is capped by max_rows and filters by equality.
+	res, err := exec.Execute(ctx, ops["items_list"], map[string]any{"where": map[string]any{"qty": float64(5)}, "limit": float64(10), "order_by": "name", "descending": true})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	body := res.Body.(map[string]any)
+	rows := body["rows"].([]map[string]any)
+	if body["limit"] != 2 || len(rows) != 2 || rows[0]["name"] != "plum" || rows[1]["name"] != "pear" {
+		t.Fatalf("unexpected list result: %#v", body)
+	}
+
+	res, err = exec.Execute(ctx, ops["items_insert"], map[string]any{"name": "fig; DROP TABLE items", "note": nil})
+	if err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	row := res.Body.(map[string]any)["row"].(map[string]any)
+	if row["id"] != int64(4) || row["qty"] != int64(0) || row["name"] != "fig; DROP TABLE items" {
+		t.Fatalf("unexpected inserted row: %#v", row)
+	}
+
+	res, err = exec.Execute(ctx, ops["items_update"], map[string]any{"id": float64(4), "set": map[string]any{"qty": float64(7), "note": "fresh"}})
+	if err != nil || res.Body.(map[string]any)["rows_affected"] != int64(1) {
+		t.Fatalf("update: %v %#v", err, res)
+	}
+	res, err = exec.Execute(ctx, ops["items_get"], map[string]any{"id": float64(4)})
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got := res.Body.(map[string]any); got["qty"] != int64(7) || got["note"] != "fresh" {
+		t.Fatalf("unexpected row after update: %#v", got)
+	}
+
+	res, err = exec.Execute(ctx, ops["items_get"], map[string]any{"id": float64(99)})
+	if err != nil || res.Status != 404 {
+		t.Fatalf("expected 404 result for missing row, got %v %#v", err, res)
+	}
+
+	if _, err := exec.Execute(ctx, ops["items_list"], map[string]any{"where": map[string]any{"name = name OR 1": "x"}}); err == nil || !strings.Contains(err.Error(), "unknown column") {
+		t.Fatalf("expected unknown column error, got %v", err)
+	}
+	if _, err := exec.Execute(ctx, ops["items_update"], map[string]any{"id": float64(1), "set": map[string]any{"id": float64(2)}}); err == nil {
+		t.Fatalf("expected error updating primary key")
+	}
+}
@@ -0,0 +1,403 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+)
+
+const (
+	// sshKillGrace is how long a command that overran its timeout gets to
+	// end after being killed before its connection is closed under it.
+	sshKillGrace = 2 * time.Second
+	// sshHandshakeTimeout bounds connecting to a host without a deadline.
+	sshHandshakeTimeout = 30 * time.Second
+)
+
+// SSHRun is a command an ssh tool ran, with everything captured of it.
+type SSHRun struct {
+	Host      string
+	User      string
+	Command   string // the command line after filling in the arguments
+	ExitCode  int    // -1 when the command ended without an exit status
+	Signal    string // signal that ended the command, if any
+	Stdout    string
+	Stderr    string
+	Truncated bool // stdout or stderr went over the output limit
+	Duration  time.Duration
+	Error     string // why the command could not run or finish
+}
+
+// SSHHook is called after every command of an ssh tool, e.g. to audit it.
+type SSHHook func(ctx context.Context, op *canonical.Operation, run SSHRun)
+
+// SetSSHHook registers fn to be called whenever an ssh tool runs a
+// command, including commands that fail or time out.
+func (e *Executor) SetSSHHook(fn SSHHook) {
+	e.sshHook = fn
+}
+
+// executeSSH runs an allowlisted command of a spec_type: ssh API on one of
+// its hosts. Only the command's template runs; arguments are checked
+// against the template's parameters and quoted for the shell.
+func (e *Executor) executeSSH(ctx context.Context, op *canonical.Operation, args map[string]any, cfg serviceConfig) (*Result, error) {
+	if op.SSH == nil {
+		return nil, fmt.Errorf("ssh operation %s missing command metadata", op.ID)
+	}
+	if cfg.SSH == nil {
+		return nil, fmt.Errorf("ssh: service %s has no ssh config", op.ServiceName)
+	}
+	command, ok := cfg.SSH.Command(op.SSH.Command)
+	if !ok {
+		return nil, fmt.Errorf("ssh: service %s has no command %s", op.ServiceName, op.SSH.Command)
+	}
+	hostName, line, err := renderSSHCommand(command, op.SSH.Hosts, args)
+	if err != nil {
+		return nil, err
+	}
+	host, ok := cfg.SSH.Host(hostName)
+	if !ok {
+		return nil, fmt.Errorf("ssh: service %s has no host %s", op.ServiceName, hostName)
+	}
+	timeout := e.timeout(ctx, op, cfg)
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	run := SSHRun{Host: host.Name, User: cfg.SSH.HostUser(host), Command: line, ExitCode: -1}
+	start := time.Now()
+	err = e.runSSH(ctx, op.ServiceName, cfg.SSH, host, line, cfg.SSH.OutputLimit(command), &run)
+	run.Duration = time.Since(start)
+	if errors.Is(err, context.DeadlineExceeded) && timeout > 0 {
+		err = fmt.Errorf("ssh: %s: the command did not finish within %s and was stopped; pass %s to allow more time", host.Name, timeout, TimeoutArgument)
+	}
+	if err != nil {
+		run.Error = e.redactor.Redact(err.Error())
+	}
+	if e.sshHook != nil {
+		e.sshHook(ctx, op, run)
+	}
+	if err != nil {
+		return nil, errors.New(run.Error)
+	}
+
+	body := map[string]any{
+		"host":      run.Host,
+		"exit_code": run.ExitCode,
+		"stdout":    run.Stdout,
+		"stderr":    run.Stderr,
+	}
+	if run.Signal != "" {
+		body["signal"] = run.Signal
+	}
+	if run.Truncated {
+		body["truncated"] = true
+	}
+	return &Result{Status: 200, ContentType: "application/json", Body: body}, nil
+}
+
+// renderSSHCommand picks the host and fills the command's placeholders
+// with the checked, shell-quoted arguments.
+func renderSSHCommand(command config.SSHCommand, hosts []string, args map[string]any) (string, string, error) {
+	params := map[string]config.SSHParam{}
+	for _, p := range command.Params {
+		params[p.Name] = p
+	}
+	for name := range args {
+		if _, ok := params[name]; !ok && name != "host" {
+			return "", "", fmt.Errorf("unknown argument %s", name)
+		}
+	}
+
+	var host string
+	switch raw, ok := args["host"]; {
+	case ok && raw != nil:
+		name, isString := raw.(string)
+		if !isString {
+			return "", "", fmt.Errorf("host must be a string")
+		}
+		host = name
+	case len(hosts) == 1:
+		host = hosts[0]
+	default:
+		return "", "", fmt.Errorf("host is required: one of %s", strings.Join(hosts, ", "))
+	}
+	allowed := false
+	for _, h := range hosts {
+		allowed = allowed || h == host
+	}
+	if !allowed {
+		return "", "", fmt.Errorf("host must be one of %s", strings.Join(hosts, ", "))
+	}
+
+	values := map[string]string{}
+	for _, p := range command.Params {
+		v, ok := args[p.Name]
+		if !ok || v == nil {
+			if p.Default == nil {
+				return "", "", fmt.Errorf("%s is required", p.Name)
+			}
+			v = p.Default
+		}
+		s, err := p.Value(v)
+		if err != nil {
+			return "", "", err
+		}
+		values[p.Name] = s
+	}
+	line := config.SSHPlaceholder.ReplaceAllStringFunc(command.Command, func(m string) string {
+		return shellQuote(values[config.SSHPlaceholder.FindStringSubmatch(m)[1]])
+	})
+	return host, line, nil
+}
+
+// shellQuote quotes s as one POSIX shell word.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// runSSH runs line on host and records its exit status and output on run.
+func (e *Executor) runSSH(ctx context.Context, service string, cfg *config.SSHConfig, host config.SSHHost, line string, limit int, run *SSHRun) error {
+	key := service + "/" + host.Name
+	client, err := e.getSSHClient(ctx, key, cfg, host)
+	if err != nil {
+		return err
+	}
+	session, err := client.NewSession()
+	if err != nil {
+		// The connection may have gone away since the last command.
+		e.dropSSHClient(key, client)
+		if client, err = e.getSSHClient(ctx, key, cfg, host); err != nil {
+			return err
+		}
+		if session, err = client.NewSession(); err != nil {
+			return fmt.Errorf("ssh: %s: %w", host.Name, err)
+		}
+	}
+	defer session.Close()
+
+	stdout, stderr := &cappedBuffer{limit: limit}, &cappedBuffer{limit: limit}
+	session.Stdout, session.Stderr = stdout, stderr
+	if err := session.Start(line); err != nil {
+		return fmt.Errorf("ssh: %s: %w", host.Name, err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- session.Wait() }()
+
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		session.Signal(ssh.SIGKILL)
+		session.Close()
+		select {
+		case <-done:
+		case <-time.After(sshKillGrace):
+			// Closing the connection ends Wait, and any other command
+			// running on it.
+			e.dropSSHClient(key, client)
+			<-done
+		}
+		err = ctx.Err()
+	}
+	run.Stdout, run.Stderr = stdout.String(), stderr.String()
+	run.Truncated = stdout.total > limit || stderr.total > limit
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	var exitErr *ssh.ExitError
+	switch {
+	case err == nil:
+		run.ExitCode = 0
+	case errors.As(err, &exitErr):
+		if exitErr.Signal() != "" {
+			run.Signal = "SIG" + exitErr.Signal()
+		} else {
+			run.ExitCode = exitErr.ExitStatus()
+		}
+	default:
+		return fmt.Errorf("ssh: %s: %w", host.Name, err)
+	}
+	return nil
+}
+
+// getSSHClient returns the connection to a host, dialing it on first use.
+// Dials run outside sshMu, so a slow or unreachable host does not hold up
+// calls to the others; concurrent first calls to one host share a dial.
+func (e *Executor) getSSHClient(ctx context.Context, key string, cfg *config.SSHConfig, host config.SSHHost) (*ssh.Client, error) {
+	e.sshMu.Lock()
+	client, ok := e.sshClients[key]
+	e.sshMu.Unlock()
+	if ok {
+		return client, nil
+	}
+	v, err, _ := e.sshDials.Do(key, func() (any, error) {
+		e.sshMu.Lock()
+		if client, ok := e.sshClients[key]; ok {
+			e.sshMu.Unlock()
+			return client, nil
+		}
+		gen := e.sshGen
+		e.sshMu.Unlock()
+
+		client, err := dialSSH(ctx, cfg, host)
+		if err != nil {
+			return nil, err
+		}
+		e.sshMu.Lock()
+		defer e.sshMu.Unlock()
+		if e.sshGen != gen {
+			// Close ran during the dial; don't outlive it.
+			client.Close()
+			return nil, fmt.Errorf("ssh: %s: connections were closed while dialing", host.Name)
+		}
+		e.sshClients[key] = client
+		return client, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*ssh.Client), nil
+}
+
+// dropSSHClient closes client and forgets it, unless it was replaced
+// already.
+func (e *Executor) dropSSHClient(key string, client *ssh.Client) {
+	e.sshMu.Lock()
+	defer e.sshMu.Unlock()
+	if e.sshClients[key] == client {
+		delete(e.sshClients, key)
+	}
+	client.Close()
+}
+
+func dialSSH(ctx context.Context, cfg *config.SSHConfig, host config.SSHHost) (*ssh.Client, error) {
+	signer, err := sshSigner(cfg)
+	if err != nil {
+		return nil, err
+	}
+	hostKeys, err := sshHostKeyCallback(cfg, host)
+	if err != nil {
+		return nil, err
+	}
+	addr := host.Address
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(strings.Trim(addr, "[]"), "22")
+	}
+	clientConfig := &ssh.ClientConfig{
+		User:            cfg.HostUser(host),
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: hostKeys,
+		ClientVersion:   "SSH-2.0-skyline-mcp",
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("ssh: %s: %w", host.Name, err)
+	}
+	// The handshake has no context of its own.
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(sshHandshakeTimeout)
+	}
+	conn.SetDeadline(deadline)
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, clientConfig)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("ssh: %s: %w", host.Name, err)
+	}
+	conn.SetDeadline(time.Time{})
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
+func sshSigner(cfg *config.SSHConfig) (ssh.Signer, error) {
+	pem := []byte(cfg.PrivateKey)
+	if cfg.PrivateKeyFile != "" {
+		var err error
+		if pem, err = os.ReadFile(expandHome(cfg.PrivateKeyFile)); err != nil {
+			return nil, fmt.Errorf("ssh: private key: %w", err)
+		}
+	}
+	var signer ssh.Signer
+	var err error
+	if cfg.Passphrase != "" {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(pem, []byte(cfg.Passphrase))
+	} else {
+		signer, err = ssh.ParsePrivateKey(pem)
+	}
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		return nil, fmt.Errorf("ssh: the private key is encrypted; set ssh.passphrase")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ssh: private key: %w", err)
+	}
+	return signer, nil
+}
+
+// sshHostKeyCallback checks the host's key against its pinned host_key,
+// or else against the known_hosts file. Unknown hosts are refused.
+func sshHostKeyCallback(cfg *config.SSHConfig, host config.SSHHost) (ssh.HostKeyCallback, error) {
+	if host.HostKey != "" {
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(host.HostKey))
+		if err != nil {
+			return nil, fmt.Errorf("ssh: %s: host_key: %w", host.Name, err)
+		}
+		return ssh.FixedHostKey(key), nil
+	}
+	callback, err := knownhosts.New(expandHome(cfg.KnownHostsFile))
+	if err != nil {
+		return nil, fmt.Errorf("ssh: known_hosts_file: %w", err)
+	}
+	return callback, nil
+}
+
+// expandHome expands a leading ~/ to the user's home directory.
+func expandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return path
+}
+
+// cappedBuffer keeps the first limit bytes written to it and counts the
+// rest.
+type cappedBuffer struct {
+	limit int
+	buf   []byte
+	total int
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	b.total += len(p)
+	if room := b.limit - len(b.buf); room > 0 {
+		b.buf = append(b.buf, p[:min(room, len(p))]...)
+	}
+	return len(p), nil
+}
+
+// String returns the kept output as valid UTF-8, without a character cut
+// in half at the limit.
+func (b *cappedBuffer) String() string {
+	out := b.buf
+	if b.total > b.limit {
+		out = trimPartialRune(out)
+	}
+	return strings.ToValidUTF8(string(out), "�")
+}
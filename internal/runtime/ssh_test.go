@@ -0,0 +1,357 @@
+package runtime
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/logging"
+	sshparser "skyline-mcp/internal/parsers/ssh"
+	"skyline-mcp/internal/redact"
+)
+
+func TestRenderSSHCommand(t *testing.T) {
+	maxLines := 500
+	tail := config.SSHCommand{
+		Command: "tail -n {{lines}} /var/log/{{file}} | grep -- {{q}}",
+		Params: []config.SSHParam{
+			{Name: "lines", Type: "integer", Maximum: &maxLines, Default: 100},
+			{Name: "file", Enum: []string{"syslog", "auth.log"}},
+			{Name: "q", Pattern: `[a-z' ]+`},
+		},
+	}
+	host, line, err := renderSSHCommand(tail, []string{"web-1"}, map[string]any{"file": "syslog", "q": "it's down"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `tail -n '100' /var/log/'syslog' | grep -- 'it'\''s down'`; host != "web-1" || line != want {
+		t.Errorf("host %s, line %s, want %s", host, line, want)
+	}
+
+	tests := []struct {
+		args map[string]any
+		want string
+	}{
+		{map[string]any{"file": "syslog; rm -rf /", "q": "x"}, "file must be one of syslog, auth.log"},
+		{map[string]any{"file": "syslog", "q": "$(reboot)"}, "q must match"},
+		{map[string]any{"file": "syslog"}, "q is required"},
+		{map[string]any{"file": "syslog", "q": "x", "lines": float64(501)}, "lines must be at most 500"},
+		{map[string]any{"file": "syslog", "q": "x", "lines": 2.5}, "lines must be an integer"},
+		{map[string]any{"file": "syslog", "q": "x", "extra": "y"}, "unknown argument extra"},
+		{map[string]any{"file": "syslog", "q": "x", "host": "db-1"}, "host must be one of web-1"},
+	}
+	for _, tt := range tests {
+		if _, _, err := renderSSHCommand(tail, []string{"web-1"}, tt.args); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%v: err = %v, want %q", tt.args, err, tt.want)
+		}
+	}
+	if _, _, err := renderSSHCommand(config.SSHCommand{Command: "uptime"}, []string{"web-1", "web-2"}, map[string]any{}); err == nil ||
+		!strings.Contains(err.Error(), "host is required") {
+		t.Errorf("missing host err = %v", err)
+	}
+}
+
+// fakeSSHServer accepts sessions authenticated with clientKey and answers
+// exec requests from outputs: stdout, stderr and exit status by command
+// line. "sleep" runs until the session is signalled or closed.
+type fakeSSHServer struct {
+	addr    string
+	hostKey ssh.PublicKey
+	conns   atomic.Int32
+
+	mu       sync.Mutex
+	commands []string
+}
+
+type fakeOutput struct {
+	stdout, stderr string
+	status         uint32
+}
+
+func newFakeSSHServer(t *testing.T, clientKey ssh.PublicKey, outputs map[string]fakeOutput) *fakeSSHServer {
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	hostSigner, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &ssh.ServerConfig{
+		PublicKeyCallback: func(meta ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if meta.User() == "deploy" && string(key.Marshal()) == string(clientKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, ssh.ErrNoAuth
+		},
+	}
+	cfg.AddHostKey(hostSigner)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	s := &fakeSSHServer{addr: ln.Addr().String(), hostKey: hostSigner.PublicKey()}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.conns.Add(1)
+			go func() {
+				_, chans, reqs, err := ssh.NewServerConn(conn, cfg)
+				if err != nil {
+					return
+				}
+				go ssh.DiscardRequests(reqs)
+				for nc := range chans {
+					ch, requests, err := nc.Accept()
+					if err != nil {
+						continue
+					}
+					go s.session(ch, requests, outputs)
+				}
+			}()
+		}
+	}()
+	return s
+}
+
+func (s *fakeSSHServer) session(ch ssh.Channel, requests <-chan *ssh.Request, outputs map[string]fakeOutput) {
+	defer ch.Close()
+	for req := range requests {
+		if req.Type != "exec" {
+			req.Reply(false, nil)
+			continue
+		}
+		var payload struct{ Command string }
+		ssh.Unmarshal(req.Payload, &payload)
+		req.Reply(true, nil)
+		s.mu.Lock()
+		s.commands = append(s.commands, payload.Command)
+		s.mu.Unlock()
+		if payload.Command == "sleep" {
+			for req := range requests {
+				if req.Type == "signal" {
+					return
+				}
+			}
+			return
+		}
+		out := outputs[payload.Command]
+		ch.Write([]byte(out.stdout))
+		ch.Stderr().Write([]byte(out.stderr))
+		ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{out.status}))
+		return
+	}
+}
+
+func TestExecuteSSH(t *testing.T) {
+	_, clientPriv, _ := ed25519.GenerateKey(rand.Reader)
+	block, err := ssh.MarshalPrivateKey(clientPriv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientSigner, _ := ssh.NewSignerFromKey(clientPriv)
+	server := newFakeSSHServer(t, clientSigner.PublicKey(), map[string]fakeOutput{
+		"sudo systemctl restart 'nginx'":          {stdout: "restarted\n"},
+		"sudo systemctl restart 'api'":            {stderr: "Job for api.service failed.\n", status: 1},
+		"tail -n '100' /var/log/app/'server.log'": {stdout: strings.Repeat("é", 40)},
+		"tail -n '5' /var/log/app/'server.log'":   {stdout: "last lines\n"},
+	})
+	hostKey := string(ssh.MarshalAuthorizedKey(server.hostKey))
+
+	cfg := &config.Config{APIs: []config.APIConfig{{
+		Name: "ops", SpecType: "ssh",
+		SSH: &config.SSHConfig{
+			User:       "deploy",
+			PrivateKey: string(pem.EncodeToMemory(block)),
+			Hosts: []config.SSHHost{
+				{Name: "web-1", Address: server.addr, HostKey: hostKey},
+				{Name: "web-2", Address: server.addr, HostKey: hostKey},
+				{Name: "spoofed", Address: server.addr, HostKey: testOtherHostKey(t)},
+			},
+			Commands: []config.SSHCommand{
+				{Name: "restart_service", Command: "sudo systemctl restart {{service}}", Hosts: []string{"web-1", "web-2", "spoofed"},
+					Params: []config.SSHParam{{Name: "service", Enum: []string{"nginx", "api"}}}},
+				{Name: "tail_logs", Command: "tail -n {{lines}} /var/log/app/{{file}}", Hosts: []string{"web-1"}, MaxOutputBytes: 33,
+					Params: []config.SSHParam{{Name: "lines", Type: "integer", Default: 100}, {Name: "file", Pattern: `[a-z]+\.log`}}},
+				{Name: "hang", Command: "sleep", Hosts: []string{"web-1"}, TimeoutSeconds: 1},
+			},
+		},
+	}}}
+	cfg.ApplyDefaults()
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	svc, err := sshparser.BuildService("ops", cfg.APIs[0].SSH)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exec, err := NewExecutor(cfg, []*canonical.Service{svc}, logging.Discard(), redact.NewRedactor())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer exec.Close()
+	var runs []SSHRun
+	exec.SetSSHHook(func(ctx context.Context, op *canonical.Operation, run SSHRun) {
+		runs = append(runs, run)
+	})
+	ops := map[string]*canonical.Operation{}
+	for _, op := range svc.Operations {
+		ops[op.ID] = op
+	}
+	ctx := context.Background()
+	run := func(tool string, args map[string]any) map[string]any {
+		t.Helper()
+		res, err := exec.Execute(ctx, ops[tool], args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.Body.(map[string]any)
+	}
+
+	body := run("restart_service", map[string]any{"host": "web-2", "service": "nginx"})
+	if body["host"] != "web-2" || body["exit_code"] != 0 || body["stdout"] != "restarted\n" || body["stderr"] != "" {
+		t.Errorf("restart = %v", body)
+	}
+	body = run("restart_service", map[string]any{"host": "web-1", "service": "api"})
+	if body["exit_code"] != 1 || body["stderr"] != "Job for api.service failed.\n" {
+		t.Errorf("failed restart = %v", body)
+	}
+	body = run("tail_logs", map[string]any{"file": "server.log"})
+	if body["truncated"] != true || body["stdout"] != strings.Repeat("é", 16) {
+		t.Errorf("truncated tail = %v", body)
+	}
+	if body := run("tail_logs", map[string]any{"file": "server.log", "lines": float64(5)}); body["stdout"] != "last lines\n" {
+		t.Errorf("tail = %v", body)
+	}
+
+	if _, err := exec.Execute(ctx, ops["restart_service"], map[string]any{"host": "web-1", "service": "nginx; reboot"}); err == nil ||
+		!strings.Contains(err.Error(), "service must be one of nginx, api") {
+		t.Errorf("injection err = %v", err)
+	}
+	if _, err := exec.Execute(ctx, ops["restart_service"], map[string]any{"host": "spoofed", "service": "nginx"}); err == nil ||
+		!strings.Contains(err.Error(), "host key mismatch") {
+		t.Errorf("spoofed host err = %v", err)
+	}
+	if _, err := exec.Execute(ctx, ops["hang"], map[string]any{}); err == nil || !strings.Contains(err.Error(), "did not finish within 1s") {
+		t.Errorf("hang err = %v", err)
+	}
+
+	server.mu.Lock()
+	commands := strings.Join(server.commands, "\n")
+	server.mu.Unlock()
+	if want := "sudo systemctl restart 'nginx'\nsudo systemctl restart 'api'\ntail -n '100' /var/log/app/'server.log'\n" +
+		"tail -n '5' /var/log/app/'server.log'\nsleep"; commands != want {
+		t.Errorf("commands run:\n%s\nwant:\n%s", commands, want)
+	}
+	// Every command is reported, including the ones that failed to run.
+	if len(runs) != 6 {
+		t.Fatalf("hook saw %d runs", len(runs))
+	}
+	if r := runs[1]; r.Host != "web-1" || r.User != "deploy" || r.Command != "sudo systemctl restart 'api'" || r.ExitCode != 1 || r.Stderr == "" {
+		t.Errorf("run = %+v", r)
+	}
+	if r := runs[4]; r.Host != "spoofed" || r.ExitCode != -1 || !strings.Contains(r.Error, "host key mismatch") {
+		t.Errorf("spoofed run = %+v", r)
+	}
+	if r := runs[5]; r.Command != "sleep" || r.Error == "" {
+		t.Errorf("hang run = %+v", r)
+	}
+}
+
+// testOtherHostKey returns a host key no test server uses.
+func testOtherHostKey(t *testing.T) string {
+	pub, _, _ := ed25519.GenerateKey(rand.Reader)
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(ssh.MarshalAuthorizedKey(key))
+}
+
+func TestGetSSHClientDialsOutsideLock(t *testing.T) {
+	_, clientPriv, _ := ed25519.GenerateKey(rand.Reader)
+	block, err := ssh.MarshalPrivateKey(clientPriv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientSigner, _ := ssh.NewSignerFromKey(clientPriv)
+	server := newFakeSSHServer(t, clientSigner.PublicKey(), nil)
+
+	// A host that accepts connections and never answers the handshake.
+	stalled, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stalled.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		if conn, err := stalled.Accept(); err == nil {
+			accepted <- conn
+		}
+	}()
+
+	cfg := &config.SSHConfig{User: "deploy", PrivateKey: string(pem.EncodeToMemory(block))}
+	web := config.SSHHost{Name: "web-1", Address: server.addr, HostKey: string(ssh.MarshalAuthorizedKey(server.hostKey))}
+	slow := config.SSHHost{Name: "slow", Address: stalled.Addr().String(), HostKey: testOtherHostKey(t)}
+	exec := &Executor{logger: logging.Discard(), sshClients: map[string]*ssh.Client{}}
+	defer exec.Close()
+
+	slowDone := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, err := exec.getSSHClient(ctx, "ops/slow", cfg, slow)
+		slowDone <- err
+	}()
+	conn := <-accepted
+	defer conn.Close()
+
+	// Calls to other hosts go ahead while the slow host is dialing, and
+	// concurrent first calls share one connection.
+	clients := make([]*ssh.Client, 8)
+	var wg sync.WaitGroup
+	for i := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			client, err := exec.getSSHClient(ctx, "ops/web-1", cfg, web)
+			if err != nil {
+				t.Error(err)
+			}
+			clients[i] = client
+		}()
+	}
+	wg.Wait()
+	select {
+	case err := <-slowDone:
+		t.Fatalf("the slow dial finished first: %v", err)
+	default:
+	}
+	for _, c := range clients {
+		if c == nil || c != clients[0] {
+			t.Fatalf("callers got different clients: %v", clients)
+		}
+	}
+	if n := server.conns.Load(); n != 1 {
+		t.Errorf("server saw %d connections, want 1", n)
+	}
+
+	conn.Close()
+	if err := <-slowDone; err == nil {
+		t.Error("dialing the stalled host succeeded")
+	}
+}
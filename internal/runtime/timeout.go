@@ -0,0 +1,85 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"skyline-mcp/internal/canonical"
+)
+
+// TimeoutArgument is the reserved tool argument callers pass to change the
+// execution timeout of a single call, e.g. for Jenkins build triggers that
+// outlast the API's timeout_seconds.
+const TimeoutArgument = "_timeout_seconds"
+
+// defaultMaxTimeout caps TimeoutArgument when the config sets no
+// max_timeout_seconds.
+const defaultMaxTimeout = 300 * time.Second
+
+type requestedTimeoutKey struct{}
+
+// WithTimeoutArgument removes TimeoutArgument from args and returns a
+// context carrying the requested timeout for Executor.Execute. Call it
+// before validating args against the tool's schema, which does not list
+// the argument. ctx is returned unchanged when the argument is absent.
+func WithTimeoutArgument(ctx context.Context, args map[string]any) (context.Context, error) {
+	raw, ok := args[TimeoutArgument]
+	if !ok {
+		return ctx, nil
+	}
+	delete(args, TimeoutArgument)
+	var seconds float64
+	switch v := raw.(type) {
+	case float64:
+		seconds = v
+	case int:
+		seconds = float64(v)
+	case int64:
+		seconds = float64(v)
+	default:
+		return ctx, fmt.Errorf("%s must be a number", TimeoutArgument)
+	}
+	if seconds <= 0 || math.IsInf(seconds, 0) || math.IsNaN(seconds) {
+		return ctx, fmt.Errorf("%s must be positive", TimeoutArgument)
+	}
+	return context.WithValue(ctx, requestedTimeoutKey{}, time.Duration(seconds*float64(time.Second))), nil
+}
+
+func requestedTimeout(ctx context.Context) time.Duration {
+	d, _ := ctx.Value(requestedTimeoutKey{}).(time.Duration)
+	return d
+}
+
+// ExecutionTimeout returns how long Execute lets op run, so handlers can
+// size their own deadlines to match. It is 0 for workflow and built-in
+// tools, whose steps are bounded individually.
+func (e *Executor) ExecutionTimeout(ctx context.Context, op *canonical.Operation) time.Duration {
+	cfg, ok := e.services[op.ServiceName]
+	if !ok {
+		return 0
+	}
+	return e.timeout(ctx, op, cfg)
+}
+
+// timeout resolves the timeout of one execution: the caller's
+// TimeoutArgument, else the operation's filter override, else the API's
+// timeout_seconds. The caller's value is capped at max_timeout_seconds, or
+// at the configured timeout if that is larger.
+func (e *Executor) timeout(ctx context.Context, op *canonical.Operation, cfg serviceConfig) time.Duration {
+	configured := cfg.Timeout
+	if op.TimeoutSeconds > 0 {
+		configured = time.Duration(op.TimeoutSeconds) * time.Second
+	}
+	requested := requestedTimeout(ctx)
+	if requested <= 0 {
+		return configured
+	}
+	limit := e.maxTimeout
+	if limit <= 0 {
+		limit = defaultMaxTimeout
+	}
+	limit = max(limit, configured)
+	return min(requested, limit)
+}
@@ -0,0 +1,103 @@
+package runtime_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/runtime"
+)
+
+func TestWithTimeoutArgument(t *testing.T) {
+	args := map[string]any{"id": "1", runtime.TimeoutArgument: float64(90)}
+	ctx, err := runtime.WithTimeoutArgument(context.Background(), args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := args[runtime.TimeoutArgument]; ok {
+		t.Fatal("timeout argument should be removed from args")
+	}
+	if len(args) != 1 {
+		t.Fatalf("args = %v", args)
+	}
+
+	exec := newExecutor(t, "http://example.com", nil, 0)
+	op := &canonical.Operation{ServiceName: "api", ID: "op"}
+	if got := exec.ExecutionTimeout(ctx, op); got != 90*time.Second {
+		t.Fatalf("timeout = %v, want 90s", got)
+	}
+
+	for _, bad := range []any{"60", float64(0), float64(-5), true} {
+		_, err := runtime.WithTimeoutArgument(context.Background(), map[string]any{runtime.TimeoutArgument: bad})
+		if err == nil {
+			t.Errorf("%v: expected an error", bad)
+		}
+	}
+}
+
+func TestExecutionTimeoutPrecedence(t *testing.T) {
+	exec := newExecutor(t, "http://example.com", nil, 0)
+	op := &canonical.Operation{ServiceName: "api", ID: "op"}
+	background := context.Background()
+
+	// The API's timeout_seconds (2s in newExecutor) applies by default.
+	if got := exec.ExecutionTimeout(background, op); got != 2*time.Second {
+		t.Fatalf("default timeout = %v, want 2s", got)
+	}
+	// A filter override replaces it.
+	op.TimeoutSeconds = 120
+	if got := exec.ExecutionTimeout(background, op); got != 120*time.Second {
+		t.Fatalf("override timeout = %v, want 120s", got)
+	}
+	// The caller's value wins but is capped at max_timeout_seconds.
+	ctx, err := runtime.WithTimeoutArgument(background, map[string]any{runtime.TimeoutArgument: 3600})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := exec.ExecutionTimeout(ctx, op); got != 300*time.Second {
+		t.Fatalf("capped timeout = %v, want 300s", got)
+	}
+	// Workflow and built-in tools have no timeout of their own.
+	if got := exec.ExecutionTimeout(background, &canonical.Operation{ServiceName: "workflows"}); got != 0 {
+		t.Fatalf("unknown service timeout = %v, want 0", got)
+	}
+}
+
+func TestExecuteHonorsTimeoutArgument(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(2500 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"queued":true}`))
+	}))
+	defer server.Close()
+
+	exec := newExecutor(t, server.URL, nil, 0)
+	op := &canonical.Operation{
+		ServiceName: "api",
+		ToolName:    "api__build",
+		Method:      "post",
+		Path:        "/job/build",
+	}
+
+	// The slow upstream outlasts the API's 2s timeout...
+	if _, err := exec.Execute(context.Background(), op, map[string]any{}); err == nil {
+		t.Fatal("expected the API timeout to cut the call short")
+	}
+
+	// ...unless the caller asks for longer.
+	args := map[string]any{runtime.TimeoutArgument: 5}
+	ctx, err := runtime.WithTimeoutArgument(context.Background(), args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := exec.Execute(ctx, op, args)
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if result.Status != http.StatusOK {
+		t.Fatalf("status = %d", result.Status)
+	}
+}
@@ -0,0 +1,134 @@
+package runtime
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"skyline-mcp/internal/redact"
+)
+
+// UpstreamError is returned when an upstream rejects a call with a 4xx
+// status. It keeps the upstream's error payload so agents can correct the
+// call instead of retrying blindly.
+type UpstreamError struct {
+	Status    int    `json:"status"`
+	Code      string `json:"code"` // machine-readable category, e.g. "validation_failed"
+	Message   string `json:"message"`
+	Upstream  any    `json:"upstream_error,omitempty"` // decoded error body, redacted
+	RequestID string `json:"request_id,omitempty"`
+	Hint      string `json:"hint,omitempty"`
+	// Headers holds the response headers allowed by the API's
+	// response_headers config, e.g. rate-limit hints.
+	Headers map[string]string `json:"headers,omitempty"`
+	// IdempotencyKey is the key sent with the request, if any.
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+}
+
+func (e *UpstreamError) Error() string {
+	msg := fmt.Sprintf("http error status %d", e.Status)
+	if e.Hint != "" {
+		msg += ": " + e.Hint
+	}
+	return msg
+}
+
+// requestIDHeaders are the response headers upstreams commonly use to
+// identify a request for their support and logs, in order of preference.
+var requestIDHeaders = []string{
+	"X-Request-Id",
+	"Request-Id",
+	"X-Correlation-Id",
+	"X-Amzn-RequestId",
+	"X-Amz-Request-Id",
+	"X-GitHub-Request-Id",
+	"X-Trace-Id",
+	"CF-Ray",
+}
+
+func newUpstreamError(resp *http.Response, body any) *UpstreamError {
+	ue := &UpstreamError{
+		Status:   resp.StatusCode,
+		Code:     upstreamErrorCode(resp.StatusCode),
+		Message:  upstreamErrorMessage(body),
+		Upstream: body,
+	}
+	if ue.Message == "" {
+		ue.Message = http.StatusText(resp.StatusCode)
+	}
+	for _, name := range requestIDHeaders {
+		if v := resp.Header.Get(name); v != "" {
+			ue.RequestID = v
+			break
+		}
+	}
+	return ue
+}
+
+// redact removes configured secrets and fields from the message and
+// payload, which upstreams sometimes echo back.
+func (e *UpstreamError) redact(r *redact.Redactor) {
+	e.Message = r.Redact(e.Message)
+	e.Upstream = r.RedactValue(e.Upstream)
+}
+
+func upstreamErrorCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "invalid_request"
+	case http.StatusUnauthorized:
+		return "unauthorized"
+	case http.StatusForbidden:
+		return "forbidden"
+	case http.StatusNotFound:
+		return "not_found"
+	case http.StatusMethodNotAllowed:
+		return "method_not_allowed"
+	case http.StatusConflict:
+		return "conflict"
+	case http.StatusGone:
+		return "gone"
+	case http.StatusPreconditionFailed:
+		return "precondition_failed"
+	case http.StatusRequestEntityTooLarge:
+		return "payload_too_large"
+	case http.StatusUnsupportedMediaType:
+		return "unsupported_media_type"
+	case http.StatusUnprocessableEntity:
+		return "validation_failed"
+	case http.StatusTooManyRequests:
+		return "rate_limited"
+	}
+	return "client_error"
+}
+
+// upstreamErrorMessage picks the human-readable message out of common
+// error body shapes: {"message"}, {"error": "..."}, {"error": {"message"}},
+// {"error_description"}, {"detail"}, {"title"} and {"errors": [{"message"}]}.
+func upstreamErrorMessage(body any) string {
+	switch v := body.(type) {
+	case string:
+		msg := strings.TrimSpace(v)
+		if len(msg) > 200 {
+			msg = msg[:197] + "..."
+		}
+		return msg
+	case map[string]any:
+		for _, key := range []string{"message", "error_description", "detail", "title", "error"} {
+			switch field := v[key].(type) {
+			case string:
+				if field != "" {
+					return field
+				}
+			case map[string]any:
+				if msg := upstreamErrorMessage(field); msg != "" {
+					return msg
+				}
+			}
+		}
+		if errs, ok := v["errors"].([]any); ok && len(errs) > 0 {
+			return upstreamErrorMessage(errs[0])
+		}
+	}
+	return ""
+}
@@ -0,0 +1,81 @@
+package runtime_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/logging"
+	"skyline-mcp/internal/redact"
+	"skyline-mcp/internal/runtime"
+)
+
+func TestExecutorUpstreamError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/pets":
+			w.Header().Set("X-Request-Id", "req-123")
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			_, _ = io.WriteString(w, `{"error":{"message":"name must not be empty","field":"name","token":"s3cret-token"}}`)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = io.WriteString(w, `{"message":"maintenance"}`)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{APIs: []config.APIConfig{{Name: "api", SpecURL: server.URL + "/openapi.json", BaseURLOverride: server.URL}}}
+	cfg.ApplyDefaults()
+	redactor := redact.NewRedactor()
+	redactor.AddSecrets([]string{"s3cret-token"})
+	exec, err := runtime.NewExecutor(cfg, []*canonical.Service{{Name: "api", BaseURL: server.URL}}, logging.Discard(), redactor)
+	if err != nil {
+		t.Fatalf("executor init failed: %v", err)
+	}
+
+	create := &canonical.Operation{ServiceName: "api", ID: "createPet", ToolName: "api__createPet", Method: "post", Path: "/pets"}
+	_, err = exec.Execute(context.Background(), create, map[string]any{})
+	var upErr *runtime.UpstreamError
+	if !errors.As(err, &upErr) {
+		t.Fatalf("err = %v, want *UpstreamError", err)
+	}
+	if upErr.Status != 422 || upErr.Code != "validation_failed" || upErr.Message != "name must not be empty" || upErr.RequestID != "req-123" {
+		t.Fatalf("unexpected envelope: %+v", upErr)
+	}
+	payload, _ := upErr.Upstream.(map[string]any)["error"].(map[string]any)
+	if payload["field"] != "name" || strings.Contains(payload["token"].(string), "s3cret") {
+		t.Fatalf("upstream payload not kept or not redacted: %#v", upErr.Upstream)
+	}
+	if err.Error() != "http error status 422" {
+		t.Fatalf("Error() = %q", err.Error())
+	}
+
+	// Field rules for the API scrub the payload too.
+	if err := redactor.AddAPIRules("api", redact.Rules{Fields: []string{"$.error.field"}}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = exec.Execute(context.Background(), create, map[string]any{})
+	if !errors.As(err, &upErr) {
+		t.Fatalf("err = %v, want *UpstreamError", err)
+	}
+	if payload := upErr.Upstream.(map[string]any)["error"].(map[string]any); payload["field"] != redact.Placeholder {
+		t.Fatalf("field rule not applied: %#v", payload)
+	}
+
+	// 5xx responses stay results and now keep their body.
+	health := &canonical.Operation{ServiceName: "api", ID: "health", ToolName: "api__health", Method: "get", Path: "/health"}
+	res, err := exec.Execute(context.Background(), health, map[string]any{})
+	if err != nil {
+		t.Fatalf("5xx should be a result: %v", err)
+	}
+	if body, _ := res.Body.(map[string]any); res.Status != 503 || body["message"] != "maintenance" {
+		t.Fatalf("unexpected 5xx result: %+v", res)
+	}
+}
@@ -0,0 +1,169 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"skyline-mcp/internal/canonical"
+)
+
+var workflowPlaceholderRe = regexp.MustCompile(`\{\{\s*([^{}]+?)\s*\}\}`)
+
+// executeWorkflow runs a workflow's steps in order. Step arguments, the
+// when condition and the output are templates over a context holding the
+// workflow input ("input") and the results of earlier steps
+// ("steps.<id>.status", "steps.<id>.body", ...). A step that errors or
+// returns a 4xx/5xx status fails the workflow unless it continues on error.
+func (e *Executor) executeWorkflow(ctx context.Context, op *canonical.Operation, args map[string]any) (*Result, error) {
+	if args == nil {
+		args = map[string]any{}
+	}
+	steps := map[string]any{}
+	scope := map[string]any{"input": args, "steps": steps}
+
+	for _, step := range op.Workflow.Steps {
+		if step.When != "" && !workflowCondition(renderWorkflowString(step.When, scope)) {
+			steps[step.ID] = map[string]any{"skipped": true}
+			e.logger.Debug("workflow step skipped", "component", "executor", "workflow", op.ID, "step", step.ID)
+			continue
+		}
+		stepArgs, _ := renderWorkflowValue(step.Args, scope).(map[string]any)
+		if stepArgs == nil {
+			stepArgs = map[string]any{}
+		}
+		e.logger.Debug("workflow step", "component", "executor", "workflow", op.ID, "step", step.ID, "tool", step.Operation.ToolName)
+		result, err := e.Execute(ctx, step.Operation, stepArgs)
+		stepResult := map[string]any{"skipped": false}
+		if result != nil {
+			stepResult["status"] = result.Status
+			stepResult["body"] = result.Body
+			if err == nil && result.Status >= 400 {
+				err = fmt.Errorf("HTTP %d", result.Status)
+			}
+		}
+		var upErr *UpstreamError
+		if errors.As(err, &upErr) {
+			stepResult["status"] = upErr.Status
+			stepResult["body"] = upErr.Upstream
+		}
+		steps[step.ID] = stepResult
+		if err != nil {
+			if !step.ContinueOnError {
+				return nil, fmt.Errorf("workflow %s: step %s (%s): %w", op.ID, step.ID, step.Operation.ToolName, err)
+			}
+			stepResult["error"] = err.Error()
+		}
+	}
+
+	var body any = map[string]any{"steps": steps}
+	if op.Workflow.Output != nil {
+		body = renderWorkflowValue(op.Workflow.Output, scope)
+	}
+	return &Result{Status: 200, ContentType: "application/json", Body: body}, nil
+}
+
+// renderWorkflowValue substitutes {{path}} placeholders in every string of
+// v. A string that is a single placeholder takes the referenced value as is,
+// so objects and numbers keep their type; otherwise values are interpolated
+// as text.
+func renderWorkflowValue(v any, scope map[string]any) any {
+	switch val := v.(type) {
+	case string:
+		if m := workflowPlaceholderRe.FindStringSubmatchIndex(val); m != nil && m[0] == 0 && m[1] == len(val) {
+			resolved, _ := lookupWorkflowPath(scope, val[m[2]:m[3]])
+			return resolved
+		}
+		return renderWorkflowString(val, scope)
+	case map[string]any:
+		out := make(map[string]any, len(val))
+		for k, item := range val {
+			out[k] = renderWorkflowValue(item, scope)
+		}
+		return out
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			out[i] = renderWorkflowValue(item, scope)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+func renderWorkflowString(s string, scope map[string]any) string {
+	return workflowPlaceholderRe.ReplaceAllStringFunc(s, func(m string) string {
+		path := workflowPlaceholderRe.FindStringSubmatch(m)[1]
+		value, ok := lookupWorkflowPath(scope, path)
+		if !ok || value == nil {
+			return ""
+		}
+		switch value.(type) {
+		case map[string]any, []any:
+			encoded, err := json.Marshal(value)
+			if err == nil {
+				return string(encoded)
+			}
+		}
+		return valueToString(value)
+	})
+}
+
+// lookupWorkflowPath resolves a dotted path such as "steps.create.body.key"
+// or "steps.list.body.items[0].id" against the scope.
+func lookupWorkflowPath(scope map[string]any, path string) (any, bool) {
+	path = strings.ReplaceAll(strings.ReplaceAll(path, "[", "."), "]", "")
+	var cur any = scope
+	for _, part := range strings.Split(path, ".") {
+		if part == "" {
+			continue
+		}
+		switch node := cur.(type) {
+		case map[string]any:
+			next, ok := node[part]
+			if !ok {
+				return nil, false
+			}
+			cur = next
+		case []any:
+			idx, err := strconv.Atoi(part)
+			if err != nil || idx < 0 || idx >= len(node) {
+				return nil, false
+			}
+			cur = node[idx]
+		default:
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// workflowCondition evaluates a rendered when expression: "a == b",
+// "a != b" (operands may be quoted), or a single value that is true unless empty, "false", "0" or
+// "null".
+func workflowCondition(expr string) bool {
+	if left, right, ok := strings.Cut(expr, "!="); ok {
+		return conditionOperand(left) != conditionOperand(right)
+	}
+	if left, right, ok := strings.Cut(expr, "=="); ok {
+		return conditionOperand(left) == conditionOperand(right)
+	}
+	switch strings.ToLower(strings.TrimSpace(expr)) {
+	case "", "false", "0", "null", "<nil>":
+		return false
+	}
+	return true
+}
+
+func conditionOperand(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
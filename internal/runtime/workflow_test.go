@@ -0,0 +1,94 @@
+package runtime_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/logging"
+	"skyline-mcp/internal/redact"
+	"skyline-mcp/internal/runtime"
+)
+
+func TestExecutorWorkflow(t *testing.T) {
+	var messages []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/issues":
+			_, _ = io.WriteString(w, `{"key":"OPS-7","fields":{"labels":["urgent"]}}`)
+		case "/messages":
+			var body map[string]any
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			messages = append(messages, body)
+			_, _ = io.WriteString(w, `{"ok":true}`)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = io.WriteString(w, `{"error":"boom"}`)
+		}
+	}))
+	defer server.Close()
+
+	jsonBody := &canonical.RequestBody{ContentType: "application/json"}
+	createIssue := &canonical.Operation{ServiceName: "api", ID: "createIssue", ToolName: "api__createIssue", Method: "post", Path: "/issues", RequestBody: jsonBody}
+	postMessage := &canonical.Operation{ServiceName: "api", ID: "postMessage", ToolName: "api__postMessage", Method: "post", Path: "/messages", RequestBody: jsonBody}
+	broken := &canonical.Operation{ServiceName: "api", ID: "broken", ToolName: "api__broken", Method: "get", Path: "/broken"}
+	services := []*canonical.Service{
+		{Name: "api", BaseURL: server.URL, Operations: []*canonical.Operation{createIssue, postMessage, broken}},
+		{Name: config.WorkflowServiceName},
+	}
+
+	cfg := &config.Config{APIs: []config.APIConfig{{Name: "api", SpecURL: server.URL + "/openapi.json", BaseURLOverride: server.URL}}}
+	cfg.ApplyDefaults()
+	exec, err := runtime.NewExecutor(cfg, services, logging.Discard(), redact.NewRedactor())
+	if err != nil {
+		t.Fatalf("executor init failed: %v", err)
+	}
+
+	wf := &canonical.Operation{
+		ServiceName: config.WorkflowServiceName,
+		ID:          "file_incident",
+		ToolName:    "workflows__file_incident",
+		Workflow: &canonical.Workflow{
+			Steps: []canonical.WorkflowStep{
+				{ID: "issue", Operation: createIssue, Args: map[string]any{"body": map[string]any{"summary": "{{input.summary}}"}}},
+				{ID: "flaky", Operation: broken, ContinueOnError: true},
+				{ID: "notify", Operation: postMessage, When: "{{steps.issue.body.fields.labels[0]}} == 'urgent'",
+					Args: map[string]any{"body": map[string]any{"text": "Filed {{steps.issue.body.key}}: {{input.summary}}", "issue": "{{steps.issue.body}}"}}},
+				{ID: "skipped", Operation: postMessage, When: "{{input.page}}"},
+			},
+			Output: map[string]any{"key": "{{steps.issue.body.key}}", "notified": "{{steps.notify.status}}", "flaky": "{{steps.flaky.error}}"},
+		},
+	}
+	res, err := exec.Execute(context.Background(), wf, map[string]any{"summary": "disk full"})
+	if err != nil {
+		t.Fatalf("workflow failed: %v", err)
+	}
+	body, _ := res.Body.(map[string]any)
+	if body["key"] != "OPS-7" || body["notified"] != 200 {
+		t.Fatalf("unexpected output: %#v", res.Body)
+	}
+	if flaky, _ := body["flaky"].(string); !strings.Contains(flaky, "500") {
+		t.Fatalf("expected recorded step error, got %#v", body["flaky"])
+	}
+	if len(messages) != 1 {
+		t.Fatalf("expected one message (second step skipped), got %d", len(messages))
+	}
+	if messages[0]["text"] != "Filed OPS-7: disk full" {
+		t.Fatalf("unexpected message text: %v", messages[0]["text"])
+	}
+	if issue, _ := messages[0]["issue"].(map[string]any); issue["key"] != "OPS-7" {
+		t.Fatalf("expected whole-object substitution, got %#v", messages[0]["issue"])
+	}
+
+	wf.Workflow.Steps[1].ContinueOnError = false
+	if _, err := exec.Execute(context.Background(), wf, map[string]any{"summary": "x"}); err == nil || !strings.Contains(err.Error(), "step flaky") {
+		t.Fatalf("expected step failure, got %v", err)
+	}
+}
@@ -0,0 +1,166 @@
+package schemalearn
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+)
+
+const (
+	// maxDepth bounds how deep schemas describe nested values; deeper
+	// values are left unconstrained.
+	maxDepth = 8
+	// maxItems is how many elements of an array are inspected.
+	maxItems = 50
+	// maxProperties bounds the properties of one object schema, so maps
+	// keyed by IDs do not grow the schema with every response.
+	maxProperties = 100
+)
+
+// Infer returns a JSON schema describing v, a value decoded from JSON. It
+// records property names and types, never values, and marks nothing
+// required: later responses may omit what this one had.
+func Infer(v any) map[string]any {
+	return infer(v, 0)
+}
+
+func infer(v any, depth int) map[string]any {
+	if depth >= maxDepth {
+		return map[string]any{}
+	}
+	switch v := v.(type) {
+	case nil:
+		return map[string]any{"type": "null"}
+	case bool:
+		return map[string]any{"type": "boolean"}
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			return map[string]any{"type": "integer"}
+		}
+		return map[string]any{"type": "number"}
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return map[string]any{"type": "integer"}
+		}
+		return map[string]any{"type": "number"}
+	case int, int32, int64:
+		return map[string]any{"type": "integer"}
+	case string:
+		return map[string]any{"type": "string"}
+	case []any:
+		schema := map[string]any{"type": "array"}
+		var items map[string]any
+		for i, item := range v {
+			if i == maxItems {
+				break
+			}
+			items = merge(items, infer(item, depth+1))
+		}
+		if items != nil {
+			schema["items"] = items
+		}
+		return schema
+	case map[string]any:
+		if len(v) > maxProperties {
+			return map[string]any{"type": "object"}
+		}
+		props := make(map[string]any, len(v))
+		for key, value := range v {
+			props[key] = infer(value, depth+1)
+		}
+		return map[string]any{"type": "object", "properties": props}
+	default:
+		return map[string]any{}
+	}
+}
+
+// Merge returns a schema that accepts what either a or b accepts. Objects
+// merge their properties, arrays their items; other differing types are
+// listed together. Neither argument is modified.
+func Merge(a, b map[string]any) map[string]any {
+	return merge(a, b)
+}
+
+func merge(a, b map[string]any) map[string]any {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	typesA, typesB := schemaTypes(a), schemaTypes(b)
+	if len(typesA) == 0 || len(typesB) == 0 {
+		// An unconstrained schema accepts anything.
+		return map[string]any{}
+	}
+	types := map[string]bool{}
+	for _, t := range append(typesA, typesB...) {
+		types[t] = true
+	}
+	if types["number"] {
+		delete(types, "integer")
+	}
+
+	out := map[string]any{}
+	if types["object"] {
+		propsA, _ := a["properties"].(map[string]any)
+		propsB, _ := b["properties"].(map[string]any)
+		if propsA != nil || propsB != nil {
+			props := make(map[string]any, len(propsA)+len(propsB))
+			for key, schema := range propsA {
+				props[key] = schema
+			}
+			for key, schema := range propsB {
+				prev, _ := props[key].(map[string]any)
+				next, _ := schema.(map[string]any)
+				props[key] = merge(prev, next)
+			}
+			if len(props) <= maxProperties {
+				out["properties"] = props
+			}
+		}
+	}
+	if types["array"] {
+		itemsA, _ := a["items"].(map[string]any)
+		itemsB, _ := b["items"].(map[string]any)
+		if items := merge(itemsA, itemsB); items != nil {
+			out["items"] = items
+		}
+	}
+
+	names := make([]string, 0, len(types))
+	for t := range types {
+		names = append(names, t)
+	}
+	sort.Strings(names)
+	if len(names) == 1 {
+		out["type"] = names[0]
+	} else {
+		list := make([]any, len(names))
+		for i, t := range names {
+			list[i] = t
+		}
+		out["type"] = list
+	}
+	return out
+}
+
+// schemaTypes returns the types a schema names, or nil for one without a
+// type, which accepts anything.
+func schemaTypes(schema map[string]any) []string {
+	switch t := schema["type"].(type) {
+	case string:
+		return []string{t}
+	case []any:
+		out := make([]string, 0, len(t))
+		for _, name := range t {
+			if s, ok := name.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	case []string:
+		return t
+	}
+	return nil
+}
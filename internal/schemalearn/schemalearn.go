@@ -0,0 +1,236 @@
+// Package schemalearn infers the response schemas of operations whose specs
+// declare none from the responses they return. Schemas are learned from a
+// sample of successful responses, up to a number of samples per operation,
+// and kept in a Store so they survive restarts.
+package schemalearn
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"skyline-mcp/internal/config"
+)
+
+// Learned is the schema inferred for one operation.
+type Learned struct {
+	Schema    map[string]any `json:"schema"`
+	Samples   int            `json:"samples"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// Store keeps learned schemas per profile, keyed by operation.
+type Store interface {
+	LearnedSchemas(ctx context.Context, profile string) (map[string]Learned, error)
+	SaveLearnedSchema(ctx context.Context, profile, operation string, learned Learned) error
+}
+
+// MemoryStore is a Store for a single process; schemas are lost on restart.
+type MemoryStore struct {
+	mu      sync.Mutex
+	schemas map[string]map[string]Learned
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{schemas: map[string]map[string]Learned{}}
+}
+
+func (m *MemoryStore) LearnedSchemas(_ context.Context, profile string) (map[string]Learned, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make(map[string]Learned, len(m.schemas[profile]))
+	for op, learned := range m.schemas[profile] {
+		out[op] = learned
+	}
+	return out, nil
+}
+
+func (m *MemoryStore) SaveLearnedSchema(_ context.Context, profile, operation string, learned Learned) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.schemas[profile] == nil {
+		m.schemas[profile] = map[string]Learned{}
+	}
+	m.schemas[profile][operation] = learned
+	return nil
+}
+
+// FileStore is a Store backed by a JSON file, for the stdio transport which
+// has no database.
+type FileStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileStore returns a FileStore writing to path.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+func (f *FileStore) read() (map[string]map[string]Learned, error) {
+	data, err := os.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return map[string]map[string]Learned{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read learned schemas: %w", err)
+	}
+	all := map[string]map[string]Learned{}
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, fmt.Errorf("parse learned schemas %s: %w", f.path, err)
+	}
+	return all, nil
+}
+
+func (f *FileStore) LearnedSchemas(_ context.Context, profile string) (map[string]Learned, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	all, err := f.read()
+	if err != nil {
+		return nil, err
+	}
+	if all[profile] == nil {
+		return map[string]Learned{}, nil
+	}
+	return all[profile], nil
+}
+
+func (f *FileStore) SaveLearnedSchema(_ context.Context, profile, operation string, learned Learned) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	all, err := f.read()
+	if err != nil {
+		return err
+	}
+	if all[profile] == nil {
+		all[profile] = map[string]Learned{}
+	}
+	all[profile][operation] = learned
+	data, err := json.MarshalIndent(all, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Write a temporary file and rename it so a crash never leaves half
+	// a file behind.
+	tmp := f.path + ".tmp"
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+		return fmt.Errorf("write learned schemas: %w", err)
+	}
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("write learned schemas: %w", err)
+	}
+	return os.Rename(tmp, f.path)
+}
+
+const (
+	defaultSampleRate = 0.2
+	defaultMaxSamples = 20
+)
+
+// Learner learns the response schemas of one profile's operations.
+type Learner struct {
+	sampleRate float64
+	maxSamples int
+	logger     *slog.Logger
+
+	mu      sync.Mutex
+	store   Store
+	profile string
+	schemas map[string]Learned
+
+	// sample decides whether a response is inspected; replaced in tests.
+	sample func() bool
+}
+
+// New returns a Learner for cfg, or nil when cfg is nil. It keeps schemas
+// in cfg.File when set and in memory otherwise, until UseStore.
+func New(cfg *config.SchemaLearningConfig, logger *slog.Logger) *Learner {
+	if cfg == nil {
+		return nil
+	}
+	l := &Learner{
+		sampleRate: cfg.SampleRate,
+		maxSamples: cfg.MaxSamples,
+		logger:     logger,
+		store:      NewMemoryStore(),
+		schemas:    map[string]Learned{},
+	}
+	if l.sampleRate == 0 {
+		l.sampleRate = defaultSampleRate
+	}
+	if l.maxSamples == 0 {
+		l.maxSamples = defaultMaxSamples
+	}
+	l.sample = func() bool { return rand.Float64() < l.sampleRate }
+	if cfg.File != "" {
+		l.UseStore(NewFileStore(cfg.File), "")
+	}
+	return l
+}
+
+// UseStore keeps schemas in store under profile and loads those learned
+// before.
+func (l *Learner) UseStore(store Store, profile string) {
+	if l == nil {
+		return
+	}
+	schemas, err := store.LearnedSchemas(context.Background(), profile)
+	if err != nil {
+		l.logger.Warn("failed to load learned schemas", "component", "schemalearn", "profile", profile, "error", err)
+		schemas = map[string]Learned{}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.store, l.profile, l.schemas = store, profile, schemas
+}
+
+// Observe learns from body, the decoded response of operation, if the
+// response is sampled and the operation still needs samples. Only objects
+// and arrays are learned from.
+func (l *Learner) Observe(ctx context.Context, operation string, body any) {
+	if l == nil {
+		return
+	}
+	switch body.(type) {
+	case map[string]any, []any:
+	default:
+		return
+	}
+	l.mu.Lock()
+	learned := l.schemas[operation]
+	if learned.Samples >= l.maxSamples || !l.sample() {
+		l.mu.Unlock()
+		return
+	}
+	learned = Learned{
+		Schema:    Merge(learned.Schema, Infer(body)),
+		Samples:   learned.Samples + 1,
+		UpdatedAt: time.Now().UTC(),
+	}
+	l.schemas[operation] = learned
+	store, profile := l.store, l.profile
+	l.mu.Unlock()
+
+	if err := store.SaveLearnedSchema(ctx, profile, operation, learned); err != nil {
+		l.logger.Warn("failed to save learned schema", "component", "schemalearn", "operation", operation, "error", err)
+	}
+}
+
+// Schema returns what was learned about operation and whether anything
+// was.
+func (l *Learner) Schema(operation string) (Learned, bool) {
+	if l == nil {
+		return Learned{}, false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	learned, ok := l.schemas[operation]
+	return learned, ok && learned.Schema != nil
+}
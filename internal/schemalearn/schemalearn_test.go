@@ -0,0 +1,103 @@
+package schemalearn
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/logging"
+)
+
+func decode(t *testing.T, s string) any {
+	t.Helper()
+	var v any
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestInferAndMerge(t *testing.T) {
+	a := Infer(decode(t, `{"id": 1, "name": "Ada", "tags": ["x"], "owner": null}`))
+	b := Infer(decode(t, `{"id": 2.5, "email": "ada@example.com", "tags": [], "owner": {"id": 7}}`))
+	got := Merge(a, b)
+
+	want := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"id":    map[string]any{"type": "number"},
+			"name":  map[string]any{"type": "string"},
+			"email": map[string]any{"type": "string"},
+			"tags":  map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+			"owner": map[string]any{
+				"type":       []any{"null", "object"},
+				"properties": map[string]any{"id": map[string]any{"type": "integer"}},
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		gotJSON, _ := json.Marshal(got)
+		t.Fatalf("merged schema = %s", gotJSON)
+	}
+	if _, ok := a["properties"].(map[string]any)["email"]; ok {
+		t.Fatal("Merge modified its argument")
+	}
+}
+
+func TestLearnerStopsAfterMaxSamples(t *testing.T) {
+	l := New(&config.SchemaLearningConfig{MaxSamples: 2}, logging.Discard())
+	l.sample = func() bool { return true }
+	store := NewMemoryStore()
+	l.UseStore(store, "crm")
+	ctx := context.Background()
+
+	l.Observe(ctx, "crm/getContact", decode(t, `{"id": 1}`))
+	l.Observe(ctx, "crm/getContact", decode(t, `{"name": "Ada"}`))
+	l.Observe(ctx, "crm/getContact", decode(t, `{"phone": "555"}`))
+	l.Observe(ctx, "crm/getContact", "plain text")
+
+	learned, ok := l.Schema("crm/getContact")
+	if !ok || learned.Samples != 2 {
+		t.Fatalf("learned = %+v, want 2 samples", learned)
+	}
+	if _, ok := learned.Schema["properties"].(map[string]any)["phone"]; ok {
+		t.Fatal("learned from a response after max_samples")
+	}
+	saved, _ := store.LearnedSchemas(ctx, "crm")
+	if saved["crm/getContact"].Samples != 2 {
+		t.Fatalf("saved = %+v", saved)
+	}
+
+	// A new learner for the profile picks up where this one stopped.
+	next := New(&config.SchemaLearningConfig{}, logging.Discard())
+	next.UseStore(store, "crm")
+	if learned, ok := next.Schema("crm/getContact"); !ok || learned.Samples != 2 {
+		t.Fatalf("reloaded = %+v, %v", learned, ok)
+	}
+
+	var off *Learner
+	off.Observe(ctx, "crm/getContact", decode(t, `{"id": 1}`))
+	if _, ok := off.Schema("crm/getContact"); ok {
+		t.Fatal("nil learner learned a schema")
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schemas", "learned.json")
+	ctx := context.Background()
+	store := NewFileStore(path)
+	if got, err := store.LearnedSchemas(ctx, ""); err != nil || len(got) != 0 {
+		t.Fatalf("missing file: %v, %v", got, err)
+	}
+	learned := Learned{Schema: map[string]any{"type": "object"}, Samples: 3}
+	if err := store.SaveLearnedSchema(ctx, "", "crm/getContact", learned); err != nil {
+		t.Fatal(err)
+	}
+	got, err := NewFileStore(path).LearnedSchemas(ctx, "")
+	if err != nil || got["crm/getContact"].Samples != 3 || got["crm/getContact"].Schema["type"] != "object" {
+		t.Fatalf("reloaded = %+v, %v", got, err)
+	}
+}
@@ -0,0 +1,335 @@
+// Package selfupdate finds Skyline releases on GitHub for an update channel
+// and verifies the checksums and signature published with them.
+package selfupdate
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Update channels.
+const (
+	ChannelStable = "stable" // published releases only
+	ChannelBeta   = "beta"   // pre-releases too
+)
+
+const (
+	// ChecksumsAsset lists the SHA-256 of every other asset of a release,
+	// in sha256sum format.
+	ChecksumsAsset = "checksums.txt"
+	// SignatureAsset is the base64 Ed25519 signature of ChecksumsAsset.
+	SignatureAsset = "checksums.txt.sig"
+)
+
+// DefaultRepo is the GitHub repository releases are published in.
+const DefaultRepo = "emadomedher/skyline-mcp"
+
+// Release is a GitHub release.
+type Release struct {
+	TagName     string    `json:"tag_name"`
+	Prerelease  bool      `json:"prerelease"`
+	Draft       bool      `json:"draft"`
+	PublishedAt time.Time `json:"published_at"`
+	HTMLURL     string    `json:"html_url"`
+	Assets      []Asset   `json:"assets"`
+}
+
+// Asset is a file attached to a release.
+type Asset struct {
+	Name               string `json:"name"`
+	BrowserDownloadURL string `json:"browser_download_url"`
+}
+
+// Asset returns the asset called name, or nil.
+func (r *Release) Asset(name string) *Asset {
+	for i := range r.Assets {
+		if r.Assets[i].Name == name {
+			return &r.Assets[i]
+		}
+	}
+	return nil
+}
+
+// PlatformAsset returns the binary archive for goos/goarch, such as
+// skyline-v1.2.0-linux-amd64.tar.gz, falling back to the bare binaries of
+// older releases (skyline-linux-amd64).
+func (r *Release) PlatformAsset(goos, goarch string) *Asset {
+	platform := goos + "-" + goarch
+	for i := range r.Assets {
+		name := r.Assets[i].Name
+		if strings.Contains(name, platform) && (strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".zip")) {
+			return &r.Assets[i]
+		}
+	}
+	bare := "skyline-" + platform
+	if goos == "windows" {
+		bare += ".exe"
+	}
+	return r.Asset(bare)
+}
+
+// Client looks up releases through the GitHub REST API.
+type Client struct {
+	HTTP    *http.Client
+	APIBase string // default https://api.github.com
+	Repo    string // default DefaultRepo
+}
+
+// Latest returns the newest release of channel: the latest published
+// release for stable, the highest version including pre-releases for beta.
+func (c *Client) Latest(ctx context.Context, channel string) (*Release, error) {
+	switch channel {
+	case "", ChannelStable:
+		var release Release
+		if err := c.get(ctx, "/releases/latest", &release); err != nil {
+			return nil, err
+		}
+		return &release, nil
+	case ChannelBeta:
+		var releases []Release
+		if err := c.get(ctx, "/releases?per_page=30", &releases); err != nil {
+			return nil, err
+		}
+		var latest *Release
+		for i := range releases {
+			r := &releases[i]
+			if r.Draft {
+				continue
+			}
+			if latest == nil || CompareVersions(r.TagName, latest.TagName) > 0 {
+				latest = r
+			}
+		}
+		if latest == nil {
+			return nil, fmt.Errorf("no releases found")
+		}
+		return latest, nil
+	}
+	return nil, fmt.Errorf("unknown channel %q (expected %s or %s)", channel, ChannelStable, ChannelBeta)
+}
+
+func (c *Client) get(ctx context.Context, path string, out any) error {
+	base, repo := c.APIBase, c.Repo
+	if base == "" {
+		base = "https://api.github.com"
+	}
+	if repo == "" {
+		repo = DefaultRepo
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(base, "/")+"/repos/"+repo+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := c.client().Do(req)
+	if err != nil {
+		return fmt.Errorf("fetch release info: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("github API returned %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode release info: %w", err)
+	}
+	return nil
+}
+
+// Download writes the asset to w.
+func (c *Client) Download(ctx context.Context, asset *Asset, w io.Writer) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, asset.BrowserDownloadURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.client().Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("download %s: %w", asset.Name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("download %s: status %d", asset.Name, resp.StatusCode)
+	}
+	return io.Copy(w, resp.Body)
+}
+
+func (c *Client) client() *http.Client {
+	if c.HTTP != nil {
+		return c.HTTP
+	}
+	return http.DefaultClient
+}
+
+// Status compares the running version with the newest release of a
+// channel.
+type Status struct {
+	Current         string    `json:"current"`
+	Channel         string    `json:"channel"`
+	Latest          string    `json:"latest"`
+	UpdateAvailable bool      `json:"update_available"`
+	ReleaseURL      string    `json:"release_url,omitempty"`
+	PublishedAt     time.Time `json:"published_at,omitzero"`
+	CheckedAt       time.Time `json:"checked_at"`
+}
+
+// Check looks up the newest release of channel and whether it is newer
+// than current.
+func (c *Client) Check(ctx context.Context, current, channel string) (*Status, *Release, error) {
+	if channel == "" {
+		channel = ChannelStable
+	}
+	release, err := c.Latest(ctx, channel)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &Status{
+		Current:         current,
+		Channel:         channel,
+		Latest:          release.TagName,
+		UpdateAvailable: CompareVersions(release.TagName, current) > 0,
+		ReleaseURL:      release.HTMLURL,
+		PublishedAt:     release.PublishedAt,
+		CheckedAt:       time.Now(),
+	}, release, nil
+}
+
+// CompareVersions compares two semantic versions such as v1.2.0 and
+// 1.3.0-beta.1, returning -1, 0 or 1. A pre-release sorts before its
+// release. A version that does not parse, such as "dev", sorts before
+// every version that does.
+func CompareVersions(a, b string) int {
+	va, okA := parseVersion(a)
+	vb, okB := parseVersion(b)
+	switch {
+	case !okA && !okB:
+		return strings.Compare(a, b)
+	case !okA:
+		return -1
+	case !okB:
+		return 1
+	}
+	for i := range 3 {
+		if va.core[i] != vb.core[i] {
+			if va.core[i] < vb.core[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case va.pre == "" && vb.pre == "":
+		return 0
+	case va.pre == "":
+		return 1
+	case vb.pre == "":
+		return -1
+	}
+	return comparePrerelease(va.pre, vb.pre)
+}
+
+type version struct {
+	core [3]int
+	pre  string
+}
+
+func parseVersion(s string) (version, bool) {
+	s = strings.TrimPrefix(s, "v")
+	s, _, _ = strings.Cut(s, "+") // build metadata does not order
+	core, pre, _ := strings.Cut(s, "-")
+	parts := strings.Split(core, ".")
+	if len(parts) == 0 || len(parts) > 3 {
+		return version{}, false
+	}
+	var v version
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return version{}, false
+		}
+		v.core[i] = n
+	}
+	v.pre = pre
+	return v, true
+}
+
+// comparePrerelease orders dot-separated pre-release identifiers as
+// semver does: numeric ones numerically and below alphanumeric ones.
+func comparePrerelease(a, b string) int {
+	pa, pb := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		na, errA := strconv.Atoi(pa[i])
+		nb, errB := strconv.Atoi(pb[i])
+		switch {
+		case errA == nil && errB == nil:
+			if na != nb {
+				if na < nb {
+					return -1
+				}
+				return 1
+			}
+		case errA == nil:
+			return -1
+		case errB == nil:
+			return 1
+		default:
+			if c := strings.Compare(pa[i], pb[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	switch {
+	case len(pa) < len(pb):
+		return -1
+	case len(pa) > len(pb):
+		return 1
+	}
+	return 0
+}
+
+// VerifyChecksum checks sum, the SHA-256 of the asset called name, against
+// a checksums file in sha256sum format.
+func VerifyChecksum(checksums []byte, name string, sum []byte) error {
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || strings.TrimPrefix(fields[1], "*") != name {
+			continue
+		}
+		want, err := hex.DecodeString(fields[0])
+		if err != nil {
+			return fmt.Errorf("%s: malformed checksum for %s", ChecksumsAsset, name)
+		}
+		if !bytes.Equal(want, sum) {
+			return fmt.Errorf("checksum mismatch for %s: got %x, want %x", name, sum, want)
+		}
+		return nil
+	}
+	return fmt.Errorf("%s has no checksum for %s", ChecksumsAsset, name)
+}
+
+// VerifySignature checks signature, a base64 Ed25519 signature, of message
+// against publicKey, a base64 Ed25519 public key.
+func VerifySignature(publicKey string, message, signature []byte) error {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(publicKey))
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid release public key")
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil {
+		return fmt.Errorf("%s: %w", SignatureAsset, err)
+	}
+	if !ed25519.Verify(ed25519.PublicKey(key), message, sig) {
+		return fmt.Errorf("%s does not match %s", SignatureAsset, ChecksumsAsset)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package selfupdate
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"v1.2.0", "v1.2.0", 0},
+		{"v1.2.0", "1.2.0", 0},
+		{"v1.10.0", "v1.9.3", 1},
+		{"v1.2", "v1.2.1", -1},
+		{"v1.3.0-beta.1", "v1.2.9", 1},
+		{"v1.3.0-beta.1", "v1.3.0", -1},
+		{"v1.3.0-beta.2", "v1.3.0-beta.10", -1},
+		{"v1.3.0-alpha", "v1.3.0-beta", -1},
+		{"v1.3.0-rc.1", "v1.3.0-rc.1.1", -1},
+		{"v1.3.0+build.5", "v1.3.0", 0},
+		{"v0.0.1", "vdev", 1},
+		{"dev", "v0.0.1", -1},
+	}
+	for _, tt := range tests {
+		if got := CompareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestLatestByChannel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/acme/skyline/releases/latest":
+			fmt.Fprint(w, `{"tag_name":"v1.2.0"}`)
+		case "/repos/acme/skyline/releases":
+			fmt.Fprint(w, `[
+				{"tag_name":"v1.4.0","draft":true},
+				{"tag_name":"v1.2.0"},
+				{"tag_name":"v1.3.0-beta.2","prerelease":true},
+				{"tag_name":"v1.3.0-beta.1","prerelease":true}
+			]`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	c := &Client{APIBase: srv.URL, Repo: "acme/skyline"}
+
+	for channel, want := range map[string]string{ChannelStable: "v1.2.0", ChannelBeta: "v1.3.0-beta.2"} {
+		release, err := c.Latest(context.Background(), channel)
+		if err != nil {
+			t.Fatalf("%s: %v", channel, err)
+		}
+		if release.TagName != want {
+			t.Errorf("%s: latest = %s, want %s", channel, release.TagName, want)
+		}
+	}
+	if _, err := c.Latest(context.Background(), "nightly"); err == nil {
+		t.Error("unknown channel accepted")
+	}
+
+	status, _, err := c.Check(context.Background(), "v1.2.0", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.UpdateAvailable || status.Channel != ChannelStable {
+		t.Errorf("status = %+v, want up to date on stable", status)
+	}
+	status, _, err = c.Check(context.Background(), "v1.2.0", ChannelBeta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.UpdateAvailable || status.Latest != "v1.3.0-beta.2" {
+		t.Errorf("status = %+v, want the beta", status)
+	}
+}
+
+func TestPlatformAsset(t *testing.T) {
+	r := &Release{Assets: []Asset{
+		{Name: "checksums.txt"},
+		{Name: "skyline-v1.2.0-linux-amd64.tar.gz"},
+		{Name: "skyline-v1.2.0-windows-amd64.zip"},
+		{Name: "skyline-darwin-arm64"},
+	}}
+	for platform, want := range map[string]string{
+		"linux/amd64":   "skyline-v1.2.0-linux-amd64.tar.gz",
+		"windows/amd64": "skyline-v1.2.0-windows-amd64.zip",
+		"darwin/arm64":  "skyline-darwin-arm64",
+	} {
+		goos, goarch, _ := strings.Cut(platform, "/")
+		if a := r.PlatformAsset(goos, goarch); a == nil || a.Name != want {
+			t.Errorf("%s: asset = %+v, want %s", platform, a, want)
+		}
+	}
+	if a := r.PlatformAsset("linux", "arm64"); a != nil {
+		t.Errorf("linux/arm64: asset = %+v, want none", a)
+	}
+}
+
+func TestVerifyChecksumAndSignature(t *testing.T) {
+	archive := []byte("binary contents")
+	sum := sha256.Sum256(archive)
+	checksums := []byte(fmt.Sprintf("%x  skyline-v1.2.0-linux-amd64.tar.gz\n%x *skyline-v1.2.0-darwin-arm64.tar.gz\n", sum, sha256.Sum256([]byte("other"))))
+
+	if err := VerifyChecksum(checksums, "skyline-v1.2.0-linux-amd64.tar.gz", sum[:]); err != nil {
+		t.Errorf("valid checksum: %v", err)
+	}
+	if err := VerifyChecksum(checksums, "skyline-v1.2.0-darwin-arm64.tar.gz", sum[:]); err == nil || !strings.Contains(err.Error(), "mismatch") {
+		t.Errorf("tampered asset: %v", err)
+	}
+	if err := VerifyChecksum(checksums, "skyline-v1.2.0-linux-arm64.tar.gz", sum[:]); err == nil {
+		t.Error("asset without checksum accepted")
+	}
+
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := base64.StdEncoding.EncodeToString(pub)
+	sig := []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(priv, checksums)) + "\n")
+	if err := VerifySignature(key, checksums, sig); err != nil {
+		t.Errorf("valid signature: %v", err)
+	}
+	if err := VerifySignature(key, append(checksums, '\n'), sig); err == nil {
+		t.Error("signature over other checksums accepted")
+	}
+	if err := VerifySignature("bm90IGEga2V5", checksums, sig); err == nil {
+		t.Error("malformed public key accepted")
+	}
+}
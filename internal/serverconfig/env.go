@@ -0,0 +1,135 @@
+package serverconfig
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// envOverride maps an environment variable to the config field it sets.
+// field returns a pointer to the field, allocating optional sections; it
+// is only called when the variable is set.
+type envOverride struct {
+	name  string
+	field func(c *ServerConfig) any
+}
+
+// envOverrides lists every setting that can come from the environment, so
+// containers can tweak the config without mounting a file.
+var envOverrides = []envOverride{
+	{"SKYLINE_SERVER_LISTEN", func(c *ServerConfig) any { return &c.Server.Listen }},
+	{"SKYLINE_SERVER_TIMEOUT", func(c *ServerConfig) any { return &c.Server.Timeout }},
+	{"SKYLINE_SERVER_MAX_REQUEST_SIZE", func(c *ServerConfig) any { return &c.Server.MaxRequestSize }},
+	{"SKYLINE_SERVER_ADMIN_TOKEN", func(c *ServerConfig) any { return &c.Server.AdminToken }},
+	{"SKYLINE_SERVER_ADMIN_PASSWORD_HASH", func(c *ServerConfig) any { return &c.Server.AdminPasswordHash }},
+	{"SKYLINE_SERVER_ADMIN_SESSION_TTL", func(c *ServerConfig) any { return &c.Server.AdminSessionTTL }},
+	{"SKYLINE_SERVER_PUBLIC_URL", func(c *ServerConfig) any { return &c.Server.PublicURL }},
+	{"SKYLINE_SERVER_PROFILE_FILES_DIR", func(c *ServerConfig) any { return &c.Server.ProfileFilesDir }},
+	{"SKYLINE_TLS_CERT", func(c *ServerConfig) any { return &c.tls().Cert }},
+	{"SKYLINE_TLS_KEY", func(c *ServerConfig) any { return &c.tls().Key }},
+
+	{"SKYLINE_CODE_EXECUTION_ENABLED", func(c *ServerConfig) any { return &c.Runtime.CodeExecution.Enabled }},
+	{"SKYLINE_CODE_EXECUTION_ENGINE", func(c *ServerConfig) any { return &c.Runtime.CodeExecution.Engine }},
+	{"SKYLINE_CODE_EXECUTION_DENO_PATH", func(c *ServerConfig) any { return &c.Runtime.CodeExecution.DenoPath }},
+	{"SKYLINE_CODE_EXECUTION_TIMEOUT", func(c *ServerConfig) any { return &c.Runtime.CodeExecution.Timeout }},
+	{"SKYLINE_CODE_EXECUTION_MEMORY_LIMIT", func(c *ServerConfig) any { return &c.Runtime.CodeExecution.MemoryLimit }},
+	{"SKYLINE_CODE_EXECUTION_CPU_TIME", func(c *ServerConfig) any { return &c.Runtime.CodeExecution.CPUTime }},
+	{"SKYLINE_CODE_EXECUTION_ALLOWED_HOSTS", func(c *ServerConfig) any { return &c.Runtime.CodeExecution.AllowedHosts }},
+	{"SKYLINE_CACHE_ENABLED", func(c *ServerConfig) any { return &c.Runtime.Cache.Enabled }},
+	{"SKYLINE_CACHE_TTL", func(c *ServerConfig) any { return &c.Runtime.Cache.TTL }},
+	{"SKYLINE_CACHE_MAX_SIZE", func(c *ServerConfig) any { return &c.Runtime.Cache.MaxSize }},
+	{"SKYLINE_CACHE_REFRESH_INTERVAL", func(c *ServerConfig) any { return &c.Runtime.Cache.RefreshInterval }},
+
+	{"SKYLINE_AUDIT_ENABLED", func(c *ServerConfig) any { return &c.Audit.Enabled }},
+	{"SKYLINE_AUDIT_DATABASE", func(c *ServerConfig) any { return &c.Audit.Database }},
+	{"SKYLINE_AUDIT_ROTATE_AFTER", func(c *ServerConfig) any { return &c.Audit.RotateAfter }},
+	{"SKYLINE_AUDIT_MAX_SIZE", func(c *ServerConfig) any { return &c.Audit.MaxSize }},
+	{"SKYLINE_AUDIT_SIGNING_KEY", func(c *ServerConfig) any { return &c.Audit.SigningKey }},
+
+	{"SKYLINE_PROFILES_BACKEND", func(c *ServerConfig) any { return &c.Profiles.Backend }},
+	{"SKYLINE_PROFILES_STORAGE", func(c *ServerConfig) any { return &c.Profiles.Storage }},
+	{"SKYLINE_PROFILES_DATABASE", func(c *ServerConfig) any { return &c.Profiles.Database }},
+
+	{"SKYLINE_METRICS_TOKEN", func(c *ServerConfig) any { return &c.Security.MetricsToken }},
+	{"SKYLINE_CORS_ORIGINS", func(c *ServerConfig) any { return &c.cors().Origins }},
+	{"SKYLINE_METRICS_REMOTE_WRITE_ENDPOINT", func(c *ServerConfig) any { return &c.remoteWrite().Endpoint }},
+	{"SKYLINE_METRICS_REMOTE_WRITE_INTERVAL", func(c *ServerConfig) any { return &c.remoteWrite().Interval }},
+	{"SKYLINE_METRICS_REMOTE_WRITE_USERNAME", func(c *ServerConfig) any { return &c.remoteWrite().Username }},
+	{"SKYLINE_METRICS_REMOTE_WRITE_PASSWORD", func(c *ServerConfig) any { return &c.remoteWrite().Password }},
+
+	{"SKYLINE_CLUSTER_REDIS", func(c *ServerConfig) any { return &c.Cluster.Redis }},
+	{"SKYLINE_CLUSTER_KEY_PREFIX", func(c *ServerConfig) any { return &c.Cluster.KeyPrefix }},
+	{"SKYLINE_CLUSTER_NODE_URL", func(c *ServerConfig) any { return &c.Cluster.NodeURL }},
+	{"SKYLINE_CLUSTER_INSECURE_SKIP_VERIFY", func(c *ServerConfig) any { return &c.Cluster.InsecureSkipVerify }},
+
+	{"SKYLINE_UPDATES_CHANNEL", func(c *ServerConfig) any { return &c.Updates.Channel }},
+	{"SKYLINE_UPDATES_CHECK_INTERVAL", func(c *ServerConfig) any { return &c.Updates.CheckInterval }},
+
+	{"SKYLINE_LOG_LEVEL", func(c *ServerConfig) any { return &c.Logging.Level }},
+	{"SKYLINE_LOG_FORMAT", func(c *ServerConfig) any { return &c.Logging.Format }},
+	{"SKYLINE_LOG_OUTPUT", func(c *ServerConfig) any { return &c.Logging.Output }},
+}
+
+func (c *ServerConfig) tls() *TLSConfig {
+	if c.Server.TLS == nil {
+		c.Server.TLS = &TLSConfig{}
+	}
+	return c.Server.TLS
+}
+
+func (c *ServerConfig) cors() *CORSConfig {
+	if c.Security.CORS == nil {
+		c.Security.CORS = &CORSConfig{}
+	}
+	// Listing origins in the environment turns CORS on.
+	c.Security.CORS.Enabled = true
+	return c.Security.CORS
+}
+
+func (c *ServerConfig) remoteWrite() *RemoteWriteConfig {
+	if c.Metrics.RemoteWrite == nil {
+		c.Metrics.RemoteWrite = &RemoteWriteConfig{}
+	}
+	return c.Metrics.RemoteWrite
+}
+
+// ApplyEnv overrides config fields from environment variables such as
+// SKYLINE_SERVER_LISTEN or SKYLINE_LOG_LEVEL, looked up with lookup
+// (os.LookupEnv in production). Unset and empty variables leave the field
+// alone. Durations use Go syntax ("30s", "1h"), booleans strconv syntax
+// and lists are comma-separated.
+func (c *ServerConfig) ApplyEnv(lookup func(string) (string, bool)) error {
+	for _, o := range envOverrides {
+		raw, ok := lookup(o.name)
+		raw = strings.TrimSpace(raw)
+		if !ok || raw == "" {
+			continue
+		}
+		switch field := o.field(c).(type) {
+		case *string:
+			*field = raw
+		case *bool:
+			v, err := strconv.ParseBool(raw)
+			if err != nil {
+				return fmt.Errorf("%s: invalid boolean %q", o.name, raw)
+			}
+			*field = v
+		case *time.Duration:
+			v, err := time.ParseDuration(raw)
+			if err != nil {
+				return fmt.Errorf("%s: invalid duration %q", o.name, raw)
+			}
+			*field = v
+		case *[]string:
+			var items []string
+			for _, item := range strings.Split(raw, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+			*field = items
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package serverconfig
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func lookupMap(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	cfg := Default()
+	err := cfg.ApplyEnv(lookupMap(map[string]string{
+		"SKYLINE_SERVER_LISTEN":                "0.0.0.0:9000",
+		"SKYLINE_SERVER_TIMEOUT":               "45s",
+		"SKYLINE_TLS_CERT":                     "/certs/tls.crt",
+		"SKYLINE_CODE_EXECUTION_ENABLED":       "false",
+		"SKYLINE_CODE_EXECUTION_ALLOWED_HOSTS": "api.example.com, *.example.org,",
+		"SKYLINE_AUDIT_DATABASE":               "/data/audit.db",
+		"SKYLINE_CORS_ORIGINS":                 "https://app.example.com",
+		"SKYLINE_LOG_LEVEL":                    "debug",
+		"SKYLINE_LOG_FORMAT":                   "",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Server.Listen != "0.0.0.0:9000" || cfg.Server.Timeout != 45*time.Second {
+		t.Fatalf("server = %+v", cfg.Server)
+	}
+	if cfg.Server.TLS == nil || cfg.Server.TLS.Cert != "/certs/tls.crt" || cfg.Server.TLS.Key != "" {
+		t.Fatalf("tls = %+v", cfg.Server.TLS)
+	}
+	if cfg.Runtime.CodeExecution.Enabled {
+		t.Fatal("code execution should be disabled")
+	}
+	if want := []string{"api.example.com", "*.example.org"}; !reflect.DeepEqual(cfg.Runtime.CodeExecution.AllowedHosts, want) {
+		t.Fatalf("allowed hosts = %q, want %q", cfg.Runtime.CodeExecution.AllowedHosts, want)
+	}
+	if cfg.Audit.Database != "/data/audit.db" {
+		t.Fatalf("audit database = %q", cfg.Audit.Database)
+	}
+	if cfg.Security.CORS == nil || !cfg.Security.CORS.Enabled || len(cfg.Security.CORS.Origins) != 1 {
+		t.Fatalf("cors = %+v", cfg.Security.CORS)
+	}
+	// Empty variables leave the value alone.
+	if cfg.Logging.Level != "debug" || cfg.Logging.Format != "json" {
+		t.Fatalf("logging = %+v", cfg.Logging)
+	}
+	// Unset optional sections stay unset.
+	if cfg.Metrics.RemoteWrite != nil {
+		t.Fatalf("remote write = %+v", cfg.Metrics.RemoteWrite)
+	}
+}
+
+func TestApplyEnvInvalid(t *testing.T) {
+	for name, value := range map[string]string{
+		"SKYLINE_SERVER_TIMEOUT": "30",
+		"SKYLINE_AUDIT_ENABLED":  "sometimes",
+	} {
+		err := Default().ApplyEnv(lookupMap(map[string]string{name: value}))
+		if err == nil {
+			t.Errorf("%s=%q: expected an error", name, value)
+		}
+	}
+}
+
+func TestLoadAppliesEnvAfterFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := "server:\n  listen: localhost:8191\ncluster:\n  nodeURL: https://10.0.0.5:8191\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SKYLINE_SERVER_LISTEN", "0.0.0.0:8191")
+	t.Setenv("SKYLINE_CLUSTER_REDIS", "redis://redis:6379/0")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Server.Listen != "0.0.0.0:8191" {
+		t.Fatalf("listen = %q", cfg.Server.Listen)
+	}
+	// Defaults that depend on overridden fields still apply.
+	if cfg.Cluster.Redis != "redis://redis:6379/0" || cfg.Cluster.KeyPrefix != "skyline:" || cfg.Cluster.NodeURL != "https://10.0.0.5:8191" {
+		t.Fatalf("cluster = %+v", cfg.Cluster)
+	}
+
+	t.Setenv("SKYLINE_SERVER_TIMEOUT", "soon")
+	if _, err := Load(path); err == nil {
+		t.Fatal("expected an error for an invalid duration")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -19,6 +20,116 @@ type ServerConfig struct {
 	Security SecuritySection `yaml:"security"`
 	Logging  LoggingSection  `yaml:"logging"`
 	Metrics  MetricsSection  `yaml:"metrics"`
+	Cluster  ClusterSection  `yaml:"cluster,omitempty"`
+	Detect   DetectSection   `yaml:"detect,omitempty"`
+	Alerts   AlertsSection   `yaml:"alerts,omitempty"`
+	Updates  UpdatesSection  `yaml:"updates,omitempty"`
+	Plugins  []PluginConfig  `yaml:"plugins,omitempty"`
+}
+
+// PluginConfig registers an external program that adds spec formats or
+// protocols. Skyline runs Command once per call, writing one JSON request
+// to its stdin and reading one JSON response from its stdout.
+type PluginConfig struct {
+	Name    string            `yaml:"name"`
+	Command string            `yaml:"command"`
+	Args    []string          `yaml:"args,omitempty"`
+	Env     map[string]string `yaml:"env,omitempty"` // added to Skyline's environment
+	// Timeout bounds each call to the plugin; default 30s.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+// UpdatesSection configures the release check behind /admin/version.
+type UpdatesSection struct {
+	// Channel is "stable" (default) for published releases or "beta" to
+	// include pre-releases.
+	Channel string `yaml:"channel,omitempty"`
+	// CheckInterval is how long a release check is reused; default 1h.
+	CheckInterval time.Duration `yaml:"checkInterval,omitempty"`
+}
+
+// AlertsSection sends notifications to webhooks when an API's error rate
+// climbs, its circuit breaker opens, or a client keeps failing to
+// authenticate. Alerting is off without webhooks.
+type AlertsSection struct {
+	Webhooks  []AlertWebhook  `yaml:"webhooks,omitempty"`
+	ErrorRate *ErrorRateAlert `yaml:"errorRate,omitempty"`
+	// BreakerOpen alerts when a circuit breaker opens; default true.
+	BreakerOpen  *bool             `yaml:"breakerOpen,omitempty"`
+	AuthFailures *AuthFailureAlert `yaml:"authFailures,omitempty"`
+	// Cooldown is the least time between two alerts of one kind about the
+	// same API or client; default 15m.
+	Cooldown time.Duration `yaml:"cooldown,omitempty"`
+}
+
+// AlertWebhook is where alerts are POSTed.
+type AlertWebhook struct {
+	URL string `yaml:"url"`
+	// Format is "json" (default), the alert as a JSON object, or "slack",
+	// a message for a Slack incoming webhook.
+	Format  string            `yaml:"format,omitempty"`
+	Headers map[string]string `yaml:"headers,omitempty"`
+	// Kinds limits the webhook to some alerts: error_rate, breaker_open or
+	// auth_failures. Empty sends every alert.
+	Kinds []string `yaml:"kinds,omitempty"`
+}
+
+// ErrorRateAlert fires when the share of failed calls to an API within
+// Window reaches Threshold percent.
+type ErrorRateAlert struct {
+	Threshold float64       `yaml:"threshold"`
+	Window    time.Duration `yaml:"window,omitempty"`   // default 5m
+	MinCalls  int           `yaml:"minCalls,omitempty"` // calls in the window before the rate counts; default 20
+}
+
+// AuthFailureAlert fires when one client fails to authenticate to a
+// profile Threshold times within Window.
+type AuthFailureAlert struct {
+	Threshold int           `yaml:"threshold,omitempty"` // default 10
+	Window    time.Duration `yaml:"window,omitempty"`    // default 5m
+}
+
+// DetectSection adds probes to the built-in ones /detect tries against a
+// base URL to find its spec.
+type DetectSection struct {
+	Probes []DetectProbe `yaml:"probes,omitempty"`
+	// PluginsDir holds YAML or JSON files with more probes, each listing
+	// them under a top-level probes key.
+	PluginsDir string `yaml:"pluginsDir,omitempty"`
+}
+
+// DetectProbe is a request /detect sends to the base URL plus Path.
+type DetectProbe struct {
+	// Type is the spec type reported when the probe matches, e.g. openapi.
+	Type    string            `yaml:"type"`
+	Path    string            `yaml:"path"`
+	Method  string            `yaml:"method,omitempty"` // default GET
+	Body    string            `yaml:"body,omitempty"`
+	Headers map[string]string `yaml:"headers,omitempty"`
+	// Match decides whether a 2xx response is the spec: empty uses the
+	// built-in detector for Type, "status" accepts any 2xx response, and
+	// "contains:<text>" and "regex:<pattern>" test the body.
+	Match string `yaml:"match,omitempty"`
+	// AllowUnauth reports a 401 as found: the server exists but needs
+	// credentials.
+	AllowUnauth bool `yaml:"allowUnauth,omitempty"`
+	// BaseContains limits the probe to base URLs containing this text;
+	// such probes are tried first.
+	BaseContains string `yaml:"baseContains,omitempty"`
+}
+
+// ClusterSection enables distributed mode: several replicas behind a load
+// balancer share rate limits, circuit breaker state and MCP session routing
+// through Redis. Leave Redis empty for single-node mode.
+type ClusterSection struct {
+	Redis     string `yaml:"redis,omitempty"`     // redis://[:password@]host:port[/db] or rediss://
+	KeyPrefix string `yaml:"keyPrefix,omitempty"` // default "skyline:"
+	// NodeURL is the address other replicas use to reach this node when an
+	// MCP session created here is routed to them, e.g. https://10.0.0.5:8191.
+	NodeURL string `yaml:"nodeURL,omitempty"`
+	// InsecureSkipVerify disables TLS verification for node-to-node proxying
+	// (needed with the auto-generated self-signed certificates).
+	InsecureSkipVerify bool `yaml:"insecureSkipVerify,omitempty"`
 }
 
 type MetricsSection struct {
@@ -38,6 +149,22 @@ type ServerSection struct {
 	MaxRequestSize string        `yaml:"maxRequestSize,omitempty"`
 	TLS            *TLSConfig    `yaml:"tls,omitempty"`
 	AdminToken     string        `yaml:"adminToken,omitempty"`
+	// AdminPasswordHash is a bcrypt hash (skyline hash-password) of the
+	// admin UI password. When set, the UI signs in with the password, and
+	// profile management, /detect and the other setup endpoints require an
+	// admin session or a profile or tenant token.
+	AdminPasswordHash string `yaml:"adminPasswordHash,omitempty"`
+	// AdminSessionTTL is how long an admin session lasts; default 8h.
+	AdminSessionTTL time.Duration `yaml:"adminSessionTTL,omitempty"`
+	// PublicURL is the address clients reach the server at, such as
+	// https://skyline.example.com. Links the server hands out, like
+	// profile share links, start with it.
+	PublicURL string `yaml:"publicURL,omitempty"`
+	// ProfileFilesDir is the directory stored profiles may keep local files
+	// in: specs, .http files, SQLite databases, SSH keys, recordings and
+	// schema files. Tenant profiles are limited to <dir>/<tenant>. Unset,
+	// stored profiles cannot name local files at all.
+	ProfileFilesDir string `yaml:"profileFilesDir,omitempty"`
 }
 
 type TLSConfig struct {
@@ -51,17 +178,92 @@ type RuntimeSection struct {
 }
 
 type CodeExecutionConfig struct {
-	Enabled     bool          `yaml:"enabled"`
-	Engine      string        `yaml:"engine"`
-	DenoPath    string        `yaml:"denoPath,omitempty"`
-	Timeout     time.Duration `yaml:"timeout,omitempty"`
-	MemoryLimit string        `yaml:"memoryLimit,omitempty"`
+	Enabled bool `yaml:"enabled"`
+	// Engine is "auto" (Deno when installed, goja otherwise), "deno" or "goja".
+	Engine   string        `yaml:"engine"`
+	DenoPath string        `yaml:"denoPath,omitempty"`
+	Timeout  time.Duration `yaml:"timeout,omitempty"`
+	// MemoryLimit is per script under Deno. Under goja it bounds the
+	// growth of the heap shared by all running scripts.
+	MemoryLimit string `yaml:"memoryLimit,omitempty"`
+	// CPUTime caps a script's compute time, not counting time spent
+	// waiting on tool calls. 0 disables the CPU limit.
+	CPUTime time.Duration `yaml:"cpuTime,omitempty"`
+	// AllowedHosts is the egress policy: hosts scripts may fetch from
+	// besides calling tools ("api.example.com", "*.example.com").
+	AllowedHosts []string `yaml:"allowedHosts,omitempty"`
+	// Profiles overrides the limits for individual profiles, by name.
+	Profiles map[string]CodeExecutionLimits `yaml:"profiles,omitempty"`
+}
+
+// CodeExecutionLimits overrides code execution limits for one profile.
+// Unset fields inherit the runtime-wide value.
+type CodeExecutionLimits struct {
+	Timeout      time.Duration `yaml:"timeout,omitempty"`
+	MemoryLimit  string        `yaml:"memoryLimit,omitempty"`
+	CPUTime      time.Duration `yaml:"cpuTime,omitempty"`
+	AllowedHosts []string      `yaml:"allowedHosts,omitempty"`
+}
+
+// ForProfile returns the code execution settings with the profile's
+// overrides applied.
+func (c CodeExecutionConfig) ForProfile(name string) CodeExecutionConfig {
+	out := c
+	out.Profiles = nil
+	limits, ok := c.Profiles[name]
+	if !ok {
+		return out
+	}
+	if limits.Timeout > 0 {
+		out.Timeout = limits.Timeout
+	}
+	if limits.MemoryLimit != "" {
+		out.MemoryLimit = limits.MemoryLimit
+	}
+	if limits.CPUTime > 0 {
+		out.CPUTime = limits.CPUTime
+	}
+	if limits.AllowedHosts != nil {
+		out.AllowedHosts = limits.AllowedHosts
+	}
+	return out
+}
+
+// ParseByteSize parses sizes such as "512MB", "1GB", "64KiB" or "1048576"
+// (bytes). Units are binary: KB and KiB both mean 1024 bytes.
+func ParseByteSize(s string) (int64, error) {
+	trimmed := strings.ToUpper(strings.TrimSpace(s))
+	units := []struct {
+		suffix string
+		mult   int64
+	}{
+		{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30},
+		{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30},
+		{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"B", 1},
+	}
+	mult := int64(1)
+	for _, u := range units {
+		if strings.HasSuffix(trimmed, u.suffix) {
+			trimmed = strings.TrimSpace(strings.TrimSuffix(trimmed, u.suffix))
+			mult = u.mult
+			break
+		}
+	}
+	n, err := strconv.ParseInt(trimmed, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n * mult, nil
 }
 
 type CacheConfig struct {
 	Enabled bool          `yaml:"enabled"`
 	TTL     time.Duration `yaml:"ttl,omitempty"`
 	MaxSize string        `yaml:"maxSize,omitempty"`
+	// RefreshInterval re-fetches the specs of cached profiles in the
+	// background and pushes tools/list_changed to MCP sessions when their
+	// tools change. 0 disables background refresh.
+	RefreshInterval time.Duration `yaml:"refreshInterval,omitempty"`
 }
 
 type AuditSection struct {
@@ -69,16 +271,155 @@ type AuditSection struct {
 	Database    string        `yaml:"database"`
 	RotateAfter time.Duration `yaml:"rotateAfter,omitempty"`
 	MaxSize     string        `yaml:"maxSize,omitempty"`
+	// SigningKey is a 32-byte ed25519 seed (base64 or hex). When set, the
+	// audit hash chain is checkpointed with signatures made with this key.
+	SigningKey string `yaml:"signingKey,omitempty"`
+	// Sinks forward stored events to files, syslog or webhooks as well.
+	Sinks []AuditSink `yaml:"sinks,omitempty"`
+}
+
+// AuditSink forwards audit events outside the database.
+type AuditSink struct {
+	// Type is "file" (NDJSON appended to Path), "syslog" or "webhook".
+	Type string `yaml:"type"`
+	Path string `yaml:"path,omitempty"`
+	// Network is udp (default), tcp, unix or unixgram; Address is the
+	// syslog server, e.g. "logs.internal:514" or "/dev/log".
+	Network  string `yaml:"network,omitempty"`
+	Address  string `yaml:"address,omitempty"`
+	Facility string `yaml:"facility,omitempty"` // default local0
+	Tag      string `yaml:"tag,omitempty"`      // default skyline
+	// URL receives batches of events as a JSON array.
+	URL     string            `yaml:"url,omitempty"`
+	Headers map[string]string `yaml:"headers,omitempty"`
+	// EventTypes limits the sink to these event types; empty forwards all.
+	EventTypes []string `yaml:"eventTypes,omitempty"`
 }
 
 type ProfilesSection struct {
+	// Backend selects where profiles are persisted: "file" (default) keeps a
+	// single encrypted YAML file at Storage; "sqlite" stores one encrypted
+	// blob per profile in the Database file; "postgres" does the same in
+	// the Postgres database whose connection string is Database.
+	Backend       string `yaml:"backend,omitempty"`
 	Storage       string `yaml:"storage"`
+	Database      string `yaml:"database,omitempty"`
 	EncryptionKey string `yaml:"encryptionKey"`
 }
 
 type SecuritySection struct {
-	CORS         *CORSConfig `yaml:"cors,omitempty"`
-	MetricsToken string      `yaml:"metricsToken,omitempty"`
+	CORS         *CORSConfig        `yaml:"cors,omitempty"`
+	MetricsToken string             `yaml:"metricsToken,omitempty"`
+	Redaction    *RedactionConfig   `yaml:"redaction,omitempty"`
+	MCP          *MCPEndpointConfig `yaml:"mcp,omitempty"`
+	OIDC         *OIDCConfig        `yaml:"oidc,omitempty"`
+	Access       *AccessConfig      `yaml:"access,omitempty"`
+	Lockout      *LockoutConfig     `yaml:"lockout,omitempty"`
+}
+
+// LockoutConfig blocks a client from a profile after repeated invalid
+// tokens, so tokens cannot be guessed. Failures are counted per profile and
+// client address in the audit database.
+type LockoutConfig struct {
+	// Enabled defaults to true.
+	Enabled *bool `yaml:"enabled,omitempty"`
+	// MaxFailures within Window start a lockout; default 10.
+	MaxFailures int           `yaml:"maxFailures,omitempty"`
+	Window      time.Duration `yaml:"window,omitempty"` // default 15m
+	// Duration is the first lockout; each one that follows within Window
+	// of the last failure doubles it, up to MaxDuration. Defaults 5m, 1h.
+	Duration    time.Duration `yaml:"duration,omitempty"`
+	MaxDuration time.Duration `yaml:"maxDuration,omitempty"`
+}
+
+// AccessConfig limits which client networks may reach each endpoint group.
+// Entries are CIDRs ("10.0.0.0/8") or single addresses; an empty list
+// leaves the group open. Health probes are never restricted.
+type AccessConfig struct {
+	// Management covers the admin UI and API, profile management, detect,
+	// verify and the other setup endpoints.
+	Management []string `yaml:"management,omitempty"`
+	// Gateway covers what agents call: profile /mcp, /tools, /execute and
+	// /code endpoints, and the MCP OAuth endpoints.
+	Gateway []string `yaml:"gateway,omitempty"`
+	// Metrics covers the Prometheus /metrics endpoint.
+	Metrics []string `yaml:"metrics,omitempty"`
+	// TrustedProxies lists the load balancers whose X-Forwarded-For header
+	// is believed. Without it the peer address is checked.
+	TrustedProxies []string `yaml:"trustedProxies,omitempty"`
+}
+
+// OIDCConfig lets access tokens (JWTs) from an OpenID Connect provider
+// authorize management requests and MCP connections, so access is granted
+// through the IdP instead of static tokens.
+type OIDCConfig struct {
+	Issuer string `yaml:"issuer"`
+	// Audience is the aud claim tokens must carry, e.g. the server's URL.
+	Audience string `yaml:"audience"`
+	// JWKSURL overrides the key set URL from the issuer's discovery
+	// document.
+	JWKSURL string `yaml:"jwksURL,omitempty"`
+	// AdminScope grants what an admin session does; default "skyline:admin".
+	AdminScope string `yaml:"adminScope,omitempty"`
+	// MCPScope grants access to profile MCP endpoints; default "skyline:mcp".
+	MCPScope string `yaml:"mcpScope,omitempty"`
+	// ProfilesClaim names a claim listing the profiles a token may use
+	// over MCP. Without it, MCPScope grants every profile.
+	ProfilesClaim string `yaml:"profilesClaim,omitempty"`
+	// RequireForMCP makes profile MCP endpoints accept only IdP tokens,
+	// not profile tokens, whatever --auth-mode says.
+	RequireForMCP bool `yaml:"requireForMCP,omitempty"`
+}
+
+// MCPEndpointConfig hardens the /profiles/{name}/mcp endpoint and sets
+// how dead connections are detected.
+type MCPEndpointConfig struct {
+	// AllowedOrigins lists browser origins, besides localhost and the
+	// server's own host, that may connect ("https://*.example.com").
+	// Requests with any other Origin header are rejected.
+	AllowedOrigins []string `yaml:"allowedOrigins,omitempty"`
+	// QueryToken accepts the profile token in an access_token query
+	// parameter for browser clients that cannot set headers.
+	QueryToken bool `yaml:"queryToken,omitempty"`
+	// IdleTimeout closes sessions without requests for this long; default 1h.
+	IdleTimeout time.Duration `yaml:"idleTimeout,omitempty"`
+	// MaxMessageSize caps a request body, e.g. "1MB"; default 10MB.
+	MaxMessageSize string `yaml:"maxMessageSize,omitempty"`
+	// KeepaliveInterval spaces the comments written to idle event
+	// streams; default 15s.
+	KeepaliveInterval time.Duration `yaml:"keepaliveInterval,omitempty"`
+	// PingInterval sends MCP pings over open event streams; sessions
+	// whose client misses PingTimeout (default twice the interval) are
+	// closed. 0 disables pings.
+	PingInterval time.Duration `yaml:"pingInterval,omitempty"`
+	PingTimeout  time.Duration `yaml:"pingTimeout,omitempty"`
+	// CompressMinSize gzips responses at least this large, e.g. "32KB",
+	// and event streams, for clients that accept gzip. Empty disables
+	// compression.
+	CompressMinSize string `yaml:"compressMinSize,omitempty"`
+}
+
+// RedactionConfig adds rules to the credential redaction applied to
+// executor logs, audit entries and error messages.
+type RedactionConfig struct {
+	RedactionRules `yaml:",inline"`
+	// APIs adds rules for APIs of the given name, in any profile.
+	APIs map[string]RedactionRules `yaml:"apis,omitempty"`
+}
+
+type RedactionRules struct {
+	Patterns []RedactionPattern `yaml:"patterns,omitempty"`
+	// Fields are JSON paths scrubbed from request and response bodies and
+	// tool arguments, e.g. "$.card.number" or "password" (any depth).
+	Fields []string `yaml:"fields,omitempty"`
+}
+
+// RedactionPattern is a regex to redact, or a built-in pattern by name:
+// credit_card, email or jwt.
+type RedactionPattern struct {
+	Name        string `yaml:"name,omitempty"`
+	Regex       string `yaml:"regex,omitempty"`
+	Replacement string `yaml:"replacement,omitempty"`
 }
 
 type CORSConfig struct {
@@ -102,7 +443,7 @@ func Default() *ServerConfig {
 		Runtime: RuntimeSection{
 			CodeExecution: CodeExecutionConfig{
 				Enabled:     true,
-				Engine:      "goja",
+				Engine:      "auto",
 				Timeout:     30 * time.Second,
 				MemoryLimit: "512MB",
 			},
@@ -117,7 +458,9 @@ func Default() *ServerConfig {
 			Database: "~/.skyline/skyline-audit.db",
 		},
 		Profiles: ProfilesSection{
+			Backend:       "file",
 			Storage:       "~/.skyline/profiles.enc.yaml",
+			Database:      "~/.skyline/profiles.db",
 			EncryptionKey: "${SKYLINE_PROFILES_KEY}",
 		},
 		Security: SecuritySection{},
@@ -128,7 +471,8 @@ func Default() *ServerConfig {
 	}
 }
 
-// Load reads config from path, returns default if not found
+// Load reads config from path, returns default if not found, and applies
+// environment overrides (see ApplyEnv)
 func Load(path string) (*ServerConfig, error) {
 	// Expand home directory
 	if len(path) > 0 && path[0] == '~' {
@@ -140,23 +484,28 @@ func Load(path string) (*ServerConfig, error) {
 	}
 
 	data, err := os.ReadFile(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			// Config doesn't exist - return default
-			return Default(), nil
-		}
+	if err != nil && !os.IsNotExist(err) {
 		return nil, fmt.Errorf("read config: %w", err)
 	}
 
-	var cfg ServerConfig
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		return nil, fmt.Errorf("parse config: %w", err)
+	// A missing config file means defaults
+	cfg := Default()
+	if err == nil {
+		cfg = &ServerConfig{}
+		if err := yaml.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("parse config: %w", err)
+		}
+	}
+
+	// Environment variables override the file
+	if err := cfg.ApplyEnv(os.LookupEnv); err != nil {
+		return nil, fmt.Errorf("environment: %w", err)
 	}
 
 	// Apply defaults for missing fields
 	cfg.ApplyDefaults()
 
-	return &cfg, nil
+	return cfg, nil
 }
 
 // ApplyDefaults fills in missing fields with default values
@@ -170,7 +519,7 @@ func (c *ServerConfig) ApplyDefaults() {
 
 	// Runtime defaults
 	if c.Runtime.CodeExecution.Engine == "" {
-		c.Runtime.CodeExecution.Engine = "goja"
+		c.Runtime.CodeExecution.Engine = "auto"
 	}
 	if c.Runtime.CodeExecution.Timeout == 0 {
 		c.Runtime.CodeExecution.Timeout = 30 * time.Second
@@ -191,6 +540,12 @@ func (c *ServerConfig) ApplyDefaults() {
 	}
 
 	// Profiles defaults
+	if c.Profiles.Backend == "" {
+		c.Profiles.Backend = "file"
+	}
+	if c.Profiles.Database == "" {
+		c.Profiles.Database = "~/.skyline/profiles.db"
+	}
 	if c.Profiles.Storage == "" {
 		c.Profiles.Storage = "~/.skyline/profiles.enc.yaml"
 	}
@@ -198,6 +553,11 @@ func (c *ServerConfig) ApplyDefaults() {
 		c.Profiles.EncryptionKey = "${SKYLINE_PROFILES_KEY}"
 	}
 
+	// Cluster defaults
+	if c.Cluster.Redis != "" && c.Cluster.KeyPrefix == "" {
+		c.Cluster.KeyPrefix = "skyline:"
+	}
+
 	// Logging defaults
 	if c.Logging.Level == "" {
 		c.Logging.Level = "info"
@@ -284,26 +644,39 @@ runtime:
   # Code execution engine (98% cost reduction vs traditional MCP)
   codeExecution:
     enabled: true
-    engine: "goja"  # embedded JS runtime (no external dependencies)
+    engine: "auto"  # Deno when installed, else the embedded goja runtime
     timeout: 30s
     memoryLimit: "512MB"
+    # cpuTime: 10s                      # compute time, excluding tool calls
+    # allowedHosts: ["api.example.com"] # hosts scripts may fetch from
+    # profiles:                         # per-profile overrides
+    #   sandbox:
+    #     timeout: 5s
+    #     allowedHosts: []
     
   # Discovery cache (for repeated API calls)
   cache:
     enabled: true
     ttl: 1h
     maxSize: 100MB
+    # refreshInterval: 15m  # re-fetch specs in the background; sessions get tools/list_changed
 
 audit:
   enabled: true
   database: "~/.skyline/skyline-audit.db"
   # rotateAfter: 30d
   # maxSize: 1GB
+  # signingKey: "base64:..."  # 32-byte ed25519 seed; signs audit chain checkpoints
 
 profiles:
   # API credentials & rate-limiting configurations
   # Managed via Web UI - stores auth tokens, rate limits, custom headers
+  # backend: "file" keeps one encrypted YAML file; "sqlite" and "postgres"
+  # store one encrypted blob per profile so several instances can share a store.
+  backend: "file"
   storage: "~/.skyline/profiles.enc.yaml"
+  # database: "~/.skyline/profiles.db"  # used when backend is sqlite
+  # database: "postgres://skyline:${PGPASSWORD}@db:5432/skyline"  # when backend is postgres
   encryptionKey: "${SKYLINE_PROFILES_KEY}"  # from skyline.env
 
 # Security
@@ -313,7 +686,29 @@ security:
   # cors:
   #   enabled: true
   #   origins: ["http://localhost:*"]
+  # mcp:
+  #   allowedOrigins: ["https://*.example.com"]  # besides localhost and this host
+  #   queryToken: false      # accept ?access_token= for browser clients
+  #   idleTimeout: 1h        # close idle sessions
+  #   maxMessageSize: 10MB   # largest request body
+  #   keepaliveInterval: 15s # comments on idle event streams
+  #   pingInterval: 30s      # MCP pings; unanswered sessions are closed
+  # redaction:                        # on top of API credentials
+  #   patterns:
+  #     - name: credit_card             # built-ins: credit_card, email, jwt
+  #     - regex: "\\bDE\\d{20}\\b"
+  #   fields: ["password", "$.card.number"]
+  #   apis:
+  #     crm:
+  #       fields: ["$..date_of_birth"]
   
+# Distributed mode (optional): share rate limits, circuit breakers and MCP
+# session routing between replicas behind a load balancer.
+# cluster:
+#   redis: "redis://:password@redis:6379/0"
+#   nodeURL: "https://10.0.0.5:8191"  # how other replicas reach this node
+#   insecureSkipVerify: true          # for self-signed node certificates
+
 # Logging
 logging:
   level: "info"  # debug, info, warn, error
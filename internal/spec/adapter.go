@@ -2,6 +2,8 @@ package spec
 
 import (
 	"context"
+	"slices"
+	"sync"
 
 	"skyline-mcp/internal/canonical"
 )
@@ -12,3 +14,31 @@ type SpecAdapter interface {
 	Detect(raw []byte) bool
 	Parse(ctx context.Context, raw []byte, apiName, baseURLOverride string) (*canonical.Service, error)
 }
+
+var (
+	registeredMu sync.RWMutex
+	registered   []SpecAdapter
+)
+
+// RegisterAdapter adds an adapter, such as one backed by a plugin, that is
+// tried after the built-in ones. It replaces a registered adapter of the
+// same name. An API whose spec_type names it is parsed by it without
+// detection.
+func RegisterAdapter(adapter SpecAdapter) {
+	registeredMu.Lock()
+	defer registeredMu.Unlock()
+	registered = slices.DeleteFunc(registered, func(a SpecAdapter) bool { return a.Name() == adapter.Name() })
+	registered = append(registered, adapter)
+}
+
+// registeredAdapter returns the registered adapter called name, or nil.
+func registeredAdapter(name string) SpecAdapter {
+	registeredMu.RLock()
+	defer registeredMu.RUnlock()
+	for _, a := range registered {
+		if a.Name() == name {
+			return a
+		}
+	}
+	return nil
+}
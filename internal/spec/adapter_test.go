@@ -0,0 +1,41 @@
+package spec
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/logging"
+	"skyline-mcp/internal/redact"
+)
+
+// stubAdapter parses any document into one operation named after it.
+type stubAdapter struct{ name string }
+
+func (a stubAdapter) Name() string       { return a.name }
+func (a stubAdapter) Detect([]byte) bool { return false }
+func (a stubAdapter) Parse(_ context.Context, raw []byte, apiName, _ string) (*canonical.Service, error) {
+	return &canonical.Service{Name: apiName, Operations: []*canonical.Operation{{ServiceName: apiName, ID: a.name, ToolName: apiName + "__" + a.name}}}, nil
+}
+
+func TestRegisteredAdapterNamedBySpecType(t *testing.T) {
+	RegisterAdapter(stubAdapter{name: "stubformat"})
+	if !slices.Contains(SpecFormats(), "stubformat") || slices.Contains(BuiltinSpecFormats(), "stubformat") {
+		t.Errorf("SpecFormats() = %v, BuiltinSpecFormats() = %v", SpecFormats(), BuiltinSpecFormats())
+	}
+
+	// The document looks like OpenAPI, but spec_type picks the registered
+	// adapter without detection.
+	dir := writeSpecFiles(t, map[string]string{"api.yaml": openAPIDoc("/pets", "listPets")})
+	api := config.APIConfig{Name: "shop", SpecFile: filepath.Join(dir, "api.yaml"), SpecType: "stubformat"}
+	svc, err := loadSingleAPI(context.Background(), NewFetcher(0), specAdapters(), api, 0, &apiLoad{}, logging.Discard(), redact.NewRedactor())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(svc.Operations) != 1 || svc.Operations[0].ID != "stubformat" {
+		t.Errorf("operations = %+v, want the stub adapter's", svc.Operations)
+	}
+}
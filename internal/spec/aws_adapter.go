@@ -0,0 +1,22 @@
+package spec
+
+import (
+	"context"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/parsers/aws"
+)
+
+// AWSAdapter loads AWS service models in the botocore JSON format, whose
+// operations are sent with the API's aws-sigv4 auth.
+type AWSAdapter struct{}
+
+func NewAWSAdapter() *AWSAdapter { return &AWSAdapter{} }
+
+func (a *AWSAdapter) Name() string { return "aws" }
+
+func (a *AWSAdapter) Detect(raw []byte) bool { return aws.LooksLikeServiceModel(raw) }
+
+func (a *AWSAdapter) Parse(ctx context.Context, raw []byte, apiName, baseURLOverride string) (*canonical.Service, error) {
+	return aws.ParseToCanonical(ctx, raw, apiName, baseURLOverride)
+}
@@ -0,0 +1,158 @@
+package spec
+
+import (
+	"log/slog"
+	"maps"
+	"slices"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+)
+
+// ApplyBodyTemplates attaches each API's body_templates to the operations
+// they name, by operation ID or generated tool name. Constant fields are
+// removed from the tool's input schema and default fields are no longer
+// required, so the model only fills in what varies. It runs before REST
+// grouping so composite tools inherit the trimmed schemas.
+func ApplyBodyTemplates(services []*canonical.Service, apis []config.APIConfig, logger *slog.Logger) []*canonical.Service {
+	templates := map[string]map[string]config.BodyTemplate{}
+	for _, api := range apis {
+		if len(api.BodyTemplates) > 0 {
+			templates[api.Name] = api.BodyTemplates
+		}
+	}
+	for _, svc := range services {
+		byOp := templates[svc.Name]
+		if byOp == nil {
+			continue
+		}
+		used := map[string]bool{}
+		for _, op := range svc.Operations {
+			key := op.ID
+			tmpl, ok := byOp[key]
+			if !ok {
+				key = op.ToolName
+				tmpl, ok = byOp[key]
+			}
+			if !ok {
+				continue
+			}
+			used[key] = true
+			if op.RequestBody == nil || op.RequestBody.Template != "" {
+				logger.Warn("body_templates entry names an operation without a body argument", "api", svc.Name, "operation", key)
+				continue
+			}
+			applyBodyTemplate(op, tmpl)
+		}
+		for key := range byOp {
+			if !used[key] {
+				logger.Warn("body_templates entry matches no operation", "api", svc.Name, "operation", key)
+			}
+		}
+	}
+	return services
+}
+
+// applyBodyTemplate records tmpl on op and trims the body schema of its
+// input schema. Schemas may be shared between operations, so the maps on
+// the way to a change are copied rather than edited.
+func applyBodyTemplate(op *canonical.Operation, tmpl config.BodyTemplate) {
+	body := *op.RequestBody
+	body.Constants = tmpl.Constants
+	body.Defaults = tmpl.Defaults
+	body.Schema = templatedSchema(body.Schema, tmpl.Constants, tmpl.Defaults)
+	// Once the template covers every required field, the body may be left
+	// out entirely.
+	if body.Required && len(requiredNames(body.Schema)) == 0 {
+		body.Required = false
+	}
+	op.RequestBody = &body
+
+	props, _ := op.InputSchema["properties"].(map[string]any)
+	if props == nil || props["body"] == nil {
+		return
+	}
+	schema := maps.Clone(op.InputSchema)
+	props = maps.Clone(props)
+	props["body"] = body.Schema
+	schema["properties"] = props
+	if !body.Required {
+		schema["required"] = withoutNames(schema["required"], map[string]any{"body": nil})
+	}
+	op.InputSchema = schema
+}
+
+// templatedSchema returns schema without the properties of constants and
+// with those of defaults optional and annotated with their default. Nested
+// objects are trimmed field by field.
+func templatedSchema(schema, constants, defaults map[string]any) map[string]any {
+	props, _ := schema["properties"].(map[string]any)
+	if props == nil {
+		return schema
+	}
+	out := maps.Clone(schema)
+	props = maps.Clone(props)
+	for name, value := range constants {
+		prop, _ := props[name].(map[string]any)
+		nested, isObject := value.(map[string]any)
+		if _, hasProps := prop["properties"]; isObject && hasProps {
+			trimmed := templatedSchema(prop, nested, nil)
+			if len(trimmed["properties"].(map[string]any)) > 0 {
+				props[name] = trimmed
+				continue
+			}
+		}
+		delete(props, name)
+	}
+	for name, value := range defaults {
+		prop, ok := props[name].(map[string]any)
+		if !ok {
+			continue
+		}
+		prop = maps.Clone(prop)
+		prop["default"] = value
+		props[name] = prop
+	}
+	out["properties"] = props
+	out["required"] = withoutNames(withoutNames(out["required"], constants), defaults)
+	if len(requiredNames(out)) == 0 {
+		delete(out, "required")
+	}
+	return out
+}
+
+// withoutNames drops the entries of names from a JSON Schema required list,
+// which parsers build as []string or []any.
+func withoutNames(required any, names map[string]any) any {
+	switch req := required.(type) {
+	case []string:
+		return slices.DeleteFunc(slices.Clone(req), func(n string) bool {
+			_, ok := names[n]
+			return ok
+		})
+	case []any:
+		return slices.DeleteFunc(slices.Clone(req), func(n any) bool {
+			s, _ := n.(string)
+			_, ok := names[s]
+			return ok
+		})
+	}
+	return required
+}
+
+// requiredNames returns the required list of an object schema.
+func requiredNames(schema map[string]any) []string {
+	switch req := schema["required"].(type) {
+	case []string:
+		return req
+	case []any:
+		names := make([]string, 0, len(req))
+		for _, n := range req {
+			if s, ok := n.(string); ok {
+				names = append(names, s)
+			}
+		}
+		return names
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package spec
+
+import (
+	"reflect"
+	"testing"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/logging"
+)
+
+func TestApplyBodyTemplates(t *testing.T) {
+	// The parsers share resolved schemas between operations.
+	bodySchema := map[string]any{
+		"type":     "object",
+		"required": []any{"project", "summary", "fields"},
+		"properties": map[string]any{
+			"project":  map[string]any{"type": "string"},
+			"summary":  map[string]any{"type": "string"},
+			"priority": map[string]any{"type": "string"},
+			"fields": map[string]any{"type": "object", "properties": map[string]any{
+				"source": map[string]any{"type": "string"},
+				"labels": map[string]any{"type": "array"},
+			}},
+		},
+	}
+	newOp := func(id string) *canonical.Operation {
+		return &canonical.Operation{
+			ID:          id,
+			ToolName:    "jira__" + id,
+			Method:      "post",
+			RequestBody: &canonical.RequestBody{Required: true, ContentType: "application/json", Schema: bodySchema},
+			InputSchema: map[string]any{
+				"type":       "object",
+				"required":   []string{"body"},
+				"properties": map[string]any{"body": bodySchema},
+			},
+		}
+	}
+	create, other := newOp("createIssue"), newOp("updateIssue")
+	services := []*canonical.Service{{Name: "jira", Operations: []*canonical.Operation{create, other}}}
+	ApplyBodyTemplates(services, []config.APIConfig{{Name: "jira", BodyTemplates: map[string]config.BodyTemplate{
+		"createIssue": {
+			Constants: map[string]any{"project": "XYZ", "fields": map[string]any{"source": "mcp"}},
+			Defaults:  map[string]any{"priority": "medium"},
+		},
+	}}}, logging.Discard())
+
+	if create.RequestBody.Constants["project"] != "XYZ" || create.RequestBody.Defaults["priority"] != "medium" {
+		t.Fatalf("request body = %+v", create.RequestBody)
+	}
+	body := create.InputSchema["properties"].(map[string]any)["body"].(map[string]any)
+	props := body["properties"].(map[string]any)
+	if _, ok := props["project"]; ok {
+		t.Errorf("constant project is still in the schema")
+	}
+	if got := props["priority"].(map[string]any)["default"]; got != "medium" {
+		t.Errorf("priority default = %v", got)
+	}
+	fields := props["fields"].(map[string]any)["properties"].(map[string]any)
+	if _, ok := fields["source"]; ok || fields["labels"] == nil {
+		t.Errorf("fields properties = %v", fields)
+	}
+	if !reflect.DeepEqual(body["required"], []any{"summary"}) {
+		t.Errorf("required = %v", body["required"])
+	}
+	if !create.RequestBody.Required || !reflect.DeepEqual(create.InputSchema["required"], []string{"body"}) {
+		t.Errorf("body is no longer required while summary is")
+	}
+
+	// The shared schema and the other operation are untouched.
+	if len(bodySchema["properties"].(map[string]any)) != 4 || len(bodySchema["required"].([]any)) != 3 {
+		t.Errorf("shared schema was modified: %v", bodySchema)
+	}
+	if other.RequestBody.Constants != nil || other.InputSchema["properties"].(map[string]any)["body"].(map[string]any)["properties"].(map[string]any)["project"] == nil {
+		t.Errorf("template applied to updateIssue")
+	}
+}
+
+func TestApplyBodyTemplatesOptionalBody(t *testing.T) {
+	op := &canonical.Operation{
+		ID: "createEvent",
+		RequestBody: &canonical.RequestBody{Required: true, ContentType: "application/json", Schema: map[string]any{
+			"type":       "object",
+			"required":   []any{"source"},
+			"properties": map[string]any{"source": map[string]any{"type": "string"}, "note": map[string]any{"type": "string"}},
+		}},
+	}
+	op.InputSchema = map[string]any{"type": "object", "required": []string{"body"}, "properties": map[string]any{"body": op.RequestBody.Schema}}
+	ApplyBodyTemplates([]*canonical.Service{{Name: "events", Operations: []*canonical.Operation{op}}},
+		[]config.APIConfig{{Name: "events", BodyTemplates: map[string]config.BodyTemplate{"createEvent": {Constants: map[string]any{"source": "mcp"}}}}},
+		logging.Discard())
+	if op.RequestBody.Required || len(op.InputSchema["required"].([]string)) != 0 {
+		t.Fatalf("body still required: %v, %v", op.RequestBody.Required, op.InputSchema["required"])
+	}
+}
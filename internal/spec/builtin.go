@@ -0,0 +1,92 @@
+package spec
+
+import (
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+)
+
+// ApplyBuiltinTools appends a "skyline" service holding the tools the
+// executor answers itself: the meta tools that describe the other tools
+// and report APIs that failed to load, plus get_job_result for async calls, api_health when health checks are
+// enabled and get_remaining_quota when budgets are configured.
+func ApplyBuiltinTools(services []*canonical.Service, cfg *config.Config) []*canonical.Service {
+	builtin := &canonical.Service{Name: config.BuiltinServiceName}
+	builtin.Operations = append(builtin.Operations,
+		builtinOperation("describe_tool",
+			"Describe a tool: its full input and output schema and the upstream method and path it calls.",
+			map[string]any{"tool": stringProp("Tool name, e.g. github__repos_get")}, "tool"),
+		builtinOperation("list_services",
+			"List the configured APIs with their base URL and tool counts.",
+			map[string]any{"include_tools": boolProp("Also list each API's tool names")}),
+		builtinOperation("get_operation_examples",
+			"Get sample argument payloads for a tool, synthesized from its input schema.",
+			map[string]any{"tool": stringProp("Tool name, e.g. github__repos_get")}, "tool"),
+		builtinOperation("list_service_errors",
+			"List the configured APIs that failed to load, with the error for each. Their tools are missing until the next reload.",
+			map[string]any{}),
+		builtinOperation("get_job_result",
+			"Get the status and result of a tool call started with _async: true.",
+			map[string]any{
+				"job_id":       stringProp("Job ID returned by the async call"),
+				"wait_seconds": numberProp("Wait up to this long (max 60) for a running job to finish"),
+			}, "job_id"),
+	)
+	if cfg.HealthCheck != nil {
+		builtin.Operations = append(builtin.Operations, builtinOperation("api_health",
+			"Report the availability of the configured APIs (status, latency, circuit breaker state). Call before long workflows.",
+			map[string]any{
+				"api":     stringProp("Only report this API"),
+				"refresh": boolProp("Probe now instead of returning the last periodic result"),
+			}))
+	}
+	if hasQuota(cfg) {
+		builtin.Operations = append(builtin.Operations, builtinOperation("get_remaining_quota",
+			"Report the call budgets of this profile and its APIs: limit, calls used and remaining, and when each resets.",
+			map[string]any{}))
+	}
+	return append(services, builtin)
+}
+
+func hasQuota(cfg *config.Config) bool {
+	if cfg.Quota != nil {
+		return true
+	}
+	for _, api := range cfg.APIs {
+		if api.Quota != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func builtinOperation(id, summary string, properties map[string]any, required ...string) *canonical.Operation {
+	inputSchema := map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		inputSchema["required"] = required
+	}
+	return &canonical.Operation{
+		ServiceName: config.BuiltinServiceName,
+		ID:          id,
+		ToolName:    canonical.ToolName(config.BuiltinServiceName, id),
+		Method:      "get",
+		Protocol:    "builtin",
+		Summary:     summary,
+		InputSchema: inputSchema,
+	}
+}
+
+func stringProp(description string) map[string]any {
+	return map[string]any{"type": "string", "description": description}
+}
+
+func numberProp(description string) map[string]any {
+	return map[string]any{"type": "number", "description": description}
+}
+
+func boolProp(description string) map[string]any {
+	return map[string]any{"type": "boolean", "description": description}
+}
@@ -0,0 +1,130 @@
+package spec
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/parsers/rawhttp"
+)
+
+// ApplyCustomOperations appends each API's custom_operations (curl commands
+// and .http requests) to its service. It runs after filtering and grouping
+// so the hand-written tools are kept exactly as configured. An operation
+// that fails to parse or clashes with an existing tool is skipped with a
+// warning.
+func ApplyCustomOperations(services []*canonical.Service, apis []config.APIConfig, logger *slog.Logger) []*canonical.Service {
+	byName := make(map[string]*canonical.Service, len(services))
+	for _, svc := range services {
+		byName[svc.Name] = svc
+	}
+	for _, api := range apis {
+		svc := byName[api.Name]
+		if svc == nil || len(api.CustomOperations) == 0 {
+			continue
+		}
+		existing := map[string]bool{}
+		for _, op := range svc.Operations {
+			existing[op.ToolName] = true
+		}
+		for i, custom := range api.CustomOperations {
+			ops, err := buildCustomOperations(api.Name, custom, svc.BaseURL)
+			if err != nil {
+				logger.Warn("skipping custom operation", "api", api.Name, "index", i, "error", err)
+				continue
+			}
+			for _, built := range ops {
+				if existing[built.op.ToolName] {
+					logger.Warn("skipping custom operation", "api", api.Name, "tool", built.op.ToolName, "error", "a tool with this name already exists")
+					continue
+				}
+				if svc.BaseURL == "" {
+					svc.BaseURL = built.base
+				}
+				existing[built.op.ToolName] = true
+				svc.Operations = append(svc.Operations, built.op)
+			}
+		}
+	}
+	return services
+}
+
+type customOp struct {
+	op   *canonical.Operation
+	base string
+}
+
+func buildCustomOperations(apiName string, custom config.CustomOperation, baseURL string) ([]customOp, error) {
+	var reqs []rawhttp.Request
+	switch {
+	case strings.TrimSpace(custom.Curl) != "":
+		req, err := rawhttp.ParseCurl(custom.Curl)
+		if err != nil {
+			return nil, err
+		}
+		reqs = append(reqs, *req)
+	case strings.TrimSpace(custom.HTTP) != "":
+		parsed, err := rawhttp.ParseHTTP(custom.HTTP)
+		if err != nil {
+			return nil, err
+		}
+		if len(parsed) != 1 {
+			return nil, fmt.Errorf("http must hold exactly one request (use http_file for several)")
+		}
+		reqs = parsed
+	default:
+		raw, err := os.ReadFile(custom.HTTPFile)
+		if err != nil {
+			return nil, fmt.Errorf("read http_file: %w", err)
+		}
+		if reqs, err = rawhttp.ParseHTTP(string(raw)); err != nil {
+			return nil, fmt.Errorf("%s: %w", custom.HTTPFile, err)
+		}
+	}
+
+	var out []customOp
+	for _, req := range reqs {
+		id, summary := custom.Name, custom.Description
+		if custom.HTTPFile != "" {
+			id, summary = httpRequestID(req), req.Title
+		}
+		if summary == "" {
+			summary = req.Title
+		}
+		op, base, err := rawhttp.BuildOperation(apiName, id, summary, &req, baseURL)
+		if err != nil {
+			return nil, err
+		}
+		if baseURL == "" {
+			baseURL = base
+		}
+		out = append(out, customOp{op: op, base: base})
+	}
+	return out, nil
+}
+
+var nonWordRE = regexp.MustCompile(`[^a-z0-9]+`)
+
+// httpRequestID names a request from an http_file: its "# @name", else its
+// "###" title, else method and URL.
+func httpRequestID(req rawhttp.Request) string {
+	if req.Name != "" {
+		return req.Name
+	}
+	src := req.Title
+	if src == "" {
+		path := req.URL
+		if i := strings.Index(path, "://"); i >= 0 {
+			if j := strings.Index(path[i+3:], "/"); j >= 0 {
+				path = path[i+3+j:]
+			}
+		}
+		path, _, _ = strings.Cut(path, "?")
+		src = req.Method + " " + path
+	}
+	return strings.Trim(nonWordRE.ReplaceAllString(strings.ToLower(src), "_"), "_")
+}
@@ -0,0 +1,53 @@
+package spec
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/logging"
+)
+
+func TestApplyCustomOperations(t *testing.T) {
+	httpFile := filepath.Join(t.TempDir(), "admin.http")
+	if err := os.WriteFile(httpFile, []byte("### Reindex search\nPOST https://api.example.com/admin/reindex\n\n###\n# @name purge_cache\nDELETE https://api.example.com/admin/cache/{{key}}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	services := []*canonical.Service{
+		{Name: "api", BaseURL: "https://api.example.com", Operations: []*canonical.Operation{
+			{ID: "list_items", ToolName: "api__list_items", Method: "get", Path: "/items"},
+		}},
+		{Name: "adhoc"},
+	}
+	apis := []config.APIConfig{
+		{Name: "api", CustomOperations: []config.CustomOperation{
+			{Name: "archive_item", Description: "Archive an item", Curl: "curl -X POST https://api.example.com/items/{{id}}/archive"},
+			{Name: "list_items", Curl: "curl https://api.example.com/items"},
+			{HTTPFile: httpFile},
+			{Name: "elsewhere", Curl: "curl https://other.example.com/x"},
+		}},
+		{Name: "adhoc", CustomOperations: []config.CustomOperation{
+			{Name: "ping", HTTP: "GET https://status.example.com/ping"},
+		}},
+	}
+
+	out := ApplyCustomOperations(services, apis, logging.Discard())
+	tools := map[string]*canonical.Operation{}
+	for _, op := range out[0].Operations {
+		tools[op.ToolName] = op
+	}
+	if len(tools) != 4 {
+		t.Fatalf("expected 4 tools (clash and foreign host skipped), got %v", tools)
+	}
+	if op := tools["api__archive_item"]; op == nil || op.Path != "/items/{id}/archive" || op.Summary != "Archive an item" {
+		t.Errorf("archive_item = %+v", op)
+	}
+	if tools["api__reindex_search"] == nil || tools["api__purge_cache"] == nil {
+		t.Errorf("http_file requests missing: %v", tools)
+	}
+	if out[1].BaseURL != "https://status.example.com" || len(out[1].Operations) != 1 {
+		t.Errorf("custom-only API = %+v", out[1])
+	}
+}
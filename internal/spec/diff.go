@@ -0,0 +1,339 @@
+package spec
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"skyline-mcp/internal/canonical"
+)
+
+// Change kinds reported by DiffServices.
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// maxDiffDepth bounds schema recursion so self-referencing schemas terminate.
+const maxDiffDepth = 32
+
+// SpecDiff describes how the operations exposed by a set of services changed
+// between two loads of the same specs.
+type SpecDiff struct {
+	Added    []OperationRef  `json:"added"`
+	Removed  []OperationRef  `json:"removed"`
+	Changed  []OperationDiff `json:"changed"`
+	Breaking bool            `json:"breaking"`
+}
+
+// OperationRef identifies an operation by the tool it is exposed as.
+type OperationRef struct {
+	Service string `json:"service"`
+	Tool    string `json:"tool"`
+	Method  string `json:"method,omitempty"`
+	Path    string `json:"path,omitempty"`
+}
+
+// OperationDiff lists the changes to an operation present in both loads.
+type OperationDiff struct {
+	OperationRef
+	Changes  []SchemaChange `json:"changes"`
+	Breaking bool           `json:"breaking"`
+}
+
+// SchemaChange is a single difference. Location is "method", "path",
+// "protocol", or a dotted schema path rooted at "input" or "output"
+// ("[]" marks array items, e.g. "output.items[].id").
+type SchemaChange struct {
+	Kind     string `json:"kind"`
+	Location string `json:"location"`
+	Old      any    `json:"old,omitempty"`
+	New      any    `json:"new,omitempty"`
+	Breaking bool   `json:"breaking"`
+}
+
+// Empty reports whether the two loads expose identical operations.
+func (d *SpecDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DiffServices compares a previously loaded set of services against a fresh
+// load. Operations are matched by service and tool name, since that is what
+// agents call. A change is breaking when a call that worked before could fail
+// or be misread now: a removed tool, a removed or newly required input, a
+// narrowed enum, a type change, or a removed output field.
+func DiffServices(before, after []*canonical.Service) *SpecDiff {
+	oldOps := indexOperations(before)
+	newOps := indexOperations(after)
+
+	diff := &SpecDiff{
+		Added:   []OperationRef{},
+		Removed: []OperationRef{},
+		Changed: []OperationDiff{},
+	}
+	for _, key := range sortedKeys(oldOps) {
+		op := oldOps[key]
+		if _, ok := newOps[key]; !ok {
+			diff.Removed = append(diff.Removed, refFor(op))
+			diff.Breaking = true
+		}
+	}
+	for _, key := range sortedKeys(newOps) {
+		op := newOps[key]
+		prev, ok := oldOps[key]
+		if !ok {
+			diff.Added = append(diff.Added, refFor(op))
+			continue
+		}
+		if opDiff, changed := diffOperation(prev, op); changed {
+			diff.Changed = append(diff.Changed, opDiff)
+			if opDiff.Breaking {
+				diff.Breaking = true
+			}
+		}
+	}
+	return diff
+}
+
+func indexOperations(services []*canonical.Service) map[string]*canonical.Operation {
+	ops := make(map[string]*canonical.Operation)
+	for _, svc := range services {
+		if svc == nil {
+			continue
+		}
+		for _, op := range svc.Operations {
+			if op == nil {
+				continue
+			}
+			ops[svc.Name+"\x00"+op.ToolName] = op
+		}
+	}
+	return ops
+}
+
+func sortedKeys(m map[string]*canonical.Operation) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func refFor(op *canonical.Operation) OperationRef {
+	return OperationRef{
+		Service: op.ServiceName,
+		Tool:    op.ToolName,
+		Method:  opMethod(op),
+		Path:    op.Path,
+	}
+}
+
+func opMethod(op *canonical.Operation) string {
+	if op.Method != "" {
+		return strings.ToUpper(op.Method)
+	}
+	return strings.ToUpper(op.HTTPMethod)
+}
+
+func diffOperation(before, after *canonical.Operation) (OperationDiff, bool) {
+	var changes []SchemaChange
+	// Skyline translates tool calls into requests, so a moved endpoint does
+	// not affect agents as long as the tool keeps its inputs.
+	if o, n := opMethod(before), opMethod(after); o != n {
+		changes = append(changes, SchemaChange{Kind: ChangeChanged, Location: "method", Old: o, New: n})
+	}
+	if before.Path != after.Path {
+		changes = append(changes, SchemaChange{Kind: ChangeChanged, Location: "path", Old: before.Path, New: after.Path})
+	}
+	if before.Protocol != after.Protocol {
+		changes = append(changes, SchemaChange{Kind: ChangeChanged, Location: "protocol", Old: before.Protocol, New: after.Protocol})
+	}
+	diffSchema(&changes, "input", before.InputSchema, after.InputSchema, true, 0)
+	diffSchema(&changes, "output", before.ResponseSchema, after.ResponseSchema, false, 0)
+
+	if len(changes) == 0 {
+		return OperationDiff{}, false
+	}
+	opDiff := OperationDiff{OperationRef: refFor(after), Changes: changes}
+	for _, c := range changes {
+		if c.Breaking {
+			opDiff.Breaking = true
+			break
+		}
+	}
+	return opDiff, true
+}
+
+// diffSchema appends the differences between two JSON Schemas. input selects
+// which direction is breaking: for inputs, anything that rejects a previously
+// valid call; for outputs, anything that drops data a caller may rely on.
+func diffSchema(changes *[]SchemaChange, loc string, before, after map[string]any, input bool, depth int) {
+	if depth > maxDiffDepth {
+		return
+	}
+	if len(before) == 0 && len(after) == 0 {
+		return
+	}
+	if len(before) == 0 || len(after) == 0 {
+		// A schema appeared or disappeared entirely (e.g. a response that was
+		// previously untyped). Only a vanished output schema is breaking.
+		if !reflect.DeepEqual(before, after) {
+			kind := ChangeAdded
+			if len(after) == 0 {
+				kind = ChangeRemoved
+			}
+			*changes = append(*changes, SchemaChange{Kind: kind, Location: loc, Breaking: kind == ChangeRemoved && !input})
+		}
+		return
+	}
+
+	oldType, newType := schemaTypeName(before), schemaTypeName(after)
+	if oldType != newType && oldType != "" && newType != "" {
+		*changes = append(*changes, SchemaChange{Kind: ChangeChanged, Location: loc + " (type)", Old: oldType, New: newType, Breaking: true})
+		return
+	}
+
+	diffEnum(changes, loc, before["enum"], after["enum"], input)
+
+	oldProps, _ := before["properties"].(map[string]any)
+	newProps, _ := after["properties"].(map[string]any)
+	oldReq, newReq := requiredSet(before), requiredSet(after)
+
+	names := make(map[string]struct{}, len(oldProps)+len(newProps))
+	for name := range oldProps {
+		names[name] = struct{}{}
+	}
+	for name := range newProps {
+		names[name] = struct{}{}
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	for _, name := range sorted {
+		child := loc + "." + name
+		op, inOld := oldProps[name]
+		np, inNew := newProps[name]
+		switch {
+		case !inNew:
+			*changes = append(*changes, SchemaChange{Kind: ChangeRemoved, Location: child, Breaking: true})
+		case !inOld:
+			*changes = append(*changes, SchemaChange{Kind: ChangeAdded, Location: child, New: requiredLabel(newReq[name]), Breaking: input && newReq[name]})
+		default:
+			if oldReq[name] != newReq[name] {
+				*changes = append(*changes, SchemaChange{
+					Kind:     ChangeChanged,
+					Location: child + " (required)",
+					Old:      oldReq[name],
+					New:      newReq[name],
+					Breaking: input && newReq[name],
+				})
+			}
+			oldChild, _ := op.(map[string]any)
+			newChild, _ := np.(map[string]any)
+			diffSchema(changes, child, oldChild, newChild, input, depth+1)
+		}
+	}
+
+	oldItems, _ := before["items"].(map[string]any)
+	newItems, _ := after["items"].(map[string]any)
+	diffSchema(changes, loc+"[]", oldItems, newItems, input, depth+1)
+}
+
+// diffEnum reports enum values that were added or removed. Removing a value
+// an agent could send is breaking; so is an output returning a new value.
+func diffEnum(changes *[]SchemaChange, loc string, before, after any, input bool) {
+	oldVals, newVals := enumValues(before), enumValues(after)
+	if oldVals == nil || newVals == nil {
+		if (oldVals == nil) != (newVals == nil) {
+			*changes = append(*changes, SchemaChange{Kind: ChangeChanged, Location: loc + " (enum)", Old: before, New: after, Breaking: input && newVals != nil})
+		}
+		return
+	}
+	var removed, added []string
+	for v := range oldVals {
+		if !newVals[v] {
+			removed = append(removed, v)
+		}
+	}
+	for v := range newVals {
+		if !oldVals[v] {
+			added = append(added, v)
+		}
+	}
+	sort.Strings(removed)
+	sort.Strings(added)
+	if len(removed) > 0 {
+		*changes = append(*changes, SchemaChange{Kind: ChangeRemoved, Location: loc + " (enum)", Old: removed, Breaking: input})
+	}
+	if len(added) > 0 {
+		*changes = append(*changes, SchemaChange{Kind: ChangeAdded, Location: loc + " (enum)", New: added, Breaking: !input})
+	}
+}
+
+func enumValues(v any) map[string]bool {
+	list, ok := v.([]any)
+	if !ok {
+		if strs, ok := v.([]string); ok {
+			list = make([]any, len(strs))
+			for i, s := range strs {
+				list[i] = s
+			}
+		} else {
+			return nil
+		}
+	}
+	set := make(map[string]bool, len(list))
+	for _, item := range list {
+		set[fmt.Sprint(item)] = true
+	}
+	return set
+}
+
+func schemaTypeName(schema map[string]any) string {
+	switch t := schema["type"].(type) {
+	case string:
+		return t
+	case []any:
+		parts := make([]string, 0, len(t))
+		for _, p := range t {
+			parts = append(parts, fmt.Sprint(p))
+		}
+		sort.Strings(parts)
+		return strings.Join(parts, "|")
+	case []string:
+		parts := append([]string(nil), t...)
+		sort.Strings(parts)
+		return strings.Join(parts, "|")
+	}
+	return ""
+}
+
+func requiredSet(schema map[string]any) map[string]bool {
+	set := map[string]bool{}
+	switch req := schema["required"].(type) {
+	case []any:
+		for _, r := range req {
+			if s, ok := r.(string); ok {
+				set[s] = true
+			}
+		}
+	case []string:
+		for _, s := range req {
+			set[s] = true
+		}
+	}
+	return set
+}
+
+func requiredLabel(required bool) string {
+	if required {
+		return "required"
+	}
+	return "optional"
+}
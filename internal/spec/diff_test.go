@@ -0,0 +1,169 @@
+package spec
+
+import (
+	"testing"
+
+	"skyline-mcp/internal/canonical"
+)
+
+func diffTestServices(ops ...*canonical.Operation) []*canonical.Service {
+	for _, op := range ops {
+		op.ServiceName = "petstore"
+	}
+	return []*canonical.Service{{Name: "petstore", Operations: ops}}
+}
+
+func petInput(required []any, props map[string]any) map[string]any {
+	return map[string]any{"type": "object", "properties": props, "required": required}
+}
+
+func findChange(d OperationDiff, loc string) (SchemaChange, bool) {
+	for _, c := range d.Changes {
+		if c.Location == loc {
+			return c, true
+		}
+	}
+	return SchemaChange{}, false
+}
+
+func TestDiffServicesAddedRemoved(t *testing.T) {
+	before := diffTestServices(
+		&canonical.Operation{ToolName: "petstore__listPets", Method: "get", Path: "/pets"},
+		&canonical.Operation{ToolName: "petstore__deletePet", Method: "delete", Path: "/pets/{id}"},
+	)
+	after := diffTestServices(
+		&canonical.Operation{ToolName: "petstore__listPets", Method: "GET", Path: "/pets"},
+		&canonical.Operation{ToolName: "petstore__createPet", Method: "post", Path: "/pets"},
+	)
+
+	d := DiffServices(before, after)
+	if len(d.Added) != 1 || d.Added[0].Tool != "petstore__createPet" {
+		t.Fatalf("added = %+v, want createPet", d.Added)
+	}
+	if len(d.Removed) != 1 || d.Removed[0].Tool != "petstore__deletePet" {
+		t.Fatalf("removed = %+v, want deletePet", d.Removed)
+	}
+	if len(d.Changed) != 0 {
+		t.Fatalf("changed = %+v, want none (method case differs only)", d.Changed)
+	}
+	if !d.Breaking {
+		t.Fatal("removing a tool should be breaking")
+	}
+}
+
+func TestDiffServicesIdentical(t *testing.T) {
+	mk := func() []*canonical.Service {
+		return diffTestServices(&canonical.Operation{
+			ToolName:    "petstore__getPet",
+			Method:      "get",
+			Path:        "/pets/{id}",
+			InputSchema: petInput([]any{"id"}, map[string]any{"id": map[string]any{"type": "string"}}),
+		})
+	}
+	d := DiffServices(mk(), mk())
+	if !d.Empty() || d.Breaking {
+		t.Fatalf("identical services should produce an empty diff, got %+v", d)
+	}
+}
+
+func TestDiffServicesInputChanges(t *testing.T) {
+	before := diffTestServices(&canonical.Operation{
+		ToolName: "petstore__findPets",
+		Method:   "get",
+		Path:     "/pets",
+		InputSchema: petInput([]any{}, map[string]any{
+			"status": map[string]any{"type": "string", "enum": []any{"available", "sold", "pending"}},
+			"limit":  map[string]any{"type": "integer"},
+			"tag":    map[string]any{"type": "string"},
+		}),
+	})
+	after := diffTestServices(&canonical.Operation{
+		ToolName: "petstore__findPets",
+		Method:   "get",
+		Path:     "/v2/pets",
+		InputSchema: petInput([]any{"owner"}, map[string]any{
+			"status": map[string]any{"type": "string", "enum": []any{"available", "sold", "adopted"}},
+			"limit":  map[string]any{"type": "string"},
+			"owner":  map[string]any{"type": "string"},
+			"color":  map[string]any{"type": "string"},
+		}),
+	})
+
+	d := DiffServices(before, after)
+	if len(d.Changed) != 1 {
+		t.Fatalf("changed = %+v, want one operation", d.Changed)
+	}
+	op := d.Changed[0]
+	if !op.Breaking || !d.Breaking {
+		t.Fatal("expected a breaking change")
+	}
+
+	tests := []struct {
+		loc      string
+		kind     string
+		breaking bool
+	}{
+		{"path", ChangeChanged, false},
+		{"input.status (enum)", ChangeRemoved, true},
+		{"input.limit (type)", ChangeChanged, true},
+		{"input.tag", ChangeRemoved, true},
+		{"input.owner", ChangeAdded, true},
+		{"input.color", ChangeAdded, false},
+	}
+	for _, tt := range tests {
+		c, ok := findChange(op, tt.loc)
+		if !ok {
+			t.Errorf("missing change at %s in %+v", tt.loc, op.Changes)
+			continue
+		}
+		if c.Kind != tt.kind || c.Breaking != tt.breaking {
+			t.Errorf("%s: got kind=%s breaking=%v, want kind=%s breaking=%v", tt.loc, c.Kind, c.Breaking, tt.kind, tt.breaking)
+		}
+	}
+}
+
+func TestDiffServicesOutputChanges(t *testing.T) {
+	before := diffTestServices(&canonical.Operation{
+		ToolName: "petstore__listPets",
+		ResponseSchema: map[string]any{"type": "array", "items": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"id":   map[string]any{"type": "integer"},
+				"name": map[string]any{"type": "string"},
+			},
+		}},
+	})
+	after := diffTestServices(&canonical.Operation{
+		ToolName: "petstore__listPets",
+		ResponseSchema: map[string]any{"type": "array", "items": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"id":  map[string]any{"type": "integer"},
+				"age": map[string]any{"type": "integer"},
+			},
+		}},
+	})
+
+	d := DiffServices(before, after)
+	if len(d.Changed) != 1 {
+		t.Fatalf("changed = %+v, want one operation", d.Changed)
+	}
+	op := d.Changed[0]
+	if c, ok := findChange(op, "output[].name"); !ok || c.Kind != ChangeRemoved || !c.Breaking {
+		t.Errorf("removed output field: got %+v (found=%v)", c, ok)
+	}
+	if c, ok := findChange(op, "output[].age"); !ok || c.Kind != ChangeAdded || c.Breaking {
+		t.Errorf("added output field: got %+v (found=%v)", c, ok)
+	}
+}
+
+func TestDiffServicesSelfReferencingSchema(t *testing.T) {
+	node := map[string]any{"type": "object"}
+	node["properties"] = map[string]any{"child": node}
+	before := diffTestServices(&canonical.Operation{ToolName: "petstore__tree", InputSchema: node})
+	after := diffTestServices(&canonical.Operation{ToolName: "petstore__tree", InputSchema: node})
+
+	if d := DiffServices(before, after); !d.Empty() {
+		t.Fatalf("expected no changes, got %+v", d)
+	}
+}
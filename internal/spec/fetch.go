@@ -7,22 +7,91 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"os"
 	"time"
 
 	"skyline-mcp/internal/config"
+	graphqlparser "skyline-mcp/internal/parsers/graphql"
 )
 
-// maxSpecSize is the maximum allowed size for fetched spec files (10 MB).
-// This prevents OOM from unexpectedly large responses.
-const maxSpecSize = 10 << 20
+// defaultMaxSpecSize is the default maximum size of a spec document
+// (10 MB). This prevents OOM from unexpectedly large responses.
+const defaultMaxSpecSize = 10 << 20
 
 type Fetcher struct {
-	client *http.Client
+	client  *http.Client
+	maxSize int64
 }
 
 func NewFetcher(timeout time.Duration) *Fetcher {
-	return &Fetcher{client: &http.Client{Timeout: timeout}}
+	return &Fetcher{client: &http.Client{Timeout: timeout}, maxSize: defaultMaxSpecSize}
+}
+
+// WithHosts returns a fetcher that connects through an API's
+// transport.hosts overrides, or f when it has none.
+func (f *Fetcher) WithHosts(transport *config.TransportConfig) *Fetcher {
+	if transport == nil || len(transport.Hosts) == 0 {
+		return f
+	}
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return dialer.DialContext(ctx, network, transport.DialAddress(addr))
+	}
+	// The client is used for one API's load only.
+	base.DisableKeepAlives = true
+	return &Fetcher{client: &http.Client{Timeout: f.client.Timeout, Transport: base}, maxSize: f.maxSize}
+}
+
+// SetMaxSize sets the size above which documents are rejected; 0 restores
+// the default.
+func (f *Fetcher) SetMaxSize(n int64) {
+	if n <= 0 {
+		n = defaultMaxSpecSize
+	}
+	f.maxSize = n
+}
+
+// ReadFile reads a local spec document, refusing files over the size limit
+// before reading them.
+func (f *Fetcher) ReadFile(path string) ([]byte, error) {
+	if info, err := os.Stat(path); err == nil && info.Size() > f.maxSize {
+		return nil, f.tooLarge(path, info.Size())
+	}
+	return os.ReadFile(path)
+}
+
+// readBody reads at most maxSize bytes of body; larger documents are an
+// error rather than silently truncated.
+func (f *Fetcher) readBody(body io.Reader, what string) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(body, f.maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > f.maxSize {
+		return nil, f.tooLarge(what, -1)
+	}
+	return data, nil
+}
+
+func (f *Fetcher) tooLarge(what string, size int64) error {
+	if size < 0 {
+		return fmt.Errorf("%s is larger than the %s limit (spec_limits.max_spec_bytes)", what, formatBytes(f.maxSize))
+	}
+	return fmt.Errorf("%s is %s, larger than the %s limit (spec_limits.max_spec_bytes)", what, formatBytes(size), formatBytes(f.maxSize))
+}
+
+// formatBytes renders n as a human-readable size, e.g. "40.0 MB".
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d bytes", n)
 }
 
 func (f *Fetcher) Fetch(ctx context.Context, url string, auth *config.AuthConfig) ([]byte, error) {
@@ -31,7 +100,7 @@ func (f *Fetcher) Fetch(ctx context.Context, url string, auth *config.AuthConfig
 		return nil, fmt.Errorf("build request: %w", err)
 	}
 	req.Header.Set("Accept", "application/json, application/yaml, text/yaml, application/xml, text/xml, */*")
-	applyAuth(req, auth)
+	ApplyAuth(req, auth)
 
 	resp, err := f.client.Do(req)
 	if err != nil {
@@ -41,7 +110,10 @@ func (f *Fetcher) Fetch(ctx context.Context, url string, auth *config.AuthConfig
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		return nil, fmt.Errorf("fetch spec: unexpected status %d", resp.StatusCode)
 	}
-	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSpecSize))
+	if resp.ContentLength > f.maxSize {
+		return nil, fmt.Errorf("read spec: %w", f.tooLarge("spec", resp.ContentLength))
+	}
+	data, err := f.readBody(resp.Body, "spec")
 	if err != nil {
 		return nil, fmt.Errorf("read spec: %w", err)
 	}
@@ -61,17 +133,22 @@ func (f *Fetcher) FetchGraphQLIntrospection(ctx context.Context, url string, aut
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "application/json")
-	applyAuth(req, auth)
+	ApplyAuth(req, auth)
 
 	resp, err := f.client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("fetch introspection: %w", err)
 	}
 	defer resp.Body.Close()
+	data, err := f.readBody(resp.Body, "introspection result")
+	if msg := graphqlparser.IntrospectionErrors(data); msg != "" {
+		// Servers with introspection disabled answer with a GraphQL error,
+		// with a 200 or a 400.
+		return nil, fmt.Errorf("introspection rejected (%s): set graphql.schema to the API's SDL file or URL", msg)
+	}
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		return nil, fmt.Errorf("fetch introspection: unexpected status %d", resp.StatusCode)
 	}
-	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSpecSize))
 	if err != nil {
 		return nil, fmt.Errorf("read introspection: %w", err)
 	}
@@ -98,7 +175,7 @@ func (f *Fetcher) FetchOpenRPCDiscover(ctx context.Context, url string, auth *co
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "application/json")
-	applyAuth(req, auth)
+	ApplyAuth(req, auth)
 
 	resp, err := f.client.Do(req)
 	if err != nil {
@@ -108,7 +185,7 @@ func (f *Fetcher) FetchOpenRPCDiscover(ctx context.Context, url string, auth *co
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		return nil, fmt.Errorf("fetch rpc.discover: unexpected status %d", resp.StatusCode)
 	}
-	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSpecSize))
+	data, err := f.readBody(resp.Body, "rpc.discover result")
 	if err != nil {
 		return nil, fmt.Errorf("read rpc.discover: %w", err)
 	}
@@ -124,7 +201,8 @@ func (f *Fetcher) FetchOpenRPCDiscover(ctx context.Context, url string, auth *co
 	return data, nil
 }
 
-func applyAuth(req *http.Request, auth *config.AuthConfig) {
+// ApplyAuth sets the headers that authenticate a spec fetch with auth.
+func ApplyAuth(req *http.Request, auth *config.AuthConfig) {
 	if auth == nil {
 		return
 	}
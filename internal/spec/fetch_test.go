@@ -33,3 +33,23 @@ func TestFetchWithBasicAuth(t *testing.T) {
 		t.Fatalf("unexpected body: %s", string(data))
 	}
 }
+
+func TestFetchWithHosts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Host))
+	}))
+	defer server.Close()
+
+	fetcher := NewFetcher(5 * time.Second)
+	if fetcher.WithHosts(&config.TransportConfig{}) != fetcher {
+		t.Fatal("fetcher copied without hosts")
+	}
+	tunneled := fetcher.WithHosts(&config.TransportConfig{Hosts: map[string]string{"specs.internal": server.Listener.Addr().String()}})
+	data, err := tunneled.Fetch(context.Background(), "http://specs.internal/openapi.json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "specs.internal" {
+		t.Fatalf("Host = %q, want the configured name", data)
+	}
+}
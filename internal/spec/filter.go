@@ -50,13 +50,16 @@ func filterOperations(ops []*canonical.Operation, filter *config.OperationFilter
 
 	result := make([]*canonical.Operation, 0, len(ops))
 	for _, op := range ops {
-		matches := operationMatches(op, filter.Operations)
+		pattern, matches := matchingPattern(op, filter.Operations)
 
 		// Allowlist: keep if matches
 		// Blocklist: keep if NOT matches
 		keep := (mode == "allowlist" && matches) || (mode == "blocklist" && !matches)
 
 		if keep {
+			if mode == "allowlist" && pattern.TimeoutSeconds > 0 {
+				op.TimeoutSeconds = pattern.TimeoutSeconds
+			}
 			result = append(result, op)
 		}
 	}
@@ -122,12 +125,18 @@ func operationTypeName(op *canonical.Operation) string {
 
 // operationMatches checks if operation matches ANY of the patterns
 func operationMatches(op *canonical.Operation, patterns []config.OperationPattern) bool {
+	_, ok := matchingPattern(op, patterns)
+	return ok
+}
+
+// matchingPattern returns the first of patterns that matches the operation.
+func matchingPattern(op *canonical.Operation, patterns []config.OperationPattern) (config.OperationPattern, bool) {
 	for _, pattern := range patterns {
 		if patternMatches(op, pattern) {
-			return true
+			return pattern, true
 		}
 	}
-	return false
+	return config.OperationPattern{}, false
 }
 
 // patternMatches checks if a single pattern matches the operation
@@ -195,6 +195,36 @@ func TestFilterOperations_Allowlist(t *testing.T) {
 	}
 }
 
+func TestFilterOperations_TimeoutOverride(t *testing.T) {
+	ops := []*canonical.Operation{
+		{ID: "getJob", Method: "GET", Path: "/job/{name}/api/json"},
+		{ID: "buildJob", Method: "POST", Path: "/job/{name}/build"},
+	}
+
+	filter := &config.OperationFilterEnhanced{
+		Mode: "allowlist",
+		Operations: []config.OperationPattern{
+			{OperationID: "build*", TimeoutSeconds: 120},
+			{Method: "*"},
+		},
+	}
+
+	result := filterOperations(ops, filter)
+
+	if len(result) != 2 {
+		t.Fatalf("expected 2 operations, got %d", len(result))
+	}
+	for _, op := range result {
+		want := 0
+		if op.ID == "buildJob" {
+			want = 120
+		}
+		if op.TimeoutSeconds != want {
+			t.Errorf("%s: timeout = %d, want %d", op.ID, op.TimeoutSeconds, want)
+		}
+	}
+}
+
 func TestFilterOperations_Blocklist(t *testing.T) {
 	ops := []*canonical.Operation{
 		{ID: "getPetById", Method: "GET", Path: "/pets/{petId}"},
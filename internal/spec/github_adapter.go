@@ -0,0 +1,23 @@
+package spec
+
+import (
+	"context"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/parsers/github"
+)
+
+// GitHubAdapter handles GitHub through a curated tool set that mixes REST
+// operations with GraphQL ones for discussions and Projects, under one API
+// and one auth config.
+type GitHubAdapter struct{}
+
+func NewGitHubAdapter() *GitHubAdapter { return &GitHubAdapter{} }
+
+func (a *GitHubAdapter) Name() string { return "github" }
+
+func (a *GitHubAdapter) Detect(raw []byte) bool { return github.LooksLikeGitHub(raw) }
+
+func (a *GitHubAdapter) Parse(ctx context.Context, raw []byte, apiName, baseURLOverride string) (*canonical.Service, error) {
+	return github.ParseToCanonical(ctx, raw, apiName, baseURLOverride)
+}
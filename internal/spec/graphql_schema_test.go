@@ -0,0 +1,100 @@
+package spec
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/logging"
+	"skyline-mcp/internal/redact"
+)
+
+const pinnedSDL = `type Query {
+  user(id: ID!): User
+}
+
+type User {
+  id: ID!
+  name: String
+}
+`
+
+// introspectionDisabledServer answers every request the way servers with
+// introspection turned off do, and counts the requests it gets.
+func introspectionDisabledServer(t *testing.T, calls *int) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"errors":[{"message":"GraphQL introspection is not allowed"}]}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestFetchGraphQLIntrospectionDisabled(t *testing.T) {
+	var calls int
+	server := introspectionDisabledServer(t, &calls)
+	_, err := NewFetcher(2*time.Second).FetchGraphQLIntrospection(context.Background(), server.URL+"/graphql", nil)
+	if err == nil || !strings.Contains(err.Error(), "introspection is not allowed") || !strings.Contains(err.Error(), "graphql.schema") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestLoadServicesPinnedGraphQLSchema(t *testing.T) {
+	var endpointCalls int
+	endpoint := introspectionDisabledServer(t, &endpointCalls)
+	dir := writeSpecFiles(t, map[string]string{"schema.graphql": pinnedSDL})
+
+	var registryAuth string
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		registryAuth = r.Header.Get("Authorization")
+		_, _ = w.Write([]byte(pinnedSDL))
+	}))
+	defer registry.Close()
+
+	tests := []struct {
+		name   string
+		schema string
+	}{
+		{"local file", filepath.Join(dir, "schema.graphql")},
+		{"url", registry.URL + "/schema.graphql"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{APIs: []config.APIConfig{{
+				Name:    "users",
+				SpecURL: endpoint.URL + "/graphql",
+				Auth:    &config.AuthConfig{Type: "bearer", Token: "secret"},
+				GraphQL: &config.GraphQLConfig{Schema: tt.schema},
+			}}}
+			services, err := LoadServices(context.Background(), cfg, logging.Discard(), redact.NewRedactor())
+			if err != nil {
+				t.Fatalf("LoadServices: %v", err)
+			}
+			svc := services[0]
+			if svc.BaseURL != endpoint.URL+"/graphql" {
+				t.Fatalf("BaseURL = %q, want the endpoint", svc.BaseURL)
+			}
+			var ids []string
+			for _, op := range svc.Operations {
+				ids = append(ids, op.ID)
+			}
+			if !strings.Contains(strings.Join(ids, ","), "query_user") {
+				t.Fatalf("operations = %v", ids)
+			}
+		})
+	}
+	if endpointCalls != 0 {
+		t.Fatalf("endpoint was asked for its schema %d times", endpointCalls)
+	}
+	if registryAuth != "" {
+		t.Fatalf("API credentials sent to another host: %q", registryAuth)
+	}
+}
@@ -0,0 +1,22 @@
+package spec
+
+import (
+	"context"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/parsers/har"
+)
+
+type HARAdapter struct{}
+
+func NewHARAdapter() *HARAdapter { return &HARAdapter{} }
+
+func (a *HARAdapter) Name() string { return "har" }
+
+func (a *HARAdapter) Detect(raw []byte) bool {
+	return har.LooksLikeHAR(raw)
+}
+
+func (a *HARAdapter) Parse(ctx context.Context, raw []byte, apiName, baseURLOverride string) (*canonical.Service, error) {
+	return har.ParseToCanonical(ctx, raw, apiName, baseURLOverride)
+}
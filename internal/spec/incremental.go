@@ -0,0 +1,85 @@
+package spec
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+)
+
+// LoadResult is the outcome of LoadServicesIncremental.
+type LoadResult struct {
+	Services   []*canonical.Service
+	LoadErrors []canonical.LoadError
+	// Parsed is what to pass as prev to the next load of the config.
+	Parsed *ParsedSpecs
+	// Unchanged names the APIs whose service was reused from the previous
+	// load rather than parsed again.
+	Unchanged map[string]bool
+}
+
+// ParsedSpecs keeps each API's service as its adapter produced it, before
+// filters, naming and the other passes, along with what it was built from.
+type ParsedSpecs struct {
+	apis map[string]*parsedAPI
+}
+
+type parsedAPI struct {
+	configHash string
+	specHash   string // empty when the service was not parsed from one document
+	service    *canonical.Service
+}
+
+// lookup returns the API's previous parse if its config is unchanged.
+func (p *ParsedSpecs) lookup(name, configHash string) *parsedAPI {
+	if p == nil {
+		return nil
+	}
+	if prev, ok := p.apis[name]; ok && prev.configHash == configHash {
+		return prev
+	}
+	return nil
+}
+
+// apiLoad carries the settings of loading one API and what it observed.
+type apiLoad struct {
+	parseTimeout time.Duration
+	configHash   string
+	// previous is the API's last parse; it is reused when the spec fetched
+	// now is identical to the one it was parsed from.
+	previous *parsedAPI
+	specHash string
+	reused   bool
+}
+
+// apiConfigHash fingerprints everything in cfg that shapes api's tools:
+// its own config and the profile-wide settings such as tool naming.
+func apiConfigHash(cfg *config.Config, api config.APIConfig) string {
+	profile := *cfg
+	profile.APIs = nil
+	h := sha256.New()
+	_ = json.NewEncoder(h).Encode(profile)
+	_ = json.NewEncoder(h).Encode(api)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func hashSpec(raw []byte) string {
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// cloneService copies svc and its operations so the passes applied after
+// loading, which rename and adjust operations in place, leave the original
+// alone. Schemas and other nested values are shared.
+func cloneService(svc *canonical.Service) *canonical.Service {
+	out := *svc
+	out.Operations = make([]*canonical.Operation, len(svc.Operations))
+	for i, op := range svc.Operations {
+		cp := *op
+		out.Operations[i] = &cp
+	}
+	return &out
+}
@@ -0,0 +1,85 @@
+package spec
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/logging"
+	"skyline-mcp/internal/redact"
+)
+
+func TestLoadServicesIncremental(t *testing.T) {
+	var mu sync.Mutex
+	docs := map[string]string{
+		"/pets.yaml":   openAPIDoc("/pets", "listPets"),
+		"/owners.yaml": openAPIDoc("/owners", "listOwners"),
+	}
+	fetches := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		fetches[r.URL.Path]++
+		_, _ = w.Write([]byte(docs[r.URL.Path]))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{APIs: []config.APIConfig{
+		{Name: "pets", SpecURL: server.URL + "/pets.yaml", ToolPrefix: "zoo"},
+		{Name: "owners", SpecURL: server.URL + "/owners.yaml"},
+	}}
+	load := func(prev *ParsedSpecs, refetch bool) *LoadResult {
+		t.Helper()
+		res, err := LoadServicesIncremental(context.Background(), cfg, prev, refetch, logging.Discard(), redact.NewRedactor())
+		if err != nil {
+			t.Fatalf("LoadServicesIncremental: %v", err)
+		}
+		return res
+	}
+	toolOf := func(res *LoadResult, service string) string {
+		for _, svc := range res.Services {
+			if svc.Name == service && len(svc.Operations) == 1 {
+				return svc.Operations[0].ToolName
+			}
+		}
+		t.Fatalf("service %s not loaded: %+v", service, res.Services)
+		return ""
+	}
+
+	first := load(nil, false)
+	if len(first.Unchanged) != 0 || toolOf(first, "pets") != "zoo__listPets" {
+		t.Fatalf("first load: unchanged = %v, tool = %s", first.Unchanged, toolOf(first, "pets"))
+	}
+
+	// An edit to one API: the other is neither fetched nor parsed again,
+	// and naming is not applied twice to the reused operations.
+	cfg.APIs[1].BaseURLOverride = "https://owners.example.com"
+	edited := load(first.Parsed, false)
+	if !edited.Unchanged["pets"] || edited.Unchanged["owners"] || fetches["/pets.yaml"] != 1 || fetches["/owners.yaml"] != 2 {
+		t.Fatalf("edit: unchanged = %v, fetches = %v", edited.Unchanged, fetches)
+	}
+	if toolOf(edited, "pets") != "zoo__listPets" {
+		t.Fatalf("reused tool renamed again: %s", toolOf(edited, "pets"))
+	}
+
+	// A refresh fetches every spec but reparses only the one that changed.
+	mu.Lock()
+	docs["/owners.yaml"] = openAPIDoc("/owners", "listAllOwners")
+	mu.Unlock()
+	refreshed := load(edited.Parsed, true)
+	if !refreshed.Unchanged["pets"] || refreshed.Unchanged["owners"] || fetches["/pets.yaml"] != 2 {
+		t.Fatalf("refresh: unchanged = %v, fetches = %v", refreshed.Unchanged, fetches)
+	}
+	if toolOf(refreshed, "owners") != "owners__listAllOwners" || toolOf(refreshed, "pets") != "zoo__listPets" {
+		t.Fatalf("refresh tools: %s, %s", toolOf(refreshed, "owners"), toolOf(refreshed, "pets"))
+	}
+
+	// Profile-wide settings invalidate every API.
+	cfg.ToolNaming = &config.ToolNamingConfig{}
+	if all := load(refreshed.Parsed, false); len(all.Unchanged) != 0 {
+		t.Fatalf("unchanged after tool_naming edit: %v", all.Unchanged)
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 	"skyline-mcp/internal/canonical"
 	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/parsers/jenkins"
 )
 
 var pathParamName = regexp.MustCompile(`\{([^}]+)\}`)
@@ -21,6 +22,7 @@ func appendJenkinsWrites(service *canonical.Service, api config.APIConfig) error
 		if err != nil {
 			return err
 		}
+		jenkins.ApplyFolderPaths([]*canonical.Operation{op})
 		if _, exists := findOperation(service.Operations, op.ToolName); exists {
 			return fmt.Errorf("duplicate tool name %s", op.ToolName)
 		}
@@ -0,0 +1,23 @@
+package spec
+
+import (
+	"context"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/parsers/jira"
+)
+
+// JiraAdapter handles Jira Cloud, Server and Data Center through a curated
+// tool set. It is used for spec_type: jira or a serverInfo response; a
+// spec_url on *.atlassian.net still loads the full Atlassian OpenAPI spec.
+type JiraAdapter struct{}
+
+func NewJiraAdapter() *JiraAdapter { return &JiraAdapter{} }
+
+func (a *JiraAdapter) Name() string { return "jira" }
+
+func (a *JiraAdapter) Detect(raw []byte) bool { return jira.LooksLikeJira(raw) }
+
+func (a *JiraAdapter) Parse(ctx context.Context, raw []byte, apiName, baseURLOverride string) (*canonical.Service, error) {
+	return jira.ParseToCanonical(ctx, raw, apiName, baseURLOverride)
+}
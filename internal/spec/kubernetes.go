@@ -0,0 +1,146 @@
+package spec
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/parsers/kubernetes"
+	"skyline-mcp/internal/redact"
+)
+
+// defaultKubernetesGroups keeps the generated registry small when an API
+// does not list the groups it wants.
+var defaultKubernetesGroups = []string{"core", "apps", "batch"}
+
+// loadKubernetes builds a spec_type: kubernetes service from the cluster's
+// discovery documents (/api/v1, /apis and /apis/{group}/{version}), which
+// also cover CRDs, and takes request body schemas from the OpenAPI endpoint.
+// Groups that fail to load (e.g. an unavailable aggregated API) are skipped.
+func loadKubernetes(ctx context.Context, fetcher *Fetcher, api config.APIConfig, logger *slog.Logger, redactor *redact.Redactor) (*canonical.Service, error) {
+	base := strings.TrimRight(api.BaseURLOverride, "/")
+	if base == "" {
+		base = strings.TrimRight(api.SpecURL, "/")
+	}
+	opts := config.KubernetesConfig{}
+	if api.Kubernetes != nil {
+		opts = *api.Kubernetes
+	}
+	groups := opts.Groups
+	if len(groups) == 0 {
+		groups = defaultKubernetesGroups
+	}
+	wanted := map[string]bool{}
+	for _, g := range groups {
+		wanted[strings.ToLower(strings.TrimSpace(g))] = true
+	}
+	all := wanted["*"]
+
+	var gvs []kubernetes.GroupVersion
+	if all || wanted["core"] || wanted[""] {
+		raw, err := fetcher.Fetch(ctx, base+"/api/v1", api.Auth)
+		if err != nil {
+			return nil, fmt.Errorf("kubernetes discovery: %w", err)
+		}
+		resources, err := kubernetes.ParseResourceList(raw)
+		if err != nil {
+			return nil, err
+		}
+		gvs = append(gvs, kubernetes.GroupVersion{Version: "v1", Resources: resources})
+	}
+
+	needGroups := all
+	for g := range wanted {
+		needGroups = needGroups || (g != "core" && g != "")
+	}
+	var apiGroups []kubernetes.APIGroup
+	if needGroups {
+		raw, err := fetcher.Fetch(ctx, base+"/apis", api.Auth)
+		if err != nil {
+			return nil, fmt.Errorf("kubernetes discovery: %w", err)
+		}
+		if apiGroups, err = kubernetes.ParseGroupList(raw); err != nil {
+			return nil, err
+		}
+	}
+	found := map[string]bool{"core": true, "": true, "*": true}
+	for _, g := range apiGroups {
+		if !all && !wanted[strings.ToLower(g.Name)] {
+			continue
+		}
+		found[strings.ToLower(g.Name)] = true
+		gv := g.PreferredVersion.GroupVersion
+		raw, err := fetcher.Fetch(ctx, base+"/apis/"+gv, api.Auth)
+		if err != nil {
+			logger.Warn("skipping kubernetes api group", "api", api.Name, "group_version", gv, "error", redactor.Redact(err.Error()))
+			continue
+		}
+		resources, err := kubernetes.ParseResourceList(raw)
+		if err != nil {
+			logger.Warn("skipping kubernetes api group", "api", api.Name, "group_version", gv, "error", err)
+			continue
+		}
+		gvs = append(gvs, kubernetes.GroupVersion{Group: g.Name, Version: g.PreferredVersion.Version, Resources: resources})
+	}
+	for g := range wanted {
+		if !found[g] {
+			logger.Warn("kubernetes api group not served by cluster", "api", api.Name, "group", g)
+		}
+	}
+
+	schemas := loadKubernetesSchemas(ctx, fetcher, api, base, opts.OpenAPI, gvs, logger, redactor)
+	logger.Info("loaded kubernetes discovery", "api", api.Name, "group_versions", len(gvs), "schemas", len(schemas))
+	return kubernetes.BuildService(api.Name, base, gvs, schemas, kubernetes.Options{
+		Resources: opts.Resources,
+		ReadOnly:  opts.ReadOnly,
+	})
+}
+
+// loadKubernetesSchemas returns object schemas for the selected group
+// versions. Failures only cost schema detail, so they are logged, not fatal.
+func loadKubernetesSchemas(ctx context.Context, fetcher *Fetcher, api config.APIConfig, base, mode string, gvs []kubernetes.GroupVersion, logger *slog.Logger, redactor *redact.Redactor) map[kubernetes.GVK]map[string]any {
+	schemas := map[kubernetes.GVK]map[string]any{}
+	merge := func(url string) {
+		raw, err := fetcher.Fetch(ctx, url, api.Auth)
+		if err == nil {
+			var indexed map[kubernetes.GVK]map[string]any
+			if indexed, err = kubernetes.IndexSchemas(raw); err == nil {
+				for k, v := range indexed {
+					schemas[k] = v
+				}
+				return
+			}
+		}
+		logger.Warn("kubernetes openapi unavailable; using generic body schemas", "api", api.Name, "url", redactor.Redact(url), "error", redactor.Redact(err.Error()))
+	}
+
+	switch mode {
+	case "none":
+	case "v3":
+		raw, err := fetcher.Fetch(ctx, base+"/openapi/v3", api.Auth)
+		if err != nil {
+			logger.Warn("kubernetes openapi unavailable; using generic body schemas", "api", api.Name, "error", redactor.Redact(err.Error()))
+			return schemas
+		}
+		paths, err := kubernetes.OpenAPIV3Paths(raw)
+		if err != nil {
+			logger.Warn("kubernetes openapi unavailable; using generic body schemas", "api", api.Name, "error", err)
+			return schemas
+		}
+		for _, gv := range gvs {
+			key := "api/" + gv.Version
+			if gv.Group != "" {
+				key = "apis/" + gv.Group + "/" + gv.Version
+			}
+			if rel, ok := paths[key]; ok {
+				merge(base + rel)
+			}
+		}
+	default:
+		merge(base + "/openapi/v2")
+	}
+	return schemas
+}
@@ -0,0 +1,76 @@
+package spec
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/logging"
+	"skyline-mcp/internal/redact"
+)
+
+func TestLoadKubernetesDiscovery(t *testing.T) {
+	responses := map[string]string{
+		"/api/v1": `{"resources": [
+			{"name": "pods", "namespaced": true, "kind": "Pod", "verbs": ["get", "list", "create"]},
+			{"name": "configmaps", "namespaced": true, "kind": "ConfigMap", "verbs": ["get", "list"]}
+		]}`,
+		"/apis": `{"groups": [
+			{"name": "apps", "preferredVersion": {"groupVersion": "apps/v1", "version": "v1"}},
+			{"name": "stable.example.com", "preferredVersion": {"groupVersion": "stable.example.com/v1", "version": "v1"}},
+			{"name": "metrics.k8s.io", "preferredVersion": {"groupVersion": "metrics.k8s.io/v1beta1", "version": "v1beta1"}}
+		]}`,
+		"/apis/apps/v1":               `{"resources": [{"name": "deployments", "namespaced": true, "kind": "Deployment", "verbs": ["get", "list"]}]}`,
+		"/apis/stable.example.com/v1": `{"resources": [{"name": "crontabs", "namespaced": true, "kind": "CronTab", "verbs": ["get", "list"]}]}`,
+		"/openapi/v2": `{"definitions": {"io.k8s.api.core.v1.Pod": {
+			"properties": {"spec": {"type": "object"}},
+			"x-kubernetes-group-version-kind": [{"group": "", "kind": "Pod", "version": "v1"}]
+		}}}`,
+	}
+	var gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			// metrics.k8s.io stands in for an aggregated API that is down.
+			http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	api := config.APIConfig{
+		Name:       "k8s",
+		SpecType:   "kubernetes",
+		SpecURL:    server.URL,
+		Auth:       &config.AuthConfig{Type: "bearer", Token: "sa-token"},
+		Kubernetes: &config.KubernetesConfig{Groups: []string{"core", "stable.example.com", "metrics.k8s.io"}},
+	}
+	svc, err := loadKubernetes(context.Background(), NewFetcher(0), api, logging.Discard(), redact.NewRedactor())
+	if err != nil {
+		t.Fatalf("loadKubernetes: %v", err)
+	}
+	if gotAuth != "Bearer sa-token" {
+		t.Errorf("Authorization = %q, want the configured token", gotAuth)
+	}
+	tools := map[string]bool{}
+	for _, op := range svc.Operations {
+		tools[op.ToolName] = true
+	}
+	if len(tools) != 3 || !tools["k8s__pods"] || !tools["k8s__configmaps"] || !tools["k8s__crontabs"] {
+		t.Fatalf("tools = %v, want pods, configmaps and the crontabs CRD", tools)
+	}
+	for _, op := range svc.Operations {
+		if op.ToolName != "k8s__pods" {
+			continue
+		}
+		body := op.RESTComposite.Actions["create"].RequestBody.Schema
+		if _, ok := body["properties"].(map[string]any)["spec"]; !ok {
+			t.Errorf("pod create body = %v, want the OpenAPI schema", body)
+		}
+	}
+}
@@ -5,8 +5,9 @@ import (
 	"fmt"
 	"log/slog"
 	"net/url"
-	"os"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"skyline-mcp/internal/canonical"
@@ -18,40 +19,99 @@ import (
 	"skyline-mcp/internal/redact"
 )
 
+// loadConcurrency bounds how many APIs of a config load at once.
+const loadConcurrency = 8
+
+// defaultParseTimeout bounds how long an adapter may parse one spec.
+const defaultParseTimeout = 60 * time.Second
+
+// LoadServices loads every API of cfg, skipping those that fail. It fails
+// only when no API loads.
 func LoadServices(ctx context.Context, cfg *config.Config, logger *slog.Logger, redactor *redact.Redactor) ([]*canonical.Service, error) {
+	services, _, err := LoadServicesWithErrors(ctx, cfg, logger, redactor)
+	return services, err
+}
+
+// LoadServicesWithErrors loads the APIs of cfg concurrently and also
+// returns why each skipped API failed to load.
+func LoadServicesWithErrors(ctx context.Context, cfg *config.Config, logger *slog.Logger, redactor *redact.Redactor) ([]*canonical.Service, []canonical.LoadError, error) {
+	res, err := LoadServicesIncremental(ctx, cfg, nil, false, logger, redactor)
+	return res.Services, res.LoadErrors, err
+}
+
+// LoadServicesIncremental loads the APIs of cfg like LoadServicesWithErrors,
+// reusing what prev parsed for APIs whose config is unchanged. Without
+// refetch those APIs are not fetched at all, as after a profile edit; with
+// refetch every spec is fetched again but only changed documents are
+// parsed, as in a scheduled refresh. prev may be nil.
+func LoadServicesIncremental(ctx context.Context, cfg *config.Config, prev *ParsedSpecs, refetch bool, logger *slog.Logger, redactor *redact.Redactor) (*LoadResult, error) {
 	fetcher := NewFetcher(15 * time.Second)
-	adapters := []SpecAdapter{
-		NewOpenAPIAdapter(),
-		NewSwagger2Adapter(),
-		NewAsyncAPIAdapter(),
-		NewPostmanAdapter(),
-		NewInsomniaAdapter(),
-		NewGoogleDiscoveryAdapter(),
-		NewOpenRPCAdapter(),
-		NewGraphQLAdapter(),
-		NewJenkinsAdapter(),
-		NewWSDLAdapter(),
-		NewODataAdapter(),
-		NewRAMLAdapter(),
-		NewAPIBlueprintAdapter(),
-		NewCKANAdapter(),
+	adapters := specAdapters()
+
+	parseTimeout := defaultParseTimeout
+	maxOperations := 0
+	if sl := cfg.SpecLimits; sl != nil {
+		fetcher.SetMaxSize(sl.MaxSpecBytes)
+		if sl.ParseTimeoutSeconds > 0 {
+			parseTimeout = time.Duration(sl.ParseTimeoutSeconds) * time.Second
+		}
+		maxOperations = sl.MaxOperations
+	}
+
+	// Results are collected by index so services keep the config order.
+	loaded := make([]*canonical.Service, len(cfg.APIs))
+	loads := make([]*apiLoad, len(cfg.APIs))
+	errs := make([]error, len(cfg.APIs))
+	sem := make(chan struct{}, loadConcurrency)
+	var wg sync.WaitGroup
+	for i, api := range cfg.APIs {
+		load := &apiLoad{parseTimeout: parseTimeout, configHash: apiConfigHash(cfg, api)}
+		loads[i] = load
+		if previous := prev.lookup(api.Name, load.configHash); previous != nil {
+			if !refetch {
+				load.specHash, load.reused = previous.specHash, true
+				loaded[i] = cloneService(previous.service)
+				continue
+			}
+			load.previous = previous
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			loaded[i], errs[i] = loadSingleAPI(ctx, fetcher.WithHosts(api.Transport), adapters, api, i, load, logger, redactor)
+			if errs[i] == nil && maxOperations > 0 {
+				errs[i] = checkOperationCount(loaded[i], api, maxOperations)
+			}
+		}()
 	}
+	wg.Wait()
 
+	res := &LoadResult{Parsed: &ParsedSpecs{apis: map[string]*parsedAPI{}}, Unchanged: map[string]bool{}}
 	var services []*canonical.Service
 	for i, api := range cfg.APIs {
-		svc, err := loadSingleAPI(ctx, fetcher, adapters, api, i, logger, redactor)
-		if err != nil {
-			logger.Warn("skipping api", "api", api.Name, "index", i, "error", err)
+		if errs[i] != nil {
+			msg := redactor.Redact(errs[i].Error())
+			logger.Warn("skipping api", "api", api.Name, "index", i, "error", msg)
+			res.LoadErrors = append(res.LoadErrors, canonical.LoadError{Service: api.Name, Error: msg, At: time.Now().UTC()})
 			continue
 		}
-		services = append(services, svc)
+		// Keep a pristine copy: the passes below rename and filter the
+		// operations of the services they are given.
+		res.Parsed.apis[api.Name] = &parsedAPI{configHash: loads[i].configHash, specHash: loads[i].specHash, service: cloneService(loaded[i])}
+		if loads[i].reused {
+			res.Unchanged[api.Name] = true
+		}
+		services = append(services, loaded[i])
 	}
 
 	if len(services) == 0 && len(cfg.APIs) > 0 {
-		return nil, fmt.Errorf("all %d APIs failed to load", len(cfg.APIs))
+		return res, fmt.Errorf("all %d APIs failed to load", len(cfg.APIs))
 	}
 	if len(services) == 0 {
-		return []*canonical.Service{}, nil
+		res.Services = []*canonical.Service{}
+		return res, nil
 	}
 
 	// Apply built-in provider-specific overrides (before user filters)
@@ -60,13 +120,105 @@ func LoadServices(ctx context.Context, cfg *config.Config, logger *slog.Logger,
 	// Apply operation filters (user-configured)
 	services = ApplyOperationFilters(services, cfg.APIs)
 
+	// Merge configured constants and defaults into request bodies
+	services = ApplyBodyTemplates(services, cfg.APIs, logger)
+
+	// Let callers trim large responses to the fields they need
+	services = ApplyProjection(services, cfg.APIs, logger)
+
 	// Apply REST CRUD grouping to reduce tool count
 	services = ApplyRESTGrouping(services, cfg.APIs, logger)
 
-	return services, nil
+	// Append hand-written curl/.http operations
+	services = ApplyCustomOperations(services, cfg.APIs, logger)
+
+	// Rename tools per tool_naming, tool_prefix and tool_names
+	services = ApplyToolNaming(services, cfg, logger)
+
+	// Compile config workflows into tools over the loaded operations
+	services = ApplyWorkflows(services, cfg.Workflows, logger)
+
+	// Add Skyline's own tools (api_health, ...)
+	services = ApplyBuiltinTools(services, cfg)
+
+	res.Services = services
+	return res, nil
+}
+
+// specAdapters returns the adapters tried on fetched specs, in detection
+// order: the built-in ones, then those registered with RegisterAdapter.
+func specAdapters() []SpecAdapter {
+	registeredMu.RLock()
+	defer registeredMu.RUnlock()
+	return append(builtinAdapters(), registered...)
+}
+
+// builtinAdapters returns the adapters compiled into Skyline, in detection
+// order.
+func builtinAdapters() []SpecAdapter {
+	return []SpecAdapter{
+		NewOpenAPIAdapter(),
+		NewSwagger2Adapter(),
+		NewAsyncAPIAdapter(),
+		NewPostmanAdapter(),
+		NewInsomniaAdapter(),
+		NewHARAdapter(),
+		NewGoogleDiscoveryAdapter(),
+		NewAWSAdapter(),
+		NewOpenRPCAdapter(),
+		NewGraphQLAdapter(),
+		NewJenkinsAdapter(),
+		NewJiraAdapter(),
+		NewGitHubAdapter(),
+		NewPrometheusAdapter(),
+		NewWSDLAdapter(),
+		NewODataAdapter(),
+		NewRAMLAdapter(),
+		NewAPIBlueprintAdapter(),
+		NewCKANAdapter(),
+	}
+}
+
+// SpecFormats lists the spec_type values Skyline can load: the spec
+// adapters, including registered ones, plus the types built without a spec
+// document (grpc, kubernetes, salesforce, sql, email).
+func SpecFormats() []string {
+	return specFormats(specAdapters())
+}
+
+// BuiltinSpecFormats is SpecFormats without the registered adapters.
+func BuiltinSpecFormats() []string {
+	return specFormats(builtinAdapters())
 }
 
-func loadSingleAPI(ctx context.Context, fetcher *Fetcher, adapters []SpecAdapter, api config.APIConfig, idx int, logger *slog.Logger, redactor *redact.Redactor) (*canonical.Service, error) {
+func specFormats(adapters []SpecAdapter) []string {
+	formats := []string{"grpc", "kubernetes", "salesforce", "sql", "messaging", "ssh", "email"}
+	for _, adapter := range adapters {
+		formats = append(formats, adapter.Name())
+	}
+	slices.Sort(formats)
+	return formats
+}
+
+// checkOperationCount fails when svc has more operations than allowed,
+// counting only those the API's filter keeps.
+func checkOperationCount(svc *canonical.Service, api config.APIConfig, limit int) error {
+	ops := svc.Operations
+	if api.Filter != nil {
+		ops = filterOperations(ops, api.Filter)
+	}
+	if len(ops) > limit {
+		return fmt.Errorf("%d operations exceed the limit of %d (spec_limits.max_operations); narrow the API with a filter or raise the limit", len(ops), limit)
+	}
+	return nil
+}
+
+func loadSingleAPI(ctx context.Context, fetcher *Fetcher, adapters []SpecAdapter, api config.APIConfig, idx int, load *apiLoad, logger *slog.Logger, redactor *redact.Redactor) (*canonical.Service, error) {
+	// An API may consist only of custom_operations.
+	if api.SpecURL == "" && api.SpecFile == "" && api.SpecType == "" && len(api.CustomOperations) > 0 {
+		return &canonical.Service{Name: api.Name, BaseURL: strings.TrimRight(api.BaseURLOverride, "/")}, nil
+	}
+
 	// Special path for gRPC: use reflection instead of file-based spec.
 	if api.SpecType == "grpc" {
 		target := strings.TrimPrefix(strings.TrimPrefix(api.BaseURLOverride, "http://"), "https://")
@@ -78,6 +230,32 @@ func loadSingleAPI(ctx context.Context, fetcher *Fetcher, adapters []SpecAdapter
 		return svc, nil
 	}
 
+	// Special path for Kubernetes: build tools from the cluster's discovery API.
+	if api.SpecType == "kubernetes" {
+		return loadKubernetes(ctx, fetcher, api, logger, redactor)
+	}
+
+	// Special path for Salesforce: build tools from the org's describe results.
+	if api.SpecType == "salesforce" {
+		return loadSalesforce(ctx, fetcher, api, logger, redactor)
+	}
+
+	// Special path for SQL databases: build table tools from introspection.
+	if api.SpecType == "sql" {
+		return loadSQL(ctx, api, logger, redactor)
+	}
+
+	// Special path for message brokers: build produce/consume tools from
+	// the configured topics.
+	if api.SpecType == "messaging" {
+		return loadMessaging(api, logger)
+	}
+
+	// Special path for remote commands: one tool per allowlisted command.
+	if api.SpecType == "ssh" {
+		return loadSSH(api, logger)
+	}
+
 	// Special path for email: build tools from email config, no spec file needed.
 	if api.SpecType == "email" {
 		if api.Email == nil {
@@ -90,8 +268,20 @@ func loadSingleAPI(ctx context.Context, fetcher *Fetcher, adapters []SpecAdapter
 		return svc, nil
 	}
 
+	// A pinned GraphQL schema stands in for introspection, which some
+	// servers disable.
+	pinned := api.GraphQL != nil && api.GraphQL.Schema != ""
+	var schemaAuth *config.AuthConfig
+	if pinned {
+		api, schemaAuth = pinGraphQLSchema(api)
+	}
+
+	// A registered adapter named by spec_type parses the fetched spec
+	// without detection.
+	forced := registeredAdapter(api.SpecType)
+
 	// If spec_type is set to a known adapter, use it directly without fetching.
-	if api.SpecType != "" {
+	if api.SpecType != "" && forced == nil {
 		for _, adapter := range adapters {
 			if adapter.Name() == api.SpecType {
 				logger.Debug("using adapter directly", "adapter", api.SpecType, "api", api.Name)
@@ -103,12 +293,29 @@ func loadSingleAPI(ctx context.Context, fetcher *Fetcher, adapters []SpecAdapter
 	var raw []byte
 	var err error
 
-	if api.SpecFile != "" {
-		logger.Debug("loading spec from file", "api", api.Name, "file", api.SpecFile)
-		raw, err = os.ReadFile(api.SpecFile)
+	var files []string  // set when the spec comes from several local documents
+	var location string // where raw was read from; anchors relative $refs
+	var locationAuth *config.AuthConfig
+	specPath, local := localSpecPath(api)
+
+	if local {
+		files, err = expandLocalSpec(specPath)
+		if err != nil {
+			return nil, err
+		}
+		logger.Debug("loading spec from file", "api", api.Name, "file", specPath, "files", len(files))
+		raw, err = fetcher.ReadFile(files[0])
 		if err != nil {
 			return nil, fmt.Errorf("read file: %w", err)
 		}
+		location = files[0]
+	} else if pinned {
+		logger.Debug("loading pinned graphql schema", "api", api.Name, "url", redactor.Redact(api.SpecURL))
+		raw, err = fetcher.Fetch(ctx, api.SpecURL, schemaAuth)
+		if err != nil {
+			return nil, fmt.Errorf("fetch graphql schema: %w", err)
+		}
+		location, locationAuth = api.SpecURL, schemaAuth
 	} else {
 		specURL := api.SpecURL
 		fetchAuth := api.Auth // auth to use for spec fetch; nil for well-known public URLs
@@ -147,6 +354,7 @@ func loadSingleAPI(ctx context.Context, fetcher *Fetcher, adapters []SpecAdapter
 		if raw == nil {
 			logger.Debug("loading spec from URL", "api", api.Name, "url", redactor.Redact(specURL))
 			raw, err = fetcher.Fetch(ctx, specURL, fetchAuth)
+			location, locationAuth = specURL, fetchAuth
 			logger.Debug("fetch completed", "api", api.Name, "size", len(raw), "error", err)
 			if err != nil {
 				if looksLikeGraphQLEndpoint(specURL) {
@@ -160,13 +368,30 @@ func loadSingleAPI(ctx context.Context, fetcher *Fetcher, adapters []SpecAdapter
 		}
 	}
 
-	parseRaw := func(raw []byte) (*canonical.Service, string, error) {
-		for _, adapter := range adapters {
+	parseRaw := func(raw []byte, location string) (*canonical.Service, string, error) {
+		candidates := adapters
+		if forced != nil {
+			candidates = []SpecAdapter{forced}
+		}
+		for _, adapter := range candidates {
 			logger.Debug("trying adapter", "adapter", adapter.Name())
-			if !adapter.Detect(raw) {
+			if forced == nil && !adapter.Detect(raw) {
 				continue
 			}
 
+			// Inline $refs into other files/URLs; the OpenAPI loader only
+			// resolves references within the document it is given.
+			if (adapter.Name() == "openapi" || adapter.Name() == "swagger2") && location != "" && hasExternalRefs(raw) {
+				resolved, warnings, err := resolveExternalRefs(ctx, fetcher, raw, location, locationAuth)
+				if err != nil {
+					return nil, "", fmt.Errorf("resolve $refs: %w", err)
+				}
+				for _, w := range warnings {
+					logger.Warn("spec reference problem", "api", api.Name, "detail", redactor.Redact(w))
+				}
+				raw = resolved
+			}
+
 			// Add GraphQL optimization to context if this is a GraphQL API
 			parseCtx := ctx
 			if adapter.Name() == "graphql" {
@@ -178,9 +403,12 @@ func loadSingleAPI(ctx context.Context, fetcher *Fetcher, adapters []SpecAdapter
 				if opt != nil {
 					parseCtx = graphqlparser.SetOptimizationInContext(ctx, opt)
 				}
+				if api.GraphQL != nil {
+					parseCtx = graphqlparser.SetConfigInContext(parseCtx, api.GraphQL)
+				}
 			}
 
-			parsed, err := adapter.Parse(parseCtx, raw, api.Name, api.BaseURLOverride) //nolint:govet // intentional err shadow
+			parsed, err := parseWithTimeout(parseCtx, load.parseTimeout, adapter, raw, api.Name, api.BaseURLOverride) //nolint:govet // intentional err shadow
 			if err != nil {
 				return nil, "", fmt.Errorf("%s parse: %w", adapter.Name(), err)
 			}
@@ -189,20 +417,39 @@ func loadSingleAPI(ctx context.Context, fetcher *Fetcher, adapters []SpecAdapter
 		return nil, "", nil
 	}
 
+	if len(files) <= 1 && raw != nil {
+		load.specHash = hashSpec(raw)
+		if load.previous != nil && load.previous.specHash == load.specHash {
+			logger.Debug("spec unchanged, reusing parsed service", "api", api.Name)
+			load.reused = true
+			return cloneService(load.previous.service), nil
+		}
+	}
+
 	logger.Debug("parsing spec", "api", api.Name, "size", len(raw))
-	service, adapterName, err := parseRaw(raw)
+	service, adapterName, err := parseRaw(raw, location)
 	if err != nil {
 		logger.Debug("parse failed", "api", api.Name, "adapter", adapterName, "error", err)
 		return nil, fmt.Errorf("parse: %w", err)
 	}
-	if api.SpecFile == "" && looksLikeGraphQLEndpoint(api.SpecURL) {
+	if len(files) > 1 && service != nil {
+		service, err = parseLocalDocuments(fetcher, service, adapterName, files, api.Name, parseRaw)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if pinned && service != nil && adapterName != "graphql" {
+		return nil, fmt.Errorf("graphql.schema: %s is a %s spec, not GraphQL SDL or introspection JSON", api.GraphQL.Schema, adapterName)
+	}
+	if !local && !pinned && looksLikeGraphQLEndpoint(api.SpecURL) {
 		if service == nil || adapterName != "graphql" {
 			logger.Debug("retrying with graphql introspection", "api", api.Name, "url", redactor.Redact(api.SpecURL))
+			load.specHash = "" // the service does not come from raw
 			raw, err = fetcher.FetchGraphQLIntrospection(ctx, api.SpecURL, api.Auth)
 			if err != nil {
 				return nil, fmt.Errorf("graphql introspection: %w", err)
 			}
-			service, adapterName, err = parseRaw(raw)
+			service, adapterName, err = parseRaw(raw, "")
 			if err != nil {
 				return nil, fmt.Errorf("graphql parse: %w", err)
 			}
@@ -222,6 +469,60 @@ func loadSingleAPI(ctx context.Context, fetcher *Fetcher, adapters []SpecAdapter
 	return service, nil
 }
 
+// parseLocalDocuments parses the remaining documents of a multi-file spec
+// (first was parsed from files[0]) and merges them into one service. All
+// documents must be detected as the same spec type.
+func parseLocalDocuments(fetcher *Fetcher, first *canonical.Service, adapterName string, files []string, apiName string, parseRaw func([]byte, string) (*canonical.Service, string, error)) (*canonical.Service, error) {
+	services := []*canonical.Service{first}
+	for _, file := range files[1:] {
+		raw, err := fetcher.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("read file: %w", err)
+		}
+		svc, name, err := parseRaw(raw, file)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", file, err)
+		}
+		if svc == nil {
+			return nil, fmt.Errorf("%s: no supported spec detected", file)
+		}
+		if name != adapterName {
+			return nil, fmt.Errorf("%s is a %s spec but %s is %s; all documents of an API must share a type", file, name, files[0], adapterName)
+		}
+		services = append(services, svc)
+	}
+	return mergeServices(apiName, services, files)
+}
+
+// parseWithTimeout runs adapter.Parse, giving up after timeout (0 means
+// none). Parsers do not all watch ctx, so an abandoned parse finishes in
+// the background and its result is discarded.
+func parseWithTimeout(ctx context.Context, timeout time.Duration, adapter SpecAdapter, raw []byte, apiName, baseURL string) (*canonical.Service, error) {
+	if timeout <= 0 {
+		return adapter.Parse(ctx, raw, apiName, baseURL)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	type result struct {
+		svc *canonical.Service
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		svc, err := adapter.Parse(ctx, raw, apiName, baseURL)
+		done <- result{svc, err}
+	}()
+	select {
+	case r := <-done:
+		return r.svc, r.err
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("parsing %s took longer than %s (spec_limits.parse_timeout_seconds)", formatBytes(int64(len(raw))), timeout)
+		}
+		return nil, ctx.Err()
+	}
+}
+
 func looksLikeGraphQLEndpoint(specURL string) bool {
 	if specURL == "" {
 		return false
@@ -238,6 +539,35 @@ func looksLikeGraphQLEndpoint(specURL string) bool {
 	return strings.Contains(path, "graphql")
 }
 
+// pinGraphQLSchema points api at its graphql.schema: the schema is read
+// from there, and calls go to the endpoint in spec_url unless
+// base_url_override names one. It returns the auth to fetch a remote schema
+// with, which is the API's own only when the schema is on the endpoint's
+// host; a schema registry or CDN must not receive the API's credentials.
+func pinGraphQLSchema(api config.APIConfig) (config.APIConfig, *config.AuthConfig) {
+	if api.BaseURLOverride == "" {
+		api.BaseURLOverride = api.SpecURL
+	}
+	schema := api.GraphQL.Schema
+	api.SpecURL, api.SpecFile, api.SpecType = "", "", ""
+	lower := strings.ToLower(schema)
+	if strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "file://") {
+		api.SpecURL = schema
+	} else {
+		api.SpecFile = schema
+	}
+
+	schemaURL, err := url.Parse(api.SpecURL)
+	if err != nil || schemaURL.Host == "" {
+		return api, nil
+	}
+	endpoint, err := url.Parse(api.BaseURLOverride)
+	if err != nil || !strings.EqualFold(schemaURL.Host, endpoint.Host) {
+		return api, nil
+	}
+	return api, api.Auth
+}
+
 func looksLikeJiraBase(specURL string) bool {
 	if specURL == "" {
 		return false
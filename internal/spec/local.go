@@ -0,0 +1,186 @@
+package spec
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+)
+
+// localSpecExtensions are the files picked up when a spec source names a
+// directory rather than a file or glob.
+var localSpecExtensions = map[string]bool{
+	".json":     true,
+	".yaml":     true,
+	".yml":      true,
+	".graphql":  true,
+	".graphqls": true,
+	".gql":      true,
+	".wsdl":     true,
+	".xml":      true,
+	".raml":     true,
+	".apib":     true,
+	".har":      true,
+}
+
+// localSpecPath returns the path, directory or glob an API's spec is read
+// from: spec_file, or spec_url when it uses the file:// scheme. ok is false
+// for remote specs.
+func localSpecPath(api config.APIConfig) (string, bool) {
+	if api.SpecFile != "" {
+		return api.SpecFile, true
+	}
+	if rest, ok := strings.CutPrefix(api.SpecURL, "file://"); ok {
+		// file:///abs/path and file://./relative/path both map to the path as written.
+		return rest, true
+	}
+	return "", false
+}
+
+// expandLocalSpec resolves a spec source to the files it names, in a stable
+// order. A plain file yields itself; a directory yields its spec files
+// (non-recursive); a glob may use * and ? within a path segment and ** to
+// match any number of directories.
+func expandLocalSpec(source string) ([]string, error) {
+	if !hasGlobMeta(source) {
+		info, err := os.Stat(source)
+		if err != nil {
+			return nil, fmt.Errorf("read file: %w", err)
+		}
+		if !info.IsDir() {
+			return []string{source}, nil
+		}
+		entries, err := os.ReadDir(source)
+		if err != nil {
+			return nil, fmt.Errorf("read dir: %w", err)
+		}
+		var files []string
+		for _, entry := range entries {
+			if !entry.IsDir() && localSpecExtensions[strings.ToLower(filepath.Ext(entry.Name()))] {
+				files = append(files, filepath.Join(source, entry.Name()))
+			}
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no spec files in directory %s", source)
+		}
+		return files, nil
+	}
+
+	var files []string
+	if strings.Contains(source, "**") {
+		matches, err := walkGlob(source)
+		if err != nil {
+			return nil, err
+		}
+		files = matches
+	} else {
+		matches, err := filepath.Glob(source)
+		if err != nil {
+			return nil, fmt.Errorf("invalid spec glob %q: %w", source, err)
+		}
+		for _, m := range matches {
+			if info, err := os.Stat(m); err == nil && !info.IsDir() {
+				files = append(files, m)
+			}
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files match %s", source)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func hasGlobMeta(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// walkGlob expands a pattern containing ** by walking from the longest
+// directory prefix without wildcards.
+func walkGlob(pattern string) ([]string, error) {
+	pattern = filepath.Clean(pattern)
+	segments := strings.Split(filepath.ToSlash(pattern), "/")
+	rootSegs := 0
+	for rootSegs < len(segments) && !hasGlobMeta(segments[rootSegs]) {
+		rootSegs++
+	}
+	root := strings.Join(segments[:rootSegs], "/")
+	switch {
+	case root == "" && strings.HasPrefix(filepath.ToSlash(pattern), "/"):
+		root = "/"
+	case root == "":
+		root = "."
+	}
+	rest := segments[rootSegs:]
+
+	var files []string
+	err := filepath.WalkDir(filepath.FromSlash(root), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(filepath.FromSlash(root), path)
+		if err != nil {
+			return nil
+		}
+		if matchSegments(rest, strings.Split(filepath.ToSlash(rel), "/")) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("expand %s: %w", pattern, err)
+	}
+	return files, nil
+}
+
+// matchSegments matches path segments against glob segments, where a "**"
+// segment matches zero or more path segments.
+func matchSegments(pattern, path []string) bool {
+	if len(pattern) == 0 {
+		return len(path) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(path); i++ {
+			if matchSegments(pattern[1:], path[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(path) == 0 {
+		return false
+	}
+	ok, err := filepath.Match(pattern[0], path[0])
+	if err != nil || !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], path[1:])
+}
+
+// mergeServices combines services parsed from several documents of one API
+// into a single service. Tool names must be unique across the documents.
+func mergeServices(name string, services []*canonical.Service, files []string) (*canonical.Service, error) {
+	merged := &canonical.Service{Name: name}
+	seen := make(map[string]string)
+	for i, svc := range services {
+		if merged.BaseURL == "" {
+			merged.BaseURL = svc.BaseURL
+		}
+		for _, op := range svc.Operations {
+			if prev, dup := seen[op.ToolName]; dup {
+				return nil, fmt.Errorf("operation %s is defined in both %s and %s", op.ToolName, prev, files[i])
+			}
+			seen[op.ToolName] = files[i]
+			merged.Operations = append(merged.Operations, op)
+		}
+	}
+	return merged, nil
+}
@@ -0,0 +1,247 @@
+package spec
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/logging"
+	"skyline-mcp/internal/redact"
+)
+
+func openAPIDoc(path, opID string) string {
+	return `openapi: 3.0.0
+info: {title: test, version: "1"}
+servers: [{url: "https://api.example.com"}]
+paths:
+  ` + path + `:
+    get:
+      operationId: ` + opID + `
+      responses: {"200": {description: ok}}
+`
+}
+
+func writeSpecFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestExpandLocalSpec(t *testing.T) {
+	dir := writeSpecFiles(t, map[string]string{
+		"pets.yaml":         "a",
+		"owners.json":       "b",
+		"README.md":         "c",
+		"v2/stores.yaml":    "d",
+		"v2/deep/toys.yaml": "e",
+	})
+
+	tests := []struct {
+		source string
+		want   []string
+	}{
+		{"pets.yaml", []string{"pets.yaml"}},
+		{".", []string{"owners.json", "pets.yaml"}},
+		{"*.yaml", []string{"pets.yaml"}},
+		{"v2/*.yaml", []string{"v2/stores.yaml"}},
+		{"**/*.yaml", []string{"pets.yaml", "v2/deep/toys.yaml", "v2/stores.yaml"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			got, err := expandLocalSpec(filepath.Join(dir, filepath.FromSlash(tt.source)))
+			if err != nil {
+				t.Fatalf("expandLocalSpec: %v", err)
+			}
+			rel := make([]string, len(got))
+			for i, p := range got {
+				r, _ := filepath.Rel(dir, p)
+				rel[i] = filepath.ToSlash(r)
+			}
+			sort.Strings(rel)
+			if strings.Join(rel, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("got %v, want %v", rel, tt.want)
+			}
+		})
+	}
+
+	if _, err := expandLocalSpec(filepath.Join(dir, "*.proto")); err == nil {
+		t.Fatal("expected an error when nothing matches")
+	}
+}
+
+func TestLoadServicesMergesLocalGlob(t *testing.T) {
+	dir := writeSpecFiles(t, map[string]string{
+		"specs/pets.yaml":   openAPIDoc("/pets", "listPets"),
+		"specs/owners.yaml": openAPIDoc("/owners", "listOwners"),
+	})
+
+	for _, api := range []config.APIConfig{
+		{Name: "shop", SpecFile: filepath.Join(dir, "specs", "*.yaml")},
+		{Name: "shop", SpecURL: "file://" + filepath.Join(dir, "specs")},
+	} {
+		cfg := &config.Config{APIs: []config.APIConfig{api}}
+		services, err := LoadServices(context.Background(), cfg, logging.Discard(), redact.NewRedactor())
+		if err != nil {
+			t.Fatalf("LoadServices: %v", err)
+		}
+		// The built-in skyline service is always appended last.
+		if len(services) != 2 || services[1].Name != config.BuiltinServiceName {
+			t.Fatalf("services = %d, want 1 merged service plus built-ins", len(services))
+		}
+		svc := services[0]
+		if svc.BaseURL != "https://api.example.com" {
+			t.Errorf("BaseURL = %q", svc.BaseURL)
+		}
+		var tools []string
+		for _, op := range svc.Operations {
+			tools = append(tools, op.ToolName)
+		}
+		sort.Strings(tools)
+		if strings.Join(tools, ",") != "shop__listOwners,shop__listPets" {
+			t.Errorf("tools = %v", tools)
+		}
+	}
+}
+
+func TestLoadLocalSpecRejectsConflicts(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			name: "duplicate operation",
+			files: map[string]string{
+				"a.yaml": openAPIDoc("/pets", "listPets"),
+				"b.yaml": openAPIDoc("/animals", "listPets"),
+			},
+			want: "defined in both",
+		},
+		{
+			name: "mixed spec types",
+			files: map[string]string{
+				"a.yaml":    openAPIDoc("/pets", "listPets"),
+				"b.graphql": "type Query { pets: [String] }",
+			},
+			want: "must share a type",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeSpecFiles(t, tt.files)
+			api := config.APIConfig{Name: "shop", SpecFile: dir, BaseURLOverride: "https://api.example.com"}
+			_, err := loadSingleAPI(context.Background(), NewFetcher(0), []SpecAdapter{NewOpenAPIAdapter(), NewGraphQLAdapter()}, api, 0, &apiLoad{}, logging.Discard(), redact.NewRedactor())
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("err = %v, want substring %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadServicesIsolatesFailingAPIs(t *testing.T) {
+	files := map[string]string{}
+	var apis []config.APIConfig
+	for i := 0; i < 2*loadConcurrency; i++ {
+		name := "api" + string(rune('a'+i))
+		files[name+".yaml"] = openAPIDoc("/"+name, "list")
+		apis = append(apis, config.APIConfig{Name: name, SpecFile: name + ".yaml"})
+	}
+	dir := writeSpecFiles(t, files)
+	for i := range apis {
+		apis[i].SpecFile = filepath.Join(dir, apis[i].SpecFile)
+	}
+	apis[3].SpecFile = filepath.Join(dir, "missing.yaml")
+	apis[9].SpecFile = filepath.Join(dir, "missing.yaml")
+
+	cfg := &config.Config{APIs: apis}
+	services, loadErrors, err := LoadServicesWithErrors(context.Background(), cfg, logging.Discard(), redact.NewRedactor())
+	if err != nil {
+		t.Fatalf("LoadServicesWithErrors: %v", err)
+	}
+	if len(loadErrors) != 2 || loadErrors[0].Service != "apid" || loadErrors[1].Service != "apij" || loadErrors[0].Error == "" {
+		t.Fatalf("loadErrors = %+v", loadErrors)
+	}
+	var names []string
+	for _, svc := range services {
+		if svc.Name != config.BuiltinServiceName {
+			names = append(names, svc.Name)
+		}
+	}
+	if strings.Join(names, ",") != "apia,apib,apic,apie,apif,apig,apih,apii,apik,apil,apim,apin,apio,apip" {
+		t.Fatalf("services out of order: %v", names)
+	}
+
+	cfg.APIs = apis[3:4]
+	if _, loadErrors, err = LoadServicesWithErrors(context.Background(), cfg, logging.Discard(), redact.NewRedactor()); err == nil || len(loadErrors) != 1 {
+		t.Fatalf("all failing: err = %v, loadErrors = %+v", err, loadErrors)
+	}
+}
+
+func TestLoadServicesSpecLimits(t *testing.T) {
+	dir := writeSpecFiles(t, map[string]string{
+		"small.yaml": openAPIDoc("/pets", "listPets"),
+		"large.yaml": openAPIDoc("/pets", "listPets") + "# " + strings.Repeat("x", 4096) + "\n",
+		"wide.yaml": openAPIDoc("/pets", "listPets") + `  /owners:
+    get:
+      operationId: listOwners
+      responses: {"200": {description: ok}}
+`,
+	})
+	cfg := &config.Config{
+		APIs: []config.APIConfig{
+			{Name: "small", SpecFile: filepath.Join(dir, "small.yaml")},
+			{Name: "large", SpecFile: filepath.Join(dir, "large.yaml")},
+			{Name: "wide", SpecFile: filepath.Join(dir, "wide.yaml")},
+			{Name: "narrowed", SpecFile: filepath.Join(dir, "wide.yaml"), Filter: &config.OperationFilterEnhanced{
+				Mode: "allowlist", Operations: []config.OperationPattern{{OperationID: "listPets"}},
+			}},
+		},
+		SpecLimits: &config.SpecLimitsConfig{MaxSpecBytes: 2048, MaxOperations: 1},
+	}
+	services, loadErrors, err := LoadServicesWithErrors(context.Background(), cfg, logging.Discard(), redact.NewRedactor())
+	if err != nil {
+		t.Fatalf("LoadServicesWithErrors: %v", err)
+	}
+	if len(services) != 3 || services[0].Name != "small" || services[1].Name != "narrowed" {
+		t.Fatalf("services = %v", services)
+	}
+	if len(loadErrors) != 2 ||
+		!strings.Contains(loadErrors[0].Error, "spec_limits.max_spec_bytes") ||
+		!strings.Contains(loadErrors[1].Error, "2 operations exceed the limit of 1") {
+		t.Fatalf("loadErrors = %+v", loadErrors)
+	}
+}
+
+type slowAdapter struct{ delay time.Duration }
+
+func (slowAdapter) Name() string           { return "slow" }
+func (slowAdapter) Detect(raw []byte) bool { return true }
+func (a slowAdapter) Parse(ctx context.Context, raw []byte, apiName, baseURLOverride string) (*canonical.Service, error) {
+	time.Sleep(a.delay)
+	return &canonical.Service{Name: apiName}, nil
+}
+
+func TestParseWithTimeout(t *testing.T) {
+	if _, err := parseWithTimeout(context.Background(), 10*time.Millisecond, slowAdapter{time.Second}, []byte("{}"), "slow", ""); err == nil || !strings.Contains(err.Error(), "parse_timeout_seconds") {
+		t.Fatalf("err = %v, want a parse timeout", err)
+	}
+	svc, err := parseWithTimeout(context.Background(), time.Second, slowAdapter{}, []byte("{}"), "fast", "")
+	if err != nil || svc.Name != "fast" {
+		t.Fatalf("svc = %v, err = %v", svc, err)
+	}
+}
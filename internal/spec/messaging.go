@@ -0,0 +1,26 @@
+package spec
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/parsers/messaging"
+)
+
+// loadMessaging builds a spec_type: messaging service from its topics.
+// Nothing is fetched and the brokers are not contacted until a tool runs.
+func loadMessaging(api config.APIConfig, logger *slog.Logger) (*canonical.Service, error) {
+	if api.Messaging == nil {
+		return nil, fmt.Errorf("messaging config is required for spec_type messaging")
+	}
+	svc, err := messaging.BuildService(api.Name, api.Messaging)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("loading messaging service", "api", api.Name, "broker", strings.ToLower(api.Messaging.Broker),
+		"topics", len(api.Messaging.Topics))
+	return svc, nil
+}
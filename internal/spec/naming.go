@@ -0,0 +1,72 @@
+package spec
+
+import (
+	"log/slog"
+	"strings"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+)
+
+// ApplyToolNaming renames the APIs' tools according to the config's
+// tool_naming strategy and each API's tool_prefix and tool_names. Collisions
+// are not resolved here; the registry rejects duplicate names.
+func ApplyToolNaming(services []*canonical.Service, cfg *config.Config, logger *slog.Logger) []*canonical.Service {
+	naming := cfg.ToolNaming
+	if naming == nil {
+		naming = &config.ToolNamingConfig{}
+	}
+	apis := map[string]config.APIConfig{}
+	for _, api := range cfg.APIs {
+		apis[api.Name] = api
+	}
+	for _, svc := range services {
+		api, ok := apis[svc.Name]
+		if !ok {
+			continue
+		}
+		if cfg.ToolNaming == nil && api.ToolPrefix == "" && len(api.ToolNames) == 0 {
+			continue
+		}
+		used := map[string]bool{}
+		for _, op := range svc.Operations {
+			if name, ok := api.ToolNames[op.ID]; ok {
+				op.ToolName = name
+				used[op.ID] = true
+				continue
+			}
+			if name, ok := api.ToolNames[op.ToolName]; ok {
+				used[op.ToolName] = true
+				op.ToolName = name
+				continue
+			}
+			op.ToolName = namedTool(svc.Name, op.ToolName, api.ToolPrefix, naming)
+		}
+		for key := range api.ToolNames {
+			if !used[key] {
+				logger.Warn("tool_names entry matches no operation", "api", api.Name, "operation", key)
+			}
+		}
+	}
+	return services
+}
+
+// namedTool rebuilds a generated tool name (<api>__<operation>) with the
+// given prefix and strategy.
+func namedTool(apiName, toolName, prefix string, naming *config.ToolNamingConfig) string {
+	operation := strings.TrimPrefix(toolName, canonical.SanitizeName(apiName)+"__")
+	switch naming.Case {
+	case "snake":
+		operation = canonical.SnakeCase(operation)
+	case "camel":
+		operation = canonical.CamelCase(operation)
+	}
+	name := operation
+	if naming.Prefix != "none" {
+		if prefix == "" {
+			prefix = apiName
+		}
+		name = canonical.SanitizeName(prefix) + "__" + operation
+	}
+	return canonical.ShortenName(name, naming.MaxLength)
+}
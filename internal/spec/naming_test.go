@@ -0,0 +1,71 @@
+package spec
+
+import (
+	"strings"
+	"testing"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/logging"
+)
+
+func TestApplyToolNaming(t *testing.T) {
+	newServices := func() []*canonical.Service {
+		return []*canonical.Service{{Name: "github", Operations: []*canonical.Operation{
+			{ID: "repos/get-content", ToolName: canonical.ToolName("github", "repos/get-content")},
+			{ID: "listPullRequestReviewComments", ToolName: canonical.ToolName("github", "listPullRequestReviewComments")},
+			{ID: "getHTTPStatus", ToolName: canonical.ToolName("github", "getHTTPStatus")},
+		}}}
+	}
+	names := func(services []*canonical.Service) []string {
+		var out []string
+		for _, op := range services[0].Operations {
+			out = append(out, op.ToolName)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name   string
+		naming *config.ToolNamingConfig
+		api    config.APIConfig
+		want   []string
+	}{
+		{
+			name: "default",
+			api:  config.APIConfig{Name: "github"},
+			want: []string{"github__repos_get-content", "github__listPullRequestReviewComments", "github__getHTTPStatus"},
+		},
+		{
+			name:   "snake with prefix override",
+			naming: &config.ToolNamingConfig{Case: "snake"},
+			api:    config.APIConfig{Name: "github", ToolPrefix: "gh"},
+			want:   []string{"gh__repos_get_content", "gh__list_pull_request_review_comments", "gh__get_http_status"},
+		},
+		{
+			name:   "camel without prefix and a rename",
+			naming: &config.ToolNamingConfig{Prefix: "none", Case: "camel"},
+			api:    config.APIConfig{Name: "github", ToolNames: map[string]string{"getHTTPStatus": "status"}},
+			want:   []string{"reposGetContent", "listPullRequestReviewComments", "status"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{APIs: []config.APIConfig{tt.api}, ToolNaming: tt.naming}
+			got := names(ApplyToolNaming(newServices(), cfg, logging.Discard()))
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("names = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	cfg := &config.Config{APIs: []config.APIConfig{{Name: "github"}}, ToolNaming: &config.ToolNamingConfig{MaxLength: 24}}
+	got := names(ApplyToolNaming(newServices(), cfg, logging.Discard()))
+	again := names(ApplyToolNaming(newServices(), cfg, logging.Discard()))
+	if len(got[1]) != 24 || !strings.HasPrefix(got[1], "github__listPul_") || got[1] != again[1] {
+		t.Fatalf("shortened name %q (again %q) is not a stable 24-char name", got[1], again[1])
+	}
+	if got[2] != "github__getHTTPStatus" {
+		t.Fatalf("short name changed: %q", got[2])
+	}
+}
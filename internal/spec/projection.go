@@ -0,0 +1,109 @@
+package spec
+
+import (
+	"log/slog"
+	"maps"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+)
+
+// defaultProjectionMinFields is how many response properties make an
+// operation's response large enough for a projection argument.
+const defaultProjectionMinFields = 10
+
+// ApplyProjection adds canonical.ProjectionArgument to the operations of
+// APIs with a projection config whose responses are large, and to those the
+// config names. It runs before REST grouping so composite tools inherit the
+// argument.
+func ApplyProjection(services []*canonical.Service, apis []config.APIConfig, logger *slog.Logger) []*canonical.Service {
+	configs := map[string]*config.ProjectionConfig{}
+	for _, api := range apis {
+		if api.Projection != nil {
+			configs[api.Name] = api.Projection
+		}
+	}
+	for _, svc := range services {
+		cfg := configs[svc.Name]
+		if cfg == nil {
+			continue
+		}
+		minFields := cfg.MinFields
+		if minFields == 0 {
+			minFields = defaultProjectionMinFields
+		}
+		named := map[string]bool{}
+		for _, name := range cfg.Operations {
+			named[name] = false
+		}
+		for _, op := range svc.Operations {
+			_, byID := named[op.ID]
+			_, byTool := named[op.ToolName]
+			if byID {
+				named[op.ID] = true
+			}
+			if byTool {
+				named[op.ToolName] = true
+			}
+			if !byID && !byTool && responseFieldCount(op.ResponseSchema) < minFields {
+				continue
+			}
+			addProjectionArgument(op, logger)
+		}
+		for name, used := range named {
+			if !used {
+				logger.Warn("projection entry matches no operation", "api", svc.Name, "operation", name)
+			}
+		}
+	}
+	return services
+}
+
+// responseFieldCount returns the number of properties of a response
+// object, of the items of a response list, or of the items of the list in
+// an envelope such as {"items": [...], "total": 3}, whichever is largest.
+func responseFieldCount(schema map[string]any) int {
+	if items, ok := schema["items"].(map[string]any); ok {
+		return responseFieldCount(items)
+	}
+	props, _ := schema["properties"].(map[string]any)
+	count := len(props)
+	for _, prop := range props {
+		sub, _ := prop.(map[string]any)
+		if items, ok := sub["items"].(map[string]any); ok {
+			if n := responseFieldCount(items); n > count {
+				count = n
+			}
+		}
+	}
+	return count
+}
+
+// addProjectionArgument adds the projection argument to op's input schema.
+// Schemas may be shared between operations, so they are copied.
+func addProjectionArgument(op *canonical.Operation, logger *slog.Logger) {
+	if op.Protocol == "builtin" || op.Workflow != nil || op.Projection {
+		return
+	}
+	props, _ := op.InputSchema["properties"].(map[string]any)
+	if _, taken := props[canonical.ProjectionArgument]; taken {
+		logger.Warn("operation already has a projection argument; skipping", "tool", op.ToolName, "argument", canonical.ProjectionArgument)
+		return
+	}
+	schema := maps.Clone(op.InputSchema)
+	if schema == nil {
+		schema = map[string]any{"type": "object"}
+	}
+	props = maps.Clone(props)
+	if props == nil {
+		props = map[string]any{}
+	}
+	props[canonical.ProjectionArgument] = map[string]any{
+		"type":        "array",
+		"items":       map[string]any{"type": "string"},
+		"description": "Return only these response fields, as dot paths such as id, name or owner.login. For lists they apply to each item. Omit to return everything.",
+	}
+	schema["properties"] = props
+	op.InputSchema = schema
+	op.Projection = true
+}
@@ -0,0 +1,43 @@
+package spec
+
+import (
+	"fmt"
+	"testing"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/logging"
+)
+
+func TestApplyProjection(t *testing.T) {
+	wide := map[string]any{}
+	for i := range 12 {
+		wide[fmt.Sprintf("field%d", i)] = map[string]any{"type": "string"}
+	}
+	shared := map[string]any{"type": "object", "properties": map[string]any{"q": map[string]any{"type": "string"}}}
+	list := &canonical.Operation{ID: "listRepos", ToolName: "gh__listRepos", InputSchema: shared,
+		ResponseSchema: map[string]any{"type": "object", "properties": map[string]any{
+			"total": map[string]any{"type": "integer"},
+			"items": map[string]any{"type": "array", "items": map[string]any{"type": "object", "properties": wide}},
+		}}}
+	small := &canonical.Operation{ID: "getRate", ToolName: "gh__getRate", InputSchema: shared,
+		ResponseSchema: map[string]any{"type": "object", "properties": map[string]any{"limit": map[string]any{"type": "integer"}}}}
+	named := &canonical.Operation{ID: "getUser", ToolName: "gh__getUser", InputSchema: shared}
+	services := []*canonical.Service{{Name: "gh", Operations: []*canonical.Operation{list, small, named}}}
+	apis := []config.APIConfig{{Name: "gh", Projection: &config.ProjectionConfig{Operations: []string{"gh__getUser"}}}}
+
+	ApplyProjection(services, apis, logging.Discard())
+
+	for _, op := range []*canonical.Operation{list, named} {
+		props := op.InputSchema["properties"].(map[string]any)
+		if !op.Projection || props[canonical.ProjectionArgument] == nil {
+			t.Fatalf("%s: no projection argument", op.ID)
+		}
+	}
+	if small.Projection {
+		t.Fatal("small response got a projection argument")
+	}
+	if _, ok := shared["properties"].(map[string]any)[canonical.ProjectionArgument]; ok {
+		t.Fatal("shared input schema was modified")
+	}
+}
@@ -0,0 +1,23 @@
+package spec
+
+import (
+	"context"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/parsers/prometheus"
+)
+
+// PrometheusAdapter handles the query API of Prometheus and compatible
+// servers such as VictoriaMetrics through a curated tool set. It is used
+// for spec_type: prometheus or a /api/v1/status/buildinfo response.
+type PrometheusAdapter struct{}
+
+func NewPrometheusAdapter() *PrometheusAdapter { return &PrometheusAdapter{} }
+
+func (a *PrometheusAdapter) Name() string { return "prometheus" }
+
+func (a *PrometheusAdapter) Detect(raw []byte) bool { return prometheus.LooksLikeBuildInfo(raw) }
+
+func (a *PrometheusAdapter) Parse(ctx context.Context, raw []byte, apiName, baseURLOverride string) (*canonical.Service, error) {
+	return prometheus.ParseToCanonical(ctx, raw, apiName, baseURLOverride)
+}
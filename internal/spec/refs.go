@@ -0,0 +1,284 @@
+package spec
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"skyline-mcp/internal/config"
+)
+
+// maxRefDocuments bounds how many external documents one spec may pull in
+// through $refs, so a misbehaving spec cannot make the loader crawl the web.
+const maxRefDocuments = 50
+
+// externalRefRE matches a $ref whose value does not start with "#", i.e. one
+// that points into another document.
+var externalRefRE = regexp.MustCompile(`["']?\$ref["']?\s*:\s*["']?[^#"'\s]`)
+
+// hasExternalRefs is a cheap pre-check so specs without external references
+// are passed to the parser untouched.
+func hasExternalRefs(raw []byte) bool {
+	return externalRefRE.Match(raw)
+}
+
+// refResolver inlines external $refs of a JSON/YAML spec document.
+type refResolver struct {
+	ctx      context.Context
+	fetcher  *Fetcher
+	auth     *config.AuthConfig
+	authHost string // auth is only sent to the host serving the root spec
+	root     string
+	docs     map[string]any
+	resolved map[string]any
+	stack    []string
+	warnings []string
+}
+
+// resolveExternalRefs returns raw (as JSON) with every $ref into another
+// document replaced by the referenced content. location is where raw was
+// read from (a URL or file path) and anchors relative references. Local
+// references ("#/components/...") of the root document are left for the
+// spec parser. Documents fetched remotely may only reference other remote
+// documents. Circular references and references that cannot be loaded are
+// replaced by a placeholder schema and reported as warnings.
+func resolveExternalRefs(ctx context.Context, fetcher *Fetcher, raw []byte, location string, auth *config.AuthConfig) ([]byte, []string, error) {
+	doc, err := decodeRefDocument(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	r := &refResolver{
+		ctx:      ctx,
+		fetcher:  fetcher,
+		auth:     auth,
+		root:     location,
+		docs:     map[string]any{location: doc},
+		resolved: map[string]any{},
+	}
+	if u, err := url.Parse(location); err == nil && isRemoteRef(u) {
+		r.authHost = u.Host
+	}
+	out, err := json.Marshal(r.resolve(doc, location))
+	if err != nil {
+		return nil, nil, fmt.Errorf("encode resolved spec: %w", err)
+	}
+	return out, r.warnings, nil
+}
+
+func (r *refResolver) resolve(node any, docLoc string) any {
+	switch v := node.(type) {
+	case map[string]any:
+		if ref, ok := v["$ref"].(string); ok {
+			if strings.HasPrefix(ref, "#") && docLoc == r.root {
+				return v
+			}
+			return r.inline(v, ref, docLoc)
+		}
+		out := make(map[string]any, len(v))
+		for key, val := range v {
+			out[key] = r.resolve(val, docLoc)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = r.resolve(item, docLoc)
+		}
+		return out
+	}
+	return node
+}
+
+// inline replaces a $ref node with the (recursively resolved) target.
+func (r *refResolver) inline(node map[string]any, ref, docLoc string) any {
+	target, fragment, _ := strings.Cut(ref, "#")
+	loc := docLoc
+	if target != "" {
+		var err error
+		if loc, err = resolveRefLocation(docLoc, target); err != nil {
+			return r.unresolved(ref, err)
+		}
+	}
+	key := loc + "#" + fragment
+	if cached, ok := r.resolved[key]; ok {
+		return withSiblings(cached, node)
+	}
+	for _, active := range r.stack {
+		if active == key {
+			r.warnings = append(r.warnings, fmt.Sprintf("circular $ref %s", ref))
+			return map[string]any{"type": "object", "description": "circular reference to " + ref}
+		}
+	}
+
+	doc, err := r.load(loc)
+	if err != nil {
+		return r.unresolved(ref, err)
+	}
+	sub, err := jsonPointer(doc, fragment)
+	if err != nil {
+		return r.unresolved(ref, err)
+	}
+
+	r.stack = append(r.stack, key)
+	resolved := r.resolve(sub, loc)
+	r.stack = r.stack[:len(r.stack)-1]
+	r.resolved[key] = resolved
+	return withSiblings(resolved, node)
+}
+
+func (r *refResolver) unresolved(ref string, err error) any {
+	r.warnings = append(r.warnings, fmt.Sprintf("unresolved $ref %s: %v", ref, err))
+	return map[string]any{"description": "unresolved reference " + ref}
+}
+
+// load returns the parsed document at loc, fetching it at most once.
+func (r *refResolver) load(loc string) (any, error) {
+	if doc, ok := r.docs[loc]; ok {
+		return doc, nil
+	}
+	if len(r.docs) > maxRefDocuments {
+		return nil, fmt.Errorf("more than %d external documents referenced", maxRefDocuments)
+	}
+
+	var raw []byte
+	var err error
+	if u, perr := url.Parse(loc); perr == nil && isRemoteRef(u) {
+		var auth *config.AuthConfig
+		if u.Host == r.authHost {
+			auth = r.auth
+		}
+		raw, err = r.fetcher.Fetch(r.ctx, loc, auth)
+	} else {
+		raw, err = r.fetcher.ReadFile(loc)
+	}
+	if err != nil {
+		return nil, err
+	}
+	doc, err := decodeRefDocument(raw)
+	if err != nil {
+		return nil, err
+	}
+	r.docs[loc] = doc
+	return doc, nil
+}
+
+// resolveRefLocation resolves the document part of a $ref against the
+// location of the document containing it.
+func resolveRefLocation(base, target string) (string, error) {
+	baseURL, err := url.Parse(base)
+	if err == nil && isRemoteRef(baseURL) {
+		ref, err := url.Parse(target)
+		if err != nil {
+			return "", err
+		}
+		resolved := baseURL.ResolveReference(ref)
+		if !isRemoteRef(resolved) {
+			return "", fmt.Errorf("remote spec may not reference local file %s", target)
+		}
+		resolved.Fragment = ""
+		return resolved.String(), nil
+	}
+
+	if u, err := url.Parse(target); err == nil && isRemoteRef(u) {
+		return target, nil
+	}
+	if path, ok := strings.CutPrefix(target, "file://"); ok {
+		return path, nil
+	}
+	if filepath.IsAbs(target) {
+		return target, nil
+	}
+	return filepath.Join(filepath.Dir(base), filepath.FromSlash(target)), nil
+}
+
+func isRemoteRef(u *url.URL) bool {
+	return u.Scheme == "http" || u.Scheme == "https"
+}
+
+// jsonPointer resolves an RFC 6901 pointer (the fragment of a $ref).
+func jsonPointer(doc any, pointer string) (any, error) {
+	if pointer == "" || pointer == "/" {
+		return doc, nil
+	}
+	if unescaped, err := url.PathUnescape(pointer); err == nil {
+		pointer = unescaped
+	}
+	node := doc
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch v := node.(type) {
+		case map[string]any:
+			next, ok := v[token]
+			if !ok {
+				return nil, fmt.Errorf("pointer %s: %q not found", pointer, token)
+			}
+			node = next
+		case []any:
+			var i int
+			if _, err := fmt.Sscanf(token, "%d", &i); err != nil || i < 0 || i >= len(v) {
+				return nil, fmt.Errorf("pointer %s: bad index %q", pointer, token)
+			}
+			node = v[i]
+		default:
+			return nil, fmt.Errorf("pointer %s: %q not found", pointer, token)
+		}
+	}
+	return node, nil
+}
+
+// withSiblings applies keys written next to a $ref (e.g. a description) on
+// top of the referenced content.
+func withSiblings(resolved any, node map[string]any) any {
+	m, ok := resolved.(map[string]any)
+	if !ok || len(node) <= 1 {
+		return resolved
+	}
+	out := make(map[string]any, len(m)+len(node))
+	for k, v := range m {
+		out[k] = v
+	}
+	for k, v := range node {
+		if k != "$ref" {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// decodeRefDocument parses a JSON or YAML document into plain maps with
+// string keys (YAML allows e.g. integer response codes as keys).
+func decodeRefDocument(raw []byte) (any, error) {
+	var doc any
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("parse referenced document: %w", err)
+	}
+	return stringKeys(doc), nil
+}
+
+func stringKeys(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for k, val := range v {
+			v[k] = stringKeys(val)
+		}
+		return v
+	case map[any]any:
+		out := make(map[string]any, len(v))
+		for k, val := range v {
+			out[fmt.Sprint(k)] = stringKeys(val)
+		}
+		return out
+	case []any:
+		for i, item := range v {
+			v[i] = stringKeys(item)
+		}
+		return v
+	}
+	return value
+}
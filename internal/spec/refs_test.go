@@ -0,0 +1,139 @@
+package spec
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/logging"
+	"skyline-mcp/internal/redact"
+)
+
+func TestLoadServicesResolvesExternalFileRefs(t *testing.T) {
+	dir := writeSpecFiles(t, map[string]string{
+		"openapi.yaml": `openapi: 3.0.0
+info: {title: pets, version: "1"}
+servers: [{url: "https://api.example.com"}]
+paths:
+  /pets:
+    post:
+      operationId: createPet
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema: {$ref: "./schemas/pet.yaml#/Pet"}
+      responses:
+        200:
+          description: ok
+          content:
+            application/json:
+              schema: {$ref: "#/components/schemas/Created"}
+components:
+  schemas:
+    Created:
+      type: object
+      properties:
+        id: {type: string}
+`,
+		"schemas/pet.yaml": `Pet:
+  type: object
+  required: [name]
+  properties:
+    name: {type: string}
+    tag: {$ref: "#/Tag"}
+    owner: {$ref: "../common.yaml#/Owner"}
+Tag:
+  type: string
+  enum: [dog, cat]
+`,
+		"common.yaml": `Owner:
+  type: object
+  properties:
+    email: {type: string}
+`,
+	})
+
+	cfg := &config.Config{APIs: []config.APIConfig{{Name: "pets", SpecFile: filepath.Join(dir, "openapi.yaml")}}}
+	services, err := LoadServices(context.Background(), cfg, logging.Discard(), redact.NewRedactor())
+	if err != nil {
+		t.Fatalf("LoadServices: %v", err)
+	}
+	op := services[0].Operations[0]
+	body, _ := op.InputSchema["properties"].(map[string]any)["body"].(map[string]any)
+	props, _ := body["properties"].(map[string]any)
+	for _, name := range []string{"name", "tag", "owner"} {
+		if _, ok := props[name]; !ok {
+			t.Fatalf("body schema missing %q: %v", name, op.InputSchema)
+		}
+	}
+	if enum, _ := props["tag"].(map[string]any)["enum"].([]any); len(enum) != 2 {
+		t.Errorf("tag enum not inlined: %v", props["tag"])
+	}
+	if _, ok := props["owner"].(map[string]any)["properties"].(map[string]any)["email"]; !ok {
+		t.Errorf("owner (two files away) not inlined: %v", props["owner"])
+	}
+	if _, ok := op.ResponseSchema["properties"].(map[string]any)["id"]; !ok {
+		t.Errorf("local component ref in root document not resolved: %v", op.ResponseSchema)
+	}
+}
+
+func TestResolveExternalRefsRemote(t *testing.T) {
+	var gotAuth []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/specs/models.json", func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = append(gotAuth, r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`{"Node": {"type": "object", "properties": {"children": {"type": "array", "items": {"$ref": "#/Node"}}}}}`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	root := []byte(`{"openapi": "3.0.0", "x": {"$ref": "models.json#/Node"}, "y": {"$ref": "/etc/passwd"}}`)
+	auth := &config.AuthConfig{Type: "bearer", Token: "secret"}
+	out, warnings, err := resolveExternalRefs(context.Background(), NewFetcher(0), root, srv.URL+"/specs/openapi.json", auth)
+	if err != nil {
+		t.Fatalf("resolveExternalRefs: %v", err)
+	}
+
+	var doc map[string]any
+	if err := json.Unmarshal(out, &doc); err != nil {
+		t.Fatal(err)
+	}
+	node := doc["x"].(map[string]any)
+	items := node["properties"].(map[string]any)["children"].(map[string]any)["items"].(map[string]any)
+	if !strings.Contains(items["description"].(string), "circular") {
+		t.Errorf("self reference should become a placeholder, got %v", items)
+	}
+	if y := doc["y"].(map[string]any); !strings.Contains(y["description"].(string), "unresolved") {
+		t.Errorf("remote spec must not read local files, got %v", y)
+	}
+	if len(gotAuth) != 1 || gotAuth[0] != "Bearer secret" {
+		t.Errorf("expected one fetch carrying the spec auth, got %v", gotAuth)
+	}
+	if len(warnings) != 2 {
+		t.Errorf("warnings = %v, want circular + unresolved", warnings)
+	}
+}
+
+func TestResolveExternalRefsBudget(t *testing.T) {
+	fetches := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		// Every document points at a new one.
+		_, _ = w.Write([]byte(`{"next": {"$ref": "` + r.URL.Path + `x"}}`))
+	}))
+	defer srv.Close()
+
+	root := []byte(`{"openapi": "3.0.0", "start": {"$ref": "a"}}`)
+	if _, _, err := resolveExternalRefs(context.Background(), NewFetcher(0), root, srv.URL+"/", nil); err != nil {
+		t.Fatalf("resolveExternalRefs: %v", err)
+	}
+	if fetches > maxRefDocuments {
+		t.Fatalf("fetched %d documents, budget is %d", fetches, maxRefDocuments)
+	}
+}
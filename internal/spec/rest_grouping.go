@@ -10,12 +10,19 @@ import (
 	"skyline-mcp/internal/config"
 )
 
+// curatedSpecTypes are the spec types whose tools are few and hand-written
+// already; grouping them would merge tools that were kept apart on purpose.
+var curatedSpecTypes = map[string]bool{"jira": true, "github": true, "salesforce": true}
+
 // ApplyRESTGrouping applies REST CRUD grouping to services that opt in.
 // Auto-enabled for well-known APIs (Jira, Slack) and any API with optimization.enable_crud_grouping.
 func ApplyRESTGrouping(services []*canonical.Service, apiConfigs []config.APIConfig, logger *slog.Logger) []*canonical.Service {
 	// Build lookup of which APIs should have REST grouping
 	shouldGroup := make(map[string]bool)
 	for _, api := range apiConfigs {
+		if curatedSpecTypes[api.SpecType] {
+			continue
+		}
 		if api.Optimization != nil && api.Optimization.EnableCRUDGrouping {
 			shouldGroup[api.Name] = true
 		}
@@ -60,7 +67,7 @@ func GroupRESTOperations(ops []*canonical.Operation, apiName string) []*canonica
 	groupOrder := make([]string, 0)
 	for _, op := range ops {
 		// Skip non-REST operations (GraphQL, gRPC, etc.)
-		if op.GraphQL != nil || op.Protocol == "grpc" || op.JSONRPC != nil || op.RESTComposite != nil {
+		if op.GraphQL != nil || op.Protocol == "grpc" || op.JSONRPC != nil || op.ODataBatch != nil || op.SQL != nil || op.AWS != nil || op.Prometheus != nil || op.Messaging != nil || op.SSH != nil || op.RESTComposite != nil {
 			continue
 		}
 		key := computeResourceKey(op.Path)
@@ -76,7 +83,7 @@ func GroupRESTOperations(ops []*canonical.Operation, apiName string) []*canonica
 	// Collect non-REST ops to pass through unchanged
 	var result []*canonical.Operation
 	for _, op := range ops {
-		if op.GraphQL != nil || op.Protocol == "grpc" || op.JSONRPC != nil || op.RESTComposite != nil {
+		if op.GraphQL != nil || op.Protocol == "grpc" || op.JSONRPC != nil || op.ODataBatch != nil || op.SQL != nil || op.AWS != nil || op.Prometheus != nil || op.Messaging != nil || op.SSH != nil || op.RESTComposite != nil {
 			result = append(result, op)
 		}
 	}
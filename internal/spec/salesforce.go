@@ -0,0 +1,113 @@
+package spec
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/parsers/salesforce"
+	"skyline-mcp/internal/redact"
+)
+
+// loadSalesforce builds a spec_type: salesforce service from the org's
+// describe results for the configured objects. Objects that fail to
+// describe (e.g. Lead in an org without it) are skipped.
+func loadSalesforce(ctx context.Context, fetcher *Fetcher, api config.APIConfig, logger *slog.Logger, redactor *redact.Redactor) (*canonical.Service, error) {
+	base := strings.TrimRight(api.BaseURLOverride, "/")
+	opts := config.SalesforceConfig{}
+	if api.Salesforce != nil {
+		opts = *api.Salesforce
+	}
+	objects := opts.Objects
+	if len(objects) == 0 {
+		objects = config.DefaultSalesforceObjects
+	}
+
+	auth := api.Auth
+	if auth != nil && auth.Type == "oauth2" {
+		token, err := fetcher.salesforceToken(ctx, auth)
+		if err != nil {
+			return nil, err
+		}
+		auth = &config.AuthConfig{Type: "bearer", Token: token}
+	}
+
+	version := opts.APIVersion
+	if version == "" {
+		raw, err := fetcher.Fetch(ctx, base+"/services/data", auth)
+		if err != nil {
+			return nil, fmt.Errorf("salesforce versions: %w", err)
+		}
+		if version, err = salesforce.LatestVersion(raw); err != nil {
+			return nil, err
+		}
+	}
+
+	var described []*salesforce.SObject
+	for _, name := range objects {
+		describeURL := fmt.Sprintf("%s/services/data/v%s/sobjects/%s/describe", base, version, url.PathEscape(name))
+		raw, err := fetcher.Fetch(ctx, describeURL, auth)
+		if err != nil {
+			logger.Warn("salesforce object skipped", "api", api.Name, "object", name, "url", redactor.Redact(describeURL), "error", err)
+			continue
+		}
+		obj, err := salesforce.ParseDescribe(raw)
+		if err != nil {
+			logger.Warn("salesforce object skipped", "api", api.Name, "object", name, "error", err)
+			continue
+		}
+		described = append(described, obj)
+	}
+	if len(described) == 0 {
+		return nil, fmt.Errorf("salesforce: none of the objects %s could be described", strings.Join(objects, ", "))
+	}
+	logger.Info("loaded salesforce org", "api", api.Name, "version", version, "objects", len(described))
+	return salesforce.BuildService(api.Name, base, version, described, opts.ReadOnly), nil
+}
+
+// salesforceToken exchanges the refresh token of auth for an access token
+// to describe the org with; the executor gets its own for tool calls.
+func (f *Fetcher) salesforceToken(ctx context.Context, auth *config.AuthConfig) (string, error) {
+	tokenURL := auth.TokenURL
+	if tokenURL == "" {
+		tokenURL = config.SalesforceTokenURL
+	}
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"client_id":     {auth.ClientID},
+		"client_secret": {auth.ClientSecret},
+		"refresh_token": {auth.RefreshToken},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("salesforce token: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("salesforce token: %w", err)
+	}
+	defer resp.Body.Close()
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		Error       string `json:"error"`
+		ErrorDesc   string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return "", fmt.Errorf("salesforce token: parse response: %w", err)
+	}
+	if tok.Error != "" {
+		return "", fmt.Errorf("salesforce token: %s — %s", tok.Error, tok.ErrorDesc)
+	}
+	if tok.AccessToken == "" {
+		return "", fmt.Errorf("salesforce token: empty access_token")
+	}
+	return tok.AccessToken, nil
+}
@@ -0,0 +1,68 @@
+package spec
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/logging"
+	"skyline-mcp/internal/redact"
+)
+
+func TestLoadSalesforceDescribe(t *testing.T) {
+	var gotAuth []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/services/oauth2/token":
+			if r.FormValue("grant_type") != "refresh_token" || r.FormValue("refresh_token") != "rt" {
+				http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+				return
+			}
+			_, _ = w.Write([]byte(`{"access_token":"at","instance_url":"https://acme.my.salesforce.com"}`))
+			return
+		case "/services/data":
+			_, _ = w.Write([]byte(`[{"version":"60.0"},{"version":"61.0"}]`))
+		case "/services/data/v61.0/sobjects/Account/describe":
+			_, _ = w.Write([]byte(`{"name":"Account","label":"Account","queryable":true,"retrieveable":true,"createable":true,
+				"fields":[{"name":"Id","type":"id"},{"name":"Name","type":"string","nameField":true,"createable":true}]}`))
+		default:
+			// An object the org does not have.
+			http.Error(w, `[{"errorCode":"NOT_FOUND"}]`, http.StatusNotFound)
+			return
+		}
+		gotAuth = append(gotAuth, r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	api := config.APIConfig{
+		Name:            "crm",
+		SpecType:        "salesforce",
+		BaseURLOverride: server.URL,
+		Auth:            &config.AuthConfig{Type: "oauth2", ClientID: "id", ClientSecret: "secret", RefreshToken: "rt", TokenURL: server.URL + "/services/oauth2/token"},
+		Salesforce:      &config.SalesforceConfig{Objects: []string{"Account", "Lead"}},
+	}
+	svc, err := loadSalesforce(context.Background(), NewFetcher(0), api, logging.Discard(), redact.NewRedactor())
+	if err != nil {
+		t.Fatalf("loadSalesforce: %v", err)
+	}
+	for _, auth := range gotAuth {
+		if auth != "Bearer at" {
+			t.Errorf("Authorization = %q, want the exchanged access token", auth)
+		}
+	}
+	tools := map[string]bool{}
+	for _, op := range svc.Operations {
+		tools[op.ID] = true
+	}
+	if !tools["queryAccount"] || !tools["createAccount"] || tools["updateAccount"] || tools["queryLead"] {
+		t.Errorf("tools = %v, want Account's query and create tools and no Lead tools", tools)
+	}
+
+	api.Salesforce.Objects = []string{"Lead"}
+	if _, err := loadSalesforce(context.Background(), NewFetcher(0), api, logging.Discard(), redact.NewRedactor()); err == nil {
+		t.Error("expected an error when no object can be described")
+	}
+}
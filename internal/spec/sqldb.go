@@ -0,0 +1,40 @@
+package spec
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/parsers/sqldb"
+	"skyline-mcp/internal/redact"
+)
+
+// loadSQL builds a spec_type: sql service by introspecting the configured
+// database. The connection is only held for introspection; the executor
+// opens its own pool on first use.
+func loadSQL(ctx context.Context, api config.APIConfig, logger *slog.Logger, redactor *redact.Redactor) (*canonical.Service, error) {
+	if api.Database == nil {
+		return nil, fmt.Errorf("database config is required for spec_type sql")
+	}
+	dialect, err := sqldb.ParseDialect(api.Database.Driver)
+	if err != nil {
+		return nil, err
+	}
+	db, err := sqldb.Open(ctx, dialect, api.Database.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("%s", redactor.Redact(err.Error()))
+	}
+	defer db.Close()
+
+	tables, err := sqldb.Introspect(ctx, db, dialect, api.Database.Schema, api.Database.Tables)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("introspected database", "api", api.Name, "driver", string(dialect), "tables", len(tables))
+	return sqldb.BuildService(api.Name, tables, sqldb.Options{
+		ReadOnly: api.Database.ReadOnly,
+		MaxRows:  api.Database.MaxRows,
+	})
+}
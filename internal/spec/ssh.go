@@ -0,0 +1,24 @@
+package spec
+
+import (
+	"fmt"
+	"log/slog"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/parsers/ssh"
+)
+
+// loadSSH builds a spec_type: ssh service from its command allowlist.
+// Hosts are not contacted until a tool runs.
+func loadSSH(api config.APIConfig, logger *slog.Logger) (*canonical.Service, error) {
+	if api.SSH == nil {
+		return nil, fmt.Errorf("ssh config is required for spec_type ssh")
+	}
+	svc, err := ssh.BuildService(api.Name, api.SSH)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("loading ssh service", "api", api.Name, "hosts", len(api.SSH.Hosts), "commands", len(api.SSH.Commands))
+	return svc, nil
+}
@@ -0,0 +1,101 @@
+package spec
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+)
+
+// ApplyWorkflows compiles the config's workflows into tools of a
+// "workflows" service appended to services. Step tools are resolved against
+// the loaded services; a workflow that names an unknown tool is skipped with
+// a warning.
+func ApplyWorkflows(services []*canonical.Service, workflows []config.WorkflowConfig, logger *slog.Logger) []*canonical.Service {
+	if len(workflows) == 0 {
+		return services
+	}
+	tools := map[string]*canonical.Operation{}
+	for _, svc := range services {
+		for _, op := range svc.Operations {
+			tools[op.ToolName] = op
+		}
+	}
+
+	wfService := &canonical.Service{Name: config.WorkflowServiceName}
+	for _, wf := range workflows {
+		op, err := compileWorkflow(wf, tools)
+		if err != nil {
+			logger.Warn("skipping workflow", "workflow", wf.Name, "error", err)
+			continue
+		}
+		wfService.Operations = append(wfService.Operations, op)
+	}
+	if len(wfService.Operations) == 0 {
+		return services
+	}
+	return append(services, wfService)
+}
+
+func compileWorkflow(wf config.WorkflowConfig, tools map[string]*canonical.Operation) (*canonical.Operation, error) {
+	workflow := &canonical.Workflow{Output: wf.Output}
+	var stepTools []string
+	for _, step := range wf.Steps {
+		target, ok := tools[step.Tool]
+		if !ok {
+			return nil, fmt.Errorf("step %s: unknown tool %s", step.ID, step.Tool)
+		}
+		workflow.Steps = append(workflow.Steps, canonical.WorkflowStep{
+			ID:              step.ID,
+			Operation:       target,
+			Args:            step.Args,
+			When:            step.When,
+			ContinueOnError: step.ContinueOnError,
+		})
+		stepTools = append(stepTools, step.Tool)
+	}
+
+	properties := map[string]any{}
+	var required []string
+	for _, in := range wf.Inputs {
+		typ := in.Type
+		if typ == "" {
+			typ = "string"
+		}
+		prop := map[string]any{"type": typ}
+		if in.Description != "" {
+			prop["description"] = in.Description
+		}
+		properties[in.Name] = prop
+		if in.Required {
+			required = append(required, in.Name)
+		}
+	}
+	inputSchema := map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		sort.Strings(required)
+		inputSchema["required"] = required
+	}
+
+	summary := wf.Description
+	if summary == "" {
+		summary = "Workflow: " + strings.Join(stepTools, " → ")
+	}
+	return &canonical.Operation{
+		ServiceName: config.WorkflowServiceName,
+		ID:          wf.Name,
+		ToolName:    canonical.ToolName(config.WorkflowServiceName, wf.Name),
+		Method:      "post",
+		Summary:     summary,
+		InputSchema: inputSchema,
+		Protocol:    "workflow",
+		Workflow:    workflow,
+	}, nil
+}
@@ -0,0 +1,66 @@
+package spec
+
+import (
+	"testing"
+
+	"skyline-mcp/internal/canonical"
+	"skyline-mcp/internal/config"
+	"skyline-mcp/internal/logging"
+)
+
+func TestApplyWorkflows(t *testing.T) {
+	createIssue := &canonical.Operation{ID: "createIssue", ToolName: "jira__createIssue", Method: "post", Path: "/issue"}
+	postMessage := &canonical.Operation{ID: "postMessage", ToolName: "slack__postMessage", Method: "post", Path: "/chat.postMessage"}
+	services := []*canonical.Service{
+		{Name: "jira", Operations: []*canonical.Operation{createIssue}},
+		{Name: "slack", Operations: []*canonical.Operation{postMessage}},
+	}
+	workflows := []config.WorkflowConfig{
+		{
+			Name:        "file_incident",
+			Description: "Create a Jira issue and announce it in Slack",
+			Inputs: []config.WorkflowInput{
+				{Name: "summary", Required: true},
+				{Name: "priority", Type: "integer"},
+			},
+			Steps: []config.WorkflowStep{
+				{ID: "issue", Tool: "jira__createIssue", Args: map[string]any{"summary": "{{input.summary}}"}},
+				{ID: "notify", Tool: "slack__postMessage", When: "{{steps.issue.status}} == 201"},
+			},
+		},
+		{
+			Name:  "broken",
+			Steps: []config.WorkflowStep{{ID: "x", Tool: "jira__missing"}},
+		},
+	}
+
+	out := ApplyWorkflows(services, workflows, logging.Discard())
+	if len(out) != 3 || out[2].Name != config.WorkflowServiceName {
+		t.Fatalf("expected a workflows service appended, got %d services", len(out))
+	}
+	ops := out[2].Operations
+	if len(ops) != 1 {
+		t.Fatalf("expected the workflow with an unknown tool to be skipped, got %d ops", len(ops))
+	}
+	op := ops[0]
+	if op.ToolName != "workflows__file_incident" || op.Summary != "Create a Jira issue and announce it in Slack" {
+		t.Fatalf("unexpected op: %s %q", op.ToolName, op.Summary)
+	}
+	if op.Workflow == nil || len(op.Workflow.Steps) != 2 {
+		t.Fatalf("expected two compiled steps, got %#v", op.Workflow)
+	}
+	if op.Workflow.Steps[0].Operation != createIssue || op.Workflow.Steps[1].Operation != postMessage {
+		t.Fatal("steps not resolved to their operations")
+	}
+	props := op.InputSchema["properties"].(map[string]any)
+	if props["priority"].(map[string]any)["type"] != "integer" || props["summary"].(map[string]any)["type"] != "string" {
+		t.Fatalf("unexpected input properties: %#v", props)
+	}
+	if req := op.InputSchema["required"].([]string); len(req) != 1 || req[0] != "summary" {
+		t.Fatalf("unexpected required: %#v", req)
+	}
+
+	if got := ApplyWorkflows(services, nil, logging.Discard()); len(got) != 2 {
+		t.Fatalf("expected services unchanged without workflows, got %d", len(got))
+	}
+}